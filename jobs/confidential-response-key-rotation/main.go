@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
+)
+
+// counts and errors by instance, kept in the run history when started by the job scheduler
+var report = jobscheduler.NewReport()
+
+func main() {
+	slog.Info("Starting confidential response key rotation job", slog.Bool("rotateDataKey", conf.RotateDataKey))
+	start := time.Now()
+
+	for _, instanceID := range conf.InstanceIDs {
+		if conf.RotateDataKey {
+			dataKeyID, err := studyDBService.RotateConfidentialResponseDataKey(instanceID)
+			if err != nil {
+				slog.Error("Failed to rotate data key", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+				report.Error(instanceID, err)
+				continue
+			}
+			slog.Info("New data key created", slog.String("instanceID", instanceID), slog.String("dataKeyID", dataKeyID))
+			report.Add(instanceID, "rotatedDataKeys", 1)
+		}
+
+		studies, err := studyDBService.GetStudies(instanceID, "", true)
+		if err != nil {
+			slog.Error("Failed to get studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+			continue
+		}
+
+		for _, study := range studies {
+			count, err := studyDBService.ReencryptConfidentialResponses(instanceID, study.Key, conf.BatchSize)
+			if err != nil {
+				slog.Error("Failed to re-encrypt confidential responses", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.Int64("updated", count), slog.String("error", err.Error()))
+				report.Add(instanceID, "reencryptedResponses", count)
+				report.Error(instanceID, err)
+				continue
+			}
+			report.Add(instanceID, "reencryptedResponses", count)
+			slog.Info("Confidential responses re-encrypted", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.Int64("updated", count))
+		}
+	}
+
+	slog.Info("Confidential response key rotation job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("confidential-response-key-rotation", start)
+	report.Write()
+}
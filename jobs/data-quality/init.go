@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	dataquality "github.com/case-framework/case-backend/pkg/study/data-quality"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME     = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD     = "STUDY_DB_PASSWORD"
+	ENV_MESSAGING_DB_USERNAME = "MESSAGING_DB_USERNAME"
+	ENV_MESSAGING_DB_PASSWORD = "MESSAGING_DB_PASSWORD"
+)
+
+const defaultAlertCooldown = 24 * time.Hour
+
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
+	// DB configs
+	DBConfigs struct {
+		StudyDB     db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+		MessagingDB db.DBConfigYaml `json:"messaging_db" yaml:"messaging_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
+
+	// Window and thresholds of the checks
+	Checks dataquality.Config `json:"checks" yaml:"checks"`
+
+	// A finding is not recorded and alerted again for the same study and type within this duration, defaults to 24h
+	AlertCooldown time.Duration `json:"alert_cooldown" yaml:"alert_cooldown"`
+
+	// Findings are saved as researcher messages of this type, which the messaging job sends to the researchers
+	// subscribed to it with the study email template of the type. Not sent if empty.
+	ResearcherMessageType string `json:"researcher_message_type" yaml:"researcher_message_type"`
+
+	// Findings are published to the webhook endpoints subscribed to study.data_quality_anomaly, failed deliveries are
+	// retried by the messaging job
+	Webhooks messagingTypes.WebhookConfig `json:"webhooks" yaml:"webhooks"`
+}
+
+var conf config
+
+var (
+	studyDBService     *studyDB.StudyDBService
+	messagingDBService *messagingDB.MessagingDBService
+)
+
+func init() {
+	// Read config from file
+	yamlFile, err := os.ReadFile(os.Getenv(ENV_CONFIG_FILE_PATH))
+	if err != nil {
+		panic(err)
+	}
+
+	err = yaml.UnmarshalStrict(yamlFile, &conf)
+	if err != nil {
+		panic(err)
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	if conf.AlertCooldown <= 0 {
+		conf.AlertCooldown = defaultAlertCooldown
+	}
+	conf.Checks = conf.Checks.WithDefaults()
+
+	// Override secrets from environment variables
+	secretsOverride()
+
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
+	// init db
+	initDBs()
+
+	webhooks.Init(messagingDBService, conf.Webhooks)
+}
+
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.DB("db_configs.messaging_db", conf.DBConfigs.MessagingDB)
+	problems.RequiredList("instance_ids", conf.InstanceIDs)
+	problems.Duration("checks.window", conf.Checks.Window, time.Minute, 0)
+	problems.NonNegative("checks.baseline_windows", conf.Checks.BaselineWindows)
+	problems.Duration("alert_cooldown", conf.AlertCooldown, time.Minute, 0)
+	problems.Webhooks("webhooks", conf.Webhooks)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
+func secretsOverride() {
+	// Override secrets from environment variables
+
+	if dbUsername := os.Getenv(ENV_STUDY_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.StudyDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_STUDY_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.StudyDB.Password = dbPassword
+	}
+
+	if dbUsername := os.Getenv(ENV_MESSAGING_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.MessagingDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_MESSAGING_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.MessagingDB.Password = dbPassword
+	}
+}
+
+func initDBs() {
+	var err error
+	studyDBService, err = studyDB.NewStudyDBService(db.DBConfigFromYamlObj(conf.DBConfigs.StudyDB, conf.InstanceIDs))
+	if err != nil {
+		slog.Error("Error connecting to Study DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	messagingDBService, err = messagingDB.NewMessagingDBService(db.DBConfigFromYamlObj(conf.DBConfigs.MessagingDB, conf.InstanceIDs))
+	if err != nil {
+		slog.Error("Error connecting to Messaging DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
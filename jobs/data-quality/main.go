@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	dataquality "github.com/case-framework/case-backend/pkg/study/data-quality"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// counts and errors by instance, kept in the run history when started by the job scheduler
+var report = jobscheduler.NewReport()
+
+func main() {
+	slog.Info("Starting data quality job", slog.String("window", conf.Checks.Window.String()))
+	start := time.Now()
+
+	for _, instanceID := range conf.InstanceIDs {
+		studies, err := studyDBService.GetStudies(instanceID, studyTypes.STUDY_STATUS_ACTIVE, false)
+		if err != nil {
+			slog.Error("Failed to get studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+			continue
+		}
+		report.Add(instanceID, "studies", int64(len(studies)))
+
+		for _, study := range studies {
+			stats, err := getStudyStats(instanceID, study.Key, start)
+			if err != nil {
+				slog.Error("Failed to get study stats", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				report.Error(instanceID, err)
+				continue
+			}
+
+			for _, finding := range dataquality.Detect(conf.Checks, study.Key, stats, start) {
+				reportFinding(instanceID, finding)
+			}
+		}
+	}
+
+	slog.Info("Data quality job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("data-quality", start)
+	report.Write()
+}
+
+// getStudyStats counts responses and rule errors of the window ending now and of the baseline windows before it
+func getStudyStats(instanceID string, studyKey string, now time.Time) (dataquality.Stats, error) {
+	windowStart := now.Add(-conf.Checks.Window)
+	baselineStart := windowStart.Add(-time.Duration(conf.Checks.BaselineWindows) * conf.Checks.Window)
+	stats := dataquality.Stats{
+		WindowStart: windowStart,
+		WindowEnd:   now,
+	}
+
+	var err error
+	if stats.Responses, err = studyDBService.CountSubmittedResponses(instanceID, studyKey, windowStart.Unix(), now.Unix()); err != nil {
+		return stats, err
+	}
+	if stats.BaselineResponses, err = studyDBService.CountSubmittedResponses(instanceID, studyKey, baselineStart.Unix(), windowStart.Unix()); err != nil {
+		return stats, err
+	}
+	if stats.Responses > 0 {
+		if stats.DuplicateResponses, err = studyDBService.CountDuplicateResponses(instanceID, studyKey, windowStart.Unix(), now.Unix()); err != nil {
+			return stats, err
+		}
+	}
+	if stats.RuleErrors, err = studyDBService.CountRuleErrors(instanceID, studyKey, windowStart, now); err != nil {
+		return stats, err
+	}
+	if stats.BaselineRuleErrors, err = studyDBService.CountRuleErrors(instanceID, studyKey, baselineStart, windowStart); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// reportFinding saves the finding and alerts about it, unless the same anomaly of the study was reported recently
+func reportFinding(instanceID string, finding studyTypes.DataQualityFinding) {
+	logAttrs := []any{slog.String("instanceID", instanceID), slog.String("studyKey", finding.StudyKey), slog.String("type", finding.Type)}
+
+	latest, err := studyDBService.GetLatestDataQualityFinding(instanceID, finding.StudyKey, finding.Type)
+	if err != nil {
+		slog.Error("Failed to get latest data quality finding", append(logAttrs, slog.String("error", err.Error()))...)
+		report.Error(instanceID, err)
+		return
+	}
+	if latest != nil && finding.DetectedAt.Sub(latest.DetectedAt) < conf.AlertCooldown {
+		slog.Debug("Data quality finding already reported", logAttrs...)
+		return
+	}
+
+	if err := studyDBService.AddDataQualityFinding(instanceID, finding); err != nil {
+		slog.Error("Failed to save data quality finding", append(logAttrs, slog.String("error", err.Error()))...)
+		report.Error(instanceID, err)
+		return
+	}
+	slog.Warn("Data quality anomaly found", append(logAttrs, slog.String("message", finding.Message))...)
+	report.Add(instanceID, "findings", 1)
+
+	webhooks.Publish(instanceID, webhooks.EVENT_DATA_QUALITY_ANOMALY, map[string]any{
+		"studyKey":    finding.StudyKey,
+		"type":        finding.Type,
+		"windowStart": finding.WindowStart.Unix(),
+		"windowEnd":   finding.WindowEnd.Unix(),
+		"value":       finding.Value,
+		"baseline":    finding.Baseline,
+		"message":     finding.Message,
+	})
+
+	if conf.ResearcherMessageType == "" {
+		return
+	}
+	err = studyDBService.SaveResearcherMessage(instanceID, finding.StudyKey, studyTypes.StudyMessage{
+		Type: conf.ResearcherMessageType,
+		Payload: map[string]string{
+			"findingType": finding.Type,
+			"windowStart": strconv.FormatInt(finding.WindowStart.Unix(), 10),
+			"windowEnd":   strconv.FormatInt(finding.WindowEnd.Unix(), 10),
+			"value":       fmt.Sprintf("%g", finding.Value),
+			"baseline":    fmt.Sprintf("%g", finding.Baseline),
+			"message":     finding.Message,
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to save researcher message", append(logAttrs, slog.String("error", err.Error()))...)
+		report.Error(instanceID, err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/backup"
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME            = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD            = "STUDY_DB_PASSWORD"
+	ENV_PARTICIPANT_USER_DB_USERNAME = "PARTICIPANT_USER_DB_USERNAME"
+	ENV_PARTICIPANT_USER_DB_PASSWORD = "PARTICIPANT_USER_DB_PASSWORD"
+	ENV_BACKUP_S3_ACCESS_KEY_ID      = "BACKUP_S3_ACCESS_KEY_ID"
+	ENV_BACKUP_S3_SECRET_ACCESS_KEY  = "BACKUP_S3_SECRET_ACCESS_KEY"
+	ENV_BACKUP_VAULT_TOKEN           = "BACKUP_VAULT_TOKEN"
+)
+
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
+	// DB configs
+	DBConfigs struct {
+		StudyDB           db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+		ParticipantUserDB db.DBConfigYaml `json:"participant_user_db" yaml:"participant_user_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
+
+	// Collections, storage, encryption keys and retention of the archives
+	Backup backup.Config `json:"backup" yaml:"backup"`
+}
+
+var conf config
+
+var (
+	studyDBService           *studyDB.StudyDBService
+	participantUserDBService *userDB.ParticipantUserDBService
+	storage                  backup.Storage
+)
+
+func init() {
+	// Read config from file
+	yamlFile, err := os.ReadFile(os.Getenv(ENV_CONFIG_FILE_PATH))
+	if err != nil {
+		panic(err)
+	}
+
+	err = yaml.UnmarshalStrict(yamlFile, &conf)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(conf.Backup.Collections) == 0 {
+		conf.Backup.Collections = backup.DefaultCollections
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
+	storage, err = backup.NewStorage(conf.Backup.Storage)
+	if err != nil {
+		slog.Error("Error creating backup storage", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	// init db
+	initDBs()
+}
+
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.DB("db_configs.participant_user_db", conf.DBConfigs.ParticipantUserDB)
+	problems.RequiredList("instance_ids", conf.InstanceIDs)
+	problems.Check("backup", conf.Backup.Validate(conf.InstanceIDs))
+	problems.Duration("backup.max_age", conf.Backup.MaxAge, time.Hour, 0)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
+func secretsOverride() {
+	// Override secrets from environment variables
+
+	if dbUsername := os.Getenv(ENV_STUDY_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.StudyDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_STUDY_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.StudyDB.Password = dbPassword
+	}
+
+	if dbUsername := os.Getenv(ENV_PARTICIPANT_USER_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.ParticipantUserDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_PARTICIPANT_USER_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.ParticipantUserDB.Password = dbPassword
+	}
+
+	if conf.Backup.Storage.S3 != nil {
+		if accessKeyID := os.Getenv(ENV_BACKUP_S3_ACCESS_KEY_ID); accessKeyID != "" {
+			conf.Backup.Storage.S3.AccessKeyID = accessKeyID
+		}
+		if secretAccessKey := os.Getenv(ENV_BACKUP_S3_SECRET_ACCESS_KEY); secretAccessKey != "" {
+			conf.Backup.Storage.S3.SecretAccessKey = secretAccessKey
+		}
+	}
+
+	// the token is used for all instances with a vault transit key
+	if vaultToken := os.Getenv(ENV_BACKUP_VAULT_TOKEN); vaultToken != "" {
+		conf.Backup.Encryption.VaultTransit.Token = vaultToken
+		for instanceID, encryption := range conf.Backup.InstanceEncryption {
+			encryption.VaultTransit.Token = vaultToken
+			conf.Backup.InstanceEncryption[instanceID] = encryption
+		}
+	}
+}
+
+func initDBs() {
+	var err error
+	studyDBService, err = studyDB.NewStudyDBService(db.DBConfigFromYamlObj(conf.DBConfigs.StudyDB, conf.InstanceIDs))
+	if err != nil {
+		slog.Error("Error connecting to Study DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	// only needed if user accounts are backed up
+	if slices.Contains(conf.Backup.Collections, backup.COLLECTION_USERS) {
+		participantUserDBService, err = userDB.NewParticipantUserDBService(db.DBConfigFromYamlObj(conf.DBConfigs.ParticipantUserDB, conf.InstanceIDs))
+		if err != nil {
+			slog.Error("Error connecting to Participant User DB", slog.String("error", err.Error()))
+			panic(err)
+		}
+	}
+}
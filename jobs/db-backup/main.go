@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/backup"
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
+
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+)
+
+// counts and errors by instance, kept in the run history when started by the job scheduler
+var report = jobscheduler.NewReport()
+
+func main() {
+	slog.Info("Starting DB backup job", slog.Any("collections", conf.Backup.Collections))
+	start := time.Now()
+
+	for _, instanceID := range conf.InstanceIDs {
+		name, err := backupInstance(instanceID, start)
+		if err != nil {
+			slog.Error("Failed to back up instance", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+			continue
+		}
+		slog.Info("Backed up instance", slog.String("instanceID", instanceID), slog.String("archive", name))
+		report.Add(instanceID, "archives", 1)
+
+		// old archives are only removed after a new one was stored
+		rotateArchives(instanceID, start)
+	}
+
+	slog.Info("DB backup job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("db-backup", start)
+	report.Write()
+}
+
+// backupInstance writes the archive to a temporary file and uploads it, returns where it was stored
+func backupInstance(instanceID string, now time.Time) (string, error) {
+	keys, err := conf.Backup.KeyProvider(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp("", "backup-*"+backup.ARCHIVE_EXTENSION)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	archive, err := backup.NewArchiveWriter(file, instanceID, keys, now)
+	if err != nil {
+		return "", err
+	}
+	if err := writeCollections(archive, instanceID); err != nil {
+		return "", err
+	}
+	if err := archive.Close(); err != nil {
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	return storage.Upload(context.Background(), file.Name(), backup.ArchiveName(instanceID, now))
+}
+
+func writeCollections(archive *backup.ArchiveWriter, instanceID string) error {
+	ctx := context.Background()
+
+	if slices.Contains(conf.Backup.Collections, backup.COLLECTION_USERS) {
+		if err := archive.StartEntry(backup.ENTRY_PREFIX_PARTICIPANT_USER_DB + userDB.COLLECTION_NAME_PARTICIPANT_USERS); err != nil {
+			return err
+		}
+		count, err := participantUserDBService.DumpCollection(ctx, instanceID, userDB.COLLECTION_NAME_PARTICIPANT_USERS, archive.WriteDocument)
+		if err != nil {
+			return err
+		}
+		report.Add(instanceID, "users", count)
+	}
+
+	suffixes := map[string]string{
+		backup.COLLECTION_PARTICIPANTS: studyDB.COLLECTION_NAME_SUFFIX_PARTICIPANTS,
+		backup.COLLECTION_RESPONSES:    studyDB.COLLECTION_NAME_SUFFIX_RESPONSES,
+		backup.COLLECTION_REPORTS:      studyDB.COLLECTION_NAME_SUFFIX_REPORTS,
+	}
+	studies, err := studyDBService.GetStudies(instanceID, "", true)
+	if err != nil {
+		return err
+	}
+	for _, study := range studies {
+		for _, collection := range conf.Backup.Collections {
+			suffix, ok := suffixes[collection]
+			if !ok {
+				continue
+			}
+			collectionName := study.Key + "_" + suffix
+			if err := archive.StartEntry(backup.ENTRY_PREFIX_STUDY_DB + collectionName); err != nil {
+				return err
+			}
+			count, err := studyDBService.DumpCollection(ctx, instanceID, collectionName, archive.WriteDocument)
+			if err != nil {
+				return err
+			}
+			report.Add(instanceID, collection, count)
+		}
+	}
+	return nil
+}
+
+// rotateArchives deletes the archives of the instance that exceed the retention settings
+func rotateArchives(instanceID string, now time.Time) {
+	if conf.Backup.KeepLast == 0 && conf.Backup.MaxAge == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	names, err := storage.List(ctx, instanceID+"/")
+	if err != nil {
+		slog.Error("Failed to list archives", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		report.Error(instanceID, err)
+		return
+	}
+	for _, name := range backup.ExpiredArchives(names, conf.Backup.KeepLast, conf.Backup.MaxAge, now) {
+		if err := storage.Delete(ctx, name); err != nil {
+			slog.Error("Failed to delete archive", slog.String("instanceID", instanceID), slog.String("archive", name), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+			continue
+		}
+		slog.Info("Deleted expired archive", slog.String("instanceID", instanceID), slog.String("archive", name))
+		report.Add(instanceID, "deletedArchives", 1)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/messaging/campaigns"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+)
+
+func handleCampaigns(wg *sync.WaitGroup) {
+	defer wg.Done()
+	slog.Info("Start handling campaigns")
+
+	for _, instanceID := range conf.InstanceIDs {
+		now := time.Now()
+		dueCampaigns, err := messagingDBService.GetDueCampaigns(instanceID, now.Unix())
+		if err != nil {
+			slog.Error("Failed to get due campaigns", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+			continue
+		}
+
+		for _, campaign := range dueCampaigns {
+			// a run falling into quiet hours (e.g. after a delayed job execution) is moved to the end of the quiet period
+			allowedAt, err := campaigns.QuietHoursEnd(campaign.QuietHours, now)
+			if err != nil {
+				slog.Error("Invalid quiet hours for campaign", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()))
+				continue
+			}
+
+			var nextRunAt int64
+			if allowedAt.After(now) {
+				nextRunAt = allowedAt.Unix()
+			} else {
+				campaign.LastRunAt = now.Unix()
+				nextRunAt, err = campaigns.NextRun(campaign, now)
+				if err != nil {
+					slog.Error("Failed to compute next run of campaign", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()))
+					continue
+				}
+			}
+
+			claimed, err := messagingDBService.ClaimCampaignRun(instanceID, campaign.ID, campaign.NextRunAt, nextRunAt)
+			if err != nil {
+				slog.Error("Failed to update campaign", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()))
+				continue
+			}
+			if !claimed {
+				slog.Debug("Campaign run already claimed", slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()))
+				continue
+			}
+			if allowedAt.After(now) {
+				slog.Info("Campaign run postponed due to quiet hours", slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()), slog.Int64("nextRunAt", nextRunAt))
+				continue
+			}
+
+			generateMessagesForCampaign(instanceID, campaign, now)
+		}
+	}
+	slog.Info("Finished handling campaigns")
+}
+
+func generateMessagesForCampaign(instanceID string, campaign messagingTypes.Campaign, now time.Time) {
+	slog.Debug("Start generating messages for campaign", slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()), slog.String("label", campaign.Label))
+
+	var study *studyTypes.Study
+	if campaign.Audience.StudyKey != "" {
+		s, err := studyDBService.GetStudy(instanceID, campaign.Audience.StudyKey)
+		if err != nil {
+			slog.Error("Failed to get study for campaign", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()))
+			return
+		}
+		study = &s
+		campaign.Template.StudyKey = campaign.Audience.StudyKey
+	}
+
+	// prepared messages are reused from scheduled emails, the campaign ID is used for tracking
+	message := messagingTypes.ScheduledEmail{
+		ID:       campaign.ID,
+		Template: campaign.Template,
+		StudyKey: campaign.Audience.StudyKey,
+		Label:    campaign.Label,
+	}
+
+	counters := InitMessageCounter()
+	err := participantUserDBService.FindAndExecuteOnUsers(
+		context.Background(),
+		instanceID,
+		campaigns.UserFilter(campaign.Audience),
+		nil,
+		false,
+		func(user umTypes.User, args ...interface{}) error {
+			if !campaigns.MatchesUser(user, campaign.Audience, campaign.Template.MessageType, now) {
+				return nil
+			}
+			if study != nil && !campaigns.HasMatchingParticipant(studyDBService, instanceID, *study, user, campaign.Audience.Flags) {
+				return nil
+			}
+
+			outgoingEmail, err := prepOutgoingFromScheduledEmail(instanceID, message, user)
+			if err != nil {
+				slog.Error("Failed to prepare outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()), slog.String("userID", user.ID.Hex()))
+				counters.IncreaseCounter(false)
+				return nil
+			}
+
+			_, err = messagingDBService.AddToOutgoingEmails(instanceID, *outgoingEmail)
+			if err != nil {
+				slog.Error("Failed to save outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()), slog.String("userID", user.ID.Hex()))
+				counters.IncreaseCounter(false)
+				return nil
+			}
+
+			counters.IncreaseCounter(true)
+			return nil
+		},
+	)
+	counters.Stop()
+	if err != nil {
+		slog.Error("Failed to get users for campaign", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()), slog.Int("generatedMessages", counters.Success), slog.Int("failedMessages", counters.Failed))
+		return
+	}
+	slog.Info("Generated messages for campaign", slog.String("instanceID", instanceID), slog.String("campaignID", campaign.ID.Hex()), slog.Int("generatedMessages", counters.Success), slog.Int("failedMessages", counters.Failed))
+}
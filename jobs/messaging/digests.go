@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/messaging/digests"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyservice "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func handleDigests(wg *sync.WaitGroup) {
+	defer wg.Done()
+	slog.Info("Start handling digest emails")
+
+	for _, instanceID := range conf.InstanceIDs {
+		counters := InitMessageCounter()
+
+		template, err := messagingDBService.GetGlobalEmailTemplateByMessageType(instanceID, messagingTypes.EMAIL_TYPE_STUDY_DIGEST)
+		if err != nil {
+			slog.Debug("No digest email template, skipping instance", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+
+		studies, err := studyDBService.GetStudies(instanceID, studyTypes.STUDY_STATUS_ACTIVE, false)
+		if err != nil {
+			slog.Error("Error getting studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+
+		filter := bson.M{
+			"account.accountConfirmedAt": bson.M{"$gt": 0},
+			"account.type":               "email",
+		}
+		err = participantUserDBService.FindAndExecuteOnUsers(
+			context.Background(),
+			instanceID,
+			filter,
+			nil,
+			false,
+			func(user umTypes.User, args ...interface{}) error {
+				if user.IsAnonymized() {
+					return nil
+				}
+
+				now := time.Now()
+				frequency := digests.EffectiveFrequency(user.ContactPreferences.DigestFrequency, conf.MessagingConfigs.Digests.DefaultFrequency)
+				if !digests.IsDue(frequency, time.Weekday(user.ContactPreferences.ReceiveWeeklyMessageDayOfWeek), user.Timestamps.LastDigestSentAt, now) {
+					return nil
+				}
+
+				tasks := getPendingTasks(instanceID, studies, user, now)
+				if len(tasks) == 0 {
+					return nil
+				}
+
+				outgoingEmail, err := prepDigestEmail(instanceID, *template, user, tasks)
+				if err != nil {
+					counters.IncreaseCounter(false)
+					return err
+				}
+
+				if _, err := messagingDBService.AddToOutgoingEmails(instanceID, *outgoingEmail); err != nil {
+					slog.Error("Failed to save outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()))
+					counters.IncreaseCounter(false)
+					return err
+				}
+				counters.IncreaseCounter(true)
+
+				return participantUserDBService.UpdateUser(instanceID, user.ID.Hex(), bson.M{"$set": bson.M{"timestamps.lastDigestSentAt": now.Unix()}})
+			},
+		)
+		counters.Stop()
+		if err != nil {
+			slog.Error("Failed to get users for digest emails", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+			continue
+		}
+		slog.Info("Generated digest emails", slog.String("instanceID", instanceID), slog.Int("generatedMessages", counters.Success), slog.Int("failedMessages", counters.Failed))
+	}
+
+	slog.Info("Finished handling digest emails")
+}
+
+// isDigestUser checks if the participant messages of the user with the type are covered by the digest
+func isDigestUser(user umTypes.User, messageType string, studyKey string) bool {
+	if digests.EffectiveFrequency(user.ContactPreferences.DigestFrequency, conf.MessagingConfigs.Digests.DefaultFrequency) == "" {
+		return false
+	}
+	if !digests.IsStudyIncluded(studyKey, user.ContactPreferences.DigestOptOutStudies) {
+		return false
+	}
+	for _, t := range conf.MessagingConfigs.Digests.ReplacesMessageTypes {
+		if t == messageType {
+			return true
+		}
+	}
+	return false
+}
+
+// getPendingTasks collects the open surveys of all profiles of the user in the studies included in the digest
+func getPendingTasks(instanceID string, studies []studyTypes.Study, user umTypes.User, now time.Time) []digests.Task {
+	tasks := []digests.Task{}
+	for _, study := range studies {
+		if !digests.IsStudyIncluded(study.Key, user.ContactPreferences.DigestOptOutStudies) {
+			continue
+		}
+
+		for _, profile := range user.Profiles {
+			participantID, _, err := studyservice.ComputeParticipantIDs(study, profile.ID.Hex())
+			if err != nil {
+				slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				continue
+			}
+
+			pState, err := studyDBService.GetParticipantByID(instanceID, study.Key, participantID)
+			if err != nil || pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
+				continue
+			}
+
+			for _, survey := range pState.AssignedSurveys {
+				if !digests.IsOpenAt(survey.ValidFrom, survey.ValidUntil, now) {
+					continue
+				}
+				tasks = append(tasks, digests.Task{
+					StudyKey:     study.Key,
+					SurveyKey:    survey.SurveyKey,
+					Category:     survey.Category,
+					ProfileAlias: profile.Alias,
+					ValidUntil:   survey.ValidUntil,
+				})
+			}
+		}
+	}
+	digests.SortTasks(tasks)
+	return tasks
+}
+
+func prepDigestEmail(instanceID string, template messagingTypes.EmailTemplate, user umTypes.User, tasks []digests.Task) (*messagingTypes.OutgoingEmail, error) {
+	payload := map[string]string{
+		"language": user.Account.PreferredLanguage,
+	}
+	digests.AddTasksToPayload(payload, tasks)
+
+	loginToken, err := getTemploginToken(instanceID, user, "")
+	if err != nil {
+		return nil, err
+	}
+	payload["loginToken"] = loginToken
+
+	subject, content, err := emailsending.GenerateEmailContent(instanceID, template, user.Account.PreferredLanguage, payload)
+	if err != nil {
+		slog.Error("Error generating email content", slog.String("instanceID", instanceID), slog.String("messageType", template.MessageType), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return &messagingTypes.OutgoingEmail{
+		MessageType:     messagingTypes.EMAIL_TYPE_STUDY_DIGEST,
+		HeaderOverrides: template.HeaderOverrides,
+		To:              []string{user.Account.AccountID},
+		Subject:         subject,
+		Content:         content,
+		Campaign:        messagingTypes.EMAIL_TYPE_STUDY_DIGEST,
+	}, nil
+}
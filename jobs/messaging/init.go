@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/cache"
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
 	"github.com/case-framework/case-backend/pkg/db"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
 	httpclient "github.com/case-framework/case-backend/pkg/http-client"
+	"github.com/case-framework/case-backend/pkg/secrets"
 	"github.com/case-framework/case-backend/pkg/study"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
 	"github.com/case-framework/case-backend/pkg/utils"
@@ -17,7 +22,10 @@ import (
 	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/sandbox"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
 )
 
 // Environment variables
@@ -33,12 +41,18 @@ const (
 	ENV_GLOBAL_INFOS_DB_PASSWORD     = "GLOBAL_INFOS_DB_PASSWORD"
 	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
 	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
+	ENV_CACHE_REDIS_PASSWORD         = "CACHE_REDIS_PASSWORD"
+	ENV_EMAIL_TRACKING_SIGNING_KEY   = "EMAIL_TRACKING_SIGNING_KEY"
 )
 
 type config struct {
 	// Logging configs
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
 
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
 	// DB configs
 	DBConfigs struct {
 		ParticipantUserDB db.DBConfigYaml `json:"participant_user_db" yaml:"participant_user_db"`
@@ -47,15 +61,26 @@ type config struct {
 		StudyDB           db.DBConfigYaml `json:"study_db" yaml:"study_db"`
 	} `json:"db_configs" yaml:"db_configs"`
 
+	// Optional cache of studies, surveys and email templates, disabled if no type is set
+	Cache cache.Config `json:"cache" yaml:"cache"`
+
 	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
 
 	MessagingConfigs messagingTypes.MessagingConfigs `json:"messaging_configs" yaml:"messaging_configs"`
 
+	// Domain events published to message brokers for external data pipelines, disabled if no brokers are set
+	DomainEvents domainevents.Config `json:"domain_events" yaml:"domain_events"`
+
 	RunTasks struct {
 		ProcessOutgoingEmails     bool `json:"process_outgoing_emails" yaml:"process_outgoing_emails"`
 		ScheduleHandler           bool `json:"schedule_handler" yaml:"schedule_handler"`
 		StudyMessagesHandler      bool `json:"study_messages_handler" yaml:"study_messages_handler"`
 		ResearcherMessagesHandler bool `json:"researcher_messages_handler" yaml:"researcher_messages_handler"`
+		CampaignHandler           bool `json:"campaign_handler" yaml:"campaign_handler"`
+		WebhookDeliveries         bool `json:"webhook_deliveries" yaml:"webhook_deliveries"`
+		DigestHandler             bool `json:"digest_handler" yaml:"digest_handler"`
+		// reminders about assigned surveys, as configured per study
+		SurveyReminderHandler bool `json:"survey_reminder_handler" yaml:"survey_reminder_handler"`
 	} `json:"run_tasks" yaml:"run_tasks"`
 
 	Intervals struct {
@@ -107,6 +132,12 @@ func init() {
 	// Override secrets from environment variables
 	secretsOverride()
 
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
 	// init db
 	initDBs()
 
@@ -117,8 +148,43 @@ func init() {
 	initStudyService()
 }
 
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.participant_user_db", conf.DBConfigs.ParticipantUserDB)
+	problems.DB("db_configs.global_infos_db", conf.DBConfigs.GlobalInfosDB)
+	problems.DB("db_configs.messaging_db", conf.DBConfigs.MessagingDB)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.Cache("cache", conf.Cache)
+	problems.RequiredList("instance_ids", conf.InstanceIDs)
+	problems.Messaging("messaging_configs", conf.MessagingConfigs)
+	problems.DomainEvents("domain_events", conf.DomainEvents)
+	problems.Duration("intervals.last_send_attempt_lock_duration", conf.Intervals.LastSendAttemptLockDuration, time.Second, 0)
+	problems.Duration("intervals.login_token_ttl", conf.Intervals.LoginTokenTTL, time.Minute, 0)
+	problems.Duration("intervals.unsubscribe_token_ttl", conf.Intervals.UnsubscribeTokenTTL, time.Minute, 0)
+	problems.Required("study_configs.global_secret", conf.StudyConfigs.GlobalSecret)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
 func secretsOverride() {
 	// Override secrets from environment variables
+	if password := os.Getenv(ENV_CACHE_REDIS_PASSWORD); password != "" {
+		conf.Cache.Redis.Password = password
+	}
 
 	if dbUsername := os.Getenv(ENV_STUDY_DB_USERNAME); dbUsername != "" {
 		conf.DBConfigs.StudyDB.Username = dbUsername
@@ -151,6 +217,10 @@ func secretsOverride() {
 	if dbPassword := os.Getenv(ENV_MESSAGING_DB_PASSWORD); dbPassword != "" {
 		conf.DBConfigs.MessagingDB.Password = dbPassword
 	}
+
+	if signingKey := os.Getenv(ENV_EMAIL_TRACKING_SIGNING_KEY); signingKey != "" {
+		conf.MessagingConfigs.EmailTracking.SigningKey = signingKey
+	}
 }
 
 func initDBs() {
@@ -178,6 +248,22 @@ func initDBs() {
 		slog.Error("Error connecting to Study DB", slog.String("error", err.Error()))
 		panic(err)
 	}
+
+	initCache()
+}
+
+func initCache() {
+	c, ttl, err := cache.New(conf.Cache)
+	if err != nil {
+		slog.Error("Error initializing cache, continuing without cache", slog.String("error", err.Error()))
+		return
+	}
+	if c == nil {
+		return
+	}
+	slog.Info("Cache enabled", slog.String("type", conf.Cache.Type), slog.Duration("ttl", ttl))
+	studyDBService.SetCache(c, ttl)
+	messagingDBService.SetCache(c, ttl)
 }
 
 func initMessageSendingConfig() {
@@ -186,6 +272,28 @@ func initMessageSendingConfig() {
 		conf.MessagingConfigs.GlobalEmailTemplateConstants,
 		messagingDBService,
 	)
+
+	if err := emailsending.InitEmailProviders(
+		conf.MessagingConfigs.EmailProviders,
+		conf.MessagingConfigs.InstanceEmailProviders,
+	); err != nil {
+		slog.Error("Error initializing email providers", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	emailsending.InitEmailTracking(conf.MessagingConfigs.EmailTracking)
+	emailsending.InitEmailRetry(conf.MessagingConfigs.EmailRetry)
+	emailsending.InitEmailAttachments(conf.MessagingConfigs.EmailAttachments)
+	emailsending.InitLanguageFallbacks(conf.MessagingConfigs.LanguageFallbacks, conf.MessagingConfigs.InstanceLanguageFallbacks)
+	webhooks.Init(messagingDBService, conf.MessagingConfigs.Webhooks)
+	sandbox.Init(messagingDBService, conf.MessagingConfigs.Sandbox)
+
+	// for survey reminders by SMS
+	sms.Init(
+		conf.MessagingConfigs.SMSConfig,
+		conf.MessagingConfigs.InstanceSMSConfigs,
+		messagingDBService,
+	)
 }
 
 func initStudyService() {
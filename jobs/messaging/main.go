@@ -4,6 +4,10 @@ import (
 	"log/slog"
 	"sync"
 	"time"
+
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
 )
 
 const (
@@ -12,10 +16,20 @@ const (
 	MAX_FAILED_ATTEMPTS_BEFORE_STOP = 100
 )
 
+// counts by instance and task, kept in the run history when started by the job scheduler
+var report = jobscheduler.NewReport()
+
 func main() {
 	slog.Info("Starting messaging job")
 	start := time.Now()
 
+	domainEvents, err := domainevents.Init(conf.DomainEvents, "messaging-job")
+	if err != nil {
+		slog.Error("failed to init domain event publisher", slog.String("error", err.Error()))
+	} else if domainEvents != nil {
+		defer domainEvents.Close()
+	}
+
 	var wg sync.WaitGroup
 
 	if conf.RunTasks.ProcessOutgoingEmails {
@@ -38,6 +52,28 @@ func main() {
 		go handleResearcherNotifications(&wg)
 	}
 
+	if conf.RunTasks.CampaignHandler {
+		wg.Add(1)
+		go handleCampaigns(&wg)
+	}
+
+	if conf.RunTasks.DigestHandler {
+		wg.Add(1)
+		go handleDigests(&wg)
+	}
+
+	if conf.RunTasks.SurveyReminderHandler {
+		wg.Add(1)
+		go handleSurveyReminders(&wg)
+	}
+
+	if conf.RunTasks.WebhookDeliveries {
+		wg.Add(1)
+		go handleWebhookDeliveries(&wg)
+	}
+
 	wg.Wait()
 	slog.Info("Messaging job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("messaging", start)
+	report.Write()
 }
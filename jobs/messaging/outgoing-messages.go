@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"log/slog"
 	"sync"
 	"time"
@@ -76,14 +77,32 @@ func handleOutgoingMessages(wg *sync.WaitGroup) {
 					continue
 				}
 
-				err := emailsending.SendOutgoingEmail(&email)
+				err := emailsending.SendOutgoingEmail(instanceID, &email)
+				if errors.Is(err, emailsending.ErrAllRecipientsSuppressed) {
+					counters.IncreaseCounter(false)
+					slog.Info("Outgoing email dropped, all recipients suppressed", slog.String("instanceID", instanceID), slog.String("messageType", email.MessageType))
+					err = messagingDBService.DeleteOutgoingEmail(instanceID, email.ID.Hex())
+					if err != nil {
+						slog.Error("Failed to delete outgoing email", slog.String("messageType", email.MessageType), slog.String("error", err.Error()))
+					}
+					continue
+				}
 				if err != nil {
 					counters.IncreaseCounter(false)
 					slog.Error("Failed to send email", slog.String("instanceID", instanceID), slog.String("messageType", email.MessageType), slog.String("error", err.Error()))
 
-					err = messagingDBService.ResetLastSendAttemptForOutgoing(instanceID, email.ID.Hex())
+					if emailsending.RegisterFailedAttempt(&email, err, time.Now()) {
+						slog.Warn("Outgoing email moved to failed emails", slog.String("instanceID", instanceID), slog.String("messageType", email.MessageType), slog.Int("attempts", email.SendAttempts))
+						err = messagingDBService.MoveOutgoingToFailedEmails(instanceID, email)
+						if err != nil {
+							slog.Error("Failed to move outgoing email to failed emails", slog.String("messageType", email.MessageType), slog.String("error", err.Error()))
+						}
+						continue
+					}
+
+					err = messagingDBService.UpdateRetryStateForOutgoing(instanceID, email)
 					if err != nil {
-						slog.Error("Failed to reset last send attempt for outgoing email", slog.String("messageType", email.MessageType), slog.String("error", err.Error()))
+						slog.Error("Failed to update retry state for outgoing email", slog.String("messageType", email.MessageType), slog.String("error", err.Error()))
 					}
 					continue
 				}
@@ -103,6 +122,7 @@ func handleOutgoingMessages(wg *sync.WaitGroup) {
 		}
 
 		counters.Stop()
+		counters.Report(instanceID, "outgoingEmails")
 		slog.Info("Finished handling outgoing messages for instance", slog.String("instanceID", instanceID), slog.Int64("duration", counters.Duration), slog.Int("success", counters.Success), slog.Int("failed", counters.Failed))
 	}
 }
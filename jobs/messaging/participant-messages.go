@@ -64,6 +64,11 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 						return nil
 					}
 
+					if user.IsAnonymized() {
+						slog.Debug("user is anonymized, skipping participant messages", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("participantID", p.ParticipantID))
+						return nil
+					}
+
 					currentProfile := user.Profiles[0]
 					for _, profile := range user.Profiles {
 						if profile.ID.Hex() == profileID {
@@ -74,6 +79,12 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 
 					sentMessages := []string{}
 					for _, message := range messages {
+						if isDigestUser(user, message.Type, study.Key) {
+							// the pending surveys are listed in the next digest instead
+							sentMessages = append(sentMessages, message.ID.Hex())
+							continue
+						}
+
 						// Retrieve the study email template
 						templateName := message.Type + study.Key
 						template, ok := messageTemplateCache[templateName]
@@ -107,7 +118,7 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 							payload["flags."+k] = v
 						}
 
-						subject, content, err := emailsending.GenerateEmailContent(template, user.Account.PreferredLanguage, payload)
+						subject, content, err := emailsending.GenerateEmailContent(instanceID, template, user.Account.PreferredLanguage, payload)
 						if err != nil {
 							counters.IncreaseCounter(false)
 							slog.Error("Error generating email content", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type), slog.String("error", err.Error()))
@@ -124,6 +135,7 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 							To:              to,
 							Subject:         subject,
 							Content:         content,
+							Campaign:        study.Key + "/" + message.Type,
 						}
 
 						_, err = messagingDBService.AddToOutgoingEmails(instanceID, outgoingEmail)
@@ -155,6 +167,7 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 		}
 
 		counters.Stop()
+		counters.Report(instanceID, "participantMessages")
 		slog.Info("Finished handling participant messages for instance", slog.String("instanceID", instanceID), slog.Int("failed", counters.Failed), slog.Int("success", counters.Success))
 	}
 
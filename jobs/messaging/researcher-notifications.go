@@ -54,7 +54,7 @@ func handleResearcherNotifications(wg *sync.WaitGroup) {
 					payload[k] = v
 				}
 
-				subject, content, err := emailsending.GenerateEmailContent(template, "", payload)
+				subject, content, err := emailsending.GenerateEmailContent(instanceID, template, "", payload)
 				if err != nil {
 					counters.IncreaseCounter(false)
 					slog.Error("Error generating email content", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", notification.Message.Type), slog.String("error", err.Error()))
@@ -87,6 +87,7 @@ func handleResearcherNotifications(wg *sync.WaitGroup) {
 		}
 
 		counters.Stop()
+		counters.Report(instanceID, "researcherNotifications")
 		slog.Info("Finished handling researcher notifications", slog.String("instanceID", instanceID), slog.Int("success", counters.Success), slog.Int("failed", counters.Failed))
 	}
 
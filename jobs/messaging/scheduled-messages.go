@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/messaging/campaigns"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	studyservice "github.com/case-framework/case-backend/pkg/study"
@@ -101,7 +102,11 @@ func generateScheduledEmailsForAllUsers(instanceID string, message messagingType
 		nil,
 		false,
 		func(user umTypes.User, args ...interface{}) error {
-			if !isSubscribed(&user, message.Template.MessageType) {
+			if user.IsAnonymized() {
+				return nil
+			}
+
+			if !campaigns.IsSubscribed(&user, message.Template.MessageType) {
 				return nil
 			}
 
@@ -154,7 +159,11 @@ func generateScheduledEmailsForStudyParticipants(instanceID string, message mess
 		nil,
 		false,
 		func(user umTypes.User, args ...interface{}) error {
-			if !isSubscribed(&user, message.Template.MessageType) {
+			if user.IsAnonymized() {
+				return nil
+			}
+
+			if !campaigns.IsSubscribed(&user, message.Template.MessageType) {
 				return nil
 			}
 
@@ -201,16 +210,6 @@ func generateScheduledEmailsForStudyParticipants(instanceID string, message mess
 	slog.Info("Generated messages for scheduled email", slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.Int("generatedMessages", counters.Success), slog.Int("failedMessages", counters.Failed))
 }
 
-func isSubscribed(user *umTypes.User, messageType string) bool {
-	switch messageType {
-	case messagingTypes.EMAIL_TYPE_WEEKLY:
-		return user.ContactPreferences.SubscribedToWeekly
-	case messagingTypes.EMAIL_TYPE_NEWSLETTER:
-		return user.ContactPreferences.SubscribedToNewsletter
-	}
-	return true
-}
-
 func hasAccountType(user *umTypes.User, accountType string) bool {
 	return user.Account.Type == accountType
 }
@@ -223,6 +222,8 @@ func prepOutgoingFromScheduledEmail(
 	outgoingEmail := messagingTypes.OutgoingEmail{
 		MessageType:     message.Template.MessageType,
 		HeaderOverrides: message.Template.HeaderOverrides,
+		Campaign:        message.ID.Hex(),
+		Attachments:     message.Attachments,
 	}
 
 	if user.Account.Type == "email" {
@@ -230,7 +231,7 @@ func prepOutgoingFromScheduledEmail(
 	}
 
 	payload := map[string]string{}
-	for k, v := range emailsending.GlobalTemplateInfos {
+	for k, v := range emailsending.GlobalTemplateInfos() {
 		payload[k] = v
 	}
 	payload["language"] = user.Account.PreferredLanguage
@@ -257,7 +258,7 @@ func prepOutgoingFromScheduledEmail(
 
 	payload["studyKey"] = message.StudyKey
 
-	subject, content, err := emailsending.GenerateEmailContent(message.Template, user.Account.PreferredLanguage, payload)
+	subject, content, err := emailsending.GenerateEmailContent(instanceID, message.Template, user.Account.PreferredLanguage, payload)
 	if err != nil {
 		return nil, err
 	}
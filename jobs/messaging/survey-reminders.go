@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyutils "github.com/case-framework/case-backend/pkg/study/utils"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func handleSurveyReminders(wg *sync.WaitGroup) {
+	defer wg.Done()
+	slog.Info("Start handling survey reminders")
+
+	for _, instanceID := range conf.InstanceIDs {
+		counters := InitMessageCounter()
+
+		studies, err := studyDBService.GetStudies(instanceID, studyTypes.STUDY_STATUS_ACTIVE, false)
+		if err != nil {
+			slog.Error("Error getting studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+
+		for _, study := range studies {
+			config := study.Configs.SurveyReminders
+			if config == nil || len(config.Rules) == 0 {
+				continue
+			}
+			now := time.Now()
+			if studyutils.IsInQuietHours(config, now) {
+				slog.Debug("Quiet hours of study, skipping survey reminders", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key))
+				continue
+			}
+
+			sender := &surveyReminderSender{
+				instanceID: instanceID,
+				study:      study,
+				now:        now,
+				counters:   &counters,
+				templates:  map[string]messagingTypes.EmailTemplate{},
+			}
+			filter := bson.M{
+				"studyStatus":       studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
+				"assignedSurveys.0": bson.M{"$exists": true},
+			}
+			err := studyDBService.FindAndExecuteOnParticipantsStates(
+				context.Background(),
+				instanceID,
+				study.Key,
+				filter,
+				nil,
+				false,
+				func(dbService *studyDB.StudyDBService, p studyTypes.Participant, instanceID, studyKey string, args ...interface{}) error {
+					sender.remindParticipant(p)
+					return nil
+				},
+			)
+			if err != nil {
+				slog.Error("Error getting participant states", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				continue
+			}
+		}
+
+		counters.Stop()
+		counters.Report(instanceID, "surveyReminders")
+		slog.Info("Finished handling survey reminders for instance", slog.String("instanceID", instanceID), slog.Int("failed", counters.Failed), slog.Int("success", counters.Success))
+	}
+
+	slog.Info("Finished handling survey reminders")
+}
+
+type surveyReminderSender struct {
+	instanceID string
+	study      studyTypes.Study
+	now        time.Time
+	counters   *MessageCounter
+	// study email templates by message type
+	templates map[string]messagingTypes.EmailTemplate
+}
+
+// remindParticipant sends the due reminders of the participant, as far as the throttles of the study allow
+func (s *surveyReminderSender) remindParticipant(p studyTypes.Participant) {
+	config := s.study.Configs.SurveyReminders
+	logAttrs := []any{slog.String("instanceID", s.instanceID), slog.String("studyKey", s.study.Key), slog.String("participantID", p.ParticipantID)}
+
+	sent, err := studyDBService.GetSentSurveyReminders(s.instanceID, s.study.Key, p.ParticipantID)
+	if err != nil {
+		slog.Error("Error getting sent survey reminders", append(logAttrs, slog.String("error", err.Error()))...)
+		return
+	}
+	due := studyutils.DueSurveyReminders(config, p.AssignedSurveys, sent, s.now)
+	if len(due) == 0 {
+		return
+	}
+	allowed := studyutils.SurveyRemindersAllowed(config, sent, s.now)
+	if allowed == 0 {
+		return
+	}
+
+	profileID, err := getProfileID(s.instanceID, s.study, p)
+	if err != nil {
+		slog.Error("Error getting profileID", append(logAttrs, slog.String("error", err.Error()))...)
+		return
+	}
+	user, err := participantUserDBService.GetUserByProfileID(s.instanceID, profileID)
+	if err != nil {
+		slog.Error("Error getting user", append(logAttrs, slog.String("error", err.Error()))...)
+		return
+	}
+	if user.IsAnonymized() || studyutils.IsSurveyReminderOptOut(s.study.Key, user.ContactPreferences.SurveyReminderOptOutStudies) {
+		return
+	}
+
+	profile := user.Profiles[0]
+	for _, pr := range user.Profiles {
+		if pr.ID.Hex() == profileID {
+			profile = pr
+			break
+		}
+	}
+
+	count := 0
+	for _, reminder := range due {
+		if allowed > 0 && count >= allowed {
+			break
+		}
+		channel := reminder.Rule.Channel
+		if channel == "" {
+			channel = studyTypes.SURVEY_REMINDER_CHANNEL_EMAIL
+		}
+		if channel == studyTypes.SURVEY_REMINDER_CHANNEL_EMAIL && isDigestUser(user, reminder.Rule.MessageType, s.study.Key) {
+			// the open survey is listed in the next digest instead
+			continue
+		}
+		if channel == studyTypes.SURVEY_REMINDER_CHANNEL_SMS {
+			if phone, err := user.GetPhoneNumber(); err != nil || phone.ConfirmedAt <= 0 {
+				continue
+			}
+		}
+
+		// recorded first, so that a concurrent run does not send the reminder again
+		id, ok, err := studyDBService.AddSentSurveyReminder(s.instanceID, studyTypes.SentSurveyReminder{
+			StudyKey:        s.study.Key,
+			ParticipantID:   p.ParticipantID,
+			RuleKey:         reminder.Rule.Key,
+			SurveyKey:       reminder.Survey.SurveyKey,
+			SurveyValidFrom: reminder.Survey.ValidFrom,
+			Channel:         channel,
+			SentAt:          s.now,
+		})
+		if err != nil {
+			slog.Error("Error recording survey reminder", append(logAttrs, slog.String("error", err.Error()))...)
+			s.counters.IncreaseCounter(false)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		payload := s.reminderPayload(p, user, profile, reminder)
+		if channel == studyTypes.SURVEY_REMINDER_CHANNEL_SMS {
+			phone, _ := user.GetPhoneNumber()
+			err = sms.SendSMS(s.instanceID, phone.Phone, user.ID.Hex(), reminder.Rule.MessageType, user.Account.PreferredLanguage, payload)
+		} else {
+			err = s.queueReminderEmail(user, reminder.Rule.MessageType, payload)
+		}
+		if err != nil {
+			slog.Error("Error sending survey reminder", append(logAttrs, slog.String("messageType", reminder.Rule.MessageType), slog.String("channel", channel), slog.String("error", err.Error()))...)
+			s.counters.IncreaseCounter(false)
+			// retried in the next run
+			if err := studyDBService.RemoveSentSurveyReminder(s.instanceID, id); err != nil {
+				slog.Error("Error removing survey reminder record", append(logAttrs, slog.String("error", err.Error()))...)
+			}
+			continue
+		}
+		s.counters.IncreaseCounter(true)
+		count++
+	}
+}
+
+func (s *surveyReminderSender) reminderPayload(p studyTypes.Participant, user umTypes.User, profile umTypes.Profile, reminder studyutils.DueSurveyReminder) map[string]string {
+	payload := map[string]string{
+		"studyKey":       s.study.Key,
+		"surveyKey":      reminder.Survey.SurveyKey,
+		"surveyCategory": reminder.Survey.Category,
+		"reminderRule":   reminder.Rule.Key,
+		"profileAlias":   profile.Alias,
+		"profileId":      profile.ID.Hex(),
+		"language":       user.Account.PreferredLanguage,
+	}
+	if reminder.Survey.ValidUntil > 0 {
+		payload["validUntil"] = strconv.FormatInt(reminder.Survey.ValidUntil, 10)
+	}
+
+	loginToken, err := getTemploginToken(s.instanceID, user, s.study.Key)
+	if err != nil {
+		slog.Error("Error getting login token", slog.String("instanceID", s.instanceID), slog.String("studyKey", s.study.Key), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+	} else {
+		payload["loginToken"] = loginToken
+	}
+
+	// include participant flags into payload:
+	for k, v := range p.Flags {
+		payload["flags."+k] = v
+	}
+	return payload
+}
+
+func (s *surveyReminderSender) queueReminderEmail(user umTypes.User, messageType string, payload map[string]string) error {
+	template, ok := s.templates[messageType]
+	if !ok {
+		t, err := messagingDBService.GetStudyEmailTemplateByMessageType(s.instanceID, s.study.Key, messageType)
+		if err != nil {
+			return err
+		}
+		s.templates[messageType] = *t
+		template = *t
+	}
+
+	subject, content, err := emailsending.GenerateEmailContent(s.instanceID, template, user.Account.PreferredLanguage, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = messagingDBService.AddToOutgoingEmails(s.instanceID, messagingTypes.OutgoingEmail{
+		MessageType:     messageType,
+		HeaderOverrides: template.HeaderOverrides,
+		To:              []string{user.Account.AccountID},
+		Subject:         subject,
+		Content:         content,
+		Campaign:        s.study.Key + "/" + messageType,
+	})
+	return err
+}
@@ -24,6 +24,12 @@ func (mc *MessageCounter) Stop() {
 	mc.Duration = time.Now().Unix() - mc.StartTime
 }
 
+// Report adds the counts to the job report, e.g. as outgoingEmailsSent and outgoingEmailsFailed
+func (mc *MessageCounter) Report(instanceID string, task string) {
+	report.Add(instanceID, task+"Sent", int64(mc.Success))
+	report.Add(instanceID, task+"Failed", int64(mc.Failed))
+}
+
 func InitMessageCounter() MessageCounter {
 	return MessageCounter{
 		Total:     0,
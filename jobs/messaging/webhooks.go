@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+)
+
+func handleWebhookDeliveries(wg *sync.WaitGroup) {
+	defer wg.Done()
+	slog.Info("Start retrying webhook deliveries")
+
+	for _, instanceID := range conf.InstanceIDs {
+		count, err := webhooks.ProcessDueDeliveries(instanceID)
+		if err != nil {
+			slog.Error("Failed to process webhook deliveries", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+		slog.Info("Webhook deliveries processed", slog.String("instanceID", instanceID), slog.Int("attempts", count))
+	}
+}
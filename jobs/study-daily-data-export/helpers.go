@@ -27,6 +27,12 @@ func responseFileName(date time.Time, surveyKey string, format string) string {
 		suffix = "long.csv"
 	case "json":
 		suffix = "json.json"
+	case "ndjson":
+		suffix = "ndjson.ndjson"
+	case "parquet":
+		suffix = "wide.parquet"
+	case "xlsx":
+		suffix = "wide.xlsx"
 	}
 	return fmt.Sprintf("%s##responses##%s##%s", dateStr, surveyKey, suffix)
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
 
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
 	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/secrets"
 	"github.com/case-framework/case-backend/pkg/utils"
 	"gopkg.in/yaml.v2"
 
@@ -25,6 +28,10 @@ type config struct {
 	// Logging configs
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
 
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
 	// DB configs
 	DBConfigs struct {
 		StudyDB db.DBConfigYaml `json:"study_db" yaml:"study_db"`
@@ -37,7 +44,26 @@ type config struct {
 		ExportFormat  string `json:"export_format" yaml:"export_format"`
 		Separator     string `json:"separator" yaml:"separator"`
 		ShortKeys     bool   `json:"short_keys" yaml:"short_keys"`
-		Sources       []struct {
+		// option labels instead of or alongside option keys: "labels" or "both", in the language label_lang
+		ValueLabels string `json:"value_labels" yaml:"value_labels"`
+		LabelLang   string `json:"label_lang" yaml:"label_lang"`
+		// for exports shared with external analysts
+		Pseudonymization struct {
+			ParticipantIDs     string   `json:"participant_ids" yaml:"participant_ids"`
+			Salt               string   `json:"salt" yaml:"salt"`
+			RedactColumns      []string `json:"redact_columns" yaml:"redact_columns"`
+			TimestampPrecision string   `json:"timestamp_precision" yaml:"timestamp_precision"`
+		} `json:"pseudonymization" yaml:"pseudonymization"`
+		// participant state joined onto each response: fields "enteredAt", "studyStatus" and flag keys
+		ParticipantInfo struct {
+			Fields []string `json:"fields" yaml:"fields"`
+			Flags  []string `json:"flags" yaml:"flags"`
+		} `json:"participant_info" yaml:"participant_info"`
+		// adds a column with the validation issues found at submission
+		ValidationIssues bool `json:"validation_issues" yaml:"validation_issues"`
+		// responses quarantined as suspected spam or bots are only exported if set
+		IncludeQuarantined bool `json:"include_quarantined" yaml:"include_quarantined"`
+		Sources            []struct {
 			InstanceID   string   `json:"instance_id" yaml:"instance_id"`
 			StudyKey     string   `json:"study_key" yaml:"study_key"`
 			SurveyKeys   []string `json:"survey_keys" yaml:"survey_keys"`
@@ -80,20 +106,14 @@ func init() {
 	// Override secrets from environment variables
 	secretsOverride()
 
-	// init db
-	initDBs()
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
 
-	if conf.ResponseExports.RetentionDays < 1 {
-		err := fmt.Errorf("retention days must be greater than 0")
-		slog.Error("Error reading config", slog.String("error", err.Error()))
-		panic(err)
-	}
+	// Report all config problems before connecting to the DBs
+	validateConfig()
 
-	if conf.ResponseExports.ExportPath == "" {
-		err := fmt.Errorf("export path must be set to define where to store the export files")
-		slog.Error("Error reading config", slog.String("error", err.Error()))
-		panic(err)
-	}
+	// init db
+	initDBs()
 
 	if _, err := os.Stat(conf.ResponseExports.ExportPath); os.IsNotExist(err) {
 		// create folder
@@ -106,6 +126,36 @@ func init() {
 	}
 }
 
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	if conf.ResponseExports.RetentionDays < 1 {
+		problems.Add("response_exports.retention_days", "must be greater than 0")
+	}
+	// the export path is created if missing
+	problems.Required("response_exports.export_path", conf.ResponseExports.ExportPath)
+	for i, source := range conf.ResponseExports.Sources {
+		problems.Required(fmt.Sprintf("response_exports.sources[%d].instance_id", i), source.InstanceID)
+		problems.Required(fmt.Sprintf("response_exports.sources[%d].study_key", i), source.StudyKey)
+	}
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
 func secretsOverride() {
 	// Override secrets from environment variables
 
@@ -10,9 +10,10 @@ import (
 	"time"
 
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	"github.com/case-framework/case-backend/pkg/study/exporter/manifest"
 	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
 	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
-	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -28,6 +29,7 @@ func main() {
 		slog.Error("Error closing DB connection", slog.String("error", err.Error()))
 	}
 	slog.Info("Study daily data export job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("study-daily-data-export", start)
 }
 
 func runResponseExportsForSource(instanceID string, studyKey string, surveyKeys []string) {
@@ -83,7 +85,7 @@ func initResponseParser(instanceID string, studyKey string, surveyKey string) (p
 		studyKey,
 		surveyKey,
 		&surveydefinition.ExtractOptions{
-			UseLabelLang: "",
+			UseLabelLang: conf.ResponseExports.LabelLang,
 			IncludeItems: nil,
 			ExcludeItems: nil,
 		},
@@ -116,12 +118,15 @@ func generateExportForSurveyForTargetDate(instanceID string, studyKey string, su
 		slog.Debug("File already exists, overriding", slog.String("path", responseFilePath))
 	}
 
-	filter := bson.M{
+	filter := studyDB.ExcludeSynthetic(bson.M{
 		"key": surveyKey,
 		"$and": bson.A{
 			bson.M{"arrivedAt": bson.M{"$lte": endOfDay(targetDate).Unix()}},
 			bson.M{"arrivedAt": bson.M{"$gte": startOfDay(targetDate).Unix()}},
 		},
+	})
+	if !conf.ResponseExports.IncludeQuarantined {
+		filter = studyDB.ExcludeQuarantined(filter)
 	}
 	// count responses for target date and survey key --> if 0, skip
 	count, err := studyDBService.GetResponsesCount(instanceID, studyKey, filter)
@@ -142,31 +147,40 @@ func generateExportForSurveyForTargetDate(instanceID string, studyKey string, su
 
 	defer file.Close()
 
-	exporter, err := surveyresponses.NewResponseExporter(
+	exporter, err := surveyresponses.NewResponseExporterWithOptions(
 		parser,
 		file,
-		conf.ResponseExports.ExportFormat,
+		surveyresponses.ExportOptions{
+			Format:      conf.ResponseExports.ExportFormat,
+			ValueLabels: conf.ResponseExports.ValueLabels,
+			Pseudonymization: surveyresponses.PseudonymizationOptions{
+				ParticipantIDs:     conf.ResponseExports.Pseudonymization.ParticipantIDs,
+				Salt:               conf.ResponseExports.Pseudonymization.Salt,
+				RedactColumns:      conf.ResponseExports.Pseudonymization.RedactColumns,
+				TimestampPrecision: conf.ResponseExports.Pseudonymization.TimestampPrecision,
+			},
+			ParticipantInfo: surveyresponses.ParticipantInfoOptions{
+				Fields: conf.ResponseExports.ParticipantInfo.Fields,
+				Flags:  conf.ResponseExports.ParticipantInfo.Flags,
+				Lookup: surveyresponses.ParticipantsFromDB(studyDBService, instanceID, studyKey),
+			},
+			ValidationIssues: conf.ResponseExports.ValidationIssues,
+		},
 	)
 	if err != nil {
 		slog.Error("failed to create response exporter", slog.String("error", err.Error()))
 		return
 	}
 
-	err = studyDBService.FindAndExecuteOnResponses(
+	exportManifest := manifest.NewExportManifest(instanceID, studyKey, surveyKey, "responses", conf.ResponseExports.ExportFormat, filter)
+	exportManifest.Columns = exporter.Schema()
+
+	_, err = exporter.Stream(
 		context.Background(),
-		instanceID,
-		studyKey,
-		filter,
-		bson.M{"arrivedAt": 1},
-		false,
-		func(dbService *studyDB.StudyDBService, r studyTypes.SurveyResponse, instanceID, studyKey string, args ...interface{}) error {
-			err := exporter.WriteResponse(&r)
-			if err != nil {
-				return err
-			}
-			return nil
+		surveyresponses.ResponsesFromDB(studyDBService, instanceID, studyKey, filter, bson.M{"arrivedAt": 1}),
+		surveyresponses.StreamOptions{
+			OnResponseWritten: exportManifest.ObserveResponse,
 		},
-		nil,
 	)
 	if err != nil {
 		slog.Error("Error generating response export", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("error", err.Error()))
@@ -179,6 +193,14 @@ func generateExportForSurveyForTargetDate(instanceID string, studyKey string, su
 		return
 	}
 	slog.Info("Generated response export", slog.String("path", responseFilePath))
+
+	if err := exportManifest.AddFile(responseFilePath); err != nil {
+		slog.Error("failed to compute export file checksum", slog.String("path", responseFilePath), slog.String("error", err.Error()))
+		return
+	}
+	if err := exportManifest.WriteToFile(manifest.ManifestPathForFile(responseFilePath)); err != nil {
+		slog.Error("failed to write export manifest", slog.String("path", responseFilePath), slog.String("error", err.Error()))
+	}
 }
 
 func cleanUpForSource(sourceDir string) error {
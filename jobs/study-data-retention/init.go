@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	"github.com/case-framework/case-backend/pkg/study"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD = "STUDY_DB_PASSWORD"
+)
+
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
+	// DB configs
+	DBConfigs struct {
+		StudyDB db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
+
+	// Path of the participant file storage, needed to delete files
+	FilestorePath string `json:"filestore_path" yaml:"filestore_path"`
+
+	// If true, only reports what would be deleted
+	DryRun bool `json:"dry_run" yaml:"dry_run"`
+}
+
+var conf config
+
+var (
+	studyDBService *studyDB.StudyDBService
+)
+
+func init() {
+	// Read config from file
+	yamlFile, err := os.ReadFile(os.Getenv(ENV_CONFIG_FILE_PATH))
+	if err != nil {
+		panic(err)
+	}
+
+	err = yaml.UnmarshalStrict(yamlFile, &conf)
+	if err != nil {
+		panic(err)
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
+	// init db
+	initDBs()
+
+	// init study service
+	study.Init(studyDBService, "", nil)
+}
+
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.RequiredList("instance_ids", conf.InstanceIDs)
+	problems.Dir("filestore_path", conf.FilestorePath)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
+func secretsOverride() {
+	// Override secrets from environment variables
+
+	if dbUsername := os.Getenv(ENV_STUDY_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.StudyDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_STUDY_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.StudyDB.Password = dbPassword
+	}
+
+}
+
+func initDBs() {
+	var err error
+	studyDBService, err = studyDB.NewStudyDBService(db.DBConfigFromYamlObj(conf.DBConfigs.StudyDB, conf.InstanceIDs))
+	if err != nil {
+		slog.Error("Error connecting to Study DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
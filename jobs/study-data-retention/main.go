@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	studyservice "github.com/case-framework/case-backend/pkg/study"
+)
+
+// counts and errors by instance, kept in the run history when started by the job scheduler
+var jobReport = jobscheduler.NewReport()
+
+func main() {
+	slog.Info("Starting study data retention job", slog.Bool("dryRun", conf.DryRun))
+	start := time.Now()
+
+	for _, instanceID := range conf.InstanceIDs {
+		studies, err := studyDBService.GetStudies(instanceID, "", false)
+		if err != nil {
+			slog.Error("Failed to get studies", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+			jobReport.Error(instanceID, err)
+			continue
+		}
+
+		for _, study := range studies {
+			if study.Configs.DataRetention == nil {
+				continue
+			}
+
+			report, err := studyservice.ApplyDataRetention(instanceID, study, conf.FilestorePath, conf.DryRun)
+			if err != nil {
+				slog.Error("Failed to apply data retention", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				jobReport.Error(instanceID, err)
+				continue
+			}
+			jobReport.Add(instanceID, "studies", 1)
+			jobReport.Add(instanceID, "responses", report.Responses)
+			jobReport.Add(instanceID, "confidentialResponses", report.ConfidentialResponses)
+			jobReport.Add(instanceID, "reports", report.Reports)
+			jobReport.Add(instanceID, "files", report.Files)
+
+			slog.Info("Data retention applied",
+				slog.String("instanceID", instanceID),
+				slog.String("studyKey", study.Key),
+				slog.Bool("dryRun", report.DryRun),
+				slog.Int64("responses", report.Responses),
+				slog.Int64("confidentialResponses", report.ConfidentialResponses),
+				slog.Int64("reports", report.Reports),
+				slog.Int64("files", report.Files),
+			)
+		}
+	}
+
+	slog.Info("Study data retention job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("study-data-retention", start)
+	jobReport.Write()
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
 	"github.com/case-framework/case-backend/pkg/db"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	"github.com/case-framework/case-backend/pkg/secrets"
 	"github.com/case-framework/case-backend/pkg/study"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
 	"github.com/case-framework/case-backend/pkg/utils"
@@ -26,6 +30,10 @@ type config struct {
 	// Logging configs
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
 
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
 	// DB configs
 	DBConfigs struct {
 		StudyDB db.DBConfigYaml `json:"study_db" yaml:"study_db"`
@@ -38,7 +46,13 @@ type config struct {
 		GlobalSecret string `json:"global_secret" yaml:"global_secret"`
 
 		ExternalServices []studyengine.ExternalService `json:"external_services" yaml:"external_services"`
+
+		// number of scheduled events claimed at once, defaults to 100
+		ScheduledEventsBatchSize int64 `json:"scheduled_events_batch_size" yaml:"scheduled_events_batch_size"`
 	} `json:"study_configs" yaml:"study_configs"`
+
+	// Domain events published to message brokers for external data pipelines, disabled if no brokers are set
+	DomainEvents domainevents.Config `json:"domain_events" yaml:"domain_events"`
 }
 
 var conf config
@@ -75,6 +89,12 @@ func init() {
 	// Override secrets from environment variables
 	secretsOverride()
 
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
 	// init db
 	initDBs()
 
@@ -82,6 +102,32 @@ func init() {
 	initStudyService()
 }
 
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.RequiredList("instance_ids", conf.InstanceIDs)
+	problems.Required("study_configs.global_secret", conf.StudyConfigs.GlobalSecret)
+	problems.DomainEvents("domain_events", conf.DomainEvents)
+	problems.ExternalServices("study_configs.external_services", conf.StudyConfigs.ExternalServices)
+	problems.NonNegative("study_configs.scheduled_events_batch_size", int(conf.StudyConfigs.ScheduledEventsBatchSize))
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
 func secretsOverride() {
 	// Override secrets from environment variables
 
@@ -1,54 +1,108 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"time"
 
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
 	studyservice "github.com/case-framework/case-backend/pkg/study"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// counts and errors by instance, kept in the run history when started by the job scheduler
+var report = jobscheduler.NewReport()
+
 func main() {
 	slog.Info("Starting study timer job")
 	start := time.Now()
 
+	domainEvents, err := domainevents.Init(conf.DomainEvents, "study-timer-job")
+	if err != nil {
+		slog.Error("failed to init domain event publisher", slog.String("error", err.Error()))
+	} else if domainEvents != nil {
+		defer domainEvents.Close()
+	}
+
 	for _, instanceID := range conf.InstanceIDs {
 		slog.Debug("Start handling study timer for instance", slog.String("instanceID", instanceID))
 		studies, err := studyDBService.GetStudies(instanceID, studyTypes.STUDY_STATUS_ACTIVE, false)
 		if err != nil {
 			slog.Error("Failed to get studies", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+			report.Error(instanceID, err)
 			continue
 		}
+		report.Add(instanceID, "studies", int64(len(studies)))
 
 		for _, study := range studies {
 			updateStudyStats(instanceID, study)
+			publishScheduledDrafts(instanceID, study)
+			promoteFromWaitingList(instanceID, study)
 			studyservice.OnStudyTimer(instanceID, &study)
 		}
+
+		studyservice.ProcessScheduledEvents(instanceID, conf.StudyConfigs.ScheduledEventsBatchSize)
+		studyservice.ProcessExternalServiceTasks(instanceID)
 	}
 
 	slog.Info("Study timer job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("study-timer", start)
+	report.Write()
+}
+
+func publishScheduledDrafts(instanceID string, study studyTypes.Study) {
+	count, err := studyservice.PublishDueDrafts(instanceID, study.Key)
+	if err != nil {
+		slog.Error("Failed to publish scheduled drafts", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", study.Key))
+		report.Error(instanceID, err)
+		return
+	}
+	report.Add(instanceID, "publishedDrafts", int64(count))
+	if count > 0 {
+		slog.Info("Published scheduled drafts", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.Int("count", count))
+	}
+}
+
+func promoteFromWaitingList(instanceID string, study studyTypes.Study) {
+	count, err := studyservice.PromoteFromWaitingList(context.Background(), instanceID, study)
+	if err != nil {
+		slog.Error("Failed to promote participants from the waiting list", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", study.Key))
+		report.Error(instanceID, err)
+		return
+	}
+	report.Add(instanceID, "promotedFromWaitingList", int64(count))
+	if count > 0 {
+		slog.Info("Promoted participants from the waiting list", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.Int("count", count))
+	}
 }
 
 func updateStudyStats(instanceID string, study studyTypes.Study) {
-	activeCount, err := studyDBService.GetParticipantCount(instanceID, study.Key, bson.M{
+	activeCount, err := studyDBService.GetParticipantCount(instanceID, study.Key, studyDB.ExcludeSynthetic(bson.M{
 		"studyStatus": studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
-	})
+	}))
 	if err != nil {
 		slog.Error("Failed to get active participant count", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
 	}
 
-	temporaryCount, err := studyDBService.GetParticipantCount(instanceID, study.Key, bson.M{
+	temporaryCount, err := studyDBService.GetParticipantCount(instanceID, study.Key, studyDB.ExcludeSynthetic(bson.M{
 		"studyStatus": studyTypes.PARTICIPANT_STUDY_STATUS_TEMPORARY,
-	})
+	}))
 	if err != nil {
 		slog.Error("Failed to get temporary participant count", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
 	}
 
-	responseCount, err := studyDBService.GetResponsesCount(instanceID, study.Key, bson.M{})
+	responseCount, err := studyDBService.GetResponsesCount(instanceID, study.Key, studyDB.ExcludeSynthetic(bson.M{}))
 	if err != nil {
 		slog.Error("Failed to get response count", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
 	}
+	if study.DataRetentionStats != nil {
+		// responses removed by the retention policy still count towards the study total
+		responseCount += study.DataRetentionStats.PrunedResponses
+	}
 
 	stats := studyTypes.StudyStats{
 		ParticipantCount:     activeCount,
@@ -59,5 +113,6 @@ func updateStudyStats(instanceID string, study studyTypes.Study) {
 	err = studyDBService.UpdateStudyStats(instanceID, study.Key, stats)
 	if err != nil {
 		slog.Error("Failed to update study stats", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		report.Error(instanceID, err)
 	}
 }
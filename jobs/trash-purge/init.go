@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME           = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD           = "STUDY_DB_PASSWORD"
+	ENV_MESSAGING_DB_USERNAME       = "MESSAGING_DB_USERNAME"
+	ENV_MESSAGING_DB_PASSWORD       = "MESSAGING_DB_PASSWORD"
+	ENV_MANAGEMENT_USER_DB_USERNAME = "MANAGEMENT_USER_DB_USERNAME"
+	ENV_MANAGEMENT_USER_DB_PASSWORD = "MANAGEMENT_USER_DB_PASSWORD"
+)
+
+const defaultRetention = 30 * 24 * time.Hour
+
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
+	// DB configs
+	DBConfigs struct {
+		StudyDB          db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+		MessagingDB      db.DBConfigYaml `json:"messaging_db" yaml:"messaging_db"`
+		ManagementUserDB db.DBConfigYaml `json:"management_user_db" yaml:"management_user_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
+
+	// Items stay in the trash for this duration before they are deleted permanently, defaults to 30 days
+	Retention time.Duration `json:"retention" yaml:"retention"`
+}
+
+var conf config
+
+var (
+	studyDBService     *studyDB.StudyDBService
+	messagingDBService *messagingDB.MessagingDBService
+	muDBService        *muDB.ManagementUserDBService
+)
+
+func init() {
+	// Read config from file
+	yamlFile, err := os.ReadFile(os.Getenv(ENV_CONFIG_FILE_PATH))
+	if err != nil {
+		panic(err)
+	}
+
+	err = yaml.UnmarshalStrict(yamlFile, &conf)
+	if err != nil {
+		panic(err)
+	}
+
+	if conf.Retention <= 0 {
+		conf.Retention = defaultRetention
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
+	// init db
+	initDBs()
+}
+
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.DB("db_configs.messaging_db", conf.DBConfigs.MessagingDB)
+	problems.DB("db_configs.management_user_db", conf.DBConfigs.ManagementUserDB)
+	problems.RequiredList("instance_ids", conf.InstanceIDs)
+	problems.Duration("retention", conf.Retention, time.Hour, 0)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
+func secretsOverride() {
+	// Override secrets from environment variables
+
+	if dbUsername := os.Getenv(ENV_STUDY_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.StudyDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_STUDY_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.StudyDB.Password = dbPassword
+	}
+
+	if dbUsername := os.Getenv(ENV_MESSAGING_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.MessagingDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_MESSAGING_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.MessagingDB.Password = dbPassword
+	}
+
+	if dbUsername := os.Getenv(ENV_MANAGEMENT_USER_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.ManagementUserDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_MANAGEMENT_USER_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.ManagementUserDB.Password = dbPassword
+	}
+}
+
+func initDBs() {
+	var err error
+	studyDBService, err = studyDB.NewStudyDBService(db.DBConfigFromYamlObj(conf.DBConfigs.StudyDB, conf.InstanceIDs))
+	if err != nil {
+		slog.Error("Error connecting to Study DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	messagingDBService, err = messagingDB.NewMessagingDBService(db.DBConfigFromYamlObj(conf.DBConfigs.MessagingDB, conf.InstanceIDs))
+	if err != nil {
+		slog.Error("Error connecting to Messaging DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	muDBService, err = muDB.NewManagementUserDBService(db.DBConfigFromYamlObj(conf.DBConfigs.ManagementUserDB, conf.InstanceIDs))
+	if err != nil {
+		slog.Error("Error connecting to Management User DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
+)
+
+// counts and errors by instance, kept in the run history when started by the job scheduler
+var report = jobscheduler.NewReport()
+
+func main() {
+	slog.Info("Starting trash purge job", slog.String("retention", conf.Retention.String()))
+	start := time.Now()
+	cutoff := start.Add(-conf.Retention)
+
+	for _, instanceID := range conf.InstanceIDs {
+		purgeStudies(instanceID, cutoff)
+		purgeSurveyVersions(instanceID, cutoff)
+
+		count, err := messagingDBService.PurgeEmailTemplatesDeletedBefore(instanceID, cutoff)
+		if err != nil {
+			slog.Error("Failed to purge message templates", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+		} else {
+			slog.Info("Purged message templates", slog.String("instanceID", instanceID), slog.Int64("count", count))
+			report.Add(instanceID, "purgedEmailTemplates", count)
+		}
+
+		count, err = muDBService.PurgeUsersDeletedBefore(instanceID, cutoff)
+		if err != nil {
+			slog.Error("Failed to purge management users", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+		} else {
+			slog.Info("Purged management users", slog.String("instanceID", instanceID), slog.Int64("count", count))
+			report.Add(instanceID, "purgedManagementUsers", count)
+		}
+	}
+
+	slog.Info("Trash purge job completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("trash-purge", start)
+	report.Write()
+}
+
+func purgeStudies(instanceID string, cutoff time.Time) {
+	studyKeys, err := studyDBService.GetStudyKeysDeletedBefore(instanceID, cutoff.Unix())
+	if err != nil {
+		slog.Error("Failed to get deleted studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		report.Error(instanceID, err)
+		return
+	}
+
+	for _, studyKey := range studyKeys {
+		if err := studyDBService.PurgeStudy(instanceID, studyKey); err != nil {
+			slog.Error("Failed to purge study", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+			continue
+		}
+		slog.Info("Purged study", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+		report.Add(instanceID, "purgedStudies", 1)
+	}
+}
+
+func purgeSurveyVersions(instanceID string, cutoff time.Time) {
+	studies, err := studyDBService.GetStudies(instanceID, "", true)
+	if err != nil {
+		slog.Error("Failed to get studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		report.Error(instanceID, err)
+		return
+	}
+
+	for _, study := range studies {
+		count, err := studyDBService.PurgeSurveyVersionsDeletedBefore(instanceID, study.Key, cutoff.Unix())
+		if err != nil {
+			slog.Error("Failed to purge survey versions", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			report.Error(instanceID, err)
+			continue
+		}
+		report.Add(instanceID, "purgedSurveyVersions", count)
+		if count > 0 {
+			slog.Info("Purged survey versions", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.Int64("count", count))
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	jobName = "user-management"
+
+	defaultCheckpointBatchSize = 1000
+	defaultCheckpointMaxAge    = 7 * 24 * time.Hour
+)
+
+// processUsersWithCheckpoint calls processBatch for the users matching the filter in _id order. The last _id of each
+// processed batch is saved as checkpoint of the task, so that a run interrupted halfway continues there instead of
+// starting over. The checkpoint is removed once all users are processed.
+func processUsersWithCheckpoint(instanceID string, task string, filter bson.M, processBatch func(users []umTypes.User)) error {
+	batchSize := conf.UserManagementConfig.CheckpointBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCheckpointBatchSize
+	}
+	maxAge := conf.UserManagementConfig.CheckpointMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCheckpointMaxAge
+	}
+
+	checkpoint, err := globalInfosDBService.GetJobCheckpoint(jobName, task, instanceID)
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil && time.Since(checkpoint.UpdatedAt) > maxAge {
+		slog.Info("Checkpoint too old, starting from the beginning", slog.String("instanceID", instanceID), slog.String("task", task), slog.Time("updatedAt", checkpoint.UpdatedAt))
+		checkpoint = nil
+	}
+	if checkpoint == nil {
+		checkpoint = &globalinfosDB.JobCheckpoint{
+			Job:        jobName,
+			Task:       task,
+			InstanceID: instanceID,
+			StartedAt:  time.Now(),
+		}
+	} else {
+		slog.Info("Resuming from checkpoint", slog.String("instanceID", instanceID), slog.String("task", task), slog.String("lastID", checkpoint.LastID.Hex()), slog.Int64("processed", checkpoint.Processed))
+	}
+
+	for {
+		users, err := participantUserDBService.FindUsersAfterID(instanceID, filter, checkpoint.LastID, int64(batchSize))
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		processBatch(users)
+
+		checkpoint.LastID = users[len(users)-1].ID
+		checkpoint.Processed += int64(len(users))
+		if err := globalInfosDBService.SaveJobCheckpoint(*checkpoint); err != nil {
+			// only matters if the run is interrupted, the batch is repeated then
+			slog.Error("Failed to save checkpoint", slog.String("instanceID", instanceID), slog.String("task", task), slog.String("error", err.Error()))
+		}
+	}
+
+	return globalInfosDBService.DeleteJobCheckpoint(jobName, task, instanceID)
+}
+
+// forEachUser calls fn for the users with up to the configured number of workers. The returned user updates are
+// written before it returns, so that they are stored when the checkpoint moves past the users. Errors of fn are
+// logged, returns the number of users fn succeeded for.
+func forEachUser(instanceID string, users []umTypes.User, fn func(user umTypes.User) (mongo.WriteModel, error)) int64 {
+	var (
+		count   atomic.Int64
+		mu      sync.Mutex
+		updates []mongo.WriteModel
+	)
+	inParallel(len(users), func(i int) {
+		update, err := fn(users[i])
+		if err != nil {
+			slog.Error("Error while executing function on user", slog.String("instanceID", instanceID), slog.String("userID", users[i].ID.Hex()), slog.String("error", err.Error()))
+			return
+		}
+		count.Add(1)
+		if update != nil {
+			mu.Lock()
+			updates = append(updates, update)
+			mu.Unlock()
+		}
+	})
+
+	userWriter := participantUserDBService.NewUserBulkWriter(instanceID, conf.UserManagementConfig.BulkWriteBatchSize)
+	err := userWriter.Add(updates...)
+	if flushErr := userWriter.Flush(); flushErr != nil {
+		err = errors.Join(err, flushErr)
+	}
+	if err != nil {
+		slog.Error("failed to update user records", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+	}
+	return count.Load()
+}
+
+// inParallel calls fn for each index with up to the configured number of concurrent calls
+func inParallel(n int, fn func(i int)) {
+	workers := conf.UserManagementConfig.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+)
+
+func TestInParallel(t *testing.T) {
+	conf.UserManagementConfig.Concurrency = 3
+	t.Cleanup(func() { conf.UserManagementConfig.Concurrency = 0 })
+
+	var (
+		mu      sync.Mutex
+		calls   = map[int]int{}
+		running atomic.Int32
+		maxSeen atomic.Int32
+	)
+	inParallel(20, func(i int) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			seen := maxSeen.Load()
+			if n <= seen || maxSeen.CompareAndSwap(seen, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		calls[i]++
+		mu.Unlock()
+	})
+
+	if len(calls) != 20 {
+		t.Errorf("expected 20 indexes, got %d", len(calls))
+	}
+	for i, n := range calls {
+		if n != 1 {
+			t.Errorf("index %d called %d times", i, n)
+		}
+	}
+	if maxSeen.Load() > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", maxSeen.Load())
+	}
+}
+
+// initTestUserManagementDBs connects the DB services of the job to the MongoDB of TEST_MONGODB_URI with a fresh
+// database prefix and adds the users in _id order, the test is skipped if not set
+func initTestUserManagementDBs(t *testing.T, instanceID string, userCount int) []umTypes.User {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	dbConfig := db.DBConfig{
+		URI:          uri,
+		DBNamePrefix: fmt.Sprintf("user_management_test_%d_", time.Now().UnixNano()),
+		Timeout:      10,
+		InstanceIDs:  []string{instanceID},
+	}
+	var err error
+	participantUserDBService, err = userDB.NewParticipantUserDBService(dbConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalInfosDBService, err = globalinfosDB.NewGlobalInfosDBService(dbConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = participantUserDBService.DBClient.Database(dbConfig.DBNamePrefix + instanceID + "_users").Drop(context.Background())
+		_ = globalInfosDBService.DBClient.Database(dbConfig.DBNamePrefix + "global-infos").Drop(context.Background())
+		_ = participantUserDBService.DBClient.Disconnect(context.Background())
+		_ = globalInfosDBService.DBClient.Disconnect(context.Background())
+		conf.UserManagementConfig = config{}.UserManagementConfig
+	})
+
+	users := make([]umTypes.User, userCount)
+	for i := range users {
+		users[i] = umTypes.User{
+			ID:      primitive.NewObjectID(),
+			Account: umTypes.Account{Type: "email", AccountID: fmt.Sprintf("user%d@example.com", i)},
+		}
+		if _, err := participantUserDBService.AddUser(instanceID, users[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return users
+}
+
+// processUntilInterrupted runs processUsersWithCheckpoint and stops it at the start of the given batch, like a run
+// killed halfway
+func processUntilInterrupted(t *testing.T, instanceID string, task string, interruptAt int, processBatch func(users []umTypes.User)) {
+	t.Helper()
+
+	batch := 0
+	interrupted := func() (r any) {
+		defer func() { r = recover() }()
+		_ = processUsersWithCheckpoint(instanceID, task, bson.M{}, func(users []umTypes.User) {
+			batch++
+			if batch == interruptAt {
+				panic("interrupted")
+			}
+			processBatch(users)
+		})
+		return nil
+	}()
+	if interrupted != "interrupted" {
+		t.Fatalf("expected interruption at batch %d, got %v", interruptAt, interrupted)
+	}
+}
+
+func userIDs(users []umTypes.User) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestFindUsersAfterID(t *testing.T) {
+	instanceID := "test"
+	users := initTestUserManagementDBs(t, instanceID, 5)
+
+	tests := []struct {
+		name    string
+		afterID primitive.ObjectID
+		limit   int64
+		want    []umTypes.User
+	}{
+		{name: "from the first user", limit: 2, want: users[:2]},
+		{name: "after a user", afterID: users[1].ID, limit: 2, want: users[2:4]},
+		{name: "last batch", afterID: users[3].ID, limit: 2, want: users[4:]},
+		{name: "after the last user", afterID: users[4].ID, limit: 2, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := participantUserDBService.FindUsersAfterID(instanceID, bson.M{}, tt.afterID, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(userIDs(found)) != fmt.Sprint(userIDs(tt.want)) {
+				t.Errorf("expected %v, got %v", userIDs(tt.want), userIDs(found))
+			}
+		})
+	}
+
+	filtered, err := participantUserDBService.FindUsersAfterID(instanceID, bson.M{"account.accountID": users[3].Account.AccountID}, users[1].ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != users[3].ID {
+		t.Errorf("filter not applied: %v", userIDs(filtered))
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestProcessUsersWithCheckpointResumes(t *testing.T) {
+	instanceID := "test"
+	task := "resume"
+	users := initTestUserManagementDBs(t, instanceID, 5)
+	conf.UserManagementConfig.CheckpointBatchSize = 2
+
+	processed := []primitive.ObjectID{}
+	processUntilInterrupted(t, instanceID, task, 2, func(batch []umTypes.User) {
+		processed = append(processed, userIDs(batch)...)
+	})
+	if fmt.Sprint(processed) != fmt.Sprint(userIDs(users[:2])) {
+		t.Errorf("unexpected users before the interruption: %v", processed)
+	}
+
+	checkpoint, err := globalInfosDBService.GetJobCheckpoint(jobName, task, instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint == nil || checkpoint.LastID != users[1].ID || checkpoint.Processed != 2 {
+		t.Fatalf("unexpected checkpoint after the interruption: %+v", checkpoint)
+	}
+
+	processed = []primitive.ObjectID{}
+	err = processUsersWithCheckpoint(instanceID, task, bson.M{}, func(batch []umTypes.User) {
+		processed = append(processed, userIDs(batch)...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(processed) != fmt.Sprint(userIDs(users[2:])) {
+		t.Errorf("expected the rerun to continue after the checkpoint, got %v", processed)
+	}
+
+	checkpoint, err = globalInfosDBService.GetJobCheckpoint(jobName, task, instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint != nil {
+		t.Errorf("checkpoint not removed after the run: %+v", checkpoint)
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestProcessUsersWithCheckpointPartlyFailingBatch(t *testing.T) {
+	instanceID := "test"
+	task := "partly-failing"
+	users := initTestUserManagementDBs(t, instanceID, 5)
+	conf.UserManagementConfig.CheckpointBatchSize = 3
+	conf.UserManagementConfig.Concurrency = 2
+
+	failingUser := users[1].ID
+	var count int64
+	processUntilInterrupted(t, instanceID, task, 2, func(batch []umTypes.User) {
+		count += forEachUser(instanceID, batch, func(user umTypes.User) (mongo.WriteModel, error) {
+			if user.ID == failingUser {
+				return nil, errors.New("failed")
+			}
+			return mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": user.ID}).
+				SetUpdate(bson.M{"$set": bson.M{"account.preferredLanguage": "de"}}), nil
+		})
+	})
+	if count != 2 {
+		t.Errorf("expected 2 successful users, got %d", count)
+	}
+
+	for _, user := range users[:3] {
+		stored, err := participantUserDBService.GetUser(instanceID, user.ID.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		updated := stored.Account.PreferredLanguage == "de"
+		if updated == (user.ID == failingUser) {
+			t.Errorf("unexpected update state of user %s: %v", user.ID.Hex(), updated)
+		}
+	}
+
+	checkpoint, err := globalInfosDBService.GetJobCheckpoint(jobName, task, instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint == nil || checkpoint.LastID != users[2].ID || checkpoint.Processed != 3 {
+		t.Errorf("expected checkpoint past the partly failed batch, got %+v", checkpoint)
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"time"
 
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
 	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/secrets"
 	"github.com/case-framework/case-backend/pkg/study"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
 	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
@@ -39,6 +42,10 @@ type config struct {
 	// Logging configs
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
 
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
 	// DB configs
 	DBConfigs struct {
 		ParticipantUserDB db.DBConfigYaml `json:"participant_user_db" yaml:"participant_user_db"`
@@ -49,6 +56,9 @@ type config struct {
 
 	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
 
+	// Path of the participant file storage, needed to remove files of studies erasing participant data on account deletion
+	FilestorePath string `json:"filestore_path" yaml:"filestore_path"`
+
 	// user management configs
 	UserManagementConfig struct {
 		DeleteUnverifiedUsersAfter                 time.Duration `json:"delete_unverified_users_after" yaml:"delete_unverified_users_after"`
@@ -56,6 +66,13 @@ type config struct {
 		EmailContactVerificationTokenTTL           time.Duration `json:"email_contact_verification_token_ttl" yaml:"email_contact_verification_token_ttl"`
 		NotifyAfterInactiveFor                     time.Duration `json:"notify_after_inactive_for" yaml:"notify_after_inactive_for"`
 		MarkForDeletionAfterInactivityNotification time.Duration `json:"mark_for_deletion_after_inactivity_notification" yaml:"mark_for_deletion_after_inactivity_notification"`
+		AnonymizeUsersAfterStudyCompletion         time.Duration `json:"anonymize_users_after_study_completion" yaml:"anonymize_users_after_study_completion"` // 0 means anonymization is disabled
+		AccountSetupTokenTTL                       time.Duration `json:"account_setup_token_ttl" yaml:"account_setup_token_ttl"`                               // defaults to the contact verification token TTL
+		DeletePendingSignupsAfter                  time.Duration `json:"delete_pending_signups_after" yaml:"delete_pending_signups_after"`                     // signups without password, defaults to DeleteUnverifiedUsersAfter
+		BulkWriteBatchSize                         int           `json:"bulk_write_batch_size" yaml:"bulk_write_batch_size"`                                   // operations per DB request, 0 uses the default
+		Concurrency                                int           `json:"concurrency" yaml:"concurrency"`                                                       // users processed in parallel per instance, 1 if not set
+		CheckpointBatchSize                        int           `json:"checkpoint_batch_size" yaml:"checkpoint_batch_size"`                                   // users processed between checkpoints, 1000 if not set
+		CheckpointMaxAge                           time.Duration `json:"checkpoint_max_age" yaml:"checkpoint_max_age"`                                         // older checkpoints are ignored and the task starts over, 7 days if not set
 	} `json:"user_management_config" yaml:"user_management_config"`
 
 	MessagingConfigs messagingTypes.MessagingConfigs `json:"messaging_configs" yaml:"messaging_configs"`
@@ -77,7 +94,9 @@ var (
 	studyDBService           *studyDB.StudyDBService
 )
 
-func init() {
+// initJob reads the config and connects the services. Called from main instead of init, so that the tests of the
+// package run without a config file.
+func initJob() {
 	// Read config from file
 	yamlFile, err := os.ReadFile(os.Getenv(ENV_CONFIG_FILE_PATH))
 	if err != nil {
@@ -105,15 +124,17 @@ func init() {
 	// Override secrets from environment variables
 	secretsOverride()
 
-	// check config values:
-	if conf.UserManagementConfig.DeleteUnverifiedUsersAfter == 0 {
-		slog.Error("DeleteUnverifiedUsersAfter is not set")
-		panic("DeleteUnverifiedUsersAfter is not set")
-	}
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
 
-	if conf.UserManagementConfig.SendReminderToConfirmAccountAfter == 0 {
-		slog.Error("SendReminderToConfirmAccountAfter is not set")
-		panic("SendReminderToConfirmAccountAfter is not set")
+	if conf.UserManagementConfig.AccountSetupTokenTTL == 0 {
+		conf.UserManagementConfig.AccountSetupTokenTTL = conf.UserManagementConfig.EmailContactVerificationTokenTTL
+	}
+	if conf.UserManagementConfig.DeletePendingSignupsAfter == 0 {
+		conf.UserManagementConfig.DeletePendingSignupsAfter = conf.UserManagementConfig.DeleteUnverifiedUsersAfter
 	}
 
 	// init db
@@ -129,6 +150,53 @@ func init() {
 	initStudyService()
 }
 
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.participant_user_db", conf.DBConfigs.ParticipantUserDB)
+	problems.DB("db_configs.global_infos_db", conf.DBConfigs.GlobalInfosDB)
+	problems.DB("db_configs.messaging_db", conf.DBConfigs.MessagingDB)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.RequiredList("instance_ids", conf.InstanceIDs)
+	problems.Dir("filestore_path", conf.FilestorePath)
+
+	umConfig := conf.UserManagementConfig
+	problems.RequiredDuration("user_management_config.delete_unverified_users_after", umConfig.DeleteUnverifiedUsersAfter, time.Hour, 0)
+	problems.RequiredDuration("user_management_config.send_reminder_to_confirm_account_after", umConfig.SendReminderToConfirmAccountAfter, time.Minute, 0)
+	if umConfig.SendReminderToConfirmAccountAfter >= umConfig.DeleteUnverifiedUsersAfter && umConfig.DeleteUnverifiedUsersAfter > 0 {
+		problems.Add("user_management_config.send_reminder_to_confirm_account_after", "must be shorter than delete_unverified_users_after")
+	}
+	problems.Duration("user_management_config.email_contact_verification_token_ttl", umConfig.EmailContactVerificationTokenTTL, time.Minute, 0)
+	problems.Duration("user_management_config.notify_after_inactive_for", umConfig.NotifyAfterInactiveFor, time.Hour, 0)
+	problems.Duration("user_management_config.mark_for_deletion_after_inactivity_notification", umConfig.MarkForDeletionAfterInactivityNotification, time.Hour, 0)
+	problems.Duration("user_management_config.anonymize_users_after_study_completion", umConfig.AnonymizeUsersAfterStudyCompletion, time.Hour, 0)
+	problems.Duration("user_management_config.account_setup_token_ttl", umConfig.AccountSetupTokenTTL, time.Minute, 0)
+	problems.Duration("user_management_config.delete_pending_signups_after", umConfig.DeletePendingSignupsAfter, time.Hour, 0)
+	problems.NonNegative("user_management_config.bulk_write_batch_size", umConfig.BulkWriteBatchSize)
+	problems.NonNegative("user_management_config.concurrency", umConfig.Concurrency)
+	problems.NonNegative("user_management_config.checkpoint_batch_size", umConfig.CheckpointBatchSize)
+	problems.Duration("user_management_config.checkpoint_max_age", umConfig.CheckpointMaxAge, time.Hour, 0)
+
+	problems.Messaging("messaging_configs", conf.MessagingConfigs)
+	problems.Required("study_configs.global_secret", conf.StudyConfigs.GlobalSecret)
+	problems.ExternalServices("study_configs.external_services", conf.StudyConfigs.ExternalServices)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
 func secretsOverride() {
 	// Override secrets from environment variables
 
@@ -198,6 +266,7 @@ func initMessageSendingConfig() {
 		conf.MessagingConfigs.GlobalEmailTemplateConstants,
 		messagingDBService,
 	)
+	emailsending.InitLanguageFallbacks(conf.MessagingConfigs.LanguageFallbacks, conf.MessagingConfigs.InstanceLanguageFallbacks)
 }
 
 func initUserManagement() {
@@ -210,4 +279,5 @@ func initStudyService() {
 		conf.StudyConfigs.GlobalSecret,
 		conf.StudyConfigs.ExternalServices,
 	)
+	study.SetParticipantFilestorePath(conf.FilestorePath)
 }
@@ -3,12 +3,16 @@ package main
 import (
 	"context"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
 	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/metrics"
 	studyService "github.com/case-framework/case-backend/pkg/study"
 	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
 	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
@@ -16,78 +20,61 @@ import (
 )
 
 func main() {
+	initJob()
+
 	slog.Info("Starting user management job")
 	start := time.Now()
 
 	cleanUpUnverifiedUsers()
+	cleanUpPendingSignups()
 	sendReminderToConfirmAccounts()
 	notifyInactiveUsersAndMarkForDeletion()
 	cleanUpUsersMarkedForDeletion()
+	anonymizeUsersAfterStudyCompletion()
 
 	slog.Info("User management jobs completed", slog.String("duration", time.Since(start).String()))
+	metrics.ReportJobRun("user-management", start)
 }
 
 func cleanUpUnverifiedUsers() {
 	for _, instanceID := range conf.InstanceIDs {
 		slog.Debug("Start cleaning up unverified users", slog.String("instanceID", instanceID))
 
-		count := 0
-		// call DB method participantUserDBService
 		createdBefore := time.Now().Add(-conf.UserManagementConfig.DeleteUnverifiedUsersAfter).Unix()
 		filter := bson.M{}
 		filter["$and"] = bson.A{
 			bson.M{"account.accountConfirmedAt": 0},
+			bson.M{"account.setupPendingSince": bson.M{"$not": bson.M{"$gt": 0}}},
 			bson.M{"timestamps.createdAt": bson.M{"$lt": createdBefore}},
 		}
-		err := participantUserDBService.FindAndExecuteOnUsers(
-			context.Background(),
-			instanceID,
-			filter,
-			nil,
-			false,
-			func(user umTypes.User, args ...interface{}) error {
-				err := usermanagement.DeleteUser(
-					instanceID,
-					user.ID.Hex(),
-					func(instanceID string, profiles []string) error {
-						for _, profile := range profiles {
-							studyService.OnProfileDeleted(instanceID, profile, nil)
-						}
-						return nil
-					},
-					func(email string) error {
-						err := emailsending.QueueEmailByTemplate(
-							instanceID,
-							[]string{
-								email,
-							},
-							emailTypes.EMAIL_TYPE_ACCOUNT_DELETED,
-							"",
-							user.Account.PreferredLanguage,
-							map[string]string{},
-							true,
-						)
-						if err != nil {
-							slog.Error("failed to queue account deleted email", slog.String("error", err.Error()))
-							return err
-						}
-						return nil
-					},
-				)
-				if err != nil {
-					slog.Error("failed to delete user", slog.String("error", err.Error()))
-					return err
-				}
-				count = count + 1
-				return nil
-			},
-		)
+		count, err := deleteUsersWithCheckpoint(instanceID, "unverified-users", filter, emailTypes.EMAIL_TYPE_ACCOUNT_DELETED)
 		if err != nil {
 			slog.Error("Error cleaning up unverified users", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
 			continue
 		}
 
-		slog.Info("Clean up unverified users finished", slog.String("instanceID", instanceID), slog.Int("count", int(count)))
+		slog.Info("Clean up unverified users finished", slog.String("instanceID", instanceID), slog.Int64("count", count))
+	}
+}
+
+// cleanUpPendingSignups removes accounts created without password, if the setup was never completed
+func cleanUpPendingSignups() {
+	for _, instanceID := range conf.InstanceIDs {
+		slog.Debug("Start cleaning up pending signups", slog.String("instanceID", instanceID))
+
+		createdBefore := time.Now().Add(-conf.UserManagementConfig.DeletePendingSignupsAfter).Unix()
+		filter := bson.M{
+			"account.setupPendingSince": bson.M{"$gt": 0, "$lt": createdBefore},
+			"account.password":          "",
+		}
+		// the account was never used, so no notification is sent
+		count, err := deleteUsersWithCheckpoint(instanceID, "pending-signups", filter, "")
+		if err != nil {
+			slog.Error("Error cleaning up pending signups", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+
+		slog.Info("Clean up pending signups finished", slog.String("instanceID", instanceID), slog.Int64("count", count))
 	}
 }
 
@@ -103,31 +90,34 @@ func sendReminderToConfirmAccounts() {
 			bson.M{"timestamps.createdAt": bson.M{"$lt": createdBefore}},
 		}
 
-		count := 0
+		var count int64
+		err := processUsersWithCheckpoint(instanceID, "confirm-account-reminders", filter, func(users []umTypes.User) {
+			count += forEachUser(instanceID, users, func(user umTypes.User) (mongo.WriteModel, error) {
+				purpose := umTypes.TOKEN_PURPOSE_CONTACT_VERIFICATION
+				messageType := emailTypes.EMAIL_TYPE_REGISTRATION
+				ttl := conf.UserManagementConfig.EmailContactVerificationTokenTTL
+				if user.IsSetupPending() {
+					// accounts without password are reminded to complete the setup
+					purpose = umTypes.TOKEN_PURPOSE_ACCOUNT_SETUP
+					messageType = emailTypes.EMAIL_TYPE_ACCOUNT_SETUP
+					ttl = conf.UserManagementConfig.AccountSetupTokenTTL
+				}
 
-		// call DB method participantUserDBService
-		err := participantUserDBService.FindAndExecuteOnUsers(
-			context.Background(),
-			instanceID,
-			filter,
-			nil,
-			false,
-			func(user umTypes.User, args ...interface{}) error {
 				// Generate token
 				tempTokenInfos := umTypes.TempToken{
 					UserID:     user.ID.Hex(),
 					InstanceID: instanceID,
-					Purpose:    umTypes.TOKEN_PURPOSE_CONTACT_VERIFICATION,
+					Purpose:    purpose,
 					Info: map[string]string{
 						"type":  umTypes.ACCOUNT_TYPE_EMAIL,
 						"email": user.Account.AccountID,
 					},
-					Expiration: umUtils.GetExpirationTime(conf.UserManagementConfig.EmailContactVerificationTokenTTL),
+					Expiration: umUtils.GetExpirationTime(ttl),
 				}
 				tempToken, err := globalInfosDBService.AddTempToken(tempTokenInfos)
 				if err != nil {
 					slog.Error("failed to create verification token", slog.String("error", err.Error()))
-					return err
+					return nil, err
 				}
 
 				// Call message sending
@@ -136,7 +126,7 @@ func sendReminderToConfirmAccounts() {
 					[]string{
 						user.Account.AccountID,
 					},
-					emailTypes.EMAIL_TYPE_REGISTRATION,
+					messageType,
 					"",
 					user.Account.PreferredLanguage,
 					map[string]string{
@@ -146,27 +136,20 @@ func sendReminderToConfirmAccounts() {
 				)
 				if err != nil {
 					slog.Error("failed to queue verification email", slog.String("error", err.Error()))
-					return err
+					return nil, err
 				}
 
 				// Update user record
 				update := bson.M{"$set": bson.M{"timestamps.reminderToConfirmSentAt": time.Now().Unix()}}
-				err = participantUserDBService.UpdateUser(instanceID, user.ID.Hex(), update)
-				if err != nil {
-					slog.Error("failed to update user record", slog.String("error", err.Error()))
-					return err
-				}
-
-				count = count + 1
-				return nil
-			},
-		)
+				return mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": user.ID}).SetUpdate(update), nil
+			})
+		})
 		if err != nil {
 			slog.Error("Error sending reminders to confirm accounts", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
 			continue
 		}
 
-		slog.Info("Preparing reminders to confirm accounts finished", slog.String("instanceID", instanceID), slog.Int("count", int(count)))
+		slog.Info("Preparing reminders to confirm accounts finished", slog.String("instanceID", instanceID), slog.Int64("count", count))
 	}
 }
 
@@ -179,8 +162,7 @@ func notifyInactiveUsersAndMarkForDeletion() {
 	for _, instanceID := range conf.InstanceIDs {
 		slog.Debug("Start notifying inactive users and mark for deletion", slog.String("instanceID", instanceID))
 
-		count := 0
-
+		var count int64
 		lastActivityEarlierThan := time.Now().Add(-conf.UserManagementConfig.NotifyAfterInactiveFor).Unix()
 		filter := bson.M{}
 		filter["$and"] = bson.A{
@@ -194,15 +176,11 @@ func notifyInactiveUsersAndMarkForDeletion() {
 			bson.M{"timestamps.lastLogin": bson.M{"$lt": lastActivityEarlierThan}},
 			bson.M{"timestamps.lastTokenRefresh": bson.M{"$lt": lastActivityEarlierThan}},
 			bson.M{"timestamps.markedForDeletion": bson.M{"$not": bson.M{"$gt": 0}}},
+			bson.M{"timestamps.anonymizedAt": bson.M{"$not": bson.M{"$gt": 0}}},
 		}
 
-		err := participantUserDBService.FindAndExecuteOnUsers(
-			context.Background(),
-			instanceID,
-			filter,
-			nil,
-			false,
-			func(user umTypes.User, args ...interface{}) error {
+		err := processUsersWithCheckpoint(instanceID, "inactive-user-notifications", filter, func(users []umTypes.User) {
+			count += forEachUser(instanceID, users, func(user umTypes.User) (mongo.WriteModel, error) {
 				// Generate token
 				tempTokenInfos := umTypes.TempToken{
 					UserID:     user.ID.Hex(),
@@ -217,7 +195,7 @@ func notifyInactiveUsersAndMarkForDeletion() {
 				tempToken, err := globalInfosDBService.AddTempToken(tempTokenInfos)
 				if err != nil {
 					slog.Error("failed to create verification token", slog.String("error", err.Error()))
-					return err
+					return nil, err
 				}
 
 				// Call message sending
@@ -236,28 +214,20 @@ func notifyInactiveUsersAndMarkForDeletion() {
 				)
 				if err != nil {
 					slog.Error("failed to queue inactivity notice email", slog.String("error", err.Error()))
-					return err
+					return nil, err
 				}
 
 				// Update user record
 				update := bson.M{"$set": bson.M{"timestamps.markedForDeletion": time.Now().Add(conf.UserManagementConfig.MarkForDeletionAfterInactivityNotification).Unix()}}
-				err = participantUserDBService.UpdateUser(instanceID, user.ID.Hex(), update)
-				if err != nil {
-					slog.Error("failed to update user record", slog.String("error", err.Error()))
-					return err
-				}
-
-				count = count + 1
-				return nil
-			},
-		)
-
+				return mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": user.ID}).SetUpdate(update), nil
+			})
+		})
 		if err != nil {
 			slog.Error("Error notifying inactive users and mark for deletion", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
 			continue
 		}
 
-		slog.Info("Notifying inactive users and mark for deletion finished", slog.String("instanceID", instanceID), slog.Int("count", int(count)))
+		slog.Info("Notifying inactive users and mark for deletion finished", slog.String("instanceID", instanceID), slog.Int64("count", count))
 	}
 }
 
@@ -265,62 +235,152 @@ func cleanUpUsersMarkedForDeletion() {
 	for _, instanceID := range conf.InstanceIDs {
 		slog.Debug("Start cleaning up users marked for deletion", slog.String("instanceID", instanceID))
 
-		count := 0
-
-		// call DB method participantUserDBService
 		filter := bson.M{}
 		filter["$and"] = bson.A{
 			bson.M{"timestamps.markedForDeletion": bson.M{"$gt": 0}},
 			bson.M{"timestamps.markedForDeletion": bson.M{"$lt": time.Now().Unix()}},
 		}
-		err := participantUserDBService.FindAndExecuteOnUsers(
-			context.Background(),
+		count, err := deleteUsersWithCheckpoint(instanceID, "marked-for-deletion", filter, emailTypes.EMAIL_TYPE_ACCOUNT_DELETED_AFTER_INACTIVITY)
+		if err != nil {
+			slog.Error("Error cleaning up users marked for deletion", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+
+		slog.Info("Clean up users marked for deletion finished", slog.String("instanceID", instanceID), slog.Int64("count", count))
+	}
+}
+
+// deleteUsersWithCheckpoint deletes the users matching the filter with bulk writes, the users of a checkpoint batch are
+// split between the workers. messageType is the email sent to each deleted user, empty to send none. Failed deletions
+// are logged and the remaining users are still processed.
+func deleteUsersWithCheckpoint(instanceID string, task string, filter bson.M, messageType string) (int64, error) {
+	batchSize := conf.UserManagementConfig.BulkWriteBatchSize
+	if batchSize <= 0 {
+		batchSize = db.DEFAULT_BULK_WRITE_BATCH_SIZE
+	}
+
+	var count atomic.Int64
+	deleteUsers := func(users []umTypes.User) {
+		deleted, err := usermanagement.DeleteUsers(
 			instanceID,
-			filter,
-			nil,
-			false,
-			func(user umTypes.User, args ...interface{}) error {
-				err := usermanagement.DeleteUser(
+			users,
+			func(instanceID string, profiles []string) error {
+				for _, profile := range profiles {
+					studyService.OnProfileDeleted(context.Background(), instanceID, profile, nil)
+				}
+				return nil
+			},
+			func(user umTypes.User) error {
+				if messageType == "" {
+					return nil
+				}
+				return emailsending.QueueEmailByTemplate(
 					instanceID,
-					user.ID.Hex(),
-					func(instanceID string, profiles []string) error {
-						for _, profile := range profiles {
-							studyService.OnProfileDeleted(instanceID, profile, nil)
-						}
-						return nil
-					},
-					func(email string) error {
-						err := emailsending.QueueEmailByTemplate(
-							instanceID,
-							[]string{
-								email,
-							},
-							emailTypes.EMAIL_TYPE_ACCOUNT_DELETED_AFTER_INACTIVITY,
-							"",
-							user.Account.PreferredLanguage,
-							map[string]string{},
-							true,
-						)
-						if err != nil {
-							slog.Error("failed to queue account deleted email", slog.String("error", err.Error()))
-							return err
-						}
-						return nil
+					[]string{
+						user.Account.AccountID,
 					},
+					messageType,
+					"",
+					user.Account.PreferredLanguage,
+					map[string]string{},
+					true,
 				)
-				if err != nil {
-					slog.Error("failed to delete user", slog.String("error", err.Error()))
-					return err
-				}
-				count = count + 1
-				return nil
 			},
+			batchSize,
 		)
+		count.Add(deleted)
 		if err != nil {
-			slog.Error("Error cleaning up users marked for deletion", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			slog.Error("failed to delete users", slog.String("instanceID", instanceID), slog.Int("batchSize", len(users)), slog.String("error", err.Error()))
+		}
+	}
+
+	err := processUsersWithCheckpoint(instanceID, task, filter, func(users []umTypes.User) {
+		chunks := splitUsers(users, batchSize)
+		inParallel(len(chunks), func(i int) {
+			deleteUsers(chunks[i])
+		})
+	})
+	return count.Load(), err
+}
+
+// splitUsers returns chunks of at most size users
+func splitUsers(users []umTypes.User, size int) [][]umTypes.User {
+	chunks := [][]umTypes.User{}
+	for len(users) > size {
+		chunks = append(chunks, users[:size])
+		users = users[size:]
+	}
+	if len(users) > 0 {
+		chunks = append(chunks, users)
+	}
+	return chunks
+}
+
+func anonymizeUsersAfterStudyCompletion() {
+	if conf.UserManagementConfig.AnonymizeUsersAfterStudyCompletion == 0 {
+		slog.Info("Anonymization of users after study completion is disabled")
+		return
+	}
+
+	for _, instanceID := range conf.InstanceIDs {
+		slog.Debug("Start anonymizing users after study completion", slog.String("instanceID", instanceID))
+
+		// the studies are loaded once, the participation of each user is checked against them
+		studies, err := studyDBService.GetStudies(instanceID, "", false)
+		if err != nil {
+			slog.Error("Error getting studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+
+		var count atomic.Int64
+		lastActivityEarlierThan := time.Now().Add(-conf.UserManagementConfig.AnonymizeUsersAfterStudyCompletion).Unix()
+		filter := bson.M{}
+		filter["$and"] = bson.A{
+			bson.M{
+				"roles": bson.M{"$nin": bson.A{
+					"SERVICE",
+					"RESEARCHER",
+					"ADMIN",
+				}},
+			}, // for legacy reasons
+			bson.M{"account.accountConfirmedAt": bson.M{"$gt": 0}},
+			bson.M{"timestamps.lastLogin": bson.M{"$lt": lastActivityEarlierThan}},
+			bson.M{"timestamps.lastTokenRefresh": bson.M{"$lt": lastActivityEarlierThan}},
+			bson.M{"timestamps.markedForDeletion": bson.M{"$not": bson.M{"$gt": 0}}},
+			bson.M{"timestamps.anonymizedAt": bson.M{"$not": bson.M{"$gt": 0}}},
+		}
+
+		err = processUsersWithCheckpoint(instanceID, "anonymization", filter, func(users []umTypes.User) {
+			forEachUser(instanceID, users, func(user umTypes.User) (mongo.WriteModel, error) {
+				profileIDs := make([]string, len(user.Profiles))
+				for i, profile := range user.Profiles {
+					profileIDs[i] = profile.ID.Hex()
+				}
+
+				completed, lastStudyActivity, err := studyService.GetStudyCompletionForProfiles(instanceID, studies, profileIDs)
+				if err != nil {
+					slog.Error("failed to check study completion", slog.String("userID", user.ID.Hex()), slog.String("error", err.Error()))
+					return nil, err
+				}
+				if !completed || lastStudyActivity >= lastActivityEarlierThan {
+					return nil, nil
+				}
+
+				err = usermanagement.AnonymizeUser(instanceID, user.ID.Hex())
+				if err != nil {
+					slog.Error("failed to anonymize user", slog.String("userID", user.ID.Hex()), slog.String("error", err.Error()))
+					return nil, err
+				}
+
+				count.Add(1)
+				return nil, nil
+			})
+		})
+		if err != nil {
+			slog.Error("Error anonymizing users after study completion", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
 			continue
 		}
 
-		slog.Info("Clean up users marked for deletion finished", slog.String("instanceID", instanceID), slog.Int("count", int(count)))
+		slog.Info("Anonymizing users after study completion finished", slog.String("instanceID", instanceID), slog.Int64("count", count.Load()))
 	}
 }
@@ -0,0 +1,31 @@
+package apihelpers
+
+import (
+	"sync/atomic"
+)
+
+// AllowedOrigins holds the origins accepted by the CORS middleware, use Allow as AllowOriginFunc so that the origins
+// can be replaced when the config is reloaded
+type AllowedOrigins struct {
+	origins atomic.Pointer[map[string]bool]
+}
+
+func NewAllowedOrigins(origins []string) *AllowedOrigins {
+	a := &AllowedOrigins{}
+	a.Set(origins)
+	return a
+}
+
+// Set replaces the allowed origins, "*" allows all origins
+func (a *AllowedOrigins) Set(origins []string) {
+	m := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		m[origin] = true
+	}
+	a.origins.Store(&m)
+}
+
+func (a *AllowedOrigins) Allow(origin string) bool {
+	origins := *a.origins.Load()
+	return origins["*"] || origins[origin]
+}
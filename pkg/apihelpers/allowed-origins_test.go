@@ -0,0 +1,25 @@
+package apihelpers
+
+import "testing"
+
+func TestAllowedOrigins(t *testing.T) {
+	a := NewAllowedOrigins([]string{"https://a.example"})
+	if !a.Allow("https://a.example") || a.Allow("https://b.example") {
+		t.Error("unexpected result for initial origins")
+	}
+
+	a.Set([]string{"https://b.example"})
+	if a.Allow("https://a.example") || !a.Allow("https://b.example") {
+		t.Error("unexpected result after replacing origins")
+	}
+
+	a.Set([]string{"*"})
+	if !a.Allow("https://c.example") {
+		t.Error("wildcard should allow all origins")
+	}
+
+	a.Set(nil)
+	if a.Allow("https://a.example") {
+		t.Error("no origin should be allowed")
+	}
+}
@@ -0,0 +1,66 @@
+package apihelpers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HEADER_DEPRECATION = "Deprecation"
+	HEADER_SUNSET      = "Sunset"
+	HEADER_LINK        = "Link"
+
+	apiVersionContextKey = "apiVersion"
+)
+
+// APIVersionLifecycle announces the deprecation of an API version to clients, configured per version
+type APIVersionLifecycle struct {
+	// sent in the Deprecation header (RFC 9745), the version counts as deprecated from this time on
+	DeprecatedAt time.Time `json:"deprecated_at" yaml:"deprecated_at"`
+	// sent in the Sunset header (RFC 8594), the time after which the version may be removed
+	SunsetAt time.Time `json:"sunset_at" yaml:"sunset_at"`
+	// migration guide, sent as Link header with rel="deprecation"
+	Link string `json:"link" yaml:"link"`
+}
+
+func (l APIVersionLifecycle) deprecated(now time.Time) bool {
+	return !l.DeprecatedAt.IsZero() && !now.Before(l.DeprecatedAt)
+}
+
+// MountAPIVersions adds a route group with the URL prefix of each version, e.g. /v1 and /v2, and calls mount to add
+// the handlers to it. Handlers shared between versions can read the version of the request with RequestAPIVersion to
+// adapt their payloads.
+func MountAPIVersions(router gin.IRouter, versions []string, lifecycles map[string]APIVersionLifecycle, mount func(version string, root *gin.RouterGroup)) {
+	for _, version := range versions {
+		root := router.Group("/" + version)
+		root.Use(APIVersionMiddleware(version, lifecycles[version]))
+		mount(version, root)
+	}
+}
+
+// APIVersionMiddleware stores the version in the context, sets the deprecation headers and counts the request
+func APIVersionMiddleware(version string, lifecycle APIVersionLifecycle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionContextKey, version)
+		if !lifecycle.DeprecatedAt.IsZero() {
+			c.Header(HEADER_DEPRECATION, "@"+strconv.FormatInt(lifecycle.DeprecatedAt.Unix(), 10))
+		}
+		if !lifecycle.SunsetAt.IsZero() {
+			c.Header(HEADER_SUNSET, lifecycle.SunsetAt.UTC().Format(http.TimeFormat))
+		}
+		if lifecycle.Link != "" {
+			c.Header(HEADER_LINK, "<"+lifecycle.Link+">; rel=\"deprecation\"")
+		}
+		metrics.CountAPIVersion(version, lifecycle.deprecated(time.Now()))
+		c.Next()
+	}
+}
+
+// RequestAPIVersion returns the API version of the request, empty for routes outside the versioned groups
+func RequestAPIVersion(c *gin.Context) string {
+	return c.GetString(apiVersionContextKey)
+}
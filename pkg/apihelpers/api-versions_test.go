@@ -0,0 +1,60 @@
+package apihelpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMountAPIVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	lifecycles := map[string]APIVersionLifecycle{
+		"v1": {
+			DeprecatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			SunsetAt:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			Link:         "https://example.com/migrate-to-v2",
+		},
+	}
+	mounted := []string{}
+	MountAPIVersions(router, []string{"v1", "v2"}, lifecycles, func(version string, root *gin.RouterGroup) {
+		mounted = append(mounted, version)
+		root.GET("/ping", func(c *gin.Context) {
+			c.String(http.StatusOK, RequestAPIVersion(c))
+		})
+	})
+	if len(mounted) != 2 {
+		t.Fatalf("unexpected versions %v", mounted)
+	}
+
+	t.Run("deprecated version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/ping", nil))
+		if w.Body.String() != "v1" {
+			t.Errorf("unexpected version %s", w.Body.String())
+		}
+		if h := w.Header().Get(HEADER_DEPRECATION); h != "@1704067200" {
+			t.Errorf("unexpected deprecation header %s", h)
+		}
+		if h := w.Header().Get(HEADER_SUNSET); h != "Wed, 01 Jan 2025 00:00:00 GMT" {
+			t.Errorf("unexpected sunset header %s", h)
+		}
+		if h := w.Header().Get(HEADER_LINK); h != "<https://example.com/migrate-to-v2>; rel=\"deprecation\"" {
+			t.Errorf("unexpected link header %s", h)
+		}
+	})
+
+	t.Run("current version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/ping", nil))
+		if w.Body.String() != "v2" {
+			t.Errorf("unexpected version %s", w.Body.String())
+		}
+		if w.Header().Get(HEADER_DEPRECATION) != "" || w.Header().Get(HEADER_SUNSET) != "" || w.Header().Get(HEADER_LINK) != "" {
+			t.Errorf("unexpected headers %v", w.Header())
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package apihelpers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stable error codes sent in the "code" field of error responses, clients should check these instead of the message
+const (
+	ERROR_CODE_REQUEST_INVALID         = "request.invalid"
+	ERROR_CODE_REQUEST_TOO_LARGE       = "request.too_large"
+	ERROR_CODE_VALIDATION_FAILED       = "validation.failed"
+	ERROR_CODE_RATE_LIMITED            = "rate.limited"
+	ERROR_CODE_INTERNAL                = "internal"
+	ERROR_CODE_NOT_FOUND               = "resource.not_found"
+	ERROR_CODE_CONFLICT                = "resource.conflict"
+	ERROR_CODE_FORBIDDEN               = "auth.forbidden"
+	ERROR_CODE_INVALID_INSTANCE        = "auth.invalid_instance"
+	ERROR_CODE_INVALID_TOKEN           = "auth.invalid_token"
+	ERROR_CODE_INVALID_OTP             = "auth.invalid_otp"
+	ERROR_CODE_TOO_MANY_ATTEMPTS       = "auth.too_many_attempts"
+	ERROR_CODE_INVALID_CREDENTIALS     = "auth.invalid_credentials"
+	ERROR_CODE_IDEMPOTENCY_KEY_REUSED  = "idempotency.key_reused"
+	ERROR_CODE_IDEMPOTENCY_IN_PROGRESS = "idempotency.in_progress"
+)
+
+// FieldError describes why the value of a request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is the body of error responses. The message stays in the "error" field, so that clients reading it keep
+// working.
+type APIError struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"error"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+func NewAPIError(status int, code string, message string, details ...FieldError) *APIError {
+	return &APIError{Status: status, Code: code, Message: message, Details: details}
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// RespondAPIError aborts the request with the error
+func RespondAPIError(c *gin.Context, err *APIError) {
+	c.AbortWithStatusJSON(err.Status, err)
+}
+
+// RespondError aborts the request with an error response
+func RespondError(c *gin.Context, status int, code string, message string) {
+	RespondAPIError(c, NewAPIError(status, code, message))
+}
+
+// RespondInternalError aborts the request with a generic internal server error, the cause should be logged instead
+func RespondInternalError(c *gin.Context, message string) {
+	RespondError(c, http.StatusInternalServerError, ERROR_CODE_INTERNAL, message)
+}
+
+// RespondValidationError aborts the request with a validation error about the fields
+func RespondValidationError(c *gin.Context, message string, details ...FieldError) {
+	RespondAPIError(c, NewAPIError(http.StatusBadRequest, ERROR_CODE_VALIDATION_FAILED, message, details...))
+}
+
+// RespondBindError aborts the request with the error of binding the request body. Values of the wrong type are
+// reported as validation error of the field.
+func RespondBindError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		RespondError(c, http.StatusRequestEntityTooLarge, ERROR_CODE_REQUEST_TOO_LARGE, "request body too large")
+		return
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		RespondValidationError(c, err.Error(), FieldError{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()})
+		return
+	}
+	RespondError(c, http.StatusBadRequest, ERROR_CODE_REQUEST_INVALID, err.Error())
+}
+
+// MissingFields returns the details for the empty values of the required fields, keyed by field name
+func MissingFields(fields map[string]string) []FieldError {
+	details := []FieldError{}
+	for field, value := range fields {
+		if value == "" {
+			details = append(details, FieldError{Field: field, Message: "required"})
+		}
+	}
+	sort.Slice(details, func(i, j int) bool {
+		return details[i].Field < details[j].Field
+	})
+	return details
+}
@@ -0,0 +1,75 @@
+package apihelpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func errorResponse(t *testing.T, handler gin.HandlerFunc, body string) (int, map[string]any) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	handler(c)
+	if !c.IsAborted() {
+		t.Error("request not aborted")
+	}
+	resp := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, resp
+}
+
+func TestRespondError(t *testing.T) {
+	t.Run("error with code", func(t *testing.T) {
+		status, resp := errorResponse(t, func(c *gin.Context) {
+			RespondError(c, http.StatusUnauthorized, ERROR_CODE_INVALID_CREDENTIALS, "invalid email or password")
+		}, "")
+		expected := map[string]any{"code": "auth.invalid_credentials", "error": "invalid email or password"}
+		if status != http.StatusUnauthorized || !reflect.DeepEqual(resp, expected) {
+			t.Errorf("unexpected response %d %v", status, resp)
+		}
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		status, resp := errorResponse(t, func(c *gin.Context) {
+			RespondValidationError(c, "missing required fields", MissingFields(map[string]string{"password": "", "email": "", "instanceId": "i1"})...)
+		}, "")
+		details, _ := resp["details"].([]any)
+		if status != http.StatusBadRequest || resp["code"] != ERROR_CODE_VALIDATION_FAILED || len(details) != 2 {
+			t.Fatalf("unexpected response %d %v", status, resp)
+		}
+		if first := details[0].(map[string]any); first["field"] != "email" || first["message"] != "required" {
+			t.Errorf("unexpected details %v", details)
+		}
+	})
+
+	t.Run("bind errors", func(t *testing.T) {
+		bind := func(c *gin.Context) {
+			var req struct {
+				Email string `json:"email"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				RespondBindError(c, err)
+			}
+		}
+		_, resp := errorResponse(t, bind, `{"email": 1}`)
+		details, _ := resp["details"].([]any)
+		if resp["code"] != ERROR_CODE_VALIDATION_FAILED || len(details) != 1 || details[0].(map[string]any)["field"] != "email" {
+			t.Errorf("unexpected response %v", resp)
+		}
+
+		_, resp = errorResponse(t, bind, `{"email": `)
+		if resp["code"] != ERROR_CODE_REQUEST_INVALID || resp["details"] != nil {
+			t.Errorf("unexpected response %v", resp)
+		}
+	})
+}
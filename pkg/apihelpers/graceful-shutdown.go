@@ -0,0 +1,156 @@
+package apihelpers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultShutdownTimeout = 30 * time.Second
+
+// ShutdownSignalContext is cancelled on SIGINT or SIGTERM, after the first signal the default behaviour is restored,
+// so that a second signal kills the process
+func ShutdownSignalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// ShutdownTimeout returns the configured drain timeout or the default if not set
+func ShutdownTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return timeout
+}
+
+type serverStreamsKey struct{}
+
+// serverStreams tracks the long-lived streams of a server, they are cancelled when the server shuts down
+type serverStreams struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+func (s *serverStreams) add() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *serverStreams) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancel()
+}
+
+func (s *serverStreams) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// StreamContext returns the context for a long-lived stream of the request, e.g. server-sent events or a WebSocket.
+// It is cancelled when the request ends or the server shuts down, and ServeUntilDone waits until the returned
+// cancel function is called. Shutdown does not wait for hijacked connections otherwise.
+func StreamContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	streams, ok := r.Context().Value(serverStreamsKey{}).(*serverStreams)
+	if !ok {
+		return ctx, cancel
+	}
+	if !streams.add() {
+		// server is already shutting down
+		cancel()
+		return ctx, cancel
+	}
+
+	stopAfterFunc := context.AfterFunc(streams.ctx, cancel)
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			stopAfterFunc()
+			cancel()
+			streams.wg.Done()
+		})
+	}
+}
+
+// ServeUntilDone serves until the context is cancelled, then stops accepting connections and waits up to the drain
+// timeout for running requests. Streams started with StreamContext are cancelled and waited for as well.
+// TLS is used if the server has a TLS config, with the certificate files passed to ListenAndServeTLS.
+// Returns nil after a clean shutdown.
+func ServeUntilDone(ctx context.Context, server *http.Server, certFile string, keyFile string, drainTimeout time.Duration) error {
+	streams := &serverStreams{}
+	streams.ctx, streams.cancel = context.WithCancel(context.Background())
+	defer streams.stop()
+
+	baseContext := server.BaseContext
+	server.BaseContext = func(listener net.Listener) context.Context {
+		base := context.Background()
+		if baseContext != nil {
+			base = baseContext(listener)
+		}
+		return context.WithValue(base, serverStreamsKey{}, streams)
+	}
+	// streams would otherwise keep their connections active until the drain timeout
+	server.RegisterOnShutdown(streams.stop)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		serveErr <- err
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down server, draining requests", slog.String("addr", server.Addr), slog.Duration("timeout", drainTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// WebSockets are hijacked connections, Shutdown does not wait for them
+	streams.stop()
+	if err := streams.wait(shutdownCtx); err != nil {
+		return errors.New("streams not finished before the drain timeout")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package apihelpers
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServeUntilDone(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	started := make(chan struct{})
+	server := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte("done"))
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeUntilDone(ctx, server, "", "", time.Second)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		var resp *http.Response
+		var err error
+		for i := 0; i < 50; i++ {
+			resp, err = http.Get("http://" + addr)
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("request not started")
+	}
+	cancel()
+
+	if err := <-served; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if b := <-body; b != "done" {
+		t.Errorf("running request not finished: %s", b)
+	}
+}
+
+func TestServeUntilDoneStopsStreams(t *testing.T) {
+	tests := []struct {
+		name   string
+		hijack bool
+	}{
+		{name: "server-sent events"},
+		{name: "hijacked connection", hijack: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Skipf("cannot listen: %v", err)
+			}
+			addr := listener.Addr().String()
+			listener.Close()
+
+			started := make(chan struct{})
+			var streamStopped atomic.Bool
+			server := &http.Server{
+				Addr: addr,
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx, cancel := StreamContext(r)
+					defer cancel()
+					if tt.hijack {
+						conn, _, err := w.(http.Hijacker).Hijack()
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						defer conn.Close()
+					} else {
+						w.WriteHeader(http.StatusOK)
+						w.(http.Flusher).Flush()
+					}
+					close(started)
+
+					<-ctx.Done()
+					// cleanup of the stream that has to finish before the DBs are closed
+					time.Sleep(100 * time.Millisecond)
+					streamStopped.Store(true)
+				}),
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			served := make(chan error, 1)
+			go func() {
+				served <- ServeUntilDone(ctx, server, "", "", 5*time.Second)
+			}()
+
+			go func() {
+				for i := 0; i < 50; i++ {
+					conn, err := net.Dial("tcp", addr)
+					if err == nil {
+						defer conn.Close()
+						_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+						_, _ = io.ReadAll(conn)
+						return
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}()
+
+			select {
+			case <-started:
+			case <-time.After(2 * time.Second):
+				t.Fatal("stream not started")
+			}
+			shutdownStarted := time.Now()
+			cancel()
+
+			if err := <-served; err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !streamStopped.Load() {
+				t.Error("returned before the stream stopped")
+			}
+			if time.Since(shutdownStarted) > 2*time.Second {
+				t.Error("stream not cancelled on shutdown")
+			}
+		})
+	}
+}
+
+func TestStreamContextWithoutServer(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx, cancel := StreamContext(r)
+	if ctx.Err() != nil {
+		t.Fatal("stream context cancelled too early")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("stream context not cancelled")
+	}
+}
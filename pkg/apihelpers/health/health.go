@@ -0,0 +1,186 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	STATUS_OK    = "ok"
+	STATUS_ERROR = "error"
+
+	defaultTimeout = 3 * time.Second
+)
+
+// Probe checks a dependency, it must return when the context is done
+type Probe func(ctx context.Context) error
+
+type dependency struct {
+	name  string
+	probe Probe
+}
+
+// DependencyStatus is the result of one probe
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Checker runs the probes of the dependencies of a service for the readiness endpoint
+type Checker struct {
+	timeout      time.Duration
+	dependencies []dependency
+}
+
+// NewChecker uses a default timeout of 3 seconds per probe if timeout is not positive
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a dependency, nil probes are ignored
+func (c *Checker) Add(name string, probe Probe) *Checker {
+	if probe != nil {
+		c.dependencies = append(c.dependencies, dependency{name: name, probe: probe})
+	}
+	return c
+}
+
+// Check runs all probes concurrently and returns false if any of them failed
+func (c *Checker) Check(ctx context.Context) (bool, []DependencyStatus) {
+	results := make([]DependencyStatus, len(c.dependencies))
+	wg := sync.WaitGroup{}
+	for i, d := range c.dependencies {
+		wg.Add(1)
+		go func(i int, d dependency) {
+			defer wg.Done()
+			results[i] = runProbe(ctx, d, c.timeout)
+		}(i, d)
+	}
+	wg.Wait()
+
+	ok := true
+	for _, r := range results {
+		if r.Status != STATUS_OK {
+			ok = false
+		}
+	}
+	return ok, results
+}
+
+func runProbe(ctx context.Context, d dependency, timeout time.Duration) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := d.probe(ctx)
+	result := DependencyStatus{
+		Name:      d.name,
+		Status:    STATUS_OK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = STATUS_ERROR
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// AddRoutes adds GET /health/live and /health/ready. The liveness probe only reports that the process serves
+// requests, so that an outage of a dependency does not restart all replicas. The readiness probe responds with 503 if
+// a dependency is not reachable.
+func (c *Checker) AddRoutes(rg gin.IRoutes) {
+	rg.GET("/health/live", LiveHandle)
+	rg.GET("/health/ready", c.ReadyHandle)
+}
+
+func LiveHandle(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": STATUS_OK})
+}
+
+func (c *Checker) ReadyHandle(ctx *gin.Context) {
+	ok, results := c.Check(ctx.Request.Context())
+	status := STATUS_OK
+	code := http.StatusOK
+	if !ok {
+		status = STATUS_ERROR
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, gin.H{"status": status, "dependencies": results})
+}
+
+// TCPProbe opens and closes a connection to the address (host:port)
+func TCPProbe(address string) Probe {
+	if address == "" {
+		return nil
+	}
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// URLProbe opens a TCP connection to the host of the URL, the port defaults to the one of the scheme. Nothing is sent,
+// so it can be used for endpoints requiring authentication.
+func URLProbe(rawURL string) Probe {
+	if rawURL == "" {
+		return nil
+	}
+	return func(ctx context.Context) error {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		if u.Hostname() == "" {
+			return errors.New("no host in URL")
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		return TCPProbe(net.JoinHostPort(u.Hostname(), port))(ctx)
+	}
+}
+
+// DirProbe checks that the directory exists and is writable by creating and removing a temporary file
+func DirProbe(path string) Probe {
+	if path == "" {
+		return nil
+	}
+	return func(_ context.Context) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return errors.New("not a directory")
+		}
+		f, err := os.CreateTemp(path, ".health-*")
+		if err != nil {
+			return err
+		}
+		name := f.Name()
+		f.Close()
+		return os.Remove(filepath.Clean(name))
+	}
+}
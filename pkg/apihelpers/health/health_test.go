@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestChecker(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("ready if all probes pass", func(t *testing.T) {
+		c := NewChecker(time.Second).
+			Add("a", func(ctx context.Context) error { return nil }).
+			Add("skipped", nil)
+		ok, results := c.Check(context.Background())
+		if !ok || len(results) != 1 || results[0].Status != STATUS_OK {
+			t.Errorf("unexpected result %v %+v", ok, results)
+		}
+	})
+
+	t.Run("not ready if a probe fails or times out", func(t *testing.T) {
+		c := NewChecker(50*time.Millisecond).
+			Add("ok", func(ctx context.Context) error { return nil }).
+			Add("failing", func(ctx context.Context) error { return errors.New("down") }).
+			Add("slow", func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+
+		router := gin.New()
+		c.AddRoutes(router)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+		var body struct {
+			Dependencies []DependencyStatus `json:"dependencies"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Dependencies) != 3 || body.Dependencies[0].Status != STATUS_OK || body.Dependencies[1].Error != "down" || body.Dependencies[2].Status != STATUS_ERROR {
+			t.Errorf("unexpected dependencies %+v", body.Dependencies)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("liveness should not depend on probes, got %d", w.Code)
+		}
+	})
+}
+
+func TestProbes(t *testing.T) {
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	address := listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	if err := TCPProbe(address)(ctx); err != nil {
+		t.Errorf("tcp probe failed: %v", err)
+	}
+	if err := URLProbe("http://" + address + "/send")(ctx); err != nil {
+		t.Errorf("url probe failed: %v", err)
+	}
+	listener.Close()
+	if err := TCPProbe(address)(ctx); err == nil {
+		t.Error("expected error for closed port")
+	}
+
+	if err := DirProbe(t.TempDir())(ctx); err != nil {
+		t.Errorf("dir probe failed: %v", err)
+	}
+	if err := DirProbe(t.TempDir() + "/missing")(ctx); err == nil {
+		t.Error("expected error for missing dir")
+	}
+
+	if TCPProbe("") != nil || URLProbe("") != nil || DirProbe("") != nil {
+		t.Error("probes of unset dependencies should be nil")
+	}
+}
@@ -0,0 +1,142 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/gin-gonic/gin"
+)
+
+type BodyLimitsConfig struct {
+	// max size of request bodies in bytes, used if no route limit matches
+	MaxBodySize int64 `json:"max_body_size" yaml:"max_body_size"`
+	// max nesting depth of objects and arrays in JSON request bodies
+	MaxJSONDepth int `json:"max_json_depth" yaml:"max_json_depth"`
+	// limits of single routes, e.g. survey submissions, the longest matching route wins
+	Routes []RouteBodyLimit `json:"routes" yaml:"routes"`
+}
+
+type RouteBodyLimit struct {
+	// prefix of the request path without the API version, e.g. /study/ for /v1/study/...
+	Route string `json:"route" yaml:"route"`
+	// all methods if empty
+	Method      string `json:"method" yaml:"method"`
+	MaxBodySize int64  `json:"max_body_size" yaml:"max_body_size"`
+}
+
+// WithDefaults returns the config with the service defaults for unset limits
+func (conf BodyLimitsConfig) WithDefaults(maxBodySize int64, maxJSONDepth int) BodyLimitsConfig {
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = maxBodySize
+	}
+	if conf.MaxJSONDepth <= 0 {
+		conf.MaxJSONDepth = maxJSONDepth
+	}
+	return conf
+}
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+func (conf BodyLimitsConfig) maxBodySize(path string, method string) int64 {
+	path = "/" + strings.TrimPrefix(apiVersionPrefix.ReplaceAllString(path, ""), "/")
+	limit := conf.MaxBodySize
+	matched := ""
+	for _, r := range conf.Routes {
+		if r.Method != "" && r.Method != method {
+			continue
+		}
+		if strings.HasPrefix(path, r.Route) && len(r.Route) > len(matched) {
+			matched = r.Route
+			limit = r.MaxBodySize
+		}
+	}
+	return limit
+}
+
+// BodyLimits rejects bodies above the size limit of the route with 413 and JSON bodies nested deeper than the max
+// depth with 400, before a handler decodes them. Bodies without content length are cut off at the limit, reading
+// them further fails with http.MaxBytesError.
+func BodyLimits(conf BodyLimitsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		limit := conf.maxBodySize(c.Request.URL.Path, c.Request.Method)
+		if limit > 0 {
+			if c.Request.ContentLength > limit {
+				slog.WarnContext(c, "request body too large", slog.String("path", c.Request.URL.Path), slog.Int64("contentLength", c.Request.ContentLength), slog.Int64("limit", limit))
+				apihelpers.RespondError(c, http.StatusRequestEntityTooLarge, apihelpers.ERROR_CODE_REQUEST_TOO_LARGE, "request body too large")
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+
+		if conf.MaxJSONDepth > 0 && isJSONContentType(c.ContentType()) {
+			// the size limit bounds the memory used for buffering the body
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					slog.WarnContext(c, "request body too large", slog.String("path", c.Request.URL.Path), slog.Int64("limit", limit))
+					apihelpers.RespondError(c, http.StatusRequestEntityTooLarge, apihelpers.ERROR_CODE_REQUEST_TOO_LARGE, "request body too large")
+					return
+				}
+				apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_REQUEST_INVALID, "failed to read request body")
+				return
+			}
+			if jsonDepthExceeds(body, conf.MaxJSONDepth) {
+				slog.WarnContext(c, "request body nested too deep", slog.String("path", c.Request.URL.Path), slog.Int("maxDepth", conf.MaxJSONDepth))
+				apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_REQUEST_INVALID, "request body nested too deep")
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		c.Next()
+	}
+}
+
+// isJSONContentType also accepts a missing content type, since the handlers bind JSON regardless of it
+func isJSONContentType(contentType string) bool {
+	return contentType == "" || contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+// jsonDepthExceeds scans the nesting of objects and arrays outside of strings, without decoding the body. Invalid
+// JSON is left to the decoder of the handler.
+func jsonDepthExceeds(body []byte, maxDepth int) bool {
+	depth := 0
+	inString := false
+	escaped := false
+	for _, b := range body {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
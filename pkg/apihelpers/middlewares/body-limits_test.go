@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodyLimits(BodyLimitsConfig{
+		Routes: []RouteBodyLimit{{Route: "/study/", Method: http.MethodPost, MaxBodySize: 32}},
+	}.WithDefaults(64, 3)))
+	handler := func(c *gin.Context) {
+		var body any
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apihelpers.RespondBindError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, body)
+	}
+	router.POST("/v1/study/submit", handler)
+	router.POST("/v1/user", handler)
+
+	request := func(path string, body string, unknownLength bool) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if unknownLength {
+			req.ContentLength = -1
+			req.Body = io.NopCloser(strings.NewReader(body))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		resp := map[string]any{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		code, _ := resp["code"].(string)
+		return w.Code, code
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		unknownLength bool
+		status        int
+		code          string
+	}{
+		{"within limits", "/v1/user", `{"a": [1, {"b": "[[[["}]}`, false, http.StatusOK, ""},
+		{"default size limit", "/v1/user", `{"a": "` + strings.Repeat("x", 64) + `"}`, false, http.StatusRequestEntityTooLarge, apihelpers.ERROR_CODE_REQUEST_TOO_LARGE},
+		{"route size limit", "/v1/study/submit", `{"a": "` + strings.Repeat("x", 32) + `"}`, false, http.StatusRequestEntityTooLarge, apihelpers.ERROR_CODE_REQUEST_TOO_LARGE},
+		{"unknown length", "/v1/study/submit", `{"a": "` + strings.Repeat("x", 32) + `"}`, true, http.StatusRequestEntityTooLarge, apihelpers.ERROR_CODE_REQUEST_TOO_LARGE},
+		{"nested too deep", "/v1/user", `{"a": [[{"b": 1}]]}`, false, http.StatusBadRequest, apihelpers.ERROR_CODE_REQUEST_INVALID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := request(tt.path, tt.body, tt.unknownLength)
+			if status != tt.status || code != tt.code {
+				t.Errorf("unexpected response %d %s", status, code)
+			}
+		})
+	}
+}
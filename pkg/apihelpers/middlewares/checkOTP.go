@@ -85,3 +85,16 @@ func getOTPConfigForRoute(route string, method string, otpConf []OTPConfig) *OTP
 	}
 	return foundConfig
 }
+
+// OTPConfigsForAPIVersion moves the configured routes of the v1 API to the URL prefix of another API version, so that
+// the routes requiring an OTP are protected in all versions
+func OTPConfigsForAPIVersion(otpConf []OTPConfig, version string) []OTPConfig {
+	configs := make([]OTPConfig, len(otpConf))
+	for i, conf := range otpConf {
+		if conf.Route == "/v1" || strings.HasPrefix(conf.Route, "/v1/") {
+			conf.Route = "/" + version + strings.TrimPrefix(conf.Route, "/v1")
+		}
+		configs[i] = conf
+	}
+	return configs
+}
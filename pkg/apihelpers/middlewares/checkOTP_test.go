@@ -0,0 +1,17 @@
+package middlewares
+
+import "testing"
+
+func TestOTPConfigsForAPIVersion(t *testing.T) {
+	configs := []OTPConfig{{Route: "/v1/user", Exact: true}, {Route: "/v1"}, {Route: "/v10/x"}, {Route: "/health"}}
+	moved := OTPConfigsForAPIVersion(configs, "v2")
+	expected := []string{"/v2/user", "/v2", "/v10/x", "/health"}
+	for i, conf := range moved {
+		if conf.Route != expected[i] {
+			t.Errorf("unexpected route %s, expected %s", conf.Route, expected[i])
+		}
+	}
+	if configs[0].Route != "/v1/user" || !moved[0].Exact {
+		t.Error("configs should be copied")
+	}
+}
@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HEADER_ETAG          = "ETag"
+	HEADER_IF_NONE_MATCH = "If-None-Match"
+	HEADER_CACHE_CONTROL = "Cache-Control"
+	HEADER_VARY          = "Vary"
+
+	// clients may keep the response, but have to revalidate it with the ETag before using it
+	DEFAULT_CACHE_CONTROL = "private, no-cache"
+)
+
+// ETag adds an ETag header with the hash of the body to successful GET responses, and answers requests with a
+// matching If-None-Match header with 304 Not Modified and without body. The response is buffered, so the handler
+// still runs for each request, but unchanged content is not sent again. cacheControl is sent as Cache-Control header
+// if the handler does not set it, DEFAULT_CACHE_CONTROL if empty. Responses to requests with an Authorization header
+// are always private and vary by the Authorization header.
+func ETag(cacheControl string) gin.HandlerFunc {
+	if cacheControl == "" {
+		cacheControl = DEFAULT_CACHE_CONTROL
+	}
+	authenticatedCacheControl := privateCacheControl(cacheControl)
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &bufferingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() != http.StatusOK {
+			w.flush()
+			return
+		}
+
+		hash := sha256.Sum256(w.body.Bytes())
+		etag := `"` + base64.RawURLEncoding.EncodeToString(hash[:]) + `"`
+		header := w.Header()
+		header.Set(HEADER_ETAG, etag)
+		if c.GetHeader("Authorization") != "" {
+			header.Add(HEADER_VARY, "Authorization")
+			if header.Get(HEADER_CACHE_CONTROL) == "" {
+				header.Set(HEADER_CACHE_CONTROL, authenticatedCacheControl)
+			}
+		} else if header.Get(HEADER_CACHE_CONTROL) == "" {
+			header.Set(HEADER_CACHE_CONTROL, cacheControl)
+		}
+
+		if etagMatches(c.GetHeader(HEADER_IF_NONE_MATCH), etag) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		w.flush()
+	}
+}
+
+// AllowsSharedCaching reports whether the Cache-Control value lets shared caches, e.g. CDNs, store the response
+func AllowsSharedCaching(cacheControl string) bool {
+	for _, directive := range cacheControlDirectives(cacheControl) {
+		if directive == "public" || directive == "s-maxage" {
+			return true
+		}
+	}
+	return false
+}
+
+// privateCacheControl replaces public and s-maxage of the Cache-Control value with private
+func privateCacheControl(cacheControl string) string {
+	directives := []string{"private"}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		switch cacheControlDirectiveName(directive) {
+		case "", "public", "private", "s-maxage":
+			continue
+		}
+		directives = append(directives, directive)
+	}
+	return strings.Join(directives, ", ")
+}
+
+func cacheControlDirectives(cacheControl string) []string {
+	names := []string{}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		names = append(names, cacheControlDirectiveName(directive))
+	}
+	return names
+}
+
+func cacheControlDirectiveName(directive string) string {
+	name, _, _ := strings.Cut(directive, "=")
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// etagMatches compares the ETags of an If-None-Match header weakly, as required by RFC 9110
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferingWriter holds the body back until the handler is done, the status is kept by the wrapped writer
+type bufferingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferingWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferingWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferingWriter) WriteHeaderNow() {}
+
+func (w *bufferingWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *bufferingWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferingWriter) flush() {
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
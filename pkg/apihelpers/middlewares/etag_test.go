@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	content := "v1"
+	router.GET("/survey", ETag(""), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"content": content})
+	})
+	router.GET("/public", ETag("public, max-age=60"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"content": content})
+	})
+	router.GET("/missing", ETag(""), func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+
+	request := func(path string, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set(HEADER_IF_NONE_MATCH, ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := request("/survey", "")
+	etag := first.Header().Get(HEADER_ETAG)
+	if first.Code != http.StatusOK || etag == "" || first.Body.String() != `{"content":"v1"}` {
+		t.Fatalf("unexpected response %d %v %s", first.Code, first.Header(), first.Body.String())
+	}
+	if first.Header().Get(HEADER_CACHE_CONTROL) != DEFAULT_CACHE_CONTROL {
+		t.Errorf("unexpected cache control %s", first.Header().Get(HEADER_CACHE_CONTROL))
+	}
+
+	t.Run("unchanged content", func(t *testing.T) {
+		for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+			w := request("/survey", ifNoneMatch)
+			if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+				t.Errorf("unexpected response for %s: %d %s", ifNoneMatch, w.Code, w.Body.String())
+			}
+			if w.Header().Get(HEADER_ETAG) != etag {
+				t.Errorf("unexpected etag %s", w.Header().Get(HEADER_ETAG))
+			}
+		}
+	})
+
+	t.Run("changed content", func(t *testing.T) {
+		content = "v2"
+		defer func() { content = "v1" }()
+		w := request("/survey", etag)
+		if w.Code != http.StatusOK || w.Body.String() != `{"content":"v2"}` || w.Header().Get(HEADER_ETAG) == etag {
+			t.Errorf("unexpected response %d %v %s", w.Code, w.Header(), w.Body.String())
+		}
+	})
+
+	t.Run("configured cache control", func(t *testing.T) {
+		w := request("/public", "")
+		if w.Header().Get(HEADER_CACHE_CONTROL) != "public, max-age=60" {
+			t.Errorf("unexpected cache control %s", w.Header().Get(HEADER_CACHE_CONTROL))
+		}
+	})
+
+	t.Run("authenticated request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/public", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Header().Get(HEADER_CACHE_CONTROL) != "private, max-age=60" {
+			t.Errorf("unexpected cache control %s", w.Header().Get(HEADER_CACHE_CONTROL))
+		}
+		if w.Header().Get(HEADER_VARY) != "Authorization" {
+			t.Errorf("unexpected vary %s", w.Header().Get(HEADER_VARY))
+		}
+	})
+
+	t.Run("errors are not tagged", func(t *testing.T) {
+		w := request("/missing", "*")
+		if w.Code != http.StatusNotFound || w.Header().Get(HEADER_ETAG) != "" || w.Body.String() != `{"error":"not found"}` {
+			t.Errorf("unexpected response %d %v %s", w.Code, w.Header(), w.Body.String())
+		}
+	})
+}
+
+func TestAllowsSharedCaching(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		want         bool
+	}{
+		{cacheControl: DEFAULT_CACHE_CONTROL},
+		{cacheControl: "max-age=60"},
+		{cacheControl: "public, max-age=60", want: true},
+		{cacheControl: "private, S-MAXAGE=60", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := AllowsSharedCaching(tt.cacheControl); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.cacheControl, tt.want, got)
+		}
+	}
+}
+
+func TestPrivateCacheControl(t *testing.T) {
+	tests := map[string]string{
+		DEFAULT_CACHE_CONTROL:              DEFAULT_CACHE_CONTROL,
+		"public, max-age=60":               "private, max-age=60",
+		"max-age=60, s-maxage=600, public": "private, max-age=60",
+		"no-cache":                         "private, no-cache",
+	}
+
+	for cacheControl, want := range tests {
+		if got := privateCacheControl(cacheControl); got != want {
+			t.Errorf("%s: expected %s, got %s", cacheControl, want, got)
+		}
+	}
+}
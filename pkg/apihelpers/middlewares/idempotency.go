@@ -0,0 +1,219 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	globalinfos "github.com/case-framework/case-backend/pkg/db/global-infos"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HEADER_IDEMPOTENCY_KEY     = "Idempotency-Key"
+	HEADER_IDEMPOTENT_REPLAYED = "Idempotent-Replayed"
+
+	DEFAULT_IDEMPOTENCY_WINDOW = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+	// larger responses are not stored, retries run the request again
+	maxReplayedBodySize = 1 << 20
+)
+
+type IdempotencyConfig struct {
+	// responses are replayed for retries with the same key within this window, defaults to 24h
+	Window time.Duration `json:"window" yaml:"window"`
+}
+
+// IdempotencyStore is implemented by the global infos DB service
+type IdempotencyStore interface {
+	ClaimIdempotencyKey(key string, fingerprint string, expiresAt time.Time) (*globalinfos.IdempotencyRecord, error)
+	CompleteIdempotencyKey(key string, statusCode int, contentType string, body []byte) error
+	ReleaseIdempotencyKey(key string) error
+}
+
+// Idempotency runs requests with an Idempotency-Key header only once per key, route and user, and replays the stored
+// response for retries, e.g. of mobile clients after a timeout. Requests without the header are not affected. Failed
+// requests (5xx and 429) are not stored, so that they can be retried. The stored response bodies are encrypted with a
+// key derived from the request, so that they can only be read by a retry of the same request.
+func Idempotency(store IdempotencyStore, conf IdempotencyConfig) gin.HandlerFunc {
+	window := conf.Window
+	if window <= 0 {
+		window = DEFAULT_IDEMPOTENCY_WINDOW
+	}
+
+	return func(c *gin.Context) {
+		clientKey := c.GetHeader(HEADER_IDEMPOTENCY_KEY)
+		if clientKey == "" {
+			c.Next()
+			return
+		}
+		if len(clientKey) > maxIdempotencyKeyLength {
+			apihelpers.RespondValidationError(c, "invalid idempotency key", apihelpers.FieldError{Field: HEADER_IDEMPOTENCY_KEY, Message: "too long"})
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				apihelpers.RespondBindError(c, err)
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		key, fingerprint, encryptionKey := idempotencyHashes(idempotencyScope(c), clientKey, c.Request, body)
+		existing, err := store.ClaimIdempotencyKey(key, fingerprint, time.Now().Add(window))
+		if err != nil {
+			// a failing store must not block the API, the request runs without protection
+			slog.ErrorContext(c, "failed to claim idempotency key", slog.String("error", err.Error()))
+			c.Next()
+			return
+		}
+		if existing != nil {
+			replayIdempotentResponse(c, existing, fingerprint, encryptionKey)
+			return
+		}
+
+		w := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		status := w.Status()
+		if status >= 500 || status == http.StatusTooManyRequests || w.overflow {
+			if err := store.ReleaseIdempotencyKey(key); err != nil {
+				slog.ErrorContext(c, "failed to release idempotency key", slog.String("error", err.Error()))
+			}
+			return
+		}
+		encrypted, err := encryptResponse(encryptionKey, w.body.Bytes())
+		if err == nil {
+			err = store.CompleteIdempotencyKey(key, status, w.Header().Get("Content-Type"), encrypted)
+		}
+		if err != nil {
+			slog.ErrorContext(c, "failed to store idempotent response", slog.String("error", err.Error()))
+			if err := store.ReleaseIdempotencyKey(key); err != nil {
+				slog.ErrorContext(c, "failed to release idempotency key", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, record *globalinfos.IdempotencyRecord, fingerprint string, encryptionKey []byte) {
+	if record.Fingerprint != fingerprint {
+		slog.WarnContext(c, "idempotency key reused for a different request", slog.String("route", c.FullPath()))
+		apihelpers.RespondError(c, http.StatusUnprocessableEntity, apihelpers.ERROR_CODE_IDEMPOTENCY_KEY_REUSED, "idempotency key was used for a different request")
+		return
+	}
+	if record.Status != globalinfos.IDEMPOTENCY_STATUS_COMPLETED {
+		apihelpers.RespondError(c, http.StatusConflict, apihelpers.ERROR_CODE_IDEMPOTENCY_IN_PROGRESS, "request with this idempotency key is in progress")
+		return
+	}
+	body, err := decryptResponse(encryptionKey, record.Body)
+	if err != nil {
+		slog.ErrorContext(c, "failed to decrypt idempotent response", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+	c.Header(HEADER_IDEMPOTENT_REPLAYED, "true")
+	c.Data(record.StatusCode, record.ContentType, body)
+	c.Abort()
+}
+
+// idempotencyScope separates the keys of routes and users, requests without token share the scope of the route
+func idempotencyScope(c *gin.Context) string {
+	scope := c.Request.Method + " " + c.FullPath()
+	if token, ok := c.Get("validatedToken"); ok {
+		if claims, ok := token.(*jwthandling.ParticipantUserClaims); ok {
+			scope += " " + claims.InstanceID + "/" + claims.Subject
+		}
+	}
+	return scope
+}
+
+// idempotencyHashes derives the stored key from the scope and the client key, and the fingerprint and the response
+// encryption key from the whole request. Only the first two are stored.
+func idempotencyHashes(scope string, clientKey string, req *http.Request, body []byte) (key string, fingerprint string, encryptionKey []byte) {
+	keyHash := sha256.Sum256([]byte("key\x00" + scope + "\x00" + clientKey))
+
+	request := sha256.New()
+	request.Write([]byte(scope + "\x00" + clientKey + "\x00" + req.URL.RequestURI() + "\x00"))
+	request.Write(body)
+	requestHash := request.Sum(nil)
+
+	fingerprintHash := sha256.Sum256(append([]byte("fingerprint\x00"), requestHash...))
+	encryptionHash := sha256.Sum256(append([]byte("response\x00"), requestHash...))
+	return hex.EncodeToString(keyHash[:]), hex.EncodeToString(fingerprintHash[:]), encryptionHash[:]
+}
+
+func encryptResponse(key []byte, body []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, body, nil), nil
+}
+
+func decryptResponse(key []byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("stored response too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// capturingWriter keeps a copy of the response body to store it
+type capturingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *capturingWriter) capture(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > maxReplayedBodySize {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	globalinfos "github.com/case-framework/case-backend/pkg/db/global-infos"
+	"github.com/gin-gonic/gin"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*globalinfos.IdempotencyRecord
+}
+
+func (s *memoryIdempotencyStore) ClaimIdempotencyKey(key string, fingerprint string, expiresAt time.Time) (*globalinfos.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.records[key]; ok {
+		copied := *r
+		return &copied, nil
+	}
+	s.records[key] = &globalinfos.IdempotencyRecord{Key: key, Fingerprint: fingerprint, Status: globalinfos.IDEMPOTENCY_STATUS_PENDING, ExpiresAt: expiresAt}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) CompleteIdempotencyKey(key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.records[key]
+	r.Status = globalinfos.IDEMPOTENCY_STATUS_COMPLETED
+	r.StatusCode = statusCode
+	r.ContentType = contentType
+	r.Body = body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) ReleaseIdempotencyKey(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{records: map[string]*globalinfos.IdempotencyRecord{}}
+	calls := 0
+	failNext := false
+	router := gin.New()
+	router.POST("/v1/submit", Idempotency(store, IdempotencyConfig{}), func(c *gin.Context) {
+		calls++
+		if failNext {
+			failNext = false
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			return
+		}
+		var body map[string]any
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	request := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/submit", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(HEADER_IDEMPOTENCY_KEY, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		resp := map[string]any{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		code, _ := resp["code"].(string)
+		return code
+	}
+
+	t.Run("retry is replayed", func(t *testing.T) {
+		first := request("key-1", `{"a":1}`)
+		retry := request("key-1", `{"a":1}`)
+		if first.Code != http.StatusOK || retry.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d, %d", first.Code, retry.Code)
+		}
+		if retry.Body.String() != first.Body.String() {
+			t.Errorf("unexpected replayed body %s, want %s", retry.Body.String(), first.Body.String())
+		}
+		if retry.Header().Get(HEADER_IDEMPOTENT_REPLAYED) != "true" || first.Header().Get(HEADER_IDEMPOTENT_REPLAYED) != "" {
+			t.Errorf("unexpected replayed headers")
+		}
+		if calls != 1 {
+			t.Errorf("handler called %d times", calls)
+		}
+	})
+
+	t.Run("stored body is encrypted", func(t *testing.T) {
+		for _, r := range store.records {
+			if strings.Contains(string(r.Body), "call") {
+				t.Errorf("response stored in plain text")
+			}
+		}
+	})
+
+	t.Run("key reused for different request", func(t *testing.T) {
+		w := request("key-1", `{"a":2}`)
+		if w.Code != http.StatusUnprocessableEntity || errorCode(w) != "idempotency.key_reused" {
+			t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("request in progress", func(t *testing.T) {
+		key, fingerprint := testIdempotencyHashes("key-2", `{"a":1}`)
+		if _, err := store.ClaimIdempotencyKey(key, fingerprint, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		w := request("key-2", `{"a":1}`)
+		if w.Code != http.StatusConflict || errorCode(w) != "idempotency.in_progress" {
+			t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("failed requests can be retried", func(t *testing.T) {
+		calls = 0
+		failNext = true
+		if w := request("key-3", `{"a":1}`); w.Code != http.StatusInternalServerError {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+		if w := request("key-3", `{"a":1}`); w.Code != http.StatusOK || w.Header().Get(HEADER_IDEMPOTENT_REPLAYED) != "" {
+			t.Errorf("unexpected response %d %v", w.Code, w.Header())
+		}
+		if calls != 2 {
+			t.Errorf("handler called %d times", calls)
+		}
+	})
+
+	t.Run("requests without key are not affected", func(t *testing.T) {
+		calls = 0
+		request("", `{"a":1}`)
+		request("", `{"a":1}`)
+		if calls != 2 {
+			t.Errorf("handler called %d times", calls)
+		}
+	})
+
+	t.Run("too long key", func(t *testing.T) {
+		w := request(strings.Repeat("k", maxIdempotencyKeyLength+1), `{"a":1}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("unexpected status %d", w.Code)
+		}
+	})
+}
+
+func testIdempotencyHashes(clientKey string, body string) (string, string) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/submit", nil)
+	key, fingerprint, _ := idempotencyHashes(http.MethodPost+" /v1/submit", clientKey, req, []byte(body))
+	return key, fingerprint
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/gin-gonic/gin"
+)
+
+// IsSuperAdmin only lets admins of the super admin instance pass, they can manage all instances. Service users are
+// never super admins. An empty superAdminInstanceID rejects all requests.
+func IsSuperAdmin(superAdminInstanceID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenValue, ok := c.Get("validatedToken")
+		if !ok {
+			slog.Warn("validatedToken not found in context")
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "validatedToken not found in context"})
+			return
+		}
+		parsedToken := tokenValue.(*jwthandling.ManagementUserClaims)
+
+		if superAdminInstanceID == "" || parsedToken.InstanceID != superAdminInstanceID || !parsedToken.IsAdmin || parsedToken.IsServiceUser {
+			slog.Warn("non super admin user tried to access super admin endpoint", slog.String("instanceID", parsedToken.InstanceID), slog.String("userID", parsedToken.Subject))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized access to super admin endpoint"})
+			return
+		}
+	}
+}
@@ -18,14 +18,23 @@ const (
 	HeaderAuthorization = "Authorization"
 	HeaderAPIKey        = "X-API-Key"
 	HeaderInstanceID    = "X-Instance-ID"
+
+	// the access token a request is authenticated with, set for personal access tokens of management users
+	ContextKeyAccessToken = "accessToken"
 )
 
-func ManagementAuthMiddleware(tokenSignKey string, allowedInstanceIds []string, muDB *mudb.ManagementUserDBService) gin.HandlerFunc {
+// ManagementAuthMiddleware validates the token or API key, isInstanceAllowed is called on each request, so that the
+// allowed instances can change at runtime
+func ManagementAuthMiddleware(tokenSignKey string, isInstanceAllowed func(instanceID string) bool, muDB *mudb.ManagementUserDBService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if isServiceUser(c) {
-			validateServiceUser(c, allowedInstanceIds, muDB)
+			if strings.HasPrefix(c.GetHeader(HeaderAPIKey), mudb.ACCESS_TOKEN_PREFIX) {
+				validateAccessToken(c, isInstanceAllowed, muDB)
+			} else {
+				validateServiceUser(c, isInstanceAllowed, muDB)
+			}
 		} else {
-			validateManagementUser(c, tokenSignKey, allowedInstanceIds)
+			validateManagementUser(c, tokenSignKey, isInstanceAllowed)
 		}
 	}
 }
@@ -39,12 +48,12 @@ func isServiceUser(c *gin.Context) bool {
 	return false
 }
 
-func validateServiceUser(c *gin.Context, allowedInstanceIds []string, muDB *mudb.ManagementUserDBService) {
+func validateServiceUser(c *gin.Context, isInstanceAllowed func(instanceID string) bool, muDB *mudb.ManagementUserDBService) {
 	slog.Debug("auth as service user")
 	apiKey := c.GetHeader(HeaderAPIKey)
 	instanceID := c.GetHeader(HeaderInstanceID)
 
-	if !isInstanceAllowed(instanceID, allowedInstanceIds) {
+	if !isInstanceAllowed(instanceID) {
 		slog.Warn("instanceID not allowed", slog.String("instanceID", instanceID), slog.String("path", c.Request.URL.Path))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instanceID not allowed"})
 		c.Abort()
@@ -79,7 +88,57 @@ func validateServiceUser(c *gin.Context, allowedInstanceIds []string, muDB *mudb
 
 }
 
-func validateManagementUser(c *gin.Context, tokenSignKey string, allowedInstanceIDs []string) {
+// validateAccessToken authenticates the request as the management user of the personal access token. The token never
+// has admin rights, its scopes are checked in addition to the permissions of the user.
+func validateAccessToken(c *gin.Context, isInstanceAllowed func(instanceID string) bool, muDB *mudb.ManagementUserDBService) {
+	slog.Debug("auth with access token")
+	instanceID := c.GetHeader(HeaderInstanceID)
+
+	if !isInstanceAllowed(instanceID) {
+		slog.Warn("instanceID not allowed", slog.String("instanceID", instanceID), slog.String("path", c.Request.URL.Path))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "instanceID not allowed"})
+		c.Abort()
+		return
+	}
+
+	accessToken, err := muDB.GetAccessToken(instanceID, c.GetHeader(HeaderAPIKey))
+	if err != nil {
+		slog.Warn("Attempted to use invalid or expired access token", slog.String("instanceID", instanceID), slog.String("path", c.Request.URL.Path))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access token"})
+		c.Abort()
+		return
+	}
+
+	// the user may have been deleted since the token was created
+	if _, err := muDB.GetUserByID(instanceID, accessToken.UserID); err != nil {
+		slog.Warn("Attempted to use access token of unknown user", slog.String("instanceID", instanceID), slog.String("userID", accessToken.UserID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access token"})
+		c.Abort()
+		return
+	}
+
+	parsedToken := &jwthandling.ManagementUserClaims{
+		InstanceID: instanceID,
+		IsAdmin:    false,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: accessToken.UserID,
+		},
+	}
+	c.Set("validatedToken", parsedToken)
+	c.Set(ContextKeyAccessToken, accessToken)
+	c.Next()
+}
+
+// RejectAccessTokens only allows sessions of management users, e.g. for managing the access tokens themselves
+func RejectAccessTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(ContextKeyAccessToken); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed with an access token"})
+		}
+	}
+}
+
+func validateManagementUser(c *gin.Context, tokenSignKey string, isInstanceAllowed func(instanceID string) bool) {
 	slog.Debug("auth as management user")
 	token, err := extractToken(c)
 	if err != nil {
@@ -99,7 +158,7 @@ func validateManagementUser(c *gin.Context, tokenSignKey string, allowedInstance
 	}
 
 	// Check if the instanceID is allowed
-	if !isInstanceAllowed(parsedToken.InstanceID, allowedInstanceIDs) {
+	if !isInstanceAllowed(parsedToken.InstanceID) {
 		slog.Warn("instanceID not allowed", slog.String("instanceID", parsedToken.InstanceID), slog.String("path", c.Request.URL.Path))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instanceID not allowed"})
 		c.Abort()
@@ -124,12 +183,3 @@ func extractToken(c *gin.Context) (string, error) {
 	}
 	return token, nil
 }
-
-func isInstanceAllowed(instanceID string, allowedInstanceIDs []string) bool {
-	for _, id := range allowedInstanceIDs {
-		if id == instanceID {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mudb "github.com/case-framework/case-backend/pkg/db/management-user"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRejectAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	request := func(withAccessToken bool) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/",
+			func(c *gin.Context) {
+				if withAccessToken {
+					c.Set(ContextKeyAccessToken, &mudb.AccessToken{UserID: "user1"})
+				}
+			},
+			RejectAccessTokens(),
+			func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			},
+		)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	t.Run("rejects access tokens", func(t *testing.T) {
+		if w := request(true); w.Code != http.StatusForbidden {
+			t.Errorf("unexpected status %d", w.Code)
+		}
+	})
+
+	t.Run("allows sessions", func(t *testing.T) {
+		if w := request(false); w.Code != http.StatusOK {
+			t.Errorf("unexpected status %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID uses the X-Request-ID header of the caller or generates a new ID, adds it to the request context and
+// returns it in the response. Handlers log with the gin context (e.g. slog.InfoContext(c, ...)) to include it, which
+// requires ContextWithFallback of the router.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.HEADER)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.HEADER, id)
+		c.Next()
+	}
+}
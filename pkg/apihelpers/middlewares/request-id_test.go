@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, requestid.FromContext(c))
+	})
+
+	request := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(requestid.HEADER, header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("uses the ID of the caller", func(t *testing.T) {
+		w := request("req-1")
+		if w.Body.String() != "req-1" || w.Header().Get(requestid.HEADER) != "req-1" {
+			t.Errorf("unexpected response %s %v", w.Body.String(), w.Header())
+		}
+	})
+
+	t.Run("replaces invalid IDs", func(t *testing.T) {
+		w := request("forged\"id")
+		id := w.Header().Get(requestid.HEADER)
+		if !requestid.Valid(id) || id == "forged\"id" || w.Body.String() != id {
+			t.Errorf("unexpected response %s %v", w.Body.String(), w.Header())
+		}
+	})
+}
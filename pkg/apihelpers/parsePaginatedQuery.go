@@ -2,6 +2,7 @@ package apihelpers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/url"
 	"strconv"
 	"strings"
@@ -87,9 +88,17 @@ type ResponseExportQuery struct {
 	UseShortKeys      bool
 	QuestionOptionSep string
 	Format            string
-	IncludeMeta       *surveyresponses.IncludeMeta
-	PaginationInfos   *PagenatedQuery
-	ExtraCtxCols      *[]string
+	ValueLabels       string
+	LabelLang         string
+	Pseudonymization  surveyresponses.PseudonymizationOptions
+	ParticipantInfo   []string
+	ParticipantFlags  []string
+	ValidationIssues  bool
+	// quarantined responses are excluded unless requested
+	IncludeQuarantined bool
+	IncludeMeta        *surveyresponses.IncludeMeta
+	PaginationInfos    *PagenatedQuery
+	ExtraCtxCols       *[]string
 }
 
 func ParseResponseExportQueryFromCtx(c *gin.Context) (*ResponseExportQuery, error) {
@@ -113,12 +122,65 @@ func ParseResponseExportQueryFromCtx(c *gin.Context) (*ResponseExportQuery, erro
 	questionOptionSep := c.DefaultQuery("questionOptionSep", "-")
 
 	format := c.DefaultQuery("format", "wide")
+
+	valueLabels := c.DefaultQuery("valueLabels", "")
+	if !surveyresponses.IsSupportedValueLabelMode(valueLabels) {
+		return nil, errors.New("unsupported valueLabels mode")
+	}
+	labelLang := c.DefaultQuery("labelLang", "")
+	if valueLabels != "" && labelLang == "" {
+		return nil, errors.New("labelLang is required for value labels")
+	}
+
+	pseudonymization := surveyresponses.PseudonymizationOptions{
+		ParticipantIDs:     c.DefaultQuery("participantIDs", ""),
+		Salt:               c.DefaultQuery("pseudonymizationSalt", ""),
+		TimestampPrecision: c.DefaultQuery("timestampPrecision", ""),
+	}
+	if redactColumns := c.DefaultQuery("redactColumns", ""); redactColumns != "" {
+		pseudonymization.RedactColumns = strings.Split(redactColumns, ",")
+	}
+	if err := pseudonymization.Validate(); err != nil {
+		return nil, err
+	}
+
+	participantInfo := []string{}
+	if fields := c.DefaultQuery("participantInfo", ""); fields != "" {
+		participantInfo = strings.Split(fields, ",")
+	}
+	for _, field := range participantInfo {
+		if !surveyresponses.IsSupportedParticipantInfoField(field) {
+			return nil, errors.New("unsupported participantInfo field")
+		}
+	}
+	participantFlags := []string{}
+	if flags := c.DefaultQuery("participantFlags", ""); flags != "" {
+		participantFlags = strings.Split(flags, ",")
+	}
+
+	validationIssues, err := strconv.ParseBool(c.DefaultQuery("validationIssues", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	includeQuarantined, err := strconv.ParseBool(c.DefaultQuery("includeQuarantined", "false"))
+	if err != nil {
+		return nil, err
+	}
+
 	q := &ResponseExportQuery{
-		SurveyKey:         surveyKey,
-		UseShortKeys:      useShortKeys,
-		QuestionOptionSep: questionOptionSep,
-		Format:            format,
-		PaginationInfos:   paginatedQuery,
+		SurveyKey:          surveyKey,
+		UseShortKeys:       useShortKeys,
+		QuestionOptionSep:  questionOptionSep,
+		Format:             format,
+		ValueLabels:        valueLabels,
+		LabelLang:          labelLang,
+		Pseudonymization:   pseudonymization,
+		ParticipantInfo:    participantInfo,
+		ParticipantFlags:   participantFlags,
+		ValidationIssues:   validationIssues,
+		IncludeQuarantined: includeQuarantined,
+		PaginationInfos:    paginatedQuery,
 	}
 
 	extraCtxColsQuery := c.DefaultQuery("extraContextColumns", "")
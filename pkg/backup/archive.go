@@ -0,0 +1,270 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Archive layout: magic, length prefixed JSON header (unencrypted), then the encrypted chunks of a gzip stream.
+// The gzip stream holds the entries, each one a length prefixed name followed by the raw BSON documents and a zero
+// length document. An empty name ends the archive.
+const archiveMagic = "CASEBKP1"
+
+// documents are limited to 16MB by MongoDB
+const maxDocumentSize = 16*1024*1024 + 16*1024
+
+// the header is small, larger values mean the file is no backup archive
+const maxHeaderSize = 64 * 1024
+
+var ErrNotAnArchive = errors.New("not a backup archive")
+
+// Header is stored unencrypted at the start of an archive, it identifies the instance and the key encryption key
+// needed to unwrap the data key of the archive
+type Header struct {
+	InstanceID string    `json:"instanceId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	KEKID      string    `json:"kekId"`
+	WrappedKey []byte    `json:"wrappedKey"`
+}
+
+// digest authenticates the unencrypted header, it is part of the additional data of every chunk
+func (h Header) digest() []byte {
+	hash := sha256.New()
+	for _, field := range [][]byte{
+		[]byte(h.InstanceID),
+		[]byte(h.CreatedAt.UTC().Format(time.RFC3339Nano)),
+		[]byte(h.KEKID),
+		h.WrappedKey,
+	} {
+		hash.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field))))
+		hash.Write(field)
+	}
+	return hash.Sum(nil)
+}
+
+// ArchiveWriter writes the documents of the backed up collections to an encrypted archive. Close must be called to
+// complete the archive.
+type ArchiveWriter struct {
+	Header Header
+
+	enc     *encryptWriter
+	gz      *gzip.Writer
+	inEntry bool
+}
+
+// NewArchiveWriter generates the data key of the archive, wraps it with the key provider of the instance and writes
+// the header
+func NewArchiveWriter(w io.Writer, instanceID string, keys fieldencryption.KeyProvider, createdAt time.Time) (*ArchiveWriter, error) {
+	if keys == nil {
+		return nil, errors.New("key provider is required")
+	}
+	dataKey, err := fieldencryption.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, kekID, err := keys.WrapKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := Header{
+		InstanceID: instanceID,
+		CreatedAt:  createdAt.UTC(),
+		KEKID:      kekID,
+		WrappedKey: wrappedKey,
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := append([]byte(archiveMagic), binary.BigEndian.AppendUint32(nil, uint32(len(headerJSON)))...)
+	if _, err := w.Write(append(prefix, headerJSON...)); err != nil {
+		return nil, err
+	}
+
+	enc := newEncryptWriter(w, dataKey, header.digest())
+	return &ArchiveWriter{
+		Header: header,
+		enc:    enc,
+		gz:     gzip.NewWriter(enc),
+	}, nil
+}
+
+// StartEntry ends the current entry and starts a new one, names are e.g. "studyDB/<studyKey>_surveyResponses"
+func (a *ArchiveWriter) StartEntry(name string) error {
+	if name == "" || len(name) > math.MaxUint16 {
+		return fmt.Errorf("invalid entry name: %q", name)
+	}
+	if err := a.endEntry(); err != nil {
+		return err
+	}
+	if err := a.writeName(name); err != nil {
+		return err
+	}
+	a.inEntry = true
+	return nil
+}
+
+// WriteDocument adds the document to the current entry
+func (a *ArchiveWriter) WriteDocument(doc bson.Raw) error {
+	if !a.inEntry {
+		return errors.New("no entry started")
+	}
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+	_, err := a.gz.Write(doc)
+	return err
+}
+
+func (a *ArchiveWriter) writeName(name string) error {
+	_, err := a.gz.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(name))), name...))
+	return err
+}
+
+func (a *ArchiveWriter) endEntry() error {
+	if !a.inEntry {
+		return nil
+	}
+	a.inEntry = false
+	_, err := a.gz.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// Close ends the archive and writes the final chunk, it does not close the underlying writer
+func (a *ArchiveWriter) Close() error {
+	if err := a.endEntry(); err != nil {
+		return err
+	}
+	if err := a.writeName(""); err != nil {
+		return err
+	}
+	if err := a.gz.Close(); err != nil {
+		return err
+	}
+	return a.enc.Close()
+}
+
+// ReadHeader reads the unencrypted header, e.g. to select the key provider of the instance
+func ReadHeader(r io.Reader) (Header, error) {
+	header := Header{}
+	prefix := make([]byte, len(archiveMagic)+4)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return header, ErrNotAnArchive
+	}
+	if string(prefix[:len(archiveMagic)]) != archiveMagic {
+		return header, ErrNotAnArchive
+	}
+	size := binary.BigEndian.Uint32(prefix[len(archiveMagic):])
+	if size > maxHeaderSize {
+		return header, ErrNotAnArchive
+	}
+	headerJSON := make([]byte, size)
+	if _, err := io.ReadFull(r, headerJSON); err != nil {
+		return header, ErrTruncatedArchive
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return header, fmt.Errorf("invalid header: %w", err)
+	}
+	return header, nil
+}
+
+// ArchiveReader reads the entries of an archive after its header
+type ArchiveReader struct {
+	r       *bufio.Reader
+	inEntry bool
+	ended   bool
+}
+
+// NewArchiveReader unwraps the data key of the header, r must be positioned after the header
+func NewArchiveReader(r io.Reader, header Header, keys fieldencryption.KeyProvider) (*ArchiveReader, error) {
+	if keys == nil {
+		return nil, errors.New("key provider is required")
+	}
+	dataKey, err := keys.UnwrapKey(header.WrappedKey, header.KEKID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unwrap data key: %w", err)
+	}
+	gz, err := gzip.NewReader(newDecryptReader(r, dataKey, header.digest()))
+	if err != nil {
+		return nil, err
+	}
+	return &ArchiveReader{r: bufio.NewReader(gz)}, nil
+}
+
+// NextEntry skips the rest of the current entry and returns the name of the next one, or io.EOF at the end
+func (a *ArchiveReader) NextEntry() (string, error) {
+	for a.inEntry {
+		if _, err := a.NextDocument(); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	if a.ended {
+		return "", io.EOF
+	}
+
+	var size uint16
+	if err := binary.Read(a.r, binary.BigEndian, &size); err != nil {
+		return "", unexpectedEnd(err)
+	}
+	if size == 0 {
+		a.ended = true
+		return "", io.EOF
+	}
+	name := make([]byte, size)
+	if _, err := io.ReadFull(a.r, name); err != nil {
+		return "", unexpectedEnd(err)
+	}
+	a.inEntry = true
+	return string(name), nil
+}
+
+// NextDocument returns the next document of the current entry, or io.EOF at the end of the entry
+func (a *ArchiveReader) NextDocument() (bson.Raw, error) {
+	if !a.inEntry {
+		return nil, io.EOF
+	}
+	lengthBytes := make([]byte, 4)
+	if _, err := io.ReadFull(a.r, lengthBytes); err != nil {
+		return nil, unexpectedEnd(err)
+	}
+	length := binary.LittleEndian.Uint32(lengthBytes)
+	if length == 0 {
+		a.inEntry = false
+		return nil, io.EOF
+	}
+	if length < 5 || length > maxDocumentSize {
+		return nil, fmt.Errorf("invalid document size %d", length)
+	}
+
+	doc := make([]byte, length)
+	copy(doc, lengthBytes)
+	if _, err := io.ReadFull(a.r, doc[4:]); err != nil {
+		return nil, unexpectedEnd(err)
+	}
+	if err := bson.Raw(doc).Validate(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func unexpectedEnd(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncatedArchive
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func testKeyProvider(t *testing.T, keyByte byte) fieldencryption.KeyProvider {
+	t.Helper()
+	keyfile := filepath.Join(t.TempDir(), "kek")
+	if err := os.WriteFile(keyfile, []byte(hex.EncodeToString(bytes.Repeat([]byte{keyByte}, 32))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := fieldencryption.NewLocalKeyfileProvider(keyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func writeTestArchive(t *testing.T, keys fieldencryption.KeyProvider, entries map[string][]bson.M, order []string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w, err := NewArchiveWriter(buf, "instance1", keys, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range order {
+		if err := w.StartEntry(name); err != nil {
+			t.Fatal(err)
+		}
+		for _, doc := range entries[name] {
+			raw, err := bson.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteDocument(raw); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func openTestArchive(t *testing.T, data []byte, keys fieldencryption.KeyProvider) (*ArchiveReader, error) {
+	t.Helper()
+	r := bytes.NewReader(data)
+	header, err := ReadHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.InstanceID != "instance1" {
+		t.Errorf("unexpected header %+v", header)
+	}
+	return NewArchiveReader(r, header, keys)
+}
+
+func TestArchive(t *testing.T) {
+	keys := testKeyProvider(t, 1)
+
+	// random payloads do not compress, so the documents span several chunks
+	large := []bson.M{}
+	for i := 0; i < 2000; i++ {
+		payload := make([]byte, 100)
+		if _, err := rand.Read(payload); err != nil {
+			t.Fatal(err)
+		}
+		large = append(large, bson.M{"_id": i, "payload": payload})
+	}
+	entries := map[string][]bson.M{
+		"participantUserDB/users":        large,
+		"studyDB/empty_reports":          {},
+		"studyDB/study1_surveyResponses": {{"_id": "r1", "key": "weekly"}},
+	}
+	order := []string{"participantUserDB/users", "studyDB/empty_reports", "studyDB/study1_surveyResponses"}
+	data := writeTestArchive(t, keys, entries, order)
+
+	t.Run("reads entries and documents", func(t *testing.T) {
+		r, err := openTestArchive(t, data, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range order {
+			entry, err := r.NextEntry()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry != name {
+				t.Fatalf("unexpected entry %s, expected %s", entry, name)
+			}
+			count := 0
+			for {
+				doc, err := r.NextDocument()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if doc.Lookup("_id").IsZero() {
+					t.Errorf("document without id in %s", name)
+				}
+				count++
+			}
+			if count != len(entries[name]) {
+				t.Errorf("entry %s: unexpected count %d", name, count)
+			}
+		}
+		if _, err := r.NextEntry(); err != io.EOF {
+			t.Errorf("expected end of archive, got %v", err)
+		}
+	})
+
+	t.Run("skips unread documents", func(t *testing.T) {
+		r, err := openTestArchive(t, data, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range order {
+			if _, err := r.NextEntry(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := r.NextEntry(); err != io.EOF {
+			t.Errorf("expected end of archive, got %v", err)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		if _, err := openTestArchive(t, data, testKeyProvider(t, 2)); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("modified header", func(t *testing.T) {
+		modifications := map[string]func(header *Header){
+			"instance":   func(header *Header) { header.InstanceID = "instance2" },
+			"created at": func(header *Header) { header.CreatedAt = header.CreatedAt.Add(-time.Hour) },
+		}
+		for name, modify := range modifications {
+			r := bytes.NewReader(data)
+			header, err := ReadHeader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			modify(&header)
+
+			archive, err := NewArchiveReader(r, header, keys)
+			for err == nil {
+				_, err = archive.NextEntry()
+			}
+			if err == io.EOF {
+				t.Errorf("%s: archive read with modified header", name)
+			}
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		// the compressed documents may fit into the first chunk, then the error is returned on open
+		r, err := openTestArchive(t, data[:len(data)-100], keys)
+		for err == nil {
+			_, err = r.NextEntry()
+		}
+		if !errors.Is(err, ErrTruncatedArchive) {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		if _, err := ReadHeader(strings.NewReader("PK\x03\x04 something else")); !errors.Is(err, ErrNotAnArchive) {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestExpiredArchives(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	names := []string{
+		ArchiveName("i1", now.AddDate(0, 0, -1)),
+		ArchiveName("i1", now.AddDate(0, 0, -3)),
+		ArchiveName("i1", now.AddDate(0, 0, -2)),
+		ArchiveName("i1", now.AddDate(0, 0, -40)),
+		"i1/notes.txt",
+	}
+
+	t.Run("keep last", func(t *testing.T) {
+		expired := ExpiredArchives(names, 2, 0, now)
+		if len(expired) != 2 || expired[0] != names[1] || expired[1] != names[3] {
+			t.Errorf("unexpected result %v", expired)
+		}
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		expired := ExpiredArchives(names, 0, 30*24*time.Hour, now)
+		if len(expired) != 1 || expired[0] != names[3] {
+			t.Errorf("unexpected result %v", expired)
+		}
+	})
+
+	t.Run("newest is kept", func(t *testing.T) {
+		expired := ExpiredArchives(names, 0, time.Hour, now)
+		if len(expired) != 3 {
+			t.Errorf("unexpected result %v", expired)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package backup
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+)
+
+// collections that can be selected for backups
+const (
+	COLLECTION_USERS        = "users"
+	COLLECTION_PARTICIPANTS = "participants"
+	COLLECTION_RESPONSES    = "responses"
+	COLLECTION_REPORTS      = "reports"
+)
+
+// entry names start with the DB the collection is restored to
+const (
+	ENTRY_PREFIX_PARTICIPANT_USER_DB = "participantUserDB/"
+	ENTRY_PREFIX_STUDY_DB            = "studyDB/"
+)
+
+const (
+	ARCHIVE_EXTENSION = ".cbk"
+
+	archiveTimeFormat = "20060102T150405Z"
+)
+
+var DefaultCollections = []string{COLLECTION_USERS, COLLECTION_RESPONSES, COLLECTION_REPORTS}
+
+type Config struct {
+	// collections included in the archives, defaults to users, responses and reports
+	Collections []string      `json:"collections" yaml:"collections"`
+	Storage     StorageConfig `json:"storage" yaml:"storage"`
+
+	// key encryption key of the instances without entry in instance_encryption
+	Encryption fieldencryption.Config `json:"encryption" yaml:"encryption"`
+	// key encryption keys by instance ID, so that the archives of one instance cannot be read with the key of another
+	InstanceEncryption map[string]fieldencryption.Config `json:"instance_encryption" yaml:"instance_encryption"`
+
+	// number of newest archives kept per instance, 0 keeps all
+	KeepLast int `json:"keep_last" yaml:"keep_last"`
+	// archives older than this are deleted, the newest archive is always kept. 0 disables the limit.
+	MaxAge time.Duration `json:"max_age" yaml:"max_age"`
+}
+
+// Validate checks the selected collections and that every instance has an encryption key configured
+func (c Config) Validate(instanceIDs []string) error {
+	for _, collection := range c.Collections {
+		switch collection {
+		case COLLECTION_USERS, COLLECTION_PARTICIPANTS, COLLECTION_RESPONSES, COLLECTION_REPORTS:
+		default:
+			return fmt.Errorf("unknown collection: %s", collection)
+		}
+	}
+	for _, instanceID := range instanceIDs {
+		if c.encryptionConfig(instanceID).Provider == "" {
+			return fmt.Errorf("no encryption configured for instance %s", instanceID)
+		}
+	}
+	if c.KeepLast < 0 || c.MaxAge < 0 {
+		return fmt.Errorf("keep_last and max_age must not be negative")
+	}
+	return nil
+}
+
+func (c Config) encryptionConfig(instanceID string) fieldencryption.Config {
+	if conf, ok := c.InstanceEncryption[instanceID]; ok {
+		return conf
+	}
+	return c.Encryption
+}
+
+// KeyProvider creates the key provider of the instance, archives are never written unencrypted
+func (c Config) KeyProvider(instanceID string) (fieldencryption.KeyProvider, error) {
+	provider, err := fieldencryption.NewKeyProvider(c.encryptionConfig(instanceID))
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("no encryption configured for instance %s", instanceID)
+	}
+	return provider, nil
+}
+
+// ArchiveName returns the storage name of an archive, archives of an instance sort by creation time
+func ArchiveName(instanceID string, createdAt time.Time) string {
+	return instanceID + "/backup_" + createdAt.UTC().Format(archiveTimeFormat) + ARCHIVE_EXTENSION
+}
+
+// archiveTime parses the creation time from the archive name
+func archiveTime(name string) (time.Time, bool) {
+	base := path.Base(name)
+	if !strings.HasPrefix(base, "backup_") || !strings.HasSuffix(base, ARCHIVE_EXTENSION) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(archiveTimeFormat, strings.TrimSuffix(strings.TrimPrefix(base, "backup_"), ARCHIVE_EXTENSION))
+	return t, err == nil
+}
+
+// ExpiredArchives selects the archives of one instance to delete: all but the keepLast newest ones and the ones
+// older than maxAge, always keeping the newest. Names that are no archives are ignored.
+func ExpiredArchives(names []string, keepLast int, maxAge time.Duration, now time.Time) []string {
+	type archive struct {
+		name      string
+		createdAt time.Time
+	}
+	archives := []archive{}
+	for _, name := range names {
+		if t, ok := archiveTime(name); ok {
+			archives = append(archives, archive{name: name, createdAt: t})
+		}
+	}
+	// newest first
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].createdAt.After(archives[j].createdAt)
+	})
+
+	expired := []string{}
+	for i, a := range archives {
+		if i == 0 {
+			continue
+		}
+		if (keepLast > 0 && i >= keepLast) || (maxAge > 0 && now.Sub(a.createdAt) > maxAge) {
+			expired = append(expired, a.name)
+		}
+	}
+	return expired
+}
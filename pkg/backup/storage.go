@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/study/exporter/delivery"
+)
+
+const (
+	STORAGE_TYPE_LOCAL = "local"
+	STORAGE_TYPE_S3    = "s3"
+)
+
+// Storage keeps the archives by name, names use "/" as separator
+type Storage interface {
+	// Upload stores the file under the name and returns where it was stored
+	Upload(ctx context.Context, filePath string, name string) (string, error)
+	// Open returns the content of the archive, the caller has to close it
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names starting with the prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type StorageConfig struct {
+	// one of STORAGE_TYPE_*
+	Type string `json:"type" yaml:"type"`
+	// directory of the archives for the local storage, e.g. a backups folder of the filestore
+	LocalDir string             `json:"local_dir" yaml:"local_dir"`
+	S3       *delivery.S3Config `json:"s3,omitempty" yaml:"s3,omitempty"`
+	Timeout  time.Duration      `json:"timeout" yaml:"timeout"`
+}
+
+func NewStorage(conf StorageConfig) (Storage, error) {
+	switch conf.Type {
+	case STORAGE_TYPE_LOCAL:
+		if conf.LocalDir == "" {
+			return nil, errors.New("local_dir is required")
+		}
+		return &localStorage{root: conf.LocalDir}, nil
+	case STORAGE_TYPE_S3:
+		if conf.S3 == nil {
+			return nil, errors.New("s3 config missing")
+		}
+		bucket, err := delivery.NewS3Bucket(*conf.S3, conf.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		return &s3Storage{bucket: bucket}, nil
+	}
+	return nil, fmt.Errorf("unsupported storage type: %s", conf.Type)
+}
+
+type localStorage struct {
+	root string
+}
+
+// path returns the file path of the name, rejecting names outside of the root
+func (s *localStorage) path(name string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid archive name: %q", name)
+	}
+	return filepath.Join(s.root, cleaned), nil
+}
+
+func (s *localStorage) Upload(_ context.Context, filePath string, name string) (string, error) {
+	target, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	// written next to the target and renamed, so that an interrupted upload never leaves a partial archive
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func (s *localStorage) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (s *localStorage) List(_ context.Context, prefix string) ([]string, error) {
+	names := []string{}
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+func (s *localStorage) Delete(_ context.Context, name string) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+type s3Storage struct {
+	bucket *delivery.S3Bucket
+}
+
+func (s *s3Storage) Upload(ctx context.Context, filePath string, name string) (string, error) {
+	return s.bucket.Deliver(ctx, filePath, name, "application/octet-stream")
+}
+
+func (s *s3Storage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.bucket.Open(ctx, name)
+}
+
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.bucket.List(ctx, prefix)
+}
+
+func (s *s3Storage) Delete(ctx context.Context, name string) error {
+	return s.bucket.Delete(ctx, name)
+}
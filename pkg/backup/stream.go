@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+)
+
+// plaintext bytes sealed per chunk, so that archives can be encrypted and decrypted without holding them in memory
+const chunkSize = 64 * 1024
+
+// upper bound of a sealed chunk, nonce and tag included, larger frames are rejected as corrupt
+const maxSealedChunkSize = chunkSize + 64
+
+var ErrTruncatedArchive = errors.New("archive is truncated")
+
+// chunkAdditionalData binds each chunk to the archive header and its position, so that chunks cannot be reordered,
+// dropped, appended or moved to an archive with another header
+func chunkAdditionalData(headerDigest []byte, index uint64, final bool) []byte {
+	ad := make([]byte, len(headerDigest)+9)
+	copy(ad, headerDigest)
+	binary.BigEndian.PutUint64(ad[len(headerDigest):], index)
+	if final {
+		ad[len(ad)-1] = 1
+	}
+	return ad
+}
+
+// encryptWriter seals the written data in length prefixed chunks, Close writes the final chunk
+type encryptWriter struct {
+	w            io.Writer
+	key          []byte
+	headerDigest []byte
+	buf          []byte
+	index        uint64
+}
+
+func newEncryptWriter(w io.Writer, key []byte, headerDigest []byte) *encryptWriter {
+	return &encryptWriter{w: w, key: key, headerDigest: headerDigest, buf: make([]byte, 0, chunkSize)}
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(e.buf) == chunkSize {
+			if err := e.writeChunk(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) writeChunk(final bool) error {
+	sealed, err := fieldencryption.Encrypt(e.key, e.buf, chunkAdditionalData(e.headerDigest, e.index, final))
+	if err != nil {
+		return err
+	}
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(sealed)), uint32(len(sealed)))
+	if _, err := e.w.Write(append(frame, sealed...)); err != nil {
+		return err
+	}
+	e.index++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// Close writes the remaining data as final chunk, it does not close the underlying writer
+func (e *encryptWriter) Close() error {
+	return e.writeChunk(true)
+}
+
+// decryptReader opens the chunks written by encryptWriter and fails if the final chunk is missing
+type decryptReader struct {
+	r            io.Reader
+	key          []byte
+	headerDigest []byte
+	buf          []byte
+	index        uint64
+	done         bool
+}
+
+func newDecryptReader(r io.Reader, key []byte, headerDigest []byte) *decryptReader {
+	return &decryptReader{r: r, key: key, headerDigest: headerDigest}
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) readChunk() error {
+	var size uint32
+	if err := binary.Read(d.r, binary.BigEndian, &size); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncatedArchive
+		}
+		return err
+	}
+	if size > maxSealedChunkSize {
+		return fmt.Errorf("invalid chunk size %d", size)
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncatedArchive
+		}
+		return err
+	}
+
+	// a chunk is either a regular one or the final one, try both positions
+	plain, err := fieldencryption.Decrypt(d.key, sealed, chunkAdditionalData(d.headerDigest, d.index, false))
+	if err != nil {
+		plain, err = fieldencryption.Decrypt(d.key, sealed, chunkAdditionalData(d.headerDigest, d.index, true))
+		if err != nil {
+			return fmt.Errorf("chunk %d cannot be decrypted: %w", d.index, err)
+		}
+		d.done = true
+	}
+	d.index++
+	d.buf = plain
+	return nil
+}
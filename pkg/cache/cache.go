@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	CACHE_TYPE_MEMORY = "memory"
+	CACHE_TYPE_REDIS  = "redis"
+)
+
+const (
+	defaultTTL        = 5 * time.Minute
+	defaultMaxEntries = 10000
+)
+
+// Cache stores serialized values by key. Errors of the backend are returned, so that callers can fall back to the
+// source of the value.
+type Cache interface {
+	// Get returns false if the key is not cached or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePrefix removes all keys starting with the prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Config of the cache in service config files. The in-memory cache is local to each process, writes of other
+// services (e.g. the management API) only reach it after the TTL, use Redis if changes must be visible immediately.
+type Config struct {
+	// memory or redis, empty to disable caching
+	Type       string        `json:"type" yaml:"type"`
+	TTL        time.Duration `json:"ttl" yaml:"ttl"`
+	MaxEntries int           `json:"max_entries" yaml:"max_entries"`
+	Redis      RedisConfig   `json:"redis" yaml:"redis"`
+}
+
+// New returns nil if caching is disabled
+func New(config Config) (Cache, time.Duration, error) {
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	switch config.Type {
+	case "":
+		return nil, ttl, nil
+	case CACHE_TYPE_MEMORY:
+		maxEntries := config.MaxEntries
+		if maxEntries <= 0 {
+			maxEntries = defaultMaxEntries
+		}
+		return NewLRU(maxEntries), ttl, nil
+	case CACHE_TYPE_REDIS:
+		c, err := NewRedis(config.Redis)
+		if err != nil {
+			return nil, ttl, err
+		}
+		return c, ttl, nil
+	default:
+		return nil, ttl, fmt.Errorf("unknown cache type %s", config.Type)
+	}
+}
+
+type envelope[T any] struct {
+	Value T `bson:"v"`
+}
+
+// GetOrLoad returns the cached value of the key or loads and caches it. Values are stored as BSON, so that each
+// caller gets its own copy. Cache errors are logged and the value is loaded from the source. A nil cache always
+// loads.
+func GetOrLoad[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func() (T, error)) (T, error) {
+	if c == nil {
+		return load()
+	}
+
+	data, found, err := c.Get(ctx, key)
+	if err != nil {
+		slog.Warn("failed to read from cache", slog.String("key", key), slog.String("error", err.Error()))
+	} else if found {
+		var cached envelope[T]
+		if err := bson.Unmarshal(data, &cached); err == nil {
+			return cached.Value, nil
+		}
+		slog.Warn("failed to decode cached value", slog.String("key", key))
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	data, err = bson.Marshal(envelope[T]{Value: value})
+	if err != nil {
+		slog.Warn("failed to encode value for cache", slog.String("key", key), slog.String("error", err.Error()))
+		return value, nil
+	}
+	if err := c.Set(ctx, key, data, ttl); err != nil {
+		slog.Warn("failed to write to cache", slog.String("key", key), slog.String("error", err.Error()))
+	}
+	return value, nil
+}
+
+// Invalidate removes the keys and all keys starting with the prefixes, errors are logged. Cached values expire
+// after the TTL if invalidation fails.
+func Invalidate(ctx context.Context, c Cache, keys []string, prefixes []string) {
+	if c == nil {
+		return
+	}
+	if len(keys) > 0 {
+		if err := c.Delete(ctx, keys...); err != nil {
+			slog.Error("failed to invalidate cache", slog.Any("keys", keys), slog.String("error", err.Error()))
+		}
+	}
+	for _, prefix := range prefixes {
+		if err := c.DeletePrefix(ctx, prefix); err != nil {
+			slog.Error("failed to invalidate cache", slog.String("prefix", prefix), slog.String("error", err.Error()))
+		}
+	}
+}
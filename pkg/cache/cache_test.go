@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testValue struct {
+	Name  string   `bson:"name"`
+	Items []string `bson:"items"`
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		c := NewLRU(2)
+		_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+		_ = c.Set(ctx, "b", []byte("2"), time.Minute)
+		if _, found, _ := c.Get(ctx, "a"); !found {
+			t.Fatal("a should be cached")
+		}
+		_ = c.Set(ctx, "c", []byte("3"), time.Minute)
+
+		if _, found, _ := c.Get(ctx, "b"); found {
+			t.Error("b should be evicted")
+		}
+		if _, found, _ := c.Get(ctx, "a"); !found {
+			t.Error("a should still be cached")
+		}
+		if c.Len() != 2 {
+			t.Errorf("unexpected size %d", c.Len())
+		}
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		c := NewLRU(10)
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+
+		now = now.Add(2 * time.Minute)
+		if _, found, _ := c.Get(ctx, "a"); found {
+			t.Error("a should be expired")
+		}
+		if c.Len() != 0 {
+			t.Errorf("expired entry not removed")
+		}
+	})
+
+	t.Run("delete prefix", func(t *testing.T) {
+		c := NewLRU(10)
+		_ = c.Set(ctx, "study:i1:s1", []byte("1"), time.Minute)
+		_ = c.Set(ctx, "study:i1:s2", []byte("2"), time.Minute)
+		_ = c.Set(ctx, "study:i2:s1", []byte("3"), time.Minute)
+		_ = c.DeletePrefix(ctx, "study:i1:")
+		if c.Len() != 1 {
+			t.Errorf("unexpected size %d", c.Len())
+		}
+	})
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	loads := 0
+	load := func() (*testValue, error) {
+		loads += 1
+		return &testValue{Name: "survey", Items: []string{"q1"}}, nil
+	}
+
+	first, err := GetOrLoad(ctx, c, "k", time.Minute, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GetOrLoad(ctx, c, "k", time.Minute, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loads != 1 {
+		t.Errorf("expected one load, got %d", loads)
+	}
+	if second.Name != "survey" || len(second.Items) != 1 {
+		t.Errorf("unexpected cached value: %+v", second)
+	}
+	// callers get their own copy
+	second.Items[0] = "changed"
+	if first.Items[0] != "q1" {
+		t.Error("cached value shared between callers")
+	}
+
+	t.Run("load errors are not cached", func(t *testing.T) {
+		_, err := GetOrLoad(ctx, c, "missing", time.Minute, func() (testValue, error) {
+			return testValue{}, errors.New("not found")
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+		if _, found, _ := c.Get(ctx, "missing"); found {
+			t.Error("error result cached")
+		}
+	})
+
+	t.Run("nil cache", func(t *testing.T) {
+		v, err := GetOrLoad(ctx, nil, "k", time.Minute, func() (string, error) { return "v", nil })
+		if err != nil || v != "v" {
+			t.Errorf("unexpected result %s %v", v, err)
+		}
+	})
+}
+
+// fakeRedis implements the commands used by the client on a map
+type fakeRedis struct {
+	mu       sync.Mutex
+	data     map[string][]byte
+	password string
+}
+
+func (f *fakeRedis) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				request, err := readRedisReply(reader)
+				if err != nil {
+					return
+				}
+				args := request.([]any)
+				if _, err := conn.Write(f.handle(args)); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (f *fakeRedis) handle(args []any) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	arg := func(i int) string { return string(args[i].([]byte)) }
+	switch strings.ToUpper(arg(0)) {
+	case "AUTH":
+		if arg(len(args)-1) != f.password {
+			return []byte("-WRONGPASS invalid password\r\n")
+		}
+		return []byte("+OK\r\n")
+	case "PING":
+		return []byte("+PONG\r\n")
+	case "SET":
+		f.data[arg(1)] = args[2].([]byte)
+		return []byte("+OK\r\n")
+	case "GET":
+		value, ok := f.data[arg(1)]
+		if !ok {
+			return []byte("$-1\r\n")
+		}
+		return encodeBulk(value)
+	case "DEL":
+		for i := 1; i < len(args); i++ {
+			delete(f.data, arg(i))
+		}
+		return []byte(":1\r\n")
+	case "SCAN":
+		prefix := strings.TrimSuffix(strings.ReplaceAll(arg(3), "\\", ""), "*")
+		keys := []byte{}
+		count := 0
+		for key := range f.data {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, encodeBulk([]byte(key))...)
+				count += 1
+			}
+		}
+		reply := append([]byte("*2\r\n"), encodeBulk([]byte("0"))...)
+		reply = append(reply, []byte("*"+strconv.Itoa(count)+"\r\n")...)
+		return append(reply, keys...)
+	default:
+		return []byte("-ERR unknown command\r\n")
+	}
+}
+
+func encodeBulk(value []byte) []byte {
+	return append([]byte("$"+strconv.Itoa(len(value))+"\r\n"), append(value, '\r', '\n')...)
+}
+
+func TestRedis(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+
+	server := &fakeRedis{data: map[string][]byte{}, password: "secret"}
+	go server.serve(listener)
+
+	if _, err := NewRedis(RedisConfig{Address: listener.Addr().String(), Password: "wrong"}); err == nil {
+		t.Fatal("expected authentication error")
+	}
+
+	c, err := NewRedis(RedisConfig{Address: listener.Addr().String(), Password: "secret", KeyPrefix: "case:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, found, err := c.Get(ctx, "a"); err != nil || found {
+		t.Fatalf("unexpected result for missing key: %v %v", found, err)
+	}
+	if err := c.Set(ctx, "study:i1:s1", []byte("value\r\nwith newline"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Set(ctx, "study:i1:s2", []byte("2"), time.Minute)
+	_ = c.Set(ctx, "study:i2:s1", []byte("3"), time.Minute)
+
+	value, found, err := c.Get(ctx, "study:i1:s1")
+	if err != nil || !found || string(value) != "value\r\nwith newline" {
+		t.Fatalf("unexpected value %q %v %v", value, found, err)
+	}
+	if _, ok := server.data["case:study:i1:s1"]; !ok {
+		t.Error("key prefix not applied")
+	}
+
+	if err := c.DeletePrefix(ctx, "study:i1:"); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.data) != 1 {
+		t.Errorf("unexpected keys after prefix delete: %v", server.data)
+	}
+	if err := c.Delete(ctx, "study:i2:s1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.data) != 0 {
+		t.Errorf("unexpected keys after delete: %v", server.data)
+	}
+}
@@ -0,0 +1,323 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRedisTimeout  = 2 * time.Second
+	defaultRedisPoolSize = 10
+	redisScanCount       = "500"
+)
+
+type RedisConfig struct {
+	Address  string `json:"address" yaml:"address"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	DB       int    `json:"db" yaml:"db"`
+	// prepended to all keys, so that several deployments can share a Redis server
+	KeyPrefix string        `json:"key_prefix" yaml:"key_prefix"`
+	TLS       bool          `json:"tls" yaml:"tls"`
+	Timeout   time.Duration `json:"timeout" yaml:"timeout"`
+	PoolSize  int           `json:"pool_size" yaml:"pool_size"`
+}
+
+// Redis is a cache client for a single Redis server, speaking the RESP protocol with a small connection pool
+type Redis struct {
+	config RedisConfig
+	pool   chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply of the server, the connection stays usable
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedis checks the connection with a PING
+func NewRedis(config RedisConfig) (*Redis, error) {
+	if config.Address == "" {
+		return nil, errors.New("redis address is not set")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultRedisTimeout
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultRedisPoolSize
+	}
+
+	c := &Redis{
+		config: config,
+		pool:   make(chan *redisConn, config.PoolSize),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	if _, err := c.do(ctx, "PING"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.do(ctx, "GET", c.config.KeyPrefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply to GET: %T", reply)
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.do(ctx, "SET", c.config.KeyPrefix+key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []any{"DEL"}
+	for _, key := range keys {
+		args = append(args, c.config.KeyPrefix+key)
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+// DeletePrefix scans for matching keys, which is slow on large databases, it is only meant for invalidation after
+// rare writes
+func (c *Redis) DeletePrefix(ctx context.Context, prefix string) error {
+	pattern := escapeRedisPattern(c.config.KeyPrefix+prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", redisScanCount)
+		if err != nil {
+			return err
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 2 {
+			return errors.New("redis: unexpected reply to SCAN")
+		}
+		next, _ := parts[0].([]byte)
+		keys, _ := parts[1].([]any)
+
+		if len(keys) > 0 {
+			args := []any{"DEL"}
+			args = append(args, keys...)
+			if _, err := c.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// Close closes the idle connections
+func (c *Redis) Close() {
+	for {
+		select {
+		case rc := <-c.pool:
+			rc.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (c *Redis) do(ctx context.Context, args ...any) (any, error) {
+	rc, err := c.getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := rc.do(ctx, c.config.Timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		rc.conn.Close()
+		return nil, err
+	}
+	c.putConn(rc)
+	return reply, err
+}
+
+func (c *Redis) getConn(ctx context.Context) (*redisConn, error) {
+	select {
+	case rc := <-c.pool:
+		return rc, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: c.config.Timeout}
+	var conn net.Conn
+	var err error
+	if c.config.TLS {
+		host, _, _ := net.SplitHostPort(c.config.Address)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}).DialContext(ctx, "tcp", c.config.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.config.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if c.config.Password != "" {
+		args := []any{"AUTH", c.config.Password}
+		if c.config.Username != "" {
+			args = []any{"AUTH", c.config.Username, c.config.Password}
+		}
+		if _, err := rc.do(ctx, c.config.Timeout, args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.config.DB != 0 {
+		if _, err := rc.do(ctx, c.config.Timeout, "SELECT", strconv.Itoa(c.config.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func (c *Redis) putConn(rc *redisConn) {
+	select {
+	case c.pool <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+func (rc *redisConn) do(ctx context.Context, timeout time.Duration, args ...any) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := rc.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := rc.conn.Write(encodeRedisCommand(args...)); err != nil {
+		return nil, err
+	}
+	return readRedisReply(rc.reader)
+}
+
+// encodeRedisCommand writes the arguments as an array of bulk strings
+func encodeRedisCommand(args ...any) []byte {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		var value []byte
+		switch v := arg.(type) {
+		case string:
+			value = []byte(v)
+		case []byte:
+			value = v
+		default:
+			value = []byte(fmt.Sprint(v))
+		}
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(value)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, value...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// readRedisReply returns string for simple strings, int64 for integers, []byte for bulk strings, []any for arrays
+// and nil for null replies. Error replies are returned as redisError.
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, errors.New("redis: invalid reply")
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := readRedisReply(r)
+			var replyErr redisError
+			if err != nil && !errors.As(err, &replyErr) {
+				return nil, err
+			}
+			// error items are kept, so that the rest of the array is read
+			if err != nil {
+				item = replyErr
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
+
+// escapeRedisPattern escapes the glob characters of a SCAN MATCH pattern
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,246 @@
+package configreload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Config struct {
+	// The config file is checked for changes in this interval, 0 to reload only on SIGHUP
+	WatchInterval time.Duration `json:"watch_interval" yaml:"watch_interval"`
+}
+
+// Section is a part of the config that can be swapped at runtime
+type Section[T any] struct {
+	// YAML path of the section, e.g. "gin_config.allow_origins"
+	Path string
+	// Apply is called with the new config if a field of the section changed
+	Apply func(conf *T)
+}
+
+// Reloader reads the config file again on SIGHUP or when the file changes, and applies the changed reloadable
+// sections. Changes of other fields take effect after a restart.
+type Reloader[T any] struct {
+	path     string
+	load     func(path string) (T, error)
+	sections []Section[T]
+
+	mu      sync.Mutex
+	current T
+	// checksum of the last read file content, to ignore touched but unchanged files
+	checksum [32]byte
+}
+
+// New creates the reloader for the config file read at startup. load has to read and validate the file the same way
+// as at startup, the current config is only replaced if it succeeds.
+func New[T any](path string, current T, load func(path string) (T, error), sections ...Section[T]) (*Reloader[T], error) {
+	for _, section := range sections {
+		if _, err := fieldByPath(reflect.ValueOf(current), section.Path); err != nil {
+			return nil, err
+		}
+	}
+	r := &Reloader[T]{
+		path:     path,
+		load:     load,
+		sections: sections,
+		current:  current,
+	}
+	if content, err := os.ReadFile(path); err == nil {
+		r.checksum = sha256.Sum256(content)
+	}
+	return r, nil
+}
+
+// Reload reads the config and applies the reloadable sections that changed, returns the changed field paths
+func (r *Reloader[T]) Reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+	next, err := r.load(r.path)
+	if err != nil {
+		return nil, err
+	}
+	r.checksum = sha256.Sum256(content)
+
+	changed := []string{}
+	for _, section := range r.sections {
+		oldValue, _ := fieldByPath(reflect.ValueOf(r.current), section.Path)
+		newValue, _ := fieldByPath(reflect.ValueOf(next), section.Path)
+		paths := ChangedFields(section.Path, oldValue.Interface(), newValue.Interface())
+		if len(paths) == 0 {
+			continue
+		}
+		section.Apply(&next)
+		changed = append(changed, paths...)
+	}
+	r.current = next
+	return changed, nil
+}
+
+// fileChanged compares the checksum of the file content with the last read one
+func (r *Reloader[T]) fileChanged() bool {
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return false
+	}
+	checksum := sha256.Sum256(content)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !bytes.Equal(checksum[:], r.checksum[:])
+}
+
+// Run reloads on SIGHUP and, if the interval is set, when the file content changed. Polling is used instead of file
+// system events, so that config maps mounted through replaced symlinks are picked up as well. It returns when the
+// context is done.
+func (r *Reloader[T]) Run(ctx context.Context, conf Config) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if conf.WatchInterval > 0 {
+		ticker := time.NewTicker(conf.WatchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			r.reloadAndLog("SIGHUP")
+		case <-tick:
+			if r.fileChanged() {
+				r.reloadAndLog("file change")
+			}
+		}
+	}
+}
+
+func (r *Reloader[T]) reloadAndLog(trigger string) {
+	changed, err := r.Reload()
+	if err != nil {
+		slog.Error("failed to reload config, keeping the current one", slog.String("trigger", trigger), slog.String("error", err.Error()))
+		return
+	}
+	if len(changed) == 0 {
+		slog.Info("config reloaded, no reloadable settings changed", slog.String("trigger", trigger))
+		return
+	}
+	// only the paths are logged, values may contain personal or secret data
+	slog.Info("config reloaded", slog.String("trigger", trigger), slog.Any("changed", changed))
+}
+
+// ChangedFields compares two values of the same type and returns the YAML paths of the differing fields below the
+// prefix. Maps are compared by key, slices as a whole.
+func ChangedFields(prefix string, oldValue any, newValue any) []string {
+	changed := []string{}
+	collectChanges(prefix, reflect.ValueOf(oldValue), reflect.ValueOf(newValue), &changed)
+	sort.Strings(changed)
+	return changed
+}
+
+func collectChanges(path string, a reflect.Value, b reflect.Value, changed *[]string) {
+	// missing map entries are invalid, interfaces may hold different types
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() {
+		if a.IsValid() || b.IsValid() {
+			*changed = append(*changed, path)
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*changed = append(*changed, path)
+			}
+			return
+		}
+		collectChanges(path, a.Elem(), b.Elem(), changed)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			collectChanges(joinPath(path, yamlName(field)), a.Field(i), b.Field(i), changed)
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, k := range a.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for name, k := range keys {
+			collectChanges(joinPath(path, name), a.MapIndex(k), b.MapIndex(k), changed)
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*changed = append(*changed, path)
+		}
+	}
+}
+
+// fieldByPath returns the struct field with the YAML path
+func fieldByPath(v reflect.Value, path string) (reflect.Value, error) {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v = reflect.New(v.Type().Elem())
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return v, fmt.Errorf("invalid config path %s: %s is no section", path, name)
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.IsExported() && yamlName(field) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return v, errors.New("unknown config path: " + path)
+		}
+	}
+	return v, nil
+}
+
+// yamlName returns the key of the field in the config file, yaml.v2 uses the lowercased field name if no tag is set
+func yamlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func joinPath(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
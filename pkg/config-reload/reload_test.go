@@ -0,0 +1,128 @@
+package configreload
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+type testConfig struct {
+	Logging struct {
+		LogLevel string `yaml:"log_level"`
+	} `yaml:"logging"`
+	GinConfig struct {
+		AllowOrigins []string `yaml:"allow_origins"`
+		Port         string   `yaml:"port"`
+	} `yaml:"gin_config"`
+	Constants map[string]string `yaml:"constants"`
+	NoTag     int
+}
+
+func loadTestConfig(path string) (testConfig, error) {
+	conf := testConfig{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return conf, err
+	}
+	err = yaml.UnmarshalStrict(content, &conf)
+	return conf, err
+}
+
+func writeTestConfig(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	a := testConfig{Constants: map[string]string{"a": "1", "b": "2"}}
+	a.GinConfig.AllowOrigins = []string{"https://a.example"}
+	b := testConfig{Constants: map[string]string{"a": "1", "c": "3"}, NoTag: 1}
+	b.GinConfig.AllowOrigins = []string{"https://b.example"}
+	b.Logging.LogLevel = "debug"
+
+	changed := ChangedFields("", a, b)
+	expected := []string{"constants.b", "constants.c", "gin_config.allow_origins", "logging.log_level", "notag"}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("unexpected changes %v", changed)
+	}
+
+	if changed := ChangedFields("x", a, a); len(changed) != 0 {
+		t.Errorf("unexpected changes %v", changed)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path, "logging:\n  log_level: info\ngin_config:\n  port: \"3000\"\n  allow_origins: [\"https://a.example\"]\n")
+	current, err := loadTestConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := map[string]int{}
+	r, err := New(path, current, loadTestConfig,
+		Section[testConfig]{Path: "logging.log_level", Apply: func(conf *testConfig) { applied[conf.Logging.LogLevel]++ }},
+		Section[testConfig]{Path: "gin_config.allow_origins", Apply: func(conf *testConfig) { applied["origins"]++ }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unknown section", func(t *testing.T) {
+		if _, err := New(path, current, loadTestConfig, Section[testConfig]{Path: "gin_config.missing"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("unchanged file", func(t *testing.T) {
+		if r.fileChanged() {
+			t.Error("file reported as changed")
+		}
+	})
+
+	t.Run("applies changed sections only", func(t *testing.T) {
+		// the port is not reloadable and not reported
+		writeTestConfig(t, path, "logging:\n  log_level: debug\ngin_config:\n  port: \"4000\"\n  allow_origins: [\"https://a.example\"]\n")
+		if !r.fileChanged() {
+			t.Error("change not detected")
+		}
+		changed, err := r.Reload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(changed, []string{"logging.log_level"}) || applied["debug"] != 1 || applied["origins"] != 0 {
+			t.Errorf("unexpected result %v %v", changed, applied)
+		}
+		if r.fileChanged() {
+			t.Error("file reported as changed after reload")
+		}
+	})
+
+	t.Run("invalid file keeps the current config", func(t *testing.T) {
+		writeTestConfig(t, path, "logging:\n  unknown_key: 1\n")
+		if _, err := r.Reload(); err == nil {
+			t.Fatal("expected error")
+		}
+		writeTestConfig(t, path, "logging:\n  log_level: debug\ngin_config:\n  allow_origins: [\"https://b.example\"]\n")
+		changed, err := r.Reload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(changed, []string{"gin_config.allow_origins"}) || applied["origins"] != 1 {
+			t.Errorf("unexpected result %v %v", changed, applied)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		os.Remove(path)
+		if _, err := r.Reload(); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package configvalidation
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Problem is an invalid config value with the YAML path of the field, or the environment variable it is set with
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// Problems collects all config problems, so that they are reported together at startup instead of one per restart
+type Problems struct {
+	list []Problem
+}
+
+// Error lists all problems of an invalid config
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "invalid config, %d problem(s):", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - " + p.String())
+	}
+	return b.String()
+}
+
+func (p *Problems) Add(path string, format string, args ...any) {
+	p.list = append(p.list, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check adds the error of an existing validation function
+func (p *Problems) Check(path string, err error) {
+	if err != nil {
+		p.Add(path, "%s", err.Error())
+	}
+}
+
+// Err returns nil if no problems were found, otherwise an *Error with all of them
+func (p *Problems) Err() error {
+	if len(p.list) == 0 {
+		return nil
+	}
+	return &Error{Problems: slices.Clone(p.list)}
+}
+
+func (p *Problems) List() []Problem {
+	return slices.Clone(p.list)
+}
+
+func (p *Problems) Required(path string, value string) {
+	if strings.TrimSpace(value) == "" {
+		p.Add(path, "is required")
+	}
+}
+
+// RequiredList checks that a list has at least one non-empty entry
+func (p *Problems) RequiredList(path string, values []string) {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return
+		}
+	}
+	p.Add(path, "at least one value is required")
+}
+
+// OneOf checks that the value is one of the allowed ones, empty values are not checked
+func (p *Problems) OneOf(path string, value string, allowed ...string) {
+	if value != "" && !slices.Contains(allowed, value) {
+		p.Add(path, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+func (p *Problems) NonNegative(path string, value int) {
+	if value < 0 {
+		p.Add(path, "must not be negative")
+	}
+}
+
+// Duration checks that a duration is not negative and, if set, within the range. A max of 0 means no upper limit.
+// Durations without unit are parsed as nanoseconds, so the minimum catches e.g. "ttl: 3600".
+func (p *Problems) Duration(path string, value time.Duration, min time.Duration, max time.Duration) {
+	switch {
+	case value < 0:
+		p.Add(path, "must not be negative")
+	case value == 0:
+	case value < min:
+		p.Add(path, "%s is shorter than %s, is the unit missing (e.g. 30s, 24h)?", value, min)
+	case max > 0 && value > max:
+		p.Add(path, "%s is longer than %s", value, max)
+	}
+}
+
+// RequiredDuration is Duration for values without default
+func (p *Problems) RequiredDuration(path string, value time.Duration, min time.Duration, max time.Duration) {
+	if value == 0 {
+		p.Add(path, "is required")
+		return
+	}
+	p.Duration(path, value, min, max)
+}
+
+// URL checks that the value is an absolute http(s) URL, empty values are not checked
+func (p *Problems) URL(path string, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.Add(path, "%q is no valid http(s) URL", value)
+	}
+}
+
+func (p *Problems) RequiredURL(path string, value string) {
+	p.Required(path, value)
+	p.URL(path, value)
+}
+
+// File checks that the file exists, empty values are not checked
+func (p *Problems) File(path string, value string) {
+	if value == "" {
+		return
+	}
+	info, err := os.Stat(value)
+	if err != nil {
+		p.Add(path, "file %s cannot be read: %v", value, err)
+	} else if info.IsDir() {
+		p.Add(path, "%s is a directory, expected a file", value)
+	}
+}
+
+// Dir checks that the directory exists, empty values are not checked
+func (p *Problems) Dir(path string, value string) {
+	if value == "" {
+		return
+	}
+	info, err := os.Stat(value)
+	if err != nil {
+		p.Add(path, "directory %s cannot be read: %v", value, err)
+	} else if !info.IsDir() {
+		p.Add(path, "%s is no directory", value)
+	}
+}
+
+// Exclusive checks that at most one of the options is set, options are given as path and whether it is set
+func (p *Problems) Exclusive(options ...Option) {
+	set := []string{}
+	for _, o := range options {
+		if o.Set {
+			set = append(set, o.Path)
+		}
+	}
+	if len(set) > 1 {
+		p.Add(set[0], "cannot be combined with %s", strings.Join(set[1:], ", "))
+	}
+}
+
+type Option struct {
+	Path string
+	Set  bool
+}
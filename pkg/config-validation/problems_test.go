@@ -0,0 +1,146 @@
+package configvalidation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/db"
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/study/exporter/delivery"
+)
+
+func problemPaths(p *Problems) []string {
+	paths := []string{}
+	for _, problem := range p.List() {
+		paths = append(paths, problem.Path)
+	}
+	return paths
+}
+
+func TestProblems(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Problems{}
+	if p.Err() != nil {
+		t.Fatal("expected no error without problems")
+	}
+
+	p.Required("ok.required", "value")
+	p.Required("required", " ")
+	p.RequiredList("list", []string{""})
+	p.OneOf("ok.one_of", "", "a")
+	p.OneOf("one_of", "c", "a", "b")
+	p.NonNegative("negative", -1)
+	p.Duration("ok.duration", 0, time.Second, 0)
+	p.Duration("duration_without_unit", 3600, time.Millisecond, 0)
+	p.Duration("duration_too_long", 2*time.Hour, time.Second, time.Hour)
+	p.RequiredDuration("duration_required", 0, time.Second, 0)
+	p.URL("ok.url", "https://example.com/path")
+	p.URL("url", "example.com")
+	p.RequiredURL("url_required", "")
+	p.File("ok.file", file)
+	p.File("file", dir)
+	p.Dir("ok.dir", dir)
+	p.Dir("dir", filepath.Join(dir, "missing"))
+	p.Exclusive(Option{Path: "ok.a", Set: true}, Option{Path: "ok.b"})
+	p.Exclusive(Option{Path: "a", Set: true}, Option{Path: "b", Set: true})
+	p.Check("ok.check", nil)
+	p.Check("check", errors.New("failed"))
+
+	expected := []string{
+		"required", "list", "one_of", "negative", "duration_without_unit", "duration_too_long", "duration_required",
+		"url", "url_required", "file", "dir", "a", "check",
+	}
+	if paths := problemPaths(&p); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("unexpected problems %v", paths)
+	}
+
+	err := p.Err()
+	var configErr *Error
+	if !errors.As(err, &configErr) || len(configErr.Problems) != len(expected) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(err.Error(), "13 problem(s)") || !strings.Contains(err.Error(), "\n  - check: failed") {
+		t.Errorf("unexpected message %s", err.Error())
+	}
+}
+
+func TestSections(t *testing.T) {
+	t.Run("db", func(t *testing.T) {
+		p := Problems{}
+		p.DB("db", db.DBConfigYaml{MaxPoolSize: 5, MinPoolSize: 10, ReadPreference: "primary", MaxStaleness: 30, Compressors: []string{"gzip"}})
+		expected := []string{"db.connection_str", "db.min_pool_size", "db.max_staleness", "db.max_staleness", "db.compressors"}
+		if paths := problemPaths(&p); !reflect.DeepEqual(paths, expected) {
+			t.Errorf("unexpected problems %v", paths)
+		}
+	})
+
+	t.Run("encryption", func(t *testing.T) {
+		p := Problems{}
+		p.Encryption("enc", fieldencryption.Config{
+			Provider:     fieldencryption.PROVIDER_VAULT_TRANSIT,
+			LocalKeyfile: "/keys/kek",
+			VaultTransit: fieldencryption.VaultTransitConfig{Address: "vault:8200", KeyName: "case"},
+		})
+		expected := []string{"enc.local_keyfile", "enc.vault_transit.address", "enc.vault_transit.token"}
+		if paths := problemPaths(&p); !reflect.DeepEqual(paths, expected) {
+			t.Errorf("unexpected problems %v", paths)
+		}
+	})
+
+	t.Run("messaging", func(t *testing.T) {
+		p := Problems{}
+		p.Messaging("messaging", messagingTypes.MessagingConfigs{
+			EmailProviders: []messagingTypes.EmailProviderConfig{
+				{Provider: "sendgrid", APIKey: "key", From: "study@example.com"},
+				{Provider: "mailgun", APIKey: "key"},
+			},
+			SMSConfig: &messagingTypes.SMSGatewayConfig{Provider: "twilio"},
+		})
+		expected := []string{"messaging.email_providers[1]", "messaging.sms_config"}
+		if paths := problemPaths(&p); !reflect.DeepEqual(paths, expected) {
+			t.Errorf("unexpected problems %v", paths)
+		}
+
+		p = Problems{}
+		p.Messaging("messaging", messagingTypes.MessagingConfigs{})
+		if paths := problemPaths(&p); !reflect.DeepEqual(paths, []string{"messaging.smtp_bridge_config.url"}) {
+			t.Errorf("smtp bridge required without providers, got %v", paths)
+		}
+	})
+
+	t.Run("destinations", func(t *testing.T) {
+		p := Problems{}
+		p.Destinations("destinations", []delivery.DestinationConfig{
+			{Name: "hook", Type: delivery.DESTINATION_TYPE_WEBHOOK, Webhook: &delivery.WebhookConfig{URL: "https://example.com/hook"}},
+			{Name: "hook", Type: delivery.DESTINATION_TYPE_WEBHOOK, Webhook: &delivery.WebhookConfig{URL: "https://example.com/hook"}, S3: &delivery.S3Config{}},
+		})
+		expected := []string{"destinations[1].name", "destinations[1].s3"}
+		if paths := problemPaths(&p); !reflect.DeepEqual(paths, expected) {
+			t.Errorf("unexpected problems %v", paths)
+		}
+	})
+}
+
+func TestAPIVersions(t *testing.T) {
+	p := Problems{}
+	p.APIVersions("api_versions", map[string]apihelpers.APIVersionLifecycle{
+		"v1": {DeprecatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), SunsetAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"v3": {Link: "docs/migration"},
+	}, []string{"v1", "v2"})
+	expected := []string{"api_versions.v1.sunset_at", "api_versions.v3", "api_versions.v3.link"}
+	if paths := problemPaths(&p); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("unexpected problems %v", paths)
+	}
+}
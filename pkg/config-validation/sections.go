@@ -0,0 +1,329 @@
+package configvalidation
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	"github.com/case-framework/case-backend/pkg/cache"
+	"github.com/case-framework/case-backend/pkg/db"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	smssending "github.com/case-framework/case-backend/pkg/messaging/sms-sending"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
+	"github.com/case-framework/case-backend/pkg/study/exporter/delivery"
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
+	"github.com/case-framework/case-backend/pkg/tracing"
+	"github.com/case-framework/case-backend/pkg/utils"
+)
+
+// Validation helpers for the config sections shared by the services and jobs
+
+// durations below are most likely values without unit, which are parsed as nanoseconds
+const minDuration = time.Millisecond
+
+func (p *Problems) Logging(path string, c utils.LoggerConfig) {
+	p.OneOf(path+".log_level", c.LogLevel, "debug", "info", "warn", "error")
+	p.OneOf(path+".include_build_info", c.IncludeBuildInfo, "never", "always", "once")
+	if c.LogToFile {
+		p.Required(path+".filename", c.Filename)
+	}
+	p.NonNegative(path+".max_size", c.MaxSize)
+	p.NonNegative(path+".max_age", c.MaxAge)
+	p.NonNegative(path+".max_backups", c.MaxBackups)
+}
+
+func (p *Problems) DB(path string, c db.DBConfigYaml) {
+	p.Required(path+".connection_str", c.ConnectionStr)
+	for name, value := range map[string]int{
+		"timeout":                  c.Timeout,
+		"idle_conn_timeout":        c.IdleConnTimeout,
+		"max_pool_size":            c.MaxPoolSize,
+		"min_pool_size":            c.MinPoolSize,
+		"max_connecting":           c.MaxConnecting,
+		"connect_timeout":          c.ConnectTimeout,
+		"server_selection_timeout": c.ServerSelectionTimeout,
+		"write_concern_timeout":    c.WriteConcernTimeout,
+		"slow_query_threshold_ms":  c.SlowQueryThresholdMs,
+	} {
+		p.NonNegative(path+"."+name, value)
+	}
+	if c.MaxPoolSize > 0 && c.MinPoolSize > c.MaxPoolSize {
+		p.Add(path+".min_pool_size", "must not be larger than max_pool_size")
+	}
+	p.OneOf(path+".read_preference", c.ReadPreference, "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest")
+	if c.MaxStaleness != 0 && c.MaxStaleness < 90 {
+		p.Add(path+".max_staleness", "must be at least 90 seconds")
+	}
+	if c.MaxStaleness != 0 && (c.ReadPreference == "" || c.ReadPreference == "primary") {
+		p.Add(path+".max_staleness", "cannot be used with the primary read preference")
+	}
+	for _, compressor := range c.Compressors {
+		p.OneOf(path+".compressors", compressor, "snappy", "zlib", "zstd")
+	}
+}
+
+func (p *Problems) Encryption(path string, c fieldencryption.Config) {
+	p.OneOf(path+".provider", c.Provider, fieldencryption.PROVIDER_LOCAL_KEYFILE, fieldencryption.PROVIDER_VAULT_TRANSIT)
+	p.Exclusive(
+		Option{Path: path + ".local_keyfile", Set: c.LocalKeyfile != ""},
+		Option{Path: path + ".vault_transit", Set: c.VaultTransit.Address != ""},
+	)
+	switch c.Provider {
+	case fieldencryption.PROVIDER_LOCAL_KEYFILE:
+		p.Required(path+".local_keyfile", c.LocalKeyfile)
+		p.File(path+".local_keyfile", c.LocalKeyfile)
+	case fieldencryption.PROVIDER_VAULT_TRANSIT:
+		p.RequiredURL(path+".vault_transit.address", c.VaultTransit.Address)
+		p.Required(path+".vault_transit.token", c.VaultTransit.Token)
+		p.Required(path+".vault_transit.key_name", c.VaultTransit.KeyName)
+		p.Duration(path+".vault_transit.timeout", c.VaultTransit.Timeout, minDuration, 0)
+	}
+}
+
+func (p *Problems) Cache(path string, c cache.Config) {
+	p.OneOf(path+".type", c.Type, cache.CACHE_TYPE_MEMORY, cache.CACHE_TYPE_REDIS)
+	p.Duration(path+".ttl", c.TTL, minDuration, 0)
+	p.NonNegative(path+".max_entries", c.MaxEntries)
+	if c.Type == cache.CACHE_TYPE_REDIS {
+		p.Required(path+".redis.address", c.Redis.Address)
+	}
+}
+
+func (p *Problems) Tracing(path string, c tracing.Config) {
+	if c.Enabled {
+		p.RequiredURL(path+".otlp_endpoint", c.OTLPEndpoint)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		p.Add(path+".sample_ratio", "must be between 0 and 1")
+	}
+	p.Duration(path+".export_interval", c.ExportInterval, minDuration, 0)
+	p.Duration(path+".export_timeout", c.ExportTimeout, minDuration, 0)
+}
+
+func (p *Problems) Secrets(path string, c secrets.Config) {
+	p.URL(path+".vault.address", c.Vault.Address)
+	p.URL(path+".aws.endpoint", c.AWS.Endpoint)
+	p.URL(path+".gcp.endpoint", c.GCP.Endpoint)
+	p.Duration(path+".timeout", c.Timeout, minDuration, 0)
+	p.Duration(path+".refresh_interval", c.RefreshInterval, 10*time.Second, 0)
+	if c.RestartOnRotation && c.RefreshInterval == 0 {
+		p.Add(path+".restart_on_rotation", "requires refresh_interval")
+	}
+}
+
+func (p *Problems) MTLS(path string, use bool, c apihelpers.CertificatePaths) {
+	if !use {
+		return
+	}
+	p.Required(path+".server_cert_path", c.ServerCertPath)
+	p.File(path+".server_cert_path", c.ServerCertPath)
+	p.Required(path+".server_key_path", c.ServerKeyPath)
+	p.File(path+".server_key_path", c.ServerKeyPath)
+	p.Required(path+".ca_cert_path", c.CACertPath)
+	p.File(path+".ca_cert_path", c.CACertPath)
+}
+
+// APIVersions checks that the lifecycles refer to served versions and the sunset is not before the deprecation
+func (p *Problems) APIVersions(path string, lifecycles map[string]apihelpers.APIVersionLifecycle, versions []string) {
+	names := make([]string, 0, len(lifecycles))
+	for name := range lifecycles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l := lifecycles[name]
+		if !slices.Contains(versions, name) {
+			p.Add(path+"."+name, "unknown API version, served versions are %v", versions)
+		}
+		if !l.SunsetAt.IsZero() && !l.DeprecatedAt.IsZero() && l.SunsetAt.Before(l.DeprecatedAt) {
+			p.Add(path+"."+name+".sunset_at", "must not be before deprecated_at")
+		}
+		p.URL(path+"."+name+".link", l.Link)
+	}
+}
+
+// BodyLimits checks that the limits are not negative and every route limit has a route and a size
+func (p *Problems) BodyLimits(path string, c middlewares.BodyLimitsConfig) {
+	if c.MaxBodySize < 0 {
+		p.Add(path+".max_body_size", "must not be negative")
+	}
+	p.NonNegative(path+".max_json_depth", c.MaxJSONDepth)
+	for i, r := range c.Routes {
+		routePath := path + ".routes[" + strconv.Itoa(i) + "]"
+		p.Required(routePath+".route", r.Route)
+		if r.MaxBodySize <= 0 {
+			p.Add(routePath+".max_body_size", "must be positive")
+		}
+	}
+}
+
+// Messaging checks the email and SMS providers, the smtp bridge is only needed if it is one of the email providers or
+// no providers are set
+func (p *Problems) Messaging(path string, c messagingTypes.MessagingConfigs) {
+	usesSmtpBridge := len(c.EmailProviders) == 0
+	for _, providers := range c.InstanceEmailProviders {
+		usesSmtpBridge = usesSmtpBridge || len(providers) == 0
+	}
+	p.emailProviderList(path+".email_providers", c.EmailProviders, &usesSmtpBridge)
+	for instanceID, providers := range c.InstanceEmailProviders {
+		p.emailProviderList(path+".instance_email_providers."+instanceID, providers, &usesSmtpBridge)
+	}
+	if usesSmtpBridge {
+		p.RequiredURL(path+".smtp_bridge_config.url", c.SmtpBridgeConfig.URL)
+	} else {
+		p.URL(path+".smtp_bridge_config.url", c.SmtpBridgeConfig.URL)
+	}
+	p.Duration(path+".smtp_bridge_config.request_timeout", c.SmtpBridgeConfig.RequestTimeout, minDuration, 0)
+
+	p.URL(path+".email_tracking.base_url", c.EmailTracking.BaseURL)
+	if len(c.EmailTracking.EnabledInstances) > 0 {
+		p.RequiredURL(path+".email_tracking.base_url", c.EmailTracking.BaseURL)
+		p.Required(path+".email_tracking.signing_key", c.EmailTracking.SigningKey)
+	}
+	p.NonNegative(path+".email_retry.max_attempts", c.EmailRetry.MaxAttempts)
+	p.Duration(path+".email_retry.initial_backoff", c.EmailRetry.InitialBackoff, minDuration, 0)
+	p.Duration(path+".email_retry.max_backoff", c.EmailRetry.MaxBackoff, minDuration, 0)
+	if c.EmailRetry.MaxBackoff > 0 && c.EmailRetry.InitialBackoff > c.EmailRetry.MaxBackoff {
+		p.Add(path+".email_retry.initial_backoff", "must not be longer than max_backoff")
+	}
+	p.OneOf(path+".digests.default_frequency", c.Digests.DefaultFrequency, "daily", "weekly")
+	p.Webhooks(path+".webhooks", c.Webhooks)
+	p.Dir(path+".email_attachments.filestore_path", c.EmailAttachments.FilestorePath)
+
+	if c.SMSConfig != nil {
+		p.SMSGateway(path+".sms_config", c.SMSConfig)
+	}
+	for instanceID, sms := range c.InstanceSMSConfigs {
+		if sms != nil {
+			p.SMSGateway(path+".instance_sms_configs."+instanceID, sms)
+		}
+	}
+}
+
+func (p *Problems) emailProviderList(path string, providers []messagingTypes.EmailProviderConfig, usesSmtpBridge *bool) {
+	for i, provider := range providers {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		p.URL(itemPath+".url", provider.URL)
+		p.Duration(itemPath+".timeout", provider.Timeout, minDuration, 0)
+		if provider.Provider == emailsending.EMAIL_PROVIDER_SMTP {
+			// the smtp provider connects to the servers when created
+			p.Required(itemPath+".smtp_servers_file", provider.SmtpServersFile)
+			p.File(itemPath+".smtp_servers_file", provider.SmtpServersFile)
+		} else {
+			// the constructors of the API providers only check their settings
+			_, err := emailsending.NewEmailProvider(provider)
+			p.Check(itemPath, err)
+		}
+		if provider.Provider == "" || provider.Provider == emailsending.EMAIL_PROVIDER_SMTP_BRIDGE {
+			*usesSmtpBridge = true
+		}
+	}
+}
+
+func (p *Problems) SMSGateway(path string, c *messagingTypes.SMSGatewayConfig) {
+	p.URL(path+".url", c.URL)
+	p.URL(path+".status_callback_url", c.StatusCallbackURL)
+	p.Duration(path+".timeout", c.Timeout, minDuration, 0)
+	_, err := smssending.NewSMSProvider(c)
+	p.Check(path, err)
+}
+
+func (p *Problems) ExternalServices(path string, services []studyengine.ExternalService) {
+	names := map[string]bool{}
+	for i, service := range services {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		p.Required(itemPath+".name", service.Name)
+		if names[service.Name] {
+			p.Add(itemPath+".name", "%s is used by several services", service.Name)
+		}
+		names[service.Name] = true
+		p.RequiredURL(itemPath+".url", service.URL)
+		p.NonNegative(itemPath+".timeout", service.Timeout)
+		p.NonNegative(itemPath+".maxRetries", service.MaxRetries)
+		p.NonNegative(itemPath+".retryBackoff", service.RetryBackoff)
+	}
+}
+
+func (p *Problems) Webhooks(path string, c messagingTypes.WebhookConfig) {
+	p.NonNegative(path+".max_attempts", c.MaxAttempts)
+	p.Duration(path+".initial_backoff", c.InitialBackoff, minDuration, 0)
+	p.Duration(path+".max_backoff", c.MaxBackoff, minDuration, 0)
+	p.Duration(path+".timeout", c.Timeout, minDuration, 0)
+	if c.MaxBackoff > 0 && c.InitialBackoff > c.MaxBackoff {
+		p.Add(path+".initial_backoff", "must not be longer than max_backoff")
+	}
+}
+
+func (p *Problems) SecurityEvents(path string, c securityevents.Config) {
+	p.NonNegative(path+".queue_size", c.QueueSize)
+	for i, sink := range c.Sinks {
+		itemPath := path + ".sinks[" + strconv.Itoa(i) + "]"
+		p.Required(itemPath+".type", sink.Type)
+		p.OneOf(itemPath+".type", sink.Type, securityevents.SINK_TYPE_FILE, securityevents.SINK_TYPE_SYSLOG, securityevents.SINK_TYPE_HTTP)
+		p.Duration(itemPath+".timeout", sink.Timeout, minDuration, 0)
+		switch sink.Type {
+		case securityevents.SINK_TYPE_FILE:
+			p.Required(itemPath+".filename", sink.Filename)
+		case securityevents.SINK_TYPE_SYSLOG:
+			p.Required(itemPath+".address", sink.Address)
+			p.OneOf(itemPath+".network", sink.Network, "udp", "tcp", "tcp+tls")
+		case securityevents.SINK_TYPE_HTTP:
+			p.RequiredURL(itemPath+".url", sink.URL)
+		}
+	}
+}
+
+func (p *Problems) DomainEvents(path string, c domainevents.Config) {
+	p.NonNegative(path+".queue_size", c.QueueSize)
+	for i, broker := range c.Brokers {
+		itemPath := path + ".brokers[" + strconv.Itoa(i) + "]"
+		p.Required(itemPath+".type", broker.Type)
+		p.OneOf(itemPath+".type", broker.Type, domainevents.BROKER_TYPE_NATS, domainevents.BROKER_TYPE_KAFKA_REST, domainevents.BROKER_TYPE_HTTP)
+		p.Duration(itemPath+".timeout", broker.Timeout, minDuration, 0)
+		for j, event := range broker.Events {
+			p.OneOf(itemPath+".events["+strconv.Itoa(j)+"]", event, domainevents.SupportedEvents...)
+		}
+		switch broker.Type {
+		case domainevents.BROKER_TYPE_NATS:
+			p.Required(itemPath+".address", broker.Address)
+		case domainevents.BROKER_TYPE_KAFKA_REST, domainevents.BROKER_TYPE_HTTP:
+			p.RequiredURL(itemPath+".url", broker.URL)
+		}
+	}
+}
+
+func (p *Problems) TaskRunner(path string, c taskrunner.Config) {
+	p.NonNegative(path+".concurrency", c.Concurrency)
+	p.NonNegative(path+".queue_size", c.QueueSize)
+	p.NonNegative(path+".max_attempts", c.MaxAttempts)
+	p.Duration(path+".retry_backoff", c.RetryBackoff, minDuration, 0)
+}
+
+// Destinations checks the export destinations, each one has exactly one destination type configured
+func (p *Problems) Destinations(path string, destinations []delivery.DestinationConfig) {
+	names := map[string]bool{}
+	for i, destination := range destinations {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		p.Required(itemPath+".name", destination.Name)
+		if names[destination.Name] {
+			p.Add(itemPath+".name", "%s is used by several destinations", destination.Name)
+		}
+		names[destination.Name] = true
+		p.Exclusive(
+			Option{Path: itemPath + ".sftp", Set: destination.SFTP != nil},
+			Option{Path: itemPath + ".s3", Set: destination.S3 != nil},
+			Option{Path: itemPath + ".webhook", Set: destination.Webhook != nil},
+		)
+		p.Duration(itemPath+".timeout", destination.Timeout, minDuration, 0)
+		_, err := delivery.NewDeliverer(destination)
+		p.Check(itemPath, err)
+	}
+}
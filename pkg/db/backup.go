@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// number of documents fetched per round trip when dumping a collection
+const dumpCursorBatchSize = 500
+
+var ErrMissingDocumentID = errors.New("document has no _id")
+
+// DumpCollection streams all documents of the collection to the handler, stopping at the first error. The raw
+// documents are only valid until the handler returns.
+func DumpCollection(ctx context.Context, collection *mongo.Collection, noCursorTimeout bool, handle func(doc bson.Raw) error) (int64, error) {
+	opts := options.Find().SetBatchSize(dumpCursorBatchSize).SetNoCursorTimeout(noCursorTimeout)
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var count int64
+	for cursor.Next(ctx) {
+		if err := handle(cursor.Current); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
+
+// RestoreModel replaces the document with the same _id or inserts it, so that restoring a dump twice does not
+// create duplicates
+func RestoreModel(doc bson.Raw) (mongo.WriteModel, error) {
+	id, err := doc.LookupErr("_id")
+	if err != nil {
+		return nil, ErrMissingDocumentID
+	}
+	return mongo.NewReplaceOneModel().
+		SetFilter(bson.D{{Key: "_id", Value: id}}).
+		SetReplacement(doc).
+		SetUpsert(true), nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestRestoreModel(t *testing.T) {
+	t.Run("upserts by id", func(t *testing.T) {
+		id := primitive.NewObjectID()
+		doc, err := bson.Marshal(bson.M{"_id": id, "key": "value"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		model, err := RestoreModel(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replace, ok := model.(*mongo.ReplaceOneModel)
+		if !ok {
+			t.Fatalf("unexpected model %T", model)
+		}
+		if replace.Upsert == nil || !*replace.Upsert {
+			t.Error("expected upsert")
+		}
+		filter := replace.Filter.(bson.D)
+		if filter[0].Value.(bson.RawValue).ObjectID() != id {
+			t.Errorf("unexpected filter %v", filter)
+		}
+	})
+
+	t.Run("missing id", func(t *testing.T) {
+		doc, err := bson.Marshal(bson.M{"key": "value"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RestoreModel(doc); !errors.Is(err, ErrMissingDocumentID) {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DEFAULT_BULK_WRITE_BATCH_SIZE is the number of operations sent in one BulkWrite request
+const DEFAULT_BULK_WRITE_BATCH_SIZE = 500
+
+// BulkWriteCollection is the part of *mongo.Collection used for bulk writes
+type BulkWriteCollection interface {
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+}
+
+// BulkWriteResult sums the results of all batches
+type BulkWriteResult struct {
+	InsertedCount int64 `json:"insertedCount"`
+	MatchedCount  int64 `json:"matchedCount"`
+	ModifiedCount int64 `json:"modifiedCount"`
+	DeletedCount  int64 `json:"deletedCount"`
+	UpsertedCount int64 `json:"upsertedCount"`
+}
+
+func (r *BulkWriteResult) add(res *mongo.BulkWriteResult) {
+	if res == nil {
+		return
+	}
+	r.InsertedCount += res.InsertedCount
+	r.MatchedCount += res.MatchedCount
+	r.ModifiedCount += res.ModifiedCount
+	r.DeletedCount += res.DeletedCount
+	r.UpsertedCount += res.UpsertedCount
+}
+
+// BulkWriter collects write operations and sends them in unordered batches, so that a failing operation does not
+// stop the others. Flush must be called after the last Add. A BulkWriter is not safe for concurrent use.
+type BulkWriter struct {
+	collection BulkWriteCollection
+	getContext func() (context.Context, context.CancelFunc)
+	batchSize  int
+
+	models []mongo.WriteModel
+	result BulkWriteResult
+}
+
+// NewBulkWriter uses DEFAULT_BULK_WRITE_BATCH_SIZE if batchSize is not positive. getContext is called for each
+// batch.
+func NewBulkWriter(collection BulkWriteCollection, getContext func() (context.Context, context.CancelFunc), batchSize int) *BulkWriter {
+	if batchSize <= 0 {
+		batchSize = DEFAULT_BULK_WRITE_BATCH_SIZE
+	}
+	return &BulkWriter{
+		collection: collection,
+		getContext: getContext,
+		batchSize:  batchSize,
+	}
+}
+
+// Add queues the operations and sends a batch once it is full
+func (w *BulkWriter) Add(models ...mongo.WriteModel) error {
+	var errs []error
+	for _, model := range models {
+		w.models = append(w.models, model)
+		if len(w.models) >= w.batchSize {
+			if err := w.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush sends the queued operations. They are not retried if the request fails.
+func (w *BulkWriter) Flush() error {
+	if len(w.models) == 0 {
+		return nil
+	}
+	models := w.models
+	w.models = nil
+
+	ctx, cancel := w.getContext()
+	defer cancel()
+
+	res, err := w.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	// the result holds the counts of the successful operations also if some failed
+	w.result.add(res)
+	return err
+}
+
+// Pending is the number of queued operations not sent yet
+func (w *BulkWriter) Pending() int {
+	return len(w.models)
+}
+
+// Result sums the results of the batches sent so far
+func (w *BulkWriter) Result() BulkWriteResult {
+	return w.result
+}
+
+// BulkWrite sends the operations in batches and returns the summed result with the errors of all batches
+func BulkWrite(collection BulkWriteCollection, getContext func() (context.Context, context.CancelFunc), models []mongo.WriteModel, batchSize int) (BulkWriteResult, error) {
+	w := NewBulkWriter(collection, getContext, batchSize)
+	err := w.Add(models...)
+	if flushErr := w.Flush(); flushErr != nil {
+		err = errors.Join(err, flushErr)
+	}
+	return w.Result(), err
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type fakeBulkCollection struct {
+	batches []int
+	failOn  int
+}
+
+func (f *fakeBulkCollection) BulkWrite(_ context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	f.batches = append(f.batches, len(models))
+	if len(f.batches) == f.failOn {
+		return &mongo.BulkWriteResult{ModifiedCount: int64(len(models) - 1)}, errors.New("write error")
+	}
+	return &mongo.BulkWriteResult{MatchedCount: int64(len(models)), ModifiedCount: int64(len(models))}, nil
+}
+
+func testContext() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+func updateModels(n int) []mongo.WriteModel {
+	models := make([]mongo.WriteModel, n)
+	for i := range models {
+		models[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"i": i}).SetUpdate(bson.M{"$set": bson.M{"x": 1}})
+	}
+	return models
+}
+
+func TestBulkWriter(t *testing.T) {
+	t.Run("sends full batches and the rest on flush", func(t *testing.T) {
+		coll := &fakeBulkCollection{}
+		w := NewBulkWriter(coll, testContext, 2)
+		if err := w.Add(updateModels(5)...); err != nil {
+			t.Fatal(err)
+		}
+		if w.Pending() != 1 {
+			t.Errorf("unexpected pending count %d", w.Pending())
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if len(coll.batches) != 3 || coll.batches[2] != 1 {
+			t.Errorf("unexpected batches %v", coll.batches)
+		}
+		if w.Result().ModifiedCount != 5 {
+			t.Errorf("unexpected result %+v", w.Result())
+		}
+		// nothing left to send
+		if err := w.Flush(); err != nil || len(coll.batches) != 3 {
+			t.Errorf("empty flush sent a batch")
+		}
+	})
+
+	t.Run("continues after failed batch", func(t *testing.T) {
+		coll := &fakeBulkCollection{failOn: 1}
+		res, err := BulkWrite(coll, testContext, updateModels(4), 2)
+		if err == nil {
+			t.Error("expected error")
+		}
+		if len(coll.batches) != 2 {
+			t.Errorf("unexpected batches %v", coll.batches)
+		}
+		if res.ModifiedCount != 3 {
+			t.Errorf("partial result not counted: %+v", res)
+		}
+	})
+
+	t.Run("default batch size", func(t *testing.T) {
+		w := NewBulkWriter(&fakeBulkCollection{}, testContext, 0)
+		if w.batchSize != DEFAULT_BULK_WRITE_BATCH_SIZE {
+			t.Errorf("unexpected batch size %d", w.batchSize)
+		}
+	})
+}
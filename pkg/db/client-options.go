@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+var supportedCompressors = []string{"snappy", "zlib", "zstd"}
+
+// ClientOptions builds the MongoDB client options of a DB service. Options that are not set keep the driver
+// defaults or the values of the connection string.
+func ClientOptions(configs DBConfig) (*options.ClientOptions, error) {
+	opts := options.Client().
+		ApplyURI(configs.URI).
+		SetMaxConnIdleTime(time.Duration(configs.IdleConnTimeout) * time.Second).
+		SetMaxPoolSize(configs.MaxPoolSize).
+		SetMonitor(NewCommandMonitor(DefaultOperationMetrics, time.Duration(configs.SlowQueryThresholdMs)*time.Millisecond)).
+		SetPoolMonitor(NewPoolMonitor(DefaultPoolMetrics))
+
+	if configs.MinPoolSize > 0 {
+		if configs.MaxPoolSize > 0 && configs.MinPoolSize > configs.MaxPoolSize {
+			return nil, fmt.Errorf("min pool size %d is larger than max pool size %d", configs.MinPoolSize, configs.MaxPoolSize)
+		}
+		opts.SetMinPoolSize(configs.MinPoolSize)
+	}
+	if configs.MaxConnecting > 0 {
+		opts.SetMaxConnecting(configs.MaxConnecting)
+	}
+	if configs.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(time.Duration(configs.ConnectTimeout) * time.Second)
+	}
+	if configs.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(time.Duration(configs.ServerSelectionTimeout) * time.Second)
+	}
+
+	if configs.ReadPreference != "" {
+		rp, err := readPreference(configs.ReadPreference, configs.MaxStaleness)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetReadPreference(rp)
+	}
+
+	if configs.WriteConcern != "" {
+		wc, err := writeConcern(configs.WriteConcern, configs.WriteConcernJournal, configs.WriteConcernTimeout)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetWriteConcern(wc)
+	}
+
+	if len(configs.Compressors) > 0 {
+		for _, c := range configs.Compressors {
+			if !slices.Contains(supportedCompressors, c) {
+				return nil, fmt.Errorf("unsupported compressor %s", c)
+			}
+		}
+		opts.SetCompressors(configs.Compressors)
+	}
+
+	return opts, opts.Validate()
+}
+
+// readPreference accepts the modes of the connection string option, e.g. secondaryPreferred. Max staleness in
+// seconds is only used for modes other than primary.
+func readPreference(mode string, maxStaleness int) (*readpref.ReadPref, error) {
+	m, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, err
+	}
+	if maxStaleness > 0 && m != readpref.PrimaryMode {
+		return readpref.New(m, readpref.WithMaxStaleness(time.Duration(maxStaleness)*time.Second))
+	}
+	return readpref.New(m)
+}
+
+// writeConcern accepts "majority", a number of nodes or the name of a custom write concern
+func writeConcern(w string, journal bool, timeout int) (*writeconcern.WriteConcern, error) {
+	wc := &writeconcern.WriteConcern{W: w}
+	if n, err := strconv.Atoi(w); err == nil {
+		if n < 0 {
+			return nil, fmt.Errorf("invalid write concern %s", w)
+		}
+		wc.W = n
+	}
+	if journal {
+		wc.Journal = &journal
+	}
+	if timeout > 0 {
+		wc.WTimeout = time.Duration(timeout) * time.Second
+	}
+	return wc, nil
+}
+
+// Ping checks that the primary of the deployment is reachable, used by the health checks of the DB services
+func Ping(ctx context.Context, client *mongo.Client) error {
+	if client == nil {
+		return errors.New("not connected")
+	}
+	return client.Ping(ctx, readpref.Primary())
+}
+
+// Disconnect closes the connections of the client, a nil client is ignored
+func Disconnect(ctx context.Context, client *mongo.Client) error {
+	if client == nil {
+		return nil
+	}
+	return client.Disconnect(ctx)
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestClientOptions(t *testing.T) {
+	base := DBConfig{URI: "mongodb://localhost:27017", MaxPoolSize: 50}
+
+	t.Run("defaults", func(t *testing.T) {
+		opts, err := ClientOptions(base)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.ReadPreference != nil || opts.WriteConcern != nil || opts.MinPoolSize != nil {
+			t.Errorf("expected driver defaults, got %+v", opts)
+		}
+	})
+
+	t.Run("all options", func(t *testing.T) {
+		configs := base
+		configs.MinPoolSize = 5
+		configs.ReadPreference = "secondaryPreferred"
+		configs.MaxStaleness = 120
+		configs.WriteConcern = "2"
+		configs.WriteConcernJournal = true
+		configs.WriteConcernTimeout = 5
+		configs.Compressors = []string{"zstd", "snappy"}
+
+		opts, err := ClientOptions(configs)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.ReadPreference.Mode() != readpref.SecondaryPreferredMode {
+			t.Errorf("unexpected read preference: %v", opts.ReadPreference)
+		}
+		if staleness, ok := opts.ReadPreference.MaxStaleness(); !ok || staleness != 120*time.Second {
+			t.Errorf("unexpected max staleness: %v", staleness)
+		}
+		if opts.WriteConcern.W != 2 || opts.WriteConcern.Journal == nil || !*opts.WriteConcern.Journal || opts.WriteConcern.WTimeout != 5*time.Second {
+			t.Errorf("unexpected write concern: %+v", opts.WriteConcern)
+		}
+		if *opts.MinPoolSize != 5 || len(opts.Compressors) != 2 {
+			t.Errorf("unexpected options: %+v", opts)
+		}
+	})
+
+	t.Run("majority write concern", func(t *testing.T) {
+		configs := base
+		configs.WriteConcern = "majority"
+		opts, err := ClientOptions(configs)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.WriteConcern.W != "majority" {
+			t.Errorf("unexpected write concern: %+v", opts.WriteConcern)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		tests := map[string]func(c *DBConfig){
+			"read preference": func(c *DBConfig) { c.ReadPreference = "secondaries" },
+			"min pool size":   func(c *DBConfig) { c.MinPoolSize = 100 },
+			"compressor":      func(c *DBConfig) { c.Compressors = []string{"gzip"} },
+			"write concern":   func(c *DBConfig) { c.WriteConcern = "-1" },
+		}
+		for name, modify := range tests {
+			configs := base
+			modify(&configs)
+			if _, err := ClientOptions(configs); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+func TestPingWithoutClient(t *testing.T) {
+	if err := Ping(context.Background(), nil); err == nil {
+		t.Error("expected error without client")
+	}
+}
@@ -7,12 +7,16 @@ import (
 
 	"github.com/case-framework/case-backend/pkg/db"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // collection names
 const (
-	COLLECTION_NAME_TEMPTOKENS = "temp-tokens"
+	COLLECTION_NAME_TEMPTOKENS       = "temp-tokens"
+	COLLECTION_NAME_INSTANCES        = "instances"
+	COLLECTION_NAME_JOB_LOCKS        = "job-locks"
+	COLLECTION_NAME_JOB_RUNS         = "job-runs"
+	COLLECTION_NAME_JOB_CHECKPOINTS  = "job-checkpoints"
+	COLLECTION_NAME_IDEMPOTENCY_KEYS = "idempotency-keys"
 )
 
 type GlobalInfosDBService struct {
@@ -27,11 +31,12 @@ func NewGlobalInfosDBService(configs db.DBConfig) (*GlobalInfosDBService, error)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(configs.Timeout)*time.Second)
 	defer cancel()
 
-	dbClient, err := mongo.Connect(ctx,
-		options.Client().ApplyURI(configs.URI),
-		options.Client().SetMaxConnIdleTime(time.Duration(configs.IdleConnTimeout)*time.Second),
-		options.Client().SetMaxPoolSize(configs.MaxPoolSize),
-	)
+	clientOptions, err := db.ClientOptions(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	dbClient, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
 		return nil, err
@@ -53,6 +58,11 @@ func NewGlobalInfosDBService(configs db.DBConfig) (*GlobalInfosDBService, error)
 		InstanceIDs:     configs.InstanceIDs,
 	}
 
+	// migrations run first, so that indexes are created on migrated data
+	if configs.RunMigrations {
+		giDBSc.applyMigrations()
+	}
+
 	if configs.RunIndexCreation {
 		giDBSc.ensureIndexes()
 	}
@@ -63,6 +73,22 @@ func (dbService *GlobalInfosDBService) getDBName() string {
 	return dbService.DBNamePrefix + "global-infos"
 }
 
+// Ping checks the connection to the global infos DB, it can be called on a nil service
+func (dbService *GlobalInfosDBService) Ping(ctx context.Context) error {
+	if dbService == nil {
+		return db.Ping(ctx, nil)
+	}
+	return db.Ping(ctx, dbService.DBClient)
+}
+
+// Close disconnects the client, called on shutdown after running requests are finished
+func (dbService *GlobalInfosDBService) Close(ctx context.Context) error {
+	if dbService == nil {
+		return nil
+	}
+	return db.Disconnect(ctx, dbService.DBClient)
+}
+
 func (dbService *GlobalInfosDBService) getContext() (ctx context.Context, cancel context.CancelFunc) {
 	return context.WithTimeout(context.Background(), time.Duration(dbService.timeout)*time.Second)
 }
@@ -71,12 +97,27 @@ func (dbService *GlobalInfosDBService) collectionTemptokens() *mongo.Collection
 	return dbService.DBClient.Database(dbService.getDBName()).Collection(COLLECTION_NAME_TEMPTOKENS)
 }
 
-func (dbService *GlobalInfosDBService) ensureIndexes() {
-	slog.Debug("Ensuring indexes for global infos DB")
+func (dbService *GlobalInfosDBService) collectionInstances() *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName()).Collection(COLLECTION_NAME_INSTANCES)
+}
 
-	err := dbService.CreateIndexForTemptokens()
-	if err != nil {
-		slog.Debug("Error creating indexes for temp tokens: ", slog.String("error", err.Error()))
+// indexRegistry lists the indexes of all collections of the DB
+func indexRegistry() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		temptokenIndexes(),
+		instanceIndexes(),
+		jobRunIndexes(),
+		jobCheckpointIndexes(),
+		idempotencyKeyIndexes(),
 	}
+}
+
+// EnsureIndexes creates missing indexes and reports indexes that are not in the registry
+func (dbService *GlobalInfosDBService) EnsureIndexes(opts db.EnsureIndexesOptions) db.IndexReport {
+	return db.EnsureIndexes(dbService.getContext, dbService.DBClient.Database(dbService.getDBName()), indexRegistry(), opts)
+}
 
+func (dbService *GlobalInfosDBService) ensureIndexes() {
+	slog.Debug("Ensuring indexes for global infos DB")
+	dbService.EnsureIndexes(db.EnsureIndexesOptions{}).Log()
 }
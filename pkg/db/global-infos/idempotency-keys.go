@@ -0,0 +1,120 @@
+package globalinfos
+
+import (
+	"errors"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	IDEMPOTENCY_STATUS_PENDING   = "pending"
+	IDEMPOTENCY_STATUS_COMPLETED = "completed"
+)
+
+// IdempotencyRecord stores the response of a request sent with an Idempotency-Key header, to replay it for retries
+type IdempotencyRecord struct {
+	// hash of the scope and the key sent by the client
+	Key string `bson:"key" json:"key"`
+	// hash of the request, a retry with the same key must send the same request
+	Fingerprint string `bson:"fingerprint" json:"fingerprint"`
+	Status      string `bson:"status" json:"status"`
+
+	StatusCode  int    `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	ContentType string `bson:"contentType,omitempty" json:"contentType,omitempty"`
+	// encrypted response body, only the retried request can decrypt it
+	Body []byte `bson:"body,omitempty" json:"-"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+func (dbService *GlobalInfosDBService) collectionIdempotencyKeys() *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName()).Collection(COLLECTION_NAME_IDEMPOTENCY_KEYS)
+}
+
+func idempotencyKeyIndexes() db.CollectionIndexes {
+	return db.CollectionIndexes{
+		Collection: COLLECTION_NAME_IDEMPOTENCY_KEYS,
+		Indexes: []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "key", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+	}
+}
+
+// ClaimIdempotencyKey stores a pending record for the key. If the key is already used, the existing record is
+// returned instead. Expired records may be returned until the TTL monitor removes them.
+func (dbService *GlobalInfosDBService) ClaimIdempotencyKey(key string, fingerprint string, expiresAt time.Time) (*IdempotencyRecord, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	record := IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      IDEMPOTENCY_STATUS_PENDING,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
+	}
+	_, err := dbService.collectionIdempotencyKeys().InsertOne(ctx, record)
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+
+	var existing IdempotencyRecord
+	err = dbService.collectionIdempotencyKeys().FindOne(ctx, bson.M{"key": key}).Decode(&existing)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// removed in between, the client can retry
+		return nil, errors.New("idempotency key changed concurrently")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing.ExpiresAt.Before(time.Now()) {
+		// expired but not yet removed, replace it with the new claim
+		res, err := dbService.collectionIdempotencyKeys().ReplaceOne(ctx, bson.M{"key": key, "expiresAt": existing.ExpiresAt}, record)
+		if err != nil {
+			return nil, err
+		}
+		if res.MatchedCount == 1 {
+			return nil, nil
+		}
+		return nil, errors.New("idempotency key changed concurrently")
+	}
+	return &existing, nil
+}
+
+// CompleteIdempotencyKey stores the response of the claimed key
+func (dbService *GlobalInfosDBService) CompleteIdempotencyKey(key string, statusCode int, contentType string, body []byte) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionIdempotencyKeys().UpdateOne(ctx, bson.M{"key": key}, bson.M{"$set": bson.M{
+		"status":      IDEMPOTENCY_STATUS_COMPLETED,
+		"statusCode":  statusCode,
+		"contentType": contentType,
+		"body":        body,
+	}})
+	return err
+}
+
+// ReleaseIdempotencyKey removes the claim, so that a retry with the key runs the request again
+func (dbService *GlobalInfosDBService) ReleaseIdempotencyKey(key string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionIdempotencyKeys().DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
@@ -0,0 +1,110 @@
+package globalinfos
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	INSTANCE_STATUS_ACTIVE   = "active"
+	INSTANCE_STATUS_ARCHIVED = "archived"
+)
+
+// Instance is a provisioned instance. Archived instances keep their data but are rejected by the APIs.
+type Instance struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	InstanceID  string             `bson:"instanceID" json:"instanceID"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Status      string             `bson:"status" json:"status"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	CreatedBy   string             `bson:"createdBy,omitempty" json:"createdBy,omitempty"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+	UpdatedBy   string             `bson:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+}
+
+func instanceIndexes() db.CollectionIndexes {
+	return db.CollectionIndexes{
+		Collection: COLLECTION_NAME_INSTANCES,
+		Indexes: []mongo.IndexModel{
+			{
+				Keys: bson.D{
+					{Key: "instanceID", Value: 1},
+				},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+	}
+}
+
+// CreateInstance adds an active instance, fails with a duplicate key error if the instance exists
+func (dbService *GlobalInfosDBService) CreateInstance(instance Instance) (Instance, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	instance.ID = primitive.NilObjectID
+	instance.Status = INSTANCE_STATUS_ACTIVE
+	instance.CreatedAt = now
+	instance.UpdatedAt = now
+	instance.UpdatedBy = instance.CreatedBy
+
+	res, err := dbService.collectionInstances().InsertOne(ctx, instance)
+	if err != nil {
+		return instance, err
+	}
+	instance.ID = res.InsertedID.(primitive.ObjectID)
+	return instance, nil
+}
+
+func (dbService *GlobalInfosDBService) GetInstances() ([]Instance, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "instanceID", Value: 1}})
+	cursor, err := dbService.collectionInstances().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	instances := []Instance{}
+	if err = cursor.All(ctx, &instances); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+func (dbService *GlobalInfosDBService) GetInstance(instanceID string) (Instance, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var instance Instance
+	err := dbService.collectionInstances().FindOne(ctx, bson.M{"instanceID": instanceID}).Decode(&instance)
+	return instance, err
+}
+
+// SetInstanceStatus updates the status, instances only listed in service configs get a record with the status
+func (dbService *GlobalInfosDBService) SetInstanceStatus(instanceID string, status string, updatedBy string) (Instance, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":    status,
+			"updatedAt": now,
+			"updatedBy": updatedBy,
+		},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var instance Instance
+	err := dbService.collectionInstances().FindOneAndUpdate(ctx, bson.M{"instanceID": instanceID}, update, opts).Decode(&instance)
+	return instance, err
+}
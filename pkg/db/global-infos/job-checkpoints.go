@@ -0,0 +1,80 @@
+package globalinfos
+
+import (
+	"errors"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobCheckpoint is the progress of a job task in an instance. Documents are processed in _id order, an interrupted run
+// continues after the last processed _id.
+type JobCheckpoint struct {
+	Job        string             `bson:"job" json:"job"`
+	Task       string             `bson:"task" json:"task"`
+	InstanceID string             `bson:"instanceID" json:"instanceID"`
+	LastID     primitive.ObjectID `bson:"lastID" json:"lastID"`
+	// documents processed since the task started from the beginning
+	Processed int64     `bson:"processed" json:"processed"`
+	StartedAt time.Time `bson:"startedAt" json:"startedAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+func (dbService *GlobalInfosDBService) collectionJobCheckpoints() *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName()).Collection(COLLECTION_NAME_JOB_CHECKPOINTS)
+}
+
+func jobCheckpointIndexes() db.CollectionIndexes {
+	return db.CollectionIndexes{
+		Collection: COLLECTION_NAME_JOB_CHECKPOINTS,
+		Indexes: []mongo.IndexModel{
+			{
+				Keys: bson.D{
+					{Key: "job", Value: 1},
+					{Key: "task", Value: 1},
+					{Key: "instanceID", Value: 1},
+				},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+	}
+}
+
+// GetJobCheckpoint returns nil if the task has no checkpoint, e.g. because its last run completed
+func (dbService *GlobalInfosDBService) GetJobCheckpoint(job string, task string, instanceID string) (*JobCheckpoint, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var checkpoint JobCheckpoint
+	err := dbService.collectionJobCheckpoints().FindOne(ctx, bson.M{"job": job, "task": task, "instanceID": instanceID}).Decode(&checkpoint)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (dbService *GlobalInfosDBService) SaveJobCheckpoint(checkpoint JobCheckpoint) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	checkpoint.UpdatedAt = time.Now()
+	filter := bson.M{"job": checkpoint.Job, "task": checkpoint.Task, "instanceID": checkpoint.InstanceID}
+	_, err := dbService.collectionJobCheckpoints().ReplaceOne(ctx, filter, checkpoint, options.Replace().SetUpsert(true))
+	return err
+}
+
+// DeleteJobCheckpoint is called when the task completed, so that the next run starts from the beginning
+func (dbService *GlobalInfosDBService) DeleteJobCheckpoint(job string, task string, instanceID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionJobCheckpoints().DeleteOne(ctx, bson.M{"job": job, "task": task, "instanceID": instanceID})
+	return err
+}
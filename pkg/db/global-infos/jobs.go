@@ -0,0 +1,256 @@
+package globalinfos
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	JOB_RUN_STATUS_RUNNING = "running"
+	JOB_RUN_STATUS_SUCCESS = "success"
+	JOB_RUN_STATUS_FAILED  = "failed"
+	JOB_RUN_STATUS_TIMEOUT = "timeout"
+)
+
+// JobLock is held by one scheduler replica while it runs the job, the lease is renewed while the job is running. The
+// scheduled time of the last run is kept after the release, so that replicas with a slightly different clock do not
+// run the same slot again.
+type JobLock struct {
+	Job             string    `bson:"_id" json:"job"`
+	Owner           string    `bson:"owner" json:"owner"`
+	AcquiredAt      time.Time `bson:"acquiredAt" json:"acquiredAt"`
+	LeaseUntil      time.Time `bson:"leaseUntil" json:"leaseUntil"`
+	LastScheduledAt time.Time `bson:"lastScheduledAt" json:"lastScheduledAt"`
+}
+
+// JobRun is the history entry of a scheduled job run
+type JobRun struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Job         string             `bson:"job" json:"job"`
+	Owner       string             `bson:"owner" json:"owner"`
+	ScheduledAt time.Time          `bson:"scheduledAt" json:"scheduledAt"`
+	StartedAt   time.Time          `bson:"startedAt" json:"startedAt"`
+	FinishedAt  *time.Time         `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+	Status      string             `bson:"status" json:"status"`
+	ExitCode    int                `bson:"exitCode" json:"exitCode"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	// last lines of the output of the job
+	Output string `bson:"output,omitempty" json:"output,omitempty"`
+	// what the job reported as processed, by instance
+	Instances []JobRunInstanceStats `bson:"instances,omitempty" json:"instances,omitempty"`
+}
+
+// JobRunInstanceStats are the counts a job reported for an instance, e.g. {"sentEmails": 12}
+type JobRunInstanceStats struct {
+	InstanceID string           `bson:"instanceID" json:"instanceID"`
+	Counts     map[string]int64 `bson:"counts,omitempty" json:"counts,omitempty"`
+	ErrorCount int              `bson:"errorCount" json:"errorCount"`
+	// first errors of the instance, the rest is only counted
+	Errors []string `bson:"errors,omitempty" json:"errors,omitempty"`
+}
+
+func (dbService *GlobalInfosDBService) collectionJobLocks() *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName()).Collection(COLLECTION_NAME_JOB_LOCKS)
+}
+
+func (dbService *GlobalInfosDBService) collectionJobRuns() *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName()).Collection(COLLECTION_NAME_JOB_RUNS)
+}
+
+func jobRunIndexes() db.CollectionIndexes {
+	return db.CollectionIndexes{
+		Collection: COLLECTION_NAME_JOB_RUNS,
+		Indexes: []mongo.IndexModel{
+			{
+				Keys: bson.D{
+					{Key: "job", Value: 1},
+					{Key: "startedAt", Value: -1},
+				},
+			},
+			{
+				Keys: bson.D{
+					{Key: "startedAt", Value: -1},
+				},
+			},
+		},
+	}
+}
+
+// AcquireJobLock takes the lock of the job for the run scheduled at the given time. Returns false if another owner
+// holds the lock or the run was already started by another replica.
+func (dbService *GlobalInfosDBService) AcquireJobLock(job string, owner string, scheduledAt time.Time, leaseUntil time.Time) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": job,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"owner": owner},
+				bson.M{"leaseUntil": bson.M{"$lt": now}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"lastScheduledAt": bson.M{"$lt": scheduledAt}},
+				bson.M{"lastScheduledAt": bson.M{"$exists": false}},
+			}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":           owner,
+		"acquiredAt":      now,
+		"leaseUntil":      leaseUntil,
+		"lastScheduledAt": scheduledAt,
+	}}
+	_, err := dbService.collectionJobLocks().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// the lock document exists and does not match the filter
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RenewJobLock extends the lease, returns false if the lock was lost, e.g. after the lease expired
+func (dbService *GlobalInfosDBService) RenewJobLock(job string, owner string, leaseUntil time.Time) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionJobLocks().UpdateOne(ctx,
+		bson.M{"_id": job, "owner": owner},
+		bson.M{"$set": bson.M{"leaseUntil": leaseUntil}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// ReleaseJobLock ends the lease if the lock is held by the owner
+func (dbService *GlobalInfosDBService) ReleaseJobLock(job string, owner string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionJobLocks().UpdateOne(ctx,
+		bson.M{"_id": job, "owner": owner},
+		bson.M{"$set": bson.M{"leaseUntil": time.Now()}},
+	)
+	return err
+}
+
+func (dbService *GlobalInfosDBService) GetJobLocks() ([]JobLock, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	cursor, err := dbService.collectionJobLocks().Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locks := []JobLock{}
+	if err = cursor.All(ctx, &locks); err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+// CreateJobRun records the start of a run
+func (dbService *GlobalInfosDBService) CreateJobRun(run JobRun) (JobRun, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	run.ID = primitive.NilObjectID
+	run.Status = JOB_RUN_STATUS_RUNNING
+	res, err := dbService.collectionJobRuns().InsertOne(ctx, run)
+	if err != nil {
+		return run, err
+	}
+	run.ID = res.InsertedID.(primitive.ObjectID)
+	return run, nil
+}
+
+// FinishJobRun records the outcome of a run
+func (dbService *GlobalInfosDBService) FinishJobRun(run JobRun) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	finishedAt := time.Now()
+	if run.FinishedAt != nil {
+		finishedAt = *run.FinishedAt
+	}
+	_, err := dbService.collectionJobRuns().UpdateOne(ctx, bson.M{"_id": run.ID}, bson.M{"$set": bson.M{
+		"finishedAt": finishedAt,
+		"status":     run.Status,
+		"exitCode":   run.ExitCode,
+		"error":      run.Error,
+		"output":     run.Output,
+		"instances":  run.Instances,
+	}})
+	return err
+}
+
+func (dbService *GlobalInfosDBService) GetJobRun(id primitive.ObjectID) (JobRun, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var run JobRun
+	err := dbService.collectionJobRuns().FindOne(ctx, bson.M{"_id": id}).Decode(&run)
+	return run, err
+}
+
+// GetJobRuns returns the latest runs first, job and status are not filtered if empty. The output of the job is only
+// returned by GetJobRun.
+func (dbService *GlobalInfosDBService) GetJobRuns(job string, status string, page int64, limit int64) ([]JobRun, int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{}
+	if job != "" {
+		filter["job"] = job
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	count, err := dbService.collectionJobRuns().CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "startedAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit).
+		SetProjection(bson.M{"output": 0})
+	cursor, err := dbService.collectionJobRuns().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	runs := []JobRun{}
+	if err = cursor.All(ctx, &runs); err != nil {
+		return nil, 0, err
+	}
+	return runs, count, nil
+}
+
+// DeleteJobRunsBefore removes history entries of runs started before the time
+func (dbService *GlobalInfosDBService) DeleteJobRunsBefore(before time.Time) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionJobRuns().DeleteMany(ctx, bson.M{"startedAt": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
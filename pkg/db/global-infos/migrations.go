@@ -0,0 +1,23 @@
+package globalinfos
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/case-framework/case-backend/pkg/db"
+)
+
+// migrations of the global infos DB, append new migrations with the next version
+func migrations() []db.Migration {
+	return []db.Migration{}
+}
+
+// ApplyMigrations runs the pending migrations on the global infos DB, which is shared by all instances
+func (dbService *GlobalInfosDBService) ApplyMigrations(ctx context.Context, opts db.MigrationOptions) db.MigrationReport {
+	return db.ApplyMigrations(ctx, dbService.DBClient.Database(dbService.getDBName()), migrations(), opts)
+}
+
+func (dbService *GlobalInfosDBService) applyMigrations() {
+	slog.Debug("Applying migrations for global infos DB")
+	dbService.ApplyMigrations(context.Background(), db.MigrationOptions{}).Log()
+}
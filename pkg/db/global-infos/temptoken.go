@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -12,12 +13,10 @@ import (
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
 )
 
-func (dbService *GlobalInfosDBService) CreateIndexForTemptokens() error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	_, err := dbService.collectionTemptokens().Indexes().CreateMany(
-		ctx, []mongo.IndexModel{
+func temptokenIndexes() db.CollectionIndexes {
+	return db.CollectionIndexes{
+		Collection: COLLECTION_NAME_TEMPTOKENS,
+		Indexes: []mongo.IndexModel{
 			{
 				Keys: bson.D{
 					{Key: "userID", Value: 1},
@@ -38,8 +37,7 @@ func (dbService *GlobalInfosDBService) CreateIndexForTemptokens() error {
 				Options: options.Index().SetUnique(true),
 			},
 		},
-	)
-	return err
+	}
 }
 
 func (dbService *GlobalInfosDBService) AddTempToken(t userTypes.TempToken) (token string, err error) {
@@ -74,6 +72,19 @@ func (dbService *GlobalInfosDBService) DeleteAllTempTokenForUser(instanceID stri
 	return nil
 }
 
+// DeleteAllTempTokensForUsers removes the temp tokens of several users with one request
+func (dbService *GlobalInfosDBService) DeleteAllTempTokensForUsers(instanceID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"instanceID": instanceID, "userID": bson.M{"$in": userIDs}}
+	_, err := dbService.collectionTemptokens().DeleteMany(ctx, filter)
+	return err
+}
+
 func (dbService *GlobalInfosDBService) GetTempToken(token string) (userTypes.TempToken, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
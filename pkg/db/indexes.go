@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// the default index of every collection, never reported as obsolete
+const defaultIDIndexName = "_id_"
+
+// CollectionIndexes are the indexes a DB service expects on a collection
+type CollectionIndexes struct {
+	Collection string
+	Indexes    []mongo.IndexModel
+}
+
+type EnsureIndexesOptions struct {
+	// only report missing, obsolete and differing indexes, nothing is created or dropped
+	DryRun bool
+	// drop indexes of registered collections that are not in the registry
+	DropObsolete bool
+	// drop and create again indexes whose keys or options differ from the registry. The index is missing in between,
+	// and a unique index cannot be created again if the collection has duplicates.
+	RecreateDiffering bool
+}
+
+// IndexReport is the result of EnsureIndexes for one database
+type IndexReport struct {
+	DBName      string                  `json:"dbName"`
+	DryRun      bool                    `json:"dryRun"`
+	Collections []CollectionIndexReport `json:"collections"`
+}
+
+// CollectionIndexReport lists index names of a collection. Missing indexes are created unless it is a dry run.
+type CollectionIndexReport struct {
+	Collection string   `json:"collection"`
+	Missing    []string `json:"missing,omitempty"`
+	Created    []string `json:"created,omitempty"`
+	Obsolete   []string `json:"obsolete,omitempty"`
+	Dropped    []string `json:"dropped,omitempty"`
+	// indexes with the name of a registered index but other keys or options, with the names of what differs
+	Differing map[string][]string `json:"differing,omitempty"`
+	Recreated []string            `json:"recreated,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// HasErrors is true if an index of any collection could not be listed, created or dropped
+func (r IndexReport) HasErrors() bool {
+	for _, c := range r.Collections {
+		if c.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Log writes one entry per collection with changes, obsolete indexes or errors
+func (r IndexReport) Log() {
+	for _, c := range r.Collections {
+		attrs := []any{slog.String("db", r.DBName), slog.String("collection", c.Collection)}
+		switch {
+		case c.Error != "":
+			slog.Error("failed to ensure indexes", append(attrs, slog.String("error", c.Error))...)
+		case len(c.Created) > 0 || len(c.Dropped) > 0 || len(c.Recreated) > 0:
+			slog.Info("updated indexes", append(attrs, slog.Any("created", c.Created), slog.Any("dropped", c.Dropped), slog.Any("recreated", c.Recreated))...)
+		case len(c.Missing) > 0:
+			slog.Warn("missing indexes", append(attrs, slog.Any("missing", c.Missing))...)
+		}
+		if len(c.Obsolete) > 0 && len(c.Dropped) == 0 {
+			slog.Warn("obsolete indexes", append(attrs, slog.Any("obsolete", c.Obsolete))...)
+		}
+		if len(c.Differing) > 0 && len(c.Recreated) == 0 {
+			slog.Warn("indexes differ from the registry", append(attrs, slog.Any("differing", c.Differing))...)
+		}
+	}
+}
+
+// IndexName returns the name MongoDB uses for the index: the name option if set, otherwise generated from the keys
+// as "field1_1_field2_-1"
+func IndexName(model mongo.IndexModel) (string, error) {
+	if model.Options != nil && model.Options.Name != nil {
+		return *model.Options.Name, nil
+	}
+
+	raw, err := bson.Marshal(model.Keys)
+	if err != nil {
+		return "", err
+	}
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return "", err
+	}
+	if len(elements) == 0 {
+		return "", errors.New("index has no keys")
+	}
+
+	name := ""
+	for i, element := range elements {
+		if i > 0 {
+			name += "_"
+		}
+		value := element.Value()
+		switch value.Type {
+		case bsontype.Int32:
+			name += fmt.Sprintf("%s_%d", element.Key(), value.Int32())
+		case bsontype.Int64:
+			name += fmt.Sprintf("%s_%d", element.Key(), value.Int64())
+		case bsontype.String:
+			name += element.Key() + "_" + value.StringValue()
+		default:
+			return "", fmt.Errorf("unsupported value for index key %s", element.Key())
+		}
+	}
+	return name, nil
+}
+
+// EnsureIndexes compares the indexes of the registered collections with the registry, creates missing ones and
+// reports indexes not in the registry or with other keys or options. Other collections of the database are not
+// checked. newContext is called for each collection, so that the timeout applies per collection.
+func EnsureIndexes(newContext func() (context.Context, context.CancelFunc), database *mongo.Database, registry []CollectionIndexes, opts EnsureIndexesOptions) IndexReport {
+	report := IndexReport{
+		DBName:      database.Name(),
+		DryRun:      opts.DryRun,
+		Collections: make([]CollectionIndexReport, 0, len(registry)),
+	}
+	for _, entry := range registry {
+		ctx, cancel := newContext()
+		report.Collections = append(report.Collections, ensureCollectionIndexes(ctx, database.Collection(entry.Collection), entry.Indexes, opts))
+		cancel()
+	}
+	return report
+}
+
+func ensureCollectionIndexes(ctx context.Context, collection *mongo.Collection, indexes []mongo.IndexModel, opts EnsureIndexesOptions) CollectionIndexReport {
+	report := CollectionIndexReport{Collection: collection.Name()}
+
+	existing, err := listIndexes(ctx, collection)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	expected := make([]string, 0, len(indexes))
+	missing := []mongo.IndexModel{}
+	differing := []mongo.IndexModel{}
+	for _, model := range indexes {
+		name, err := IndexName(model)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		expected = append(expected, name)
+
+		spec, ok := existing[name]
+		if !ok {
+			report.Missing = append(report.Missing, name)
+			missing = append(missing, model)
+			continue
+		}
+		differences, err := indexDifferences(model, spec)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		if len(differences) > 0 {
+			if report.Differing == nil {
+				report.Differing = map[string][]string{}
+			}
+			report.Differing[name] = differences
+			differing = append(differing, model)
+		}
+	}
+	for name := range existing {
+		if name != defaultIDIndexName && !slices.Contains(expected, name) {
+			report.Obsolete = append(report.Obsolete, name)
+		}
+	}
+	slices.Sort(report.Obsolete)
+
+	if opts.DryRun {
+		return report
+	}
+
+	if len(missing) > 0 {
+		created, err := collection.Indexes().CreateMany(ctx, missing)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		report.Created = created
+	}
+	if opts.RecreateDiffering {
+		for _, model := range differing {
+			name, _ := IndexName(model)
+			if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+				report.Error = err.Error()
+				return report
+			}
+			if _, err := collection.Indexes().CreateOne(ctx, model); err != nil {
+				report.Error = err.Error()
+				return report
+			}
+			report.Recreated = append(report.Recreated, name)
+		}
+	}
+	if opts.DropObsolete {
+		for _, name := range report.Obsolete {
+			if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+				report.Error = err.Error()
+				return report
+			}
+			report.Dropped = append(report.Dropped, name)
+		}
+	}
+	return report
+}
+
+// listIndexes returns the specifications of the indexes of the collection by name
+func listIndexes(ctx context.Context, collection *mongo.Collection) (map[string]bson.Raw, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		// the collection is created together with its first index
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == namespaceNotFoundCode {
+			return map[string]bson.Raw{}, nil
+		}
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	specs := map[string]bson.Raw{}
+	for cursor.Next(ctx) {
+		name, ok := cursor.Current.Lookup("name").StringValueOK()
+		if !ok {
+			return nil, errors.New("index without name")
+		}
+		specs[name] = slices.Clone(cursor.Current)
+	}
+	return specs, cursor.Err()
+}
+
+const namespaceNotFoundCode = 26
+
+// indexDifferences compares the keys and the options unique, expireAfterSeconds and partialFilterExpression of the
+// existing index specification with the model, returns the names of those that differ
+func indexDifferences(model mongo.IndexModel, spec bson.Raw) ([]string, error) {
+	keys, err := bson.Marshal(model.Keys)
+	if err != nil {
+		return nil, err
+	}
+	var unique bool
+	var expireAfterSeconds, partialFilter any
+	if model.Options != nil {
+		unique = model.Options.Unique != nil && *model.Options.Unique
+		if model.Options.ExpireAfterSeconds != nil {
+			expireAfterSeconds = float64(*model.Options.ExpireAfterSeconds)
+		}
+		if model.Options.PartialFilterExpression != nil {
+			filter, err := bson.Marshal(model.Options.PartialFilterExpression)
+			if err != nil {
+				return nil, err
+			}
+			partialFilter = comparableBSON(bson.RawValue{Type: bsontype.EmbeddedDocument, Value: filter})
+		}
+	}
+
+	differences := []string{}
+	if !reflect.DeepEqual(comparableBSON(bson.RawValue{Type: bsontype.EmbeddedDocument, Value: keys}), comparableBSON(spec.Lookup("key"))) {
+		differences = append(differences, "keys")
+	}
+	if existingUnique, _ := spec.Lookup("unique").BooleanOK(); existingUnique != unique {
+		differences = append(differences, "unique")
+	}
+	if !reflect.DeepEqual(expireAfterSeconds, comparableBSON(spec.Lookup("expireAfterSeconds"))) {
+		differences = append(differences, "expireAfterSeconds")
+	}
+	if !reflect.DeepEqual(partialFilter, comparableBSON(spec.Lookup("partialFilterExpression"))) {
+		differences = append(differences, "partialFilterExpression")
+	}
+	return differences, nil
+}
+
+// comparableBSON converts the value for comparisons with reflect.DeepEqual. Numbers are compared as float64, since
+// the server may return another number type than the driver sent, and documents keep the order of their fields.
+// Returns nil for a missing value.
+func comparableBSON(value bson.RawValue) any {
+	switch value.Type {
+	case 0:
+		return nil
+	case bsontype.Int32:
+		return float64(value.Int32())
+	case bsontype.Int64:
+		return float64(value.Int64())
+	case bsontype.Double:
+		return value.Double()
+	case bsontype.EmbeddedDocument:
+		elements, _ := value.Document().Elements()
+		doc := make([][2]any, len(elements))
+		for i, element := range elements {
+			doc[i] = [2]any{element.Key(), comparableBSON(element.Value())}
+		}
+		return doc
+	case bsontype.Array:
+		values, _ := value.Array().Values()
+		array := make([]any, len(values))
+		for i, v := range values {
+			array[i] = comparableBSON(v)
+		}
+		return array
+	default:
+		return value.String()
+	}
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestIndexName(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   mongo.IndexModel
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "compound index",
+			model: mongo.IndexModel{Keys: bson.D{{Key: "participantID", Value: 1}, {Key: "arrivedAt", Value: -1}}},
+			want:  "participantID_1_arrivedAt_-1",
+		},
+		{
+			name:  "int64 and string values",
+			model: mongo.IndexModel{Keys: bson.D{{Key: "expiresAt", Value: int64(1)}, {Key: "flags.$**", Value: "text"}}},
+			want:  "expiresAt_1_flags.$**_text",
+		},
+		{
+			name: "name option",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "key", Value: 1}},
+				Options: options.Index().SetName("uniqueKey"),
+			},
+			want: "uniqueKey",
+		},
+		{
+			name:    "no keys",
+			model:   mongo.IndexModel{Keys: bson.D{}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IndexName(tt.model)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got name %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexDifferences(t *testing.T) {
+	// index specification as listed by the server
+	spec := func(fields bson.D) bson.Raw {
+		raw, err := bson.Marshal(append(bson.D{{Key: "v", Value: int32(2)}, {Key: "name", Value: "index"}}, fields...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	keys := bson.D{{Key: "participantID", Value: 1}, {Key: "arrivedAt", Value: -1}}
+	filter := bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"active", "paused"}}}}}
+
+	tests := []struct {
+		name     string
+		model    mongo.IndexModel
+		existing bson.Raw
+		want     []string
+	}{
+		{
+			name:     "same keys with other number types",
+			model:    mongo.IndexModel{Keys: keys},
+			existing: spec(bson.D{{Key: "key", Value: bson.D{{Key: "participantID", Value: int32(1)}, {Key: "arrivedAt", Value: float64(-1)}}}}),
+			want:     []string{},
+		},
+		{
+			name:     "other key order",
+			model:    mongo.IndexModel{Keys: keys},
+			existing: spec(bson.D{{Key: "key", Value: bson.D{{Key: "arrivedAt", Value: -1}, {Key: "participantID", Value: 1}}}}),
+			want:     []string{"keys"},
+		},
+		{
+			name:     "other direction",
+			model:    mongo.IndexModel{Keys: keys},
+			existing: spec(bson.D{{Key: "key", Value: bson.D{{Key: "participantID", Value: 1}, {Key: "arrivedAt", Value: 1}}}}),
+			want:     []string{"keys"},
+		},
+		{
+			name:     "unique missing",
+			model:    mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(true)},
+			existing: spec(bson.D{{Key: "key", Value: keys}}),
+			want:     []string{"unique"},
+		},
+		{
+			name:     "unique not expected",
+			model:    mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(false)},
+			existing: spec(bson.D{{Key: "key", Value: keys}, {Key: "unique", Value: true}}),
+			want:     []string{"unique"},
+		},
+		{
+			name:     "same expireAfterSeconds",
+			model:    mongo.IndexModel{Keys: keys, Options: options.Index().SetExpireAfterSeconds(3600)},
+			existing: spec(bson.D{{Key: "key", Value: keys}, {Key: "expireAfterSeconds", Value: int64(3600)}}),
+			want:     []string{},
+		},
+		{
+			name:     "other expireAfterSeconds",
+			model:    mongo.IndexModel{Keys: keys, Options: options.Index().SetExpireAfterSeconds(3600)},
+			existing: spec(bson.D{{Key: "key", Value: keys}, {Key: "expireAfterSeconds", Value: int32(60)}}),
+			want:     []string{"expireAfterSeconds"},
+		},
+		{
+			name:     "expireAfterSeconds not expected",
+			model:    mongo.IndexModel{Keys: keys},
+			existing: spec(bson.D{{Key: "key", Value: keys}, {Key: "expireAfterSeconds", Value: int32(60)}}),
+			want:     []string{"expireAfterSeconds"},
+		},
+		{
+			name:     "same partial filter",
+			model:    mongo.IndexModel{Keys: keys, Options: options.Index().SetPartialFilterExpression(filter)},
+			existing: spec(bson.D{{Key: "key", Value: keys}, {Key: "partialFilterExpression", Value: filter}}),
+			want:     []string{},
+		},
+		{
+			name:     "other partial filter",
+			model:    mongo.IndexModel{Keys: keys, Options: options.Index().SetPartialFilterExpression(filter)},
+			existing: spec(bson.D{{Key: "key", Value: keys}, {Key: "partialFilterExpression", Value: bson.D{{Key: "status", Value: "active"}}}}),
+			want:     []string{"partialFilterExpression"},
+		},
+		{
+			name:     "partial filter missing",
+			model:    mongo.IndexModel{Keys: keys, Options: options.Index().SetPartialFilterExpression(filter)},
+			existing: spec(bson.D{{Key: "key", Value: keys}}),
+			want:     []string{"partialFilterExpression"},
+		},
+		{
+			name:     "several differences",
+			model:    mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+			existing: spec(bson.D{{Key: "key", Value: keys}, {Key: "expireAfterSeconds", Value: int32(60)}}),
+			want:     []string{"keys", "unique", "expireAfterSeconds"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := indexDifferences(tt.model, tt.existing)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// upper bounds of the latency buckets in milliseconds, slower operations are counted in the last bucket
+var LatencyBucketsMs = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// commands of the connection handshake and authentication, not recorded
+var ignoredCommands = map[string]bool{
+	"hello":        true,
+	"isMaster":     true,
+	"ismaster":     true,
+	"ping":         true,
+	"saslStart":    true,
+	"saslContinue": true,
+	"buildInfo":    true,
+	"endSessions":  true,
+}
+
+// OperationKey identifies the operations of a latency histogram
+type OperationKey struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+	Command    string `json:"command"`
+}
+
+// OperationStats is a snapshot of the latency histogram of an operation. Buckets holds the cumulative count of
+// operations up to each bound of LatencyBucketsMs, Count includes slower operations.
+type OperationStats struct {
+	OperationKey
+	Count   int64   `json:"count"`
+	Errors  int64   `json:"errors"`
+	SumMs   float64 `json:"sumMs"`
+	Buckets []int64 `json:"buckets"`
+}
+
+type operationHistogram struct {
+	count   int64
+	errors  int64
+	sumMs   float64
+	buckets []int64
+}
+
+// OperationMetrics collects latency histograms of DB commands by database, collection and command
+type OperationMetrics struct {
+	mu         sync.Mutex
+	histograms map[OperationKey]*operationHistogram
+}
+
+// DefaultOperationMetrics records the commands of all DB services
+var DefaultOperationMetrics = NewOperationMetrics()
+
+func NewOperationMetrics() *OperationMetrics {
+	return &OperationMetrics{histograms: map[OperationKey]*operationHistogram{}}
+}
+
+// Observe records the duration of an operation
+func (m *OperationMetrics) Observe(key OperationKey, duration time.Duration, failed bool) {
+	ms := float64(duration) / float64(time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.histograms[key]
+	if !ok {
+		h = &operationHistogram{buckets: make([]int64, len(LatencyBucketsMs))}
+		m.histograms[key] = h
+	}
+	h.count += 1
+	h.sumMs += ms
+	if failed {
+		h.errors += 1
+	}
+	for i, bound := range LatencyBucketsMs {
+		if ms <= bound {
+			h.buckets[i] += 1
+		}
+	}
+}
+
+// Snapshot returns the histograms ordered by database, collection and command
+func (m *OperationMetrics) Snapshot() []OperationStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]OperationStats, 0, len(m.histograms))
+	for key, h := range m.histograms {
+		stats = append(stats, OperationStats{
+			OperationKey: key,
+			Count:        h.count,
+			Errors:       h.errors,
+			SumMs:        h.sumMs,
+			Buckets:      append([]int64{}, h.buckets...),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i].OperationKey, stats[j].OperationKey
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		if a.Collection != b.Collection {
+			return a.Collection < b.Collection
+		}
+		return a.Command < b.Command
+	})
+	return stats
+}
+
+type startedCommand struct {
+	key  OperationKey
+	span *tracing.Span
+}
+
+// NewCommandMonitor records the latency of each command in metrics and logs commands slower than the threshold,
+// a threshold of 0 disables the slow query log. Only the command name and collection are logged, never the filter
+// or documents, which can contain participant data. If tracing is enabled, each command is recorded as a client span
+// too, as child of the span in the context of the operation.
+func NewCommandMonitor(metrics *OperationMetrics, slowQueryThreshold time.Duration) *event.CommandMonitor {
+	// the collection is only part of the started event
+	var started sync.Map
+
+	finished := func(e event.CommandFinishedEvent, failure string) {
+		value, ok := started.LoadAndDelete(e.RequestID)
+		if !ok {
+			return
+		}
+		command := value.(startedCommand)
+		key := command.key
+		metrics.Observe(key, e.Duration, failure != "")
+
+		if failure != "" {
+			command.span.SetStatus(tracing.STATUS_CODE_ERROR, failure)
+		}
+		command.span.End()
+
+		if slowQueryThreshold > 0 && e.Duration >= slowQueryThreshold {
+			attrs := []any{
+				slog.String("db", key.Database),
+				slog.String("collection", key.Collection),
+				slog.String("command", key.Command),
+				slog.Int64("durationMs", e.Duration.Milliseconds()),
+			}
+			if failure != "" {
+				attrs = append(attrs, slog.String("error", failure))
+			}
+			slog.Warn("slow DB operation", attrs...)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if ignoredCommands[e.CommandName] {
+				return
+			}
+			key := OperationKey{
+				Database:   e.DatabaseName,
+				Collection: commandCollection(e.CommandName, e.Command),
+				Command:    e.CommandName,
+			}
+			_, span := tracing.Start(ctx, e.CommandName+" "+key.Collection, tracing.SPAN_KIND_CLIENT)
+			span.SetAttributes(
+				tracing.String("db.system", "mongodb"),
+				tracing.String("db.namespace", key.Database),
+				tracing.String("db.collection.name", key.Collection),
+				tracing.String("db.operation.name", key.Command),
+			)
+			started.Store(e.RequestID, startedCommand{key: key, span: span})
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finished(e.CommandFinishedEvent, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finished(e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
+
+// commandCollection reads the collection name of a command, which is the value of the command name for most
+// commands. getMore names the collection in its own field.
+func commandCollection(commandName string, command bson.Raw) string {
+	field := commandName
+	if commandName == "getMore" {
+		field = "collection"
+	}
+	value, err := command.LookupErr(field)
+	if err != nil || value.Type != bsontype.String {
+		return ""
+	}
+	return value.StringValue()
+}
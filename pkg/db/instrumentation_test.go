@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestOperationMetrics(t *testing.T) {
+	metrics := NewOperationMetrics()
+	key := OperationKey{Database: "inst_users", Collection: "users", Command: "find"}
+
+	metrics.Observe(key, 3*time.Millisecond, false)
+	metrics.Observe(key, 40*time.Millisecond, true)
+	metrics.Observe(key, 10*time.Second, false)
+
+	stats := metrics.Snapshot()
+	if len(stats) != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	s := stats[0]
+	if s.Count != 3 || s.Errors != 1 || s.SumMs != 10043 {
+		t.Errorf("unexpected counts: %+v", s)
+	}
+	// cumulative buckets: 5ms holds the first operation, 50ms the first two, the last one is slower than all bounds
+	if s.Buckets[2] != 1 || s.Buckets[5] != 2 || s.Buckets[len(s.Buckets)-1] != 2 {
+		t.Errorf("unexpected buckets: %v", s.Buckets)
+	}
+}
+
+func TestCommandMonitor(t *testing.T) {
+	metrics := NewOperationMetrics()
+	monitor := NewCommandMonitor(metrics, 0)
+	ctx := context.Background()
+
+	commands := []struct {
+		name    string
+		command bson.D
+	}{
+		{name: "find", command: bson.D{{Key: "find", Value: "responses"}, {Key: "filter", Value: bson.M{}}}},
+		{name: "getMore", command: bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "responses"}}},
+		{name: "hello", command: bson.D{{Key: "hello", Value: 1}}},
+	}
+	for i, c := range commands {
+		raw, err := bson.Marshal(c.command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		monitor.Started(ctx, &event.CommandStartedEvent{Command: raw, CommandName: c.name, DatabaseName: "inst_studyDB", RequestID: int64(i)})
+		monitor.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: c.name, RequestID: int64(i), Duration: time.Millisecond}})
+	}
+
+	stats := metrics.Snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats[0].Command != "find" || stats[0].Collection != "responses" || stats[1].Command != "getMore" || stats[1].Collection != "responses" {
+		t.Errorf("unexpected operations: %+v", stats)
+	}
+}
@@ -0,0 +1,137 @@
+package managementuser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ACCESS_TOKEN_PREFIX distinguishes access tokens from the API keys of service users
+const ACCESS_TOKEN_PREFIX = "cpat_"
+
+func (dbService *ManagementUserDBService) collectionAccessTokens(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_ACCESS_TOKENS)
+}
+
+func accessTokenIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_ACCESS_TOKENS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				{
+					Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+				},
+				{
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(0),
+				},
+			},
+		},
+	}
+}
+
+// HashAccessToken returns the hash under which the token is stored
+func HashAccessToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateAccessToken stores the hash of the token
+func (dbService *ManagementUserDBService) CreateAccessToken(instanceID string, accessToken AccessToken, token string) (*AccessToken, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	accessToken.ID = primitive.NilObjectID
+	accessToken.TokenHash = HashAccessToken(token)
+	accessToken.CreatedAt = time.Now()
+	res, err := dbService.collectionAccessTokens(instanceID).InsertOne(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	accessToken.ID = res.InsertedID.(primitive.ObjectID)
+	return &accessToken, nil
+}
+
+// GetAccessToken returns the unexpired access token and records its use
+func (dbService *ManagementUserDBService) GetAccessToken(instanceID string, token string) (*AccessToken, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	var accessToken AccessToken
+	err := dbService.collectionAccessTokens(instanceID).FindOneAndUpdate(
+		ctx,
+		bson.M{"tokenHash": HashAccessToken(token), "expiresAt": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"lastUsedAt": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return &accessToken, nil
+}
+
+// GetAccessTokensByUserID returns the access tokens of the user, newest first
+func (dbService *ManagementUserDBService) GetAccessTokensByUserID(instanceID string, userID string) ([]AccessToken, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := dbService.collectionAccessTokens(instanceID).Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	accessTokens := []AccessToken{}
+	if err := cursor.All(ctx, &accessTokens); err != nil {
+		return nil, err
+	}
+	return accessTokens, nil
+}
+
+// CountAccessTokensByUserID counts the unexpired access tokens of the user
+func (dbService *ManagementUserDBService) CountAccessTokensByUserID(instanceID string, userID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	return dbService.collectionAccessTokens(instanceID).CountDocuments(ctx, bson.M{"userId": userID, "expiresAt": bson.M{"$gt": time.Now()}})
+}
+
+// DeleteAccessToken removes the access token if it belongs to the user
+func (dbService *ManagementUserDBService) DeleteAccessToken(instanceID string, userID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := dbService.collectionAccessTokens(instanceID).DeleteOne(ctx, bson.M{"_id": objID, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteAccessTokensByUserID removes all access tokens of the user
+func (dbService *ManagementUserDBService) DeleteAccessTokensByUserID(instanceID string, userID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionAccessTokens(instanceID).DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
@@ -18,6 +18,7 @@ const (
 	COLLECTION_NAME_SESSIONS              = "management_user_sessions"
 	COLLECTION_NAME_SERVICE_USERS         = "service_users"
 	COLLECTION_NAME_SERVICE_USER_API_KEYS = "service_user_api_keys"
+	COLLECTION_NAME_ACCESS_TOKENS         = "management_user_access_tokens"
 )
 
 const (
@@ -36,11 +37,12 @@ func NewManagementUserDBService(configs db.DBConfig) (*ManagementUserDBService,
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(configs.Timeout)*time.Second)
 	defer cancel()
 
-	dbClient, err := mongo.Connect(ctx,
-		options.Client().ApplyURI(configs.URI),
-		options.Client().SetMaxConnIdleTime(time.Duration(configs.IdleConnTimeout)*time.Second),
-		options.Client().SetMaxPoolSize(configs.MaxPoolSize),
-	)
+	clientOptions, err := db.ClientOptions(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	dbClient, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
 		return nil, err
@@ -62,10 +64,13 @@ func NewManagementUserDBService(configs db.DBConfig) (*ManagementUserDBService,
 		InstanceIDs:     configs.InstanceIDs,
 	}
 
+	// migrations run first, so that indexes are created on migrated data
+	if configs.RunMigrations {
+		muDBSc.applyMigrations()
+	}
+
 	if configs.RunIndexCreation {
-		if err := muDBSc.ensureIndexes(); err != nil {
-			slog.Error("Error ensuring indexes for management user DB", slog.String("error", err.Error()))
-		}
+		muDBSc.ensureIndexes()
 	}
 
 	return muDBSc, nil
@@ -83,59 +88,78 @@ func (dbService *ManagementUserDBService) collectionPermissions(instanceID strin
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_PERMISSIONS)
 }
 
+// Ping checks the connection to the management user DB, it can be called on a nil service
+func (dbService *ManagementUserDBService) Ping(ctx context.Context) error {
+	if dbService == nil {
+		return db.Ping(ctx, nil)
+	}
+	return db.Ping(ctx, dbService.DBClient)
+}
+
+// Close disconnects the client, called on shutdown after running requests are finished
+func (dbService *ManagementUserDBService) Close(ctx context.Context) error {
+	if dbService == nil {
+		return nil
+	}
+	return db.Disconnect(ctx, dbService.DBClient)
+}
+
 func (dbService *ManagementUserDBService) getContext() (ctx context.Context, cancel context.CancelFunc) {
 	return context.WithTimeout(context.Background(), time.Duration(dbService.timeout)*time.Second)
 }
 
-func (dbService *ManagementUserDBService) ensureIndexes() error {
-	slog.Debug("Ensuring indexes for management user DB")
-	for _, instanceID := range dbService.InstanceIDs {
-		ctx, cancel := dbService.getContext()
-		defer cancel()
-
-		// create unique index for sub
-		_, err := dbService.collectionManagementUsers(instanceID).Indexes().CreateOne(
-			ctx,
-			mongo.IndexModel{
-				Keys:    bson.D{{Key: "sub", Value: 1}},
-				Options: options.Index().SetUnique(true),
+// indexRegistry lists the indexes of all collections of the DB
+func indexRegistry() []db.CollectionIndexes {
+	registry := []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_MANAGEMENT_USERS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "sub", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
 			},
-		)
-		if err != nil {
-			slog.Error("Error creating unique index for sub in userDB.management_users", slog.String("error", err.Error()))
-		}
-
-		// create index for permissions
-		_, err = dbService.collectionPermissions(instanceID).Indexes().CreateOne(
-			ctx,
-			mongo.IndexModel{
-				Keys: bson.D{
-					{Key: "subjectID", Value: 1},
-					{Key: "subjectType", Value: 1},
-					{Key: "resourceType", Value: 1},
-					{Key: "resourceID", Value: 1},
-					{Key: "action", Value: 1},
+		},
+		{
+			Collection: COLLECTION_NAME_PERMISSIONS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "subjectID", Value: 1},
+						{Key: "subjectType", Value: 1},
+						{Key: "resourceType", Value: 1},
+						{Key: "resourceID", Value: 1},
+						{Key: "action", Value: 1},
+					},
 				},
 			},
-		)
-		if err != nil {
-			slog.Error("Error creating index for permissions in userDB.permissions", slog.String("error", err.Error()))
-		}
-
-		// create index for sessions
-		_, err = dbService.collectionSessions(instanceID).Indexes().CreateOne(
-			ctx,
-			mongo.IndexModel{
-				Keys:    bson.D{{Key: "createdAt", Value: 1}},
-				Options: options.Index().SetExpireAfterSeconds(REMOVE_SESSIONS_AFTER),
+		},
+		{
+			Collection: COLLECTION_NAME_SESSIONS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "createdAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(REMOVE_SESSIONS_AFTER),
+				},
+				{
+					Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+				},
 			},
-		)
-		if err != nil {
-			slog.Error("Error creating index for createdAt in userDB.sessions", slog.String("error", err.Error()))
-		}
-
-		dbService.createIndexForServiceUserAPIKeys(instanceID)
+		},
 	}
+	registry = append(registry, serviceUserAPIKeyIndexes()...)
+	registry = append(registry, accessTokenIndexes()...)
+	return registry
+}
+
+// EnsureIndexes creates missing indexes in the DB of the instance and reports indexes that are not in the registry
+func (dbService *ManagementUserDBService) EnsureIndexes(instanceID string, opts db.EnsureIndexesOptions) db.IndexReport {
+	return db.EnsureIndexes(dbService.getContext, dbService.DBClient.Database(dbService.getDBName(instanceID)), indexRegistry(), opts)
+}
 
-	return nil
+func (dbService *ManagementUserDBService) ensureIndexes() {
+	slog.Debug("Ensuring indexes for management user DB")
+	for _, instanceID := range dbService.InstanceIDs {
+		dbService.EnsureIndexes(instanceID, db.EnsureIndexesOptions{}).Log()
+	}
 }
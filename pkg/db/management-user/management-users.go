@@ -5,7 +5,10 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
 )
 
 func (dbService *ManagementUserDBService) CreateUser(
@@ -31,7 +34,7 @@ func (dbService *ManagementUserDBService) GetUserBySub(
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 	var user ManagementUser
-	err := dbService.collectionManagementUsers(instanceID).FindOne(ctx, bson.M{"sub": sub}).Decode(&user)
+	err := dbService.collectionManagementUsers(instanceID).FindOne(ctx, db.NotDeleted(bson.M{"sub": sub})).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +53,7 @@ func (dbService *ManagementUserDBService) GetUserByID(
 	if err != nil {
 		return nil, err
 	}
-	err = dbService.collectionManagementUsers(instanceID).FindOne(ctx, bson.M{"_id": objID}).Decode(&user)
+	err = dbService.collectionManagementUsers(instanceID).FindOne(ctx, db.NotDeleted(bson.M{"_id": objID})).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +78,7 @@ func (dbService *ManagementUserDBService) UpdateUser(
 	}
 	_, err = dbService.collectionManagementUsers(instanceID).UpdateOne(
 		ctx,
-		bson.M{"_id": objID},
+		db.NotDeleted(bson.M{"_id": objID}),
 		bson.M{
 			"$set": bson.M{
 				"email":       email,
@@ -89,7 +92,33 @@ func (dbService *ManagementUserDBService) UpdateUser(
 	return err
 }
 
-// delete user
+// UpdateUserAlertSubscriptions sets the system alerts the user receives by email
+func (dbService *ManagementUserDBService) UpdateUserAlertSubscriptions(
+	instanceID string,
+	id string,
+	alerts []string,
+) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := dbService.collectionManagementUsers(instanceID).UpdateOne(
+		ctx,
+		db.NotDeleted(bson.M{"_id": objID}),
+		bson.M{"$set": bson.M{"alertSubscriptions": alerts}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// move user to the trash
 func (dbService *ManagementUserDBService) DeleteUser(
 	instanceID string,
 	id string,
@@ -100,7 +129,11 @@ func (dbService *ManagementUserDBService) DeleteUser(
 	if err != nil {
 		return err
 	}
-	_, err = dbService.collectionManagementUsers(instanceID).DeleteOne(ctx, bson.M{"_id": objID})
+	_, err = dbService.collectionManagementUsers(instanceID).UpdateOne(
+		ctx,
+		db.NotDeleted(bson.M{"_id": objID}),
+		bson.M{"$set": bson.M{db.FIELD_DELETED_AT: time.Now()}},
+	)
 	return err
 }
 
@@ -112,7 +145,7 @@ func (dbService *ManagementUserDBService) GetAllUsers(
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.D{}
+	filter := db.NotDeleted(bson.M{})
 
 	opts := options.Find()
 	if !returnFullObject {
@@ -159,7 +192,7 @@ func (dbService *ManagementUserDBService) GetUsersByIDs(
 		objIDs = append(objIDs, objID)
 	}
 
-	filter := bson.M{"_id": bson.M{"$in": objIDs}}
+	filter := db.NotDeleted(bson.M{"_id": bson.M{"$in": objIDs}})
 
 	opts := options.Find()
 	if !returnFullObject {
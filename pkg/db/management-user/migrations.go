@@ -0,0 +1,25 @@
+package managementuser
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/case-framework/case-backend/pkg/db"
+)
+
+// migrations of the management user DB, append new migrations with the next version
+func migrations() []db.Migration {
+	return []db.Migration{}
+}
+
+// ApplyMigrations runs the pending migrations on the DB of the instance
+func (dbService *ManagementUserDBService) ApplyMigrations(ctx context.Context, instanceID string, opts db.MigrationOptions) db.MigrationReport {
+	return db.ApplyMigrations(ctx, dbService.DBClient.Database(dbService.getDBName(instanceID)), migrations(), opts)
+}
+
+func (dbService *ManagementUserDBService) applyMigrations() {
+	slog.Debug("Applying migrations for management user DB")
+	for _, instanceID := range dbService.InstanceIDs {
+		dbService.ApplyMigrations(context.Background(), instanceID, db.MigrationOptions{}).Log()
+	}
+}
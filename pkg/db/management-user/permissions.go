@@ -186,3 +186,24 @@ func (dbService *ManagementUserDBService) DeletePermissionsBySubject(
 	_, err := dbService.collectionPermissions(instanceID).DeleteMany(ctx, bson.M{"subjectId": subjectID, "subjectType": subjectType})
 	return err
 }
+
+// Find permissions by resource type for any of the resource keys, e.g. a study key and *
+func (dbService *ManagementUserDBService) GetPermissionsByResourceKeys(
+	instanceID string,
+	resourceType string,
+	resourceKeys []string,
+) ([]*Permission, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var permissions []*Permission
+	cursor, err := dbService.collectionPermissions(instanceID).Find(ctx, bson.M{"resourceType": resourceType, "resourceKey": bson.M{"$in": resourceKeys}})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -18,28 +19,25 @@ func (dbService *ManagementUserDBService) collectionServiceUserAPIKeys(instanceI
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_SERVICE_USER_API_KEYS)
 }
 
-func (dbService *ManagementUserDBService) createIndexForServiceUserAPIKeys(instanceID string) {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-	_, err := dbService.collectionServiceUserAPIKeys(instanceID).Indexes().CreateMany(
-		ctx,
-		[]mongo.IndexModel{
-			{
-				Keys: bson.D{
-					{Key: "key", Value: 1},
+func serviceUserAPIKeyIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_SERVICE_USER_API_KEYS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "key", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
 				},
-				Options: options.Index().SetUnique(true),
-			},
-			{
-				Keys: bson.D{
-					{Key: "expiresAt", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "expiresAt", Value: 1},
+					},
+					Options: options.Index().SetExpireAfterSeconds(0),
 				},
-				Options: options.Index().SetExpireAfterSeconds(0),
 			},
 		},
-	)
-	if err != nil {
-		slog.Error("Error creating index for service user api keys", slog.String("error", err.Error()))
 	}
 }
 
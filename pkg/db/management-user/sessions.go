@@ -3,8 +3,10 @@ package managementuser
 import (
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func (dbService *ManagementUserDBService) collectionSessions(instanceID string) *mongo.Collection {
@@ -80,3 +82,25 @@ func (dbService *ManagementUserDBService) DeleteSessionsByUserID(
 	_, err := dbService.collectionSessions(instanceID).DeleteMany(ctx, primitive.M{"userId": userID})
 	return err
 }
+
+// GetSessionsByUserID returns the sessions of the user, newest first
+func (dbService *ManagementUserDBService) GetSessionsByUserID(
+	instanceID string,
+	userID string,
+) ([]Session, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := dbService.collectionSessions(instanceID).Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,86 @@
+package managementuser
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+)
+
+// IsDeletedUser checks if the user with the sub is in the trash
+func (dbService *ManagementUserDBService) IsDeletedUser(
+	instanceID string,
+	sub string,
+) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	count, err := dbService.collectionManagementUsers(instanceID).CountDocuments(ctx, db.OnlyDeleted(bson.M{"sub": sub}))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// get users in the trash
+func (dbService *ManagementUserDBService) GetDeletedUsers(
+	instanceID string,
+) ([]*ManagementUser, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: db.FIELD_DELETED_AT, Value: -1}})
+	cursor, err := dbService.collectionManagementUsers(instanceID).Find(ctx, db.OnlyDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*ManagementUser
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// move user out of the trash
+func (dbService *ManagementUserDBService) RestoreUser(
+	instanceID string,
+	id string,
+) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := dbService.collectionManagementUsers(instanceID).UpdateOne(
+		ctx,
+		db.OnlyDeleted(bson.M{"_id": objID}),
+		bson.M{"$unset": bson.M{db.FIELD_DELETED_AT: ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// permanently delete users moved to the trash before the cutoff
+func (dbService *ManagementUserDBService) PurgeUsersDeletedBefore(
+	instanceID string,
+	cutoff time.Time,
+) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	res, err := dbService.collectionManagementUsers(instanceID).DeleteMany(ctx, db.DeletedBefore(bson.M{}, cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
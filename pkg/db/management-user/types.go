@@ -33,6 +33,9 @@ type ManagementUser struct {
 	IsAdmin     bool               `json:"isAdmin,omitempty" bson:"isAdmin,omitempty"`
 	LastLoginAt time.Time          `json:"lastLoginAt,omitempty" bson:"lastLoginAt,omitempty"`
 	CreatedAt   time.Time          `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	DeletedAt   *time.Time         `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	// system alerts the user receives by email, e.g. export-completed
+	AlertSubscriptions []string `json:"alertSubscriptions,omitempty" bson:"alertSubscriptions,omitempty"`
 }
 
 type Session struct {
@@ -57,3 +60,26 @@ type ServiceUserAPIKey struct {
 	CreatedAt     time.Time          `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
 	LastUsedAt    time.Time          `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
 }
+
+// AccessToken is a personal access token of a management user for scripts. Only the hash of the token is stored, the
+// token is shown once when it is created.
+type AccessToken struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID    string             `json:"userId,omitempty" bson:"userId,omitempty"`
+	Label     string             `json:"label,omitempty" bson:"label,omitempty"`
+	TokenHash string             `json:"-" bson:"tokenHash,omitempty"`
+	// last characters of the token, to recognise it in lists
+	Hint string `json:"hint,omitempty" bson:"hint,omitempty"`
+	// the token is limited to these permissions of the user, it never has admin rights
+	Scopes     []AccessTokenScope `json:"scopes" bson:"scopes"`
+	ExpiresAt  time.Time          `json:"expiresAt" bson:"expiresAt"`
+	CreatedAt  time.Time          `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	LastUsedAt time.Time          `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+}
+
+// AccessTokenScope allows an action on a resource, like a permission. ResourceKey and Action can be * for all.
+type AccessTokenScope struct {
+	ResourceType string `json:"resourceType" bson:"resourceType"`
+	ResourceKey  string `json:"resourceKey" bson:"resourceKey"`
+	Action       string `json:"action" bson:"action"`
+}
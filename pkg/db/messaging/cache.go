@@ -0,0 +1,29 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/cache"
+)
+
+// SetCache enables caching of email templates looked up by message type, a nil cache disables it
+func (dbService *MessagingDBService) SetCache(c cache.Cache, ttl time.Duration) {
+	dbService.cache = c
+	dbService.cacheTTL = ttl
+}
+
+func emailTemplateCachePrefix(instanceID string) string {
+	return "emailTemplate:" + instanceID + ":"
+}
+
+// global templates have an empty study key
+func emailTemplateCacheKey(instanceID string, studyKey string, messageType string) string {
+	return emailTemplateCachePrefix(instanceID) + studyKey + ":" + messageType
+}
+
+// invalidateEmailTemplateCache removes all templates of the instance, since an update by ID can change the message
+// type or study of a template
+func (dbService *MessagingDBService) invalidateEmailTemplateCache(instanceID string) {
+	cache.Invalidate(context.Background(), dbService.cache, nil, []string{emailTemplateCachePrefix(instanceID)})
+}
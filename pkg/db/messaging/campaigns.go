@@ -0,0 +1,180 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func campaignIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_CAMPAIGNS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "status", Value: 1},
+						{Key: "nextRunAt", Value: 1},
+					},
+				},
+			},
+		},
+	}
+}
+
+// get all campaigns
+func (dbService *MessagingDBService) GetCampaigns(instanceID string) ([]messagingTypes.Campaign, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := dbService.collectionCampaigns(instanceID).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := []messagingTypes.Campaign{}
+	if err = cursor.All(ctx, &campaigns); err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// get campaign by id
+func (dbService *MessagingDBService) GetCampaignByID(instanceID string, id string) (*messagingTypes.Campaign, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var campaign messagingTypes.Campaign
+	err = dbService.collectionCampaigns(instanceID).FindOne(ctx, bson.M{"_id": _id}).Decode(&campaign)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// save campaign, creating it if it has no ID yet
+func (dbService *MessagingDBService) SaveCampaign(instanceID string, campaign messagingTypes.Campaign) (messagingTypes.Campaign, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	campaign.UpdatedAt = time.Now().Unix()
+	if campaign.ID.IsZero() {
+		campaign.ID = primitive.NewObjectID()
+		campaign.CreatedAt = campaign.UpdatedAt
+		_, err := dbService.collectionCampaigns(instanceID).InsertOne(ctx, campaign)
+		return campaign, err
+	}
+
+	elem := messagingTypes.Campaign{}
+	err := dbService.collectionCampaigns(instanceID).FindOneAndReplace(
+		ctx,
+		bson.M{"_id": campaign.ID},
+		campaign,
+		options.FindOneAndReplace().SetReturnDocument(options.After),
+	).Decode(&elem)
+	return elem, err
+}
+
+// delete campaign
+func (dbService *MessagingDBService) DeleteCampaign(instanceID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := dbService.collectionCampaigns(instanceID).DeleteOne(ctx, bson.M{"_id": _id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetDueCampaigns returns active campaigns whose next run is due
+func (dbService *MessagingDBService) GetDueCampaigns(instanceID string, now int64) ([]messagingTypes.Campaign, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"status":    messagingTypes.CAMPAIGN_STATUS_ACTIVE,
+		"nextRunAt": bson.M{"$gt": 0, "$lte": now},
+	}
+	cursor, err := dbService.collectionCampaigns(instanceID).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := []messagingTypes.Campaign{}
+	if err = cursor.All(ctx, &campaigns); err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// ClaimCampaignRun moves the campaign to its next run time, if it is still scheduled for the expected run.
+// Returns false if another worker has already claimed this run.
+func (dbService *MessagingDBService) ClaimCampaignRun(instanceID string, id primitive.ObjectID, expectedRunAt int64, nextRunAt int64) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"_id":       id,
+		"status":    messagingTypes.CAMPAIGN_STATUS_ACTIVE,
+		"nextRunAt": expectedRunAt,
+	}
+	set := bson.M{
+		"nextRunAt": nextRunAt,
+		"lastRunAt": time.Now().Unix(),
+	}
+	if nextRunAt == 0 {
+		set["status"] = messagingTypes.CAMPAIGN_STATUS_FINISHED
+	}
+
+	res, err := dbService.collectionCampaigns(instanceID).UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// ApproveCampaign activates a campaign waiting for approval, to run at nextRunAt
+func (dbService *MessagingDBService) ApproveCampaign(instanceID string, id primitive.ObjectID, approvedBy string, nextRunAt int64) (messagingTypes.Campaign, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now().Unix()
+	filter := bson.M{
+		"_id":    id,
+		"status": messagingTypes.CAMPAIGN_STATUS_PENDING_APPROVAL,
+	}
+	update := bson.M{"$set": bson.M{
+		"status":     messagingTypes.CAMPAIGN_STATUS_ACTIVE,
+		"approvedBy": approvedBy,
+		"approvedAt": now,
+		"nextRunAt":  nextRunAt,
+		"updatedAt":  now,
+	}}
+
+	elem := messagingTypes.Campaign{}
+	err := dbService.collectionCampaigns(instanceID).FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&elem)
+	return elem, err
+}
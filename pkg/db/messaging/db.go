@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/cache"
 	"github.com/case-framework/case-backend/pkg/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,12 +14,22 @@ import (
 
 // collection names
 const (
-	COLLECTION_NAME_EMAIL_TEMPLATES = "email-templates"
-	COLLECTION_NAME_SMS_TEMPLATES   = "sms-templates"
-	COLLECTION_NAME_EMAIL_SCHEDULES = "auto-messages"
-	COLLECTION_NAME_OUTGOING_EMAILS = "outgoing-emails"
-	COLLECTION_NAME_SENT_EMAILS     = "sent-emails"
-	COLLECTION_NAME_SENT_SMS        = "sent-sms"
+	COLLECTION_NAME_EMAIL_TEMPLATES    = "email-templates"
+	COLLECTION_NAME_SMS_TEMPLATES      = "sms-templates"
+	COLLECTION_NAME_EMAIL_SCHEDULES    = "auto-messages"
+	COLLECTION_NAME_OUTGOING_EMAILS    = "outgoing-emails"
+	COLLECTION_NAME_SENT_EMAILS        = "sent-emails"
+	COLLECTION_NAME_SENT_SMS           = "sent-sms"
+	COLLECTION_NAME_EMAIL_SUPPRESSIONS = "email-suppressions"
+	COLLECTION_NAME_EMAIL_TRACKING     = "email-tracking-stats"
+	COLLECTION_NAME_CAMPAIGNS          = "campaigns"
+	COLLECTION_NAME_FAILED_EMAILS      = "failed-emails"
+
+	COLLECTION_NAME_EMAIL_TEMPLATE_VERSIONS = "email-template-versions"
+	COLLECTION_NAME_EMAIL_LAYOUTS           = "email-layouts"
+	COLLECTION_NAME_WEBHOOK_ENDPOINTS       = "webhook-endpoints"
+	COLLECTION_NAME_WEBHOOK_DELIVERIES      = "webhook-deliveries"
+	COLLECTION_NAME_SANDBOX_MESSAGES        = "sandbox-messages"
 )
 
 type MessagingDBService struct {
@@ -27,17 +38,22 @@ type MessagingDBService struct {
 	noCursorTimeout bool
 	DBNamePrefix    string
 	InstanceIDs     []string
+
+	// optional shared cache of email templates, see SetCache
+	cache    cache.Cache
+	cacheTTL time.Duration
 }
 
 func NewMessagingDBService(configs db.DBConfig) (*MessagingDBService, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(configs.Timeout)*time.Second)
 	defer cancel()
 
-	dbClient, err := mongo.Connect(ctx,
-		options.Client().ApplyURI(configs.URI),
-		options.Client().SetMaxConnIdleTime(time.Duration(configs.IdleConnTimeout)*time.Second),
-		options.Client().SetMaxPoolSize(configs.MaxPoolSize),
-	)
+	clientOptions, err := db.ClientOptions(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	dbClient, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
 		return nil, err
@@ -59,10 +75,13 @@ func NewMessagingDBService(configs db.DBConfig) (*MessagingDBService, error) {
 		InstanceIDs:     configs.InstanceIDs,
 	}
 
+	// migrations run first, so that indexes are created on migrated data
+	if configs.RunMigrations {
+		messagingDBSc.applyMigrations()
+	}
+
 	if configs.RunIndexCreation {
-		if err := messagingDBSc.ensureIndexes(); err != nil {
-			slog.Error("Error ensuring indexes for messaging DB: ", slog.String("error", err.Error()))
-		}
+		messagingDBSc.ensureIndexes()
 	}
 
 	return messagingDBSc, nil
@@ -96,47 +115,101 @@ func (dbService *MessagingDBService) collectionSentSMS(instanceID string) *mongo
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_SENT_SMS)
 }
 
+func (dbService *MessagingDBService) collectionEmailSuppressions(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_EMAIL_SUPPRESSIONS)
+}
+
+func (dbService *MessagingDBService) collectionEmailTrackingStats(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_EMAIL_TRACKING)
+}
+
+func (dbService *MessagingDBService) collectionCampaigns(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_CAMPAIGNS)
+}
+
+func (dbService *MessagingDBService) collectionFailedEmails(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_FAILED_EMAILS)
+}
+
+func (dbService *MessagingDBService) collectionEmailTemplateVersions(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_EMAIL_TEMPLATE_VERSIONS)
+}
+
+func (dbService *MessagingDBService) collectionEmailLayouts(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_EMAIL_LAYOUTS)
+}
+
+func (dbService *MessagingDBService) collectionWebhookEndpoints(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_WEBHOOK_ENDPOINTS)
+}
+
+func (dbService *MessagingDBService) collectionWebhookDeliveries(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_WEBHOOK_DELIVERIES)
+}
+
+func (dbService *MessagingDBService) collectionSandboxMessages(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_SANDBOX_MESSAGES)
+}
+
+// Ping checks the connection to the messaging DB, it can be called on a nil service
+func (dbService *MessagingDBService) Ping(ctx context.Context) error {
+	if dbService == nil {
+		return db.Ping(ctx, nil)
+	}
+	return db.Ping(ctx, dbService.DBClient)
+}
+
+// Close disconnects the client, called on shutdown after running requests are finished
+func (dbService *MessagingDBService) Close(ctx context.Context) error {
+	if dbService == nil {
+		return nil
+	}
+	return db.Disconnect(ctx, dbService.DBClient)
+}
+
 func (dbService *MessagingDBService) getContext() (ctx context.Context, cancel context.CancelFunc) {
 	return context.WithTimeout(context.Background(), time.Duration(dbService.timeout)*time.Second)
 }
 
-func (dbService *MessagingDBService) ensureIndexes() error {
-	slog.Debug("Ensuring indexes for messaging DB")
-	for _, instanceID := range dbService.InstanceIDs {
-		ctx, cancel := dbService.getContext()
-		defer cancel()
-
-		// Email Templates
-		_, err := dbService.collectionEmailTemplates(instanceID).Indexes().CreateOne(
-			ctx,
-			// index unique on messageType and studyKey combo:
-			mongo.IndexModel{
-				Keys: bson.D{
-					{Key: "messageType", Value: 1},
-					{Key: "studyKey", Value: 1},
+// indexRegistry lists the indexes of all collections of the DB
+func indexRegistry() []db.CollectionIndexes {
+	registry := []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_EMAIL_TEMPLATES,
+			Indexes: []mongo.IndexModel{
+				// unique on messageType and studyKey combo
+				{
+					Keys: bson.D{
+						{Key: "messageType", Value: 1},
+						{Key: "studyKey", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
 				},
-				Options: options.Index().SetUnique(true),
 			},
-		)
-		if err != nil {
-			slog.Error("Error creating index for email templates: ", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
-
-		// Sent SMS
-		err = dbService.CreateSentSMSIndex(instanceID)
-		if err != nil {
-			slog.Error("Error creating index for sent SMS: ", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
-
-		// Outgoing Emails
-		// add index generation here if needed
+		},
+	}
+	registry = append(registry, smsTemplateIndexes()...)
+	registry = append(registry, sentSMSIndexes()...)
+	registry = append(registry, emailSuppressionIndexes()...)
+	registry = append(registry, emailTrackingStatsIndexes()...)
+	registry = append(registry, campaignIndexes()...)
+	registry = append(registry, outgoingEmailIndexes()...)
+	registry = append(registry, failedEmailIndexes()...)
+	registry = append(registry, emailTemplateVersionIndexes()...)
+	registry = append(registry, emailLayoutIndexes()...)
+	registry = append(registry, webhookIndexes()...)
+	registry = append(registry, sandboxMessageIndexes()...)
+	return registry
+}
 
-		// Sent Emails
-		// add index generation here if needed
+// EnsureIndexes creates missing indexes in the DB of the instance and reports indexes that are not in the registry
+func (dbService *MessagingDBService) EnsureIndexes(instanceID string, opts db.EnsureIndexesOptions) db.IndexReport {
+	return db.EnsureIndexes(dbService.getContext, dbService.DBClient.Database(dbService.getDBName(instanceID)), indexRegistry(), opts)
+}
 
-		// Email Schedules
-		// add index generation here if needed
+func (dbService *MessagingDBService) ensureIndexes() {
+	slog.Debug("Ensuring indexes for messaging DB")
+	for _, instanceID := range dbService.InstanceIDs {
+		dbService.EnsureIndexes(instanceID, db.EnsureIndexesOptions{}).Log()
 	}
-
-	return nil
 }
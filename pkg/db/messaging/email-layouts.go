@@ -0,0 +1,98 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func emailLayoutIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_EMAIL_LAYOUTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "key", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+	}
+}
+
+// GetEmailLayouts returns all email layouts of the instance
+func (dbService *MessagingDBService) GetEmailLayouts(instanceID string) ([]messagingTypes.EmailLayout, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	layouts := []messagingTypes.EmailLayout{}
+	cursor, err := dbService.collectionEmailLayouts(instanceID).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &layouts); err != nil {
+		return nil, err
+	}
+	return layouts, nil
+}
+
+// GetEmailLayout returns the email layout with the given key
+func (dbService *MessagingDBService) GetEmailLayout(instanceID string, key string) (*messagingTypes.EmailLayout, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var layout messagingTypes.EmailLayout
+	err := dbService.collectionEmailLayouts(instanceID).FindOne(ctx, bson.M{"key": key}).Decode(&layout)
+	if err != nil {
+		return nil, err
+	}
+	return &layout, nil
+}
+
+// SaveEmailLayout creates or replaces the email layout with the key of the given layout
+func (dbService *MessagingDBService) SaveEmailLayout(instanceID string, layout messagingTypes.EmailLayout) (messagingTypes.EmailLayout, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	layout.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"description": layout.Description,
+		"templateDef": layout.TemplateDef,
+		"updatedAt":   layout.UpdatedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved messagingTypes.EmailLayout
+	err := dbService.collectionEmailLayouts(instanceID).FindOneAndUpdate(ctx, bson.M{"key": layout.Key}, update, opts).Decode(&saved)
+	if err != nil {
+		return messagingTypes.EmailLayout{}, err
+	}
+	return saved, nil
+}
+
+// DeleteEmailLayout removes the email layout with the given key
+func (dbService *MessagingDBService) DeleteEmailLayout(instanceID string, key string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionEmailLayouts(instanceID).DeleteOne(ctx, bson.M{"key": key})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// CountEmailTemplatesUsingLayout returns how many email templates are rendered with the given layout
+func (dbService *MessagingDBService) CountEmailTemplatesUsingLayout(instanceID string, key string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	return dbService.collectionEmailTemplates(instanceID).CountDocuments(ctx, bson.M{"layout": key})
+}
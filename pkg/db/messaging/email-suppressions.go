@@ -0,0 +1,86 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func emailSuppressionIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_EMAIL_SUPPRESSIONS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "address", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+	}
+}
+
+// AddEmailSuppression adds the address to the suppression list or updates the existing entry
+func (dbService *MessagingDBService) AddEmailSuppression(instanceID string, suppression types.EmailSuppression) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"address": suppression.Address}
+	update := bson.M{
+		"$set": bson.M{
+			"reason":    suppression.Reason,
+			"provider":  suppression.Provider,
+			"details":   suppression.Details,
+			"updatedAt": now,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+	_, err := dbService.collectionEmailSuppressions(instanceID).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetSuppressedAddresses returns the subset of the given addresses that are on the suppression list
+func (dbService *MessagingDBService) GetSuppressedAddresses(instanceID string, addresses []string) ([]string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"address": bson.M{"$in": addresses}}
+	cursor, err := dbService.collectionEmailSuppressions(instanceID).Find(ctx, filter, options.Find().SetProjection(bson.M{"address": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var suppressions []types.EmailSuppression
+	if err = cursor.All(ctx, &suppressions); err != nil {
+		return nil, err
+	}
+
+	suppressed := make([]string, len(suppressions))
+	for i, s := range suppressions {
+		suppressed[i] = s.Address
+	}
+	return suppressed, nil
+}
+
+func (dbService *MessagingDBService) RemoveEmailSuppression(instanceID string, address string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionEmailSuppressions(instanceID).DeleteOne(ctx, bson.M{"address": address})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
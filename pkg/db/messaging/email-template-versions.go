@@ -0,0 +1,121 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func emailTemplateVersionIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_EMAIL_TEMPLATE_VERSIONS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "messageType", Value: 1},
+						{Key: "studyKey", Value: 1},
+						{Key: "version", Value: -1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+	}
+}
+
+func templateVersionFilter(messageType string, studyKey string) bson.M {
+	filter := bson.M{"messageType": messageType, "studyKey": studyKey}
+	if studyKey == "" {
+		filter["studyKey"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+// SaveEmailTemplateWithHistory saves the template with an incremented version number and stores a snapshot of it in the version history
+func (dbService *MessagingDBService) SaveEmailTemplateWithHistory(instanceID string, emailTemplate messagingTypes.EmailTemplate, savedBy string) (messagingTypes.EmailTemplate, error) {
+	latest, err := dbService.getLatestEmailTemplateVersionNumber(instanceID, emailTemplate.MessageType, emailTemplate.StudyKey)
+	if err != nil {
+		return messagingTypes.EmailTemplate{}, err
+	}
+	if emailTemplate.Version > latest {
+		latest = emailTemplate.Version
+	}
+	emailTemplate.Version = latest + 1
+	emailTemplate.UpdatedAt = time.Now()
+
+	saved, err := dbService.SaveEmailTemplate(instanceID, emailTemplate)
+	if err != nil {
+		return messagingTypes.EmailTemplate{}, err
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err = dbService.collectionEmailTemplateVersions(instanceID).InsertOne(ctx, messagingTypes.EmailTemplateVersion{
+		MessageType: saved.MessageType,
+		StudyKey:    saved.StudyKey,
+		Version:     saved.Version,
+		Template:    saved,
+		CreatedAt:   saved.UpdatedAt,
+		CreatedBy:   savedBy,
+	})
+	if err != nil {
+		return saved, err
+	}
+	return saved, nil
+}
+
+func (dbService *MessagingDBService) getLatestEmailTemplateVersionNumber(instanceID string, messageType string, studyKey string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.M{"version": 1})
+	var version messagingTypes.EmailTemplateVersion
+	err := dbService.collectionEmailTemplateVersions(instanceID).FindOne(ctx, templateVersionFilter(messageType, studyKey), opts).Decode(&version)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version.Version, nil
+}
+
+// GetEmailTemplateVersions returns the version history of a template, newest first, without the template content
+func (dbService *MessagingDBService) GetEmailTemplateVersions(instanceID string, messageType string, studyKey string) ([]messagingTypes.EmailTemplateVersion, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.M{"template": 0})
+
+	versions := []messagingTypes.EmailTemplateVersion{}
+	cursor, err := dbService.collectionEmailTemplateVersions(instanceID).Find(ctx, templateVersionFilter(messageType, studyKey), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetEmailTemplateVersion returns one snapshot from the version history of a template
+func (dbService *MessagingDBService) GetEmailTemplateVersion(instanceID string, messageType string, studyKey string, version int64) (*messagingTypes.EmailTemplateVersion, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := templateVersionFilter(messageType, studyKey)
+	filter["version"] = version
+
+	var templateVersion messagingTypes.EmailTemplateVersion
+	err := dbService.collectionEmailTemplateVersions(instanceID).FindOne(ctx, filter).Decode(&templateVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &templateVersion, nil
+}
@@ -1,10 +1,15 @@
 package messaging
 
 import (
+	"context"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/cache"
+	"github.com/case-framework/case-backend/pkg/db"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 )
 
@@ -13,7 +18,7 @@ func (messagingDBService *MessagingDBService) GetGlobalEmailTemplates(instanceID
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
 
-	filter := bson.M{"studyKey": bson.M{"$exists": false}}
+	filter := db.NotDeleted(bson.M{"studyKey": bson.M{"$exists": false}})
 
 	var emailTemplates []messagingTypes.EmailTemplate
 	cursor, err := messagingDBService.collectionEmailTemplates(instanceID).Find(ctx, filter)
@@ -28,10 +33,16 @@ func (messagingDBService *MessagingDBService) GetGlobalEmailTemplates(instanceID
 
 // find one email template by message type and study key empty
 func (messagingDBService *MessagingDBService) GetGlobalEmailTemplateByMessageType(instanceID string, messageType string) (*messagingTypes.EmailTemplate, error) {
+	return cache.GetOrLoad(context.Background(), messagingDBService.cache, emailTemplateCacheKey(instanceID, "", messageType), messagingDBService.cacheTTL, func() (*messagingTypes.EmailTemplate, error) {
+		return messagingDBService.findGlobalEmailTemplateByMessageType(instanceID, messageType)
+	})
+}
+
+func (messagingDBService *MessagingDBService) findGlobalEmailTemplateByMessageType(instanceID string, messageType string) (*messagingTypes.EmailTemplate, error) {
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
 
-	filter := bson.M{"messageType": messageType, "studyKey": bson.M{"$exists": false}}
+	filter := db.NotDeleted(bson.M{"messageType": messageType, "studyKey": bson.M{"$exists": false}})
 
 	var emailTemplate messagingTypes.EmailTemplate
 	err := messagingDBService.collectionEmailTemplates(instanceID).FindOne(ctx, filter).Decode(&emailTemplate)
@@ -51,7 +62,7 @@ func (messagingDBService *MessagingDBService) GetEmailTemplateByID(instanceID st
 		return nil, err
 	}
 
-	filter := bson.M{"_id": _id}
+	filter := db.NotDeleted(bson.M{"_id": _id})
 
 	var emailTemplate messagingTypes.EmailTemplate
 	err = messagingDBService.collectionEmailTemplates(instanceID).FindOne(ctx, filter).Decode(&emailTemplate)
@@ -65,9 +76,16 @@ func (messagingDBService *MessagingDBService) GetEmailTemplateByID(instanceID st
 func (messagingDBService *MessagingDBService) SaveEmailTemplate(instanceID string, emailTemplate messagingTypes.EmailTemplate) (messagingTypes.EmailTemplate, error) {
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
+	defer messagingDBService.invalidateEmailTemplateCache(instanceID)
+	emailTemplate.DeletedAt = nil
 
 	if emailTemplate.ID.IsZero() {
 		emailTemplate.ID = primitive.NewObjectID()
+		// a new template replaces a template of the same type in the trash, since the type is unique
+		trashFilter := db.OnlyDeleted(templateVersionFilter(emailTemplate.MessageType, emailTemplate.StudyKey))
+		if _, err := messagingDBService.collectionEmailTemplates(instanceID).DeleteOne(ctx, trashFilter); err != nil {
+			return messagingTypes.EmailTemplate{}, err
+		}
 		// new email template
 		res, err := messagingDBService.collectionEmailTemplates(instanceID).InsertOne(ctx, emailTemplate)
 		if err != nil {
@@ -78,7 +96,7 @@ func (messagingDBService *MessagingDBService) SaveEmailTemplate(instanceID strin
 	}
 
 	// update email template
-	filter := bson.M{"_id": emailTemplate.ID}
+	filter := db.NotDeleted(bson.M{"_id": emailTemplate.ID})
 	upsert := false
 	after := options.After
 	opt := options.FindOneAndReplaceOptions{Upsert: &upsert, ReturnDocument: &after}
@@ -89,16 +107,15 @@ func (messagingDBService *MessagingDBService) SaveEmailTemplate(instanceID strin
 	return emailTemplate, nil
 }
 
-// delete an email template by message type and study key
+// move an email template by message type and study key to the trash
 func (messagingDBService *MessagingDBService) DeleteEmailTemplate(instanceID string, messageType string, studyKey string) error {
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
+	defer messagingDBService.invalidateEmailTemplateCache(instanceID)
 
-	filter := bson.M{"messageType": messageType, "studyKey": studyKey}
-	if studyKey == "" {
-		filter["studyKey"] = bson.M{"$exists": false}
-	}
-	_, err := messagingDBService.collectionEmailTemplates(instanceID).DeleteOne(ctx, filter)
+	filter := db.NotDeleted(templateVersionFilter(messageType, studyKey))
+	update := bson.M{"$set": bson.M{db.FIELD_DELETED_AT: time.Now()}}
+	_, err := messagingDBService.collectionEmailTemplates(instanceID).UpdateOne(ctx, filter, update)
 	return err
 }
 
@@ -107,7 +124,7 @@ func (messagingDBService *MessagingDBService) GetEmailTemplatesForAllStudies(ins
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
 
-	filter := bson.M{"studyKey": bson.M{"$exists": true}}
+	filter := db.NotDeleted(bson.M{"studyKey": bson.M{"$exists": true}})
 
 	var emailTemplates []messagingTypes.EmailTemplate
 	cursor, err := messagingDBService.collectionEmailTemplates(instanceID).Find(ctx, filter)
@@ -125,7 +142,7 @@ func (messagingDBService *MessagingDBService) GetStudyEmailTemplates(instanceID
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
 
-	filter := bson.M{"studyKey": studyKey}
+	filter := db.NotDeleted(bson.M{"studyKey": studyKey})
 
 	var emailTemplates []messagingTypes.EmailTemplate
 	cursor, err := messagingDBService.collectionEmailTemplates(instanceID).Find(ctx, filter)
@@ -140,10 +157,16 @@ func (messagingDBService *MessagingDBService) GetStudyEmailTemplates(instanceID
 
 // find one email template by message type and study key
 func (messagingDBService *MessagingDBService) GetStudyEmailTemplateByMessageType(instanceID string, studyKey string, messageType string) (*messagingTypes.EmailTemplate, error) {
+	return cache.GetOrLoad(context.Background(), messagingDBService.cache, emailTemplateCacheKey(instanceID, studyKey, messageType), messagingDBService.cacheTTL, func() (*messagingTypes.EmailTemplate, error) {
+		return messagingDBService.findStudyEmailTemplateByMessageType(instanceID, studyKey, messageType)
+	})
+}
+
+func (messagingDBService *MessagingDBService) findStudyEmailTemplateByMessageType(instanceID string, studyKey string, messageType string) (*messagingTypes.EmailTemplate, error) {
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
 
-	filter := bson.M{"messageType": messageType, "studyKey": studyKey}
+	filter := db.NotDeleted(bson.M{"messageType": messageType, "studyKey": studyKey})
 
 	var emailTemplate messagingTypes.EmailTemplate
 	err := messagingDBService.collectionEmailTemplates(instanceID).FindOne(ctx, filter).Decode(&emailTemplate)
@@ -0,0 +1,72 @@
+package messaging
+
+import (
+	"errors"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func emailTrackingStatsIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_EMAIL_TRACKING,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "campaign", Value: 1},
+						{Key: "day", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+	}
+}
+
+// IncrementEmailTrackingCounter increases a counter of the campaign's stats for the current day
+func (dbService *MessagingDBService) IncrementEmailTrackingCounter(instanceID string, campaign string, counter string, count int64) error {
+	switch counter {
+	case types.EMAIL_TRACKING_COUNTER_SENT, types.EMAIL_TRACKING_COUNTER_OPENS, types.EMAIL_TRACKING_COUNTER_CLICKS:
+	default:
+		return errors.New("unknown tracking counter")
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"campaign": campaign,
+		"day":      time.Now().UTC().Format(time.DateOnly),
+	}
+	update := bson.M{"$inc": bson.M{counter: count}}
+	_, err := dbService.collectionEmailTrackingStats(instanceID).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetEmailTrackingStats returns the daily stats, for all campaigns if campaign is empty
+func (dbService *MessagingDBService) GetEmailTrackingStats(instanceID string, campaign string, since time.Time) ([]types.EmailTrackingStats, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"day": bson.M{"$gte": since.UTC().Format(time.DateOnly)}}
+	if campaign != "" {
+		filter["campaign"] = campaign
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "campaign", Value: 1}, {Key: "day", Value: 1}})
+
+	cursor, err := dbService.collectionEmailTrackingStats(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []types.EmailTrackingStats{}
+	if err = cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func failedEmailIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_FAILED_EMAILS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{{Key: "failedAt", Value: -1}},
+				},
+			},
+		},
+	}
+}
+
+// AddToFailedEmails stores an email that could not be delivered
+func (dbService *MessagingDBService) AddToFailedEmails(instanceID string, email messagingTypes.OutgoingEmail) (messagingTypes.OutgoingEmail, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if email.FailedAt <= 0 {
+		email.FailedAt = time.Now().Unix()
+	}
+	if email.ID.IsZero() {
+		email.ID = primitive.NewObjectID()
+	}
+	email.LastSendAttempt = 0
+	email.NextAttemptAt = 0
+
+	_, err := dbService.collectionFailedEmails(instanceID).InsertOne(ctx, email)
+	return email, err
+}
+
+// MoveOutgoingToFailedEmails removes the email from the outgoing queue and stores it with the failed emails
+func (dbService *MessagingDBService) MoveOutgoingToFailedEmails(instanceID string, email messagingTypes.OutgoingEmail) error {
+	if _, err := dbService.AddToFailedEmails(instanceID, email); err != nil {
+		return err
+	}
+	return dbService.DeleteOutgoingEmail(instanceID, email.ID.Hex())
+}
+
+// GetFailedEmails returns a page of failed emails, most recent failures first
+func (dbService *MessagingDBService) GetFailedEmails(instanceID string, page int64, limit int64) (emails []messagingTypes.OutgoingEmail, totalCount int64, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	totalCount, err = dbService.collectionFailedEmails(instanceID).CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "failedAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := dbService.collectionFailedEmails(instanceID).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	emails = []messagingTypes.OutgoingEmail{}
+	if err = cursor.All(ctx, &emails); err != nil {
+		return nil, 0, err
+	}
+	return emails, totalCount, nil
+}
+
+func (dbService *MessagingDBService) GetFailedEmail(instanceID string, id string) (*messagingTypes.OutgoingEmail, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var email messagingTypes.OutgoingEmail
+	if err := dbService.collectionFailedEmails(instanceID).FindOne(ctx, bson.M{"_id": _id}).Decode(&email); err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// RequeueFailedEmail moves the failed email back to the outgoing queue with a reset retry state
+func (dbService *MessagingDBService) RequeueFailedEmail(instanceID string, id string) (messagingTypes.OutgoingEmail, error) {
+	email, err := dbService.GetFailedEmail(instanceID, id)
+	if err != nil {
+		return messagingTypes.OutgoingEmail{}, err
+	}
+
+	email.SendAttempts = 0
+	email.NextAttemptAt = 0
+	email.LastSendAttempt = 0
+	email.FailedAt = 0
+	email.LastError = ""
+	// the original expiration would prevent sending the requeued email
+	email.ExpiresAt = 0
+
+	queued, err := dbService.AddToOutgoingEmails(instanceID, *email)
+	if err != nil {
+		return queued, err
+	}
+	return queued, dbService.DeleteFailedEmail(instanceID, id)
+}
+
+func (dbService *MessagingDBService) DeleteFailedEmail(instanceID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := dbService.collectionFailedEmails(instanceID).DeleteOne(ctx, bson.M{"_id": _id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
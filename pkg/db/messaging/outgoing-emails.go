@@ -4,11 +4,29 @@ import (
 	"errors"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+func outgoingEmailIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_OUTGOING_EMAILS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "lastSendAttempt", Value: 1},
+						{Key: "nextAttemptAt", Value: 1},
+					},
+				},
+			},
+		},
+	}
+}
+
 func (dbService *MessagingDBService) AddToOutgoingEmails(instanceID string, email messagingTypes.OutgoingEmail) (messagingTypes.OutgoingEmail, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
@@ -49,7 +67,11 @@ func (dbService *MessagingDBService) GetOutgoingEmailsForSending(
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{"lastSendAttempt": bson.M{"$lt": lastSendAttemptOlderThan}}
+	filter := bson.M{
+		"lastSendAttempt": bson.M{"$lt": lastSendAttemptOlderThan},
+		// emails waiting for a retry are skipped until their backoff has passed
+		"nextAttemptAt": bson.M{"$not": bson.M{"$gt": time.Now().Unix()}},
+	}
 	if onlyHighPrio {
 		filter["highPrio"] = true
 	}
@@ -90,6 +112,28 @@ func (dbService *MessagingDBService) ResetLastSendAttemptForOutgoing(instanceID
 	return nil
 }
 
+// UpdateRetryStateForOutgoing stores the retry state after a failed attempt and releases the send lock
+func (dbService *MessagingDBService) UpdateRetryStateForOutgoing(instanceID string, email messagingTypes.OutgoingEmail) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": email.ID}
+	update := bson.M{"$set": bson.M{
+		"lastSendAttempt": 0,
+		"sendAttempts":    email.SendAttempts,
+		"nextAttemptAt":   email.NextAttemptAt,
+		"lastError":       email.LastError,
+	}}
+	res, err := dbService.collectionOutgoingEmails(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return errors.New("no outgoing email found with the given id")
+	}
+	return nil
+}
+
 func (dbService *MessagingDBService) DeleteOutgoingEmail(instanceID string, id string) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
@@ -106,3 +150,15 @@ func (dbService *MessagingDBService) DeleteOutgoingEmail(instanceID string, id s
 	}
 	return nil
 }
+
+// CountOutgoingEmails returns the number of emails waiting to be sent, including emails waiting for a retry
+func (dbService *MessagingDBService) CountOutgoingEmails(instanceID string, highPrio bool) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"highPrio": bson.M{"$ne": true}}
+	if highPrio {
+		filter = bson.M{"highPrio": true}
+	}
+	return dbService.collectionOutgoingEmails(instanceID).CountDocuments(ctx, filter)
+}
@@ -0,0 +1,87 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// captured messages are removed after this period
+const sandboxMessageRetention = 30 * 24 * time.Hour
+
+func sandboxMessageIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_SANDBOX_MESSAGES,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "capturedAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(int32(sandboxMessageRetention.Seconds())),
+				},
+				{
+					Keys: bson.D{
+						{Key: "channel", Value: 1},
+						{Key: "capturedAt", Value: -1},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (dbService *MessagingDBService) AddCapturedMessage(instanceID string, message messagingTypes.CapturedMessage) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if message.CapturedAt.IsZero() {
+		message.CapturedAt = time.Now()
+	}
+	_, err := dbService.collectionSandboxMessages(instanceID).InsertOne(ctx, message)
+	return err
+}
+
+// GetCapturedMessages returns a page of captured messages, newest first. An empty channel returns all channels.
+func (dbService *MessagingDBService) GetCapturedMessages(instanceID string, channel string, page int64, limit int64) (messages []messagingTypes.CapturedMessage, totalCount int64, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{}
+	if channel != "" {
+		filter["channel"] = channel
+	}
+
+	totalCount, err = dbService.collectionSandboxMessages(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "capturedAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := dbService.collectionSandboxMessages(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	messages = []messagingTypes.CapturedMessage{}
+	if err = cursor.All(ctx, &messages); err != nil {
+		return nil, 0, err
+	}
+	return messages, totalCount, nil
+}
+
+func (dbService *MessagingDBService) DeleteCapturedMessages(instanceID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionSandboxMessages(instanceID).DeleteMany(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -3,29 +3,35 @@ package messaging
 import (
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	"github.com/case-framework/case-backend/pkg/messaging/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (dbService *MessagingDBService) CreateSentSMSIndex(instanceID string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	_, err := dbService.collectionSentSMS(instanceID).Indexes().CreateMany(
-		ctx, []mongo.IndexModel{
-			{
-				Keys: bson.D{
-					{Key: "userID", Value: 1},
-					{Key: "sentAt", Value: 1},
-					{Key: "messageType", Value: 1},
+func sentSMSIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_SENT_SMS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "userID", Value: 1},
+						{Key: "sentAt", Value: 1},
+						{Key: "messageType", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "providerMessageID", Value: 1},
+					},
+					Options: options.Index().SetSparse(true),
 				},
 			},
 		},
-	)
-
-	return err
+	}
 }
 
 func (dbService *MessagingDBService) AddToSentSMS(instanceID string, sms types.SentSMS) (types.SentSMS, error) {
@@ -40,6 +46,25 @@ func (dbService *MessagingDBService) AddToSentSMS(instanceID string, sms types.S
 	return sms, nil
 }
 
+func (dbService *MessagingDBService) UpdateSentSMSStatus(instanceID string, providerMessageID string, status string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"providerMessageID": providerMessageID}
+	update := bson.M{"$set": bson.M{
+		"status":          status,
+		"statusUpdatedAt": time.Now(),
+	}}
+	res, err := dbService.collectionSentSMS(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (dbService *MessagingDBService) CountSentSMSForUser(instanceID string, userID string, messageType string, sentAfter time.Time) (int64, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
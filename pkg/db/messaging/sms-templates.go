@@ -6,23 +6,24 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 )
 
-func (messagingDBService *MessagingDBService) CreateIndexForSMSTemplates(instanceID string) error {
-	ctx, cancel := messagingDBService.getContext()
-	defer cancel()
-
-	_, err := messagingDBService.collectionSMSTemplates(instanceID).Indexes().CreateOne(
-		ctx,
-		mongo.IndexModel{
-			Keys: bson.D{
-				{Key: "messageType", Value: 1},
+func smsTemplateIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_SMS_TEMPLATES,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "messageType", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
 			},
-			Options: options.Index().SetUnique(true),
 		},
-	)
-	return err
+	}
 }
 
 // save email template (if id is empty, insert, else update)
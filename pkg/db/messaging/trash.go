@@ -0,0 +1,67 @@
+package messaging
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+// GetDeletedEmailTemplates returns the email templates in the trash, global and study templates
+func (dbService *MessagingDBService) GetDeletedEmailTemplates(instanceID string) ([]messagingTypes.EmailTemplate, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: db.FIELD_DELETED_AT, Value: -1}})
+	cursor, err := dbService.collectionEmailTemplates(instanceID).Find(ctx, db.OnlyDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var emailTemplates []messagingTypes.EmailTemplate
+	if err = cursor.All(ctx, &emailTemplates); err != nil {
+		return nil, err
+	}
+	return emailTemplates, nil
+}
+
+// RestoreEmailTemplate moves the email template out of the trash
+func (dbService *MessagingDBService) RestoreEmailTemplate(instanceID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateEmailTemplateCache(instanceID)
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := db.OnlyDeleted(bson.M{"_id": _id})
+	update := bson.M{"$unset": bson.M{db.FIELD_DELETED_AT: ""}}
+	res, err := dbService.collectionEmailTemplates(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// PurgeEmailTemplatesDeletedBefore permanently deletes the email templates moved to the trash before the cutoff, the
+// version history of the templates is kept
+func (dbService *MessagingDBService) PurgeEmailTemplatesDeletedBefore(instanceID string, cutoff time.Time) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionEmailTemplates(instanceID).DeleteMany(ctx, db.DeletedBefore(bson.M{}, cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,283 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// delivery logs are removed after this period
+const webhookDeliveryRetention = 90 * 24 * time.Hour
+
+func webhookIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_WEBHOOK_ENDPOINTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "enabled", Value: 1},
+						{Key: "events", Value: 1},
+					},
+				},
+			},
+		},
+		{
+			Collection: COLLECTION_NAME_WEBHOOK_DELIVERIES,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "status", Value: 1},
+						{Key: "nextAttemptAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "endpointId", Value: 1},
+						{Key: "createdAt", Value: -1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "createdAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(int32(webhookDeliveryRetention.Seconds())),
+				},
+			},
+		},
+	}
+}
+
+func (dbService *MessagingDBService) GetWebhookEndpoints(instanceID string) ([]messagingTypes.WebhookEndpoint, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := dbService.collectionWebhookEndpoints(instanceID).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []messagingTypes.WebhookEndpoint{}
+	if err = cursor.All(ctx, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// GetWebhookEndpointsForEvent returns the enabled endpoints subscribed to the event
+func (dbService *MessagingDBService) GetWebhookEndpointsForEvent(instanceID string, event string) ([]messagingTypes.WebhookEndpoint, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	cursor, err := dbService.collectionWebhookEndpoints(instanceID).Find(ctx, bson.M{"enabled": true, "events": event})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []messagingTypes.WebhookEndpoint{}
+	if err = cursor.All(ctx, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (dbService *MessagingDBService) GetWebhookEndpoint(instanceID string, id string) (*messagingTypes.WebhookEndpoint, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var endpoint messagingTypes.WebhookEndpoint
+	if err := dbService.collectionWebhookEndpoints(instanceID).FindOne(ctx, bson.M{"_id": _id}).Decode(&endpoint); err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// SaveWebhookEndpoint creates the endpoint if it has no ID yet, otherwise updates it while keeping its secret
+func (dbService *MessagingDBService) SaveWebhookEndpoint(instanceID string, endpoint messagingTypes.WebhookEndpoint) (messagingTypes.WebhookEndpoint, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	endpoint.UpdatedAt = time.Now().Unix()
+	if endpoint.ID.IsZero() {
+		endpoint.ID = primitive.NewObjectID()
+		endpoint.CreatedAt = endpoint.UpdatedAt
+		_, err := dbService.collectionWebhookEndpoints(instanceID).InsertOne(ctx, endpoint)
+		return endpoint, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"url":         endpoint.URL,
+		"description": endpoint.Description,
+		"events":      endpoint.Events,
+		"enabled":     endpoint.Enabled,
+		"updatedAt":   endpoint.UpdatedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var saved messagingTypes.WebhookEndpoint
+	err := dbService.collectionWebhookEndpoints(instanceID).FindOneAndUpdate(ctx, bson.M{"_id": endpoint.ID}, update, opts).Decode(&saved)
+	return saved, err
+}
+
+func (dbService *MessagingDBService) DeleteWebhookEndpoint(instanceID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	res, err := dbService.collectionWebhookEndpoints(instanceID).DeleteOne(ctx, bson.M{"_id": _id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *MessagingDBService) AddWebhookDelivery(instanceID string, delivery messagingTypes.WebhookDelivery) (messagingTypes.WebhookDelivery, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	_, err := dbService.collectionWebhookDeliveries(instanceID).InsertOne(ctx, delivery)
+	return delivery, err
+}
+
+// ClaimDueWebhookDelivery returns a pending delivery that is due and moves its next attempt to leaseUntil,
+// so that no other worker picks it up meanwhile. Returns nil if no delivery is due.
+func (dbService *MessagingDBService) ClaimDueWebhookDelivery(instanceID string, now int64, leaseUntil int64) (*messagingTypes.WebhookDelivery, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"status":        messagingTypes.WEBHOOK_DELIVERY_STATUS_PENDING,
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"nextAttemptAt": leaseUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery messagingTypes.WebhookDelivery
+	err := dbService.collectionWebhookDeliveries(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// UpdateWebhookDeliveryState stores the outcome of a delivery attempt
+func (dbService *MessagingDBService) UpdateWebhookDeliveryState(instanceID string, delivery messagingTypes.WebhookDelivery) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"status":             delivery.Status,
+		"attempts":           delivery.Attempts,
+		"nextAttemptAt":      delivery.NextAttemptAt,
+		"lastError":          delivery.LastError,
+		"lastResponseStatus": delivery.LastResponseStatus,
+		"deliveredAt":        delivery.DeliveredAt,
+	}}
+	_, err := dbService.collectionWebhookDeliveries(instanceID).UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+	return err
+}
+
+// GetWebhookDeliveries returns a page of the delivery log of an endpoint, newest first
+func (dbService *MessagingDBService) GetWebhookDeliveries(instanceID string, endpointID string, status string, page int64, limit int64) (deliveries []messagingTypes.WebhookDelivery, totalCount int64, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"endpointId": endpointID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	totalCount, err = dbService.collectionWebhookDeliveries(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := dbService.collectionWebhookDeliveries(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deliveries = []messagingTypes.WebhookDelivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, 0, err
+	}
+	return deliveries, totalCount, nil
+}
+
+// ResetWebhookDelivery queues the delivery of the endpoint again for immediate sending
+func (dbService *MessagingDBService) ResetWebhookDelivery(instanceID string, endpointID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{
+		"status":        messagingTypes.WEBHOOK_DELIVERY_STATUS_PENDING,
+		"attempts":      0,
+		"nextAttemptAt": time.Now().Unix(),
+		"lastError":     "",
+	}}
+	res, err := dbService.collectionWebhookDeliveries(instanceID).UpdateOne(ctx, bson.M{"_id": _id, "endpointId": endpointID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// UpdateWebhookDeliveryBody sets the payload of a delivery, which contains the delivery ID itself
+func (dbService *MessagingDBService) UpdateWebhookDeliveryBody(instanceID string, id primitive.ObjectID, body string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionWebhookDeliveries(instanceID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"body": body}})
+	return err
+}
+
+func (dbService *MessagingDBService) UpdateWebhookEndpointSecret(instanceID string, id primitive.ObjectID, secret string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"secret": secret, "updatedAt": time.Now().Unix()}}
+	res, err := dbService.collectionWebhookEndpoints(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// COLLECTION_NAME_MIGRATIONS tracks the migrations applied to a database
+const COLLECTION_NAME_MIGRATIONS = "migrations"
+
+const (
+	MIGRATION_STATUS_RUNNING = "running"
+	MIGRATION_STATUS_APPLIED = "applied"
+)
+
+// Migration changes data of a database from the previous version to Version. Migrations are applied in order of their
+// version and only once per database, a failed migration is retried on the next run, so it must be safe to re-run.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, database *mongo.Database) error
+}
+
+// AppliedMigration is the tracking entry of a migration
+type AppliedMigration struct {
+	Version    int    `bson:"version" json:"version"`
+	Name       string `bson:"name" json:"name"`
+	Status     string `bson:"status" json:"status"`
+	StartedAt  int64  `bson:"startedAt" json:"startedAt"`
+	AppliedAt  int64  `bson:"appliedAt,omitempty" json:"appliedAt,omitempty"`
+	DurationMs int64  `bson:"durationMs,omitempty" json:"durationMs,omitempty"`
+}
+
+type MigrationOptions struct {
+	// only report pending migrations
+	DryRun bool
+	// apply migrations up to and including this version, 0 for all
+	TargetVersion int
+}
+
+// MigrationReport is the result of ApplyMigrations for one database
+type MigrationReport struct {
+	DBName string `json:"dbName"`
+	DryRun bool   `json:"dryRun"`
+	// highest applied version before the run
+	CurrentVersion int                `json:"currentVersion"`
+	Pending        []string           `json:"pending,omitempty"`
+	Applied        []AppliedMigration `json:"applied,omitempty"`
+	Error          string             `json:"error,omitempty"`
+}
+
+// HasErrors is true if the applied migrations could not be read or a migration failed
+func (r MigrationReport) HasErrors() bool {
+	return r.Error != ""
+}
+
+// Log writes the applied and pending migrations and the error of the run
+func (r MigrationReport) Log() {
+	attrs := []any{slog.String("db", r.DBName), slog.Int("currentVersion", r.CurrentVersion)}
+	for _, m := range r.Applied {
+		slog.Info("applied migration", append(attrs, slog.Int("version", m.Version), slog.String("name", m.Name), slog.Int64("durationMs", m.DurationMs))...)
+	}
+	if r.Error != "" {
+		slog.Error("failed to apply migrations", append(attrs, slog.String("error", r.Error))...)
+		return
+	}
+	if r.DryRun && len(r.Pending) > 0 {
+		slog.Info("pending migrations", append(attrs, slog.Any("pending", r.Pending))...)
+	}
+}
+
+// MigrationLabel is used to list migrations in reports, e.g. "3_rename-contact-infos"
+func MigrationLabel(m Migration) string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// ValidateMigrations checks that versions are positive and strictly increasing and that each migration has a name
+// and a function
+func ValidateMigrations(migrations []Migration) error {
+	last := 0
+	for _, m := range migrations {
+		if m.Version <= last {
+			return fmt.Errorf("migration %s: versions must be positive and strictly increasing", MigrationLabel(m))
+		}
+		if m.Name == "" {
+			return fmt.Errorf("migration %d has no name", m.Version)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migration %s has no function", MigrationLabel(m))
+		}
+		last = m.Version
+	}
+	return nil
+}
+
+// PendingMigrations returns the migrations after the current version, up to the target version if set
+func PendingMigrations(migrations []Migration, currentVersion int, targetVersion int) []Migration {
+	pending := []Migration{}
+	for _, m := range migrations {
+		if m.Version <= currentVersion {
+			continue
+		}
+		if targetVersion > 0 && m.Version > targetVersion {
+			break
+		}
+		pending = append(pending, m)
+	}
+	return pending
+}
+
+// ApplyMigrations runs the pending migrations of the registry in order and stops at the first failure. The tracking
+// entry of a migration is inserted before it runs, so that a second process applying migrations to the same database
+// stops instead of running it twice. The context is used for the whole run, migrations are not limited by the DB
+// timeout.
+func ApplyMigrations(ctx context.Context, database *mongo.Database, migrations []Migration, opts MigrationOptions) MigrationReport {
+	report := MigrationReport{
+		DBName: database.Name(),
+		DryRun: opts.DryRun,
+	}
+	if err := ValidateMigrations(migrations); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	collection := database.Collection(COLLECTION_NAME_MIGRATIONS)
+	currentVersion, err := currentMigrationVersion(ctx, collection)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.CurrentVersion = currentVersion
+
+	pending := PendingMigrations(migrations, currentVersion, opts.TargetVersion)
+	for _, m := range pending {
+		report.Pending = append(report.Pending, MigrationLabel(m))
+	}
+	if opts.DryRun || len(pending) == 0 {
+		return report
+	}
+
+	// the unique version prevents that two processes insert the tracking entry of the same migration
+	if _, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	for _, m := range pending {
+		applied, err := applyMigration(ctx, database, collection, m)
+		if err != nil {
+			report.Error = fmt.Sprintf("migration %s: %s", MigrationLabel(m), err.Error())
+			return report
+		}
+		report.Applied = append(report.Applied, applied)
+		report.Pending = report.Pending[1:]
+	}
+	return report
+}
+
+// currentMigrationVersion is the highest version with a tracking entry, an entry of a running migration counts
+// as well so that it is not started twice
+func currentMigrationVersion(ctx context.Context, collection *mongo.Collection) (int, error) {
+	var last AppliedMigration
+	err := collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})).Decode(&last)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if last.Status == MIGRATION_STATUS_RUNNING {
+		return 0, fmt.Errorf("migration %d_%s is running since %s", last.Version, last.Name, time.Unix(last.StartedAt, 0).UTC().Format(time.RFC3339))
+	}
+	return last.Version, nil
+}
+
+func applyMigration(ctx context.Context, database *mongo.Database, collection *mongo.Collection, m Migration) (AppliedMigration, error) {
+	start := time.Now()
+	entry := AppliedMigration{
+		Version:   m.Version,
+		Name:      m.Name,
+		Status:    MIGRATION_STATUS_RUNNING,
+		StartedAt: start.Unix(),
+	}
+	if _, err := collection.InsertOne(ctx, entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return entry, errors.New("already applied or started by another process")
+		}
+		return entry, err
+	}
+
+	if err := m.Up(ctx, database); err != nil {
+		// remove the entry, so that the migration is retried on the next run
+		if _, delErr := collection.DeleteOne(context.Background(), bson.M{"version": m.Version, "status": MIGRATION_STATUS_RUNNING}); delErr != nil {
+			slog.Error("failed to remove tracking entry of failed migration", slog.String("db", database.Name()), slog.Int("version", m.Version), slog.String("error", delErr.Error()))
+		}
+		return entry, err
+	}
+
+	entry.Status = MIGRATION_STATUS_APPLIED
+	entry.AppliedAt = time.Now().Unix()
+	entry.DurationMs = time.Since(start).Milliseconds()
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"version": m.Version},
+		bson.M{"$set": bson.M{
+			"status":     entry.Status,
+			"appliedAt":  entry.AppliedAt,
+			"durationMs": entry.DurationMs,
+		}},
+	)
+	return entry, err
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func testMigration(version int, name string) Migration {
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up:      func(ctx context.Context, database *mongo.Database) error { return nil },
+	}
+}
+
+func TestValidateMigrations(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		if err := ValidateMigrations([]Migration{testMigration(1, "a"), testMigration(2, "b"), testMigration(5, "c")}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := map[string][]Migration{
+			"zero version":      {testMigration(0, "a")},
+			"duplicate version": {testMigration(1, "a"), testMigration(1, "b")},
+			"wrong order":       {testMigration(2, "a"), testMigration(1, "b")},
+			"no name":           {testMigration(1, "")},
+			"no function":       {{Version: 1, Name: "a"}},
+		}
+		for name, migrations := range tests {
+			if err := ValidateMigrations(migrations); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+func TestPendingMigrations(t *testing.T) {
+	migrations := []Migration{testMigration(1, "a"), testMigration(2, "b"), testMigration(3, "c")}
+
+	tests := []struct {
+		name           string
+		currentVersion int
+		targetVersion  int
+		want           []string
+	}{
+		{name: "new DB", currentVersion: 0, targetVersion: 0, want: []string{"1_a", "2_b", "3_c"}},
+		{name: "partially migrated", currentVersion: 1, targetVersion: 0, want: []string{"2_b", "3_c"}},
+		{name: "up to date", currentVersion: 3, targetVersion: 0, want: []string{}},
+		{name: "target version", currentVersion: 0, targetVersion: 2, want: []string{"1_a", "2_b"}},
+		{name: "target below current", currentVersion: 2, targetVersion: 1, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := PendingMigrations(migrations, tt.currentVersion, tt.targetVersion)
+			if len(pending) != len(tt.want) {
+				t.Fatalf("got %d migrations, want %v", len(pending), tt.want)
+			}
+			for i, m := range pending {
+				if MigrationLabel(m) != tt.want[i] {
+					t.Errorf("got %s, want %s", MigrationLabel(m), tt.want[i])
+				}
+			}
+		})
+	}
+}
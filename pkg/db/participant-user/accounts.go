@@ -0,0 +1,133 @@
+package participantuser
+
+import (
+	"context"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Account writes that touch several collections of the participant user DB. They run in a transaction if the
+// deployment supports it. Temp tokens are stored in the global infos DB and cannot be part of these transactions.
+
+// CreateUserWithRenewToken adds the user and its first renew token, the user is not created if the token cannot be
+// saved
+func (dbService *ParticipantUserDBService) CreateUserWithRenewToken(instanceID string, user umTypes.User, renewToken string) (id string, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	err = db.RunInTransaction(ctx, dbService.DBClient, dbService.useTransactions, func(ctx context.Context) error {
+		id, err = dbService.addUser(ctx, instanceID, user)
+		if err != nil {
+			return err
+		}
+		return dbService.createRenewToken(ctx, instanceID, id, renewToken, 0)
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// RotateRenewToken marks the renew token as used, saves its successor and updates the refresh timestamps of the
+// user. It returns the renew token to use next: the given nextToken, or the successor saved when the token was used
+// before within the grace period.
+func (dbService *ParticipantUserDBService) RotateRenewToken(instanceID string, userID string, renewToken string, nextToken string) (string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	newRenewToken := nextToken
+	err := db.RunInTransaction(ctx, dbService.DBClient, dbService.useTransactions, func(ctx context.Context) error {
+		rt, err := dbService.findAndUpdateRenewToken(ctx, instanceID, userID, renewToken, nextToken)
+		if err != nil {
+			return err
+		}
+
+		if rt.NextToken == nextToken {
+			// this is the first time the refresh token is used
+			if err := dbService.createRenewToken(ctx, instanceID, userID, nextToken, 0); err != nil {
+				return err
+			}
+			newRenewToken = nextToken
+		} else {
+			newRenewToken = rt.NextToken
+		}
+
+		// reset marked for deletion, the user is active
+		return dbService.updateUser(ctx, instanceID, userID, bson.M{
+			"$set": bson.M{
+				"timestamps.lastTokenRefresh":  time.Now().Unix(),
+				"timestamps.markedForDeletion": 0,
+			},
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return newRenewToken, nil
+}
+
+// DeleteUserAccount removes the user together with its renew tokens and OTPs
+func (dbService *ParticipantUserDBService) DeleteUserAccount(instanceID string, userID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	return db.RunInTransaction(ctx, dbService.DBClient, dbService.useTransactions, func(ctx context.Context) error {
+		if _, err := dbService.deleteRenewTokensForUser(ctx, instanceID, userID); err != nil {
+			return err
+		}
+		if err := dbService.deleteOTPs(ctx, instanceID, userID); err != nil {
+			return err
+		}
+		return dbService.deleteUser(ctx, instanceID, userID)
+	})
+}
+
+// DeleteUserAccounts removes the users together with their renew tokens and OTPs in batches, each batch in its own
+// transaction. Invalid IDs are skipped. Returns the number of deleted users.
+func (dbService *ParticipantUserDBService) DeleteUserAccounts(instanceID string, userIDs []string, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = db.DEFAULT_BULK_WRITE_BATCH_SIZE
+	}
+
+	var deleted int64
+	for start := 0; start < len(userIDs); start += batchSize {
+		batch := userIDs[start:min(start+batchSize, len(userIDs))]
+		objectIDs := make([]primitive.ObjectID, 0, len(batch))
+		for _, userID := range batch {
+			_id, err := primitive.ObjectIDFromHex(userID)
+			if err != nil {
+				continue
+			}
+			objectIDs = append(objectIDs, _id)
+		}
+
+		// the transaction callback can be retried, the count is only taken from the last run
+		var batchDeleted int64
+		ctx, cancel := dbService.getContext()
+		err := db.RunInTransaction(ctx, dbService.DBClient, dbService.useTransactions, func(ctx context.Context) error {
+			filter := bson.M{"userID": bson.M{"$in": batch}}
+			if _, err := dbService.collectionRenewTokens(instanceID).DeleteMany(ctx, filter); err != nil {
+				return err
+			}
+			if _, err := dbService.collectionOTPs(instanceID).DeleteMany(ctx, filter); err != nil {
+				return err
+			}
+			res, err := dbService.collectionParticipantUsers(instanceID).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": objectIDs}})
+			if err != nil {
+				return err
+			}
+			batchDeleted = res.DeletedCount
+			return nil
+		})
+		cancel()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += batchDeleted
+	}
+	return deleted, nil
+}
@@ -0,0 +1,112 @@
+package participantuser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+)
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017, the transaction cases need a replica set
+func TestAccountWrites(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	instanceID := "test"
+	dbService, err := NewParticipantUserDBService(db.DBConfig{
+		URI:              uri,
+		DBNamePrefix:     fmt.Sprintf("participant_user_test_%d_", time.Now().UnixNano()),
+		Timeout:          10,
+		InstanceIDs:      []string{instanceID},
+		RunIndexCreation: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	supportsTransactions := dbService.useTransactions
+	t.Cleanup(func() {
+		_ = dbService.DBClient.Database(dbService.getDBName(instanceID)).Drop(context.Background())
+		_ = dbService.DBClient.Disconnect(context.Background())
+	})
+
+	renewTokenCount := func(userID string) int64 {
+		ctx, cancel := dbService.getContext()
+		defer cancel()
+		n, err := dbService.collectionRenewTokens(instanceID).CountDocuments(ctx, bson.M{"userID": userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	newUser := func(accountID string) umTypes.User {
+		return umTypes.User{Account: umTypes.Account{Type: "email", AccountID: accountID}}
+	}
+
+	userID, err := dbService.CreateUserWithRenewToken(instanceID, newUser("first@example.com"), "token-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewTokenCount(userID) != 1 {
+		t.Error("renew token of the new user missing")
+	}
+
+	// the renew token is unique, so the second write of the account fails
+	t.Run("standalone fallback keeps the user", func(t *testing.T) {
+		dbService.useTransactions = false
+		defer func() { dbService.useTransactions = supportsTransactions }()
+
+		if _, err := dbService.CreateUserWithRenewToken(instanceID, newUser("fallback@example.com"), "token-1"); err == nil {
+			t.Fatal("expected error for duplicate renew token")
+		}
+		if _, err := dbService.GetUserByAccountID(instanceID, "fallback@example.com"); err != nil {
+			t.Errorf("writes without transaction should not be rolled back: %v", err)
+		}
+	})
+
+	t.Run("transaction is aborted", func(t *testing.T) {
+		if !supportsTransactions {
+			t.Skip("server does not support transactions")
+		}
+		if _, err := dbService.CreateUserWithRenewToken(instanceID, newUser("second@example.com"), "token-1"); err == nil {
+			t.Fatal("expected error for duplicate renew token")
+		}
+		if _, err := dbService.GetUserByAccountID(instanceID, "second@example.com"); err == nil {
+			t.Error("user created although its renew token could not be saved")
+		}
+	})
+
+	t.Run("rotate renew token", func(t *testing.T) {
+		next, err := dbService.RotateRenewToken(instanceID, userID, "token-1", "token-2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// retries within the grace period get the same successor
+		retried, err := dbService.RotateRenewToken(instanceID, userID, "token-1", "token-3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next != "token-2" || retried != "token-2" || renewTokenCount(userID) != 2 {
+			t.Errorf("unexpected tokens %s, %s and count %d", next, retried, renewTokenCount(userID))
+		}
+	})
+
+	t.Run("delete account", func(t *testing.T) {
+		if err := dbService.DeleteUserAccount(instanceID, userID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbService.GetUser(instanceID, userID); err == nil {
+			t.Error("user not deleted")
+		}
+		if renewTokenCount(userID) != 0 {
+			t.Error("renew tokens not deleted")
+		}
+	})
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/case-framework/case-backend/pkg/db"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // collection names
@@ -24,17 +23,20 @@ type ParticipantUserDBService struct {
 	noCursorTimeout bool
 	DBNamePrefix    string
 	InstanceIDs     []string
+	// multi-document transactions are used if the deployment supports them
+	useTransactions bool
 }
 
 func NewParticipantUserDBService(configs db.DBConfig) (*ParticipantUserDBService, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(configs.Timeout)*time.Second)
 	defer cancel()
 
-	dbClient, err := mongo.Connect(ctx,
-		options.Client().ApplyURI(configs.URI),
-		options.Client().SetMaxConnIdleTime(time.Duration(configs.IdleConnTimeout)*time.Second),
-		options.Client().SetMaxPoolSize(configs.MaxPoolSize),
-	)
+	clientOptions, err := db.ClientOptions(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	dbClient, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
 		return nil, err
@@ -54,6 +56,15 @@ func NewParticipantUserDBService(configs db.DBConfig) (*ParticipantUserDBService
 		noCursorTimeout: configs.NoCursorTimeout,
 		DBNamePrefix:    configs.DBNamePrefix,
 		InstanceIDs:     configs.InstanceIDs,
+		useTransactions: db.SupportsTransactions(ctx, dbClient),
+	}
+	if !puDBSc.useTransactions {
+		slog.Warn("participant user DB does not support transactions, account writes are not atomic")
+	}
+
+	// migrations run first, so that indexes are created on migrated data
+	if configs.RunMigrations {
+		puDBSc.applyMigrations()
 	}
 
 	if configs.RunIndexCreation {
@@ -66,6 +77,22 @@ func (dbService *ParticipantUserDBService) getDBName(instanceID string) string {
 	return dbService.DBNamePrefix + instanceID + "_users"
 }
 
+// Ping checks the connection to the participant user DB, it can be called on a nil service
+func (dbService *ParticipantUserDBService) Ping(ctx context.Context) error {
+	if dbService == nil {
+		return db.Ping(ctx, nil)
+	}
+	return db.Ping(ctx, dbService.DBClient)
+}
+
+// Close disconnects the client, called on shutdown after running requests are finished
+func (dbService *ParticipantUserDBService) Close(ctx context.Context) error {
+	if dbService == nil {
+		return nil
+	}
+	return db.Disconnect(ctx, dbService.DBClient)
+}
+
 func (dbService *ParticipantUserDBService) getContext() (ctx context.Context, cancel context.CancelFunc) {
 	return context.WithTimeout(context.Background(), time.Duration(dbService.timeout)*time.Second)
 }
@@ -86,34 +113,24 @@ func (dbService *ParticipantUserDBService) collectionFailedOtpAttempts(instanceI
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_FAILED_OTP_ATTEMPTS)
 }
 
+// indexRegistry lists the indexes of all collections of the DB
+func indexRegistry() []db.CollectionIndexes {
+	registry := []db.CollectionIndexes{}
+	registry = append(registry, participantUserIndexes()...)
+	registry = append(registry, renewTokenIndexes()...)
+	registry = append(registry, otpIndexes()...)
+	registry = append(registry, failedOtpAttemptIndexes()...)
+	return registry
+}
+
+// EnsureIndexes creates missing indexes in the DB of the instance and reports indexes that are not in the registry
+func (dbService *ParticipantUserDBService) EnsureIndexes(instanceID string, opts db.EnsureIndexesOptions) db.IndexReport {
+	return db.EnsureIndexes(dbService.getContext, dbService.DBClient.Database(dbService.getDBName(instanceID)), indexRegistry(), opts)
+}
+
 func (dbService *ParticipantUserDBService) ensureIndexes() {
 	slog.Debug("Ensuring indexes for participant user DB")
 	for _, instanceID := range dbService.InstanceIDs {
-
-		err := dbService.CreateIndexForParticipantUsers(instanceID)
-		if err != nil {
-			slog.Debug("Error creating indexes for participant users: ", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
-
-		err = dbService.CreateIndexForRenewTokens(instanceID)
-		if err != nil {
-			slog.Debug("Error creating indexes for renew tokens: ", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
-
-		err = dbService.CreateIndexForOTPs(instanceID)
-		if err != nil {
-			slog.Debug("Error creating indexes for OTPs: ", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
-
-		err = dbService.CreateIndexForFailedOtpAttempts(instanceID)
-		if err != nil {
-			slog.Debug("Error creating indexes for failed OTP attempts: ", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
-
-		// Fix field name for contactInfos
-		err = dbService.FixFieldNameForContactInfos(instanceID)
-		if err != nil {
-			slog.Debug("Error fixing field name for contactInfos: ", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
+		dbService.EnsureIndexes(instanceID, db.EnsureIndexesOptions{}).Log()
 	}
 }
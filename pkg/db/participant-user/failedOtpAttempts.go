@@ -3,6 +3,7 @@ package participantuser
 import (
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -17,25 +18,25 @@ type FailedOtpAttempt struct {
 	UserID    string    `json:"userId" bson:"userID"`
 }
 
-func (dbService *ParticipantUserDBService) CreateIndexForFailedOtpAttempts(instanceID string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-	_, err := dbService.collectionFailedOtpAttempts(instanceID).Indexes().CreateMany(
-		ctx, []mongo.IndexModel{
-			{
-				Keys: bson.D{
-					{Key: "userID", Value: 1},
+func failedOtpAttemptIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_FAILED_OTP_ATTEMPTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "userID", Value: 1},
+					},
 				},
-			},
-			{
-				Keys: bson.D{
-					{Key: "timestamp", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "timestamp", Value: 1},
+					},
+					Options: options.Index().SetExpireAfterSeconds(FAILED_OTP_ATTEMP_WINDOW),
 				},
-				Options: options.Index().SetExpireAfterSeconds(FAILED_OTP_ATTEMP_WINDOW),
 			},
 		},
-	)
-	return err
+	}
 }
 
 func (dbService *ParticipantUserDBService) CountFailedOtpAttempts(instanceID string, userID string) (int64, error) {
@@ -0,0 +1,39 @@
+package participantuser
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migrations of the participant user DB, append new migrations with the next version
+func migrations() []db.Migration {
+	return []db.Migration{
+		{
+			Version: 1,
+			Name:    "rename-contactinfos",
+			Up: func(ctx context.Context, database *mongo.Database) error {
+				_, err := database.Collection(COLLECTION_NAME_PARTICIPANT_USERS).UpdateMany(ctx,
+					bson.M{"contactinfos": bson.M{"$exists": true}},
+					bson.M{"$rename": bson.M{"contactinfos": "contactInfos"}},
+				)
+				return err
+			},
+		},
+	}
+}
+
+// ApplyMigrations runs the pending migrations on the DB of the instance
+func (dbService *ParticipantUserDBService) ApplyMigrations(ctx context.Context, instanceID string, opts db.MigrationOptions) db.MigrationReport {
+	return db.ApplyMigrations(ctx, dbService.DBClient.Database(dbService.getDBName(instanceID)), migrations(), opts)
+}
+
+func (dbService *ParticipantUserDBService) applyMigrations() {
+	slog.Debug("Applying migrations for participant user DB")
+	for _, instanceID := range dbService.InstanceIDs {
+		dbService.ApplyMigrations(context.Background(), instanceID, db.MigrationOptions{}).Log()
+	}
+}
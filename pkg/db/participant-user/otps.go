@@ -1,6 +1,7 @@
 package participantuser
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 )
 
@@ -15,28 +17,27 @@ const (
 	OTP_TTL = 60 * 15
 )
 
-func (dbService *ParticipantUserDBService) CreateIndexForOTPs(instanceID string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	_, err := dbService.collectionOTPs(instanceID).Indexes().CreateMany(
-		ctx, []mongo.IndexModel{
-			{
-				Keys: bson.D{
-					{Key: "userID", Value: 1},
-					{Key: "code", Value: 1},
+func otpIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_OTPS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "userID", Value: 1},
+						{Key: "code", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
 				},
-				Options: options.Index().SetUnique(true),
-			},
-			{
-				Keys: bson.D{
-					{Key: "createdAt", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "createdAt", Value: 1},
+					},
+					Options: options.Index().SetExpireAfterSeconds(OTP_TTL),
 				},
-				Options: options.Index().SetExpireAfterSeconds(OTP_TTL),
 			},
 		},
-	)
-	return err
+	}
 }
 
 func (dbService *ParticipantUserDBService) CreateOTP(instanceID string, userID string, code string, t userTypes.OTPType, maxOTPCount int64) error {
@@ -97,6 +98,10 @@ func (dbService *ParticipantUserDBService) DeleteOTPs(instanceID string, userID
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
+	return dbService.deleteOTPs(ctx, instanceID, userID)
+}
+
+func (dbService *ParticipantUserDBService) deleteOTPs(ctx context.Context, instanceID string, userID string) error {
 	filter := bson.M{"userID": userID}
 	_, err := dbService.collectionOTPs(instanceID).DeleteMany(ctx, filter)
 	return err
@@ -1,6 +1,7 @@
 package participantuser
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 )
 
@@ -16,40 +18,43 @@ const (
 	RENEW_TOKEN_DEFAULT_LIFETIME = 60 * 60 * 24 * 90
 )
 
-func (dbService *ParticipantUserDBService) CreateIndexForRenewTokens(instanceID string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	_, err := dbService.collectionRenewTokens(instanceID).Indexes().CreateMany(
-		ctx, []mongo.IndexModel{
-			{
-				Keys: bson.D{
-					{Key: "userID", Value: 1},
-					{Key: "renewToken", Value: 1},
-					{Key: "expiresAt", Value: 1},
+func renewTokenIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_RENEW_TOKENS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "userID", Value: 1},
+						{Key: "renewToken", Value: 1},
+						{Key: "expiresAt", Value: 1},
+					},
 				},
-			},
-			{
-				Keys: bson.D{
-					{Key: "expiresAt", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "expiresAt", Value: 1},
+					},
+					Options: options.Index().SetExpireAfterSeconds(RENEW_TOKEN_GRACE_PERIOD),
 				},
-				Options: options.Index().SetExpireAfterSeconds(RENEW_TOKEN_GRACE_PERIOD),
-			},
-			{
-				Keys: bson.D{
-					{Key: "renewToken", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "renewToken", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
 				},
-				Options: options.Index().SetUnique(true),
 			},
 		},
-	)
-	return err
+	}
 }
 
 func (dbService *ParticipantUserDBService) CreateRenewToken(instanceID string, userID string, token string, lifeTimeInSec int) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
+	return dbService.createRenewToken(ctx, instanceID, userID, token, lifeTimeInSec)
+}
+
+func (dbService *ParticipantUserDBService) createRenewToken(ctx context.Context, instanceID string, userID string, token string, lifeTimeInSec int) error {
 	ttl := time.Duration(lifeTimeInSec) * time.Second
 	if lifeTimeInSec <= 0 {
 		ttl = time.Duration(RENEW_TOKEN_DEFAULT_LIFETIME) * time.Second
@@ -80,10 +85,14 @@ func (dbService *ParticipantUserDBService) DeleteRenewTokenByToken(instanceID st
 }
 
 func (dbService *ParticipantUserDBService) DeleteRenewTokensForUser(instanceID string, userID string) (int64, error) {
-	filter := bson.M{"userID": userID}
-
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+
+	return dbService.deleteRenewTokensForUser(ctx, instanceID, userID)
+}
+
+func (dbService *ParticipantUserDBService) deleteRenewTokensForUser(ctx context.Context, instanceID string, userID string) (int64, error) {
+	filter := bson.M{"userID": userID}
 	res, err := dbService.collectionRenewTokens(instanceID).DeleteMany(ctx, filter, nil)
 	if err != nil {
 		return 0, err
@@ -95,6 +104,10 @@ func (dbService *ParticipantUserDBService) FindAndUpdateRenewToken(instanceID st
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
+	return dbService.findAndUpdateRenewToken(ctx, instanceID, userID, renewToken, nextToken)
+}
+
+func (dbService *ParticipantUserDBService) findAndUpdateRenewToken(ctx context.Context, instanceID string, userID string, renewToken string, nextToken string) (rtObj userTypes.RenewToken, err error) {
 	filter := bson.M{"userID": userID, "renewToken": renewToken, "expiresAt": bson.M{"$gt": time.Now()}}
 	updatePipeline := bson.A{
 		bson.M{
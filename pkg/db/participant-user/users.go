@@ -11,65 +11,77 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 )
 
-func (dbService *ParticipantUserDBService) CreateIndexForParticipantUsers(instanceID string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	_, err := dbService.collectionParticipantUsers(instanceID).Indexes().CreateMany(
-		ctx, []mongo.IndexModel{
-			{
-				Keys: bson.D{
-					{Key: "timestamps.markedForDeletion", Value: 1},
+func participantUserIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_PARTICIPANT_USERS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "timestamps.markedForDeletion", Value: 1},
+					},
 				},
-			},
-			{
-				Keys: bson.D{
-					{Key: "account.accountID", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "account.accountID", Value: 1},
+					},
 				},
-			},
-			{
-				Keys: bson.D{
-					{Key: "timestamps.createdAt", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "timestamps.createdAt", Value: 1},
+					},
 				},
-			},
-			{
-				Keys: bson.D{
-					{Key: "account.accountConfirmedAt", Value: 1},
-					{Key: "timestamps.createdAt", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "account.accountConfirmedAt", Value: 1},
+						{Key: "timestamps.createdAt", Value: 1},
+					},
 				},
-			},
-			{
-				Keys: bson.D{
-					{Key: "contactPreferences.receiveWeeklyMessageDayOfWeek", Value: 1},
+				{
+					Keys: bson.D{
+						{Key: "contactPreferences.receiveWeeklyMessageDayOfWeek", Value: 1},
+					},
 				},
 			},
 		},
-	)
-	return err
+	}
 }
 
-func (dbService *ParticipantUserDBService) FixFieldNameForContactInfos(instanceID string) error {
+// MarkContactInfoUndeliverable flags all email contact infos with the given address as undeliverable
+func (dbService *ParticipantUserDBService) MarkContactInfoUndeliverable(instanceID string, address string, reason string) (int64, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	collection := dbService.collectionParticipantUsers(instanceID)
-	filter := bson.M{
-		"contactinfos": bson.M{
-			"$exists": true,
-		},
+	filter := bson.M{"contactInfos.email": address}
+	update := bson.M{"$set": bson.M{
+		"contactInfos.$[ci].undeliverableSince":  time.Now().Unix(),
+		"contactInfos.$[ci].undeliverableReason": reason,
+	}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"ci.email": address}},
+	})
+	res, err := dbService.collectionParticipantUsers(instanceID).UpdateMany(ctx, filter, update, opts)
+	if err != nil {
+		return 0, err
 	}
-	update := bson.M{"$rename": bson.M{"contactinfos": "contactInfos"}}
-	_, err := collection.UpdateMany(ctx, filter, update)
-	return err
+	return res.ModifiedCount, nil
 }
 
+// ErrUserExists is returned by AddUser if an account with the account ID exists already
+var ErrUserExists = errors.New("user already exists")
+
 func (dbService *ParticipantUserDBService) AddUser(instanceID string, user umTypes.User) (id string, err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
+	return dbService.addUser(ctx, instanceID, user)
+}
+
+func (dbService *ParticipantUserDBService) addUser(ctx context.Context, instanceID string, user umTypes.User) (id string, err error) {
 	filter := bson.M{"account.accountID": user.Account.AccountID}
 	upsert := true
 	opts := options.UpdateOptions{
@@ -83,7 +95,7 @@ func (dbService *ParticipantUserDBService) AddUser(instanceID string, user umTyp
 	}
 
 	if res.UpsertedCount < 1 {
-		err = errors.New("user already exists")
+		err = ErrUserExists
 		return
 	}
 
@@ -197,6 +209,10 @@ func (dbService *ParticipantUserDBService) DeleteUser(instanceID, userID string)
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
+	return dbService.deleteUser(ctx, instanceID, userID)
+}
+
+func (dbService *ParticipantUserDBService) deleteUser(ctx context.Context, instanceID, userID string) error {
 	_id, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return err
@@ -218,6 +234,10 @@ func (dbService *ParticipantUserDBService) UpdateUser(instanceID string, userID
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
+	return dbService.updateUser(ctx, instanceID, userID, update)
+}
+
+func (dbService *ParticipantUserDBService) updateUser(ctx context.Context, instanceID string, userID string, update bson.M) error {
 	_id, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return err
@@ -261,3 +281,32 @@ func (dbService *ParticipantUserDBService) FindAndExecuteOnUsers(
 	}
 	return nil
 }
+
+// FindUsersAfterID returns the next users matching the filter in _id order, starting after afterID, or from the first
+// user if afterID is nil. Used to process all users in batches with resumable progress.
+func (dbService *ParticipantUserDBService) FindUsersAfterID(instanceID string, filter bson.M, afterID primitive.ObjectID, limit int64) ([]umTypes.User, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if !afterID.IsZero() {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": afterID}}}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+
+	cursor, err := dbService.collectionParticipantUsers(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []umTypes.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// NewUserBulkWriter batches updates and deletions of participant users, see db.BulkWriter
+func (dbService *ParticipantUserDBService) NewUserBulkWriter(instanceID string, batchSize int) *db.BulkWriter {
+	return db.NewBulkWriter(dbService.collectionParticipantUsers(instanceID), dbService.getContext, batchSize)
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// PoolStats is a snapshot of the connection pools to a server, summed over all clients of the process
+type PoolStats struct {
+	Address string `json:"address"`
+	// open connections, idle or in use
+	Open  int64 `json:"open"`
+	InUse int64 `json:"inUse"`
+	// total number of check outs, failed ones include timeouts waiting for a free connection
+	CheckedOut     int64 `json:"checkedOut"`
+	CheckOutFailed int64 `json:"checkOutFailed"`
+}
+
+// PoolMetrics counts connection pool events by server address
+type PoolMetrics struct {
+	mu    sync.Mutex
+	pools map[string]*PoolStats
+}
+
+// DefaultPoolMetrics records the pools of all DB services
+var DefaultPoolMetrics = NewPoolMetrics()
+
+func NewPoolMetrics() *PoolMetrics {
+	return &PoolMetrics{pools: map[string]*PoolStats{}}
+}
+
+func (m *PoolMetrics) handle(e *event.PoolEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.pools[e.Address]
+	if !ok {
+		stats = &PoolStats{Address: e.Address}
+		m.pools[e.Address] = stats
+	}
+	switch e.Type {
+	case event.ConnectionCreated:
+		stats.Open += 1
+	case event.ConnectionClosed:
+		stats.Open -= 1
+	case event.GetSucceeded:
+		stats.InUse += 1
+		stats.CheckedOut += 1
+	case event.GetFailed:
+		stats.CheckOutFailed += 1
+	case event.ConnectionReturned:
+		stats.InUse -= 1
+	}
+}
+
+// Snapshot returns the stats ordered by address
+func (m *PoolMetrics) Snapshot() []PoolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]PoolStats, 0, len(m.pools))
+	for _, s := range m.pools {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Address < stats[j].Address })
+	return stats
+}
+
+// NewPoolMonitor records the pool events of a client in metrics
+func NewPoolMonitor(metrics *PoolMetrics) *event.PoolMonitor {
+	return &event.PoolMonitor{Event: metrics.handle}
+}
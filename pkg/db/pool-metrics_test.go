@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestPoolMetrics(t *testing.T) {
+	m := NewPoolMetrics()
+	monitor := NewPoolMonitor(m)
+
+	for _, e := range []string{
+		event.ConnectionCreated,
+		event.ConnectionCreated,
+		event.GetSucceeded,
+		event.GetSucceeded,
+		event.ConnectionReturned,
+		event.GetFailed,
+		event.ConnectionClosed,
+	} {
+		monitor.Event(&event.PoolEvent{Type: e, Address: "db1:27017"})
+	}
+	monitor.Event(&event.PoolEvent{Type: event.ConnectionCreated, Address: "db0:27017"})
+
+	stats := m.Snapshot()
+	if len(stats) != 2 || stats[0].Address != "db0:27017" {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	s := stats[1]
+	if s.Open != 1 || s.InUse != 1 || s.CheckedOut != 2 || s.CheckOutFailed != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
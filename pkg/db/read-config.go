@@ -94,6 +94,20 @@ func DBConfigFromYamlObj(yamlObj DBConfigYaml, instanceIDs []string) DBConfig {
 		DBNamePrefix:     DBNamePrefix,
 		InstanceIDs:      instanceIDs,
 		RunIndexCreation: yamlObj.RunIndexCreation,
+		RunMigrations:    yamlObj.RunMigrations,
+
+		MinPoolSize:            uint64(max(yamlObj.MinPoolSize, 0)),
+		MaxConnecting:          uint64(max(yamlObj.MaxConnecting, 0)),
+		ConnectTimeout:         yamlObj.ConnectTimeout,
+		ServerSelectionTimeout: yamlObj.ServerSelectionTimeout,
+		ReadPreference:         yamlObj.ReadPreference,
+		MaxStaleness:           yamlObj.MaxStaleness,
+		WriteConcern:           yamlObj.WriteConcern,
+		WriteConcernJournal:    yamlObj.WriteConcernJournal,
+		WriteConcernTimeout:    yamlObj.WriteConcernTimeout,
+		Compressors:            yamlObj.Compressors,
+
+		SlowQueryThresholdMs: yamlObj.SlowQueryThresholdMs,
 	}
 
 }
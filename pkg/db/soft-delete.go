@@ -0,0 +1,28 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FIELD_DELETED_AT marks soft-deleted documents. They are hidden from default queries and stay in the trash until
+// they are restored or purged.
+const FIELD_DELETED_AT = "deletedAt"
+
+// NotDeleted adds the condition excluding soft-deleted documents to the filter
+func NotDeleted(filter bson.M) bson.M {
+	filter[FIELD_DELETED_AT] = bson.M{"$exists": false}
+	return filter
+}
+
+// OnlyDeleted adds the condition selecting soft-deleted documents to the filter
+func OnlyDeleted(filter bson.M) bson.M {
+	filter[FIELD_DELETED_AT] = bson.M{"$exists": true}
+	return filter
+}
+
+// DeletedBefore adds the condition selecting documents soft-deleted before the cutoff to the filter. The cutoff must
+// have the type of the deletedAt field of the collection.
+func DeletedBefore(filter bson.M, cutoff any) bson.M {
+	filter[FIELD_DELETED_AT] = bson.M{"$lt": cutoff}
+	return filter
+}
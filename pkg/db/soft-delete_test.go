@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSoftDeleteFilters(t *testing.T) {
+	t.Run("not deleted keeps existing conditions", func(t *testing.T) {
+		filter := NotDeleted(bson.M{"key": "s1"})
+		if filter["key"] != "s1" {
+			t.Errorf("condition lost: %v", filter)
+		}
+		cond, ok := filter[FIELD_DELETED_AT].(bson.M)
+		if !ok || cond["$exists"] != false {
+			t.Errorf("unexpected condition: %v", filter)
+		}
+	})
+
+	t.Run("only deleted", func(t *testing.T) {
+		cond := OnlyDeleted(bson.M{})[FIELD_DELETED_AT].(bson.M)
+		if cond["$exists"] != true {
+			t.Errorf("unexpected condition: %v", cond)
+		}
+	})
+
+	t.Run("deleted before", func(t *testing.T) {
+		cond := DeletedBefore(bson.M{}, int64(100))[FIELD_DELETED_AT].(bson.M)
+		if cond["$lt"] != int64(100) {
+			t.Errorf("unexpected condition: %v", cond)
+		}
+	})
+}
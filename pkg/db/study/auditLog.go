@@ -0,0 +1,88 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) collectionAuditLog(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_AUDIT_LOG)
+}
+
+func auditLogIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_AUDIT_LOG,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "time", Value: -1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "userID", Value: 1},
+						{Key: "time", Value: -1},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (dbService *StudyDBService) AddAuditLogEntry(instanceID string, entry studyTypes.AuditLogEntry) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	_, err := dbService.collectionAuditLog(instanceID).InsertOne(ctx, entry)
+	return err
+}
+
+// GetAuditLogEntries returns the audit log of the study, optionally filtered by user and action, newest first
+func (dbService *StudyDBService) GetAuditLogEntries(instanceID string, studyKey string, userID string, action string, page int64, limit int64) (entries []studyTypes.AuditLogEntry, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if userID != "" {
+		filter["userID"] = userID
+	}
+	if action != "" {
+		filter["action"] = action
+	}
+
+	totalCount, err := dbService.collectionAuditLog(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	paginationInfo = prepPaginationInfos(totalCount, page, limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "time", Value: -1}}).
+		SetSkip((paginationInfo.CurrentPage - 1) * paginationInfo.PageSize).
+		SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionAuditLog(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries = []studyTypes.AuditLogEntry{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, paginationInfo, nil
+}
@@ -0,0 +1,27 @@
+package study
+
+import (
+	"context"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DumpCollection streams the raw documents of the collection of the instance, used by the backup job
+func (dbService *StudyDBService) DumpCollection(ctx context.Context, instanceID string, collectionName string, handle func(doc bson.Raw) error) (int64, error) {
+	collection := dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionName)
+	return db.DumpCollection(ctx, collection, dbService.noCursorTimeout, handle)
+}
+
+// NewRestoreWriter returns a bulk writer for the db.RestoreModel operations of a backup restore
+func (dbService *StudyDBService) NewRestoreWriter(instanceID string, collectionName string) *db.BulkWriter {
+	collection := dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionName)
+	return db.NewBulkWriter(collection, dbService.getContext, 0)
+}
+
+// DropCollection removes the collection before a backup is restored into an empty collection
+func (dbService *StudyDBService) DropCollection(instanceID string, collectionName string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionName).Drop(ctx)
+}
@@ -0,0 +1,34 @@
+package study
+
+import (
+	"context"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/cache"
+)
+
+// SetCache enables caching of study infos and current survey versions, a nil cache disables it
+func (dbService *StudyDBService) SetCache(c cache.Cache, ttl time.Duration) {
+	dbService.cache = c
+	dbService.cacheTTL = ttl
+}
+
+func studyCacheKey(instanceID string, studyKey string) string {
+	return "study:" + instanceID + ":" + studyKey
+}
+
+func surveyCachePrefix(instanceID string, studyKey string) string {
+	return "survey:" + instanceID + ":" + studyKey + ":"
+}
+
+func currentSurveyCacheKey(instanceID string, studyKey string, surveyKey string) string {
+	return surveyCachePrefix(instanceID, studyKey) + surveyKey + ":current"
+}
+
+func (dbService *StudyDBService) invalidateStudyCache(instanceID string, studyKey string) {
+	cache.Invalidate(context.Background(), dbService.cache, []string{studyCacheKey(instanceID, studyKey)}, nil)
+}
+
+func (dbService *StudyDBService) invalidateSurveyCache(instanceID string, studyKey string, surveyKey string) {
+	cache.Invalidate(context.Background(), dbService.cache, []string{currentSurveyCacheKey(instanceID, studyKey, surveyKey)}, nil)
+}
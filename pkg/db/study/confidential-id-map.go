@@ -47,3 +47,11 @@ func (dbService *StudyDBService) RemoveConfidentialIDMapEntriesForProfile(instan
 	_, err := dbService.collectionConfidentialIDMap(instanceID).DeleteMany(ctx, bson.M{"profileID": profileID, "studyKey": studyKey})
 	return err
 }
+
+func (dbService *StudyDBService) RemoveConfidentialIDMapEntry(instanceID, confidentialID, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionConfidentialIDMap(instanceID).DeleteMany(ctx, bson.M{"confidentialID": confidentialID, "studyKey": studyKey})
+	return err
+}
@@ -0,0 +1,123 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) collectionConfidentialAccessGrants(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_CONFIDENTIAL_ACCESS_GRANTS)
+}
+
+func confidentialAccessGrantIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_CONFIDENTIAL_ACCESS_GRANTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "userID", Value: 1},
+						{Key: "expiresAt", Value: -1},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (dbService *StudyDBService) AddConfidentialAccessGrant(instanceID string, grant studyTypes.ConfidentialAccessGrant) (studyTypes.ConfidentialAccessGrant, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	// callers may set the ID upfront, e.g. to reference the grant in the audit log before it is added
+	if grant.ID.IsZero() {
+		grant.ID = primitive.NewObjectID()
+	}
+	grant.CreatedAt = time.Now()
+	grant.RevokedAt = nil
+
+	_, err := dbService.collectionConfidentialAccessGrants(instanceID).InsertOne(ctx, grant)
+	return grant, err
+}
+
+// GetActiveConfidentialAccessGrants returns the not revoked and not expired grants of the user for the study, latest expiry first
+func (dbService *StudyDBService) GetActiveConfidentialAccessGrants(instanceID string, studyKey string, userID string) (grants []studyTypes.ConfidentialAccessGrant, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":  studyKey,
+		"userID":    userID,
+		"expiresAt": bson.M{"$gt": time.Now()},
+		"revokedAt": bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "expiresAt", Value: -1}})
+
+	cursor, err := dbService.collectionConfidentialAccessGrants(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	grants = []studyTypes.ConfidentialAccessGrant{}
+	if err = cursor.All(ctx, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// GetConfidentialAccessGrants returns all grants of the study, including expired and revoked ones, newest first
+func (dbService *StudyDBService) GetConfidentialAccessGrants(instanceID string, studyKey string) (grants []studyTypes.ConfidentialAccessGrant, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := dbService.collectionConfidentialAccessGrants(instanceID).Find(ctx, bson.M{"studyKey": studyKey}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	grants = []studyTypes.ConfidentialAccessGrant{}
+	if err = cursor.All(ctx, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (dbService *StudyDBService) RevokeConfidentialAccessGrant(instanceID string, studyKey string, grantID string, revokedBy string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(grantID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":       _id,
+		"studyKey":  studyKey,
+		"revokedAt": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{
+		"revokedAt": time.Now(),
+		"revokedBy": revokedBy,
+	}}
+
+	res, err := dbService.collectionConfidentialAccessGrants(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
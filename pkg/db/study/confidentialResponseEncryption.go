@@ -0,0 +1,381 @@
+package study
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// the active data key is cached shortly, other processes pick up a rotated key after the cache period
+const activeDataKeyCacheTTL = time.Minute
+
+// responses of version 0 only authenticate the data key ID, they are migrated by ReencryptConfidentialResponses
+const confidentialResponseEncryptionVersion = 1
+
+// DataKey is a per-instance key used to encrypt confidential responses, stored wrapped by the key provider
+type DataKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	WrappedKey []byte             `bson:"wrappedKey" json:"-"`
+	KEKID      string             `bson:"kekID" json:"kekID"`
+	Active     bool               `bson:"active" json:"active"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type activeDataKey struct {
+	id       string
+	loadedAt time.Time
+}
+
+type confidentialResponseEncryption struct {
+	provider fieldencryption.KeyProvider
+
+	mu         sync.Mutex
+	keys       map[string][]byte        // data key ID -> unwrapped key
+	activeKeys map[string]activeDataKey // instanceID -> active data key
+}
+
+// encrypted part of a confidential response
+type confidentialResponseData struct {
+	Responses []studyTypes.SurveyItemResponse `bson:"responses"`
+	Context   map[string]string               `bson:"context"`
+}
+
+func (dbService *StudyDBService) collectionDataKeys(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_DATA_KEYS)
+}
+
+// EnableConfidentialResponseEncryption makes the service encrypt confidential responses on write with data keys wrapped by the provider.
+// Encrypted responses can only be read if encryption is enabled.
+func (dbService *StudyDBService) EnableConfidentialResponseEncryption(provider fieldencryption.KeyProvider) {
+	if provider == nil {
+		dbService.encryption = nil
+		return
+	}
+	dbService.encryption = &confidentialResponseEncryption{
+		provider:   provider,
+		keys:       map[string][]byte{},
+		activeKeys: map[string]activeDataKey{},
+	}
+}
+
+func (dbService *StudyDBService) getActiveDataKeyID(instanceID string) (string, error) {
+	enc := dbService.encryption
+	enc.mu.Lock()
+	cached, ok := enc.activeKeys[instanceID]
+	enc.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < activeDataKeyCacheTTL {
+		return cached.id, nil
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var dataKey DataKey
+	err := dbService.collectionDataKeys(instanceID).FindOne(
+		ctx,
+		bson.M{"active": true},
+		options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	).Decode(&dataKey)
+	if err == mongo.ErrNoDocuments {
+		// first use in this instance
+		return dbService.RotateConfidentialResponseDataKey(instanceID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	enc.mu.Lock()
+	enc.activeKeys[instanceID] = activeDataKey{id: dataKey.ID.Hex(), loadedAt: time.Now()}
+	enc.mu.Unlock()
+	return dataKey.ID.Hex(), nil
+}
+
+func (dbService *StudyDBService) getDataKey(instanceID string, dataKeyID string) ([]byte, error) {
+	enc := dbService.encryption
+	enc.mu.Lock()
+	key, ok := enc.keys[dataKeyID]
+	enc.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	_id, err := primitive.ObjectIDFromHex(dataKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var dataKey DataKey
+	if err := dbService.collectionDataKeys(instanceID).FindOne(ctx, bson.M{"_id": _id}).Decode(&dataKey); err != nil {
+		return nil, err
+	}
+
+	key, err = enc.provider.UnwrapKey(dataKey.WrappedKey, dataKey.KEKID)
+	if err != nil {
+		return nil, err
+	}
+
+	enc.mu.Lock()
+	enc.keys[dataKeyID] = key
+	enc.mu.Unlock()
+	return key, nil
+}
+
+// RotateConfidentialResponseDataKey creates a new active data key for the instance, used for all following writes.
+// Existing responses stay readable with their previous key until they are re-encrypted.
+func (dbService *StudyDBService) RotateConfidentialResponseDataKey(instanceID string) (string, error) {
+	enc := dbService.encryption
+	if enc == nil {
+		return "", errors.New("confidential response encryption is not enabled")
+	}
+
+	key, err := fieldencryption.GenerateDataKey()
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, kekID, err := enc.provider.WrapKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionDataKeys(instanceID).InsertOne(ctx, DataKey{
+		WrappedKey: wrappedKey,
+		KEKID:      kekID,
+		Active:     true,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+	dataKeyID := res.InsertedID.(primitive.ObjectID)
+
+	_, err = dbService.collectionDataKeys(instanceID).UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$ne": dataKeyID}, "active": true},
+		bson.M{"$set": bson.M{"active": false}},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	enc.mu.Lock()
+	enc.keys[dataKeyID.Hex()] = key
+	enc.activeKeys[instanceID] = activeDataKey{id: dataKeyID.Hex(), loadedAt: time.Now()}
+	enc.mu.Unlock()
+	return dataKeyID.Hex(), nil
+}
+
+// encryptConfidentialResponse moves the responses and context of the response into the encrypted field, if encryption is enabled
+func (dbService *StudyDBService) encryptConfidentialResponse(instanceID string, response *studyTypes.SurveyResponse) error {
+	if dbService.encryption == nil {
+		return nil
+	}
+
+	dataKeyID, err := dbService.getActiveDataKeyID(instanceID)
+	if err != nil {
+		return err
+	}
+	key, err := dbService.getDataKey(instanceID, dataKeyID)
+	if err != nil {
+		return err
+	}
+
+	if response.ID.IsZero() {
+		return errors.New("confidential response needs an ID before it is encrypted")
+	}
+
+	plaintext, err := bson.Marshal(confidentialResponseData{
+		Responses: response.Responses,
+		Context:   response.Context,
+	})
+	if err != nil {
+		return err
+	}
+	encrypted, err := fieldencryption.Encrypt(key, plaintext, confidentialResponseAdditionalData(*response, dataKeyID, confidentialResponseEncryptionVersion))
+	if err != nil {
+		return err
+	}
+
+	response.EncryptedData = encrypted
+	response.DataKeyID = dataKeyID
+	response.EncryptionVersion = confidentialResponseEncryptionVersion
+	response.Responses = nil
+	response.Context = nil
+	return nil
+}
+
+// confidentialResponseAdditionalData binds the encrypted data to the response and its participant, so that it cannot
+// be copied to another response
+func confidentialResponseAdditionalData(response studyTypes.SurveyResponse, dataKeyID string, version int) []byte {
+	if version == 0 {
+		return []byte(dataKeyID)
+	}
+	return []byte(strings.Join([]string{strconv.Itoa(version), dataKeyID, response.ID.Hex(), response.ParticipantID}, "\x00"))
+}
+
+// decryptConfidentialResponse restores the responses and context of an encrypted response, plaintext responses are unchanged
+func (dbService *StudyDBService) decryptConfidentialResponse(instanceID string, response *studyTypes.SurveyResponse) error {
+	if len(response.EncryptedData) == 0 {
+		return nil
+	}
+	if dbService.encryption == nil {
+		return errors.New("confidential response is encrypted, but encryption is not enabled")
+	}
+
+	key, err := dbService.getDataKey(instanceID, response.DataKeyID)
+	if err != nil {
+		return err
+	}
+	plaintext, err := fieldencryption.Decrypt(key, response.EncryptedData, confidentialResponseAdditionalData(*response, response.DataKeyID, response.EncryptionVersion))
+	if err != nil {
+		return err
+	}
+
+	var data confidentialResponseData
+	if err := bson.Unmarshal(plaintext, &data); err != nil {
+		return err
+	}
+
+	response.Responses = data.Responses
+	response.Context = data.Context
+	response.EncryptedData = nil
+	response.DataKeyID = ""
+	response.EncryptionVersion = 0
+	return nil
+}
+
+// ReencryptConfidentialResponses encrypts all confidential responses of the study that are not encrypted with the active data key
+// in the current format, including responses stored before encryption was enabled. Returns the number of updated responses.
+func (dbService *StudyDBService) ReencryptConfidentialResponses(instanceID string, studyKey string, batchSize int64) (int64, error) {
+	if dbService.encryption == nil {
+		return 0, errors.New("confidential response encryption is not enabled")
+	}
+
+	return dbService.reencryptConfidentialResponses(
+		instanceID,
+		func(activeKeyID string, after primitive.ObjectID) ([]studyTypes.SurveyResponse, error) {
+			return dbService.findConfidentialResponsesToReencrypt(instanceID, studyKey, activeKeyID, after, batchSize)
+		},
+		func(response studyTypes.SurveyResponse, previous studyTypes.SurveyResponse) (bool, error) {
+			return dbService.replaceConfidentialResponseByID(instanceID, studyKey, response, previous)
+		},
+	)
+}
+
+// reencryptConfidentialResponses pages through the responses by ID, so every response is visited once, even if it is
+// rotated again or cannot be replaced. The active key is reloaded for each batch to pick up rotations.
+func (dbService *StudyDBService) reencryptConfidentialResponses(
+	instanceID string,
+	findBatch func(activeKeyID string, after primitive.ObjectID) ([]studyTypes.SurveyResponse, error),
+	replace func(response studyTypes.SurveyResponse, previous studyTypes.SurveyResponse) (bool, error),
+) (int64, error) {
+	var count int64
+	lastSeen := primitive.NilObjectID
+	for {
+		activeKeyID, err := dbService.getActiveDataKeyID(instanceID)
+		if err != nil {
+			return count, err
+		}
+
+		responses, err := findBatch(activeKeyID, lastSeen)
+		if err != nil {
+			return count, err
+		}
+		if len(responses) == 0 {
+			return count, nil
+		}
+
+		batchStart := lastSeen
+		for _, response := range responses {
+			if bytes.Compare(response.ID[:], lastSeen[:]) > 0 {
+				lastSeen = response.ID
+			}
+
+			previous := response
+			if err := dbService.decryptConfidentialResponse(instanceID, &response); err != nil {
+				return count, err
+			}
+			if err := dbService.encryptConfidentialResponse(instanceID, &response); err != nil {
+				return count, err
+			}
+
+			replaced, err := replace(response, previous)
+			if err != nil {
+				return count, err
+			}
+			if replaced {
+				count += 1
+			}
+		}
+
+		if lastSeen == batchStart {
+			return count, errors.New("re-encryption made no progress")
+		}
+	}
+}
+
+func (dbService *StudyDBService) findConfidentialResponsesToReencrypt(instanceID string, studyKey string, dataKeyID string, after primitive.ObjectID, limit int64) (responses []studyTypes.SurveyResponse, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	// also matches plaintext responses without a data key
+	filter := bson.M{
+		"_id": bson.M{"$gt": after},
+		"$or": bson.A{
+			bson.M{"dataKeyID": bson.M{"$ne": dataKeyID}},
+			bson.M{"encryptionVersion": bson.M{"$ne": confidentialResponseEncryptionVersion}},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := dbService.collectionConfidentialResponses(instanceID, studyKey).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &responses); err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// replaceConfidentialResponseByID replaces the response only if it is still encrypted as the previous one,
+// so responses changed in the meantime are not overwritten
+func (dbService *StudyDBService) replaceConfidentialResponseByID(instanceID string, studyKey string, response studyTypes.SurveyResponse, previous studyTypes.SurveyResponse) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": response.ID}
+	if previous.DataKeyID == "" {
+		filter["dataKeyID"] = bson.M{"$exists": false}
+	} else {
+		filter["dataKeyID"] = previous.DataKeyID
+	}
+	if previous.EncryptionVersion == 0 {
+		filter["encryptionVersion"] = bson.M{"$exists": false}
+	} else {
+		filter["encryptionVersion"] = previous.EncryptionVersion
+	}
+
+	res, err := dbService.collectionConfidentialResponses(instanceID, studyKey).ReplaceOne(ctx, filter, response)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
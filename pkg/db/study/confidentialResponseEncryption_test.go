@@ -0,0 +1,266 @@
+package study
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// returns a service with a cached active data key, so no database is needed
+func testServiceWithDataKey(t *testing.T, instanceID string, dataKeyID string) *StudyDBService {
+	dataKey, err := fieldencryption.GenerateDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbService := &StudyDBService{}
+	dbService.EnableConfidentialResponseEncryption(testKeyProvider{})
+	dbService.encryption.keys[dataKeyID] = dataKey
+	dbService.encryption.activeKeys[instanceID] = activeDataKey{id: dataKeyID, loadedAt: time.Now()}
+	return dbService
+}
+
+type testKeyProvider struct{}
+
+func (testKeyProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	return dataKey, "test", nil
+}
+
+func (testKeyProvider) UnwrapKey(wrappedKey []byte, kekID string) ([]byte, error) {
+	return wrappedKey, nil
+}
+
+func TestConfidentialResponseEncryption(t *testing.T) {
+	dataKeyID := "65f1a0c2e4b0a1b2c3d4e5f6"
+	dbService := testServiceWithDataKey(t, "test", dataKeyID)
+
+	original := studyTypes.SurveyResponse{
+		ID:            primitive.NewObjectID(),
+		Key:           "contact",
+		ParticipantID: "p1",
+		Responses: []studyTypes.SurveyItemResponse{
+			{Key: "contact.email"},
+		},
+		Context: map[string]string{"lang": "en"},
+	}
+
+	response := original
+	if err := dbService.encryptConfidentialResponse("test", &response); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("encrypted fields", func(t *testing.T) {
+		if response.Responses != nil || response.Context != nil {
+			t.Error("responses and context should be cleared")
+		}
+		if len(response.EncryptedData) == 0 || response.DataKeyID != dataKeyID || response.EncryptionVersion != confidentialResponseEncryptionVersion {
+			t.Errorf("unexpected encryption fields: %s, version %d", response.DataKeyID, response.EncryptionVersion)
+		}
+		if response.Key != original.Key || response.ParticipantID != original.ParticipantID {
+			t.Error("query fields should stay readable")
+		}
+	})
+
+	t.Run("decrypt", func(t *testing.T) {
+		decrypted := response
+		if err := dbService.decryptConfidentialResponse("test", &decrypted); err != nil {
+			t.Fatal(err)
+		}
+		if len(decrypted.Responses) != 1 || decrypted.Responses[0].Key != "contact.email" || decrypted.Context["lang"] != "en" {
+			t.Errorf("unexpected decrypted response: %+v", decrypted)
+		}
+		if decrypted.EncryptedData != nil || decrypted.DataKeyID != "" || decrypted.EncryptionVersion != 0 {
+			t.Error("encryption fields should be cleared")
+		}
+	})
+
+	t.Run("encrypted data copied to another response", func(t *testing.T) {
+		otherID := response
+		otherID.ID = primitive.NewObjectID()
+		if err := dbService.decryptConfidentialResponse("test", &otherID); err == nil {
+			t.Error("expected error for another response ID")
+		}
+
+		otherParticipant := response
+		otherParticipant.ParticipantID = "p2"
+		if err := dbService.decryptConfidentialResponse("test", &otherParticipant); err == nil {
+			t.Error("expected error for another participant")
+		}
+	})
+
+	t.Run("response without ID", func(t *testing.T) {
+		withoutID := original
+		withoutID.ID = primitive.NilObjectID
+		if err := dbService.encryptConfidentialResponse("test", &withoutID); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("plaintext response is unchanged", func(t *testing.T) {
+		plain := original
+		if err := dbService.decryptConfidentialResponse("test", &plain); err != nil {
+			t.Fatal(err)
+		}
+		if len(plain.Responses) != 1 {
+			t.Error("plaintext response changed")
+		}
+	})
+
+	t.Run("encrypted response without encryption enabled", func(t *testing.T) {
+		disabled := &StudyDBService{}
+		encrypted := response
+		if err := disabled.decryptConfidentialResponse("test", &encrypted); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("encryption disabled stores plaintext", func(t *testing.T) {
+		disabled := &StudyDBService{}
+		plain := original
+		if err := disabled.encryptConfidentialResponse("test", &plain); err != nil {
+			t.Fatal(err)
+		}
+		if plain.EncryptedData != nil || len(plain.Responses) != 1 {
+			t.Error("response should not be encrypted")
+		}
+	})
+}
+
+func TestReencryptConfidentialResponses(t *testing.T) {
+	oldKeyID := "65f1a0c2e4b0a1b2c3d4e5f6"
+	newKeyID := "65f1a0c2e4b0a1b2c3d4e5f7"
+	dbService := testServiceWithDataKey(t, "test", oldKeyID)
+
+	// responses stored under the old key, plaintext responses from before encryption was enabled
+	// and responses encrypted in the legacy format that only authenticates the data key ID
+	stored := []studyTypes.SurveyResponse{}
+	for i := 0; i < 6; i++ {
+		response := studyTypes.SurveyResponse{
+			ID:            primitive.NewObjectID(),
+			ParticipantID: "p1",
+			Responses:     []studyTypes.SurveyItemResponse{{Key: "contact.email"}},
+		}
+		switch i % 3 {
+		case 0:
+			if err := dbService.encryptConfidentialResponse("test", &response); err != nil {
+				t.Fatal(err)
+			}
+		case 1:
+			response = encryptLegacyConfidentialResponse(t, dbService, response, oldKeyID)
+		}
+		stored = append(stored, response)
+	}
+
+	newKey, err := fieldencryption.GenerateDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbService.encryption.keys[newKeyID] = newKey
+	dbService.encryption.activeKeys["test"] = activeDataKey{id: newKeyID, loadedAt: time.Now()}
+
+	findBatch := func(activeKeyID string, after primitive.ObjectID) ([]studyTypes.SurveyResponse, error) {
+		batch := []studyTypes.SurveyResponse{}
+		for _, response := range stored {
+			needsUpdate := response.DataKeyID != activeKeyID || response.EncryptionVersion != confidentialResponseEncryptionVersion
+			if response.ID.Hex() > after.Hex() && needsUpdate && len(batch) < 2 {
+				batch = append(batch, response)
+			}
+		}
+		return batch, nil
+	}
+
+	t.Run("replace fails", func(t *testing.T) {
+		calls := 0
+		count, err := dbService.reencryptConfidentialResponses("test", findBatch, func(response studyTypes.SurveyResponse, previous studyTypes.SurveyResponse) (bool, error) {
+			calls += 1
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 || calls != len(stored) {
+			t.Errorf("unexpected count %d and calls %d", count, calls)
+		}
+	})
+
+	t.Run("re-encrypts each response once", func(t *testing.T) {
+		calls := 0
+		count, err := dbService.reencryptConfidentialResponses("test", findBatch, func(response studyTypes.SurveyResponse, previous studyTypes.SurveyResponse) (bool, error) {
+			calls += 1
+			for i := range stored {
+				if stored[i].ID == response.ID && stored[i].DataKeyID == previous.DataKeyID && stored[i].EncryptionVersion == previous.EncryptionVersion {
+					stored[i] = response
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != int64(len(stored)) || calls != len(stored) {
+			t.Errorf("unexpected count %d and calls %d", count, calls)
+		}
+		for _, response := range stored {
+			if response.DataKeyID != newKeyID || response.EncryptionVersion != confidentialResponseEncryptionVersion {
+				t.Errorf("response %s not encrypted with the active key in the current format", response.ID.Hex())
+			}
+			if err := dbService.decryptConfidentialResponse("test", &response); err != nil || len(response.Responses) != 1 {
+				t.Errorf("response %s cannot be decrypted: %v", response.ID.Hex(), err)
+			}
+		}
+	})
+
+	t.Run("migrates legacy responses under the active key", func(t *testing.T) {
+		legacy := []studyTypes.SurveyResponse{encryptLegacyConfidentialResponse(t, dbService, studyTypes.SurveyResponse{
+			ID:            primitive.NewObjectID(),
+			ParticipantID: "p1",
+			Responses:     []studyTypes.SurveyItemResponse{{Key: "contact.email"}},
+		}, newKeyID)}
+		count, err := dbService.reencryptConfidentialResponses("test",
+			func(activeKeyID string, after primitive.ObjectID) ([]studyTypes.SurveyResponse, error) {
+				if legacy[0].EncryptionVersion == confidentialResponseEncryptionVersion || after == legacy[0].ID {
+					return nil, nil
+				}
+				return legacy, nil
+			},
+			func(response studyTypes.SurveyResponse, previous studyTypes.SurveyResponse) (bool, error) {
+				legacy[0] = response
+				return true, nil
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 || legacy[0].EncryptionVersion != confidentialResponseEncryptionVersion {
+			t.Errorf("legacy response not migrated: count %d, version %d", count, legacy[0].EncryptionVersion)
+		}
+	})
+}
+
+// encryptLegacyConfidentialResponse encrypts the response as stored before the response ID and participant were authenticated
+func encryptLegacyConfidentialResponse(t *testing.T, dbService *StudyDBService, response studyTypes.SurveyResponse, dataKeyID string) studyTypes.SurveyResponse {
+	key, err := dbService.getDataKey("test", dataKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := bson.Marshal(confidentialResponseData{Responses: response.Responses, Context: response.Context})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := fieldencryption.Encrypt(key, plaintext, []byte(dataKeyID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response.EncryptedData = encrypted
+	response.DataKeyID = dataKeyID
+	response.Responses = nil
+	response.Context = nil
+	return response
+}
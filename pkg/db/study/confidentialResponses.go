@@ -6,6 +6,7 @@ import (
 	studytypes "github.com/case-framework/case-backend/pkg/study/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -15,9 +16,17 @@ func (dbService *StudyDBService) AddConfidentialResponse(instanceID string, stud
 	if len(response.ParticipantID) < 1 {
 		return "", errors.New("participantID must be defined")
 	}
-	res, err := dbService.collectionConfidentialResponses(instanceID, studyKey).InsertOne(ctx, response)
-	id := res.InsertedID.(primitive.ObjectID)
-	return id.Hex(), err
+	// the encrypted data is bound to the ID of the response
+	if response.ID.IsZero() {
+		response.ID = primitive.NewObjectID()
+	}
+	if err := dbService.encryptConfidentialResponse(instanceID, &response); err != nil {
+		return "", err
+	}
+	if _, err := dbService.collectionConfidentialResponses(instanceID, studyKey).InsertOne(ctx, response); err != nil {
+		return "", err
+	}
+	return response.ID.Hex(), nil
 }
 
 func (dbService *StudyDBService) ReplaceConfidentialResponse(instanceID string, studyKey string, response studytypes.SurveyResponse) error {
@@ -29,11 +38,29 @@ func (dbService *StudyDBService) ReplaceConfidentialResponse(instanceID string,
 		"key":           response.Key,
 	}
 
+	// the encrypted data is bound to the ID of the response, so the ID of the replaced response is kept
+	var existing studytypes.SurveyResponse
+	err := dbService.collectionConfidentialResponses(instanceID, studyKey).FindOne(
+		ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1}),
+	).Decode(&existing)
+	switch {
+	case err == nil:
+		response.ID = existing.ID
+	case err == mongo.ErrNoDocuments:
+		response.ID = primitive.NewObjectID()
+	default:
+		return err
+	}
+
+	if err := dbService.encryptConfidentialResponse(instanceID, &response); err != nil {
+		return err
+	}
+
 	upsert := true
 	options := options.ReplaceOptions{
 		Upsert: &upsert,
 	}
-	_, err := dbService.collectionConfidentialResponses(instanceID, studyKey).ReplaceOne(ctx, filter, response, &options)
+	_, err = dbService.collectionConfidentialResponses(instanceID, studyKey).ReplaceOne(ctx, filter, response, &options)
 	return err
 }
 
@@ -67,6 +94,9 @@ func (dbService *StudyDBService) FindConfidentialResponses(instanceID string, st
 		if err != nil {
 			return responses, err
 		}
+		if err := dbService.decryptConfidentialResponse(instanceID, &result); err != nil {
+			return responses, err
+		}
 
 		responses = append(responses, result)
 	}
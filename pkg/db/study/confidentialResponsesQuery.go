@@ -0,0 +1,231 @@
+package study
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	CONFIDENTIAL_RESPONSES_SORT_BY_ARRIVED_AT   = "arrivedAt"
+	CONFIDENTIAL_RESPONSES_SORT_BY_SUBMITTED_AT = "submittedAt"
+
+	maxConfidentialResponsesPageSize     = 500
+	defaultConfidentialResponsesPageSize = 50
+)
+
+// ErrInvalidConfidentialResponsesQuery is returned for invalid sort fields, projections or cursors
+var ErrInvalidConfidentialResponsesQuery = errors.New("invalid confidential responses query")
+
+// fields that can be selected with ConfidentialResponsesQuery.Fields
+var confidentialResponseProjectableFields = map[string]bool{
+	"key":         true,
+	"versionID":   true,
+	"openedAt":    true,
+	"submittedAt": true,
+	"arrivedAt":   true,
+	"responses":   true,
+	"context":     true,
+}
+
+func confidentialResponseIndexes(studyKey string) []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: studyKey + "_" + COLLECTION_NAME_SUFFIX_CONFIDENTIAL_RESPONSES,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+						{Key: "arrivedAt", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+						{Key: "submittedAt", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+						{Key: "key", Value: 1},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ConfidentialResponsesQuery selects a page of the confidential responses of a participant
+type ConfidentialResponsesQuery struct {
+	ParticipantID string
+	// exact survey key, or key prefix if KeyPrefix is set
+	Key       string
+	KeyPrefix string
+	// "arrivedAt" (default) or "submittedAt"
+	SortBy   string
+	SortDesc bool
+	// top level fields to return, all if empty. ID and participantID are always returned
+	Fields []string
+	// cursor returned by the previous page, empty for the first page
+	Cursor string
+	Limit  int64
+}
+
+// FindConfidentialResponsesPage returns one page of the participant's confidential responses and the cursor of the next page,
+// which is empty if there are no further responses
+func (dbService *StudyDBService) FindConfidentialResponsesPage(instanceID string, studyKey string, query ConfidentialResponsesQuery) (responses []studyTypes.SurveyResponse, nextCursor string, err error) {
+	filter, findOpts, err := prepConfidentialResponsesQuery(query)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrInvalidConfidentialResponsesQuery, err.Error())
+	}
+	limit := *findOpts.Limit
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	// one more than requested to know if there is a next page
+	findOpts.SetLimit(limit + 1)
+	cursor, err := dbService.collectionConfidentialResponses(instanceID, studyKey).Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	responses = []studyTypes.SurveyResponse{}
+	if err = cursor.All(ctx, &responses); err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(responses)) > limit {
+		responses = responses[:limit]
+		last := responses[len(responses)-1]
+		nextCursor = encodeConfidentialResponsesCursor(sortValue(last, sortField(query.SortBy)), last.ID)
+	}
+
+	for i := range responses {
+		if err := dbService.decryptConfidentialResponse(instanceID, &responses[i]); err != nil {
+			return nil, "", err
+		}
+	}
+	return responses, nextCursor, nil
+}
+
+func sortField(sortBy string) string {
+	if sortBy == "" {
+		return CONFIDENTIAL_RESPONSES_SORT_BY_ARRIVED_AT
+	}
+	return sortBy
+}
+
+func sortValue(response studyTypes.SurveyResponse, field string) int64 {
+	if field == CONFIDENTIAL_RESPONSES_SORT_BY_SUBMITTED_AT {
+		return response.SubmittedAt
+	}
+	return response.ArrivedAt
+}
+
+func prepConfidentialResponsesQuery(query ConfidentialResponsesQuery) (bson.M, *options.FindOptions, error) {
+	if query.ParticipantID == "" {
+		return nil, nil, errors.New("participant id must be defined")
+	}
+
+	field := sortField(query.SortBy)
+	if field != CONFIDENTIAL_RESPONSES_SORT_BY_ARRIVED_AT && field != CONFIDENTIAL_RESPONSES_SORT_BY_SUBMITTED_AT {
+		return nil, nil, fmt.Errorf("invalid sort field: %s", query.SortBy)
+	}
+
+	filter := bson.M{"participantID": query.ParticipantID}
+	if query.KeyPrefix != "" {
+		filter["key"] = bson.M{"$regex": "^" + regexp.QuoteMeta(query.KeyPrefix)}
+	} else if query.Key != "" {
+		filter["key"] = query.Key
+	}
+
+	sortOrder := 1
+	cmp := "$gt"
+	if query.SortDesc {
+		sortOrder = -1
+		cmp = "$lt"
+	}
+
+	if query.Cursor != "" {
+		value, id, err := decodeConfidentialResponsesCursor(query.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		// continue after the last returned document, the ID breaks ties between equal timestamps
+		filter["$or"] = bson.A{
+			bson.M{field: bson.M{cmp: value}},
+			bson.M{field: value, "_id": bson.M{cmp: id}},
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultConfidentialResponsesPageSize
+	}
+	if limit > maxConfidentialResponsesPageSize {
+		limit = maxConfidentialResponsesPageSize
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: sortOrder}, {Key: "_id", Value: sortOrder}}).
+		SetLimit(limit)
+
+	if len(query.Fields) > 0 {
+		projection := bson.M{"_id": 1, "participantID": 1, field: 1}
+		for _, f := range query.Fields {
+			if !confidentialResponseProjectableFields[f] {
+				return nil, nil, fmt.Errorf("invalid field: %s", f)
+			}
+			projection[f] = 1
+			if f == "responses" || f == "context" {
+				// encrypted responses store both in the encrypted data
+				projection["encryptedData"] = 1
+				projection["dataKeyID"] = 1
+				projection["encryptionVersion"] = 1
+			}
+		}
+		opts.SetProjection(projection)
+	}
+
+	return filter, opts, nil
+}
+
+func encodeConfidentialResponsesCursor(value int64, id primitive.ObjectID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(value, 10) + ":" + id.Hex()))
+}
+
+func decodeConfidentialResponsesCursor(cursor string) (int64, primitive.ObjectID, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+	value, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+	id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+	return value, id, nil
+}
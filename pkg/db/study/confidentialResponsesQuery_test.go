@@ -0,0 +1,117 @@
+package study
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestConfidentialResponsesCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	cursor := encodeConfidentialResponsesCursor(1700000000, id)
+
+	value, decodedID, err := decodeConfidentialResponsesCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 1700000000 || decodedID != id {
+		t.Errorf("unexpected cursor content: %d %s", value, decodedID.Hex())
+	}
+
+	for _, invalid := range []string{"%%%", "bm8tc2VwYXJhdG9y", "YWJjOjEyMw"} {
+		if _, _, err := decodeConfidentialResponsesCursor(invalid); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
+
+func TestPrepConfidentialResponsesQuery(t *testing.T) {
+	t.Run("participant required", func(t *testing.T) {
+		if _, _, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		filter, opts, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{ParticipantID: "p1", Key: "contact"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter["key"] != "contact" {
+			t.Errorf("unexpected key filter: %v", filter["key"])
+		}
+		if *opts.Limit != defaultConfidentialResponsesPageSize {
+			t.Errorf("unexpected limit: %d", *opts.Limit)
+		}
+		sort := opts.Sort.(bson.D)
+		if sort[0].Key != "arrivedAt" || sort[0].Value != 1 || sort[1].Key != "_id" {
+			t.Errorf("unexpected sort: %v", sort)
+		}
+		if opts.Projection != nil {
+			t.Error("expected no projection")
+		}
+	})
+
+	t.Run("key prefix is escaped", func(t *testing.T) {
+		filter, _, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{ParticipantID: "p1", KeyPrefix: "a.b", Key: "ignored"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter["key"].(bson.M)["$regex"] != `^a\.b` {
+			t.Errorf("unexpected key filter: %v", filter["key"])
+		}
+	})
+
+	t.Run("cursor descending", func(t *testing.T) {
+		id := primitive.NewObjectID()
+		filter, opts, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{
+			ParticipantID: "p1",
+			SortBy:        "submittedAt",
+			SortDesc:      true,
+			Cursor:        encodeConfidentialResponsesCursor(10, id),
+			Limit:         10000,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		or := filter["$or"].(bson.A)
+		if or[0].(bson.M)["submittedAt"].(bson.M)["$lt"] != int64(10) {
+			t.Errorf("unexpected cursor filter: %v", or)
+		}
+		if or[1].(bson.M)["_id"].(bson.M)["$lt"] != id {
+			t.Errorf("unexpected tie breaker: %v", or)
+		}
+		if *opts.Limit != maxConfidentialResponsesPageSize {
+			t.Errorf("limit should be capped: %d", *opts.Limit)
+		}
+	})
+
+	t.Run("projection", func(t *testing.T) {
+		_, opts, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{ParticipantID: "p1", Fields: []string{"key", "responses"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		projection := opts.Projection.(bson.M)
+		for _, f := range []string{"_id", "participantID", "arrivedAt", "key", "responses", "encryptedData", "dataKeyID"} {
+			if projection[f] != 1 {
+				t.Errorf("expected %s in projection", f)
+			}
+		}
+		if _, ok := projection["context"]; ok {
+			t.Error("context should not be projected")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, _, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{ParticipantID: "p1", SortBy: "key"}); err == nil {
+			t.Error("expected error for sort field")
+		}
+		if _, _, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{ParticipantID: "p1", Fields: []string{"encryptedData"}}); err == nil {
+			t.Error("expected error for field")
+		}
+		if _, _, err := prepConfidentialResponsesQuery(ConfidentialResponsesQuery{ParticipantID: "p1", Cursor: "invalid"}); err == nil {
+			t.Error("expected error for cursor")
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	REMOVE_DATA_QUALITY_FINDINGS_AFTER = 60 * 60 * 24 * 180 // 180 days
+)
+
+func (dbService *StudyDBService) collectionDataQualityFindings(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_DATA_QUALITY_FINDINGS)
+}
+
+func dataQualityFindingIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_DATA_QUALITY_FINDINGS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "type", Value: 1},
+						{Key: "detectedAt", Value: -1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "detectedAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(REMOVE_DATA_QUALITY_FINDINGS_AFTER),
+				},
+			},
+		},
+	}
+}
+
+func (dbService *StudyDBService) AddDataQualityFinding(instanceID string, finding studyTypes.DataQualityFinding) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if finding.DetectedAt.IsZero() {
+		finding.DetectedAt = time.Now()
+	}
+
+	_, err := dbService.collectionDataQualityFindings(instanceID).InsertOne(ctx, finding)
+	return err
+}
+
+// GetLatestDataQualityFinding returns the newest finding of the type for the study, nil if there is none
+func (dbService *StudyDBService) GetLatestDataQualityFinding(instanceID string, studyKey string, findingType string) (*studyTypes.DataQualityFinding, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "type": findingType}
+	opts := options.FindOne().SetSort(bson.D{{Key: "detectedAt", Value: -1}})
+
+	var finding studyTypes.DataQualityFinding
+	err := dbService.collectionDataQualityFindings(instanceID).FindOne(ctx, filter, opts).Decode(&finding)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &finding, nil
+}
+
+// GetDataQualityFindings returns the findings of the study, optionally filtered by type, newest first
+func (dbService *StudyDBService) GetDataQualityFindings(instanceID string, studyKey string, findingType string, page int64, limit int64) (findings []studyTypes.DataQualityFinding, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if findingType != "" {
+		filter["type"] = findingType
+	}
+
+	totalCount, err := dbService.collectionDataQualityFindings(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	paginationInfo = prepPaginationInfos(totalCount, page, limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "detectedAt", Value: -1}}).
+		SetSkip((paginationInfo.CurrentPage - 1) * paginationInfo.PageSize).
+		SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionDataQualityFindings(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	findings = []studyTypes.DataQualityFinding{}
+	if err = cursor.All(ctx, &findings); err != nil {
+		return nil, nil, err
+	}
+	return findings, paginationInfo, nil
+}
+
+func submittedResponsesFilter(from int64, until int64) bson.M {
+	return ExcludeSynthetic(bson.M{
+		"submittedAt":  bson.M{"$gte": from, "$lt": until},
+		"supersededBy": bson.M{"$exists": false},
+	})
+}
+
+// CountSubmittedResponses counts the responses submitted in [from, until), superseded responses and responses of
+// synthetic participants are not counted
+func (dbService *StudyDBService) CountSubmittedResponses(instanceID string, studyKey string, from int64, until int64) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	return dbService.collectionResponses(instanceID, studyKey).CountDocuments(ctx, submittedResponsesFilter(from, until))
+}
+
+// CountDuplicateResponses counts the responses submitted in [from, until) beyond the first one of each participant
+// and survey
+func (dbService *StudyDBService) CountDuplicateResponses(instanceID string, studyKey string, from int64, until int64) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: submittedResponsesFilter(from, until)}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"participantID": "$participantID", "key": "$key"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        nil,
+			"duplicates": bson.M{"$sum": bson.M{"$subtract": bson.A{"$count", 1}}},
+		}}},
+	}
+	cursor, err := dbService.collectionResponses(instanceID, studyKey).Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Duplicates int64 `bson:"duplicates"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Duplicates, nil
+}
+
+// CountRuleErrors counts the rule errors of the study in [from, until)
+func (dbService *StudyDBService) CountRuleErrors(instanceID string, studyKey string, from time.Time, until time.Time) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey": studyKey,
+		"time":     bson.M{"$gte": from, "$lt": until},
+	}
+	return dbService.collectionRuleErrors(instanceID).CountDocuments(ctx, filter)
+}
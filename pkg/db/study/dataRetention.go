@@ -0,0 +1,147 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) UpdateStudyDataRetentionPolicy(instanceID string, studyKey string, policy *studyTypes.DataRetentionPolicy) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.dataRetention": policy}}
+	if policy == nil {
+		update = bson.M{"$unset": bson.M{"configs.dataRetention": ""}}
+	}
+
+	res, err := dbService.collectionStudyInfos(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// AddDataRetentionStats adds the pruned counts of a retention run to the study totals
+func (dbService *StudyDBService) AddDataRetentionStats(instanceID string, studyKey string, report studyTypes.DataRetentionReport) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	filter := bson.M{"key": studyKey}
+	update := bson.M{
+		"$set": bson.M{"dataRetentionStats.lastRunAt": time.Now().Unix()},
+		"$inc": bson.M{
+			"dataRetentionStats.prunedResponses":             report.Responses,
+			"dataRetentionStats.prunedConfidentialResponses": report.ConfidentialResponses,
+			"dataRetentionStats.prunedReports":               report.Reports,
+			"dataRetentionStats.prunedFiles":                 report.Files,
+		},
+	}
+	_, err := dbService.collectionStudyInfos(instanceID).UpdateOne(ctx, filter, update)
+	return err
+}
+
+func responsesBeforeFilter(cutoff int64) bson.M {
+	return bson.M{"arrivedAt": bson.M{"$lt": cutoff}}
+}
+
+func reportsBeforeFilter(cutoff int64) bson.M {
+	return bson.M{"timestamp": bson.M{"$lt": cutoff}}
+}
+
+func filesBeforeFilter(cutoff int64) bson.M {
+	return bson.M{"submittedAt": bson.M{"$lt": cutoff}}
+}
+
+// CountDataBeforeCutoffs counts the documents of the study, which would be removed with the cutoffs
+func (dbService *StudyDBService) CountDataBeforeCutoffs(instanceID string, studyKey string, cutoffs studyTypes.DataRetentionCutoffs) (report studyTypes.DataRetentionReport, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	report.StudyKey = studyKey
+	report.Cutoffs = cutoffs
+
+	if cutoffs.Responses > 0 {
+		report.Responses, err = dbService.collectionResponses(instanceID, studyKey).CountDocuments(ctx, responsesBeforeFilter(cutoffs.Responses))
+		if err != nil {
+			return report, err
+		}
+	}
+	if cutoffs.ConfidentialResponses > 0 {
+		report.ConfidentialResponses, err = dbService.collectionConfidentialResponses(instanceID, studyKey).CountDocuments(ctx, responsesBeforeFilter(cutoffs.ConfidentialResponses))
+		if err != nil {
+			return report, err
+		}
+	}
+	if cutoffs.Reports > 0 {
+		report.Reports, err = dbService.collectionReports(instanceID, studyKey).CountDocuments(ctx, reportsBeforeFilter(cutoffs.Reports))
+		if err != nil {
+			return report, err
+		}
+	}
+	if cutoffs.Files > 0 {
+		report.Files, err = dbService.collectionFiles(instanceID, studyKey).CountDocuments(ctx, filesBeforeFilter(cutoffs.Files))
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (dbService *StudyDBService) DeleteResponsesBefore(instanceID string, studyKey string, cutoff int64) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionResponses(instanceID, studyKey).DeleteMany(ctx, responsesBeforeFilter(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (dbService *StudyDBService) DeleteConfidentialResponsesBefore(instanceID string, studyKey string, cutoff int64) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionConfidentialResponses(instanceID, studyKey).DeleteMany(ctx, responsesBeforeFilter(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (dbService *StudyDBService) DeleteReportsBefore(instanceID string, studyKey string, cutoff int64) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionReports(instanceID, studyKey).DeleteMany(ctx, reportsBeforeFilter(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// GetParticipantFileInfosBefore returns up to limit file infos submitted before the cutoff, so the stored files can be removed before their file info
+func (dbService *StudyDBService) GetParticipantFileInfosBefore(instanceID string, studyKey string, cutoff int64, limit int64) (fileInfos []studyTypes.FileInfo, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "submittedAt", Value: 1}}).SetLimit(limit)
+	cursor, err := dbService.collectionFiles(instanceID, studyKey).Find(ctx, filesBeforeFilter(cutoff), opts)
+	if err != nil {
+		return nil, err
+	}
+	fileInfos = []studyTypes.FileInfo{}
+	err = cursor.All(ctx, &fileInfos)
+	return fileInfos, err
+}
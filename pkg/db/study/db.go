@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/cache"
 	"github.com/case-framework/case-backend/pkg/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -24,29 +25,63 @@ const (
 	COLLECTION_NAME_SUFFIX_FILES                  = "participantFiles"
 	COLLECTION_NAME_SUFFIX_RESEARCHER_MESSAGES    = "researcherMessages"
 	COLLECTION_NAME_TASK_QUEUE                    = "taskQueue"
+	COLLECTION_NAME_ENROLLMENT_COUNTERS           = "enrollmentCounters"
+	COLLECTION_NAME_EXTERNAL_SERVICE_TASKS        = "externalServiceTasks"
+	COLLECTION_NAME_RANDOMIZATION_BLOCKS          = "randomizationBlocks"
+	COLLECTION_NAME_RANDOMIZATION_ALLOCATIONS     = "randomizationAllocations"
+	COLLECTION_NAME_SCHEDULED_EVENTS              = "scheduledEvents"
+	COLLECTION_NAME_CONFIDENTIAL_ACCESS_GRANTS    = "confidentialAccessGrants"
+	COLLECTION_NAME_AUDIT_LOG                     = "auditLog"
+	COLLECTION_NAME_DATA_KEYS                     = "dataKeys"
+	COLLECTION_NAME_DRAFTS                        = "drafts"
+	COLLECTION_NAME_REPORT_TEMPLATES              = "reportTemplates"
+	COLLECTION_NAME_RULE_ERRORS                   = "ruleErrors"
+	COLLECTION_NAME_EXPORT_JOBS                   = "exportJobs"
+	COLLECTION_NAME_EXPORT_CHECKPOINTS            = "exportCheckpoints"
+	COLLECTION_NAME_SCHEDULED_EXPORTS             = "scheduledExports"
+	COLLECTION_NAME_SURVEY_REMINDERS              = "surveyReminders"
+	COLLECTION_NAME_DATA_QUALITY_FINDINGS         = "dataQualityFindings"
+	COLLECTION_NAME_PARTICIPANT_BULK_JOBS         = "participantBulkJobs"
+	COLLECTION_NAME_PARTICIPANT_BULK_JOB_RESULTS  = "participantBulkJobResults"
 )
 
 const (
 	REMOVE_TASK_FROM_QUEUE_AFTER = 60 * 60 * 24 * 2 // 2 days
+
+	REMOVE_RULE_ERRORS_AFTER = 60 * 60 * 24 * 30 // 30 days
 )
 
+// number of documents fetched per round trip when streaming exports, keeps memory use bounded for large studies
+const exportCursorBatchSize = 500
+
 type StudyDBService struct {
 	DBClient        *mongo.Client
 	timeout         int
 	noCursorTimeout bool
 	DBNamePrefix    string
 	InstanceIDs     []string
+
+	flagTypes  flagTypesCache
+	statistics statisticsCache
+
+	// optional shared cache of study infos and current survey versions, see SetCache
+	cache    cache.Cache
+	cacheTTL time.Duration
+
+	// nil if confidential responses are stored unencrypted
+	encryption *confidentialResponseEncryption
 }
 
 func NewStudyDBService(configs db.DBConfig) (*StudyDBService, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(configs.Timeout)*time.Second)
 	defer cancel()
 
-	dbClient, err := mongo.Connect(ctx,
-		options.Client().ApplyURI(configs.URI),
-		options.Client().SetMaxConnIdleTime(time.Duration(configs.IdleConnTimeout)*time.Second),
-		options.Client().SetMaxPoolSize(configs.MaxPoolSize),
-	)
+	clientOptions, err := db.ClientOptions(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	dbClient, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
 		return nil, err
@@ -68,6 +103,11 @@ func NewStudyDBService(configs db.DBConfig) (*StudyDBService, error) {
 		InstanceIDs:     configs.InstanceIDs,
 	}
 
+	// migrations run first, so that indexes are created on migrated data
+	if configs.RunMigrations {
+		studyDBSc.applyMigrations()
+	}
+
 	if configs.RunIndexCreation {
 		if err := studyDBSc.ensureIndexes(); err != nil {
 			slog.Error("Error ensuring indexes for study DB", slog.String("error", err.Error()))
@@ -93,6 +133,10 @@ func (dbService *StudyDBService) collectionTaskQueue(instanceID string) *mongo.C
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_TASK_QUEUE)
 }
 
+func (dbService *StudyDBService) collectionEnrollmentCounters(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_ENROLLMENT_COUNTERS)
+}
+
 func (dbService *StudyDBService) collectionSurveys(instanceID string, studyKey string) *mongo.Collection {
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(studyKey + "_" + COLLECTION_NAME_SUFFIX_SURVEYS)
 }
@@ -125,88 +169,105 @@ func (dbService *StudyDBService) collectionResearcherMessages(instanceID string,
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(studyKey + "_" + COLLECTION_NAME_SUFFIX_RESEARCHER_MESSAGES)
 }
 
+// Ping checks the connection to the study DB, it can be called on a nil service
+func (dbService *StudyDBService) Ping(ctx context.Context) error {
+	if dbService == nil {
+		return db.Ping(ctx, nil)
+	}
+	return db.Ping(ctx, dbService.DBClient)
+}
+
+// Close disconnects the client, called on shutdown after running requests are finished
+func (dbService *StudyDBService) Close(ctx context.Context) error {
+	if dbService == nil {
+		return nil
+	}
+	return db.Disconnect(ctx, dbService.DBClient)
+}
+
 func (dbService *StudyDBService) getContext() (ctx context.Context, cancel context.CancelFunc) {
 	return context.WithTimeout(context.Background(), time.Duration(dbService.timeout)*time.Second)
 }
 
-func (dbService *StudyDBService) ensureIndexes() error {
-	slog.Debug("Ensuring indexes for study DB")
-	for _, instanceID := range dbService.InstanceIDs {
-		ctx, cancel := dbService.getContext()
-		defer cancel()
-
-		// task queue: auto delete on creation date
-		_, err := dbService.collectionTaskQueue(instanceID).Indexes().CreateOne(
-			ctx,
-			mongo.IndexModel{
-				Keys:    bson.D{{Key: "updatedAt", Value: 1}},
-				Options: options.Index().SetExpireAfterSeconds(REMOVE_TASK_FROM_QUEUE_AFTER),
+// instanceIndexRegistry lists the indexes of the collections shared by all studies of an instance
+func instanceIndexRegistry() []db.CollectionIndexes {
+	registry := []db.CollectionIndexes{
+		{
+			// task queue: auto delete on creation date
+			Collection: COLLECTION_NAME_TASK_QUEUE,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "updatedAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(REMOVE_TASK_FROM_QUEUE_AFTER),
+				},
 			},
-		)
-		if err != nil {
-			slog.Error("Error creating index for createdAt in userDB.sessions", slog.String("error", err.Error()))
-		}
+		},
+		{
+			Collection: COLLECTION_NAME_CONFIDENTIAL_ID_MAP,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "confidentialID", Value: 1},
+						{Key: "studyKey", Value: 1},
+					},
+				},
+			},
+		},
+	}
+	registry = append(registry, studyInfoIndexes()...)
+	registry = append(registry, studyRuleIndexes()...)
+	registry = append(registry, enrollmentCounterIndexes()...)
+	registry = append(registry, externalServiceTaskIndexes()...)
+	registry = append(registry, randomizationIndexes()...)
+	registry = append(registry, scheduledEventIndexes()...)
+	registry = append(registry, confidentialAccessGrantIndexes()...)
+	registry = append(registry, auditLogIndexes()...)
+	registry = append(registry, draftIndexes()...)
+	registry = append(registry, reportTemplateIndexes()...)
+	registry = append(registry, ruleErrorIndexes()...)
+	registry = append(registry, exportJobIndexes()...)
+	registry = append(registry, scheduledExportIndexes()...)
+	registry = append(registry, surveyReminderIndexes()...)
+	registry = append(registry, dataQualityFindingIndexes()...)
+	registry = append(registry, participantBulkJobIndexes()...)
+	return registry
+}
 
-		// index on studyInfos
-		err = dbService.createIndexForStudyInfosCollection(instanceID)
-		if err != nil {
-			slog.Error("Error creating index for studyInfos", slog.String("error", err.Error()))
-		}
+// studyIndexRegistry lists the indexes of the collections of a study
+func studyIndexRegistry(studyKey string) []db.CollectionIndexes {
+	registry := []db.CollectionIndexes{}
+	registry = append(registry, surveyIndexes(studyKey)...)
+	registry = append(registry, participantIndexes(studyKey)...)
+	registry = append(registry, responseIndexes(studyKey)...)
+	registry = append(registry, reportIndexes(studyKey)...)
+	registry = append(registry, confidentialResponseIndexes(studyKey)...)
+	return registry
+}
 
-		// index on confidentialIDMap
-		_, err = dbService.collectionConfidentialIDMap(instanceID).Indexes().CreateOne(
-			ctx,
-			mongo.IndexModel{
-				Keys: bson.D{
-					{Key: "confidentialID", Value: 1},
-					{Key: "studyKey", Value: 1},
-				}},
-		)
-		if err != nil {
-			slog.Error("Error creating index for confidentialIDMap", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
-		}
+// EnsureIndexes creates missing indexes in the DB of the instance, for the shared collections and the collections of
+// all studies, and reports indexes that are not in the registry
+func (dbService *StudyDBService) EnsureIndexes(instanceID string, opts db.EnsureIndexesOptions) (db.IndexReport, error) {
+	studies, err := dbService.GetStudies(instanceID, "", true)
+	if err != nil {
+		return db.IndexReport{}, err
+	}
 
-		//fetch studyKeys from studyInfos
-		studies, err := dbService.GetStudies(instanceID, "", true)
+	registry := instanceIndexRegistry()
+	for _, study := range studies {
+		registry = append(registry, studyIndexRegistry(study.Key)...)
+	}
+	return db.EnsureIndexes(dbService.getContext, dbService.DBClient.Database(dbService.getDBName(instanceID)), registry, opts), nil
+}
+
+func (dbService *StudyDBService) ensureIndexes() error {
+	slog.Debug("Ensuring indexes for study DB")
+	for _, instanceID := range dbService.InstanceIDs {
+		report, err := dbService.EnsureIndexes(instanceID, db.EnsureIndexesOptions{})
 		if err != nil {
 			slog.Error("Error fetching studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
 			return err
 		}
-
-		// index on studyRules
-		err = dbService.CreateIndexForStudyRulesCollection(instanceID)
-		if err != nil {
-			slog.Error("Error creating index for studyRules: ", slog.String("error", err.Error()))
-		}
-
-		for _, study := range studies {
-			studyKey := study.Key
-
-			// index on surveys
-			err = dbService.CreateIndexForSurveyCollection(instanceID, studyKey)
-			if err != nil {
-				slog.Error("Error creating index for surveys: ", slog.String("error", err.Error()))
-			}
-
-			// index on participants
-			err = dbService.CreateIndexForParticipantsCollection(instanceID, studyKey)
-			if err != nil {
-				slog.Error("Error creating index for participants: ", slog.String("error", err.Error()))
-			}
-
-			// index on responses
-			err = dbService.CreateIndexForResponsesCollection(instanceID, studyKey)
-			if err != nil {
-				slog.Error("Error creating index for responses: ", slog.String("error", err.Error()))
-			}
-
-			// index on reports
-			err = dbService.CreateIndexForReportsCollection(instanceID, studyKey)
-			if err != nil {
-				slog.Error("Error creating index for reports: ", slog.String("error", err.Error()))
-			}
-		}
-
+		report.Log()
 	}
 	return nil
 }
@@ -0,0 +1,223 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) collectionDrafts(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_DRAFTS)
+}
+
+func draftIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_DRAFTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "type", Value: 1},
+						{Key: "surveyKey", Value: 1},
+						{Key: "status", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "status", Value: 1},
+						{Key: "scheduledFor", Value: 1},
+					},
+				},
+			},
+		},
+	}
+}
+
+// study rules are stored serialised, same as in the study rules collection
+func prepDraftForSave(draft studyTypes.Draft) (studyTypes.Draft, error) {
+	if draft.StudyRules != nil {
+		rules := *draft.StudyRules
+		if err := rules.MarshalRules(); err != nil {
+			return draft, err
+		}
+		draft.StudyRules = &rules
+	}
+	return draft, nil
+}
+
+func prepDraftAfterLoad(draft *studyTypes.Draft) error {
+	if draft.StudyRules != nil {
+		return draft.StudyRules.UnmarshalRules()
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) AddDraft(instanceID string, draft studyTypes.Draft) (studyTypes.Draft, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	draft.ID = primitive.NilObjectID
+	toSave, err := prepDraftForSave(draft)
+	if err != nil {
+		return draft, err
+	}
+
+	res, err := dbService.collectionDrafts(instanceID).InsertOne(ctx, toSave)
+	if err != nil {
+		return draft, err
+	}
+	draft.ID = res.InsertedID.(primitive.ObjectID)
+	return draft, nil
+}
+
+// ReplaceDraft saves the draft if its stored status is still expectedStatus, otherwise returns mongo.ErrNoDocuments
+func (dbService *StudyDBService) ReplaceDraft(instanceID string, draft studyTypes.Draft, expectedStatus string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	toSave, err := prepDraftForSave(draft)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":      draft.ID,
+		"studyKey": draft.StudyKey,
+		"status":   expectedStatus,
+	}
+	res, err := dbService.collectionDrafts(instanceID).ReplaceOne(ctx, filter, toSave)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) findDrafts(instanceID string, filter bson.M, opts *options.FindOptions) (drafts []studyTypes.Draft, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	cursor, err := dbService.collectionDrafts(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	drafts = []studyTypes.Draft{}
+	if err = cursor.All(ctx, &drafts); err != nil {
+		return nil, err
+	}
+	for i := range drafts {
+		if err = prepDraftAfterLoad(&drafts[i]); err != nil {
+			return nil, err
+		}
+	}
+	return drafts, nil
+}
+
+// GetDrafts returns the drafts of the study, newest first. Published drafts are only included if requested.
+func (dbService *StudyDBService) GetDrafts(instanceID string, studyKey string, includePublished bool) ([]studyTypes.Draft, error) {
+	filter := bson.M{"studyKey": studyKey}
+	if !includePublished {
+		filter["status"] = bson.M{"$ne": studyTypes.DRAFT_STATUS_PUBLISHED}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: -1}})
+	return dbService.findDrafts(instanceID, filter, opts)
+}
+
+func (dbService *StudyDBService) GetDraftByID(instanceID string, studyKey string, draftID string) (draft studyTypes.Draft, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(draftID)
+	if err != nil {
+		return draft, err
+	}
+
+	filter := bson.M{
+		"_id":      _id,
+		"studyKey": studyKey,
+	}
+	err = dbService.collectionDrafts(instanceID).FindOne(ctx, filter).Decode(&draft)
+	if err != nil {
+		return draft, err
+	}
+	err = prepDraftAfterLoad(&draft)
+	return draft, err
+}
+
+// GetOpenDraft returns the not yet published draft of the survey (or of the study rules if surveyKey is empty)
+func (dbService *StudyDBService) GetOpenDraft(instanceID string, studyKey string, draftType string, surveyKey string) (draft studyTypes.Draft, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey": studyKey,
+		"type":     draftType,
+		"status":   bson.M{"$ne": studyTypes.DRAFT_STATUS_PUBLISHED},
+	}
+	if surveyKey != "" {
+		filter["surveyKey"] = surveyKey
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "updatedAt", Value: -1}})
+
+	err = dbService.collectionDrafts(instanceID).FindOne(ctx, filter, opts).Decode(&draft)
+	if err != nil {
+		return draft, err
+	}
+	err = prepDraftAfterLoad(&draft)
+	return draft, err
+}
+
+// GetDueScheduledDrafts returns the scheduled drafts of the study whose publication time has been reached
+func (dbService *StudyDBService) GetDueScheduledDrafts(instanceID string, studyKey string, now time.Time) ([]studyTypes.Draft, error) {
+	filter := bson.M{
+		"studyKey":     studyKey,
+		"status":       studyTypes.DRAFT_STATUS_SCHEDULED,
+		"scheduledFor": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "scheduledFor", Value: 1}})
+	return dbService.findDrafts(instanceID, filter, opts)
+}
+
+// DeleteDraft removes a draft that has not been published yet
+func (dbService *StudyDBService) DeleteDraft(instanceID string, studyKey string, draftID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(draftID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":      _id,
+		"studyKey": studyKey,
+		"status":   bson.M{"$ne": studyTypes.DRAFT_STATUS_PUBLISHED},
+	}
+	res, err := dbService.collectionDrafts(instanceID).DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) deleteDraftsOfStudy(instanceID string, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionDrafts(instanceID).DeleteMany(ctx, bson.M{"studyKey": studyKey})
+	return err
+}
@@ -0,0 +1,63 @@
+package study
+
+import (
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func enrollmentCounterIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_ENROLLMENT_COUNTERS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "day", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+	}
+}
+
+// IncrementEnrollmentCounterIfBelow counts an enrollment for the day, if the limit is not reached yet.
+// Returns false if the limit was already reached.
+func (dbService *StudyDBService) IncrementEnrollmentCounterIfBelow(instanceID string, studyKey string, day string, limit int64) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey": studyKey,
+		"day":      day,
+		"count":    bson.M{"$lt": limit},
+	}
+	update := bson.M{"$inc": bson.M{"count": 1}}
+	_, err := dbService.collectionEnrollmentCounters(instanceID).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// the counter of the day exists, but is not below the limit: the upsert conflicts with the unique index
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// GetEnrollmentCount returns the number of counted enrollments of the day
+func (dbService *StudyDBService) GetEnrollmentCount(instanceID string, studyKey string, day string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var counter struct {
+		Count int64 `bson:"count"`
+	}
+	err := dbService.collectionEnrollmentCounters(instanceID).FindOne(ctx, bson.M{"studyKey": studyKey, "day": day}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return counter.Count, err
+}
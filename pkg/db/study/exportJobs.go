@@ -0,0 +1,394 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) collectionExportJobs(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_EXPORT_JOBS)
+}
+
+func (dbService *StudyDBService) collectionExportCheckpoints(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_EXPORT_CHECKPOINTS)
+}
+
+func exportJobIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_EXPORT_CHECKPOINTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "deltaKey", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+		{
+			Collection: COLLECTION_NAME_EXPORT_JOBS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "status", Value: 1},
+						{Key: "createdAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "createdBy", Value: 1},
+						{Key: "createdAt", Value: -1},
+					},
+				},
+				{
+					Keys: bson.D{{Key: "expiresAt", Value: 1}},
+				},
+				{
+					Keys: bson.D{
+						{Key: "delivery.status", Value: 1},
+						{Key: "delivery.nextAttemptAt", Value: 1},
+					},
+				},
+			},
+		},
+	}
+}
+
+// CreateExportJob queues a new export job
+func (dbService *StudyDBService) CreateExportJob(instanceID string, job studyTypes.ExportJob) (studyTypes.ExportJob, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	job.ID = primitive.NilObjectID
+	job.Status = studyTypes.EXPORT_JOB_STATUS_QUEUED
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	job.ProcessedCount = 0
+
+	res, err := dbService.collectionExportJobs(instanceID).InsertOne(ctx, job)
+	if err != nil {
+		return job, err
+	}
+	job.ID = res.InsertedID.(primitive.ObjectID)
+	return job, nil
+}
+
+// ClaimQueuedExportJob marks the oldest queued job as running until leaseUntil, so concurrent workers don't pick it up.
+// Jobs of crashed workers are picked up again after the lease expired. Returns nil if no job is waiting.
+func (dbService *StudyDBService) ClaimQueuedExportJob(instanceID string, now time.Time, leaseUntil time.Time) (*studyTypes.ExportJob, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": studyTypes.EXPORT_JOB_STATUS_QUEUED},
+			bson.M{"status": studyTypes.EXPORT_JOB_STATUS_RUNNING, "leaseUntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":         studyTypes.EXPORT_JOB_STATUS_RUNNING,
+			"processedCount": 0,
+			"leaseUntil":     leaseUntil,
+			"updatedAt":      now,
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job studyTypes.ExportJob
+	err := dbService.collectionExportJobs(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (dbService *StudyDBService) UpdateExportJobTargetCount(instanceID string, id primitive.ObjectID, targetCount int) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"targetCount": targetCount,
+			"updatedAt":   time.Now(),
+		},
+	}
+	_, err := dbService.collectionExportJobs(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// UpdateExportJobProgress stores the processed count and extends the lease of the running job
+func (dbService *StudyDBService) UpdateExportJobProgress(instanceID string, id primitive.ObjectID, processedCount int, leaseUntil time.Time) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"processedCount": processedCount,
+			"leaseUntil":     leaseUntil,
+			"updatedAt":      time.Now(),
+		},
+	}
+	_, err := dbService.collectionExportJobs(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// CompleteExportJob stores the artifact of the job, which is kept until expiresAt
+func (dbService *StudyDBService) CompleteExportJob(
+	instanceID string,
+	id primitive.ObjectID,
+	processedCount int,
+	resultFile string,
+	fileSize int64,
+	window *studyTypes.ExportWindow,
+	expiresAt time.Time,
+) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{
+		"status":         studyTypes.EXPORT_JOB_STATUS_COMPLETED,
+		"processedCount": processedCount,
+		"resultFile":     resultFile,
+		"fileSize":       fileSize,
+		"completedAt":    now,
+		"expiresAt":      expiresAt,
+		"updatedAt":      now,
+	}
+	if window != nil {
+		set["window"] = window
+	}
+	update := bson.M{
+		"$set":   set,
+		"$unset": bson.M{"leaseUntil": ""},
+	}
+	_, err := dbService.collectionExportJobs(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// FailExportJob marks the job as failed, the job is removed after expiresAt
+func (dbService *StudyDBService) FailExportJob(instanceID string, id primitive.ObjectID, errMsg string, expiresAt time.Time) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":      studyTypes.EXPORT_JOB_STATUS_FAILED,
+			"error":       errMsg,
+			"completedAt": now,
+			"expiresAt":   expiresAt,
+			"updatedAt":   now,
+		},
+		"$unset": bson.M{"leaseUntil": ""},
+	}
+	_, err := dbService.collectionExportJobs(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// ClaimDueExportDelivery returns a completed job whose pending delivery is due and hides it from other workers until
+// leaseUntil. Returns nil if no delivery is due.
+func (dbService *StudyDBService) ClaimDueExportDelivery(instanceID string, now time.Time, leaseUntil time.Time) (*studyTypes.ExportJob, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"status":                 studyTypes.EXPORT_JOB_STATUS_COMPLETED,
+		"delivery.status":        studyTypes.EXPORT_DELIVERY_STATUS_PENDING,
+		"delivery.nextAttemptAt": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"delivery.nextAttemptAt": leaseUntil,
+			"updatedAt":              now,
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "delivery.nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job studyTypes.ExportJob
+	err := dbService.collectionExportJobs(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (dbService *StudyDBService) UpdateExportJobDelivery(instanceID string, id primitive.ObjectID, delivery studyTypes.ExportDelivery) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"delivery":  delivery,
+			"updatedAt": time.Now(),
+		},
+	}
+	_, err := dbService.collectionExportJobs(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (dbService *StudyDBService) GetExportJobByID(instanceID string, jobID string) (job studyTypes.ExportJob, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return job, err
+	}
+
+	err = dbService.collectionExportJobs(instanceID).FindOne(ctx, bson.M{"_id": _id}).Decode(&job)
+	return job, err
+}
+
+// GetExportJobs returns the export jobs of the study, optionally only of one user, newest first
+func (dbService *StudyDBService) GetExportJobs(instanceID string, studyKey string, createdBy string, page int64, limit int64) (jobs []studyTypes.ExportJob, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if createdBy != "" {
+		filter["createdBy"] = createdBy
+	}
+
+	totalCount, err := dbService.collectionExportJobs(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	paginationInfo = prepPaginationInfos(totalCount, page, limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((paginationInfo.CurrentPage - 1) * paginationInfo.PageSize).
+		SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionExportJobs(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs = []studyTypes.ExportJob{}
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, nil, err
+	}
+	return jobs, paginationInfo, nil
+}
+
+// GetExpiredExportJobs returns finished jobs whose artifacts expired before the reference time, artifacts waiting for delivery are kept
+func (dbService *StudyDBService) GetExpiredExportJobs(instanceID string, before time.Time) (jobs []studyTypes.ExportJob, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"status":          bson.M{"$in": []string{studyTypes.EXPORT_JOB_STATUS_COMPLETED, studyTypes.EXPORT_JOB_STATUS_FAILED}},
+		"expiresAt":       bson.M{"$lte": before},
+		"delivery.status": bson.M{"$ne": studyTypes.EXPORT_DELIVERY_STATUS_PENDING},
+	}
+	cursor, err := dbService.collectionExportJobs(instanceID).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs = []studyTypes.ExportJob{}
+	err = cursor.All(ctx, &jobs)
+	return jobs, err
+}
+
+func (dbService *StudyDBService) DeleteExportJob(instanceID string, id primitive.ObjectID) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionExportJobs(instanceID).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetExportCheckpoint returns the checkpoint of the delta export configuration, nil if there was no successful export yet
+func (dbService *StudyDBService) GetExportCheckpoint(instanceID string, studyKey string, deltaKey string) (*studyTypes.ExportCheckpoint, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var checkpoint studyTypes.ExportCheckpoint
+	err := dbService.collectionExportCheckpoints(instanceID).FindOne(ctx, bson.M{"studyKey": studyKey, "deltaKey": deltaKey}).Decode(&checkpoint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// AdvanceExportCheckpoint moves the checkpoint of the delta export configuration forward to until, it never moves backwards
+func (dbService *StudyDBService) AdvanceExportCheckpoint(instanceID string, studyKey string, deltaKey string, until int64, jobID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "deltaKey": deltaKey}
+	update := bson.M{
+		"$max": bson.M{"until": until},
+		"$set": bson.M{
+			"jobID":     jobID,
+			"updatedAt": time.Now(),
+		},
+	}
+	_, err := dbService.collectionExportCheckpoints(instanceID).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (dbService *StudyDBService) GetExportCheckpoints(instanceID string, studyKey string) (checkpoints []studyTypes.ExportCheckpoint, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	cursor, err := dbService.collectionExportCheckpoints(instanceID).Find(ctx, bson.M{"studyKey": studyKey}, options.Find().SetSort(bson.D{{Key: "deltaKey", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	checkpoints = []studyTypes.ExportCheckpoint{}
+	err = cursor.All(ctx, &checkpoints)
+	return checkpoints, err
+}
+
+// DeleteExportCheckpoint resets the delta export configuration, so that the next job exports all responses
+func (dbService *StudyDBService) DeleteExportCheckpoint(instanceID string, studyKey string, deltaKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionExportCheckpoints(instanceID).DeleteOne(ctx, bson.M{"studyKey": studyKey, "deltaKey": deltaKey})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	REMOVE_EXTERNAL_SERVICE_TASKS_AFTER = 60 * 60 * 24 * 30 // 30 days
+)
+
+func (dbService *StudyDBService) collectionExternalServiceTasks(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_EXTERNAL_SERVICE_TASKS)
+}
+
+func externalServiceTaskIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_EXTERNAL_SERVICE_TASKS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "status", Value: 1},
+						{Key: "nextAttemptAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "participantID", Value: 1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "createdAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(REMOVE_EXTERNAL_SERVICE_TASKS_AFTER),
+				},
+			},
+		},
+	}
+}
+
+func (dbService *StudyDBService) AddExternalServiceTask(instanceID string, task studyTypes.ExternalServiceTask) (string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	task.ID = primitive.NilObjectID
+	task.Status = studyTypes.EXTERNAL_SERVICE_TASK_STATUS_PENDING
+	task.CreatedAt = time.Now()
+	if task.NextAttemptAt.IsZero() {
+		task.NextAttemptAt = task.CreatedAt
+	}
+
+	res, err := dbService.collectionExternalServiceTasks(instanceID).InsertOne(ctx, task)
+	if err != nil {
+		return "", err
+	}
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// ClaimDueExternalServiceTask marks the next due task as processing until leaseUntil, so concurrent workers don't pick it up.
+// Tasks of crashed workers are picked up again after the lease expired. Returns nil if no task is due.
+func (dbService *StudyDBService) ClaimDueExternalServiceTask(instanceID string, now time.Time, leaseUntil time.Time) (*studyTypes.ExternalServiceTask, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"status": bson.M{"$in": []string{
+			studyTypes.EXTERNAL_SERVICE_TASK_STATUS_PENDING,
+			studyTypes.EXTERNAL_SERVICE_TASK_STATUS_PROCESSING,
+		}},
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":        studyTypes.EXTERNAL_SERVICE_TASK_STATUS_PROCESSING,
+			"nextAttemptAt": leaseUntil,
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var task studyTypes.ExternalServiceTask
+	err := dbService.collectionExternalServiceTasks(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&task)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// UpdateExternalServiceTaskState stores the result of an attempt
+func (dbService *StudyDBService) UpdateExternalServiceTaskState(
+	instanceID string,
+	id primitive.ObjectID,
+	status string,
+	attempts int,
+	nextAttemptAt time.Time,
+	lastError string,
+) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	set := bson.M{
+		"status":        status,
+		"attempts":      attempts,
+		"nextAttemptAt": nextAttemptAt,
+		"lastError":     lastError,
+	}
+	if status == studyTypes.EXTERNAL_SERVICE_TASK_STATUS_DONE || status == studyTypes.EXTERNAL_SERVICE_TASK_STATUS_FAILED {
+		set["completedAt"] = time.Now()
+	}
+
+	res, err := dbService.collectionExternalServiceTasks(instanceID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetExternalServiceTasks returns the tasks of the study, optionally filtered by participant and status, newest first
+func (dbService *StudyDBService) GetExternalServiceTasks(instanceID string, studyKey string, participantID string, status string, page int64, limit int64) (tasks []studyTypes.ExternalServiceTask, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if participantID != "" {
+		filter["participantID"] = participantID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	totalCount, err := dbService.collectionExternalServiceTasks(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	paginationInfo = prepPaginationInfos(totalCount, page, limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((paginationInfo.CurrentPage - 1) * paginationInfo.PageSize).
+		SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionExternalServiceTasks(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tasks = []studyTypes.ExternalServiceTask{}
+	if err = cursor.All(ctx, &tasks); err != nil {
+		return nil, nil, err
+	}
+	return tasks, paginationInfo, nil
+}
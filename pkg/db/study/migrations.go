@@ -0,0 +1,62 @@
+package study
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migrations of the study DB, append new migrations with the next version
+func migrations() []db.Migration {
+	return []db.Migration{
+		{
+			Version: 1,
+			Name:    "participants-last-submitted-at",
+			Up: func(ctx context.Context, database *mongo.Database) error {
+				collections, err := database.ListCollectionNames(ctx, bson.M{})
+				if err != nil {
+					return err
+				}
+				for _, collection := range collections {
+					if !strings.HasSuffix(collection, "_"+COLLECTION_NAME_SUFFIX_PARTICIPANTS) {
+						continue
+					}
+					_, err := database.Collection(collection).UpdateMany(ctx,
+						bson.M{"lastSubmittedAt": bson.M{"$exists": false}},
+						mongo.Pipeline{
+							{{Key: "$set", Value: bson.M{"lastSubmittedAt": bson.M{"$ifNull": bson.A{
+								bson.M{"$max": bson.M{
+									"$map": bson.M{
+										"input": bson.M{"$objectToArray": bson.M{"$ifNull": bson.A{"$lastSubmission", bson.M{}}}},
+										"in":    "$$this.v",
+									},
+								}},
+								0,
+							}}}}},
+						},
+					)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// ApplyMigrations runs the pending migrations on the DB of the instance
+func (dbService *StudyDBService) ApplyMigrations(ctx context.Context, instanceID string, opts db.MigrationOptions) db.MigrationReport {
+	return db.ApplyMigrations(ctx, dbService.DBClient.Database(dbService.getDBName(instanceID)), migrations(), opts)
+}
+
+func (dbService *StudyDBService) applyMigrations() {
+	slog.Debug("Applying migrations for study DB")
+	for _, instanceID := range dbService.InstanceIDs {
+		dbService.ApplyMigrations(context.Background(), instanceID, db.MigrationOptions{}).Log()
+	}
+}
@@ -0,0 +1,283 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const maxParticipantBulkJobResultsPageSize = 1000
+
+func (dbService *StudyDBService) collectionParticipantBulkJobs(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_PARTICIPANT_BULK_JOBS)
+}
+
+func (dbService *StudyDBService) collectionParticipantBulkJobResults(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_PARTICIPANT_BULK_JOB_RESULTS)
+}
+
+func participantBulkJobIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_PARTICIPANT_BULK_JOBS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "createdAt", Value: -1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(0),
+				},
+			},
+		},
+		{
+			Collection: COLLECTION_NAME_PARTICIPANT_BULK_JOB_RESULTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "jobID", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "jobID", Value: 1},
+						{Key: "result", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(0),
+				},
+			},
+		},
+	}
+}
+
+// CreateParticipantBulkJob stores a new queued bulk job
+func (dbService *StudyDBService) CreateParticipantBulkJob(instanceID string, job studyTypes.ParticipantBulkJob) (studyTypes.ParticipantBulkJob, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	job.ID = primitive.NilObjectID
+	job.Status = studyTypes.PARTICIPANT_BULK_JOB_STATUS_QUEUED
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+
+	res, err := dbService.collectionParticipantBulkJobs(instanceID).InsertOne(ctx, job)
+	if err != nil {
+		return job, err
+	}
+	job.ID = res.InsertedID.(primitive.ObjectID)
+	return job, nil
+}
+
+func (dbService *StudyDBService) GetParticipantBulkJob(instanceID string, studyKey string, jobID string) (job studyTypes.ParticipantBulkJob, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return job, err
+	}
+
+	err = dbService.collectionParticipantBulkJobs(instanceID).FindOne(ctx, bson.M{"_id": _id, "studyKey": studyKey}).Decode(&job)
+	return job, err
+}
+
+// GetParticipantBulkJobs returns the bulk jobs of the study, newest first
+func (dbService *StudyDBService) GetParticipantBulkJobs(instanceID string, studyKey string, page int64, limit int64) (jobs []studyTypes.ParticipantBulkJob, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	totalCount, err := dbService.collectionParticipantBulkJobs(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	paginationInfo = prepPaginationInfos(totalCount, page, limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((paginationInfo.CurrentPage - 1) * paginationInfo.PageSize).
+		SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionParticipantBulkJobs(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs = []studyTypes.ParticipantBulkJob{}
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, nil, err
+	}
+	return jobs, paginationInfo, nil
+}
+
+// StartParticipantBulkJob marks the queued job as running. Returns mongo.ErrNoDocuments if the job was cancelled before.
+func (dbService *StudyDBService) StartParticipantBulkJob(instanceID string, id primitive.ObjectID, targetCount int, leaseUntil time.Time) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": id, "status": studyTypes.PARTICIPANT_BULK_JOB_STATUS_QUEUED}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      studyTypes.PARTICIPANT_BULK_JOB_STATUS_RUNNING,
+			"targetCount": targetCount,
+			"leaseUntil":  leaseUntil,
+			"updatedAt":   time.Now(),
+		},
+	}
+	res, err := dbService.collectionParticipantBulkJobs(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// UpdateParticipantBulkJobProgress stores the counts, extends the lease of the running job and returns if its
+// cancellation was requested
+func (dbService *StudyDBService) UpdateParticipantBulkJobProgress(instanceID string, id primitive.ObjectID, processedCount int, updatedCount int, failedCount int, leaseUntil time.Time) (cancelRequested bool, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"processedCount": processedCount,
+			"updatedCount":   updatedCount,
+			"failedCount":    failedCount,
+			"leaseUntil":     leaseUntil,
+			"updatedAt":      time.Now(),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var job studyTypes.ParticipantBulkJob
+	if err := dbService.collectionParticipantBulkJobs(instanceID).FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&job); err != nil {
+		return false, err
+	}
+	return job.CancelRequested, nil
+}
+
+// FinishParticipantBulkJob stores the final status and counts of the job
+func (dbService *StudyDBService) FinishParticipantBulkJob(instanceID string, id primitive.ObjectID, status string, processedCount int, updatedCount int, failedCount int, errMsg string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":         status,
+			"processedCount": processedCount,
+			"updatedCount":   updatedCount,
+			"failedCount":    failedCount,
+			"error":          errMsg,
+			"completedAt":    now,
+			"updatedAt":      now,
+		},
+		"$unset": bson.M{"leaseUntil": ""},
+	}
+	_, err := dbService.collectionParticipantBulkJobs(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// CancelParticipantBulkJob requests the cancellation of a queued or running job, which stops at its next progress
+// update. Queued jobs and jobs that are not running anymore, because their lease expired, are marked as cancelled
+// directly. Returns mongo.ErrNoDocuments if the job is finished.
+func (dbService *StudyDBService) CancelParticipantBulkJob(instanceID string, studyKey string, id primitive.ObjectID, now time.Time) (job studyTypes.ParticipantBulkJob, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"_id":      id,
+		"studyKey": studyKey,
+		"status": bson.M{"$in": bson.A{
+			studyTypes.PARTICIPANT_BULK_JOB_STATUS_QUEUED,
+			studyTypes.PARTICIPANT_BULK_JOB_STATUS_RUNNING,
+		}},
+	}
+	update := bson.M{"$set": bson.M{"cancelRequested": true, "updatedAt": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err = dbService.collectionParticipantBulkJobs(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return job, err
+	}
+
+	if job.Status == studyTypes.PARTICIPANT_BULK_JOB_STATUS_RUNNING && job.LeaseUntil.After(now) {
+		return job, nil
+	}
+	update = bson.M{
+		"$set":   bson.M{"status": studyTypes.PARTICIPANT_BULK_JOB_STATUS_CANCELLED, "completedAt": now, "updatedAt": now},
+		"$unset": bson.M{"leaseUntil": ""},
+	}
+	err = dbService.collectionParticipantBulkJobs(instanceID).FindOneAndUpdate(ctx, bson.M{"_id": id, "status": job.Status}, update, opts).Decode(&job)
+	return job, err
+}
+
+// AddParticipantBulkJobResults stores the results of a batch of participants
+func (dbService *StudyDBService) AddParticipantBulkJobResults(instanceID string, results []studyTypes.ParticipantBulkJobResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	docs := make([]interface{}, len(results))
+	for i, r := range results {
+		docs[i] = r
+	}
+	_, err := dbService.collectionParticipantBulkJobResults(instanceID).InsertMany(ctx, docs)
+	return err
+}
+
+// GetParticipantBulkJobResults returns the results of the job in processing order after the result with ID after,
+// optionally only with one result value. The cursor of the next page is empty if there are no further results.
+func (dbService *StudyDBService) GetParticipantBulkJobResults(instanceID string, jobID primitive.ObjectID, result string, after string, limit int64) (results []studyTypes.ParticipantBulkJobResult, nextCursor string, err error) {
+	filter := bson.M{"jobID": jobID}
+	if result != "" {
+		filter["result"] = result
+	}
+	if after != "" {
+		afterID, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return nil, "", err
+		}
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+	if limit <= 0 || limit > maxParticipantBulkJobResultsPageSize {
+		limit = maxParticipantBulkJobResultsPageSize
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit + 1)
+	cursor, err := dbService.collectionParticipantBulkJobResults(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	results = []studyTypes.ParticipantBulkJobResult{}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, "", err
+	}
+	if int64(len(results)) > limit {
+		results = results[:limit]
+		nextCursor = results[len(results)-1].ID.Hex()
+	}
+	return results, nextCursor, nil
+}
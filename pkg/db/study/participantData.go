@@ -0,0 +1,149 @@
+package study
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// Methods to remove all documents of a single participant, used for data erasure requests.
+// Unlike the generic delete methods, these do not fail if the participant has no documents.
+
+func (dbService *StudyDBService) DeleteResponsesOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionResponses(instanceID, studyKey).DeleteMany(ctx, bson.M{"participantID": participantID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (dbService *StudyDBService) DeleteReportsOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionReports(instanceID, studyKey).DeleteMany(ctx, bson.M{"participantID": participantID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// GetFileInfosOfParticipant returns up to limit file infos of the participant
+func (dbService *StudyDBService) GetFileInfosOfParticipant(instanceID string, studyKey string, participantID string, limit int64) (fileInfos []studyTypes.FileInfo, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetLimit(limit)
+	cursor, err := dbService.collectionFiles(instanceID, studyKey).Find(ctx, bson.M{"participantID": participantID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &fileInfos); err != nil {
+		return nil, err
+	}
+	return fileInfos, nil
+}
+
+// DeleteScheduledEventsOfParticipant removes all scheduled events of the participant, including already processed ones
+func (dbService *StudyDBService) DeleteScheduledEventsOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "participantID": participantID}
+	res, err := dbService.collectionScheduledEvents(instanceID).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (dbService *StudyDBService) DeleteExternalServiceTasksOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "participantID": participantID}
+	res, err := dbService.collectionExternalServiceTasks(instanceID).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteRandomizationAllocationsOfParticipant removes the allocation records, block positions are kept so the balance of running blocks is unchanged
+func (dbService *StudyDBService) DeleteRandomizationAllocationsOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "participantID": participantID}
+	res, err := dbService.collectionRandomizationAllocations(instanceID).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteRuleErrorsOfParticipant removes the recorded rule evaluation errors of the participant
+func (dbService *StudyDBService) DeleteRuleErrorsOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "participantID": participantID}
+	res, err := dbService.collectionRuleErrors(instanceID).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteSurveyRemindersOfParticipant removes the records of reminders sent to the participant
+func (dbService *StudyDBService) DeleteSurveyRemindersOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "participantID": participantID}
+	res, err := dbService.collectionSurveyReminders(instanceID).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteParticipantBulkJobResultsOfParticipant removes the results of the participant from the bulk jobs of the study,
+// the jobs themselves only store the selection and are kept
+func (dbService *StudyDBService) DeleteParticipantBulkJobResultsOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	jobIDs, err := dbService.collectionParticipantBulkJobs(instanceID).Distinct(ctx, "_id", bson.M{"studyKey": studyKey})
+	if err != nil {
+		return 0, err
+	}
+	if len(jobIDs) == 0 {
+		return 0, nil
+	}
+
+	filter := bson.M{"jobID": bson.M{"$in": jobIDs}, "participantID": participantID}
+	res, err := dbService.collectionParticipantBulkJobResults(instanceID).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteResearcherMessagesOfParticipant removes the messages to researchers that refer to the participant
+func (dbService *StudyDBService) DeleteResearcherMessagesOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionResearcherMessages(instanceID, studyKey).DeleteMany(ctx, bson.M{"participantID": participantID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -1,6 +1,8 @@
 package study
 
 import (
+	"errors"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -51,6 +53,23 @@ func (dbService *StudyDBService) DeleteParticipantFileInfoByID(instanceID string
 	return err
 }
 
+func (dbService *StudyDBService) UpdateParticipantIDonFileInfos(instanceID string, studyKey string, oldID string, newID string) (count int64, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if oldID == "" || newID == "" {
+		return 0, errors.New("participant id must be defined")
+	}
+	filter := bson.M{"participantID": oldID}
+	update := bson.M{"$set": bson.M{"participantID": newID}}
+
+	res, err := dbService.collectionFiles(instanceID, studyKey).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, err
+}
+
 // count by query
 func (dbService *StudyDBService) CountParticipantFileInfos(instanceID string, studyKey string, query bson.M) (int64, error) {
 	ctx, cancel := dbService.getContext()
@@ -0,0 +1,121 @@
+package study
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// flag types are read for every saved participant state, so they are cached shortly.
+// Other processes pick up changes after the cache period.
+const flagTypesCacheTTL = time.Minute
+
+type flagTypesCacheEntry struct {
+	flagTypes map[string]string
+	loadedAt  time.Time
+}
+
+type flagTypesCache struct {
+	mu      sync.Mutex
+	entries map[string]flagTypesCacheEntry
+}
+
+func (c *flagTypesCache) get(key string) (map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.loadedAt) > flagTypesCacheTTL {
+		return nil, false
+	}
+	return entry.flagTypes, true
+}
+
+func (c *flagTypesCache) set(key string, flagTypes map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]flagTypesCacheEntry{}
+	}
+	c.entries[key] = flagTypesCacheEntry{flagTypes: flagTypes, loadedAt: time.Now()}
+}
+
+// GetParticipantFlagTypes returns the declared participant flag types of the study
+func (dbService *StudyDBService) GetParticipantFlagTypes(instanceID string, studyKey string) (map[string]string, error) {
+	cacheKey := instanceID + "/" + studyKey
+	if flagTypes, ok := dbService.flagTypes.get(cacheKey); ok {
+		return flagTypes, nil
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var study studyTypes.Study
+	err := dbService.collectionStudyInfos(instanceID).FindOne(
+		ctx,
+		bson.M{"key": studyKey},
+		options.FindOne().SetProjection(bson.M{"configs.participantFlagTypes": 1}),
+	).Decode(&study)
+	if err != nil {
+		return nil, err
+	}
+
+	dbService.flagTypes.set(cacheKey, study.Configs.ParticipantFlagTypes)
+	return study.Configs.ParticipantFlagTypes, nil
+}
+
+func (dbService *StudyDBService) UpdateStudyParticipantFlagTypes(instanceID string, studyKey string, flagTypes map[string]string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.participantFlagTypes": flagTypes}}
+	if len(flagTypes) == 0 {
+		update = bson.M{"$unset": bson.M{"configs.participantFlagTypes": ""}}
+	}
+
+	res, err := dbService.collectionStudyInfos(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	dbService.flagTypes.set(instanceID+"/"+studyKey, flagTypes)
+	return nil
+}
+
+// RebuildParticipantTypedFlags recomputes the typed flags of all participants of the study, e.g., after the flag types changed
+func (dbService *StudyDBService) RebuildParticipantTypedFlags(ctx context.Context, instanceID string, studyKey string, flagTypes map[string]string) (count int64, err error) {
+	collection := dbService.collectionParticipants(instanceID, studyKey)
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"flags": 1, "typedFlags": 1}).SetNoCursorTimeout(dbService.noCursorTimeout))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var p studyTypes.Participant
+		if err := cursor.Decode(&p); err != nil {
+			slog.Error("Error decoding participant", slog.String("error", err.Error()))
+			continue
+		}
+
+		update := bson.M{"$unset": bson.M{"typedFlags": ""}}
+		if typedFlags := studyTypes.ComputeTypedFlags(p.Flags, flagTypes); typedFlags != nil {
+			update = bson.M{"$set": bson.M{"typedFlags": typedFlags}}
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": p.ID}, update); err != nil {
+			return count, err
+		}
+		count += 1
+	}
+	return count, cursor.Err()
+}
@@ -0,0 +1,217 @@
+package study
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	PARTICIPANT_SEARCH_SORT_BY_ENTERED_AT        = "enteredAt"
+	PARTICIPANT_SEARCH_SORT_BY_LAST_SUBMITTED_AT = "lastSubmittedAt"
+
+	maxParticipantSearchPageSize     = 500
+	defaultParticipantSearchPageSize = 50
+)
+
+// ErrInvalidParticipantSearchQuery is returned for invalid sort fields, ranges, survey keys or cursors
+var ErrInvalidParticipantSearchQuery = errors.New("invalid participant search query")
+
+// ParticipantSearchQuery combines filters on the participant states, all set filters must match
+type ParticipantSearchQuery struct {
+	// selected participants, any participant if empty
+	ParticipantIDs []string
+	// study statuses, any status if empty
+	Statuses []string
+	// filter on the participant flags, e.g. from studyutils.BuildFlagQueryFilter
+	FlagFilter bson.M
+	// ranges of unix timestamps, inclusive, unbounded if 0
+	EnteredFrom int64
+	EnteredTo   int64
+	// last submission of any survey, or of SurveyKey if set
+	LastSubmissionFrom int64
+	LastSubmissionTo   int64
+	SurveyKey          string
+	// "enteredAt" (default) or "lastSubmittedAt"
+	SortBy   string
+	SortDesc bool
+	// cursor returned by the previous page, empty for the first page
+	Cursor string
+	Limit  int64
+}
+
+// SearchParticipants returns one page of the participants matching the query and the cursor of the next page,
+// which is empty if there are no further participants
+func (dbService *StudyDBService) SearchParticipants(instanceID string, studyKey string, query ParticipantSearchQuery) (participants []studyTypes.Participant, nextCursor string, err error) {
+	filter, findOpts, err := prepParticipantSearchQuery(query)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrInvalidParticipantSearchQuery, err.Error())
+	}
+	limit := *findOpts.Limit
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	// one more than requested to know if there is a next page
+	findOpts.SetLimit(limit + 1)
+	cursor, err := dbService.collectionParticipants(instanceID, studyKey).Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	participants = []studyTypes.Participant{}
+	if err = cursor.All(ctx, &participants); err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(participants)) > limit {
+		participants = participants[:limit]
+		last := participants[len(participants)-1]
+		nextCursor = encodeConfidentialResponsesCursor(participantSortValue(last, participantSortField(query.SortBy)), last.ID)
+	}
+	return participants, nextCursor, nil
+}
+
+// CountParticipantsForSearch counts all participants matching the query, the cursor and limit are ignored
+func (dbService *StudyDBService) CountParticipantsForSearch(instanceID string, studyKey string, query ParticipantSearchQuery) (int64, error) {
+	filter, err := ParticipantSearchFilter(query)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	return dbService.collectionParticipants(instanceID, studyKey).CountDocuments(ctx, filter)
+}
+
+// ParticipantSearchFilter returns the filter of the query without cursor, e.g. to process all matching participants
+func ParticipantSearchFilter(query ParticipantSearchQuery) (bson.M, error) {
+	query.Cursor = ""
+	filter, _, err := prepParticipantSearchQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidParticipantSearchQuery, err.Error())
+	}
+	return filter, nil
+}
+
+func participantSortField(sortBy string) string {
+	if sortBy == "" {
+		return PARTICIPANT_SEARCH_SORT_BY_ENTERED_AT
+	}
+	return sortBy
+}
+
+func participantSortValue(participant studyTypes.Participant, field string) int64 {
+	if field == PARTICIPANT_SEARCH_SORT_BY_LAST_SUBMITTED_AT {
+		return participant.LastSubmittedAt
+	}
+	return participant.EnteredAt
+}
+
+func timeRangeCondition(from int64, to int64) (bson.M, error) {
+	if from > 0 && to > 0 && from > to {
+		return nil, errors.New("range start is after its end")
+	}
+	condition := bson.M{}
+	if from > 0 {
+		condition["$gte"] = from
+	}
+	if to > 0 {
+		condition["$lte"] = to
+	}
+	if len(condition) == 0 {
+		return nil, nil
+	}
+	return condition, nil
+}
+
+func prepParticipantSearchQuery(query ParticipantSearchQuery) (bson.M, *options.FindOptions, error) {
+	field := participantSortField(query.SortBy)
+	if field != PARTICIPANT_SEARCH_SORT_BY_ENTERED_AT && field != PARTICIPANT_SEARCH_SORT_BY_LAST_SUBMITTED_AT {
+		return nil, nil, fmt.Errorf("invalid sort field: %s", query.SortBy)
+	}
+
+	conditions := bson.A{}
+	if len(query.FlagFilter) > 0 {
+		conditions = append(conditions, query.FlagFilter)
+	}
+
+	filter := bson.M{}
+	if len(query.ParticipantIDs) > 0 {
+		filter["participantID"] = bson.M{"$in": query.ParticipantIDs}
+	}
+	if len(query.Statuses) == 1 {
+		filter["studyStatus"] = query.Statuses[0]
+	} else if len(query.Statuses) > 1 {
+		filter["studyStatus"] = bson.M{"$in": query.Statuses}
+	}
+
+	entered, err := timeRangeCondition(query.EnteredFrom, query.EnteredTo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("enteredAt: %s", err.Error())
+	}
+	if entered != nil {
+		filter["enteredAt"] = entered
+	}
+
+	lastSubmission, err := timeRangeCondition(query.LastSubmissionFrom, query.LastSubmissionTo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("last submission: %s", err.Error())
+	}
+	if query.SurveyKey != "" {
+		if strings.ContainsAny(query.SurveyKey, ".$") {
+			return nil, nil, fmt.Errorf("invalid survey key: %s", query.SurveyKey)
+		}
+		if lastSubmission == nil {
+			// any submission of the survey
+			lastSubmission = bson.M{"$gt": 0}
+		}
+		filter["lastSubmission."+query.SurveyKey] = lastSubmission
+	} else if lastSubmission != nil {
+		filter["lastSubmittedAt"] = lastSubmission
+	}
+
+	sortOrder := 1
+	cmp := "$gt"
+	if query.SortDesc {
+		sortOrder = -1
+		cmp = "$lt"
+	}
+
+	if query.Cursor != "" {
+		value, id, err := decodeConfidentialResponsesCursor(query.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		// continue after the last returned participant, the ID breaks ties between equal timestamps
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{cmp: value}},
+			bson.M{field: value, "_id": bson.M{cmp: id}},
+		}})
+	}
+
+	if len(conditions) > 0 {
+		// kept apart from the other filters, the flag filter and the cursor can both contain $or
+		filter["$and"] = conditions
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultParticipantSearchPageSize
+	}
+	if limit > maxParticipantSearchPageSize {
+		limit = maxParticipantSearchPageSize
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: sortOrder}, {Key: "_id", Value: sortOrder}}).
+		SetLimit(limit)
+	return filter, opts, nil
+}
@@ -0,0 +1,131 @@
+package study
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPrepParticipantSearchQuery(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		filter, opts, err := prepParticipantSearchQuery(ParticipantSearchQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filter) != 0 {
+			t.Errorf("unexpected filter: %v", filter)
+		}
+		if *opts.Limit != defaultParticipantSearchPageSize {
+			t.Errorf("unexpected limit: %d", *opts.Limit)
+		}
+		sort := opts.Sort.(bson.D)
+		if sort[0].Key != "enteredAt" || sort[0].Value != 1 || sort[1].Key != "_id" {
+			t.Errorf("unexpected sort: %v", sort)
+		}
+	})
+
+	t.Run("compound filters", func(t *testing.T) {
+		filter, opts, err := prepParticipantSearchQuery(ParticipantSearchQuery{
+			Statuses:           []string{"active", "paused"},
+			FlagFilter:         bson.M{"flags.group": "a"},
+			EnteredFrom:        100,
+			LastSubmissionFrom: 200,
+			LastSubmissionTo:   300,
+			SortBy:             PARTICIPANT_SEARCH_SORT_BY_LAST_SUBMITTED_AT,
+			SortDesc:           true,
+			Limit:              10000,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := filter["studyStatus"].(bson.M); len(status["$in"].([]string)) != 2 {
+			t.Errorf("unexpected status filter: %v", status)
+		}
+		if entered := filter["enteredAt"].(bson.M); entered["$gte"] != int64(100) || entered["$lte"] != nil {
+			t.Errorf("unexpected entered filter: %v", entered)
+		}
+		if last := filter["lastSubmittedAt"].(bson.M); last["$gte"] != int64(200) || last["$lte"] != int64(300) {
+			t.Errorf("unexpected last submission filter: %v", last)
+		}
+		if and := filter["$and"].(bson.A); len(and) != 1 {
+			t.Errorf("unexpected conditions: %v", and)
+		}
+		if *opts.Limit != maxParticipantSearchPageSize {
+			t.Errorf("unexpected limit: %d", *opts.Limit)
+		}
+		sort := opts.Sort.(bson.D)
+		if sort[0].Key != "lastSubmittedAt" || sort[0].Value != -1 || sort[1].Value != -1 {
+			t.Errorf("unexpected sort: %v", sort)
+		}
+	})
+
+	t.Run("single status and survey key", func(t *testing.T) {
+		filter, _, err := prepParticipantSearchQuery(ParticipantSearchQuery{Statuses: []string{"active"}, SurveyKey: "weekly"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter["studyStatus"] != "active" {
+			t.Errorf("unexpected status filter: %v", filter["studyStatus"])
+		}
+		if last := filter["lastSubmission.weekly"].(bson.M); last["$gt"] != 0 {
+			t.Errorf("unexpected survey filter: %v", last)
+		}
+		if _, ok := filter["lastSubmittedAt"]; ok {
+			t.Error("unexpected last submission filter")
+		}
+	})
+
+	t.Run("filter without cursor", func(t *testing.T) {
+		filter, err := ParticipantSearchFilter(ParticipantSearchQuery{
+			ParticipantIDs: []string{"p1", "p2"},
+			Cursor:         encodeConfidentialResponsesCursor(1, primitive.NewObjectID()),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := filter["participantID"].(bson.M)["$in"].([]string); len(ids) != 2 {
+			t.Errorf("unexpected participant filter: %v", ids)
+		}
+		if _, ok := filter["$and"]; ok {
+			t.Errorf("unexpected cursor condition: %v", filter)
+		}
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		id := primitive.NewObjectID()
+		filter, _, err := prepParticipantSearchQuery(ParticipantSearchQuery{
+			Cursor:   encodeConfidentialResponsesCursor(1700000000, id),
+			SortDesc: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		or := filter["$and"].(bson.A)[0].(bson.M)["$or"].(bson.A)
+		if or[0].(bson.M)["enteredAt"].(bson.M)["$lt"] != int64(1700000000) || or[1].(bson.M)["_id"].(bson.M)["$lt"] != id {
+			t.Errorf("unexpected cursor filter: %v", or)
+		}
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		for name, query := range map[string]ParticipantSearchQuery{
+			"sort field": {SortBy: "participantID"},
+			"range":      {EnteredFrom: 200, EnteredTo: 100},
+			"survey key": {SurveyKey: "a.b"},
+			"cursor":     {Cursor: "%%%"},
+		} {
+			if _, _, err := prepParticipantSearchQuery(query); err == nil {
+				t.Errorf("expected error for %s", name)
+			}
+		}
+	})
+}
+
+func TestLatestSubmission(t *testing.T) {
+	if latest := latestSubmission(map[string]int64{"a": 10, "b": 30, "c": 20}); latest != 30 {
+		t.Errorf("unexpected latest submission: %d", latest)
+	}
+	if latest := latestSubmission(nil); latest != 0 {
+		t.Errorf("unexpected latest submission: %d", latest)
+	}
+}
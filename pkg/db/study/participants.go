@@ -8,51 +8,95 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/net/context"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
-func (dbService *StudyDBService) CreateIndexForParticipantsCollection(instanceID string, studyKey string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	collection := dbService.collectionParticipants(instanceID, studyKey)
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{
-				{Key: "participantID", Value: 1},
-			},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys: bson.D{
-				{Key: "studyStatus", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "enteredAt", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "messages.scheduledFor", Value: 1},
-				{Key: "studyStatus", Value: 1},
-			},
-		},
+func participantIndexes(studyKey string) []db.CollectionIndexes {
+	return []db.CollectionIndexes{
 		{
-			Keys: bson.D{
-				{Key: "messages.scheduledFor", Value: 1},
+			Collection: studyKey + "_" + COLLECTION_NAME_SUFFIX_PARTICIPANTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+				{
+					Keys: bson.D{
+						{Key: "studyStatus", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "enteredAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "messages.scheduledFor", Value: 1},
+						{Key: "studyStatus", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "messages.scheduledFor", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "flags.$**", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "typedFlags.$**", Value: 1},
+					},
+				},
+				// participant search: status filter combined with the sort fields, the ID keeps cursor pages stable
+				{
+					Keys: bson.D{
+						{Key: "studyStatus", Value: 1},
+						{Key: "enteredAt", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "studyStatus", Value: 1},
+						{Key: "lastSubmittedAt", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "enteredAt", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "lastSubmittedAt", Value: 1},
+						{Key: "_id", Value: 1},
+					},
+				},
 			},
 		},
 	}
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
 }
 
 func (dbService *StudyDBService) SaveParticipantState(instanceID string, studyKey string, pState studyTypes.Participant) (studyTypes.Participant, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
+	flagTypes, err := dbService.GetParticipantFlagTypes(instanceID, studyKey)
+	if err != nil {
+		slog.Warn("could not get participant flag types", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+	pState.TypedFlags = studyTypes.ComputeTypedFlags(pState.Flags, flagTypes)
+	pState.LastSubmittedAt = latestSubmission(pState.LastSubmissions)
+
 	filter := bson.M{"participantID": pState.ParticipantID}
 
 	upsert := true
@@ -62,12 +106,23 @@ func (dbService *StudyDBService) SaveParticipantState(instanceID string, studyKe
 		ReturnDocument: &rd,
 	}
 	elem := studyTypes.Participant{}
-	err := dbService.collectionParticipants(instanceID, studyKey).FindOneAndReplace(
+	err = dbService.collectionParticipants(instanceID, studyKey).FindOneAndReplace(
 		ctx, filter, pState, &options,
 	).Decode(&elem)
 	return elem, err
 }
 
+// latestSubmission is the most recent of the last submissions per survey, 0 if there are none
+func latestSubmission(lastSubmissions map[string]int64) int64 {
+	var latest int64
+	for _, ts := range lastSubmissions {
+		if ts > latest {
+			latest = ts
+		}
+	}
+	return latest
+}
+
 // get participant by id
 func (dbService *StudyDBService) GetParticipantByID(instanceID string, studyKey string, participantID string) (participant studyTypes.Participant, err error) {
 	ctx, cancel := dbService.getContext()
@@ -193,3 +248,37 @@ func (dbService *StudyDBService) DeleteMessagesFromParticipant(instanceID string
 	_, err := dbService.collectionParticipants(instanceID, studyKey).UpdateOne(ctx, filter, update)
 	return err
 }
+
+// mark participant as created by synthetic monitoring
+func (dbService *StudyDBService) MarkParticipantAsSynthetic(instanceID string, studyKey string, participantID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID}
+	update := bson.M{"$set": bson.M{"synthetic": true}}
+	res, err := dbService.collectionParticipants(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// WatchParticipantStates opens a change stream on the participant states with the given IDs. Requires a replica set.
+func (dbService *StudyDBService) WatchParticipantStates(ctx context.Context, instanceID string, studyKey string, participantIDs []string) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType":              bson.M{"$in": bson.A{"insert", "update", "replace"}},
+			"fullDocument.participantID": bson.M{"$in": participantIDs},
+		}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	return dbService.collectionParticipants(instanceID, studyKey).Watch(ctx, pipeline, opts)
+}
+
+// NewParticipantBulkWriter batches writes to the participant states of a study, see db.BulkWriter
+func (dbService *StudyDBService) NewParticipantBulkWriter(instanceID string, studyKey string, batchSize int) *db.BulkWriter {
+	return db.NewBulkWriter(dbService.collectionParticipants(instanceID, studyKey), dbService.getContext, batchSize)
+}
@@ -0,0 +1,82 @@
+package study
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// HasResponseWithContentHash checks if another participant already submitted a response with the same content
+func (dbService *StudyDBService) HasResponseWithContentHash(instanceID string, studyKey string, surveyKey string, contentHash string, participantID string) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"key":           surveyKey,
+		"contentHash":   contentHash,
+		"participantID": bson.M{"$ne": participantID},
+	}
+	err := dbService.collectionResponses(instanceID, studyKey).FindOne(ctx, filter).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetQuarantinedResponses returns the quarantined responses of the study, newest first
+func (dbService *StudyDBService) GetQuarantinedResponses(instanceID string, studyKey string, surveyKey string, page int64, limit int64) (responses []studyTypes.SurveyResponse, paginationInfo *PaginationInfos, err error) {
+	filter := bson.M{"quarantine": bson.M{"$exists": true}}
+	if surveyKey != "" {
+		filter["key"] = surveyKey
+	}
+	responses, paginationInfo, err = dbService.GetResponses(instanceID, studyKey, filter, bson.M{"arrivedAt": -1}, page, limit)
+	if responses == nil {
+		responses = []studyTypes.SurveyResponse{}
+	}
+	return responses, paginationInfo, err
+}
+
+// AcceptQuarantinedResponse releases the response from the quarantine, so that it is exported again
+func (dbService *StudyDBService) AcceptQuarantinedResponse(instanceID string, studyKey string, responseID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(responseID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": _id, "quarantine": bson.M{"$exists": true}}
+	update := bson.M{"$unset": bson.M{"quarantine": ""}}
+	res, err := dbService.collectionResponses(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// PurgeQuarantinedResponse deletes the response, if it is quarantined
+func (dbService *StudyDBService) PurgeQuarantinedResponse(instanceID string, studyKey string, responseID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(responseID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": _id, "quarantine": bson.M{"$exists": true}}
+	res, err := dbService.collectionResponses(instanceID, studyKey).DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
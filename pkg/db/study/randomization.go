@@ -0,0 +1,235 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	maxRandomizationBlockRetries = 10
+)
+
+func (dbService *StudyDBService) collectionRandomizationBlocks(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_RANDOMIZATION_BLOCKS)
+}
+
+func (dbService *StudyDBService) collectionRandomizationAllocations(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_RANDOMIZATION_ALLOCATIONS)
+}
+
+func randomizationIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_RANDOMIZATION_BLOCKS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "schemeKey", Value: 1},
+						{Key: "stratum", Value: 1},
+						{Key: "blockNumber", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+		{
+			Collection: COLLECTION_NAME_RANDOMIZATION_ALLOCATIONS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "schemeKey", Value: 1},
+						{Key: "participantID", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+	}
+}
+
+// AssignRandomizationArm allocates the next free slot of the current block of the stratum to the participant.
+// If the participant was already allocated within the scheme, the existing arm is returned.
+// newBlock is called to generate the arm sequence when no block with free slots is left.
+func (dbService *StudyDBService) AssignRandomizationArm(
+	instanceID string,
+	studyKey string,
+	schemeKey string,
+	stratum string,
+	participantID string,
+	newBlock func() []string,
+) (string, error) {
+	existing, err := dbService.GetRandomizationAllocation(instanceID, studyKey, schemeKey, participantID)
+	if err == nil {
+		return existing.Arm, nil
+	} else if err != mongo.ErrNoDocuments {
+		return "", err
+	}
+
+	arm, blockNumber, err := dbService.claimRandomizationSlot(instanceID, studyKey, schemeKey, stratum, newBlock)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	allocation := studyTypes.RandomizationAllocation{
+		StudyKey:      studyKey,
+		SchemeKey:     schemeKey,
+		Stratum:       stratum,
+		ParticipantID: participantID,
+		Arm:           arm,
+		BlockNumber:   blockNumber,
+		AllocatedAt:   time.Now(),
+	}
+	_, err = dbService.collectionRandomizationAllocations(instanceID).InsertOne(ctx, allocation)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// allocated concurrently, the claimed slot stays unused
+			existing, err := dbService.GetRandomizationAllocation(instanceID, studyKey, schemeKey, participantID)
+			if err != nil {
+				return "", err
+			}
+			return existing.Arm, nil
+		}
+		return "", err
+	}
+	return arm, nil
+}
+
+func (dbService *StudyDBService) claimRandomizationSlot(
+	instanceID string,
+	studyKey string,
+	schemeKey string,
+	stratum string,
+	newBlock func() []string,
+) (string, int, error) {
+	for i := 0; i < maxRandomizationBlockRetries; i++ {
+		block, err := dbService.incrementRandomizationBlockPosition(instanceID, studyKey, schemeKey, stratum)
+		if err == nil {
+			return block.Arms[block.Position], block.BlockNumber, nil
+		} else if err != mongo.ErrNoDocuments {
+			return "", 0, err
+		}
+
+		// no free slot left: start next block, unless another request created it already
+		lastBlockNumber, err := dbService.getLastRandomizationBlockNumber(instanceID, studyKey, schemeKey, stratum)
+		if err != nil {
+			return "", 0, err
+		}
+		err = dbService.addRandomizationBlock(instanceID, studyTypes.RandomizationBlock{
+			StudyKey:    studyKey,
+			SchemeKey:   schemeKey,
+			Stratum:     stratum,
+			BlockNumber: lastBlockNumber + 1,
+			Arms:        newBlock(),
+			CreatedAt:   time.Now(),
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return "", 0, err
+		}
+	}
+	return "", 0, mongo.ErrNoDocuments
+}
+
+// incrementRandomizationBlockPosition takes a slot from a block with free slots and returns the block as before the update
+func (dbService *StudyDBService) incrementRandomizationBlockPosition(instanceID string, studyKey string, schemeKey string, stratum string) (block studyTypes.RandomizationBlock, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":  studyKey,
+		"schemeKey": schemeKey,
+		"stratum":   stratum,
+		"$expr":     bson.M{"$lt": bson.A{"$position", bson.M{"$size": "$arms"}}},
+	}
+	update := bson.M{"$inc": bson.M{"position": 1}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "blockNumber", Value: 1}}).
+		SetReturnDocument(options.Before)
+
+	err = dbService.collectionRandomizationBlocks(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&block)
+	return block, err
+}
+
+func (dbService *StudyDBService) getLastRandomizationBlockNumber(instanceID string, studyKey string, schemeKey string, stratum string) (int, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":  studyKey,
+		"schemeKey": schemeKey,
+		"stratum":   stratum,
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "blockNumber", Value: -1}})
+
+	var block studyTypes.RandomizationBlock
+	err := dbService.collectionRandomizationBlocks(instanceID).FindOne(ctx, filter, opts).Decode(&block)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return block.BlockNumber, err
+}
+
+func (dbService *StudyDBService) addRandomizationBlock(instanceID string, block studyTypes.RandomizationBlock) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionRandomizationBlocks(instanceID).InsertOne(ctx, block)
+	return err
+}
+
+func (dbService *StudyDBService) GetRandomizationAllocation(instanceID string, studyKey string, schemeKey string, participantID string) (allocation studyTypes.RandomizationAllocation, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":      studyKey,
+		"schemeKey":     schemeKey,
+		"participantID": participantID,
+	}
+	err = dbService.collectionRandomizationAllocations(instanceID).FindOne(ctx, filter).Decode(&allocation)
+	return allocation, err
+}
+
+// GetRandomizationAllocations returns the allocations of the study, optionally filtered by scheme, in allocation order
+func (dbService *StudyDBService) GetRandomizationAllocations(instanceID string, studyKey string, schemeKey string, page int64, limit int64) (allocations []studyTypes.RandomizationAllocation, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if schemeKey != "" {
+		filter["schemeKey"] = schemeKey
+	}
+
+	totalCount, err := dbService.collectionRandomizationAllocations(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	paginationInfo = prepPaginationInfos(totalCount, page, limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "allocatedAt", Value: 1}}).
+		SetSkip((paginationInfo.CurrentPage - 1) * paginationInfo.PageSize).
+		SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionRandomizationAllocations(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	allocations = []studyTypes.RandomizationAllocation{}
+	if err = cursor.All(ctx, &allocations); err != nil {
+		return nil, nil, err
+	}
+	return allocations, paginationInfo, nil
+}
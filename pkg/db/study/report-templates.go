@@ -0,0 +1,109 @@
+package study
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) collectionReportTemplates(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_REPORT_TEMPLATES)
+}
+
+func reportTemplateIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_REPORT_TEMPLATES,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "reportKey", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+			},
+		},
+	}
+}
+
+// SaveReportTemplate creates or replaces the template of the report key
+func (dbService *StudyDBService) SaveReportTemplate(instanceID string, template studyTypes.ReportTemplate) (studyTypes.ReportTemplate, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":  template.StudyKey,
+		"reportKey": template.ReportKey,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"defaultLanguage": template.DefaultLanguage,
+			"translations":    template.Translations,
+			"updatedAt":       template.UpdatedAt,
+			"updatedBy":       template.UpdatedBy,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved studyTypes.ReportTemplate
+	err := dbService.collectionReportTemplates(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved)
+	return saved, err
+}
+
+func (dbService *StudyDBService) GetReportTemplates(instanceID string, studyKey string) ([]studyTypes.ReportTemplate, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "reportKey", Value: 1}})
+	cursor, err := dbService.collectionReportTemplates(instanceID).Find(ctx, bson.M{"studyKey": studyKey}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []studyTypes.ReportTemplate{}
+	err = cursor.All(ctx, &templates)
+	return templates, err
+}
+
+func (dbService *StudyDBService) GetReportTemplate(instanceID string, studyKey string, reportKey string) (template studyTypes.ReportTemplate, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":  studyKey,
+		"reportKey": reportKey,
+	}
+	err = dbService.collectionReportTemplates(instanceID).FindOne(ctx, filter).Decode(&template)
+	return template, err
+}
+
+func (dbService *StudyDBService) DeleteReportTemplate(instanceID string, studyKey string, reportKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":  studyKey,
+		"reportKey": reportKey,
+	}
+	res, err := dbService.collectionReportTemplates(instanceID).DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) deleteReportTemplatesOfStudy(instanceID string, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionReportTemplates(instanceID).DeleteMany(ctx, bson.M{"studyKey": studyKey})
+	return err
+}
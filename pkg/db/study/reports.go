@@ -10,35 +10,35 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
-func (dbService *StudyDBService) CreateIndexForReportsCollection(instanceID string, studyKey string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	collection := dbService.collectionReports(instanceID, studyKey)
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{
-				{Key: "participantID", Value: 1},
-			},
-		},
+func reportIndexes(studyKey string) []db.CollectionIndexes {
+	return []db.CollectionIndexes{
 		{
-			Keys: bson.D{
-				{Key: "timestamp", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "participantID", Value: 1},
-				{Key: "key", Value: 1},
-				{Key: "timestamp", Value: 1},
+			Collection: studyKey + "_" + COLLECTION_NAME_SUFFIX_REPORTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "timestamp", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+						{Key: "key", Value: 1},
+						{Key: "timestamp", Value: 1},
+					},
+				},
 			},
 		},
 	}
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
 }
 
 func (dbService *StudyDBService) SaveReport(instanceID string, studyKey string, report studyTypes.Report) error {
@@ -11,45 +11,58 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
-func (dbService *StudyDBService) CreateIndexForResponsesCollection(instanceID string, studyKey string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	collection := dbService.collectionResponses(instanceID, studyKey)
-	indexes := []mongo.IndexModel{
+func responseIndexes(studyKey string) []db.CollectionIndexes {
+	return []db.CollectionIndexes{
 		{
-			Keys: bson.D{
-				{Key: "participantID", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "participantID", Value: 1},
-				{Key: "key", Value: 1},
-				{Key: "submittedAt", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "submittedAt", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "arrivedAt", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "key", Value: 1},
+			Collection: studyKey + "_" + COLLECTION_NAME_SUFFIX_RESPONSES,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "participantID", Value: 1},
+						{Key: "key", Value: 1},
+						{Key: "submittedAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "submittedAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "arrivedAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "key", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "key", Value: 1},
+						{Key: "contentHash", Value: 1},
+					},
+					Options: options.Index().SetPartialFilterExpression(bson.M{"contentHash": bson.M{"$exists": true}}),
+				},
+				{
+					Keys: bson.D{
+						{Key: "arrivedAt", Value: -1},
+					},
+					Options: options.Index().SetName("quarantine_arrivedAt").SetPartialFilterExpression(bson.M{"quarantine": bson.M{"$exists": true}}),
+				},
 			},
 		},
 	}
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
 }
 
 func (dbService *StudyDBService) AddSurveyResponse(instanceID string, studyKey string, response studyTypes.SurveyResponse) (string, error) {
@@ -210,6 +223,36 @@ func (dbService *StudyDBService) FindAndExecuteOnResponses(
 	return nil
 }
 
+// StreamResponses iterates over the matching responses with a cursor, fetching them in batches of exportCursorBatchSize,
+// and stops at the first error returned by fn
+func (dbService *StudyDBService) StreamResponses(
+	ctx context.Context,
+	instanceID string, studyKey string,
+	filter bson.M,
+	sort bson.M,
+	fn func(r studyTypes.SurveyResponse) error,
+) error {
+	opts := options.Find().SetSort(sort).SetBatchSize(exportCursorBatchSize)
+
+	cursor, err := dbService.collectionResponses(instanceID, studyKey).Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var response studyTypes.SurveyResponse
+		if err = cursor.Decode(&response); err != nil {
+			slog.Error("Error while decoding response", slog.String("error", err.Error()))
+			continue
+		}
+		if err = fn(response); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
 // delete response by id
 func (dbService *StudyDBService) DeleteResponseByID(instanceID string, studyKey string, responseID string) error {
 	ctx, cancel := dbService.getContext()
@@ -249,6 +292,32 @@ func (dbService *StudyDBService) UpdateParticipantIDonResponses(instanceID strin
 	return res.ModifiedCount, err
 }
 
+// MarkResponseAsSuperseded links the response to its correction, if it was not superseded already
+func (dbService *StudyDBService) MarkResponseAsSuperseded(instanceID string, studyKey string, responseID string, supersededBy string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(responseID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":          _id,
+		"supersededBy": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"supersededBy": supersededBy}}
+
+	res, err := dbService.collectionResponses(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 // delete responses by query
 func (dbService *StudyDBService) DeleteResponses(instanceID string, studyKey string, filter bson.M) error {
 	ctx, cancel := dbService.getContext()
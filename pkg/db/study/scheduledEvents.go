@@ -0,0 +1,222 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	REMOVE_PROCESSED_SCHEDULED_EVENTS_AFTER = 60 * 60 * 24 * 30 // 30 days
+)
+
+func (dbService *StudyDBService) collectionScheduledEvents(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_SCHEDULED_EVENTS)
+}
+
+func scheduledEventIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_SCHEDULED_EVENTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "status", Value: 1},
+						{Key: "fireAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "participantID", Value: 1},
+						{Key: "eventKey", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{{Key: "claimID", Value: 1}},
+				},
+				{
+					// only fired or failed events have processedAt and are removed
+					Keys:    bson.D{{Key: "processedAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(REMOVE_PROCESSED_SCHEDULED_EVENTS_AFTER),
+				},
+			},
+		},
+	}
+}
+
+func (dbService *StudyDBService) AddScheduledEvent(instanceID string, event studyTypes.ScheduledEvent) (string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	event.ID = primitive.NilObjectID
+	event.Status = studyTypes.SCHEDULED_EVENT_STATUS_PENDING
+	event.CreatedAt = time.Now()
+
+	res, err := dbService.collectionScheduledEvents(instanceID).InsertOne(ctx, event)
+	if err != nil {
+		return "", err
+	}
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// CancelScheduledEvents removes the pending events of the participant, with the event key if not empty
+func (dbService *StudyDBService) CancelScheduledEvents(instanceID string, studyKey string, participantID string, eventKey string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":      studyKey,
+		"participantID": participantID,
+		"status":        studyTypes.SCHEDULED_EVENT_STATUS_PENDING,
+	}
+	if eventKey != "" {
+		filter["eventKey"] = eventKey
+	}
+
+	res, err := dbService.collectionScheduledEvents(instanceID).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// ClaimDueScheduledEvents marks up to batchSize due events with the claimID until leaseUntil and returns them.
+// Events of crashed runners are claimed again after their lease expired.
+func (dbService *StudyDBService) ClaimDueScheduledEvents(instanceID string, claimID string, now time.Time, leaseUntil time.Time, batchSize int64) ([]studyTypes.ScheduledEvent, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	claimable := bson.M{
+		"fireAt": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"status": studyTypes.SCHEDULED_EVENT_STATUS_PENDING},
+			bson.M{
+				"status":     studyTypes.SCHEDULED_EVENT_STATUS_PROCESSING,
+				"leaseUntil": bson.M{"$lt": now},
+			},
+		},
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "fireAt", Value: 1}}).
+		SetLimit(batchSize).
+		SetProjection(bson.M{"_id": 1})
+	cursor, err := dbService.collectionScheduledEvents(instanceID).Find(ctx, claimable, opts)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return []studyTypes.ScheduledEvent{}, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+
+	// re-check the claimable condition, so events claimed concurrently are not taken twice
+	filter := bson.M{
+		"_id":  bson.M{"$in": ids},
+		"$and": bson.A{claimable},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     studyTypes.SCHEDULED_EVENT_STATUS_PROCESSING,
+			"claimID":    claimID,
+			"leaseUntil": leaseUntil,
+		},
+	}
+	if _, err := dbService.collectionScheduledEvents(instanceID).UpdateMany(ctx, filter, update); err != nil {
+		return nil, err
+	}
+
+	cursor, err = dbService.collectionScheduledEvents(instanceID).Find(
+		ctx,
+		bson.M{"claimID": claimID, "status": studyTypes.SCHEDULED_EVENT_STATUS_PROCESSING},
+		options.Find().SetSort(bson.D{{Key: "fireAt", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	events := []studyTypes.ScheduledEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// MarkScheduledEventProcessed stores the outcome of firing the event
+func (dbService *StudyDBService) MarkScheduledEventProcessed(instanceID string, id primitive.ObjectID, status string, lastError string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":      status,
+			"lastError":   lastError,
+			"processedAt": time.Now(),
+		},
+		"$unset": bson.M{
+			"claimID":    "",
+			"leaseUntil": "",
+		},
+	}
+	res, err := dbService.collectionScheduledEvents(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetScheduledEvents returns the scheduled events of the study, optionally filtered by participant and status, by fire time
+func (dbService *StudyDBService) GetScheduledEvents(instanceID string, studyKey string, participantID string, status string, page int64, limit int64) (events []studyTypes.ScheduledEvent, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if participantID != "" {
+		filter["participantID"] = participantID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	totalCount, err := dbService.collectionScheduledEvents(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	paginationInfo = prepPaginationInfos(totalCount, page, limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "fireAt", Value: 1}}).
+		SetSkip((paginationInfo.CurrentPage - 1) * paginationInfo.PageSize).
+		SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionScheduledEvents(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events = []studyTypes.ScheduledEvent{}
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, nil, err
+	}
+	return events, paginationInfo, nil
+}
@@ -0,0 +1,213 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) collectionScheduledExports(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_SCHEDULED_EXPORTS)
+}
+
+func scheduledExportIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_SCHEDULED_EXPORTS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "active", Value: 1},
+						{Key: "nextRunAt", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{{Key: "studyKey", Value: 1}},
+				},
+			},
+		},
+	}
+}
+
+func (dbService *StudyDBService) CreateScheduledExport(instanceID string, scheduledExport studyTypes.ScheduledExport) (studyTypes.ScheduledExport, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	scheduledExport.ID = primitive.NilObjectID
+	scheduledExport.CreatedAt = time.Now()
+	scheduledExport.UpdatedAt = scheduledExport.CreatedAt
+
+	res, err := dbService.collectionScheduledExports(instanceID).InsertOne(ctx, scheduledExport)
+	if err != nil {
+		return scheduledExport, err
+	}
+	scheduledExport.ID = res.InsertedID.(primitive.ObjectID)
+	return scheduledExport, nil
+}
+
+func (dbService *StudyDBService) GetScheduledExports(instanceID string, studyKey string) (scheduledExports []studyTypes.ScheduledExport, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	cursor, err := dbService.collectionScheduledExports(instanceID).Find(ctx, bson.M{"studyKey": studyKey}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	scheduledExports = []studyTypes.ScheduledExport{}
+	err = cursor.All(ctx, &scheduledExports)
+	return scheduledExports, err
+}
+
+func (dbService *StudyDBService) GetScheduledExportByID(instanceID string, studyKey string, id string) (scheduledExport studyTypes.ScheduledExport, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return scheduledExport, err
+	}
+
+	err = dbService.collectionScheduledExports(instanceID).FindOne(ctx, bson.M{"_id": _id, "studyKey": studyKey}).Decode(&scheduledExport)
+	return scheduledExport, err
+}
+
+// UpdateScheduledExport replaces the settings of the scheduled export, the run and delivery status is kept
+func (dbService *StudyDBService) UpdateScheduledExport(instanceID string, scheduledExport studyTypes.ScheduledExport) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": scheduledExport.ID, "studyKey": scheduledExport.StudyKey}
+	update := bson.M{
+		"$set": bson.M{
+			"label":       scheduledExport.Label,
+			"cron":        scheduledExport.Cron,
+			"timezone":    scheduledExport.Timezone,
+			"request":     scheduledExport.Request,
+			"incremental": scheduledExport.Incremental,
+			"destination": scheduledExport.Destination,
+			"active":      scheduledExport.Active,
+			"nextRunAt":   scheduledExport.NextRunAt,
+			"updatedAt":   time.Now(),
+		},
+	}
+	res, err := dbService.collectionScheduledExports(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) DeleteScheduledExport(instanceID string, studyKey string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	res, err := dbService.collectionScheduledExports(instanceID).DeleteOne(ctx, bson.M{"_id": _id, "studyKey": studyKey})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetDueScheduledExports returns the active scheduled exports whose next run is not after now
+func (dbService *StudyDBService) GetDueScheduledExports(instanceID string, now time.Time) (scheduledExports []studyTypes.ScheduledExport, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"active":    true,
+		"nextRunAt": bson.M{"$lte": now},
+	}
+	cursor, err := dbService.collectionScheduledExports(instanceID).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "nextRunAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	scheduledExports = []studyTypes.ScheduledExport{}
+	err = cursor.All(ctx, &scheduledExports)
+	return scheduledExports, err
+}
+
+// AdvanceScheduledExport moves the next run forward if it is still prevNextRunAt. Returns false if another worker
+// already advanced it, so each run is only started once.
+func (dbService *StudyDBService) AdvanceScheduledExport(instanceID string, id primitive.ObjectID, prevNextRunAt time.Time, nextRunAt time.Time) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": id, "nextRunAt": prevNextRunAt}
+	update := bson.M{
+		"$set": bson.M{
+			"nextRunAt": nextRunAt,
+			"updatedAt": time.Now(),
+		},
+	}
+	res, err := dbService.collectionScheduledExports(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (dbService *StudyDBService) SetScheduledExportLastJob(instanceID string, id primitive.ObjectID, jobID string, runAt time.Time) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"lastJobID": jobID,
+			"lastRunAt": runAt,
+			"updatedAt": time.Now(),
+		},
+	}
+	_, err := dbService.collectionScheduledExports(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// SetScheduledExportDeliveryResult stores the outcome of the latest delivery and counts consecutive failures
+func (dbService *StudyDBService) SetScheduledExportDeliveryResult(instanceID string, id primitive.ObjectID, status string, errMsg string, now time.Time) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var update bson.M
+	if status == studyTypes.EXPORT_DELIVERY_STATUS_DELIVERED {
+		update = bson.M{
+			"$set": bson.M{
+				"lastDeliveryStatus":  status,
+				"lastDeliveredAt":     now,
+				"consecutiveFailures": 0,
+				"updatedAt":           now,
+			},
+			"$unset": bson.M{"lastDeliveryError": ""},
+		}
+	} else {
+		update = bson.M{
+			"$set": bson.M{
+				"lastDeliveryStatus": status,
+				"lastDeliveryError":  errMsg,
+				"updatedAt":          now,
+			},
+			"$inc": bson.M{"consecutiveFailures": 1},
+		}
+	}
+	_, err := dbService.collectionScheduledExports(instanceID).UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
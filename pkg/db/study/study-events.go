@@ -0,0 +1,86 @@
+package study
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func (dbService *StudyDBService) collectionRuleErrors(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_RULE_ERRORS)
+}
+
+func ruleErrorIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_RULE_ERRORS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "time", Value: -1},
+					},
+				},
+				{
+					Keys:    bson.D{{Key: "time", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(REMOVE_RULE_ERRORS_AFTER),
+				},
+			},
+		},
+	}
+}
+
+func (dbService *StudyDBService) AddRuleError(instanceID string, ruleError studyTypes.RuleError) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if ruleError.Time.IsZero() {
+		ruleError.Time = time.Now()
+	}
+
+	_, err := dbService.collectionRuleErrors(instanceID).InsertOne(ctx, ruleError)
+	return err
+}
+
+func (dbService *StudyDBService) deleteRuleErrorsOfStudy(instanceID string, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionRuleErrors(instanceID).DeleteMany(ctx, bson.M{"studyKey": studyKey})
+	return err
+}
+
+func insertsOfNonSyntheticDocuments() mongo.Pipeline {
+	return mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"operationType":          "insert",
+		"fullDocument.synthetic": bson.M{"$ne": true},
+	}}}}
+}
+
+// WatchNewResponses opens a change stream on the responses submitted to the study. Requires a replica set.
+func (dbService *StudyDBService) WatchNewResponses(ctx context.Context, instanceID string, studyKey string) (*mongo.ChangeStream, error) {
+	pipeline := insertsOfNonSyntheticDocuments()
+	// only metadata is streamed, the responses themselves stay in the database
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{"fullDocument.responses": 0, "fullDocument.context": 0, "fullDocument.encryptedData": 0}}})
+	return dbService.collectionResponses(instanceID, studyKey).Watch(ctx, pipeline)
+}
+
+// WatchNewParticipants opens a change stream on the participants entering the study. Requires a replica set.
+func (dbService *StudyDBService) WatchNewParticipants(ctx context.Context, instanceID string, studyKey string) (*mongo.ChangeStream, error) {
+	return dbService.collectionParticipants(instanceID, studyKey).Watch(ctx, insertsOfNonSyntheticDocuments())
+}
+
+// WatchRuleErrors opens a change stream on the rule errors recorded for the study. Requires a replica set.
+func (dbService *StudyDBService) WatchRuleErrors(ctx context.Context, instanceID string, studyKey string) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"operationType":         "insert",
+		"fullDocument.studyKey": studyKey,
+	}}}}
+	return dbService.collectionRuleErrors(instanceID).Watch(ctx, pipeline)
+}
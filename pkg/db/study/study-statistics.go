@@ -0,0 +1,348 @@
+package study
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// statistics are computed with aggregations over whole collections, so results are reused for a few minutes
+const statisticsCacheTTL = 5 * time.Minute
+
+const statisticsDateFormat = "%Y-%m-%d"
+
+type DailySurveyResponseCount struct {
+	Date      string `bson:"date" json:"date"`
+	SurveyKey string `bson:"surveyKey" json:"surveyKey"`
+	Count     int64  `bson:"count" json:"count"`
+}
+
+type ParticipantCounts struct {
+	Total    int64            `json:"total"`
+	ByStatus map[string]int64 `json:"byStatus"`
+	// participants with a submission since ActiveSince
+	RecentlyActive int64 `json:"recentlyActive"`
+	ActiveSince    int64 `json:"activeSince"`
+}
+
+// FunnelStep counts the participants who submitted the survey of the step and all previous ones.
+// Exited counts those of them who left the study without submitting the survey of the next step.
+type FunnelStep struct {
+	SurveyKey string `json:"surveyKey"`
+	Reached   int64  `json:"reached"`
+	Exited    int64  `json:"exited"`
+}
+
+type DailyEnrollmentCount struct {
+	Date       string `bson:"date" json:"date"`
+	Count      int64  `bson:"count" json:"count"`
+	Cumulative int64  `bson:"-" json:"cumulative"`
+}
+
+type StatisticsResult struct {
+	ComputedAt time.Time   `json:"computedAt"`
+	Data       interface{} `json:"data"`
+}
+
+type statisticsCache struct {
+	mu      sync.Mutex
+	entries map[string]StatisticsResult
+}
+
+func (c *statisticsCache) get(key string) (StatisticsResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.entries[key]
+	if !ok || time.Since(result.ComputedAt) > statisticsCacheTTL {
+		return StatisticsResult{}, false
+	}
+	return result, true
+}
+
+func (c *statisticsCache) set(key string, result StatisticsResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]StatisticsResult{}
+	}
+	for k, cached := range c.entries {
+		if time.Since(cached.ComputedAt) > statisticsCacheTTL {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = result
+}
+
+func (dbService *StudyDBService) cachedStatistics(key string, compute func() (interface{}, error)) (StatisticsResult, error) {
+	if result, ok := dbService.statistics.get(key); ok {
+		return result, nil
+	}
+	data, err := compute()
+	if err != nil {
+		return StatisticsResult{}, err
+	}
+	result := StatisticsResult{ComputedAt: time.Now(), Data: data}
+	dbService.statistics.set(key, result)
+	return result, nil
+}
+
+func dayExpression(field string, timezone string) bson.M {
+	return bson.M{
+		"$dateToString": bson.M{
+			"format":   statisticsDateFormat,
+			"date":     bson.M{"$toDate": bson.M{"$multiply": bson.A{field, 1000}}},
+			"timezone": timezone,
+		},
+	}
+}
+
+func responsesPerDayPipeline(from int64, until int64, timezone string) mongo.Pipeline {
+	match := ExcludeSynthetic(bson.M{
+		"submittedAt":  bson.M{"$gte": from, "$lte": until},
+		"supersededBy": bson.M{"$exists": false},
+	})
+	return mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"surveyKey": "$key",
+				"date":      dayExpression("$submittedAt", timezone),
+			},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"surveyKey": "$_id.surveyKey",
+			"date":      "$_id.date",
+			"count":     1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}, {Key: "surveyKey", Value: 1}}}},
+	}
+}
+
+// GetResponseCountsPerDay returns the number of submitted responses per survey and day (in the given timezone).
+// Superseded responses and responses of synthetic participants are not counted.
+func (dbService *StudyDBService) GetResponseCountsPerDay(instanceID string, studyKey string, from int64, until int64, timezone string) (StatisticsResult, error) {
+	key := fmt.Sprintf("%s/%s/responsesPerDay/%d/%d/%s", instanceID, studyKey, from, until, timezone)
+	return dbService.cachedStatistics(key, func() (interface{}, error) {
+		ctx, cancel := dbService.getContext()
+		defer cancel()
+
+		cursor, err := dbService.collectionResponses(instanceID, studyKey).Aggregate(ctx, responsesPerDayPipeline(from, until, timezone))
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		counts := []DailySurveyResponseCount{}
+		err = cursor.All(ctx, &counts)
+		return counts, err
+	})
+}
+
+func participantCountsPipeline(activeSince int64) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: ExcludeSynthetic(bson.M{})}},
+		{{Key: "$project", Value: bson.M{
+			"studyStatus": 1,
+			"lastSubmissionAt": bson.M{"$max": bson.M{
+				"$map": bson.M{
+					"input": bson.M{"$objectToArray": bson.M{"$ifNull": bson.A{"$lastSubmission", bson.M{}}}},
+					"in":    "$$this.v",
+				},
+			}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$studyStatus",
+			"count": bson.M{"$sum": 1},
+			"recentlyActive": bson.M{"$sum": bson.M{
+				"$cond": bson.A{bson.M{"$gte": bson.A{"$lastSubmissionAt", activeSince}}, 1, 0},
+			}},
+		}}},
+	}
+}
+
+// GetParticipantCounts returns the number of participants per study status and the number of participants with a submission since activeSince
+func (dbService *StudyDBService) GetParticipantCounts(instanceID string, studyKey string, activeSince int64) (StatisticsResult, error) {
+	key := fmt.Sprintf("%s/%s/participantCounts/%d", instanceID, studyKey, activeSince)
+	return dbService.cachedStatistics(key, func() (interface{}, error) {
+		ctx, cancel := dbService.getContext()
+		defer cancel()
+
+		cursor, err := dbService.collectionParticipants(instanceID, studyKey).Aggregate(ctx, participantCountsPipeline(activeSince))
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		var groups []struct {
+			Status         string `bson:"_id"`
+			Count          int64  `bson:"count"`
+			RecentlyActive int64  `bson:"recentlyActive"`
+		}
+		if err := cursor.All(ctx, &groups); err != nil {
+			return nil, err
+		}
+
+		counts := ParticipantCounts{
+			ByStatus:    map[string]int64{},
+			ActiveSince: activeSince,
+		}
+		for _, g := range groups {
+			counts.ByStatus[g.Status] = g.Count
+			counts.Total += g.Count
+			counts.RecentlyActive += g.RecentlyActive
+		}
+		return counts, nil
+	})
+}
+
+func ValidateFunnelSurveyKeys(surveyKeys []string) error {
+	if len(surveyKeys) == 0 {
+		return errors.New("at least one survey key is required")
+	}
+	for _, key := range surveyKeys {
+		if key == "" || strings.ContainsAny(key, ".$") {
+			return fmt.Errorf("invalid survey key: %q", key)
+		}
+	}
+	return nil
+}
+
+func funnelPipeline(surveyKeys []string) mongo.Pipeline {
+	reached := make([]bson.M, len(surveyKeys))
+	for i := range surveyKeys {
+		conditions := bson.A{}
+		for _, key := range surveyKeys[:i+1] {
+			conditions = append(conditions, bson.M{"$gt": bson.A{"$lastSubmission." + key, 0}})
+		}
+		reached[i] = bson.M{"$and": conditions}
+	}
+
+	group := bson.M{"_id": nil}
+	for i := range surveyKeys {
+		group[fmt.Sprintf("reached%d", i)] = bson.M{"$sum": bson.M{"$cond": bson.A{reached[i], 1, 0}}}
+
+		exited := bson.A{reached[i], bson.M{"$eq": bson.A{"$studyStatus", studyTypes.PARTICIPANT_STUDY_STATUS_EXITED}}}
+		if i+1 < len(surveyKeys) {
+			exited = append(exited, bson.M{"$not": bson.A{reached[i+1]}})
+		}
+		group[fmt.Sprintf("exited%d", i)] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$and": exited}, 1, 0}}}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: ExcludeSynthetic(bson.M{})}},
+		{{Key: "$group", Value: group}},
+	}
+}
+
+// GetCompletionFunnel counts how many participants submitted the given surveys in order and how many exited the study at each step
+func (dbService *StudyDBService) GetCompletionFunnel(instanceID string, studyKey string, surveyKeys []string) (StatisticsResult, error) {
+	if err := ValidateFunnelSurveyKeys(surveyKeys); err != nil {
+		return StatisticsResult{}, err
+	}
+
+	key := fmt.Sprintf("%s/%s/funnel/%s", instanceID, studyKey, strings.Join(surveyKeys, ","))
+	return dbService.cachedStatistics(key, func() (interface{}, error) {
+		ctx, cancel := dbService.getContext()
+		defer cancel()
+
+		cursor, err := dbService.collectionParticipants(instanceID, studyKey).Aggregate(ctx, funnelPipeline(surveyKeys))
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		var results []bson.M
+		if err := cursor.All(ctx, &results); err != nil {
+			return nil, err
+		}
+
+		steps := make([]FunnelStep, len(surveyKeys))
+		for i, surveyKey := range surveyKeys {
+			steps[i].SurveyKey = surveyKey
+			if len(results) > 0 {
+				steps[i].Reached = toInt64(results[0][fmt.Sprintf("reached%d", i)])
+				steps[i].Exited = toInt64(results[0][fmt.Sprintf("exited%d", i)])
+			}
+		}
+		return steps, nil
+	})
+}
+
+func enrollmentPerDayPipeline(from int64, until int64, timezone string) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: ExcludeSynthetic(bson.M{
+			"enteredAt": bson.M{"$gte": from, "$lte": until},
+		})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   dayExpression("$enteredAt", timezone),
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":   0,
+			"date":  "$_id",
+			"count": 1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}}}},
+	}
+}
+
+func addCumulativeEnrollment(counts []DailyEnrollmentCount, enrolledBefore int64) {
+	total := enrolledBefore
+	for i := range counts {
+		total += counts[i].Count
+		counts[i].Cumulative = total
+	}
+}
+
+// GetEnrollmentPerDay returns the number of participants who entered the study per day, with the cumulative number of participants
+func (dbService *StudyDBService) GetEnrollmentPerDay(instanceID string, studyKey string, from int64, until int64, timezone string) (StatisticsResult, error) {
+	key := fmt.Sprintf("%s/%s/enrollment/%d/%d/%s", instanceID, studyKey, from, until, timezone)
+	return dbService.cachedStatistics(key, func() (interface{}, error) {
+		ctx, cancel := dbService.getContext()
+		defer cancel()
+
+		enrolledBefore, err := dbService.collectionParticipants(instanceID, studyKey).CountDocuments(
+			ctx,
+			ExcludeSynthetic(bson.M{"enteredAt": bson.M{"$lt": from}}),
+			options.Count(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		cursor, err := dbService.collectionParticipants(instanceID, studyKey).Aggregate(ctx, enrollmentPerDayPipeline(from, until, timezone))
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		counts := []DailyEnrollmentCount{}
+		if err := cursor.All(ctx, &counts); err != nil {
+			return nil, err
+		}
+		addCumulativeEnrollment(counts, enrolledBefore)
+		return counts, nil
+	})
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+package study
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestStatisticsCache(t *testing.T) {
+	dbService := &StudyDBService{}
+	calls := 0
+	compute := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	first, err := dbService.cachedStatistics("key", compute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := dbService.cachedStatistics("key", compute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 || first.Data != second.Data || !first.ComputedAt.Equal(second.ComputedAt) {
+		t.Errorf("expected cached result, got %+v and %+v", first, second)
+	}
+
+	t.Run("expired entry", func(t *testing.T) {
+		dbService.statistics.entries["key"] = StatisticsResult{ComputedAt: time.Now().Add(-statisticsCacheTTL - time.Second), Data: 0}
+		result, _ := dbService.cachedStatistics("key", compute)
+		if calls != 2 || result.Data != 2 {
+			t.Errorf("expected recomputed result, got %+v", result)
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		_, err := dbService.cachedStatistics("failing", func() (interface{}, error) { return nil, errors.New("failed") })
+		if err == nil {
+			t.Error("expected error")
+		}
+		if _, ok := dbService.statistics.get("failing"); ok {
+			t.Error("error result cached")
+		}
+	})
+}
+
+func TestAddCumulativeEnrollment(t *testing.T) {
+	counts := []DailyEnrollmentCount{
+		{Date: "2024-01-01", Count: 2},
+		{Date: "2024-01-03", Count: 5},
+	}
+	addCumulativeEnrollment(counts, 10)
+	if counts[0].Cumulative != 12 || counts[1].Cumulative != 17 {
+		t.Errorf("unexpected counts: %+v", counts)
+	}
+}
+
+func TestValidateFunnelSurveyKeys(t *testing.T) {
+	if err := ValidateFunnelSurveyKeys([]string{"intake", "weekly"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, keys := range [][]string{{}, {"intake", ""}, {"a.b"}, {"$where"}} {
+		if err := ValidateFunnelSurveyKeys(keys); err == nil {
+			t.Errorf("expected error for %v", keys)
+		}
+	}
+}
+
+func TestFunnelPipeline(t *testing.T) {
+	pipeline := funnelPipeline([]string{"intake", "weekly", "exit"})
+	if len(pipeline) != 2 {
+		t.Fatalf("unexpected pipeline: %v", pipeline)
+	}
+
+	group := pipeline[1][0].Value.(bson.M)
+	for _, field := range []string{"reached0", "reached1", "reached2", "exited0", "exited1", "exited2"} {
+		if _, ok := group[field]; !ok {
+			t.Errorf("missing %s in group stage", field)
+		}
+	}
+
+	// the last step has no next step to miss
+	lastExited := group["exited2"].(bson.M)["$sum"].(bson.M)["$cond"].(bson.A)[0].(bson.M)["$and"].(bson.A)
+	if len(lastExited) != 2 {
+		t.Errorf("unexpected exit condition for last step: %v", lastExited)
+	}
+	firstExited := group["exited0"].(bson.M)["$sum"].(bson.M)["$cond"].(bson.A)[0].(bson.M)["$and"].(bson.A)
+	if len(firstExited) != 3 {
+		t.Errorf("unexpected exit condition for first step: %v", firstExited)
+	}
+}
@@ -1,30 +1,34 @@
 package study
 
 import (
+	"context"
 	"log/slog"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/cache"
+	"github.com/case-framework/case-backend/pkg/db"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
-func (dbService *StudyDBService) createIndexForStudyInfosCollection(instanceID string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	_, err := dbService.collectionStudyInfos(instanceID).Indexes().CreateOne(
-		ctx,
-		mongo.IndexModel{
-			Keys: bson.D{
-				{Key: "key", Value: 1},
+func studyInfoIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_STUDY_INFOS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "key", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
 			},
-			Options: options.Index().SetUnique(true),
 		},
-	)
-	return err
+	}
 }
 
 // get studies
@@ -33,7 +37,7 @@ func (dbService *StudyDBService) GetStudies(instanceID string, statusFilter stri
 	defer cancel()
 
 	collection := dbService.collectionStudyInfos(instanceID)
-	filter := bson.M{}
+	filter := db.NotDeleted(bson.M{})
 	if statusFilter != "" {
 		filter["status"] = statusFilter
 	}
@@ -70,41 +74,24 @@ func (dbService *StudyDBService) CreateStudy(instanceID string, study studyTypes
 		return err
 	}
 
-	studyKey := study.Key
-
-	// index on surveys
-	err = dbService.CreateIndexForSurveyCollection(instanceID, studyKey)
-	if err != nil {
-		slog.Error("Error creating index for surveys: ", slog.String("error", err.Error()))
-	}
-
-	// index on participants
-	err = dbService.CreateIndexForParticipantsCollection(instanceID, studyKey)
-	if err != nil {
-		slog.Error("Error creating index for participants: ", slog.String("error", err.Error()))
-	}
-
-	// index on responses
-	err = dbService.CreateIndexForResponsesCollection(instanceID, studyKey)
-	if err != nil {
-		slog.Error("Error creating index for responses: ", slog.String("error", err.Error()))
-	}
-
-	// index on reports
-	err = dbService.CreateIndexForReportsCollection(instanceID, studyKey)
-	if err != nil {
-		slog.Error("Error creating index for reports: ", slog.String("error", err.Error()))
-	}
+	// indexes of the study collections, existing studies get them with EnsureIndexes
+	db.EnsureIndexes(dbService.getContext, dbService.DBClient.Database(dbService.getDBName(instanceID)), studyIndexRegistry(study.Key), db.EnsureIndexesOptions{}).Log()
 	return nil
 }
 
 // get study by study key
-func (dbService *StudyDBService) GetStudy(instanceID string, studyKey string) (study studyTypes.Study, err error) {
+func (dbService *StudyDBService) GetStudy(instanceID string, studyKey string) (studyTypes.Study, error) {
+	return cache.GetOrLoad(context.Background(), dbService.cache, studyCacheKey(instanceID, studyKey), dbService.cacheTTL, func() (studyTypes.Study, error) {
+		return dbService.findStudy(instanceID, studyKey)
+	})
+}
+
+func (dbService *StudyDBService) findStudy(instanceID string, studyKey string) (study studyTypes.Study, err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
 	collection := dbService.collectionStudyInfos(instanceID)
-	filter := bson.M{"key": studyKey}
+	filter := db.NotDeleted(bson.M{"key": studyKey})
 	err = collection.FindOne(ctx, filter).Decode(&study)
 	if err != nil {
 		return study, err
@@ -117,6 +104,7 @@ func (dbService *StudyDBService) GetStudy(instanceID string, studyKey string) (s
 func (dbService *StudyDBService) UpdateStudyStatus(instanceID string, studyKey string, status string) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
 
 	collection := dbService.collectionStudyInfos(instanceID)
 	filter := bson.M{"key": studyKey}
@@ -133,6 +121,7 @@ func (dbService *StudyDBService) UpdateStudyStatus(instanceID string, studyKey s
 func (dbService *StudyDBService) UpdateStudyIsDefault(instanceID string, studyKey string, isDefault bool) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
 
 	collection := dbService.collectionStudyInfos(instanceID)
 	filter := bson.M{"key": studyKey}
@@ -149,6 +138,7 @@ func (dbService *StudyDBService) UpdateStudyIsDefault(instanceID string, studyKe
 func (dbService *StudyDBService) UpdateStudyFileUploadRule(instanceID string, studyKey string, fileUploadRule *studyTypes.Expression) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
 
 	collection := dbService.collectionStudyInfos(instanceID)
 	filter := bson.M{"key": studyKey}
@@ -162,9 +152,150 @@ func (dbService *StudyDBService) UpdateStudyFileUploadRule(instanceID string, st
 	return nil
 }
 
+func (dbService *StudyDBService) UpdateStudyResponseCorrectionConfig(instanceID string, studyKey string, config *studyTypes.ResponseCorrectionConfig) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.responseCorrections": config}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudyResponseValidationConfig(instanceID string, studyKey string, config *studyTypes.ResponseValidationConfig) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.responseValidation": config}}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudyResponseQuarantineConfig(instanceID string, studyKey string, config *studyTypes.ResponseQuarantineConfig) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.responseQuarantine": config}}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudyEnrollmentWindow(instanceID string, studyKey string, window *studyTypes.EnrollmentWindow) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.enrollmentWindow": window}}
+	if window == nil {
+		update = bson.M{"$unset": bson.M{"configs.enrollmentWindow": ""}}
+	}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudyEnrollmentCap(instanceID string, studyKey string, enrollmentCap *studyTypes.EnrollmentCap) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.enrollmentCap": enrollmentCap}}
+	if enrollmentCap == nil {
+		update = bson.M{"$unset": bson.M{"configs.enrollmentCap": ""}}
+	}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudyRequireReview(instanceID string, studyKey string, requireReview bool) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.requireReview": requireReview}}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudySurveyReminders(instanceID string, studyKey string, config *studyTypes.SurveyReminderConfig) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.surveyReminders": config}}
+	if config == nil {
+		update = bson.M{"$unset": bson.M{"configs.surveyReminders": ""}}
+	}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (dbService *StudyDBService) UpdateStudyDisplayProps(instanceID string, studyKey string, name []studyTypes.LocalisedObject, description []studyTypes.LocalisedObject, tags []studyTypes.Tag) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
 
 	collection := dbService.collectionStudyInfos(instanceID)
 	filter := bson.M{"key": studyKey}
@@ -181,6 +312,7 @@ func (dbService *StudyDBService) UpdateStudyDisplayProps(instanceID string, stud
 func (dbService *StudyDBService) UpdateStudyStats(instanceID string, studyKey string, stats studyTypes.StudyStats) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
 
 	filter := bson.M{
 		"key": studyKey,
@@ -198,7 +330,7 @@ func (dbService *StudyDBService) GetNotificationSubscriptions(instanceID string,
 	defer cancel()
 
 	collection := dbService.collectionStudyInfos(instanceID)
-	filter := bson.M{"key": studyKey}
+	filter := db.NotDeleted(bson.M{"key": studyKey})
 
 	var study studyTypes.Study
 	err := collection.FindOne(ctx, filter).Decode(&study)
@@ -212,6 +344,7 @@ func (dbService *StudyDBService) GetNotificationSubscriptions(instanceID string,
 func (dbService *StudyDBService) UpdateStudyNotificationSubscriptions(instanceID string, studyKey string, subscriptions []studyTypes.NotificationSubscription) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateStudyCache(instanceID, studyKey)
 
 	collection := dbService.collectionStudyInfos(instanceID)
 	filter := bson.M{"key": studyKey}
@@ -225,10 +358,29 @@ func (dbService *StudyDBService) UpdateStudyNotificationSubscriptions(instanceID
 	return nil
 }
 
-// delete study by study key
+// DeleteStudy moves the study to the trash, its data is kept until the study is purged
 func (dbService *StudyDBService) DeleteStudy(instanceID string, studyKey string) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer cache.Invalidate(context.Background(), dbService.cache, []string{studyCacheKey(instanceID, studyKey)}, []string{surveyCachePrefix(instanceID, studyKey)})
+
+	filter := db.NotDeleted(bson.M{"key": studyKey})
+	update := bson.M{"$set": bson.M{db.FIELD_DELETED_AT: time.Now().Unix()}}
+	res, err := dbService.collectionStudyInfos(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// PurgeStudy permanently deletes the study with all its collections
+func (dbService *StudyDBService) PurgeStudy(instanceID string, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer cache.Invalidate(context.Background(), dbService.cache, []string{studyCacheKey(instanceID, studyKey)}, []string{surveyCachePrefix(instanceID, studyKey)})
 
 	// delete study collections
 	err := dbService.collectionFiles(instanceID, studyKey).Drop(ctx)
@@ -272,6 +424,21 @@ func (dbService *StudyDBService) DeleteStudy(instanceID string, studyKey string)
 		slog.Error("Error deleting study rules", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
 	}
 
+	err = dbService.deleteDraftsOfStudy(instanceID, studyKey)
+	if err != nil {
+		slog.Error("Error deleting drafts", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
+	err = dbService.deleteReportTemplatesOfStudy(instanceID, studyKey)
+	if err != nil {
+		slog.Error("Error deleting report templates", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
+	err = dbService.deleteRuleErrorsOfStudy(instanceID, studyKey)
+	if err != nil {
+		slog.Error("Error deleting rule errors", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
 	err = dbService.RemoveConfidentialIDMapEntriesForStudy(instanceID, studyKey)
 	if err != nil {
 		slog.Error("Error deleting confidential ID map entries", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
@@ -6,29 +6,29 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/db"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
-func (dbService *StudyDBService) CreateIndexForStudyRulesCollection(instanceID string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	collection := dbService.collectionStudyRules(instanceID)
-	indexes := []mongo.IndexModel{
+func studyRuleIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
 		{
-			Keys: bson.D{
-				{Key: "studyKey", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "uploadedAt", Value: 1},
-				{Key: "studyKey", Value: 1},
+			Collection: COLLECTION_NAME_STUDY_RULES,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "uploadedAt", Value: 1},
+						{Key: "studyKey", Value: 1},
+					},
+				},
 			},
 		},
 	}
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
 }
 
 func (dbService *StudyDBService) deleteStudyRules(instanceID string, studyKey string) error {
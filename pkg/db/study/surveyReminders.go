@@ -0,0 +1,102 @@
+package study
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	// longer than surveys are usually assigned, so that a reminder is not sent again for the same survey
+	REMOVE_SENT_SURVEY_REMINDERS_AFTER = 60 * 60 * 24 * 365 // 1 year
+)
+
+func (dbService *StudyDBService) collectionSurveyReminders(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_SURVEY_REMINDERS)
+}
+
+func surveyReminderIndexes() []db.CollectionIndexes {
+	return []db.CollectionIndexes{
+		{
+			Collection: COLLECTION_NAME_SURVEY_REMINDERS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "studyKey", Value: 1},
+						{Key: "participantID", Value: 1},
+						{Key: "ruleKey", Value: 1},
+						{Key: "surveyKey", Value: 1},
+						{Key: "surveyValidFrom", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "sentAt", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(REMOVE_SENT_SURVEY_REMINDERS_AFTER),
+				},
+			},
+		},
+	}
+}
+
+// GetSentSurveyReminders returns the reminders sent to the participant
+func (dbService *StudyDBService) GetSentSurveyReminders(instanceID string, studyKey string, participantID string) ([]studyTypes.SentSurveyReminder, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":      studyKey,
+		"participantID": participantID,
+	}
+	cursor, err := dbService.collectionSurveyReminders(instanceID).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reminders := []studyTypes.SentSurveyReminder{}
+	if err := cursor.All(ctx, &reminders); err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// AddSentSurveyReminder records the reminder before it is sent. Returns false if it was already recorded, e.g. by a
+// concurrent run.
+func (dbService *StudyDBService) AddSentSurveyReminder(instanceID string, reminder studyTypes.SentSurveyReminder) (string, bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	reminder.ID = primitive.NilObjectID
+	if reminder.SentAt.IsZero() {
+		reminder.SentAt = time.Now()
+	}
+
+	res, err := dbService.collectionSurveyReminders(instanceID).InsertOne(ctx, reminder)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return res.InsertedID.(primitive.ObjectID).Hex(), true, nil
+}
+
+// RemoveSentSurveyReminder removes the record of a reminder that could not be sent, so that it is retried
+func (dbService *StudyDBService) RemoveSentSurveyReminder(instanceID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = dbService.collectionSurveyReminders(instanceID).DeleteOne(ctx, bson.M{"_id": _id})
+	return err
+}
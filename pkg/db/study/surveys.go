@@ -1,6 +1,7 @@
 package study
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -9,48 +10,50 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/case-framework/case-backend/pkg/cache"
+	"github.com/case-framework/case-backend/pkg/db"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
-func (dbService *StudyDBService) CreateIndexForSurveyCollection(instanceID string, studyKey string) error {
-	ctx, cancel := dbService.getContext()
-	defer cancel()
-
-	collection := dbService.collectionSurveys(instanceID, studyKey)
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{
-				{Key: "surveyDefinition.key", Value: 1},
-				{Key: "unpublished", Value: 1},
-				{Key: "published", Value: -1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "published", Value: 1},
-				{Key: "surveyDefinition.key", Value: 1},
-			},
-		},
-		{
-			Keys: bson.D{
-				{Key: "unpublished", Value: 1},
-			},
-		},
+func surveyIndexes(studyKey string) []db.CollectionIndexes {
+	return []db.CollectionIndexes{
 		{
-			Keys: bson.D{
-				{Key: "surveyDefinition.key", Value: 1},
-				{Key: "versionID", Value: 1},
+			Collection: studyKey + "_" + COLLECTION_NAME_SUFFIX_SURVEYS,
+			Indexes: []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "surveyDefinition.key", Value: 1},
+						{Key: "unpublished", Value: 1},
+						{Key: "published", Value: -1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "published", Value: 1},
+						{Key: "surveyDefinition.key", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "unpublished", Value: 1},
+					},
+				},
+				{
+					Keys: bson.D{
+						{Key: "surveyDefinition.key", Value: 1},
+						{Key: "versionID", Value: 1},
+					},
+					Options: options.Index().SetUnique(true),
+				},
 			},
-			Options: options.Index().SetUnique(true),
 		},
 	}
-	_, err := collection.Indexes().CreateMany(ctx, indexes)
-	return err
 }
 
 func (dbService *StudyDBService) SaveSurveyVersion(instanceID string, studyKey string, survey *studyTypes.Survey) (err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateSurveyCache(instanceID, studyKey, survey.SurveyDefinition.Key)
 
 	ret, err := dbService.collectionSurveys(instanceID, studyKey).InsertOne(ctx, survey)
 	if err != nil {
@@ -65,7 +68,7 @@ func (dbService *StudyDBService) GetSurveyKeysForStudy(instanceID string, studyK
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{}
+	filter := db.NotDeleted(bson.M{})
 	if !includeUnpublished {
 		filter["unpublished"] = 0
 	}
@@ -96,7 +99,7 @@ func (dbService *StudyDBService) GetSurveyVersions(instanceID string, studyKey s
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{}
+	filter := db.NotDeleted(bson.M{})
 	if len(surveyKey) > 0 {
 		filter["surveyDefinition.key"] = surveyKey
 	}
@@ -121,14 +124,34 @@ func (dbService *StudyDBService) GetSurveyVersions(instanceID string, studyKey s
 	return surveys, nil
 }
 
+// GetSurveyVersionIDs returns the version IDs of the survey including versions in the trash, so that new version
+// IDs do not collide with restorable versions
+func (dbService *StudyDBService) GetSurveyVersionIDs(instanceID string, studyKey string, surveyKey string) (surveys []*studyTypes.Survey, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"surveyDefinition.key": surveyKey}
+	opts := options.Find().SetProjection(bson.D{primitive.E{Key: "versionID", Value: 1}})
+
+	cur, err := dbService.collectionSurveys(instanceID, studyKey).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cur.All(ctx, &surveys); err != nil {
+		return nil, err
+	}
+	return surveys, nil
+}
+
 func (dbService *StudyDBService) GetSurveyVersion(instanceID string, studyKey string, surveyKey string, versionID string) (survey *studyTypes.Survey, err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{
+	filter := db.NotDeleted(bson.M{
 		"surveyDefinition.key": surveyKey,
 		"versionID":            versionID,
-	}
+	})
 
 	err = dbService.collectionSurveys(instanceID, studyKey).FindOne(ctx, filter).Decode(&survey)
 	if err != nil {
@@ -137,17 +160,23 @@ func (dbService *StudyDBService) GetSurveyVersion(instanceID string, studyKey st
 	return survey, nil
 }
 
-func (dbService *StudyDBService) GetCurrentSurveyVersion(instanceID string, studyKey string, surveyKey string) (survey *studyTypes.Survey, err error) {
+func (dbService *StudyDBService) GetCurrentSurveyVersion(instanceID string, studyKey string, surveyKey string) (*studyTypes.Survey, error) {
+	return cache.GetOrLoad(context.Background(), dbService.cache, currentSurveyCacheKey(instanceID, studyKey, surveyKey), dbService.cacheTTL, func() (*studyTypes.Survey, error) {
+		return dbService.findCurrentSurveyVersion(instanceID, studyKey, surveyKey)
+	})
+}
+
+func (dbService *StudyDBService) findCurrentSurveyVersion(instanceID string, studyKey string, surveyKey string) (survey *studyTypes.Survey, err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{
+	filter := db.NotDeleted(bson.M{
 		"surveyDefinition.key": surveyKey,
 		"$or": []bson.M{
 			{"unpublished": 0},
 			{"unpublished": bson.M{"$exists": false}},
 		},
-	}
+	})
 
 	opts := &options.FindOneOptions{}
 	opts.SetSort(sortByPublishedDesc)
@@ -159,25 +188,32 @@ func (dbService *StudyDBService) GetCurrentSurveyVersion(instanceID string, stud
 	return survey, nil
 }
 
+// DeleteSurveyVersion moves the survey version to the trash
 func (dbService *StudyDBService) DeleteSurveyVersion(instanceID string, studyKey string, surveyKey string, versionID string) (err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateSurveyCache(instanceID, studyKey, surveyKey)
 
-	filter := bson.M{
+	filter := db.NotDeleted(bson.M{
 		"surveyDefinition.key": surveyKey,
 		"versionID":            versionID,
-	}
+	})
+	update := bson.M{"$set": bson.M{db.FIELD_DELETED_AT: time.Now().Unix()}}
 
-	res, err := dbService.collectionSurveys(instanceID, studyKey).DeleteOne(ctx, filter)
-	if res.DeletedCount < 1 {
+	res, err := dbService.collectionSurveys(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount < 1 {
 		return errors.New("no item was deleted")
 	}
-	return err
+	return nil
 }
 
 func (dbService *StudyDBService) UnpublishSurvey(instanceID string, studyKey string, surveyKey string) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
+	defer dbService.invalidateSurveyCache(instanceID, studyKey, surveyKey)
 
 	filter := bson.M{
 		"surveyDefinition.key": surveyKey,
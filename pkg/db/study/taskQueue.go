@@ -16,12 +16,24 @@ func (dbService *StudyDBService) CreateTask(
 	createdBy string,
 	targetCount int,
 	fileType string,
+) (task studyTypes.Task, err error) {
+	return dbService.CreateStudyTask(instanceID, "", createdBy, targetCount, fileType)
+}
+
+// CreateStudyTask creates a task bound to the study, e.g. for results only accessible within the study
+func (dbService *StudyDBService) CreateStudyTask(
+	instanceID string,
+	studyKey string,
+	createdBy string,
+	targetCount int,
+	fileType string,
 ) (task studyTypes.Task, err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
 	task = studyTypes.Task{
 		CreatedBy:      createdBy,
+		StudyKey:       studyKey,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 		Status:         studyTypes.TASK_STATUS_IN_PROGRESS,
@@ -0,0 +1,125 @@
+package study
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// GetDeletedStudies returns the studies in the trash, without secret keys
+func (dbService *StudyDBService) GetDeletedStudies(instanceID string) (studies []studyTypes.Study, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.D{
+		primitive.E{Key: "key", Value: 1},
+		primitive.E{Key: "status", Value: 1},
+		primitive.E{Key: "props", Value: 1},
+		primitive.E{Key: db.FIELD_DELETED_AT, Value: 1},
+	}).SetSort(bson.D{primitive.E{Key: db.FIELD_DELETED_AT, Value: -1}})
+
+	cursor, err := dbService.collectionStudyInfos(instanceID).Find(ctx, db.OnlyDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &studies); err != nil {
+		return nil, err
+	}
+	return studies, nil
+}
+
+// RestoreStudy moves the study out of the trash
+func (dbService *StudyDBService) RestoreStudy(instanceID string, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := db.OnlyDeleted(bson.M{"key": studyKey})
+	update := bson.M{"$unset": bson.M{db.FIELD_DELETED_AT: ""}}
+	res, err := dbService.collectionStudyInfos(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetStudyKeysDeletedBefore returns the keys of the studies moved to the trash before the cutoff (unix timestamp)
+func (dbService *StudyDBService) GetStudyKeysDeletedBefore(instanceID string, cutoff int64) ([]string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionStudyInfos(instanceID).Distinct(ctx, "key", db.DeletedBefore(bson.M{}, cutoff))
+	if err != nil {
+		return nil, err
+	}
+	studyKeys := make([]string, 0, len(res))
+	for _, r := range res {
+		if key, ok := r.(string); ok {
+			studyKeys = append(studyKeys, key)
+		}
+	}
+	return studyKeys, nil
+}
+
+// GetDeletedSurveyVersions returns the survey versions of the study in the trash, without survey content and rules
+func (dbService *StudyDBService) GetDeletedSurveyVersions(instanceID string, studyKey string) (surveys []*studyTypes.Survey, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().
+		SetProjection(projectionToRemoveSurveyContentAndRules).
+		SetSort(bson.D{primitive.E{Key: db.FIELD_DELETED_AT, Value: -1}})
+
+	cursor, err := dbService.collectionSurveys(instanceID, studyKey).Find(ctx, db.OnlyDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &surveys); err != nil {
+		return nil, err
+	}
+	return surveys, nil
+}
+
+// RestoreSurveyVersion moves the survey version out of the trash
+func (dbService *StudyDBService) RestoreSurveyVersion(instanceID string, studyKey string, surveyKey string, versionID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+	defer dbService.invalidateSurveyCache(instanceID, studyKey, surveyKey)
+
+	filter := db.OnlyDeleted(bson.M{
+		"surveyDefinition.key": surveyKey,
+		"versionID":            versionID,
+	})
+	update := bson.M{"$unset": bson.M{db.FIELD_DELETED_AT: ""}}
+	res, err := dbService.collectionSurveys(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// PurgeSurveyVersionsDeletedBefore permanently deletes the survey versions moved to the trash before the cutoff
+// (unix timestamp)
+func (dbService *StudyDBService) PurgeSurveyVersionsDeletedBefore(instanceID string, studyKey string, cutoff int64) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionSurveys(instanceID, studyKey).DeleteMany(ctx, db.DeletedBefore(bson.M{}, cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
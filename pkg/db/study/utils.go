@@ -1,5 +1,7 @@
 package study
 
+import "go.mongodb.org/mongo-driver/bson"
+
 const (
 	FALLBACK_PAGE_SIZE = 10
 )
@@ -31,3 +33,27 @@ func getTotalPages(totalCount int64, limit int64) int64 {
 	}
 	return (totalCount + limit - 1) / limit
 }
+
+// ExcludeSynthetic returns a copy of the filter that skips documents of synthetic-monitoring participants, unless the filter already refers to the synthetic field
+func ExcludeSynthetic(filter bson.M) bson.M {
+	result := bson.M{}
+	for k, v := range filter {
+		result[k] = v
+	}
+	if _, ok := result["synthetic"]; !ok {
+		result["synthetic"] = bson.M{"$ne": true}
+	}
+	return result
+}
+
+// ExcludeQuarantined returns a copy of the filter that skips quarantined responses, unless the filter already refers to the quarantine field
+func ExcludeQuarantined(filter bson.M) bson.M {
+	result := bson.M{}
+	for k, v := range filter {
+		result[k] = v
+	}
+	if _, ok := result["quarantine"]; !ok {
+		result["quarantine"] = bson.M{"$exists": false}
+	}
+	return result
+}
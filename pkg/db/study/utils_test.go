@@ -2,6 +2,8 @@ package study
 
 import (
 	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestGetTotalPages(t *testing.T) {
@@ -71,3 +73,51 @@ func TestGetTotalPages(t *testing.T) {
 		})
 	}
 }
+
+func TestExcludeSynthetic(t *testing.T) {
+	t.Run("adds exclusion without modifying input", func(t *testing.T) {
+		filter := bson.M{"key": "s1"}
+		got := ExcludeSynthetic(filter)
+		if _, ok := filter["synthetic"]; ok {
+			t.Error("input filter should not be modified")
+		}
+		if got["key"] != "s1" || got["synthetic"] == nil {
+			t.Errorf("unexpected filter: %v", got)
+		}
+	})
+
+	t.Run("keeps explicit synthetic filter", func(t *testing.T) {
+		got := ExcludeSynthetic(bson.M{"synthetic": true})
+		if got["synthetic"] != true {
+			t.Errorf("unexpected filter: %v", got)
+		}
+	})
+
+	t.Run("nil filter", func(t *testing.T) {
+		got := ExcludeSynthetic(nil)
+		if len(got) != 1 {
+			t.Errorf("unexpected filter: %v", got)
+		}
+	})
+}
+
+func TestExcludeQuarantined(t *testing.T) {
+	t.Run("adds exclusion without modifying input", func(t *testing.T) {
+		filter := bson.M{"key": "s1"}
+		got := ExcludeQuarantined(filter)
+		if _, ok := filter["quarantine"]; ok {
+			t.Error("input filter should not be modified")
+		}
+		if got["key"] != "s1" || got["quarantine"] == nil {
+			t.Errorf("unexpected filter: %v", got)
+		}
+	})
+
+	t.Run("keeps explicit quarantine filter", func(t *testing.T) {
+		explicit := bson.M{"$exists": true}
+		got := ExcludeQuarantined(bson.M{"quarantine": explicit})
+		if got["quarantine"].(bson.M)["$exists"] != true {
+			t.Errorf("unexpected filter: %v", got)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package study
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// GetWaitingListParticipants returns up to limit participants of the waiting list, in the order they joined or, if
+// random, a random sample
+func (dbService *StudyDBService) GetWaitingListParticipants(instanceID string, studyKey string, limit int64, random bool) (participants []studyTypes.Participant, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyStatus": studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST}
+
+	var cursor *mongo.Cursor
+	if random {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: filter}},
+			{{Key: "$sample", Value: bson.M{"size": limit}}},
+		}
+		cursor, err = dbService.collectionParticipants(instanceID, studyKey).Aggregate(ctx, pipeline)
+	} else {
+		// enteredAt has day resolution, the _id keeps the order within a day
+		opts := options.Find().SetSort(bson.D{{Key: "enteredAt", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit)
+		cursor, err = dbService.collectionParticipants(instanceID, studyKey).Find(ctx, filter, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &participants)
+	return participants, err
+}
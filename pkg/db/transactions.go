@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SupportsTransactions checks with the hello command if the deployment is a replica set or a sharded cluster.
+// Standalone servers do not support multi-document transactions.
+func SupportsTransactions(ctx context.Context, client *mongo.Client) bool {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
+
+// RunInTransaction runs fn in a transaction if useTransaction is set, fn is retried by the driver on transient
+// errors. Otherwise fn runs with ctx directly and the writes are not atomic, e.g. for standalone servers. All
+// operations in fn must use the context passed to fn.
+func RunInTransaction(ctx context.Context, client *mongo.Client, useTransaction bool, fn func(ctx context.Context) error) error {
+	if !useTransaction {
+		return fn(ctx)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestRunInTransactionWithoutTransaction(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "outer")
+	fnErr := errors.New("write failed")
+
+	calls := 0
+	err := RunInTransaction(ctx, nil, false, func(fnCtx context.Context) error {
+		calls += 1
+		if fnCtx.Value(ctxKey{}) != "outer" {
+			t.Error("fn should run with the given context")
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) || calls != 1 {
+		t.Errorf("unexpected error %v after %d calls", err, calls)
+	}
+}
+
+func TestSupportsTransactionsUnreachableServer(t *testing.T) {
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	if SupportsTransactions(context.Background(), client) {
+		t.Error("unreachable server should fall back to writes without transactions")
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017, the transaction cases need a replica set
+func TestRunInTransaction(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := client.Database(fmt.Sprintf("transactions_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		_ = database.Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+	})
+
+	// collections cannot be created in transactions on older servers
+	if err := database.CreateCollection(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateCollection(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	count := func(collection string, key string) int64 {
+		n, err := database.Collection(collection).CountDocuments(ctx, bson.M{"key": key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	writeBoth := func(key string, fail bool) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			if _, err := database.Collection("a").InsertOne(ctx, bson.M{"key": key}); err != nil {
+				return err
+			}
+			if _, err := database.Collection("b").InsertOne(ctx, bson.M{"key": key}); err != nil {
+				return err
+			}
+			if fail {
+				return errors.New("failed after the writes")
+			}
+			return nil
+		}
+	}
+
+	t.Run("standalone fallback keeps partial writes", func(t *testing.T) {
+		if err := RunInTransaction(ctx, client, false, writeBoth("fallback", true)); err == nil {
+			t.Fatal("expected error")
+		}
+		if count("a", "fallback") != 1 || count("b", "fallback") != 1 {
+			t.Error("writes without transaction should not be rolled back")
+		}
+	})
+
+	if !SupportsTransactions(ctx, client) {
+		t.Skip("server does not support transactions")
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		if err := RunInTransaction(ctx, client, true, writeBoth("commit", false)); err != nil {
+			t.Fatal(err)
+		}
+		if count("a", "commit") != 1 || count("b", "commit") != 1 {
+			t.Error("committed writes missing")
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		if err := RunInTransaction(ctx, client, true, writeBoth("abort", true)); err == nil {
+			t.Fatal("expected error")
+		}
+		if count("a", "abort") != 0 || count("b", "abort") != 0 {
+			t.Error("writes of the aborted transaction were kept")
+		}
+	})
+}
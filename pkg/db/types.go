@@ -9,6 +9,22 @@ type DBConfig struct {
 	IdleConnTimeout  int
 	InstanceIDs      []string
 	RunIndexCreation bool
+	RunMigrations    bool
+
+	// client options, driver defaults are used if not set
+	MinPoolSize            uint64
+	MaxConnecting          uint64
+	ConnectTimeout         int // seconds
+	ServerSelectionTimeout int // seconds
+	ReadPreference         string
+	MaxStaleness           int // seconds
+	WriteConcern           string
+	WriteConcernJournal    bool
+	WriteConcernTimeout    int // seconds
+	Compressors            []string
+
+	// commands taking longer are logged, 0 to disable
+	SlowQueryThresholdMs int
 }
 
 type DBConfigYaml struct {
@@ -22,4 +38,23 @@ type DBConfigYaml struct {
 	UseNoCursorTimeout bool   `yaml:"use_no_cursor_timeout"`
 	DBNamePrefix       string `yaml:"db_name_prefix"`
 	RunIndexCreation   bool   `yaml:"run_index_creation"`
+	RunMigrations      bool   `yaml:"run_migrations"`
+
+	MinPoolSize            int `yaml:"min_pool_size"`
+	MaxConnecting          int `yaml:"max_connecting"`
+	ConnectTimeout         int `yaml:"connect_timeout"`
+	ServerSelectionTimeout int `yaml:"server_selection_timeout"`
+	// primary, primaryPreferred, secondary, secondaryPreferred or nearest
+	ReadPreference string `yaml:"read_preference"`
+	// max replication lag of secondaries in seconds, at least 90
+	MaxStaleness int `yaml:"max_staleness"`
+	// "majority", number of nodes or name of a custom write concern
+	WriteConcern        string `yaml:"write_concern"`
+	WriteConcernJournal bool   `yaml:"write_concern_journal"`
+	WriteConcernTimeout int    `yaml:"write_concern_timeout"`
+	// snappy, zlib or zstd, in order of preference
+	Compressors []string `yaml:"compressors"`
+
+	// commands taking longer are logged as slow operations, 0 to disable
+	SlowQueryThresholdMs int `yaml:"slow_query_threshold_ms"`
 }
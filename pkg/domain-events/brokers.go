@@ -0,0 +1,333 @@
+package domainevents
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BROKER_TYPE_NATS       = "nats"
+	BROKER_TYPE_KAFKA_REST = "kafka-rest"
+	BROKER_TYPE_HTTP       = "http"
+)
+
+const (
+	defaultBrokerTimeout = 5 * time.Second
+	defaultSubjectPrefix = "case"
+
+	HEADER_EVENT = "X-Case-Event"
+)
+
+// Config of the domain event publication in service config files, events are sent to all brokers subscribed to them
+type Config struct {
+	Brokers   []BrokerConfig `json:"brokers" yaml:"brokers"`
+	QueueSize int            `json:"queue_size" yaml:"queue_size"`
+}
+
+type BrokerConfig struct {
+	// nats, kafka-rest or http
+	Type string `json:"type" yaml:"type"`
+	// events sent to this broker, all if empty
+	Events []string `json:"events" yaml:"events"`
+	// NATS subjects and Kafka topics are <prefix>.<event>, e.g. case.user.created, "case" if empty
+	SubjectPrefix string `json:"subject_prefix" yaml:"subject_prefix"`
+
+	// nats: host:port of the server, authenticated with a token or username and password
+	Address  string `json:"address" yaml:"address"`
+	TLS      bool   `json:"tls" yaml:"tls"`
+	Token    string `json:"token" yaml:"token"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+
+	// kafka-rest: base URL of a Kafka REST proxy (v2 API), http: endpoint each event is posted to as JSON
+	URL     string            `json:"url" yaml:"url"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+func (c BrokerConfig) subject(eventType string) string {
+	prefix := c.SubjectPrefix
+	if prefix == "" {
+		prefix = defaultSubjectPrefix
+	}
+	return prefix + "." + eventType
+}
+
+// Broker receives the events it accepts, calls of Publish are not concurrent
+type Broker interface {
+	Name() string
+	Accepts(eventType string) bool
+	Publish(event Event) error
+	Close() error
+}
+
+func NewBrokers(configs []BrokerConfig) ([]Broker, error) {
+	brokers := []Broker{}
+	for i, config := range configs {
+		broker, err := NewBroker(config)
+		if err != nil {
+			for _, b := range brokers {
+				b.Close()
+			}
+			return nil, fmt.Errorf("domain event broker %d: %w", i, err)
+		}
+		brokers = append(brokers, broker)
+	}
+	return brokers, nil
+}
+
+func NewBroker(config BrokerConfig) (Broker, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultBrokerTimeout
+	}
+	for _, event := range config.Events {
+		if !IsSupportedEvent(event) {
+			return nil, fmt.Errorf("unsupported event %s", event)
+		}
+	}
+
+	switch config.Type {
+	case BROKER_TYPE_NATS:
+		if config.Address == "" {
+			return nil, errors.New("address is required")
+		}
+		return &natsBroker{config: config}, nil
+	case BROKER_TYPE_KAFKA_REST:
+		if config.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		return &kafkaRESTBroker{config: config, client: &http.Client{Timeout: config.Timeout}}, nil
+	case BROKER_TYPE_HTTP:
+		if config.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		return &httpBroker{config: config, client: &http.Client{Timeout: config.Timeout}}, nil
+	default:
+		return nil, fmt.Errorf("unknown broker type %s", config.Type)
+	}
+}
+
+func accepts(config BrokerConfig, eventType string) bool {
+	return len(config.Events) == 0 || slices.Contains(config.Events, eventType)
+}
+
+// natsBroker speaks the text protocol of NATS core over one connection and reconnects after errors. Each publish is
+// followed by a PING, so that errors of the server are noticed before the next event.
+type natsBroker struct {
+	config BrokerConfig
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (b *natsBroker) Name() string { return BROKER_TYPE_NATS }
+
+func (b *natsBroker) Accepts(eventType string) bool { return accepts(b.config, eventType) }
+
+func (b *natsBroker) Publish(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := append([]byte(fmt.Sprintf("PUB %s %d\r\n", b.config.subject(event.Type), len(body))), body...)
+	msg = append(msg, "\r\nPING\r\n"...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// one retry with a new connection, the server may have closed an idle connection
+	for attempt := 0; attempt < 2; attempt++ {
+		if b.conn == nil {
+			if err = b.connect(); err != nil {
+				return err
+			}
+		}
+		_ = b.conn.SetDeadline(time.Now().Add(b.config.Timeout))
+		if _, err = b.conn.Write(msg); err == nil {
+			if err = b.awaitPong(); err == nil {
+				return nil
+			}
+		}
+		b.closeConn()
+	}
+	return err
+}
+
+func (b *natsBroker) connect() error {
+	conn, err := net.DialTimeout("tcp", b.config.Address, b.config.Timeout)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(b.config.Timeout))
+
+	// the server greets with its INFO, TLS is started after it
+	info, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected greeting from nats server: %s", strings.TrimSpace(info))
+	}
+	if b.config.TLS {
+		host, _, _ := net.SplitHostPort(b.config.Address)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+	}
+
+	options := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "case-backend",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if b.config.Token != "" {
+		options["auth_token"] = b.config.Token
+	}
+	if b.config.Username != "" {
+		options["user"] = b.config.Username
+		options["pass"] = b.config.Password
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	b.conn = conn
+	b.reader = bufio.NewReader(conn)
+	if _, err := conn.Write([]byte("CONNECT " + string(connect) + "\r\nPING\r\n")); err != nil {
+		b.closeConn()
+		return err
+	}
+	if err := b.awaitPong(); err != nil {
+		b.closeConn()
+		return err
+	}
+	return nil
+}
+
+// awaitPong reads until the PONG of the last PING, errors of the preceding commands arrive before it
+func (b *natsBroker) awaitPong() error {
+	for {
+		line, err := b.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := b.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (b *natsBroker) closeConn() {
+	if b.conn != nil {
+		b.conn.Close()
+	}
+	b.conn = nil
+	b.reader = nil
+}
+
+func (b *natsBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeConn()
+	return nil
+}
+
+// kafkaRESTBroker produces the events to Kafka through a REST proxy, keyed by instance so that the events of an
+// instance stay in order
+type kafkaRESTBroker struct {
+	config BrokerConfig
+	client *http.Client
+}
+
+func (b *kafkaRESTBroker) Name() string { return BROKER_TYPE_KAFKA_REST }
+
+func (b *kafkaRESTBroker) Accepts(eventType string) bool { return accepts(b.config, eventType) }
+
+func (b *kafkaRESTBroker) Publish(event Event) error {
+	body, err := json.Marshal(map[string]any{
+		"records": []map[string]any{{"key": event.InstanceID, "value": event}},
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimSuffix(b.config.URL, "/") + "/topics/" + url.PathEscape(b.config.subject(event.Type))
+	return post(b.client, endpoint, "application/vnd.kafka.json.v2+json", b.config.Headers, event.Type, body)
+}
+
+func (b *kafkaRESTBroker) Close() error {
+	return nil
+}
+
+// httpBroker posts each event as JSON, e.g. to the ingestion endpoint of a data pipeline
+type httpBroker struct {
+	config BrokerConfig
+	client *http.Client
+}
+
+func (b *httpBroker) Name() string { return BROKER_TYPE_HTTP }
+
+func (b *httpBroker) Accepts(eventType string) bool { return accepts(b.config, eventType) }
+
+func (b *httpBroker) Publish(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return post(b.client, b.config.URL, "application/json", b.config.Headers, event.Type, body)
+}
+
+func (b *httpBroker) Close() error {
+	return nil
+}
+
+func post(client *http.Client, endpoint string, contentType string, headers map[string]string, eventType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(HEADER_EVENT, eventType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("broker responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package domainevents publishes domain events (new users, submitted responses, sent emails, participant state
+// changes) to message brokers, so that external data pipelines can react in near real time without polling the
+// database. Unlike the webhooks, which are registered per instance in the management API, brokers are configured per
+// service and receive the events of all instances. Events only contain IDs, no personal data or response contents.
+package domainevents
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SCHEMA_VERSION is increased if fields are renamed or removed
+const SCHEMA_VERSION = 1
+
+const (
+	EVENT_USER_CREATED              = "user.created"
+	EVENT_RESPONSE_SUBMITTED        = "response.submitted"
+	EVENT_EMAIL_SENT                = "email.sent"
+	EVENT_PARTICIPANT_STATE_CHANGED = "participant.state_changed"
+)
+
+// Events brokers can subscribe to
+var SupportedEvents = []string{
+	EVENT_USER_CREATED,
+	EVENT_RESPONSE_SUBMITTED,
+	EVENT_EMAIL_SENT,
+	EVENT_PARTICIPANT_STATE_CHANGED,
+}
+
+const defaultQueueSize = 1000
+
+type Event struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	ID            string         `json:"id"`
+	Time          time.Time      `json:"time"`
+	Service       string         `json:"service"`
+	Type          string         `json:"type"`
+	InstanceID    string         `json:"instanceID"`
+	Data          map[string]any `json:"data"`
+}
+
+// Publisher sends events to its brokers in the background, so that slow brokers do not delay requests. Events are
+// dropped with a warning in the application log if the queue is full.
+type Publisher struct {
+	service string
+	brokers []Broker
+	queue   chan Event
+	dropped atomic.Int64
+	done    chan struct{}
+
+	// guards sending to the queue against closing it
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewPublisher(service string, brokers []Broker, queueSize int) *Publisher {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	p := &Publisher{
+		service: service,
+		brokers: brokers,
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *Publisher) Publish(event Event) {
+	event.SchemaVersion = SCHEMA_VERSION
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if event.Data == nil {
+		event.Data = map[string]any{}
+	}
+	event.Service = p.service
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- event:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+	for event := range p.queue {
+		if dropped := p.dropped.Swap(0); dropped > 0 {
+			slog.Warn("domain event queue full, events dropped", slog.Int64("count", dropped))
+		}
+
+		for _, broker := range p.brokers {
+			if !broker.Accepts(event.Type) {
+				continue
+			}
+			if err := broker.Publish(event); err != nil {
+				slog.Error("failed to publish domain event", slog.String("broker", broker.Name()), slog.String("type", event.Type), slog.String("instanceID", event.InstanceID), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Close publishes the queued events and closes the brokers, events published afterwards are lost
+func (p *Publisher) Close() {
+	defaultPublisher.CompareAndSwap(p, nil)
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	<-p.done
+	for _, broker := range p.brokers {
+		if err := broker.Close(); err != nil {
+			slog.Error("failed to close domain event broker", slog.String("broker", broker.Name()), slog.String("error", err.Error()))
+		}
+	}
+}
+
+var defaultPublisher atomic.Pointer[Publisher]
+
+// Init creates the brokers of the config and sets the publisher used by Publish. Without brokers events are
+// discarded.
+func Init(config Config, service string) (*Publisher, error) {
+	brokers, err := NewBrokers(config.Brokers)
+	if err != nil {
+		return nil, err
+	}
+	if len(brokers) == 0 {
+		return nil, nil
+	}
+	p := NewPublisher(service, brokers, config.QueueSize)
+	defaultPublisher.Store(p)
+	return p, nil
+}
+
+// Publish sends the event with the publisher set by Init
+func Publish(instanceID string, eventType string, data map[string]any) {
+	if p := defaultPublisher.Load(); p != nil {
+		p.Publish(Event{Type: eventType, InstanceID: instanceID, Data: data})
+	}
+}
+
+func IsSupportedEvent(event string) bool {
+	return slices.Contains(SupportedEvents, event)
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
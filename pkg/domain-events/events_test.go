@@ -0,0 +1,192 @@
+package domainevents
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memoryBroker struct {
+	subscribed []string
+	mu         sync.Mutex
+	events     []Event
+	closed     bool
+}
+
+func (b *memoryBroker) Name() string { return "memory" }
+
+func (b *memoryBroker) Accepts(eventType string) bool {
+	return accepts(BrokerConfig{Events: b.subscribed}, eventType)
+}
+
+func (b *memoryBroker) Publish(event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	return nil
+}
+
+func (b *memoryBroker) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	all := &memoryBroker{}
+	users := &memoryBroker{subscribed: []string{EVENT_USER_CREATED}}
+	p := NewPublisher("participant-api", []Broker{all, users}, 10)
+	defaultPublisher.Store(p)
+
+	Publish("i1", EVENT_USER_CREATED, map[string]any{"userId": "u1"})
+	Publish("i1", EVENT_RESPONSE_SUBMITTED, nil)
+	p.Close()
+	Publish("i1", EVENT_EMAIL_SENT, nil)
+
+	if len(all.events) != 2 || len(users.events) != 1 {
+		t.Fatalf("unexpected events %v, %v", all.events, users.events)
+	}
+	e := users.events[0]
+	if e.Type != EVENT_USER_CREATED || e.InstanceID != "i1" || e.Service != "participant-api" || e.ID == "" || e.SchemaVersion != SCHEMA_VERSION || e.Data["userId"] != "u1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if all.events[1].Data == nil {
+		t.Errorf("data should not be nil")
+	}
+	if !all.closed || !users.closed {
+		t.Errorf("brokers not closed")
+	}
+}
+
+func TestNewBroker(t *testing.T) {
+	if _, err := NewBroker(BrokerConfig{Type: BROKER_TYPE_NATS}); err == nil {
+		t.Errorf("expected error for missing address")
+	}
+	if _, err := NewBroker(BrokerConfig{Type: BROKER_TYPE_HTTP, URL: "http://localhost", Events: []string{"unknown"}}); err == nil {
+		t.Errorf("expected error for unsupported event")
+	}
+	if _, err := NewBroker(BrokerConfig{Type: "amqp"}); err == nil {
+		t.Errorf("expected error for unknown type")
+	}
+}
+
+func TestNATSBroker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	type published struct {
+		subject string
+		body    []byte
+	}
+	received := make(chan published, 2)
+	connects := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+				publishedOnConn := false
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch fields[0] {
+					case "CONNECT":
+						connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT "))
+					case "PING":
+						conn.Write([]byte("PONG\r\n"))
+						if publishedOnConn {
+							// closed like an idle connection, the next event needs a new one
+							return
+						}
+					case "PUB":
+						size, _ := strconv.Atoi(fields[2])
+						body := make([]byte, size+2)
+						if _, err := io.ReadFull(r, body); err != nil {
+							return
+						}
+						received <- published{subject: fields[1], body: body[:size]}
+						publishedOnConn = true
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	broker, err := NewBroker(BrokerConfig{Type: BROKER_TYPE_NATS, Address: listener.Addr().String(), Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+
+	for _, eventType := range []string{EVENT_USER_CREATED, EVENT_EMAIL_SENT} {
+		if err := broker.Publish(Event{Type: eventType, InstanceID: "i1"}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	connect := <-connects
+	if !strings.Contains(connect, `"auth_token":"secret"`) {
+		t.Errorf("unexpected connect %s", connect)
+	}
+	first := <-received
+	if first.subject != "case.user.created" {
+		t.Errorf("unexpected subject %s", first.subject)
+	}
+	var e Event
+	if err := json.Unmarshal(first.body, &e); err != nil || e.Type != EVENT_USER_CREATED {
+		t.Errorf("unexpected body %s", first.body)
+	}
+	if second := <-received; second.subject != "case.email.sent" {
+		t.Errorf("unexpected subject %s", second.subject)
+	}
+	if len(connects) != 1 {
+		t.Errorf("expected a reconnect, got %d more connects", len(connects))
+	}
+}
+
+func TestKafkaRESTBroker(t *testing.T) {
+	var path, contentType string
+	var body map[string][]struct {
+		Key   string `json:"key"`
+		Value Event  `json:"value"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	broker, err := NewBroker(BrokerConfig{Type: BROKER_TYPE_KAFKA_REST, URL: server.URL + "/", SubjectPrefix: "study-events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish(Event{Type: EVENT_PARTICIPANT_STATE_CHANGED, InstanceID: "i1"}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/topics/study-events.participant.state_changed" || contentType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("unexpected request %s %s", path, contentType)
+	}
+	records := body["records"]
+	if len(records) != 1 || records[0].Key != "i1" || records[0].Value.Type != EVENT_PARTICIPANT_STATE_CHANGED {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
@@ -0,0 +1,95 @@
+package fieldencryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	DATA_KEY_SIZE = 32 // AES-256
+
+	PROVIDER_LOCAL_KEYFILE = "local-keyfile"
+	PROVIDER_VAULT_TRANSIT = "vault-transit"
+)
+
+// KeyProvider wraps (encrypts) and unwraps data keys with a key encryption key that never leaves the provider (KMS) or the keyfile.
+type KeyProvider interface {
+	// WrapKey encrypts the data key and returns the wrapped key and the ID of the key encryption key used
+	WrapKey(dataKey []byte) (wrappedKey []byte, kekID string, err error)
+	UnwrapKey(wrappedKey []byte, kekID string) (dataKey []byte, err error)
+}
+
+type Config struct {
+	// Empty to disable encryption, otherwise "local-keyfile" or "vault-transit"
+	Provider string `json:"provider" yaml:"provider"`
+
+	// Path of a file containing the 32 byte key encryption key, hex or base64 encoded
+	LocalKeyfile string `json:"local_keyfile" yaml:"local_keyfile"`
+
+	VaultTransit VaultTransitConfig `json:"vault_transit" yaml:"vault_transit"`
+}
+
+// NewKeyProvider creates the provider of the config, or returns nil if encryption is disabled
+func NewKeyProvider(config Config) (KeyProvider, error) {
+	switch config.Provider {
+	case "":
+		return nil, nil
+	case PROVIDER_LOCAL_KEYFILE:
+		return NewLocalKeyfileProvider(config.LocalKeyfile)
+	case PROVIDER_VAULT_TRANSIT:
+		return NewVaultTransitProvider(config.VaultTransit)
+	default:
+		return nil, fmt.Errorf("unknown key provider: %s", config.Provider)
+	}
+}
+
+// GenerateDataKey returns a new random data key
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, DATA_KEY_SIZE)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt seals the plaintext with AES-GCM, the random nonce is prepended to the result.
+// The additional data is authenticated but not encrypted, and must be the same for decryption.
+func Encrypt(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func Decrypt(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != DATA_KEY_SIZE {
+		return nil, fmt.Errorf("key must be %d bytes", DATA_KEY_SIZE)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,179 @@
+package fieldencryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("confidential answer")
+
+	ciphertext, err := Encrypt(key, plaintext, []byte("key-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Error("ciphertext contains plaintext")
+	}
+
+	t.Run("roundtrip", func(t *testing.T) {
+		res, err := Decrypt(key, ciphertext, []byte("key-1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(res, plaintext) {
+			t.Errorf("unexpected plaintext: %s", res)
+		}
+	})
+
+	t.Run("wrong additional data", func(t *testing.T) {
+		if _, err := Decrypt(key, ciphertext, []byte("key-2")); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+		tampered := append([]byte{}, ciphertext...)
+		tampered[len(tampered)-1] ^= 1
+		if _, err := Decrypt(key, tampered, []byte("key-1")); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		if _, err := Encrypt([]byte("short"), plaintext, nil); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestLocalKeyfileProvider(t *testing.T) {
+	kek, _ := GenerateDataKey()
+	dir := t.TempDir()
+
+	hexPath := filepath.Join(dir, "hex.key")
+	if err := os.WriteFile(hexPath, []byte(hex.EncodeToString(kek)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b64Path := filepath.Join(dir, "b64.key")
+	if err := os.WriteFile(b64Path, []byte(base64.StdEncoding.EncodeToString(kek)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hexProvider, err := NewLocalKeyfileProvider(hexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64Provider, err := NewLocalKeyfileProvider(b64Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataKey, _ := GenerateDataKey()
+	wrapped, kekID, err := hexProvider.WrapKey(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("same key in other encoding unwraps", func(t *testing.T) {
+		res, err := b64Provider.UnwrapKey(wrapped, kekID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(res, dataKey) {
+			t.Error("unexpected data key")
+		}
+	})
+
+	t.Run("other key encryption key", func(t *testing.T) {
+		otherKek, _ := GenerateDataKey()
+		other := newLocalKeyProvider(otherKek)
+		if _, err := other.UnwrapKey(wrapped, kekID); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("invalid keyfile", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.key")
+		if err := os.WriteFile(path, []byte("not a key"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewLocalKeyfileProvider(path); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestVaultTransitProvider(t *testing.T) {
+	// fake transit engine: "encrypts" by prefixing the base64 plaintext
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/study-data":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]}})
+		case "/v1/transit/decrypt/study-data":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewKeyProvider(Config{
+		Provider:     PROVIDER_VAULT_TRANSIT,
+		VaultTransit: VaultTransitConfig{Address: server.URL, Token: "token", KeyName: "study-data"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataKey, _ := GenerateDataKey()
+	wrapped, kekID, err := provider.WrapKey(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kekID != "vault:study-data" {
+		t.Errorf("unexpected kek id: %s", kekID)
+	}
+	res, err := provider.UnwrapKey(wrapped, kekID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, dataKey) {
+		t.Error("unexpected data key")
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		p, _ := NewVaultTransitProvider(VaultTransitConfig{Address: server.URL, Token: "wrong", KeyName: "study-data"})
+		if _, _, err := p.WrapKey(dataKey); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	p, err := NewKeyProvider(Config{})
+	if err != nil || p != nil {
+		t.Errorf("expected disabled provider, got %v, %v", p, err)
+	}
+	if _, err := NewKeyProvider(Config{Provider: "unknown"}); err == nil {
+		t.Error("expected error")
+	}
+}
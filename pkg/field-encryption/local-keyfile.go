@@ -0,0 +1,67 @@
+package fieldencryption
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LocalKeyfileProvider wraps data keys with a key encryption key read from a local file
+type LocalKeyfileProvider struct {
+	kek   []byte
+	kekID string
+}
+
+func NewLocalKeyfileProvider(path string) (*LocalKeyfileProvider, error) {
+	if path == "" {
+		return nil, errors.New("keyfile path is required")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, err := decodeKey(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, err
+	}
+	return newLocalKeyProvider(kek), nil
+}
+
+func newLocalKeyProvider(kek []byte) *LocalKeyfileProvider {
+	// the ID identifies the key without revealing it, so rotated keyfiles can be detected
+	sum := sha256.Sum256(kek)
+	return &LocalKeyfileProvider{
+		kek:   kek,
+		kekID: "local:" + hex.EncodeToString(sum[:8]),
+	}
+}
+
+func (p *LocalKeyfileProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	wrapped, err := Encrypt(p.kek, dataKey, []byte(p.kekID))
+	if err != nil {
+		return nil, "", err
+	}
+	return wrapped, p.kekID, nil
+}
+
+func (p *LocalKeyfileProvider) UnwrapKey(wrappedKey []byte, kekID string) ([]byte, error) {
+	if kekID != p.kekID {
+		return nil, fmt.Errorf("data key was wrapped with a different key (%s)", kekID)
+	}
+	return Decrypt(p.kek, wrappedKey, []byte(kekID))
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == DATA_KEY_SIZE {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == DATA_KEY_SIZE {
+		return key, nil
+	}
+	return nil, fmt.Errorf("keyfile must contain a %d byte key, hex or base64 encoded", DATA_KEY_SIZE)
+}
@@ -0,0 +1,63 @@
+package filestore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidRef = errors.New("invalid file reference")
+
+// FileStore gives read access to generated files (e.g. exports or receipts) by their reference
+type FileStore interface {
+	// Open returns a reader for the referenced file, the caller has to close it
+	Open(ref string) (io.ReadCloser, error)
+	// Size returns the size of the referenced file in bytes
+	Size(ref string) (int64, error)
+}
+
+// LocalFileStore serves files from a directory, references are paths relative to the root
+type LocalFileStore struct {
+	Root string
+}
+
+func NewLocalFileStore(root string) *LocalFileStore {
+	return &LocalFileStore{Root: root}
+}
+
+// resolve returns the absolute path of the reference, rejecting paths outside of the root
+func (s *LocalFileStore) resolve(ref string) (string, error) {
+	if s.Root == "" || ref == "" || filepath.IsAbs(ref) {
+		return "", ErrInvalidRef
+	}
+	cleaned := filepath.Clean(ref)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidRef
+	}
+	return filepath.Join(s.Root, cleaned), nil
+}
+
+func (s *LocalFileStore) Open(ref string) (io.ReadCloser, error) {
+	path, err := s.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalFileStore) Size(ref string) (int64, error) {
+	path, err := s.resolve(ref)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir() {
+		return 0, ErrInvalidRef
+	}
+	return info.Size(), nil
+}
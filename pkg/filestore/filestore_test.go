@@ -0,0 +1,49 @@
+package filestore
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalFileStore(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "exports"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "exports", "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewLocalFileStore(root)
+
+	t.Run("read file", func(t *testing.T) {
+		size, err := store.Size("exports/a.txt")
+		if err != nil || size != 5 {
+			t.Fatalf("unexpected size %d: %v", size, err)
+		}
+		r, err := store.Open("exports/a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		content, _ := io.ReadAll(r)
+		if string(content) != "hello" {
+			t.Errorf("unexpected content: %s", content)
+		}
+	})
+
+	t.Run("reject paths outside of root", func(t *testing.T) {
+		for _, ref := range []string{"../a.txt", "exports/../../a.txt", "/etc/passwd", "", "."} {
+			if _, err := store.Open(ref); err != ErrInvalidRef {
+				t.Errorf("expected invalid ref for %s, got %v", ref, err)
+			}
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		if _, err := store.Size("exports"); err != ErrInvalidRef {
+			t.Errorf("expected invalid ref, got %v", err)
+		}
+	})
+}
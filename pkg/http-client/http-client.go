@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	"github.com/case-framework/case-backend/pkg/tracing"
 )
 
 type ClientConfig struct {
@@ -19,53 +22,65 @@ type ClientConfig struct {
 }
 
 func (cConfig ClientConfig) RunHTTPcall(pathname string, payload interface{}) (map[string]interface{}, error) {
+	res, _, err := cConfig.RunHTTPcallWithStatus(context.Background(), pathname, payload)
+	return res, err
+}
+
+// RunHTTPcallWithStatus posts the payload and returns the decoded response together with the http status code.
+// The status code is 0 if no response was received.
+func (cConfig ClientConfig) RunHTTPcallWithStatus(ctx context.Context, pathname string, payload interface{}) (map[string]interface{}, int, error) {
 	json_data, err := json.Marshal(payload)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	transport, err := getTransportWithMTLSConfig(cConfig.MutualTLSCertificatePaths)
 	if err != nil {
 		slog.Error("Error creating transport with mTLS config", slog.String("error", err.Error()))
-		return nil, err
+		return nil, 0, err
 	}
 
 	client := &http.Client{
-		Timeout: cConfig.Timeout,
+		Timeout:   cConfig.Timeout,
+		Transport: tracing.NewTransport(nil),
 	}
 	if transport != nil {
-		client.Transport = transport
+		client.Transport = tracing.NewTransport(transport)
 	}
 
 	url, err := url.JoinPath(cConfig.RootURL, pathname)
 	if err != nil {
 		slog.Error("unexpected error in joining url", slog.String("error", err.Error()))
-		return nil, err
+		return nil, 0, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(json_data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(json_data))
 	if err != nil {
 		slog.Error("unexpected error in preparing http request", slog.String("error", err.Error()))
-		return nil, err
+		return nil, 0, err
 	}
 	if cConfig.APIKey != "" {
 		req.Header.Set("Api-Key", cConfig.APIKey)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.HEADER, id)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
 		slog.Error("unexpected error in http call", slog.String("error", err.Error()))
-		return nil, err
+		return nil, 0, err
 	}
+	defer resp.Body.Close()
 
 	var res map[string]interface{}
 	err = json.NewDecoder(resp.Body).Decode(&res)
 	if err != nil {
 		slog.Error("Error decoding response", slog.String("error", err.Error()))
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
-	return res, nil
+	return res, resp.StatusCode, nil
 }
 
 func getTransportWithMTLSConfig(mTLSCertificatePaths *apihelpers.CertificatePaths) (*http.Transport, error) {
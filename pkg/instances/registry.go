@@ -0,0 +1,127 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+)
+
+const DEFAULT_RELOAD_INTERVAL = time.Minute
+
+// instance IDs are part of DB names, which are limited to 64 bytes including the prefix and suffix
+var instanceIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidateInstanceID checks that the ID can be used in DB names
+func ValidateInstanceID(instanceID string) error {
+	if !instanceIDPattern.MatchString(instanceID) {
+		return errors.New("instance ID must be 1-32 lowercase letters, digits, '-' or '_' and start with a letter or digit")
+	}
+	return nil
+}
+
+// InstanceSource lists the provisioned instances, implemented by the global infos DB service
+type InstanceSource interface {
+	GetInstances() ([]globalinfosDB.Instance, error)
+}
+
+// Registry holds the allowed instance IDs: the IDs of the service config and the active provisioned instances.
+// Archived instances are not allowed, even if they are listed in the config.
+type Registry struct {
+	staticIDs []string
+	source    InstanceSource
+
+	mu  sync.RWMutex
+	ids []string
+}
+
+// NewRegistry allows the static IDs until Reload is called. source can be nil to only use the static IDs.
+func NewRegistry(staticIDs []string, source InstanceSource) *Registry {
+	r := &Registry{
+		staticIDs: staticIDs,
+		source:    source,
+	}
+	r.ids = mergeInstanceIDs(staticIDs, nil)
+	return r
+}
+
+// Reload reads the provisioned instances, the current list is kept on errors
+func (r *Registry) Reload() error {
+	if r.source == nil {
+		return nil
+	}
+	instances, err := r.source.GetInstances()
+	if err != nil {
+		return err
+	}
+
+	ids := mergeInstanceIDs(r.staticIDs, instances)
+	r.mu.Lock()
+	r.ids = ids
+	r.mu.Unlock()
+	return nil
+}
+
+// Run reloads the registry in the given interval until the context is done
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DEFAULT_RELOAD_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				slog.Error("failed to reload instances", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (r *Registry) IsAllowed(instanceID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, id := range r.ids {
+		if id == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceIDs returns a copy of the allowed IDs
+func (r *Registry) InstanceIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string{}, r.ids...)
+}
+
+func mergeInstanceIDs(staticIDs []string, instances []globalinfosDB.Instance) []string {
+	status := map[string]string{}
+	for _, id := range staticIDs {
+		status[id] = globalinfosDB.INSTANCE_STATUS_ACTIVE
+	}
+	for _, instance := range instances {
+		status[instance.InstanceID] = instance.Status
+	}
+
+	ids := []string{}
+	for id, s := range status {
+		if s == globalinfosDB.INSTANCE_STATUS_ACTIVE {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,72 @@
+package instances
+
+import (
+	"errors"
+	"testing"
+
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+)
+
+type fakeSource struct {
+	instances []globalinfosDB.Instance
+	err       error
+}
+
+func (f *fakeSource) GetInstances() ([]globalinfosDB.Instance, error) {
+	return f.instances, f.err
+}
+
+func TestRegistry(t *testing.T) {
+	source := &fakeSource{}
+	r := NewRegistry([]string{"default", "legacy"}, source)
+
+	if !r.IsAllowed("default") || r.IsAllowed("new") {
+		t.Fatal("unexpected initial instances")
+	}
+
+	source.instances = []globalinfosDB.Instance{
+		{InstanceID: "new", Status: globalinfosDB.INSTANCE_STATUS_ACTIVE},
+		{InstanceID: "legacy", Status: globalinfosDB.INSTANCE_STATUS_ARCHIVED},
+		{InstanceID: "old", Status: globalinfosDB.INSTANCE_STATUS_ARCHIVED},
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	ids := r.InstanceIDs()
+	if len(ids) != 2 || ids[0] != "default" || ids[1] != "new" {
+		t.Errorf("unexpected instances %v", ids)
+	}
+	if r.IsAllowed("legacy") {
+		t.Error("archived instance from the config should not be allowed")
+	}
+
+	t.Run("keeps list on errors", func(t *testing.T) {
+		source.err = errors.New("db error")
+		if err := r.Reload(); err == nil {
+			t.Error("expected error")
+		}
+		if !r.IsAllowed("new") {
+			t.Error("list changed after failed reload")
+		}
+	})
+
+	t.Run("without source", func(t *testing.T) {
+		r := NewRegistry([]string{"default"}, nil)
+		if err := r.Reload(); err != nil || !r.IsAllowed("default") {
+			t.Error("static instances not allowed")
+		}
+	})
+}
+
+func TestValidateInstanceID(t *testing.T) {
+	for _, id := range []string{"default", "study-1", "a_b"} {
+		if err := ValidateInstanceID(id); err != nil {
+			t.Errorf("%s should be valid", id)
+		}
+	}
+	for _, id := range []string{"", "Upper", "-start", "with.dot", "with space", "a234567890123456789012345678901234"} {
+		if err := ValidateInstanceID(id); err == nil {
+			t.Errorf("%s should be invalid", id)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package internalapi
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type authenticator struct {
+	useMTLS    bool
+	jwtSignKey string
+	clients    map[string][]string
+}
+
+func newAuthenticator(conf Config) *authenticator {
+	return &authenticator{
+		useMTLS:    conf.MTLS.Use,
+		jwtSignKey: conf.ServiceJWTSignKey,
+		clients:    conf.Clients,
+	}
+}
+
+// authenticate returns the name of the client, from its verified client certificate or its service JWT
+func (a *authenticator) authenticate(ctx context.Context) (string, error) {
+	if a.useMTLS {
+		if p, ok := peer.FromContext(ctx); ok {
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+				for _, chain := range tlsInfo.State.VerifiedChains {
+					if len(chain) > 0 && chain[0].Subject.CommonName != "" {
+						return chain[0].Subject.CommonName, nil
+					}
+				}
+			}
+		}
+	}
+
+	if a.jwtSignKey != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, value := range md.Get("authorization") {
+			token, ok := strings.CutPrefix(value, "Bearer ")
+			if !ok {
+				continue
+			}
+			claims, valid, err := jwthandling.ValidateServiceToken(token, a.jwtSignKey)
+			if err == nil && valid && claims.Subject != "" {
+				return claims.Subject, nil
+			}
+		}
+	}
+	return "", status.Error(codes.Unauthenticated, "client certificate or service token required")
+}
+
+// unaryInterceptor rejects calls of unknown clients and calls for instances the client may not access
+func (a *authenticator) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	client, err := a.authenticate(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "unauthenticated internal API call", slog.String("method", info.FullMethod))
+		return nil, err
+	}
+
+	instanceID := ""
+	if r, ok := req.(interface{ GetInstanceId() string }); ok {
+		instanceID = r.GetInstanceId()
+	}
+	if instanceID == "" || !slices.Contains(a.clients[client], instanceID) {
+		slog.WarnContext(ctx, "internal API call for instance not allowed", slog.String("method", info.FullMethod), slog.String("client", client), slog.String("instanceID", instanceID))
+		return nil, status.Error(codes.PermissionDenied, "instance not allowed")
+	}
+
+	slog.DebugContext(ctx, "internal API call", slog.String("method", info.FullMethod), slog.String("client", client), slog.String("instanceID", instanceID))
+	return handler(ctx, req)
+}
@@ -0,0 +1,121 @@
+package internalapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	internalapiv1 "github.com/case-framework/case-backend/pkg/internal-api/v1"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const testSignKey = "test-sign-key"
+
+func testAuthenticator() *authenticator {
+	conf := Config{ServiceJWTSignKey: testSignKey, Clients: map[string][]string{
+		"survey-engine": {"instance1"},
+	}}
+	conf.MTLS.Use = true
+	return newAuthenticator(conf)
+}
+
+func ctxWithToken(t *testing.T, serviceName string, signKey string) context.Context {
+	token, err := jwthandling.GenerateNewServiceToken(time.Minute, serviceName, signKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func ctxWithClientCert(commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	a := testAuthenticator()
+	info := &grpc.UnaryServerInfo{FullMethod: "/case.internal.v1.InternalService/GetUser"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		instanceID string
+		wantCode   codes.Code
+	}{
+		{name: "service token", ctx: ctxWithToken(t, "survey-engine", testSignKey), instanceID: "instance1", wantCode: codes.OK},
+		{name: "client certificate", ctx: ctxWithClientCert("survey-engine"), instanceID: "instance1", wantCode: codes.OK},
+		{name: "no credentials", ctx: context.Background(), instanceID: "instance1", wantCode: codes.Unauthenticated},
+		{name: "token with wrong key", ctx: ctxWithToken(t, "survey-engine", "other-key"), instanceID: "instance1", wantCode: codes.Unauthenticated},
+		{name: "unknown client", ctx: ctxWithClientCert("unknown"), instanceID: "instance1", wantCode: codes.PermissionDenied},
+		{name: "instance not allowed", ctx: ctxWithToken(t, "survey-engine", testSignKey), instanceID: "instance2", wantCode: codes.PermissionDenied},
+		{name: "missing instance", ctx: ctxWithToken(t, "survey-engine", testSignKey), instanceID: "", wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &internalapiv1.GetUserRequest{InstanceId: tt.instanceID}
+			_, err := a.unaryInterceptor(tt.ctx, req, info, handler)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("expected %v, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithoutMTLS(t *testing.T) {
+	a := newAuthenticator(Config{ServiceJWTSignKey: testSignKey})
+	if _, err := a.authenticate(ctxWithClientCert("survey-engine")); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("client certificate must be ignored when mTLS is off, got %v", err)
+	}
+}
+
+func TestAuthenticationModes(t *testing.T) {
+	clients := map[string][]string{"survey-engine": {"instance1"}}
+	certOnly := Config{Clients: clients}
+	certOnly.MTLS.Use = true
+	jwtOnly := Config{ServiceJWTSignKey: testSignKey, Clients: clients}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/case.internal.v1.InternalService/GetUser"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name     string
+		conf     Config
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{name: "cert only: client certificate", conf: certOnly, ctx: ctxWithClientCert("survey-engine"), wantCode: codes.OK},
+		{name: "cert only: service token rejected", conf: certOnly, ctx: ctxWithToken(t, "survey-engine", testSignKey), wantCode: codes.Unauthenticated},
+		{name: "cert only: no credentials", conf: certOnly, ctx: context.Background(), wantCode: codes.Unauthenticated},
+		{name: "jwt only: service token", conf: jwtOnly, ctx: ctxWithToken(t, "survey-engine", testSignKey), wantCode: codes.OK},
+		{name: "jwt only: client certificate rejected", conf: jwtOnly, ctx: ctxWithClientCert("survey-engine"), wantCode: codes.Unauthenticated},
+		{name: "jwt only: token without bearer prefix", conf: jwtOnly, ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "token")), wantCode: codes.Unauthenticated},
+		{name: "jwt only: unknown client", conf: jwtOnly, ctx: ctxWithToken(t, "unknown", testSignKey), wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &internalapiv1.GetUserRequest{InstanceId: "instance1"}
+			_, err := newAuthenticator(tt.conf).unaryInterceptor(tt.ctx, req, info, handler)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("expected %v, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package internalapi
+
+import "github.com/case-framework/case-backend/pkg/apihelpers"
+
+// Config of the internal gRPC API. Clients authenticate with an mTLS client certificate or a service JWT, at least
+// one of them has to be set up. The server always uses TLS.
+type Config struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Port    string `json:"port" yaml:"port"`
+
+	// Client certificates are verified against the CA, the common name of the certificate identifies the client
+	MTLS struct {
+		Use              bool                        `json:"use" yaml:"use"`
+		CertificatePaths apihelpers.CertificatePaths `json:"certificate_paths" yaml:"certificate_paths"`
+	} `json:"mtls" yaml:"mtls"`
+
+	// Key of the service JWTs sent as "authorization: Bearer <token>" metadata, the subject identifies the client.
+	// Service JWTs are not accepted if empty.
+	ServiceJWTSignKey string `json:"service_jwt_sign_key" yaml:"service_jwt_sign_key"`
+
+	// Server certificate for service JWT clients if mTLS is not used, bearer tokens are never accepted in plaintext
+	TLS struct {
+		ServerCertPath string `json:"server_cert_path" yaml:"server_cert_path"`
+		ServerKeyPath  string `json:"server_key_path" yaml:"server_key_path"`
+	} `json:"tls" yaml:"tls"`
+
+	// Instance IDs each client may access, by client name
+	Clients map[string][]string `json:"clients" yaml:"clients"`
+}
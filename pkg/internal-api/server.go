@@ -0,0 +1,240 @@
+package internalapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	internalapiv1 "github.com/case-framework/case-backend/pkg/internal-api/v1"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+type userStore interface {
+	GetUser(instanceID, objectID string) (umTypes.User, error)
+	GetUserByAccountID(instanceID, accountID string) (umTypes.User, error)
+}
+
+type emailQueue interface {
+	AddToOutgoingEmails(instanceID string, email messagingTypes.OutgoingEmail) (messagingTypes.OutgoingEmail, error)
+}
+
+type submitResponseFn func(ctx context.Context, instanceID string, studyKey string, id string, response studyTypes.SurveyResponse) ([]studyTypes.AssignedSurvey, error)
+
+// Server implements the internal API with the study service and the DB services of the participant API
+type Server struct {
+	internalapiv1.UnimplementedInternalServiceServer
+
+	users  userStore
+	emails emailQueue
+	// the study service functions, replaced in tests
+	submitResponse     submitResponseFn
+	submitTempResponse submitResponseFn
+}
+
+func NewServer(users userStore, emails emailQueue) *Server {
+	return &Server{
+		users:              users,
+		emails:             emails,
+		submitResponse:     studyService.OnSubmitResponse,
+		submitTempResponse: studyService.OnSubmitResponseForTempParticipant,
+	}
+}
+
+// Serve starts the internal API and stops it gracefully when the context is done. The returned channel is closed
+// once the server is stopped and all pending calls are finished.
+func Serve(ctx context.Context, conf Config, server *Server) (<-chan struct{}, error) {
+	creds, err := serverCredentials(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.UnaryInterceptor(newAuthenticator(conf).unaryInterceptor),
+	)
+	internalapiv1.RegisterInternalServiceServer(grpcServer, server)
+
+	listener, err := net.Listen("tcp", ":"+conf.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+		close(done)
+	}()
+	go func() {
+		slog.Info("Starting internal API on port " + conf.Port)
+		if err := grpcServer.Serve(listener); err != nil {
+			slog.Error("Exited internal API", slog.String("error", err.Error()))
+		}
+	}()
+	return done, nil
+}
+
+// serverCredentials returns the TLS credentials of the server, service JWTs are bearer tokens and must not be sent in plaintext
+func serverCredentials(conf Config) (credentials.TransportCredentials, error) {
+	switch {
+	case conf.MTLS.Use:
+		tlsConfig, err := apihelpers.LoadTLSConfig(conf.MTLS.CertificatePaths)
+		if err != nil {
+			return nil, err
+		}
+		if conf.ServiceJWTSignKey != "" {
+			// clients without certificate authenticate with a service JWT
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		return credentials.NewTLS(tlsConfig), nil
+	case conf.ServiceJWTSignKey != "":
+		if conf.TLS.ServerCertPath == "" || conf.TLS.ServerKeyPath == "" {
+			return nil, errors.New("service_jwt_sign_key without TLS: a server certificate is required")
+		}
+		serverCert, err := tls.LoadX509KeyPair(conf.TLS.ServerCertPath, conf.TLS.ServerKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		return credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			MinVersion:   tls.VersionTLS12,
+		}), nil
+	default:
+		return nil, errors.New("mtls or service_jwt_sign_key is required")
+	}
+}
+
+func (s *Server) SubmitResponse(ctx context.Context, req *internalapiv1.SubmitResponseRequest) (*internalapiv1.SubmitResponseReply, error) {
+	if req.StudyKey == "" || len(req.ResponseJson) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "study_key and response_json are required")
+	}
+	var response studyTypes.SurveyResponse
+	if err := json.Unmarshal(req.ResponseJson, &response); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid response_json")
+	}
+
+	if requestid.Valid(req.RequestId) {
+		ctx = requestid.NewContext(ctx, req.RequestId)
+	} else {
+		ctx = requestid.NewContext(ctx, requestid.New())
+	}
+
+	var surveys []studyTypes.AssignedSurvey
+	var err error
+	switch {
+	case req.ParticipantId != "" && req.UserId == "" && req.ProfileId == "":
+		surveys, err = s.submitTempResponse(ctx, req.InstanceId, req.StudyKey, req.ParticipantId, response)
+	case req.ParticipantId == "" && req.UserId != "" && req.ProfileId != "":
+		if !s.profileBelongsToUser(req.InstanceId, req.UserId, req.ProfileId) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		surveys, err = s.submitResponse(ctx, req.InstanceId, req.StudyKey, req.ProfileId, response)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "either user_id and profile_id or participant_id is required")
+	}
+	if err != nil {
+		var validationErr *studyService.ResponseValidationError
+		if errors.As(err, &validationErr) {
+			return nil, status.Error(codes.InvalidArgument, "response is not valid")
+		}
+		slog.ErrorContext(ctx, "error submitting response via internal API", slog.String("instanceID", req.InstanceId), slog.String("studyKey", req.StudyKey), slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, "error submitting response")
+	}
+
+	surveysJSON, err := json.Marshal(surveys)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "error encoding assigned surveys")
+	}
+	return &internalapiv1.SubmitResponseReply{SurveysJson: surveysJSON}, nil
+}
+
+func (s *Server) profileBelongsToUser(instanceID string, userID string, profileID string) bool {
+	user, err := s.users.GetUser(instanceID, userID)
+	if err != nil {
+		return false
+	}
+	for _, profile := range user.Profiles {
+		if profile.ID.Hex() == profileID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) GetUser(ctx context.Context, req *internalapiv1.GetUserRequest) (*internalapiv1.User, error) {
+	var user umTypes.User
+	var err error
+	switch {
+	case req.GetUserId() != "":
+		user, err = s.users.GetUser(req.InstanceId, req.GetUserId())
+	case req.GetAccountEmail() != "":
+		user, err = s.users.GetUserByAccountID(req.InstanceId, req.GetAccountEmail())
+	default:
+		return nil, status.Error(codes.InvalidArgument, "user_id or account_email is required")
+	}
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		slog.ErrorContext(ctx, "error getting user via internal API", slog.String("instanceID", req.InstanceId), slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, "error getting user")
+	}
+
+	reply := &internalapiv1.User{
+		Id:                user.ID.Hex(),
+		AccountEmail:      user.Account.AccountID,
+		AccountConfirmed:  user.Account.AccountConfirmedAt > 0,
+		PreferredLanguage: user.Account.PreferredLanguage,
+		CreatedAt:         user.Timestamps.CreatedAt,
+		LastLogin:         user.Timestamps.LastLogin,
+	}
+	for _, profile := range user.Profiles {
+		reply.Profiles = append(reply.Profiles, &internalapiv1.Profile{
+			Id:          profile.ID.Hex(),
+			Alias:       profile.Alias,
+			MainProfile: profile.MainProfile,
+		})
+	}
+	return reply, nil
+}
+
+func (s *Server) EnqueueEmail(ctx context.Context, req *internalapiv1.EnqueueEmailRequest) (*internalapiv1.EnqueueEmailReply, error) {
+	if len(req.To) == 0 || req.MessageType == "" || req.Subject == "" || req.Content == "" {
+		return nil, status.Error(codes.InvalidArgument, "to, message_type, subject and content are required")
+	}
+	if req.ExpiresIn < 0 {
+		return nil, status.Error(codes.InvalidArgument, "expires_in must not be negative")
+	}
+
+	email := messagingTypes.OutgoingEmail{
+		MessageType: req.MessageType,
+		To:          req.To,
+		Subject:     req.Subject,
+		Content:     req.Content,
+		HighPrio:    req.HighPrio,
+	}
+	if req.ExpiresIn > 0 {
+		email.ExpiresAt = time.Now().Unix() + req.ExpiresIn
+	}
+
+	email, err := s.emails.AddToOutgoingEmails(req.InstanceId, email)
+	if err != nil {
+		slog.ErrorContext(ctx, "error enqueueing email via internal API", slog.String("instanceID", req.InstanceId), slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, "error enqueueing email")
+	}
+	return &internalapiv1.EnqueueEmailReply{EmailId: email.ID.Hex()}, nil
+}
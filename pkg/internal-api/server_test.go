@@ -0,0 +1,376 @@
+package internalapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	internalapiv1 "github.com/case-framework/case-backend/pkg/internal-api/v1"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeUserStore struct {
+	users []umTypes.User
+}
+
+func (f *fakeUserStore) GetUser(instanceID, objectID string) (umTypes.User, error) {
+	for _, u := range f.users {
+		if u.ID.Hex() == objectID {
+			return u, nil
+		}
+	}
+	return umTypes.User{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeUserStore) GetUserByAccountID(instanceID, accountID string) (umTypes.User, error) {
+	for _, u := range f.users {
+		if u.Account.AccountID == accountID {
+			return u, nil
+		}
+	}
+	return umTypes.User{}, mongo.ErrNoDocuments
+}
+
+type fakeEmailQueue struct {
+	emails []messagingTypes.OutgoingEmail
+}
+
+func (f *fakeEmailQueue) AddToOutgoingEmails(instanceID string, email messagingTypes.OutgoingEmail) (messagingTypes.OutgoingEmail, error) {
+	email.ID = primitive.NewObjectID()
+	f.emails = append(f.emails, email)
+	return email, nil
+}
+
+func testUser() umTypes.User {
+	user := umTypes.User{ID: primitive.NewObjectID()}
+	user.Account.AccountID = "user@example.com"
+	user.Account.AccountConfirmedAt = 1
+	user.Profiles = []umTypes.Profile{{ID: primitive.NewObjectID(), Alias: "main", MainProfile: true}}
+	return user
+}
+
+// startTestServer serves the internal API over an in-memory connection and returns a client authenticated with a service token
+func startTestServer(t *testing.T, server *Server) internalapiv1.InternalServiceClient {
+	conf := Config{ServiceJWTSignKey: testSignKey, Clients: map[string][]string{"survey-engine": {"instance1"}}}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(newAuthenticator(conf).unaryInterceptor))
+	internalapiv1.RegisterInternalServiceServer(grpcServer, server)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return internalapiv1.NewInternalServiceClient(conn)
+}
+
+func authCtx(t *testing.T) context.Context {
+	token, err := jwthandling.GenerateNewServiceToken(time.Minute, "survey-engine", testSignKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestGetUser(t *testing.T) {
+	user := testUser()
+	client := startTestServer(t, NewServer(&fakeUserStore{users: []umTypes.User{user}}, &fakeEmailQueue{}))
+
+	t.Run("by id", func(t *testing.T) {
+		reply, err := client.GetUser(authCtx(t), &internalapiv1.GetUserRequest{InstanceId: "instance1", Lookup: &internalapiv1.GetUserRequest_UserId{UserId: user.ID.Hex()}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if reply.AccountEmail != "user@example.com" || !reply.AccountConfirmed || len(reply.Profiles) != 1 || !reply.Profiles[0].MainProfile {
+			t.Errorf("unexpected user: %v", reply)
+		}
+	})
+
+	t.Run("by email", func(t *testing.T) {
+		reply, err := client.GetUser(authCtx(t), &internalapiv1.GetUserRequest{InstanceId: "instance1", Lookup: &internalapiv1.GetUserRequest_AccountEmail{AccountEmail: "user@example.com"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if reply.Id != user.ID.Hex() {
+			t.Errorf("unexpected user id: %s", reply.Id)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := client.GetUser(authCtx(t), &internalapiv1.GetUserRequest{InstanceId: "instance1", Lookup: &internalapiv1.GetUserRequest_AccountEmail{AccountEmail: "other@example.com"}})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected not found, got %v", err)
+		}
+	})
+
+	t.Run("without token", func(t *testing.T) {
+		_, err := client.GetUser(context.Background(), &internalapiv1.GetUserRequest{InstanceId: "instance1", Lookup: &internalapiv1.GetUserRequest_UserId{UserId: user.ID.Hex()}})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected unauthenticated, got %v", err)
+		}
+	})
+}
+
+func TestEnqueueEmail(t *testing.T) {
+	emails := &fakeEmailQueue{}
+	client := startTestServer(t, NewServer(&fakeUserStore{}, emails))
+
+	t.Run("missing fields", func(t *testing.T) {
+		_, err := client.EnqueueEmail(authCtx(t), &internalapiv1.EnqueueEmailRequest{InstanceId: "instance1", To: []string{"user@example.com"}})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected invalid argument, got %v", err)
+		}
+	})
+
+	t.Run("enqueued", func(t *testing.T) {
+		reply, err := client.EnqueueEmail(authCtx(t), &internalapiv1.EnqueueEmailRequest{
+			InstanceId:  "instance1",
+			MessageType: "reminder",
+			To:          []string{"user@example.com"},
+			Subject:     "Reminder",
+			Content:     "<p>Hello</p>",
+			HighPrio:    true,
+			ExpiresIn:   3600,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(emails.emails) != 1 || emails.emails[0].ID.Hex() != reply.EmailId {
+			t.Fatalf("email not enqueued: %v", emails.emails)
+		}
+		if !emails.emails[0].HighPrio || emails.emails[0].ExpiresAt <= time.Now().Unix() {
+			t.Errorf("unexpected email: %v", emails.emails[0])
+		}
+	})
+}
+
+func TestSubmitResponse(t *testing.T) {
+	user := testUser()
+	server := NewServer(&fakeUserStore{users: []umTypes.User{user}}, &fakeEmailQueue{})
+	var submittedFor string
+	server.submitResponse = func(ctx context.Context, instanceID, studyKey, id string, response studyTypes.SurveyResponse) ([]studyTypes.AssignedSurvey, error) {
+		submittedFor = id
+		if response.Key == "invalid" {
+			return nil, &studyService.ResponseValidationError{}
+		}
+		return []studyTypes.AssignedSurvey{{SurveyKey: "weekly"}}, nil
+	}
+	server.submitTempResponse = func(ctx context.Context, instanceID, studyKey, id string, response studyTypes.SurveyResponse) ([]studyTypes.AssignedSurvey, error) {
+		submittedFor = id
+		return nil, errors.New("db error")
+	}
+	client := startTestServer(t, server)
+
+	responseJSON, _ := json.Marshal(studyTypes.SurveyResponse{Key: "intake"})
+	profileID := user.Profiles[0].ID.Hex()
+
+	t.Run("submitted for profile", func(t *testing.T) {
+		reply, err := client.SubmitResponse(authCtx(t), &internalapiv1.SubmitResponseRequest{
+			InstanceId: "instance1", StudyKey: "study1", UserId: user.ID.Hex(), ProfileId: profileID, ResponseJson: responseJSON,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var surveys []studyTypes.AssignedSurvey
+		if err := json.Unmarshal(reply.SurveysJson, &surveys); err != nil || len(surveys) != 1 || surveys[0].SurveyKey != "weekly" {
+			t.Errorf("unexpected surveys: %s", reply.SurveysJson)
+		}
+		if submittedFor != profileID {
+			t.Errorf("submitted for %s, expected %s", submittedFor, profileID)
+		}
+	})
+
+	t.Run("profile of other user", func(t *testing.T) {
+		_, err := client.SubmitResponse(authCtx(t), &internalapiv1.SubmitResponseRequest{
+			InstanceId: "instance1", StudyKey: "study1", UserId: user.ID.Hex(), ProfileId: primitive.NewObjectID().Hex(), ResponseJson: responseJSON,
+		})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected not found, got %v", err)
+		}
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		invalidJSON, _ := json.Marshal(studyTypes.SurveyResponse{Key: "invalid"})
+		_, err := client.SubmitResponse(authCtx(t), &internalapiv1.SubmitResponseRequest{
+			InstanceId: "instance1", StudyKey: "study1", UserId: user.ID.Hex(), ProfileId: profileID, ResponseJson: invalidJSON,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected invalid argument, got %v", err)
+		}
+	})
+
+	t.Run("ambiguous participant", func(t *testing.T) {
+		_, err := client.SubmitResponse(authCtx(t), &internalapiv1.SubmitResponseRequest{
+			InstanceId: "instance1", StudyKey: "study1", UserId: user.ID.Hex(), ParticipantId: "temp1", ResponseJson: responseJSON,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected invalid argument, got %v", err)
+		}
+	})
+
+	t.Run("temporary participant error", func(t *testing.T) {
+		_, err := client.SubmitResponse(authCtx(t), &internalapiv1.SubmitResponseRequest{
+			InstanceId: "instance1", StudyKey: "study1", ParticipantId: "temp1", ResponseJson: responseJSON,
+		})
+		if status.Code(err) != codes.Internal || submittedFor != "temp1" {
+			t.Errorf("expected internal error for temporary participant, got %v", err)
+		}
+	})
+}
+
+// writeTestServerCert writes a self-signed certificate for localhost and returns its paths and the pool trusting it
+func writeTestServerCert(t *testing.T) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "server.crt")
+	keyPath := filepath.Join(dir, "server.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return certPath, keyPath, pool
+}
+
+func TestServerCredentials(t *testing.T) {
+	certPath, keyPath, _ := writeTestServerCert(t)
+
+	t.Run("service JWT without TLS", func(t *testing.T) {
+		if _, err := serverCredentials(Config{ServiceJWTSignKey: testSignKey}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("service JWT with TLS", func(t *testing.T) {
+		conf := Config{ServiceJWTSignKey: testSignKey}
+		conf.TLS.ServerCertPath = certPath
+		conf.TLS.ServerKeyPath = keyPath
+		creds, err := serverCredentials(conf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if creds.Info().SecurityProtocol != "tls" {
+			t.Errorf("unexpected security protocol: %s", creds.Info().SecurityProtocol)
+		}
+	})
+
+	t.Run("no authentication", func(t *testing.T) {
+		if _, err := serverCredentials(Config{}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestServe(t *testing.T) {
+	certPath, keyPath, pool := writeTestServerCert(t)
+
+	// reserve a free port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	conf := Config{Port: port, ServiceJWTSignKey: testSignKey, Clients: map[string][]string{"survey-engine": {"instance1"}}}
+	conf.TLS.ServerCertPath = certPath
+	conf.TLS.ServerKeyPath = keyPath
+
+	user := testUser()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped, err := Serve(ctx, conf, NewServer(&fakeUserStore{users: []umTypes.User{user}}, &fakeEmailQueue{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conn, err := grpc.NewClient("127.0.0.1:"+port, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool})))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	callCtx, callCancel := context.WithTimeout(authCtx(t), 5*time.Second)
+	defer callCancel()
+	reply, err := internalapiv1.NewInternalServiceClient(conn).GetUser(callCtx, &internalapiv1.GetUserRequest{
+		InstanceId: "instance1",
+		Lookup:     &internalapiv1.GetUserRequest_UserId{UserId: user.ID.Hex()},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply.Id != user.ID.Hex() {
+		t.Errorf("unexpected user: %s", reply.Id)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("server not stopped after the context is done")
+	}
+}
@@ -0,0 +1,176 @@
+package jobscheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+)
+
+const (
+	ALERT_EVENT_JOB_FAILED = "job.failed"
+	ALERT_EVENT_JOB_SLOW   = "job.slow"
+)
+
+const defaultAlertWebhookTimeout = 10 * time.Second
+
+// AlertConfig sends alerts about failed and slow job runs, to the webhook and by email if configured
+type AlertConfig struct {
+	// runs taking longer are reported once while still running, not checked if not set. Can be set per job.
+	DurationThreshold time.Duration `json:"duration_threshold" yaml:"duration_threshold"`
+
+	Webhook AlertWebhookConfig `json:"webhook" yaml:"webhook"`
+	Email   AlertEmailConfig   `json:"email" yaml:"email"`
+}
+
+type AlertWebhookConfig struct {
+	URL string `json:"url" yaml:"url"`
+	// the body is signed like messaging webhooks, in the X-Case-Signature header, if set
+	Secret  string        `json:"secret" yaml:"secret"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+type AlertEmailConfig struct {
+	To []string `json:"to" yaml:"to"`
+	// the smtp bridge is not available to the scheduler, one of the direct providers must be used
+	Provider messagingTypes.EmailProviderConfig `json:"provider" yaml:"provider"`
+}
+
+// Alert is posted as JSON to the webhook
+type Alert struct {
+	Event       string    `json:"event"`
+	Job         string    `json:"job"`
+	RunID       string    `json:"runId,omitempty"`
+	Owner       string    `json:"owner"`
+	Status      string    `json:"status,omitempty"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	StartedAt   time.Time `json:"startedAt"`
+	Duration    string    `json:"duration"`
+	ExitCode    int       `json:"exitCode"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Alerter delivers alerts, failures are logged
+type Alerter interface {
+	Alert(alert Alert)
+}
+
+type alerter struct {
+	webhook       AlertWebhookConfig
+	webhookClient *http.Client
+	emailTo       []string
+	emailProvider emailsending.EmailProvider
+}
+
+// NewAlerter returns nil if neither a webhook nor email recipients are configured
+func NewAlerter(config AlertConfig) (Alerter, error) {
+	a := &alerter{webhook: config.Webhook}
+	if config.Webhook.URL != "" {
+		timeout := config.Webhook.Timeout
+		if timeout <= 0 {
+			timeout = defaultAlertWebhookTimeout
+		}
+		a.webhookClient = &http.Client{Timeout: timeout}
+	}
+
+	if len(config.Email.To) > 0 {
+		if config.Email.Provider.Provider == "" || config.Email.Provider.Provider == emailsending.EMAIL_PROVIDER_SMTP_BRIDGE {
+			return nil, errors.New("alert emails need an email provider other than the smtp bridge")
+		}
+		provider, err := emailsending.NewEmailProvider(config.Email.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("alert email provider: %w", err)
+		}
+		a.emailTo = config.Email.To
+		a.emailProvider = provider
+	}
+
+	if a.webhookClient == nil && a.emailProvider == nil {
+		return nil, nil
+	}
+	return a, nil
+}
+
+func (a *alerter) Alert(alert Alert) {
+	if a.webhookClient != nil {
+		if err := a.postWebhook(alert); err != nil {
+			slog.Error("failed to send job alert webhook", slog.String("job", alert.Job), slog.String("error", err.Error()))
+		}
+	}
+	if a.emailProvider != nil {
+		subject, content := alertEmail(alert)
+		err := a.emailProvider.Send(&messagingTypes.OutgoingEmail{
+			MessageType: "job-alert",
+			To:          a.emailTo,
+			Subject:     subject,
+			Content:     content,
+			HighPrio:    true,
+		})
+		if err != nil {
+			slog.Error("failed to send job alert email", slog.String("job", alert.Job), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (a *alerter) postWebhook(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooks.HEADER_EVENT, alert.Event)
+	if a.webhook.Secret != "" {
+		req.Header.Set(webhooks.HEADER_SIGNATURE, webhooks.Sign(a.webhook.Secret, time.Now().Unix(), body))
+	}
+
+	resp, err := a.webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func alertEmail(alert Alert) (subject string, content string) {
+	if alert.Event == ALERT_EVENT_JOB_SLOW {
+		subject = fmt.Sprintf("Job %s running for %s", alert.Job, alert.Duration)
+	} else {
+		subject = fmt.Sprintf("Job %s %s", alert.Job, alert.Status)
+	}
+
+	var b strings.Builder
+	b.WriteString("<p>" + html.EscapeString(subject) + "</p><ul>")
+	for _, line := range [][2]string{
+		{"Run", alert.RunID},
+		{"Scheduler", alert.Owner},
+		{"Scheduled at", alert.ScheduledAt.Format(time.RFC3339)},
+		{"Started at", alert.StartedAt.Format(time.RFC3339)},
+		{"Duration", alert.Duration},
+		{"Error", alert.Error},
+	} {
+		if line[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "<li>%s: %s</li>", line[0], html.EscapeString(line[1]))
+	}
+	b.WriteString("</ul>")
+	return subject, b.String()
+}
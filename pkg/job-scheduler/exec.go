@@ -0,0 +1,84 @@
+package jobscheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// time a cancelled job gets to exit after SIGTERM before it is killed
+const terminateGracePeriod = 30 * time.Second
+
+// ExecCommand runs the job binary. The output is forwarded to the output of the scheduler, the end of it is kept for
+// the run history together with the report of the job.
+func ExecCommand(ctx context.Context, job JobConfig, outputLength int) Result {
+	reportFile, err := os.CreateTemp("", "job-report-*.json")
+	if err != nil {
+		return Result{ExitCode: -1, Err: err}
+	}
+	reportFile.Close()
+	defer os.Remove(reportFile.Name())
+
+	cmd := exec.CommandContext(ctx, job.Command, job.Args...)
+	cmd.Dir = job.WorkingDir
+	cmd.Env = os.Environ()
+	for key, value := range job.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Env = append(cmd.Env, ENV_JOB_REPORT_FILE+"="+reportFile.Name())
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = terminateGracePeriod
+
+	output := &tailBuffer{limit: outputLength}
+	cmd.Stdout = io.MultiWriter(os.Stdout, output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, output)
+
+	err = cmd.Run()
+	result := Result{Output: output.String(), Err: err}
+
+	instances, reportErr := ReadReport(reportFile.Name())
+	if reportErr != nil {
+		slog.Error("failed to read job report", slog.String("job", job.Name), slog.String("error", reportErr.Error()))
+	}
+	result.Instances = instances
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		result.ExitCode = -1
+	}
+	return result
+}
+
+// tailBuffer keeps the last bytes written to it
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = append([]byte{}, b.data[len(b.data)-b.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
@@ -0,0 +1,33 @@
+package jobscheduler
+
+import (
+	"sync/atomic"
+
+	"github.com/case-framework/case-backend/pkg/metrics"
+)
+
+// jobs run from seconds to hours
+var jobDurationBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200, 21600}
+
+var (
+	jobRuns = metrics.DefaultRegistry.NewCounter(
+		"scheduler_job_runs_total", "Job runs of this scheduler replica by job and status.",
+		"job", "status",
+	)
+	jobRunDuration = metrics.DefaultRegistry.NewHistogram(
+		"scheduler_job_run_duration_seconds", "Duration of the job runs of this scheduler replica by job.",
+		jobDurationBuckets, "job",
+	)
+	jobRunProcessed = metrics.DefaultRegistry.NewCounter(
+		"scheduler_job_processed_total", "Counts reported by the jobs by job, instance and counter.",
+		"job", "instance_id", "counter",
+	)
+
+	runningJobs atomic.Int64
+)
+
+func init() {
+	metrics.DefaultRegistry.NewGaugeFunc("scheduler_jobs_running", "Jobs currently running on this scheduler replica.", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(runningJobs.Load())}}
+	})
+}
@@ -0,0 +1,108 @@
+package jobscheduler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+)
+
+// ENV_JOB_REPORT_FILE is set by the scheduler for the jobs it starts. Jobs write what they processed there, so that it
+// is kept in the run history.
+const ENV_JOB_REPORT_FILE = "JOB_REPORT_FILE"
+
+// errors kept per instance, further errors are only counted
+const maxReportedErrors = 20
+
+// Report collects the counts and errors of a job run by instance
+type Report struct {
+	mu        sync.Mutex
+	instances []*globalinfosDB.JobRunInstanceStats
+}
+
+func NewReport() *Report {
+	return &Report{}
+}
+
+func (r *Report) instance(instanceID string) *globalinfosDB.JobRunInstanceStats {
+	for _, stats := range r.instances {
+		if stats.InstanceID == instanceID {
+			return stats
+		}
+	}
+	stats := &globalinfosDB.JobRunInstanceStats{InstanceID: instanceID, Counts: map[string]int64{}}
+	r.instances = append(r.instances, stats)
+	return stats
+}
+
+// Add increases a counter of the instance, e.g. Add(instanceID, "sentEmails", 1)
+func (r *Report) Add(instanceID string, counter string, count int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instance(instanceID).Counts[counter] += count
+}
+
+// Error records a failure of the job for the instance
+func (r *Report) Error(instanceID string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.instance(instanceID)
+	stats.ErrorCount += 1
+	if len(stats.Errors) < maxReportedErrors {
+		stats.Errors = append(stats.Errors, err.Error())
+	}
+}
+
+// Instances returns a copy of the collected stats
+func (r *Report) Instances() []globalinfosDB.JobRunInstanceStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instances := make([]globalinfosDB.JobRunInstanceStats, 0, len(r.instances))
+	for _, stats := range r.instances {
+		counts := make(map[string]int64, len(stats.Counts))
+		for k, v := range stats.Counts {
+			counts[k] = v
+		}
+		instances = append(instances, globalinfosDB.JobRunInstanceStats{
+			InstanceID: stats.InstanceID,
+			Counts:     counts,
+			ErrorCount: stats.ErrorCount,
+			Errors:     append([]string{}, stats.Errors...),
+		})
+	}
+	return instances
+}
+
+// Write saves the report for the scheduler, does nothing if the job was not started by the scheduler. Errors are
+// logged, they do not fail the job.
+func (r *Report) Write() {
+	path := os.Getenv(ENV_JOB_REPORT_FILE)
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(r.Instances())
+	if err == nil {
+		err = os.WriteFile(path, data, 0600)
+	}
+	if err != nil {
+		slog.Error("failed to write job report", slog.String("path", path), slog.String("error", err.Error()))
+	}
+}
+
+// ReadReport reads the report written by a job, an empty or missing file means the job did not report anything
+func ReadReport(path string) ([]globalinfosDB.JobRunInstanceStats, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	instances := []globalinfosDB.JobRunInstanceStats{}
+	if err := json.Unmarshal(data, &instances); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
@@ -0,0 +1,446 @@
+package jobscheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	"github.com/case-framework/case-backend/pkg/utils/cron"
+)
+
+const (
+	defaultLockTTL       = time.Minute
+	defaultJobTimeout    = 6 * time.Hour
+	defaultOutputLength  = 8 * 1024
+	defaultShutdownWait  = time.Minute
+	historyPruneInterval = time.Hour
+)
+
+// JobConfig registers a job binary with its schedule
+type JobConfig struct {
+	Name string `json:"name" yaml:"name"`
+	// five field cron expression, e.g. "*/5 * * * *"
+	Schedule string `json:"schedule" yaml:"schedule"`
+	// IANA timezone of the schedule, UTC if not set
+	Timezone string `json:"timezone" yaml:"timezone"`
+
+	Command string   `json:"command" yaml:"command"`
+	Args    []string `json:"args" yaml:"args"`
+	// added to the environment of the scheduler, e.g. CONFIG_FILE_PATH of the job
+	Env        map[string]string `json:"env" yaml:"env"`
+	WorkingDir string            `json:"working_dir" yaml:"working_dir"`
+
+	// the job is killed after the timeout, 6h if not set
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// alert if a run takes longer, overrides the duration threshold of the alerts config
+	AlertAfter time.Duration `json:"alert_after" yaml:"alert_after"`
+}
+
+// Config of the scheduler
+type Config struct {
+	Jobs []JobConfig `json:"jobs" yaml:"jobs"`
+	// lease of the job locks, renewed while a job is running, 1m if not set
+	LockTTL time.Duration `json:"lock_ttl" yaml:"lock_ttl"`
+	// bytes of the job output kept in the run history, 8KB if not set
+	OutputLength int `json:"output_length" yaml:"output_length"`
+	// run history entries older than this are removed, kept forever if not set
+	HistoryRetention time.Duration `json:"history_retention" yaml:"history_retention"`
+	// time running jobs get to finish on shutdown before they are killed, 1m if not set
+	ShutdownWait time.Duration `json:"shutdown_wait" yaml:"shutdown_wait"`
+	// alerts about failed and slow runs
+	Alerts AlertConfig `json:"alerts" yaml:"alerts"`
+}
+
+// Store keeps the job locks and the run history, shared by all replicas of the scheduler
+type Store interface {
+	AcquireJobLock(job string, owner string, scheduledAt time.Time, leaseUntil time.Time) (bool, error)
+	RenewJobLock(job string, owner string, leaseUntil time.Time) (bool, error)
+	ReleaseJobLock(job string, owner string) error
+	CreateJobRun(run globalinfosDB.JobRun) (globalinfosDB.JobRun, error)
+	FinishJobRun(run globalinfosDB.JobRun) error
+	DeleteJobRunsBefore(before time.Time) (int64, error)
+}
+
+// Result of a job run
+type Result struct {
+	ExitCode int
+	Output   string
+	Err      error
+	// reported by the job
+	Instances []globalinfosDB.JobRunInstanceStats
+}
+
+// Executor runs a job until it finishes or the context is done
+type Executor func(ctx context.Context, job JobConfig, outputLength int) Result
+
+type scheduledJob struct {
+	config   JobConfig
+	schedule *cron.CronSchedule
+	location *time.Location
+	next     time.Time
+}
+
+// Scheduler runs the registered jobs on their schedules, a job is started by one replica per scheduled time
+type Scheduler struct {
+	config   Config
+	store    Store
+	owner    string
+	executor Executor
+	alerter  Alerter
+	now      func() time.Time
+
+	jobs    []*scheduledJob
+	running sync.Map
+	wg      sync.WaitGroup
+
+	// cancels the context of running jobs after the shutdown wait
+	killCtx   context.Context
+	killJobs  context.CancelFunc
+	lastPrune time.Time
+}
+
+// New validates the job configs, the owner identifies this replica in the locks and the run history
+func New(config Config, store Store, owner string, executor Executor) (*Scheduler, error) {
+	if config.LockTTL <= 0 {
+		config.LockTTL = defaultLockTTL
+	}
+	if config.OutputLength <= 0 {
+		config.OutputLength = defaultOutputLength
+	}
+	if config.ShutdownWait <= 0 {
+		config.ShutdownWait = defaultShutdownWait
+	}
+	if executor == nil {
+		executor = ExecCommand
+	}
+
+	alerter, err := NewAlerter(config.Alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	killCtx, killJobs := context.WithCancel(context.Background())
+	s := &Scheduler{
+		config:   config,
+		store:    store,
+		owner:    owner,
+		executor: executor,
+		alerter:  alerter,
+		now:      time.Now,
+		killCtx:  killCtx,
+		killJobs: killJobs,
+	}
+
+	names := map[string]bool{}
+	for _, job := range config.Jobs {
+		if job.Name == "" {
+			return nil, errors.New("job name is missing")
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("duplicate job name %s", job.Name)
+		}
+		names[job.Name] = true
+		if job.Command == "" {
+			return nil, fmt.Errorf("job %s: command is missing", job.Name)
+		}
+		schedule, err := cron.ParseCron(job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		loc := time.UTC
+		if job.Timezone != "" {
+			loc, err = time.LoadLocation(job.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("job %s: invalid timezone: %w", job.Name, err)
+			}
+		}
+		s.jobs = append(s.jobs, &scheduledJob{config: job, schedule: schedule, location: loc})
+	}
+	return s, nil
+}
+
+// Run starts the due jobs until the context is done, then waits for the running jobs up to the shutdown wait and
+// kills them afterwards. Runs missed while no replica was running are not caught up.
+func (s *Scheduler) Run(ctx context.Context) {
+	now := s.now()
+	for _, job := range s.jobs {
+		s.scheduleNext(job, now)
+	}
+
+	for {
+		wait := time.Minute
+		if next, ok := s.nextDue(); ok {
+			wait = time.Until(next)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.shutdown()
+			return
+		case <-timer.C:
+		}
+
+		now := s.now()
+		for _, job := range s.jobs {
+			if job.next.IsZero() || job.next.After(now) {
+				continue
+			}
+			scheduledAt := job.next
+			s.scheduleNext(job, now)
+			s.start(job.config, scheduledAt)
+		}
+		s.pruneHistory(now)
+	}
+}
+
+func (s *Scheduler) shutdown() {
+	slog.Info("scheduler stopped, waiting for running jobs", slog.Duration("timeout", s.config.ShutdownWait))
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(s.config.ShutdownWait):
+		slog.Warn("killing running jobs")
+		s.killJobs()
+		<-done
+	}
+	s.killJobs()
+}
+
+func (s *Scheduler) nextDue() (time.Time, bool) {
+	var next time.Time
+	for _, job := range s.jobs {
+		if job.next.IsZero() {
+			continue
+		}
+		if next.IsZero() || job.next.Before(next) {
+			next = job.next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (s *Scheduler) scheduleNext(job *scheduledJob, after time.Time) {
+	next, err := job.schedule.Next(after.In(job.location))
+	if err != nil {
+		slog.Error("failed to compute next run", slog.String("job", job.config.Name), slog.String("error", err.Error()))
+		job.next = time.Time{}
+		return
+	}
+	job.next = next
+	slog.Debug("next job run", slog.String("job", job.config.Name), slog.Time("at", next))
+}
+
+// start runs the job in the background, a job still running from its previous schedule is skipped
+func (s *Scheduler) start(job JobConfig, scheduledAt time.Time) {
+	if _, running := s.running.LoadOrStore(job.Name, true); running {
+		slog.Warn("job still running, skipping scheduled run", slog.String("job", job.Name), slog.Time("scheduledAt", scheduledAt))
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.running.Delete(job.Name)
+		s.runJob(job, scheduledAt)
+	}()
+}
+
+// runJob takes the lock, runs the job and records its outcome in the history
+func (s *Scheduler) runJob(job JobConfig, scheduledAt time.Time) {
+	acquired, err := s.store.AcquireJobLock(job.Name, s.owner, scheduledAt, s.now().Add(s.config.LockTTL))
+	if err != nil {
+		slog.Error("failed to acquire job lock", slog.String("job", job.Name), slog.String("error", err.Error()))
+		return
+	}
+	if !acquired {
+		slog.Debug("job run taken by another replica", slog.String("job", job.Name), slog.Time("scheduledAt", scheduledAt))
+		return
+	}
+	defer func() {
+		if err := s.store.ReleaseJobLock(job.Name, s.owner); err != nil {
+			slog.Error("failed to release job lock", slog.String("job", job.Name), slog.String("error", err.Error()))
+		}
+	}()
+
+	runningJobs.Add(1)
+	defer runningJobs.Add(-1)
+
+	run := globalinfosDB.JobRun{
+		Job:         job.Name,
+		Owner:       s.owner,
+		ScheduledAt: scheduledAt,
+		StartedAt:   s.now(),
+		Status:      globalinfosDB.JOB_RUN_STATUS_RUNNING,
+	}
+	if created, err := s.store.CreateJobRun(run); err != nil {
+		// the job is run anyway, only its history entry is missing
+		slog.Error("failed to record job run", slog.String("job", job.Name), slog.String("error", err.Error()))
+	} else {
+		run = created
+	}
+
+	slog.Info("starting job", slog.String("job", job.Name), slog.Time("scheduledAt", scheduledAt))
+	start := s.now()
+
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = defaultJobTimeout
+	}
+	jobCtx, cancel := context.WithTimeout(s.killCtx, timeout)
+	defer cancel()
+	lockLost := s.keepLock(jobCtx, job.Name, cancel)
+	stopSlowAlert := s.alertIfSlow(job, run)
+	defer stopSlowAlert()
+
+	result := s.executor(jobCtx, job, s.config.OutputLength)
+
+	run.Status = globalinfosDB.JOB_RUN_STATUS_SUCCESS
+	switch {
+	case errors.Is(jobCtx.Err(), context.DeadlineExceeded):
+		run.Status = globalinfosDB.JOB_RUN_STATUS_TIMEOUT
+		run.Error = "job timed out after " + timeout.String()
+	case lockLost():
+		run.Status = globalinfosDB.JOB_RUN_STATUS_FAILED
+		run.Error = "job lock lost"
+	case result.Err != nil:
+		run.Status = globalinfosDB.JOB_RUN_STATUS_FAILED
+		run.Error = result.Err.Error()
+	}
+	finishedAt := s.now()
+	run.FinishedAt = &finishedAt
+	run.ExitCode = result.ExitCode
+	run.Output = result.Output
+	run.Instances = result.Instances
+	duration := finishedAt.Sub(start)
+
+	jobRuns.Inc(job.Name, run.Status)
+	jobRunDuration.Observe(duration.Seconds(), job.Name)
+	for _, stats := range run.Instances {
+		for counter, count := range stats.Counts {
+			jobRunProcessed.Add(float64(count), job.Name, stats.InstanceID, counter)
+		}
+		if stats.ErrorCount > 0 {
+			jobRunProcessed.Add(float64(stats.ErrorCount), job.Name, stats.InstanceID, "errors")
+		}
+	}
+
+	logAttrs := []any{slog.String("job", job.Name), slog.String("status", run.Status), slog.Int("exitCode", result.ExitCode), slog.String("duration", duration.String())}
+	if run.Status == globalinfosDB.JOB_RUN_STATUS_SUCCESS {
+		slog.Info("job finished", logAttrs...)
+	} else {
+		slog.Error("job failed", append(logAttrs, slog.String("error", run.Error))...)
+		s.alert(ALERT_EVENT_JOB_FAILED, run, duration)
+	}
+
+	if run.ID.IsZero() {
+		return
+	}
+	if err := s.store.FinishJobRun(run); err != nil {
+		slog.Error("failed to record job outcome", slog.String("job", job.Name), slog.String("error", err.Error()))
+	}
+}
+
+// alertIfSlow sends an alert once the run takes longer than the threshold of the job, the returned function stops the
+// check
+func (s *Scheduler) alertIfSlow(job JobConfig, run globalinfosDB.JobRun) (stop func()) {
+	threshold := job.AlertAfter
+	if threshold <= 0 {
+		threshold = s.config.Alerts.DurationThreshold
+	}
+	if threshold <= 0 || s.alerter == nil {
+		return func() {}
+	}
+
+	timer := time.AfterFunc(threshold, func() {
+		slog.Warn("job exceeds duration threshold", slog.String("job", job.Name), slog.Duration("threshold", threshold))
+		s.alert(ALERT_EVENT_JOB_SLOW, run, threshold)
+	})
+	return func() { timer.Stop() }
+}
+
+func (s *Scheduler) alert(event string, run globalinfosDB.JobRun, duration time.Duration) {
+	if s.alerter == nil {
+		return
+	}
+	alert := Alert{
+		Event:       event,
+		Job:         run.Job,
+		Owner:       s.owner,
+		Status:      run.Status,
+		ScheduledAt: run.ScheduledAt,
+		StartedAt:   run.StartedAt,
+		Duration:    duration.Round(time.Second).String(),
+		ExitCode:    run.ExitCode,
+		Error:       run.Error,
+	}
+	if !run.ID.IsZero() {
+		alert.RunID = run.ID.Hex()
+	}
+	s.alerter.Alert(alert)
+}
+
+// keepLock renews the lease until the context is done. If the lock is lost, the job is cancelled, so that it does not
+// run twice.
+func (s *Scheduler) keepLock(ctx context.Context, job string, cancel context.CancelFunc) (lost func() bool) {
+	var mu sync.Mutex
+	isLost := false
+
+	go func() {
+		ticker := time.NewTicker(s.config.LockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			renewed, err := s.store.RenewJobLock(job, s.owner, s.now().Add(s.config.LockTTL))
+			if err != nil {
+				// the lease is still valid for a while, the next renewal can succeed
+				slog.Error("failed to renew job lock", slog.String("job", job), slog.String("error", err.Error()))
+				continue
+			}
+			if !renewed {
+				slog.Error("job lock lost, stopping job", slog.String("job", job))
+				mu.Lock()
+				isLost = true
+				mu.Unlock()
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return isLost
+	}
+}
+
+// pruneHistory removes old run history entries, at most once an hour
+func (s *Scheduler) pruneHistory(now time.Time) {
+	if s.config.HistoryRetention <= 0 || now.Sub(s.lastPrune) < historyPruneInterval {
+		return
+	}
+	s.lastPrune = now
+	count, err := s.store.DeleteJobRunsBefore(now.Add(-s.config.HistoryRetention))
+	if err != nil {
+		slog.Error("failed to prune job run history", slog.String("error", err.Error()))
+		return
+	}
+	if count > 0 {
+		slog.Info("pruned job run history", slog.Int64("count", count))
+	}
+}
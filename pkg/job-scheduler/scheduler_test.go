@@ -0,0 +1,306 @@
+package jobscheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memStore implements the lock semantics of the DB on a map
+type memStore struct {
+	mu    sync.Mutex
+	locks map[string]globalinfosDB.JobLock
+	runs  map[primitive.ObjectID]globalinfosDB.JobRun
+}
+
+func newMemStore() *memStore {
+	return &memStore{locks: map[string]globalinfosDB.JobLock{}, runs: map[primitive.ObjectID]globalinfosDB.JobRun{}}
+}
+
+func (m *memStore) AcquireJobLock(job string, owner string, scheduledAt time.Time, leaseUntil time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, exists := m.locks[job]
+	if exists {
+		free := lock.Owner == owner || lock.LeaseUntil.Before(time.Now())
+		if !free || !lock.LastScheduledAt.Before(scheduledAt) {
+			return false, nil
+		}
+	}
+	m.locks[job] = globalinfosDB.JobLock{Job: job, Owner: owner, LeaseUntil: leaseUntil, LastScheduledAt: scheduledAt}
+	return true, nil
+}
+
+func (m *memStore) RenewJobLock(job string, owner string, leaseUntil time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, exists := m.locks[job]
+	if !exists || lock.Owner != owner {
+		return false, nil
+	}
+	lock.LeaseUntil = leaseUntil
+	m.locks[job] = lock
+	return true, nil
+}
+
+func (m *memStore) ReleaseJobLock(job string, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lock, exists := m.locks[job]; exists && lock.Owner == owner {
+		lock.LeaseUntil = time.Now().Add(-time.Millisecond)
+		m.locks[job] = lock
+	}
+	return nil
+}
+
+func (m *memStore) CreateJobRun(run globalinfosDB.JobRun) (globalinfosDB.JobRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run.ID = primitive.NewObjectID()
+	run.Status = globalinfosDB.JOB_RUN_STATUS_RUNNING
+	m.runs[run.ID] = run
+	return run, nil
+}
+
+func (m *memStore) FinishJobRun(run globalinfosDB.JobRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[run.ID] = run
+	return nil
+}
+
+func (m *memStore) DeleteJobRunsBefore(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+type alertRecorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (a *alertRecorder) Alert(alert Alert) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.alerts = append(a.alerts, alert)
+}
+
+func (a *alertRecorder) events() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	events := []string{}
+	for _, alert := range a.alerts {
+		events = append(events, alert.Event)
+	}
+	return events
+}
+
+func (m *memStore) onlyRun(t *testing.T) globalinfosDB.JobRun {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.runs) != 1 {
+		t.Fatalf("expected one run, got %d", len(m.runs))
+	}
+	for _, run := range m.runs {
+		return run
+	}
+	return globalinfosDB.JobRun{}
+}
+
+func TestNew(t *testing.T) {
+	valid := JobConfig{Name: "study-timer", Schedule: "*/5 * * * *", Command: "study-timer"}
+
+	if _, err := New(Config{Jobs: []JobConfig{valid}}, newMemStore(), "a", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invalid := map[string][]JobConfig{
+		"duplicate name":   {valid, valid},
+		"invalid cron":     {{Name: "j", Schedule: "* * *", Command: "j"}},
+		"invalid timezone": {{Name: "j", Schedule: "* * * * *", Timezone: "Mars/Olympus", Command: "j"}},
+		"missing command":  {{Name: "j", Schedule: "* * * * *"}},
+	}
+	for name, jobs := range invalid {
+		if _, err := New(Config{Jobs: jobs}, newMemStore(), "a", nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	job := JobConfig{Name: "messaging", Schedule: "* * * * *", Command: "messaging"}
+	scheduledAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("one replica runs a scheduled time", func(t *testing.T) {
+		store := newMemStore()
+		var runs int32
+		executor := func(ctx context.Context, job JobConfig, outputLength int) Result {
+			atomic.AddInt32(&runs, 1)
+			return Result{Output: "done"}
+		}
+		a, _ := New(Config{Jobs: []JobConfig{job}}, store, "replica-a", executor)
+		b, _ := New(Config{Jobs: []JobConfig{job}}, store, "replica-b", executor)
+
+		a.runJob(job, scheduledAt)
+		b.runJob(job, scheduledAt)
+		if runs != 1 {
+			t.Errorf("job ran %d times", runs)
+		}
+		run := store.onlyRun(t)
+		if run.Status != globalinfosDB.JOB_RUN_STATUS_SUCCESS || run.Owner != "replica-a" || run.Output != "done" {
+			t.Errorf("unexpected run: %+v", run)
+		}
+
+		// the next scheduled time can be run by any replica
+		b.runJob(job, scheduledAt.Add(time.Minute))
+		if runs != 2 {
+			t.Errorf("next run not started")
+		}
+	})
+
+	t.Run("records failures and timeouts", func(t *testing.T) {
+		store := newMemStore()
+		s, _ := New(Config{}, store, "a", func(ctx context.Context, job JobConfig, outputLength int) Result {
+			return Result{ExitCode: 2, Err: errors.New("exit status 2")}
+		})
+		s.runJob(job, scheduledAt)
+		if run := store.onlyRun(t); run.Status != globalinfosDB.JOB_RUN_STATUS_FAILED || run.ExitCode != 2 {
+			t.Errorf("unexpected run: %+v", run)
+		}
+
+		store = newMemStore()
+		s, _ = New(Config{}, store, "a", func(ctx context.Context, job JobConfig, outputLength int) Result {
+			<-ctx.Done()
+			return Result{Err: ctx.Err()}
+		})
+		timedOut := job
+		timedOut.Timeout = 10 * time.Millisecond
+		s.runJob(timedOut, scheduledAt)
+		if run := store.onlyRun(t); run.Status != globalinfosDB.JOB_RUN_STATUS_TIMEOUT {
+			t.Errorf("unexpected run: %+v", run)
+		}
+	})
+}
+
+func TestRunJobAlerts(t *testing.T) {
+	job := JobConfig{Name: "export", Schedule: "* * * * *", Command: "export", AlertAfter: 10 * time.Millisecond}
+	scheduledAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	store := newMemStore()
+	s, _ := New(Config{}, store, "a", func(ctx context.Context, job JobConfig, outputLength int) Result {
+		time.Sleep(50 * time.Millisecond)
+		return Result{ExitCode: 1, Err: errors.New("exit status 1")}
+	})
+	alerts := &alertRecorder{}
+	s.alerter = alerts
+
+	s.runJob(job, scheduledAt)
+	if events := alerts.events(); strings.Join(events, ",") != ALERT_EVENT_JOB_SLOW+","+ALERT_EVENT_JOB_FAILED {
+		t.Errorf("unexpected alerts: %v", events)
+	}
+	if alert := alerts.alerts[1]; alert.Job != "export" || alert.RunID == "" || alert.Error != "exit status 1" {
+		t.Errorf("unexpected alert: %+v", alert)
+	}
+
+	// successful runs within the threshold are not reported
+	alerts.alerts = nil
+	s.executor = func(ctx context.Context, job JobConfig, outputLength int) Result {
+		return Result{}
+	}
+	job.AlertAfter = time.Second
+	s.runJob(job, scheduledAt.Add(time.Minute))
+	if events := alerts.events(); len(events) > 0 {
+		t.Errorf("unexpected alerts: %v", events)
+	}
+}
+
+func TestWebhookAlert(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer server.Close()
+
+	a, err := NewAlerter(AlertConfig{Webhook: AlertWebhookConfig{URL: server.URL, Secret: "secret"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.Alert(Alert{Event: ALERT_EVENT_JOB_FAILED, Job: "messaging"})
+
+	r := <-received
+	if r.Header.Get(webhooks.HEADER_EVENT) != ALERT_EVENT_JOB_FAILED || r.Header.Get(webhooks.HEADER_SIGNATURE) == "" {
+		t.Errorf("unexpected headers: %v", r.Header)
+	}
+
+	if a, _ := NewAlerter(AlertConfig{}); a != nil {
+		t.Error("expected no alerter without webhook and email")
+	}
+	if _, err := NewAlerter(AlertConfig{Email: AlertEmailConfig{To: []string{"ops@example.com"}}}); err == nil {
+		t.Error("expected error for the smtp bridge")
+	}
+}
+
+func TestReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	t.Setenv(ENV_JOB_REPORT_FILE, path)
+
+	report := NewReport()
+	report.Add("inst1", "sentEmails", 2)
+	report.Add("inst1", "sentEmails", 3)
+	report.Error("inst2", errors.New("db down"))
+	report.Write()
+
+	instances, err := ReadReport(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(instances) != 2 || instances[0].Counts["sentEmails"] != 5 || instances[1].ErrorCount != 1 || instances[1].Errors[0] != "db down" {
+		t.Errorf("unexpected report: %+v", instances)
+	}
+
+	if instances, err := ReadReport(filepath.Join(t.TempDir(), "missing.json")); err != nil || instances != nil {
+		t.Errorf("expected empty report: %v %v", instances, err)
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	result := ExecCommand(context.Background(), JobConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo $JOB_GREETING; exit 3"},
+		Env:     map[string]string{"JOB_GREETING": "hello"},
+	}, 4)
+	if result.ExitCode != 3 || result.Err == nil {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	// only the end of the output is kept
+	if result.Output != "llo\n" {
+		t.Errorf("unexpected output: %q", result.Output)
+	}
+
+	result = ExecCommand(context.Background(), JobConfig{Command: "sh", Args: []string{"-c", "echo ok"}}, 100)
+	if result.Err != nil || strings.TrimSpace(result.Output) != "ok" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// the report written by the job is returned
+	result = ExecCommand(context.Background(), JobConfig{Command: "sh", Args: []string{"-c", `echo '[{"instanceID":"inst1","counts":{"purged":4}}]' > $` + ENV_JOB_REPORT_FILE}}, 100)
+	if result.Err != nil || len(result.Instances) != 1 || result.Instances[0].Counts["purged"] != 4 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
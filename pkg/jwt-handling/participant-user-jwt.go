@@ -1,7 +1,9 @@
 package jwthandling
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
@@ -62,6 +64,9 @@ func ValidateParticipantUserToken(tokenString string, secretKey string) (claims
 		return
 	}
 	claims, valid = token.Claims.(*ParticipantUserClaims)
+	if valid && slices.Contains(claims.Audience, TEMP_PARTICIPANT_TOKEN_AUDIENCE) {
+		return claims, false, errors.New("temporary participant token cannot be used as participant user token")
+	}
 	valid = valid && token.Valid
 	return
 }
@@ -0,0 +1,45 @@
+package jwthandling
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const SERVICE_TOKEN_AUDIENCE = "internal-api"
+
+// Information a service token encodes (subject is the name of the calling service)
+type ServiceClaims struct {
+	jwt.RegisteredClaims
+}
+
+func GenerateNewServiceToken(expiresIn time.Duration, serviceName string, secretKey string) (tokenString string, err error) {
+	claims := ServiceClaims{
+		jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   serviceName,
+			Audience:  jwt.ClaimStrings{SERVICE_TOKEN_AUDIENCE},
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err = token.SignedString([]byte(secretKey))
+	return
+}
+
+func ValidateServiceToken(tokenString string, secretKey string) (claims *ServiceClaims, valid bool, err error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ServiceClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secretKey), nil
+	})
+	if token == nil {
+		return
+	}
+	claims, valid = token.Claims.(*ServiceClaims)
+	valid = valid && token.Valid && slices.Contains(claims.Audience, SERVICE_TOKEN_AUDIENCE)
+	return
+}
@@ -0,0 +1,54 @@
+package jwthandling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestServiceToken(t *testing.T) {
+	secretKey := "test-secret"
+
+	t.Run("valid token", func(t *testing.T) {
+		token, err := GenerateNewServiceToken(time.Minute, "survey-engine", secretKey)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		claims, valid, err := ValidateServiceToken(token, secretKey)
+		if err != nil || !valid {
+			t.Fatalf("expected valid token, got valid=%v err=%v", valid, err)
+		}
+		if claims.Subject != "survey-engine" {
+			t.Errorf("unexpected subject: %s", claims.Subject)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		token, _ := GenerateNewServiceToken(time.Minute, "survey-engine", secretKey)
+		_, valid, err := ValidateServiceToken(token, "other-secret")
+		if err == nil || valid {
+			t.Error("expected invalid token")
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		token, _ := GenerateNewServiceToken(-time.Minute, "survey-engine", secretKey)
+		_, valid, err := ValidateServiceToken(token, secretKey)
+		if err == nil || valid {
+			t.Error("expected invalid token")
+		}
+	})
+
+	t.Run("participant token is not a service token", func(t *testing.T) {
+		claims := jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			Subject:   "survey-engine",
+		}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+		_, valid, _ := ValidateServiceToken(token, secretKey)
+		if valid {
+			t.Error("expected token without service audience to be invalid")
+		}
+	})
+}
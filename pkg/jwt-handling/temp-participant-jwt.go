@@ -0,0 +1,49 @@
+package jwthandling
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const TEMP_PARTICIPANT_TOKEN_AUDIENCE = "temp-participant"
+
+// Information a temporary participant token encodes (subject is the temporary participant ID)
+type TempParticipantClaims struct {
+	InstanceID string `json:"instance_id,omitempty"`
+	StudyKey   string `json:"study_key,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func GenerateNewTempParticipantToken(expiresIn time.Duration, participantID string, instanceID string, studyKey string, secretKey string) (tokenString string, err error) {
+	claims := TempParticipantClaims{
+		instanceID,
+		studyKey,
+		jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   participantID,
+			Audience:  jwt.ClaimStrings{TEMP_PARTICIPANT_TOKEN_AUDIENCE},
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err = token.SignedString([]byte(secretKey))
+	return
+}
+
+func ValidateTempParticipantToken(tokenString string, secretKey string) (claims *TempParticipantClaims, valid bool, err error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TempParticipantClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secretKey), nil
+	})
+	if token == nil {
+		return
+	}
+	claims, valid = token.Claims.(*TempParticipantClaims)
+	valid = valid && token.Valid && slices.Contains(claims.Audience, TEMP_PARTICIPANT_TOKEN_AUDIENCE)
+	return
+}
@@ -0,0 +1,120 @@
+package campaigns
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyservice "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func validateAudience(audience messagingTypes.CampaignAudience) error {
+	if len(audience.Flags) > 0 && audience.StudyKey == "" {
+		return errors.New("flag filters require a study key")
+	}
+	switch audience.AccountStatus {
+	case "", messagingTypes.AUDIENCE_ACCOUNT_CONFIRMED, messagingTypes.AUDIENCE_ACCOUNT_UNCONFIRMED, messagingTypes.AUDIENCE_ACCOUNT_ANY:
+	default:
+		return errors.New("invalid account status filter")
+	}
+	if audience.LastLoginFrom > 0 && audience.LastLoginUntil > 0 && audience.LastLoginFrom > audience.LastLoginUntil {
+		return errors.New("invalid last login range")
+	}
+	return nil
+}
+
+// UserFilter returns the database filter preselecting the users of the audience
+func UserFilter(audience messagingTypes.CampaignAudience) bson.M {
+	filter := bson.M{
+		"account.type": "email",
+	}
+
+	switch audience.AccountStatus {
+	case "", messagingTypes.AUDIENCE_ACCOUNT_CONFIRMED:
+		filter["account.accountConfirmedAt"] = bson.M{"$gt": 0}
+	case messagingTypes.AUDIENCE_ACCOUNT_UNCONFIRMED:
+		filter["account.accountConfirmedAt"] = bson.M{"$not": bson.M{"$gt": 0}}
+	}
+
+	lastLogin := bson.M{}
+	if audience.LastLoginFrom > 0 {
+		lastLogin["$gte"] = audience.LastLoginFrom
+	}
+	if audience.LastLoginUntil > 0 {
+		lastLogin["$lte"] = audience.LastLoginUntil
+	}
+	if len(lastLogin) > 0 {
+		filter["timestamps.lastLogin"] = lastLogin
+	}
+	return filter
+}
+
+// IsSubscribed checks the contact preferences of the user for message types users can unsubscribe from
+func IsSubscribed(user *umTypes.User, messageType string) bool {
+	switch messageType {
+	case messagingTypes.EMAIL_TYPE_WEEKLY:
+		return user.ContactPreferences.SubscribedToWeekly
+	case messagingTypes.EMAIL_TYPE_NEWSLETTER:
+		return user.ContactPreferences.SubscribedToNewsletter
+	}
+	return true
+}
+
+// MatchesUser checks the criteria of the audience that are not covered by UserFilter, except participant flags
+func MatchesUser(user umTypes.User, audience messagingTypes.CampaignAudience, messageType string, now time.Time) bool {
+	if user.IsAnonymized() || user.Account.Type != "email" {
+		return false
+	}
+	if !IsSubscribed(&user, messageType) {
+		return false
+	}
+	return MatchesActivityFilter(user, audience, now)
+}
+
+// MatchesActivityFilter checks the relative activity bounds of the audience
+func MatchesActivityFilter(user umTypes.User, audience messagingTypes.CampaignAudience, now time.Time) bool {
+	lastActivity := max(user.Timestamps.LastLogin, user.Timestamps.LastTokenRefresh)
+	if audience.ActiveWithin > 0 && lastActivity < now.Unix()-audience.ActiveWithin {
+		return false
+	}
+	if audience.InactiveForMin > 0 && lastActivity > now.Unix()-audience.InactiveForMin {
+		return false
+	}
+	return true
+}
+
+// HasMatchingParticipant checks if one of the profiles of the user is an active participant of the study with the given flags
+func HasMatchingParticipant(studyDBService *studyDB.StudyDBService, instanceID string, study studyTypes.Study, user umTypes.User, flags map[string]string) bool {
+	for _, profile := range user.Profiles {
+		participantID, _, err := studyservice.ComputeParticipantIDs(study, profile.ID.Hex())
+		if err != nil {
+			slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			continue
+		}
+
+		participant, err := studyDBService.GetParticipantByID(instanceID, study.Key, participantID)
+		if err != nil || participant.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
+			continue
+		}
+
+		if MatchesFlags(participant.Flags, flags) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesFlags checks that the participant has all flags with the expected values
+func MatchesFlags(participantFlags map[string]string, flags map[string]string) bool {
+	for key, value := range flags {
+		if participantFlags[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package campaigns
+
+import (
+	"testing"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidateAudience(t *testing.T) {
+	valid := []messagingTypes.CampaignAudience{
+		{},
+		{StudyKey: "s1", Flags: map[string]string{"group": "a"}},
+		{AccountStatus: messagingTypes.AUDIENCE_ACCOUNT_ANY, LastLoginFrom: 10, LastLoginUntil: 20},
+	}
+	for _, a := range valid {
+		if err := validateAudience(a); err != nil {
+			t.Errorf("unexpected error for %+v: %v", a, err)
+		}
+	}
+
+	invalid := []messagingTypes.CampaignAudience{
+		{Flags: map[string]string{"group": "a"}},
+		{AccountStatus: "deleted"},
+		{LastLoginFrom: 20, LastLoginUntil: 10},
+	}
+	for _, a := range invalid {
+		if err := validateAudience(a); err == nil {
+			t.Errorf("expected error for %+v", a)
+		}
+	}
+}
+
+func TestUserFilter(t *testing.T) {
+	filter := UserFilter(messagingTypes.CampaignAudience{})
+	if _, ok := filter["account.accountConfirmedAt"]; !ok {
+		t.Error("expected confirmed accounts by default")
+	}
+	if _, ok := filter["timestamps.lastLogin"]; ok {
+		t.Error("expected no last login filter")
+	}
+
+	filter = UserFilter(messagingTypes.CampaignAudience{AccountStatus: messagingTypes.AUDIENCE_ACCOUNT_ANY, LastLoginFrom: 10})
+	if _, ok := filter["account.accountConfirmedAt"]; ok {
+		t.Error("expected no account status filter")
+	}
+	lastLogin, ok := filter["timestamps.lastLogin"].(bson.M)
+	if !ok || lastLogin["$gte"] != int64(10) {
+		t.Errorf("unexpected last login filter: %v", filter["timestamps.lastLogin"])
+	}
+	if _, ok := lastLogin["$lte"]; ok {
+		t.Error("expected open upper bound")
+	}
+}
+
+func TestMatchesUser(t *testing.T) {
+	now := time.Unix(100000, 0)
+	user := umTypes.User{
+		Account:    umTypes.Account{Type: "email"},
+		Timestamps: umTypes.Timestamps{LastLogin: now.Unix() - 100},
+	}
+
+	if !MatchesUser(user, messagingTypes.CampaignAudience{ActiveWithin: 200}, "custom", now) {
+		t.Error("expected recently active user to match")
+	}
+	if MatchesUser(user, messagingTypes.CampaignAudience{InactiveForMin: 200}, "custom", now) {
+		t.Error("expected recently active user not to match inactivity filter")
+	}
+	if MatchesUser(user, messagingTypes.CampaignAudience{}, messagingTypes.EMAIL_TYPE_NEWSLETTER, now) {
+		t.Error("expected unsubscribed user not to match")
+	}
+}
+
+func TestMatchesFlags(t *testing.T) {
+	flags := map[string]string{"group": "a", "lang": "de"}
+	if !MatchesFlags(flags, nil) || !MatchesFlags(flags, map[string]string{"group": "a"}) {
+		t.Error("expected flags to match")
+	}
+	if MatchesFlags(flags, map[string]string{"group": "b"}) || MatchesFlags(flags, map[string]string{"missing": "x"}) {
+		t.Error("expected flags not to match")
+	}
+}
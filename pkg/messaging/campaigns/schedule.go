@@ -0,0 +1,142 @@
+package campaigns
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/utils/cron"
+)
+
+func loadLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %s", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateCampaign checks recurrence and quiet hour settings
+func ValidateCampaign(campaign messagingTypes.Campaign) error {
+	if campaign.Template.MessageType == "" {
+		return errors.New("message type missing")
+	}
+	if err := validateAudience(campaign.Audience); err != nil {
+		return err
+	}
+	if campaign.Recurrence.Cron != "" {
+		if _, err := cron.ParseCron(campaign.Recurrence.Cron); err != nil {
+			return err
+		}
+	}
+	if _, err := loadLocation(campaign.Recurrence.Timezone); err != nil {
+		return err
+	}
+	if qh := campaign.QuietHours; qh != nil {
+		if _, err := parseClock(qh.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(qh.End); err != nil {
+			return err
+		}
+		if _, err := loadLocation(qh.Timezone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QuietHoursEnd returns the end of the quiet period if t is within quiet hours, otherwise t itself
+func QuietHoursEnd(qh *messagingTypes.QuietHours, t time.Time) (time.Time, error) {
+	if qh == nil {
+		return t, nil
+	}
+	loc, err := loadLocation(qh.Timezone)
+	if err != nil {
+		return t, err
+	}
+	start, err := parseClock(qh.Start)
+	if err != nil {
+		return t, err
+	}
+	end, err := parseClock(qh.End)
+	if err != nil {
+		return t, err
+	}
+	if start == end {
+		return t, nil
+	}
+
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	endToday := midnight.Add(time.Duration(end) * time.Minute)
+
+	if start < end {
+		// window within the same day
+		if now >= start && now < end {
+			return endToday, nil
+		}
+		return t, nil
+	}
+
+	// window spans midnight
+	if now >= start {
+		return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc).Add(time.Duration(end) * time.Minute), nil
+	}
+	if now < end {
+		return endToday, nil
+	}
+	return t, nil
+}
+
+// NextRun computes the next run of the campaign after the given time. Returns 0 if the campaign has no further runs.
+func NextRun(campaign messagingTypes.Campaign, after time.Time) (int64, error) {
+	var next time.Time
+	if campaign.Recurrence.Cron == "" {
+		if campaign.LastRunAt > 0 {
+			return 0, nil
+		}
+		next = time.Unix(campaign.Recurrence.StartAt, 0)
+		if next.Before(after) {
+			next = after
+		}
+	} else {
+		schedule, err := cron.ParseCron(campaign.Recurrence.Cron)
+		if err != nil {
+			return 0, err
+		}
+		loc, err := loadLocation(campaign.Recurrence.Timezone)
+		if err != nil {
+			return 0, err
+		}
+		from := after
+		if start := time.Unix(campaign.Recurrence.StartAt, 0); start.After(from) {
+			// the start time itself is a valid run
+			from = start.Add(-time.Minute)
+		}
+		next, err = schedule.Next(from.In(loc))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	next, err := QuietHoursEnd(campaign.QuietHours, next)
+	if err != nil {
+		return 0, err
+	}
+
+	if campaign.Recurrence.Until > 0 && next.Unix() > campaign.Recurrence.Until {
+		return 0, nil
+	}
+	return next.Unix(), nil
+}
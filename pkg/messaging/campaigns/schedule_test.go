@@ -0,0 +1,110 @@
+package campaigns
+
+import (
+	"testing"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	overnight := &messagingTypes.QuietHours{Start: "22:00", End: "07:00"}
+	daytime := &messagingTypes.QuietHours{Start: "12:00", End: "13:30"}
+
+	tests := []struct {
+		name     string
+		qh       *messagingTypes.QuietHours
+		t        time.Time
+		expected time.Time
+	}{
+		{"no quiet hours", nil, time.Date(2024, 5, 15, 23, 0, 0, 0, time.UTC), time.Date(2024, 5, 15, 23, 0, 0, 0, time.UTC)},
+		{"before midnight", overnight, time.Date(2024, 5, 15, 23, 0, 0, 0, time.UTC), time.Date(2024, 5, 16, 7, 0, 0, 0, time.UTC)},
+		{"after midnight", overnight, time.Date(2024, 5, 16, 3, 0, 0, 0, time.UTC), time.Date(2024, 5, 16, 7, 0, 0, 0, time.UTC)},
+		{"outside overnight", overnight, time.Date(2024, 5, 16, 9, 0, 0, 0, time.UTC), time.Date(2024, 5, 16, 9, 0, 0, 0, time.UTC)},
+		{"within daytime", daytime, time.Date(2024, 5, 16, 12, 15, 0, 0, time.UTC), time.Date(2024, 5, 16, 13, 30, 0, 0, time.UTC)},
+		{"end is not quiet", daytime, time.Date(2024, 5, 16, 13, 30, 0, 0, time.UTC), time.Date(2024, 5, 16, 13, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := QuietHoursEnd(tt.qh, tt.t)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !res.Equal(tt.expected) {
+				t.Errorf("unexpected result: %s, expected %s", res, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+
+	t.Run("one time campaign", func(t *testing.T) {
+		c := messagingTypes.Campaign{Recurrence: messagingTypes.CampaignRecurrence{StartAt: now.Add(time.Hour).Unix()}}
+		next, err := NextRun(c, now)
+		if err != nil || next != now.Add(time.Hour).Unix() {
+			t.Errorf("unexpected next run: %d, %v", next, err)
+		}
+
+		c.LastRunAt = now.Unix()
+		next, err = NextRun(c, now)
+		if err != nil || next != 0 {
+			t.Errorf("expected no further run: %d, %v", next, err)
+		}
+	})
+
+	t.Run("recurring campaign with quiet hours", func(t *testing.T) {
+		c := messagingTypes.Campaign{
+			Recurrence: messagingTypes.CampaignRecurrence{Cron: "0 23 * * *"},
+			QuietHours: &messagingTypes.QuietHours{Start: "22:00", End: "07:00"},
+		}
+		next, err := NextRun(c, now)
+		if err != nil || next != time.Date(2024, 5, 16, 7, 0, 0, 0, time.UTC).Unix() {
+			t.Errorf("unexpected next run: %s, %v", time.Unix(next, 0).UTC(), err)
+		}
+	})
+
+	t.Run("recurring campaign ends", func(t *testing.T) {
+		c := messagingTypes.Campaign{
+			Recurrence: messagingTypes.CampaignRecurrence{Cron: "0 9 * * *", Until: now.Add(time.Hour).Unix()},
+		}
+		next, err := NextRun(c, now)
+		if err != nil || next != 0 {
+			t.Errorf("expected no further run: %d, %v", next, err)
+		}
+	})
+
+	t.Run("recurring campaign starts in the future", func(t *testing.T) {
+		start := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+		c := messagingTypes.Campaign{
+			Recurrence: messagingTypes.CampaignRecurrence{Cron: "0 9 * * *", StartAt: start.Unix()},
+		}
+		next, err := NextRun(c, now)
+		if err != nil || next != start.Unix() {
+			t.Errorf("unexpected next run: %s, %v", time.Unix(next, 0).UTC(), err)
+		}
+	})
+}
+
+func TestValidateCampaign(t *testing.T) {
+	c := messagingTypes.Campaign{
+		Template:   messagingTypes.EmailTemplate{MessageType: "reminder"},
+		Recurrence: messagingTypes.CampaignRecurrence{Cron: "0 9 * * 1"},
+	}
+	if err := ValidateCampaign(c); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c.Audience.Flags = map[string]string{"group": "a"}
+	if err := ValidateCampaign(c); err == nil {
+		t.Error("expected error for flags without study")
+	}
+
+	c.Audience.Flags = nil
+	c.QuietHours = &messagingTypes.QuietHours{Start: "25:00", End: "07:00"}
+	if err := ValidateCampaign(c); err == nil {
+		t.Error("expected error for invalid quiet hours")
+	}
+}
@@ -0,0 +1,122 @@
+package digests
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FREQUENCY_DAILY  = "daily"
+	FREQUENCY_WEEKLY = "weekly"
+	// explicit opt-out, overrides the default frequency of the instance
+	FREQUENCY_NONE = "none"
+)
+
+// minimum time between two digests, slightly less than the period so that a job running a bit earlier than the day before still sends
+const (
+	minDailyInterval  = 20 * time.Hour
+	minWeeklyInterval = 6 * 24 * time.Hour
+)
+
+// Task is a pending survey listed in the digest
+type Task struct {
+	StudyKey     string
+	SurveyKey    string
+	Category     string
+	ProfileAlias string
+	ValidUntil   int64
+}
+
+func IsValidFrequency(frequency string) bool {
+	switch frequency {
+	case "", FREQUENCY_DAILY, FREQUENCY_WEEKLY, FREQUENCY_NONE:
+		return true
+	}
+	return false
+}
+
+// EffectiveFrequency returns the frequency chosen by the user, or the default of the instance if the user did not choose one
+func EffectiveFrequency(userFrequency string, defaultFrequency string) string {
+	frequency := userFrequency
+	if frequency == "" {
+		frequency = defaultFrequency
+	}
+	if frequency == FREQUENCY_DAILY || frequency == FREQUENCY_WEEKLY {
+		return frequency
+	}
+	return ""
+}
+
+// IsDue checks if a digest with the given frequency should be sent now. Weekly digests are sent on the given weekday.
+func IsDue(frequency string, weekday time.Weekday, lastSentAt int64, now time.Time) bool {
+	last := time.Unix(lastSentAt, 0)
+	switch frequency {
+	case FREQUENCY_DAILY:
+		return lastSentAt == 0 || now.Sub(last) >= minDailyInterval
+	case FREQUENCY_WEEKLY:
+		if now.Weekday() != weekday {
+			return false
+		}
+		return lastSentAt == 0 || now.Sub(last) >= minWeeklyInterval
+	}
+	return false
+}
+
+// IsStudyIncluded checks that the user did not opt out of digests for the study
+func IsStudyIncluded(studyKey string, optOutStudies []string) bool {
+	return !slices.Contains(optOutStudies, studyKey)
+}
+
+// IsOpenAt checks if a survey assigned with the given validity can be filled out at the time
+func IsOpenAt(validFrom int64, validUntil int64, now time.Time) bool {
+	ts := now.Unix()
+	if validFrom > 0 && validFrom > ts {
+		return false
+	}
+	if validUntil > 0 && validUntil < ts {
+		return false
+	}
+	return true
+}
+
+// SortTasks orders tasks by study, then by deadline with open-ended tasks last
+func SortTasks(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].StudyKey != tasks[j].StudyKey {
+			return tasks[i].StudyKey < tasks[j].StudyKey
+		}
+		ui, uj := tasks[i].ValidUntil, tasks[j].ValidUntil
+		if ui == 0 || uj == 0 {
+			return ui != 0 && uj == 0
+		}
+		return ui < uj
+	})
+}
+
+// AddTasksToPayload exposes the tasks as template variables: "taskCount", "studyKeys" (comma separated)
+// and "tasks.<index>.studyKey", "tasks.<index>.surveyKey", "tasks.<index>.category",
+// "tasks.<index>.profileAlias" and "tasks.<index>.validUntil" (unix timestamp, empty if open-ended)
+func AddTasksToPayload(payload map[string]string, tasks []Task) {
+	studyKeys := []string{}
+	for i, task := range tasks {
+		prefix := fmt.Sprintf("tasks.%d.", i)
+		payload[prefix+"studyKey"] = task.StudyKey
+		payload[prefix+"surveyKey"] = task.SurveyKey
+		payload[prefix+"category"] = task.Category
+		payload[prefix+"profileAlias"] = task.ProfileAlias
+		if task.ValidUntil > 0 {
+			payload[prefix+"validUntil"] = strconv.FormatInt(task.ValidUntil, 10)
+		} else {
+			payload[prefix+"validUntil"] = ""
+		}
+		if !slices.Contains(studyKeys, task.StudyKey) {
+			studyKeys = append(studyKeys, task.StudyKey)
+		}
+	}
+	payload["taskCount"] = strconv.Itoa(len(tasks))
+	payload["studyKeys"] = strings.Join(studyKeys, ",")
+}
@@ -0,0 +1,93 @@
+package digests
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEffectiveFrequency(t *testing.T) {
+	tests := []struct {
+		user     string
+		def      string
+		expected string
+	}{
+		{"", "", ""},
+		{"", FREQUENCY_WEEKLY, FREQUENCY_WEEKLY},
+		{FREQUENCY_DAILY, FREQUENCY_WEEKLY, FREQUENCY_DAILY},
+		{FREQUENCY_NONE, FREQUENCY_DAILY, ""},
+		{"unknown", FREQUENCY_DAILY, ""},
+	}
+	for _, tt := range tests {
+		if got := EffectiveFrequency(tt.user, tt.def); got != tt.expected {
+			t.Errorf("EffectiveFrequency(%q, %q) = %q, expected %q", tt.user, tt.def, got, tt.expected)
+		}
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2024, 5, 15, 8, 0, 0, 0, time.UTC)
+
+	t.Run("daily", func(t *testing.T) {
+		if !IsDue(FREQUENCY_DAILY, 0, 0, now) {
+			t.Error("expected first digest to be due")
+		}
+		if !IsDue(FREQUENCY_DAILY, 0, now.Add(-23*time.Hour).Unix(), now) {
+			t.Error("expected digest to be due after a day")
+		}
+		if IsDue(FREQUENCY_DAILY, 0, now.Add(-2*time.Hour).Unix(), now) {
+			t.Error("expected digest not to be due twice a day")
+		}
+	})
+
+	t.Run("weekly", func(t *testing.T) {
+		if !IsDue(FREQUENCY_WEEKLY, time.Wednesday, now.Add(-7*24*time.Hour).Unix(), now) {
+			t.Error("expected digest to be due on the weekday")
+		}
+		if IsDue(FREQUENCY_WEEKLY, time.Thursday, 0, now) {
+			t.Error("expected digest not to be due on another weekday")
+		}
+		if IsDue(FREQUENCY_WEEKLY, time.Wednesday, now.Add(-time.Hour).Unix(), now) {
+			t.Error("expected digest not to be due twice on the same day")
+		}
+	})
+
+	if IsDue("", time.Wednesday, 0, now) {
+		t.Error("expected no digest without frequency")
+	}
+}
+
+func TestIsOpenAt(t *testing.T) {
+	now := time.Unix(1000, 0)
+	if !IsOpenAt(0, 0, now) || !IsOpenAt(900, 1100, now) {
+		t.Error("expected survey to be open")
+	}
+	if IsOpenAt(1100, 0, now) || IsOpenAt(0, 900, now) {
+		t.Error("expected survey to be closed")
+	}
+}
+
+func TestSortTasksAndPayload(t *testing.T) {
+	tasks := []Task{
+		{StudyKey: "b", SurveyKey: "s1"},
+		{StudyKey: "a", SurveyKey: "open"},
+		{StudyKey: "a", SurveyKey: "late", ValidUntil: 2000},
+		{StudyKey: "a", SurveyKey: "soon", ValidUntil: 1000},
+	}
+	SortTasks(tasks)
+	order := []string{"soon", "late", "open", "s1"}
+	for i, key := range order {
+		if tasks[i].SurveyKey != key {
+			t.Fatalf("unexpected order at %d: %s", i, tasks[i].SurveyKey)
+		}
+	}
+
+	payload := map[string]string{}
+	AddTasksToPayload(payload, tasks)
+	if payload["taskCount"] != "4" || payload["studyKeys"] != "a,b" {
+		t.Errorf("unexpected summary: %v", payload)
+	}
+	if payload["tasks.0.validUntil"] != "1000" || payload["tasks.2.validUntil"] != "" || payload["tasks.3.studyKey"] != "b" {
+		t.Errorf("unexpected task variables: %v", payload)
+	}
+}
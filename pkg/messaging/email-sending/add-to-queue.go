@@ -2,6 +2,8 @@ package emailsending
 
 import (
 	"log/slog"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 )
 
 func QueueEmailByTemplate(
@@ -13,6 +15,25 @@ func QueueEmailByTemplate(
 	payload map[string]string,
 	useLowPrio bool,
 ) error {
+	return QueueEmailWithAttachments(instanceID, to, messageType, studyKey, lang, payload, useLowPrio, nil)
+}
+
+// QueueEmailWithAttachments adds the email generated from the template to the outgoing emails, with files from the filestore attached
+func QueueEmailWithAttachments(
+	instanceID string,
+	to []string,
+	messageType string,
+	studyKey string,
+	lang string,
+	payload map[string]string,
+	useLowPrio bool,
+	attachments []messagingTypes.EmailAttachment,
+) error {
+	attachments, err := PrepareAttachments(attachments)
+	if err != nil {
+		return err
+	}
+
 	outgoingEmail, err := prepOutgoingEmail(
 		messageDBService,
 		instanceID,
@@ -26,6 +47,7 @@ func QueueEmailByTemplate(
 	if err != nil {
 		return err
 	}
+	outgoingEmail.Attachments = attachments
 
 	_, err = messageDBService.AddToOutgoingEmails(instanceID, *outgoingEmail)
 	if err != nil {
@@ -0,0 +1,274 @@
+package emailsending
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+// sesProvider uses the Amazon SES v2 API
+type sesProvider struct {
+	config messagingTypes.EmailProviderConfig
+	client *http.Client
+}
+
+func (p *sesProvider) Name() string {
+	return EMAIL_PROVIDER_SES
+}
+
+func (p *sesProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	from, replyTo := senderInfos(p.config, email.HeaderOverrides)
+
+	simpleContent := map[string]interface{}{
+		"Subject": map[string]string{"Data": email.Subject, "Charset": "UTF-8"},
+		"Body": map[string]interface{}{
+			"Html": map[string]string{"Data": email.Content, "Charset": "UTF-8"},
+		},
+	}
+	if len(email.Attachments) > 0 {
+		attachments, err := loadAttachments(email)
+		if err != nil {
+			return err
+		}
+		sesAttachments := make([]map[string]string, len(attachments))
+		for i, a := range attachments {
+			sesAttachments[i] = map[string]string{
+				"FileName":           a.Filename,
+				"ContentType":        a.ContentType,
+				"ContentDisposition": "ATTACHMENT",
+				"RawContent":         base64.StdEncoding.EncodeToString(a.Content),
+			}
+		}
+		simpleContent["Attachments"] = sesAttachments
+	}
+
+	payload := map[string]interface{}{
+		"FromEmailAddress": from,
+		"Destination": map[string]interface{}{
+			"ToAddresses": email.To,
+		},
+		"ReplyToAddresses": replyTo,
+		"Content": map[string]interface{}{
+			"Simple": simpleContent,
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	baseURL := p.config.URL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://email.%s.amazonaws.com", p.config.Region)
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAWSRequestV4(req, body, p.config.APIKey, p.config.APISecret, p.config.Region, "ses", time.Now())
+
+	return doProviderRequest(p.client, req)
+}
+
+// sendgridProvider uses the SendGrid v3 mail send API
+type sendgridProvider struct {
+	config messagingTypes.EmailProviderConfig
+	client *http.Client
+}
+
+func (p *sendgridProvider) Name() string {
+	return EMAIL_PROVIDER_SENDGRID
+}
+
+type sendgridAddress struct {
+	Email string `json:"email"`
+}
+
+func (p *sendgridProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	from, replyTo := senderInfos(p.config, email.HeaderOverrides)
+
+	to := make([]sendgridAddress, len(email.To))
+	for i, addr := range email.To {
+		to[i] = sendgridAddress{Email: addr}
+	}
+	payload := map[string]interface{}{
+		"personalizations": []map[string]interface{}{
+			{"to": to},
+		},
+		"from":    sendgridAddress{Email: from},
+		"subject": email.Subject,
+		"content": []map[string]string{
+			{"type": "text/html", "value": email.Content},
+		},
+	}
+	if len(email.Attachments) > 0 {
+		attachments, err := loadAttachments(email)
+		if err != nil {
+			return err
+		}
+		sendgridAttachments := make([]map[string]string, len(attachments))
+		for i, a := range attachments {
+			sendgridAttachments[i] = map[string]string{
+				"content":     base64.StdEncoding.EncodeToString(a.Content),
+				"filename":    a.Filename,
+				"type":        a.ContentType,
+				"disposition": "attachment",
+			}
+		}
+		payload["attachments"] = sendgridAttachments
+	}
+	if len(replyTo) > 0 {
+		replyToList := make([]sendgridAddress, len(replyTo))
+		for i, addr := range replyTo {
+			replyToList[i] = sendgridAddress{Email: addr}
+		}
+		payload["reply_to_list"] = replyToList
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	baseURL := p.config.URL
+	if baseURL == "" {
+		baseURL = "https://api.sendgrid.com"
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+
+	return doProviderRequest(p.client, req)
+}
+
+// mailgunProvider uses the Mailgun messages API
+type mailgunProvider struct {
+	config messagingTypes.EmailProviderConfig
+	client *http.Client
+}
+
+func (p *mailgunProvider) Name() string {
+	return EMAIL_PROVIDER_MAILGUN
+}
+
+func (p *mailgunProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	from, replyTo := senderInfos(p.config, email.HeaderOverrides)
+
+	form := url.Values{}
+	form.Set("from", from)
+	for _, addr := range email.To {
+		form.Add("to", addr)
+	}
+	form.Set("subject", email.Subject)
+	form.Set("html", email.Content)
+	if len(replyTo) > 0 {
+		form.Set("h:Reply-To", strings.Join(replyTo, ", "))
+	}
+	if email.HeaderOverrides != nil && email.HeaderOverrides.Sender != "" {
+		form.Set("h:Sender", email.HeaderOverrides.Sender)
+	}
+
+	baseURL := p.config.URL
+	if baseURL == "" {
+		baseURL = "https://api.mailgun.net"
+	}
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", strings.TrimSuffix(baseURL, "/"), url.PathEscape(p.config.Domain))
+
+	if len(email.Attachments) > 0 {
+		return p.sendMultipart(endpoint, form, email.Attachments)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("api", p.config.APIKey)
+
+	return doProviderRequest(p.client, req)
+}
+
+// sendMultipart streams the attachments from the filestore into a multipart request body
+func (p *mailgunProvider) sendMultipart(endpoint string, form url.Values, attachments []messagingTypes.EmailAttachment) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		err := writeMailgunMultipart(mw, form, attachments)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, pr)
+	if err != nil {
+		pr.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetBasicAuth("api", p.config.APIKey)
+
+	return doProviderRequest(p.client, req)
+}
+
+func writeMailgunMultipart(mw *multipart.Writer, form url.Values, attachments []messagingTypes.EmailAttachment) error {
+	for key, values := range form {
+		for _, v := range values {
+			if err := mw.WriteField(key, v); err != nil {
+				return err
+			}
+		}
+	}
+	for _, a := range attachments {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="attachment"; filename="%s"`, strings.ReplaceAll(a.Filename, `"`, "")))
+		h.Set("Content-Type", a.ContentType)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		r, err := openAttachment(a)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func doProviderRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("provider returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		// rejected requests are not retried, except for rate limiting and timeouts
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusRequestTimeout {
+			return permanentError{err: err}
+		}
+		return err
+	}
+	return nil
+}
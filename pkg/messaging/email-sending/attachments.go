@@ -0,0 +1,153 @@
+package emailsending
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+
+	"github.com/case-framework/case-backend/pkg/filestore"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	smtp_client "github.com/case-framework/case-backend/pkg/smtp-client"
+)
+
+const (
+	defaultMaxAttachmentSize      = 10 * 1024 * 1024
+	defaultMaxTotalAttachmentSize = 20 * 1024 * 1024
+)
+
+var (
+	ErrAttachmentsNotConfigured = errors.New("no filestore configured for email attachments")
+	ErrAttachmentTooLarge       = errors.New("email attachment exceeds size limit")
+)
+
+var (
+	attachmentStore  filestore.FileStore
+	attachmentLimits = messagingTypes.EmailAttachmentsConfig{
+		MaxAttachmentSize: defaultMaxAttachmentSize,
+		MaxTotalSize:      defaultMaxTotalAttachmentSize,
+	}
+)
+
+// InitEmailAttachments sets the filestore and the size limits for attachments, unset limits keep their defaults
+func InitEmailAttachments(config messagingTypes.EmailAttachmentsConfig) {
+	if config.FilestorePath != "" {
+		attachmentStore = filestore.NewLocalFileStore(config.FilestorePath)
+	}
+	if config.MaxAttachmentSize > 0 {
+		attachmentLimits.MaxAttachmentSize = config.MaxAttachmentSize
+	}
+	if config.MaxTotalSize > 0 {
+		attachmentLimits.MaxTotalSize = config.MaxTotalSize
+	}
+}
+
+// SetAttachmentStore replaces the filestore attachments are read from
+func SetAttachmentStore(store filestore.FileStore) {
+	attachmentStore = store
+}
+
+// PrepareAttachments checks that the referenced files exist and are within the size limits.
+// The size and a missing content type are filled in.
+func PrepareAttachments(attachments []messagingTypes.EmailAttachment) ([]messagingTypes.EmailAttachment, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	if attachmentStore == nil {
+		return nil, ErrAttachmentsNotConfigured
+	}
+
+	prepared := make([]messagingTypes.EmailAttachment, len(attachments))
+	var total int64
+	for i, a := range attachments {
+		size, err := attachmentStore.Size(a.FileRef)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s: %w", a.FileRef, err)
+		}
+		if size > attachmentLimits.MaxAttachmentSize {
+			return nil, fmt.Errorf("%w: %s has %d bytes", ErrAttachmentTooLarge, a.FileRef, size)
+		}
+		total += size
+		if total > attachmentLimits.MaxTotalSize {
+			return nil, fmt.Errorf("%w: attachments have more than %d bytes in total", ErrAttachmentTooLarge, attachmentLimits.MaxTotalSize)
+		}
+
+		if a.Filename == "" {
+			a.Filename = filepath.Base(a.FileRef)
+		}
+		if a.ContentType == "" {
+			a.ContentType = attachmentContentType(a.Filename)
+		}
+		a.Size = size
+		prepared[i] = a
+	}
+	return prepared, nil
+}
+
+func attachmentContentType(filename string) string {
+	if ct := mime.TypeByExtension(filepath.Ext(filename)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// openAttachment returns a reader for the attachment content that fails if the file grew beyond the size limit
+func openAttachment(a messagingTypes.EmailAttachment) (io.ReadCloser, error) {
+	if attachmentStore == nil {
+		return nil, permanentError{err: ErrAttachmentsNotConfigured}
+	}
+	r, err := attachmentStore.Open(a.FileRef)
+	if err != nil {
+		// the file will not reappear, retrying does not help
+		return nil, permanentError{err: fmt.Errorf("attachment %s: %w", a.FileRef, err)}
+	}
+	return &limitedReadCloser{r: r, remaining: attachmentLimits.MaxAttachmentSize, ref: a.FileRef}, nil
+}
+
+type limitedReadCloser struct {
+	r         io.ReadCloser
+	remaining int64
+	ref       string
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, permanentError{err: fmt.Errorf("%w: %s", ErrAttachmentTooLarge, l.ref)}
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, permanentError{err: fmt.Errorf("%w: %s", ErrAttachmentTooLarge, l.ref)}
+	}
+	return n, err
+}
+
+func (l *limitedReadCloser) Close() error {
+	return l.r.Close()
+}
+
+// loadAttachments reads the content of all attachments of the email from the filestore
+func loadAttachments(email *messagingTypes.OutgoingEmail) ([]smtp_client.Attachment, error) {
+	loaded := make([]smtp_client.Attachment, 0, len(email.Attachments))
+	for _, a := range email.Attachments {
+		r, err := openAttachment(a)
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, smtp_client.Attachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Content:     content,
+		})
+	}
+	return loaded, nil
+}
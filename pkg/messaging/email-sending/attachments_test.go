@@ -0,0 +1,82 @@
+package emailsending
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/case-framework/case-backend/pkg/filestore"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func TestAttachments(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "receipt.pdf"), []byte("pdf-content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "large.zip"), []byte(strings.Repeat("x", 100)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	previousLimits := attachmentLimits
+	SetAttachmentStore(filestore.NewLocalFileStore(root))
+	attachmentLimits = messagingTypes.EmailAttachmentsConfig{MaxAttachmentSize: 50, MaxTotalSize: 60}
+	defer func() {
+		SetAttachmentStore(nil)
+		attachmentLimits = previousLimits
+	}()
+
+	t.Run("prepare fills size and content type", func(t *testing.T) {
+		prepared, err := PrepareAttachments([]messagingTypes.EmailAttachment{{FileRef: "receipt.pdf"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prepared[0].Filename != "receipt.pdf" || prepared[0].ContentType != "application/pdf" || prepared[0].Size != 11 {
+			t.Errorf("unexpected attachment: %+v", prepared[0])
+		}
+	})
+
+	t.Run("single attachment too large", func(t *testing.T) {
+		_, err := PrepareAttachments([]messagingTypes.EmailAttachment{{FileRef: "large.zip"}})
+		if !errors.Is(err, ErrAttachmentTooLarge) {
+			t.Errorf("expected size error, got %v", err)
+		}
+	})
+
+	t.Run("total size too large", func(t *testing.T) {
+		a := messagingTypes.EmailAttachment{FileRef: "receipt.pdf"}
+		_, err := PrepareAttachments([]messagingTypes.EmailAttachment{a, a, a, a, a, a})
+		if !errors.Is(err, ErrAttachmentTooLarge) {
+			t.Errorf("expected size error, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := PrepareAttachments([]messagingTypes.EmailAttachment{{FileRef: "missing.pdf"}}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("load content", func(t *testing.T) {
+		loaded, err := loadAttachments(&messagingTypes.OutgoingEmail{
+			Attachments: []messagingTypes.EmailAttachment{{FileRef: "receipt.pdf", Filename: "r.pdf", ContentType: "application/pdf"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded) != 1 || string(loaded[0].Content) != "pdf-content" || loaded[0].Filename != "r.pdf" {
+			t.Errorf("unexpected attachments: %+v", loaded)
+		}
+	})
+
+	t.Run("file grew beyond limit is a permanent error", func(t *testing.T) {
+		_, err := loadAttachments(&messagingTypes.OutgoingEmail{
+			Attachments: []messagingTypes.EmailAttachment{{FileRef: "large.zip"}},
+		})
+		if !errors.Is(err, ErrAttachmentTooLarge) || !isPermanentError(err) {
+			t.Errorf("expected permanent size error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package emailsending
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	EMAIL_EVENT_TYPE_BOUNCE    = "bounce"
+	EMAIL_EVENT_TYPE_COMPLAINT = "complaint"
+)
+
+var ErrAllRecipientsSuppressed = errors.New("all recipients are on the suppression list")
+
+// EmailDeliveryEvent is a bounce or complaint reported by an email provider
+type EmailDeliveryEvent struct {
+	Address   string `json:"email"`
+	Type      string `json:"type"`
+	Permanent bool   `json:"permanent"`
+	Details   string `json:"reason"`
+}
+
+// SuppressionReason returns the reason to suppress the address for, or an empty string if the event does not require suppression
+func (e EmailDeliveryEvent) SuppressionReason() string {
+	switch {
+	case e.Type == EMAIL_EVENT_TYPE_COMPLAINT:
+		return messagingTypes.EMAIL_SUPPRESSION_REASON_COMPLAINT
+	case e.Type == EMAIL_EVENT_TYPE_BOUNCE && e.Permanent:
+		return messagingTypes.EMAIL_SUPPRESSION_REASON_HARD_BOUNCE
+	default:
+		return ""
+	}
+}
+
+// VerifyWebhookSecret checks the secret sent along with a bounce or complaint webhook call
+func VerifyWebhookSecret(instanceID string, provider string, secret string) bool {
+	secrets, ok := instanceWebhookSecrets[instanceID]
+	if !ok {
+		secrets = defaultWebhookSecrets
+	}
+	expected, ok := secrets[provider]
+	if !ok || expected == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) == 1
+}
+
+// ParseDeliveryEvents extracts bounce and complaint events from the webhook request of the given provider
+func ParseDeliveryEvents(provider string, r *http.Request) ([]EmailDeliveryEvent, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []EmailDeliveryEvent
+	switch provider {
+	case EMAIL_PROVIDER_SES:
+		events, err = parseSESEvents(body)
+	case EMAIL_PROVIDER_SENDGRID:
+		events, err = parseSendgridEvents(body)
+	case EMAIL_PROVIDER_MAILGUN:
+		events, err = parseMailgunEvents(body)
+	case EMAIL_PROVIDER_SMTP_BRIDGE, EMAIL_PROVIDER_SMTP:
+		events, err = parseGenericEvents(body)
+	default:
+		return nil, fmt.Errorf("unknown email provider: %s", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range events {
+		events[i].Address = strings.ToLower(strings.TrimSpace(events[i].Address))
+	}
+	return events, nil
+}
+
+type snsMessage struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Bounce           struct {
+		BounceType        string `json:"bounceType"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplaintFeedbackType string `json:"complaintFeedbackType"`
+		ComplainedRecipients  []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+}
+
+// parseSESEvents handles SES notifications delivered through SNS
+func parseSESEvents(body []byte) ([]EmailDeliveryEvent, error) {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		// subscriptions are not confirmed automatically to avoid calling URLs from the request
+		slog.Warn("SNS subscription confirmation received, confirm it manually", slog.String("subscribeURL", msg.SubscribeURL))
+		return nil, nil
+	case "Notification":
+	default:
+		return nil, fmt.Errorf("unexpected SNS message type: %s", msg.Type)
+	}
+
+	var notification sesNotification
+	if err := json.Unmarshal([]byte(msg.Message), &notification); err != nil {
+		return nil, err
+	}
+
+	notificationType := notification.NotificationType
+	if notificationType == "" {
+		notificationType = notification.EventType
+	}
+
+	events := []EmailDeliveryEvent{}
+	switch notificationType {
+	case "Bounce":
+		for _, r := range notification.Bounce.BouncedRecipients {
+			events = append(events, EmailDeliveryEvent{
+				Address:   r.EmailAddress,
+				Type:      EMAIL_EVENT_TYPE_BOUNCE,
+				Permanent: notification.Bounce.BounceType == "Permanent",
+				Details:   r.DiagnosticCode,
+			})
+		}
+	case "Complaint":
+		for _, r := range notification.Complaint.ComplainedRecipients {
+			events = append(events, EmailDeliveryEvent{
+				Address: r.EmailAddress,
+				Type:    EMAIL_EVENT_TYPE_COMPLAINT,
+				Details: notification.Complaint.ComplaintFeedbackType,
+			})
+		}
+	}
+	return events, nil
+}
+
+type sendgridEvent struct {
+	Email  string `json:"email"`
+	Event  string `json:"event"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+func parseSendgridEvents(body []byte) ([]EmailDeliveryEvent, error) {
+	var sgEvents []sendgridEvent
+	if err := json.Unmarshal(body, &sgEvents); err != nil {
+		return nil, err
+	}
+
+	events := []EmailDeliveryEvent{}
+	for _, e := range sgEvents {
+		switch e.Event {
+		case "bounce":
+			events = append(events, EmailDeliveryEvent{
+				Address: e.Email,
+				Type:    EMAIL_EVENT_TYPE_BOUNCE,
+				// blocked messages are temporary rejections
+				Permanent: e.Type != "blocked",
+				Details:   e.Reason,
+			})
+		case "spamreport":
+			events = append(events, EmailDeliveryEvent{
+				Address: e.Email,
+				Type:    EMAIL_EVENT_TYPE_COMPLAINT,
+			})
+		}
+	}
+	return events, nil
+}
+
+type mailgunWebhook struct {
+	EventData struct {
+		Event          string `json:"event"`
+		Severity       string `json:"severity"`
+		Recipient      string `json:"recipient"`
+		DeliveryStatus struct {
+			Message     string `json:"message"`
+			Description string `json:"description"`
+		} `json:"delivery-status"`
+	} `json:"event-data"`
+}
+
+func parseMailgunEvents(body []byte) ([]EmailDeliveryEvent, error) {
+	var webhook mailgunWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, err
+	}
+
+	data := webhook.EventData
+	switch data.Event {
+	case "failed":
+		details := data.DeliveryStatus.Description
+		if details == "" {
+			details = data.DeliveryStatus.Message
+		}
+		return []EmailDeliveryEvent{{
+			Address:   data.Recipient,
+			Type:      EMAIL_EVENT_TYPE_BOUNCE,
+			Permanent: data.Severity == "permanent",
+			Details:   details,
+		}}, nil
+	case "complained":
+		return []EmailDeliveryEvent{{
+			Address: data.Recipient,
+			Type:    EMAIL_EVENT_TYPE_COMPLAINT,
+		}}, nil
+	}
+	return []EmailDeliveryEvent{}, nil
+}
+
+// parseGenericEvents accepts a single event or a list of events in the EmailDeliveryEvent format
+func parseGenericEvents(body []byte) ([]EmailDeliveryEvent, error) {
+	var events []EmailDeliveryEvent
+	if err := json.Unmarshal(body, &events); err == nil {
+		return events, nil
+	}
+
+	var event EmailDeliveryEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return []EmailDeliveryEvent{event}, nil
+}
+
+// OnDeliveryEvent adds the address of the event to the suppression list if needed. Returns the suppression reason or an empty string if the address was not suppressed.
+func OnDeliveryEvent(instanceID string, provider string, event EmailDeliveryEvent) (string, error) {
+	reason := event.SuppressionReason()
+	if reason == "" {
+		slog.Debug("email delivery event does not require suppression", slog.String("instanceID", instanceID), slog.String("provider", provider), slog.String("type", event.Type))
+		return "", nil
+	}
+	if event.Address == "" {
+		return "", errors.New("address missing in email delivery event")
+	}
+
+	err := messageDBService.AddEmailSuppression(instanceID, messagingTypes.EmailSuppression{
+		Address:  event.Address,
+		Reason:   reason,
+		Provider: provider,
+		Details:  event.Details,
+	})
+	if err != nil {
+		return "", err
+	}
+	return reason, nil
+}
+
+// removeSuppressedRecipients drops recipients that are on the suppression list
+func removeSuppressedRecipients(instanceID string, outgoing *messagingTypes.OutgoingEmail) error {
+	if messageDBService == nil || len(outgoing.To) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, len(outgoing.To))
+	for i, to := range outgoing.To {
+		addresses[i] = strings.ToLower(strings.TrimSpace(to))
+	}
+
+	suppressed, err := messageDBService.GetSuppressedAddresses(instanceID, addresses)
+	if err != nil {
+		return err
+	}
+	if len(suppressed) == 0 {
+		return nil
+	}
+
+	isSuppressed := map[string]bool{}
+	for _, s := range suppressed {
+		isSuppressed[s] = true
+	}
+
+	to := []string{}
+	for i, addr := range addresses {
+		if isSuppressed[addr] {
+			continue
+		}
+		to = append(to, outgoing.To[i])
+	}
+	slog.Info("suppressed email recipients removed", slog.String("instanceID", instanceID), slog.String("messageType", outgoing.MessageType), slog.Int("count", len(outgoing.To)-len(to)))
+
+	if len(to) == 0 {
+		return ErrAllRecipientsSuppressed
+	}
+	outgoing.To = to
+	return nil
+}
@@ -0,0 +1,117 @@
+package emailsending
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func newEventRequest(body string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestParseDeliveryEvents(t *testing.T) {
+	t.Run("ses permanent bounce", func(t *testing.T) {
+		body := `{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"bounce\":{\"bounceType\":\"Permanent\",\"bouncedRecipients\":[{\"emailAddress\":\"Test@Example.com\",\"diagnosticCode\":\"550 unknown user\"}]}}"}`
+		events, err := ParseDeliveryEvents(EMAIL_PROVIDER_SES, newEventRequest(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("unexpected number of events: %d", len(events))
+		}
+		if events[0].Address != "test@example.com" || events[0].SuppressionReason() != messagingTypes.EMAIL_SUPPRESSION_REASON_HARD_BOUNCE {
+			t.Errorf("unexpected event: %+v", events[0])
+		}
+	})
+
+	t.Run("ses subscription confirmation", func(t *testing.T) {
+		body := `{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.example.com"}`
+		events, err := ParseDeliveryEvents(EMAIL_PROVIDER_SES, newEventRequest(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != 0 {
+			t.Errorf("unexpected events: %+v", events)
+		}
+	})
+
+	t.Run("sendgrid events", func(t *testing.T) {
+		body := `[{"email":"a@example.com","event":"bounce","type":"bounce"},{"email":"b@example.com","event":"bounce","type":"blocked"},{"email":"c@example.com","event":"spamreport"},{"email":"d@example.com","event":"delivered"}]`
+		events, err := ParseDeliveryEvents(EMAIL_PROVIDER_SENDGRID, newEventRequest(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("unexpected number of events: %d", len(events))
+		}
+		expected := []string{
+			messagingTypes.EMAIL_SUPPRESSION_REASON_HARD_BOUNCE,
+			"",
+			messagingTypes.EMAIL_SUPPRESSION_REASON_COMPLAINT,
+		}
+		for i, e := range events {
+			if e.SuppressionReason() != expected[i] {
+				t.Errorf("unexpected suppression reason for %s: %s", e.Address, e.SuppressionReason())
+			}
+		}
+	})
+
+	t.Run("mailgun temporary failure", func(t *testing.T) {
+		body := `{"event-data":{"event":"failed","severity":"temporary","recipient":"a@example.com"}}`
+		events, err := ParseDeliveryEvents(EMAIL_PROVIDER_MAILGUN, newEventRequest(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != 1 || events[0].SuppressionReason() != "" {
+			t.Errorf("unexpected events: %+v", events)
+		}
+	})
+
+	t.Run("generic single event", func(t *testing.T) {
+		body := `{"email":"a@example.com","type":"complaint"}`
+		events, err := ParseDeliveryEvents(EMAIL_PROVIDER_SMTP_BRIDGE, newEventRequest(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != 1 || events[0].SuppressionReason() != messagingTypes.EMAIL_SUPPRESSION_REASON_COMPLAINT {
+			t.Errorf("unexpected events: %+v", events)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		_, err := ParseDeliveryEvents("unknown", newEventRequest(`{}`))
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestVerifyWebhookSecret(t *testing.T) {
+	err := InitEmailProviders(
+		[]messagingTypes.EmailProviderConfig{{WebhookSecret: "secret"}},
+		map[string][]messagingTypes.EmailProviderConfig{
+			"other": {{Provider: EMAIL_PROVIDER_SENDGRID, APIKey: "key", From: "a@example.com", WebhookSecret: "other-secret"}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !VerifyWebhookSecret("test", EMAIL_PROVIDER_SMTP_BRIDGE, "secret") {
+		t.Error("expected default secret to be accepted")
+	}
+	if VerifyWebhookSecret("test", EMAIL_PROVIDER_SMTP_BRIDGE, "wrong") {
+		t.Error("expected wrong secret to be rejected")
+	}
+	if VerifyWebhookSecret("other", EMAIL_PROVIDER_SMTP_BRIDGE, "secret") {
+		t.Error("expected default secret to be rejected for instance with own providers")
+	}
+	if !VerifyWebhookSecret("other", EMAIL_PROVIDER_SENDGRID, "other-secret") {
+		t.Error("expected instance secret to be accepted")
+	}
+}
@@ -0,0 +1,48 @@
+package emailsending
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/messaging/templates"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+var (
+	defaultLanguageFallbacks  map[string][]string
+	instanceLanguageFallbacks map[string]map[string][]string
+)
+
+// InitLanguageFallbacks sets the languages tried if a template has no translation for the requested language
+func InitLanguageFallbacks(fallbacks map[string][]string, perInstance map[string]map[string][]string) {
+	defaultLanguageFallbacks = fallbacks
+	instanceLanguageFallbacks = perInstance
+}
+
+func getLanguageFallbacks(instanceID string) map[string][]string {
+	if fallbacks, ok := instanceLanguageFallbacks[instanceID]; ok {
+		return fallbacks
+	}
+	return defaultLanguageFallbacks
+}
+
+// getTranslationWithFallback resolves the translation of the template along the fallback chain of the instance
+func getTranslationWithFallback(instanceID string, templateDef messagingTypes.EmailTemplate, lang string) (messagingTypes.LocalizedTemplate, error) {
+	chain := templates.LanguageChain(lang, getLanguageFallbacks(instanceID), templateDef.DefaultLanguage)
+	translation, found := templates.FindTranslation(templateDef.Translations, chain)
+	if !found {
+		return translation, fmt.Errorf("no translation of template %s found for language %s, tried %v", templateDef.MessageType, lang, chain)
+	}
+
+	if !strings.EqualFold(translation.Lang, lang) {
+		slog.Info("email template language fallback used",
+			slog.String("instanceID", instanceID),
+			slog.String("messageType", templateDef.MessageType),
+			slog.String("studyKey", templateDef.StudyKey),
+			slog.String("requestedLanguage", lang),
+			slog.String("usedLanguage", translation.Lang),
+		)
+	}
+	return translation, nil
+}
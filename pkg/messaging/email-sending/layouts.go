@@ -0,0 +1,71 @@
+package emailsending
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	emailtemplates "github.com/case-framework/case-backend/pkg/messaging/email-templates"
+	"github.com/case-framework/case-backend/pkg/messaging/templates"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+// how long a layout is used from memory before it is read from the DB again
+const layoutCacheTTL = time.Minute
+
+type cachedLayout struct {
+	layoutDef string
+	fetchedAt time.Time
+}
+
+var (
+	layoutCache   = map[string]cachedLayout{}
+	layoutCacheMu sync.Mutex
+)
+
+func getLayoutDef(instanceID string, key string) (string, error) {
+	cacheKey := instanceID + "/" + key
+
+	layoutCacheMu.Lock()
+	cached, ok := layoutCache[cacheKey]
+	layoutCacheMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < layoutCacheTTL {
+		return cached.layoutDef, nil
+	}
+
+	if messageDBService == nil {
+		return "", fmt.Errorf("messaging DB not initialized, cannot load layout %s", key)
+	}
+	layout, err := messageDBService.GetEmailLayout(instanceID, key)
+	if err != nil {
+		return "", fmt.Errorf("error when loading layout %s: %v", key, err)
+	}
+	layoutDef, err := emailtemplates.DecodeLayout(*layout)
+	if err != nil {
+		return "", fmt.Errorf("error when decoding layout %s: %v", key, err)
+	}
+
+	layoutCacheMu.Lock()
+	layoutCache[cacheKey] = cachedLayout{layoutDef: layoutDef, fetchedAt: time.Now()}
+	layoutCacheMu.Unlock()
+	return layoutDef, nil
+}
+
+// renderTemplateContent renders the decoded template content, into the layout of the template if it has one
+func renderTemplateContent(
+	instanceID string,
+	templateDef messagingTypes.EmailTemplate,
+	templateName string,
+	content string,
+	payload map[string]string,
+) (string, error) {
+	if templateDef.Layout == "" {
+		return templates.ResolveTemplate(templateName, content, payload)
+	}
+
+	layoutDef, err := getLayoutDef(instanceID, templateDef.Layout)
+	if err != nil {
+		return "", err
+	}
+	return emailtemplates.RenderWithLayout(layoutDef, content, payload)
+}
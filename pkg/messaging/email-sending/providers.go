@@ -0,0 +1,174 @@
+package emailsending
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/tracing"
+)
+
+const (
+	EMAIL_PROVIDER_SMTP_BRIDGE = "smtp-bridge"
+	EMAIL_PROVIDER_SMTP        = "smtp"
+	EMAIL_PROVIDER_SES         = "ses"
+	EMAIL_PROVIDER_SENDGRID    = "sendgrid"
+	EMAIL_PROVIDER_MAILGUN     = "mailgun"
+)
+
+const (
+	defaultProviderRequestTimeout = 30 * time.Second
+)
+
+// EmailProvider delivers a prepared email
+type EmailProvider interface {
+	Name() string
+	Send(email *messagingTypes.OutgoingEmail) error
+}
+
+var (
+	defaultEmailProviders  []EmailProvider
+	instanceEmailProviders = map[string][]EmailProvider{}
+
+	// webhook secrets by provider name
+	defaultWebhookSecrets  = map[string]string{}
+	instanceWebhookSecrets = map[string]map[string]string{}
+)
+
+// InitEmailProviders sets up the providers used for sending. Without configured providers, emails are sent through the smtp bridge.
+func InitEmailProviders(
+	providers []messagingTypes.EmailProviderConfig,
+	instanceProviders map[string][]messagingTypes.EmailProviderConfig,
+) error {
+	var err error
+	defaultEmailProviders, err = newEmailProviders(providers)
+	if err != nil {
+		return err
+	}
+	defaultWebhookSecrets = webhookSecrets(providers)
+
+	instanceEmailProviders = map[string][]EmailProvider{}
+	instanceWebhookSecrets = map[string]map[string]string{}
+	for instanceID, configs := range instanceProviders {
+		p, err := newEmailProviders(configs)
+		if err != nil {
+			return fmt.Errorf("instance %s: %w", instanceID, err)
+		}
+		instanceEmailProviders[instanceID] = p
+		instanceWebhookSecrets[instanceID] = webhookSecrets(configs)
+	}
+	return nil
+}
+
+func webhookSecrets(configs []messagingTypes.EmailProviderConfig) map[string]string {
+	secrets := map[string]string{}
+	for _, conf := range configs {
+		provider := conf.Provider
+		if provider == "" {
+			provider = EMAIL_PROVIDER_SMTP_BRIDGE
+		}
+		if conf.WebhookSecret != "" {
+			secrets[provider] = conf.WebhookSecret
+		}
+	}
+	return secrets
+}
+
+func newEmailProviders(configs []messagingTypes.EmailProviderConfig) ([]EmailProvider, error) {
+	providers := []EmailProvider{}
+	for _, conf := range configs {
+		p, err := NewEmailProvider(conf)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+// NewEmailProvider creates the provider selected in the config
+func NewEmailProvider(config messagingTypes.EmailProviderConfig) (EmailProvider, error) {
+	client := &http.Client{Timeout: config.Timeout, Transport: tracing.NewTransport(nil)}
+	if config.Timeout <= 0 {
+		client.Timeout = defaultProviderRequestTimeout
+	}
+
+	switch config.Provider {
+	case "", EMAIL_PROVIDER_SMTP_BRIDGE:
+		return &smtpBridgeProvider{}, nil
+	case EMAIL_PROVIDER_SMTP:
+		return newSmtpProvider(config)
+	case EMAIL_PROVIDER_SES:
+		if config.APIKey == "" || config.APISecret == "" || config.Region == "" {
+			return nil, errors.New("api key, api secret and region required for ses")
+		}
+		if config.From == "" {
+			return nil, errors.New("from address required for ses")
+		}
+		return &sesProvider{config: config, client: client}, nil
+	case EMAIL_PROVIDER_SENDGRID:
+		if config.APIKey == "" || config.From == "" {
+			return nil, errors.New("api key and from address required for sendgrid")
+		}
+		return &sendgridProvider{config: config, client: client}, nil
+	case EMAIL_PROVIDER_MAILGUN:
+		if config.APIKey == "" || config.Domain == "" || config.From == "" {
+			return nil, errors.New("api key, domain and from address required for mailgun")
+		}
+		return &mailgunProvider{config: config, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown email provider: %s", config.Provider)
+	}
+}
+
+func getEmailProviders(instanceID string) []EmailProvider {
+	if p, ok := instanceEmailProviders[instanceID]; ok && len(p) > 0 {
+		return p
+	}
+	if len(defaultEmailProviders) > 0 {
+		return defaultEmailProviders
+	}
+	return []EmailProvider{&smtpBridgeProvider{}}
+}
+
+// sendWithFailover tries the providers in order until one succeeds
+func sendWithFailover(providers []EmailProvider, email *messagingTypes.OutgoingEmail) error {
+	var errs []error
+	allPermanent := true
+	for _, p := range providers {
+		err := p.Send(email)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("email provider failed", slog.String("provider", p.Name()), slog.String("messageType", email.MessageType), slog.String("error", err.Error()))
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		allPermanent = allPermanent && isPermanentError(err)
+	}
+	if len(errs) == 0 {
+		return errors.New("no email provider configured")
+	}
+	if allPermanent {
+		return fmt.Errorf("%w: %w", ErrPermanentFailure, errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// senderInfos applies the template header overrides to the provider defaults
+func senderInfos(config messagingTypes.EmailProviderConfig, overrides *messagingTypes.HeaderOverrides) (from string, replyTo []string) {
+	from = config.From
+	replyTo = config.ReplyTo
+	if overrides != nil {
+		if overrides.From != "" {
+			from = overrides.From
+		}
+		if overrides.NoReplyTo {
+			replyTo = []string{}
+		} else if len(overrides.ReplyTo) > 0 {
+			replyTo = overrides.ReplyTo
+		}
+	}
+	return
+}
@@ -0,0 +1,94 @@
+package emailsending
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+type mockProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *mockProvider) Name() string {
+	return p.name
+}
+
+func (p *mockProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	p.calls += 1
+	return p.err
+}
+
+func TestSendWithFailover(t *testing.T) {
+	email := &messagingTypes.OutgoingEmail{To: []string{"test@example.com"}}
+
+	t.Run("first provider succeeds", func(t *testing.T) {
+		p1 := &mockProvider{name: "p1"}
+		p2 := &mockProvider{name: "p2"}
+		if err := sendWithFailover([]EmailProvider{p1, p2}, email); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if p1.calls != 1 || p2.calls != 0 {
+			t.Errorf("unexpected calls: %d, %d", p1.calls, p2.calls)
+		}
+	})
+
+	t.Run("fails over to second provider", func(t *testing.T) {
+		p1 := &mockProvider{name: "p1", err: errors.New("down")}
+		p2 := &mockProvider{name: "p2"}
+		if err := sendWithFailover([]EmailProvider{p1, p2}, email); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if p1.calls != 1 || p2.calls != 1 {
+			t.Errorf("unexpected calls: %d, %d", p1.calls, p2.calls)
+		}
+	})
+
+	t.Run("all providers fail", func(t *testing.T) {
+		p1 := &mockProvider{name: "p1", err: errors.New("down")}
+		p2 := &mockProvider{name: "p2", err: errors.New("down too")}
+		if err := sendWithFailover([]EmailProvider{p1, p2}, email); err == nil {
+			t.Error("should fail with error")
+		}
+	})
+}
+
+func TestNewEmailProvider(t *testing.T) {
+	if _, err := NewEmailProvider(messagingTypes.EmailProviderConfig{Provider: "unknown"}); err == nil {
+		t.Error("should fail for unknown provider")
+	}
+	if _, err := NewEmailProvider(messagingTypes.EmailProviderConfig{Provider: EMAIL_PROVIDER_SES, APIKey: "key"}); err == nil {
+		t.Error("should fail for incomplete ses config")
+	}
+	p, err := NewEmailProvider(messagingTypes.EmailProviderConfig{})
+	if err != nil || p.Name() != EMAIL_PROVIDER_SMTP_BRIDGE {
+		t.Errorf("unexpected default provider: %v, %v", p, err)
+	}
+}
+
+func TestSenderInfos(t *testing.T) {
+	conf := messagingTypes.EmailProviderConfig{From: "default@example.com", ReplyTo: []string{"reply@example.com"}}
+
+	from, replyTo := senderInfos(conf, nil)
+	if from != "default@example.com" || len(replyTo) != 1 {
+		t.Errorf("unexpected sender infos: %s, %v", from, replyTo)
+	}
+
+	from, replyTo = senderInfos(conf, &messagingTypes.HeaderOverrides{From: "study@example.com", NoReplyTo: true})
+	if from != "study@example.com" || len(replyTo) != 0 {
+		t.Errorf("unexpected sender infos: %s, %v", from, replyTo)
+	}
+}
+
+func TestAWSSigningKey(t *testing.T) {
+	// example from the AWS signature version 4 documentation
+	key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if hex.EncodeToString(key) != expected {
+		t.Errorf("unexpected signing key: %s", hex.EncodeToString(key))
+	}
+}
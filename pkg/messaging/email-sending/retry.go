@@ -0,0 +1,95 @@
+package emailsending
+
+import (
+	"errors"
+	"net/textproto"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	defaultMaxSendAttempts = 10
+	defaultInitialBackoff  = time.Minute
+	defaultMaxBackoff      = 6 * time.Hour
+)
+
+// ErrPermanentFailure is returned if all providers rejected the email in a way that a retry will not fix
+var ErrPermanentFailure = errors.New("permanent delivery failure")
+
+var retryConfig = messagingTypes.EmailRetryConfig{
+	MaxAttempts:    defaultMaxSendAttempts,
+	InitialBackoff: defaultInitialBackoff,
+	MaxBackoff:     defaultMaxBackoff,
+}
+
+// InitEmailRetry sets the retry policy for outgoing emails, unset values keep their defaults
+func InitEmailRetry(config messagingTypes.EmailRetryConfig) {
+	if config.MaxAttempts > 0 {
+		retryConfig.MaxAttempts = config.MaxAttempts
+	}
+	if config.InitialBackoff > 0 {
+		retryConfig.InitialBackoff = config.InitialBackoff
+	}
+	if config.MaxBackoff > 0 {
+		retryConfig.MaxBackoff = config.MaxBackoff
+	}
+}
+
+// permanentError marks provider errors that should not be retried
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func isPermanentError(err error) bool {
+	var pErr permanentError
+	if errors.As(err, &pErr) {
+		return true
+	}
+	// smtp reply codes 5xx are permanent, 4xx are transient
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code >= 500
+	}
+	return false
+}
+
+// retryBackoff returns the delay before the next attempt, doubling with each failed attempt
+func retryBackoff(attempts int, initial time.Duration, max time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := initial
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= max {
+			return max
+		}
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
+
+// RegisterFailedAttempt updates the retry state of the email after a failed send attempt.
+// Returns true if the email should not be retried anymore.
+func RegisterFailedAttempt(email *messagingTypes.OutgoingEmail, sendErr error, now time.Time) (giveUp bool) {
+	email.SendAttempts += 1
+	email.LastError = sendErr.Error()
+	if errors.Is(sendErr, ErrPermanentFailure) || email.SendAttempts >= retryConfig.MaxAttempts {
+		email.FailedAt = now.Unix()
+		email.NextAttemptAt = 0
+		return true
+	}
+	email.NextAttemptAt = now.Add(retryBackoff(email.SendAttempts, retryConfig.InitialBackoff, retryConfig.MaxBackoff)).Unix()
+	return false
+}
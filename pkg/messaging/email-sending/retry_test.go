@@ -0,0 +1,104 @@
+package emailsending
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{10, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if d := retryBackoff(tt.attempts, time.Minute, time.Hour); d != tt.expected {
+			t.Errorf("unexpected backoff for %d attempts: %s, expected %s", tt.attempts, d, tt.expected)
+		}
+	}
+}
+
+func TestIsPermanentError(t *testing.T) {
+	if isPermanentError(errors.New("connection refused")) {
+		t.Error("plain errors should be transient")
+	}
+	if !isPermanentError(fmt.Errorf("wrapped: %w", permanentError{err: errors.New("bad request")})) {
+		t.Error("wrapped permanent error not detected")
+	}
+	if !isPermanentError(&textproto.Error{Code: 550, Msg: "mailbox unavailable"}) {
+		t.Error("smtp 5xx should be permanent")
+	}
+	if isPermanentError(&textproto.Error{Code: 421, Msg: "try again later"}) {
+		t.Error("smtp 4xx should be transient")
+	}
+}
+
+type failingProvider struct {
+	err error
+}
+
+func (p *failingProvider) Name() string { return "failing" }
+func (p *failingProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	return p.err
+}
+
+func TestSendWithFailoverPermanentErrors(t *testing.T) {
+	email := &messagingTypes.OutgoingEmail{MessageType: "test"}
+	permanent := &failingProvider{err: permanentError{err: errors.New("rejected")}}
+	transient := &failingProvider{err: errors.New("timeout")}
+
+	err := sendWithFailover([]EmailProvider{permanent, permanent}, email)
+	if !errors.Is(err, ErrPermanentFailure) {
+		t.Errorf("expected permanent failure, got %v", err)
+	}
+
+	err = sendWithFailover([]EmailProvider{permanent, transient}, email)
+	if err == nil || errors.Is(err, ErrPermanentFailure) {
+		t.Errorf("expected transient failure, got %v", err)
+	}
+}
+
+func TestRegisterFailedAttempt(t *testing.T) {
+	defer func(c messagingTypes.EmailRetryConfig) { retryConfig = c }(retryConfig)
+	InitEmailRetry(messagingTypes.EmailRetryConfig{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Hour})
+
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	email := messagingTypes.OutgoingEmail{}
+
+	if RegisterFailedAttempt(&email, errors.New("timeout"), now) {
+		t.Fatal("should retry after first attempt")
+	}
+	if email.SendAttempts != 1 || email.NextAttemptAt != now.Add(time.Minute).Unix() || email.LastError != "timeout" {
+		t.Errorf("unexpected retry state: %+v", email)
+	}
+
+	if RegisterFailedAttempt(&email, errors.New("timeout"), now) {
+		t.Fatal("should retry after second attempt")
+	}
+	if email.NextAttemptAt != now.Add(2*time.Minute).Unix() {
+		t.Errorf("unexpected next attempt: %d", email.NextAttemptAt)
+	}
+
+	if !RegisterFailedAttempt(&email, errors.New("timeout"), now) {
+		t.Fatal("should give up after max attempts")
+	}
+	if email.FailedAt != now.Unix() || email.NextAttemptAt != 0 {
+		t.Errorf("unexpected state after giving up: %+v", email)
+	}
+
+	other := messagingTypes.OutgoingEmail{}
+	if !RegisterFailedAttempt(&other, fmt.Errorf("%w: rejected", ErrPermanentFailure), now) {
+		t.Error("should give up on permanent failures")
+	}
+}
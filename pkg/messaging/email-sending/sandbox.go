@@ -0,0 +1,47 @@
+package emailsending
+
+import (
+	"log/slog"
+
+	"github.com/case-framework/case-backend/pkg/messaging/sandbox"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+)
+
+// sendSandboxedEmail captures the email instead of sending it to the recipients, and delivers it to the
+// catch-all address if one is configured
+func sendSandboxedEmail(instanceID string, outgoing *messagingTypes.OutgoingEmail) error {
+	captured := messagingTypes.CapturedMessage{
+		Channel:      messagingTypes.SANDBOX_CHANNEL_EMAIL,
+		MessageType:  outgoing.MessageType,
+		To:           outgoing.To,
+		RedirectedTo: sandbox.CatchAllEmail(),
+		Subject:      outgoing.Subject,
+		Content:      outgoing.Content,
+	}
+	if err := sandbox.Capture(instanceID, captured); err != nil {
+		return err
+	}
+
+	if captured.RedirectedTo != "" {
+		email := *outgoing
+		email.To = []string{captured.RedirectedTo}
+		email.Subject = sandbox.SUBJECT_PREFIX + email.Subject
+		instrumentEmailForTracking(instanceID, &email)
+		if err := sendWithFailover(getEmailProviders(instanceID), &email); err != nil {
+			return err
+		}
+		countSentForTracking(instanceID, &email)
+	}
+
+	slog.Debug("email captured by sandbox", slog.String("instanceID", instanceID), slog.String("messageType", outgoing.MessageType), slog.String("redirectedTo", captured.RedirectedTo))
+
+	go webhooks.Publish(instanceID, webhooks.EVENT_MESSAGE_SENT, map[string]any{
+		"channel":        "email",
+		"messageType":    outgoing.MessageType,
+		"recipientCount": len(outgoing.To),
+		"sandbox":        true,
+	})
+	publishEmailSent(instanceID, outgoing, true)
+	return nil
+}
@@ -0,0 +1,57 @@
+package emailsending
+
+import (
+	"testing"
+
+	"github.com/case-framework/case-backend/pkg/messaging/sandbox"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+type recordingProvider struct {
+	sent []messagingTypes.OutgoingEmail
+}
+
+func (p *recordingProvider) Name() string {
+	return "recording"
+}
+
+func (p *recordingProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	p.sent = append(p.sent, *email)
+	return nil
+}
+
+func TestSendOutgoingEmailInSandbox(t *testing.T) {
+	provider := &recordingProvider{}
+	instanceEmailProviders["sandboxed"] = []EmailProvider{provider}
+	defer delete(instanceEmailProviders, "sandboxed")
+	defer sandbox.Init(nil, messagingTypes.SandboxConfig{})
+
+	email := &messagingTypes.OutgoingEmail{To: []string{"participant@example.com"}, Subject: "Hello"}
+
+	t.Run("captured only", func(t *testing.T) {
+		sandbox.Init(nil, messagingTypes.SandboxConfig{EnabledInstances: []string{"sandboxed"}})
+		if err := SendOutgoingEmail("sandboxed", email); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(provider.sent) != 0 {
+			t.Errorf("expected no email to be sent, got %d", len(provider.sent))
+		}
+	})
+
+	t.Run("redirected to catch-all", func(t *testing.T) {
+		sandbox.Init(nil, messagingTypes.SandboxConfig{EnabledInstances: []string{"sandboxed"}, CatchAllEmail: "qa@example.com"})
+		if err := SendOutgoingEmail("sandboxed", email); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(provider.sent) != 1 {
+			t.Fatalf("expected one email to be sent, got %d", len(provider.sent))
+		}
+		sent := provider.sent[0]
+		if len(sent.To) != 1 || sent.To[0] != "qa@example.com" || sent.Subject != sandbox.SUBJECT_PREFIX+"Hello" {
+			t.Errorf("unexpected email: %+v", sent)
+		}
+		if email.To[0] != "participant@example.com" {
+			t.Error("original email should not be modified")
+		}
+	})
+}
@@ -3,59 +3,107 @@ package emailsending
 import (
 	"errors"
 	"log/slog"
+	"sync"
+	"time"
 
 	messageDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
 	httpclient "github.com/case-framework/case-backend/pkg/http-client"
+	"github.com/case-framework/case-backend/pkg/messaging/sandbox"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	smtp_client "github.com/case-framework/case-backend/pkg/smtp-client"
 )
 
 var (
 	HttpClient       *httpclient.ClientConfig
 	messageDBService *messageDB.MessagingDBService
 
-	GlobalTemplateInfos = map[string]string{}
+	// replaced as a whole when the config is reloaded, the maps are never modified
+	globalTemplateInfos   = map[string]string{}
+	globalTemplateInfosMu sync.RWMutex
 )
 
 func InitMessageSendingVariables(
 	newClientConfig *httpclient.ClientConfig,
-	globalTemplateInfos map[string]string,
+	templateInfos map[string]string,
 	mdb *messageDB.MessagingDBService,
 ) {
 	HttpClient = newClientConfig
-	GlobalTemplateInfos = globalTemplateInfos
+	SetGlobalTemplateInfos(templateInfos)
 	messageDBService = mdb
 }
 
+// SetGlobalTemplateInfos replaces the constants available in all email templates
+func SetGlobalTemplateInfos(infos map[string]string) {
+	if infos == nil {
+		infos = map[string]string{}
+	}
+	globalTemplateInfosMu.Lock()
+	defer globalTemplateInfosMu.Unlock()
+	globalTemplateInfos = infos
+}
+
+// GlobalTemplateInfos returns the constants available in all email templates, the map must not be modified
+func GlobalTemplateInfos() map[string]string {
+	globalTemplateInfosMu.RLock()
+	defer globalTemplateInfosMu.RUnlock()
+	return globalTemplateInfos
+}
+
 type SendEmailReq struct {
 	To              []string                        `json:"to"`
 	Subject         string                          `json:"subject"`
 	Content         string                          `json:"content"`
 	HighPrio        bool                            `json:"highPrio"`
 	HeaderOverrides *messagingTypes.HeaderOverrides `json:"headerOverrides"`
+	Attachments     []smtp_client.Attachment        `json:"attachments,omitempty"`
 }
 
+// SendOutgoingEmail sends the email with the providers of the instance, falling back to the next provider on errors.
+// Recipients on the suppression list are removed, ErrAllRecipientsSuppressed is returned if none are left.
 func SendOutgoingEmail(
+	instanceID string,
 	outgoing *messagingTypes.OutgoingEmail,
 ) error {
-	if HttpClient == nil || HttpClient.RootURL == "" {
-		return errors.New("connection to smtp bridge not initialized")
+	if sandbox.IsEnabled(instanceID) {
+		return sendSandboxedEmail(instanceID, outgoing)
 	}
 
-	sendEmailReq := SendEmailReq{
-		To:              outgoing.To,
-		Subject:         outgoing.Subject,
-		Content:         outgoing.Content,
-		HighPrio:        outgoing.HighPrio,
-		HeaderOverrides: outgoing.HeaderOverrides,
+	if err := removeSuppressedRecipients(instanceID, outgoing); err != nil {
+		return err
 	}
-	resp, err := HttpClient.RunHTTPcall("/send-email", sendEmailReq)
-	if err == nil && resp != nil {
-		errMsg, hasError := resp["error"]
-		if hasError {
-			err = errors.New(errMsg.(string))
-		}
+
+	// instrument a copy, so that the stored email keeps the original content
+	email := *outgoing
+	instrumentEmailForTracking(instanceID, &email)
+	if err := sendWithFailover(getEmailProviders(instanceID), &email); err != nil {
+		return err
+	}
+	countSentForTracking(instanceID, &email)
+
+	go webhooks.Publish(instanceID, webhooks.EVENT_MESSAGE_SENT, map[string]any{
+		"channel":        "email",
+		"messageType":    outgoing.MessageType,
+		"recipientCount": len(outgoing.To),
+	})
+	publishEmailSent(instanceID, outgoing, false)
+	return nil
+}
+
+// publishEmailSent publishes the domain event of a sent email, instant emails are not stored and have no ID
+func publishEmailSent(instanceID string, outgoing *messagingTypes.OutgoingEmail, sandbox bool) {
+	data := map[string]any{
+		"messageType":    outgoing.MessageType,
+		"recipientCount": len(outgoing.To),
+	}
+	if !outgoing.ID.IsZero() {
+		data["emailId"] = outgoing.ID.Hex()
+	}
+	if sandbox {
+		data["sandbox"] = true
 	}
-	return err
+	domainevents.Publish(instanceID, domainevents.EVENT_EMAIL_SENT, data)
 }
 
 func SendInstantEmailByTemplate(
@@ -68,8 +116,25 @@ func SendInstantEmailByTemplate(
 	useLowPrio bool,
 	expiresAt int64,
 ) error {
-	if HttpClient == nil || HttpClient.RootURL == "" {
-		return errors.New("connection to smtp bridge not initialized")
+	return SendInstantEmailWithAttachments(instanceID, to, messageType, studyKey, lang, payload, useLowPrio, expiresAt, nil)
+}
+
+// SendInstantEmailWithAttachments sends the email generated from the template with files from the filestore attached.
+// The attachments are checked against the size limits before anything is sent.
+func SendInstantEmailWithAttachments(
+	instanceID string,
+	to []string,
+	messageType string,
+	studyKey string,
+	lang string,
+	payload map[string]string,
+	useLowPrio bool,
+	expiresAt int64,
+	attachments []messagingTypes.EmailAttachment,
+) error {
+	attachments, err := PrepareAttachments(attachments)
+	if err != nil {
+		return err
 	}
 
 	outgoingEmail, err := prepOutgoingEmail(
@@ -86,11 +151,23 @@ func SendInstantEmailByTemplate(
 		return err
 	}
 	outgoingEmail.ExpiresAt = expiresAt
+	outgoingEmail.Attachments = attachments
 
 	// send email
-	err = SendOutgoingEmail(outgoingEmail)
+	err = SendOutgoingEmail(instanceID, outgoingEmail)
+	if errors.Is(err, ErrAllRecipientsSuppressed) {
+		return err
+	}
 	if err != nil {
 		slog.Debug("error while sending email", slog.String("error", err.Error()))
+		if RegisterFailedAttempt(outgoingEmail, err, time.Now()) {
+			if _, errS := messageDBService.AddToFailedEmails(instanceID, *outgoingEmail); errS != nil {
+				slog.Error("failed to save failed email", slog.String("error", errS.Error()))
+				return errS
+			}
+			slog.Warn("email could not be delivered and was moved to failed emails", slog.String("instanceID", instanceID), slog.String("messageType", messageType), slog.String("error", err.Error()))
+			return err
+		}
 		_, errS := messageDBService.AddToOutgoingEmails(instanceID, *outgoingEmail)
 		if errS != nil {
 			slog.Error("failed to save outgoing email", slog.String("error", errS.Error()))
@@ -0,0 +1,92 @@
+package emailsending
+
+import (
+	"errors"
+	"fmt"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	smtp_client "github.com/case-framework/case-backend/pkg/smtp-client"
+)
+
+// smtpBridgeProvider forwards emails to the smtp bridge service
+type smtpBridgeProvider struct{}
+
+func (p *smtpBridgeProvider) Name() string {
+	return EMAIL_PROVIDER_SMTP_BRIDGE
+}
+
+func (p *smtpBridgeProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	if HttpClient == nil || HttpClient.RootURL == "" {
+		return errors.New("connection to smtp bridge not initialized")
+	}
+
+	attachments, err := loadAttachments(email)
+	if err != nil {
+		return err
+	}
+
+	sendEmailReq := SendEmailReq{
+		To:              email.To,
+		Subject:         email.Subject,
+		Content:         email.Content,
+		HighPrio:        email.HighPrio,
+		HeaderOverrides: email.HeaderOverrides,
+		Attachments:     attachments,
+	}
+	resp, err := HttpClient.RunHTTPcall("/send-email", sendEmailReq)
+	if err == nil && resp != nil {
+		errMsg, hasError := resp["error"]
+		if hasError {
+			err = errors.New(errMsg.(string))
+		}
+	}
+	return err
+}
+
+// smtpProvider sends emails directly through a pool of smtp servers
+type smtpProvider struct {
+	clients *smtp_client.SmtpClients
+}
+
+func newSmtpProvider(config messagingTypes.EmailProviderConfig) (p *smtpProvider, err error) {
+	if config.SmtpServersFile == "" {
+		return nil, errors.New("smtp servers file required for smtp provider")
+	}
+
+	servers := smtp_client.SmtpServerList{}
+	if err := servers.ReadFromFile(config.SmtpServersFile); err != nil {
+		return nil, err
+	}
+	if config.From != "" {
+		servers.From = config.From
+	}
+	if len(config.ReplyTo) > 0 {
+		servers.ReplyTo = config.ReplyTo
+	}
+
+	// the smtp client panics if none of the servers can be reached
+	defer func() {
+		if r := recover(); r != nil {
+			p = nil
+			err = fmt.Errorf("failed to connect to smtp servers: %v", r)
+		}
+	}()
+
+	clients, err := smtp_client.NewSmtpClients(servers)
+	if err != nil {
+		return nil, err
+	}
+	return &smtpProvider{clients: clients}, nil
+}
+
+func (p *smtpProvider) Name() string {
+	return EMAIL_PROVIDER_SMTP
+}
+
+func (p *smtpProvider) Send(email *messagingTypes.OutgoingEmail) error {
+	attachments, err := loadAttachments(email)
+	if err != nil {
+		return err
+	}
+	return p.clients.SendMail(email.To, email.Subject, email.Content, email.HeaderOverrides, attachments...)
+}
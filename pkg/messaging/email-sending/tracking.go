@@ -0,0 +1,103 @@
+package emailsending
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+var trackingConfig *messagingTypes.EmailTrackingConfig
+
+var (
+	trackableLinkRegex = regexp.MustCompile(`(?i)href\s*=\s*"(https?://[^"]+)"`)
+	closingBodyRegex   = regexp.MustCompile(`(?i)</body>`)
+)
+
+// InitEmailTracking sets up open and click tracking. Tracking stays disabled without base url and signing key.
+func InitEmailTracking(config messagingTypes.EmailTrackingConfig) {
+	if config.BaseURL == "" || config.SigningKey == "" || len(config.EnabledInstances) == 0 {
+		trackingConfig = nil
+		return
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	trackingConfig = &config
+}
+
+// IsTrackingEnabled returns true if the instance opted in to email tracking
+func IsTrackingEnabled(instanceID string) bool {
+	return trackingConfig != nil && slices.Contains(trackingConfig.EnabledInstances, instanceID)
+}
+
+func campaignForEmail(email *messagingTypes.OutgoingEmail) string {
+	if email.Campaign != "" {
+		return email.Campaign
+	}
+	return email.MessageType
+}
+
+func trackingSignature(instanceID string, campaign string, target string) string {
+	mac := hmac.New(sha256.New, []byte(trackingConfig.SigningKey))
+	mac.Write([]byte(instanceID + "\n" + campaign + "\n" + target))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyTrackingSignature checks the signature of a tracking url. Use an empty target for open tracking.
+func VerifyTrackingSignature(instanceID string, campaign string, target string, signature string) bool {
+	if trackingConfig == nil {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(trackingSignature(instanceID, campaign, target)))
+}
+
+func trackingURL(path string, instanceID string, campaign string, target string) string {
+	q := url.Values{}
+	q.Set("c", campaign)
+	if target != "" {
+		q.Set("url", target)
+	}
+	q.Set("sig", trackingSignature(instanceID, campaign, target))
+	return trackingConfig.BaseURL + "/" + path + "/" + url.PathEscape(instanceID) + "?" + q.Encode()
+}
+
+// instrumentEmailForTracking adds a tracking pixel and replaces links with redirect links. High priority (transactional) emails are never instrumented.
+func instrumentEmailForTracking(instanceID string, email *messagingTypes.OutgoingEmail) {
+	if !IsTrackingEnabled(instanceID) || email.HighPrio {
+		return
+	}
+	campaign := campaignForEmail(email)
+
+	content := trackableLinkRegex.ReplaceAllStringFunc(email.Content, func(match string) string {
+		target := html.UnescapeString(trackableLinkRegex.FindStringSubmatch(match)[1])
+		return `href="` + html.EscapeString(trackingURL("click", instanceID, campaign, target)) + `"`
+	})
+
+	pixel := `<img src="` + html.EscapeString(trackingURL("open", instanceID, campaign, "")) + `" width="1" height="1" alt="" style="display:none" />`
+	if loc := closingBodyRegex.FindStringIndex(content); loc != nil {
+		content = content[:loc[0]] + pixel + content[loc[0]:]
+	} else {
+		content += pixel
+	}
+	email.Content = content
+}
+
+func countSentForTracking(instanceID string, email *messagingTypes.OutgoingEmail) {
+	if !IsTrackingEnabled(instanceID) || email.HighPrio || messageDBService == nil {
+		return
+	}
+	if err := messageDBService.IncrementEmailTrackingCounter(instanceID, campaignForEmail(email), messagingTypes.EMAIL_TRACKING_COUNTER_SENT, int64(len(email.To))); err != nil {
+		slog.Error("failed to count sent email for tracking", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+	}
+}
+
+// OnEmailTrackingEvent increases the open or click counter of the campaign
+func OnEmailTrackingEvent(instanceID string, campaign string, counter string) error {
+	return messageDBService.IncrementEmailTrackingCounter(instanceID, campaign, counter, 1)
+}
@@ -0,0 +1,72 @@
+package emailsending
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func TestInstrumentEmailForTracking(t *testing.T) {
+	InitEmailTracking(messagingTypes.EmailTrackingConfig{
+		BaseURL:          "https://example.com/track/",
+		SigningKey:       "key",
+		EnabledInstances: []string{"tracked"},
+	})
+	defer InitEmailTracking(messagingTypes.EmailTrackingConfig{})
+
+	content := `<html><body><a href="https://example.com/study?a=1&amp;b=2">link</a><a href="mailto:info@example.com">mail</a></body></html>`
+
+	t.Run("instance without opt-in", func(t *testing.T) {
+		email := &messagingTypes.OutgoingEmail{MessageType: "weekly", Content: content}
+		instrumentEmailForTracking("other", email)
+		if email.Content != content {
+			t.Errorf("content should not be changed: %s", email.Content)
+		}
+	})
+
+	t.Run("high priority email", func(t *testing.T) {
+		email := &messagingTypes.OutgoingEmail{MessageType: "password-reset", Content: content, HighPrio: true}
+		instrumentEmailForTracking("tracked", email)
+		if email.Content != content {
+			t.Errorf("content should not be changed: %s", email.Content)
+		}
+	})
+
+	t.Run("instrumented email", func(t *testing.T) {
+		email := &messagingTypes.OutgoingEmail{MessageType: "weekly", Campaign: "campaign-1", Content: content}
+		instrumentEmailForTracking("tracked", email)
+
+		if !strings.Contains(email.Content, `href="mailto:info@example.com"`) {
+			t.Error("mailto link should not be replaced")
+		}
+		if strings.Contains(email.Content, `href="https://example.com/study`) {
+			t.Error("link should be replaced")
+		}
+		if !strings.Contains(email.Content, `https://example.com/track/click/tracked?`) {
+			t.Errorf("click tracking link missing: %s", email.Content)
+		}
+		if !strings.Contains(email.Content, `https://example.com/track/open/tracked?`) || !strings.HasSuffix(email.Content, "</body></html>") {
+			t.Errorf("tracking pixel missing or misplaced: %s", email.Content)
+		}
+	})
+
+	t.Run("signature of replaced link", func(t *testing.T) {
+		target := "https://example.com/study?a=1&b=2"
+		u, err := url.Parse(trackingURL("click", "tracked", "campaign-1", target))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		q := u.Query()
+		if q.Get("url") != target {
+			t.Errorf("unexpected target: %s", q.Get("url"))
+		}
+		if !VerifyTrackingSignature("tracked", q.Get("c"), q.Get("url"), q.Get("sig")) {
+			t.Error("signature should be valid")
+		}
+		if VerifyTrackingSignature("tracked", q.Get("c"), "https://evil.example.com", q.Get("sig")) {
+			t.Error("signature should not be valid for other targets")
+		}
+	})
+}
@@ -4,8 +4,6 @@ import (
 	"encoding/base64"
 
 	messageDB "github.com/case-framework/case-backend/pkg/db/messaging"
-	emailtemplates "github.com/case-framework/case-backend/pkg/messaging/email-templates"
-	"github.com/case-framework/case-backend/pkg/messaging/templates"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 )
 
@@ -33,7 +31,10 @@ func prepOutgoingEmail(
 		return nil, err
 	}
 
-	translation := emailtemplates.GetTemplateTranslation(*templateDef, lang)
+	translation, err := getTranslationWithFallback(instanceID, *templateDef, lang)
+	if err != nil {
+		return nil, err
+	}
 
 	decodedTemplate, err := base64.StdEncoding.DecodeString(translation.TemplateDef)
 	if err != nil {
@@ -43,14 +44,16 @@ func prepOutgoingEmail(
 	if payload == nil {
 		payload = map[string]string{}
 	}
-	for k, v := range GlobalTemplateInfos {
+	for k, v := range GlobalTemplateInfos() {
 		payload[k] = v
 	}
 
 	payload["language"] = lang
 	// execute template
 	templateName := instanceID + messageType + studyKey + lang
-	content, err := templates.ResolveTemplate(
+	content, err := renderTemplateContent(
+		instanceID,
+		*templateDef,
 		templateName,
 		string(decodedTemplate),
 		payload,
@@ -71,11 +74,15 @@ func prepOutgoingEmail(
 }
 
 func GenerateEmailContent(
+	instanceID string,
 	templateDef messagingTypes.EmailTemplate,
 	lang string,
 	payload map[string]string,
 ) (string, string, error) {
-	translation := emailtemplates.GetTemplateTranslation(templateDef, lang)
+	translation, err := getTranslationWithFallback(instanceID, templateDef, lang)
+	if err != nil {
+		return "", "", err
+	}
 
 	decodedTemplate, err := base64.StdEncoding.DecodeString(translation.TemplateDef)
 	if err != nil {
@@ -85,13 +92,15 @@ func GenerateEmailContent(
 	if payload == nil {
 		payload = map[string]string{}
 	}
-	for k, v := range GlobalTemplateInfos {
+	for k, v := range GlobalTemplateInfos() {
 		payload[k] = v
 	}
 
 	// execute template
 	templateName := templateDef.ID.Hex() + lang
-	content, err := templates.ResolveTemplate(
+	content, err := renderTemplateContent(
+		instanceID,
+		templateDef,
 		templateName,
 		string(decodedTemplate),
 		payload,
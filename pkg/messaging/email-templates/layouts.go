@@ -0,0 +1,157 @@
+package emailtemplates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+	"sync"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+// Name of the block a template fills if it does not define any blocks itself
+const LAYOUT_CONTENT_BLOCK = "content"
+
+const maxCompiledLayoutCacheSize = 500
+
+var (
+	compiledLayoutCache   = map[string]*template.Template{}
+	compiledLayoutCacheMu sync.RWMutex
+
+	defineRegex = regexp.MustCompile(`{{-?\s*define\s`)
+)
+
+// LayoutFuncs are helpers available in layouts and templates using them. They produce table based markup,
+// which renders consistently in Outlook and other email clients with limited CSS support.
+var LayoutFuncs = template.FuncMap{
+	"button":  layoutButton,
+	"spacer":  layoutSpacer,
+	"divider": layoutDivider,
+}
+
+// CompileWithLayout parses the layout and the template content into one template. The content either defines
+// the blocks of the layout with {{ define "name" }}, or is used as the "content" block as a whole.
+// Compiled templates are cached by their source.
+func CompileWithLayout(layoutDef string, contentDef string) (*template.Template, error) {
+	if strings.TrimSpace(layoutDef) == "" {
+		return nil, errors.New("empty layout")
+	}
+
+	hash := sha256.Sum256([]byte(layoutDef + "\x00" + contentDef))
+	cacheKey := hex.EncodeToString(hash[:])
+
+	compiledLayoutCacheMu.RLock()
+	tmpl, ok := compiledLayoutCache[cacheKey]
+	compiledLayoutCacheMu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.New("layout").Funcs(LayoutFuncs).Parse(layoutDef)
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing layout: %v", err)
+	}
+
+	if !defineRegex.MatchString(contentDef) {
+		contentDef = `{{ define "` + LAYOUT_CONTENT_BLOCK + `" }}` + contentDef + `{{ end }}`
+	}
+	if _, err = tmpl.Parse(contentDef); err != nil {
+		return nil, fmt.Errorf("error when parsing template content: %v", err)
+	}
+
+	compiledLayoutCacheMu.Lock()
+	if len(compiledLayoutCache) >= maxCompiledLayoutCacheSize {
+		// layouts change rarely, so starting over is cheaper than tracking usage
+		compiledLayoutCache = map[string]*template.Template{}
+	}
+	compiledLayoutCache[cacheKey] = tmpl
+	compiledLayoutCacheMu.Unlock()
+	return tmpl, nil
+}
+
+// RenderWithLayout compiles the template content into the layout and executes it with the payload
+func RenderWithLayout(layoutDef string, contentDef string, payload map[string]string) (string, error) {
+	tmpl, err := CompileWithLayout(layoutDef, contentDef)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, "layout", payload); err != nil {
+		return "", fmt.Errorf("error during executing layout: %v", err)
+	}
+	return out.String(), nil
+}
+
+func safeURL(url string) string {
+	lower := strings.ToLower(strings.TrimSpace(url))
+	if strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "mailto:") {
+		return template.HTMLEscapeString(strings.TrimSpace(url))
+	}
+	return "#"
+}
+
+// layoutButton renders a link styled as a button, with the optional background and text color
+func layoutButton(url string, label string, colors ...string) template.HTML {
+	bg := "#333333"
+	fg := "#ffffff"
+	if len(colors) > 0 && colors[0] != "" {
+		bg = colors[0]
+	}
+	if len(colors) > 1 && colors[1] != "" {
+		fg = colors[1]
+	}
+	bg = template.HTMLEscapeString(bg)
+	fg = template.HTMLEscapeString(fg)
+	href := safeURL(url)
+	text := template.HTMLEscapeString(label)
+
+	return template.HTML(`<table role="presentation" border="0" cellpadding="0" cellspacing="0" style="margin:0 auto;"><tr>` +
+		`<td align="center" bgcolor="` + bg + `" style="border-radius:4px;background:` + bg + `;">` +
+		`<a href="` + href + `" target="_blank" style="display:inline-block;padding:12px 24px;font-family:Arial,sans-serif;font-size:16px;color:` + fg + `;text-decoration:none;border-radius:4px;">` + text + `</a>` +
+		`</td></tr></table>`)
+}
+
+// layoutSpacer renders vertical space of the given height in pixels
+func layoutSpacer(height int) template.HTML {
+	if height < 0 {
+		height = 0
+	}
+	return template.HTML(fmt.Sprintf(`<table role="presentation" border="0" cellpadding="0" cellspacing="0" width="100%%"><tr><td height="%d" style="height:%dpx;font-size:%dpx;line-height:%dpx;">&nbsp;</td></tr></table>`, height, height, height, height))
+}
+
+// layoutDivider renders a horizontal line
+func layoutDivider() template.HTML {
+	return template.HTML(`<table role="presentation" border="0" cellpadding="0" cellspacing="0" width="100%"><tr><td style="border-top:1px solid #dddddd;font-size:1px;line-height:1px;">&nbsp;</td></tr></table>`)
+}
+
+// DecodeLayout returns the decoded layout definition
+func DecodeLayout(layout messagingTypes.EmailLayout) (string, error) {
+	return decodeTemplateDef(layout.TemplateDef)
+}
+
+// CheckAllTranslationsWithLayout checks that every translation of the template can be rendered into the layout
+func CheckAllTranslationsWithLayout(tDef messagingTypes.EmailTemplate, layout messagingTypes.EmailLayout) error {
+	if len(tDef.Translations) == 0 {
+		return errors.New("error when decoding template: translation list is empty")
+	}
+	layoutDef, err := DecodeLayout(layout)
+	if err != nil {
+		return fmt.Errorf("error when decoding layout %s: %v", layout.Key, err)
+	}
+	for _, tr := range tDef.Translations {
+		content, err := decodeTemplateDef(tr.TemplateDef)
+		if err != nil {
+			return fmt.Errorf("error when decoding template %s: %v", tDef.MessageType+tr.Lang, err)
+		}
+		if _, err := RenderWithLayout(layoutDef, content, map[string]string{}); err != nil {
+			return errors.New("could not resolve template for `" + tr.Lang + "` - error: " + err.Error())
+		}
+	}
+	return nil
+}
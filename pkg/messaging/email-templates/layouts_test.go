@@ -0,0 +1,64 @@
+package emailtemplates
+
+import (
+	"strings"
+	"testing"
+)
+
+const testLayout = `<html><head><title>{{ block "title" . }}Default title{{ end }}</title></head>` +
+	`<body>{{ block "content" . }}{{ end }}{{ divider }}<p>footer {{ .language }}</p></body></html>`
+
+func TestRenderWithLayout(t *testing.T) {
+	t.Run("content without defines fills the content block", func(t *testing.T) {
+		out, err := RenderWithLayout(testLayout, `<p>Hello {{ .name }}</p>`, map[string]string{"name": "<b>Ann</b>", "language": "en"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "<p>Hello &lt;b&gt;Ann&lt;/b&gt;</p>") {
+			t.Errorf("content not rendered or not escaped: %s", out)
+		}
+		if !strings.Contains(out, "Default title") || !strings.Contains(out, "footer en") {
+			t.Errorf("layout not rendered: %s", out)
+		}
+	})
+
+	t.Run("content defining blocks", func(t *testing.T) {
+		out, err := RenderWithLayout(testLayout, `{{ define "title" }}Custom{{ end }}{{ define "content" }}{{ button .link "Open" }}{{ end }}`, map[string]string{"link": "https://example.com/?a=1&b=2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "<title>Custom</title>") {
+			t.Errorf("title block not replaced: %s", out)
+		}
+		if !strings.Contains(out, `href="https://example.com/?a=1&amp;b=2"`) {
+			t.Errorf("button not rendered: %s", out)
+		}
+	})
+
+	t.Run("unsafe button url", func(t *testing.T) {
+		out, err := RenderWithLayout(testLayout, `{{ button "javascript:alert(1)" "x" }}`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out, "javascript") {
+			t.Errorf("unsafe url rendered: %s", out)
+		}
+	})
+
+	t.Run("invalid layout", func(t *testing.T) {
+		if _, err := RenderWithLayout(`{{ block "content" . }}`, "x", nil); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("compiled template is cached", func(t *testing.T) {
+		a, err := CompileWithLayout(testLayout, "cached")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := CompileWithLayout(testLayout, "cached")
+		if a != b {
+			t.Error("expected cached template")
+		}
+	})
+}
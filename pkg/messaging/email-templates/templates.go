@@ -1,6 +1,10 @@
 package emailtemplates
 
 import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+
 	"github.com/case-framework/case-backend/pkg/messaging/templates"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 )
@@ -12,3 +16,110 @@ func GetTemplateTranslation(tDef messagingTypes.EmailTemplate, lang string) mess
 func CheckAllTranslationsParsable(tempTranslations messagingTypes.EmailTemplate) (err error) {
 	return templates.CheckAllTranslationsParsable(tempTranslations.Translations, tempTranslations.MessageType)
 }
+
+// TranslationDiff describes the changes of one language between two template versions
+type TranslationDiff struct {
+	Lang           string               `json:"lang"`
+	Status         string               `json:"status"`
+	SubjectChanged bool                 `json:"subjectChanged"`
+	OldSubject     string               `json:"oldSubject,omitempty"`
+	NewSubject     string               `json:"newSubject,omitempty"`
+	Content        []templates.DiffLine `json:"content,omitempty"`
+}
+
+const (
+	TRANSLATION_DIFF_ADDED     = "added"
+	TRANSLATION_DIFF_REMOVED   = "removed"
+	TRANSLATION_DIFF_CHANGED   = "changed"
+	TRANSLATION_DIFF_UNCHANGED = "unchanged"
+)
+
+type EmailTemplateDiff struct {
+	DefaultLanguageChanged bool              `json:"defaultLanguageChanged"`
+	HeaderOverridesChanged bool              `json:"headerOverridesChanged"`
+	Translations           []TranslationDiff `json:"translations"`
+}
+
+// DiffEmailTemplates compares two versions of a template language by language, on the decoded template content
+func DiffEmailTemplates(oldTemplate messagingTypes.EmailTemplate, newTemplate messagingTypes.EmailTemplate) (EmailTemplateDiff, error) {
+	diff := EmailTemplateDiff{
+		DefaultLanguageChanged: oldTemplate.DefaultLanguage != newTemplate.DefaultLanguage,
+		HeaderOverridesChanged: !reflect.DeepEqual(oldTemplate.HeaderOverrides, newTemplate.HeaderOverrides),
+		Translations:           []TranslationDiff{},
+	}
+
+	langs := []string{}
+	oldTranslations := map[string]messagingTypes.LocalizedTemplate{}
+	newTranslations := map[string]messagingTypes.LocalizedTemplate{}
+	for _, tr := range oldTemplate.Translations {
+		oldTranslations[tr.Lang] = tr
+		langs = append(langs, tr.Lang)
+	}
+	for _, tr := range newTemplate.Translations {
+		newTranslations[tr.Lang] = tr
+		if _, ok := oldTranslations[tr.Lang]; !ok {
+			langs = append(langs, tr.Lang)
+		}
+	}
+
+	for _, lang := range langs {
+		oldTr, inOld := oldTranslations[lang]
+		newTr, inNew := newTranslations[lang]
+
+		oldContent, err := decodeTemplateDef(oldTr.TemplateDef)
+		if err != nil {
+			return diff, fmt.Errorf("error when decoding old template for %s: %v", lang, err)
+		}
+		newContent, err := decodeTemplateDef(newTr.TemplateDef)
+		if err != nil {
+			return diff, fmt.Errorf("error when decoding new template for %s: %v", lang, err)
+		}
+
+		trDiff := TranslationDiff{
+			Lang:           lang,
+			SubjectChanged: oldTr.Subject != newTr.Subject,
+			OldSubject:     oldTr.Subject,
+			NewSubject:     newTr.Subject,
+			Content:        templates.DiffLines(oldContent, newContent),
+		}
+		switch {
+		case !inOld:
+			trDiff.Status = TRANSLATION_DIFF_ADDED
+		case !inNew:
+			trDiff.Status = TRANSLATION_DIFF_REMOVED
+		case trDiff.SubjectChanged || templates.HasChanges(trDiff.Content):
+			trDiff.Status = TRANSLATION_DIFF_CHANGED
+		default:
+			trDiff.Status = TRANSLATION_DIFF_UNCHANGED
+		}
+		diff.Translations = append(diff.Translations, trDiff)
+	}
+	return diff, nil
+}
+
+// FindUnknownPlaceholders returns per language the placeholders that are not in the list of known variables
+func FindUnknownPlaceholders(tDef messagingTypes.EmailTemplate, knownVariables []string) (map[string][]string, error) {
+	unknown := map[string][]string{}
+	for _, tr := range tDef.Translations {
+		content, err := decodeTemplateDef(tr.TemplateDef)
+		if err != nil {
+			return nil, fmt.Errorf("error when decoding template for %s: %v", tr.Lang, err)
+		}
+		placeholders, err := templates.ExtractPlaceholders(content)
+		if err != nil {
+			return nil, fmt.Errorf("error in template for %s: %v", tr.Lang, err)
+		}
+		if u := templates.FindUnknownPlaceholders(placeholders, knownVariables); len(u) > 0 {
+			unknown[tr.Lang] = u
+		}
+	}
+	return unknown, nil
+}
+
+func decodeTemplateDef(templateDef string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(templateDef)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,67 @@
+package emailtemplates
+
+import (
+	"encoding/base64"
+	"testing"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestDiffEmailTemplates(t *testing.T) {
+	oldTemplate := messagingTypes.EmailTemplate{
+		DefaultLanguage: "en",
+		Translations: []messagingTypes.LocalizedTemplate{
+			{Lang: "en", Subject: "Hello", TemplateDef: encode("<p>Hi</p>\n<p>{{ .token }}</p>")},
+			{Lang: "de", Subject: "Hallo", TemplateDef: encode("<p>Hallo</p>")},
+		},
+	}
+	newTemplate := messagingTypes.EmailTemplate{
+		DefaultLanguage: "en",
+		Translations: []messagingTypes.LocalizedTemplate{
+			{Lang: "en", Subject: "Hello", TemplateDef: encode("<p>Hello</p>\n<p>{{ .token }}</p>")},
+			{Lang: "fr", Subject: "Bonjour", TemplateDef: encode("<p>Bonjour</p>")},
+		},
+	}
+
+	diff, err := DiffEmailTemplates(oldTemplate, newTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.DefaultLanguageChanged || diff.HeaderOverridesChanged {
+		t.Errorf("unexpected template level changes: %v", diff)
+	}
+
+	expected := map[string]string{
+		"en": TRANSLATION_DIFF_CHANGED,
+		"de": TRANSLATION_DIFF_REMOVED,
+		"fr": TRANSLATION_DIFF_ADDED,
+	}
+	if len(diff.Translations) != len(expected) {
+		t.Fatalf("unexpected number of translations: %v", diff.Translations)
+	}
+	for _, tr := range diff.Translations {
+		if tr.Status != expected[tr.Lang] {
+			t.Errorf("unexpected status for %s: %s", tr.Lang, tr.Status)
+		}
+	}
+}
+
+func TestFindUnknownPlaceholders(t *testing.T) {
+	tDef := messagingTypes.EmailTemplate{
+		Translations: []messagingTypes.LocalizedTemplate{
+			{Lang: "en", TemplateDef: encode("{{ .token }} {{ .unknownValue }}")},
+			{Lang: "de", TemplateDef: encode("{{ .token }}")},
+		},
+	}
+	unknown, err := FindUnknownPlaceholders(tDef, []string{"token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unknown) != 1 || len(unknown["en"]) != 1 || unknown["en"][0] != "unknownValue" {
+		t.Errorf("unexpected unknown placeholders: %v", unknown)
+	}
+}
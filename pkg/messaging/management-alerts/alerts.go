@@ -0,0 +1,101 @@
+// Package managementalerts emails system alerts, e.g. about finished exports or failed jobs, to the management user
+// who started the work, if the user subscribed to the alert type.
+package managementalerts
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"slices"
+	"strings"
+
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	ALERT_EXPORT_COMPLETED = "export-completed"
+	ALERT_JOB_FAILED       = "job-failed"
+	ALERT_RULE_ERRORS      = "rule-errors"
+)
+
+// Alert types management users can subscribe to
+var SupportedAlerts = []string{
+	ALERT_EXPORT_COMPLETED,
+	ALERT_JOB_FAILED,
+	ALERT_RULE_ERRORS,
+}
+
+const MESSAGE_TYPE_MANAGEMENT_ALERT = "management-alert"
+
+type Alert struct {
+	Type     string
+	StudyKey string
+	// short description used as subject, e.g. "Export job failed"
+	Title string
+	// listed in the email in this order, empty values are skipped
+	Details [][2]string
+}
+
+var (
+	muDBService        *muDB.ManagementUserDBService
+	messagingDBService *messagingDB.MessagingDBService
+)
+
+// Init enables sending alerts, without it Send does nothing
+func Init(mudb *muDB.ManagementUserDBService, mdb *messagingDB.MessagingDBService) {
+	muDBService = mudb
+	messagingDBService = mdb
+}
+
+func IsSupportedAlert(alertType string) bool {
+	return slices.Contains(SupportedAlerts, alertType)
+}
+
+// Send queues the alert email for the management user, if the user subscribed to the alert type. Users that do not
+// exist, e.g. service accounts, are skipped.
+func Send(instanceID string, userID string, alert Alert) {
+	if muDBService == nil || messagingDBService == nil || userID == "" {
+		return
+	}
+
+	user, err := muDBService.GetUserByID(instanceID, userID)
+	if err != nil || user.Email == "" || !slices.Contains(user.AlertSubscriptions, alert.Type) {
+		return
+	}
+
+	subject, content := alertEmail(alert)
+	_, err = messagingDBService.AddToOutgoingEmails(instanceID, messagingTypes.OutgoingEmail{
+		MessageType: MESSAGE_TYPE_MANAGEMENT_ALERT,
+		To:          []string{user.Email},
+		Subject:     subject,
+		Content:     content,
+		HighPrio:    true,
+	})
+	if err != nil {
+		slog.Error("failed to queue management alert", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("type", alert.Type), slog.String("error", err.Error()))
+	}
+}
+
+func alertEmail(alert Alert) (subject string, content string) {
+	subject = alert.Title
+	if alert.StudyKey != "" {
+		subject = fmt.Sprintf("[%s] %s", alert.StudyKey, alert.Title)
+	}
+
+	var b strings.Builder
+	b.WriteString("<p>" + html.EscapeString(subject) + "</p>")
+	if len(alert.Details) > 0 {
+		b.WriteString("<ul>")
+		for _, line := range alert.Details {
+			if line[1] == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "<li>%s: %s</li>", html.EscapeString(line[0]), html.EscapeString(line[1]))
+		}
+		b.WriteString("</ul>")
+	}
+	b.WriteString("<p>You receive this email because you subscribed to " + html.EscapeString(alert.Type) + " alerts in the management API.</p>")
+	return subject, b.String()
+}
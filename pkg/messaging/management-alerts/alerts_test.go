@@ -0,0 +1,39 @@
+package managementalerts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAlertEmail(t *testing.T) {
+	subject, content := alertEmail(Alert{
+		Type:     ALERT_JOB_FAILED,
+		StudyKey: "study1",
+		Title:    "Export job failed",
+		Details: [][2]string{
+			{"Job", "abc"},
+			{"Error", "<unexpected>"},
+			{"Empty", ""},
+		},
+	})
+	if subject != "[study1] Export job failed" {
+		t.Errorf("unexpected subject %s", subject)
+	}
+	if !strings.Contains(content, "<li>Job: abc</li>") || !strings.Contains(content, "&lt;unexpected&gt;") {
+		t.Errorf("unexpected content %s", content)
+	}
+	if strings.Contains(content, "Empty") {
+		t.Errorf("empty values should be skipped: %s", content)
+	}
+}
+
+func TestSendWithoutInit(t *testing.T) {
+	// must not panic if alerts are not enabled, e.g. in services without management user DB
+	Send("i1", "u1", Alert{Type: ALERT_EXPORT_COMPLETED})
+}
+
+func TestIsSupportedAlert(t *testing.T) {
+	if !IsSupportedAlert(ALERT_RULE_ERRORS) || IsSupportedAlert("unknown") {
+		t.Errorf("unexpected result")
+	}
+}
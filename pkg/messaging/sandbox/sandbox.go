@@ -0,0 +1,45 @@
+package sandbox
+
+import (
+	"slices"
+
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+// prefix of the subject of emails redirected to the catch-all address
+const SUBJECT_PREFIX = "[SANDBOX] "
+
+var (
+	messagingDBService *messagingDB.MessagingDBService
+	config             messagingTypes.SandboxConfig
+)
+
+// Init sets the instances whose outgoing messages are captured instead of sent
+func Init(mdb *messagingDB.MessagingDBService, conf messagingTypes.SandboxConfig) {
+	messagingDBService = mdb
+	config = conf
+}
+
+// IsEnabled checks if messages of the instance are captured
+func IsEnabled(instanceID string) bool {
+	return slices.Contains(config.EnabledInstances, instanceID)
+}
+
+// CatchAllEmail returns the address captured emails are delivered to, empty if they are only stored
+func CatchAllEmail() string {
+	return config.CatchAllEmail
+}
+
+// CatchAllPhoneNumber returns the phone number captured SMS are delivered to, empty if they are only stored
+func CatchAllPhoneNumber() string {
+	return config.CatchAllPhoneNumber
+}
+
+// Capture stores the message in the sandbox collection of the instance
+func Capture(instanceID string, message messagingTypes.CapturedMessage) error {
+	if messagingDBService == nil {
+		return nil
+	}
+	return messagingDBService.AddCapturedMessage(instanceID, message)
+}
@@ -0,0 +1,105 @@
+package smssending
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+type DeliveryStatusUpdate struct {
+	ProviderMessageID string `json:"messageId"`
+	Status            string `json:"status"`
+	ErrorCode         string `json:"errorCode,omitempty"`
+}
+
+// VerifyCallbackSecret checks the secret sent along with a delivery status callback
+func VerifyCallbackSecret(config *types.SMSGatewayConfig, secret string) bool {
+	if config == nil || config.StatusCallbackSecret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(config.StatusCallbackSecret)) == 1
+}
+
+// ParseDeliveryStatusCallback extracts the delivery status from the callback request of the given provider
+func ParseDeliveryStatusCallback(provider string, r *http.Request) (DeliveryStatusUpdate, error) {
+	var update DeliveryStatusUpdate
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		values := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&values); err != nil {
+			return update, err
+		}
+		update = parseCallbackValues(provider, func(key string) string {
+			v, ok := values[key]
+			if !ok || v == nil {
+				return ""
+			}
+			if s, ok := v.(string); ok {
+				return s
+			}
+			b, _ := json.Marshal(v)
+			return string(b)
+		})
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return update, err
+		}
+		update = parseCallbackValues(provider, r.Form.Get)
+	}
+
+	if update.ProviderMessageID == "" {
+		return update, errors.New("message id missing in status callback")
+	}
+	return update, nil
+}
+
+func parseCallbackValues(provider string, get func(key string) string) DeliveryStatusUpdate {
+	switch provider {
+	case SMS_PROVIDER_TWILIO:
+		return DeliveryStatusUpdate{
+			ProviderMessageID: get("MessageSid"),
+			Status:            mapTwilioStatus(get("MessageStatus")),
+			ErrorCode:         get("ErrorCode"),
+		}
+	case SMS_PROVIDER_VONAGE:
+		return DeliveryStatusUpdate{
+			ProviderMessageID: get("messageId"),
+			Status:            mapVonageStatus(get("status")),
+			ErrorCode:         get("err-code"),
+		}
+	case "", SMS_PROVIDER_CM:
+		return DeliveryStatusUpdate{
+			ProviderMessageID: get("reference"),
+			Status:            mapCMStatus(get("status")),
+			ErrorCode:         get("errorCode"),
+		}
+	default:
+		status := get("status")
+		if status == "" {
+			status = SMS_STATUS_UNKNOWN
+		}
+		return DeliveryStatusUpdate{
+			ProviderMessageID: get("messageId"),
+			Status:            status,
+			ErrorCode:         get("errorCode"),
+		}
+	}
+}
+
+// mapCMStatus maps the numeric status of CM status reports
+func mapCMStatus(status string) string {
+	switch status {
+	case "0":
+		return SMS_STATUS_SENT
+	case "2":
+		return SMS_STATUS_DELIVERED
+	case "1", "3":
+		return SMS_STATUS_FAILED
+	default:
+		return SMS_STATUS_UNKNOWN
+	}
+}
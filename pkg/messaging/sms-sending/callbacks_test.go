@@ -0,0 +1,88 @@
+package smssending
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func TestParseDeliveryStatusCallback(t *testing.T) {
+	t.Run("twilio form callback", func(t *testing.T) {
+		form := url.Values{}
+		form.Set("MessageSid", "SM123")
+		form.Set("MessageStatus", "undelivered")
+		form.Set("ErrorCode", "30003")
+		req := httptest.NewRequest(http.MethodPost, "/status", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		update, err := ParseDeliveryStatusCallback(SMS_PROVIDER_TWILIO, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if update.ProviderMessageID != "SM123" || update.Status != SMS_STATUS_FAILED || update.ErrorCode != "30003" {
+			t.Errorf("unexpected update: %v", update)
+		}
+	})
+
+	t.Run("vonage json callback", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/status", strings.NewReader(`{"messageId": "abc", "status": "delivered", "err-code": "0"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		update, err := ParseDeliveryStatusCallback(SMS_PROVIDER_VONAGE, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if update.ProviderMessageID != "abc" || update.Status != SMS_STATUS_DELIVERED {
+			t.Errorf("unexpected update: %v", update)
+		}
+	})
+
+	t.Run("cm query callback", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/status?reference=ref1&status=2", nil)
+
+		update, err := ParseDeliveryStatusCallback(SMS_PROVIDER_CM, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if update.ProviderMessageID != "ref1" || update.Status != SMS_STATUS_DELIVERED {
+			t.Errorf("unexpected update: %v", update)
+		}
+	})
+
+	t.Run("missing message id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/status?status=delivered", nil)
+		if _, err := ParseDeliveryStatusCallback(SMS_PROVIDER_HTTP_GATEWAY, req); err == nil {
+			t.Error("should fail with error")
+		}
+	})
+}
+
+func TestVerifyCallbackSecret(t *testing.T) {
+	if VerifyCallbackSecret(&types.SMSGatewayConfig{}, "") {
+		t.Error("callbacks without configured secret should be rejected")
+	}
+	conf := &types.SMSGatewayConfig{StatusCallbackSecret: "s3cret"}
+	if VerifyCallbackSecret(conf, "wrong") {
+		t.Error("wrong secret should be rejected")
+	}
+	if !VerifyCallbackSecret(conf, "s3cret") {
+		t.Error("correct secret should be accepted")
+	}
+}
+
+func TestNewSMSProvider(t *testing.T) {
+	if _, err := NewSMSProvider(&types.SMSGatewayConfig{Provider: "unknown", URL: "http://localhost"}); err == nil {
+		t.Error("should fail for unknown provider")
+	}
+	if _, err := NewSMSProvider(&types.SMSGatewayConfig{Provider: SMS_PROVIDER_TWILIO}); err == nil {
+		t.Error("should fail for missing twilio credentials")
+	}
+	p, err := NewSMSProvider(&types.SMSGatewayConfig{URL: "http://localhost"})
+	if err != nil || p.Name() != SMS_PROVIDER_CM {
+		t.Errorf("unexpected default provider: %v, %v", p, err)
+	}
+}
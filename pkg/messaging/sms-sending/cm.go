@@ -1,4 +1,4 @@
-package sms
+package smssending
 
 import (
 	"bytes"
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
 )
 
 type SMSTo struct {
@@ -22,6 +24,7 @@ type SingleSMS struct {
 	From            string   `json:"from"`
 	To              []SMSTo  `json:"to"`
 	Body            SMSBody  `json:"body"`
+	Reference       string   `json:"reference,omitempty"`
 }
 
 type SMSAuth struct {
@@ -35,9 +38,19 @@ type SMSSendingReq struct {
 	} `json:"messages"`
 }
 
-func runSMSsending(to string, message string, from string) error {
-	if SmsGatewayConfig == nil || SmsGatewayConfig.URL == "" {
-		return errors.New("connection to sms gateway not initialized")
+type cmProvider struct {
+	config *types.SMSGatewayConfig
+	client *http.Client
+}
+
+func (p *cmProvider) Name() string {
+	return SMS_PROVIDER_CM
+}
+
+func (p *cmProvider) Send(to string, from string, message string) (SendResult, error) {
+	reference, err := newMessageReference()
+	if err != nil {
+		return SendResult{}, err
 	}
 
 	payload := SMSSendingReq{
@@ -46,7 +59,7 @@ func runSMSsending(to string, message string, from string) error {
 			Msg            []SingleSMS `json:"msg"`
 		}{
 			Authentication: SMSAuth{
-				Producttoken: SmsGatewayConfig.APIKey,
+				Producttoken: p.config.APIKey,
 			},
 			Msg: []SingleSMS{
 				{
@@ -61,6 +74,7 @@ func runSMSsending(to string, message string, from string) error {
 						Type:    "auto",
 						Content: message,
 					},
+					Reference: reference,
 				},
 			},
 		},
@@ -68,26 +82,27 @@ func runSMSsending(to string, message string, from string) error {
 
 	json_data, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return SendResult{}, err
 	}
 
-	resp, err := http.Post(SmsGatewayConfig.URL, "application/json", bytes.NewBuffer(json_data))
+	resp, err := p.client.Post(p.config.URL, "application/json", bytes.NewBuffer(json_data))
 	if err != nil {
-		return err
+		return SendResult{}, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		slog.Error("sms gateway returned error", slog.String("status", resp.Status))
-		return errors.New("sms gateway returned error")
+		return SendResult{}, errors.New("sms gateway returned error")
 	}
 
 	var res map[string]interface{}
 	err = json.NewDecoder(resp.Body).Decode(&res)
 	if err != nil {
 		slog.Error("Error decoding response", slog.String("error", err.Error()))
-		return err
+		return SendResult{}, err
 	}
 
 	slog.Debug("sms gateway response", slog.Any("response", res))
-	return nil
+	return SendResult{ProviderMessageID: reference, Status: SMS_STATUS_SENT}, nil
 }
@@ -0,0 +1,79 @@
+package smssending
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+// httpGatewayProvider posts messages as JSON to a custom gateway
+type httpGatewayProvider struct {
+	config *types.SMSGatewayConfig
+	client *http.Client
+}
+
+type httpGatewaySendReq struct {
+	To                string `json:"to"`
+	From              string `json:"from"`
+	Message           string `json:"message"`
+	StatusCallbackURL string `json:"statusCallbackUrl,omitempty"`
+}
+
+type httpGatewaySendResponse struct {
+	MessageID string `json:"messageId"`
+	Status    string `json:"status"`
+}
+
+func (p *httpGatewayProvider) Name() string {
+	return SMS_PROVIDER_HTTP_GATEWAY
+}
+
+func (p *httpGatewayProvider) Send(to string, from string, message string) (SendResult, error) {
+	json_data, err := json.Marshal(httpGatewaySendReq{
+		To:                to,
+		From:              from,
+		Message:           message,
+		StatusCallbackURL: p.config.StatusCallbackURL,
+	})
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.config.URL, bytes.NewBuffer(json_data))
+	if err != nil {
+		return SendResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	}
+	for k, v := range p.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return SendResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		slog.Error("sms gateway returned error", slog.String("status", resp.Status))
+		return SendResult{}, errors.New("sms gateway returned error")
+	}
+
+	var res httpGatewaySendResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		// gateways are not required to return a body
+		slog.Debug("no message infos in sms gateway response", slog.String("error", err.Error()))
+	}
+	status := res.Status
+	if status == "" {
+		status = SMS_STATUS_SENT
+	}
+	return SendResult{ProviderMessageID: res.MessageID, Status: status}, nil
+}
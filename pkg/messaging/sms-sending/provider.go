@@ -0,0 +1,87 @@
+package smssending
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	SMS_PROVIDER_CM           = "cm"
+	SMS_PROVIDER_TWILIO       = "twilio"
+	SMS_PROVIDER_VONAGE       = "vonage"
+	SMS_PROVIDER_HTTP_GATEWAY = "http"
+)
+
+const (
+	SMS_STATUS_QUEUED    = "queued"
+	SMS_STATUS_SENT      = "sent"
+	SMS_STATUS_DELIVERED = "delivered"
+	SMS_STATUS_FAILED    = "failed"
+	SMS_STATUS_UNKNOWN   = "unknown"
+)
+
+const (
+	defaultRequestTimeout = 10 * time.Second
+)
+
+type SendResult struct {
+	ProviderMessageID string
+	Status            string
+}
+
+// SMSProvider sends a single text message through an SMS gateway
+type SMSProvider interface {
+	Name() string
+	Send(to string, from string, message string) (SendResult, error)
+}
+
+// NewSMSProvider creates the provider selected in the config
+func NewSMSProvider(config *types.SMSGatewayConfig) (SMSProvider, error) {
+	if config == nil {
+		return nil, errors.New("sms provider config missing")
+	}
+
+	client := &http.Client{Timeout: config.Timeout}
+	if config.Timeout <= 0 {
+		client.Timeout = defaultRequestTimeout
+	}
+
+	switch config.Provider {
+	case "", SMS_PROVIDER_CM:
+		if config.URL == "" {
+			return nil, errors.New("url missing for cm sms provider")
+		}
+		return &cmProvider{config: config, client: client}, nil
+	case SMS_PROVIDER_TWILIO:
+		if config.AccountID == "" || config.APISecret == "" {
+			return nil, errors.New("account id and api secret required for twilio sms provider")
+		}
+		return &twilioProvider{config: config, client: client}, nil
+	case SMS_PROVIDER_VONAGE:
+		if config.APIKey == "" || config.APISecret == "" {
+			return nil, errors.New("api key and api secret required for vonage sms provider")
+		}
+		return &vonageProvider{config: config, client: client}, nil
+	case SMS_PROVIDER_HTTP_GATEWAY:
+		if config.URL == "" {
+			return nil, errors.New("url missing for http sms gateway")
+		}
+		return &httpGatewayProvider{config: config, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown sms provider: %s", config.Provider)
+	}
+}
+
+func newMessageReference() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
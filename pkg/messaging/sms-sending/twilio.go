@@ -0,0 +1,88 @@
+package smssending
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	twilioDefaultAPIURL = "https://api.twilio.com"
+)
+
+type twilioProvider struct {
+	config *types.SMSGatewayConfig
+	client *http.Client
+}
+
+type twilioMessageResponse struct {
+	SID     string `json:"sid"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func (p *twilioProvider) Name() string {
+	return SMS_PROVIDER_TWILIO
+}
+
+func (p *twilioProvider) Send(to string, from string, message string) (SendResult, error) {
+	baseURL := p.config.URL
+	if baseURL == "" {
+		baseURL = twilioDefaultAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimSuffix(baseURL, "/"), url.PathEscape(p.config.AccountID))
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", from)
+	form.Set("Body", message)
+	if p.config.StatusCallbackURL != "" {
+		form.Set("StatusCallback", p.config.StatusCallbackURL)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return SendResult{}, err
+	}
+	req.SetBasicAuth(p.config.AccountID, p.config.APISecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return SendResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var res twilioMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		slog.Error("Error decoding twilio response", slog.String("error", err.Error()))
+		return SendResult{}, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		slog.Error("twilio returned error", slog.String("status", resp.Status), slog.String("message", res.Message))
+		return SendResult{}, fmt.Errorf("twilio returned error: %s", res.Message)
+	}
+
+	return SendResult{ProviderMessageID: res.SID, Status: mapTwilioStatus(res.Status)}, nil
+}
+
+func mapTwilioStatus(status string) string {
+	switch status {
+	case "accepted", "scheduled", "queued", "sending":
+		return SMS_STATUS_QUEUED
+	case "sent":
+		return SMS_STATUS_SENT
+	case "delivered":
+		return SMS_STATUS_DELIVERED
+	case "failed", "undelivered", "canceled":
+		return SMS_STATUS_FAILED
+	default:
+		return SMS_STATUS_UNKNOWN
+	}
+}
@@ -0,0 +1,105 @@
+package smssending
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	vonageDefaultAPIURL = "https://rest.nexmo.com"
+)
+
+type vonageProvider struct {
+	config *types.SMSGatewayConfig
+	client *http.Client
+}
+
+type vonageSendReq struct {
+	APIKey    string `json:"api_key"`
+	APISecret string `json:"api_secret"`
+	To        string `json:"to"`
+	From      string `json:"from"`
+	Text      string `json:"text"`
+	Type      string `json:"type"`
+	Callback  string `json:"callback,omitempty"`
+}
+
+type vonageSendResponse struct {
+	Messages []struct {
+		Status    string `json:"status"`
+		MessageID string `json:"message-id"`
+		ErrorText string `json:"error-text"`
+	} `json:"messages"`
+}
+
+func (p *vonageProvider) Name() string {
+	return SMS_PROVIDER_VONAGE
+}
+
+func (p *vonageProvider) Send(to string, from string, message string) (SendResult, error) {
+	baseURL := p.config.URL
+	if baseURL == "" {
+		baseURL = vonageDefaultAPIURL
+	}
+
+	payload := vonageSendReq{
+		APIKey:    p.config.APIKey,
+		APISecret: p.config.APISecret,
+		// Vonage expects numbers in E.164 format without leading +
+		To:       strings.TrimPrefix(to, "+"),
+		From:     from,
+		Text:     message,
+		Type:     "unicode",
+		Callback: p.config.StatusCallbackURL,
+	}
+	json_data, err := json.Marshal(payload)
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	resp, err := p.client.Post(strings.TrimSuffix(baseURL, "/")+"/sms/json", "application/json", bytes.NewBuffer(json_data))
+	if err != nil {
+		return SendResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		slog.Error("vonage returned error", slog.String("status", resp.Status))
+		return SendResult{}, errors.New("vonage returned error")
+	}
+
+	var res vonageSendResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		slog.Error("Error decoding vonage response", slog.String("error", err.Error()))
+		return SendResult{}, err
+	}
+	if len(res.Messages) < 1 {
+		return SendResult{}, errors.New("vonage response contains no messages")
+	}
+
+	msg := res.Messages[0]
+	if msg.Status != "0" {
+		return SendResult{}, fmt.Errorf("vonage rejected message: %s", msg.ErrorText)
+	}
+	return SendResult{ProviderMessageID: msg.MessageID, Status: SMS_STATUS_SENT}, nil
+}
+
+func mapVonageStatus(status string) string {
+	switch status {
+	case "accepted", "buffered":
+		return SMS_STATUS_QUEUED
+	case "delivered":
+		return SMS_STATUS_DELIVERED
+	case "expired", "failed", "rejected":
+		return SMS_STATUS_FAILED
+	default:
+		return SMS_STATUS_UNKNOWN
+	}
+}
@@ -2,16 +2,24 @@ package sms
 
 import (
 	"encoding/base64"
+	"errors"
+	"log/slog"
 	"time"
 
 	messageDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	"github.com/case-framework/case-backend/pkg/messaging/sandbox"
+	smssending "github.com/case-framework/case-backend/pkg/messaging/sms-sending"
 	"github.com/case-framework/case-backend/pkg/messaging/templates"
 	"github.com/case-framework/case-backend/pkg/messaging/types"
 )
 
 var (
-	SmsGatewayConfig *types.SMSGatewayConfig
-	MessageDBService *messageDB.MessagingDBService
+	SmsGatewayConfig   *types.SMSGatewayConfig
+	InstanceSMSConfigs map[string]*types.SMSGatewayConfig
+	MessageDBService   *messageDB.MessagingDBService
+
+	defaultProvider   smssending.SMSProvider
+	instanceProviders = map[string]smssending.SMSProvider{}
 )
 
 const (
@@ -21,10 +29,51 @@ const (
 
 func Init(
 	smsGatewayConfig *types.SMSGatewayConfig,
+	instanceSMSConfigs map[string]*types.SMSGatewayConfig,
 	mdb *messageDB.MessagingDBService,
 ) {
 	SmsGatewayConfig = smsGatewayConfig
+	InstanceSMSConfigs = instanceSMSConfigs
 	MessageDBService = mdb
+
+	defaultProvider = nil
+	instanceProviders = map[string]smssending.SMSProvider{}
+
+	if smsGatewayConfig != nil {
+		p, err := smssending.NewSMSProvider(smsGatewayConfig)
+		if err != nil {
+			slog.Error("failed to init sms provider", slog.String("error", err.Error()))
+		} else {
+			defaultProvider = p
+		}
+	}
+
+	for instanceID, conf := range instanceSMSConfigs {
+		p, err := smssending.NewSMSProvider(conf)
+		if err != nil {
+			slog.Error("failed to init sms provider for instance", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+		instanceProviders[instanceID] = p
+	}
+}
+
+func getProvider(instanceID string) (smssending.SMSProvider, error) {
+	if p, ok := instanceProviders[instanceID]; ok {
+		return p, nil
+	}
+	if defaultProvider == nil {
+		return nil, errors.New("connection to sms gateway not initialized")
+	}
+	return defaultProvider, nil
+}
+
+// GetSMSConfig returns the provider config used for the instance
+func GetSMSConfig(instanceID string) *types.SMSGatewayConfig {
+	if conf, ok := InstanceSMSConfigs[instanceID]; ok {
+		return conf
+	}
+	return SmsGatewayConfig
 }
 
 func SendSMS(instanceID string, to string, userID string, messageType string, lang string, payload map[string]string) error {
@@ -57,18 +106,43 @@ func SendSMS(instanceID string, to string, userID string, messageType string, la
 		return err
 	}
 
+	if sandbox.IsEnabled(instanceID) {
+		captured := types.CapturedMessage{
+			Channel:      types.SANDBOX_CHANNEL_SMS,
+			MessageType:  messageType,
+			To:           []string{to},
+			RedirectedTo: sandbox.CatchAllPhoneNumber(),
+			Content:      content,
+		}
+		if err := sandbox.Capture(instanceID, captured); err != nil {
+			return err
+		}
+		if captured.RedirectedTo == "" {
+			return nil
+		}
+		to = captured.RedirectedTo
+	}
+
+	provider, err := getProvider(instanceID)
+	if err != nil {
+		return err
+	}
+
 	// send sms
-	err = runSMSsending(to, content, templateDef.From)
+	result, err := provider.Send(to, templateDef.From, content)
 	if err != nil {
 		return err
 	}
 
 	// save sent sms
 	_, err = MessageDBService.AddToSentSMS(instanceID, types.SentSMS{
-		MessageType: messageType,
-		PhoneNumber: to,
-		UserID:      userID,
-		SentAt:      time.Now(),
+		MessageType:       messageType,
+		PhoneNumber:       to,
+		UserID:            userID,
+		SentAt:            time.Now(),
+		Provider:          provider.Name(),
+		ProviderMessageID: result.ProviderMessageID,
+		Status:            result.Status,
 	})
 	if err != nil {
 		return err
@@ -76,3 +150,8 @@ func SendSMS(instanceID string, to string, userID string, messageType string, la
 
 	return nil
 }
+
+// OnDeliveryStatusUpdate stores the delivery status reported by the provider
+func OnDeliveryStatusUpdate(instanceID string, update smssending.DeliveryStatusUpdate) error {
+	return MessageDBService.UpdateSentSMSStatus(instanceID, update.ProviderMessageID, update.Status)
+}
@@ -0,0 +1,78 @@
+package templates
+
+import "strings"
+
+const (
+	DIFF_OP_EQUAL  = "equal"
+	DIFF_OP_INSERT = "insert"
+	DIFF_OP_DELETE = "delete"
+)
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines computes a line based diff between two texts using the longest common subsequence
+func DiffLines(oldText string, newText string) []DiffLine {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := []DiffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DIFF_OP_EQUAL, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DIFF_OP_DELETE, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DIFF_OP_INSERT, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: DIFF_OP_DELETE, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: DIFF_OP_INSERT, Text: b[j]})
+	}
+	return diff
+}
+
+// HasChanges returns true if the diff contains any inserted or deleted line
+func HasChanges(diff []DiffLine) bool {
+	for _, l := range diff {
+		if l.Op != DIFF_OP_EQUAL {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
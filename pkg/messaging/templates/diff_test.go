@@ -0,0 +1,36 @@
+package templates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	t.Run("identical", func(t *testing.T) {
+		diff := DiffLines("a\nb", "a\nb")
+		if HasChanges(diff) || len(diff) != 2 {
+			t.Errorf("unexpected diff: %v", diff)
+		}
+	})
+
+	t.Run("changed line", func(t *testing.T) {
+		diff := DiffLines("a\nb\nc", "a\nx\nc\nd")
+		expected := []DiffLine{
+			{Op: DIFF_OP_EQUAL, Text: "a"},
+			{Op: DIFF_OP_DELETE, Text: "b"},
+			{Op: DIFF_OP_INSERT, Text: "x"},
+			{Op: DIFF_OP_EQUAL, Text: "c"},
+			{Op: DIFF_OP_INSERT, Text: "d"},
+		}
+		if !reflect.DeepEqual(diff, expected) {
+			t.Errorf("unexpected diff: %v", diff)
+		}
+	})
+
+	t.Run("from empty", func(t *testing.T) {
+		diff := DiffLines("", "a")
+		if !reflect.DeepEqual(diff, []DiffLine{{Op: DIFF_OP_INSERT, Text: "a"}}) {
+			t.Errorf("unexpected diff: %v", diff)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package templates
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Variables filled in by the backend when email or SMS templates are rendered
+var KnownTemplateVariables = []string{
+	"language",
+	"token",
+	"loginToken",
+	"unsubscribeToken",
+	"verificationCode",
+	"validUntil",
+	"newEmail",
+	"newPhoneNumber",
+	"studyKey",
+	"profileAlias",
+	"profileId",
+	"participantID",
+	// digest emails
+	"taskCount",
+	"studyKeys",
+}
+
+// Prefixes of variables with dynamic names, e.g. participant flags as "flags.<key>"
+var KnownTemplateVariablePrefixes = []string{
+	"flags.",
+	// digest tasks as "tasks.<index>.<field>"
+	"tasks.",
+}
+
+// ExtractPlaceholders returns the sorted list of variables a template refers to,
+// either as {{ .name }} or as {{ index . "name" }}
+func ExtractPlaceholders(templateDef string) ([]string, error) {
+	// functions are not checked, so templates using layout helpers can be inspected too
+	trees := map[string]*parse.Tree{}
+	t := parse.New("template")
+	t.Mode = parse.SkipFuncCheck
+	_, err := t.Parse(templateDef, "", "", trees)
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing template: %v", err)
+	}
+
+	found := map[string]bool{}
+	for _, tree := range trees {
+		if tree == nil || tree.Root == nil {
+			continue
+		}
+		collectPlaceholders(tree.Root, found)
+	}
+
+	placeholders := make([]string, 0, len(found))
+	for name := range found {
+		placeholders = append(placeholders, name)
+	}
+	sort.Strings(placeholders)
+	return placeholders, nil
+}
+
+// FindUnknownPlaceholders returns the placeholders which are neither in the known variables nor match a known prefix
+func FindUnknownPlaceholders(placeholders []string, knownVariables []string) []string {
+	unknown := []string{}
+	for _, p := range placeholders {
+		if slices.Contains(knownVariables, p) {
+			continue
+		}
+		hasKnownPrefix := false
+		for _, prefix := range KnownTemplateVariablePrefixes {
+			if strings.HasPrefix(p, prefix) {
+				hasKnownPrefix = true
+				break
+			}
+		}
+		if !hasKnownPrefix {
+			unknown = append(unknown, p)
+		}
+	}
+	return unknown
+}
+
+func collectPlaceholders(node parse.Node, found map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectPlaceholders(child, found)
+		}
+	case *parse.ActionNode:
+		collectPlaceholders(n.Pipe, found)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectPlaceholders(cmd, found)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) == 3 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "index" {
+				if _, ok := n.Args[1].(*parse.DotNode); ok {
+					if key, ok := n.Args[2].(*parse.StringNode); ok {
+						found[key.Text] = true
+						return
+					}
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			collectPlaceholders(arg, found)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) > 0 {
+			found[n.Ident[0]] = true
+		}
+	case *parse.IfNode:
+		collectBranchPlaceholders(&n.BranchNode, found)
+	case *parse.RangeNode:
+		collectBranchPlaceholders(&n.BranchNode, found)
+	case *parse.WithNode:
+		collectBranchPlaceholders(&n.BranchNode, found)
+	case *parse.TemplateNode:
+		collectPlaceholders(n.Pipe, found)
+	}
+}
+
+func collectBranchPlaceholders(n *parse.BranchNode, found map[string]bool) {
+	collectPlaceholders(n.Pipe, found)
+	if n.List != nil {
+		collectPlaceholders(n.List, found)
+	}
+	if n.ElseList != nil {
+		collectPlaceholders(n.ElseList, found)
+	}
+}
@@ -0,0 +1,48 @@
+package templates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPlaceholders(t *testing.T) {
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := ExtractPlaceholders("{{ .token ")
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("fields, index and branches", func(t *testing.T) {
+		placeholders, err := ExtractPlaceholders(`<p>{{ .token }}</p>
+{{ if eq .language "de" }}Hallo{{ else }}{{ index . "flags.group" }}{{ end }}
+{{ with .studyKey }}{{ . }}{{ end }}{{ .token }}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"flags.group", "language", "studyKey", "token"}
+		if !reflect.DeepEqual(placeholders, expected) {
+			t.Errorf("unexpected placeholders: %v", placeholders)
+		}
+	})
+
+	t.Run("no placeholders", func(t *testing.T) {
+		placeholders, err := ExtractPlaceholders("<p>static</p>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(placeholders) != 0 {
+			t.Errorf("unexpected placeholders: %v", placeholders)
+		}
+	})
+}
+
+func TestFindUnknownPlaceholders(t *testing.T) {
+	unknown := FindUnknownPlaceholders(
+		[]string{"token", "flags.group", "customValue", "appName"},
+		append([]string{"appName"}, KnownTemplateVariables...),
+	)
+	if !reflect.DeepEqual(unknown, []string{"customValue"}) {
+		t.Errorf("unexpected unknown placeholders: %v", unknown)
+	}
+}
@@ -1,6 +1,7 @@
 package templates
 
 import (
+	"reflect"
 	"testing"
 
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
@@ -30,3 +31,43 @@ func TestTemplateLanguageSelection(t *testing.T) {
 		}
 	})
 }
+
+func TestLanguageChain(t *testing.T) {
+	t.Run("without fallbacks", func(t *testing.T) {
+		chain := LanguageChain("de-CH", nil, "en")
+		if !reflect.DeepEqual(chain, []string{"de-CH", "de", "en"}) {
+			t.Errorf("unexpected chain: %v", chain)
+		}
+	})
+
+	t.Run("configured fallbacks", func(t *testing.T) {
+		chain := LanguageChain("fr-CH", map[string][]string{"fr-CH": {"fr", "de"}, "de": {"en"}}, "it")
+		if !reflect.DeepEqual(chain, []string{"fr-CH", "fr", "de", "en", "it"}) {
+			t.Errorf("unexpected chain: %v", chain)
+		}
+	})
+
+	t.Run("no duplicates", func(t *testing.T) {
+		chain := LanguageChain("en", map[string][]string{"en": {"EN", "de"}}, "de")
+		if !reflect.DeepEqual(chain, []string{"en", "de"}) {
+			t.Errorf("unexpected chain: %v", chain)
+		}
+	})
+}
+
+func TestFindTranslation(t *testing.T) {
+	translations := []messagingTypes.LocalizedTemplate{
+		{Lang: "en", Subject: "EN"},
+		{Lang: "de", Subject: "DE"},
+	}
+
+	tr, found := FindTranslation(translations, LanguageChain("de-CH", nil, "en"))
+	if !found || tr.Subject != "DE" {
+		t.Errorf("unexpected translation: %v", tr)
+	}
+
+	_, found = FindTranslation(translations, []string{"fr"})
+	if found {
+		t.Error("expected no translation")
+	}
+}
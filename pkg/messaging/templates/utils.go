@@ -31,15 +31,54 @@ func ResolveTemplate(tempName string, templateDef string, contentInfos map[strin
 }
 
 func GetTemplateTranslation(translations []messagingTypes.LocalizedTemplate, lang string, defaultLang string) messagingTypes.LocalizedTemplate {
-	var defaultTranslation messagingTypes.LocalizedTemplate
-	for _, tr := range translations {
-		if tr.Lang == lang {
-			return tr
-		} else if tr.Lang == defaultLang {
-			defaultTranslation = tr
+	translation, _ := FindTranslation(translations, LanguageChain(lang, nil, defaultLang))
+	return translation
+}
+
+// LanguageChain returns the languages to try in order: the requested language followed by its configured fallbacks,
+// the base language of regional variants (e.g. de-CH -> de) and finally the default language of the template.
+func LanguageChain(lang string, fallbacks map[string][]string, defaultLang string) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+
+	var add func(l string)
+	add = func(l string) {
+		if l == "" || seen[strings.ToLower(l)] {
+			return
+		}
+		seen[strings.ToLower(l)] = true
+		chain = append(chain, l)
+
+		for _, f := range fallbacks[l] {
+			add(f)
+		}
+		if base := baseLanguage(l); base != l {
+			add(base)
+		}
+	}
+
+	add(lang)
+	add(defaultLang)
+	return chain
+}
+
+// FindTranslation returns the translation for the first language of the chain the template has
+func FindTranslation(translations []messagingTypes.LocalizedTemplate, chain []string) (messagingTypes.LocalizedTemplate, bool) {
+	for _, lang := range chain {
+		for _, tr := range translations {
+			if strings.EqualFold(tr.Lang, lang) {
+				return tr, true
+			}
 		}
 	}
-	return defaultTranslation
+	return messagingTypes.LocalizedTemplate{}, false
+}
+
+func baseLanguage(lang string) string {
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		return lang[:i]
+	}
+	return lang
 }
 
 func CheckAllTranslationsParsable(tempTranslations []messagingTypes.LocalizedTemplate, messageType string) error {
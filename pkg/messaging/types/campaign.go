@@ -0,0 +1,74 @@
+package types
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CAMPAIGN_STATUS_ACTIVE   = "active"
+	CAMPAIGN_STATUS_PAUSED   = "paused"
+	CAMPAIGN_STATUS_FINISHED = "finished"
+	// ad-hoc campaigns with a large audience wait for another user to approve them
+	CAMPAIGN_STATUS_PENDING_APPROVAL = "pendingApproval"
+)
+
+const (
+	AUDIENCE_ACCOUNT_CONFIRMED   = "confirmed"
+	AUDIENCE_ACCOUNT_UNCONFIRMED = "unconfirmed"
+	AUDIENCE_ACCOUNT_ANY         = "any"
+)
+
+// Campaign sends a templated email to an audience, once or recurring
+type Campaign struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Label      string             `bson:"label" json:"label"`
+	Template   EmailTemplate      `bson:"template" json:"template"`
+	Audience   CampaignAudience   `bson:"audience" json:"audience"`
+	Recurrence CampaignRecurrence `bson:"recurrence" json:"recurrence"`
+	QuietHours *QuietHours        `bson:"quietHours,omitempty" json:"quietHours,omitempty"`
+	Status     string             `bson:"status" json:"status"`
+
+	// One-off campaigns created for a filtered cohort, with the audience size at creation
+	AdHoc        bool   `bson:"adHoc,omitempty" json:"adHoc,omitempty"`
+	AudienceSize int64  `bson:"audienceSize,omitempty" json:"audienceSize,omitempty"`
+	CreatedBy    string `bson:"createdBy,omitempty" json:"createdBy,omitempty"`
+	ApprovedBy   string `bson:"approvedBy,omitempty" json:"approvedBy,omitempty"`
+	ApprovedAt   int64  `bson:"approvedAt,omitempty" json:"approvedAt,omitempty"`
+
+	NextRunAt int64 `bson:"nextRunAt" json:"nextRunAt"`
+	LastRunAt int64 `bson:"lastRunAt" json:"lastRunAt"`
+	CreatedAt int64 `bson:"createdAt" json:"createdAt"`
+	UpdatedAt int64 `bson:"updatedAt" json:"updatedAt"`
+}
+
+// CampaignAudience selects the users receiving the campaign. All set criteria have to match.
+type CampaignAudience struct {
+	// Only users with an active participant in the study
+	StudyKey string `bson:"studyKey,omitempty" json:"studyKey,omitempty"`
+	// Participant flags that must have the given values, requires StudyKey
+	Flags map[string]string `bson:"flags,omitempty" json:"flags,omitempty"`
+	// Last login or token refresh of the user must be within these bounds (seconds before the run)
+	ActiveWithin   int64 `bson:"activeWithin,omitempty" json:"activeWithin,omitempty"`
+	InactiveForMin int64 `bson:"inactiveForMin,omitempty" json:"inactiveForMin,omitempty"`
+	// Absolute bounds of the last login (unix timestamps)
+	LastLoginFrom  int64 `bson:"lastLoginFrom,omitempty" json:"lastLoginFrom,omitempty"`
+	LastLoginUntil int64 `bson:"lastLoginUntil,omitempty" json:"lastLoginUntil,omitempty"`
+	// "confirmed" (default), "unconfirmed" or "any"
+	AccountStatus string `bson:"accountStatus,omitempty" json:"accountStatus,omitempty"`
+}
+
+// CampaignRecurrence defines when the campaign runs. Without cron expression, the campaign runs once at StartAt.
+type CampaignRecurrence struct {
+	// Cron expression with five fields: minute hour day-of-month month day-of-week
+	Cron     string `bson:"cron,omitempty" json:"cron,omitempty"`
+	Timezone string `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	StartAt  int64  `bson:"startAt" json:"startAt"`
+	Until    int64  `bson:"until,omitempty" json:"until,omitempty"`
+}
+
+// QuietHours defines a daily window in which no campaign runs are started, e.g. from "22:00" to "07:00"
+type QuietHours struct {
+	Start    string `bson:"start" json:"start"`
+	End      string `bson:"end" json:"end"`
+	Timezone string `bson:"timezone" json:"timezone"`
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EMAIL_SUPPRESSION_REASON_HARD_BOUNCE = "hard-bounce"
+	EMAIL_SUPPRESSION_REASON_COMPLAINT   = "complaint"
+)
+
+// EmailSuppression blocks further emails to an address that bounced permanently or reported a complaint
+type EmailSuppression struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Address   string             `bson:"address" json:"address"`
+	Reason    string             `bson:"reason" json:"reason"`
+	Provider  string             `bson:"provider" json:"provider"`
+	Details   string             `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
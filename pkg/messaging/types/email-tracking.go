@@ -0,0 +1,16 @@
+package types
+
+const (
+	EMAIL_TRACKING_COUNTER_SENT   = "sent"
+	EMAIL_TRACKING_COUNTER_OPENS  = "opens"
+	EMAIL_TRACKING_COUNTER_CLICKS = "clicks"
+)
+
+// EmailTrackingStats holds the aggregated daily metrics of a campaign, no per recipient data is stored
+type EmailTrackingStats struct {
+	Campaign string `bson:"campaign" json:"campaign"`
+	Day      string `bson:"day" json:"day"`
+	Sent     int64  `bson:"sent" json:"sent"`
+	Opens    int64  `bson:"opens" json:"opens"`
+	Clicks   int64  `bson:"clicks" json:"clicks"`
+}
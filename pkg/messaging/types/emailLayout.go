@@ -0,0 +1,18 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailLayout is a shared base layout for email templates. The layout defines the surrounding HTML and
+// declares blocks (e.g. {{ block "content" . }}{{ end }}) that are filled by the templates using it.
+type EmailLayout struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Key         string             `bson:"key" json:"key"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	// base64 encoded layout definition
+	TemplateDef string    `bson:"templateDef" json:"templateDef"`
+	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
+}
@@ -1,6 +1,10 @@
 package types
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 const (
 	EMAIL_TYPE_REGISTRATION                     = "registration"
@@ -12,10 +16,12 @@ const (
 	EMAIL_TYPE_ACCOUNT_ID_CHANGED               = "account-id-changed"
 	EMAIL_TYPE_WEEKLY                           = "weekly"
 	EMAIL_TYPE_STUDY_REMINDER                   = "study-reminder"
+	EMAIL_TYPE_STUDY_DIGEST                     = "study-digest"
 	EMAIL_TYPE_NEWSLETTER                       = "newsletter"
 	EMAIL_TYPE_ACCOUNT_DELETED                  = "account-deleted"
 	EMAIL_TYPE_ACCOUNT_DELETED_AFTER_INACTIVITY = "account-deleted-after-inactivity"
 	EMAIL_TYPE_ACCOUNT_INACTIVITY               = "account-inactivity"
+	EMAIL_TYPE_ACCOUNT_SETUP                    = "account-setup"
 
 	EMAIL_TYPE_PHONE_NUMBER_CHANGED = "phone-number-changed"
 )
@@ -27,6 +33,23 @@ type EmailTemplate struct {
 	DefaultLanguage string              `bson:"defaultLanguage" json:"defaultLanguage"`
 	HeaderOverrides *HeaderOverrides    `bson:"headerOverrides" json:"headerOverrides"`
 	Translations    []LocalizedTemplate `bson:"translations" json:"translations"`
+	// Key of the email layout the translations are rendered into, empty for standalone HTML templates
+	Layout    string    `bson:"layout,omitempty" json:"layout,omitempty"`
+	Version   int64     `bson:"version,omitempty" json:"version,omitempty"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+	// set while the template is in the trash
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+// EmailTemplateVersion is a snapshot of an email template stored each time the template is saved
+type EmailTemplateVersion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	MessageType string             `bson:"messageType" json:"messageType"`
+	StudyKey    string             `bson:"studyKey,omitempty" json:"studyKey,omitempty"`
+	Version     int64              `bson:"version" json:"version"`
+	Template    EmailTemplate      `bson:"template" json:"template"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	CreatedBy   string             `bson:"createdBy,omitempty" json:"createdBy,omitempty"`
 }
 
 type HeaderOverrides struct {
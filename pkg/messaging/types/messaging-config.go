@@ -5,8 +5,85 @@ import (
 )
 
 type SMSGatewayConfig struct {
-	URL    string `yaml:"url"`
-	APIKey string `yaml:"api_key"`
+	// Provider is one of "cm" (default), "twilio", "vonage" or "http"
+	Provider string `yaml:"provider"`
+	URL      string `yaml:"url"`
+	APIKey   string `yaml:"api_key"`
+	// APISecret is the Vonage API secret or the Twilio auth token
+	APISecret string `yaml:"api_secret"`
+	// AccountID is the Twilio account SID
+	AccountID string `yaml:"account_id"`
+	// Extra headers sent to the generic HTTP gateway
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+
+	// Delivery status callbacks
+	StatusCallbackURL    string `yaml:"status_callback_url"`
+	StatusCallbackSecret string `yaml:"status_callback_secret"`
+}
+
+type EmailProviderConfig struct {
+	// Provider is one of "smtp-bridge", "smtp", "ses", "sendgrid" or "mailgun"
+	Provider string `json:"provider" yaml:"provider"`
+
+	// Default sender infos, can be overridden by the email template
+	From    string   `json:"from" yaml:"from"`
+	ReplyTo []string `json:"reply_to" yaml:"reply_to"`
+
+	// Optional base URL override for API based providers
+	URL       string `json:"url" yaml:"url"`
+	APIKey    string `json:"api_key" yaml:"api_key"`
+	APISecret string `json:"api_secret" yaml:"api_secret"`
+	// AWS region for SES
+	Region string `json:"region" yaml:"region"`
+	// Sending domain for Mailgun
+	Domain string `json:"domain" yaml:"domain"`
+	// Server list for the smtp provider
+	SmtpServersFile string `json:"smtp_servers_file" yaml:"smtp_servers_file"`
+
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+
+	// Secret expected as query parameter by the bounce and complaint webhook of this provider
+	WebhookSecret string `json:"webhook_secret" yaml:"webhook_secret"`
+}
+
+type EmailRetryConfig struct {
+	// Emails are moved to the failed emails after this many attempts
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+	// Delay after the first failed attempt, doubled with each further attempt up to MaxBackoff
+	InitialBackoff time.Duration `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff" yaml:"max_backoff"`
+}
+
+type EmailAttachmentsConfig struct {
+	// Root directory of the filestore attachments are read from, attachments are rejected if empty
+	FilestorePath string `json:"filestore_path" yaml:"filestore_path"`
+	// Size limits in bytes for a single attachment and for all attachments of an email
+	MaxAttachmentSize int64 `json:"max_attachment_size" yaml:"max_attachment_size"`
+	MaxTotalSize      int64 `json:"max_total_size" yaml:"max_total_size"`
+}
+
+type DigestConfig struct {
+	// Digest frequency ("daily" or "weekly") for users who did not choose one, empty to send digests only on opt-in
+	DefaultFrequency string `json:"default_frequency" yaml:"default_frequency"`
+	// Participant message types that are collected into the digest instead of being sent one by one, e.g. "study-reminder"
+	ReplacesMessageTypes []string `json:"replaces_message_types" yaml:"replaces_message_types"`
+}
+
+type SandboxConfig struct {
+	// Outgoing emails and SMS of these instances are captured instead of being sent to the participants
+	EnabledInstances []string `json:"enabled_instances" yaml:"enabled_instances"`
+	// If set, captured messages are additionally delivered to this address or phone number
+	CatchAllEmail       string `json:"catch_all_email" yaml:"catch_all_email"`
+	CatchAllPhoneNumber string `json:"catch_all_phone_number" yaml:"catch_all_phone_number"`
+}
+
+type EmailTrackingConfig struct {
+	// Public URL of the participant API email tracking endpoints, e.g. https://example.com/api/participant/v1/email/track
+	BaseURL    string `json:"base_url" yaml:"base_url"`
+	SigningKey string `json:"signing_key" yaml:"signing_key"`
+	// Tracking is opt-in, emails are only instrumented for the instances listed here
+	EnabledInstances []string `json:"enabled_instances" yaml:"enabled_instances"`
 }
 
 type MessagingConfigs struct {
@@ -18,5 +95,35 @@ type MessagingConfigs struct {
 		RequestTimeout time.Duration `json:"request_timeout" yaml:"request_timeout"`
 	} `json:"smtp_bridge_config" yaml:"smtp_bridge_config"`
 
+	// Email providers in order of preference, the next one is used if sending fails. If empty, the smtp bridge is used.
+	EmailProviders []EmailProviderConfig `json:"email_providers" yaml:"email_providers"`
+	// Per instance email providers, instances not listed here use EmailProviders
+	InstanceEmailProviders map[string][]EmailProviderConfig `json:"instance_email_providers" yaml:"instance_email_providers"`
+
+	// Open and click tracking of low priority emails
+	EmailTracking EmailTrackingConfig `json:"email_tracking" yaml:"email_tracking"`
+
+	// Retries of outgoing emails after failed send attempts
+	EmailRetry EmailRetryConfig `json:"email_retry" yaml:"email_retry"`
+
+	// Capture outgoing messages of staging instances instead of sending them to participants
+	Sandbox SandboxConfig `json:"sandbox" yaml:"sandbox"`
+
+	// Digest emails summarising the pending study tasks of a participant
+	Digests DigestConfig `json:"digests" yaml:"digests"`
+
+	// Delivery of events to registered webhook endpoints
+	Webhooks WebhookConfig `json:"webhooks" yaml:"webhooks"`
+
+	// Files attached to outgoing emails
+	EmailAttachments EmailAttachmentsConfig `json:"email_attachments" yaml:"email_attachments"`
+
+	// Languages tried if a template has no translation for the preferred language, e.g. "de-CH": ["de", "en"]
+	LanguageFallbacks map[string][]string `json:"language_fallbacks" yaml:"language_fallbacks"`
+	// Per instance language fallbacks, instances not listed here use LanguageFallbacks
+	InstanceLanguageFallbacks map[string]map[string][]string `json:"instance_language_fallbacks" yaml:"instance_language_fallbacks"`
+
 	SMSConfig *SMSGatewayConfig `json:"sms_config" yaml:"sms_config"`
+	// Per instance SMS provider configs, instances not listed here use SMSConfig
+	InstanceSMSConfigs map[string]*SMSGatewayConfig `json:"instance_sms_configs" yaml:"instance_sms_configs"`
 }
@@ -13,4 +13,23 @@ type OutgoingEmail struct {
 	ExpiresAt       int64              `bson:"expiresAt" json:"expiresAt"`
 	HighPrio        bool               `bson:"highPrio" json:"highPrio"`
 	LastSendAttempt int64              `bson:"lastSendAttempt" json:"lastSendAttempt"`
+	// Files from the filestore attached when the email is sent
+	Attachments []EmailAttachment `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	// Campaign groups emails for open/click metrics, the message type is used if empty
+	Campaign string `bson:"campaign,omitempty" json:"campaign,omitempty"`
+
+	// Retry state, emails are moved to the failed emails after too many attempts or a permanent error
+	SendAttempts  int    `bson:"sendAttempts,omitempty" json:"sendAttempts,omitempty"`
+	NextAttemptAt int64  `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+	LastError     string `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	FailedAt      int64  `bson:"failedAt,omitempty" json:"failedAt,omitempty"`
+}
+
+// EmailAttachment references a file in the filestore, its content is only read when the email is sent
+type EmailAttachment struct {
+	Filename    string `bson:"filename" json:"filename"`
+	ContentType string `bson:"contentType,omitempty" json:"contentType,omitempty"`
+	// Reference of the file in the filestore, e.g. its path relative to the filestore root
+	FileRef string `bson:"fileRef" json:"fileRef"`
+	Size    int64  `bson:"size,omitempty" json:"size,omitempty"`
 }
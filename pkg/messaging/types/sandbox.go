@@ -0,0 +1,25 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SANDBOX_CHANNEL_EMAIL = "email"
+	SANDBOX_CHANNEL_SMS   = "sms"
+)
+
+// CapturedMessage is an email or SMS of a sandboxed instance that was not sent to its recipients
+type CapturedMessage struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Channel     string             `bson:"channel" json:"channel"`
+	MessageType string             `bson:"messageType" json:"messageType"`
+	To          []string           `bson:"to" json:"to"`
+	// Catch-all address or phone number the message was delivered to instead, if configured
+	RedirectedTo string    `bson:"redirectedTo,omitempty" json:"redirectedTo,omitempty"`
+	Subject      string    `bson:"subject,omitempty" json:"subject,omitempty"`
+	Content      string    `bson:"content" json:"content"`
+	CapturedAt   time.Time `bson:"capturedAt" json:"capturedAt"`
+}
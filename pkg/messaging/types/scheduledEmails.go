@@ -15,4 +15,6 @@ type ScheduledEmail struct {
 	Period    int64                `bson:"period" json:"period"`
 	Label     string               `bson:"label" json:"label"`
 	Until     int64                `bson:"until" json:"until"`
+	// Attached to every email sent by this schedule
+	Attachments []EmailAttachment `bson:"attachments,omitempty" json:"attachments,omitempty"`
 }
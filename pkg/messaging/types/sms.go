@@ -12,6 +12,12 @@ type SentSMS struct {
 	MessageType string             `bson:"messageType" json:"messageType"`
 	SentAt      time.Time          `bson:"sentAt" json:"sentAt"`
 	PhoneNumber string             `bson:"phoneNumber" json:"phoneNumber"`
+
+	// Delivery tracking
+	Provider          string    `bson:"provider,omitempty" json:"provider,omitempty"`
+	ProviderMessageID string    `bson:"providerMessageID,omitempty" json:"providerMessageID,omitempty"`
+	Status            string    `bson:"status,omitempty" json:"status,omitempty"`
+	StatusUpdatedAt   time.Time `bson:"statusUpdatedAt,omitempty" json:"statusUpdatedAt,omitempty"`
 }
 
 type SMSTemplate struct {
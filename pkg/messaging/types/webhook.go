@@ -0,0 +1,52 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	WEBHOOK_DELIVERY_STATUS_PENDING   = "pending"
+	WEBHOOK_DELIVERY_STATUS_DELIVERED = "delivered"
+	WEBHOOK_DELIVERY_STATUS_FAILED    = "failed"
+)
+
+// WebhookEndpoint receives the events it subscribed to as signed POST requests
+type WebhookEndpoint struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	URL         string             `bson:"url" json:"url"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	// Used to sign the request body, only returned when the endpoint is created
+	Secret    string   `bson:"secret" json:"secret,omitempty"`
+	Events    []string `bson:"events" json:"events"`
+	Enabled   bool     `bson:"enabled" json:"enabled"`
+	CreatedAt int64    `bson:"createdAt" json:"createdAt"`
+	UpdatedAt int64    `bson:"updatedAt" json:"updatedAt"`
+}
+
+// WebhookDelivery is the delivery log entry of one event sent to one endpoint
+type WebhookDelivery struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	EndpointID string             `bson:"endpointId" json:"endpointId"`
+	Event      string             `bson:"event" json:"event"`
+	// JSON body sent to the endpoint, kept so that retries send the same payload
+	Body               string    `bson:"body" json:"body"`
+	Status             string    `bson:"status" json:"status"`
+	Attempts           int       `bson:"attempts" json:"attempts"`
+	NextAttemptAt      int64     `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+	LastError          string    `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	LastResponseStatus int       `bson:"lastResponseStatus,omitempty" json:"lastResponseStatus,omitempty"`
+	CreatedAt          time.Time `bson:"createdAt" json:"createdAt"`
+	DeliveredAt        int64     `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+}
+
+type WebhookConfig struct {
+	// Deliveries are marked as failed after this many attempts
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+	// Delay after the first failed attempt, doubled with each further attempt up to MaxBackoff
+	InitialBackoff time.Duration `json:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff" yaml:"max_backoff"`
+	// Timeout of one delivery request
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
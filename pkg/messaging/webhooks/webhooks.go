@@ -0,0 +1,344 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	EVENT_PARTICIPANT_SIGNUP = "participant.signup"
+	EVENT_RESPONSE_SUBMITTED = "study.response_submitted"
+	EVENT_MESSAGE_SENT       = "messaging.message_sent"
+	// a scheduled export could not be delivered to its destination
+	EVENT_EXPORT_DELIVERY_FAILED = "study.export_delivery_failed"
+	// the data quality job found an anomaly in the responses or rule evaluations of a study
+	EVENT_DATA_QUALITY_ANOMALY = "study.data_quality_anomaly"
+	// sent on request from the management api to test an endpoint
+	EVENT_PING = "ping"
+)
+
+// Events endpoints can subscribe to
+var SupportedEvents = []string{
+	EVENT_PARTICIPANT_SIGNUP,
+	EVENT_RESPONSE_SUBMITTED,
+	EVENT_MESSAGE_SENT,
+	EVENT_EXPORT_DELIVERY_FAILED,
+	EVENT_DATA_QUALITY_ANOMALY,
+}
+
+const (
+	HEADER_SIGNATURE = "X-Case-Signature"
+	HEADER_EVENT     = "X-Case-Event"
+	HEADER_DELIVERY  = "X-Case-Delivery"
+)
+
+const (
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = 30 * time.Second
+	defaultMaxBackoff     = 6 * time.Hour
+	defaultTimeout        = 10 * time.Second
+
+	// time a claimed delivery is hidden from other workers
+	deliveryLease = 5 * time.Minute
+	// response bodies are only kept in the log up to this size
+	maxLoggedResponseSize = 512
+)
+
+var (
+	messagingDBService *messagingDB.MessagingDBService
+	httpClient         *http.Client
+
+	config = messagingTypes.WebhookConfig{
+		MaxAttempts:    defaultMaxAttempts,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		Timeout:        defaultTimeout,
+	}
+)
+
+// Init enables publishing of webhook events, unset config values keep their defaults
+func Init(mdb *messagingDB.MessagingDBService, conf messagingTypes.WebhookConfig) {
+	messagingDBService = mdb
+	if conf.MaxAttempts > 0 {
+		config.MaxAttempts = conf.MaxAttempts
+	}
+	if conf.InitialBackoff > 0 {
+		config.InitialBackoff = conf.InitialBackoff
+	}
+	if conf.MaxBackoff > 0 {
+		config.MaxBackoff = conf.MaxBackoff
+	}
+	if conf.Timeout > 0 {
+		config.Timeout = conf.Timeout
+	}
+	httpClient = &http.Client{Timeout: config.Timeout}
+}
+
+// Payload is the JSON body posted to the endpoints
+type Payload struct {
+	ID         string         `json:"id"`
+	Event      string         `json:"event"`
+	InstanceID string         `json:"instanceId"`
+	CreatedAt  int64          `json:"createdAt"`
+	Data       map[string]any `json:"data"`
+}
+
+func IsSupportedEvent(event string) bool {
+	for _, e := range SupportedEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateEndpoint checks the url and the subscribed events of an endpoint
+func ValidateEndpoint(endpoint messagingTypes.WebhookEndpoint) error {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) url")
+	}
+	if len(endpoint.Events) == 0 {
+		return errors.New("at least one event is required")
+	}
+	for _, event := range endpoint.Events {
+		if !IsSupportedEvent(event) {
+			return fmt.Errorf("unsupported event: %s", event)
+		}
+	}
+	return nil
+}
+
+// GenerateSecret returns a new random signing secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign computes the signature header value for the body sent at timestamp ts.
+// Receivers recompute the HMAC-SHA256 of "<t>.<body>" with their secret and compare it to v1.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Publish creates a delivery for each endpoint subscribed to the event and attempts it in the background.
+// Failed deliveries are retried by the messaging job. Does nothing if webhooks are not initialised.
+func Publish(instanceID string, event string, data map[string]any) {
+	if messagingDBService == nil {
+		return
+	}
+
+	endpoints, err := messagingDBService.GetWebhookEndpointsForEvent(instanceID, event)
+	if err != nil {
+		slog.Error("failed to get webhook endpoints", slog.String("instanceID", instanceID), slog.String("event", event), slog.String("error", err.Error()))
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		delivery, err := createDelivery(instanceID, endpoint, event, data)
+		if err != nil {
+			slog.Error("failed to create webhook delivery", slog.String("instanceID", instanceID), slog.String("endpointID", endpoint.ID.Hex()), slog.String("event", event), slog.String("error", err.Error()))
+			continue
+		}
+		go attemptDelivery(instanceID, endpoint, delivery)
+	}
+}
+
+// SendPing delivers a ping event to the endpoint right away and returns the logged delivery
+func SendPing(instanceID string, endpoint messagingTypes.WebhookEndpoint) (messagingTypes.WebhookDelivery, error) {
+	if messagingDBService == nil {
+		return messagingTypes.WebhookDelivery{}, errors.New("webhooks not initialised")
+	}
+	delivery, err := createDelivery(instanceID, endpoint, EVENT_PING, map[string]any{})
+	if err != nil {
+		return delivery, err
+	}
+	return attemptDelivery(instanceID, endpoint, delivery), nil
+}
+
+func createDelivery(instanceID string, endpoint messagingTypes.WebhookEndpoint, event string, data map[string]any) (messagingTypes.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := messagingTypes.WebhookDelivery{
+		EndpointID: endpoint.ID.Hex(),
+		Event:      event,
+		Status:     messagingTypes.WEBHOOK_DELIVERY_STATUS_PENDING,
+		// leased, so that the job does not pick it up while the first attempt is running
+		NextAttemptAt: now.Add(deliveryLease).Unix(),
+		CreatedAt:     now,
+	}
+	delivery, err := messagingDBService.AddWebhookDelivery(instanceID, delivery)
+	if err != nil {
+		return delivery, err
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:         delivery.ID.Hex(),
+		Event:      event,
+		InstanceID: instanceID,
+		CreatedAt:  now.Unix(),
+		Data:       data,
+	})
+	if err != nil {
+		return delivery, err
+	}
+	delivery.Body = string(body)
+	return delivery, messagingDBService.UpdateWebhookDeliveryBody(instanceID, delivery.ID, delivery.Body)
+}
+
+// ProcessDueDeliveries retries pending deliveries that are due and returns the number of attempts made
+func ProcessDueDeliveries(instanceID string) (int, error) {
+	if messagingDBService == nil {
+		return 0, errors.New("webhooks not initialised")
+	}
+
+	endpoints := map[string]*messagingTypes.WebhookEndpoint{}
+	count := 0
+	for {
+		now := time.Now()
+		delivery, err := messagingDBService.ClaimDueWebhookDelivery(instanceID, now.Unix(), now.Add(deliveryLease).Unix())
+		if err != nil {
+			return count, err
+		}
+		if delivery == nil {
+			return count, nil
+		}
+
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			endpoint, err = messagingDBService.GetWebhookEndpoint(instanceID, delivery.EndpointID)
+			if err != nil {
+				endpoint = nil
+			}
+			endpoints[delivery.EndpointID] = endpoint
+		}
+		if endpoint == nil || !endpoint.Enabled {
+			delivery.Status = messagingTypes.WEBHOOK_DELIVERY_STATUS_FAILED
+			delivery.NextAttemptAt = 0
+			delivery.LastError = "endpoint removed or disabled"
+			if err := messagingDBService.UpdateWebhookDeliveryState(instanceID, *delivery); err != nil {
+				slog.Error("failed to update webhook delivery", slog.String("instanceID", instanceID), slog.String("deliveryID", delivery.ID.Hex()), slog.String("error", err.Error()))
+			}
+			continue
+		}
+
+		attemptDelivery(instanceID, *endpoint, *delivery)
+		count += 1
+	}
+}
+
+// attemptDelivery sends the delivery once and stores the outcome with the next retry time
+func attemptDelivery(instanceID string, endpoint messagingTypes.WebhookEndpoint, delivery messagingTypes.WebhookDelivery) messagingTypes.WebhookDelivery {
+	now := time.Now()
+	statusCode, err := send(endpoint, delivery, now)
+	registerAttempt(&delivery, statusCode, err, now)
+
+	if err != nil {
+		slog.Warn("webhook delivery failed",
+			slog.String("instanceID", instanceID),
+			slog.String("endpointID", endpoint.ID.Hex()),
+			slog.String("deliveryID", delivery.ID.Hex()),
+			slog.Int("attempts", delivery.Attempts),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if err := messagingDBService.UpdateWebhookDeliveryState(instanceID, delivery); err != nil {
+		slog.Error("failed to update webhook delivery", slog.String("instanceID", instanceID), slog.String("deliveryID", delivery.ID.Hex()), slog.String("error", err.Error()))
+	}
+	return delivery
+}
+
+func send(endpoint messagingTypes.WebhookEndpoint, delivery messagingTypes.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Body)
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CASE-Webhooks/1.0")
+	req.Header.Set(HEADER_EVENT, delivery.Event)
+	req.Header.Set(HEADER_DELIVERY, delivery.ID.Hex())
+	req.Header.Set(HEADER_SIGNATURE, Sign(endpoint.Secret, now.Unix(), body))
+
+	client := httpClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedResponseSize))
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return resp.StatusCode, nil
+}
+
+// registerAttempt updates the delivery after an attempt. Failed deliveries are retried with exponential
+// backoff until the maximum number of attempts is reached.
+func registerAttempt(delivery *messagingTypes.WebhookDelivery, statusCode int, sendErr error, now time.Time) {
+	delivery.Attempts += 1
+	delivery.LastResponseStatus = statusCode
+	if sendErr == nil {
+		delivery.Status = messagingTypes.WEBHOOK_DELIVERY_STATUS_DELIVERED
+		delivery.DeliveredAt = now.Unix()
+		delivery.NextAttemptAt = 0
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = sendErr.Error()
+	if delivery.Attempts >= config.MaxAttempts || delivery.Event == EVENT_PING {
+		delivery.Status = messagingTypes.WEBHOOK_DELIVERY_STATUS_FAILED
+		delivery.NextAttemptAt = 0
+		return
+	}
+	delivery.Status = messagingTypes.WEBHOOK_DELIVERY_STATUS_PENDING
+	delivery.NextAttemptAt = now.Add(retryBackoff(delivery.Attempts, config.InitialBackoff, config.MaxBackoff)).Unix()
+}
+
+// retryBackoff returns the delay before the next attempt, doubling with each failed attempt
+func retryBackoff(attempts int, initial time.Duration, max time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := initial
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= max {
+			return max
+		}
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
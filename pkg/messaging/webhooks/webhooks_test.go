@@ -0,0 +1,150 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"ping"}`)
+	sig := Sign("secret", 1700000000, body)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	expected := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if sig != expected {
+		t.Errorf("unexpected signature: %s, expected %s", sig, expected)
+	}
+
+	if Sign("other", 1700000000, body) == sig {
+		t.Error("signature should depend on the secret")
+	}
+	if Sign("secret", 1700000001, body) == sig {
+		t.Error("signature should depend on the timestamp")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{10, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts, time.Minute, time.Hour); got != tt.expected {
+			t.Errorf("attempts %d: expected %v, got %v", tt.attempts, tt.expected, got)
+		}
+	}
+}
+
+func TestRegisterAttempt(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	t.Run("success", func(t *testing.T) {
+		d := messagingTypes.WebhookDelivery{Event: EVENT_MESSAGE_SENT, LastError: "previous"}
+		registerAttempt(&d, 200, nil, now)
+		if d.Status != messagingTypes.WEBHOOK_DELIVERY_STATUS_DELIVERED || d.DeliveredAt != now.Unix() || d.LastError != "" || d.Attempts != 1 {
+			t.Errorf("unexpected delivery state: %+v", d)
+		}
+	})
+
+	t.Run("retry scheduled", func(t *testing.T) {
+		d := messagingTypes.WebhookDelivery{Event: EVENT_MESSAGE_SENT}
+		registerAttempt(&d, 500, errors.New("status 500"), now)
+		if d.Status != messagingTypes.WEBHOOK_DELIVERY_STATUS_PENDING || d.NextAttemptAt != now.Add(config.InitialBackoff).Unix() {
+			t.Errorf("unexpected delivery state: %+v", d)
+		}
+	})
+
+	t.Run("max attempts reached", func(t *testing.T) {
+		d := messagingTypes.WebhookDelivery{Event: EVENT_MESSAGE_SENT, Attempts: config.MaxAttempts - 1}
+		registerAttempt(&d, 0, errors.New("timeout"), now)
+		if d.Status != messagingTypes.WEBHOOK_DELIVERY_STATUS_FAILED || d.NextAttemptAt != 0 {
+			t.Errorf("unexpected delivery state: %+v", d)
+		}
+	})
+
+	t.Run("ping not retried", func(t *testing.T) {
+		d := messagingTypes.WebhookDelivery{Event: EVENT_PING}
+		registerAttempt(&d, 404, errors.New("status 404"), now)
+		if d.Status != messagingTypes.WEBHOOK_DELIVERY_STATUS_FAILED {
+			t.Errorf("unexpected delivery state: %+v", d)
+		}
+	})
+}
+
+func TestSend(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		b, _ := io.ReadAll(r.Body)
+		receivedBody = string(b)
+		if strings.Contains(receivedBody, "fail") {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("upstream down"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	endpoint := messagingTypes.WebhookEndpoint{ID: primitive.NewObjectID(), URL: server.URL, Secret: "secret"}
+	delivery := messagingTypes.WebhookDelivery{ID: primitive.NewObjectID(), Event: EVENT_PING, Body: `{"event":"ping"}`}
+	now := time.Now()
+
+	status, err := send(endpoint, delivery, now)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+	if receivedBody != delivery.Body {
+		t.Errorf("unexpected body: %s", receivedBody)
+	}
+	if received.Header.Get(HEADER_EVENT) != EVENT_PING || received.Header.Get(HEADER_DELIVERY) != delivery.ID.Hex() {
+		t.Errorf("unexpected headers: %v", received.Header)
+	}
+	if received.Header.Get(HEADER_SIGNATURE) != Sign("secret", now.Unix(), []byte(delivery.Body)) {
+		t.Errorf("unexpected signature: %s", received.Header.Get(HEADER_SIGNATURE))
+	}
+
+	delivery.Body = `{"event":"fail"}`
+	status, err = send(endpoint, delivery, now)
+	if err == nil || status != http.StatusBadGateway || !strings.Contains(err.Error(), "upstream down") {
+		t.Errorf("expected failure with response body, got %d %v", status, err)
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	valid := messagingTypes.WebhookEndpoint{URL: "https://example.com/hook", Events: []string{EVENT_MESSAGE_SENT}}
+	if err := ValidateEndpoint(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := []messagingTypes.WebhookEndpoint{
+		{URL: "ftp://example.com", Events: []string{EVENT_MESSAGE_SENT}},
+		{URL: "/relative", Events: []string{EVENT_MESSAGE_SENT}},
+		{URL: "https://example.com"},
+		{URL: "https://example.com", Events: []string{"unknown"}},
+	}
+	for _, e := range invalid {
+		if err := ValidateEndpoint(e); err == nil {
+			t.Errorf("expected error for %+v", e)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"bytes"
+
+	"github.com/case-framework/case-backend/pkg/db"
+)
+
+// RegisterDBMetrics exports the command latencies and connection pool stats recorded by the DB services
+func RegisterDBMetrics(r *Registry) {
+	r.NewHistogramFunc("db_operation_duration_seconds", "Duration of DB commands by database, collection and command.", func(w *bytes.Buffer) {
+		labelNames := []string{"database", "collection", "command"}
+		bounds := make([]float64, len(db.LatencyBucketsMs))
+		for i, ms := range db.LatencyBucketsMs {
+			bounds[i] = ms / 1000
+		}
+		for _, s := range db.DefaultOperationMetrics.Snapshot() {
+			buckets := make([]uint64, len(s.Buckets))
+			for i, count := range s.Buckets {
+				buckets[i] = uint64(count)
+			}
+			WriteHistogram(w, "db_operation_duration_seconds", labelNames, []string{s.Database, s.Collection, s.Command}, bounds, buckets, uint64(s.Count), s.SumMs/1000)
+		}
+	})
+
+	r.NewCounterFunc("db_operation_errors_total", "Failed DB commands by database, collection and command.", []string{"database", "collection", "command"}, func() []Sample {
+		samples := []Sample{}
+		for _, s := range db.DefaultOperationMetrics.Snapshot() {
+			samples = append(samples, Sample{LabelValues: []string{s.Database, s.Collection, s.Command}, Value: float64(s.Errors)})
+		}
+		return samples
+	})
+
+	poolGauge := func(name string, help string, value func(s db.PoolStats) int64) {
+		r.NewGaugeFunc(name, help, []string{"address"}, func() []Sample {
+			samples := []Sample{}
+			for _, s := range db.DefaultPoolMetrics.Snapshot() {
+				samples = append(samples, Sample{LabelValues: []string{s.Address}, Value: float64(value(s))})
+			}
+			return samples
+		})
+	}
+	poolGauge("db_pool_connections_open", "Open connections of the DB connection pool, idle or in use.", func(s db.PoolStats) int64 { return s.Open })
+	poolGauge("db_pool_connections_in_use", "Connections of the DB connection pool checked out by operations.", func(s db.PoolStats) int64 { return s.InUse })
+
+	poolCounter := func(name string, help string, value func(s db.PoolStats) int64) {
+		r.NewCounterFunc(name, help, []string{"address"}, func() []Sample {
+			samples := []Sample{}
+			for _, s := range db.DefaultPoolMetrics.Snapshot() {
+				samples = append(samples, Sample{LabelValues: []string{s.Address}, Value: float64(value(s))})
+			}
+			return samples
+		})
+	}
+	poolCounter("db_pool_checkouts_total", "Connections checked out of the DB connection pool.", func(s db.PoolStats) int64 { return s.CheckedOut })
+	poolCounter("db_pool_checkout_failures_total", "Failed check outs of the DB connection pool, including wait timeouts.", func(s db.PoolStats) int64 { return s.CheckOutFailed })
+}
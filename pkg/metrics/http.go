@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	AUTH_OUTCOME_SUCCESS      = "success"
+	AUTH_OUTCOME_DENIED       = "denied"
+	AUTH_OUTCOME_RATE_LIMITED = "rate_limited"
+	AUTH_OUTCOME_INVALID      = "invalid"
+	AUTH_OUTCOME_ERROR        = "error"
+)
+
+// Config of the metrics endpoint in service config files
+type Config struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// serves /metrics on a separate port, e.g. only reachable inside the cluster, instead of the API port
+	Port string `json:"port" yaml:"port"`
+	// required as bearer token in the Authorization header if set
+	BearerToken string `json:"bearer_token" yaml:"bearer_token"`
+}
+
+var (
+	httpRequests = DefaultRegistry.NewCounter(
+		"http_requests_total", "HTTP requests by method, route and status code.",
+		"method", "route", "status",
+	)
+	httpRequestDuration = DefaultRegistry.NewHistogram(
+		"http_request_duration_seconds", "Duration of HTTP requests by method and route.",
+		nil, "method", "route",
+	)
+	authEvents = DefaultRegistry.NewCounter(
+		"auth_events_total", "Login, signup and token renewal attempts by event and outcome.",
+		"event", "outcome",
+	)
+	apiVersionRequests = DefaultRegistry.NewCounter(
+		"http_api_version_requests_total", "HTTP requests by API version and whether the version is deprecated.",
+		"version", "deprecated",
+	)
+)
+
+// Setup records the requests of the router and adds the metrics endpoint, if enabled. Middlewares only apply to
+// routes added afterwards, so it must be called before adding the routes.
+func Setup(router *gin.Engine, config Config) {
+	if !config.Enabled {
+		return
+	}
+	router.Use(Middleware())
+
+	if config.Port == "" {
+		router.GET("/metrics", gin.WrapH(Handler(DefaultRegistry, config.BearerToken)))
+		return
+	}
+	Serve(config)
+}
+
+// Serve starts the metrics endpoint on the port of the config in the background, for services without an API
+func Serve(config Config) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(DefaultRegistry, config.BearerToken))
+	go func() {
+		slog.Info("Starting metrics endpoint on port " + config.Port)
+		if err := http.ListenAndServe(":"+config.Port, mux); err != nil {
+			slog.Error("Exited metrics endpoint", slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// Handler writes the metrics of the registry, requests without the bearer token are rejected if a token is set
+func Handler(r *Registry, bearerToken string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if bearerToken != "" {
+			expected := []byte("Bearer " + bearerToken)
+			if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), expected) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if _, err := r.WriteTo(w); err != nil {
+			slog.Error("failed to write metrics", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// Middleware counts requests and records their duration. Routes are labeled with their pattern, so that path
+// parameters like IDs do not create new series.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		httpRequestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+	}
+}
+
+// AuthOutcome counts the outcome of an authentication endpoint, derived from the response status
+func AuthOutcome(event string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		authEvents.Inc(event, authOutcome(c.Writer.Status()))
+	}
+}
+
+func authOutcome(status int) string {
+	switch {
+	case status < 400:
+		return AUTH_OUTCOME_SUCCESS
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return AUTH_OUTCOME_DENIED
+	case status == http.StatusTooManyRequests:
+		return AUTH_OUTCOME_RATE_LIMITED
+	case status < 500:
+		return AUTH_OUTCOME_INVALID
+	default:
+		return AUTH_OUTCOME_ERROR
+	}
+}
+
+// CountAPIVersion counts a request to the API version, to see when clients stopped using a deprecated version
+func CountAPIVersion(version string, deprecated bool) {
+	apiVersionRequests.Inc(version, strconv.FormatBool(deprecated))
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ENV_METRICS_TEXTFILE_DIR is the directory read by the textfile collector of the node exporter. Jobs are short
+// lived processes that cannot be scraped, so they write their metrics there. Not set disables job metrics.
+const ENV_METRICS_TEXTFILE_DIR = "METRICS_TEXTFILE_DIR"
+
+// ReportJobRun writes the duration and completion time of a job run to <METRICS_TEXTFILE_DIR>/<job>.prom, errors are
+// logged
+func ReportJobRun(job string, start time.Time) {
+	dir := os.Getenv(ENV_METRICS_TEXTFILE_DIR)
+	if dir == "" {
+		return
+	}
+	if err := WriteJobMetrics(dir, job, time.Since(start), time.Now()); err != nil {
+		slog.Error("failed to write job metrics", slog.String("job", job), slog.String("error", err.Error()))
+	}
+}
+
+// WriteJobMetrics writes the file atomically, so that the collector never reads a partial file
+func WriteJobMetrics(dir string, job string, duration time.Duration, finishedAt time.Time) error {
+	r := NewRegistry()
+	r.NewGaugeFunc("job_duration_seconds", "Duration of the last run of the job.", []string{"job"}, func() []Sample {
+		return []Sample{{LabelValues: []string{job}, Value: duration.Seconds()}}
+	})
+	r.NewGaugeFunc("job_last_run_timestamp_seconds", "Unix time when the last run of the job finished.", []string{"job"}, func() []Sample {
+		return []Sample{{LabelValues: []string{job}, Value: float64(finishedAt.Unix())}}
+	})
+
+	tmp, err := os.CreateTemp(dir, job+".prom.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := r.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, job+".prom"))
+}
@@ -0,0 +1,328 @@
+// Package metrics collects counters, histograms and gauges and writes them in the Prometheus text exposition format.
+// It covers the subset of the format needed by the services, so that no client library is required.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of request latency histograms in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample is a value with the values of the label names of its metric, in the same order
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+type collector interface {
+	write(w *bytes.Buffer)
+}
+
+// Registry holds the metrics exported by a process
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]bool
+	collectors []collector
+}
+
+// DefaultRegistry is exported by the metrics endpoint of the services
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic("metric registered twice: " + name)
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes all metrics in the text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector{}, r.collectors...)
+	r.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, c := range collectors {
+		c.write(&buf)
+	}
+	return buf.WriteTo(w)
+}
+
+type desc struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+}
+
+func (d desc) writeHeader(w *bytes.Buffer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.metricType)
+}
+
+func (d desc) checkLabels(labelValues []string) {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", d.name, len(d.labelNames), len(labelValues)))
+	}
+}
+
+// labelKey joins label values with a separator that cannot be part of valid UTF-8
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*Sample
+}
+
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, metricType: "counter", labelNames: labelNames},
+		values: map[string]*Sample{},
+	}
+	r.register(name, c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter, negative values are ignored
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	c.checkLabels(labelValues)
+	if v < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := labelKey(labelValues)
+	s, ok := c.values[key]
+	if !ok {
+		s = &Sample{LabelValues: append([]string{}, labelValues...)}
+		c.values[key] = s
+	}
+	s.Value += v
+}
+
+func (c *CounterVec) write(w *bytes.Buffer) {
+	c.mu.Lock()
+	samples := make([]Sample, 0, len(c.values))
+	for _, s := range c.values {
+		samples = append(samples, *s)
+	}
+	c.mu.Unlock()
+
+	c.writeHeader(w)
+	writeSamples(w, c.name, c.labelNames, samples)
+}
+
+type histogramValues struct {
+	labelValues []string
+	buckets     []uint64
+	count       uint64
+	sum         float64
+}
+
+// HistogramVec counts observations in buckets, partitioned by labels
+type HistogramVec struct {
+	desc
+	bounds []float64
+	mu     sync.Mutex
+	values map[string]*histogramValues
+}
+
+// NewHistogram uses DefaultBuckets if buckets is empty
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bounds := append([]float64{}, buckets...)
+	sort.Float64s(bounds)
+
+	h := &HistogramVec{
+		desc:   desc{name: name, help: help, metricType: "histogram", labelNames: labelNames},
+		bounds: bounds,
+		values: map[string]*histogramValues{},
+	}
+	r.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.checkLabels(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := labelKey(labelValues)
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValues{labelValues: append([]string{}, labelValues...), buckets: make([]uint64, len(h.bounds))}
+		h.values[key] = hv
+	}
+	hv.count += 1
+	hv.sum += v
+	for i, bound := range h.bounds {
+		if v <= bound {
+			hv.buckets[i] += 1
+		}
+	}
+}
+
+func (h *HistogramVec) write(w *bytes.Buffer) {
+	h.mu.Lock()
+	values := make([]histogramValues, 0, len(h.values))
+	for _, hv := range h.values {
+		values = append(values, histogramValues{
+			labelValues: hv.labelValues,
+			buckets:     append([]uint64{}, hv.buckets...),
+			count:       hv.count,
+			sum:         hv.sum,
+		})
+	}
+	h.mu.Unlock()
+	sort.Slice(values, func(i, j int) bool {
+		return labelKey(values[i].labelValues) < labelKey(values[j].labelValues)
+	})
+
+	h.writeHeader(w)
+	for _, hv := range values {
+		WriteHistogram(w, h.name, h.labelNames, hv.labelValues, h.bounds, hv.buckets, hv.count, hv.sum)
+	}
+}
+
+// WriteHistogram writes the series of one histogram. buckets holds the cumulative count of each bound, count includes
+// the observations above the largest bound.
+func WriteHistogram(w *bytes.Buffer, name string, labelNames []string, labelValues []string, bounds []float64, buckets []uint64, count uint64, sum float64) {
+	bucketLabels := append(append([]string{}, labelNames...), "le")
+	for i, bound := range bounds {
+		writeSample(w, name+"_bucket", bucketLabels, append(append([]string{}, labelValues...), formatFloat(bound)), float64(buckets[i]))
+	}
+	writeSample(w, name+"_bucket", bucketLabels, append(append([]string{}, labelValues...), "+Inf"), float64(count))
+	writeSample(w, name+"_sum", labelNames, labelValues, sum)
+	writeSample(w, name+"_count", labelNames, labelValues, float64(count))
+}
+
+// funcCollector writes the output of a function called on each scrape
+type funcCollector struct {
+	desc
+	collect func() []Sample
+}
+
+// NewGaugeFunc registers a gauge with values computed on each scrape, e.g. queue sizes read from the DB
+func (r *Registry) NewGaugeFunc(name string, help string, labelNames []string, collect func() []Sample) {
+	r.register(name, &funcCollector{
+		desc:    desc{name: name, help: help, metricType: "gauge", labelNames: labelNames},
+		collect: collect,
+	})
+}
+
+// NewCounterFunc registers a counter maintained elsewhere, e.g. by the DB instrumentation
+func (r *Registry) NewCounterFunc(name string, help string, labelNames []string, collect func() []Sample) {
+	r.register(name, &funcCollector{
+		desc:    desc{name: name, help: help, metricType: "counter", labelNames: labelNames},
+		collect: collect,
+	})
+}
+
+func (f *funcCollector) write(w *bytes.Buffer) {
+	f.writeHeader(w)
+	writeSamples(w, f.name, f.labelNames, f.collect())
+}
+
+// rawCollector writes complete series, used for histograms recorded elsewhere
+type rawCollector struct {
+	desc
+	writeFn func(w *bytes.Buffer)
+}
+
+// NewHistogramFunc registers a histogram written by the function on each scrape with WriteHistogram
+func (r *Registry) NewHistogramFunc(name string, help string, write func(w *bytes.Buffer)) {
+	r.register(name, &rawCollector{
+		desc:    desc{name: name, help: help, metricType: "histogram"},
+		writeFn: write,
+	})
+}
+
+func (c *rawCollector) write(w *bytes.Buffer) {
+	c.writeHeader(w)
+	c.writeFn(w)
+}
+
+func writeSamples(w *bytes.Buffer, name string, labelNames []string, samples []Sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return labelKey(samples[i].LabelValues) < labelKey(samples[j].LabelValues)
+	})
+	for _, s := range samples {
+		writeSample(w, name, labelNames, s.LabelValues, s.Value)
+	}
+}
+
+func writeSample(w *bytes.Buffer, name string, labelNames []string, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			labelValue := ""
+			if i < len(labelValues) {
+				labelValue = labelValues[i]
+			}
+			w.WriteString(labelName)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabelValue(labelValue))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounter("requests_total", "Requests.", "route")
+	counter.Inc("/b")
+	counter.Add(2, "/a")
+	counter.Add(-1, "/a")
+	counter.Inc("say \"hi\"\n")
+
+	histogram := r.NewHistogram("duration_seconds", "Duration.", []float64{1, 0.1}, "route")
+	histogram.Observe(0.05, "/a")
+	histogram.Observe(0.5, "/a")
+	histogram.Observe(5, "/a")
+
+	r.NewGaugeFunc("queue_depth", "Queue depth.", []string{"instance_id"}, func() []Sample {
+		return []Sample{{LabelValues: []string{"i1"}, Value: 3}}
+	})
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a"} 2
+requests_total{route="/b"} 1
+requests_total{route="say \"hi\"\n"} 1
+# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 1
+duration_seconds_bucket{route="/a",le="1"} 2
+duration_seconds_bucket{route="/a",le="+Inf"} 3
+duration_seconds_sum{route="/a"} 5.55
+duration_seconds_count{route="/a"} 3
+# HELP queue_depth Queue depth.
+# TYPE queue_depth gauge
+queue_depth{instance_id="i1"} 3
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+
+	t.Run("duplicate names panic", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		r.NewCounter("requests_total", "Requests.")
+	})
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	Setup(router, Config{Enabled: true, BearerToken: "secret"})
+	router.POST("/auth/login/:instanceID", AuthOutcome("test_login"), func(c *gin.Context) {
+		c.Status(http.StatusUnauthorized)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/login/i1", nil))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized without token, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{method="POST",route="/auth/login/:instanceID",status="401"} 1`,
+		`auth_events_total{event="test_login",outcome="denied"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/auth/login/:instanceID"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %s in:\n%s", line, body)
+		}
+	}
+}
+
+func TestAuthOutcome(t *testing.T) {
+	for status, outcome := range map[int]string{
+		200: AUTH_OUTCOME_SUCCESS,
+		401: AUTH_OUTCOME_DENIED,
+		403: AUTH_OUTCOME_DENIED,
+		429: AUTH_OUTCOME_RATE_LIMITED,
+		400: AUTH_OUTCOME_INVALID,
+		500: AUTH_OUTCOME_ERROR,
+	} {
+		if got := authOutcome(status); got != outcome {
+			t.Errorf("status %d: expected %s, got %s", status, outcome, got)
+		}
+	}
+}
+
+func TestWriteJobMetrics(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteJobMetrics(dir, "trash-purge", 1500*time.Millisecond, time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected only the metrics file, got %d files", len(files))
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "trash-purge.prom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`job_duration_seconds{job="trash-purge"} 1.5`,
+		`job_last_run_timestamp_seconds{job="trash-purge"} 1.7e+09`,
+	} {
+		if !strings.Contains(string(content), line) {
+			t.Errorf("missing %s in:\n%s", line, content)
+		}
+	}
+}
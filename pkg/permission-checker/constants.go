@@ -18,6 +18,10 @@ const (
 	RESOURCE_KEY_MESSAGING_STUDY_EMAIL_TEMPLATES  = "study-email-templates"
 	RESOURCE_KEY_MESSAGING_SCHEDULED_EMAILS       = "scheduled-emails"
 	RESOURCE_KEY_MESSAGING_SMS_TEMPLATES          = "sms-templates"
+	RESOURCE_KEY_MESSAGING_CAMPAIGNS              = "campaigns"
+	RESOURCE_KEY_MESSAGING_FAILED_EMAILS          = "failed-emails"
+	RESOURCE_KEY_MESSAGING_WEBHOOKS               = "webhooks"
+	RESOURCE_KEY_MESSAGING_SANDBOX                = "sandbox"
 )
 
 const (
@@ -37,6 +41,9 @@ const (
 	ACTION_UNPUBLISH_SURVEY      = "unpublish-survey"
 	ACTION_DELETE_SURVEY_VERSION = "delete-survey-version"
 
+	ACTION_REVIEW_DRAFTS  = "review-drafts"
+	ACTION_PUBLISH_DRAFTS = "publish-drafts"
+
 	ACTION_GET_RESPONSES              = "get-responses"
 	ACTION_DELETE_RESPONSES           = "delete-responses"
 	ACTION_GET_CONFIDENTIAL_RESPONSES = "get-confidential-responses"
@@ -45,6 +52,9 @@ const (
 	ACTION_GET_PARTICIPANT_STATES     = "get-participant-states"
 	ACTION_GET_REPORTS                = "get-reports"
 	ACTION_DELETE_REPORTS             = "delete-reports"
+	ACTION_DELETE_PARTICIPANT_DATA    = "delete-participant-data"
+	ACTION_IMPORT_PARTICIPANTS        = "import-participants"
+	ACTION_EDIT_PARTICIPANT_STATES    = "edit-participant-states"
 
 	ACTION_DELETE_USERS = "delete-users"
 
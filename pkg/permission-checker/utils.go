@@ -1,6 +1,8 @@
 package permissionchecker
 
 import (
+	"slices"
+
 	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
 )
 
@@ -43,6 +45,23 @@ func IsAuthorized(
 	return false
 }
 
+// IsInScope checks if one of the scopes of an access token allows the action on one of the resource keys. Access
+// tokens are authorized by the permissions of their user and their scopes.
+func IsInScope(scopes []muDB.AccessTokenScope, resourceType string, resourceKeys []string, action string) bool {
+	for _, scope := range scopes {
+		if scope.ResourceType != resourceType {
+			continue
+		}
+		if scope.Action != ACTION_ALL && scope.Action != action {
+			continue
+		}
+		if scope.ResourceKey == "*" || slices.Contains(resourceKeys, scope.ResourceKey) {
+			return true
+		}
+	}
+	return false
+}
+
 func getRelevantPermissions(db MuDBConnector, instanceID string, subjectID string, subjectType string, resourceType string, resourceKeys []string, action string) ([]*muDB.Permission, error) {
 	permissions, err := db.GetPermissionBySubjectAndResourceForAction(instanceID, subjectID, subjectType, resourceType, resourceKeys, action)
 	if err != nil {
@@ -260,3 +260,33 @@ func TestCompareLimiter(t *testing.T) {
 		}
 	}
 }
+
+func TestIsInScope(t *testing.T) {
+	t.Parallel()
+	scopes := []muDB.AccessTokenScope{
+		{ResourceType: RESOURCE_TYPE_STUDY, ResourceKey: "study1", Action: ACTION_GET_RESPONSES},
+		{ResourceType: RESOURCE_TYPE_MESSAGING, ResourceKey: "*", Action: ACTION_ALL},
+	}
+
+	tests := []struct {
+		name         string
+		resourceType string
+		resourceKeys []string
+		action       string
+		expected     bool
+	}{
+		{"matching study", RESOURCE_TYPE_STUDY, []string{RESOURCE_KEY_STUDY_ALL, "study1"}, ACTION_GET_RESPONSES, true},
+		{"other study", RESOURCE_TYPE_STUDY, []string{RESOURCE_KEY_STUDY_ALL, "study2"}, ACTION_GET_RESPONSES, false},
+		{"other action", RESOURCE_TYPE_STUDY, []string{RESOURCE_KEY_STUDY_ALL, "study1"}, ACTION_DELETE_RESPONSES, false},
+		{"all keys and actions", RESOURCE_TYPE_MESSAGING, []string{RESOURCE_KEY_MESSAGING_CAMPAIGNS}, "send-campaign", true},
+		{"other resource type", RESOURCE_TYPE_USERS, []string{"*"}, ACTION_DELETE_USERS, false},
+	}
+	for _, test := range tests {
+		if result := IsInScope(scopes, test.resourceType, test.resourceKeys, test.action); result != test.expected {
+			t.Errorf("%s: expected %t but got %t", test.name, test.expected, result)
+		}
+	}
+	if IsInScope(nil, RESOURCE_TYPE_STUDY, []string{"study1"}, ACTION_GET_RESPONSES) {
+		t.Errorf("expected no access without scopes")
+	}
+}
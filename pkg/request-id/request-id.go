@@ -0,0 +1,91 @@
+// Package requestid carries the ID of an API request through the context, so that log entries and calls to other
+// systems caused by the request can be correlated.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+const (
+	HEADER = "X-Request-ID"
+	// key of the ID in log entries
+	LOG_KEY = "requestID"
+
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// New generates a random request ID
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an ID sent by the caller can be used, only short IDs of printable characters are accepted
+// so that they cannot forge log entries or headers
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		valid := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' || r == ':'
+		if !valid {
+			return false
+		}
+	}
+	return true
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of the context, empty if there is none
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns the default logger with the request ID of the context attached
+func Logger(ctx context.Context) *slog.Logger {
+	id := FromContext(ctx)
+	if id == "" {
+		return slog.Default()
+	}
+	return slog.Default().With(slog.String(LOG_KEY, id))
+}
+
+// Handler adds the request ID of the context to the records logged with the context, e.g. with slog.InfoContext
+type Handler struct {
+	slog.Handler
+}
+
+func (h Handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String(LOG_KEY, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h Handler) WithGroup(name string) slog.Handler {
+	return Handler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Detach returns a context carrying only the request ID, for background work that outlives the request
+func Detach(ctx context.Context) context.Context {
+	return NewContext(context.Background(), FromContext(ctx))
+}
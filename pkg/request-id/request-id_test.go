@@ -0,0 +1,57 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	valid := []string{"abc", "4bf92f3577b34da6a3ce929d0e0e4736", "req-1.2:3_4"}
+	for _, id := range valid {
+		if !Valid(id) {
+			t.Errorf("%q should be valid", id)
+		}
+	}
+	invalid := []string{"", "a b", "line\nbreak", "<script>", strings.Repeat("a", 129)}
+	for _, id := range invalid {
+		if Valid(id) {
+			t.Errorf("%q should be invalid", id)
+		}
+	}
+	if id := New(); !Valid(id) || len(id) != 32 {
+		t.Errorf("unexpected generated ID %q", id)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(Handler{Handler: slog.NewJSONHandler(buf, nil)}).With(slog.String("service", "test"))
+
+	ctx := NewContext(context.Background(), "req-1")
+	logger.InfoContext(ctx, "with ID")
+	logger.Info("without ID")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output %s", buf.String())
+	}
+	for i, expected := range []string{"req-1", ""} {
+		entry := map[string]any{}
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatal(err)
+		}
+		id, _ := entry[LOG_KEY].(string)
+		if id != expected || entry["service"] != "test" {
+			t.Errorf("unexpected entry %v", entry)
+		}
+	}
+
+	detached := Detach(ctx)
+	if FromContext(detached) != "req-1" || FromContext(context.Background()) != "" {
+		t.Error("unexpected request ID in context")
+	}
+}
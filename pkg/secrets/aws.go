@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	awssigv4 "github.com/case-framework/case-backend/pkg/aws-sigv4"
+)
+
+const (
+	ENV_AWS_REGION            = "AWS_REGION"
+	ENV_AWS_ACCESS_KEY_ID     = "AWS_ACCESS_KEY_ID"
+	ENV_AWS_SECRET_ACCESS_KEY = "AWS_SECRET_ACCESS_KEY"
+	ENV_AWS_SESSION_TOKEN     = "AWS_SESSION_TOKEN"
+
+	awsSecretsManagerService = "secretsmanager"
+	// limit for error messages read from responses
+	maxErrorBodySize = 4096
+)
+
+type AWSConfig struct {
+	Region          string `json:"region" yaml:"region"`
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key"`
+	SessionToken    string `json:"session_token" yaml:"session_token"`
+	// optional, e.g. for VPC endpoints, defaults to https://secretsmanager.<region>.amazonaws.com
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+}
+
+func (c *AWSConfig) applyEnv() {
+	if c.Region == "" {
+		c.Region = os.Getenv(ENV_AWS_REGION)
+	}
+	// credentials from the environment are only used together
+	if c.AccessKeyID == "" && c.SecretAccessKey == "" {
+		c.AccessKeyID = os.Getenv(ENV_AWS_ACCESS_KEY_ID)
+		c.SecretAccessKey = os.Getenv(ENV_AWS_SECRET_ACCESS_KEY)
+		c.SessionToken = os.Getenv(ENV_AWS_SESSION_TOKEN)
+	}
+}
+
+// awsProvider reads secrets from AWS Secrets Manager, the name is the secret name or ARN
+type awsProvider struct {
+	config AWSConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+func newAWSProvider(config AWSConfig, timeout time.Duration) *awsProvider {
+	if config.Endpoint == "" && config.Region != "" {
+		config.Endpoint = "https://secretsmanager." + config.Region + ".amazonaws.com"
+	}
+	return &awsProvider{config: config, client: &http.Client{Timeout: timeout}, now: time.Now}
+}
+
+func (p *awsProvider) Fetch(ctx context.Context, name string) (string, error) {
+	if p.config.Region == "" || p.config.AccessKeyID == "" || p.config.SecretAccessKey == "" {
+		return "", fmt.Errorf("%w: aws region and credentials are required", ErrProviderNotConfigured)
+	}
+	body, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	creds := awssigv4.Credentials{
+		AccessKeyID:     p.config.AccessKeyID,
+		SecretAccessKey: p.config.SecretAccessKey,
+		SessionToken:    p.config.SessionToken,
+	}
+	awssigv4.Sign(req, creds, p.config.Region, awsSecretsManagerService, awssigv4.PayloadHash(body), p.now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return "", fmt.Errorf("secrets manager responded with status %d: %s", resp.StatusCode, msg)
+	}
+
+	var res struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary []byte `json:"SecretBinary"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	if res.SecretString == "" && len(res.SecretBinary) > 0 {
+		return string(res.SecretBinary), nil
+	}
+	return res.SecretString, nil
+}
@@ -0,0 +1,19 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// fileProvider reads secrets mounted as files, e.g. Docker or Kubernetes secrets. The name is the file path.
+type fileProvider struct{}
+
+func (fileProvider) Fetch(_ context.Context, name string) (string, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	// editors and `echo` add a trailing newline that is not part of the secret
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
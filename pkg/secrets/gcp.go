@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com"
+	gcpMetadataTokenURL      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+type GCPConfig struct {
+	// OAuth access token, if not set the token of the service account is requested from the metadata server
+	AccessToken string `json:"access_token" yaml:"access_token"`
+	// optional, defaults to https://secretmanager.googleapis.com
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	// optional, defaults to the token endpoint of the metadata server
+	MetadataTokenURL string `json:"metadata_token_url" yaml:"metadata_token_url"`
+}
+
+// gcpProvider reads secrets from GCP Secret Manager, the name is the resource name of the secret or of a version,
+// e.g. "projects/p/secrets/db-password". Without version the latest one is read.
+type gcpProvider struct {
+	config GCPConfig
+	client *http.Client
+}
+
+func newGCPProvider(config GCPConfig, timeout time.Duration) *gcpProvider {
+	if config.Endpoint == "" {
+		config.Endpoint = gcpSecretManagerEndpoint
+	}
+	if config.MetadataTokenURL == "" {
+		config.MetadataTokenURL = gcpMetadataTokenURL
+	}
+	return &gcpProvider{config: config, client: &http.Client{Timeout: timeout}}
+}
+
+func (p *gcpProvider) Fetch(ctx context.Context, name string) (string, error) {
+	if !strings.HasPrefix(name, "projects/") {
+		return "", errors.New("gcp secret name must start with projects/")
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Endpoint+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var res struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := p.getJSON(req, &res); err != nil {
+		return "", fmt.Errorf("secret manager: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// accessToken returns the configured token or requests one for the service account, tokens are valid for about an
+// hour and secrets are only read at startup and refresh, so the token is not cached
+func (p *gcpProvider) accessToken(ctx context.Context) (string, error) {
+	if p.config.AccessToken != "" {
+		return p.config.AccessToken, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.MetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := p.getJSON(req, &res); err != nil {
+		return "", fmt.Errorf("%w: no access token and metadata server not available: %v", ErrProviderNotConfigured, err)
+	}
+	return res.AccessToken, nil
+}
+
+func (p *gcpProvider) getJSON(req *http.Request, result any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, msg)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,338 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// REFERENCE_PREFIX marks config values that are fetched from a secret provider, e.g.
+// "secret:vault:secret/data/case/db#password" or "secret:file:/run/secrets/db-password"
+const REFERENCE_PREFIX = "secret:"
+
+const (
+	PROVIDER_FILE   = "file"
+	PROVIDER_VAULT  = "vault"
+	PROVIDER_AWS_SM = "aws-sm"
+	PROVIDER_GCP_SM = "gcp-sm"
+)
+
+const defaultTimeout = 10 * time.Second
+
+var ErrProviderNotConfigured = errors.New("secret provider not configured")
+
+type Config struct {
+	Vault VaultConfig `json:"vault" yaml:"vault"`
+	AWS   AWSConfig   `json:"aws" yaml:"aws"`
+	GCP   GCPConfig   `json:"gcp" yaml:"gcp"`
+
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+
+	// referenced secrets are fetched again in this interval to detect rotations, 0 disables it
+	RefreshInterval time.Duration `json:"refresh_interval" yaml:"refresh_interval"`
+	// shut down gracefully when a secret was rotated, so that the restarted process uses the new value. Otherwise the
+	// rotation is only logged.
+	RestartOnRotation bool `json:"restart_on_rotation" yaml:"restart_on_rotation"`
+}
+
+// Provider fetches the value of a secret by its provider specific name
+type Provider interface {
+	Fetch(ctx context.Context, name string) (string, error)
+}
+
+// Reference is a parsed secret reference
+type Reference struct {
+	Provider string
+	Name     string
+	// optional key of the value if the secret is a JSON object
+	Key string
+}
+
+// ParseReference parses a config value of the form "secret:<provider>:<name>[#<key>]", ok is false for plain values
+func ParseReference(value string) (ref Reference, ok bool, err error) {
+	if !strings.HasPrefix(value, REFERENCE_PREFIX) {
+		return ref, false, nil
+	}
+	provider, name, found := strings.Cut(strings.TrimPrefix(value, REFERENCE_PREFIX), ":")
+	if !found || provider == "" || name == "" {
+		return ref, true, fmt.Errorf("invalid secret reference %q", value)
+	}
+	ref = Reference{Provider: provider, Name: name}
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		ref.Name, ref.Key = name[:i], name[i+1:]
+	}
+	return ref, true, nil
+}
+
+func (r Reference) String() string {
+	s := REFERENCE_PREFIX + r.Provider + ":" + r.Name
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// Resolver replaces secret references in a config with the fetched values and remembers them for rotation checks
+type Resolver struct {
+	conf      Config
+	providers map[string]Provider
+
+	mu sync.Mutex
+	// reference and last fetched value by config path
+	refs   map[string]Reference
+	values map[string]string
+}
+
+// NewResolver creates the providers of the config. Credentials of the providers themselves may be file references,
+// e.g. a mounted Vault token, and fall back to the usual environment variables (VAULT_ADDR, AWS_ACCESS_KEY_ID, ...).
+func NewResolver(conf Config) (*Resolver, error) {
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultTimeout
+	}
+	file := fileProvider{}
+	err := walk(reflect.ValueOf(&conf).Elem(), "", func(path string, value string) (string, error) {
+		ref, ok, err := ParseReference(value)
+		if err != nil || !ok {
+			return value, err
+		}
+		if ref.Provider != PROVIDER_FILE {
+			return value, fmt.Errorf("%s: secret provider credentials can only reference files", path)
+		}
+		return fetch(context.Background(), file, ref)
+	})
+	if err != nil {
+		return nil, err
+	}
+	conf.Vault.applyEnv()
+	conf.AWS.applyEnv()
+
+	return &Resolver{
+		conf: conf,
+		providers: map[string]Provider{
+			PROVIDER_FILE:   file,
+			PROVIDER_VAULT:  newVaultProvider(conf.Vault, conf.Timeout),
+			PROVIDER_AWS_SM: newAWSProvider(conf.AWS, conf.Timeout),
+			PROVIDER_GCP_SM: newGCPProvider(conf.GCP, conf.Timeout),
+		},
+		refs:   map[string]Reference{},
+		values: map[string]string{},
+	}, nil
+}
+
+// Resolve creates a resolver and resolves the references in target, which has to be a pointer to the config struct
+func Resolve(ctx context.Context, conf Config, target any) (*Resolver, error) {
+	r, err := NewResolver(conf)
+	if err != nil {
+		return nil, err
+	}
+	return r, r.Resolve(ctx, target)
+}
+
+// Resolve replaces all string values of target that are secret references. Values referenced more than once are
+// fetched once.
+func (r *Resolver) Resolve(ctx context.Context, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("secrets can only be resolved in a non-nil pointer")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fetched := map[Reference]string{}
+	return walk(v.Elem(), "", func(path string, value string) (string, error) {
+		ref, ok, err := ParseReference(value)
+		if err != nil || !ok {
+			return value, err
+		}
+		secret, done := fetched[ref]
+		if !done {
+			secret, err = r.fetch(ctx, ref)
+			if err != nil {
+				return value, fmt.Errorf("%s: %w", path, err)
+			}
+			fetched[ref] = secret
+		}
+		r.refs[path] = ref
+		r.values[path] = secret
+		return secret, nil
+	})
+}
+
+func (r *Resolver) fetch(ctx context.Context, ref Reference) (string, error) {
+	provider, ok := r.providers[ref.Provider]
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", ref.Provider)
+	}
+	return fetch(ctx, provider, ref)
+}
+
+func fetch(ctx context.Context, provider Provider, ref Reference) (string, error) {
+	value, err := provider.Fetch(ctx, ref.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+	if ref.Key == "" {
+		return value, nil
+	}
+	return jsonKey(value, ref.Key)
+}
+
+// jsonKey returns the value of the key from a secret stored as JSON object, non-string values are returned as JSON
+func jsonKey(secret string, key string) (string, error) {
+	values := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("secret is no JSON object, cannot read key %s", key)
+	}
+	raw, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no key %s", key)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	return string(raw), nil
+}
+
+// Refresh fetches the resolved secrets again and returns the config paths of the rotated ones
+func (r *Resolver) Refresh(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fetched := map[Reference]string{}
+	changed := []string{}
+	var errs []error
+	for path, ref := range r.refs {
+		secret, done := fetched[ref]
+		if !done {
+			var err error
+			secret, err = r.fetch(ctx, ref)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fetched[ref] = secret
+		}
+		if secret != r.values[path] {
+			r.values[path] = secret
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed, errors.Join(errs...)
+}
+
+// Watch refreshes the secrets in the configured interval until the context is done. On rotation shutdown is called
+// if restart_on_rotation is set. Services use clients created at startup, so a restart is needed to use new values.
+func (r *Resolver) Watch(ctx context.Context, shutdown func()) {
+	if r.conf.RefreshInterval <= 0 || len(r.refs) == 0 {
+		return
+	}
+	ticker := time.NewTicker(r.conf.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Refresh(ctx)
+			if err != nil {
+				slog.Error("failed to refresh secrets", slog.String("error", err.Error()))
+			}
+			if len(changed) == 0 {
+				continue
+			}
+			// only the paths are logged, never the values
+			if !r.conf.RestartOnRotation {
+				slog.Warn("secrets rotated, restart to apply them", slog.Any("paths", changed))
+				continue
+			}
+			slog.Warn("secrets rotated, shutting down to restart with the new values", slog.Any("paths", changed))
+			shutdown()
+			return
+		}
+	}
+}
+
+// walk calls visit for every string reachable from v and replaces it with the result. Map values and interfaces are
+// not addressable, they are copied and set again.
+func walk(v reflect.Value, path string, visit func(path string, value string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return walk(v.Elem(), path, visit)
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return nil
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := walk(elem, path, visit); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if err := walk(v.Field(i), joinPath(path, yamlName(field)), visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i), visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			if err := walk(elem, joinPath(path, fmt.Sprint(iter.Key().Interface())), visit); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		value, err := visit(path, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(value)
+	}
+	return nil
+}
+
+// yamlName returns the key of the field in the config file, yaml.v2 uses the lowercased field name if no tag is set
+func yamlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func joinPath(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
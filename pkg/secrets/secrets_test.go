@@ -0,0 +1,221 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	DB struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"db"`
+	JWTKey    string            `yaml:"jwt_key"`
+	APIKeys   []string          `yaml:"api_keys"`
+	Headers   map[string]string `yaml:"headers"`
+	Providers map[string]struct {
+		Token string `yaml:"token"`
+	} `yaml:"providers"`
+	SMS *struct {
+		APIKey string `yaml:"api_key"`
+	} `yaml:"sms"`
+}
+
+func writeSecretFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseReference(t *testing.T) {
+	ref, ok, err := ParseReference("secret:aws-sm:arn:aws:secretsmanager:eu-west-1:1:secret:db#password")
+	if err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if ref.Provider != PROVIDER_AWS_SM || ref.Name != "arn:aws:secretsmanager:eu-west-1:1:secret:db" || ref.Key != "password" {
+		t.Errorf("unexpected reference %+v", ref)
+	}
+
+	if _, ok, _ := ParseReference("plain value"); ok {
+		t.Error("plain value parsed as reference")
+	}
+	if _, ok, err := ParseReference("secret:vault"); !ok || err == nil {
+		t.Error("expected error for missing name")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	passwordFile := writeSecretFile(t, "password", "p4ss\n")
+	jsonFile := writeSecretFile(t, "db.json", `{"username": "case", "port": 27017}`)
+
+	conf := testConfig{JWTKey: "plain"}
+	conf.DB.Username = "secret:file:" + jsonFile + "#username"
+	conf.DB.Password = "secret:file:" + passwordFile
+	conf.APIKeys = []string{"secret:file:" + passwordFile, "k2"}
+	conf.Headers = map[string]string{"port": "secret:file:" + jsonFile + "#port"}
+	conf.Providers = map[string]struct {
+		Token string `yaml:"token"`
+	}{"mailgun": {Token: "secret:file:" + passwordFile}}
+	conf.SMS = &struct {
+		APIKey string `yaml:"api_key"`
+	}{APIKey: "secret:file:" + passwordFile}
+
+	r, err := Resolve(context.Background(), Config{}, &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.DB.Username != "case" || conf.DB.Password != "p4ss" || conf.JWTKey != "plain" ||
+		conf.APIKeys[0] != "p4ss" || conf.Headers["port"] != "27017" || conf.Providers["mailgun"].Token != "p4ss" ||
+		conf.SMS.APIKey != "p4ss" {
+		t.Errorf("unexpected config %+v", conf)
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		if changed, err := r.Refresh(context.Background()); err != nil || len(changed) != 0 {
+			t.Errorf("unexpected result %v %v", changed, err)
+		}
+		if err := os.WriteFile(passwordFile, []byte("rotated"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		changed, err := r.Refresh(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"api_keys[0]", "db.password", "providers.mailgun.token", "sms.api_key"}
+		if !reflect.DeepEqual(changed, expected) {
+			t.Errorf("unexpected changes %v", changed)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, value := range []string{
+			"secret:file:" + filepath.Join(t.TempDir(), "missing"),
+			"secret:file:" + jsonFile + "#missing",
+			"secret:unknown:x",
+			"secret:vault:secret/data/x#key",
+		} {
+			c := testConfig{JWTKey: value}
+			if _, err := Resolve(context.Background(), Config{}, &c); err == nil || !strings.Contains(err.Error(), "jwt_key") {
+				t.Errorf("%s: unexpected error %v", value, err)
+			}
+		}
+	})
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/case/db":
+			w.Write([]byte(`{"data": {"data": {"password": "kv2"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv1/case/db":
+			w.Write([]byte(`{"data": {"password": "kv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := writeSecretFile(t, "token", "token\n")
+	r, err := NewResolver(Config{Vault: VaultConfig{Address: server.URL, Token: "secret:file:" + tokenFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := testConfig{APIKeys: []string{"secret:vault:secret/data/case/db#password", "secret:vault:kv1/case/db#password"}}
+	if err := r.Resolve(context.Background(), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.APIKeys[0] != "kv2" || conf.APIKeys[1] != "kv1" {
+		t.Errorf("unexpected values %v", conf.APIKeys)
+	}
+
+	conf = testConfig{JWTKey: "secret:vault:secret/data/missing#key"}
+	if err := r.Resolve(context.Background(), &conf); err == nil {
+		t.Error("expected error")
+	}
+
+	if _, err := NewResolver(Config{Vault: VaultConfig{Token: "secret:vault:x#y"}}); err == nil {
+		t.Error("expected error for provider credentials from vault")
+	}
+}
+
+func TestAWSProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request") ||
+			r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body struct{ SecretId string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.SecretId != "case/db" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"Name": "case/db", "SecretString": "{\"password\": \"aws\"}"}`))
+	}))
+	defer server.Close()
+
+	conf := testConfig{JWTKey: "secret:aws-sm:case/db#password"}
+	_, err := Resolve(context.Background(), Config{AWS: AWSConfig{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Endpoint:        server.URL,
+	}}, &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.JWTKey != "aws" {
+		t.Errorf("unexpected value %s", conf.JWTKey)
+	}
+}
+
+func TestGCPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token": "gcp-token", "expires_in": 3599}`))
+		case "/v1/projects/p/secrets/jwt/versions/latest:access":
+			if r.Header.Get("Authorization") != "Bearer gcp-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("gcp"))},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	conf := testConfig{JWTKey: "secret:gcp-sm:projects/p/secrets/jwt"}
+	_, err := Resolve(context.Background(), Config{GCP: GCPConfig{Endpoint: server.URL, MetadataTokenURL: server.URL + "/token"}}, &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.JWTKey != "gcp" {
+		t.Errorf("unexpected value %s", conf.JWTKey)
+	}
+}
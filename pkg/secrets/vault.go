@@ -0,0 +1,89 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	ENV_VAULT_ADDR      = "VAULT_ADDR"
+	ENV_VAULT_TOKEN     = "VAULT_TOKEN"
+	ENV_VAULT_NAMESPACE = "VAULT_NAMESPACE"
+)
+
+type VaultConfig struct {
+	Address   string `json:"address" yaml:"address"`
+	Token     string `json:"token" yaml:"token"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+}
+
+func (c *VaultConfig) applyEnv() {
+	if c.Address == "" {
+		c.Address = os.Getenv(ENV_VAULT_ADDR)
+	}
+	if c.Token == "" {
+		c.Token = os.Getenv(ENV_VAULT_TOKEN)
+	}
+	if c.Namespace == "" {
+		c.Namespace = os.Getenv(ENV_VAULT_NAMESPACE)
+	}
+}
+
+// vaultProvider reads secrets from the KV secrets engine, the name is the API path, e.g. "secret/data/case/db".
+// Secrets are JSON objects, the key is selected with "#key".
+type vaultProvider struct {
+	config VaultConfig
+	client *http.Client
+}
+
+func newVaultProvider(config VaultConfig, timeout time.Duration) *vaultProvider {
+	return &vaultProvider{config: config, client: &http.Client{Timeout: timeout}}
+}
+
+func (p *vaultProvider) Fetch(ctx context.Context, name string) (string, error) {
+	if p.config.Address == "" || p.config.Token == "" {
+		return "", fmt.Errorf("%w: vault address and token are required", ErrProviderNotConfigured)
+	}
+	endpoint, err := url.JoinPath(p.config.Address, "v1", name)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.config.Token)
+	if p.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.config.Namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("vault responded with status %d", resp.StatusCode)
+	}
+
+	var res struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	// KV version 2 nests the secret and its metadata, version 1 returns the secret directly
+	data, isV2 := res.Data["data"]
+	if _, hasMetadata := res.Data["metadata"]; !isV2 || !hasMetadata {
+		raw, err := json.Marshal(res.Data)
+		return string(raw), err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,196 @@
+// Package securityevents emits security relevant events (logins, OTPs, password resets, token revocations,
+// privilege changes) as JSON to dedicated sinks for SIEM ingestion. Events are independent of the slog application
+// logs, their schema is versioned and only extended with optional fields.
+package securityevents
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SCHEMA_VERSION is increased if fields are renamed or removed
+const SCHEMA_VERSION = 1
+
+const (
+	EVENT_LOGIN                    = "auth.login"
+	EVENT_OTP_ISSUED               = "auth.otp.issued"
+	EVENT_OTP_VERIFICATION         = "auth.otp.verification"
+	EVENT_TOKEN_REVOKED            = "auth.token.revoked"
+	EVENT_PASSWORD_RESET_REQUESTED = "account.password_reset.requested"
+	EVENT_PASSWORD_RESET           = "account.password_reset.completed"
+	EVENT_PASSWORD_CHANGED         = "account.password.changed"
+	EVENT_PRIVILEGE_CHANGED        = "admin.privilege.changed"
+)
+
+const (
+	OUTCOME_SUCCESS = "success"
+	OUTCOME_FAILURE = "failure"
+)
+
+const (
+	ACTOR_PARTICIPANT     = "participant"
+	ACTOR_MANAGEMENT_USER = "management-user"
+	ACTOR_SERVICE_ACCOUNT = "service-account"
+)
+
+const defaultQueueSize = 1000
+
+// Event is the stable schema of security events. Personal data like email addresses is not included, users are
+// identified by their IDs.
+type Event struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	ID            string    `json:"id"`
+	Time          time.Time `json:"time"`
+	Service       string    `json:"service"`
+	Type          string    `json:"type"`
+	Outcome       string    `json:"outcome"`
+	InstanceID    string    `json:"instanceID,omitempty"`
+	// user performing the action, empty for failed logins of unknown accounts
+	ActorType string `json:"actorType,omitempty"`
+	ActorID   string `json:"actorID,omitempty"`
+	// user affected by the action if not the actor, e.g. the user whose permissions changed
+	TargetType string `json:"targetType,omitempty"`
+	TargetID   string `json:"targetID,omitempty"`
+	SourceIP   string `json:"sourceIP,omitempty"`
+	UserAgent  string `json:"userAgent,omitempty"`
+	// machine readable cause of failures, e.g. wrong-password
+	Reason  string            `json:"reason,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Logger writes events to its sinks in the background, so that slow sinks do not delay requests. Events are dropped
+// with a warning in the application log if the queue is full.
+type Logger struct {
+	service string
+	sinks   []Sink
+	queue   chan Event
+	dropped atomic.Int64
+	done    chan struct{}
+
+	// guards sending to the queue against closing it
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewLogger(service string, sinks []Sink, queueSize int) *Logger {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	l := &Logger{
+		service: service,
+		sinks:   sinks,
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *Logger) Emit(event Event) {
+	event.SchemaVersion = SCHEMA_VERSION
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	event.Service = l.service
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.queue <- event:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	for event := range l.queue {
+		if dropped := l.dropped.Swap(0); dropped > 0 {
+			slog.Warn("security event queue full, events dropped", slog.Int64("count", dropped))
+		}
+
+		line, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("failed to encode security event", slog.String("type", event.Type), slog.String("error", err.Error()))
+			continue
+		}
+		for _, sink := range l.sinks {
+			if err := sink.Write(line); err != nil {
+				slog.Error("failed to write security event", slog.String("sink", sink.Name()), slog.String("type", event.Type), slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Close writes the queued events and closes the sinks, events emitted afterwards are lost
+func (l *Logger) Close() {
+	defaultLogger.CompareAndSwap(l, nil)
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.queue)
+	l.mu.Unlock()
+
+	<-l.done
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			slog.Error("failed to close security event sink", slog.String("sink", sink.Name()), slog.String("error", err.Error()))
+		}
+	}
+}
+
+var defaultLogger atomic.Pointer[Logger]
+
+// Init creates the sinks of the config and sets the logger used by Emit. Without sinks events are discarded.
+func Init(config Config, service string) (*Logger, error) {
+	sinks, err := NewSinks(config.Sinks)
+	if err != nil {
+		return nil, err
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	l := NewLogger(service, sinks, config.QueueSize)
+	defaultLogger.Store(l)
+	return l, nil
+}
+
+// Emit writes the event with the logger set by Init
+func Emit(event Event) {
+	if l := defaultLogger.Load(); l != nil {
+		l.Emit(event)
+	}
+}
+
+// EmitForRequest adds the client address and user agent of the request to the event
+func EmitForRequest(c *gin.Context, event Event) {
+	if defaultLogger.Load() == nil {
+		return
+	}
+	event.SourceIP = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	Emit(event)
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
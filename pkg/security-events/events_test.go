@@ -0,0 +1,162 @@
+package securityevents
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memorySink struct {
+	mu     sync.Mutex
+	events []Event
+	closed bool
+}
+
+func (s *memorySink) Name() string { return "memory" }
+
+func (s *memorySink) Write(event []byte) error {
+	var e Event
+	if err := json.Unmarshal(event, &e); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	sink := &memorySink{}
+	l := NewLogger("participant-api", []Sink{sink}, 10)
+	defaultLogger.Store(l)
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/login", nil)
+	c.Request.Header.Set("User-Agent", "test-agent")
+	c.Request.RemoteAddr = "192.0.2.1:1234"
+
+	EmitForRequest(c, Event{
+		Type:       EVENT_LOGIN,
+		Outcome:    OUTCOME_FAILURE,
+		InstanceID: "i1",
+		ActorType:  ACTOR_PARTICIPANT,
+		ActorID:    "u1",
+		Reason:     "wrong-password",
+	})
+	l.Close()
+	// events after closing are discarded
+	l.Emit(Event{Type: EVENT_LOGIN})
+	Emit(Event{Type: EVENT_LOGIN})
+
+	if !sink.closed {
+		t.Error("sink not closed")
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(sink.events))
+	}
+	e := sink.events[0]
+	if e.SchemaVersion != SCHEMA_VERSION || e.ID == "" || e.Time.IsZero() || e.Service != "participant-api" {
+		t.Errorf("metadata not set: %+v", e)
+	}
+	if e.SourceIP != "192.0.2.1" || e.UserAgent != "test-agent" || e.Reason != "wrong-password" {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "security.log")
+	sink, err := NewSink(SinkConfig{Type: SINK_TYPE_FILE, Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = sink.Write([]byte(`{"type":"a"}`))
+	_ = sink.Write([]byte(`{"type":"b"}`))
+	sink.Close()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "{\"type\":\"a\"}\n{\"type\":\"b\"}\n" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		length, _ := reader.ReadString(' ')
+		n, _ := strconv.Atoi(strings.TrimSpace(length))
+		msg := make([]byte, n)
+		_, _ = io.ReadFull(reader, msg)
+		received <- string(msg)
+	}()
+
+	sink, err := NewSink(SinkConfig{Type: SINK_TYPE_SYSLOG, Network: "tcp", Address: listener.Addr().String(), AppName: "participant-api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err := sink.Write([]byte(`{"type":"auth.login"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-received
+	if !strings.HasPrefix(msg, "<37>1 ") || !strings.Contains(msg, " participant-api ") || !strings.HasSuffix(msg, ` security-event - {"type":"auth.login"}`) {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var body, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkConfig{Type: SINK_TYPE_HTTP, URL: server.URL, Headers: map[string]string{"Authorization": "Splunk token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write([]byte(`{"type":"auth.login"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"type":"auth.login"}` || auth != "Splunk token" {
+		t.Errorf("unexpected request %s %s", body, auth)
+	}
+
+	if _, err := NewSinks([]SinkConfig{{Type: SINK_TYPE_HTTP, URL: server.URL}, {Type: "kafka"}}); err == nil {
+		t.Error("expected error for unknown sink type")
+	}
+}
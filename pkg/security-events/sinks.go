@@ -0,0 +1,237 @@
+package securityevents
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	SINK_TYPE_FILE   = "file"
+	SINK_TYPE_SYSLOG = "syslog"
+	SINK_TYPE_HTTP   = "http"
+)
+
+const (
+	defaultSinkTimeout = 5 * time.Second
+	// facility auth (4) with severity notice (5)
+	syslogPriority = 4*8 + 5
+)
+
+// Config of the security event log in service config files, events are written to all sinks
+type Config struct {
+	Sinks     []SinkConfig `json:"sinks" yaml:"sinks"`
+	QueueSize int          `json:"queue_size" yaml:"queue_size"`
+}
+
+type SinkConfig struct {
+	// file, syslog or http
+	Type string `json:"type" yaml:"type"`
+
+	// file: one event per line, rotated by size
+	Filename   string `json:"filename" yaml:"filename"`
+	MaxSize    int    `json:"max_size" yaml:"max_size"` // megabytes
+	MaxAge     int    `json:"max_age" yaml:"max_age"`   // days
+	MaxBackups int    `json:"max_backups" yaml:"max_backups"`
+	Compress   bool   `json:"compress" yaml:"compress"`
+
+	// syslog: RFC 5424 messages over udp, tcp or tcp+tls
+	Network string `json:"network" yaml:"network"`
+	Address string `json:"address" yaml:"address"`
+	AppName string `json:"app_name" yaml:"app_name"`
+
+	// http: each event is posted as JSON
+	URL     string            `json:"url" yaml:"url"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// Sink receives the JSON encoded events, writes are not concurrent
+type Sink interface {
+	Name() string
+	Write(event []byte) error
+	Close() error
+}
+
+func NewSinks(configs []SinkConfig) ([]Sink, error) {
+	sinks := []Sink{}
+	for i, config := range configs {
+		sink, err := NewSink(config)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, fmt.Errorf("security event sink %d: %w", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func NewSink(config SinkConfig) (Sink, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSinkTimeout
+	}
+
+	switch config.Type {
+	case SINK_TYPE_FILE:
+		if config.Filename == "" {
+			return nil, errors.New("filename is required")
+		}
+		return &fileSink{logger: &lumberjack.Logger{
+			Filename:   config.Filename,
+			MaxSize:    config.MaxSize,
+			MaxAge:     config.MaxAge,
+			MaxBackups: config.MaxBackups,
+			Compress:   config.Compress,
+		}}, nil
+	case SINK_TYPE_SYSLOG:
+		if config.Address == "" {
+			return nil, errors.New("address is required")
+		}
+		switch config.Network {
+		case "":
+			config.Network = "udp"
+		case "udp", "tcp", "tcp+tls":
+		default:
+			return nil, fmt.Errorf("unknown network %s", config.Network)
+		}
+		if config.AppName == "" {
+			config.AppName = "case-backend"
+		}
+		hostname, _ := os.Hostname()
+		if hostname == "" {
+			hostname = "-"
+		}
+		return &syslogSink{config: config, hostname: hostname}, nil
+	case SINK_TYPE_HTTP:
+		if config.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		return &httpSink{config: config, client: &http.Client{Timeout: config.Timeout}}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %s", config.Type)
+	}
+}
+
+type fileSink struct {
+	logger *lumberjack.Logger
+}
+
+func (s *fileSink) Name() string { return SINK_TYPE_FILE }
+
+func (s *fileSink) Write(event []byte) error {
+	_, err := s.logger.Write(append(event, '\n'))
+	return err
+}
+
+func (s *fileSink) Close() error {
+	return s.logger.Close()
+}
+
+// syslogSink keeps one connection open and reconnects after errors
+type syslogSink struct {
+	config   SinkConfig
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (s *syslogSink) Name() string { return SINK_TYPE_SYSLOG }
+
+func (s *syslogSink) Write(event []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := formatSyslogMessage(s.hostname, s.config.AppName, time.Now(), event)
+	if s.config.Network != "udp" {
+		// octet counting framing of RFC 6587, messages may contain newlines
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	// one retry with a new connection, the server may have closed an idle connection
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = s.dial(); err != nil {
+				return err
+			}
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.config.Timeout))
+		if _, err = s.conn.Write(msg); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *syslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	if s.config.Network == "tcp+tls" {
+		host, _, _ := net.SplitHostPort(s.config.Address)
+		return tls.DialWithDialer(dialer, "tcp", s.config.Address, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+	}
+	return dialer.Dial(s.config.Network, s.config.Address)
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// formatSyslogMessage builds an RFC 5424 message with the event as message body
+func formatSyslogMessage(hostname string, appName string, t time.Time, event []byte) []byte {
+	header := fmt.Sprintf("<%d>1 %s %s %s %d security-event - ", syslogPriority, t.UTC().Format(time.RFC3339Nano), hostname, appName, os.Getpid())
+	return append([]byte(header), event...)
+}
+
+type httpSink struct {
+	config SinkConfig
+	client *http.Client
+}
+
+func (s *httpSink) Name() string { return SINK_TYPE_HTTP }
+
+func (s *httpSink) Write(event []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(event))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sink responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	return nil
+}
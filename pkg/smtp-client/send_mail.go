@@ -1,6 +1,7 @@
 package smtp_client
 
 import (
+	"bytes"
 	"errors"
 	"log/slog"
 	"net/textproto"
@@ -9,11 +10,19 @@ import (
 	"github.com/knadh/smtppool"
 )
 
+// Attachment is a file sent with the email
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     []byte `json:"content"`
+}
+
 func (sc *SmtpClients) SendMail(
 	to []string,
 	subject string,
 	htmlContent string,
 	overrides *messagingTypes.HeaderOverrides,
+	attachments ...Attachment,
 ) error {
 	sc.counter += 1
 	if len(sc.connectionPool) < 1 {
@@ -54,6 +63,11 @@ func (sc *SmtpClients) SendMail(
 		HTML:    []byte(htmlContent),
 		Headers: textproto.MIMEHeader{},
 	}
+	for _, a := range attachments {
+		if _, err := e.Attach(bytes.NewReader(a.Content), a.Filename, a.ContentType); err != nil {
+			return err
+		}
+	}
 	err := selectedServer.Send(e)
 
 	if err != nil {
@@ -0,0 +1,286 @@
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// increase when the bundle layout changes in a way older importers cannot read
+	BUNDLE_FORMAT_VERSION = 1
+
+	ZIP_MANIFEST_FILE       = "manifest.json"
+	ZIP_STUDY_FILE          = "study.json"
+	ZIP_RULES_FILE          = "rules.json"
+	ZIP_SURVEYS_DIR         = "surveys/"
+	ZIP_EMAIL_TEMPLATES_DIR = "email-templates/"
+)
+
+const (
+	maxZipEntrySize = 64 << 20
+	maxZipEntries   = 10000
+)
+
+// StudyBundle is a portable copy of a study's definition: study props and configs, current survey versions,
+// current study rules and the study's email templates. It does not contain participant data.
+type StudyBundle struct {
+	FormatVersion    int                            `json:"formatVersion"`
+	ExportedAt       time.Time                      `json:"exportedAt"`
+	SourceInstanceID string                         `json:"sourceInstanceId,omitempty"`
+	Study            studyTypes.Study               `json:"study"`
+	Surveys          []studyTypes.Survey            `json:"surveys"`
+	StudyRules       *studyTypes.StudyRules         `json:"studyRules,omitempty"`
+	EmailTemplates   []messagingTypes.EmailTemplate `json:"emailTemplates"`
+}
+
+type zipManifest struct {
+	FormatVersion    int       `json:"formatVersion"`
+	ExportedAt       time.Time `json:"exportedAt"`
+	SourceInstanceID string    `json:"sourceInstanceId,omitempty"`
+	StudyKey         string    `json:"studyKey"`
+}
+
+// NewStudyBundle creates a bundle and removes the instance specific data from its content
+func NewStudyBundle(
+	sourceInstanceID string,
+	study studyTypes.Study,
+	surveys []studyTypes.Survey,
+	rules *studyTypes.StudyRules,
+	emailTemplates []messagingTypes.EmailTemplate,
+) StudyBundle {
+	b := StudyBundle{
+		FormatVersion:    BUNDLE_FORMAT_VERSION,
+		ExportedAt:       time.Now().UTC(),
+		SourceInstanceID: sourceInstanceID,
+		Study:            study,
+		Surveys:          surveys,
+		StudyRules:       rules,
+		EmailTemplates:   emailTemplates,
+	}
+	if b.Surveys == nil {
+		b.Surveys = []studyTypes.Survey{}
+	}
+	if b.EmailTemplates == nil {
+		b.EmailTemplates = []messagingTypes.EmailTemplate{}
+	}
+	return b.RemapStudyKey(study.Key)
+}
+
+// RemapStudyKey returns a copy of the bundle for the given study key. Database IDs, the study secret,
+// notification subscriptions and statistics are removed, as they are only valid in the source instance.
+func (b StudyBundle) RemapStudyKey(studyKey string) StudyBundle {
+	study := b.Study
+	study.ID = primitive.NilObjectID
+	study.Key = studyKey
+	study.SecretKey = ""
+	study.NotificationSubscriptions = []studyTypes.NotificationSubscription{}
+	study.DataRetentionStats = nil
+	study.Stats = studyTypes.StudyStats{}
+	study.NextTimerEvent = 0
+	b.Study = study
+
+	surveys := make([]studyTypes.Survey, len(b.Surveys))
+	for i, s := range b.Surveys {
+		s.ID = primitive.NilObjectID
+		s.SurveyKey = s.SurveyDefinition.Key
+		surveys[i] = s
+	}
+	b.Surveys = surveys
+
+	if b.StudyRules != nil {
+		rules := *b.StudyRules
+		rules.ID = primitive.NilObjectID
+		rules.StudyKey = studyKey
+		b.StudyRules = &rules
+	}
+
+	templates := make([]messagingTypes.EmailTemplate, len(b.EmailTemplates))
+	for i, t := range b.EmailTemplates {
+		t.ID = primitive.NilObjectID
+		t.StudyKey = studyKey
+		t.Version = 0
+		templates[i] = t
+	}
+	b.EmailTemplates = templates
+	return b
+}
+
+// Validate checks that the bundle can be imported by this version
+func (b StudyBundle) Validate() error {
+	if b.FormatVersion < 1 || b.FormatVersion > BUNDLE_FORMAT_VERSION {
+		return fmt.Errorf("unsupported bundle format version %d", b.FormatVersion)
+	}
+	if b.Study.Key == "" {
+		return errors.New("bundle has no study key")
+	}
+
+	surveyKeys := map[string]bool{}
+	for _, s := range b.Surveys {
+		key := s.SurveyDefinition.Key
+		if key == "" {
+			return errors.New("bundle contains a survey without key")
+		}
+		if surveyKeys[key] {
+			return fmt.Errorf("bundle contains survey %s more than once", key)
+		}
+		surveyKeys[key] = true
+	}
+
+	messageTypes := map[string]bool{}
+	for _, t := range b.EmailTemplates {
+		if t.MessageType == "" {
+			return errors.New("bundle contains an email template without message type")
+		}
+		if messageTypes[t.MessageType] {
+			return fmt.Errorf("bundle contains email template %s more than once", t.MessageType)
+		}
+		messageTypes[t.MessageType] = true
+	}
+	return nil
+}
+
+// WriteZip writes the bundle as a ZIP archive with one JSON file per survey and email template
+func WriteZip(w io.Writer, b StudyBundle) error {
+	zw := zip.NewWriter(w)
+
+	manifest := zipManifest{
+		FormatVersion:    b.FormatVersion,
+		ExportedAt:       b.ExportedAt,
+		SourceInstanceID: b.SourceInstanceID,
+		StudyKey:         b.Study.Key,
+	}
+	if err := writeZipJSON(zw, ZIP_MANIFEST_FILE, manifest); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, ZIP_STUDY_FILE, b.Study); err != nil {
+		return err
+	}
+	if b.StudyRules != nil {
+		if err := writeZipJSON(zw, ZIP_RULES_FILE, b.StudyRules); err != nil {
+			return err
+		}
+	}
+	for _, s := range b.Surveys {
+		if err := writeZipJSON(zw, ZIP_SURVEYS_DIR+safeFileName(s.SurveyDefinition.Key)+".json", s); err != nil {
+			return err
+		}
+	}
+	for _, t := range b.EmailTemplates {
+		if err := writeZipJSON(zw, ZIP_EMAIL_TEMPLATES_DIR+safeFileName(t.MessageType)+".json", t); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadZip reads a bundle written by WriteZip
+func ReadZip(r io.ReaderAt, size int64) (StudyBundle, error) {
+	b := StudyBundle{
+		Surveys:        []studyTypes.Survey{},
+		EmailTemplates: []messagingTypes.EmailTemplate{},
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return b, err
+	}
+	if len(zr.File) > maxZipEntries {
+		return b, errors.New("bundle contains too many files")
+	}
+
+	hasManifest := false
+	hasStudy := false
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Clean(f.Name)
+		switch {
+		case name == ZIP_MANIFEST_FILE:
+			var manifest zipManifest
+			if err := readZipJSON(f, &manifest); err != nil {
+				return b, err
+			}
+			b.FormatVersion = manifest.FormatVersion
+			b.ExportedAt = manifest.ExportedAt
+			b.SourceInstanceID = manifest.SourceInstanceID
+			hasManifest = true
+		case name == ZIP_STUDY_FILE:
+			if err := readZipJSON(f, &b.Study); err != nil {
+				return b, err
+			}
+			hasStudy = true
+		case name == ZIP_RULES_FILE:
+			var rules studyTypes.StudyRules
+			if err := readZipJSON(f, &rules); err != nil {
+				return b, err
+			}
+			b.StudyRules = &rules
+		case strings.HasPrefix(name, ZIP_SURVEYS_DIR):
+			var survey studyTypes.Survey
+			if err := readZipJSON(f, &survey); err != nil {
+				return b, err
+			}
+			b.Surveys = append(b.Surveys, survey)
+		case strings.HasPrefix(name, ZIP_EMAIL_TEMPLATES_DIR):
+			var template messagingTypes.EmailTemplate
+			if err := readZipJSON(f, &template); err != nil {
+				return b, err
+			}
+			b.EmailTemplates = append(b.EmailTemplates, template)
+		}
+	}
+
+	if !hasManifest {
+		return b, errors.New("bundle has no manifest")
+	}
+	if !hasStudy {
+		return b, errors.New("bundle has no study")
+	}
+	return b, nil
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func readZipJSON(f *zip.File, v interface{}) error {
+	if f.UncompressedSize64 > maxZipEntrySize {
+		return fmt.Errorf("%s is too large", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := json.NewDecoder(io.LimitReader(rc, maxZipEntrySize)).Decode(v); err != nil {
+		return fmt.Errorf("invalid %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("/\\:", r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,131 @@
+package bundle
+
+import (
+	"bytes"
+	"testing"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testBundle() StudyBundle {
+	return NewStudyBundle(
+		"source",
+		studyTypes.Study{
+			ID:        primitive.NewObjectID(),
+			Key:       "study1",
+			SecretKey: "secret",
+			Status:    studyTypes.STUDY_STATUS_ACTIVE,
+			NotificationSubscriptions: []studyTypes.NotificationSubscription{
+				{MessageType: "participant-flags", Email: "user@example.com"},
+			},
+		},
+		[]studyTypes.Survey{
+			{ID: primitive.NewObjectID(), VersionID: "24-01-1", SurveyDefinition: studyTypes.SurveyItem{Key: "intake"}},
+			{ID: primitive.NewObjectID(), VersionID: "24-01-2", SurveyDefinition: studyTypes.SurveyItem{Key: "weekly"}},
+		},
+		&studyTypes.StudyRules{ID: primitive.NewObjectID(), StudyKey: "study1", Rules: []studyTypes.Expression{{Name: "IFTHEN"}}},
+		[]messagingTypes.EmailTemplate{
+			{ID: primitive.NewObjectID(), MessageType: "reminder", StudyKey: "study1", Version: 3},
+		},
+	)
+}
+
+func TestNewStudyBundle(t *testing.T) {
+	b := testBundle()
+
+	if b.FormatVersion != BUNDLE_FORMAT_VERSION || b.SourceInstanceID != "source" {
+		t.Errorf("unexpected bundle infos: %+v", b)
+	}
+	if !b.Study.ID.IsZero() || b.Study.SecretKey != "" || len(b.Study.NotificationSubscriptions) != 0 {
+		t.Errorf("instance specific study data not removed: %+v", b.Study)
+	}
+	for _, s := range b.Surveys {
+		if !s.ID.IsZero() || s.SurveyKey != s.SurveyDefinition.Key {
+			t.Errorf("unexpected survey: %+v", s)
+		}
+	}
+	if !b.StudyRules.ID.IsZero() {
+		t.Errorf("rules ID not removed")
+	}
+	if !b.EmailTemplates[0].ID.IsZero() || b.EmailTemplates[0].Version != 0 {
+		t.Errorf("unexpected email template: %+v", b.EmailTemplates[0])
+	}
+	if err := b.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestRemapStudyKey(t *testing.T) {
+	b := testBundle()
+	remapped := b.RemapStudyKey("study2")
+
+	if remapped.Study.Key != "study2" || remapped.StudyRules.StudyKey != "study2" || remapped.EmailTemplates[0].StudyKey != "study2" {
+		t.Errorf("study key not remapped: %+v", remapped)
+	}
+	if b.Study.Key != "study1" || b.StudyRules.StudyKey != "study1" || b.EmailTemplates[0].StudyKey != "study1" {
+		t.Errorf("original bundle modified: %+v", b)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("unsupported version", func(t *testing.T) {
+		b := testBundle()
+		b.FormatVersion = BUNDLE_FORMAT_VERSION + 1
+		if err := b.Validate(); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("duplicate survey", func(t *testing.T) {
+		b := testBundle()
+		b.Surveys = append(b.Surveys, b.Surveys[0])
+		if err := b.Validate(); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("template without message type", func(t *testing.T) {
+		b := testBundle()
+		b.EmailTemplates = append(b.EmailTemplates, messagingTypes.EmailTemplate{})
+		if err := b.Validate(); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestZipRoundTrip(t *testing.T) {
+	b := testBundle()
+
+	buf := &bytes.Buffer{}
+	if err := WriteZip(buf, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	read, err := ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := read.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if read.Study.Key != "study1" || read.SourceInstanceID != "source" || !read.ExportedAt.Equal(b.ExportedAt) {
+		t.Errorf("unexpected bundle: %+v", read)
+	}
+	if len(read.Surveys) != 2 || read.Surveys[1].VersionID != "24-01-2" {
+		t.Errorf("unexpected surveys: %+v", read.Surveys)
+	}
+	if read.StudyRules == nil || len(read.StudyRules.Rules) != 1 {
+		t.Errorf("unexpected rules: %+v", read.StudyRules)
+	}
+	if len(read.EmailTemplates) != 1 || read.EmailTemplates[0].MessageType != "reminder" {
+		t.Errorf("unexpected email templates: %+v", read.EmailTemplates)
+	}
+}
+
+func TestReadZipInvalid(t *testing.T) {
+	if _, err := ReadZip(bytes.NewReader([]byte("not a zip")), 9); err == nil {
+		t.Error("expected error")
+	}
+}
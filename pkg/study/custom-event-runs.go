@@ -0,0 +1,137 @@
+package study
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"time"
+
+	studydb "github.com/case-framework/case-backend/pkg/db/study"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// failed participants listed in the result of a custom event run, the others are only counted
+const maxListedFailedParticipants = 100
+
+type RunCustomEventReq struct {
+	InstanceID string
+	StudyKey   string
+	EventKey   string
+	Payload    map[string]interface{}
+	// selects the participants, all participants with an account if nil
+	Filter       bson.M
+	OnProgressFn RunStudyActionProgressFn
+}
+
+type RunCustomEventResult struct {
+	EventKey             string
+	ParticipantCount     int64
+	ChangedCount         int64
+	FailedCount          int64
+	FailedParticipantIDs []string
+	Duration             int64
+}
+
+// OnRunCustomEvent runs the study rules for a custom event on the selected participants, as if each of them had sent
+// the event, e.g. to backfill states after a rule fix. Rule errors are recorded and the run continues with the next
+// participant.
+func OnRunCustomEvent(ctx context.Context, req RunCustomEventReq) (*RunCustomEventResult, error) {
+	if studyDBService == nil {
+		return nil, errors.New("studyDBService is not initialized")
+	}
+	if req.InstanceID == "" || req.StudyKey == "" || req.EventKey == "" {
+		return nil, errors.New("instanceID, studyKey and eventKey are required")
+	}
+
+	study, err := studyDBService.GetStudy(req.InstanceID, req.StudyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"studyStatus": bson.M{"$nin": bson.A{
+		studyTypes.PARTICIPANT_STUDY_STATUS_TEMPORARY,
+		studyTypes.PARTICIPANT_STUDY_STATUS_ACCOUNT_DELETED,
+	}}}
+	if req.Filter != nil {
+		filter = bson.M{"$and": bson.A{req.Filter, filter}}
+	}
+
+	count, err := studyDBService.GetParticipantCount(req.InstanceID, req.StudyKey, filter)
+	if err != nil {
+		return nil, err
+	}
+	if req.OnProgressFn != nil {
+		req.OnProgressFn(count, 0)
+	}
+
+	result := &RunCustomEventResult{
+		EventKey:             req.EventKey,
+		FailedParticipantIDs: []string{},
+	}
+	start := time.Now()
+
+	err = studyDBService.FindAndExecuteOnParticipantsStates(
+		ctx,
+		req.InstanceID,
+		req.StudyKey,
+		filter,
+		bson.M{"_id": 1},
+		false,
+		func(dbService *studydb.StudyDBService, p studyTypes.Participant, instanceID, studyKey string, args ...interface{}) error {
+			result.ParticipantCount += 1
+			if req.OnProgressFn != nil {
+				req.OnProgressFn(count, result.ParticipantCount)
+			}
+
+			changed, err := runCustomEventForParticipant(ctx, study, req, p)
+			if err != nil {
+				result.FailedCount += 1
+				if len(result.FailedParticipantIDs) < maxListedFailedParticipants {
+					result.FailedParticipantIDs = append(result.FailedParticipantIDs, p.ParticipantID)
+				}
+				return err
+			}
+			if changed {
+				result.ChangedCount += 1
+			}
+			return nil
+		},
+	)
+	result.Duration = int64(time.Since(start).Seconds())
+	return result, err
+}
+
+func runCustomEventForParticipant(ctx context.Context, study studyTypes.Study, req RunCustomEventReq, p studyTypes.Participant) (changed bool, err error) {
+	confidentialID, err := ComputeConfidentialIDForParticipant(study, p.ParticipantID)
+	if err != nil {
+		return false, err
+	}
+
+	event := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
+		Type:                                  studyengine.STUDY_EVENT_TYPE_CUSTOM,
+		InstanceID:                            req.InstanceID,
+		StudyKey:                              req.StudyKey,
+		ParticipantIDForConfidentialResponses: confidentialID,
+		EventKey:                              req.EventKey,
+		Payload:                               req.Payload,
+	}
+	actionResult, err := getAndPerformStudyRules(req.InstanceID, req.StudyKey, p, event)
+	if err != nil {
+		return false, err
+	}
+
+	if !reflect.DeepEqual(actionResult.PState, p) {
+		if _, err := saveParticipantState(req.InstanceID, req.StudyKey, p.StudyStatus, actionResult.PState); err != nil {
+			slog.Error("Error saving participant state", slog.String("instanceID", req.InstanceID), slog.String("studyKey", req.StudyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+			return false, err
+		}
+		changed = true
+	}
+	saveReports(req.InstanceID, req.StudyKey, actionResult.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_CUSTOM)
+	return changed, nil
+}
@@ -0,0 +1,125 @@
+package dataquality
+
+import (
+	"fmt"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	defaultWindow                = 24 * time.Hour
+	defaultBaselineWindows       = 7
+	defaultMinBaselineResponses  = 5
+	defaultMaxDuplicateRatio     = 0.2
+	defaultMinDuplicateResponses = 20
+	defaultRuleErrorSpikeFactor  = 3
+	defaultMinRuleErrors         = 10
+)
+
+// Config sets the thresholds of the checks, unset values use the defaults
+type Config struct {
+	// length of the checked window, ending at the time of the check. Defaults to 24h.
+	Window time.Duration `json:"window" yaml:"window"`
+	// number of windows before the checked one, averaged as baseline. Defaults to 7.
+	BaselineWindows int `json:"baseline_windows" yaml:"baseline_windows"`
+
+	// no responses in the window are reported if the baseline average is at least this. Defaults to 5.
+	MinBaselineResponses float64 `json:"min_baseline_responses" yaml:"min_baseline_responses"`
+	// duplicates are reported if their share of the responses in the window is above this. Defaults to 0.2.
+	MaxDuplicateRatio float64 `json:"max_duplicate_ratio" yaml:"max_duplicate_ratio"`
+	// windows with fewer responses are not checked for duplicates. Defaults to 20.
+	MinDuplicateResponses int64 `json:"min_duplicate_responses" yaml:"min_duplicate_responses"`
+	// rule errors are reported if they exceed the baseline average by this factor. Defaults to 3.
+	RuleErrorSpikeFactor float64 `json:"rule_error_spike_factor" yaml:"rule_error_spike_factor"`
+	// fewer rule errors in the window are not reported. Defaults to 10.
+	MinRuleErrors int64 `json:"min_rule_errors" yaml:"min_rule_errors"`
+}
+
+// WithDefaults returns the config with unset values replaced by the defaults
+func (c Config) WithDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = defaultWindow
+	}
+	if c.BaselineWindows <= 0 {
+		c.BaselineWindows = defaultBaselineWindows
+	}
+	if c.MinBaselineResponses <= 0 {
+		c.MinBaselineResponses = defaultMinBaselineResponses
+	}
+	if c.MaxDuplicateRatio <= 0 {
+		c.MaxDuplicateRatio = defaultMaxDuplicateRatio
+	}
+	if c.MinDuplicateResponses <= 0 {
+		c.MinDuplicateResponses = defaultMinDuplicateResponses
+	}
+	if c.RuleErrorSpikeFactor <= 0 {
+		c.RuleErrorSpikeFactor = defaultRuleErrorSpikeFactor
+	}
+	if c.MinRuleErrors <= 0 {
+		c.MinRuleErrors = defaultMinRuleErrors
+	}
+	return c
+}
+
+// Stats are the counts of a study in the checked window and the baseline
+type Stats struct {
+	WindowStart time.Time
+	WindowEnd   time.Time
+
+	Responses int64
+	// responses beyond the first one of a participant for a survey within the window
+	DuplicateResponses int64
+	RuleErrors         int64
+
+	// totals of all baseline windows
+	BaselineResponses  int64
+	BaselineRuleErrors int64
+}
+
+// Detect returns the anomalies found in the stats of a study
+func Detect(config Config, studyKey string, stats Stats, now time.Time) []studyTypes.DataQualityFinding {
+	config = config.WithDefaults()
+	newFinding := func(t string, value float64, baseline float64, message string) studyTypes.DataQualityFinding {
+		return studyTypes.DataQualityFinding{
+			StudyKey:    studyKey,
+			Type:        t,
+			DetectedAt:  now,
+			WindowStart: stats.WindowStart,
+			WindowEnd:   stats.WindowEnd,
+			Value:       value,
+			Baseline:    baseline,
+			Message:     message,
+		}
+	}
+
+	findings := []studyTypes.DataQualityFinding{}
+
+	avgResponses := float64(stats.BaselineResponses) / float64(config.BaselineWindows)
+	if stats.Responses == 0 && avgResponses >= config.MinBaselineResponses {
+		findings = append(findings, newFinding(
+			studyTypes.DATA_QUALITY_FINDING_RESPONSE_DROP, 0, avgResponses,
+			fmt.Sprintf("no responses in the last %s, %.1f on average before", config.Window, avgResponses),
+		))
+	}
+
+	if stats.Responses >= config.MinDuplicateResponses {
+		ratio := float64(stats.DuplicateResponses) / float64(stats.Responses)
+		if ratio > config.MaxDuplicateRatio {
+			findings = append(findings, newFinding(
+				studyTypes.DATA_QUALITY_FINDING_DUPLICATE_RESPONSES, ratio, config.MaxDuplicateRatio,
+				fmt.Sprintf("%d of %d responses in the last %s are repeated submissions of the same survey by a participant", stats.DuplicateResponses, stats.Responses, config.Window),
+			))
+		}
+	}
+
+	avgRuleErrors := float64(stats.BaselineRuleErrors) / float64(config.BaselineWindows)
+	if stats.RuleErrors >= config.MinRuleErrors && float64(stats.RuleErrors) > avgRuleErrors*config.RuleErrorSpikeFactor {
+		findings = append(findings, newFinding(
+			studyTypes.DATA_QUALITY_FINDING_RULE_ERROR_SPIKE, float64(stats.RuleErrors), avgRuleErrors,
+			fmt.Sprintf("%d rule errors in the last %s, %.1f on average before", stats.RuleErrors, config.Window, avgRuleErrors),
+		))
+	}
+
+	return findings
+}
@@ -0,0 +1,56 @@
+package dataquality
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestDetect(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	config := Config{BaselineWindows: 7}
+
+	tests := []struct {
+		name     string
+		stats    Stats
+		expected []string
+	}{
+		{"normal", Stats{Responses: 40, DuplicateResponses: 2, RuleErrors: 1, BaselineResponses: 280, BaselineRuleErrors: 7}, nil},
+		{"response drop", Stats{Responses: 0, BaselineResponses: 280}, []string{studyTypes.DATA_QUALITY_FINDING_RESPONSE_DROP}},
+		{"no responses in a quiet study", Stats{Responses: 0, BaselineResponses: 14}, nil},
+		{"duplicates", Stats{Responses: 40, DuplicateResponses: 20, BaselineResponses: 280}, []string{studyTypes.DATA_QUALITY_FINDING_DUPLICATE_RESPONSES}},
+		{"duplicates with few responses", Stats{Responses: 10, DuplicateResponses: 8, BaselineResponses: 70}, nil},
+		{"rule error spike", Stats{Responses: 40, RuleErrors: 30, BaselineResponses: 280, BaselineRuleErrors: 14}, []string{studyTypes.DATA_QUALITY_FINDING_RULE_ERROR_SPIKE}},
+		{"rule errors below minimum", Stats{Responses: 40, RuleErrors: 5, BaselineResponses: 280}, nil},
+		{"rule errors as usual", Stats{Responses: 40, RuleErrors: 30, BaselineResponses: 280, BaselineRuleErrors: 140}, nil},
+		{"drop and rule errors", Stats{Responses: 0, RuleErrors: 50, BaselineResponses: 280}, []string{studyTypes.DATA_QUALITY_FINDING_RESPONSE_DROP, studyTypes.DATA_QUALITY_FINDING_RULE_ERROR_SPIKE}},
+	}
+	for _, tt := range tests {
+		findings := Detect(config, "study", tt.stats, now)
+		if len(findings) != len(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, findings)
+			continue
+		}
+		for i, f := range findings {
+			if f.Type != tt.expected[i] {
+				t.Errorf("%s: expected %v, got %s at %d", tt.name, tt.expected, f.Type, i)
+			}
+			if f.StudyKey != "study" || !f.DetectedAt.Equal(now) || f.Message == "" {
+				t.Errorf("%s: unexpected finding %+v", tt.name, f)
+			}
+		}
+	}
+}
+
+func TestConfigWithDefaults(t *testing.T) {
+	c := Config{}.WithDefaults()
+	if c.Window != defaultWindow || c.BaselineWindows != defaultBaselineWindows || c.MaxDuplicateRatio != defaultMaxDuplicateRatio {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+
+	c = Config{Window: time.Hour, MinRuleErrors: 1}.WithDefaults()
+	if c.Window != time.Hour || c.MinRuleErrors != 1 {
+		t.Errorf("configured values replaced: %+v", c)
+	}
+}
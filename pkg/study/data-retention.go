@@ -0,0 +1,110 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
+)
+
+const (
+	dataRetentionFileBatchSize = 100
+)
+
+// ApplyDataRetention deletes the study data older than the retention periods of the study's policy.
+// With dryRun, only the number of affected documents is reported. Stored participant files are removed from the filestorePath.
+func ApplyDataRetention(instanceID string, study studyTypes.Study, filestorePath string, dryRun bool) (studyTypes.DataRetentionReport, error) {
+	cutoffs := studyUtils.ComputeDataRetentionCutoffs(study.Configs.DataRetention, time.Now())
+	if dryRun {
+		report, err := studyDBService.CountDataBeforeCutoffs(instanceID, study.Key, cutoffs)
+		report.DryRun = true
+		return report, err
+	}
+
+	report := studyTypes.DataRetentionReport{
+		StudyKey: study.Key,
+		Cutoffs:  cutoffs,
+	}
+
+	var err error
+	if cutoffs.Responses > 0 {
+		report.Responses, err = studyDBService.DeleteResponsesBefore(instanceID, study.Key, cutoffs.Responses)
+		if err != nil {
+			return report, err
+		}
+	}
+	if cutoffs.ConfidentialResponses > 0 {
+		report.ConfidentialResponses, err = studyDBService.DeleteConfidentialResponsesBefore(instanceID, study.Key, cutoffs.ConfidentialResponses)
+		if err != nil {
+			return report, err
+		}
+	}
+	if cutoffs.Reports > 0 {
+		report.Reports, err = studyDBService.DeleteReportsBefore(instanceID, study.Key, cutoffs.Reports)
+		if err != nil {
+			return report, err
+		}
+	}
+	if cutoffs.Files > 0 {
+		report.Files, err = deleteParticipantFilesBefore(instanceID, study.Key, cutoffs.Files, filestorePath)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if err := studyDBService.AddDataRetentionStats(instanceID, study.Key, report); err != nil {
+		slog.Error("Error saving data retention stats", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+	}
+	return report, nil
+}
+
+func deleteParticipantFilesBefore(instanceID string, studyKey string, cutoff int64, filestorePath string) (int64, error) {
+	if filestorePath == "" {
+		return 0, errors.New("filestore path is required to delete participant files")
+	}
+
+	var count int64
+	for {
+		fileInfos, err := studyDBService.GetParticipantFileInfosBefore(instanceID, studyKey, cutoff, dataRetentionFileBatchSize)
+		if err != nil {
+			return count, err
+		}
+
+		deletedInBatch := 0
+		for _, fileInfo := range fileInfos {
+			if err := removeStoredFile(filestorePath, fileInfo.Path); err != nil {
+				slog.Error("Error removing participant file", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()), slog.String("error", err.Error()))
+				continue
+			}
+			if err := removeStoredFile(filestorePath, fileInfo.PreviewPath); err != nil {
+				slog.Error("Error removing participant file preview", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()), slog.String("error", err.Error()))
+			}
+			if err := studyDBService.DeleteParticipantFileInfoByID(instanceID, studyKey, fileInfo.ID.Hex()); err != nil {
+				slog.Error("Error deleting participant file info", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()), slog.String("error", err.Error()))
+				continue
+			}
+			deletedInBatch += 1
+		}
+		count += int64(deletedInBatch)
+
+		// stop if nothing could be removed, to not loop on the same failing files
+		if len(fileInfos) < dataRetentionFileBatchSize || deletedInBatch == 0 {
+			return count, nil
+		}
+	}
+}
+
+func removeStoredFile(filestorePath string, relativePath string) error {
+	if relativePath == "" {
+		return nil
+	}
+	err := os.Remove(filepath.Join(filestorePath, relativePath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
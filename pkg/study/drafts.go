@@ -0,0 +1,295 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrDraftNotApprovable    = errors.New("draft is not waiting for approval")
+	ErrDraftSelfApproval     = errors.New("draft cannot be approved by its last editor")
+	ErrDraftNotApproved      = errors.New("draft has not been approved")
+	ErrDraftAlreadyPublished = errors.New("draft has already been published")
+)
+
+// SaveSurveyDraft creates the draft of the survey or replaces the content of its open draft. Changing the content resets the approval.
+func SaveSurveyDraft(instanceID string, studyKey string, survey studyTypes.Survey, userID string) (studyTypes.Draft, error) {
+	survey.SurveyKey = survey.SurveyDefinition.Key
+	survey.ID = primitive.NilObjectID
+	survey.VersionID = ""
+	survey.Published = 0
+	survey.Unpublished = 0
+
+	draft, err := studyDBService.GetOpenDraft(instanceID, studyKey, studyTypes.DRAFT_TYPE_SURVEY, survey.SurveyKey)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return draft, err
+		}
+		return studyDBService.AddDraft(instanceID, newDraft(studyKey, studyTypes.DRAFT_TYPE_SURVEY, survey.SurveyKey, &survey, nil, userID))
+	}
+
+	draft.Survey = &survey
+	return updateDraftContent(instanceID, draft, userID)
+}
+
+// SaveStudyRulesDraft creates the draft of the study rules or replaces the content of the open one. Changing the content resets the approval.
+func SaveStudyRulesDraft(instanceID string, studyKey string, rules studyTypes.StudyRules, userID string) (studyTypes.Draft, error) {
+	rules.ID = primitive.NilObjectID
+	rules.StudyKey = studyKey
+	rules.UploadedAt = 0
+	rules.UploadedBy = ""
+
+	draft, err := studyDBService.GetOpenDraft(instanceID, studyKey, studyTypes.DRAFT_TYPE_STUDY_RULES, "")
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return draft, err
+		}
+		return studyDBService.AddDraft(instanceID, newDraft(studyKey, studyTypes.DRAFT_TYPE_STUDY_RULES, "", nil, &rules, userID))
+	}
+
+	draft.StudyRules = &rules
+	return updateDraftContent(instanceID, draft, userID)
+}
+
+func newDraft(studyKey string, draftType string, surveyKey string, survey *studyTypes.Survey, rules *studyTypes.StudyRules, userID string) studyTypes.Draft {
+	now := time.Now()
+	return studyTypes.Draft{
+		StudyKey:   studyKey,
+		Type:       draftType,
+		SurveyKey:  surveyKey,
+		Survey:     survey,
+		StudyRules: rules,
+		Status:     studyTypes.DRAFT_STATUS_DRAFT,
+		CreatedBy:  userID,
+		CreatedAt:  now,
+		UpdatedBy:  userID,
+		UpdatedAt:  now,
+	}
+}
+
+func updateDraftContent(instanceID string, draft studyTypes.Draft, userID string) (studyTypes.Draft, error) {
+	previousStatus := draft.Status
+
+	draft.Status = studyTypes.DRAFT_STATUS_DRAFT
+	draft.UpdatedBy = userID
+	draft.UpdatedAt = time.Now()
+	draft.ApprovedBy = ""
+	draft.ApprovedAt = nil
+	draft.ApprovalComment = ""
+	draft.ScheduledFor = nil
+
+	err := studyDBService.ReplaceDraft(instanceID, draft, previousStatus)
+	return draft, err
+}
+
+// ApproveDraft records the review of the draft. The reviewer has to be someone else than the last editor.
+func ApproveDraft(instanceID string, studyKey string, draftID string, reviewerID string, comment string) (studyTypes.Draft, error) {
+	draft, err := studyDBService.GetDraftByID(instanceID, studyKey, draftID)
+	if err != nil {
+		return draft, err
+	}
+	if err := checkDraftApprovable(draft, reviewerID); err != nil {
+		return draft, err
+	}
+
+	now := time.Now()
+	draft.Status = studyTypes.DRAFT_STATUS_APPROVED
+	draft.ApprovedBy = reviewerID
+	draft.ApprovedAt = &now
+	draft.ApprovalComment = comment
+
+	err = studyDBService.ReplaceDraft(instanceID, draft, studyTypes.DRAFT_STATUS_DRAFT)
+	return draft, err
+}
+
+// PublishDraft publishes an approved draft. If scheduledFor is in the future, the draft is only scheduled and
+// will be published by PublishDueDrafts. A scheduled draft can be rescheduled or published immediately.
+func PublishDraft(instanceID string, studyKey string, draftID string, userID string, scheduledFor *time.Time) (studyTypes.Draft, error) {
+	draft, err := studyDBService.GetDraftByID(instanceID, studyKey, draftID)
+	if err != nil {
+		return draft, err
+	}
+	if err := checkDraftPublishable(draft); err != nil {
+		return draft, err
+	}
+
+	if scheduledFor != nil && scheduledFor.After(time.Now()) {
+		previousStatus := draft.Status
+		draft.Status = studyTypes.DRAFT_STATUS_SCHEDULED
+		draft.ScheduledFor = scheduledFor
+		draft.PublishedBy = userID
+		err = studyDBService.ReplaceDraft(instanceID, draft, previousStatus)
+		return draft, err
+	}
+
+	return publishDraftNow(instanceID, draft, userID)
+}
+
+func checkDraftApprovable(draft studyTypes.Draft, reviewerID string) error {
+	if draft.Status != studyTypes.DRAFT_STATUS_DRAFT {
+		return ErrDraftNotApprovable
+	}
+	if draft.UpdatedBy == reviewerID {
+		return ErrDraftSelfApproval
+	}
+	return nil
+}
+
+func checkDraftPublishable(draft studyTypes.Draft) error {
+	switch draft.Status {
+	case studyTypes.DRAFT_STATUS_APPROVED, studyTypes.DRAFT_STATUS_SCHEDULED:
+		return nil
+	case studyTypes.DRAFT_STATUS_PUBLISHED:
+		return ErrDraftAlreadyPublished
+	default:
+		return ErrDraftNotApproved
+	}
+}
+
+// PublishDueDrafts publishes the scheduled drafts of the study whose publication time has been reached
+func PublishDueDrafts(instanceID string, studyKey string) (int, error) {
+	drafts, err := studyDBService.GetDueScheduledDrafts(instanceID, studyKey, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, draft := range drafts {
+		if _, err := publishDraftNow(instanceID, draft, draft.PublishedBy); err != nil {
+			slog.Error("failed to publish scheduled draft", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("draftID", draft.ID.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+func publishDraftNow(instanceID string, draft studyTypes.Draft, userID string) (studyTypes.Draft, error) {
+	previousStatus := draft.Status
+	now := time.Now()
+
+	// mark as published first, so that a concurrent publish of the same draft fails instead of creating a second version
+	draft.Status = studyTypes.DRAFT_STATUS_PUBLISHED
+	draft.PublishedBy = userID
+	draft.PublishedAt = &now
+
+	switch draft.Type {
+	case studyTypes.DRAFT_TYPE_SURVEY:
+		if draft.Survey == nil {
+			return draft, errors.New("survey draft has no survey")
+		}
+		survey := *draft.Survey
+		surveyHistory, err := studyDBService.GetSurveyVersionIDs(instanceID, draft.StudyKey, draft.SurveyKey)
+		if err != nil {
+			return draft, err
+		}
+		survey.VersionID = utils.GenerateSurveyVersionID(surveyHistory)
+		survey.Published = now.Unix()
+		draft.PublishedVersionID = survey.VersionID
+
+		if err := studyDBService.ReplaceDraft(instanceID, draft, previousStatus); err != nil {
+			return draft, err
+		}
+		if err := studyDBService.SaveSurveyVersion(instanceID, draft.StudyKey, &survey); err != nil {
+			return draft, rollbackDraftPublication(instanceID, draft, previousStatus, err)
+		}
+	case studyTypes.DRAFT_TYPE_STUDY_RULES:
+		if draft.StudyRules == nil {
+			return draft, errors.New("study rules draft has no rules")
+		}
+		rules := *draft.StudyRules
+		rules.StudyKey = draft.StudyKey
+		rules.UploadedAt = now.Unix()
+		rules.UploadedBy = userID
+		if err := rules.MarshalRules(); err != nil {
+			return draft, err
+		}
+
+		if err := studyDBService.ReplaceDraft(instanceID, draft, previousStatus); err != nil {
+			return draft, err
+		}
+		if err := studyDBService.SaveStudyRules(instanceID, draft.StudyKey, rules); err != nil {
+			return draft, rollbackDraftPublication(instanceID, draft, previousStatus, err)
+		}
+	default:
+		return draft, errors.New("unknown draft type")
+	}
+
+	slog.Info("draft published", slog.String("instanceID", instanceID), slog.String("studyKey", draft.StudyKey), slog.String("draftID", draft.ID.Hex()), slog.String("type", draft.Type), slog.String("userID", userID))
+	return draft, nil
+}
+
+func rollbackDraftPublication(instanceID string, draft studyTypes.Draft, previousStatus string, cause error) error {
+	draft.Status = previousStatus
+	draft.PublishedAt = nil
+	draft.PublishedVersionID = ""
+	if err := studyDBService.ReplaceDraft(instanceID, draft, studyTypes.DRAFT_STATUS_PUBLISHED); err != nil {
+		slog.Error("failed to reset draft status after failed publication", slog.String("draftID", draft.ID.Hex()), slog.String("error", err.Error()))
+	}
+	return cause
+}
+
+// prefix of the version ID of survey drafts shown to preview participants, followed by the draft ID
+const draftSurveyVersionPrefix = "draft-"
+
+func isDraftPreviewParticipant(pState studyTypes.Participant) bool {
+	return pState.Flags[studyTypes.PARTICIPANT_FLAG_DRAFT_PREVIEW] == "true"
+}
+
+// getSurveyDefForParticipant returns the open draft of the survey for preview participants, otherwise the current survey version
+func getSurveyDefForParticipant(instanceID string, studyKey string, surveyKey string, pState studyTypes.Participant) (*studyTypes.Survey, error) {
+	if isDraftPreviewParticipant(pState) {
+		draft, err := studyDBService.GetOpenDraft(instanceID, studyKey, studyTypes.DRAFT_TYPE_SURVEY, surveyKey)
+		if err == nil && draft.Survey != nil {
+			survey := *draft.Survey
+			survey.VersionID = draftSurveyVersionPrefix + draft.ID.Hex()
+			return &survey, nil
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			slog.Error("failed to get survey draft", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("error", err.Error()))
+		}
+	}
+	return studyDBService.GetCurrentSurveyVersion(instanceID, studyKey, surveyKey)
+}
+
+// getSurveyVersionForResponse returns the survey version the response was submitted for. Responses of preview
+// participants can refer to the open draft of the survey.
+func getSurveyVersionForResponse(instanceID string, studyKey string, pState studyTypes.Participant, surveyKey string, versionID string) (*studyTypes.Survey, error) {
+	draftID, isDraft := strings.CutPrefix(versionID, draftSurveyVersionPrefix)
+	if !isDraft || !isDraftPreviewParticipant(pState) {
+		return studyDBService.GetSurveyVersion(instanceID, studyKey, surveyKey, versionID)
+	}
+
+	draft, err := studyDBService.GetOpenDraft(instanceID, studyKey, studyTypes.DRAFT_TYPE_SURVEY, surveyKey)
+	if err != nil {
+		return nil, err
+	}
+	if draft.ID.Hex() != draftID || draft.Survey == nil {
+		// the draft has been published or replaced in the meantime
+		return nil, mongo.ErrNoDocuments
+	}
+	survey := *draft.Survey
+	survey.VersionID = versionID
+	return &survey, nil
+}
+
+// getStudyRulesForParticipant returns the open draft of the study rules for preview participants, otherwise the current rules
+func getStudyRulesForParticipant(instanceID string, studyKey string, pState studyTypes.Participant) (studyTypes.StudyRules, error) {
+	if isDraftPreviewParticipant(pState) {
+		draft, err := studyDBService.GetOpenDraft(instanceID, studyKey, studyTypes.DRAFT_TYPE_STUDY_RULES, "")
+		if err == nil && draft.StudyRules != nil {
+			return *draft.StudyRules, nil
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			slog.Error("failed to get study rules draft", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		}
+	}
+	return studyDBService.GetCurrentStudyRules(instanceID, studyKey)
+}
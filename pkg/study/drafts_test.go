@@ -0,0 +1,174 @@
+package study
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestCheckDraftApprovable(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		reviewerID string
+		wantErr    error
+	}{
+		{name: "draft reviewed by someone else", status: studyTypes.DRAFT_STATUS_DRAFT, reviewerID: "reviewer"},
+		{name: "draft reviewed by its editor", status: studyTypes.DRAFT_STATUS_DRAFT, reviewerID: "editor", wantErr: ErrDraftSelfApproval},
+		{name: "already approved", status: studyTypes.DRAFT_STATUS_APPROVED, reviewerID: "reviewer", wantErr: ErrDraftNotApprovable},
+		{name: "scheduled", status: studyTypes.DRAFT_STATUS_SCHEDULED, reviewerID: "reviewer", wantErr: ErrDraftNotApprovable},
+		{name: "published", status: studyTypes.DRAFT_STATUS_PUBLISHED, reviewerID: "reviewer", wantErr: ErrDraftNotApprovable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDraftApprovable(studyTypes.Draft{Status: tt.status, UpdatedBy: "editor"}, tt.reviewerID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckDraftPublishable(t *testing.T) {
+	tests := []struct {
+		status  string
+		wantErr error
+	}{
+		{status: studyTypes.DRAFT_STATUS_DRAFT, wantErr: ErrDraftNotApproved},
+		{status: studyTypes.DRAFT_STATUS_APPROVED},
+		{status: studyTypes.DRAFT_STATUS_SCHEDULED},
+		{status: studyTypes.DRAFT_STATUS_PUBLISHED, wantErr: ErrDraftAlreadyPublished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			err := checkDraftPublishable(studyTypes.Draft{Status: tt.status})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestSurveyDraftWorkflow(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	instanceID := "test"
+	studyKey := "drafts"
+	initTestStudyDB(t, uri, instanceID)
+
+	survey := studyTypes.Survey{SurveyDefinition: studyTypes.SurveyItem{Key: "intake"}}
+	draft, err := SaveSurveyDraft(instanceID, studyKey, survey, "editor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	draftID := draft.ID.Hex()
+
+	if _, err := PublishDraft(instanceID, studyKey, draftID, "editor", nil); !errors.Is(err, ErrDraftNotApproved) {
+		t.Errorf("unapproved draft published: %v", err)
+	}
+	if _, err := ApproveDraft(instanceID, studyKey, draftID, "editor", ""); !errors.Is(err, ErrDraftSelfApproval) {
+		t.Errorf("draft approved by its editor: %v", err)
+	}
+	if _, err := ApproveDraft(instanceID, studyKey, draftID, "reviewer", "ok"); err != nil {
+		t.Fatal(err)
+	}
+
+	// editing the approved draft resets the approval
+	draft, err = SaveSurveyDraft(instanceID, studyKey, survey, "reviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draft.ID.Hex() != draftID || draft.Status != studyTypes.DRAFT_STATUS_DRAFT || draft.ApprovedBy != "" {
+		t.Errorf("unexpected draft after edit: %+v", draft)
+	}
+	if _, err := ApproveDraft(instanceID, studyKey, draftID, "reviewer", ""); !errors.Is(err, ErrDraftSelfApproval) {
+		t.Errorf("draft approved by its last editor: %v", err)
+	}
+	if _, err := ApproveDraft(instanceID, studyKey, draftID, "editor", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduledFor := time.Now().Add(time.Hour)
+	draft, err = PublishDraft(instanceID, studyKey, draftID, "editor", &scheduledFor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draft.Status != studyTypes.DRAFT_STATUS_SCHEDULED {
+		t.Errorf("expected scheduled draft, got %s", draft.Status)
+	}
+	if count, err := PublishDueDrafts(instanceID, studyKey); err != nil || count != 0 {
+		t.Errorf("draft published before its time: %d, %v", count, err)
+	}
+	if _, err := studyDBService.GetCurrentSurveyVersion(instanceID, studyKey, "intake"); err == nil {
+		t.Error("survey published before the draft")
+	}
+
+	draft, err = PublishDraft(instanceID, studyKey, draftID, "editor", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draft.Status != studyTypes.DRAFT_STATUS_PUBLISHED || draft.PublishedVersionID == "" {
+		t.Errorf("unexpected published draft: %+v", draft)
+	}
+	current, err := studyDBService.GetCurrentSurveyVersion(instanceID, studyKey, "intake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.VersionID != draft.PublishedVersionID {
+		t.Errorf("unexpected current version %s, expected %s", current.VersionID, draft.PublishedVersionID)
+	}
+
+	if _, err := PublishDraft(instanceID, studyKey, draftID, "editor", nil); !errors.Is(err, ErrDraftAlreadyPublished) {
+		t.Errorf("draft published twice: %v", err)
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestDraftPreviewParticipantData(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	instanceID := "test"
+	studyKey := "preview"
+	database := initTestStudyDB(t, uri, instanceID)
+
+	pState := studyTypes.Participant{
+		ParticipantID: "p1",
+		StudyStatus:   studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
+		Flags:         map[string]string{studyTypes.PARTICIPANT_FLAG_DRAFT_PREVIEW: "true"},
+	}
+	saved, err := saveParticipantState(instanceID, studyKey, "", pState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.Synthetic {
+		t.Error("preview participant not marked as synthetic")
+	}
+
+	if _, err := saveResponses(instanceID, studyKey, studyTypes.SurveyResponse{Key: "intake"}, pState, ""); err != nil {
+		t.Fatal(err)
+	}
+	responses := database.Collection(studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_RESPONSES)
+	count, err := responses.CountDocuments(context.Background(), studyDB.ExcludeSynthetic(bson.M{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("preview response not excluded from exports and statistics")
+	}
+}
@@ -0,0 +1,107 @@
+package study
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
+)
+
+// EnrollmentClosedError is returned if a participant tries to enter a study outside of its enrollment window
+type EnrollmentClosedError struct {
+	Reason string
+	// Unix timestamp when enrollment opens again, 0 if unknown or never
+	NextOpensAt int64
+	Message     []studyTypes.LocalisedObject
+}
+
+func (e *EnrollmentClosedError) Error() string {
+	return fmt.Sprintf("enrollment closed: %s", e.Reason)
+}
+
+// EnrollmentStatus describes if a study currently accepts new participants
+type EnrollmentStatus struct {
+	Open        bool                         `json:"open"`
+	WaitingList bool                         `json:"waitingList,omitempty"` // new participants are put on the waiting list
+	Reason      string                       `json:"reason,omitempty"`
+	NextOpensAt int64                        `json:"nextOpensAt,omitempty"`
+	Message     []studyTypes.LocalisedObject `json:"message,omitempty"`
+}
+
+// GetEnrollmentStatus checks the enrollment window of the study without counting an enrollment
+func GetEnrollmentStatus(instanceID string, studyKey string) (EnrollmentStatus, error) {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		return EnrollmentStatus{}, err
+	}
+
+	w := study.Configs.EnrollmentWindow
+	now := time.Now()
+	reason, nextOpensAt := studyUtils.CheckEnrollmentWindow(w, now)
+	if reason == "" && w != nil && w.MaxEnrollmentsPerDay > 0 {
+		count, err := studyDBService.GetEnrollmentCount(instanceID, studyKey, studyUtils.EnrollmentDay(w, now))
+		if err != nil {
+			return EnrollmentStatus{}, err
+		}
+		if count >= w.MaxEnrollmentsPerDay {
+			reason = studyUtils.ENROLLMENT_CLOSED_DAILY_LIMIT
+			nextOpensAt = studyUtils.NextOpeningAfterDailyLimit(w, now)
+		}
+	}
+
+	if reason == "" {
+		full, err := isEnrollmentCapReached(instanceID, study)
+		if err != nil {
+			return EnrollmentStatus{}, err
+		}
+		return EnrollmentStatus{Open: true, WaitingList: full}, nil
+	}
+	return EnrollmentStatus{
+		Reason:      reason,
+		NextOpensAt: nextOpensAt,
+		Message:     w.ClosedMessage,
+	}, nil
+}
+
+// checkAndCountEnrollment returns an EnrollmentClosedError if the study does not accept new participants.
+// Otherwise the enrollment is counted for the daily limit.
+func checkAndCountEnrollment(instanceID string, study studyTypes.Study) error {
+	w := study.Configs.EnrollmentWindow
+	if w == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if err := checkEnrollmentWindow(w, now); err != nil {
+		return err
+	}
+
+	if w.MaxEnrollmentsPerDay > 0 {
+		counted, err := studyDBService.IncrementEnrollmentCounterIfBelow(instanceID, study.Key, studyUtils.EnrollmentDay(w, now), w.MaxEnrollmentsPerDay)
+		if err != nil {
+			slog.Error("Error counting enrollment", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			return err
+		}
+		if !counted {
+			return &EnrollmentClosedError{
+				Reason:      studyUtils.ENROLLMENT_CLOSED_DAILY_LIMIT,
+				NextOpensAt: studyUtils.NextOpeningAfterDailyLimit(w, now),
+				Message:     w.ClosedMessage,
+			}
+		}
+	}
+	return nil
+}
+
+// checkEnrollmentWindow returns an EnrollmentClosedError if the window is closed, without counting an enrollment
+func checkEnrollmentWindow(w *studyTypes.EnrollmentWindow, now time.Time) error {
+	if w == nil {
+		return nil
+	}
+	if reason, nextOpensAt := studyUtils.CheckEnrollmentWindow(w, now); reason != "" {
+		return &EnrollmentClosedError{Reason: reason, NextOpensAt: nextOpensAt, Message: w.ClosedMessage}
+	}
+	return nil
+}
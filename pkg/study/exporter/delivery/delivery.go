@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DESTINATION_TYPE_SFTP    = "sftp"
+	DESTINATION_TYPE_S3      = "s3"
+	DESTINATION_TYPE_WEBHOOK = "webhook"
+)
+
+const defaultTimeout = 5 * time.Minute
+
+// Deliverer pushes export files to a destination
+type Deliverer interface {
+	// Deliver uploads the file under the name, returns where it was stored
+	Deliver(ctx context.Context, filePath string, name string, contentType string) (string, error)
+}
+
+// DestinationConfig configures a destination scheduled exports can be delivered to, credentials stay in the service config
+type DestinationConfig struct {
+	Name string `json:"name" yaml:"name"`
+	// one of DESTINATION_TYPE_*
+	Type    string        `json:"type" yaml:"type"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+
+	SFTP    *SFTPConfig    `json:"sftp,omitempty" yaml:"sftp,omitempty"`
+	S3      *S3Config      `json:"s3,omitempty" yaml:"s3,omitempty"`
+	Webhook *WebhookConfig `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+}
+
+// NewDeliverer creates the deliverer for the configured destination
+func NewDeliverer(conf DestinationConfig) (Deliverer, error) {
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch conf.Type {
+	case DESTINATION_TYPE_SFTP:
+		if conf.SFTP == nil {
+			return nil, errors.New("sftp config missing")
+		}
+		d, err := newSFTPDeliverer(*conf.SFTP, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	case DESTINATION_TYPE_S3:
+		if conf.S3 == nil {
+			return nil, errors.New("s3 config missing")
+		}
+		d, err := newS3Deliverer(*conf.S3, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	case DESTINATION_TYPE_WEBHOOK:
+		if conf.Webhook == nil {
+			return nil, errors.New("webhook config missing")
+		}
+		d, err := newWebhookDeliverer(*conf.Webhook, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported destination type: %s", conf.Type)
+}
+
+// NewDeliverers creates the deliverers of all destinations by name
+func NewDeliverers(confs []DestinationConfig) (map[string]Deliverer, error) {
+	deliverers := map[string]Deliverer{}
+	for _, conf := range confs {
+		if conf.Name == "" {
+			return nil, errors.New("destination name missing")
+		}
+		if _, ok := deliverers[conf.Name]; ok {
+			return nil, fmt.Errorf("duplicate destination name: %s", conf.Name)
+		}
+		d, err := NewDeliverer(conf)
+		if err != nil {
+			return nil, fmt.Errorf("destination %s: %w", conf.Name, err)
+		}
+		deliverers[conf.Name] = d
+	}
+	return deliverers, nil
+}
@@ -0,0 +1,117 @@
+package delivery
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	awssigv4 "github.com/case-framework/case-backend/pkg/aws-sigv4"
+)
+
+// S3Bucket extends the S3 deliverer with reading, listing and deleting objects, e.g. to rotate backups
+type S3Bucket struct {
+	*s3Deliverer
+}
+
+func NewS3Bucket(conf S3Config, timeout time.Duration) (*S3Bucket, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	d, err := newS3Deliverer(conf, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Bucket{s3Deliverer: d}, nil
+}
+
+// key returns the object key of the name, including the configured prefix
+func (b *S3Bucket) key(name string) string {
+	return strings.TrimPrefix(b.conf.Prefix+name, "/")
+}
+
+func (b *S3Bucket) do(ctx context.Context, method string, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(s3ContentSHA256, awssigv4.EMPTY_PAYLOAD_HASH)
+	signS3Request(req, b.conf.AccessKeyID, b.conf.SecretAccessKey, b.conf.Region, time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, fmt.Errorf("s3 responded with status %d: %s", resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+// Open returns the content of the object with the name, the caller has to close it
+func (b *S3Bucket) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := b.do(ctx, http.MethodGet, b.objectURL(b.key(name)))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object with the name
+func (b *S3Bucket) Delete(ctx context.Context, name string) error {
+	resp, err := b.do(ctx, http.MethodDelete, b.objectURL(b.key(name)))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the names of the objects starting with the prefix, relative to the configured prefix
+func (b *S3Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	keyPrefix := b.key(prefix)
+	names := []string{}
+	continuationToken := ""
+	for {
+		u := b.objectURL("")
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", keyPrefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		u.RawQuery = query.Encode()
+
+		resp, err := b.do(ctx, http.MethodGet, u)
+		if err != nil {
+			return nil, err
+		}
+		result := s3ListResult{}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, strings.TrimPrefix(b.conf.Prefix, "/")))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
@@ -0,0 +1,131 @@
+package delivery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	awssigv4 "github.com/case-framework/case-backend/pkg/aws-sigv4"
+)
+
+const (
+	s3Service       = "s3"
+	s3ContentSHA256 = awssigv4.HEADER_CONTENT_SHA
+	s3DefaultRegion = "us-east-1"
+)
+
+type S3Config struct {
+	// e.g. https://s3.eu-central-1.amazonaws.com or the URL of an S3 compatible store, defaults to AWS for the region
+	Endpoint        string `json:"endpoint" yaml:"endpoint"`
+	Region          string `json:"region" yaml:"region"`
+	Bucket          string `json:"bucket" yaml:"bucket"`
+	Prefix          string `json:"prefix" yaml:"prefix"`
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key"`
+	// use <endpoint>/<bucket>/<key> instead of <bucket>.<endpoint host>/<key>, needed by most S3 compatible stores
+	PathStyle bool `json:"path_style" yaml:"path_style"`
+}
+
+type s3Deliverer struct {
+	conf     S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func newS3Deliverer(conf S3Config, timeout time.Duration) (*s3Deliverer, error) {
+	if conf.Bucket == "" || conf.AccessKeyID == "" || conf.SecretAccessKey == "" {
+		return nil, errors.New("bucket and credentials are required")
+	}
+	if conf.Region == "" {
+		conf.Region = s3DefaultRegion
+	}
+	if conf.Endpoint == "" {
+		conf.Endpoint = "https://s3." + conf.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(conf.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return nil, errors.New("endpoint must be an absolute http(s) url")
+	}
+	return &s3Deliverer{
+		conf:     conf,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// objectURL returns the URL of the object with the key
+func (d *s3Deliverer) objectURL(key string) *url.URL {
+	u := *d.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if d.conf.PathStyle {
+		u.Path = basePath + "/" + d.conf.Bucket + "/" + key
+	} else {
+		u.Host = d.conf.Bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	return &u
+}
+
+// Deliver uploads the file with a single PUT request
+func (d *s3Deliverer) Deliver(ctx context.Context, filePath string, name string, contentType string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	key := strings.TrimPrefix(d.conf.Prefix+name, "/")
+	objectURL := d.objectURL(key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), file)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(s3ContentSHA256, hex.EncodeToString(hash.Sum(nil)))
+	signS3Request(req, d.conf.AccessKeyID, d.conf.SecretAccessKey, d.conf.Region, time.Now())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return "", fmt.Errorf("s3 responded with status %d: %s", resp.StatusCode, body)
+	}
+	return "s3://" + d.conf.Bucket + "/" + key, nil
+}
+
+// signS3Request adds the AWS signature version 4 authorization header. The request must already contain the
+// X-Amz-Content-Sha256 header with the hex encoded hash of the payload.
+func signS3Request(req *http.Request, accessKeyID string, secretAccessKey string, region string, now time.Time) {
+	creds := awssigv4.Credentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey}
+	awssigv4.Sign(req, creds, region, s3Service, req.Header.Get(s3ContentSHA256), now)
+}
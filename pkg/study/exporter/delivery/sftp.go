@@ -0,0 +1,357 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP protocol version 3 (draft-ietf-secsh-filexfer-02), only what is needed to upload files
+const (
+	sftpProtocolVersion = 3
+
+	sshFxpInit    = 1
+	sshFxpVersion = 2
+	sshFxpOpen    = 3
+	sshFxpClose   = 4
+	sshFxpWrite   = 6
+	sshFxpRemove  = 13
+	sshFxpRename  = 18
+	sshFxpStatus  = 101
+	sshFxpHandle  = 102
+
+	sshFxfWrite = 0x00000002
+	sshFxfCreat = 0x00000008
+	sshFxfTrunc = 0x00000010
+
+	sshFxOK = 0
+
+	// max data per write request most servers accept
+	sftpMaxWriteSize = 32 * 1024
+	// upper bound for packets read from the server
+	sftpMaxPacketSize = 256 * 1024
+
+	sftpDefaultPort = 22
+	// files are uploaded under this suffix and renamed when complete, so consumers never see partial files
+	sftpPartialSuffix = ".part"
+)
+
+type SFTPConfig struct {
+	Host     string `json:"host" yaml:"host"`
+	Port     int    `json:"port" yaml:"port"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	// path of a PEM encoded private key, used instead of or in addition to the password
+	PrivateKeyFile string `json:"private_key_file" yaml:"private_key_file"`
+	// public key of the server in authorized_keys format, e.g. "ssh-ed25519 AAAA...", connections to other hosts are rejected
+	HostKey   string `json:"host_key" yaml:"host_key"`
+	Directory string `json:"directory" yaml:"directory"`
+}
+
+type sftpDeliverer struct {
+	conf      SFTPConfig
+	sshConfig *ssh.ClientConfig
+}
+
+func newSFTPDeliverer(conf SFTPConfig, timeout time.Duration) (*sftpDeliverer, error) {
+	if conf.Host == "" || conf.Username == "" {
+		return nil, errors.New("host and username are required")
+	}
+	if conf.Port == 0 {
+		conf.Port = sftpDefaultPort
+	}
+	if conf.HostKey == "" {
+		return nil, errors.New("host key is required")
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(conf.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host key: %w", err)
+	}
+
+	auth := []ssh.AuthMethod{}
+	if conf.PrivateKeyFile != "" {
+		pem, err := os.ReadFile(conf.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if conf.Password != "" {
+		auth = append(auth, ssh.Password(conf.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("password or private key is required")
+	}
+
+	return &sftpDeliverer{
+		conf: conf,
+		sshConfig: &ssh.ClientConfig{
+			User:            conf.Username,
+			Auth:            auth,
+			HostKeyCallback: ssh.FixedHostKey(hostKey),
+			Timeout:         timeout,
+		},
+	}, nil
+}
+
+// Deliver uploads the file into the configured directory
+func (d *sftpDeliverer) Deliver(ctx context.Context, filePath string, name string, contentType string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(d.conf.Host, strconv.Itoa(d.conf.Port)), d.sshConfig)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	// the ssh connection doesn't take a context, so close it on cancellation
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return "", err
+	}
+
+	sftp, err := newSFTPClient(r, w)
+	if err != nil {
+		return "", err
+	}
+
+	remotePath := path.Join(d.conf.Directory, name)
+	if err := sftp.upload(file, remotePath); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	return "sftp://" + d.conf.Host + "/" + remotePath, nil
+}
+
+// sftpClient speaks the SFTP protocol over the streams of an ssh subsystem session, one request at a time
+type sftpClient struct {
+	r      io.Reader
+	w      io.Writer
+	nextID uint32
+}
+
+func newSFTPClient(r io.Reader, w io.Writer) (*sftpClient, error) {
+	c := &sftpClient{r: r, w: w}
+
+	init := binary.BigEndian.AppendUint32(nil, sftpProtocolVersion)
+	if err := c.writePacket(sshFxpInit, init); err != nil {
+		return nil, err
+	}
+	typ, payload, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if typ != sshFxpVersion || len(payload) < 4 {
+		return nil, errors.New("sftp: unexpected response to init")
+	}
+	if version := binary.BigEndian.Uint32(payload); version < sftpProtocolVersion {
+		return nil, fmt.Errorf("sftp: unsupported protocol version %d", version)
+	}
+	return c, nil
+}
+
+// upload writes the content to a temporary file next to the target and renames it when complete
+func (c *sftpClient) upload(src io.Reader, remotePath string) error {
+	partialPath := remotePath + sftpPartialSuffix
+	handle, err := c.open(partialPath, sshFxfWrite|sshFxfCreat|sshFxfTrunc)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, sftpMaxWriteSize)
+	var offset uint64
+	for {
+		n, readErr := io.ReadFull(src, buf)
+		if n > 0 {
+			if err := c.write(handle, offset, buf[:n]); err != nil {
+				c.close(handle)
+				return err
+			}
+			offset += uint64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			c.close(handle)
+			return readErr
+		}
+	}
+
+	if err := c.close(handle); err != nil {
+		return err
+	}
+
+	// version 3 servers don't overwrite on rename
+	_ = c.remove(remotePath)
+	return c.rename(partialPath, remotePath)
+}
+
+func (c *sftpClient) open(path string, pflags uint32) ([]byte, error) {
+	id, payload := c.newRequest()
+	payload = appendSFTPString(payload, []byte(path))
+	payload = binary.BigEndian.AppendUint32(payload, pflags)
+	// no attributes
+	payload = binary.BigEndian.AppendUint32(payload, 0)
+
+	typ, resp, err := c.request(sshFxpOpen, id, payload)
+	if err != nil {
+		return nil, err
+	}
+	if typ == sshFxpStatus {
+		return nil, statusError("open", resp)
+	}
+	if typ != sshFxpHandle {
+		return nil, fmt.Errorf("sftp: unexpected response type %d to open", typ)
+	}
+	handle, _, err := readSFTPString(resp)
+	return handle, err
+}
+
+func (c *sftpClient) write(handle []byte, offset uint64, data []byte) error {
+	id, payload := c.newRequest()
+	payload = appendSFTPString(payload, handle)
+	payload = binary.BigEndian.AppendUint64(payload, offset)
+	payload = appendSFTPString(payload, data)
+	return c.statusRequest("write", sshFxpWrite, id, payload)
+}
+
+func (c *sftpClient) close(handle []byte) error {
+	id, payload := c.newRequest()
+	payload = appendSFTPString(payload, handle)
+	return c.statusRequest("close", sshFxpClose, id, payload)
+}
+
+func (c *sftpClient) remove(path string) error {
+	id, payload := c.newRequest()
+	payload = appendSFTPString(payload, []byte(path))
+	return c.statusRequest("remove", sshFxpRemove, id, payload)
+}
+
+func (c *sftpClient) rename(oldPath string, newPath string) error {
+	id, payload := c.newRequest()
+	payload = appendSFTPString(payload, []byte(oldPath))
+	payload = appendSFTPString(payload, []byte(newPath))
+	return c.statusRequest("rename", sshFxpRename, id, payload)
+}
+
+func (c *sftpClient) newRequest() (uint32, []byte) {
+	c.nextID += 1
+	return c.nextID, binary.BigEndian.AppendUint32(nil, c.nextID)
+}
+
+// statusRequest sends a request answered with a status packet
+func (c *sftpClient) statusRequest(op string, typ byte, id uint32, payload []byte) error {
+	respType, resp, err := c.request(typ, id, payload)
+	if err != nil {
+		return err
+	}
+	if respType != sshFxpStatus {
+		return fmt.Errorf("sftp: unexpected response type %d to %s", respType, op)
+	}
+	return statusError(op, resp)
+}
+
+// request sends the packet and returns the response without the request id
+func (c *sftpClient) request(typ byte, id uint32, payload []byte) (byte, []byte, error) {
+	if err := c.writePacket(typ, payload); err != nil {
+		return 0, nil, err
+	}
+	respType, resp, err := c.readPacket()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(resp) < 4 || binary.BigEndian.Uint32(resp) != id {
+		return 0, nil, errors.New("sftp: response for unexpected request")
+	}
+	return respType, resp[4:], nil
+}
+
+func (c *sftpClient) writePacket(typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(make([]byte, 0, 5+len(payload)), uint32(len(payload)+1))
+	packet = append(packet, typ)
+	packet = append(packet, payload...)
+	_, err := c.w.Write(packet)
+	return err
+}
+
+func (c *sftpClient) readPacket() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length < 1 || length > sftpMaxPacketSize {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// statusError returns nil for SSH_FX_OK, otherwise an error with the message of the server
+func statusError(op string, payload []byte) error {
+	if len(payload) < 4 {
+		return fmt.Errorf("sftp: invalid status response to %s", op)
+	}
+	code := binary.BigEndian.Uint32(payload)
+	if code == sshFxOK {
+		return nil
+	}
+	msg, _, _ := readSFTPString(payload[4:])
+	return fmt.Errorf("sftp: %s failed with status %d: %s", op, code, bytes.TrimSpace(msg))
+}
+
+func appendSFTPString(b []byte, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func readSFTPString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("sftp: truncated string")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return nil, nil, errors.New("sftp: truncated string")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
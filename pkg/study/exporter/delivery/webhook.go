@@ -0,0 +1,112 @@
+package delivery
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	HEADER_SIGNATURE       = "X-Case-Signature"
+	HEADER_EXPORT_FILENAME = "X-Case-Export-Filename"
+)
+
+// maximum size of the response body included in errors
+const maxErrorBodySize = 512
+
+type WebhookConfig struct {
+	URL string `json:"url" yaml:"url"`
+	// if set, the body is signed as for messaging webhooks: HMAC-SHA256 of "<t>.<body>" in the X-Case-Signature header
+	Secret  string            `json:"secret" yaml:"secret"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+}
+
+type webhookDeliverer struct {
+	conf   WebhookConfig
+	client *http.Client
+}
+
+func newWebhookDeliverer(conf WebhookConfig, timeout time.Duration) (*webhookDeliverer, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, errors.New("url must be an absolute http(s) url")
+	}
+	return &webhookDeliverer{
+		conf:   conf,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Deliver posts the file as request body
+func (d *webhookDeliverer) Deliver(ctx context.Context, filePath string, name string, contentType string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	var signature string
+	if d.conf.Secret != "" {
+		signature, err = signFile(d.conf.Secret, time.Now().Unix(), file)
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.conf.URL, file)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = info.Size()
+	for k, v := range d.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(HEADER_EXPORT_FILENAME, name)
+	if signature != "" {
+		req.Header.Set(HEADER_SIGNATURE, signature)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return "", fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, body)
+	}
+	return d.conf.URL, nil
+}
+
+// signFile computes the signature header value for the file content sent at timestamp ts
+func signFile(secret string, ts int64, r io.Reader) (string, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	if _, err := io.Copy(mac, r); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil))), nil
+}
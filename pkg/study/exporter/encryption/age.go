@@ -0,0 +1,32 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// ParseAgeRecipient parses an X25519 recipient in the "age1..." format
+func ParseAgeRecipient(s string) (*age.X25519Recipient, error) {
+	recipient, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipient: %w", err)
+	}
+	return recipient, nil
+}
+
+// EncryptAge returns a writer encrypting everything written to it for the recipients into w (binary age v1 file).
+// The returned writer must be closed to write the last chunk, it doesn't close w.
+func EncryptAge(w io.Writer, recipients []*age.X25519Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	ageRecipients := make([]age.Recipient, 0, len(recipients))
+	for _, r := range recipients {
+		ageRecipients = append(ageRecipients, r)
+	}
+	return age.Encrypt(w, ageRecipients...)
+}
@@ -0,0 +1,117 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Recipient key types
+const (
+	RECIPIENT_TYPE_AGE = "age"
+	RECIPIENT_TYPE_GPG = "gpg"
+)
+
+const maxRecipients = 20
+
+// Recipients of an encrypted export, everyone of them can decrypt the file with their private key
+type Recipients struct {
+	Type string `json:"type"`
+	// age recipients ("age1...") or ASCII armored GPG public keys
+	Keys []string `json:"keys"`
+}
+
+// Validate parses the keys, so that invalid keys are rejected before an export starts
+func (r Recipients) Validate() error {
+	_, _, err := r.parse()
+	return err
+}
+
+// Fingerprints identify the recipient keys, e.g. for the audit log: the age recipients themselves or the GPG key fingerprints
+func (r Recipients) Fingerprints() ([]string, error) {
+	ageRecipients, gpgEntities, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := []string{}
+	for i := range ageRecipients {
+		fingerprints = append(fingerprints, strings.ToLower(strings.TrimSpace(r.Keys[i])))
+	}
+	for _, entity := range gpgEntities {
+		fingerprints = append(fingerprints, gpgFingerprint(entity))
+	}
+	return fingerprints, nil
+}
+
+// FileExtension is appended to the name of the encrypted file
+func (r Recipients) FileExtension() string {
+	switch r.Type {
+	case RECIPIENT_TYPE_AGE:
+		return ".age"
+	case RECIPIENT_TYPE_GPG:
+		return ".gpg"
+	default:
+		return ""
+	}
+}
+
+// ContentType of the encrypted file
+func (r Recipients) ContentType() string {
+	switch r.Type {
+	case RECIPIENT_TYPE_GPG:
+		return "application/pgp-encrypted"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// NewEncryptWriter returns a writer encrypting everything written to it for the recipients into w. Close must be
+// called to finish the file, it doesn't close w.
+func NewEncryptWriter(w io.Writer, r Recipients, fileName string) (io.WriteCloser, error) {
+	ageRecipients, gpgEntities, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	if r.Type == RECIPIENT_TYPE_GPG {
+		return EncryptGPG(w, gpgEntities, fileName)
+	}
+	return EncryptAge(w, ageRecipients)
+}
+
+func (r Recipients) parse() ([]*age.X25519Recipient, []*openpgp.Entity, error) {
+	if len(r.Keys) == 0 {
+		return nil, nil, errors.New("no recipient keys")
+	}
+	if len(r.Keys) > maxRecipients {
+		return nil, nil, fmt.Errorf("too many recipient keys, at most %d are allowed", maxRecipients)
+	}
+
+	switch r.Type {
+	case RECIPIENT_TYPE_AGE:
+		recipients := make([]*age.X25519Recipient, 0, len(r.Keys))
+		for _, key := range r.Keys {
+			recipient, err := ParseAgeRecipient(key)
+			if err != nil {
+				return nil, nil, err
+			}
+			recipients = append(recipients, recipient)
+		}
+		return recipients, nil, nil
+	case RECIPIENT_TYPE_GPG:
+		entities := []*openpgp.Entity{}
+		for _, key := range r.Keys {
+			keyEntities, err := ParseGPGRecipient(key)
+			if err != nil {
+				return nil, nil, err
+			}
+			entities = append(entities, keyEntities...)
+		}
+		return nil, entities, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported recipient type %s, use %s or %s", r.Type, RECIPIENT_TYPE_AGE, RECIPIENT_TYPE_GPG)
+	}
+}
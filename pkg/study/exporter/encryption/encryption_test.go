@@ -0,0 +1,161 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// identity of the age documentation, the recipient was derived with the age tool
+const (
+	testAgeIdentity  = "AGE-SECRET-KEY-184JMZMVQH3E6U0PSL869004Y3U2NYV7R30EU99CSEDNPH02YUVFSZW44VU"
+	testAgeRecipient = "age1cy0su9fwf3gf9mw868g5yut09p6nytfmmnktexz2ya5uqg9vl9sss4euqm"
+)
+
+func TestParseAgeRecipient(t *testing.T) {
+	t.Run("example recipient of the age documentation", func(t *testing.T) {
+		if _, err := ParseAgeRecipient("age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid recipients", func(t *testing.T) {
+		for _, key := range []string{
+			"",
+			"age1",
+			testAgeRecipient[:len(testAgeRecipient)-1] + "q",
+			strings.ToUpper(testAgeRecipient[:10]) + testAgeRecipient[10:],
+			testAgeIdentity,
+		} {
+			if _, err := ParseAgeRecipient(key); err == nil {
+				t.Errorf("expected error for %q", key)
+			}
+		}
+	})
+}
+
+func TestEncryptAge(t *testing.T) {
+	identity1, err := age.ParseX25519Identity(testAgeIdentity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity2, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// age encrypts in chunks of 64 KiB
+	chunkSize := 64 * 1024
+	for _, size := range []int{0, 10, chunkSize, chunkSize + 1, 3*chunkSize + 123} {
+		plaintext := make([]byte, size)
+		if _, err := rand.Read(plaintext); err != nil {
+			t.Fatal(err)
+		}
+
+		out := &bytes.Buffer{}
+		w, err := NewEncryptWriter(out, Recipients{Type: RECIPIENT_TYPE_AGE, Keys: []string{testAgeRecipient, identity2.Recipient().String()}}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// write in small pieces to cover chunk boundaries
+		for i := 0; i < len(plaintext); i += 1000 {
+			end := min(i+1000, len(plaintext))
+			if _, err := w.Write(plaintext[i:end]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, identity := range []age.Identity{identity1, identity2} {
+			r, err := age.Decrypt(bytes.NewReader(out.Bytes()), identity)
+			if err != nil {
+				t.Fatalf("size %d: %v", size, err)
+			}
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("size %d: %v", size, err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("size %d: decrypted content differs", size)
+			}
+		}
+	}
+}
+
+func TestEncryptGPG(t *testing.T) {
+	entity, err := openpgp.NewEntity("Data Manager", "", "dm@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	armored := &bytes.Buffer{}
+	aw, err := armor.Encode(armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(aw); err != nil {
+		t.Fatal(err)
+	}
+	aw.Close()
+
+	recipients := Recipients{Type: RECIPIENT_TYPE_GPG, Keys: []string{armored.String()}}
+	fingerprints, err := recipients.Fingerprints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fingerprints) != 1 || len(fingerprints[0]) != 40 {
+		t.Errorf("unexpected fingerprints: %v", fingerprints)
+	}
+
+	out := &bytes.Buffer{}
+	w, err := NewEncryptWriter(out, recipients, "export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(`{"responses":[]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	md, err := openpgp.ReadMessage(out, openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != `{"responses":[]}` {
+		t.Errorf("unexpected content: %s", decrypted)
+	}
+}
+
+func TestRecipientsValidate(t *testing.T) {
+	recipient := testAgeRecipient
+	tests := []struct {
+		name       string
+		recipients Recipients
+		wantErr    bool
+	}{
+		{name: "age", recipients: Recipients{Type: RECIPIENT_TYPE_AGE, Keys: []string{recipient}}},
+		{name: "no keys", recipients: Recipients{Type: RECIPIENT_TYPE_AGE}, wantErr: true},
+		{name: "unknown type", recipients: Recipients{Type: "zip", Keys: []string{recipient}}, wantErr: true},
+		{name: "age key as GPG key", recipients: Recipients{Type: RECIPIENT_TYPE_GPG, Keys: []string{recipient}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.recipients.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// ParseGPGRecipient parses an ASCII armored public key, all entities of the key ring are used as recipients
+func ParseGPGRecipient(armoredKey string) ([]*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GPG public key: %w", err)
+	}
+	if len(entities) == 0 {
+		return nil, errors.New("invalid GPG public key: no key found")
+	}
+	return entities, nil
+}
+
+// EncryptGPG returns a writer encrypting everything written to it for the recipients into w (binary OpenPGP message).
+// The returned writer must be closed to finish the message.
+func EncryptGPG(w io.Writer, recipients []*openpgp.Entity, fileName string) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	return openpgp.Encrypt(w, recipients, nil, &openpgp.FileHints{IsBinary: true, FileName: fileName}, nil)
+}
+
+// gpgFingerprint is the fingerprint of the primary key, in hex as shown by gpg
+func gpgFingerprint(entity *openpgp.Entity) string {
+	return fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+}
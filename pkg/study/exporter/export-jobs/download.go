@@ -0,0 +1,56 @@
+package exportjobs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidDownloadSignature = errors.New("invalid download signature")
+	ErrDownloadLinkExpired      = errors.New("download link expired")
+)
+
+// signatures are bound to this purpose, so a key shared with other signers can't be used to forge download links
+const downloadSignaturePurpose = "export-job-download"
+
+// SignDownload returns the signature allowing to download the artifact of the job until expiresAt (unix seconds)
+func SignDownload(key string, instanceID string, jobID string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(downloadSignaturePurpose + "\n" + instanceID + "\n" + jobID + "\n" + strconv.FormatInt(expiresAt, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DownloadQuery returns the query parameters of a signed download link
+func DownloadQuery(key string, instanceID string, jobID string, expiresAt int64) url.Values {
+	return url.Values{
+		"expires":   []string{strconv.FormatInt(expiresAt, 10)},
+		"signature": []string{SignDownload(key, instanceID, jobID, expiresAt)},
+	}
+}
+
+// VerifyDownload checks the signature and expiry of a download link
+func VerifyDownload(key string, instanceID string, jobID string, expires string, signature string, now time.Time) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || key == "" {
+		return ErrInvalidDownloadSignature
+	}
+
+	expected, err := hex.DecodeString(SignDownload(key, instanceID, jobID, expiresAt))
+	if err != nil {
+		return ErrInvalidDownloadSignature
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, given) {
+		return ErrInvalidDownloadSignature
+	}
+
+	if now.Unix() > expiresAt {
+		return ErrDownloadLinkExpired
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package exportjobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignedDownloads(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	expiresAt := now.Add(time.Hour).Unix()
+	query := DownloadQuery("key", "instance", "job1", expiresAt)
+
+	t.Run("valid link", func(t *testing.T) {
+		if err := VerifyDownload("key", "instance", "job1", query.Get("expires"), query.Get("signature"), now); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		if err := VerifyDownload("key", "instance", "job1", query.Get("expires"), query.Get("signature"), now.Add(2*time.Hour)); err != ErrDownloadLinkExpired {
+			t.Errorf("expected expired link, got %v", err)
+		}
+	})
+
+	t.Run("tampered links", func(t *testing.T) {
+		cases := []struct {
+			name       string
+			key        string
+			instanceID string
+			jobID      string
+			expires    string
+			signature  string
+		}{
+			{"other key", "other", "instance", "job1", query.Get("expires"), query.Get("signature")},
+			{"other instance", "key", "instance2", "job1", query.Get("expires"), query.Get("signature")},
+			{"other job", "key", "instance", "job2", query.Get("expires"), query.Get("signature")},
+			{"extended expiry", "key", "instance", "job1", "9999999999", query.Get("signature")},
+			{"invalid expiry", "key", "instance", "job1", "tomorrow", query.Get("signature")},
+			{"missing signature", "key", "instance", "job1", query.Get("expires"), ""},
+			{"no key", "", "instance", "job1", query.Get("expires"), SignDownload("", "instance", "job1", expiresAt)},
+		}
+		for _, c := range cases {
+			if err := VerifyDownload(c.key, c.instanceID, c.jobID, c.expires, c.signature, now); err != ErrInvalidDownloadSignature {
+				t.Errorf("%s: expected invalid signature, got %v", c.name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package exportjobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/case-framework/case-backend/pkg/utils/cron"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeltaKeyForScheduledExport is the delta key of incremental scheduled exports, so that every run exports the responses since the previous one
+func DeltaKeyForScheduledExport(scheduledExportID string) string {
+	return "scheduled_" + scheduledExportID
+}
+
+// NextScheduledRun returns the first run of the cron expression in the timezone after the reference time
+func NextScheduledRun(cronExpr string, timezone string, after time.Time) (time.Time, error) {
+	schedule, err := cron.ParseCron(cronExpr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc := time.UTC
+	if timezone != "" {
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	return schedule.Next(after.In(loc))
+}
+
+// startDueScheduledExports queues a job for each scheduled export whose run is due. Runs missed while no worker was
+// running are not caught up, the schedule continues with its next run after now.
+func (w *Worker) startDueScheduledExports(instanceID string, now time.Time) {
+	scheduledExports, err := w.studyDBService.GetDueScheduledExports(instanceID, now)
+	if err != nil {
+		slog.Error("failed to get due scheduled exports", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		return
+	}
+
+	for _, scheduledExport := range scheduledExports {
+		logAttrs := []any{slog.String("instanceID", instanceID), slog.String("studyKey", scheduledExport.StudyKey), slog.String("scheduledExportID", scheduledExport.ID.Hex())}
+
+		nextRunAt, err := NextScheduledRun(scheduledExport.Cron, scheduledExport.Timezone, now)
+		if err != nil {
+			slog.Error("invalid schedule of scheduled export", append(logAttrs, slog.String("error", err.Error()))...)
+			continue
+		}
+
+		// only the worker advancing the schedule starts the run
+		advanced, err := w.studyDBService.AdvanceScheduledExport(instanceID, scheduledExport.ID, scheduledExport.NextRunAt, nextRunAt)
+		if err != nil {
+			slog.Error("failed to advance scheduled export", append(logAttrs, slog.String("error", err.Error()))...)
+			continue
+		}
+		if !advanced {
+			continue
+		}
+
+		req := scheduledExport.Request
+		if scheduledExport.Incremental {
+			req.DeltaKey = DeltaKeyForScheduledExport(scheduledExport.ID.Hex())
+		}
+
+		job, err := w.studyDBService.CreateExportJob(instanceID, studyTypes.ExportJob{
+			StudyKey:          scheduledExport.StudyKey,
+			CreatedBy:         scheduledExport.CreatedBy,
+			Request:           req,
+			FileType:          FileType(scheduledExport.Request.Format),
+			ScheduledExportID: scheduledExport.ID.Hex(),
+			Delivery: &studyTypes.ExportDelivery{
+				Destination:   scheduledExport.Destination,
+				Status:        studyTypes.EXPORT_DELIVERY_STATUS_PENDING,
+				NextAttemptAt: now,
+			},
+		})
+		if err != nil {
+			slog.Error("failed to create export job for scheduled export", append(logAttrs, slog.String("error", err.Error()))...)
+			continue
+		}
+
+		if err := w.studyDBService.SetScheduledExportLastJob(instanceID, scheduledExport.ID, job.ID.Hex(), now); err != nil {
+			slog.Error("failed to update scheduled export", append(logAttrs, slog.String("error", err.Error()))...)
+		}
+		slog.Info("started scheduled export", append(logAttrs, slog.String("jobID", job.ID.Hex()), slog.Time("nextRunAt", nextRunAt))...)
+	}
+}
+
+// deliver pushes the artifact of the completed job to its destination
+func (w *Worker) deliver(ctx context.Context, instanceID string, job studyTypes.ExportJob) {
+	d := *job.Delivery
+	logAttrs := []any{slog.String("instanceID", instanceID), slog.String("studyKey", job.StudyKey), slog.String("jobID", job.ID.Hex()), slog.String("destination", d.Destination)}
+
+	var location string
+	var err error
+	deliverer, ok := w.conf.Destinations[d.Destination]
+	if !ok {
+		err = fmt.Errorf("destination %s is not configured", d.Destination)
+	} else {
+		location, err = deliverer.Deliver(ctx, filepath.Join(w.conf.FilestorePath, job.ResultFile), deliveryFileName(job), job.FileType)
+	}
+	if err != nil && ctx.Err() != nil {
+		slog.Info("export delivery interrupted, will be retried after the lease expired", logAttrs...)
+		return
+	}
+
+	registerDeliveryAttempt(&d, location, err, time.Now(), w.conf.MaxDeliveryAttempts, w.conf.DeliveryInitialBackoff)
+
+	switch d.Status {
+	case studyTypes.EXPORT_DELIVERY_STATUS_DELIVERED:
+		if err := w.studyDBService.UpdateExportJobDelivery(instanceID, job.ID, d); err != nil {
+			slog.Error("failed to update export delivery", append(logAttrs, slog.String("error", err.Error()))...)
+		}
+		if job.ScheduledExportID != "" {
+			w.setScheduledExportDeliveryResult(instanceID, job.ScheduledExportID, d)
+		}
+		slog.Info("export delivered", append(logAttrs, slog.String("location", location))...)
+	case studyTypes.EXPORT_DELIVERY_STATUS_FAILED:
+		w.deliveryFailed(instanceID, job, d)
+	default:
+		if err := w.studyDBService.UpdateExportJobDelivery(instanceID, job.ID, d); err != nil {
+			slog.Error("failed to update export delivery", append(logAttrs, slog.String("error", err.Error()))...)
+		}
+		slog.Warn("export delivery failed, will be retried", append(logAttrs, slog.Int("attempts", d.Attempts), slog.Time("nextAttemptAt", d.NextAttemptAt), slog.String("error", d.LastError))...)
+	}
+}
+
+// deliveryFailed stores the final failure of the delivery and alerts about it through the log and webhooks
+func (w *Worker) deliveryFailed(instanceID string, job studyTypes.ExportJob, d studyTypes.ExportDelivery) {
+	logAttrs := []any{slog.String("instanceID", instanceID), slog.String("studyKey", job.StudyKey), slog.String("jobID", job.ID.Hex()), slog.String("destination", d.Destination)}
+
+	if err := w.studyDBService.UpdateExportJobDelivery(instanceID, job.ID, d); err != nil {
+		slog.Error("failed to update export delivery", append(logAttrs, slog.String("error", err.Error()))...)
+	}
+	if job.ScheduledExportID != "" {
+		w.setScheduledExportDeliveryResult(instanceID, job.ScheduledExportID, d)
+	}
+
+	slog.Error("export delivery failed", append(logAttrs, slog.String("scheduledExportID", job.ScheduledExportID), slog.Int("attempts", d.Attempts), slog.String("error", d.LastError))...)
+	go webhooks.Publish(instanceID, webhooks.EVENT_EXPORT_DELIVERY_FAILED, map[string]any{
+		"studyKey":          job.StudyKey,
+		"jobID":             job.ID.Hex(),
+		"scheduledExportID": job.ScheduledExportID,
+		"destination":       d.Destination,
+		"attempts":          d.Attempts,
+		"error":             d.LastError,
+	})
+}
+
+func (w *Worker) setScheduledExportDeliveryResult(instanceID string, scheduledExportID string, d studyTypes.ExportDelivery) {
+	id, err := primitive.ObjectIDFromHex(scheduledExportID)
+	if err != nil {
+		return
+	}
+	if err := w.studyDBService.SetScheduledExportDeliveryResult(instanceID, id, d.Status, d.LastError, time.Now()); err != nil {
+		slog.Error("failed to update scheduled export", slog.String("instanceID", instanceID), slog.String("scheduledExportID", scheduledExportID), slog.String("error", err.Error()))
+	}
+}
+
+// registerDeliveryAttempt updates the delivery after an attempt. Failed deliveries are retried with exponential
+// backoff until the maximum number of attempts is reached.
+func registerDeliveryAttempt(d *studyTypes.ExportDelivery, location string, deliverErr error, now time.Time, maxAttempts int, initialBackoff time.Duration) {
+	d.Attempts += 1
+	if deliverErr == nil {
+		d.Status = studyTypes.EXPORT_DELIVERY_STATUS_DELIVERED
+		d.Location = location
+		d.DeliveredAt = now
+		d.NextAttemptAt = time.Time{}
+		d.LastError = ""
+		return
+	}
+
+	d.LastError = deliverErr.Error()
+	if d.Attempts >= maxAttempts {
+		d.Status = studyTypes.EXPORT_DELIVERY_STATUS_FAILED
+		d.NextAttemptAt = time.Time{}
+		return
+	}
+
+	backoff := initialBackoff
+	for i := 1; i < d.Attempts && backoff < maxDeliveryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxDeliveryBackoff {
+		backoff = maxDeliveryBackoff
+	}
+	d.Status = studyTypes.EXPORT_DELIVERY_STATUS_PENDING
+	d.NextAttemptAt = now.Add(backoff)
+}
+
+// deliveryFileName names delivered files after the study, survey and run, e.g. study1_intake_20240101-020000_<jobID>.csv
+func deliveryFileName(job studyTypes.ExportJob) string {
+	return fmt.Sprintf(
+		"%s_%s_%s_%s%s",
+		job.StudyKey,
+		job.Request.SurveyKey,
+		job.CreatedAt.UTC().Format("20060102-150405"),
+		job.ID.Hex(),
+		filepath.Ext(job.ResultFile),
+	)
+}
@@ -0,0 +1,87 @@
+package exportjobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNextScheduledRun(t *testing.T) {
+	after := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	next, err := NextScheduledRun("0 2 * * *", "", after)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !next.Equal(time.Date(2024, 3, 11, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next run: %v", next)
+	}
+
+	next, err = NextScheduledRun("0 2 * * *", "Europe/Berlin", after)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !next.Equal(time.Date(2024, 3, 11, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next run in timezone: %v", next.UTC())
+	}
+
+	if _, err := NextScheduledRun("0 2 * *", "", after); err == nil {
+		t.Error("expected error for invalid cron")
+	}
+	if _, err := NextScheduledRun("0 2 * * *", "Mars/Olympus", after); err == nil {
+		t.Error("expected error for invalid timezone")
+	}
+}
+
+func TestRegisterDeliveryAttempt(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	t.Run("delivered", func(t *testing.T) {
+		d := studyTypes.ExportDelivery{Status: studyTypes.EXPORT_DELIVERY_STATUS_PENDING, Attempts: 2, LastError: "previous"}
+		registerDeliveryAttempt(&d, "s3://bucket/file.csv", nil, now, 5, time.Minute)
+		if d.Status != studyTypes.EXPORT_DELIVERY_STATUS_DELIVERED || d.Attempts != 3 || d.LastError != "" || d.Location != "s3://bucket/file.csv" || !d.DeliveredAt.Equal(now) {
+			t.Errorf("unexpected delivery: %+v", d)
+		}
+	})
+
+	t.Run("retry with backoff", func(t *testing.T) {
+		d := studyTypes.ExportDelivery{Status: studyTypes.EXPORT_DELIVERY_STATUS_PENDING, Attempts: 2}
+		registerDeliveryAttempt(&d, "", errors.New("unreachable"), now, 5, time.Minute)
+		if d.Status != studyTypes.EXPORT_DELIVERY_STATUS_PENDING || d.LastError != "unreachable" || !d.NextAttemptAt.Equal(now.Add(4*time.Minute)) {
+			t.Errorf("unexpected delivery: %+v", d)
+		}
+	})
+
+	t.Run("backoff is capped", func(t *testing.T) {
+		d := studyTypes.ExportDelivery{Status: studyTypes.EXPORT_DELIVERY_STATUS_PENDING, Attempts: 30}
+		registerDeliveryAttempt(&d, "", errors.New("unreachable"), now, 100, time.Minute)
+		if !d.NextAttemptAt.Equal(now.Add(maxDeliveryBackoff)) {
+			t.Errorf("unexpected next attempt: %v", d.NextAttemptAt)
+		}
+	})
+
+	t.Run("last attempt fails", func(t *testing.T) {
+		d := studyTypes.ExportDelivery{Status: studyTypes.EXPORT_DELIVERY_STATUS_PENDING, Attempts: 4}
+		registerDeliveryAttempt(&d, "", errors.New("unreachable"), now, 5, time.Minute)
+		if d.Status != studyTypes.EXPORT_DELIVERY_STATUS_FAILED || !d.NextAttemptAt.IsZero() {
+			t.Errorf("unexpected delivery: %+v", d)
+		}
+	})
+}
+
+func TestDeliveryFileName(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65a000000000000000000001")
+	job := studyTypes.ExportJob{
+		ID:         id,
+		StudyKey:   "study1",
+		CreatedAt:  time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC),
+		Request:    studyTypes.ExportJobRequest{SurveyKey: "intake"},
+		ResultFile: "instance/exports/export-job_65a000000000000000000001.csv",
+	}
+	if name := deliveryFileName(job); name != "study1_intake_20240101-020000_65a000000000000000000001.csv" {
+		t.Errorf("unexpected file name: %s", name)
+	}
+}
@@ -0,0 +1,512 @@
+package exportjobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	managementalerts "github.com/case-framework/case-backend/pkg/messaging/management-alerts"
+	"github.com/case-framework/case-backend/pkg/study/exporter/delivery"
+	"github.com/case-framework/case-backend/pkg/study/exporter/manifest"
+	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// a running job extends its lease on every progress update
+	exportJobLease = 10 * time.Minute
+
+	defaultConcurrency  = 2
+	defaultPollInterval = 5 * time.Second
+	defaultArtifactTTL  = 7 * 24 * time.Hour
+	defaultGCInterval   = time.Hour
+
+	// responses are stored a moment after their arrival time is set, so delta windows end slightly in the past to not skip them
+	deltaWindowSafetyMargin = 5 * time.Second
+
+	defaultMaxDeliveryAttempts    = 6
+	defaultDeliveryInitialBackoff = time.Minute
+	maxDeliveryBackoff            = 2 * time.Hour
+	// time a claimed delivery is hidden from other workers
+	deliveryLease = 15 * time.Minute
+)
+
+type WorkerConfig struct {
+	// artifacts are written to <FilestorePath>/<instanceID>/exports
+	FilestorePath string
+	// number of jobs processed at the same time
+	Concurrency  int
+	PollInterval time.Duration
+	// completed artifacts and failed jobs are removed after this duration
+	ArtifactTTL time.Duration
+	GCInterval  time.Duration
+
+	// destinations of scheduled exports by name
+	Destinations map[string]delivery.Deliverer
+	// failed deliveries are retried with exponential backoff until the maximum number of attempts is reached
+	MaxDeliveryAttempts    int
+	DeliveryInitialBackoff time.Duration
+}
+
+// Worker produces the artifacts of queued export jobs and removes expired ones
+type Worker struct {
+	studyDBService *studyDB.StudyDBService
+	instanceIDs    func() []string
+	conf           WorkerConfig
+}
+
+// NewWorker calls instanceIDs on each poll, so that instances provisioned at runtime are picked up
+func NewWorker(studyDBService *studyDB.StudyDBService, instanceIDs func() []string, conf WorkerConfig) *Worker {
+	if conf.Concurrency < 1 {
+		conf.Concurrency = defaultConcurrency
+	}
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaultPollInterval
+	}
+	if conf.ArtifactTTL <= 0 {
+		conf.ArtifactTTL = defaultArtifactTTL
+	}
+	if conf.GCInterval <= 0 {
+		conf.GCInterval = defaultGCInterval
+	}
+	if conf.MaxDeliveryAttempts < 1 {
+		conf.MaxDeliveryAttempts = defaultMaxDeliveryAttempts
+	}
+	if conf.DeliveryInitialBackoff <= 0 {
+		conf.DeliveryInitialBackoff = defaultDeliveryInitialBackoff
+	}
+	return &Worker{
+		studyDBService: studyDBService,
+		instanceIDs:    instanceIDs,
+		conf:           conf,
+	}
+}
+
+// ArtifactTTL is how long completed artifacts are kept
+func (w *Worker) ArtifactTTL() time.Duration {
+	return w.conf.ArtifactTTL
+}
+
+// Run processes queued jobs until the context is cancelled. Jobs interrupted by the cancellation stay running
+// and are picked up again by a worker once their lease expired.
+func (w *Worker) Run(ctx context.Context) {
+	slots := make(chan struct{}, w.conf.Concurrency)
+	wg := sync.WaitGroup{}
+
+	pollTicker := time.NewTicker(w.conf.PollInterval)
+	defer pollTicker.Stop()
+	gcTicker := time.NewTicker(w.conf.GCInterval)
+	defer gcTicker.Stop()
+
+	w.collectGarbage()
+	for {
+		w.dispatch(ctx, slots, &wg)
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-gcTicker.C:
+			w.collectGarbage()
+		case <-pollTicker.C:
+		}
+	}
+}
+
+// dispatch queues the jobs of due scheduled exports, then claims queued jobs and due deliveries as long as there are free slots
+func (w *Worker) dispatch(ctx context.Context, slots chan struct{}, wg *sync.WaitGroup) {
+	for _, instanceID := range w.instanceIDs() {
+		w.startDueScheduledExports(instanceID, time.Now())
+
+		for {
+			select {
+			case slots <- struct{}{}:
+			default:
+				return
+			}
+
+			now := time.Now()
+			job, err := w.studyDBService.ClaimQueuedExportJob(instanceID, now, now.Add(exportJobLease))
+			if err != nil || job == nil {
+				<-slots
+				if err != nil {
+					slog.Error("failed to claim export job", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+				}
+				break
+			}
+
+			wg.Add(1)
+			go func(instanceID string, job studyTypes.ExportJob) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				w.runJob(ctx, instanceID, job)
+			}(instanceID, *job)
+		}
+
+		for {
+			select {
+			case slots <- struct{}{}:
+			default:
+				return
+			}
+
+			now := time.Now()
+			job, err := w.studyDBService.ClaimDueExportDelivery(instanceID, now, now.Add(deliveryLease))
+			if err != nil || job == nil {
+				<-slots
+				if err != nil {
+					slog.Error("failed to claim export delivery", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+				}
+				break
+			}
+
+			wg.Add(1)
+			go func(instanceID string, job studyTypes.ExportJob) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				w.deliver(ctx, instanceID, job)
+			}(instanceID, *job)
+		}
+	}
+}
+
+func (w *Worker) runJob(ctx context.Context, instanceID string, job studyTypes.ExportJob) {
+	logAttrs := []any{slog.String("instanceID", instanceID), slog.String("studyKey", job.StudyKey), slog.String("jobID", job.ID.Hex())}
+	slog.Info("running export job", logAttrs...)
+
+	start := time.Now()
+	window, err := w.exportWindow(instanceID, job, start)
+	if err != nil {
+		slog.Error("failed to get export checkpoint", append(logAttrs, slog.String("error", err.Error()))...)
+		w.failJob(instanceID, job, "failed to get export checkpoint")
+		return
+	}
+
+	relativeFilepath, count, err := w.produceArtifact(ctx, instanceID, job, window)
+	if err != nil {
+		if ctx.Err() != nil {
+			slog.Info("export job interrupted, will be resumed after the lease expired", logAttrs...)
+			return
+		}
+		slog.Error("export job failed", append(logAttrs, slog.String("error", err.Error()))...)
+		w.failJob(instanceID, job, err.Error())
+		return
+	}
+
+	var size int64
+	if info, err := os.Stat(filepath.Join(w.conf.FilestorePath, relativeFilepath)); err == nil {
+		size = info.Size()
+	}
+
+	if err := w.studyDBService.CompleteExportJob(instanceID, job.ID, count, relativeFilepath, size, window, time.Now().Add(w.conf.ArtifactTTL)); err != nil {
+		slog.Error("failed to update export job", append(logAttrs, slog.String("error", err.Error()))...)
+		return
+	}
+
+	// if this fails, the next delta export overlaps with this one, which is better than missing responses
+	if job.Request.DeltaKey != "" {
+		if err := w.studyDBService.AdvanceExportCheckpoint(instanceID, job.StudyKey, job.Request.DeltaKey, window.Until, job.ID.Hex()); err != nil {
+			slog.Error("failed to update export checkpoint", append(logAttrs, slog.String("deltaKey", job.Request.DeltaKey), slog.String("error", err.Error()))...)
+		}
+	}
+	slog.Info("export job completed", append(logAttrs, slog.Int("responses", count), slog.Duration("duration", time.Since(start)))...)
+	managementalerts.Send(instanceID, job.CreatedBy, managementalerts.Alert{
+		Type:     managementalerts.ALERT_EXPORT_COMPLETED,
+		StudyKey: job.StudyKey,
+		Title:    "Export job completed",
+		Details: [][2]string{
+			{"Job", job.ID.Hex()},
+			{"Responses", strconv.Itoa(count)},
+		},
+	})
+}
+
+// failJob marks the job as failed, for scheduled exports the delivery fails as well
+func (w *Worker) failJob(instanceID string, job studyTypes.ExportJob, errMsg string) {
+	if err := w.studyDBService.FailExportJob(instanceID, job.ID, errMsg, time.Now().Add(w.conf.ArtifactTTL)); err != nil {
+		slog.Error("failed to update export job", slog.String("instanceID", instanceID), slog.String("jobID", job.ID.Hex()), slog.String("error", err.Error()))
+	}
+	managementalerts.Send(instanceID, job.CreatedBy, managementalerts.Alert{
+		Type:     managementalerts.ALERT_JOB_FAILED,
+		StudyKey: job.StudyKey,
+		Title:    "Export job failed",
+		Details: [][2]string{
+			{"Job", job.ID.Hex()},
+			{"Error", errMsg},
+		},
+	})
+	if job.Delivery != nil {
+		d := *job.Delivery
+		d.Status = studyTypes.EXPORT_DELIVERY_STATUS_FAILED
+		d.LastError = "export failed: " + errMsg
+		d.NextAttemptAt = time.Time{}
+		w.deliveryFailed(instanceID, job, d)
+	}
+}
+
+// produceArtifact writes the export file of the job, returns its path relative to the filestore root
+func (w *Worker) produceArtifact(ctx context.Context, instanceID string, job studyTypes.ExportJob, window *studyTypes.ExportWindow) (string, int, error) {
+	req := job.Request
+
+	filter := bson.M{}
+	if req.Filter != "" {
+		if err := json.Unmarshal([]byte(req.Filter), &filter); err != nil {
+			return "", 0, errors.New("invalid filter")
+		}
+	}
+	filter["key"] = req.SurveyKey
+	if window != nil {
+		filter = withExportWindow(filter, *window)
+	}
+	// responses of synthetic-monitoring accounts are not exported
+	filter = studyDB.ExcludeSynthetic(filter)
+	if !req.IncludeQuarantined {
+		filter = studyDB.ExcludeQuarantined(filter)
+	}
+
+	// the count at job creation includes responses outside of the delta window
+	if window != nil {
+		if targetCount, err := w.studyDBService.GetResponsesCount(instanceID, job.StudyKey, filter); err == nil {
+			if err := w.studyDBService.UpdateExportJobTargetCount(instanceID, job.ID, int(targetCount)); err != nil {
+				slog.Error("failed to update export job target count", slog.String("jobID", job.ID.Hex()), slog.String("error", err.Error()))
+			}
+		}
+	}
+
+	var sort bson.M
+	if req.Sort != "" {
+		if err := json.Unmarshal([]byte(req.Sort), &sort); err != nil {
+			return "", 0, errors.New("invalid sort")
+		}
+	}
+
+	surveyVersions, err := surveydefinition.PrepareSurveyInfosFromDB(
+		w.studyDBService,
+		instanceID,
+		job.StudyKey,
+		req.SurveyKey,
+		&surveydefinition.ExtractOptions{
+			UseLabelLang: req.LabelLang,
+		},
+	)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var extraCtxCols *[]string
+	if len(req.ExtraContextColumns) > 0 {
+		extraCtxCols = &req.ExtraContextColumns
+	}
+	respParser, err := surveyresponses.NewResponseParser(
+		req.SurveyKey,
+		surveyVersions,
+		req.UseShortKeys,
+		&surveyresponses.IncludeMeta{},
+		req.QuestionOptionSep,
+		extraCtxCols,
+	)
+	if err != nil {
+		return "", 0, err
+	}
+
+	relativeFolderName := filepath.Join(instanceID, "exports")
+	if err := os.MkdirAll(filepath.Join(w.conf.FilestorePath, relativeFolderName), os.ModePerm); err != nil {
+		return "", 0, err
+	}
+	relativeFilepath := filepath.Join(relativeFolderName, "export-job_"+job.ID.Hex()+surveyresponses.ExportFileExtension(req.Format))
+	exportFilePath := filepath.Join(w.conf.FilestorePath, relativeFilepath)
+
+	file, err := os.Create(exportFilePath)
+	if err != nil {
+		return "", 0, err
+	}
+	count, err := w.writeExport(ctx, instanceID, job, filter, sort, respParser, file, exportFilePath)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		removeArtifact(exportFilePath)
+		return "", 0, err
+	}
+	return relativeFilepath, count, nil
+}
+
+func (w *Worker) writeExport(
+	ctx context.Context,
+	instanceID string,
+	job studyTypes.ExportJob,
+	filter bson.M,
+	sort bson.M,
+	respParser *surveyresponses.ResponseParser,
+	file *os.File,
+	exportFilePath string,
+) (int, error) {
+	req := job.Request
+
+	exporter, err := surveyresponses.NewResponseExporterWithOptions(
+		respParser,
+		file,
+		surveyresponses.ExportOptions{
+			Format:      req.Format,
+			ValueLabels: req.ValueLabels,
+			Pseudonymization: surveyresponses.PseudonymizationOptions{
+				ParticipantIDs:     req.ParticipantIDs,
+				Salt:               req.PseudonymizationSalt,
+				RedactColumns:      req.RedactColumns,
+				TimestampPrecision: req.TimestampPrecision,
+			},
+			ParticipantInfo: surveyresponses.ParticipantInfoOptions{
+				Fields: req.ParticipantInfo,
+				Flags:  req.ParticipantFlags,
+				Lookup: surveyresponses.ParticipantsFromDB(w.studyDBService, instanceID, job.StudyKey),
+			},
+			ValidationIssues: req.ValidationIssues,
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	exportManifest := manifest.NewExportManifest(instanceID, job.StudyKey, req.SurveyKey, "responses", req.Format, filter)
+	exportManifest.Columns = exporter.Schema()
+
+	count, err := exporter.Stream(
+		ctx,
+		surveyresponses.ResponsesFromDB(w.studyDBService, instanceID, job.StudyKey, filter, sort),
+		surveyresponses.StreamOptions{
+			OnResponseWritten: exportManifest.ObserveResponse,
+			OnProgress: func(written int) {
+				if err := w.studyDBService.UpdateExportJobProgress(instanceID, job.ID, written, time.Now().Add(exportJobLease)); err != nil {
+					// not a big issue, so let's try next time
+					slog.Error("failed to update export job progress", slog.String("jobID", job.ID.Hex()), slog.String("error", err.Error()))
+				}
+			},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if err := exporter.Finish(); err != nil {
+		return 0, err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, err
+	}
+
+	if err := exportManifest.AddFile(exportFilePath); err != nil {
+		slog.Error("failed to compute export file checksum", slog.String("path", exportFilePath), slog.String("error", err.Error()))
+	} else if err := exportManifest.WriteToFile(manifest.ManifestPathForFile(exportFilePath)); err != nil {
+		slog.Error("failed to write export manifest", slog.String("path", exportFilePath), slog.String("error", err.Error()))
+	}
+	return count, nil
+}
+
+// FileType returns the content type of export files in the format
+func FileType(format string) string {
+	switch format {
+	case surveyresponses.EXPORT_FORMAT_JSON:
+		return studyTypes.TASK_FILE_TYPE_JSON
+	case surveyresponses.EXPORT_FORMAT_NDJSON:
+		return studyTypes.TASK_FILE_TYPE_NDJSON
+	case surveyresponses.EXPORT_FORMAT_PARQUET:
+		return studyTypes.TASK_FILE_TYPE_PARQUET
+	case surveyresponses.EXPORT_FORMAT_XLSX:
+		return studyTypes.TASK_FILE_TYPE_XLSX
+	}
+	return studyTypes.TASK_FILE_TYPE_CSV
+}
+
+// exportWindow returns the arrival time range of the responses to export for delta jobs, nil for full exports
+func (w *Worker) exportWindow(instanceID string, job studyTypes.ExportJob, now time.Time) (*studyTypes.ExportWindow, error) {
+	req := job.Request
+	if req.Since <= 0 && req.DeltaKey == "" {
+		return nil, nil
+	}
+
+	var checkpointUntil int64
+	if req.DeltaKey != "" {
+		checkpoint, err := w.studyDBService.GetExportCheckpoint(instanceID, job.StudyKey, req.DeltaKey)
+		if err != nil {
+			return nil, err
+		}
+		if checkpoint != nil {
+			checkpointUntil = checkpoint.Until
+		}
+	}
+	window := deltaWindow(req.Since, checkpointUntil, now)
+	return &window, nil
+}
+
+// deltaWindow starts at the later of the requested start and the checkpoint of the last successful delta export
+func deltaWindow(since int64, checkpointUntil int64, now time.Time) studyTypes.ExportWindow {
+	from := since
+	if checkpointUntil > from {
+		from = checkpointUntil
+	}
+	return studyTypes.ExportWindow{
+		From:  from,
+		Until: now.Add(-deltaWindowSafetyMargin).Unix(),
+	}
+}
+
+// withExportWindow restricts the response filter to the arrival time range, keeping conditions of the filter on arrivedAt
+func withExportWindow(filter bson.M, window studyTypes.ExportWindow) bson.M {
+	windowCondition := bson.M{"$gt": window.From, "$lte": window.Until}
+	if _, ok := filter["arrivedAt"]; !ok {
+		filter["arrivedAt"] = windowCondition
+		return filter
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{"arrivedAt": windowCondition}}}
+}
+
+func (w *Worker) collectGarbage() {
+	for _, instanceID := range w.instanceIDs() {
+		removed, err := w.CollectGarbage(instanceID, time.Now())
+		if err != nil {
+			slog.Error("failed to remove expired export jobs", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+		if removed > 0 {
+			slog.Info("removed expired export jobs", slog.String("instanceID", instanceID), slog.Int("count", removed))
+		}
+	}
+}
+
+// CollectGarbage deletes the artifacts and records of jobs that expired before now, returns the number of removed jobs
+func (w *Worker) CollectGarbage(instanceID string, now time.Time) (int, error) {
+	jobs, err := w.studyDBService.GetExpiredExportJobs(instanceID, now)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, job := range jobs {
+		if job.ResultFile != "" {
+			removeArtifact(filepath.Join(w.conf.FilestorePath, job.ResultFile))
+		}
+		if err := w.studyDBService.DeleteExportJob(instanceID, job.ID); err != nil {
+			slog.Error("failed to delete export job", slog.String("instanceID", instanceID), slog.String("jobID", job.ID.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+		removed += 1
+	}
+	return removed, nil
+}
+
+// removeArtifact deletes the export file and its manifest
+func removeArtifact(exportFilePath string) {
+	for _, path := range []string{exportFilePath, manifest.ManifestPathForFile(exportFilePath)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			slog.Error("failed to remove export artifact", slog.String("path", path), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package exportjobs
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDeltaWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	until := now.Add(-deltaWindowSafetyMargin).Unix()
+
+	cases := []struct {
+		name            string
+		since           int64
+		checkpointUntil int64
+		expectedFrom    int64
+	}{
+		{"first delta export", 0, 0, 0},
+		{"since only", 100, 0, 100},
+		{"checkpoint after since", 100, 500, 500},
+		{"since after checkpoint", 700, 500, 700},
+	}
+	for _, c := range cases {
+		window := deltaWindow(c.since, c.checkpointUntil, now)
+		if window.From != c.expectedFrom || window.Until != until {
+			t.Errorf("%s: unexpected window %+v", c.name, window)
+		}
+	}
+}
+
+func TestWithExportWindow(t *testing.T) {
+	window := studyTypes.ExportWindow{From: 10, Until: 20}
+
+	t.Run("adds arrival time condition", func(t *testing.T) {
+		filter := withExportWindow(bson.M{"key": "S"}, window)
+		condition, ok := filter["arrivedAt"].(bson.M)
+		if !ok || condition["$gt"] != int64(10) || condition["$lte"] != int64(20) || filter["key"] != "S" {
+			t.Errorf("unexpected filter: %v", filter)
+		}
+	})
+
+	t.Run("keeps existing arrival time condition", func(t *testing.T) {
+		filter := withExportWindow(bson.M{"key": "S", "arrivedAt": bson.M{"$gt": 15}}, window)
+		conditions, ok := filter["$and"].(bson.A)
+		if !ok || len(conditions) != 2 {
+			t.Fatalf("unexpected filter: %v", filter)
+		}
+		if original := conditions[0].(bson.M); original["arrivedAt"].(bson.M)["$gt"] != 15 {
+			t.Errorf("original condition lost: %v", filter)
+		}
+	})
+}
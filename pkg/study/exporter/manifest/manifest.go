@@ -0,0 +1,161 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	MANIFEST_FILE_SUFFIX = ".manifest.json"
+)
+
+type TimeRange struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+type FileChecksum struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ColumnSchema describes a column of a response export, in the order of the wide format. In the long format, response
+// and meta columns are values of the responseSlot column.
+type ColumnSchema struct {
+	Name string `json:"name"`
+	// Header is set if the header in the file differs from the name, e.g. with value labels
+	Header string `json:"header,omitempty"`
+	// Category is one of fixed, context, response or meta
+	Category    string `json:"category"`
+	QuestionKey string `json:"questionKey,omitempty"`
+	// Type is string, number or date (unix seconds)
+	Type string `json:"type"`
+	// IntroducedIn is the oldest survey version containing the column
+	IntroducedIn string `json:"introducedIn,omitempty"`
+}
+
+// ExportManifest describes an export, so that downstream pipelines can verify integrity and reproduce results
+type ExportManifest struct {
+	GeneratedAt      int64          `json:"generatedAt"`
+	GeneratorVersion string         `json:"generatorVersion"`
+	InstanceID       string         `json:"instanceID"`
+	StudyKey         string         `json:"studyKey"`
+	SurveyKey        string         `json:"surveyKey,omitempty"`
+	ExportType       string         `json:"exportType"`
+	Format           string         `json:"format"`
+	Filter           interface{}    `json:"filter,omitempty"`
+	RowCount         int            `json:"rowCount"`
+	TimeRange        *TimeRange     `json:"timeRange,omitempty"`
+	SurveyVersions   []string       `json:"surveyVersions,omitempty"`
+	Files            []FileChecksum `json:"files"`
+	// Columns is the schema of response exports
+	Columns []ColumnSchema `json:"columns,omitempty"`
+
+	surveyVersions map[string]bool
+}
+
+func NewExportManifest(instanceID string, studyKey string, surveyKey string, exportType string, format string, filter interface{}) *ExportManifest {
+	return &ExportManifest{
+		GeneratorVersion: GeneratorVersion(),
+		InstanceID:       instanceID,
+		StudyKey:         studyKey,
+		SurveyKey:        surveyKey,
+		ExportType:       exportType,
+		Format:           format,
+		Filter:           filter,
+		Files:            []FileChecksum{},
+		surveyVersions:   map[string]bool{},
+	}
+}
+
+// ObserveResponse updates row count, covered time range and included survey versions
+func (m *ExportManifest) ObserveResponse(r *studytypes.SurveyResponse) {
+	m.ObserveRow(r.ArrivedAt)
+	if r.VersionID != "" && !m.surveyVersions[r.VersionID] {
+		m.surveyVersions[r.VersionID] = true
+		m.SurveyVersions = append(m.SurveyVersions, r.VersionID)
+		sort.Strings(m.SurveyVersions)
+	}
+}
+
+// ObserveRow counts an exported row and extends the time range with the given timestamp (ignored if 0)
+func (m *ExportManifest) ObserveRow(ts int64) {
+	m.RowCount += 1
+	if ts <= 0 {
+		return
+	}
+	if m.TimeRange == nil {
+		m.TimeRange = &TimeRange{From: ts, To: ts}
+		return
+	}
+	if ts < m.TimeRange.From {
+		m.TimeRange.From = ts
+	}
+	if ts > m.TimeRange.To {
+		m.TimeRange.To = ts
+	}
+}
+
+// AddFile computes the SHA-256 checksum of the file and adds it to the manifest
+func (m *ExportManifest) AddFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+
+	m.Files = append(m.Files, FileChecksum{
+		Name:   filepath.Base(path),
+		Size:   size,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	})
+	return nil
+}
+
+// WriteToFile stores the manifest as JSON
+func (m *ExportManifest) WriteToFile(path string) error {
+	m.GeneratedAt = time.Now().Unix()
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// ManifestPathForFile returns the path of the manifest belonging to an export file
+func ManifestPathForFile(exportFilePath string) string {
+	ext := filepath.Ext(exportFilePath)
+	return exportFilePath[:len(exportFilePath)-len(ext)] + MANIFEST_FILE_SUFFIX
+}
+
+// GeneratorVersion returns the module version and VCS revision of the running binary, if available
+func GeneratorVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			version += "+" + s.Value
+			break
+		}
+	}
+	return version
+}
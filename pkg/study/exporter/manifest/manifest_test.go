@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestObserveResponse(t *testing.T) {
+	m := NewExportManifest("inst", "study", "survey", "responses", "wide", nil)
+
+	m.ObserveResponse(&studytypes.SurveyResponse{ArrivedAt: 20, VersionID: "v2"})
+	m.ObserveResponse(&studytypes.SurveyResponse{ArrivedAt: 10, VersionID: "v1"})
+	m.ObserveResponse(&studytypes.SurveyResponse{ArrivedAt: 30, VersionID: "v2"})
+
+	if m.RowCount != 3 {
+		t.Errorf("unexpected row count: %d", m.RowCount)
+	}
+	if m.TimeRange == nil || m.TimeRange.From != 10 || m.TimeRange.To != 30 {
+		t.Errorf("unexpected time range: %v", m.TimeRange)
+	}
+	if len(m.SurveyVersions) != 2 || m.SurveyVersions[0] != "v1" || m.SurveyVersions[1] != "v2" {
+		t.Errorf("unexpected survey versions: %v", m.SurveyVersions)
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.csv")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewExportManifest("inst", "study", "", "participants", "json", nil)
+	if err := m.AddFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Files) != 1 {
+		t.Fatalf("unexpected number of files: %d", len(m.Files))
+	}
+	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if m.Files[0].SHA256 != expected || m.Files[0].Size != 5 || m.Files[0].Name != "export.csv" {
+		t.Errorf("unexpected file checksum: %v", m.Files[0])
+	}
+}
+
+func TestManifestPathForFile(t *testing.T) {
+	if p := ManifestPathForFile("/tmp/responses_abc.csv"); p != "/tmp/responses_abc.manifest.json" {
+		t.Errorf("unexpected manifest path: %s", p)
+	}
+}
@@ -0,0 +1,332 @@
+package surveydefinition
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// CODEBOOK_FORMAT_CSV is a data dictionary with one line per response option or response slot
+	CODEBOOK_FORMAT_CSV = "csv"
+	// CODEBOOK_FORMAT_DDI is a subset of the DDI Codebook 2.5 XML format, one variable per response slot
+	CODEBOOK_FORMAT_DDI      = "ddi"
+	CODEBOOK_FORMAT_MARKDOWN = "markdown"
+	CODEBOOK_FORMAT_HTML     = "html"
+)
+
+// Codebook documents one version of a survey, so that it can be published alongside datasets
+type Codebook struct {
+	StudyKey  string
+	SurveyKey string
+	Version   SurveyVersionPreview
+}
+
+func IsSupportedCodebookFormat(format string) bool {
+	switch format {
+	case CODEBOOK_FORMAT_CSV, CODEBOOK_FORMAT_DDI, CODEBOOK_FORMAT_MARKDOWN, CODEBOOK_FORMAT_HTML:
+		return true
+	}
+	return false
+}
+
+// CodebookFileExtension returns the file extension (with dot) for codebooks of the given format
+func CodebookFileExtension(format string) string {
+	switch format {
+	case CODEBOOK_FORMAT_DDI:
+		return ".xml"
+	case CODEBOOK_FORMAT_MARKDOWN:
+		return ".md"
+	case CODEBOOK_FORMAT_HTML:
+		return ".html"
+	default:
+		return ".csv"
+	}
+}
+
+func CodebookContentType(format string) string {
+	switch format {
+	case CODEBOOK_FORMAT_DDI:
+		return "application/xml"
+	case CODEBOOK_FORMAT_MARKDOWN:
+		return "text/markdown; charset=utf-8"
+	case CODEBOOK_FORMAT_HTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "text/csv"
+	}
+}
+
+// SelectSurveyVersion returns the version with the given ID, or the most recently published version if versionID is empty
+func SelectSurveyVersion(surveyInfos []SurveyVersionPreview, versionID string) (SurveyVersionPreview, bool) {
+	if len(surveyInfos) == 0 {
+		return SurveyVersionPreview{}, false
+	}
+	if versionID != "" {
+		i := slices.IndexFunc(surveyInfos, func(v SurveyVersionPreview) bool { return v.VersionID == versionID })
+		if i < 0 {
+			return SurveyVersionPreview{}, false
+		}
+		return surveyInfos[i], true
+	}
+	latest := surveyInfos[0]
+	for _, v := range surveyInfos[1:] {
+		if v.Published > latest.Published {
+			latest = v
+		}
+	}
+	return latest, true
+}
+
+// Write renders the codebook in the format
+func (cb Codebook) Write(w io.Writer, format string) error {
+	switch format {
+	case CODEBOOK_FORMAT_CSV:
+		return cb.writeCSV(w)
+	case CODEBOOK_FORMAT_DDI:
+		return cb.writeDDI(w)
+	case CODEBOOK_FORMAT_MARKDOWN:
+		return cb.writeMarkdown(w)
+	case CODEBOOK_FORMAT_HTML:
+		return codebookHTMLTemplate.Execute(w, cb)
+	default:
+		return fmt.Errorf("unsupported codebook format: %s", format)
+	}
+}
+
+func (cb Codebook) published() string {
+	if cb.Version.Published <= 0 {
+		return ""
+	}
+	return time.Unix(cb.Version.Published, 0).UTC().Format("2006-01-02")
+}
+
+func (cb Codebook) writeCSV(writer io.Writer) error {
+	w := csv.NewWriter(writer)
+	err := w.Write([]string{
+		"surveyKey", "versionID", "published", "questionKey", "questionText", "questionType",
+		"responseKey", "responseType", "responseLabel", "valueCode", "valueType", "valueLabel",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, question := range cb.Version.Questions {
+		questionCols := []string{cb.SurveyKey, cb.Version.VersionID, cb.published(), question.ID, question.Title, question.QuestionType}
+		for _, slot := range question.Responses {
+			slotCols := append(slices.Clone(questionCols), slot.ID, slot.ResponseType, slot.Label)
+			if len(slot.Options) == 0 {
+				if err := w.Write(append(slotCols, "", "", "")); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, option := range slot.Options {
+				if err := w.Write(append(slices.Clone(slotCols), option.ID, option.OptionType, option.Label)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+type ddiCodeBook struct {
+	XMLName  xml.Name    `xml:"codeBook"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Version  string      `xml:"version,attr"`
+	ID       string      `xml:"ID,attr"`
+	StdyDscr ddiStdyDscr `xml:"stdyDscr"`
+	DataDscr ddiDataDscr `xml:"dataDscr"`
+}
+
+type ddiStdyDscr struct {
+	Title   string `xml:"citation>titlStmt>titl"`
+	IDNo    string `xml:"citation>titlStmt>IDNo"`
+	Version struct {
+		Value string `xml:",chardata"`
+		Date  string `xml:"date,attr,omitempty"`
+	} `xml:"citation>verStmt>version"`
+}
+
+type ddiDataDscr struct {
+	Vars []ddiVar `xml:"var"`
+}
+
+type ddiVar struct {
+	Name      string        `xml:"name,attr"`
+	ID        string        `xml:"ID,attr"`
+	Labl      string        `xml:"labl,omitempty"`
+	QstnLit   string        `xml:"qstn>qstnLit,omitempty"`
+	Catgry    []ddiCategory `xml:"catgry,omitempty"`
+	VarFormat struct {
+		Type string `xml:"type,attr"`
+	} `xml:"varFormat"`
+	Notes string `xml:"notes,omitempty"`
+}
+
+type ddiCategory struct {
+	CatValu string `xml:"catValu"`
+	Labl    string `xml:"labl,omitempty"`
+}
+
+func (cb Codebook) writeDDI(w io.Writer) error {
+	doc := ddiCodeBook{
+		Xmlns:   "ddi:codebook:2_5",
+		Version: "2.5",
+		ID:      xmlID(cb.SurveyKey + "_" + cb.Version.VersionID),
+	}
+	doc.StdyDscr.Title = cb.SurveyKey
+	doc.StdyDscr.IDNo = cb.StudyKey
+	doc.StdyDscr.Version.Value = cb.Version.VersionID
+	doc.StdyDscr.Version.Date = cb.published()
+
+	for _, question := range cb.Version.Questions {
+		for _, slot := range question.Responses {
+			name := question.ID + "." + slot.ID
+			v := ddiVar{
+				Name:    name,
+				ID:      xmlID(name),
+				Labl:    strings.TrimSpace(question.Title + " " + slot.Label),
+				QstnLit: question.Title,
+				Notes:   "questionType: " + question.QuestionType + ", responseType: " + slot.ResponseType,
+			}
+			v.VarFormat.Type = "character"
+			if slot.ResponseType == QUESTION_TYPE_NUMBER_INPUT || slot.ResponseType == QUESTION_TYPE_NUMERIC_SLIDER {
+				v.VarFormat.Type = "numeric"
+			}
+			for _, option := range slot.Options {
+				v.Catgry = append(v.Catgry, ddiCategory{CatValu: option.ID, Labl: option.Label})
+			}
+			doc.DataDscr.Vars = append(doc.DataDscr.Vars, v)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// xmlID turns a key into a valid XML ID (letters, digits, "_", "-" and ".", not starting with a digit)
+func xmlID(key string) string {
+	id := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, key)
+	if id == "" || id[0] >= '0' && id[0] <= '9' || id[0] == '-' || id[0] == '.' {
+		id = "_" + id
+	}
+	return id
+}
+
+func (cb Codebook) writeMarkdown(w io.Writer) error {
+	md := strings.Builder{}
+	md.WriteString("# Codebook: " + mdEscape(cb.SurveyKey) + "\n\n")
+	md.WriteString("- Study: " + mdEscape(cb.StudyKey) + "\n")
+	md.WriteString("- Version: " + mdEscape(cb.Version.VersionID) + "\n")
+	if published := cb.published(); published != "" {
+		md.WriteString("- Published: " + published + "\n")
+	}
+
+	for _, question := range cb.Version.Questions {
+		md.WriteString("\n## " + mdEscape(question.ID) + "\n\n")
+		if question.Title != "" {
+			md.WriteString(mdEscape(question.Title) + "\n\n")
+		}
+		md.WriteString("Type: `" + question.QuestionType + "`\n")
+		if len(question.Responses) == 0 {
+			continue
+		}
+		md.WriteString("\n| Response | Type | Code | Label |\n|---|---|---|---|\n")
+		for _, slot := range question.Responses {
+			if len(slot.Options) == 0 {
+				md.WriteString("| " + mdCell(slot.ID) + " | " + mdCell(slot.ResponseType) + " | | " + mdCell(slot.Label) + " |\n")
+				continue
+			}
+			for _, option := range slot.Options {
+				md.WriteString("| " + mdCell(slot.ID) + " | " + mdCell(option.OptionType) + " | " + mdCell(option.ID) + " | " + mdCell(option.Label) + " |\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, md.String())
+	return err
+}
+
+var mdEscaper = strings.NewReplacer(
+	"\\", "\\\\", "*", "\\*", "_", "\\_", "`", "\\`", "#", "\\#", "<", "&lt;", ">", "&gt;", "[", "\\[", "]", "\\]",
+)
+
+func mdEscape(s string) string {
+	return mdEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+// mdCell escapes text for a table cell, where pipes end the cell
+func mdCell(s string) string {
+	return strings.ReplaceAll(mdEscape(s), "|", "\\|")
+}
+
+var codebookHTMLTemplate = template.Must(template.New("codebook").Funcs(template.FuncMap{
+	"published": func(cb Codebook) string { return cb.published() },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Codebook: {{.SurveyKey}} ({{.Version.VersionID}})</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Codebook: {{.SurveyKey}}</h1>
+<ul>
+<li>Study: {{.StudyKey}}</li>
+<li>Version: {{.Version.VersionID}}</li>
+{{- with published .}}
+<li>Published: {{.}}</li>
+{{- end}}
+</ul>
+{{- range .Version.Questions}}
+<section id="{{.ID}}">
+<h2>{{.ID}}</h2>
+{{- if .Title}}
+<p>{{.Title}}</p>
+{{- end}}
+<p>Type: <code>{{.QuestionType}}</code></p>
+{{- if .Responses}}
+<table>
+<tr><th>Response</th><th>Type</th><th>Code</th><th>Label</th></tr>
+{{- range $slot := .Responses}}
+{{- if $slot.Options}}
+{{- range $slot.Options}}
+<tr><td>{{$slot.ID}}</td><td>{{.OptionType}}</td><td>{{.ID}}</td><td>{{.Label}}</td></tr>
+{{- end}}
+{{- else}}
+<tr><td>{{$slot.ID}}</td><td>{{$slot.ResponseType}}</td><td></td><td>{{$slot.Label}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
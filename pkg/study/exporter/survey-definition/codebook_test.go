@@ -0,0 +1,148 @@
+package surveydefinition
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testCodebook() Codebook {
+	return Codebook{
+		StudyKey:  "study1",
+		SurveyKey: "intake",
+		Version: SurveyVersionPreview{
+			VersionID: "v2",
+			Published: 1710510330,
+			Questions: []SurveyQuestion{
+				{ID: "intake.Q1", Title: "Do you smoke? <b>|</b>", QuestionType: QUESTION_TYPE_SINGLE_CHOICE, Responses: []ResponseDef{
+					{ID: "scg", ResponseType: QUESTION_TYPE_SINGLE_CHOICE, Options: []ResponseOption{
+						{ID: "1", OptionType: OPTION_TYPE_RADIO, Label: "Yes"},
+						{ID: "2", OptionType: OPTION_TYPE_RADIO, Label: "No"},
+					}},
+				}},
+				{ID: "intake.Q2", Title: "Age", QuestionType: QUESTION_TYPE_NUMBER_INPUT, Responses: []ResponseDef{
+					{ID: "number", ResponseType: QUESTION_TYPE_NUMBER_INPUT, Label: "years"},
+				}},
+			},
+		},
+	}
+}
+
+func TestCodebook(t *testing.T) {
+	cb := testCodebook()
+
+	t.Run("csv", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		if err := cb.Write(out, CODEBOOK_FORMAT_CSV); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(out).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 4 {
+			t.Fatalf("expected header and 3 lines, got %v", records)
+		}
+		if strings.Join(records[1], ",") != "intake,v2,2024-03-15,intake.Q1,Do you smoke? <b>|</b>,single_choice,scg,single_choice,,1,radio,Yes" {
+			t.Errorf("unexpected line: %v", records[1])
+		}
+		if strings.Join(records[3], ",") != "intake,v2,2024-03-15,intake.Q2,Age,number,number,number,years,,," {
+			t.Errorf("unexpected line: %v", records[3])
+		}
+	})
+
+	t.Run("ddi", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		if err := cb.Write(out, CODEBOOK_FORMAT_DDI); err != nil {
+			t.Fatal(err)
+		}
+		doc := ddiCodeBook{}
+		if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, out.String())
+		}
+		if doc.StdyDscr.IDNo != "study1" || doc.StdyDscr.Version.Value != "v2" || len(doc.DataDscr.Vars) != 2 {
+			t.Fatalf("unexpected document: %+v", doc)
+		}
+		q1 := doc.DataDscr.Vars[0]
+		if q1.Name != "intake.Q1.scg" || len(q1.Catgry) != 2 || q1.Catgry[1].CatValu != "2" || q1.Catgry[1].Labl != "No" || q1.VarFormat.Type != "character" {
+			t.Errorf("unexpected variable: %+v", q1)
+		}
+		if doc.DataDscr.Vars[1].VarFormat.Type != "numeric" {
+			t.Errorf("number input should be numeric: %+v", doc.DataDscr.Vars[1])
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		if err := cb.Write(out, CODEBOOK_FORMAT_MARKDOWN); err != nil {
+			t.Fatal(err)
+		}
+		md := out.String()
+		for _, expected := range []string{
+			"# Codebook: intake\n",
+			"## intake.Q1\n",
+			"Do you smoke? &lt;b&gt;|&lt;/b&gt;\n",
+			"| scg | radio | 1 | Yes |\n",
+			"| number | number | | years |\n",
+		} {
+			if !strings.Contains(md, expected) {
+				t.Errorf("expected %q in:\n%s", expected, md)
+			}
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		if err := cb.Write(out, CODEBOOK_FORMAT_HTML); err != nil {
+			t.Fatal(err)
+		}
+		html := out.String()
+		if strings.Contains(html, "<b>") || !strings.Contains(html, "Do you smoke? &lt;b&gt;|&lt;/b&gt;") {
+			t.Errorf("labels should be escaped:\n%s", html)
+		}
+		if !strings.Contains(html, "<tr><td>scg</td><td>radio</td><td>2</td><td>No</td></tr>") {
+			t.Errorf("missing option row:\n%s", html)
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		if err := cb.Write(&bytes.Buffer{}, "pdf"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestSelectSurveyVersion(t *testing.T) {
+	versions := []SurveyVersionPreview{
+		{VersionID: "v1", Published: 100},
+		{VersionID: "v3", Published: 300},
+		{VersionID: "v2", Published: 200},
+	}
+	if v, ok := SelectSurveyVersion(versions, ""); !ok || v.VersionID != "v3" {
+		t.Errorf("expected latest version, got %v", v.VersionID)
+	}
+	if v, ok := SelectSurveyVersion(versions, "v2"); !ok || v.VersionID != "v2" {
+		t.Errorf("expected v2, got %v", v.VersionID)
+	}
+	if _, ok := SelectSurveyVersion(versions, "v9"); ok {
+		t.Error("unknown version should not be found")
+	}
+	if _, ok := SelectSurveyVersion(nil, ""); ok {
+		t.Error("no versions should not be found")
+	}
+}
+
+func TestXMLID(t *testing.T) {
+	for key, expected := range map[string]string{
+		"intake.Q1": "intake.Q1",
+		"1abc":      "_1abc",
+		"a b/c":     "a_b_c",
+		"":          "_",
+	} {
+		if id := xmlID(key); id != expected {
+			t.Errorf("xmlID(%q) = %q, expected %q", key, id, expected)
+		}
+	}
+}
@@ -542,6 +542,10 @@ func mapToResponseDef(rItem *studytypes.ItemComponent, lang string) []ResponseDe
 		}
 		return responses
 	default:
+		if isCustomResponseType(itemRole) {
+			responseDef.ResponseType = itemRole
+			return []ResponseDef{responseDef}
+		}
 		if roleSeparatorIndex > 0 {
 			responseDef.ResponseType = QUESTION_TYPE_UNKNOWN
 			return []ResponseDef{responseDef}
@@ -0,0 +1,22 @@
+package surveydefinition
+
+import "sync"
+
+var (
+	customResponseTypesMu sync.RWMutex
+	customResponseTypes   = map[string]bool{}
+)
+
+// RegisterCustomResponseType makes response components with the given role (e.g. a custom item component of a deployment)
+// appear in the survey definition as responses of the same type, instead of being ignored.
+func RegisterCustomResponseType(role string) {
+	customResponseTypesMu.Lock()
+	defer customResponseTypesMu.Unlock()
+	customResponseTypes[role] = true
+}
+
+func isCustomResponseType(role string) bool {
+	customResponseTypesMu.RLock()
+	defer customResponseTypesMu.RUnlock()
+	return customResponseTypes[role]
+}
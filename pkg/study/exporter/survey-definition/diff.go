@@ -0,0 +1,219 @@
+package surveydefinition
+
+const (
+	SURVEY_DIFF_ADDED   = "added"
+	SURVEY_DIFF_REMOVED = "removed"
+	SURVEY_DIFF_CHANGED = "changed"
+)
+
+// SurveyVersionDiff lists the questions, responses and options that differ between two survey versions
+type SurveyVersionDiff struct {
+	FromVersionID   string         `json:"fromVersionId"`
+	ToVersionID     string         `json:"toVersionId"`
+	HasChanges      bool           `json:"hasChanges"`
+	Questions       []QuestionDiff `json:"questions"`
+	PossibleRenames []KeyRename    `json:"possibleRenames"`
+}
+
+type QuestionDiff struct {
+	ID              string         `json:"id"`
+	Status          string         `json:"status"`
+	OldQuestionType string         `json:"oldQuestionType,omitempty"`
+	NewQuestionType string         `json:"newQuestionType,omitempty"`
+	OldTitle        string         `json:"oldTitle,omitempty"`
+	NewTitle        string         `json:"newTitle,omitempty"`
+	Responses       []ResponseDiff `json:"responses,omitempty"`
+}
+
+type ResponseDiff struct {
+	ID              string       `json:"id"`
+	Status          string       `json:"status"`
+	OldResponseType string       `json:"oldResponseType,omitempty"`
+	NewResponseType string       `json:"newResponseType,omitempty"`
+	OldLabel        string       `json:"oldLabel,omitempty"`
+	NewLabel        string       `json:"newLabel,omitempty"`
+	Options         []OptionDiff `json:"options,omitempty"`
+}
+
+type OptionDiff struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	OldOptionType string `json:"oldOptionType,omitempty"`
+	NewOptionType string `json:"newOptionType,omitempty"`
+	OldLabel      string `json:"oldLabel,omitempty"`
+	NewLabel      string `json:"newLabel,omitempty"`
+}
+
+// KeyRename is a removed question that has the same structure as an added one, which is often an accidental key change
+type KeyRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DiffSurveyVersions compares the questions of two survey version previews. Questions, responses and options are matched by their keys,
+// so a renamed key shows up as removed and added. Titles and labels are only compared if the previews were extracted with a label language.
+func DiffSurveyVersions(from SurveyVersionPreview, to SurveyVersionPreview) SurveyVersionDiff {
+	diff := SurveyVersionDiff{
+		FromVersionID:   from.VersionID,
+		ToVersionID:     to.VersionID,
+		Questions:       []QuestionDiff{},
+		PossibleRenames: []KeyRename{},
+	}
+
+	oldQuestions := map[string]SurveyQuestion{}
+	for _, q := range from.Questions {
+		oldQuestions[q.ID] = q
+	}
+	newQuestions := map[string]SurveyQuestion{}
+	for _, q := range to.Questions {
+		newQuestions[q.ID] = q
+	}
+
+	removed := []SurveyQuestion{}
+	for _, q := range from.Questions {
+		if _, ok := newQuestions[q.ID]; !ok {
+			diff.Questions = append(diff.Questions, QuestionDiff{ID: q.ID, Status: SURVEY_DIFF_REMOVED, OldQuestionType: q.QuestionType, OldTitle: q.Title})
+			removed = append(removed, q)
+		}
+	}
+
+	added := []SurveyQuestion{}
+	for _, q := range to.Questions {
+		oldQ, ok := oldQuestions[q.ID]
+		if !ok {
+			diff.Questions = append(diff.Questions, QuestionDiff{ID: q.ID, Status: SURVEY_DIFF_ADDED, NewQuestionType: q.QuestionType, NewTitle: q.Title})
+			added = append(added, q)
+			continue
+		}
+		if qDiff, changed := diffQuestion(oldQ, q); changed {
+			diff.Questions = append(diff.Questions, qDiff)
+		}
+	}
+
+	for _, r := range removed {
+		for _, a := range added {
+			if haveSameStructure(r, a) {
+				diff.PossibleRenames = append(diff.PossibleRenames, KeyRename{From: r.ID, To: a.ID})
+			}
+		}
+	}
+
+	diff.HasChanges = len(diff.Questions) > 0
+	return diff
+}
+
+func diffQuestion(oldQ SurveyQuestion, newQ SurveyQuestion) (QuestionDiff, bool) {
+	qDiff := QuestionDiff{
+		ID:     newQ.ID,
+		Status: SURVEY_DIFF_CHANGED,
+	}
+	changed := false
+	if oldQ.QuestionType != newQ.QuestionType {
+		qDiff.OldQuestionType = oldQ.QuestionType
+		qDiff.NewQuestionType = newQ.QuestionType
+		changed = true
+	}
+	if oldQ.Title != newQ.Title {
+		qDiff.OldTitle = oldQ.Title
+		qDiff.NewTitle = newQ.Title
+		changed = true
+	}
+
+	oldResponses := map[string]ResponseDef{}
+	for _, r := range oldQ.Responses {
+		oldResponses[r.ID] = r
+	}
+	newResponses := map[string]ResponseDef{}
+	for _, r := range newQ.Responses {
+		newResponses[r.ID] = r
+	}
+	for _, r := range oldQ.Responses {
+		if _, ok := newResponses[r.ID]; !ok {
+			qDiff.Responses = append(qDiff.Responses, ResponseDiff{ID: r.ID, Status: SURVEY_DIFF_REMOVED, OldResponseType: r.ResponseType, OldLabel: r.Label})
+		}
+	}
+	for _, r := range newQ.Responses {
+		oldR, ok := oldResponses[r.ID]
+		if !ok {
+			qDiff.Responses = append(qDiff.Responses, ResponseDiff{ID: r.ID, Status: SURVEY_DIFF_ADDED, NewResponseType: r.ResponseType, NewLabel: r.Label})
+			continue
+		}
+		if rDiff, rChanged := diffResponse(oldR, r); rChanged {
+			qDiff.Responses = append(qDiff.Responses, rDiff)
+		}
+	}
+
+	return qDiff, changed || len(qDiff.Responses) > 0
+}
+
+func diffResponse(oldR ResponseDef, newR ResponseDef) (ResponseDiff, bool) {
+	rDiff := ResponseDiff{
+		ID:     newR.ID,
+		Status: SURVEY_DIFF_CHANGED,
+	}
+	changed := false
+	if oldR.ResponseType != newR.ResponseType {
+		rDiff.OldResponseType = oldR.ResponseType
+		rDiff.NewResponseType = newR.ResponseType
+		changed = true
+	}
+	if oldR.Label != newR.Label {
+		rDiff.OldLabel = oldR.Label
+		rDiff.NewLabel = newR.Label
+		changed = true
+	}
+
+	oldOptions := map[string]ResponseOption{}
+	for _, o := range oldR.Options {
+		oldOptions[o.ID] = o
+	}
+	newOptions := map[string]ResponseOption{}
+	for _, o := range newR.Options {
+		newOptions[o.ID] = o
+	}
+	for _, o := range oldR.Options {
+		if _, ok := newOptions[o.ID]; !ok {
+			rDiff.Options = append(rDiff.Options, OptionDiff{ID: o.ID, Status: SURVEY_DIFF_REMOVED, OldOptionType: o.OptionType, OldLabel: o.Label})
+		}
+	}
+	for _, o := range newR.Options {
+		oldO, ok := oldOptions[o.ID]
+		if !ok {
+			rDiff.Options = append(rDiff.Options, OptionDiff{ID: o.ID, Status: SURVEY_DIFF_ADDED, NewOptionType: o.OptionType, NewLabel: o.Label})
+			continue
+		}
+		if oldO.OptionType != o.OptionType || oldO.Label != o.Label {
+			oDiff := OptionDiff{ID: o.ID, Status: SURVEY_DIFF_CHANGED}
+			if oldO.OptionType != o.OptionType {
+				oDiff.OldOptionType = oldO.OptionType
+				oDiff.NewOptionType = o.OptionType
+			}
+			if oldO.Label != o.Label {
+				oDiff.OldLabel = oldO.Label
+				oDiff.NewLabel = o.Label
+			}
+			rDiff.Options = append(rDiff.Options, oDiff)
+		}
+	}
+
+	return rDiff, changed || len(rDiff.Options) > 0
+}
+
+// haveSameStructure checks if two questions have the same type, responses and options, ignoring the question key and labels
+func haveSameStructure(a SurveyQuestion, b SurveyQuestion) bool {
+	if a.QuestionType != b.QuestionType || len(a.Responses) != len(b.Responses) {
+		return false
+	}
+	for i, r := range a.Responses {
+		other := b.Responses[i]
+		if r.ID != other.ID || r.ResponseType != other.ResponseType || len(r.Options) != len(other.Options) {
+			return false
+		}
+		for j, o := range r.Options {
+			if o.ID != other.Options[j].ID || o.OptionType != other.Options[j].OptionType {
+				return false
+			}
+		}
+	}
+	return true
+}
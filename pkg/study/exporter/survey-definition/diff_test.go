@@ -0,0 +1,89 @@
+package surveydefinition
+
+import (
+	"testing"
+)
+
+func TestDiffSurveyVersions(t *testing.T) {
+	from := SurveyVersionPreview{
+		VersionID: "v1",
+		Questions: []SurveyQuestion{
+			{ID: "S.Q1", QuestionType: QUESTION_TYPE_SINGLE_CHOICE, Responses: []ResponseDef{
+				{ID: "scg", ResponseType: QUESTION_TYPE_SINGLE_CHOICE, Options: []ResponseOption{
+					{ID: "1", OptionType: OPTION_TYPE_RADIO},
+					{ID: "2", OptionType: OPTION_TYPE_RADIO},
+				}},
+			}},
+			{ID: "S.Q2", QuestionType: QUESTION_TYPE_TEXT_INPUT, Responses: []ResponseDef{
+				{ID: "input", ResponseType: QUESTION_TYPE_TEXT_INPUT},
+			}},
+			{ID: "S.Q3", QuestionType: QUESTION_TYPE_NUMBER_INPUT, Responses: []ResponseDef{
+				{ID: "number", ResponseType: QUESTION_TYPE_NUMBER_INPUT},
+			}},
+		},
+	}
+
+	t.Run("same version", func(t *testing.T) {
+		diff := DiffSurveyVersions(from, from)
+		if diff.HasChanges || len(diff.Questions) != 0 || len(diff.PossibleRenames) != 0 {
+			t.Errorf("unexpected diff: %+v", diff)
+		}
+	})
+
+	t.Run("changed version", func(t *testing.T) {
+		to := SurveyVersionPreview{
+			VersionID: "v2",
+			Questions: []SurveyQuestion{
+				{ID: "S.Q1", QuestionType: QUESTION_TYPE_SINGLE_CHOICE, Responses: []ResponseDef{
+					{ID: "scg", ResponseType: QUESTION_TYPE_SINGLE_CHOICE, Options: []ResponseOption{
+						{ID: "1", OptionType: OPTION_TYPE_RADIO},
+						{ID: "3", OptionType: OPTION_TYPE_RADIO},
+					}},
+				}},
+				{ID: "S.Q2b", QuestionType: QUESTION_TYPE_TEXT_INPUT, Responses: []ResponseDef{
+					{ID: "input", ResponseType: QUESTION_TYPE_TEXT_INPUT},
+				}},
+				{ID: "S.Q3", QuestionType: QUESTION_TYPE_TEXT_INPUT, Responses: []ResponseDef{
+					{ID: "number", ResponseType: QUESTION_TYPE_TEXT_INPUT},
+				}},
+			},
+		}
+
+		diff := DiffSurveyVersions(from, to)
+		if !diff.HasChanges || diff.FromVersionID != "v1" || diff.ToVersionID != "v2" {
+			t.Errorf("unexpected diff: %+v", diff)
+		}
+		if len(diff.Questions) != 4 {
+			t.Fatalf("unexpected question diffs: %+v", diff.Questions)
+		}
+
+		if diff.Questions[0].ID != "S.Q2" || diff.Questions[0].Status != SURVEY_DIFF_REMOVED {
+			t.Errorf("expected S.Q2 removed: %+v", diff.Questions[0])
+		}
+
+		q1 := diff.Questions[1]
+		if q1.ID != "S.Q1" || q1.Status != SURVEY_DIFF_CHANGED || len(q1.Responses) != 1 {
+			t.Fatalf("expected S.Q1 changed: %+v", q1)
+		}
+		options := q1.Responses[0].Options
+		if len(options) != 2 || options[0].ID != "2" || options[0].Status != SURVEY_DIFF_REMOVED || options[1].ID != "3" || options[1].Status != SURVEY_DIFF_ADDED {
+			t.Errorf("unexpected option diffs: %+v", options)
+		}
+
+		if diff.Questions[2].ID != "S.Q2b" || diff.Questions[2].Status != SURVEY_DIFF_ADDED {
+			t.Errorf("expected S.Q2b added: %+v", diff.Questions[2])
+		}
+
+		q3 := diff.Questions[3]
+		if q3.ID != "S.Q3" || q3.OldQuestionType != QUESTION_TYPE_NUMBER_INPUT || q3.NewQuestionType != QUESTION_TYPE_TEXT_INPUT {
+			t.Errorf("expected S.Q3 type change: %+v", q3)
+		}
+		if len(q3.Responses) != 1 || q3.Responses[0].NewResponseType != QUESTION_TYPE_TEXT_INPUT {
+			t.Errorf("expected S.Q3 response type change: %+v", q3.Responses)
+		}
+
+		if len(diff.PossibleRenames) != 1 || diff.PossibleRenames[0] != (KeyRename{From: "S.Q2", To: "S.Q2b"}) {
+			t.Errorf("unexpected possible renames: %+v", diff.PossibleRenames)
+		}
+	})
+}
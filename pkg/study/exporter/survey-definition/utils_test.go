@@ -541,3 +541,24 @@ func mockResponsiveSingleChoiceArray(lang string, categoryLabels []mockOpionDef,
 	}
 	return rg
 }
+
+func TestCustomResponseType(t *testing.T) {
+	rg := &studytypes.ItemComponent{Key: "rg", Role: "responseGroup", Items: []studytypes.ItemComponent{
+		{Key: "w", Role: "testMapWidget"},
+	}}
+
+	t.Run("not registered", func(t *testing.T) {
+		_, qType := extractResponses(rg, "en")
+		if qType != QUESTION_TYPE_EMPTY {
+			t.Errorf("unexpected question type: %s", qType)
+		}
+	})
+
+	t.Run("registered", func(t *testing.T) {
+		RegisterCustomResponseType("testMapWidget")
+		responses, qType := extractResponses(rg, "en")
+		if qType != "testMapWidget" || len(responses) != 1 || responses[0].ID != "w" {
+			t.Errorf("unexpected result: %s %v", qType, responses)
+		}
+	})
+}
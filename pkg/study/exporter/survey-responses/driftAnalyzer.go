@@ -0,0 +1,270 @@
+package surveyresponses
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// DriftReport summarises differences between the survey definitions and the responses actually received
+type DriftReport struct {
+	ResponseCount          int             `json:"responseCount"`
+	NeverAnsweredQuestions []string        `json:"neverAnsweredQuestions"`
+	UnknownQuestionKeys    []KeyOccurrence `json:"unknownQuestionKeys"`
+	UnknownResponseKeys    []KeyOccurrence `json:"unknownResponseKeys"`
+	TypeMismatches         []TypeMismatch  `json:"typeMismatches"`
+}
+
+type KeyOccurrence struct {
+	QuestionKey       string `json:"questionKey"`
+	ResponseKey       string `json:"responseKey,omitempty"`
+	Count             int    `json:"count"`
+	ExampleResponseID string `json:"exampleResponseId"`
+}
+
+type TypeMismatch struct {
+	QuestionKey       string `json:"questionKey"`
+	ResponseKey       string `json:"responseKey"`
+	ExpectedType      string `json:"expectedType"`
+	ExampleValue      string `json:"exampleValue"`
+	Count             int    `json:"count"`
+	ExampleResponseID string `json:"exampleResponseId"`
+}
+
+type DriftAnalyzer struct {
+	versions []sd.SurveyVersionPreview
+
+	// question key -> known response slot paths (union over all versions)
+	knownKeys map[string]map[string]bool
+	// question key -> definition contains slots that cannot be checked
+	uncheckable map[string]bool
+	answered    map[string]bool
+
+	responseCount  int
+	unknownQKeys   map[string]*KeyOccurrence
+	unknownRKeys   map[string]*KeyOccurrence
+	typeMismatches map[string]*TypeMismatch
+}
+
+func NewDriftAnalyzer(versions []sd.SurveyVersionPreview) *DriftAnalyzer {
+	a := &DriftAnalyzer{
+		versions:       versions,
+		knownKeys:      map[string]map[string]bool{},
+		uncheckable:    map[string]bool{},
+		answered:       map[string]bool{},
+		unknownQKeys:   map[string]*KeyOccurrence{},
+		unknownRKeys:   map[string]*KeyOccurrence{},
+		typeMismatches: map[string]*TypeMismatch{},
+	}
+
+	for _, v := range versions {
+		for _, q := range v.Questions {
+			if _, ok := a.knownKeys[q.ID]; !ok {
+				a.knownKeys[q.ID] = map[string]bool{}
+			}
+			for _, rDef := range q.Responses {
+				if rDef.ResponseType == sd.QUESTION_TYPE_UNKNOWN {
+					a.uncheckable[q.ID] = true
+				}
+				addPathWithPrefixes(a.knownKeys[q.ID], rDef.ID)
+				for _, o := range rDef.Options {
+					addPathWithPrefixes(a.knownKeys[q.ID], rDef.ID+"."+o.ID)
+				}
+			}
+		}
+	}
+	return a
+}
+
+func addPathWithPrefixes(set map[string]bool, path string) {
+	parts := strings.Split(path, ".")
+	for i := range parts {
+		set[strings.Join(parts[:i+1], ".")] = true
+	}
+}
+
+// AddResponse checks a single response against the survey definitions
+func (a *DriftAnalyzer) AddResponse(r *studytypes.SurveyResponse) {
+	a.responseCount += 1
+	responseID := r.ID.Hex()
+
+	var version *sd.SurveyVersionPreview
+	if v, err := findSurveyVersion(r.VersionID, r.SubmittedAt, a.versions); err == nil {
+		version = &v
+	}
+
+	for _, item := range flattenItemResponses(r.Responses) {
+		if item.Response == nil {
+			continue
+		}
+
+		known, ok := a.knownKeys[item.Key]
+		if !ok {
+			occ, exists := a.unknownQKeys[item.Key]
+			if !exists {
+				occ = &KeyOccurrence{QuestionKey: item.Key, ExampleResponseID: responseID}
+				a.unknownQKeys[item.Key] = occ
+			}
+			occ.Count += 1
+			continue
+		}
+		a.answered[item.Key] = true
+
+		for path, value := range flattenResponseItem(item.Response) {
+			if !known[path] && !a.uncheckable[item.Key] {
+				id := item.Key + "|" + path
+				occ, exists := a.unknownRKeys[id]
+				if !exists {
+					occ = &KeyOccurrence{QuestionKey: item.Key, ResponseKey: path, ExampleResponseID: responseID}
+					a.unknownRKeys[id] = occ
+				}
+				occ.Count += 1
+				continue
+			}
+
+			if version == nil || value == "" {
+				continue
+			}
+			expectedType := findExpectedValueType(*version, item.Key, path)
+			if expectedType == "" || valueMatchesType(value, expectedType) {
+				continue
+			}
+			id := item.Key + "|" + path
+			m, exists := a.typeMismatches[id]
+			if !exists {
+				m = &TypeMismatch{
+					QuestionKey:       item.Key,
+					ResponseKey:       path,
+					ExpectedType:      expectedType,
+					ExampleValue:      value,
+					ExampleResponseID: responseID,
+				}
+				a.typeMismatches[id] = m
+			}
+			m.Count += 1
+		}
+	}
+}
+
+// Report returns the collected findings, sorted by key
+func (a *DriftAnalyzer) Report() DriftReport {
+	report := DriftReport{
+		ResponseCount:          a.responseCount,
+		NeverAnsweredQuestions: []string{},
+		UnknownQuestionKeys:    []KeyOccurrence{},
+		UnknownResponseKeys:    []KeyOccurrence{},
+		TypeMismatches:         []TypeMismatch{},
+	}
+
+	for qKey := range a.knownKeys {
+		if !a.answered[qKey] {
+			report.NeverAnsweredQuestions = append(report.NeverAnsweredQuestions, qKey)
+		}
+	}
+	sort.Strings(report.NeverAnsweredQuestions)
+
+	for _, occ := range a.unknownQKeys {
+		report.UnknownQuestionKeys = append(report.UnknownQuestionKeys, *occ)
+	}
+	for _, occ := range a.unknownRKeys {
+		report.UnknownResponseKeys = append(report.UnknownResponseKeys, *occ)
+	}
+	for _, m := range a.typeMismatches {
+		report.TypeMismatches = append(report.TypeMismatches, *m)
+	}
+
+	sort.Slice(report.UnknownQuestionKeys, func(i, j int) bool {
+		return report.UnknownQuestionKeys[i].QuestionKey < report.UnknownQuestionKeys[j].QuestionKey
+	})
+	sort.Slice(report.UnknownResponseKeys, func(i, j int) bool {
+		x, y := report.UnknownResponseKeys[i], report.UnknownResponseKeys[j]
+		return x.QuestionKey+"|"+x.ResponseKey < y.QuestionKey+"|"+y.ResponseKey
+	})
+	sort.Slice(report.TypeMismatches, func(i, j int) bool {
+		x, y := report.TypeMismatches[i], report.TypeMismatches[j]
+		return x.QuestionKey+"|"+x.ResponseKey < y.QuestionKey+"|"+y.ResponseKey
+	})
+	return report
+}
+
+func flattenItemResponses(items []studytypes.SurveyItemResponse) []studytypes.SurveyItemResponse {
+	result := []studytypes.SurveyItemResponse{}
+	for _, item := range items {
+		if len(item.Items) > 0 {
+			result = append(result, flattenItemResponses(item.Items)...)
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// flattenResponseItem returns the dot separated paths of all response slots below the response root, mapped to their values
+func flattenResponseItem(root *studytypes.ResponseItem) map[string]string {
+	paths := map[string]string{}
+	var walk func(item *studytypes.ResponseItem, prefix string)
+	walk = func(item *studytypes.ResponseItem, prefix string) {
+		if item == nil {
+			return
+		}
+		path := item.Key
+		if prefix != "" {
+			path = prefix + "." + item.Key
+		}
+		paths[path] = item.Value
+		for _, child := range item.Items {
+			walk(child, path)
+		}
+	}
+	for _, child := range root.Items {
+		walk(child, "")
+	}
+	return paths
+}
+
+func findExpectedValueType(version sd.SurveyVersionPreview, questionKey string, path string) string {
+	for _, q := range version.Questions {
+		if q.ID != questionKey {
+			continue
+		}
+		for _, rDef := range q.Responses {
+			if rDef.ID == path {
+				switch rDef.ResponseType {
+				case sd.QUESTION_TYPE_NUMBER_INPUT, sd.QUESTION_TYPE_NUMERIC_SLIDER, sd.QUESTION_TYPE_MATRIX_NUMBER_INPUT:
+					return sd.QUESTION_TYPE_NUMBER_INPUT
+				case sd.QUESTION_TYPE_DATE_INPUT:
+					return sd.QUESTION_TYPE_DATE_INPUT
+				}
+				return ""
+			}
+			for _, o := range rDef.Options {
+				if rDef.ID+"."+o.ID != path {
+					continue
+				}
+				switch o.OptionType {
+				case sd.OPTION_TYPE_NUMBER_INPUT, sd.OPTION_TYPE_EMBEDDED_CLOZE_NUMBER_INPUT:
+					return sd.QUESTION_TYPE_NUMBER_INPUT
+				case sd.OPTION_TYPE_DATE_INPUT, sd.OPTION_TYPE_EMBEDDED_CLOZE_DATE_INPUT:
+					return sd.QUESTION_TYPE_DATE_INPUT
+				}
+				return ""
+			}
+		}
+	}
+	return ""
+}
+
+func valueMatchesType(value string, expectedType string) bool {
+	switch expectedType {
+	case sd.QUESTION_TYPE_NUMBER_INPUT:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case sd.QUESTION_TYPE_DATE_INPUT:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	}
+	return true
+}
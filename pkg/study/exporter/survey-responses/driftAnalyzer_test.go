@@ -0,0 +1,73 @@
+package surveyresponses
+
+import (
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestDriftAnalyzer(t *testing.T) {
+	versions := []sd.SurveyVersionPreview{
+		{
+			VersionID: "v1",
+			Published: 10,
+			Questions: []sd.SurveyQuestion{
+				{
+					ID: "s.Q1",
+					Responses: []sd.ResponseDef{
+						{ID: "scg", ResponseType: sd.QUESTION_TYPE_SINGLE_CHOICE, Options: []sd.ResponseOption{{ID: "a", OptionType: sd.OPTION_TYPE_RADIO}}},
+					},
+				},
+				{
+					ID: "s.Q2",
+					Responses: []sd.ResponseDef{
+						{ID: "num", ResponseType: sd.QUESTION_TYPE_NUMBER_INPUT},
+					},
+				},
+				{
+					ID: "s.Q3",
+					Responses: []sd.ResponseDef{
+						{ID: "ti", ResponseType: sd.QUESTION_TYPE_TEXT_INPUT},
+					},
+				},
+			},
+		},
+	}
+
+	newResp := func(items ...studytypes.SurveyItemResponse) *studytypes.SurveyResponse {
+		return &studytypes.SurveyResponse{VersionID: "v1", SubmittedAt: 20, Responses: items}
+	}
+	slot := func(qKey string, children ...*studytypes.ResponseItem) studytypes.SurveyItemResponse {
+		return studytypes.SurveyItemResponse{Key: qKey, Response: &studytypes.ResponseItem{Key: "rg", Items: children}}
+	}
+
+	analyzer := NewDriftAnalyzer(versions)
+	analyzer.AddResponse(newResp(
+		slot("s.Q1", &studytypes.ResponseItem{Key: "scg", Items: []*studytypes.ResponseItem{{Key: "b"}}}),
+		slot("s.Q2", &studytypes.ResponseItem{Key: "num", Value: "abc"}),
+		slot("s.Q9", &studytypes.ResponseItem{Key: "ti", Value: "x"}),
+	))
+	analyzer.AddResponse(newResp(
+		slot("s.Q1", &studytypes.ResponseItem{Key: "scg", Items: []*studytypes.ResponseItem{{Key: "a"}}}),
+		slot("s.Q2", &studytypes.ResponseItem{Key: "num", Value: "12.5"}),
+	))
+
+	report := analyzer.Report()
+
+	if report.ResponseCount != 2 {
+		t.Errorf("unexpected response count: %d", report.ResponseCount)
+	}
+	if len(report.NeverAnsweredQuestions) != 1 || report.NeverAnsweredQuestions[0] != "s.Q3" {
+		t.Errorf("unexpected never answered questions: %v", report.NeverAnsweredQuestions)
+	}
+	if len(report.UnknownQuestionKeys) != 1 || report.UnknownQuestionKeys[0].QuestionKey != "s.Q9" {
+		t.Errorf("unexpected unknown question keys: %v", report.UnknownQuestionKeys)
+	}
+	if len(report.UnknownResponseKeys) != 1 || report.UnknownResponseKeys[0].ResponseKey != "scg.b" {
+		t.Errorf("unexpected unknown response keys: %v", report.UnknownResponseKeys)
+	}
+	if len(report.TypeMismatches) != 1 || report.TypeMismatches[0].ResponseKey != "num" || report.TypeMismatches[0].Count != 1 {
+		t.Errorf("unexpected type mismatches: %v", report.TypeMismatches)
+	}
+}
@@ -9,23 +9,82 @@ import (
 	studytypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
+const (
+	EXPORT_FORMAT_WIDE    = "wide"
+	EXPORT_FORMAT_LONG    = "long"
+	EXPORT_FORMAT_JSON    = "json"
+	EXPORT_FORMAT_NDJSON  = "ndjson"
+	EXPORT_FORMAT_PARQUET = "parquet"
+	EXPORT_FORMAT_XLSX    = "xlsx"
+)
+
+type ExportOptions struct {
+	// Format is one of the EXPORT_FORMAT_* values
+	Format string
+	// ParquetRowGroupSize is the number of responses buffered per parquet row group (default 10000)
+	ParquetRowGroupSize int
+	// ValueLabels selects if option labels are exported instead of or alongside option keys, see VALUE_LABELS_*
+	ValueLabels string
+	// Pseudonymization of participant IDs, redaction of columns and truncation of timestamps
+	Pseudonymization PseudonymizationOptions
+	// ParticipantInfo joins participant state (enrollment time, study status, flags) onto each response
+	ParticipantInfo ParticipantInfoOptions
+	// ValidationIssues adds a column with the validation issues found at submission
+	ValidationIssues bool
+}
+
 type ResponseExporter struct {
-	parser    *ResponseParser
-	writer    io.Writer
-	csvWriter *csv.Writer
-	format    string
-	counter   int
+	parser        *ResponseParser
+	writer        io.Writer
+	csvWriter     *csv.Writer
+	parquetWriter *parquetWriter
+	xlsxWriter    *xlsxWriter
+	pseudonymizer *pseudonymizer
+	participants  *participantInfoJoiner
+	format        string
+	options       ExportOptions
+	counter       int
 }
 
 func NewResponseExporter(
 	parser *ResponseParser,
 	writer io.Writer,
 	format string,
+) (*ResponseExporter, error) {
+	return NewResponseExporterWithOptions(parser, writer, ExportOptions{Format: format})
+}
+
+func NewResponseExporterWithOptions(
+	parser *ResponseParser,
+	writer io.Writer,
+	options ExportOptions,
 ) (*ResponseExporter, error) {
 	re := &ResponseExporter{
-		parser: parser,
-		writer: writer,
-		format: format,
+		parser:  parser,
+		writer:  writer,
+		format:  options.Format,
+		options: options,
+	}
+
+	participants, err := newParticipantInfoJoiner(options.ParticipantInfo, parser)
+	if err != nil {
+		return nil, err
+	}
+	re.participants = participants
+	if options.ValidationIssues {
+		addValidationIssuesColumn(parser)
+	}
+
+	if err := parser.UseValueLabels(options.ValueLabels); err != nil {
+		return nil, err
+	}
+	pseudo, err := newPseudonymizer(options.Pseudonymization, parser)
+	if err != nil {
+		return nil, err
+	}
+	re.pseudonymizer = pseudo
+	if err := parser.RedactColumns(options.Pseudonymization.RedactColumns); err != nil {
+		return nil, err
 	}
 
 	if err := re.init(); err != nil {
@@ -40,30 +99,31 @@ func NewResponseExporter(
 func (re *ResponseExporter) init() error {
 	var err error
 	switch re.format {
-	case "wide":
+	case EXPORT_FORMAT_WIDE:
 		re.csvWriter = csv.NewWriter(re.writer)
-		record := []string{}
-		record = append(record, re.parser.columns.FixedColumns...)
-		record = append(record, re.parser.columns.ContextColumns...)
-		record = append(record, re.parser.columns.ResponseColumns...)
-		record = append(record, re.parser.columns.MetaColumns...)
-		err = re.csvWriter.Write(record)
+		err = re.csvWriter.Write(re.parser.columnHeaders(re.parser.columns.allColumns()))
 		if err != nil {
 			return err
 		}
-	case "long":
+	case EXPORT_FORMAT_LONG:
 		re.csvWriter = csv.NewWriter(re.writer)
 		record := []string{}
-		record = append(record, re.parser.columns.FixedColumns...)
-		record = append(record, re.parser.columns.ContextColumns...)
+		record = append(record, re.parser.columnHeaders(re.parser.columns.FixedColumns)...)
+		record = append(record, re.parser.columnHeaders(re.parser.columns.ContextColumns)...)
 		record = append(record, "responseSlot")
 		record = append(record, "value")
 		err = re.csvWriter.Write(record)
 		if err != nil {
 			return err
 		}
-	case "json":
+	case EXPORT_FORMAT_JSON:
 		_, err = re.writer.Write([]byte("{ \"responses\": ["))
+	case EXPORT_FORMAT_NDJSON:
+		// no header, every line is a response
+	case EXPORT_FORMAT_PARQUET:
+		re.parquetWriter, err = newParquetWriter(re.writer, re.parser.columnHeaders(re.parser.columns.allColumns()), re.options.ParquetRowGroupSize)
+	case EXPORT_FORMAT_XLSX:
+		re.xlsxWriter = newXLSXWriter(re.writer, re.parser)
 	default:
 		return fmt.Errorf("unsupported format: %s", re.format)
 	}
@@ -85,9 +145,16 @@ func (re *ResponseExporter) WriteResponse(
 	if err != nil {
 		return err
 	}
+	if err := re.participants.apply(&parsedResp); err != nil {
+		return err
+	}
+	if re.options.ValidationIssues {
+		applyValidationIssues(&parsedResp, rawResp.ValidationIssues)
+	}
+	re.pseudonymizer.apply(&parsedResp)
 
 	switch re.format {
-	case "wide":
+	case EXPORT_FORMAT_WIDE:
 		cells, err := re.parser.ResponseToStrList(parsedResp)
 		if err != nil {
 			return err
@@ -96,7 +163,7 @@ func (re *ResponseExporter) WriteResponse(
 		if err != nil {
 			return err
 		}
-	case "long":
+	case EXPORT_FORMAT_LONG:
 		records, err := re.parser.ResponseToLongFormat(parsedResp)
 		if err != nil {
 			return err
@@ -107,13 +174,13 @@ func (re *ResponseExporter) WriteResponse(
 				return err
 			}
 		}
-	case "json":
+	case EXPORT_FORMAT_JSON:
 		// write to json
 		flatObj, err := re.parser.ResponseToFlatObj(parsedResp)
 		if err != nil {
 			return err
 		}
-		rV, err := json.Marshal(flatObj)
+		rV, err := json.Marshal(re.parser.withColumnHeaders(flatObj))
 		if err != nil {
 			return err
 		}
@@ -127,6 +194,37 @@ func (re *ResponseExporter) WriteResponse(
 		if err != nil {
 			return err
 		}
+	case EXPORT_FORMAT_NDJSON:
+		flatObj, err := re.parser.ResponseToFlatObj(parsedResp)
+		if err != nil {
+			return err
+		}
+		rV, err := json.Marshal(re.parser.withColumnHeaders(flatObj))
+		if err != nil {
+			return err
+		}
+		_, err = re.writer.Write(append(rV, '\n'))
+		if err != nil {
+			return err
+		}
+	case EXPORT_FORMAT_PARQUET:
+		cells, err := re.parser.ResponseToStrList(parsedResp)
+		if err != nil {
+			return err
+		}
+		err = re.parquetWriter.Write(cells)
+		if err != nil {
+			return err
+		}
+	case EXPORT_FORMAT_XLSX:
+		flatObj, err := re.parser.ResponseToFlatObj(parsedResp)
+		if err != nil {
+			return err
+		}
+		err = re.xlsxWriter.WriteResponse(rawResp, flatObj)
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unsupported format: %s", re.format)
 	}
@@ -138,17 +236,44 @@ func (re *ResponseExporter) WriteResponse(
 
 func (re *ResponseExporter) Finish() error {
 	switch re.format {
-	case "wide":
-		re.csvWriter.Flush()
-	case "long":
-		re.csvWriter.Flush()
-	case "json":
+	case EXPORT_FORMAT_WIDE, EXPORT_FORMAT_LONG:
+		return re.flush()
+	case EXPORT_FORMAT_JSON:
 		_, err := re.writer.Write([]byte("]}"))
 		if err != nil {
 			return err
 		}
+	case EXPORT_FORMAT_NDJSON:
+	case EXPORT_FORMAT_PARQUET:
+		return re.parquetWriter.Close()
+	case EXPORT_FORMAT_XLSX:
+		return re.xlsxWriter.Close()
 	default:
 		return fmt.Errorf("unsupported format: %s", re.format)
 	}
 	return nil
 }
+
+// ExportFileExtension returns the file extension (with dot) for files of the given export format
+func ExportFileExtension(format string) string {
+	switch format {
+	case EXPORT_FORMAT_JSON:
+		return ".json"
+	case EXPORT_FORMAT_NDJSON:
+		return ".ndjson"
+	case EXPORT_FORMAT_PARQUET:
+		return ".parquet"
+	case EXPORT_FORMAT_XLSX:
+		return ".xlsx"
+	default:
+		return ".csv"
+	}
+}
+
+func IsSupportedExportFormat(format string) bool {
+	switch format {
+	case EXPORT_FORMAT_WIDE, EXPORT_FORMAT_LONG, EXPORT_FORMAT_JSON, EXPORT_FORMAT_NDJSON, EXPORT_FORMAT_PARQUET, EXPORT_FORMAT_XLSX:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package surveyresponses
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Minimal Parquet writer for the wide export format: every column is a required UTF8 string, values are PLAIN encoded
+// and uncompressed, and each row group holds a single data page per column.
+// Format reference: https://github.com/apache/parquet-format
+
+const defaultParquetRowGroupSize = 10000
+
+const parquetMagic = "PAR1"
+
+// parquet-format enum values
+const (
+	parquetTypeByteArray         = 6
+	parquetRepetitionRequired    = 0
+	parquetConvertedTypeUTF8     = 0
+	parquetEncodingPlain         = 0
+	parquetEncodingRLE           = 3
+	parquetCodecUncompressed     = 0
+	parquetPageTypeDataPage      = 0
+	parquetFileMetaDataVersion   = 1
+	parquetCreatedBy             = "case-backend survey response exporter"
+	parquetMaxInt32PageSizeBytes = 1<<31 - 1
+)
+
+type parquetColumnChunk struct {
+	dataPageOffset int64
+	totalSize      int64
+	numValues      int64
+}
+
+type parquetRowGroup struct {
+	columns   []parquetColumnChunk
+	totalSize int64
+	numRows   int64
+}
+
+type parquetWriter struct {
+	w            io.Writer
+	offset       int64
+	columns      []string
+	rowGroupSize int
+
+	// column major buffer of the current row group
+	values    [][]byte
+	rowCount  int
+	rowGroups []parquetRowGroup
+	numRows   int64
+}
+
+func newParquetWriter(w io.Writer, columns []string, rowGroupSize int) (*parquetWriter, error) {
+	if rowGroupSize <= 0 {
+		rowGroupSize = defaultParquetRowGroupSize
+	}
+	pw := &parquetWriter{
+		w:            w,
+		columns:      columns,
+		rowGroupSize: rowGroupSize,
+		values:       make([][]byte, len(columns)),
+	}
+	if err := pw.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *parquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// Write adds a row, the values must be in the order of the columns
+func (pw *parquetWriter) Write(record []string) error {
+	if len(record) != len(pw.columns) {
+		return errors.New("parquet: record length does not match number of columns")
+	}
+	for i, v := range record {
+		pw.values[i] = binary.LittleEndian.AppendUint32(pw.values[i], uint32(len(v)))
+		pw.values[i] = append(pw.values[i], v...)
+		if len(pw.values[i]) > parquetMaxInt32PageSizeBytes {
+			return errors.New("parquet: page too large, use a smaller row group size")
+		}
+	}
+	pw.rowCount += 1
+	if pw.rowCount >= pw.rowGroupSize {
+		return pw.flushRowGroup()
+	}
+	return nil
+}
+
+func (pw *parquetWriter) flushRowGroup() error {
+	if pw.rowCount == 0 {
+		return nil
+	}
+
+	rowGroup := parquetRowGroup{numRows: int64(pw.rowCount)}
+	for i := range pw.columns {
+		data := pw.values[i]
+
+		header := &thriftCompactWriter{}
+		header.i32Field(1, parquetPageTypeDataPage)
+		header.i32Field(2, int32(len(data)))
+		header.i32Field(3, int32(len(data)))
+		header.structField(5)
+		header.i32Field(1, int32(pw.rowCount))
+		header.i32Field(2, parquetEncodingPlain)
+		header.i32Field(3, parquetEncodingRLE)
+		header.i32Field(4, parquetEncodingRLE)
+		header.structEnd()
+		header.structEnd()
+
+		chunk := parquetColumnChunk{
+			dataPageOffset: pw.offset,
+			totalSize:      int64(len(header.buf) + len(data)),
+			numValues:      int64(pw.rowCount),
+		}
+		if err := pw.write(header.buf); err != nil {
+			return err
+		}
+		if err := pw.write(data); err != nil {
+			return err
+		}
+		rowGroup.columns = append(rowGroup.columns, chunk)
+		rowGroup.totalSize += chunk.totalSize
+
+		pw.values[i] = pw.values[i][:0]
+	}
+
+	pw.rowGroups = append(pw.rowGroups, rowGroup)
+	pw.numRows += int64(pw.rowCount)
+	pw.rowCount = 0
+	return nil
+}
+
+// Close writes the last row group and the file footer
+func (pw *parquetWriter) Close() error {
+	if err := pw.flushRowGroup(); err != nil {
+		return err
+	}
+
+	meta := &thriftCompactWriter{}
+	meta.i32Field(1, parquetFileMetaDataVersion)
+
+	meta.listField(2, thriftTypeStruct, len(pw.columns)+1)
+	meta.stringField(4, "schema")
+	meta.i32Field(5, int32(len(pw.columns)))
+	meta.structEnd()
+	for _, col := range pw.columns {
+		meta.i32Field(1, parquetTypeByteArray)
+		meta.i32Field(3, parquetRepetitionRequired)
+		meta.stringField(4, col)
+		meta.i32Field(6, parquetConvertedTypeUTF8)
+		meta.structEnd()
+	}
+
+	meta.i64Field(3, pw.numRows)
+
+	meta.listField(4, thriftTypeStruct, len(pw.rowGroups))
+	for _, rg := range pw.rowGroups {
+		meta.listField(1, thriftTypeStruct, len(rg.columns))
+		for i, chunk := range rg.columns {
+			meta.i64Field(2, chunk.dataPageOffset)
+			meta.structField(3)
+			meta.i32Field(1, parquetTypeByteArray)
+			meta.listField(2, thriftTypeI32, 2)
+			meta.zigzag(parquetEncodingPlain)
+			meta.zigzag(parquetEncodingRLE)
+			meta.listField(3, thriftTypeBinary, 1)
+			meta.binary([]byte(pw.columns[i]))
+			meta.i32Field(4, parquetCodecUncompressed)
+			meta.i64Field(5, chunk.numValues)
+			meta.i64Field(6, chunk.totalSize)
+			meta.i64Field(7, chunk.totalSize)
+			meta.i64Field(9, chunk.dataPageOffset)
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.i64Field(2, rg.totalSize)
+		meta.i64Field(3, rg.numRows)
+		meta.structEnd()
+	}
+
+	meta.stringField(6, parquetCreatedBy)
+	meta.structEnd()
+
+	if err := pw.write(meta.buf); err != nil {
+		return err
+	}
+	if err := pw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta.buf)))); err != nil {
+		return err
+	}
+	return pw.write([]byte(parquetMagic))
+}
+
+// thrift compact protocol types used by the parquet metadata
+const (
+	thriftTypeI32    = 5
+	thriftTypeI64    = 6
+	thriftTypeBinary = 8
+	thriftTypeList   = 9
+	thriftTypeStruct = 12
+)
+
+// thriftCompactWriter encodes structs with the thrift compact protocol. Nested structs are opened with structField or as
+// list elements and must be closed with structEnd.
+type thriftCompactWriter struct {
+	buf         []byte
+	lastFieldID []int16
+	current     int16
+}
+
+func (tw *thriftCompactWriter) varint(v uint64) {
+	tw.buf = binary.AppendUvarint(tw.buf, v)
+}
+
+func (tw *thriftCompactWriter) zigzag(v int64) {
+	tw.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (tw *thriftCompactWriter) fieldHeader(id int16, fieldType byte) {
+	delta := id - tw.current
+	if delta > 0 && delta <= 15 {
+		tw.buf = append(tw.buf, byte(delta)<<4|fieldType)
+	} else {
+		tw.buf = append(tw.buf, fieldType)
+		tw.zigzag(int64(id))
+	}
+	tw.current = id
+}
+
+func (tw *thriftCompactWriter) binary(b []byte) {
+	tw.varint(uint64(len(b)))
+	tw.buf = append(tw.buf, b...)
+}
+
+func (tw *thriftCompactWriter) i32Field(id int16, v int32) {
+	tw.fieldHeader(id, thriftTypeI32)
+	tw.zigzag(int64(v))
+}
+
+func (tw *thriftCompactWriter) i64Field(id int16, v int64) {
+	tw.fieldHeader(id, thriftTypeI64)
+	tw.zigzag(v)
+}
+
+func (tw *thriftCompactWriter) stringField(id int16, v string) {
+	tw.fieldHeader(id, thriftTypeBinary)
+	tw.binary([]byte(v))
+}
+
+func (tw *thriftCompactWriter) structField(id int16) {
+	tw.fieldHeader(id, thriftTypeStruct)
+	tw.lastFieldID = append(tw.lastFieldID, tw.current)
+	tw.current = 0
+}
+
+// listField writes the list header. Struct elements are then written field by field, each closed with structEnd.
+func (tw *thriftCompactWriter) listField(id int16, elemType byte, size int) {
+	tw.fieldHeader(id, thriftTypeList)
+	if size < 15 {
+		tw.buf = append(tw.buf, byte(size)<<4|elemType)
+	} else {
+		tw.buf = append(tw.buf, 0xf0|elemType)
+		tw.varint(uint64(size))
+	}
+	if elemType == thriftTypeStruct && size > 0 {
+		// every element starts with a fresh field id context, the list field id is restored after the last element
+		tw.lastFieldID = append(tw.lastFieldID, id)
+		for i := 1; i < size; i++ {
+			tw.lastFieldID = append(tw.lastFieldID, 0)
+		}
+		tw.current = 0
+	}
+}
+
+func (tw *thriftCompactWriter) structEnd() {
+	tw.buf = append(tw.buf, 0)
+	if len(tw.lastFieldID) == 0 {
+		return
+	}
+	tw.current = tw.lastFieldID[len(tw.lastFieldID)-1]
+	tw.lastFieldID = tw.lastFieldID[:len(tw.lastFieldID)-1]
+}
@@ -0,0 +1,77 @@
+//go:build parquetreader
+
+// Checks the Parquet writer against an independent implementation. The reader is not a dependency of the module,
+// run with: go get github.com/parquet-go/parquet-go && go test -tags parquetreader ./pkg/study/exporter/survey-responses/
+package surveyresponses
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetWriterRoundTrip(t *testing.T) {
+	columns := []string{"ID", "S.Q1", "S.Q2"}
+	rows := [][]string{{"1", "a", "äöü"}, {"2", "", "x"}, {"3", "c", ""}, {"4", "d", "long " + strings.Repeat("v", 1000)}, {"5", "e", "f"}}
+
+	out := &bytes.Buffer{}
+	pw, err := newParquetWriter(out, columns, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := pw.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumRows() != int64(len(rows)) {
+		t.Fatalf("unexpected number of rows: %d", f.NumRows())
+	}
+	if len(f.RowGroups()) != 3 {
+		t.Errorf("expected 3 row groups, got %d", len(f.RowGroups()))
+	}
+	for i, field := range f.Schema().Fields() {
+		if field.Name() != columns[i] {
+			t.Errorf("unexpected column name %s, expected %s", field.Name(), columns[i])
+		}
+	}
+
+	read := [][]string{}
+	for _, rowGroup := range f.RowGroups() {
+		rowReader := rowGroup.Rows()
+		buf := make([]parquet.Row, rowGroup.NumRows())
+		n, err := rowReader.ReadRows(buf)
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		rowReader.Close()
+		for _, row := range buf[:n] {
+			values := make([]string, len(columns))
+			for _, value := range row {
+				values[value.Column()] = string(value.ByteArray())
+			}
+			read = append(read, values)
+		}
+	}
+
+	if len(read) != len(rows) {
+		t.Fatalf("unexpected number of rows read: %d", len(read))
+	}
+	for i := range rows {
+		if strings.Join(read[i], "|") != strings.Join(rows[i], "|") {
+			t.Errorf("row %d: unexpected values %v", i, read[i])
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// thriftValue decodes a thrift compact encoded value, structs become map[int16]interface{} and lists []interface{}
+func thriftValue(t *testing.T, data []byte, pos *int, valueType byte) interface{} {
+	readVarint := func() uint64 {
+		v, n := binary.Uvarint(data[*pos:])
+		if n <= 0 {
+			t.Fatalf("invalid varint at %d", *pos)
+		}
+		*pos += n
+		return v
+	}
+	readZigzag := func() int64 {
+		v := readVarint()
+		return int64(v>>1) ^ -int64(v&1)
+	}
+
+	switch valueType {
+	case thriftTypeI32, thriftTypeI64:
+		return readZigzag()
+	case thriftTypeBinary:
+		l := int(readVarint())
+		v := string(data[*pos : *pos+l])
+		*pos += l
+		return v
+	case thriftTypeList:
+		header := data[*pos]
+		*pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(readVarint())
+		}
+		list := []interface{}{}
+		for i := 0; i < size; i++ {
+			list = append(list, thriftValue(t, data, pos, header&0x0f))
+		}
+		return list
+	case thriftTypeStruct:
+		fields := map[int16]interface{}{}
+		var lastID int16
+		for {
+			header := data[*pos]
+			*pos++
+			if header == 0 {
+				return fields
+			}
+			id := lastID + int16(header>>4)
+			if header>>4 == 0 {
+				id = int16(readZigzag())
+			}
+			fields[id] = thriftValue(t, data, pos, header&0x0f)
+			lastID = id
+		}
+	}
+	t.Fatalf("unexpected thrift type %d", valueType)
+	return nil
+}
+
+type parquetTestFile struct {
+	meta map[int16]interface{}
+	data []byte
+}
+
+func readParquetTestFile(t *testing.T, data []byte) parquetTestFile {
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatal("missing parquet magic bytes")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metaStart := len(data) - 8 - metaLen
+	pos := metaStart
+	meta := thriftValue(t, data, &pos, thriftTypeStruct).(map[int16]interface{})
+	if pos != len(data)-8 {
+		t.Fatalf("footer length mismatch: decoded until %d, expected %d", pos, len(data)-8)
+	}
+	return parquetTestFile{meta: meta, data: data}
+}
+
+// columnValues returns the values of a column over all row groups
+func (f parquetTestFile) columnValues(t *testing.T, column int) []string {
+	values := []string{}
+	for _, rg := range f.meta[4].([]interface{}) {
+		chunk := rg.(map[int16]interface{})[1].([]interface{})[column].(map[int16]interface{})
+		colMeta := chunk[3].(map[int16]interface{})
+		pos := int(colMeta[9].(int64))
+
+		header := thriftValue(t, f.data, &pos, thriftTypeStruct).(map[int16]interface{})
+		pageSize := int(header[3].(int64))
+		numValues := int(header[5].(map[int16]interface{})[1].(int64))
+		end := pos + pageSize
+		for i := 0; i < numValues; i++ {
+			l := int(binary.LittleEndian.Uint32(f.data[pos:]))
+			values = append(values, string(f.data[pos+4:pos+4+l]))
+			pos += 4 + l
+		}
+		if pos != end {
+			t.Fatalf("page size mismatch")
+		}
+	}
+	return values
+}
+
+func TestParquetWriter(t *testing.T) {
+	t.Run("with multiple row groups", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		pw, err := newParquetWriter(out, []string{"ID", "S.Q1"}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range [][]string{{"1", "a"}, {"2", ""}, {"3", "äöü"}} {
+			if err := pw.Write(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := pw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		f := readParquetTestFile(t, out.Bytes())
+		if f.meta[3].(int64) != 3 {
+			t.Errorf("unexpected number of rows: %v", f.meta[3])
+		}
+		schema := f.meta[2].([]interface{})
+		if len(schema) != 3 || schema[0].(map[int16]interface{})[5].(int64) != 2 || schema[2].(map[int16]interface{})[4].(string) != "S.Q1" {
+			t.Errorf("unexpected schema: %v", schema)
+		}
+		if len(f.meta[4].([]interface{})) != 2 {
+			t.Errorf("expected 2 row groups, got %d", len(f.meta[4].([]interface{})))
+		}
+		if v := f.columnValues(t, 1); strings.Join(v, ",") != "a,,äöü" {
+			t.Errorf("unexpected column values: %v", v)
+		}
+	})
+
+	t.Run("with many columns", func(t *testing.T) {
+		cols := []string{}
+		row := []string{}
+		for i := 0; i < 40; i++ {
+			cols = append(cols, "c"+strings.Repeat("x", i))
+			row = append(row, strings.Repeat("v", i))
+		}
+		out := &bytes.Buffer{}
+		pw, _ := newParquetWriter(out, cols, 0)
+		if err := pw.Write(row); err != nil {
+			t.Fatal(err)
+		}
+		if err := pw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f := readParquetTestFile(t, out.Bytes())
+		if v := f.columnValues(t, 39); len(v) != 1 || v[0] != row[39] {
+			t.Errorf("unexpected column values: %v", v)
+		}
+	})
+
+	t.Run("without rows", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		pw, _ := newParquetWriter(out, []string{"ID"}, 0)
+		if err := pw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f := readParquetTestFile(t, out.Bytes())
+		if f.meta[3].(int64) != 0 {
+			t.Errorf("unexpected number of rows: %v", f.meta[3])
+		}
+	})
+}
+
+func TestExportFormats(t *testing.T) {
+	t.Run("ndjson", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		exporter := testStreamExporter(t, out, EXPORT_FORMAT_NDJSON)
+		if _, err := exporter.Stream(context.Background(), testStreamSource(3), StreamOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := exporter.Finish(); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected 3 lines, got %d", len(lines))
+		}
+		for _, line := range lines {
+			var obj map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &obj); err != nil {
+				t.Fatal(err)
+			}
+			if obj["S.Q1"] != "hello" {
+				t.Errorf("unexpected value: %v", obj["S.Q1"])
+			}
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		exporter := testStreamExporter(t, out, EXPORT_FORMAT_PARQUET)
+		if _, err := exporter.Stream(context.Background(), testStreamSource(3), StreamOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := exporter.Finish(); err != nil {
+			t.Fatal(err)
+		}
+
+		f := readParquetTestFile(t, out.Bytes())
+		cols := exporter.parser.columns.allColumns()
+		qCol := -1
+		for i, c := range cols {
+			if c == "S.Q1" {
+				qCol = i
+			}
+		}
+		if v := f.columnValues(t, qCol); strings.Join(v, ",") != "hello,hello,hello" {
+			t.Errorf("unexpected column values: %v", v)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		if IsSupportedExportFormat("xml") {
+			t.Error("xml should not be supported")
+		}
+		if _, err := NewResponseExporterWithOptions(exporterTestParser(t), &bytes.Buffer{}, ExportOptions{Format: "xml"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
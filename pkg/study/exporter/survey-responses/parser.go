@@ -10,6 +10,14 @@ import (
 )
 
 var (
+	fixedColNames = []string{
+		"ID",
+		"participantID",
+		"version",
+		"opened",
+		"submitted",
+		"arrived",
+	}
 	defaultCtxColNames = []string{
 		"language",
 		"engineVersion",
@@ -24,6 +32,8 @@ type ResponseParser struct {
 	columns           ColumnNames
 	includeMeta       *IncludeMeta
 	questionOptionSep string
+	valueLabels       *valueLabels
+	redactedColumns   map[string]bool
 }
 
 func NewResponseParser(
@@ -50,14 +60,7 @@ func NewResponseParser(
 }
 
 func (rp *ResponseParser) initColumnNames(extraContextColumns *[]string) error {
-	fixedCols := []string{
-		"ID",
-		"participantID",
-		"version",
-		"opened",
-		"submitted",
-		"arrived",
-	}
+	fixedCols := slices.Clone(fixedColNames)
 
 	ctxCols := defaultCtxColNames
 	if extraContextColumns != nil {
@@ -72,11 +75,9 @@ func (rp *ResponseParser) initColumnNames(extraContextColumns *[]string) error {
 		}
 	}
 
+	// survey definition order, see mergeColumnOrder
 	respCols := getResponseColNamesForAllVersions(rp.surveyVersions, rp.questionOptionSep)
-	slices.Sort(respCols)
-
 	metaCols := getMetaColNamesForAllVersions(rp.surveyVersions, rp.includeMeta, rp.questionOptionSep)
-	slices.Sort(metaCols)
 
 	rp.columns = ColumnNames{
 		FixedColumns:    fixedCols,
@@ -129,6 +130,9 @@ func (rp *ResponseParser) ParseResponse(
 		resp := findResponse(rawResp.Responses, question.ID)
 
 		responseColumns := getResponseColumns(question, resp, rp.questionOptionSep)
+		if rp.valueLabels != nil {
+			rp.valueLabels.apply(currentVersion.VersionID, responseColumns, rp.questionOptionSep)
+		}
 		for k, v := range responseColumns {
 			_, hasKey := parsedResponse.Responses[k]
 			if hasKey {
@@ -223,7 +227,7 @@ func (rp *ResponseParser) ResponseToLongFormat(
 	for _, colName := range rp.columns.ResponseColumns {
 		currentRespLine := []string{}
 		currentRespLine = append(currentRespLine, fixedValues...)
-		currentRespLine = append(currentRespLine, colName)
+		currentRespLine = append(currentRespLine, rp.ColumnHeader(colName))
 		currentRespLine = append(currentRespLine, valueToStr(result[colName]))
 		out = append(out, currentRespLine)
 	}
@@ -254,14 +258,18 @@ func (rp *ResponseParser) ResponseToFlatObj(
 func (rp ResponseParser) initWithFixedColumnsWithValues(
 	parsedResponse *ParsedResponse,
 ) map[string]interface{} {
-	return map[string]interface{}{
-		rp.columns.FixedColumns[0]: parsedResponse.ID,
-		rp.columns.FixedColumns[1]: parsedResponse.ParticipantID,
-		rp.columns.FixedColumns[2]: parsedResponse.Version,
-		rp.columns.FixedColumns[3]: parsedResponse.OpenedAt,
-		rp.columns.FixedColumns[4]: parsedResponse.SubmittedAt,
-		rp.columns.FixedColumns[5]: parsedResponse.ArrivedAt,
+	res := map[string]interface{}{
+		fixedColNames[0]: parsedResponse.ID,
+		fixedColNames[1]: parsedResponse.ParticipantID,
+		fixedColNames[2]: parsedResponse.Version,
+		fixedColNames[3]: parsedResponse.OpenedAt,
+		fixedColNames[4]: parsedResponse.SubmittedAt,
+		fixedColNames[5]: parsedResponse.ArrivedAt,
 	}
+	for col := range rp.redactedColumns {
+		delete(res, col)
+	}
+	return res
 }
 
 func (rp ResponseParser) addContextColumnsWithValues(
@@ -0,0 +1,175 @@
+package surveyresponses
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// PARTICIPANT_INFO_ENTERED_AT adds the enrollment time of the participant (unix seconds)
+	PARTICIPANT_INFO_ENTERED_AT = "enteredAt"
+	// PARTICIPANT_INFO_STUDY_STATUS adds the current study status of the participant
+	PARTICIPANT_INFO_STUDY_STATUS = "studyStatus"
+)
+
+const (
+	participantInfoColPrefix = "participant."
+	participantFlagColPrefix = "participant.flags."
+)
+
+// ParticipantLookup returns the current state of the participant, or nil if the participant doesn't exist
+type ParticipantLookup func(participantID string) (*studytypes.Participant, error)
+
+// ParticipantGetter is implemented by the study DB service
+type ParticipantGetter interface {
+	GetParticipantByID(instanceID string, studyKey string, participantID string) (studytypes.Participant, error)
+}
+
+// ParticipantsFromDB returns a lookup reading participant states from the study DB
+func ParticipantsFromDB(db ParticipantGetter, instanceID string, studyKey string) ParticipantLookup {
+	return func(participantID string) (*studytypes.Participant, error) {
+		p, err := db.GetParticipantByID(instanceID, studyKey, participantID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return &p, nil
+	}
+}
+
+// ParticipantInfoOptions select participant state joined onto each response row. The values are the current state
+// of the participant at export time, not the state at submission.
+type ParticipantInfoOptions struct {
+	// Fields are PARTICIPANT_INFO_* values
+	Fields []string
+	// Flags are keys of participant flags, each is exported as a column
+	Flags []string
+	// Lookup is required if any field or flag is selected
+	Lookup ParticipantLookup
+}
+
+func (o ParticipantInfoOptions) Validate() error {
+	for _, field := range o.Fields {
+		if !IsSupportedParticipantInfoField(field) {
+			return fmt.Errorf("unsupported participant info field: %s", field)
+		}
+	}
+	if len(o.columns()) > 0 && o.Lookup == nil {
+		return errors.New("participant lookup is required for participant info")
+	}
+	return nil
+}
+
+func IsSupportedParticipantInfoField(field string) bool {
+	switch field {
+	case PARTICIPANT_INFO_ENTERED_AT, PARTICIPANT_INFO_STUDY_STATUS:
+		return true
+	}
+	return false
+}
+
+// columns returns the names of the participant info columns, e.g. participant.studyStatus or participant.flags.group
+func (o ParticipantInfoOptions) columns() []string {
+	cols := []string{}
+	for _, field := range o.Fields {
+		if col := participantInfoColPrefix + field; !slices.Contains(cols, col) {
+			cols = append(cols, col)
+		}
+	}
+	for _, flag := range o.Flags {
+		if col := participantFlagColPrefix + flag; !slices.Contains(cols, col) {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// participantInfoJoiner adds the participant info columns to the context of parsed responses. Participants are
+// looked up once per export.
+type participantInfoJoiner struct {
+	options ParticipantInfoOptions
+	cache   map[string]map[string]string
+}
+
+func newParticipantInfoJoiner(options ParticipantInfoOptions, parser *ResponseParser) (*participantInfoJoiner, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	cols := options.columns()
+	if len(cols) == 0 {
+		return nil, nil
+	}
+
+	ctxCols := slices.Clone(parser.columns.ContextColumns)
+	for _, col := range cols {
+		if !slices.Contains(ctxCols, col) {
+			ctxCols = append(ctxCols, col)
+		}
+	}
+	parser.columns.ContextColumns = ctxCols
+
+	return &participantInfoJoiner{
+		options: options,
+		cache:   map[string]map[string]string{},
+	}, nil
+}
+
+// apply has to be called before the participant ID is pseudonymized
+func (j *participantInfoJoiner) apply(parsedResponse *ParsedResponse) error {
+	if j == nil {
+		return nil
+	}
+	values, err := j.participantValues(parsedResponse.ParticipantID)
+	if err != nil {
+		return err
+	}
+
+	// copy, the context map is shared with the raw response
+	ctx := make(map[string]string, len(parsedResponse.Context)+len(values))
+	for k, v := range parsedResponse.Context {
+		ctx[k] = v
+	}
+	for k, v := range values {
+		ctx[k] = v
+	}
+	parsedResponse.Context = ctx
+	return nil
+}
+
+func (j *participantInfoJoiner) participantValues(participantID string) (map[string]string, error) {
+	if values, ok := j.cache[participantID]; ok {
+		return values, nil
+	}
+
+	values := map[string]string{}
+	p, err := j.options.Lookup(participantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up participant: %w", err)
+	}
+	if p != nil {
+		for _, field := range j.options.Fields {
+			switch field {
+			case PARTICIPANT_INFO_ENTERED_AT:
+				if p.EnteredAt > 0 {
+					values[participantInfoColPrefix+field] = strconv.FormatInt(p.EnteredAt, 10)
+				}
+			case PARTICIPANT_INFO_STUDY_STATUS:
+				values[participantInfoColPrefix+field] = p.StudyStatus
+			}
+		}
+		for _, flag := range j.options.Flags {
+			if v, ok := p.Flags[flag]; ok {
+				values[participantFlagColPrefix+flag] = v
+			}
+		}
+	}
+	j.cache[participantID] = values
+	return values, nil
+}
@@ -0,0 +1,173 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func participantInfoTestExport(t *testing.T, format string, options ExportOptions, pids ...string) (string, error) {
+	parser, err := NewResponseParser("S", []sd.SurveyVersionPreview{
+		{VersionID: "v1", Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+		}},
+	}, false, nil, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	options.Format = format
+	exporter, err := NewResponseExporterWithOptions(parser, out, options)
+	if err != nil {
+		return "", err
+	}
+	source := func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error {
+		for _, pid := range pids {
+			err := yield(studytypes.SurveyResponse{
+				ID: primitive.NewObjectID(), ParticipantID: pid, VersionID: "v1", SubmittedAt: pseudonymizeTestTs,
+				Context: map[string]string{"language": "en"},
+				Responses: []studytypes.SurveyItemResponse{
+					{Key: "S.Q1", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("input", "text"))},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := exporter.Stream(context.Background(), source, StreamOptions{}); err != nil {
+		return "", err
+	}
+	if err := exporter.Finish(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func testParticipantLookup(calls map[string]int) ParticipantLookup {
+	participants := map[string]*studytypes.Participant{
+		"p1": {ParticipantID: "p1", EnteredAt: pseudonymizeTestTs, StudyStatus: studytypes.PARTICIPANT_STUDY_STATUS_ACTIVE, Flags: map[string]string{"group": "A", "other": "x"}},
+		"p2": {ParticipantID: "p2", EnteredAt: pseudonymizeTestTs - 86400, StudyStatus: studytypes.PARTICIPANT_STUDY_STATUS_EXITED},
+	}
+	return func(participantID string) (*studytypes.Participant, error) {
+		calls[participantID] += 1
+		return participants[participantID], nil
+	}
+}
+
+func TestParticipantInfoJoin(t *testing.T) {
+	t.Run("fields and flags in ndjson", func(t *testing.T) {
+		calls := map[string]int{}
+		out, err := participantInfoTestExport(t, EXPORT_FORMAT_NDJSON, ExportOptions{
+			ParticipantInfo: ParticipantInfoOptions{
+				Fields: []string{PARTICIPANT_INFO_ENTERED_AT, PARTICIPANT_INFO_STUDY_STATUS},
+				Flags:  []string{"group"},
+				Lookup: testParticipantLookup(calls),
+			},
+		}, "p1", "p2", "p1", "unknown")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows := []map[string]interface{}{}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			row := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(line), &row); err != nil {
+				t.Fatal(err)
+			}
+			rows = append(rows, row)
+		}
+
+		if rows[0]["participant.enteredAt"] != "1710510330" || rows[0]["participant.studyStatus"] != "active" || rows[0]["participant.flags.group"] != "A" {
+			t.Errorf("unexpected participant info: %v", rows[0])
+		}
+		if _, ok := rows[0]["participant.flags.other"]; ok {
+			t.Error("only selected flags should be exported")
+		}
+		if rows[1]["participant.studyStatus"] != "exited" || rows[1]["participant.flags.group"] != "" {
+			t.Errorf("unexpected participant info: %v", rows[1])
+		}
+		if rows[3]["participant.studyStatus"] != "" || rows[3]["language"] != "en" {
+			t.Errorf("unknown participants should have empty values: %v", rows[3])
+		}
+		if calls["p1"] != 1 {
+			t.Errorf("participants should be looked up once, got %d", calls["p1"])
+		}
+	})
+
+	t.Run("columns in wide format", func(t *testing.T) {
+		out, err := participantInfoTestExport(t, EXPORT_FORMAT_WIDE, ExportOptions{
+			ParticipantInfo: ParticipantInfoOptions{
+				Fields: []string{PARTICIPANT_INFO_STUDY_STATUS},
+				Lookup: testParticipantLookup(map[string]int{}),
+			},
+		}, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		col := slices.Index(records[0], "participant.studyStatus")
+		if col < 0 || records[1][col] != "active" {
+			t.Errorf("unexpected export: %v", records)
+		}
+	})
+
+	t.Run("lookup with pseudonymization", func(t *testing.T) {
+		out, err := participantInfoTestExport(t, EXPORT_FORMAT_NDJSON, ExportOptions{
+			Pseudonymization: PseudonymizationOptions{ParticipantIDs: PARTICIPANT_IDS_REMAP, TimestampPrecision: TIMESTAMP_PRECISION_DAY},
+			ParticipantInfo: ParticipantInfoOptions{
+				Fields: []string{PARTICIPANT_INFO_ENTERED_AT, PARTICIPANT_INFO_STUDY_STATUS},
+				Lookup: testParticipantLookup(map[string]int{}),
+			},
+		}, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		row := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(out), &row); err != nil {
+			t.Fatal(err)
+		}
+		if row["participantID"] != "P1" || row["participant.studyStatus"] != "active" || row["participant.enteredAt"] != "1710460800" {
+			t.Errorf("unexpected row: %v", row)
+		}
+	})
+
+	t.Run("lookup error fails the export", func(t *testing.T) {
+		_, err := participantInfoTestExport(t, EXPORT_FORMAT_NDJSON, ExportOptions{
+			ParticipantInfo: ParticipantInfoOptions{
+				Fields: []string{PARTICIPANT_INFO_STUDY_STATUS},
+				Lookup: func(participantID string) (*studytypes.Participant, error) {
+					return nil, errors.New("db down")
+				},
+			},
+		}, "p1")
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, o := range []ParticipantInfoOptions{
+			{Fields: []string{"email"}, Lookup: testParticipantLookup(map[string]int{})},
+			{Flags: []string{"group"}},
+		} {
+			if err := o.Validate(); err == nil {
+				t.Errorf("expected error for %v", o)
+			}
+		}
+	})
+}
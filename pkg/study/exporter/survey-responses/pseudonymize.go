@@ -0,0 +1,223 @@
+package surveyresponses
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PARTICIPANT_IDS_KEEP exports the participant IDs as stored
+	PARTICIPANT_IDS_KEEP = ""
+	// PARTICIPANT_IDS_HASH replaces participant IDs with an HMAC-SHA256 keyed with the export salt
+	PARTICIPANT_IDS_HASH = "hash"
+	// PARTICIPANT_IDS_REMAP replaces participant IDs with sequential numbers (P1, P2, ...) in order of appearance
+	PARTICIPANT_IDS_REMAP = "remap"
+)
+
+const (
+	TIMESTAMP_PRECISION_SECOND = ""
+	TIMESTAMP_PRECISION_MINUTE = "minute"
+	TIMESTAMP_PRECISION_HOUR   = "hour"
+	TIMESTAMP_PRECISION_DAY    = "day"
+	TIMESTAMP_PRECISION_MONTH  = "month"
+)
+
+const generatedSaltLength = 32
+
+type PseudonymizationOptions struct {
+	// ParticipantIDs is one of PARTICIPANT_IDS_*
+	ParticipantIDs string
+	// Salt for PARTICIPANT_IDS_HASH. A random salt is generated for each export if empty, use the same salt to link exports.
+	Salt string
+	// RedactColumns are dropped from the export. Entries are column names or patterns as in path.Match (e.g. "*.Q1-*").
+	RedactColumns []string
+	// TimestampPrecision truncates submission times, meta times and date responses (UTC), see TIMESTAMP_PRECISION_*
+	TimestampPrecision string
+}
+
+func (o PseudonymizationOptions) Validate() error {
+	switch o.ParticipantIDs {
+	case PARTICIPANT_IDS_KEEP, PARTICIPANT_IDS_HASH, PARTICIPANT_IDS_REMAP:
+	default:
+		return fmt.Errorf("unsupported participant ID mode: %s", o.ParticipantIDs)
+	}
+	switch o.TimestampPrecision {
+	case TIMESTAMP_PRECISION_SECOND, TIMESTAMP_PRECISION_MINUTE, TIMESTAMP_PRECISION_HOUR, TIMESTAMP_PRECISION_DAY, TIMESTAMP_PRECISION_MONTH:
+	default:
+		return fmt.Errorf("unsupported timestamp precision: %s", o.TimestampPrecision)
+	}
+	for _, pattern := range o.RedactColumns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid column pattern %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+type pseudonymizer struct {
+	options     PseudonymizationOptions
+	salt        []byte
+	remapped    map[string]string
+	dateColumns map[string]bool
+}
+
+func newPseudonymizer(options PseudonymizationOptions, parser *ResponseParser) (*pseudonymizer, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &pseudonymizer{
+		options:     options,
+		salt:        []byte(options.Salt),
+		remapped:    map[string]string{},
+		dateColumns: map[string]bool{},
+	}
+	if options.ParticipantIDs == PARTICIPANT_IDS_HASH && options.Salt == "" {
+		p.salt = make([]byte, generatedSaltLength)
+		if _, err := rand.Read(p.salt); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.TimestampPrecision != TIMESTAMP_PRECISION_SECOND {
+		for _, version := range parser.surveyVersions {
+			for _, question := range version.Questions {
+				for _, col := range getResponseColNamesForQuestion(question, parser.questionOptionSep) {
+					if responseColumnType(question, col, parser.questionOptionSep) == XLSX_COLUMN_TYPE_DATE {
+						p.dateColumns[col] = true
+					}
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *pseudonymizer) apply(parsedResponse *ParsedResponse) {
+	parsedResponse.ParticipantID = p.participantID(parsedResponse.ParticipantID)
+
+	if p.options.TimestampPrecision == TIMESTAMP_PRECISION_SECOND {
+		return
+	}
+	parsedResponse.OpenedAt = p.truncate(parsedResponse.OpenedAt)
+	parsedResponse.SubmittedAt = p.truncate(parsedResponse.SubmittedAt)
+	parsedResponse.ArrivedAt = p.truncate(parsedResponse.ArrivedAt)
+
+	enteredAtCol := participantInfoColPrefix + PARTICIPANT_INFO_ENTERED_AT
+	if value, ok := parsedResponse.Context[enteredAtCol]; ok {
+		if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+			parsedResponse.Context[enteredAtCol] = strconv.FormatInt(p.truncate(ts), 10)
+		}
+	}
+
+	for col := range p.dateColumns {
+		value, ok := parsedResponse.Responses[col].(string)
+		if !ok {
+			continue
+		}
+		if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+			parsedResponse.Responses[col] = strconv.FormatInt(p.truncate(ts), 10)
+		}
+	}
+
+	// meta times are in milliseconds
+	for _, times := range []map[string][]int64{parsedResponse.Meta.Initialised, parsedResponse.Meta.Displayed, parsedResponse.Meta.Responded} {
+		for col, values := range times {
+			truncated := make([]int64, len(values))
+			for i, v := range values {
+				truncated[i] = p.truncate(v/1000) * 1000
+			}
+			times[col] = truncated
+		}
+	}
+}
+
+func (p *pseudonymizer) participantID(pid string) string {
+	if pid == "" {
+		return pid
+	}
+	switch p.options.ParticipantIDs {
+	case PARTICIPANT_IDS_HASH:
+		mac := hmac.New(sha256.New, p.salt)
+		mac.Write([]byte(pid))
+		return hex.EncodeToString(mac.Sum(nil))
+	case PARTICIPANT_IDS_REMAP:
+		if mapped, ok := p.remapped[pid]; ok {
+			return mapped
+		}
+		mapped := "P" + strconv.Itoa(len(p.remapped)+1)
+		p.remapped[pid] = mapped
+		return mapped
+	}
+	return pid
+}
+
+// truncate cuts a unix timestamp (seconds) to the configured precision
+func (p *pseudonymizer) truncate(ts int64) int64 {
+	if ts <= 0 {
+		return ts
+	}
+	t := time.Unix(ts, 0).UTC()
+	switch p.options.TimestampPrecision {
+	case TIMESTAMP_PRECISION_MINUTE:
+		t = t.Truncate(time.Minute)
+	case TIMESTAMP_PRECISION_HOUR:
+		t = t.Truncate(time.Hour)
+	case TIMESTAMP_PRECISION_DAY:
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case TIMESTAMP_PRECISION_MONTH:
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.Unix()
+}
+
+// RedactColumns removes the columns matching any of the patterns (see path.Match) from the export
+func (rp *ResponseParser) RedactColumns(patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	if rp.redactedColumns == nil {
+		rp.redactedColumns = map[string]bool{}
+	}
+
+	filter := func(cols []string) []string {
+		kept := []string{}
+		for _, col := range cols {
+			if matchesAnyPattern(col, patterns) {
+				rp.redactedColumns[col] = true
+				continue
+			}
+			kept = append(kept, col)
+		}
+		return kept
+	}
+	rp.columns.FixedColumns = filter(rp.columns.FixedColumns)
+	rp.columns.ContextColumns = filter(rp.columns.ContextColumns)
+	rp.columns.ResponseColumns = filter(rp.columns.ResponseColumns)
+	rp.columns.MetaColumns = filter(rp.columns.MetaColumns)
+	return nil
+}
+
+func (rp *ResponseParser) isRedacted(col string) bool {
+	return rp.redactedColumns[col]
+}
+
+func matchesAnyPattern(col string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == col {
+			return true
+		}
+		if ok, _ := path.Match(pattern, col); ok {
+			return true
+		}
+	}
+	return false
+}
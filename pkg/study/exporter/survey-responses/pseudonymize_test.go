@@ -0,0 +1,131 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 2024-03-15 13:45:30 UTC
+const pseudonymizeTestTs = 1710510330
+
+func pseudonymizeTestExport(t *testing.T, options PseudonymizationOptions, pids ...string) []map[string]interface{} {
+	parser, err := NewResponseParser("S", []sd.SurveyVersionPreview{
+		{VersionID: "v1", Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+			{ID: "S.Q2", QuestionType: sd.QUESTION_TYPE_DATE_INPUT, Responses: []sd.ResponseDef{{ID: "date"}}},
+		}},
+	}, false, &IncludeMeta{DisplayedTimes: true}, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	exporter, err := NewResponseExporterWithOptions(parser, out, ExportOptions{Format: EXPORT_FORMAT_NDJSON, Pseudonymization: options})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error {
+		for _, pid := range pids {
+			err := yield(studytypes.SurveyResponse{
+				ID: primitive.NewObjectID(), ParticipantID: pid, VersionID: "v1", SubmittedAt: pseudonymizeTestTs,
+				Responses: []studytypes.SurveyItemResponse{
+					{Key: "S.Q1", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("input", "confidential"))},
+					{Key: "S.Q2", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("date", "1710510330")), Meta: studytypes.ResponseMeta{Displayed: []int64{pseudonymizeTestTs*1000 + 123}}},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := exporter.Stream(context.Background(), source, StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		row := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestPseudonymization(t *testing.T) {
+	t.Run("hashed participant IDs", func(t *testing.T) {
+		rows := pseudonymizeTestExport(t, PseudonymizationOptions{ParticipantIDs: PARTICIPANT_IDS_HASH, Salt: "s1"}, "p1", "p2", "p1")
+		if rows[0]["participantID"] == "p1" || len(rows[0]["participantID"].(string)) != 64 {
+			t.Errorf("participant ID not hashed: %v", rows[0]["participantID"])
+		}
+		if rows[0]["participantID"] != rows[2]["participantID"] || rows[0]["participantID"] == rows[1]["participantID"] {
+			t.Error("hashes must be stable within an export and differ between participants")
+		}
+
+		sameSalt := pseudonymizeTestExport(t, PseudonymizationOptions{ParticipantIDs: PARTICIPANT_IDS_HASH, Salt: "s1"}, "p1")
+		otherSalt := pseudonymizeTestExport(t, PseudonymizationOptions{ParticipantIDs: PARTICIPANT_IDS_HASH, Salt: "s2"}, "p1")
+		generatedSalt := pseudonymizeTestExport(t, PseudonymizationOptions{ParticipantIDs: PARTICIPANT_IDS_HASH}, "p1")
+		if sameSalt[0]["participantID"] != rows[0]["participantID"] {
+			t.Error("same salt should give the same hash")
+		}
+		if otherSalt[0]["participantID"] == rows[0]["participantID"] || generatedSalt[0]["participantID"] == rows[0]["participantID"] {
+			t.Error("different salts should give different hashes")
+		}
+	})
+
+	t.Run("remapped participant IDs", func(t *testing.T) {
+		rows := pseudonymizeTestExport(t, PseudonymizationOptions{ParticipantIDs: PARTICIPANT_IDS_REMAP}, "p9", "p3", "p9")
+		if rows[0]["participantID"] != "P1" || rows[1]["participantID"] != "P2" || rows[2]["participantID"] != "P1" {
+			t.Errorf("unexpected participant IDs: %v %v %v", rows[0]["participantID"], rows[1]["participantID"], rows[2]["participantID"])
+		}
+	})
+
+	t.Run("redacted columns", func(t *testing.T) {
+		rows := pseudonymizeTestExport(t, PseudonymizationOptions{RedactColumns: []string{"ID", "S.Q1*"}}, "p1")
+		if _, ok := rows[0]["ID"]; ok {
+			t.Error("ID column should be redacted")
+		}
+		if _, ok := rows[0]["S.Q1"]; ok {
+			t.Error("S.Q1 column should be redacted")
+		}
+		if rows[0]["participantID"] != "p1" || rows[0]["S.Q2"] == nil {
+			t.Errorf("other columns should be kept: %v", rows[0])
+		}
+	})
+
+	t.Run("truncated timestamps", func(t *testing.T) {
+		rows := pseudonymizeTestExport(t, PseudonymizationOptions{TimestampPrecision: TIMESTAMP_PRECISION_DAY}, "p1")
+		dayStart := float64(1710460800)
+		if rows[0]["submitted"] != dayStart {
+			t.Errorf("unexpected submitted: %v", rows[0]["submitted"])
+		}
+		if rows[0]["S.Q2"] != "1710460800" {
+			t.Errorf("unexpected date response: %v", rows[0]["S.Q2"])
+		}
+		displayed := rows[0]["S.Q2-metaDisplayed"].([]interface{})
+		if displayed[0] != dayStart*1000 {
+			t.Errorf("unexpected meta time: %v", displayed)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, o := range []PseudonymizationOptions{
+			{ParticipantIDs: "encrypt"},
+			{TimestampPrecision: "week"},
+			{RedactColumns: []string{"["}},
+		} {
+			if err := o.Validate(); err == nil {
+				t.Errorf("expected error for %v", o)
+			}
+		}
+	})
+}
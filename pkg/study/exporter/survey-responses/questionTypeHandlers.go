@@ -2,6 +2,7 @@ package surveyresponses
 
 import (
 	"log/slog"
+	"sync"
 
 	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
 	studytypes "github.com/case-framework/case-backend/pkg/study/types"
@@ -33,6 +34,32 @@ var questionTypeHandlers = map[string]QuestionTypeHandler{
 	sd.QUESTION_TYPE_UNKNOWN:                         &UnknownTypeHandler{},
 }
 
+var questionTypeHandlersMu sync.RWMutex
+
+// fallbackQuestionTypeHandler is used for question types without a registered handler, it exports the raw responses as JSON
+var fallbackQuestionTypeHandler QuestionTypeHandler = &UnknownTypeHandler{}
+
+// RegisterQuestionTypeHandler sets the handler used to export questions of the given type. Can be used to support custom
+// item components: response components with the role questionType are recognised in the survey definition from now on.
+func RegisterQuestionTypeHandler(questionType string, handler QuestionTypeHandler) {
+	questionTypeHandlersMu.Lock()
+	defer questionTypeHandlersMu.Unlock()
+
+	questionTypeHandlers[questionType] = handler
+	sd.RegisterCustomResponseType(questionType)
+}
+
+func getQuestionTypeHandler(questionType string) (QuestionTypeHandler, bool) {
+	questionTypeHandlersMu.RLock()
+	defer questionTypeHandlersMu.RUnlock()
+
+	handler, ok := questionTypeHandlers[questionType]
+	if !ok {
+		return fallbackQuestionTypeHandler, false
+	}
+	return handler, true
+}
+
 // SingleChoiceHandler implements the QuestionTypeHandler interface for single choice questions
 type SingleChoiceHandler struct{}
 
@@ -331,7 +358,7 @@ func (h *ClozeHandler) GetResponseColumnNames(question sd.SurveyQuestion, questi
 	if len(question.Responses) == 1 {
 		rSlot := question.Responses[0]
 		for _, option := range rSlot.Options {
-			if option.OptionType == sd.OPTION_TYPE_DATE_INPUT || option.OptionType == sd.OPTION_TYPE_NUMBER_INPUT || option.OptionType == sd.OPTION_TYPE_TEXT_INPUT || option.OptionType == sd.OPTION_TYPE_DROPDOWN {
+			if isClozeInput(option.OptionType) {
 				slotKey := question.ID + questionOptionSep + option.ID
 				colNames = append(colNames, slotKey)
 			}
@@ -340,7 +367,7 @@ func (h *ClozeHandler) GetResponseColumnNames(question sd.SurveyQuestion, questi
 	} else {
 		for _, rSlot := range question.Responses {
 			for _, option := range rSlot.Options {
-				if option.OptionType == sd.OPTION_TYPE_DATE_INPUT || option.OptionType == sd.OPTION_TYPE_NUMBER_INPUT || option.OptionType == sd.OPTION_TYPE_TEXT_INPUT || option.OptionType == sd.OPTION_TYPE_DROPDOWN {
+				if isClozeInput(option.OptionType) {
 					slotKey := question.ID + questionOptionSep + rSlot.ID + "." + option.ID
 					colNames = append(colNames, slotKey)
 				}
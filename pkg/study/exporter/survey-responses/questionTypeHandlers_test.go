@@ -0,0 +1,377 @@
+package surveyresponses
+
+import (
+	"reflect"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const testSep = "-"
+
+func ri(key string, value string, items ...*studytypes.ResponseItem) *studytypes.ResponseItem {
+	return &studytypes.ResponseItem{Key: key, Value: value, Items: items}
+}
+
+func itemResponse(items ...*studytypes.ResponseItem) *studytypes.SurveyItemResponse {
+	return &studytypes.SurveyItemResponse{
+		Key:      "S.Q1",
+		Response: ri(sd.RESPONSE_ROOT_KEY, "", items...),
+	}
+}
+
+func opt(id string, optionType string) sd.ResponseOption {
+	return sd.ResponseOption{ID: id, OptionType: optionType}
+}
+
+type handlerTestCase struct {
+	name            string
+	question        sd.SurveyQuestion
+	response        *studytypes.SurveyItemResponse
+	expectedCols    []string
+	expectedValues  map[string]interface{}
+	unexpectedCols  []string
+	expectNoColumns bool
+}
+
+func runHandlerTests(t *testing.T, tests []handlerTestCase) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, ok := questionTypeHandlers[tt.question.QuestionType]
+			if !ok {
+				t.Fatalf("no handler for %s", tt.question.QuestionType)
+			}
+
+			if tt.expectedCols != nil {
+				cols := handler.GetResponseColumnNames(tt.question, testSep)
+				if !reflect.DeepEqual(cols, tt.expectedCols) {
+					t.Errorf("unexpected columns: got %v, expected %v", cols, tt.expectedCols)
+				}
+			}
+
+			values := handler.ParseResponse(tt.question, tt.response, testSep)
+			if tt.expectNoColumns && len(values) > 0 {
+				t.Errorf("expected no values, got %v", values)
+			}
+			for col, expected := range tt.expectedValues {
+				got, ok := values[col]
+				if !ok {
+					t.Errorf("missing value for %s in %v", col, values)
+					continue
+				}
+				if !reflect.DeepEqual(got, expected) {
+					t.Errorf("unexpected value for %s: got %v, expected %v", col, got, expected)
+				}
+			}
+			for _, col := range tt.unexpectedCols {
+				if _, ok := values[col]; ok {
+					t.Errorf("unexpected value for %s: %v", col, values[col])
+				}
+			}
+		})
+	}
+}
+
+func TestAllQuestionTypesHaveHandlers(t *testing.T) {
+	for _, qType := range []string{
+		sd.QUESTION_TYPE_CONSENT, sd.QUESTION_TYPE_SINGLE_CHOICE, sd.QUESTION_TYPE_MULTIPLE_CHOICE,
+		sd.QUESTION_TYPE_TEXT_INPUT, sd.QUESTION_TYPE_NUMBER_INPUT, sd.QUESTION_TYPE_DATE_INPUT,
+		sd.QUESTION_TYPE_DROPDOWN, sd.QUESTION_TYPE_LIKERT, sd.QUESTION_TYPE_LIKERT_GROUP,
+		sd.QUESTION_TYPE_EQ5D_SLIDER, sd.QUESTION_TYPE_NUMERIC_SLIDER, sd.QUESTION_TYPE_RESPONSIVE_TABLE,
+		sd.QUESTION_TYPE_MATRIX, sd.QUESTION_TYPE_RESPONSIVE_SINGLE_CHOICE_ARRAY,
+		sd.QUESTION_TYPE_RESPONSIVE_BIPOLAR_LIKERT_ARRAY, sd.QUESTION_TYPE_CLOZE,
+		sd.QUESTION_TYPE_UNKNOWN, sd.QUESTION_TYPE_EMPTY,
+	} {
+		if _, ok := questionTypeHandlers[qType]; !ok {
+			t.Errorf("no handler for question type %s", qType)
+		}
+	}
+}
+
+func TestSingleChoiceHandlers(t *testing.T) {
+	runHandlerTests(t, []handlerTestCase{
+		{
+			name: "single choice with open option",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_SINGLE_CHOICE, Responses: []sd.ResponseDef{
+				{ID: "scg", Options: []sd.ResponseOption{opt("a", sd.OPTION_TYPE_RADIO), opt("other", sd.OPTION_TYPE_TEXT_INPUT)}},
+			}},
+			response:       itemResponse(ri("scg", "", ri("other", "free text"))),
+			expectedCols:   []string{"S.Q1", "S.Q1-other"},
+			expectedValues: map[string]interface{}{"S.Q1": "other", "S.Q1-other": "free text"},
+		},
+		{
+			name: "single choice with cloze option",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_SINGLE_CHOICE, Responses: []sd.ResponseDef{
+				{ID: "scg", Options: []sd.ResponseOption{opt("a", sd.OPTION_TYPE_RADIO), opt("c", sd.OPTION_TYPE_CLOZE)}},
+			}},
+			response:       itemResponse(ri("scg", "", ri("c", "", ri("t1", "x"), ri("dd", "", ri("o2", ""))))),
+			expectedCols:   []string{"S.Q1"},
+			expectedValues: map[string]interface{}{"S.Q1": "c", "S.Q1-c.t1": "x", "S.Q1-c.dd": "o2"},
+		},
+		{
+			name: "dropdown",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_DROPDOWN, Responses: []sd.ResponseDef{
+				{ID: "ddg", Options: []sd.ResponseOption{opt("1", sd.OPTION_TYPE_DROPDOWN_OPTION), opt("2", sd.OPTION_TYPE_DROPDOWN_OPTION)}},
+			}},
+			response:       itemResponse(ri("ddg", "", ri("2", ""))),
+			expectedCols:   []string{"S.Q1"},
+			expectedValues: map[string]interface{}{"S.Q1": "2"},
+		},
+		{
+			name: "likert group",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_LIKERT_GROUP, Responses: []sd.ResponseDef{
+				{ID: "row1", Options: []sd.ResponseOption{opt("1", sd.OPTION_TYPE_RADIO), opt("2", sd.OPTION_TYPE_RADIO)}},
+				{ID: "row2", Options: []sd.ResponseOption{opt("1", sd.OPTION_TYPE_RADIO), opt("2", sd.OPTION_TYPE_RADIO)}},
+			}},
+			response:       itemResponse(ri("lg", "", ri("row1", "", ri("2", "")), ri("row2", "", ri("1", "")))),
+			expectedCols:   []string{"S.Q1-row1", "S.Q1-row2"},
+			expectedValues: map[string]interface{}{"S.Q1-row1": "2", "S.Q1-row2": "1"},
+		},
+		{
+			name: "responsive single choice array with missing row",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_RESPONSIVE_SINGLE_CHOICE_ARRAY, Responses: []sd.ResponseDef{
+				{ID: "r1", Options: []sd.ResponseOption{opt("a", sd.OPTION_TYPE_RADIO)}},
+				{ID: "r2", Options: []sd.ResponseOption{opt("a", sd.OPTION_TYPE_RADIO)}},
+			}},
+			response:       itemResponse(ri("rsca", "", ri("r1", "", ri("a", "")))),
+			expectedCols:   []string{"S.Q1-r1", "S.Q1-r2"},
+			expectedValues: map[string]interface{}{"S.Q1-r1": "a"},
+			unexpectedCols: []string{"S.Q1-r2"},
+		},
+	})
+}
+
+func TestMultipleChoiceHandler(t *testing.T) {
+	runHandlerTests(t, []handlerTestCase{
+		{
+			name: "single slot with open field and embedded cloze",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_MULTIPLE_CHOICE, Responses: []sd.ResponseDef{
+				{ID: "mcg", Options: []sd.ResponseOption{
+					opt("a", sd.OPTION_TYPE_CHECKBOX),
+					opt("b", sd.OPTION_TYPE_CHECKBOX),
+					opt("o", sd.OPTION_TYPE_TEXT_INPUT),
+					opt("e", sd.OPTION_TYPE_EMBEDDED_CLOZE_TEXT_INPUT),
+				}},
+			}},
+			response:     itemResponse(ri("mcg", "", ri("b", ""), ri("o", "note"), ri("e", "5 days"))),
+			expectedCols: []string{"S.Q1-a", "S.Q1-b", "S.Q1-o", "S.Q1-o-open", "S.Q1-e"},
+			expectedValues: map[string]interface{}{
+				"S.Q1-a":      sd.FALSE_VALUE,
+				"S.Q1-b":      sd.TRUE_VALUE,
+				"S.Q1-o":      sd.TRUE_VALUE,
+				"S.Q1-o-open": "note",
+				"S.Q1-e":      "5 days",
+			},
+			unexpectedCols: []string{"S.Q1-e-open"},
+		},
+		{
+			name: "multiple slots with unselected embedded cloze",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_MULTIPLE_CHOICE, Responses: []sd.ResponseDef{
+				{ID: "m1", Options: []sd.ResponseOption{opt("a", sd.OPTION_TYPE_CHECKBOX), opt("e", sd.OPTION_TYPE_EMBEDDED_CLOZE_NUMBER_INPUT)}},
+				{ID: "m2", Options: []sd.ResponseOption{opt("x", sd.OPTION_TYPE_CHECKBOX)}},
+			}},
+			response:       itemResponse(ri("m1", "", ri("a", "")), ri("m2", "", ri("x", ""))),
+			expectedCols:   []string{"S.Q1-m1.a", "S.Q1-m1.e", "S.Q1-m2.x"},
+			expectedValues: map[string]interface{}{"S.Q1-m1.a": sd.TRUE_VALUE, "S.Q1-m1.e": "", "S.Q1-m2.x": sd.TRUE_VALUE},
+			unexpectedCols: []string{"S.Q1-e"},
+		},
+		{
+			name: "no response",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_MULTIPLE_CHOICE, Responses: []sd.ResponseDef{
+				{ID: "mcg", Options: []sd.ResponseOption{opt("a", sd.OPTION_TYPE_CHECKBOX)}},
+			}},
+			response:        nil,
+			expectNoColumns: true,
+		},
+	})
+}
+
+func TestConsentAndInputHandlers(t *testing.T) {
+	consentQuestion := sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_CONSENT, Responses: []sd.ResponseDef{{ID: "consent"}}}
+
+	runHandlerTests(t, []handlerTestCase{
+		{
+			name:           "consent given",
+			question:       consentQuestion,
+			response:       itemResponse(ri("consent", "")),
+			expectedCols:   []string{"S.Q1"},
+			expectedValues: map[string]interface{}{"S.Q1": sd.TRUE_VALUE},
+		},
+		{
+			name:           "consent missing",
+			question:       consentQuestion,
+			response:       nil,
+			expectedValues: map[string]interface{}{"S.Q1": sd.FALSE_VALUE},
+		},
+		{
+			name:           "text input",
+			question:       sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+			response:       itemResponse(ri("input", "hello")),
+			expectedCols:   []string{"S.Q1"},
+			expectedValues: map[string]interface{}{"S.Q1": "hello"},
+		},
+		{
+			name: "number inputs",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_NUMBER_INPUT, Responses: []sd.ResponseDef{
+				{ID: "min"}, {ID: "max"},
+			}},
+			response:       itemResponse(ri("max", "12")),
+			expectedCols:   []string{"S.Q1-min", "S.Q1-max"},
+			expectedValues: map[string]interface{}{"S.Q1-min": "", "S.Q1-max": "12"},
+		},
+		{
+			name:           "date input",
+			question:       sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_DATE_INPUT, Responses: []sd.ResponseDef{{ID: "date"}}},
+			response:       itemResponse(ri("date", "1704067200")),
+			expectedValues: map[string]interface{}{"S.Q1": "1704067200"},
+		},
+		{
+			name:           "slider",
+			question:       sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_NUMERIC_SLIDER, Responses: []sd.ResponseDef{{ID: "slider"}}},
+			response:       itemResponse(ri("slider", "7")),
+			expectedValues: map[string]interface{}{"S.Q1": "7"},
+		},
+		{
+			name:           "eq5d slider",
+			question:       sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_EQ5D_SLIDER, Responses: []sd.ResponseDef{{ID: "eq5d"}}},
+			response:       itemResponse(ri("eq5d", "85")),
+			expectedValues: map[string]interface{}{"S.Q1": "85"},
+		},
+	})
+}
+
+func TestTableAndMatrixHandlers(t *testing.T) {
+	runHandlerTests(t, []handlerTestCase{
+		{
+			name: "responsive table",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_RESPONSIVE_TABLE, Responses: []sd.ResponseDef{
+				{ID: "r1"}, {ID: "r2"},
+			}},
+			response:       itemResponse(ri("table", "", ri("r1", "3"))),
+			expectedCols:   []string{"S.Q1-r1", "S.Q1-r2"},
+			expectedValues: map[string]interface{}{"S.Q1-r1": "3"},
+			unexpectedCols: []string{"S.Q1-r2"},
+		},
+		{
+			name: "matrix with radio row, dropdown and input cells",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_MATRIX, Responses: []sd.ResponseDef{
+				{ID: "row1", ResponseType: sd.QUESTION_TYPE_MATRIX_RADIO_ROW},
+				{ID: "row2.col1", ResponseType: sd.QUESTION_TYPE_MATRIX_DROPDOWN},
+				{ID: "row3.col1", ResponseType: sd.QUESTION_TYPE_MATRIX_INPUT},
+			}},
+			response: itemResponse(
+				ri("row1", "", ri("c2", "")),
+				ri("row2", "", ri("col1", "", ri("opt3", ""))),
+				ri("row3", "", ri("col1", "", ri("in", "12"))),
+			),
+			expectedCols:   []string{"S.Q1-row1", "S.Q1-row2.col1", "S.Q1-row3.col1"},
+			expectedValues: map[string]interface{}{"S.Q1-row1": "c2", "S.Q1-row2.col1": "opt3", "S.Q1-row3.col1": "12"},
+		},
+	})
+}
+
+func TestClozeHandler(t *testing.T) {
+	clozeOptions := []sd.ResponseOption{
+		opt("text1", "text"),
+		opt("t", sd.OPTION_TYPE_TEXT_INPUT),
+		opt("dd", sd.OPTION_TYPE_DROPDOWN),
+		opt("n", sd.OPTION_TYPE_NUMBER_INPUT),
+	}
+
+	runHandlerTests(t, []handlerTestCase{
+		{
+			name: "single slot",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_CLOZE, Responses: []sd.ResponseDef{
+				{ID: "cloze", Options: clozeOptions[1:]},
+			}},
+			response:       itemResponse(ri("cloze", "", ri("t", "abc"), ri("dd", "", ri("o1", "")))),
+			expectedCols:   []string{"S.Q1-t", "S.Q1-dd", "S.Q1-n"},
+			expectedValues: map[string]interface{}{"S.Q1-t": "abc", "S.Q1-dd": "o1", "S.Q1-n": ""},
+		},
+		{
+			name: "multiple slots",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_CLOZE, Responses: []sd.ResponseDef{
+				{ID: "c1", Options: []sd.ResponseOption{opt("t", sd.OPTION_TYPE_TEXT_INPUT)}},
+				{ID: "c2", Options: []sd.ResponseOption{opt("dd", sd.OPTION_TYPE_DROPDOWN)}},
+			}},
+			response:       itemResponse(ri("c1", "", ri("t", "abc")), ri("c2", "", ri("dd", "", ri("o2", "")))),
+			expectedCols:   []string{"S.Q1-c1.t", "S.Q1-c2.dd"},
+			expectedValues: map[string]interface{}{"S.Q1-c1.t": "abc", "S.Q1-c2.dd": "o2"},
+		},
+		{
+			name: "unknown item keys are ignored",
+			question: sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_CLOZE, Responses: []sd.ResponseDef{
+				{ID: "cloze", Options: []sd.ResponseOption{opt("t", sd.OPTION_TYPE_TEXT_INPUT)}},
+			}},
+			response:       itemResponse(ri("cloze", "", ri("removed", "old"))),
+			expectedValues: map[string]interface{}{"S.Q1-t": ""},
+			unexpectedCols: []string{"S.Q1-removed"},
+		},
+	})
+}
+
+func TestEmptyAndUnknownHandlers(t *testing.T) {
+	unknownResponse := ri("x", "", ri("y", "1"))
+
+	runHandlerTests(t, []handlerTestCase{
+		{
+			name:            "empty",
+			question:        sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_EMPTY},
+			response:        itemResponse(ri("x", "1")),
+			expectedCols:    []string{},
+			expectNoColumns: true,
+		},
+		{
+			name:           "unknown",
+			question:       sd.SurveyQuestion{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_UNKNOWN, Responses: []sd.ResponseDef{{ID: "x"}}},
+			response:       itemResponse(unknownResponse),
+			expectedCols:   []string{"S.Q1-x"},
+			expectedValues: map[string]interface{}{"S.Q1-x": unknownResponse},
+		},
+	})
+}
+
+type testCustomHandler struct{}
+
+func (h *testCustomHandler) GetResponseColumnNames(question sd.SurveyQuestion, questionOptionSep string) []string {
+	return []string{question.ID + questionOptionSep + "custom"}
+}
+
+func (h *testCustomHandler) ParseResponse(question sd.SurveyQuestion, response *studytypes.SurveyItemResponse, questionOptionSep string) map[string]interface{} {
+	return map[string]interface{}{question.ID + questionOptionSep + "custom": "parsed"}
+}
+
+func TestRegisterQuestionTypeHandler(t *testing.T) {
+	question := sd.SurveyQuestion{ID: "S.Q1", QuestionType: "testCustomWidget", Responses: []sd.ResponseDef{{ID: "w", ResponseType: "testCustomWidget"}}}
+	response := itemResponse(ri("w", "", ri("v", "1")))
+
+	t.Run("fallback for unregistered type", func(t *testing.T) {
+		cols := getResponseColNamesForQuestion(question, testSep)
+		if !reflect.DeepEqual(cols, []string{"S.Q1-w"}) {
+			t.Errorf("unexpected columns: %v", cols)
+		}
+		values := getResponseColumns(question, response, testSep)
+		if valueToStr(values["S.Q1-w"]) != `{"key":"w","items":[{"key":"v","value":"1"}]}` {
+			t.Errorf("unexpected value: %s", valueToStr(values["S.Q1-w"]))
+		}
+	})
+
+	t.Run("registered handler", func(t *testing.T) {
+		RegisterQuestionTypeHandler("testCustomWidget", &testCustomHandler{})
+		defer func() {
+			questionTypeHandlersMu.Lock()
+			delete(questionTypeHandlers, "testCustomWidget")
+			questionTypeHandlersMu.Unlock()
+		}()
+
+		cols := getResponseColNamesForQuestion(question, testSep)
+		if !reflect.DeepEqual(cols, []string{"S.Q1-custom"}) {
+			t.Errorf("unexpected columns: %v", cols)
+		}
+		values := getResponseColumns(question, response, testSep)
+		if values["S.Q1-custom"] != "parsed" {
+			t.Errorf("unexpected values: %v", values)
+		}
+	})
+}
@@ -12,6 +12,20 @@ func isEmbeddedCloze(optionType string) bool {
 		optionType == sd.OPTION_TYPE_EMBEDDED_CLOZE_NUMBER_INPUT || optionType == sd.OPTION_TYPE_EMBEDDED_CLOZE_TEXT_INPUT
 }
 
+// isClozeInput returns true for the option types of a cloze question that hold a response value
+func isClozeInput(optionType string) bool {
+	return optionType == sd.OPTION_TYPE_DATE_INPUT || optionType == sd.OPTION_TYPE_NUMBER_INPUT ||
+		optionType == sd.OPTION_TYPE_TEXT_INPUT || optionType == sd.OPTION_TYPE_DROPDOWN
+}
+
+// embeddedClozeValue returns the entered value of an embedded cloze item, or the selected key for dropdowns
+func embeddedClozeValue(item *studytypes.ResponseItem) string {
+	if item.Value == "" && len(item.Items) == 1 {
+		return item.Items[0].Key
+	}
+	return item.Value
+}
+
 func parseSimpleSingleChoiceGroup(questionKey string, responseSlotDef sd.ResponseDef, response *studytypes.SurveyItemResponse, questionOptionSep string) map[string]interface{} {
 	responseCols := map[string]interface{}{}
 
@@ -119,10 +133,20 @@ func parseSimpleMultipleChoiceGroup(questionKey string, responseSlotDef sd.Respo
 
 				// Check if selected option is a cloze option
 				cloze := false
+				embeddedCloze := false
 				for _, option := range responseSlotDef.Options {
 					if option.ID == item.Key && option.OptionType == sd.OPTION_TYPE_CLOZE {
 						cloze = true
 					}
+					if option.ID == item.Key && isEmbeddedCloze(option.OptionType) {
+						embeddedCloze = true
+					}
+				}
+
+				// Embedded cloze inputs have no open field column, their column holds the value
+				if embeddedCloze {
+					responseCols[valueKey] = embeddedClozeValue(item)
+					continue
 				}
 
 				// Handle cloze option specifically if we found it
@@ -161,7 +185,7 @@ func parseMultipleChoiceGroupList(questionKey string, responseSlotDefs []sd.Resp
 				for _, option := range rSlot.Options {
 					responseCols[slotKeyPrefix+option.ID] = sd.FALSE_VALUE
 					if isEmbeddedCloze(option.OptionType) {
-						responseCols[questionKey+questionOptionSep+option.ID] = ""
+						responseCols[slotKeyPrefix+option.ID] = ""
 					}
 				}
 
@@ -171,10 +195,20 @@ func parseMultipleChoiceGroupList(questionKey string, responseSlotDefs []sd.Resp
 
 					// Check if selected option is a cloze option
 					cloze := false
+					embeddedCloze := false
 					for _, option := range rSlot.Options {
 						if option.ID == item.Key && option.OptionType == sd.OPTION_TYPE_CLOZE {
 							cloze = true
 						}
+						if option.ID == item.Key && isEmbeddedCloze(option.OptionType) {
+							embeddedCloze = true
+						}
+					}
+
+					// Embedded cloze inputs have no open field column, their column holds the value
+					if embeddedCloze {
+						responseCols[valueKey] = embeddedClozeValue(item)
+						continue
 					}
 
 					// Handle cloze option specifically if we found it
@@ -207,6 +241,12 @@ func parseSimpleCloze(questionKey string, responseSlotDef sd.ResponseDef, respon
 	// Find responses
 	rGroup := retrieveResponseItem(response, sd.RESPONSE_ROOT_KEY+"."+responseSlotDef.ID)
 	if rGroup != nil {
+		for _, option := range responseSlotDef.Options {
+			if isClozeInput(option.OptionType) {
+				responseCols[questionKey+questionOptionSep+option.ID] = ""
+			}
+		}
+
 		for _, item := range rGroup.Items {
 			valueKey := questionKey + questionOptionSep + item.Key
 
@@ -245,6 +285,12 @@ func parseClozeList(questionKey string, responseSlotDefs []sd.ResponseDef, respo
 		if rGroup == nil {
 			continue
 		}
+		for _, option := range rSlot.Options {
+			if isClozeInput(option.OptionType) {
+				responseCols[questionKey+questionOptionSep+rSlot.ID+"."+option.ID] = ""
+			}
+		}
+
 		for _, item := range rGroup.Items {
 			valueKey := questionKey + questionOptionSep + rSlot.ID + "." + item.Key
 
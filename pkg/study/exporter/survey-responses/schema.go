@@ -0,0 +1,103 @@
+package surveyresponses
+
+import (
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/study/exporter/manifest"
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+)
+
+const (
+	COLUMN_CATEGORY_FIXED    = "fixed"
+	COLUMN_CATEGORY_CONTEXT  = "context"
+	COLUMN_CATEGORY_RESPONSE = "response"
+	COLUMN_CATEGORY_META     = "meta"
+)
+
+// Schema describes the columns of the export, for the manifest
+func (re *ResponseExporter) Schema() []manifest.ColumnSchema {
+	return re.parser.schema()
+}
+
+type columnOrigin struct {
+	questionKey  string
+	colType      string
+	introducedIn string
+}
+
+func (rp *ResponseParser) schema() []manifest.ColumnSchema {
+	// oldest version first, so that the first occurrence is the version introducing the column
+	versions := versionsNewestFirst(rp.surveyVersions)
+	origins := map[string]columnOrigin{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		for _, question := range version.Questions {
+			cols := getResponseColNamesForQuestion(question, rp.questionOptionSep)
+			for _, col := range cols {
+				if _, ok := origins[col]; ok {
+					continue
+				}
+				origins[col] = columnOrigin{
+					questionKey:  question.ID,
+					colType:      responseColumnType(question, col, rp.questionOptionSep),
+					introducedIn: version.VersionID,
+				}
+				if rp.valueLabels != nil && rp.valueLabels.mode == VALUE_LABELS_BOTH {
+					origins[col+rp.questionOptionSep+valueLabelColumnSuffix] = columnOrigin{
+						questionKey:  question.ID,
+						colType:      XLSX_COLUMN_TYPE_STRING,
+						introducedIn: version.VersionID,
+					}
+				}
+			}
+
+			metaCols := getMetaColNamesForAllVersions([]sd.SurveyVersionPreview{{Questions: []sd.SurveyQuestion{question}}}, rp.includeMeta, rp.questionOptionSep)
+			for _, col := range metaCols {
+				if _, ok := origins[col]; ok {
+					continue
+				}
+				colType := XLSX_COLUMN_TYPE_STRING
+				if strings.HasSuffix(col, "metaPosition") {
+					colType = XLSX_COLUMN_TYPE_NUMBER
+				}
+				origins[col] = columnOrigin{questionKey: question.ID, colType: colType, introducedIn: version.VersionID}
+			}
+		}
+	}
+
+	columns := []manifest.ColumnSchema{}
+	add := func(col string, category string, origin columnOrigin) {
+		c := manifest.ColumnSchema{
+			Name:         col,
+			Category:     category,
+			QuestionKey:  origin.questionKey,
+			Type:         origin.colType,
+			IntroducedIn: origin.introducedIn,
+		}
+		if header := rp.ColumnHeader(col); header != col {
+			c.Header = header
+		}
+		if c.Type == "" {
+			c.Type = XLSX_COLUMN_TYPE_STRING
+		}
+		columns = append(columns, c)
+	}
+
+	for _, col := range rp.columns.FixedColumns {
+		add(col, COLUMN_CATEGORY_FIXED, columnOrigin{colType: xlsxFixedColumnTypes[col]})
+	}
+	for _, col := range rp.columns.ContextColumns {
+		origin := columnOrigin{}
+		if col == participantInfoColPrefix+PARTICIPANT_INFO_ENTERED_AT {
+			origin.colType = XLSX_COLUMN_TYPE_DATE
+		}
+		add(col, COLUMN_CATEGORY_CONTEXT, origin)
+	}
+	for _, col := range rp.columns.ResponseColumns {
+		add(col, COLUMN_CATEGORY_RESPONSE, origins[col])
+	}
+	for _, col := range rp.columns.MetaColumns {
+		add(col, COLUMN_CATEGORY_META, origins[col])
+	}
+	return columns
+}
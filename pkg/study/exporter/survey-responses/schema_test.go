@@ -0,0 +1,57 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/case-framework/case-backend/pkg/study/exporter/manifest"
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+)
+
+func TestExportSchema(t *testing.T) {
+	parser, err := NewResponseParser("S", []sd.SurveyVersionPreview{
+		{VersionID: "v2", Published: 200, Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_NUMBER_INPUT, Responses: []sd.ResponseDef{{ID: "number"}}},
+			{ID: "S.Q2", QuestionType: sd.QUESTION_TYPE_DATE_INPUT, Responses: []sd.ResponseDef{{ID: "date"}}},
+		}},
+		{VersionID: "v1", Published: 100, Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_NUMBER_INPUT, Responses: []sd.ResponseDef{{ID: "number"}}},
+		}},
+	}, true, &IncludeMeta{Postion: true}, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter, err := NewResponseExporterWithOptions(parser, &bytes.Buffer{}, ExportOptions{Format: EXPORT_FORMAT_WIDE})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns := map[string]manifest.ColumnSchema{}
+	names := []string{}
+	for _, c := range exporter.Schema() {
+		columns[c.Name] = c
+		names = append(names, c.Name)
+	}
+	if len(names) != len(parser.columns.allColumns()) {
+		t.Fatalf("schema should describe every column: %v", names)
+	}
+	for i, col := range parser.columns.allColumns() {
+		if names[i] != col {
+			t.Fatalf("schema order differs from the export: %v", names)
+		}
+	}
+
+	expected := map[string]manifest.ColumnSchema{
+		"participantID":   {Name: "participantID", Category: COLUMN_CATEGORY_FIXED, Type: XLSX_COLUMN_TYPE_STRING},
+		"submitted":       {Name: "submitted", Category: COLUMN_CATEGORY_FIXED, Type: XLSX_COLUMN_TYPE_DATE},
+		"language":        {Name: "language", Category: COLUMN_CATEGORY_CONTEXT, Type: XLSX_COLUMN_TYPE_STRING},
+		"Q1":              {Name: "Q1", Category: COLUMN_CATEGORY_RESPONSE, QuestionKey: "Q1", Type: XLSX_COLUMN_TYPE_NUMBER, IntroducedIn: "v1"},
+		"Q2":              {Name: "Q2", Category: COLUMN_CATEGORY_RESPONSE, QuestionKey: "Q2", Type: XLSX_COLUMN_TYPE_DATE, IntroducedIn: "v2"},
+		"Q2-metaPosition": {Name: "Q2-metaPosition", Category: COLUMN_CATEGORY_META, QuestionKey: "Q2", Type: XLSX_COLUMN_TYPE_NUMBER, IntroducedIn: "v2"},
+	}
+	for name, want := range expected {
+		if got := columns[name]; got != want {
+			t.Errorf("column %s: expected %+v, got %+v", name, want, got)
+		}
+	}
+}
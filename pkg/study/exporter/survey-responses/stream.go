@@ -0,0 +1,118 @@
+package surveyresponses
+
+import (
+	"context"
+	"errors"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultStreamBufferSize       = 100
+	defaultStreamProgressInterval = 1000
+)
+
+// ResponseSource calls yield for every response to export, in export order, and stops with the error returned by yield
+type ResponseSource func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error
+
+// ResponseStreamer is implemented by the study DB service
+type ResponseStreamer interface {
+	StreamResponses(ctx context.Context, instanceID string, studyKey string, filter bson.M, sort bson.M, fn func(r studytypes.SurveyResponse) error) error
+}
+
+// ResponsesFromDB returns a source reading the responses matching the filter with a DB cursor
+func ResponsesFromDB(db ResponseStreamer, instanceID string, studyKey string, filter bson.M, sort bson.M) ResponseSource {
+	return func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error {
+		return db.StreamResponses(ctx, instanceID, studyKey, filter, sort, yield)
+	}
+}
+
+type StreamOptions struct {
+	// BufferSize is the number of responses read ahead of the writer. Reading pauses while the buffer is full.
+	BufferSize int
+	// ProgressInterval is the number of written responses between two OnProgress calls and flushes of the output
+	ProgressInterval int
+	// OnProgress is called with the number of responses written so far
+	OnProgress func(written int)
+	// OnResponseWritten is called after each response has been written, e.g. to collect manifest infos
+	OnResponseWritten func(r *studytypes.SurveyResponse)
+}
+
+// Stream reads the responses from source and writes them one by one to the exporter's writer, so that memory use does not
+// depend on the number of exported responses. Finish still has to be called afterwards. Returns the number of written responses.
+func (re *ResponseExporter) Stream(ctx context.Context, source ResponseSource, opts StreamOptions) (int, error) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultStreamBufferSize
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = defaultStreamProgressInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make(chan studytypes.SurveyResponse, opts.BufferSize)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(responses)
+		readErr <- source(ctx, func(r studytypes.SurveyResponse) error {
+			select {
+			case responses <- r:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	written := 0
+	for r := range responses {
+		if err := re.WriteResponse(&r); err != nil {
+			cancel()
+			// wait for the reader to stop before returning
+			for range responses {
+			}
+			return written, err
+		}
+		written += 1
+
+		if opts.OnResponseWritten != nil {
+			opts.OnResponseWritten(&r)
+		}
+		if written%opts.ProgressInterval == 0 {
+			if err := re.flush(); err != nil {
+				cancel()
+				for range responses {
+				}
+				return written, err
+			}
+			if opts.OnProgress != nil {
+				opts.OnProgress(written)
+			}
+		}
+	}
+
+	if err := <-readErr; err != nil && !errors.Is(err, context.Canceled) {
+		return written, err
+	}
+	if err := ctx.Err(); err != nil {
+		return written, err
+	}
+	if err := re.flush(); err != nil {
+		return written, err
+	}
+	if opts.OnProgress != nil && written%opts.ProgressInterval != 0 {
+		opts.OnProgress(written)
+	}
+	return written, nil
+}
+
+// flush passes buffered CSV rows on to the underlying writer
+func (re *ResponseExporter) flush() error {
+	if re.csvWriter == nil {
+		return nil
+	}
+	re.csvWriter.Flush()
+	return re.csvWriter.Error()
+}
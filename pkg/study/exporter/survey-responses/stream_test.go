@@ -0,0 +1,142 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testStreamSource(n int) ResponseSource {
+	return func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error {
+		for i := 0; i < n; i++ {
+			r := studytypes.SurveyResponse{
+				ID:            primitive.NewObjectID(),
+				Key:           "S",
+				ParticipantID: "p1",
+				VersionID:     "v1",
+				Responses: []studytypes.SurveyItemResponse{
+					{Key: "S.Q1", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("input", "hello"))},
+				},
+			}
+			if err := yield(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func exporterTestParser(t *testing.T) *ResponseParser {
+	parser, err := NewResponseParser("S", []sd.SurveyVersionPreview{
+		{VersionID: "v1", Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+		}},
+	}, false, nil, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parser
+}
+
+func testStreamExporter(t *testing.T, out *bytes.Buffer, format string) *ResponseExporter {
+	exporter, err := NewResponseExporter(exporterTestParser(t), out, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exporter
+}
+
+func TestResponseExporterStream(t *testing.T) {
+	t.Run("writes all responses and reports progress", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		exporter := testStreamExporter(t, out, "wide")
+
+		progress := []int{}
+		observed := 0
+		written, err := exporter.Stream(context.Background(), testStreamSource(25), StreamOptions{
+			BufferSize:        2,
+			ProgressInterval:  10,
+			OnProgress:        func(n int) { progress = append(progress, n) },
+			OnResponseWritten: func(r *studytypes.SurveyResponse) { observed += 1 },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := exporter.Finish(); err != nil {
+			t.Fatal(err)
+		}
+
+		if written != 25 || observed != 25 {
+			t.Errorf("unexpected counts: written %d, observed %d", written, observed)
+		}
+		if len(progress) != 3 || progress[0] != 10 || progress[1] != 20 || progress[2] != 25 {
+			t.Errorf("unexpected progress calls: %v", progress)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 26 {
+			t.Errorf("expected header and 25 rows, got %d lines", len(lines))
+		}
+		if !strings.Contains(lines[1], "hello") {
+			t.Errorf("response value missing: %s", lines[1])
+		}
+	})
+
+	t.Run("output is flushed incrementally", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		exporter := testStreamExporter(t, out, "wide")
+
+		sizes := []int{}
+		_, err := exporter.Stream(context.Background(), testStreamSource(3), StreamOptions{
+			ProgressInterval: 1,
+			OnProgress:       func(n int) { sizes = append(sizes, out.Len()) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sizes) != 3 || !(sizes[0] < sizes[1] && sizes[1] < sizes[2]) {
+			t.Errorf("expected growing output at each progress call, got %v", sizes)
+		}
+	})
+
+	t.Run("source error is returned", func(t *testing.T) {
+		exporter := testStreamExporter(t, &bytes.Buffer{}, "json")
+		sourceErr := errors.New("cursor failed")
+
+		_, err := exporter.Stream(context.Background(), func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error {
+			if err := testStreamSource(5)(ctx, yield); err != nil {
+				return err
+			}
+			return sourceErr
+		}, StreamOptions{})
+		if !errors.Is(err, sourceErr) {
+			t.Errorf("expected source error, got %v", err)
+		}
+	})
+
+	t.Run("cancelled context stops reading", func(t *testing.T) {
+		exporter := testStreamExporter(t, &bytes.Buffer{}, "long")
+		ctx, cancel := context.WithCancel(context.Background())
+
+		written, err := exporter.Stream(ctx, testStreamSource(1000), StreamOptions{
+			BufferSize:       1,
+			ProgressInterval: 1,
+			OnProgress: func(n int) {
+				if n == 5 {
+					cancel()
+				}
+			},
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if written >= 1000 {
+			t.Errorf("expected stream to stop early, wrote %d", written)
+		}
+	})
+}
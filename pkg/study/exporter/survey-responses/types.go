@@ -32,3 +32,13 @@ type ColumnNames struct {
 	ResponseColumns []string
 	MetaColumns     []string
 }
+
+// allColumns returns the columns in the order of the wide format
+func (c ColumnNames) allColumns() []string {
+	cols := []string{}
+	cols = append(cols, c.FixedColumns...)
+	cols = append(cols, c.ContextColumns...)
+	cols = append(cols, c.ResponseColumns...)
+	cols = append(cols, c.MetaColumns...)
+	return cols
+}
@@ -1,9 +1,11 @@
 package surveyresponses
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 
 	studydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
@@ -68,59 +70,76 @@ func getMetaColNamesForAllVersions(
 		return []string{}
 	}
 
-	colNames := map[string]bool{}
-	for _, version := range surveyVersions {
+	versionCols := [][]string{}
+	for _, version := range versionsNewestFirst(surveyVersions) {
+		colNames := []string{}
 		for _, question := range version.Questions {
 			if includeMeta.InitTimes {
-				colName := question.ID + questionOptionSep + "metaInit"
-				colNames[colName] = true
+				colNames = append(colNames, question.ID+questionOptionSep+"metaInit")
 			}
 
 			if includeMeta.DisplayedTimes {
-				colName := question.ID + questionOptionSep + "metaDisplayed"
-				colNames[colName] = true
+				colNames = append(colNames, question.ID+questionOptionSep+"metaDisplayed")
 			}
 
 			if includeMeta.ResponsedTimes {
-				colName := question.ID + questionOptionSep + "metaResponse"
-				colNames[colName] = true
+				colNames = append(colNames, question.ID+questionOptionSep+"metaResponse")
 			}
 
 			if includeMeta.Postion {
-				colName := question.ID + questionOptionSep + "metaPosition"
-				colNames[colName] = true
+				colNames = append(colNames, question.ID+questionOptionSep+"metaPosition")
 			}
 		}
+		versionCols = append(versionCols, colNames)
 	}
 
-	uniqueColNames := []string{}
-	for colName := range colNames {
-		uniqueColNames = append(uniqueColNames, colName)
-	}
-
-	return uniqueColNames
+	return mergeColumnOrder(versionCols)
 }
 
 func getResponseColNamesForAllVersions(
 	surveyVersions []studydefinition.SurveyVersionPreview,
 	questionOptionSep string,
 ) []string {
-	colNames := map[string]bool{}
-	for _, version := range surveyVersions {
+	versionCols := [][]string{}
+	for _, version := range versionsNewestFirst(surveyVersions) {
+		colNames := []string{}
 		for _, question := range version.Questions {
-			newColNames := getResponseColNamesForQuestion(question, questionOptionSep)
-			for _, colName := range newColNames {
-				colNames[colName] = true
-			}
+			colNames = append(colNames, getResponseColNamesForQuestion(question, questionOptionSep)...)
 		}
+		versionCols = append(versionCols, colNames)
 	}
 
-	uniqueColNames := []string{}
-	for colName := range colNames {
-		uniqueColNames = append(uniqueColNames, colName)
-	}
+	return mergeColumnOrder(versionCols)
+}
 
-	return uniqueColNames
+// versionsNewestFirst orders survey versions by publication time, latest first
+func versionsNewestFirst(surveyVersions []studydefinition.SurveyVersionPreview) []studydefinition.SurveyVersionPreview {
+	versions := slices.Clone(surveyVersions)
+	slices.SortStableFunc(versions, func(a, b studydefinition.SurveyVersionPreview) int {
+		return cmp.Compare(b.Published, a.Published)
+	})
+	return versions
+}
+
+// mergeColumnOrder combines the column orders of the survey versions (newest first) into one deterministic order.
+// Columns keep the survey definition order of the newest version containing them, columns of removed questions are
+// placed after the column they followed in the older version.
+func mergeColumnOrder(versionCols [][]string) []string {
+	merged := []string{}
+	known := map[string]bool{}
+	for _, cols := range versionCols {
+		insertAt := 0
+		for _, col := range cols {
+			if known[col] {
+				insertAt = slices.Index(merged, col) + 1
+				continue
+			}
+			merged = slices.Insert(merged, insertAt, col)
+			known[col] = true
+			insertAt += 1
+		}
+	}
+	return merged
 }
 
 func getResponseColumns(
@@ -128,11 +147,7 @@ func getResponseColumns(
 	response *studytypes.SurveyItemResponse,
 	questionOptionSep string,
 ) map[string]interface{} {
-	qTypeHandl, ok := questionTypeHandlers[question.QuestionType]
-	if !ok {
-		slog.Error("no handler found for question type", slog.String("questionType", question.QuestionType))
-		return map[string]interface{}{}
-	}
+	qTypeHandl, _ := getQuestionTypeHandler(question.QuestionType)
 	return qTypeHandl.ParseResponse(question, response, questionOptionSep)
 }
 
@@ -140,10 +155,9 @@ func getResponseColNamesForQuestion(
 	question studydefinition.SurveyQuestion,
 	questionOptionSep string,
 ) []string {
-	qTypeHandl, ok := questionTypeHandlers[question.QuestionType]
+	qTypeHandl, ok := getQuestionTypeHandler(question.QuestionType)
 	if !ok {
-		slog.Error("no handler found for question type", slog.String("questionType", question.QuestionType))
-		return []string{}
+		slog.Warn("no handler found for question type, responses are exported as JSON", slog.String("questionType", question.QuestionType), slog.String("questionKey", question.ID))
 	}
 	return qTypeHandl.GetResponseColumnNames(question, questionOptionSep)
 }
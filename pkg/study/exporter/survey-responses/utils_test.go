@@ -1,8 +1,11 @@
 package surveyresponses
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
 	studytypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
@@ -247,3 +250,68 @@ func TestRetrieveResponseItemByShortKey(t *testing.T) {
 	})
 
 }
+
+func TestMergeColumnOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		versionCols [][]string
+		expected    []string
+	}{
+		{
+			name:        "single version keeps definition order",
+			versionCols: [][]string{{"Q2", "Q1", "Q3"}},
+			expected:    []string{"Q2", "Q1", "Q3"},
+		},
+		{
+			name:        "removed question stays after its predecessor",
+			versionCols: [][]string{{"Q1", "Q3"}, {"Q1", "Q2", "Q3"}},
+			expected:    []string{"Q1", "Q2", "Q3"},
+		},
+		{
+			name:        "removed first question",
+			versionCols: [][]string{{"Q2", "Q3"}, {"Q1", "Q2"}},
+			expected:    []string{"Q1", "Q2", "Q3"},
+		},
+		{
+			name:        "newest version order wins",
+			versionCols: [][]string{{"Q3", "Q1"}, {"Q1", "Q2", "Q3"}},
+			expected:    []string{"Q3", "Q1", "Q2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mergeColumnOrder(tt.versionCols)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestColumnOrderFollowsSurveyDefinition(t *testing.T) {
+	versions := []sd.SurveyVersionPreview{
+		{VersionID: "v2", Published: 200, Questions: []sd.SurveyQuestion{
+			{ID: "S.Zeta", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+			{ID: "S.Alpha", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+		}},
+		{VersionID: "v1", Published: 100, Questions: []sd.SurveyQuestion{
+			{ID: "S.Zeta", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+			{ID: "S.Old", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+		}},
+	}
+
+	// the order of the versions passed in doesn't matter
+	for _, input := range [][]sd.SurveyVersionPreview{versions, {versions[1], versions[0]}} {
+		parser, err := NewResponseParser("S", slices.Clone(input), false, &IncludeMeta{Postion: true}, "-", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cols := strings.Join(parser.columns.ResponseColumns, ","); cols != "S.Zeta,S.Old,S.Alpha" {
+			t.Errorf("unexpected response columns: %s", cols)
+		}
+		if cols := strings.Join(parser.columns.MetaColumns, ","); cols != "S.Zeta-metaPosition,S.Old-metaPosition,S.Alpha-metaPosition" {
+			t.Errorf("unexpected meta columns: %s", cols)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package surveyresponses
+
+import (
+	"slices"
+	"strings"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// VALIDATION_ISSUES_COLUMN lists the validation issues stored with the response at submission, as "itemKey:code"
+// separated by ";"
+const VALIDATION_ISSUES_COLUMN = "validationIssues"
+
+func addValidationIssuesColumn(parser *ResponseParser) {
+	if slices.Contains(parser.columns.ContextColumns, VALIDATION_ISSUES_COLUMN) {
+		return
+	}
+	ctxCols := slices.Clone(parser.columns.ContextColumns)
+	parser.columns.ContextColumns = append(ctxCols, VALIDATION_ISSUES_COLUMN)
+}
+
+func formatValidationIssues(issues []studytypes.ResponseValidationIssue) string {
+	values := make([]string, len(issues))
+	for i, issue := range issues {
+		values[i] = issue.Code
+		if issue.ItemKey != "" {
+			values[i] = issue.ItemKey + ":" + issue.Code
+		}
+	}
+	return strings.Join(values, ";")
+}
+
+func applyValidationIssues(parsedResponse *ParsedResponse, issues []studytypes.ResponseValidationIssue) {
+	// copy, the context map is shared with the raw response
+	ctx := make(map[string]string, len(parsedResponse.Context)+1)
+	for k, v := range parsedResponse.Context {
+		ctx[k] = v
+	}
+	ctx[VALIDATION_ISSUES_COLUMN] = formatValidationIssues(issues)
+	parsedResponse.Context = ctx
+}
@@ -0,0 +1,70 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFormatValidationIssues(t *testing.T) {
+	got := formatValidationIssues([]studytypes.ResponseValidationIssue{
+		{ItemKey: "S.Q1", Code: "missingResponse"},
+		{Code: "unknownSurveyVersion"},
+	})
+	if got != "S.Q1:missingResponse;unknownSurveyVersion" {
+		t.Errorf("unexpected value: %s", got)
+	}
+	if got := formatValidationIssues(nil); got != "" {
+		t.Errorf("unexpected value: %s", got)
+	}
+}
+
+func TestExportValidationIssues(t *testing.T) {
+	parser, err := NewResponseParser("S", []sd.SurveyVersionPreview{
+		{VersionID: "v1", Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+		}},
+	}, false, nil, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	exporter, err := NewResponseExporterWithOptions(parser, out, ExportOptions{Format: EXPORT_FORMAT_WIDE, ValidationIssues: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawResp := &studytypes.SurveyResponse{
+		ID: primitive.NewObjectID(), ParticipantID: "p1", VersionID: "v1",
+		Context: map[string]string{"language": "en"},
+		ValidationIssues: []studytypes.ResponseValidationIssue{
+			{ItemKey: "S.Q1", Code: "outOfRange"},
+		},
+	}
+	if err := exporter.WriteResponse(rawResp); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rawResp.Context[VALIDATION_ISSUES_COLUMN]; ok {
+		t.Error("context of the raw response was modified")
+	}
+
+	records, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := slices.Index(records[0], VALIDATION_ISSUES_COLUMN)
+	if col < 0 {
+		t.Fatalf("column missing: %v", records[0])
+	}
+	if records[1][col] != "S.Q1:outOfRange" {
+		t.Errorf("unexpected value: %s", records[1][col])
+	}
+}
@@ -0,0 +1,244 @@
+package surveyresponses
+
+import (
+	"fmt"
+	"strings"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+)
+
+// Value label modes: option labels are taken from the survey definition, so the survey versions have to be extracted
+// with a label language (ExtractOptions.UseLabelLang).
+const (
+	// VALUE_LABELS_NONE exports option keys only
+	VALUE_LABELS_NONE = ""
+	// VALUE_LABELS_REPLACE exports option labels instead of option keys, in cell values and column headers
+	VALUE_LABELS_REPLACE = "labels"
+	// VALUE_LABELS_BOTH keeps the option keys, adds a label column for every selected option and the labels to the column headers
+	VALUE_LABELS_BOTH = "both"
+)
+
+const valueLabelColumnSuffix = "label"
+
+type valueLabels struct {
+	mode string
+	// survey version ID -> column -> option key -> option label
+	values map[string]map[string]map[string]string
+	// column -> header used in the export
+	headers map[string]string
+}
+
+func IsSupportedValueLabelMode(mode string) bool {
+	return mode == VALUE_LABELS_NONE || mode == VALUE_LABELS_REPLACE || mode == VALUE_LABELS_BOTH
+}
+
+// UseValueLabels sets how option labels are exported, see VALUE_LABELS_*. Can be called again to change the mode.
+func (rp *ResponseParser) UseValueLabels(mode string) error {
+	if !IsSupportedValueLabelMode(mode) {
+		return fmt.Errorf("unsupported value label mode: %s", mode)
+	}
+
+	// remove label columns of a previous call
+	respCols := []string{}
+	for _, col := range rp.columns.ResponseColumns {
+		if rp.valueLabels == nil || !rp.valueLabels.isLabelColumn(col, rp.questionOptionSep) {
+			respCols = append(respCols, col)
+		}
+	}
+	rp.columns.ResponseColumns = respCols
+	rp.valueLabels = nil
+
+	if mode == VALUE_LABELS_NONE {
+		return nil
+	}
+
+	vl := &valueLabels{
+		mode:    mode,
+		values:  map[string]map[string]map[string]string{},
+		headers: map[string]string{},
+	}
+
+	labelCols := map[string]bool{}
+	headerLabels := map[string]string{}
+	for _, version := range rp.surveyVersions {
+		versionLabels := map[string]map[string]string{}
+		for _, question := range version.Questions {
+			for col, labels := range optionLabelsOfColumns(question, rp.questionOptionSep) {
+				versionLabels[col] = labels
+				labelCols[col] = true
+			}
+			for _, col := range getResponseColNamesForQuestion(question, rp.questionOptionSep) {
+				if header, ok := labeledColumnHeader(question, col, rp.questionOptionSep, mode); ok {
+					headerLabels[col] = header
+				}
+			}
+		}
+		vl.values[version.VersionID] = versionLabels
+	}
+
+	if mode == VALUE_LABELS_BOTH {
+		// each label column follows the column of its option keys
+		withLabelCols := []string{}
+		for _, col := range rp.columns.ResponseColumns {
+			withLabelCols = append(withLabelCols, col)
+			if labelCols[col] {
+				withLabelCols = append(withLabelCols, col+rp.questionOptionSep+valueLabelColumnSuffix)
+			}
+		}
+		rp.columns.ResponseColumns = withLabelCols
+	}
+
+	// labels are only used as headers if they keep the headers unique
+	used := map[string]int{}
+	for _, col := range rp.columns.allColumns() {
+		header := col
+		if h, ok := headerLabels[col]; ok {
+			header = h
+		}
+		used[header] += 1
+	}
+	for col, header := range headerLabels {
+		if used[header] == 1 {
+			vl.headers[col] = header
+		}
+	}
+
+	rp.valueLabels = vl
+	return nil
+}
+
+// ColumnHeader returns the name of the column to use in the export header
+func (rp *ResponseParser) ColumnHeader(col string) string {
+	if rp.valueLabels == nil {
+		return col
+	}
+	if header, ok := rp.valueLabels.headers[col]; ok {
+		return header
+	}
+	return col
+}
+
+func (rp *ResponseParser) columnHeaders(cols []string) []string {
+	headers := make([]string, len(cols))
+	for i, col := range cols {
+		headers[i] = rp.ColumnHeader(col)
+	}
+	return headers
+}
+
+// withColumnHeaders returns the flat response object with column headers as keys
+func (rp *ResponseParser) withColumnHeaders(flatObj map[string]interface{}) map[string]interface{} {
+	if rp.valueLabels == nil || len(rp.valueLabels.headers) == 0 {
+		return flatObj
+	}
+	result := make(map[string]interface{}, len(flatObj))
+	for col, v := range flatObj {
+		result[rp.ColumnHeader(col)] = v
+	}
+	return result
+}
+
+// apply replaces or complements option keys in the parsed response columns of a question
+func (vl *valueLabels) apply(versionID string, responseColumns map[string]interface{}, questionOptionSep string) {
+	versionLabels := vl.values[versionID]
+	for col, value := range responseColumns {
+		key, ok := value.(string)
+		if !ok || key == "" {
+			continue
+		}
+		label, ok := versionLabels[col][key]
+		if !ok || label == "" {
+			continue
+		}
+
+		if vl.mode == VALUE_LABELS_REPLACE {
+			responseColumns[col] = label
+		} else {
+			responseColumns[col+questionOptionSep+valueLabelColumnSuffix] = label
+		}
+	}
+}
+
+func (vl *valueLabels) isLabelColumn(col string, questionOptionSep string) bool {
+	suffix := questionOptionSep + valueLabelColumnSuffix
+	if !strings.HasSuffix(col, suffix) {
+		return false
+	}
+	base := strings.TrimSuffix(col, suffix)
+	for _, versionLabels := range vl.values {
+		if _, ok := versionLabels[base]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// optionLabelsOfColumns returns the option labels for columns containing the key of the selected option
+func optionLabelsOfColumns(question sd.SurveyQuestion, questionOptionSep string) map[string]map[string]string {
+	result := map[string]map[string]string{}
+
+	handler, _ := getQuestionTypeHandler(question.QuestionType)
+	switch handler.(type) {
+	case *SingleChoiceHandler, *SingleChoiceGroupHandler, *MatrixHandler:
+	default:
+		return result
+	}
+
+	for _, col := range handler.GetResponseColumnNames(question, questionOptionSep) {
+		for _, rSlot := range question.Responses {
+			if col != question.ID+questionOptionSep+rSlot.ID && !(col == question.ID && len(question.Responses) == 1) {
+				continue
+			}
+			labels := map[string]string{}
+			for _, option := range rSlot.Options {
+				if option.Label != "" {
+					labels[option.ID] = option.Label
+				}
+			}
+			if len(labels) > 0 {
+				result[col] = labels
+			}
+		}
+	}
+	return result
+}
+
+// labeledColumnHeader returns the header for columns belonging to a response slot or option with a label
+func labeledColumnHeader(question sd.SurveyQuestion, col string, questionOptionSep string, mode string) (string, bool) {
+	format := func(keyPart string, labeledPart string, label string) string {
+		if mode == VALUE_LABELS_BOTH {
+			return col + " (" + label + ")"
+		}
+		return labeledPart + strings.TrimPrefix(col, keyPart)
+	}
+
+	for _, rSlot := range question.Responses {
+		slotKey := question.ID + questionOptionSep + rSlot.ID
+		slotLabel := rSlot.ID
+		if rSlot.Label != "" {
+			slotLabel = rSlot.Label
+		}
+
+		for _, option := range rSlot.Options {
+			if option.Label == "" {
+				continue
+			}
+			candidates := [][2]string{
+				{slotKey + "." + option.ID, question.ID + questionOptionSep + slotLabel + "." + option.Label},
+			}
+			if len(question.Responses) == 1 {
+				candidates = append(candidates, [2]string{question.ID + questionOptionSep + option.ID, question.ID + questionOptionSep + option.Label})
+			}
+			for _, c := range candidates {
+				if col == c[0] || strings.HasPrefix(col, c[0]+questionOptionSep) {
+					return format(c[0], c[1], option.Label), true
+				}
+			}
+		}
+
+		if rSlot.Label != "" && col == slotKey {
+			return format(slotKey, question.ID+questionOptionSep+rSlot.Label, rSlot.Label), true
+		}
+	}
+	return "", false
+}
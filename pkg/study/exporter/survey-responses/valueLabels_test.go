@@ -0,0 +1,124 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func valueLabelTestExport(t *testing.T, mode string) []map[string]string {
+	parser, err := NewResponseParser("S", []sd.SurveyVersionPreview{
+		{VersionID: "v1", Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_SINGLE_CHOICE, Responses: []sd.ResponseDef{
+				{ID: "scg", Options: []sd.ResponseOption{
+					{ID: "a", OptionType: sd.OPTION_TYPE_RADIO, Label: "Yes"},
+					{ID: "b", OptionType: sd.OPTION_TYPE_RADIO, Label: "No"},
+				}},
+			}},
+			{ID: "S.Q2", QuestionType: sd.QUESTION_TYPE_MULTIPLE_CHOICE, Responses: []sd.ResponseDef{
+				{ID: "mcg", Options: []sd.ResponseOption{
+					{ID: "x", OptionType: sd.OPTION_TYPE_CHECKBOX, Label: "Fever"},
+					{ID: "y", OptionType: sd.OPTION_TYPE_CHECKBOX, Label: "Cough"},
+				}},
+			}},
+		}},
+	}, false, nil, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	exporter, err := NewResponseExporterWithOptions(parser, out, ExportOptions{Format: EXPORT_FORMAT_WIDE, ValueLabels: mode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error {
+		return yield(studytypes.SurveyResponse{ID: primitive.NewObjectID(), VersionID: "v1", Responses: []studytypes.SurveyItemResponse{
+			{Key: "S.Q1", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("scg", "", ri("b", "")))},
+			{Key: "S.Q2", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("mcg", "", ri("x", "")))},
+		}})
+	}
+	if _, err := exporter.Stream(context.Background(), source, StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []map[string]string{}
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, header := range records[0] {
+			row[header] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestValueLabels(t *testing.T) {
+	t.Run("keys only", func(t *testing.T) {
+		row := valueLabelTestExport(t, VALUE_LABELS_NONE)[0]
+		if row["S.Q1"] != "b" || row["S.Q2-x"] != sd.TRUE_VALUE {
+			t.Errorf("unexpected row: %v", row)
+		}
+	})
+
+	t.Run("labels", func(t *testing.T) {
+		row := valueLabelTestExport(t, VALUE_LABELS_REPLACE)[0]
+		if row["S.Q1"] != "No" {
+			t.Errorf("expected label as value: %v", row)
+		}
+		if row["S.Q2-Fever"] != sd.TRUE_VALUE || row["S.Q2-Cough"] != sd.FALSE_VALUE {
+			t.Errorf("expected labels in headers: %v", row)
+		}
+		if _, ok := row["S.Q2-x"]; ok {
+			t.Errorf("unexpected key header: %v", row)
+		}
+	})
+
+	t.Run("both", func(t *testing.T) {
+		row := valueLabelTestExport(t, VALUE_LABELS_BOTH)[0]
+		if row["S.Q1"] != "b" || row["S.Q1-label"] != "No" {
+			t.Errorf("expected key and label: %v", row)
+		}
+		if row["S.Q2-x (Fever)"] != sd.TRUE_VALUE {
+			t.Errorf("expected key and label in header: %v", row)
+		}
+	})
+
+	t.Run("unsupported mode", func(t *testing.T) {
+		parser := exporterTestParser(t)
+		if err := parser.UseValueLabels("translated"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("mode can be changed", func(t *testing.T) {
+		parser, _ := NewResponseParser("S", []sd.SurveyVersionPreview{{VersionID: "v1", Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", QuestionType: sd.QUESTION_TYPE_DROPDOWN, Responses: []sd.ResponseDef{
+				{ID: "ddg", Options: []sd.ResponseOption{{ID: "1", OptionType: sd.OPTION_TYPE_DROPDOWN_OPTION, Label: "One"}}},
+			}},
+		}}}, false, nil, "-", nil)
+		initialCols := len(parser.columns.ResponseColumns)
+
+		_ = parser.UseValueLabels(VALUE_LABELS_BOTH)
+		_ = parser.UseValueLabels(VALUE_LABELS_BOTH)
+		if len(parser.columns.ResponseColumns) != initialCols+1 {
+			t.Errorf("unexpected columns: %v", parser.columns.ResponseColumns)
+		}
+		_ = parser.UseValueLabels(VALUE_LABELS_NONE)
+		if len(parser.columns.ResponseColumns) != initialCols {
+			t.Errorf("label columns not removed: %v", parser.columns.ResponseColumns)
+		}
+	})
+}
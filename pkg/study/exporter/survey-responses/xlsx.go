@@ -0,0 +1,492 @@
+package surveyresponses
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// XLSX export: a metadata sheet describing every column, followed by one data sheet (wide format) per survey version.
+// Rows of the data sheets are buffered in temporary files, the workbook is assembled in Finish.
+
+const (
+	XLSX_COLUMN_TYPE_STRING = "string"
+	XLSX_COLUMN_TYPE_NUMBER = "number"
+	XLSX_COLUMN_TYPE_DATE   = "date"
+)
+
+const (
+	xlsxMetadataSheetName = "metadata"
+	xlsxMaxSheetNameLen   = 31
+	xlsxMaxCellTextLen    = 32767
+
+	// cell style indexes in xlsxStyles
+	xlsxStyleDate   = 1
+	xlsxStyleHeader = 2
+
+	// days between the excel epoch (1899-12-30) and the unix epoch
+	xlsxUnixEpochSerial = 25569
+)
+
+var xlsxFixedColumnTypes = map[string]string{
+	"opened":    XLSX_COLUMN_TYPE_DATE,
+	"submitted": XLSX_COLUMN_TYPE_DATE,
+	"arrived":   XLSX_COLUMN_TYPE_DATE,
+}
+
+type xlsxSheet struct {
+	name        string
+	versionID   string
+	columns     []string
+	columnTypes map[string]string
+	tmpFile     *os.File
+	buf         *bufio.Writer
+	rowCount    int
+}
+
+type xlsxWriter struct {
+	w      io.Writer
+	parser *ResponseParser
+	sheets []*xlsxSheet
+	// sheet index by survey version ID
+	sheetIndex map[string]int
+}
+
+func newXLSXWriter(w io.Writer, parser *ResponseParser) *xlsxWriter {
+	return &xlsxWriter{
+		w:          w,
+		parser:     parser,
+		sheetIndex: map[string]int{},
+	}
+}
+
+func (xw *xlsxWriter) WriteResponse(rawResp *studytypes.SurveyResponse, flatObj map[string]interface{}) error {
+	version, err := findSurveyVersion(rawResp.VersionID, rawResp.ArrivedAt, xw.parser.surveyVersions)
+	if err != nil {
+		return err
+	}
+
+	sheet, err := xw.sheetForVersion(version)
+	if err != nil {
+		return err
+	}
+
+	sheet.rowCount += 1
+	return writeXLSXRow(sheet.buf, sheet.rowCount+1, sheet.columns, func(col string) (string, string) {
+		return valueToStr(flatObj[col]), sheet.columnTypes[col]
+	})
+}
+
+func (xw *xlsxWriter) sheetForVersion(version sd.SurveyVersionPreview) (*xlsxSheet, error) {
+	if i, ok := xw.sheetIndex[version.VersionID]; ok {
+		return xw.sheets[i], nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "xlsx-sheet-*.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	columns, columnTypes := xw.parser.versionColumns(version)
+	sheet := &xlsxSheet{
+		name:        xw.uniqueSheetName(version.VersionID),
+		versionID:   version.VersionID,
+		columns:     columns,
+		columnTypes: columnTypes,
+		tmpFile:     tmpFile,
+		buf:         bufio.NewWriter(tmpFile),
+	}
+	xw.sheetIndex[version.VersionID] = len(xw.sheets)
+	xw.sheets = append(xw.sheets, sheet)
+
+	err = writeXLSXRow(sheet.buf, 1, columns, func(col string) (string, string) { return xw.parser.ColumnHeader(col), "" })
+	return sheet, err
+}
+
+func (xw *xlsxWriter) uniqueSheetName(versionID string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, versionID)
+	if name == "" {
+		name = "unknown version"
+	}
+	runes := []rune(name)
+	if len(runes) > xlsxMaxSheetNameLen {
+		name = string(runes[:xlsxMaxSheetNameLen])
+	}
+
+	isUsed := func(n string) bool {
+		if strings.EqualFold(n, xlsxMetadataSheetName) {
+			return true
+		}
+		for _, s := range xw.sheets {
+			if strings.EqualFold(s.name, n) {
+				return true
+			}
+		}
+		return false
+	}
+	unique := name
+	for i := 2; isUsed(unique); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		base := []rune(name)
+		if len(base)+len(suffix) > xlsxMaxSheetNameLen {
+			base = base[:xlsxMaxSheetNameLen-len(suffix)]
+		}
+		unique = string(base) + suffix
+	}
+	return unique
+}
+
+// Close assembles the workbook and removes the temporary files
+func (xw *xlsxWriter) Close() error {
+	defer func() {
+		for _, sheet := range xw.sheets {
+			sheet.tmpFile.Close()
+			os.Remove(sheet.tmpFile.Name())
+		}
+	}()
+
+	zw := zip.NewWriter(xw.w)
+
+	sheetNames := []string{xlsxMetadataSheetName}
+	for _, sheet := range xw.sheets {
+		sheetNames = append(sheetNames, sheet.name)
+	}
+
+	if err := writeZipFile(zw, "[Content_Types].xml", xlsxContentTypes(len(sheetNames))); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "_rels/.rels", xlsxRootRels); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "xl/workbook.xml", xlsxWorkbook(sheetNames)); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheetNames))); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "xl/styles.xml", xlsxStyles); err != nil {
+		return err
+	}
+	if err := xw.writeMetadataSheet(zw); err != nil {
+		return err
+	}
+
+	for i, sheet := range xw.sheets {
+		if err := sheet.buf.Flush(); err != nil {
+			return err
+		}
+		if _, err := sheet.tmpFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		f, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+2))
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xlsxSheetStart); err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, sheet.tmpFile); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xlsxSheetEnd); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (xw *xlsxWriter) writeMetadataSheet(zw *zip.Writer) error {
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(xlsxSheetStart); err != nil {
+		return err
+	}
+
+	header := []string{"sheet", "version", "column", "question", "questionTitle", "questionType", "label", "valueType"}
+	if err := writeXLSXRow(w, 1, header, func(col string) (string, string) { return col, "" }); err != nil {
+		return err
+	}
+
+	rowIndex := 1
+	for _, version := range xw.parser.surveyVersions {
+		sheetName := ""
+		if i, ok := xw.sheetIndex[version.VersionID]; ok {
+			sheetName = xw.sheets[i].name
+		}
+		_, columnTypes := xw.parser.versionColumns(version)
+
+		for _, question := range version.Questions {
+			for _, col := range getResponseColNamesForQuestion(question, xw.parser.questionOptionSep) {
+				if xw.parser.isRedacted(col) {
+					continue
+				}
+				values := map[string]string{
+					"sheet":         sheetName,
+					"version":       version.VersionID,
+					"column":        col,
+					"question":      question.ID,
+					"questionTitle": question.Title,
+					"questionType":  question.QuestionType,
+					"label":         responseColumnLabel(question, col, xw.parser.questionOptionSep),
+					"valueType":     columnTypes[col],
+				}
+				rowIndex += 1
+				if err := writeXLSXRow(w, rowIndex, header, func(c string) (string, string) { return values[c], "" }); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if _, err := w.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// versionColumns returns the wide format columns used by responses of the survey version and their value types
+func (rp *ResponseParser) versionColumns(version sd.SurveyVersionPreview) ([]string, map[string]string) {
+	columnTypes := map[string]string{}
+	for col, t := range xlsxFixedColumnTypes {
+		columnTypes[col] = t
+	}
+
+	respCols := []string{}
+	for _, question := range version.Questions {
+		for _, col := range getResponseColNamesForQuestion(question, rp.questionOptionSep) {
+			if !rp.isRedacted(col) {
+				respCols = append(respCols, col)
+				columnTypes[col] = responseColumnType(question, col, rp.questionOptionSep)
+			}
+
+			// the label column follows the column of the option keys
+			if rp.valueLabels == nil || rp.valueLabels.mode != VALUE_LABELS_BOTH {
+				continue
+			}
+			if _, ok := rp.valueLabels.values[version.VersionID][col]; !ok {
+				continue
+			}
+			if labelCol := col + rp.questionOptionSep + valueLabelColumnSuffix; !rp.isRedacted(labelCol) {
+				respCols = append(respCols, labelCol)
+			}
+		}
+	}
+
+	metaCols := []string{}
+	for _, col := range getMetaColNamesForAllVersions([]sd.SurveyVersionPreview{version}, rp.includeMeta, rp.questionOptionSep) {
+		if !rp.isRedacted(col) {
+			metaCols = append(metaCols, col)
+		}
+	}
+	for _, col := range metaCols {
+		if strings.HasSuffix(col, "metaPosition") {
+			columnTypes[col] = XLSX_COLUMN_TYPE_NUMBER
+		}
+	}
+
+	columns := []string{}
+	columns = append(columns, rp.columns.FixedColumns...)
+	columns = append(columns, rp.columns.ContextColumns...)
+	columns = append(columns, respCols...)
+	columns = append(columns, metaCols...)
+
+	for _, col := range columns {
+		if _, ok := columnTypes[col]; !ok {
+			columnTypes[col] = XLSX_COLUMN_TYPE_STRING
+		}
+	}
+	return columns, columnTypes
+}
+
+// responseColumnType derives the value type of a response column from the question and option types
+func responseColumnType(question sd.SurveyQuestion, col string, questionOptionSep string) string {
+	switch question.QuestionType {
+	case sd.QUESTION_TYPE_NUMBER_INPUT, sd.QUESTION_TYPE_NUMERIC_SLIDER, sd.QUESTION_TYPE_EQ5D_SLIDER:
+		return XLSX_COLUMN_TYPE_NUMBER
+	case sd.QUESTION_TYPE_DATE_INPUT:
+		return XLSX_COLUMN_TYPE_DATE
+	}
+
+	for _, rSlot := range question.Responses {
+		for _, option := range rSlot.Options {
+			if !strings.HasSuffix(col, questionOptionSep+option.ID) && !strings.HasSuffix(col, "."+option.ID) {
+				continue
+			}
+			switch option.OptionType {
+			case sd.OPTION_TYPE_NUMBER_INPUT, sd.OPTION_TYPE_EMBEDDED_CLOZE_NUMBER_INPUT:
+				return XLSX_COLUMN_TYPE_NUMBER
+			case sd.OPTION_TYPE_DATE_INPUT, sd.OPTION_TYPE_EMBEDDED_CLOZE_DATE_INPUT:
+				return XLSX_COLUMN_TYPE_DATE
+			}
+		}
+	}
+	return XLSX_COLUMN_TYPE_STRING
+}
+
+// responseColumnLabel returns the label of the response slot or option a column belongs to
+func responseColumnLabel(question sd.SurveyQuestion, col string, questionOptionSep string) string {
+	for _, rSlot := range question.Responses {
+		slotKey := question.ID + questionOptionSep + rSlot.ID
+		if col == slotKey || (col == question.ID && len(question.Responses) == 1) {
+			return rSlot.Label
+		}
+		for _, option := range rSlot.Options {
+			if col == question.ID+questionOptionSep+option.ID || col == slotKey+"."+option.ID ||
+				strings.HasPrefix(col, question.ID+questionOptionSep+option.ID+questionOptionSep) ||
+				strings.HasPrefix(col, slotKey+"."+option.ID+questionOptionSep) {
+				return option.Label
+			}
+		}
+	}
+	return ""
+}
+
+func writeXLSXRow(w io.Writer, rowIndex int, columns []string, cell func(col string) (value string, valueType string)) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<row r="%d">`, rowIndex)
+	for i, col := range columns {
+		value, valueType := cell(col)
+		if value == "" {
+			continue
+		}
+		ref := xlsxColumnName(i) + strconv.Itoa(rowIndex)
+
+		if rowIndex == 1 {
+			fmt.Fprintf(&sb, `<c r="%s" s="%d" t="inlineStr"><is><t>`, ref, xlsxStyleHeader)
+			xlsxEscape(&sb, value)
+			sb.WriteString(`</t></is></c>`)
+			continue
+		}
+
+		switch valueType {
+		case XLSX_COLUMN_TYPE_NUMBER:
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				fmt.Fprintf(&sb, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(n, 'f', -1, 64))
+				continue
+			}
+		case XLSX_COLUMN_TYPE_DATE:
+			if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+				if ts == 0 {
+					continue
+				}
+				serial := float64(ts)/86400 + xlsxUnixEpochSerial
+				fmt.Fprintf(&sb, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDate, strconv.FormatFloat(serial, 'f', -1, 64))
+				continue
+			}
+		}
+
+		fmt.Fprintf(&sb, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		xlsxEscape(&sb, value)
+		sb.WriteString(`</t></is></c>`)
+	}
+	sb.WriteString("</row>")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func xlsxEscape(sb *strings.Builder, value string) {
+	if len(value) > xlsxMaxCellTextLen {
+		value = value[:xlsxMaxCellTextLen]
+	}
+	// replaces characters not allowed in XML as well
+	_ = xml.EscapeText(sb, []byte(value))
+}
+
+// xlsxColumnName converts a zero based column index to the spreadsheet column name (A, B, ..., Z, AA, ...)
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func writeZipFile(zw *zip.Writer, name string, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+const xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+
+const xlsxSheetEnd = `</sheetData></worksheet>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func xlsxContentTypes(sheetCount int) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	sb.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	sb.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&sb, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	sb.WriteString(`</Types>`)
+	return sb.String()
+}
+
+func xlsxWorkbook(sheetNames []string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range sheetNames {
+		sb.WriteString(`<sheet name="`)
+		_ = xml.EscapeText(&sb, []byte(name))
+		fmt.Fprintf(&sb, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	sb.WriteString(`</sheets></workbook>`)
+	return sb.String()
+}
+
+func xlsxWorkbookRels(sheetCount int) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheetCount+1)
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
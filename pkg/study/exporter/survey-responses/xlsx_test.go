@@ -0,0 +1,231 @@
+package surveyresponses
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func readZipEntries(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// every part must be well-formed XML
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("invalid XML in %s: %v", f.Name, err)
+			}
+		}
+		entries[f.Name] = string(content)
+	}
+	return entries
+}
+
+// writeTestXLSXExport exports a response of each of two survey versions
+func writeTestXLSXExport(t *testing.T) []byte {
+	parser, err := NewResponseParser("S", []sd.SurveyVersionPreview{
+		{VersionID: "v1", Published: 0, Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", Title: "Age", QuestionType: sd.QUESTION_TYPE_NUMBER_INPUT, Responses: []sd.ResponseDef{{ID: "input", Label: "Age in years"}}},
+		}},
+		{VersionID: "v2", Published: 100, Questions: []sd.SurveyQuestion{
+			{ID: "S.Q1", Title: "Age", QuestionType: sd.QUESTION_TYPE_NUMBER_INPUT, Responses: []sd.ResponseDef{{ID: "input", Label: "Age in years"}}},
+			{ID: "S.Q2", Title: "Comment", QuestionType: sd.QUESTION_TYPE_TEXT_INPUT, Responses: []sd.ResponseDef{{ID: "input"}}},
+		}},
+	}, false, nil, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	exporter, err := NewResponseExporterWithOptions(parser, out, ExportOptions{Format: EXPORT_FORMAT_XLSX})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := []studytypes.SurveyResponse{
+		{ID: primitive.NewObjectID(), VersionID: "v1", SubmittedAt: 86400, Responses: []studytypes.SurveyItemResponse{
+			{Key: "S.Q1", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("input", "42"))},
+		}},
+		{ID: primitive.NewObjectID(), VersionID: "v2", SubmittedAt: 86400, Responses: []studytypes.SurveyItemResponse{
+			{Key: "S.Q1", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("input", "not a number"))},
+			{Key: "S.Q2", Response: ri(sd.RESPONSE_ROOT_KEY, "", ri("input", "a < b & c"))},
+		}},
+	}
+	_, err = exporter.Stream(context.Background(), func(ctx context.Context, yield func(r studytypes.SurveyResponse) error) error {
+		for _, r := range responses {
+			if err := yield(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, StreamOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestXLSXExport(t *testing.T) {
+	entries := readZipEntries(t, writeTestXLSXExport(t))
+
+	if !strings.Contains(entries["xl/workbook.xml"], `<sheet name="metadata" sheetId="1"`) ||
+		!strings.Contains(entries["xl/workbook.xml"], `<sheet name="v1" sheetId="2"`) ||
+		!strings.Contains(entries["xl/workbook.xml"], `<sheet name="v2" sheetId="3"`) {
+		t.Errorf("unexpected sheets: %s", entries["xl/workbook.xml"])
+	}
+
+	v1Sheet := entries["xl/worksheets/sheet2.xml"]
+	if !strings.Contains(v1Sheet, `<v>42</v>`) {
+		t.Errorf("number cell missing: %s", v1Sheet)
+	}
+	if !strings.Contains(v1Sheet, `s="1"><v>25570</v>`) {
+		t.Errorf("date cell missing: %s", v1Sheet)
+	}
+	if strings.Contains(v1Sheet, "S.Q2") {
+		t.Error("v1 sheet should not contain columns of v2")
+	}
+
+	v2Sheet := entries["xl/worksheets/sheet3.xml"]
+	if !strings.Contains(v2Sheet, `not a number`) || !strings.Contains(v2Sheet, `a &lt; b &amp; c`) {
+		t.Errorf("text cells missing: %s", v2Sheet)
+	}
+
+	metadata := entries["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(metadata, "Age in years") || !strings.Contains(metadata, XLSX_COLUMN_TYPE_NUMBER) {
+		t.Errorf("column infos missing in metadata sheet: %s", metadata)
+	}
+}
+
+// reads the export with an independent XLSX implementation
+func TestXLSXExportRoundTrip(t *testing.T) {
+	f, err := excelize.OpenReader(bytes.NewReader(writeTestXLSXExport(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if sheets := f.GetSheetList(); strings.Join(sheets, ",") != "metadata,v1,v2" {
+		t.Fatalf("unexpected sheets: %v", sheets)
+	}
+
+	cellByHeader := func(sheet string, header string) (string, excelize.CellType) {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("%s: expected header and one row, got %d rows", sheet, len(rows))
+		}
+		for i, h := range rows[0] {
+			if h != header {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(i+1, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := f.GetCellValue(sheet, cell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cellType, err := f.GetCellType(sheet, cell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return value, cellType
+		}
+		t.Fatalf("%s: column %s not found in %v", sheet, header, rows[0])
+		return "", excelize.CellTypeUnset
+	}
+
+	if value, cellType := cellByHeader("v1", "S.Q1"); value != "42" || cellType != excelize.CellTypeUnset {
+		t.Errorf("unexpected number cell %q of type %v", value, cellType)
+	}
+	if value, _ := cellByHeader("v1", "submitted"); value != "1970-01-02 00:00:00" {
+		t.Errorf("unexpected date cell %q", value)
+	}
+	if value, cellType := cellByHeader("v2", "S.Q1"); value != "not a number" || cellType != excelize.CellTypeInlineString {
+		t.Errorf("unexpected text cell %q of type %v", value, cellType)
+	}
+	if value, _ := cellByHeader("v2", "S.Q2"); value != "a < b & c" {
+		t.Errorf("unexpected text cell %q", value)
+	}
+
+	metadata, err := f.GetRows("metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, row := range metadata[1:] {
+		if len(row) > 7 && row[2] == "S.Q1" && row[6] == "Age in years" && row[7] == XLSX_COLUMN_TYPE_NUMBER {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("column infos missing in metadata sheet: %v", metadata)
+	}
+}
+
+func TestXLSXHelpers(t *testing.T) {
+	t.Run("column names", func(t *testing.T) {
+		for i, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+			if name := xlsxColumnName(i); name != expected {
+				t.Errorf("column %d: got %s, expected %s", i, name, expected)
+			}
+		}
+	})
+
+	t.Run("sheet names", func(t *testing.T) {
+		xw := newXLSXWriter(&bytes.Buffer{}, nil)
+		xw.sheets = []*xlsxSheet{{name: "v/1"}}
+
+		if name := xw.uniqueSheetName("a[b]:c"); name != "a_b__c" {
+			t.Errorf("unexpected name: %s", name)
+		}
+		if name := xw.uniqueSheetName("Metadata"); name != "Metadata (2)" {
+			t.Errorf("unexpected name: %s", name)
+		}
+		if name := xw.uniqueSheetName(strings.Repeat("x", 40)); len(name) != xlsxMaxSheetNameLen {
+			t.Errorf("name not truncated: %s", name)
+		}
+	})
+
+	t.Run("column types", func(t *testing.T) {
+		question := sd.SurveyQuestion{ID: "Q", QuestionType: sd.QUESTION_TYPE_CLOZE, Responses: []sd.ResponseDef{
+			{ID: "cloze", Options: []sd.ResponseOption{{ID: "n", OptionType: sd.OPTION_TYPE_NUMBER_INPUT}, {ID: "d", OptionType: sd.OPTION_TYPE_DATE_INPUT}, {ID: "t", OptionType: sd.OPTION_TYPE_TEXT_INPUT}}},
+		}}
+		for col, expected := range map[string]string{"Q-n": XLSX_COLUMN_TYPE_NUMBER, "Q-d": XLSX_COLUMN_TYPE_DATE, "Q-t": XLSX_COLUMN_TYPE_STRING} {
+			if colType := responseColumnType(question, col, "-"); colType != expected {
+				t.Errorf("%s: got %s, expected %s", col, colType, expected)
+			}
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package study
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	externalServiceTaskLease         = 5 * time.Minute
+	externalServiceTaskInitialDelay  = time.Minute
+	externalServiceTaskMaxRetryDelay = time.Hour
+)
+
+// ProcessExternalServiceTasks runs the due external service calls queued by study rules of the instance.
+// Failed calls are retried with exponential backoff on later runs, until the attempts configured for the service are used up.
+func ProcessExternalServiceTasks(instanceID string) {
+	for {
+		now := time.Now()
+		task, err := studyDBService.ClaimDueExternalServiceTask(instanceID, now, now.Add(externalServiceTaskLease))
+		if err != nil {
+			slog.Error("Error claiming external service task", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			return
+		}
+		if task == nil {
+			return
+		}
+		processExternalServiceTask(instanceID, *task)
+	}
+}
+
+func processExternalServiceTask(instanceID string, task studyTypes.ExternalServiceTask) {
+	response, maxAttempts, err := studyengine.RunExternalServiceTask(task)
+	attempts := task.Attempts + 1
+
+	if err != nil {
+		slog.Warn("external service task failed", slog.String("instanceID", instanceID), slog.String("taskID", task.ID.Hex()), slog.String("serviceName", task.ServiceName), slog.Int("attempt", attempts), slog.String("error", err.Error()))
+
+		status := studyTypes.EXTERNAL_SERVICE_TASK_STATUS_PENDING
+		nextAttemptAt := time.Now().Add(externalServiceTaskRetryDelay(attempts))
+		if attempts >= maxAttempts {
+			status = studyTypes.EXTERNAL_SERVICE_TASK_STATUS_FAILED
+			nextAttemptAt = time.Now()
+		}
+		if err := studyDBService.UpdateExternalServiceTaskState(instanceID, task.ID, status, attempts, nextAttemptAt, err.Error()); err != nil {
+			slog.Error("Error updating external service task", slog.String("instanceID", instanceID), slog.String("taskID", task.ID.Hex()), slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	if err := studyDBService.UpdateExternalServiceTaskState(instanceID, task.ID, studyTypes.EXTERNAL_SERVICE_TASK_STATUS_DONE, attempts, time.Now(), ""); err != nil {
+		slog.Error("Error updating external service task", slog.String("instanceID", instanceID), slog.String("taskID", task.ID.Hex()), slog.String("error", err.Error()))
+	}
+
+	if task.FollowUpEventKey != "" {
+		onExternalServiceTaskResult(instanceID, task, response)
+	}
+}
+
+func externalServiceTaskRetryDelay(attempts int) time.Duration {
+	delay := externalServiceTaskInitialDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= externalServiceTaskMaxRetryDelay {
+			return externalServiceTaskMaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// onExternalServiceTaskResult triggers the follow-up custom event of the task, with the service response as event payload
+func onExternalServiceTaskResult(instanceID string, task studyTypes.ExternalServiceTask, response map[string]interface{}) {
+	study, err := getStudyIfActive(instanceID, task.StudyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("instanceID", instanceID), slog.String("studyKey", task.StudyKey), slog.String("error", err.Error()))
+		return
+	}
+
+	pState, err := studyDBService.GetParticipantByID(instanceID, task.StudyKey, task.ParticipantID)
+	if err != nil {
+		slog.Error("Error getting participant state", slog.String("instanceID", instanceID), slog.String("studyKey", task.StudyKey), slog.String("participantID", task.ParticipantID), slog.String("error", err.Error()))
+		return
+	}
+
+	confidentialID, err := ComputeConfidentialIDForParticipant(study, task.ParticipantID)
+	if err != nil {
+		slog.Error("Error computing confidential ID", slog.String("instanceID", instanceID), slog.String("studyKey", task.StudyKey), slog.String("participantID", task.ParticipantID), slog.String("error", err.Error()))
+		return
+	}
+
+	currentEvent := studyengine.StudyEvent{
+		Type:                                  studyengine.STUDY_EVENT_TYPE_CUSTOM,
+		InstanceID:                            instanceID,
+		StudyKey:                              task.StudyKey,
+		ParticipantIDForConfidentialResponses: confidentialID,
+		EventKey:                              task.FollowUpEventKey,
+		Payload:                               response,
+	}
+
+	actionResult, err := getAndPerformStudyRules(instanceID, task.StudyKey, pState, currentEvent)
+	if err != nil {
+		slog.Error("Error getting and performing study rules", slog.String("instanceID", instanceID), slog.String("studyKey", task.StudyKey), slog.String("participantID", task.ParticipantID), slog.String("error", err.Error()))
+		return
+	}
+
+	_, err = saveParticipantState(instanceID, task.StudyKey, pState.StudyStatus, actionResult.PState)
+	if err != nil {
+		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", task.StudyKey), slog.String("participantID", task.ParticipantID), slog.String("error", err.Error()))
+		return
+	}
+
+	saveReports(instanceID, task.StudyKey, actionResult.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_CUSTOM)
+}
@@ -38,13 +38,14 @@ func getAndPerformStudyRules(instanceID, studyKey string, pState studyTypes.Part
 		ReportsToCreate: map[string]types.Report{},
 	}
 
-	rulesObj, err := studyDBService.GetCurrentStudyRules(instanceID, studyKey)
+	rulesObj, err := getStudyRulesForParticipant(instanceID, studyKey, pState)
 	if err != nil {
 		return
 	}
 	for _, rule := range rulesObj.Rules {
 		newState, err = studyengine.ActionEval(rule, newState, currentEvent)
 		if err != nil {
+			recordRuleError(instanceID, studyKey, pState.ParticipantID, currentEvent, err)
 			return
 		}
 	}
@@ -52,6 +53,20 @@ func getAndPerformStudyRules(instanceID, studyKey string, pState studyTypes.Part
 	return newState, nil
 }
 
+// recordRuleError stores the failed rule evaluation, so that it shows up in the study event stream
+func recordRuleError(instanceID string, studyKey string, participantID string, event studyengine.StudyEvent, ruleErr error) {
+	err := studyDBService.AddRuleError(instanceID, studyTypes.RuleError{
+		StudyKey:      studyKey,
+		ParticipantID: participantID,
+		EventType:     event.Type,
+		EventKey:      event.EventKey,
+		Error:         ruleErr.Error(),
+	})
+	if err != nil {
+		slog.Error("Error saving rule error", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+}
+
 func saveResponses(instanceID string, studyKey string, response studyTypes.SurveyResponse, pState studyTypes.Participant, confidentialID string) (string, error) {
 	nonConfidentialResponses := []studyTypes.SurveyItemResponse{}
 	confidentialResponses := []studyTypes.SurveyItemResponse{}
@@ -71,6 +86,7 @@ func saveResponses(instanceID string, studyKey string, response studyTypes.Surve
 		response.Context = map[string]string{}
 	}
 	response.Context["session"] = pState.CurrentStudySession
+	response.Synthetic = pState.Synthetic || isDraftPreviewParticipant(pState)
 
 	var rID string
 	var err error
@@ -0,0 +1,77 @@
+package study
+
+import (
+	"log/slog"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	NEXT_ACTION_COMPLETE_RECONSENT       = "complete-reconsent"
+	NEXT_ACTION_PENDING_REQUIRED_SURVEY  = "pending-required-survey"
+	NEXT_ACTION_DEFAULT_RECONSENT_FLAG   = "reconsentRequired"
+	NEXT_ACTION_RECONSENT_FLAG_VALUE_SET = "true"
+)
+
+type StudyNextAction struct {
+	Type      string `json:"type"`
+	StudyKey  string `json:"studyKey"`
+	ProfileID string `json:"profileID"`
+	SurveyKey string `json:"surveyKey,omitempty"`
+}
+
+// GetStudyNextActions collects actions the participant should complete next, based on the participant states of all active studies
+func GetStudyNextActions(instanceID string, profileIDs []string, reconsentFlagKey string) (actions []StudyNextAction, err error) {
+	actions = []StudyNextAction{}
+
+	studies, err := studyDBService.GetStudies(instanceID, studyTypes.STUDY_STATUS_ACTIVE, true)
+	if err != nil {
+		slog.Error("error getting studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		return
+	}
+
+	if reconsentFlagKey == "" {
+		reconsentFlagKey = NEXT_ACTION_DEFAULT_RECONSENT_FLAG
+	}
+
+	now := time.Now().Unix()
+	for _, study := range studies {
+		for _, profileID := range profileIDs {
+			participantID, _, err := ComputeParticipantIDs(study, profileID)
+			if err != nil {
+				slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				continue
+			}
+
+			pState, err := studyDBService.GetParticipantByID(instanceID, study.Key, participantID)
+			if err != nil || pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
+				continue
+			}
+
+			if pState.Flags[reconsentFlagKey] == NEXT_ACTION_RECONSENT_FLAG_VALUE_SET {
+				actions = append(actions, StudyNextAction{
+					Type:      NEXT_ACTION_COMPLETE_RECONSENT,
+					StudyKey:  study.Key,
+					ProfileID: profileID,
+				})
+			}
+
+			for _, survey := range pState.AssignedSurveys {
+				if survey.Category != studyTypes.ASSIGNED_SURVEY_CATEGORY_PRIO {
+					continue
+				}
+				if survey.ValidFrom > now || (survey.ValidUntil > 0 && survey.ValidUntil < now) {
+					continue
+				}
+				actions = append(actions, StudyNextAction{
+					Type:      NEXT_ACTION_PENDING_REQUIRED_SURVEY,
+					StudyKey:  study.Key,
+					ProfileID: profileID,
+					SurveyKey: survey.SurveyKey,
+				})
+			}
+		}
+	}
+	return actions, nil
+}
@@ -0,0 +1,273 @@
+package study
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"time"
+
+	studydb "github.com/case-framework/case-backend/pkg/db/study"
+	managementalerts "github.com/case-framework/case-backend/pkg/messaging/management-alerts"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	participantBulkJobRetention = 30 * 24 * time.Hour
+	participantBulkJobLease     = 5 * time.Minute
+	// results are stored and the cancellation is checked after each batch
+	participantBulkJobBatchSize = 100
+)
+
+var errParticipantBulkJobCancelled = errors.New("bulk job cancelled")
+
+// ValidateParticipantBulkAction checks that the fields required by the action type are set
+func ValidateParticipantBulkAction(action studyTypes.ParticipantBulkAction) error {
+	switch action.Type {
+	case studyTypes.PARTICIPANT_BULK_ACTION_SET_FLAG:
+		if action.FlagKey == "" || action.FlagValue == "" {
+			return errors.New("flagKey and flagValue are required")
+		}
+	case studyTypes.PARTICIPANT_BULK_ACTION_CHANGE_STATUS:
+		switch action.Status {
+		case "":
+			return errors.New("status is required")
+		case studyTypes.PARTICIPANT_STUDY_STATUS_TEMPORARY, studyTypes.PARTICIPANT_STUDY_STATUS_ACCOUNT_DELETED:
+			return fmt.Errorf("status %s cannot be set", action.Status)
+		}
+	case studyTypes.PARTICIPANT_BULK_ACTION_SEND_MESSAGE:
+		if action.MessageType == "" {
+			return errors.New("messageType is required")
+		}
+	case studyTypes.PARTICIPANT_BULK_ACTION_TRIGGER_RULE:
+		if len(action.Rules) == 0 {
+			return errors.New("rules are required")
+		}
+	default:
+		return fmt.Errorf("unknown action type: %s", action.Type)
+	}
+	return nil
+}
+
+// CreateParticipantBulkJob stores a queued job for the action, selection is the JSON encoded participant search kept
+// for the record
+func CreateParticipantBulkJob(instanceID string, studyKey string, createdBy string, action studyTypes.ParticipantBulkAction, selection string) (studyTypes.ParticipantBulkJob, error) {
+	if err := ValidateParticipantBulkAction(action); err != nil {
+		return studyTypes.ParticipantBulkJob{}, err
+	}
+	return studyDBService.CreateParticipantBulkJob(instanceID, studyTypes.ParticipantBulkJob{
+		StudyKey:  studyKey,
+		CreatedBy: createdBy,
+		Action:    action,
+		Selection: selection,
+		ExpiresAt: time.Now().Add(participantBulkJobRetention),
+	})
+}
+
+// RunParticipantBulkJob applies the action of the queued job to the participants matching the filter, except
+// temporary participants and deleted accounts, and stores the result of each participant. The job stops early if its
+// cancellation is requested.
+func RunParticipantBulkJob(ctx context.Context, instanceID string, job studyTypes.ParticipantBulkJob, filter bson.M) {
+	run := &participantBulkJobRun{instanceID: instanceID, job: job}
+
+	study, err := studyDBService.GetStudy(instanceID, job.StudyKey)
+	if err != nil {
+		run.finish(studyTypes.PARTICIPANT_BULK_JOB_STATUS_FAILED, "failed to get study: "+err.Error())
+		return
+	}
+
+	filter = bson.M{"$and": bson.A{
+		filter,
+		bson.M{"studyStatus": bson.M{"$nin": bson.A{
+			studyTypes.PARTICIPANT_STUDY_STATUS_TEMPORARY,
+			studyTypes.PARTICIPANT_STUDY_STATUS_ACCOUNT_DELETED,
+		}}},
+	}}
+	count, err := studyDBService.GetParticipantCount(instanceID, job.StudyKey, filter)
+	if err != nil {
+		run.finish(studyTypes.PARTICIPANT_BULK_JOB_STATUS_FAILED, "failed to count participants: "+err.Error())
+		return
+	}
+
+	if err := studyDBService.StartParticipantBulkJob(instanceID, job.ID, int(count), time.Now().Add(participantBulkJobLease)); err != nil {
+		if err == mongo.ErrNoDocuments {
+			slog.Info("participant bulk job cancelled before start", slog.String("instanceID", instanceID), slog.String("jobID", job.ID.Hex()))
+			return
+		}
+		slog.Error("failed to start participant bulk job", slog.String("instanceID", instanceID), slog.String("jobID", job.ID.Hex()), slog.String("error", err.Error()))
+		return
+	}
+
+	err = studyDBService.FindAndExecuteOnParticipantsStates(
+		ctx,
+		instanceID,
+		job.StudyKey,
+		filter,
+		bson.M{"_id": 1},
+		true,
+		func(dbService *studydb.StudyDBService, p studyTypes.Participant, instanceID, studyKey string, args ...interface{}) error {
+			changed, err := applyParticipantBulkAction(ctx, instanceID, study, job.Action, p)
+			run.add(p.ParticipantID, changed, err)
+			if len(run.results) < participantBulkJobBatchSize {
+				return nil
+			}
+			return run.flush()
+		},
+	)
+	if err == nil {
+		err = run.flush()
+	}
+
+	switch {
+	case err == nil:
+		run.finish(studyTypes.PARTICIPANT_BULK_JOB_STATUS_COMPLETED, "")
+	case errors.Is(err, errParticipantBulkJobCancelled):
+		run.finish(studyTypes.PARTICIPANT_BULK_JOB_STATUS_CANCELLED, "")
+	default:
+		run.finish(studyTypes.PARTICIPANT_BULK_JOB_STATUS_FAILED, err.Error())
+	}
+}
+
+type participantBulkJobRun struct {
+	instanceID string
+	job        studyTypes.ParticipantBulkJob
+
+	results   []studyTypes.ParticipantBulkJobResult
+	processed int
+	updated   int
+	failed    int
+}
+
+func (r *participantBulkJobRun) add(participantID string, changed bool, err error) {
+	result := studyTypes.ParticipantBulkJobResult{
+		JobID:         r.job.ID,
+		ParticipantID: participantID,
+		Result:        studyTypes.PARTICIPANT_BULK_RESULT_UNCHANGED,
+		ProcessedAt:   time.Now(),
+		ExpiresAt:     r.job.ExpiresAt,
+	}
+	r.processed++
+	if err != nil {
+		result.Result = studyTypes.PARTICIPANT_BULK_RESULT_FAILED
+		result.Error = err.Error()
+		r.failed++
+	} else if changed {
+		result.Result = studyTypes.PARTICIPANT_BULK_RESULT_UPDATED
+		r.updated++
+	}
+	r.results = append(r.results, result)
+}
+
+// flush stores the pending results and the progress, returns errParticipantBulkJobCancelled if the job should stop
+func (r *participantBulkJobRun) flush() error {
+	if err := studyDBService.AddParticipantBulkJobResults(r.instanceID, r.results); err != nil {
+		return fmt.Errorf("failed to store results: %w", err)
+	}
+	r.results = r.results[:0]
+
+	cancelRequested, err := studyDBService.UpdateParticipantBulkJobProgress(r.instanceID, r.job.ID, r.processed, r.updated, r.failed, time.Now().Add(participantBulkJobLease))
+	if err != nil {
+		return fmt.Errorf("failed to update progress: %w", err)
+	}
+	if cancelRequested {
+		return errParticipantBulkJobCancelled
+	}
+	return nil
+}
+
+func (r *participantBulkJobRun) finish(status string, errMsg string) {
+	slog.Info("participant bulk job finished", slog.String("instanceID", r.instanceID), slog.String("studyKey", r.job.StudyKey), slog.String("jobID", r.job.ID.Hex()), slog.String("status", status), slog.Int("processed", r.processed), slog.Int("updated", r.updated), slog.Int("failed", r.failed))
+	if err := studyDBService.FinishParticipantBulkJob(r.instanceID, r.job.ID, status, r.processed, r.updated, r.failed, errMsg); err != nil {
+		slog.Error("failed to finish participant bulk job", slog.String("instanceID", r.instanceID), slog.String("jobID", r.job.ID.Hex()), slog.String("error", err.Error()))
+	}
+
+	alert := managementalerts.Alert{
+		StudyKey: r.job.StudyKey,
+		Details: [][2]string{
+			{"Job", r.job.ID.Hex()},
+			{"Action", r.job.Action.Type},
+			{"Processed participants", strconv.Itoa(r.processed)},
+			{"Failed participants", strconv.Itoa(r.failed)},
+			{"Error", errMsg},
+		},
+	}
+	switch {
+	case status == studyTypes.PARTICIPANT_BULK_JOB_STATUS_FAILED:
+		alert.Type = managementalerts.ALERT_JOB_FAILED
+		alert.Title = "Participant bulk job failed"
+	case r.failed > 0 && r.job.Action.Type == studyTypes.PARTICIPANT_BULK_ACTION_TRIGGER_RULE:
+		alert.Type = managementalerts.ALERT_RULE_ERRORS
+		alert.Title = "Rules failed for some participants of a bulk job"
+	default:
+		return
+	}
+	managementalerts.Send(r.instanceID, r.job.CreatedBy, alert)
+}
+
+// applyParticipantBulkAction applies the action to the participant and saves the new state if it changed
+func applyParticipantBulkAction(ctx context.Context, instanceID string, study studyTypes.Study, action studyTypes.ParticipantBulkAction, p studyTypes.Participant) (changed bool, err error) {
+	newState := studyengine.ActionData{
+		PState:          p,
+		ReportsToCreate: map[string]studyTypes.Report{},
+	}
+
+	switch action.Type {
+	case studyTypes.PARTICIPANT_BULK_ACTION_SET_FLAG:
+		flags := make(map[string]string, len(p.Flags)+1)
+		for k, v := range p.Flags {
+			flags[k] = v
+		}
+		flags[action.FlagKey] = action.FlagValue
+		newState.PState.Flags = flags
+	case studyTypes.PARTICIPANT_BULK_ACTION_CHANGE_STATUS:
+		newState.PState.StudyStatus = action.Status
+	case studyTypes.PARTICIPANT_BULK_ACTION_SEND_MESSAGE:
+		scheduledFor := action.ScheduledFor
+		if scheduledFor <= 0 {
+			scheduledFor = time.Now().Unix()
+		}
+		messages := make([]studyTypes.ParticipantMessage, len(p.Messages), len(p.Messages)+1)
+		copy(messages, p.Messages)
+		newState.PState.Messages = append(messages, studyTypes.ParticipantMessage{
+			ID:           primitive.NewObjectID().Hex(),
+			Type:         action.MessageType,
+			ScheduledFor: scheduledFor,
+		})
+	case studyTypes.PARTICIPANT_BULK_ACTION_TRIGGER_RULE:
+		confidentialID, err := ComputeConfidentialIDForParticipant(study, p.ParticipantID)
+		if err != nil {
+			return false, err
+		}
+		event := studyengine.StudyEvent{
+			RequestID:                             requestid.FromContext(ctx),
+			InstanceID:                            instanceID,
+			StudyKey:                              study.Key,
+			Type:                                  studyengine.STUDY_EVENT_TYPE_CUSTOM,
+			ParticipantIDForConfidentialResponses: confidentialID,
+		}
+		for _, rule := range action.Rules {
+			newState, err = studyengine.ActionEval(rule, newState, event)
+			if err != nil {
+				return false, err
+			}
+		}
+	default:
+		return false, fmt.Errorf("unknown action type: %s", action.Type)
+	}
+
+	if !reflect.DeepEqual(newState.PState, p) {
+		if _, err := saveParticipantState(instanceID, study.Key, p.StudyStatus, newState.PState); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	saveReports(instanceID, study.Key, newState.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_CUSTOM)
+	return changed, nil
+}
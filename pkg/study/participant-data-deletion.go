@@ -0,0 +1,152 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	participantDataFileBatchSize = 100
+)
+
+// path of the participant file storage, needed to remove stored files when erasing participant data
+var participantFilestorePath string
+
+// SetParticipantFilestorePath configures where participant files are stored, so they can be removed by DeleteAllParticipantData
+func SetParticipantFilestorePath(path string) {
+	participantFilestorePath = path
+}
+
+type ParticipantDataDeletionResult struct {
+	Files                    int64 `json:"files"`
+	Responses                int64 `json:"responses"`
+	ConfidentialResponses    int64 `json:"confidentialResponses"`
+	Reports                  int64 `json:"reports"`
+	ScheduledEvents          int64 `json:"scheduledEvents"`
+	ExternalServiceTasks     int64 `json:"externalServiceTasks"`
+	RandomizationAllocations int64 `json:"randomizationAllocations"`
+	RuleErrors               int64 `json:"ruleErrors"`
+	SurveyReminders          int64 `json:"surveyReminders"`
+	BulkJobResults           int64 `json:"bulkJobResults"`
+	ResearcherMessages       int64 `json:"researcherMessages"`
+	ParticipantState         bool  `json:"participantState"`
+}
+
+// DeleteAllParticipantData removes every document of the participant from the study collections and the stored files.
+// The participant state is removed last: if any step fails, the state is kept and the deletion can be repeated until it completes.
+// Data quality findings only hold aggregates and the audit log is kept as the record of the erasure.
+func DeleteAllParticipantData(instanceID string, studyKey string, participantID string) (result ParticipantDataDeletionResult, err error) {
+	if participantID == "" {
+		return result, errors.New("participant id must be defined")
+	}
+
+	study, err := studyDBService.GetStudy(instanceID, studyKey)
+	if err != nil {
+		return result, err
+	}
+
+	confidentialID, err := ComputeConfidentialIDForParticipant(study, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.Files, err = deleteAllFilesOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.Responses, err = studyDBService.DeleteResponsesOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.ConfidentialResponses, err = studyDBService.DeleteConfidentialResponses(instanceID, studyKey, confidentialID, "")
+	if err != nil {
+		return result, err
+	}
+
+	err = studyDBService.RemoveConfidentialIDMapEntry(instanceID, confidentialID, studyKey)
+	if err != nil {
+		return result, err
+	}
+
+	result.Reports, err = studyDBService.DeleteReportsOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.ScheduledEvents, err = studyDBService.DeleteScheduledEventsOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.ExternalServiceTasks, err = studyDBService.DeleteExternalServiceTasksOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.RandomizationAllocations, err = studyDBService.DeleteRandomizationAllocationsOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.RuleErrors, err = studyDBService.DeleteRuleErrorsOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.SurveyReminders, err = studyDBService.DeleteSurveyRemindersOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.BulkJobResults, err = studyDBService.DeleteParticipantBulkJobResultsOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	result.ResearcherMessages, err = studyDBService.DeleteResearcherMessagesOfParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return result, err
+	}
+
+	err = studyDBService.DeleteParticipantByID(instanceID, studyKey, participantID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return result, err
+	}
+	result.ParticipantState = err == nil
+
+	slog.Info("participant data deleted", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+	return result, nil
+}
+
+func deleteAllFilesOfParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	var count int64
+	for {
+		fileInfos, err := studyDBService.GetFileInfosOfParticipant(instanceID, studyKey, participantID, participantDataFileBatchSize)
+		if err != nil {
+			return count, err
+		}
+		if len(fileInfos) == 0 {
+			return count, nil
+		}
+		if participantFilestorePath == "" {
+			return count, errors.New("filestore path is required to delete participant files")
+		}
+
+		for _, fileInfo := range fileInfos {
+			if err := removeStoredFile(participantFilestorePath, fileInfo.Path); err != nil {
+				return count, err
+			}
+			if err := removeStoredFile(participantFilestorePath, fileInfo.PreviewPath); err != nil {
+				return count, err
+			}
+			if err := studyDBService.DeleteParticipantFileInfoByID(instanceID, studyKey, fileInfo.ID.Hex()); err != nil && err != mongo.ErrNoDocuments {
+				return count, err
+			}
+			count += 1
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package study
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// initTestStudyDB sets up the study service with a fresh database, which is dropped after the test
+func initTestStudyDB(t *testing.T, uri string, instanceID string) *mongo.Database {
+	dbService, err := studyDB.NewStudyDBService(db.DBConfig{
+		URI:          uri,
+		DBNamePrefix: fmt.Sprintf("study_test_%d_", time.Now().UnixNano()),
+		Timeout:      10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := dbService.DBClient.Database(dbService.DBNamePrefix + instanceID + "_studyDB")
+	t.Cleanup(func() {
+		_ = database.Drop(context.Background())
+		_ = dbService.DBClient.Disconnect(context.Background())
+	})
+
+	studyDBService, globalSecret = dbService, "global-secret"
+	return database
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestDeleteAllParticipantData(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	instanceID := "test"
+	studyKey := "erasure"
+	participantID := "p1"
+	otherParticipantID := "p2"
+
+	database := initTestStudyDB(t, uri, instanceID)
+	filestorePath := t.TempDir()
+	SetParticipantFilestorePath(filestorePath)
+
+	study := studyTypes.Study{
+		Key:       studyKey,
+		SecretKey: "study-secret",
+		Configs:   studyTypes.StudyConfigs{IdMappingMethod: studyTypes.DEFAULT_ID_MAPPING_METHOD},
+	}
+	confidentialID, err := ComputeConfidentialIDForParticipant(study, participantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherConfidentialID, err := ComputeConfidentialIDForParticipant(study, otherParticipantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(filestorePath, "file1"), []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bulkJobID := primitive.NewObjectID()
+
+	// collection -> filter matching a document of the participant
+	participantDocs := map[string]func(pid string, cid string) bson.M{
+		studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_PARTICIPANTS: func(pid, cid string) bson.M {
+			return bson.M{"participantID": pid}
+		},
+		studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_RESPONSES: func(pid, cid string) bson.M {
+			return bson.M{"participantID": pid}
+		},
+		studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_CONFIDENTIAL_RESPONSES: func(pid, cid string) bson.M {
+			return bson.M{"participantID": cid}
+		},
+		studyDB.COLLECTION_NAME_CONFIDENTIAL_ID_MAP: func(pid, cid string) bson.M {
+			return bson.M{"confidentialID": cid, "studyKey": studyKey}
+		},
+		studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_REPORTS: func(pid, cid string) bson.M {
+			return bson.M{"participantID": pid}
+		},
+		studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_FILES: func(pid, cid string) bson.M {
+			return bson.M{"participantID": pid}
+		},
+		studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_RESEARCHER_MESSAGES: func(pid, cid string) bson.M {
+			return bson.M{"participantID": pid}
+		},
+		studyDB.COLLECTION_NAME_SCHEDULED_EVENTS: func(pid, cid string) bson.M {
+			return bson.M{"studyKey": studyKey, "participantID": pid}
+		},
+		studyDB.COLLECTION_NAME_EXTERNAL_SERVICE_TASKS: func(pid, cid string) bson.M {
+			return bson.M{"studyKey": studyKey, "participantID": pid}
+		},
+		studyDB.COLLECTION_NAME_RANDOMIZATION_ALLOCATIONS: func(pid, cid string) bson.M {
+			return bson.M{"studyKey": studyKey, "participantID": pid}
+		},
+		studyDB.COLLECTION_NAME_RULE_ERRORS: func(pid, cid string) bson.M {
+			return bson.M{"studyKey": studyKey, "participantID": pid}
+		},
+		studyDB.COLLECTION_NAME_SURVEY_REMINDERS: func(pid, cid string) bson.M {
+			return bson.M{"studyKey": studyKey, "participantID": pid}
+		},
+		studyDB.COLLECTION_NAME_PARTICIPANT_BULK_JOB_RESULTS: func(pid, cid string) bson.M {
+			return bson.M{"jobID": bulkJobID, "participantID": pid}
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := database.Collection(studyDB.COLLECTION_NAME_STUDY_INFOS).InsertOne(ctx, study); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Collection(studyDB.COLLECTION_NAME_PARTICIPANT_BULK_JOBS).InsertOne(ctx, bson.M{"_id": bulkJobID, "studyKey": studyKey}); err != nil {
+		t.Fatal(err)
+	}
+	for collection, doc := range participantDocs {
+		for _, ids := range [][2]string{{participantID, confidentialID}, {otherParticipantID, otherConfidentialID}} {
+			if _, err := database.Collection(collection).InsertOne(ctx, doc(ids[0], ids[1])); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	fileCollection := database.Collection(studyKey + "_" + studyDB.COLLECTION_NAME_SUFFIX_FILES)
+	if _, err := fileCollection.UpdateOne(ctx, bson.M{"participantID": participantID}, bson.M{"$set": bson.M{"path": "file1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DeleteAllParticipantData(instanceID, studyKey, participantID); err != nil {
+		t.Fatal(err)
+	}
+
+	for collection, doc := range participantDocs {
+		count, err := database.Collection(collection).CountDocuments(ctx, doc(participantID, confidentialID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s still contains %d documents of the participant", collection, count)
+		}
+
+		count, err = database.Collection(collection).CountDocuments(ctx, doc(otherParticipantID, otherConfidentialID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("%s lost documents of other participants", collection)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filestorePath, "file1")); !os.IsNotExist(err) {
+		t.Error("stored file of the participant was not removed")
+	}
+}
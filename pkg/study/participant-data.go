@@ -177,20 +177,21 @@ func GetAssignedSurveyWithContext(instanceID string, studyKey string, surveyKey
 		return
 	}
 
-	surveyDef, err := studyDBService.GetCurrentSurveyVersion(instanceID, studyKey, surveyKey)
+	participantID, _, err := ComputeParticipantIDs(study, profileID)
 	if err != nil {
-		slog.Error("error getting survey", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
 		return
 	}
 
-	participantID, _, err := ComputeParticipantIDs(study, profileID)
+	pState, pErr := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+
+	surveyDef, err := getSurveyDefForParticipant(instanceID, studyKey, surveyKey, pState)
 	if err != nil {
-		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		slog.Error("error getting survey", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 		return
 	}
 
-	pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
-	if err != nil {
+	if pErr != nil {
 		// participant not found
 		if surveyDef.AvailableFor == studyTypes.SURVEY_AVAILABLE_FOR_PUBLIC {
 			pState.AssignedSurveys = []studyTypes.AssignedSurvey{
@@ -0,0 +1,157 @@
+package study
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// entry rules can assign arms, call external services and schedule events, they are never run in a dry run
+var ErrEntryRulesInDryRun = errors.New("entry rules cannot be re-run in a dry run")
+
+// statuses support staff may set, temporary and deleted participants are never the result of a correction
+var patchableStudyStatuses = []string{
+	studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
+	studyTypes.PARTICIPANT_STUDY_STATUS_EXITED,
+	studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST,
+}
+
+// ParticipantStatePatch is a structured correction of a participant state by support staff. Removals are applied
+// before additions.
+type ParticipantStatePatch struct {
+	StudyStatus string            `json:"studyStatus,omitempty"`
+	SetFlags    map[string]string `json:"setFlags,omitempty"`
+	RemoveFlags []string          `json:"removeFlags,omitempty"`
+	// survey keys, all assignments of the survey are removed
+	RemoveAssignedSurveys []string                    `json:"removeAssignedSurveys,omitempty"`
+	AddAssignedSurveys    []studyTypes.AssignedSurvey `json:"addAssignedSurveys,omitempty"`
+	// message IDs
+	RemoveMessages []string `json:"removeMessages,omitempty"`
+	// run the entry rules of the study on the patched state
+	RerunEntryRules bool `json:"rerunEntryRules,omitempty"`
+}
+
+func (p ParticipantStatePatch) isEmpty() bool {
+	return p.StudyStatus == "" && len(p.SetFlags) == 0 && len(p.RemoveFlags) == 0 &&
+		len(p.RemoveAssignedSurveys) == 0 && len(p.AddAssignedSurveys) == 0 && len(p.RemoveMessages) == 0 &&
+		!p.RerunEntryRules
+}
+
+// ValidateParticipantStatePatch checks the patch before it is applied
+func ValidateParticipantStatePatch(patch ParticipantStatePatch) error {
+	if patch.isEmpty() {
+		return errors.New("patch is empty")
+	}
+	if patch.StudyStatus != "" && !slices.Contains(patchableStudyStatuses, patch.StudyStatus) {
+		return fmt.Errorf("status %s cannot be set", patch.StudyStatus)
+	}
+	for k := range patch.SetFlags {
+		if k == "" {
+			return errors.New("flag keys must not be empty")
+		}
+		if slices.Contains(patch.RemoveFlags, k) {
+			return fmt.Errorf("flag %s is set and removed", k)
+		}
+	}
+	for _, survey := range patch.AddAssignedSurveys {
+		if survey.SurveyKey == "" {
+			return errors.New("surveyKey of assigned surveys is required")
+		}
+	}
+	return nil
+}
+
+// PatchParticipantState applies the patch to the participant and, unless dryRun, saves the new state. It returns the
+// state before and after the patch.
+func PatchParticipantState(ctx context.Context, instanceID string, studyKey string, participantID string, patch ParticipantStatePatch, dryRun bool) (before studyTypes.Participant, after studyTypes.Participant, err error) {
+	if dryRun && patch.RerunEntryRules {
+		return before, after, ErrEntryRulesInDryRun
+	}
+	study, err := studyDBService.GetStudy(instanceID, studyKey)
+	if err != nil {
+		return before, after, err
+	}
+	before, err = studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+	if err != nil {
+		return before, after, err
+	}
+
+	newState := studyengine.ActionData{
+		PState:          applyParticipantStatePatch(before, patch, studyKey),
+		ReportsToCreate: map[string]studyTypes.Report{},
+	}
+
+	if patch.RerunEntryRules {
+		confidentialID, err := ComputeConfidentialIDForParticipant(study, participantID)
+		if err != nil {
+			return before, after, err
+		}
+		event := studyengine.StudyEvent{
+			RequestID:                             requestid.FromContext(ctx),
+			Type:                                  studyengine.STUDY_EVENT_TYPE_ENTER,
+			InstanceID:                            instanceID,
+			StudyKey:                              studyKey,
+			ParticipantIDForConfidentialResponses: confidentialID,
+		}
+		newState, err = getAndPerformStudyRules(instanceID, studyKey, newState.PState, event)
+		if err != nil {
+			return before, after, err
+		}
+	}
+
+	if dryRun {
+		return before, newState.PState, nil
+	}
+
+	after, err = saveParticipantState(instanceID, studyKey, before.StudyStatus, newState.PState)
+	if err != nil {
+		return before, after, err
+	}
+	saveReports(instanceID, studyKey, newState.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_ENTER)
+	return before, after, nil
+}
+
+// applyParticipantStatePatch returns a patched copy, the participant is not modified
+func applyParticipantStatePatch(p studyTypes.Participant, patch ParticipantStatePatch, studyKey string) studyTypes.Participant {
+	if patch.StudyStatus != "" {
+		p.StudyStatus = patch.StudyStatus
+	}
+
+	flags := make(map[string]string, len(p.Flags)+len(patch.SetFlags))
+	for k, v := range p.Flags {
+		if !slices.Contains(patch.RemoveFlags, k) {
+			flags[k] = v
+		}
+	}
+	for k, v := range patch.SetFlags {
+		flags[k] = v
+	}
+	p.Flags = flags
+
+	assignedSurveys := make([]studyTypes.AssignedSurvey, 0, len(p.AssignedSurveys)+len(patch.AddAssignedSurveys))
+	for _, survey := range p.AssignedSurveys {
+		if !slices.Contains(patch.RemoveAssignedSurveys, survey.SurveyKey) {
+			assignedSurveys = append(assignedSurveys, survey)
+		}
+	}
+	for _, survey := range patch.AddAssignedSurveys {
+		survey.StudyKey = studyKey
+		assignedSurveys = append(assignedSurveys, survey)
+	}
+	p.AssignedSurveys = assignedSurveys
+
+	messages := make([]studyTypes.ParticipantMessage, 0, len(p.Messages))
+	for _, message := range p.Messages {
+		if !slices.Contains(patch.RemoveMessages, message.ID) {
+			messages = append(messages, message)
+		}
+	}
+	p.Messages = messages
+
+	return p
+}
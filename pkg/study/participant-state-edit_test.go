@@ -0,0 +1,102 @@
+package study
+
+import (
+	"context"
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestValidateParticipantStatePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   ParticipantStatePatch
+		wantErr bool
+	}{
+		{name: "empty", patch: ParticipantStatePatch{}, wantErr: true},
+		{name: "active", patch: ParticipantStatePatch{StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE}},
+		{name: "exited", patch: ParticipantStatePatch{StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_EXITED}},
+		{name: "waiting list", patch: ParticipantStatePatch{StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST}},
+		{name: "temporary", patch: ParticipantStatePatch{StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_TEMPORARY}, wantErr: true},
+		{name: "account deleted", patch: ParticipantStatePatch{StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_ACCOUNT_DELETED}, wantErr: true},
+		{name: "unknown status", patch: ParticipantStatePatch{StudyStatus: "paused"}, wantErr: true},
+		{name: "empty flag key", patch: ParticipantStatePatch{SetFlags: map[string]string{"": "1"}}, wantErr: true},
+		{name: "flag set and removed", patch: ParticipantStatePatch{SetFlags: map[string]string{"group": "a"}, RemoveFlags: []string{"group"}}, wantErr: true},
+		{name: "flags", patch: ParticipantStatePatch{SetFlags: map[string]string{"group": "a"}, RemoveFlags: []string{"old"}}},
+		{name: "assigned survey without key", patch: ParticipantStatePatch{AddAssignedSurveys: []studyTypes.AssignedSurvey{{}}}, wantErr: true},
+		{name: "rerun entry rules only", patch: ParticipantStatePatch{RerunEntryRules: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParticipantStatePatch(tt.patch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyParticipantStatePatch(t *testing.T) {
+	participant := studyTypes.Participant{
+		ParticipantID: "p1",
+		StudyStatus:   studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
+		Flags:         map[string]string{"group": "a", "old": "1"},
+		AssignedSurveys: []studyTypes.AssignedSurvey{
+			{SurveyKey: "intake", StudyKey: "study1"},
+			{SurveyKey: "weekly", StudyKey: "study1"},
+			{SurveyKey: "weekly", StudyKey: "study1"},
+		},
+		Messages: []studyTypes.ParticipantMessage{{ID: "m1"}, {ID: "m2"}},
+	}
+
+	patched := applyParticipantStatePatch(participant, ParticipantStatePatch{
+		StudyStatus:           studyTypes.PARTICIPANT_STUDY_STATUS_EXITED,
+		SetFlags:              map[string]string{"group": "b", "new": "1"},
+		RemoveFlags:           []string{"old"},
+		RemoveAssignedSurveys: []string{"weekly"},
+		AddAssignedSurveys:    []studyTypes.AssignedSurvey{{SurveyKey: "exit"}},
+		RemoveMessages:        []string{"m1"},
+	}, "study1")
+
+	t.Run("patched state", func(t *testing.T) {
+		if patched.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_EXITED {
+			t.Errorf("unexpected status: %s", patched.StudyStatus)
+		}
+		if len(patched.Flags) != 2 || patched.Flags["group"] != "b" || patched.Flags["new"] != "1" {
+			t.Errorf("unexpected flags: %v", patched.Flags)
+		}
+		if len(patched.AssignedSurveys) != 2 || patched.AssignedSurveys[0].SurveyKey != "intake" || patched.AssignedSurveys[1].SurveyKey != "exit" {
+			t.Errorf("unexpected assigned surveys: %v", patched.AssignedSurveys)
+		}
+		if patched.AssignedSurveys[1].StudyKey != "study1" {
+			t.Errorf("added survey should get the study key, got %s", patched.AssignedSurveys[1].StudyKey)
+		}
+		if len(patched.Messages) != 1 || patched.Messages[0].ID != "m2" {
+			t.Errorf("unexpected messages: %v", patched.Messages)
+		}
+	})
+
+	t.Run("original not modified", func(t *testing.T) {
+		if participant.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE || len(participant.Flags) != 2 || participant.Flags["group"] != "a" {
+			t.Errorf("participant was modified: %v", participant)
+		}
+		if len(participant.AssignedSurveys) != 3 || len(participant.Messages) != 2 {
+			t.Errorf("participant was modified: %v", participant)
+		}
+	})
+
+	t.Run("empty status keeps status", func(t *testing.T) {
+		p := applyParticipantStatePatch(participant, ParticipantStatePatch{SetFlags: map[string]string{"x": "1"}}, "study1")
+		if p.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
+			t.Errorf("unexpected status: %s", p.StudyStatus)
+		}
+	})
+}
+
+func TestPatchParticipantStateRejectsEntryRulesInDryRun(t *testing.T) {
+	_, _, err := PatchParticipantState(context.Background(), "instance1", "study1", "p1", ParticipantStatePatch{RerunEntryRules: true}, true)
+	if err != ErrEntryRulesInDryRun {
+		t.Errorf("expected ErrEntryRulesInDryRun, got %v", err)
+	}
+}
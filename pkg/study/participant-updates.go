@@ -0,0 +1,108 @@
+package study
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type ParticipantMessages struct {
+	ProfileID string                          `json:"profileID"`
+	Messages  []studyTypes.ParticipantMessage `json:"messages"`
+}
+
+// GetParticipantMessages returns the pending messages of the profiles' participants
+func GetParticipantMessages(instanceID string, studyKey string, profileIDs []string) ([]ParticipantMessages, error) {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ParticipantMessages{}
+	for _, profileID := range profileIDs {
+		participantID, _, err := ComputeParticipantIDs(study, profileID)
+		if err != nil {
+			return nil, err
+		}
+
+		pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+		if err != nil || pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
+			continue
+		}
+
+		messages := pState.Messages
+		if messages == nil {
+			messages = []studyTypes.ParticipantMessage{}
+		}
+		result = append(result, ParticipantMessages{ProfileID: profileID, Messages: messages})
+	}
+	return result, nil
+}
+
+// WatchParticipantUpdates calls onChange whenever the state of a participant of the profiles changes, until ctx is done.
+// Uses change streams if the database supports them and otherwise compares the participant states in the given interval.
+func WatchParticipantUpdates(ctx context.Context, instanceID string, studyKey string, profileIDs []string, pollInterval time.Duration, onChange func()) error {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		return err
+	}
+
+	participantIDs := make([]string, len(profileIDs))
+	for i, profileID := range profileIDs {
+		participantIDs[i], _, err = ComputeParticipantIDs(study, profileID)
+		if err != nil {
+			return err
+		}
+	}
+
+	stream, err := studyDBService.WatchParticipantStates(ctx, instanceID, studyKey, participantIDs)
+	if err == nil {
+		defer stream.Close(context.Background())
+		for stream.Next(ctx) {
+			onChange()
+		}
+		return ctx.Err()
+	}
+	slog.Debug("change streams not available, polling participant states", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+
+	lastFingerprint := participantStatesFingerprint(instanceID, studyKey, participantIDs)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			fingerprint := participantStatesFingerprint(instanceID, studyKey, participantIDs)
+			if !bytes.Equal(fingerprint, lastFingerprint) {
+				lastFingerprint = fingerprint
+				onChange()
+			}
+		}
+	}
+}
+
+// participantStatesFingerprint serialises the parts of the participant states relevant for the participant's inbox
+func participantStatesFingerprint(instanceID string, studyKey string, participantIDs []string) []byte {
+	fingerprint := []byte{}
+	for _, participantID := range participantIDs {
+		pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+		if err != nil {
+			continue
+		}
+		b, err := bson.Marshal(bson.M{
+			"status":   pState.StudyStatus,
+			"surveys":  pState.AssignedSurveys,
+			"messages": pState.Messages,
+		})
+		if err != nil {
+			continue
+		}
+		fingerprint = append(fingerprint, b...)
+	}
+	return fingerprint
+}
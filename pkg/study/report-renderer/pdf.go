@@ -0,0 +1,234 @@
+package reportrenderer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Page layout of generated PDFs in points (A4)
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 56
+
+	pdfBodyFontSize      = 11
+	pdfBodyLineHeight    = 15
+	pdfHeadingFontSize   = 14
+	pdfHeadingLineHeight = 20
+
+	// approximate number of Helvetica characters fitting the text width
+	pdfBodyCharsPerLine    = 85
+	pdfHeadingCharsPerLine = 60
+)
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true, "ul": true, "ol": true,
+	"section": true, "article": true, "header": true, "footer": true, "blockquote": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var headingElements = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+type textLine struct {
+	text    string
+	heading bool
+}
+
+// RenderPDF creates a PDF document with the text content of the rendered report. Styling and images of the
+// HTML are not kept: block elements start a new line, headings are set in bold and long lines are wrapped.
+func RenderPDF(r RenderedReport) ([]byte, error) {
+	doc, err := html.Parse(strings.NewReader(r.HTML))
+	if err != nil {
+		return nil, err
+	}
+	return writePDF(r.Title, extractTextLines(doc)), nil
+}
+
+func extractTextLines(root *html.Node) []textLine {
+	lines := []textLine{}
+	current := strings.Builder{}
+	heading := false
+
+	flush := func() {
+		text := strings.Join(strings.Fields(current.String()), " ")
+		if text != "" {
+			lines = append(lines, textLine{text: text, heading: heading})
+		}
+		current.Reset()
+	}
+
+	var walk func(n *html.Node, inHeading bool)
+	walk = func(n *html.Node, inHeading bool) {
+		switch n.Type {
+		case html.TextNode:
+			heading = inHeading
+			current.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "head", "script", "style", "template":
+				return
+			}
+		}
+
+		isBlock := n.Type == html.ElementNode && blockElements[n.Data]
+		if isBlock {
+			flush()
+			if n.Data == "li" {
+				current.WriteString("- ")
+			}
+		}
+		childInHeading := inHeading || (n.Type == html.ElementNode && headingElements[n.Data])
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, childInHeading)
+		}
+		if n.Type == html.ElementNode && (n.Data == "td" || n.Data == "th") {
+			current.WriteString(" ")
+		}
+		if isBlock {
+			flush()
+		}
+	}
+	walk(root, false)
+	flush()
+	return lines
+}
+
+func writePDF(title string, lines []textLine) []byte {
+	pages := layoutPages(lines)
+
+	objects := []string{}
+	addObject := func(content string) int {
+		objects = append(objects, content)
+		return len(objects)
+	}
+
+	catalogID := addObject("") // set once the page tree is known
+	pagesID := addObject("")
+	bodyFontID := addObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	headingFontID := addObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	infoID := addObject(fmt.Sprintf("<< /Title (%s) /Producer (case-backend) >>", pdfString(title)))
+
+	pageIDs := []string{}
+	for _, content := range pages {
+		contentID := addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+		pageID := addObject(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, pdfPageWidth, pdfPageHeight, bodyFontID, headingFontID, contentID,
+		))
+		pageIDs = append(pageIDs, fmt.Sprintf("%d 0 R", pageID))
+	}
+	objects[catalogID-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID)
+	objects[pagesID-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageIDs, " "), len(pageIDs))
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalogID, infoID, xrefOffset)
+	return buf.Bytes()
+}
+
+// layoutPages wraps the lines and returns the content stream of each page
+func layoutPages(lines []textLine) []string {
+	pages := []string{}
+	content := &strings.Builder{}
+	y := pdfPageHeight - pdfMargin
+
+	for _, line := range lines {
+		font, size, lineHeight, width := "F1", pdfBodyFontSize, pdfBodyLineHeight, pdfBodyCharsPerLine
+		if line.heading {
+			font, size, lineHeight, width = "F2", pdfHeadingFontSize, pdfHeadingLineHeight, pdfHeadingCharsPerLine
+		}
+
+		for _, part := range wrapText(line.text, width) {
+			if y-lineHeight < pdfMargin {
+				pages = append(pages, content.String())
+				content.Reset()
+				y = pdfPageHeight - pdfMargin
+			}
+			y -= lineHeight
+			fmt.Fprintf(content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, size, pdfMargin, y, pdfString(part))
+		}
+	}
+	pages = append(pages, content.String())
+	return pages
+}
+
+func wrapText(text string, width int) []string {
+	lines := []string{}
+	current := []rune{}
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		for len(w) > width {
+			if len(current) > 0 {
+				lines = append(lines, string(current))
+				current = current[:0]
+			}
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		if len(current) > 0 && len(current)+1+len(w) > width {
+			lines = append(lines, string(current))
+			current = current[:0]
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfString encodes the text for a PDF literal string in WinAnsiEncoding. Characters outside of it are replaced by '?'.
+func pdfString(text string) string {
+	sb := strings.Builder{}
+	for _, r := range text {
+		var b byte
+		switch {
+		case r >= 0x20 && r < 0x7f:
+			b = byte(r)
+		case r >= 0xa0 && r <= 0xff:
+			b = byte(r)
+		default:
+			if c, ok := winAnsiExtra[r]; ok {
+				b = c
+			} else {
+				b = '?'
+			}
+		}
+
+		switch {
+		case b == '(' || b == ')' || b == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case b >= 0x80:
+			fmt.Fprintf(&sb, "\\%03o", b)
+		default:
+			sb.WriteByte(b)
+		}
+	}
+	return sb.String()
+}
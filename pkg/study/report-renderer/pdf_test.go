@@ -0,0 +1,83 @@
+package reportrenderer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractTextLines(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head><title>T</title><style>p { color: red; }</style></head>
+<body><h1>Title</h1><p>First   line with <b>bold</b> text</p><ul><li>one</li><li>two</li></ul><table><tr><td>a</td><td>b</td></tr></table></body></html>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := extractTextLines(doc)
+	expected := []textLine{
+		{text: "Title", heading: true},
+		{text: "First line with bold text"},
+		{text: "- one"},
+		{text: "- two"},
+		{text: "a b"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected lines: %+v", lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText("aaa bbb ccc dddddddddd", 7)
+	expected := []string{"aaa bbb", "ccc", "ddddddd", "ddd"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	if s := pdfString(`a (b) \ ä €`); s != `a \(b\) \\ \344 \200` {
+		t.Errorf("unexpected result: %s", s)
+	}
+	if s := pdfString("日本"); s != "??" {
+		t.Errorf("unexpected result: %s", s)
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	r, err := RenderHTML(testTemplate(), testReport(), "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pdf, err := RenderPDF(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Errorf("not a PDF document")
+	}
+	if !bytes.Contains(pdf, []byte("(Score: 12) Tj")) {
+		t.Errorf("report text missing")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 1")) {
+		t.Errorf("expected one page")
+	}
+
+	t.Run("multiple pages", func(t *testing.T) {
+		r := RenderedReport{Title: "long", HTML: "<body>" + strings.Repeat("<p>line</p>", 100) + "</body>"}
+		pdf, err := RenderPDF(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Contains(pdf, []byte("/Count 3")) {
+			t.Errorf("expected three pages")
+		}
+	})
+}
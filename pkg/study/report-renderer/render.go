@@ -0,0 +1,162 @@
+package reportrenderer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	FORMAT_HTML = "html"
+	FORMAT_PDF  = "pdf"
+)
+
+var (
+	ErrNoTranslation = errors.New("report template has no translation")
+)
+
+var documentTemplate = template.Must(template.New("document").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{.Body}}
+</body>
+</html>
+`))
+
+var templateFuncs = template.FuncMap{
+	// formatDate formats a time with a Go layout string, e.g. {{ formatDate .Timestamp "2006-01-02" }}
+	"formatDate": func(t time.Time, layout string) string {
+		return t.Format(layout)
+	},
+}
+
+type RenderedReport struct {
+	Lang  string `json:"lang"`
+	Title string `json:"title"`
+	HTML  string `json:"html"`
+}
+
+// TemplateData is available inside report templates. Report data values can be accessed by key, e.g. {{ .Data.score }}.
+type TemplateData struct {
+	Key       string
+	Timestamp time.Time
+	Data      map[string]string
+	Items     []studyTypes.ReportData
+}
+
+// ValidateTemplate checks that the template has a translation for its default language and that all templates can be parsed
+func ValidateTemplate(t studyTypes.ReportTemplate) error {
+	if t.ReportKey == "" {
+		return errors.New("report key is missing")
+	}
+	if t.DefaultLanguage == "" {
+		return errors.New("default language is missing")
+	}
+
+	langs := map[string]bool{}
+	for _, tr := range t.Translations {
+		if tr.Lang == "" {
+			return errors.New("translation without language")
+		}
+		if langs[tr.Lang] {
+			return fmt.Errorf("duplicate translation for language %s", tr.Lang)
+		}
+		langs[tr.Lang] = true
+
+		if _, err := parseTemplate(tr); err != nil {
+			return fmt.Errorf("invalid template for language %s: %w", tr.Lang, err)
+		}
+	}
+	if !langs[t.DefaultLanguage] {
+		return fmt.Errorf("no translation for default language %s", t.DefaultLanguage)
+	}
+	return nil
+}
+
+// RenderHTML renders the report as an HTML document in the requested language, or in the template's default language
+// if there is no translation for it. Report values are escaped by the template engine.
+func RenderHTML(t studyTypes.ReportTemplate, report studyTypes.Report, lang string) (RenderedReport, error) {
+	tr, ok := selectTranslation(t, lang)
+	if !ok {
+		return RenderedReport{}, ErrNoTranslation
+	}
+
+	tmpl, err := parseTemplate(tr)
+	if err != nil {
+		return RenderedReport{}, err
+	}
+
+	body := &bytes.Buffer{}
+	if err := tmpl.Execute(body, newTemplateData(report)); err != nil {
+		return RenderedReport{}, err
+	}
+
+	doc := &bytes.Buffer{}
+	err = documentTemplate.Execute(doc, struct {
+		Lang  string
+		Title string
+		Body  template.HTML
+	}{
+		Lang:  tr.Lang,
+		Title: tr.Title,
+		Body:  template.HTML(body.String()),
+	})
+	if err != nil {
+		return RenderedReport{}, err
+	}
+
+	return RenderedReport{
+		Lang:  tr.Lang,
+		Title: tr.Title,
+		HTML:  doc.String(),
+	}, nil
+}
+
+func parseTemplate(tr studyTypes.ReportTemplateTranslation) (*template.Template, error) {
+	return template.New(tr.Lang).Funcs(templateFuncs).Option("missingkey=zero").Parse(tr.Template)
+}
+
+func selectTranslation(t studyTypes.ReportTemplate, lang string) (studyTypes.ReportTemplateTranslation, bool) {
+	var fallback *studyTypes.ReportTemplateTranslation
+	for i, tr := range t.Translations {
+		if tr.Lang == lang {
+			return tr, true
+		}
+		if tr.Lang == t.DefaultLanguage {
+			fallback = &t.Translations[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	if len(t.Translations) > 0 {
+		return t.Translations[0], true
+	}
+	return studyTypes.ReportTemplateTranslation{}, false
+}
+
+func newTemplateData(report studyTypes.Report) TemplateData {
+	data := make(map[string]string, len(report.Data))
+	for _, d := range report.Data {
+		data[d.Key] = d.Value
+	}
+	items := report.Data
+	if items == nil {
+		items = []studyTypes.ReportData{}
+	}
+	return TemplateData{
+		Key:       report.Key,
+		Timestamp: time.Unix(report.Timestamp, 0).UTC(),
+		Data:      data,
+		Items:     items,
+	}
+}
@@ -0,0 +1,109 @@
+package reportrenderer
+
+import (
+	"strings"
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func testTemplate() studyTypes.ReportTemplate {
+	return studyTypes.ReportTemplate{
+		StudyKey:        "study1",
+		ReportKey:       "symptomScore",
+		DefaultLanguage: "en",
+		Translations: []studyTypes.ReportTemplateTranslation{
+			{Lang: "en", Title: "Your symptom score", Template: `<p>Score: {{ .Data.score }}</p><p>Date: {{ formatDate .Timestamp "2006-01-02" }}</p><p>{{ .Data.missing }}</p>`},
+			{Lang: "de", Title: "Ihr Symptom-Score", Template: `<p>Punkte: {{ .Data.score }}</p>`},
+		},
+	}
+}
+
+func testReport() studyTypes.Report {
+	return studyTypes.Report{
+		Key:       "symptomScore",
+		Timestamp: 1704067200, // 2024-01-01
+		Data: []studyTypes.ReportData{
+			{Key: "score", Value: "12", Dtype: "int"},
+		},
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	if err := ValidateTemplate(testTemplate()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	t.Run("missing default translation", func(t *testing.T) {
+		tmpl := testTemplate()
+		tmpl.DefaultLanguage = "fr"
+		if err := ValidateTemplate(tmpl); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("duplicate language", func(t *testing.T) {
+		tmpl := testTemplate()
+		tmpl.Translations = append(tmpl.Translations, tmpl.Translations[0])
+		if err := ValidateTemplate(tmpl); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		tmpl := testTemplate()
+		tmpl.Translations[1].Template = "{{ .Data.score "
+		if err := ValidateTemplate(tmpl); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestRenderHTML(t *testing.T) {
+	t.Run("requested language", func(t *testing.T) {
+		r, err := RenderHTML(testTemplate(), testReport(), "en")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if r.Lang != "en" || r.Title != "Your symptom score" {
+			t.Errorf("unexpected result: %+v", r)
+		}
+		for _, expected := range []string{`<html lang="en">`, "<title>Your symptom score</title>", "<p>Score: 12</p>", "<p>Date: 2024-01-01</p>", "<p></p>"} {
+			if !strings.Contains(r.HTML, expected) {
+				t.Errorf("expected %s in %s", expected, r.HTML)
+			}
+		}
+	})
+
+	t.Run("fallback to default language", func(t *testing.T) {
+		tmpl := testTemplate()
+		tmpl.DefaultLanguage = "de"
+		r, err := RenderHTML(tmpl, testReport(), "nl")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if r.Lang != "de" || !strings.Contains(r.HTML, "<p>Punkte: 12</p>") {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	})
+
+	t.Run("values are escaped", func(t *testing.T) {
+		report := testReport()
+		report.Data[0].Value = "<script>alert(1)</script>"
+		r, err := RenderHTML(testTemplate(), report, "en")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(r.HTML, "<script>") {
+			t.Errorf("value not escaped: %s", r.HTML)
+		}
+	})
+
+	t.Run("no translations", func(t *testing.T) {
+		tmpl := testTemplate()
+		tmpl.Translations = nil
+		if _, err := RenderHTML(tmpl, testReport(), "en"); err != ErrNoTranslation {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package study
+
+import (
+	"log/slog"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
+)
+
+// screenSubmittedResponse scores the submission for spam and bots, if the study enabled it, and marks suspicious
+// responses as quarantined. Responses of synthetic participants are not scored.
+func screenSubmittedResponse(instanceID string, study studyTypes.Study, pState studyTypes.Participant, response *studyTypes.SurveyResponse) {
+	config := study.Configs.ResponseQuarantine
+	if config == nil || !config.Enabled || pState.Synthetic {
+		return
+	}
+
+	duplicate := false
+	response.ContentHash = studyUtils.ResponseContentHash(*response, config.DuplicateMinItems)
+	if response.ContentHash != "" {
+		var err error
+		duplicate, err = studyDBService.HasResponseWithContentHash(instanceID, study.Key, response.Key, response.ContentHash, pState.ParticipantID)
+		if err != nil {
+			// the response is scored without the duplicate check
+			slog.Error("failed to check for duplicate responses", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("surveyKey", response.Key), slog.String("error", err.Error()))
+		}
+	}
+
+	response.Quarantine = studyUtils.ScoreSubmission(*config, *response, duplicate)
+	if response.Quarantine != nil {
+		slog.Warn("submitted response quarantined", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("surveyKey", response.Key), slog.Float64("score", response.Quarantine.Score), slog.Any("signals", response.Quarantine.Signals))
+	}
+}
@@ -0,0 +1,106 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DEFAULT_SCHEDULED_EVENTS_BATCH_SIZE = 100
+	scheduledEventsLease                = 10 * time.Minute
+)
+
+type studyWithRules struct {
+	study studyTypes.Study
+	rules []studyTypes.Expression
+	err   error
+}
+
+// ProcessScheduledEvents fires the due scheduled events of the instance through the timer rules of the study.
+// Events are claimed in batches, so multiple job runners can work in parallel.
+func ProcessScheduledEvents(instanceID string, batchSize int64) {
+	if batchSize <= 0 {
+		batchSize = DEFAULT_SCHEDULED_EVENTS_BATCH_SIZE
+	}
+
+	studies := map[string]*studyWithRules{}
+	counter := 0
+	for {
+		now := time.Now()
+		events, err := studyDBService.ClaimDueScheduledEvents(instanceID, primitive.NewObjectID().Hex(), now, now.Add(scheduledEventsLease), batchSize)
+		if err != nil {
+			slog.Error("Error claiming scheduled events", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			break
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		for _, event := range events {
+			s, ok := studies[event.StudyKey]
+			if !ok {
+				s = loadStudyWithRules(instanceID, event.StudyKey)
+				studies[event.StudyKey] = s
+			}
+
+			status := studyTypes.SCHEDULED_EVENT_STATUS_FIRED
+			errMsg := ""
+			if err := fireScheduledEvent(instanceID, s, event); err != nil {
+				status = studyTypes.SCHEDULED_EVENT_STATUS_FAILED
+				errMsg = err.Error()
+			}
+			if err := studyDBService.MarkScheduledEventProcessed(instanceID, event.ID, status, errMsg); err != nil {
+				slog.Error("Error updating scheduled event", slog.String("instanceID", instanceID), slog.String("eventID", event.ID.Hex()), slog.String("error", err.Error()))
+			}
+			counter += 1
+		}
+	}
+
+	if counter > 0 {
+		slog.Info("Processed scheduled events", slog.String("instanceID", instanceID), slog.Int("count", counter))
+	}
+}
+
+func loadStudyWithRules(instanceID string, studyKey string) *studyWithRules {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		return &studyWithRules{err: err}
+	}
+	rulesObj, err := studyDBService.GetCurrentStudyRules(instanceID, studyKey)
+	if err != nil {
+		return &studyWithRules{err: err}
+	}
+	return &studyWithRules{study: study, rules: rulesObj.Rules}
+}
+
+func fireScheduledEvent(instanceID string, s *studyWithRules, event studyTypes.ScheduledEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	pState, err := studyDBService.GetParticipantByID(instanceID, event.StudyKey, event.ParticipantID)
+	if err != nil {
+		slog.Error("Error getting participant state", slog.String("instanceID", instanceID), slog.String("studyKey", event.StudyKey), slog.String("participantID", event.ParticipantID), slog.String("error", err.Error()))
+		return err
+	}
+	if pState.StudyStatus == studyTypes.PARTICIPANT_STUDY_STATUS_ACCOUNT_DELETED {
+		return errors.New("participant account deleted")
+	}
+
+	currentEvent := studyengine.StudyEvent{
+		Type:       studyengine.STUDY_EVENT_TYPE_TIMER,
+		InstanceID: instanceID,
+		StudyKey:   event.StudyKey,
+		EventKey:   event.EventKey,
+		Payload: map[string]interface{}{
+			"scheduledEventID": event.ID.Hex(),
+			"scheduledFor":     float64(event.FireAt.Unix()),
+		},
+	}
+	return runTimerRulesForParticipant(s.study, s.rules, pState, currentEvent)
+}
@@ -0,0 +1,92 @@
+package study
+
+import (
+	"errors"
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestStudyCompletionForProfiles(t *testing.T) {
+	activeStudy := studyTypes.Study{Key: "active", SecretKey: "secret1", Status: studyTypes.STUDY_STATUS_ACTIVE}
+	closedStudy := studyTypes.Study{Key: "closed", SecretKey: "secret2", Status: "inactive"}
+	studies := []studyTypes.Study{activeStudy, closedStudy}
+	profileIDs := []string{"profile1", "profile2"}
+
+	// participant states by study key and profile ID
+	lookup := func(t *testing.T, states map[string]map[string]studyTypes.Participant) func(string, string) (studyTypes.Participant, error) {
+		byParticipantID := map[string]studyTypes.Participant{}
+		for _, study := range studies {
+			for profileID, state := range states[study.Key] {
+				participantID, _, err := ComputeParticipantIDs(study, profileID)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				byParticipantID[study.Key+participantID] = state
+			}
+		}
+		return func(studyKey string, participantID string) (studyTypes.Participant, error) {
+			state, ok := byParticipantID[studyKey+participantID]
+			if !ok {
+				return state, mongo.ErrNoDocuments
+			}
+			return state, nil
+		}
+	}
+
+	t.Run("no participation", func(t *testing.T) {
+		completed, _, err := studyCompletionForProfiles(studies, profileIDs, lookup(t, nil))
+		if err != nil || completed {
+			t.Errorf("profiles without participation must not count as completed, got %v %v", completed, err)
+		}
+	})
+
+	t.Run("no studies", func(t *testing.T) {
+		completed, _, err := studyCompletionForProfiles(nil, profileIDs, lookup(t, nil))
+		if err != nil || completed {
+			t.Errorf("expected not completed, got %v %v", completed, err)
+		}
+	})
+
+	t.Run("active participation", func(t *testing.T) {
+		completed, _, err := studyCompletionForProfiles(studies, profileIDs, lookup(t, map[string]map[string]studyTypes.Participant{
+			"active": {"profile1": {StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE}},
+			"closed": {"profile2": {StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE}},
+		}))
+		if err != nil || completed {
+			t.Errorf("expected not completed, got %v %v", completed, err)
+		}
+	})
+
+	t.Run("on waiting list", func(t *testing.T) {
+		completed, _, err := studyCompletionForProfiles(studies, profileIDs, lookup(t, map[string]map[string]studyTypes.Participant{
+			"active": {"profile1": {StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST}},
+		}))
+		if err != nil || completed {
+			t.Errorf("expected not completed, got %v %v", completed, err)
+		}
+	})
+
+	t.Run("exited and study closed", func(t *testing.T) {
+		completed, lastActivity, err := studyCompletionForProfiles(studies, profileIDs, lookup(t, map[string]map[string]studyTypes.Participant{
+			"active": {"profile1": {StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_EXITED, EnteredAt: 100, LastSubmissions: map[string]int64{"weekly": 300}}},
+			"closed": {"profile2": {StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE, EnteredAt: 200}},
+		}))
+		if err != nil || !completed {
+			t.Errorf("expected completed, got %v %v", completed, err)
+		}
+		if lastActivity != 300 {
+			t.Errorf("expected last activity 300, got %d", lastActivity)
+		}
+	})
+
+	t.Run("lookup error", func(t *testing.T) {
+		_, _, err := studyCompletionForProfiles(studies, profileIDs, func(string, string) (studyTypes.Participant, error) {
+			return studyTypes.Participant{}, errors.New("db error")
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package study
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	STUDY_STREAM_EVENT_NEW_RESPONSE    = "newResponse"
+	STUDY_STREAM_EVENT_NEW_PARTICIPANT = "newParticipant"
+	STUDY_STREAM_EVENT_RULE_ERROR      = "ruleError"
+)
+
+var allStudyStreamEventTypes = []string{
+	STUDY_STREAM_EVENT_NEW_RESPONSE,
+	STUDY_STREAM_EVENT_NEW_PARTICIPANT,
+	STUDY_STREAM_EVENT_RULE_ERROR,
+}
+
+type StudyStreamEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type NewResponseEventData struct {
+	ResponseID    string `json:"responseId"`
+	SurveyKey     string `json:"surveyKey"`
+	VersionID     string `json:"versionId"`
+	ParticipantID string `json:"participantId"`
+	SubmittedAt   int64  `json:"submittedAt"`
+}
+
+type NewParticipantEventData struct {
+	ParticipantID string `json:"participantId"`
+	StudyStatus   string `json:"studyStatus"`
+	EnteredAt     int64  `json:"enteredAt"`
+}
+
+// ParseStudyStreamEventTypes parses a comma separated list of event types. An empty list selects all event types.
+func ParseStudyStreamEventTypes(list string) ([]string, error) {
+	if list == "" {
+		return allStudyStreamEventTypes, nil
+	}
+	eventTypes := []string{}
+	for _, t := range strings.Split(list, ",") {
+		known := false
+		for _, k := range allStudyStreamEventTypes {
+			if t == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event type: %s", t)
+		}
+		eventTypes = append(eventTypes, t)
+	}
+	return eventTypes, nil
+}
+
+type changeStreamConverter func(fullDocument bson.Raw) (StudyStreamEvent, error)
+
+// WatchStudyEvents calls send for every new event of the given types until ctx is done or send fails.
+// It uses MongoDB change streams and returns an error right away if they are not available (no replica set).
+func WatchStudyEvents(ctx context.Context, instanceID string, studyKey string, eventTypes []string, send func(StudyStreamEvent) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streams := []*mongo.ChangeStream{}
+	converters := []changeStreamConverter{}
+	defer func() {
+		for _, stream := range streams {
+			stream.Close(context.Background())
+		}
+	}()
+
+	for _, eventType := range eventTypes {
+		var stream *mongo.ChangeStream
+		var err error
+		var converter changeStreamConverter
+
+		switch eventType {
+		case STUDY_STREAM_EVENT_NEW_RESPONSE:
+			stream, err = studyDBService.WatchNewResponses(ctx, instanceID, studyKey)
+			converter = newResponseEvent
+		case STUDY_STREAM_EVENT_NEW_PARTICIPANT:
+			stream, err = studyDBService.WatchNewParticipants(ctx, instanceID, studyKey)
+			converter = newParticipantEvent
+		case STUDY_STREAM_EVENT_RULE_ERROR:
+			stream, err = studyDBService.WatchRuleErrors(ctx, instanceID, studyKey)
+			converter = ruleErrorEvent
+		default:
+			return fmt.Errorf("unknown event type: %s", eventType)
+		}
+		if err != nil {
+			return err
+		}
+		streams = append(streams, stream)
+		converters = append(converters, converter)
+	}
+
+	events := make(chan StudyStreamEvent)
+	errs := make(chan error, len(streams))
+	for i := range streams {
+		go forwardChangeStream(ctx, streams[i], converters[i], events, errs)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case event := <-events:
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func forwardChangeStream(ctx context.Context, stream *mongo.ChangeStream, convert changeStreamConverter, events chan<- StudyStreamEvent, errs chan<- error) {
+	for stream.Next(ctx) {
+		var change struct {
+			FullDocument bson.Raw `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			slog.Error("failed to decode change event", slog.String("error", err.Error()))
+			continue
+		}
+		event, err := convert(change.FullDocument)
+		if err != nil {
+			slog.Error("failed to decode changed document", slog.String("error", err.Error()))
+			continue
+		}
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		errs <- err
+	}
+}
+
+func newResponseEvent(doc bson.Raw) (StudyStreamEvent, error) {
+	var response studyTypes.SurveyResponse
+	if err := bson.Unmarshal(doc, &response); err != nil {
+		return StudyStreamEvent{}, err
+	}
+	return StudyStreamEvent{
+		Type: STUDY_STREAM_EVENT_NEW_RESPONSE,
+		Data: NewResponseEventData{
+			ResponseID:    response.ID.Hex(),
+			SurveyKey:     response.Key,
+			VersionID:     response.VersionID,
+			ParticipantID: response.ParticipantID,
+			SubmittedAt:   response.SubmittedAt,
+		},
+	}, nil
+}
+
+func newParticipantEvent(doc bson.Raw) (StudyStreamEvent, error) {
+	var pState studyTypes.Participant
+	if err := bson.Unmarshal(doc, &pState); err != nil {
+		return StudyStreamEvent{}, err
+	}
+	return StudyStreamEvent{
+		Type: STUDY_STREAM_EVENT_NEW_PARTICIPANT,
+		Data: NewParticipantEventData{
+			ParticipantID: pState.ParticipantID,
+			StudyStatus:   pState.StudyStatus,
+			EnteredAt:     pState.EnteredAt,
+		},
+	}, nil
+}
+
+func ruleErrorEvent(doc bson.Raw) (StudyStreamEvent, error) {
+	var ruleError studyTypes.RuleError
+	if err := bson.Unmarshal(doc, &ruleError); err != nil {
+		return StudyStreamEvent{}, err
+	}
+	return StudyStreamEvent{
+		Type: STUDY_STREAM_EVENT_RULE_ERROR,
+		Data: ruleError,
+	}, nil
+}
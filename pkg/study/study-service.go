@@ -8,12 +8,16 @@ import (
 	"time"
 
 	studydb "github.com/case-framework/case-backend/pkg/db/study"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
 	"github.com/case-framework/case-backend/pkg/study/types"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -35,7 +39,20 @@ func Init(
 	studyengine.InitStudyEngine(studyDB, externalServices)
 }
 
-func OnEnterStudy(instanceID string, studyKey string, profileID string) (result []studyTypes.AssignedSurvey, err error) {
+// OnEnterStudy enters the profile into the study. If the study is full, the participant is put on the waiting list
+// and waitingList is true.
+func OnEnterStudy(ctx context.Context, instanceID string, studyKey string, profileID string) (result []studyTypes.AssignedSurvey, waitingList bool, err error) {
+	return enterStudy(ctx, instanceID, studyKey, profileID, nil)
+}
+
+// OnImportParticipant enters the profile of an account created by a participant import into the study. The flags are
+// set before the entry rules run, so that the rules can use them.
+func OnImportParticipant(ctx context.Context, instanceID string, studyKey string, profileID string, flags map[string]string) error {
+	_, _, err := enterStudy(ctx, instanceID, studyKey, profileID, flags)
+	return err
+}
+
+func enterStudy(ctx context.Context, instanceID string, studyKey string, profileID string, flags map[string]string) (result []studyTypes.AssignedSurvey, waitingList bool, err error) {
 	study, err := getStudyIfActive(instanceID, studyKey)
 	if err != nil {
 		slog.Error("error getting study", slog.String("error", err.Error()))
@@ -52,6 +69,7 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string) (result
 	noon := time.Now().Truncate(24 * time.Hour).Add(12 * time.Hour).Unix()
 
 	isNewParticipant := true
+	previousStatus := ""
 
 	// if participant exists, reuse it
 	pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
@@ -61,12 +79,32 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string) (result
 
 		if pState.StudyStatus == studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
 			slog.Debug("Participant is already active, do not run study rules", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
-			return pState.AssignedSurveys, nil
+			return pState.AssignedSurveys, false, nil
+		}
+		if pState.StudyStatus == studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST {
+			slog.Debug("Participant is on the waiting list", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+			return []studyTypes.AssignedSurvey{}, true, nil
 		}
+		previousStatus = pState.StudyStatus
 		pState.StudyStatus = studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE
 		isNewParticipant = false
 	}
 
+	full, err := isEnrollmentCapReached(instanceID, study)
+	if err != nil {
+		return
+	}
+	if full {
+		// a closed enrollment window rejects instead of putting the participant on the waiting list
+		if err = checkEnrollmentWindow(study.Configs.EnrollmentWindow, time.Now()); err != nil {
+			slog.Info("Enrollment rejected", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("reason", err.Error()))
+			return
+		}
+	} else if err = checkAndCountEnrollment(instanceID, study); err != nil {
+		slog.Info("Enrollment rejected", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("reason", err.Error()))
+		return
+	}
+
 	if isNewParticipant {
 		pState = studyTypes.Participant{
 			ParticipantID: participantID,
@@ -74,6 +112,14 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string) (result
 			StudyStatus:   studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
 		}
 	}
+	if len(flags) > 0 {
+		if pState.Flags == nil {
+			pState.Flags = map[string]string{}
+		}
+		for k, v := range flags {
+			pState.Flags[k] = v
+		}
+	}
 
 	if isNewParticipant {
 		// save particicpant id profile lookup
@@ -83,7 +129,20 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string) (result
 		}
 	}
 
+	if full {
+		// the entry rules run when the participant is promoted from the waiting list
+		pState.StudyStatus = studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST
+		pState.EnteredAt = noon
+		if _, err = saveParticipantState(instanceID, studyKey, previousStatus, pState); err != nil {
+			slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+			return
+		}
+		slog.Info("Participant put on the waiting list", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+		return []studyTypes.AssignedSurvey{}, true, nil
+	}
+
 	currentEvent := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
 		Type:                                  studyengine.STUDY_EVENT_TYPE_ENTER,
 		InstanceID:                            instanceID,
 		StudyKey:                              studyKey,
@@ -96,7 +155,7 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string) (result
 	}
 
 	// save participant state
-	pState, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	pState, err = saveParticipantState(instanceID, studyKey, previousStatus, actionResult.PState)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -114,13 +173,18 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string) (result
 	return
 }
 
-func OnRegisterTempParticipant(instanceID string, studyKey string) (pState *studyTypes.Participant, err error) {
+func OnRegisterTempParticipant(ctx context.Context, instanceID string, studyKey string) (pState *studyTypes.Participant, err error) {
 	study, err := getStudyIfActive(instanceID, studyKey)
 	if err != nil {
 		slog.Error("error getting study", slog.String("error", err.Error()))
 		return
 	}
 
+	if err = checkAndCountEnrollment(instanceID, study); err != nil {
+		slog.Info("Enrollment of temporary participant rejected", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("reason", err.Error()))
+		return
+	}
+
 	tempProfileID := primitive.NewObjectID().Hex()
 	participantID, _, err := ComputeParticipantIDs(study, tempProfileID)
 	if err != nil {
@@ -135,6 +199,7 @@ func OnRegisterTempParticipant(instanceID string, studyKey string) (pState *stud
 	}
 
 	currentEvent := studyengine.StudyEvent{
+		RequestID:  requestid.FromContext(ctx),
 		Type:       studyengine.STUDY_EVENT_TYPE_ENTER,
 		InstanceID: instanceID,
 		StudyKey:   studyKey,
@@ -147,7 +212,7 @@ func OnRegisterTempParticipant(instanceID string, studyKey string) (pState *stud
 	}
 
 	// save participant state
-	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	_, err = saveParticipantState(instanceID, studyKey, "", actionResult.PState)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -157,7 +222,7 @@ func OnRegisterTempParticipant(instanceID string, studyKey string) (pState *stud
 	return
 }
 
-func OnCustomStudyEvent(instanceID string, studyKey string, profileID string, eventKey string, payload map[string]interface{}) (result []studyTypes.AssignedSurvey, err error) {
+func OnCustomStudyEvent(ctx context.Context, instanceID string, studyKey string, profileID string, eventKey string, payload map[string]interface{}) (result []studyTypes.AssignedSurvey, err error) {
 	study, err := getStudyIfActive(instanceID, studyKey)
 	if err != nil {
 		slog.Error("error getting study", slog.String("error", err.Error()))
@@ -177,6 +242,7 @@ func OnCustomStudyEvent(instanceID string, studyKey string, profileID string, ev
 	}
 
 	currentEvent := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
 		Type:                                  studyengine.STUDY_EVENT_TYPE_CUSTOM,
 		InstanceID:                            instanceID,
 		StudyKey:                              studyKey,
@@ -192,7 +258,7 @@ func OnCustomStudyEvent(instanceID string, studyKey string, profileID string, ev
 	}
 
 	// save participant state
-	pState, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	pState, err = saveParticipantState(instanceID, studyKey, pState.StudyStatus, actionResult.PState)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -210,7 +276,7 @@ func OnCustomStudyEvent(instanceID string, studyKey string, profileID string, ev
 	return
 }
 
-func OnMergeTempParticipant(instanceID string, studyKey string, profileID string, temporaryParticipantID string) (result []studyTypes.AssignedSurvey, err error) {
+func OnMergeTempParticipant(ctx context.Context, instanceID string, studyKey string, profileID string, temporaryParticipantID string) (result []studyTypes.AssignedSurvey, err error) {
 	study, err := getStudyIfActive(instanceID, studyKey)
 	if err != nil {
 		slog.Error("error getting study", slog.String("error", err.Error()))
@@ -244,7 +310,9 @@ func OnMergeTempParticipant(instanceID string, studyKey string, profileID string
 	}
 
 	pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+	previousStatus := pState.StudyStatus
 	if err != nil {
+		previousStatus = ""
 		slog.Info("participant not found, creating new one", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
 		pState = studyTypes.Participant{
 			ParticipantID: participantID,
@@ -262,6 +330,7 @@ func OnMergeTempParticipant(instanceID string, studyKey string, profileID string
 
 	// Merge participant states
 	currentEvent := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
 		InstanceID:                            instanceID,
 		StudyKey:                              studyKey,
 		Type:                                  studyengine.STUDY_EVENT_TYPE_MERGE,
@@ -276,7 +345,7 @@ func OnMergeTempParticipant(instanceID string, studyKey string, profileID string
 	}
 
 	// save participant state
-	pState, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	pState, err = saveParticipantState(instanceID, studyKey, previousStatus, actionResult.PState)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -298,6 +367,14 @@ func OnMergeTempParticipant(instanceID string, studyKey string, profileID string
 		slog.Debug("updated reports for participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.Int64("count", count))
 	}
 
+	// update participant ID to all file infos
+	count, err = studyDBService.UpdateParticipantIDonFileInfos(instanceID, studyKey, temporaryParticipantID, participantID)
+	if err != nil {
+		slog.Error("Error updating participant ID on file infos", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+	} else {
+		slog.Debug("updated file infos for participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.Int64("count", count))
+	}
+
 	// update participant ID to all confidential responses
 	oldConfidentialID, err := ComputeConfidentialIDForParticipant(study, temporaryParticipantID)
 	if err != nil {
@@ -323,7 +400,7 @@ func OnMergeTempParticipant(instanceID string, studyKey string, profileID string
 	return
 }
 
-func OnSubmitResponse(instanceID string, studyKey string, profileID string, response studyTypes.SurveyResponse) (result []studyTypes.AssignedSurvey, err error) {
+func OnSubmitResponse(ctx context.Context, instanceID string, studyKey string, profileID string, response studyTypes.SurveyResponse) (result []studyTypes.AssignedSurvey, err error) {
 	response.ArrivedAt = time.Now().Unix()
 
 	study, err := getStudyIfActive(instanceID, studyKey)
@@ -350,7 +427,13 @@ func OnSubmitResponse(instanceID string, studyKey string, profileID string, resp
 		return
 	}
 
+	if err = validateSubmittedResponse(instanceID, study, pState, &response); err != nil {
+		return
+	}
+	screenSubmittedResponse(instanceID, study, pState, &response)
+
 	currentEvent := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
 		Type:                                  studyengine.STUDY_EVENT_TYPE_SUBMIT,
 		InstanceID:                            instanceID,
 		StudyKey:                              studyKey,
@@ -365,7 +448,7 @@ func OnSubmitResponse(instanceID string, studyKey string, profileID string, resp
 	}
 
 	// save participant state
-	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	_, err = saveParticipantState(instanceID, studyKey, pState.StudyStatus, actionResult.PState)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -378,6 +461,7 @@ func OnSubmitResponse(instanceID string, studyKey string, profileID string, resp
 	}
 
 	saveReports(instanceID, studyKey, actionResult.ReportsToCreate, responseId)
+	publishResponseSubmitted(instanceID, studyKey, response.Key, participantID, responseId)
 
 	result = make([]studyTypes.AssignedSurvey, len(actionResult.PState.AssignedSurveys))
 	for i, survey := range actionResult.PState.AssignedSurveys {
@@ -388,7 +472,92 @@ func OnSubmitResponse(instanceID string, studyKey string, profileID string, resp
 	return
 }
 
-func OnSubmitResponseForTempParticipant(instanceID string, studyKey string, participantID string, response studyTypes.SurveyResponse) (result []studyTypes.AssignedSurvey, err error) {
+// OnSubmitResponseCorrection stores a corrected version of an earlier response of the participant, if the study allows corrections.
+// Study rules are not evaluated for corrections, the original submission event already took place.
+func OnSubmitResponseCorrection(instanceID string, studyKey string, profileID string, originalResponseID string, response studyTypes.SurveyResponse) (responseID string, err error) {
+	response.ArrivedAt = time.Now().Unix()
+
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("error", err.Error()))
+		return
+	}
+
+	if study.Configs.ResponseCorrections == nil || !study.Configs.ResponseCorrections.Allowed {
+		err = errors.New("response corrections are not allowed for this study")
+		return
+	}
+
+	participantID, confidentialID, err := ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return
+	}
+
+	pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+	if err != nil {
+		slog.Error("error getting participant state", slog.String("error", err.Error()))
+		return
+	}
+
+	if pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
+		slog.Error("participant is not active", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+		err = errors.New("participant is not active")
+		return
+	}
+
+	original, err := studyDBService.GetResponseByID(instanceID, studyKey, originalResponseID)
+	if err != nil {
+		slog.Error("error getting original response", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("responseID", originalResponseID), slog.String("error", err.Error()))
+		return
+	}
+
+	if original.ParticipantID != participantID {
+		slog.Warn("response does not belong to participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("responseID", originalResponseID))
+		err = errors.New("response not found")
+		return
+	}
+	if original.SupersededBy != "" {
+		err = errors.New("response has already been corrected")
+		return
+	}
+	if original.Key != response.Key {
+		err = errors.New("survey key of correction does not match original response")
+		return
+	}
+	window := study.Configs.ResponseCorrections.Window
+	if window > 0 && original.ArrivedAt+window < time.Now().Unix() {
+		err = errors.New("correction window expired")
+		return
+	}
+
+	if err = validateSubmittedResponse(instanceID, study, pState, &response); err != nil {
+		return
+	}
+
+	response.Supersedes = originalResponseID
+	response.SupersededBy = ""
+	response.Revision = original.Revision + 1
+
+	responseID, err = saveResponses(instanceID, studyKey, response, pState, confidentialID)
+	if err != nil {
+		slog.Error("Error saving responses", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		return
+	}
+
+	err = studyDBService.MarkResponseAsSuperseded(instanceID, studyKey, originalResponseID, responseID)
+	if err != nil {
+		slog.Error("Error marking response as superseded", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("responseID", originalResponseID), slog.String("error", err.Error()))
+		if delErr := studyDBService.DeleteResponseByID(instanceID, studyKey, responseID); delErr != nil {
+			slog.Error("Error removing correction", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("responseID", responseID), slog.String("error", delErr.Error()))
+		}
+		responseID = ""
+		return
+	}
+	return
+}
+
+func OnSubmitResponseForTempParticipant(ctx context.Context, instanceID string, studyKey string, participantID string, response studyTypes.SurveyResponse) (result []studyTypes.AssignedSurvey, err error) {
 	response.ArrivedAt = time.Now().Unix()
 
 	study, err := getStudyIfActive(instanceID, studyKey)
@@ -423,7 +592,13 @@ func OnSubmitResponseForTempParticipant(instanceID string, studyKey string, part
 		return
 	}
 
+	if err = validateSubmittedResponse(instanceID, study, pState, &response); err != nil {
+		return
+	}
+	screenSubmittedResponse(instanceID, study, pState, &response)
+
 	currentEvent := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
 		Type:                                  studyengine.STUDY_EVENT_TYPE_SUBMIT,
 		InstanceID:                            instanceID,
 		StudyKey:                              studyKey,
@@ -437,7 +612,7 @@ func OnSubmitResponseForTempParticipant(instanceID string, studyKey string, part
 	}
 
 	// save participant state
-	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	_, err = saveParticipantState(instanceID, studyKey, pState.StudyStatus, actionResult.PState)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -450,6 +625,7 @@ func OnSubmitResponseForTempParticipant(instanceID string, studyKey string, part
 	}
 
 	saveReports(instanceID, studyKey, actionResult.ReportsToCreate, responseId)
+	publishResponseSubmitted(instanceID, studyKey, response.Key, participantID, responseId)
 
 	result = pState.AssignedSurveys
 	return
@@ -471,7 +647,7 @@ type RunStudyActionResult struct {
 	Duration                       int64
 }
 
-func OnRunStudyAction(req RunStudyActionReq) (*RunStudyActionResult, error) {
+func OnRunStudyAction(ctx context.Context, req RunStudyActionReq) (*RunStudyActionResult, error) {
 	if studyDBService == nil {
 		return nil, errors.New("studyDBService is not initialized")
 	}
@@ -543,6 +719,7 @@ func OnRunStudyAction(req RunStudyActionReq) (*RunStudyActionResult, error) {
 
 			for i, rule := range req.Rules {
 				event := studyengine.StudyEvent{
+					RequestID:                             requestid.FromContext(ctx),
 					InstanceID:                            instanceID,
 					StudyKey:                              studyKey,
 					Type:                                  studyengine.STUDY_EVENT_TYPE_CUSTOM,
@@ -563,7 +740,7 @@ func OnRunStudyAction(req RunStudyActionReq) (*RunStudyActionResult, error) {
 			}
 
 			if anyChange {
-				_, err = studyDBService.SaveParticipantState(instanceID, studyKey, participantData.PState)
+				_, err = saveParticipantState(instanceID, studyKey, p.StudyStatus, participantData.PState)
 				if err != nil {
 					slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
 					return err
@@ -585,7 +762,7 @@ func OnRunStudyAction(req RunStudyActionReq) (*RunStudyActionResult, error) {
 	return result, nil
 }
 
-func OnRunStudyActionForPreviousResponses(req RunStudyActionReq, surveyKeys []string, from int64, to int64) (*RunStudyActionResult, error) {
+func OnRunStudyActionForPreviousResponses(ctx context.Context, req RunStudyActionReq, surveyKeys []string, from int64, to int64) (*RunStudyActionResult, error) {
 	if req.InstanceID == "" || req.StudyKey == "" {
 		return nil, errors.New("instanceID and studyKey are required")
 	}
@@ -682,6 +859,7 @@ func OnRunStudyActionForPreviousResponses(req RunStudyActionReq, surveyKeys []st
 
 					for _, rule := range req.Rules {
 						event := studyengine.StudyEvent{
+							RequestID:                             requestid.FromContext(ctx),
 							InstanceID:                            instanceID,
 							StudyKey:                              studyKey,
 							Type:                                  studyengine.STUDY_EVENT_TYPE_SUBMIT,
@@ -704,7 +882,7 @@ func OnRunStudyActionForPreviousResponses(req RunStudyActionReq, surveyKeys []st
 					}
 					saveReports(instanceID, studyKey, participantData.ReportsToCreate, r.ID.Hex())
 
-					_, err = studyDBService.SaveParticipantState(instanceID, studyKey, participantData.PState)
+					_, err = saveParticipantState(instanceID, studyKey, freshPState.StudyStatus, participantData.PState)
 					if err != nil {
 						slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
 						return err
@@ -767,45 +945,113 @@ func OnStudyTimer(instanceID string, study *studyTypes.Study) {
 		nil,
 		false,
 		func(dbService *studydb.StudyDBService, p studyTypes.Participant, instanceID string, studyKey string, args ...interface{}) error {
-			confidentialID, err := ComputeConfidentialIDForParticipant(*study, p.ParticipantID)
-			if err != nil {
-				slog.Error("Error computing confidential ID", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
-				return err
-			}
+			return runTimerRulesForParticipant(*study, rulesObj.Rules, p, currentEvent)
+		},
+	)
+	if err != nil {
+		slog.Error("Error executing study timer event", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+	}
+}
 
-			currentEvent.ParticipantIDForConfidentialResponses = confidentialID
+// runTimerRulesForParticipant evaluates the rules for a timer event and saves the resulting participant state.
+// Failing rules are skipped, so one broken rule does not block the others.
+func runTimerRulesForParticipant(study studyTypes.Study, rules []studyTypes.Expression, p studyTypes.Participant, currentEvent studyengine.StudyEvent) error {
+	instanceID := currentEvent.InstanceID
+	studyKey := study.Key
 
-			newState := studyengine.ActionData{
-				PState:          p,
-				ReportsToCreate: map[string]studyTypes.Report{},
+	confidentialID, err := ComputeConfidentialIDForParticipant(study, p.ParticipantID)
+	if err != nil {
+		slog.Error("Error computing confidential ID", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+		return err
+	}
+
+	currentEvent.ParticipantIDForConfidentialResponses = confidentialID
+
+	newState := studyengine.ActionData{
+		PState:          p,
+		ReportsToCreate: map[string]studyTypes.Report{},
+	}
+
+	for _, rule := range rules {
+		newState, err = studyengine.ActionEval(rule, newState, currentEvent)
+		if err != nil {
+			slog.Error("Error evaluating study rule", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+			continue
+		}
+	}
+
+	// save participant state
+	_, err = saveParticipantState(instanceID, studyKey, p.StudyStatus, newState.PState)
+	if err != nil {
+		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+		return err
+	}
+
+	saveReports(instanceID, studyKey, newState.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_TIMER)
+	return nil
+}
+
+// MarkParticipantAsSynthetic flags the participant of the profile as synthetic-monitoring participant, so that its data is excluded from statistics and exports
+func MarkParticipantAsSynthetic(instanceID string, studyKey string, profileID string) error {
+	study, err := studyDBService.GetStudy(instanceID, studyKey)
+	if err != nil {
+		return err
+	}
+
+	participantID, _, err := ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		return err
+	}
+
+	return studyDBService.MarkParticipantAsSynthetic(instanceID, studyKey, participantID)
+}
+
+// GetStudyCompletionForProfiles checks whether the profiles have completed their participation in the studies, which
+// callers processing many users load once. A participation is completed if the participant exited or the study is not
+// active anymore, profiles that never took part in a study have not completed anything. Returns the time of the latest
+// study activity of the profiles.
+func GetStudyCompletionForProfiles(instanceID string, studies []studyTypes.Study, profileIDs []string) (completed bool, lastActivity int64, err error) {
+	return studyCompletionForProfiles(studies, profileIDs, func(studyKey string, participantID string) (studyTypes.Participant, error) {
+		return studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+	})
+}
+
+func studyCompletionForProfiles(studies []studyTypes.Study, profileIDs []string, getParticipant func(studyKey string, participantID string) (studyTypes.Participant, error)) (completed bool, lastActivity int64, err error) {
+	finishedParticipations := 0
+	for _, study := range studies {
+		for _, profileID := range profileIDs {
+			participantID, _, err := ComputeParticipantIDs(study, profileID)
+			if err != nil {
+				return false, 0, err
 			}
 
-			for _, rule := range rulesObj.Rules {
-				newState, err = studyengine.ActionEval(rule, newState, currentEvent)
-				if err != nil {
-					slog.Error("Error evaluating study rule", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+			pState, err := getParticipant(study.Key, participantID)
+			if err != nil {
+				if err == mongo.ErrNoDocuments {
 					continue
 				}
+				return false, 0, err
 			}
 
-			// save participant state
-			_, err = studyDBService.SaveParticipantState(instanceID, studyKey, newState.PState)
-			if err != nil {
-				slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
-				return err
+			if study.Status == studyTypes.STUDY_STATUS_ACTIVE && pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_EXITED {
+				return false, 0, nil
 			}
+			finishedParticipations++
 
-			saveReports(instanceID, studyKey, newState.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_TIMER)
-
-			return nil
-		},
-	)
-	if err != nil {
-		slog.Error("Error executing study timer event", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			if pState.EnteredAt > lastActivity {
+				lastActivity = pState.EnteredAt
+			}
+			for _, ts := range pState.LastSubmissions {
+				if ts > lastActivity {
+					lastActivity = ts
+				}
+			}
+		}
 	}
+	return finishedParticipations > 0, lastActivity, nil
 }
 
-func OnLeaveStudy(instanceID string, studyKey string, profileID string) (result []studyTypes.AssignedSurvey, err error) {
+func OnLeaveStudy(ctx context.Context, instanceID string, studyKey string, profileID string) (result []studyTypes.AssignedSurvey, err error) {
 	study, err := getStudyIfActive(instanceID, studyKey)
 	if err != nil {
 		slog.Error("error getting study", slog.String("error", err.Error()))
@@ -824,6 +1070,16 @@ func OnLeaveStudy(instanceID string, studyKey string, profileID string) (result
 		return
 	}
 
+	if pState.StudyStatus == studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST {
+		// the participant never entered, so the leave rules do not run
+		pState.StudyStatus = studyTypes.PARTICIPANT_STUDY_STATUS_EXITED
+		if _, err = saveParticipantState(instanceID, studyKey, studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST, pState); err != nil {
+			slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+			return
+		}
+		return []studyTypes.AssignedSurvey{}, nil
+	}
+
 	if pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
 		slog.Error("participant is not active", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", profileID))
 		err = errors.New("participant is not active")
@@ -833,6 +1089,7 @@ func OnLeaveStudy(instanceID string, studyKey string, profileID string) (result
 	pState.StudyStatus = studyTypes.PARTICIPANT_STUDY_STATUS_EXITED
 
 	currentEvent := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
 		Type:                                  studyengine.STUDY_EVENT_TYPE_LEAVE,
 		InstanceID:                            instanceID,
 		StudyKey:                              studyKey,
@@ -845,7 +1102,7 @@ func OnLeaveStudy(instanceID string, studyKey string, profileID string) (result
 		return
 	}
 
-	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	_, err = saveParticipantState(instanceID, studyKey, studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE, actionResult.PState)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -861,7 +1118,7 @@ func OnLeaveStudy(instanceID string, studyKey string, profileID string) (result
 	return
 }
 
-func OnProfileDeleted(instanceID, profileID string, exitSurveyResp *studyTypes.SurveyResponse) {
+func OnProfileDeleted(ctx context.Context, instanceID, profileID string, exitSurveyResp *studyTypes.SurveyResponse) {
 	if exitSurveyResp != nil {
 		exitSurveyResp.ArrivedAt = time.Now().Unix()
 	}
@@ -899,6 +1156,7 @@ func OnProfileDeleted(instanceID, profileID string, exitSurveyResp *studyTypes.S
 		}
 
 		currentEvent := studyengine.StudyEvent{
+			RequestID:                             requestid.FromContext(ctx),
 			Type:                                  studyengine.STUDY_EVENT_TYPE_LEAVE,
 			InstanceID:                            instanceID,
 			StudyKey:                              study.Key,
@@ -912,10 +1170,19 @@ func OnProfileDeleted(instanceID, profileID string, exitSurveyResp *studyTypes.S
 			continue
 		}
 
+		if study.Configs.DataRetention != nil && study.Configs.DataRetention.EraseOnAccountDeletion {
+			// the study does not keep data of deleted accounts, reports of the leave event are not saved either
+			_, err = DeleteAllParticipantData(instanceID, studyKey, participantID)
+			if err != nil {
+				slog.Error("Error deleting participant data", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+			}
+			continue
+		}
+
 		// save participant state
 		actionResult.PState.StudyStatus = studyTypes.PARTICIPANT_STUDY_STATUS_ACCOUNT_DELETED
 
-		_, err = studyDBService.SaveParticipantState(instanceID, study.Key, actionResult.PState)
+		_, err = saveParticipantState(instanceID, study.Key, pState.StudyStatus, actionResult.PState)
 		if err != nil {
 			slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 			return
@@ -983,3 +1250,40 @@ func ComputeConfidentialIDForParticipant(study studyTypes.Study, participantID s
 	}
 	return confidentialID, nil
 }
+
+func publishResponseSubmitted(instanceID string, studyKey string, surveyKey string, participantID string, responseID string) {
+	go webhooks.Publish(instanceID, webhooks.EVENT_RESPONSE_SUBMITTED, map[string]any{
+		"studyKey":      studyKey,
+		"surveyKey":     surveyKey,
+		"participantId": participantID,
+		"responseId":    responseID,
+	})
+	domainevents.Publish(instanceID, domainevents.EVENT_RESPONSE_SUBMITTED, map[string]any{
+		"studyKey":      studyKey,
+		"surveyKey":     surveyKey,
+		"participantId": participantID,
+		"responseId":    responseID,
+	})
+}
+
+// saveParticipantState saves the state and publishes the change of the study status, previousStatus is empty for
+// new participants
+func saveParticipantState(instanceID string, studyKey string, previousStatus string, pState studyTypes.Participant) (studyTypes.Participant, error) {
+	if isDraftPreviewParticipant(pState) {
+		// preview participants test unpublished drafts, their data is excluded from statistics and exports
+		pState.Synthetic = true
+	}
+	saved, err := studyDBService.SaveParticipantState(instanceID, studyKey, pState)
+	if err != nil {
+		return saved, err
+	}
+	if pState.StudyStatus != previousStatus {
+		domainevents.Publish(instanceID, domainevents.EVENT_PARTICIPANT_STATE_CHANGED, map[string]any{
+			"studyKey":       studyKey,
+			"participantId":  pState.ParticipantID,
+			"previousStatus": previousStatus,
+			"status":         pState.StudyStatus,
+		})
+	}
+	return saved, nil
+}
@@ -0,0 +1,107 @@
+package study
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestOnSubmitResponseCorrection(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	instanceID := "test"
+	initTestStudyDB(t, uri, instanceID)
+
+	newStudy := func(key string, corrections *studyTypes.ResponseCorrectionConfig) studyTypes.Study {
+		study := studyTypes.Study{
+			Key:       key,
+			SecretKey: "study-secret",
+			Status:    studyTypes.STUDY_STATUS_ACTIVE,
+			Configs:   studyTypes.StudyConfigs{ResponseCorrections: corrections},
+		}
+		if err := studyDBService.CreateStudy(instanceID, study); err != nil {
+			t.Fatal(err)
+		}
+		return study
+	}
+	// enters the profile into the study and stores a response of it, returns the response ID
+	submitResponse := func(study studyTypes.Study, profileID string, arrivedAt int64) string {
+		participantID, confidentialID, err := ComputeParticipantIDs(study, profileID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pState := studyTypes.Participant{ParticipantID: participantID, StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE}
+		if _, err := studyDBService.SaveParticipantState(instanceID, study.Key, pState); err != nil {
+			t.Fatal(err)
+		}
+		responseID, err := saveResponses(instanceID, study.Key, studyTypes.SurveyResponse{Key: "intake", ArrivedAt: arrivedAt}, pState, confidentialID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return responseID
+	}
+
+	study := newStudy("corrections", &studyTypes.ResponseCorrectionConfig{Allowed: true, Window: 3600})
+	closedStudy := newStudy("no-corrections", nil)
+
+	original := submitResponse(study, "profile1", time.Now().Unix())
+	expired := submitResponse(study, "profile1", time.Now().Add(-2*time.Hour).Unix())
+	otherParticipants := submitResponse(study, "profile2", time.Now().Unix())
+	closedStudyResponse := submitResponse(closedStudy, "profile1", time.Now().Unix())
+
+	correctionID, err := OnSubmitResponseCorrection(instanceID, study.Key, "profile1", original, studyTypes.SurveyResponse{Key: "intake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	correction, err := studyDBService.GetResponseByID(instanceID, study.Key, correctionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if correction.Supersedes != original || correction.Revision != 1 {
+		t.Errorf("unexpected correction: %+v", correction)
+	}
+	superseded, err := studyDBService.GetResponseByID(instanceID, study.Key, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if superseded.SupersededBy != correctionID {
+		t.Errorf("original response not linked to its correction: %+v", superseded)
+	}
+
+	secondID, err := OnSubmitResponseCorrection(instanceID, study.Key, "profile1", correctionID, studyTypes.SurveyResponse{Key: "intake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, err := studyDBService.GetResponseByID(instanceID, study.Key, secondID); err != nil || second.Revision != 2 {
+		t.Errorf("unexpected revision of the correction of a correction: %+v, %v", second, err)
+	}
+
+	tests := []struct {
+		name       string
+		studyKey   string
+		responseID string
+		surveyKey  string
+		wantErr    string
+	}{
+		{name: "already corrected", studyKey: study.Key, responseID: original, surveyKey: "intake", wantErr: "response has already been corrected"},
+		{name: "other survey", studyKey: study.Key, responseID: expired, surveyKey: "weekly", wantErr: "survey key of correction does not match original response"},
+		{name: "window expired", studyKey: study.Key, responseID: expired, surveyKey: "intake", wantErr: "correction window expired"},
+		{name: "response of another participant", studyKey: study.Key, responseID: otherParticipants, surveyKey: "intake", wantErr: "response not found"},
+		{name: "corrections not allowed", studyKey: closedStudy.Key, responseID: closedStudyResponse, surveyKey: "intake", wantErr: "response corrections are not allowed for this study"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OnSubmitResponseCorrection(instanceID, tt.studyKey, "profile1", tt.responseID, studyTypes.SurveyResponse{Key: tt.surveyKey})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package studyengine
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -10,14 +11,22 @@ import (
 	"strings"
 	"time"
 
-	"github.com/case-framework/case-backend/pkg/apihelpers"
-	httpclient "github.com/case-framework/case-backend/pkg/http-client"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func ActionEval(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	if event.trace != nil {
+		step := event.trace.begin(TRACE_STEP_KIND_ACTION, action.Name)
+		defer func() { event.trace.end(step, nil, err) }()
+
+		if sideEffectActions[action.Name] {
+			step.Skipped = true
+			return oldState, nil
+		}
+	}
+
 	if event.Type == STUDY_EVENT_TYPE_SUBMIT {
 		oldState, err = updateLastSubmissionForSurvey(oldState, event)
 		if err != nil {
@@ -70,6 +79,16 @@ func ActionEval(action studyTypes.Expression, oldState ActionData, event StudyEv
 		newState, err = removeAllConfidentialResponses(action, oldState, event)
 	case "EXTERNAL_EVENT_HANDLER":
 		newState, err = externalEventHandler(action, oldState, event)
+	case "EXTERNAL_EVENT_HANDLER_ASYNC":
+		newState, err = externalEventHandlerAsync(action, oldState, event)
+	case "ASSIGN_ARM_BLOCK_RANDOMIZATION":
+		newState, err = blockRandomizationAction(action, oldState, event)
+	case "ASSIGN_ARM_STRATIFIED_RANDOMIZATION":
+		newState, err = stratifiedRandomizationAction(action, oldState, event)
+	case "SCHEDULE_EVENT":
+		newState, err = scheduleEventAction(action, oldState, event)
+	case "CANCEL_SCHEDULED_EVENTS":
+		newState, err = cancelScheduledEventsAction(action, oldState, event)
 	default:
 		newState = oldState
 		err = errors.New("action name not known")
@@ -854,22 +873,6 @@ func externalEventHandler(action studyTypes.Expression, oldState ActionData, eve
 		pathname = route
 	}
 
-	var mTLSConfig *apihelpers.CertificatePaths
-	if serviceConfig.MutualTLSConfig != nil {
-		mTLSConfig = &apihelpers.CertificatePaths{
-			CACertPath:     serviceConfig.MutualTLSConfig.CAFile,
-			ServerCertPath: serviceConfig.MutualTLSConfig.CertFile,
-			ServerKeyPath:  serviceConfig.MutualTLSConfig.KeyFile,
-		}
-	}
-
-	httpClient := httpclient.ClientConfig{
-		RootURL:                   serviceConfig.URL,
-		APIKey:                    serviceConfig.APIKey,
-		Timeout:                   time.Duration(serviceConfig.Timeout) * time.Second,
-		MutualTLSCertificatePaths: mTLSConfig,
-	}
-
 	payload := ExternalEventPayload{
 		ParticipantState: newState.PState,
 		EventType:        event.Type,
@@ -880,8 +883,12 @@ func externalEventHandler(action studyTypes.Expression, oldState ActionData, eve
 		Payload:          event.Payload,
 	}
 
-	response, err := httpClient.RunHTTPcall(pathname, payload)
+	response, err := callExternalService(serviceConfig, pathname, payload, event.RequestID)
 	if err != nil {
+		if serviceConfig.SkipActionOnFailure {
+			slog.Warn("external event handler failed, action skipped", slog.String("action", action.Name), slog.String("serviceName", serviceName), slog.String("error", err.Error()))
+			return newState, nil
+		}
 		slog.Debug("unexpected error with external event handler", slog.String("action", action.Name), slog.String("serviceName", serviceName), slog.String("error", err.Error()))
 		return newState, err
 	}
@@ -904,3 +911,75 @@ func externalEventHandler(action studyTypes.Expression, oldState ActionData, eve
 	}
 	return
 }
+
+// queue a call to an external service, which is processed in the background and can trigger a follow-up custom event with the response
+func externalEventHandlerAsync(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+
+	if len(action.Data) < 1 {
+		msg := "externalEventHandlerAsync must have at least 1 argument"
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", msg))
+		return newState, errors.New(msg)
+	}
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+
+	// arguments: service name, optional route and optional follow-up event key
+	args := make([]string, min(len(action.Data), 3))
+	for i := range args {
+		v, err := EvalContext.expressionArgResolver(action.Data[i])
+		if err != nil {
+			return newState, err
+		}
+		str, ok := v.(string)
+		if !ok {
+			return newState, errors.New("could not parse arguments")
+		}
+		args[i] = str
+	}
+
+	serviceName := args[0]
+	if _, err := getExternalServicesConfigByName(serviceName); err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
+
+	route := ""
+	if len(args) > 1 {
+		route = strings.TrimPrefix(args[1], "/")
+	}
+	followUpEventKey := ""
+	if len(args) > 2 {
+		followUpEventKey = args[2]
+	}
+
+	payload, err := json.Marshal(ExternalEventPayload{
+		ParticipantState: newState.PState,
+		EventType:        event.Type,
+		StudyKey:         event.StudyKey,
+		InstanceID:       event.InstanceID,
+		Response:         event.Response,
+		EventKey:         event.EventKey,
+		Payload:          event.Payload,
+	})
+	if err != nil {
+		return newState, err
+	}
+
+	_, err = CurrentStudyEngine.studyDBService.AddExternalServiceTask(event.InstanceID, studyTypes.ExternalServiceTask{
+		StudyKey:         event.StudyKey,
+		ParticipantID:    newState.PState.ParticipantID,
+		ServiceName:      serviceName,
+		Route:            route,
+		Payload:          string(payload),
+		FollowUpEventKey: followUpEventKey,
+		RequestID:        event.RequestID,
+	})
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
+	return
+}
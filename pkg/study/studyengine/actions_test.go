@@ -668,3 +668,70 @@ func TestReportActions(t *testing.T) {
 		}
 	})
 }
+
+type mockTaskQueueDBService struct {
+	MockStudyDBService
+	tasks *[]studyTypes.ExternalServiceTask
+}
+
+func (db mockTaskQueueDBService) AddExternalServiceTask(instanceID string, task studyTypes.ExternalServiceTask) (string, error) {
+	*db.tasks = append(*db.tasks, task)
+	return "", nil
+}
+
+func TestExternalEventHandlerAsyncAction(t *testing.T) {
+	tasks := []studyTypes.ExternalServiceTask{}
+	CurrentStudyEngine = &StudyEngine{
+		studyDBService:   mockTaskQueueDBService{tasks: &tasks},
+		externalServices: []ExternalService{{Name: "service1", URL: "http://localhost"}},
+	}
+
+	actionData := ActionData{
+		PState: studyTypes.Participant{ParticipantID: "P1"},
+	}
+	event := StudyEvent{
+		Type:     STUDY_EVENT_TYPE_SUBMIT,
+		StudyKey: "S1",
+		Response: studyTypes.SurveyResponse{Key: "survey1"},
+	}
+
+	t.Run("unknown service", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "EXTERNAL_EVENT_HANDLER_ASYNC",
+			Data: []studyTypes.ExpressionArg{{DType: "str", Str: "unknown"}},
+		}
+		_, err := ActionEval(action, actionData, event)
+		if err == nil {
+			t.Error("expected error")
+		}
+		if len(tasks) != 0 {
+			t.Errorf("unexpected tasks: %v", tasks)
+		}
+	})
+
+	t.Run("queues task", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "EXTERNAL_EVENT_HANDLER_ASYNC",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "service1"},
+				{DType: "str", Str: "/route"},
+				{DType: "str", Str: "service1Result"},
+			},
+		}
+		_, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(tasks) != 1 {
+			t.Fatalf("expected 1 task, got %d", len(tasks))
+		}
+		task := tasks[0]
+		if task.ServiceName != "service1" || task.Route != "route" || task.FollowUpEventKey != "service1Result" || task.ParticipantID != "P1" || task.StudyKey != "S1" {
+			t.Errorf("unexpected task: %+v", task)
+		}
+		if task.Payload == "" {
+			t.Error("payload should be set")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package studyengine
+
+import (
+	"errors"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	TRACE_STEP_KIND_ACTION     = "action"
+	TRACE_STEP_KIND_EXPRESSION = "expression"
+)
+
+// actions with effects outside of the participant state, these are not executed during dry runs
+var sideEffectActions = map[string]bool{
+	"NOTIFY_RESEARCHER":                   true,
+	"REMOVE_CONFIDENTIAL_RESPONSE_BY_KEY": true,
+	"REMOVE_ALL_CONFIDENTIAL_RESPONSES":   true,
+	"EXTERNAL_EVENT_HANDLER":              true,
+	"EXTERNAL_EVENT_HANDLER_ASYNC":        true,
+	"ASSIGN_ARM_BLOCK_RANDOMIZATION":      true,
+	"ASSIGN_ARM_STRATIFIED_RANDOMIZATION": true,
+	"SCHEDULE_EVENT":                      true,
+	"CANCEL_SCHEDULED_EVENTS":             true,
+}
+
+var errSkippedInDryRun = errors.New("external service calls are not executed in dry run")
+
+// EvalTraceStep is one evaluated action or expression, with the nested evaluations it triggered
+type EvalTraceStep struct {
+	Kind    string           `json:"kind"`
+	Name    string           `json:"name"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Skipped bool             `json:"skipped,omitempty"`
+	Steps   []*EvalTraceStep `json:"steps,omitempty"`
+}
+
+// EvalTrace records the evaluation tree of a dry run
+type EvalTrace struct {
+	Steps []*EvalTraceStep `json:"steps"`
+	stack []*EvalTraceStep
+}
+
+func (t *EvalTrace) begin(kind string, name string) *EvalTraceStep {
+	step := &EvalTraceStep{Kind: kind, Name: name}
+	if len(t.stack) > 0 {
+		parent := t.stack[len(t.stack)-1]
+		parent.Steps = append(parent.Steps, step)
+	} else {
+		t.Steps = append(t.Steps, step)
+	}
+	t.stack = append(t.stack, step)
+	return step
+}
+
+func (t *EvalTrace) end(step *EvalTraceStep, result interface{}, err error) {
+	step.Result = result
+	if err != nil {
+		step.Error = err.Error()
+	}
+	if len(t.stack) > 0 {
+		t.stack = t.stack[:len(t.stack)-1]
+	}
+}
+
+// IsDryRun is true for events created for dry runs, which must not cause side effects
+func (event StudyEvent) IsDryRun() bool {
+	return event.trace != nil
+}
+
+// DryRunRules evaluates the rules for the event on a copy of the participant state without side effects.
+// Unlike the regular rule evaluation, it continues after failing rules, errors are reported in the trace.
+func DryRunRules(rules []studyTypes.Expression, pState studyTypes.Participant, event StudyEvent) (ActionData, *EvalTrace) {
+	trace := &EvalTrace{Steps: []*EvalTraceStep{}}
+	event.trace = trace
+
+	state := ActionData{
+		PState:          pState,
+		ReportsToCreate: map[string]studyTypes.Report{},
+	}
+	for _, rule := range rules {
+		newState, err := ActionEval(rule, state, event)
+		if err != nil {
+			continue
+		}
+		state = newState
+	}
+	return state, trace
+}
+
+// DryRunExpression evaluates a single expression for the participant state and event, and returns the value with its evaluation trace
+func DryRunExpression(expression studyTypes.Expression, pState studyTypes.Participant, event StudyEvent) (interface{}, *EvalTrace, error) {
+	trace := &EvalTrace{Steps: []*EvalTraceStep{}}
+	event.trace = trace
+
+	val, err := ExpressionEval(expression, EvalContext{
+		Event:            event,
+		ParticipantState: pState,
+	})
+	return val, trace, err
+}
@@ -0,0 +1,85 @@
+package studyengine
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestDryRunRules(t *testing.T) {
+	rules := []studyTypes.Expression{
+		{
+			Name: "IFTHEN",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "exp", Exp: &studyTypes.Expression{
+					Name: "checkEventType",
+					Data: []studyTypes.ExpressionArg{{DType: "str", Str: STUDY_EVENT_TYPE_CUSTOM}},
+				}},
+				{DType: "exp", Exp: &studyTypes.Expression{
+					Name: "UPDATE_FLAG",
+					Data: []studyTypes.ExpressionArg{
+						{DType: "str", Str: "key"},
+						{DType: "str", Str: "value"},
+					},
+				}},
+				{DType: "exp", Exp: &studyTypes.Expression{
+					Name: "NOTIFY_RESEARCHER",
+					Data: []studyTypes.ExpressionArg{{DType: "str", Str: "type"}},
+				}},
+			},
+		},
+		{Name: "UNKNOWN_ACTION"},
+	}
+
+	pState := studyTypes.Participant{ParticipantID: "P1", Flags: map[string]string{}}
+	state, trace := DryRunRules(rules, pState, StudyEvent{Type: STUDY_EVENT_TYPE_CUSTOM})
+
+	if state.PState.Flags["key"] != "value" {
+		t.Errorf("flag should be set in the returned state: %v", state.PState.Flags)
+	}
+	if len(trace.Steps) != 2 {
+		t.Fatalf("expected 2 top level steps, got %d", len(trace.Steps))
+	}
+
+	ifThen := trace.Steps[0]
+	if ifThen.Name != "IFTHEN" || len(ifThen.Steps) != 3 {
+		t.Fatalf("unexpected IFTHEN step: %+v", ifThen)
+	}
+	if ifThen.Steps[0].Kind != TRACE_STEP_KIND_EXPRESSION || ifThen.Steps[0].Result != true {
+		t.Errorf("unexpected condition step: %+v", ifThen.Steps[0])
+	}
+	if !ifThen.Steps[2].Skipped {
+		t.Errorf("side effect action should be skipped: %+v", ifThen.Steps[2])
+	}
+
+	if trace.Steps[1].Error == "" {
+		t.Error("unknown action should be reported as error")
+	}
+	if len(trace.stack) != 0 {
+		t.Error("trace stack should be empty after the run")
+	}
+}
+
+func TestDryRunExpression(t *testing.T) {
+	exp := studyTypes.Expression{
+		Name: "and",
+		Data: []studyTypes.ExpressionArg{
+			{DType: "exp", Exp: &studyTypes.Expression{
+				Name: "checkEventKey",
+				Data: []studyTypes.ExpressionArg{{DType: "str", Str: "key1"}},
+			}},
+			{DType: "num", Num: 1},
+		},
+	}
+
+	val, trace, err := DryRunExpression(exp, studyTypes.Participant{}, StudyEvent{Type: STUDY_EVENT_TYPE_CUSTOM, EventKey: "key1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != true {
+		t.Errorf("unexpected value: %v", val)
+	}
+	if len(trace.Steps) != 1 || len(trace.Steps[0].Steps) != 1 || trace.Steps[0].Steps[0].Name != "checkEventKey" {
+		t.Errorf("unexpected trace: %+v", trace.Steps)
+	}
+}
@@ -10,13 +10,16 @@ import (
 	"strings"
 	"time"
 
-	"github.com/case-framework/case-backend/pkg/apihelpers"
-	httpclient "github.com/case-framework/case-backend/pkg/http-client"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 func ExpressionEval(expression studyTypes.Expression, evalCtx EvalContext) (val interface{}, err error) {
+	if evalCtx.Event.trace != nil {
+		step := evalCtx.Event.trace.begin(TRACE_STEP_KIND_EXPRESSION, expression.Name)
+		defer func() { evalCtx.Event.trace.end(step, val, err) }()
+	}
+
 	switch expression.Name {
 	case "checkEventType":
 		val, err = evalCtx.checkEventType(expression)
@@ -1567,20 +1570,11 @@ func (ctx EvalContext) externalEventEval(exp studyTypes.Expression) (val interfa
 		pathname = route
 	}
 
-	var mTLSConfig *apihelpers.CertificatePaths
-	if serviceConfig.MutualTLSConfig != nil {
-		mTLSConfig = &apihelpers.CertificatePaths{
-			CACertPath:     serviceConfig.MutualTLSConfig.CAFile,
-			ServerCertPath: serviceConfig.MutualTLSConfig.CertFile,
-			ServerKeyPath:  serviceConfig.MutualTLSConfig.KeyFile,
+	if ctx.Event.IsDryRun() {
+		if serviceConfig.FallbackValue != nil {
+			return serviceConfig.FallbackValue, nil
 		}
-	}
-
-	httpClient := httpclient.ClientConfig{
-		RootURL:                   serviceConfig.URL,
-		APIKey:                    serviceConfig.APIKey,
-		Timeout:                   time.Duration(serviceConfig.Timeout) * time.Second,
-		MutualTLSCertificatePaths: mTLSConfig,
+		return val, errSkippedInDryRun
 	}
 
 	payload := ExternalEventPayload{
@@ -1593,8 +1587,12 @@ func (ctx EvalContext) externalEventEval(exp studyTypes.Expression) (val interfa
 		Payload:          ctx.Event.Payload,
 	}
 
-	response, err := httpClient.RunHTTPcall(pathname, payload)
+	response, err := callExternalService(serviceConfig, pathname, payload, ctx.Event.RequestID)
 	if err != nil {
+		if serviceConfig.FallbackValue != nil {
+			slog.Warn("external service call failed, using fallback value", slog.String("expression", exp.Name), slog.String("serviceName", serviceName), slog.String("error", err.Error()))
+			return serviceConfig.FallbackValue, nil
+		}
 		slog.Error("unexpected error during expression eval", slog.String("expression", exp.Name), slog.String("error", err.Error()))
 		return val, err
 	}
@@ -218,6 +218,22 @@ func (db MockStudyDBService) SaveResearcherMessage(instanceID string, studyKey s
 	return nil
 }
 
+func (db MockStudyDBService) AddExternalServiceTask(instanceID string, task studyTypes.ExternalServiceTask) (string, error) {
+	return "", nil
+}
+
+func (db MockStudyDBService) AssignRandomizationArm(instanceID string, studyKey string, schemeKey string, stratum string, participantID string, newBlock func() []string) (string, error) {
+	return newBlock()[0], nil
+}
+
+func (db MockStudyDBService) AddScheduledEvent(instanceID string, event studyTypes.ScheduledEvent) (string, error) {
+	return "", nil
+}
+
+func (db MockStudyDBService) CancelScheduledEvents(instanceID string, studyKey string, participantID string, eventKey string) (int64, error) {
+	return 0, nil
+}
+
 func TestEvalCheckConditionForOldResponses(t *testing.T) {
 
 	testResponses := []studyTypes.SurveyResponse{
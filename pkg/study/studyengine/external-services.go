@@ -0,0 +1,183 @@
+package studyengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	httpclient "github.com/case-framework/case-backend/pkg/http-client"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	defaultExternalServiceTimeout = 10 * time.Second
+	defaultRetryBackoff           = 200 * time.Millisecond
+	defaultCircuitOpenDuration    = 30 * time.Second
+	defaultAsyncMaxAttempts       = 5
+)
+
+// ErrCircuitOpen is returned without calling the service while its circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type externalServiceStatusError struct {
+	statusCode int
+}
+
+func (e externalServiceStatusError) Error() string {
+	return fmt.Sprintf("external service responded with status %d", e.statusCode)
+}
+
+type circuitBreaker struct {
+	mu                  sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+	// a single trial call is let through after the open period
+	trialRunning bool
+}
+
+var (
+	circuitBreakersMu sync.Mutex
+	circuitBreakers   = map[string]*circuitBreaker{}
+)
+
+func getCircuitBreaker(serviceName string) *circuitBreaker {
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+	cb, ok := circuitBreakers[serviceName]
+	if !ok {
+		cb = &circuitBreaker{}
+		circuitBreakers[serviceName] = cb
+	}
+	return cb
+}
+
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(cb.openUntil) || cb.trialRunning {
+		return false
+	}
+	cb.trialRunning = true
+	return true
+}
+
+func (cb *circuitBreaker) recordResult(success bool, config CircuitBreakerConfig, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.trialRunning = false
+	if success {
+		cb.consecutiveFailures = 0
+		cb.openUntil = time.Time{}
+		return
+	}
+
+	cb.consecutiveFailures += 1
+	if cb.consecutiveFailures >= config.FailureThreshold {
+		openDuration := time.Duration(config.OpenDuration) * time.Second
+		if openDuration <= 0 {
+			openDuration = defaultCircuitOpenDuration
+		}
+		cb.openUntil = now.Add(openDuration)
+	}
+}
+
+// isRetryableStatus reports whether the service was unavailable (no response, status 429 or 5xx)
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// callExternalService posts the payload to the service, applying its timeout, retry and circuit breaker settings
+func callExternalService(serviceConfig ExternalService, pathname string, payload interface{}, requestID string) (map[string]interface{}, error) {
+	var cb *circuitBreaker
+	if serviceConfig.CircuitBreaker != nil && serviceConfig.CircuitBreaker.FailureThreshold > 0 {
+		cb = getCircuitBreaker(serviceConfig.Name)
+		if !cb.allow(time.Now()) {
+			return nil, fmt.Errorf("%s: %w", serviceConfig.Name, ErrCircuitOpen)
+		}
+	}
+
+	var mTLSConfig *apihelpers.CertificatePaths
+	if serviceConfig.MutualTLSConfig != nil {
+		mTLSConfig = &apihelpers.CertificatePaths{
+			CACertPath:     serviceConfig.MutualTLSConfig.CAFile,
+			ServerCertPath: serviceConfig.MutualTLSConfig.CertFile,
+			ServerKeyPath:  serviceConfig.MutualTLSConfig.KeyFile,
+		}
+	}
+
+	timeout := time.Duration(serviceConfig.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultExternalServiceTimeout
+	}
+	httpClient := httpclient.ClientConfig{
+		RootURL:                   serviceConfig.URL,
+		APIKey:                    serviceConfig.APIKey,
+		Timeout:                   timeout,
+		MutualTLSCertificatePaths: mTLSConfig,
+	}
+
+	backoff := time.Duration(serviceConfig.RetryBackoff) * time.Millisecond
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	var (
+		response   map[string]interface{}
+		statusCode int
+		err        error
+	)
+	for attempt := 0; attempt <= serviceConfig.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		response, statusCode, err = httpClient.RunHTTPcallWithStatus(requestid.NewContext(context.Background(), requestID), pathname, payload)
+		if statusCode != 0 && isRetryableStatus(statusCode) {
+			err = externalServiceStatusError{statusCode: statusCode}
+		}
+		if err == nil || !isRetryableStatus(statusCode) {
+			break
+		}
+		slog.Warn("external service call failed", slog.String("serviceName", serviceConfig.Name), slog.Int("attempt", attempt+1), slog.String("error", err.Error()))
+	}
+
+	if cb != nil {
+		// other errors mean the service is reachable, only unavailability opens the circuit
+		cb.recordResult(err == nil || !isRetryableStatus(statusCode), *serviceConfig.CircuitBreaker, time.Now())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// RunExternalServiceTask posts the queued payload to the task's external service.
+// Returns the maximum number of attempts configured for the service, so the caller can decide whether to retry.
+func RunExternalServiceTask(task studyTypes.ExternalServiceTask) (response map[string]interface{}, maxAttempts int, err error) {
+	maxAttempts = defaultAsyncMaxAttempts
+	if CurrentStudyEngine == nil {
+		return nil, maxAttempts, errors.New("study engine not initialized")
+	}
+
+	serviceConfig, err := getExternalServicesConfigByName(task.ServiceName)
+	if err != nil {
+		return nil, maxAttempts, err
+	}
+	if serviceConfig.AsyncMaxAttempts > 0 {
+		maxAttempts = serviceConfig.AsyncMaxAttempts
+	}
+
+	response, err = callExternalService(serviceConfig, task.Route, json.RawMessage(task.Payload), task.RequestID)
+	return response, maxAttempts, err
+}
@@ -0,0 +1,101 @@
+package studyengine
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+)
+
+func newTestExternalService(t *testing.T, statusCodes ...int) (*httptest.Server, *int32) {
+	calls := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := atomic.AddInt32(calls, 1) - 1
+		status := statusCodes[len(statusCodes)-1]
+		if int(i) < len(statusCodes) {
+			status = statusCodes[i]
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": "ok"})
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func TestCallExternalService(t *testing.T) {
+	t.Run("retries on server errors", func(t *testing.T) {
+		server, calls := newTestExternalService(t, 503, 500, 200)
+		resp, err := callExternalService(ExternalService{Name: "retry-test", URL: server.URL, MaxRetries: 2, RetryBackoff: 1}, "", nil, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp["value"] != "ok" {
+			t.Errorf("unexpected response: %v", resp)
+		}
+		if *calls != 3 {
+			t.Errorf("expected 3 calls, got %d", *calls)
+		}
+	})
+
+	t.Run("forwards the request ID", func(t *testing.T) {
+		received := ""
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Get(requestid.HEADER)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": "ok"})
+		}))
+		t.Cleanup(server.Close)
+		if _, err := callExternalService(ExternalService{Name: "request-id-test", URL: server.URL}, "", nil, "req-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if received != "req-1" {
+			t.Errorf("unexpected request ID %q", received)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		server, calls := newTestExternalService(t, 500)
+		_, err := callExternalService(ExternalService{Name: "max-retry-test", URL: server.URL, MaxRetries: 1, RetryBackoff: 1}, "", nil, "")
+		if err == nil {
+			t.Error("expected error")
+		}
+		if *calls != 2 {
+			t.Errorf("expected 2 calls, got %d", *calls)
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		server, calls := newTestExternalService(t, 400)
+		_, err := callExternalService(ExternalService{Name: "client-error-test", URL: server.URL, MaxRetries: 3, RetryBackoff: 1}, "", nil, "")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if *calls != 1 {
+			t.Errorf("expected 1 call, got %d", *calls)
+		}
+	})
+
+	t.Run("circuit breaker opens after failures", func(t *testing.T) {
+		server, calls := newTestExternalService(t, 500)
+		service := ExternalService{
+			Name:           "breaker-test",
+			URL:            server.URL,
+			CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: 60},
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := callExternalService(service, "", nil, ""); err == nil || errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("expected service error, got %v", err)
+			}
+		}
+		_, err := callExternalService(service, "", nil, "")
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected open circuit, got %v", err)
+		}
+		if *calls != 2 {
+			t.Errorf("expected 2 calls, got %d", *calls)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package studyengine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+type randomizationArm struct {
+	Name   string
+	Weight int
+}
+
+// parseRandomizationArm parses an arm definition in the form "name" or "name:weight"
+func parseRandomizationArm(def string) (randomizationArm, error) {
+	name, weightStr, hasWeight := strings.Cut(def, ":")
+	arm := randomizationArm{Name: strings.TrimSpace(name), Weight: 1}
+	if arm.Name == "" {
+		return arm, errors.New("arm name must not be empty")
+	}
+	if hasWeight {
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil || weight < 1 {
+			return arm, fmt.Errorf("invalid weight for arm %s", arm.Name)
+		}
+		arm.Weight = weight
+	}
+	return arm, nil
+}
+
+// generateRandomizationBlock returns a shuffled block containing each arm weight * multiplier times
+func generateRandomizationBlock(arms []randomizationArm, multiplier int) []string {
+	block := []string{}
+	for _, arm := range arms {
+		for i := 0; i < arm.Weight*multiplier; i++ {
+			block = append(block, arm.Name)
+		}
+	}
+	rand.Shuffle(len(block), func(i, j int) {
+		block[i], block[j] = block[j], block[i]
+	})
+	return block
+}
+
+// blockRandomizationAction assigns the participant to an arm using permuted blocks and stores the arm in a participant flag.
+// Arguments: scheme key, flag key, block multiplier, arms ("name" or "name:weight")
+func blockRandomizationAction(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	if len(action.Data) < 5 {
+		return oldState, errors.New("blockRandomizationAction must have at least five arguments")
+	}
+	return randomizeArm(action, oldState, event, nil, action.Data[2], action.Data[3:])
+}
+
+// stratifiedRandomizationAction is the same as blockRandomizationAction, with separate blocks per stratum.
+// Arguments: scheme key, flag key, stratum, block multiplier, arms ("name" or "name:weight")
+func stratifiedRandomizationAction(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	if len(action.Data) < 6 {
+		return oldState, errors.New("stratifiedRandomizationAction must have at least six arguments")
+	}
+	return randomizeArm(action, oldState, event, &action.Data[2], action.Data[3], action.Data[4:])
+}
+
+func randomizeArm(
+	action studyTypes.Expression,
+	oldState ActionData,
+	event StudyEvent,
+	stratumArg *studyTypes.ExpressionArg,
+	multiplierArg studyTypes.ExpressionArg,
+	armArgs []studyTypes.ExpressionArg,
+) (newState ActionData, err error) {
+	newState = oldState
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+
+	schemeKey, err := resolveStringArg(EvalContext, action.Data[0])
+	if err != nil {
+		return newState, err
+	}
+	flagKey, err := resolveStringArg(EvalContext, action.Data[1])
+	if err != nil {
+		return newState, err
+	}
+	if schemeKey == "" || flagKey == "" {
+		return newState, errors.New("scheme key and flag key must not be empty")
+	}
+
+	// already randomized
+	if _, ok := newState.PState.Flags[flagKey]; ok {
+		return newState, nil
+	}
+
+	stratum := ""
+	if stratumArg != nil {
+		v, err := EvalContext.expressionArgResolver(*stratumArg)
+		if err != nil {
+			return newState, err
+		}
+		switch s := v.(type) {
+		case string:
+			stratum = s
+		case float64:
+			stratum = strconv.FormatFloat(s, 'f', -1, 64)
+		case bool:
+			stratum = strconv.FormatBool(s)
+		default:
+			return newState, errors.New("could not parse stratum")
+		}
+	}
+
+	m, err := EvalContext.expressionArgResolver(multiplierArg)
+	if err != nil {
+		return newState, err
+	}
+	multiplier, ok := m.(float64)
+	if !ok || multiplier < 1 {
+		return newState, errors.New("block multiplier must be a number of at least 1")
+	}
+
+	arms := make([]randomizationArm, len(armArgs))
+	for i, arg := range armArgs {
+		def, err := resolveStringArg(EvalContext, arg)
+		if err != nil {
+			return newState, err
+		}
+		arms[i], err = parseRandomizationArm(def)
+		if err != nil {
+			return newState, err
+		}
+	}
+
+	arm, err := CurrentStudyEngine.studyDBService.AssignRandomizationArm(
+		event.InstanceID,
+		event.StudyKey,
+		schemeKey,
+		stratum,
+		newState.PState.ParticipantID,
+		func() []string { return generateRandomizationBlock(arms, int(multiplier)) },
+	)
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
+
+	newState.PState.Flags = make(map[string]string)
+	for k, v := range oldState.PState.Flags {
+		newState.PState.Flags[k] = v
+	}
+	newState.PState.Flags[flagKey] = arm
+	return newState, nil
+}
+
+func resolveStringArg(ctx EvalContext, arg studyTypes.ExpressionArg) (string, error) {
+	v, err := ctx.expressionArgResolver(arg)
+	if err != nil {
+		return "", err
+	}
+	str, ok := v.(string)
+	if !ok {
+		return "", errors.New("could not parse arguments")
+	}
+	return str, nil
+}
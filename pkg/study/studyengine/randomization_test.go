@@ -0,0 +1,143 @@
+package studyengine
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+type mockRandomizationDBService struct {
+	MockStudyDBService
+	strata *[]string
+}
+
+func (db mockRandomizationDBService) AssignRandomizationArm(instanceID string, studyKey string, schemeKey string, stratum string, participantID string, newBlock func() []string) (string, error) {
+	*db.strata = append(*db.strata, stratum)
+	return newBlock()[0], nil
+}
+
+func TestParseRandomizationArm(t *testing.T) {
+	arm, err := parseRandomizationArm("control")
+	if err != nil || arm.Name != "control" || arm.Weight != 1 {
+		t.Errorf("unexpected result: %+v, %v", arm, err)
+	}
+
+	arm, err = parseRandomizationArm("treatment:2")
+	if err != nil || arm.Name != "treatment" || arm.Weight != 2 {
+		t.Errorf("unexpected result: %+v, %v", arm, err)
+	}
+
+	for _, def := range []string{"", ":2", "a:0", "a:x"} {
+		if _, err := parseRandomizationArm(def); err == nil {
+			t.Errorf("expected error for %q", def)
+		}
+	}
+}
+
+func TestGenerateRandomizationBlock(t *testing.T) {
+	block := generateRandomizationBlock([]randomizationArm{
+		{Name: "A", Weight: 1},
+		{Name: "B", Weight: 2},
+	}, 2)
+
+	if len(block) != 6 {
+		t.Fatalf("unexpected block size: %d", len(block))
+	}
+	counts := map[string]int{}
+	for _, arm := range block {
+		counts[arm]++
+	}
+	if counts["A"] != 2 || counts["B"] != 4 {
+		t.Errorf("unexpected arm counts: %v", counts)
+	}
+}
+
+func TestRandomizationActions(t *testing.T) {
+	strata := []string{}
+	CurrentStudyEngine = &StudyEngine{
+		studyDBService: mockRandomizationDBService{strata: &strata},
+	}
+
+	actionData := ActionData{
+		PState: studyTypes.Participant{ParticipantID: "P1", Flags: map[string]string{"region": "north"}},
+	}
+	event := StudyEvent{Type: STUDY_EVENT_TYPE_CUSTOM, StudyKey: "S1"}
+
+	t.Run("block randomization", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "ASSIGN_ARM_BLOCK_RANDOMIZATION",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "scheme1"},
+				{DType: "str", Str: "arm"},
+				{DType: "num", Num: 1},
+				{DType: "str", Str: "A"},
+				{DType: "str", Str: "B"},
+			},
+		}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		arm := newState.PState.Flags["arm"]
+		if arm != "A" && arm != "B" {
+			t.Errorf("unexpected arm: %s", arm)
+		}
+		if _, ok := actionData.PState.Flags["arm"]; ok {
+			t.Error("original state should not be modified")
+		}
+
+		// already assigned participants keep their arm
+		newState.PState.Flags["arm"] = "C"
+		newState, err = ActionEval(action, newState, event)
+		if err != nil || newState.PState.Flags["arm"] != "C" {
+			t.Errorf("arm should not be reassigned: %v, %v", newState.PState.Flags, err)
+		}
+		if len(strata) != 1 || strata[0] != "" {
+			t.Errorf("unexpected allocations: %v", strata)
+		}
+	})
+
+	t.Run("stratified randomization", func(t *testing.T) {
+		strata = strata[:0]
+		action := studyTypes.Expression{
+			Name: "ASSIGN_ARM_STRATIFIED_RANDOMIZATION",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "scheme2"},
+				{DType: "str", Str: "arm2"},
+				{DType: "exp", Exp: &studyTypes.Expression{
+					Name: "getParticipantFlagValue",
+					Data: []studyTypes.ExpressionArg{{DType: "str", Str: "region"}},
+				}},
+				{DType: "num", Num: 2},
+				{DType: "str", Str: "A:2"},
+				{DType: "str", Str: "B"},
+			},
+		}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if newState.PState.Flags["arm2"] == "" {
+			t.Error("arm should be assigned")
+		}
+		if len(strata) != 1 || strata[0] != "north" {
+			t.Errorf("unexpected strata: %v", strata)
+		}
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "ASSIGN_ARM_BLOCK_RANDOMIZATION",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "scheme1"},
+				{DType: "str", Str: "arm"},
+				{DType: "num", Num: 0},
+				{DType: "str", Str: "A"},
+				{DType: "str", Str: "B"},
+			},
+		}
+		if _, err := ActionEval(action, actionData, event); err == nil {
+			t.Error("expected error for block multiplier 0")
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package studyengine
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// scheduleEventAction registers a timer event with the event key for the participant.
+// Arguments: event key, timestamp when to fire the event, optional max jitter in seconds added randomly to spread the load
+func scheduleEventAction(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+	if len(action.Data) < 2 || len(action.Data) > 3 {
+		return newState, errors.New("scheduleEventAction must have two or three arguments")
+	}
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+
+	eventKey, err := resolveStringArg(EvalContext, action.Data[0])
+	if err != nil {
+		return newState, err
+	}
+	if eventKey == "" {
+		return newState, errors.New("event key must not be empty")
+	}
+
+	ts, err := EvalContext.expressionArgResolver(action.Data[1])
+	if err != nil {
+		return newState, err
+	}
+	fireAtTs, ok := ts.(float64)
+	if !ok {
+		return newState, errors.New("could not parse timestamp")
+	}
+
+	jitter := 0.0
+	if len(action.Data) == 3 {
+		j, err := EvalContext.expressionArgResolver(action.Data[2])
+		if err != nil {
+			return newState, err
+		}
+		jitter, ok = j.(float64)
+		if !ok || jitter < 0 {
+			return newState, errors.New("jitter must be a positive number")
+		}
+	}
+
+	fireAt := time.Unix(int64(fireAtTs), 0).Add(scheduledEventJitter(jitter))
+
+	_, err = CurrentStudyEngine.studyDBService.AddScheduledEvent(event.InstanceID, studyTypes.ScheduledEvent{
+		StudyKey:      event.StudyKey,
+		ParticipantID: newState.PState.ParticipantID,
+		EventKey:      eventKey,
+		FireAt:        fireAt,
+	})
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
+	return
+}
+
+// cancelScheduledEventsAction removes pending scheduled events of the participant, optionally only the ones with the event key
+func cancelScheduledEventsAction(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+	if len(action.Data) > 1 {
+		return newState, errors.New("cancelScheduledEventsAction must have at most one argument")
+	}
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+
+	eventKey := ""
+	if len(action.Data) == 1 {
+		eventKey, err = resolveStringArg(EvalContext, action.Data[0])
+		if err != nil {
+			return newState, err
+		}
+	}
+
+	_, err = CurrentStudyEngine.studyDBService.CancelScheduledEvents(event.InstanceID, event.StudyKey, newState.PState.ParticipantID, eventKey)
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+	}
+	return
+}
+
+func scheduledEventJitter(maxSeconds float64) time.Duration {
+	if maxSeconds < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(maxSeconds))) * time.Second
+}
@@ -0,0 +1,101 @@
+package studyengine
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+type mockScheduledEventsDBService struct {
+	MockStudyDBService
+	events    *[]studyTypes.ScheduledEvent
+	cancelled *[]string
+}
+
+func (db mockScheduledEventsDBService) AddScheduledEvent(instanceID string, event studyTypes.ScheduledEvent) (string, error) {
+	*db.events = append(*db.events, event)
+	return "", nil
+}
+
+func (db mockScheduledEventsDBService) CancelScheduledEvents(instanceID string, studyKey string, participantID string, eventKey string) (int64, error) {
+	*db.cancelled = append(*db.cancelled, eventKey)
+	return 1, nil
+}
+
+func TestScheduledEventActions(t *testing.T) {
+	events := []studyTypes.ScheduledEvent{}
+	cancelled := []string{}
+	CurrentStudyEngine = &StudyEngine{
+		studyDBService: mockScheduledEventsDBService{events: &events, cancelled: &cancelled},
+	}
+
+	actionData := ActionData{
+		PState: studyTypes.Participant{ParticipantID: "P1"},
+	}
+	event := StudyEvent{Type: STUDY_EVENT_TYPE_CUSTOM, StudyKey: "S1"}
+
+	t.Run("schedule event", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "SCHEDULE_EVENT",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "followUp"},
+				{DType: "num", Num: 1000},
+			},
+		}
+		if _, err := ActionEval(action, actionData, event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("expected 1 event, got %d", len(events))
+		}
+		if events[0].EventKey != "followUp" || events[0].ParticipantID != "P1" || events[0].StudyKey != "S1" || events[0].FireAt.Unix() != 1000 {
+			t.Errorf("unexpected event: %+v", events[0])
+		}
+	})
+
+	t.Run("schedule event with jitter", func(t *testing.T) {
+		events = events[:0]
+		action := studyTypes.Expression{
+			Name: "SCHEDULE_EVENT",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "followUp"},
+				{DType: "num", Num: 1000},
+				{DType: "num", Num: 60},
+			},
+		}
+		for i := 0; i < 20; i++ {
+			if _, err := ActionEval(action, actionData, event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		for _, e := range events {
+			if e.FireAt.Before(time.Unix(1000, 0)) || !e.FireAt.Before(time.Unix(1060, 0)) {
+				t.Errorf("fire time outside of jitter range: %v", e.FireAt.Unix())
+			}
+		}
+	})
+
+	t.Run("missing timestamp", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "SCHEDULE_EVENT",
+			Data: []studyTypes.ExpressionArg{{DType: "str", Str: "followUp"}},
+		}
+		if _, err := ActionEval(action, actionData, event); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("cancel scheduled events", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "CANCEL_SCHEDULED_EVENTS",
+			Data: []studyTypes.ExpressionArg{{DType: "str", Str: "followUp"}},
+		}
+		if _, err := ActionEval(action, actionData, event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cancelled) != 1 || cancelled[0] != "followUp" {
+			t.Errorf("unexpected cancellations: %v", cancelled)
+		}
+	})
+}
@@ -36,6 +36,10 @@ type StudyDBService interface {
 	GetResponses(instanceID string, studyKey string, filter bson.M, sort bson.M, page int64, limit int64) (responses []studyTypes.SurveyResponse, paginationInfo *studyDB.PaginationInfos, err error)
 	DeleteConfidentialResponses(instanceID string, studyKey string, participantID string, key string) (count int64, err error)
 	SaveResearcherMessage(instanceID string, studyKey string, message studyTypes.StudyMessage) error
+	AddExternalServiceTask(instanceID string, task studyTypes.ExternalServiceTask) (string, error)
+	AssignRandomizationArm(instanceID string, studyKey string, schemeKey string, stratum string, participantID string, newBlock func() []string) (string, error)
+	AddScheduledEvent(instanceID string, event studyTypes.ScheduledEvent) (string, error)
+	CancelScheduledEvents(instanceID string, studyKey string, participantID string, eventKey string) (int64, error)
 }
 
 type ActionData struct {
@@ -47,8 +51,29 @@ type ExternalService struct {
 	Name            string           `yaml:"name"`
 	URL             string           `yaml:"url"`
 	APIKey          string           `yaml:"apiKey"`
-	Timeout         int              `yaml:"timeout"`
+	Timeout         int              `yaml:"timeout"` // seconds per attempt, defaults to 10
 	MutualTLSConfig *MutualTLSConfig `yaml:"mTLSConfig"`
+
+	// Failed calls (network errors, status 429 or 5xx) are retried up to MaxRetries times,
+	// waiting RetryBackoff milliseconds before the first retry and doubling the wait for each further one
+	MaxRetries   int `yaml:"maxRetries"`
+	RetryBackoff int `yaml:"retryBackoff"`
+
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuitBreaker"`
+
+	// Returned by the externalEventEval expression if the service call fails
+	FallbackValue interface{} `yaml:"fallbackValue"`
+	// If true, a failing externalEventHandler action leaves the participant state unchanged instead of returning an error
+	SkipActionOnFailure bool `yaml:"skipActionOnFailure"`
+
+	// Number of attempts for tasks queued by the EXTERNAL_EVENT_HANDLER_ASYNC action, defaults to 5
+	AsyncMaxAttempts int `yaml:"asyncMaxAttempts"`
+}
+
+// CircuitBreakerConfig stops calling a service after consecutive failures, until OpenDuration has passed
+type CircuitBreakerConfig struct {
+	FailureThreshold int `yaml:"failureThreshold"`
+	OpenDuration     int `yaml:"openDuration"` // seconds
 }
 
 type MutualTLSConfig struct {
@@ -66,6 +91,9 @@ type StudyEvent struct {
 	EventKey                              string                    // key of the event	(for custom events)
 	MergeWithParticipant                  studyTypes.Participant    // if need to merge with other participant state, is added here
 	ParticipantIDForConfidentialResponses string
+	RequestID                             string // ID of the API request causing the event, forwarded to external services
+
+	trace *EvalTrace // set for dry runs
 }
 
 // EvalContext contains all the data that can be looked up by expressions
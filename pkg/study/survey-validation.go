@@ -0,0 +1,91 @@
+package study
+
+import (
+	"fmt"
+	"log/slog"
+
+	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const RESPONSE_VALIDATION_UNKNOWN_SURVEY_VERSION = "unknownSurveyVersion"
+
+// ResponseValidationError is returned for submitted responses with validation issues if the study rejects them
+type ResponseValidationError struct {
+	Issues []studyTypes.ResponseValidationIssue
+}
+
+func (e *ResponseValidationError) Error() string {
+	return fmt.Sprintf("response has %d validation issue(s)", len(e.Issues))
+}
+
+// ValidateSurveyDefinition lints the survey definition and reports questions the response exporter cannot handle.
+// The survey is not modified; the warnings are meant to be reviewed before the survey is published.
+func ValidateSurveyDefinition(survey studyTypes.Survey) []studyUtils.SurveyLintWarning {
+	warnings := studyUtils.LintSurveyDefinition(survey)
+	if survey.SurveyDefinition.Key == "" {
+		return warnings
+	}
+
+	preview := surveydefinition.SurveyDefToVersionPreview(&survey, nil)
+	for _, question := range preview.Questions {
+		if question.QuestionType == surveydefinition.QUESTION_TYPE_UNKNOWN {
+			warnings = append(warnings, studyUtils.SurveyLintWarning{
+				Severity: studyUtils.SURVEY_LINT_SEVERITY_WARNING,
+				Code:     studyUtils.SURVEY_LINT_UNKNOWN_QUESTION_TYPE,
+				ItemKey:  question.ID,
+				Message:  "question type is not recognised by the response exporter",
+			})
+			continue
+		}
+		for _, response := range question.Responses {
+			if response.ResponseType != surveydefinition.QUESTION_TYPE_UNKNOWN {
+				continue
+			}
+			warnings = append(warnings, studyUtils.SurveyLintWarning{
+				Severity: studyUtils.SURVEY_LINT_SEVERITY_WARNING,
+				Code:     studyUtils.SURVEY_LINT_UNKNOWN_QUESTION_TYPE,
+				ItemKey:  question.ID,
+				Message:  fmt.Sprintf("response '%s' is not recognised by the response exporter", response.ID),
+			})
+		}
+	}
+	return warnings
+}
+
+// validateSubmittedResponse checks the response against the survey version it was submitted for, depending on the
+// response validation mode of the study: issues are stored with the response, or the response is rejected.
+func validateSubmittedResponse(instanceID string, study studyTypes.Study, pState studyTypes.Participant, response *studyTypes.SurveyResponse) error {
+	config := study.Configs.ResponseValidation
+	if config == nil || (config.Mode != studyTypes.RESPONSE_VALIDATION_MODE_WARN && config.Mode != studyTypes.RESPONSE_VALIDATION_MODE_REJECT) {
+		return nil
+	}
+
+	var issues []studyTypes.ResponseValidationIssue
+	survey, err := getSurveyVersionForResponse(instanceID, study.Key, pState, response.Key, response.VersionID)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			// the response is not lost because of a DB error, it is stored without validation
+			slog.Error("failed to get survey version for response validation", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("surveyKey", response.Key), slog.String("error", err.Error()))
+			return nil
+		}
+		issues = []studyTypes.ResponseValidationIssue{{
+			Code:    RESPONSE_VALIDATION_UNKNOWN_SURVEY_VERSION,
+			Message: fmt.Sprintf("survey version '%s' not found", response.VersionID),
+		}}
+	} else {
+		issues = studyUtils.ValidateSurveyResponse(*survey, *response)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	slog.Warn("submitted response has validation issues", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("surveyKey", response.Key), slog.String("mode", config.Mode), slog.Int("issues", len(issues)))
+	if config.Mode == studyTypes.RESPONSE_VALIDATION_MODE_REJECT {
+		return &ResponseValidationError{Issues: issues}
+	}
+	response.ValidationIssues = issues
+	return nil
+}
@@ -0,0 +1,62 @@
+package study
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestValidateSubmittedResponseForDraft(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	instanceID := "test"
+	initTestStudyDB(t, uri, instanceID)
+
+	study := studyTypes.Study{
+		Key: "validation",
+		Configs: studyTypes.StudyConfigs{
+			ResponseValidation: &studyTypes.ResponseValidationConfig{Mode: studyTypes.RESPONSE_VALIDATION_MODE_REJECT},
+		},
+	}
+	draft, err := SaveSurveyDraft(instanceID, study.Key, studyTypes.Survey{SurveyDefinition: studyTypes.SurveyItem{Key: "intake"}}, "editor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previewParticipant := studyTypes.Participant{
+		ParticipantID: "p1",
+		Flags:         map[string]string{studyTypes.PARTICIPANT_FLAG_DRAFT_PREVIEW: "true"},
+	}
+	participant := studyTypes.Participant{ParticipantID: "p2"}
+
+	tests := []struct {
+		name      string
+		pState    studyTypes.Participant
+		versionID string
+		wantErr   bool
+	}{
+		{name: "preview participant with open draft", pState: previewParticipant, versionID: draftSurveyVersionPrefix + draft.ID.Hex()},
+		{name: "preview participant with other draft", pState: previewParticipant, versionID: draftSurveyVersionPrefix + "000000000000000000000000", wantErr: true},
+		{name: "participant without preview flag", pState: participant, versionID: draftSurveyVersionPrefix + draft.ID.Hex(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := studyTypes.SurveyResponse{Key: "intake", VersionID: tt.versionID}
+			err := validateSubmittedResponse(instanceID, study, tt.pState, &response)
+			var validationErr *ResponseValidationError
+			if tt.wantErr != errors.As(err, &validationErr) {
+				t.Errorf("expected validation error: %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && (len(validationErr.Issues) != 1 || validationErr.Issues[0].Code != RESPONSE_VALIDATION_UNKNOWN_SURVEY_VERSION) {
+				t.Errorf("unexpected issues: %+v", validationErr.Issues)
+			}
+		})
+	}
+}
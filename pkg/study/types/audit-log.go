@@ -0,0 +1,30 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AUDIT_ACTION_CONFIDENTIAL_ACCESS_GRANTED       = "confidential-access-granted"
+	AUDIT_ACTION_CONFIDENTIAL_ACCESS_REVOKED       = "confidential-access-revoked"
+	AUDIT_ACTION_CONFIDENTIAL_RESPONSES_READ       = "confidential-responses-read"
+	AUDIT_ACTION_CONFIDENTIAL_RESPONSES_EXPORTED   = "confidential-responses-exported"
+	AUDIT_ACTION_CONFIDENTIAL_RESPONSES_DOWNLOADED = "confidential-responses-downloaded"
+	AUDIT_ACTION_PARTICIPANT_STATE_EDITED          = "participant-state-edited"
+	AUDIT_ACTION_RESPONSE_QUARANTINE_ACCEPTED      = "response-quarantine-accepted"
+	AUDIT_ACTION_RESPONSE_QUARANTINE_PURGED        = "response-quarantine-purged"
+)
+
+// AuditLogEntry records an access to or change of sensitive study data by a management user
+type AuditLogEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Time          time.Time          `bson:"time" json:"time"`
+	StudyKey      string             `bson:"studyKey" json:"studyKey"`
+	UserID        string             `bson:"userID" json:"userID"`
+	Action        string             `bson:"action" json:"action"`
+	ParticipantID string             `bson:"participantID,omitempty" json:"participantID,omitempty"`
+	Purpose       string             `bson:"purpose,omitempty" json:"purpose,omitempty"`
+	Details       map[string]string  `bson:"details,omitempty" json:"details,omitempty"`
+}
@@ -0,0 +1,20 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConfidentialAccessGrant allows a management user to read confidential responses of a study for a stated purpose until it expires
+type ConfidentialAccessGrant struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey  string             `bson:"studyKey" json:"studyKey"`
+	UserID    string             `bson:"userID" json:"userID"`
+	Purpose   string             `bson:"purpose" json:"purpose"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	GrantedBy string             `bson:"grantedBy" json:"grantedBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	RevokedAt *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	RevokedBy string             `bson:"revokedBy,omitempty" json:"revokedBy,omitempty"`
+}
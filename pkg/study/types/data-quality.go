@@ -0,0 +1,31 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// a study that usually receives responses received none in the checked window
+	DATA_QUALITY_FINDING_RESPONSE_DROP = "responseDrop"
+	// many responses of the window were submitted repeatedly by the same participant for the same survey
+	DATA_QUALITY_FINDING_DUPLICATE_RESPONSES = "duplicateResponses"
+	// the study rules failed much more often than usual
+	DATA_QUALITY_FINDING_RULE_ERROR_SPIKE = "ruleErrorSpike"
+)
+
+// DataQualityFinding is an anomaly found by the data quality job in the responses or rule evaluations of a study
+type DataQualityFinding struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey    string             `bson:"studyKey" json:"studyKey"`
+	Type        string             `bson:"type" json:"type"`
+	DetectedAt  time.Time          `bson:"detectedAt" json:"detectedAt"`
+	WindowStart time.Time          `bson:"windowStart" json:"windowStart"`
+	WindowEnd   time.Time          `bson:"windowEnd" json:"windowEnd"`
+	// the observed value in the window, e.g. the number of responses or the share of duplicates
+	Value float64 `bson:"value" json:"value"`
+	// the value it was compared to, e.g. the average of the preceding windows or the threshold
+	Baseline float64 `bson:"baseline" json:"baseline"`
+	Message  string  `bson:"message" json:"message"`
+}
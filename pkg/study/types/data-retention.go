@@ -0,0 +1,38 @@
+package types
+
+// DataRetentionPolicy defines after how many months study data is deleted. 0 keeps the data forever.
+type DataRetentionPolicy struct {
+	ResponsesMonths             int `bson:"responsesMonths,omitempty" json:"responsesMonths,omitempty"`
+	ConfidentialResponsesMonths int `bson:"confidentialResponsesMonths,omitempty" json:"confidentialResponsesMonths,omitempty"`
+	ReportsMonths               int `bson:"reportsMonths,omitempty" json:"reportsMonths,omitempty"`
+	FilesMonths                 int `bson:"filesMonths,omitempty" json:"filesMonths,omitempty"`
+	// If true, all data of the participant is erased when their account is deleted, instead of keeping the pseudonymous data
+	EraseOnAccountDeletion bool `bson:"eraseOnAccountDeletion,omitempty" json:"eraseOnAccountDeletion,omitempty"`
+}
+
+// DataRetentionCutoffs are the unix timestamps before which data is deleted, 0 means no deletion
+type DataRetentionCutoffs struct {
+	Responses             int64 `json:"responses"`
+	ConfidentialResponses int64 `json:"confidentialResponses"`
+	Reports               int64 `json:"reports"`
+	Files                 int64 `json:"files"`
+}
+
+// DataRetentionReport lists the number of documents deleted (or to be deleted in dry runs) per collection
+type DataRetentionReport struct {
+	StudyKey              string               `json:"studyKey"`
+	DryRun                bool                 `json:"dryRun"`
+	Cutoffs               DataRetentionCutoffs `json:"cutoffs"`
+	Responses             int64                `json:"responses"`
+	ConfidentialResponses int64                `json:"confidentialResponses"`
+	Reports               int64                `json:"reports"`
+	Files                 int64                `json:"files"`
+}
+
+type DataRetentionStats struct {
+	LastRunAt                   int64 `bson:"lastRunAt" json:"lastRunAt"`
+	PrunedResponses             int64 `bson:"prunedResponses" json:"prunedResponses"`
+	PrunedConfidentialResponses int64 `bson:"prunedConfidentialResponses" json:"prunedConfidentialResponses"`
+	PrunedReports               int64 `bson:"prunedReports" json:"prunedReports"`
+	PrunedFiles                 int64 `bson:"prunedFiles" json:"prunedFiles"`
+}
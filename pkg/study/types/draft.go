@@ -0,0 +1,55 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DRAFT_TYPE_SURVEY      = "survey"
+	DRAFT_TYPE_STUDY_RULES = "studyRules"
+)
+
+const (
+	DRAFT_STATUS_DRAFT     = "draft"
+	DRAFT_STATUS_APPROVED  = "approved"
+	DRAFT_STATUS_SCHEDULED = "scheduled"
+	DRAFT_STATUS_PUBLISHED = "published"
+)
+
+// Participants with this flag set to "true" get the open drafts of surveys and study rules instead of the published
+// versions. They are marked as synthetic, so their data is excluded from statistics and exports.
+const PARTICIPANT_FLAG_DRAFT_PREVIEW = "draftPreview"
+
+// Draft is an unpublished version of a survey or of the study rules. It has to be approved by a reviewer other than
+// its last editor before it can be published, either immediately or at the scheduled time.
+type Draft struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey   string             `bson:"studyKey" json:"studyKey"`
+	Type       string             `bson:"type" json:"type"`
+	SurveyKey  string             `bson:"surveyKey,omitempty" json:"surveyKey,omitempty"`
+	Survey     *Survey            `bson:"survey,omitempty" json:"survey,omitempty"`
+	StudyRules *StudyRules        `bson:"studyRules,omitempty" json:"studyRules,omitempty"`
+	Status     string             `bson:"status" json:"status"`
+
+	CreatedBy string    `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedBy string    `bson:"updatedBy" json:"updatedBy"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+
+	ApprovedBy      string     `bson:"approvedBy,omitempty" json:"approvedBy,omitempty"`
+	ApprovedAt      *time.Time `bson:"approvedAt,omitempty" json:"approvedAt,omitempty"`
+	ApprovalComment string     `bson:"approvalComment,omitempty" json:"approvalComment,omitempty"`
+
+	ScheduledFor *time.Time `bson:"scheduledFor,omitempty" json:"scheduledFor,omitempty"`
+	PublishedBy  string     `bson:"publishedBy,omitempty" json:"publishedBy,omitempty"`
+	PublishedAt  *time.Time `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"`
+	// ID of the survey version or study rules version created on publication
+	PublishedVersionID string `bson:"publishedVersionID,omitempty" json:"publishedVersionId,omitempty"`
+}
+
+// IsOpen is true until the draft is published
+func (d Draft) IsOpen() bool {
+	return d.Status != DRAFT_STATUS_PUBLISHED
+}
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EXPORT_JOB_STATUS_QUEUED    = "queued"
+	EXPORT_JOB_STATUS_RUNNING   = "running"
+	EXPORT_JOB_STATUS_COMPLETED = "completed"
+	EXPORT_JOB_STATUS_FAILED    = "failed"
+)
+
+// ExportJob is a response export requested by a management user, produced by the export worker into the filestore
+type ExportJob struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey  string             `bson:"studyKey" json:"studyKey"`
+	CreatedBy string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+
+	Request ExportJobRequest `bson:"request" json:"request"`
+	// arrival time range of the exported responses, for delta exports
+	Window *ExportWindow `bson:"window,omitempty" json:"window,omitempty"`
+
+	Status         string `bson:"status" json:"status"`
+	TargetCount    int    `bson:"targetCount" json:"targetCount"`
+	ProcessedCount int    `bson:"processedCount" json:"processedCount"`
+	// set while a worker runs the job, jobs of crashed workers are picked up again after the lease expired
+	LeaseUntil time.Time `bson:"leaseUntil,omitempty" json:"-"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+
+	// path of the artifact relative to the filestore root
+	ResultFile  string    `bson:"resultFile,omitempty" json:"-"`
+	FileType    string    `bson:"fileType" json:"fileType"`
+	FileSize    int64     `bson:"fileSize,omitempty" json:"fileSize,omitempty"`
+	CompletedAt time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	// the artifact is deleted after this time
+	ExpiresAt time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+
+	// set for jobs created by a scheduled export
+	ScheduledExportID string          `bson:"scheduledExportID,omitempty" json:"scheduledExportID,omitempty"`
+	Delivery          *ExportDelivery `bson:"delivery,omitempty" json:"delivery,omitempty"`
+}
+
+// ExportJobRequest holds the export settings of a job
+type ExportJobRequest struct {
+	SurveyKey string `bson:"surveyKey" json:"surveyKey"`
+	Format    string `bson:"format" json:"format"`
+	// JSON encoded response filter and sort
+	Filter string `bson:"filter,omitempty" json:"filter,omitempty"`
+	Sort   string `bson:"sort,omitempty" json:"sort,omitempty"`
+
+	UseShortKeys        bool     `bson:"useShortKeys" json:"useShortKeys"`
+	QuestionOptionSep   string   `bson:"questionOptionSep" json:"questionOptionSep"`
+	ExtraContextColumns []string `bson:"extraContextColumns,omitempty" json:"extraContextColumns,omitempty"`
+	ValueLabels         string   `bson:"valueLabels,omitempty" json:"valueLabels,omitempty"`
+	LabelLang           string   `bson:"labelLang,omitempty" json:"labelLang,omitempty"`
+
+	// only responses that arrived after Since (unix seconds) are exported
+	Since int64 `bson:"since,omitempty" json:"since,omitempty"`
+	// names the export configuration for delta exports: only responses that arrived after the last successful job with the same key are exported
+	DeltaKey string `bson:"deltaKey,omitempty" json:"deltaKey,omitempty"`
+
+	ParticipantIDs       string   `bson:"participantIDs,omitempty" json:"participantIDs,omitempty"`
+	PseudonymizationSalt string   `bson:"pseudonymizationSalt,omitempty" json:"-"`
+	RedactColumns        []string `bson:"redactColumns,omitempty" json:"redactColumns,omitempty"`
+	TimestampPrecision   string   `bson:"timestampPrecision,omitempty" json:"timestampPrecision,omitempty"`
+
+	// participant state joined onto each response, see surveyresponses.PARTICIPANT_INFO_*
+	ParticipantInfo  []string `bson:"participantInfo,omitempty" json:"participantInfo,omitempty"`
+	ParticipantFlags []string `bson:"participantFlags,omitempty" json:"participantFlags,omitempty"`
+
+	// adds a column with the validation issues found at submission
+	ValidationIssues bool `bson:"validationIssues,omitempty" json:"validationIssues,omitempty"`
+
+	// exports the responses held in the response quarantine too
+	IncludeQuarantined bool `bson:"includeQuarantined,omitempty" json:"includeQuarantined,omitempty"`
+}
+
+// ExportWindow is a range of response arrival times (unix seconds), From is exclusive and Until inclusive
+type ExportWindow struct {
+	From  int64 `bson:"from" json:"from"`
+	Until int64 `bson:"until" json:"until"`
+}
+
+// ExportCheckpoint tracks up to which arrival time the responses were exported for a delta export configuration
+type ExportCheckpoint struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey  string             `bson:"studyKey" json:"studyKey"`
+	DeltaKey  string             `bson:"deltaKey" json:"deltaKey"`
+	Until     int64              `bson:"until" json:"until"`
+	JobID     string             `bson:"jobID" json:"jobID"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EXTERNAL_SERVICE_TASK_STATUS_PENDING    = "pending"
+	EXTERNAL_SERVICE_TASK_STATUS_PROCESSING = "processing"
+	EXTERNAL_SERVICE_TASK_STATUS_DONE       = "done"
+	EXTERNAL_SERVICE_TASK_STATUS_FAILED     = "failed"
+)
+
+// ExternalServiceTask is an external service call queued by a study rule, to be run outside of the participant request
+type ExternalServiceTask struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey      string             `bson:"studyKey" json:"studyKey"`
+	ParticipantID string             `bson:"participantID" json:"participantID"`
+	ServiceName   string             `bson:"serviceName" json:"serviceName"`
+	Route         string             `bson:"route" json:"route"`
+	// JSON encoded request body
+	Payload string `bson:"payload" json:"payload"`
+	// If set, a custom study event with this key and the service response as payload is triggered for the participant on success
+	FollowUpEventKey string `bson:"followUpEventKey,omitempty" json:"followUpEventKey,omitempty"`
+	// ID of the API request that queued the task, forwarded to the service for correlation
+	RequestID string `bson:"requestID,omitempty" json:"requestID,omitempty"`
+
+	Status        string    `bson:"status" json:"status"`
+	Attempts      int       `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LastError     string    `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt     time.Time `bson:"createdAt" json:"createdAt"`
+	CompletedAt   time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+}
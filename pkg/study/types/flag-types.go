@@ -0,0 +1,71 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	FLAG_TYPE_STRING = "string"
+	FLAG_TYPE_NUMBER = "number"
+	FLAG_TYPE_DATE   = "date"
+	FLAG_TYPE_BOOL   = "bool"
+)
+
+func IsValidFlagType(flagType string) bool {
+	switch flagType {
+	case FLAG_TYPE_STRING, FLAG_TYPE_NUMBER, FLAG_TYPE_DATE, FLAG_TYPE_BOOL:
+		return true
+	}
+	return false
+}
+
+// ParseTypedFlagValue converts a flag value to the declared type.
+// Dates can be unix timestamps (seconds), RFC3339 or "YYYY-MM-DD" strings.
+func ParseTypedFlagValue(flagType string, value string) (interface{}, error) {
+	switch flagType {
+	case FLAG_TYPE_STRING:
+		return value, nil
+	case FLAG_TYPE_NUMBER:
+		return strconv.ParseFloat(value, 64)
+	case FLAG_TYPE_BOOL:
+		return strconv.ParseBool(value)
+	case FLAG_TYPE_DATE:
+		if ts, err := strconv.ParseFloat(value, 64); err == nil {
+			return time.Unix(int64(ts), 0).UTC(), nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t.UTC(), nil
+		}
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date: %s", value)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("unknown flag type: %s", flagType)
+}
+
+// ComputeTypedFlags returns the typed values of the declared flags. Flags with values not matching their type are left out.
+func ComputeTypedFlags(flags map[string]string, flagTypes map[string]string) map[string]interface{} {
+	if len(flagTypes) == 0 || len(flags) == 0 {
+		return nil
+	}
+	typedFlags := map[string]interface{}{}
+	for key, flagType := range flagTypes {
+		value, ok := flags[key]
+		if !ok {
+			continue
+		}
+		typedValue, err := ParseTypedFlagValue(flagType, value)
+		if err != nil {
+			continue
+		}
+		typedFlags[key] = typedValue
+	}
+	if len(typedFlags) == 0 {
+		return nil
+	}
+	return typedFlags
+}
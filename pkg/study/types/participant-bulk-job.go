@@ -0,0 +1,83 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	PARTICIPANT_BULK_ACTION_SET_FLAG      = "setFlag"
+	PARTICIPANT_BULK_ACTION_CHANGE_STATUS = "changeStatus"
+	PARTICIPANT_BULK_ACTION_SEND_MESSAGE  = "sendMessage"
+	PARTICIPANT_BULK_ACTION_TRIGGER_RULE  = "triggerRule"
+)
+
+const (
+	PARTICIPANT_BULK_JOB_STATUS_QUEUED    = "queued"
+	PARTICIPANT_BULK_JOB_STATUS_RUNNING   = "running"
+	PARTICIPANT_BULK_JOB_STATUS_COMPLETED = "completed"
+	PARTICIPANT_BULK_JOB_STATUS_FAILED    = "failed"
+	PARTICIPANT_BULK_JOB_STATUS_CANCELLED = "cancelled"
+)
+
+const (
+	PARTICIPANT_BULK_RESULT_UPDATED   = "updated"
+	PARTICIPANT_BULK_RESULT_UNCHANGED = "unchanged"
+	PARTICIPANT_BULK_RESULT_FAILED    = "failed"
+)
+
+// ParticipantBulkAction is applied to each participant of a bulk job, the fields used depend on the type
+type ParticipantBulkAction struct {
+	Type string `bson:"type" json:"type"`
+
+	// setFlag
+	FlagKey   string `bson:"flagKey,omitempty" json:"flagKey,omitempty"`
+	FlagValue string `bson:"flagValue,omitempty" json:"flagValue,omitempty"`
+	// changeStatus
+	Status string `bson:"status,omitempty" json:"status,omitempty"`
+	// sendMessage: participant message of the type, sent by the message scheduler from ScheduledFor (unix seconds) on,
+	// immediately if 0
+	MessageType  string `bson:"messageType,omitempty" json:"messageType,omitempty"`
+	ScheduledFor int64  `bson:"scheduledFor,omitempty" json:"scheduledFor,omitempty"`
+	// triggerRule: rules evaluated like a custom study event
+	Rules []Expression `bson:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// ParticipantBulkJob applies an action to a selection of participants in the background of the management API
+type ParticipantBulkJob struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey  string             `bson:"studyKey" json:"studyKey"`
+	CreatedBy string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+
+	Action ParticipantBulkAction `bson:"action" json:"action"`
+	// JSON encoded participant search of the selected participants
+	Selection string `bson:"selection" json:"selection"`
+
+	Status         string `bson:"status" json:"status"`
+	TargetCount    int    `bson:"targetCount" json:"targetCount"`
+	ProcessedCount int    `bson:"processedCount" json:"processedCount"`
+	UpdatedCount   int    `bson:"updatedCount" json:"updatedCount"`
+	FailedCount    int    `bson:"failedCount" json:"failedCount"`
+	// the job stops before the next participant once cancellation was requested
+	CancelRequested bool `bson:"cancelRequested,omitempty" json:"cancelRequested,omitempty"`
+	// extended while the job runs, a running job with an expired lease was interrupted by a restart
+	LeaseUntil  time.Time `bson:"leaseUntil,omitempty" json:"-"`
+	Error       string    `bson:"error,omitempty" json:"error,omitempty"`
+	CompletedAt time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	// the job and its results are deleted after this time
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+// ParticipantBulkJobResult is the outcome of the action for one participant
+type ParticipantBulkJobResult struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	JobID         primitive.ObjectID `bson:"jobID" json:"jobID"`
+	ParticipantID string             `bson:"participantID" json:"participantId"`
+	Result        string             `bson:"result" json:"result"`
+	Error         string             `bson:"error,omitempty" json:"error,omitempty"`
+	ProcessedAt   time.Time          `bson:"processedAt" json:"processedAt"`
+	ExpiresAt     time.Time          `bson:"expiresAt" json:"-"`
+}
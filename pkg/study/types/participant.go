@@ -7,19 +7,27 @@ const (
 	PARTICIPANT_STUDY_STATUS_TEMPORARY       = "temporary" // for participants without a registered account
 	PARTICIPANT_STUDY_STATUS_EXITED          = "exited"
 	PARTICIPANT_STUDY_STATUS_ACCOUNT_DELETED = "accountDeleted"
+	PARTICIPANT_STUDY_STATUS_WAITING_LIST    = "waitingList" // joined while the study was full, see EnrollmentCap
 )
 
 // Participant defines the datamodel for current state of the participant in a study as stored in the database
 type Participant struct {
-	ID                  primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
-	ParticipantID       string               `bson:"participantID" json:"participantId"` // reference to the study specific participant ID
-	CurrentStudySession string               `bson:"currentStudySession" json:"currentStudySession"`
-	EnteredAt           int64                `bson:"enteredAt" json:"enteredAt"`
-	StudyStatus         string               `bson:"studyStatus" json:"studyStatus"` // shows if participant is active in the study - possible values: "active", "temporary", "exited". Other values are possible and are handled like "exited" on the server.
-	Flags               map[string]string    `bson:"flags" json:"flags"`
-	AssignedSurveys     []AssignedSurvey     `bson:"assignedSurveys" json:"assignedSurveys"`
-	LastSubmissions     map[string]int64     `bson:"lastSubmission" json:"lastSubmissions"` // surveyKey with timestamp
-	Messages            []ParticipantMessage `bson:"messages" json:"messages"`
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ParticipantID       string             `bson:"participantID" json:"participantId"` // reference to the study specific participant ID
+	CurrentStudySession string             `bson:"currentStudySession" json:"currentStudySession"`
+	EnteredAt           int64              `bson:"enteredAt" json:"enteredAt"`
+	StudyStatus         string             `bson:"studyStatus" json:"studyStatus"` // shows if participant is active in the study - possible values: "active", "temporary", "exited". Other values are possible and are handled like "exited" on the server.
+	Flags               map[string]string  `bson:"flags" json:"flags"`
+	// Typed copies of the flags declared in the study's participantFlagTypes, maintained by the database layer
+	TypedFlags      map[string]interface{} `bson:"typedFlags,omitempty" json:"typedFlags,omitempty"`
+	AssignedSurveys []AssignedSurvey       `bson:"assignedSurveys" json:"assignedSurveys"`
+	LastSubmissions map[string]int64       `bson:"lastSubmission" json:"lastSubmissions"` // surveyKey with timestamp
+	// Latest of the last submissions, maintained by the database layer for searching and sorting participants
+	LastSubmittedAt int64                `bson:"lastSubmittedAt" json:"lastSubmittedAt,omitempty"`
+	Messages        []ParticipantMessage `bson:"messages" json:"messages"`
+
+	// Synthetic participants are created by monitoring probes or preview drafts and are excluded from statistics and exports
+	Synthetic bool `bson:"synthetic,omitempty" json:"synthetic,omitempty"`
 }
 
 type ParticipantMessage struct {
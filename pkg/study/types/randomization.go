@@ -0,0 +1,31 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RandomizationBlock is a shuffled sequence of arms, which is used up slot by slot when assigning participants
+type RandomizationBlock struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey    string             `bson:"studyKey" json:"studyKey"`
+	SchemeKey   string             `bson:"schemeKey" json:"schemeKey"`
+	Stratum     string             `bson:"stratum" json:"stratum"`
+	BlockNumber int                `bson:"blockNumber" json:"blockNumber"`
+	Arms        []string           `bson:"arms" json:"arms"`
+	Position    int                `bson:"position" json:"position"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// RandomizationAllocation records the arm assigned to a participant within a randomization scheme
+type RandomizationAllocation struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey      string             `bson:"studyKey" json:"studyKey"`
+	SchemeKey     string             `bson:"schemeKey" json:"schemeKey"`
+	Stratum       string             `bson:"stratum" json:"stratum"`
+	ParticipantID string             `bson:"participantID" json:"participantID"`
+	Arm           string             `bson:"arm" json:"arm"`
+	BlockNumber   int                `bson:"blockNumber" json:"blockNumber"`
+	AllocatedAt   time.Time          `bson:"allocatedAt" json:"allocatedAt"`
+}
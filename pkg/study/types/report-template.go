@@ -0,0 +1,26 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportTemplate makes the reports with the given key available to participants and defines how they are rendered.
+// Reports without a template are not shown to participants.
+type ReportTemplate struct {
+	ID              primitive.ObjectID          `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey        string                      `bson:"studyKey" json:"studyKey"`
+	ReportKey       string                      `bson:"reportKey" json:"reportKey"`
+	DefaultLanguage string                      `bson:"defaultLanguage" json:"defaultLanguage"`
+	Translations    []ReportTemplateTranslation `bson:"translations" json:"translations"`
+	UpdatedAt       time.Time                   `bson:"updatedAt" json:"updatedAt"`
+	UpdatedBy       string                      `bson:"updatedBy" json:"updatedBy"`
+}
+
+// ReportTemplateTranslation contains the title and the HTML body template (Go html/template syntax) for one language
+type ReportTemplateTranslation struct {
+	Lang     string `bson:"lang" json:"lang"`
+	Title    string `bson:"title" json:"title"`
+	Template string `bson:"template" json:"template"`
+}
@@ -0,0 +1,18 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RuleError records a failed evaluation of the study rules for a participant event
+type RuleError struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Time          time.Time          `bson:"time" json:"time"`
+	StudyKey      string             `bson:"studyKey" json:"studyKey"`
+	ParticipantID string             `bson:"participantID" json:"participantID"`
+	EventType     string             `bson:"eventType" json:"eventType"`
+	EventKey      string             `bson:"eventKey,omitempty" json:"eventKey,omitempty"`
+	Error         string             `bson:"error" json:"error"`
+}
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SCHEDULED_EVENT_STATUS_PENDING    = "pending"
+	SCHEDULED_EVENT_STATUS_PROCESSING = "processing"
+	SCHEDULED_EVENT_STATUS_FIRED      = "fired"
+	SCHEDULED_EVENT_STATUS_FAILED     = "failed"
+)
+
+// ScheduledEvent is a timer event registered by a study rule, to be fired for the participant at a later time
+type ScheduledEvent struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey      string             `bson:"studyKey" json:"studyKey"`
+	ParticipantID string             `bson:"participantID" json:"participantID"`
+	EventKey      string             `bson:"eventKey" json:"eventKey"`
+	FireAt        time.Time          `bson:"fireAt" json:"fireAt"`
+	Status        string             `bson:"status" json:"status"`
+	// set while a job runner processes the event
+	ClaimID     string    `bson:"claimID,omitempty" json:"-"`
+	LeaseUntil  time.Time `bson:"leaseUntil,omitempty" json:"-"`
+	LastError   string    `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+	ProcessedAt time.Time `bson:"processedAt,omitempty" json:"processedAt,omitempty"`
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EXPORT_DELIVERY_STATUS_PENDING   = "pending"
+	EXPORT_DELIVERY_STATUS_DELIVERED = "delivered"
+	EXPORT_DELIVERY_STATUS_FAILED    = "failed"
+)
+
+// ScheduledExport is a recurring export configured by an admin, whose artifacts are pushed to a destination of the service config
+type ScheduledExport struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey string             `bson:"studyKey" json:"studyKey"`
+	Label    string             `bson:"label" json:"label"`
+
+	// five field cron expression, evaluated in Timezone (IANA name, defaults to UTC)
+	Cron     string `bson:"cron" json:"cron"`
+	Timezone string `bson:"timezone,omitempty" json:"timezone,omitempty"`
+
+	Request ExportJobRequest `bson:"request" json:"request"`
+	// each run only exports the responses that arrived since the previous successful run
+	Incremental bool `bson:"incremental" json:"incremental"`
+	// name of the delivery destination in the service config
+	Destination string `bson:"destination" json:"destination"`
+	Active      bool   `bson:"active" json:"active"`
+
+	NextRunAt time.Time `bson:"nextRunAt" json:"nextRunAt"`
+	LastRunAt time.Time `bson:"lastRunAt,omitempty" json:"lastRunAt,omitempty"`
+	LastJobID string    `bson:"lastJobID,omitempty" json:"lastJobID,omitempty"`
+
+	// status of the latest finished delivery, one of EXPORT_DELIVERY_STATUS_*
+	LastDeliveryStatus  string    `bson:"lastDeliveryStatus,omitempty" json:"lastDeliveryStatus,omitempty"`
+	LastDeliveryError   string    `bson:"lastDeliveryError,omitempty" json:"lastDeliveryError,omitempty"`
+	LastDeliveredAt     time.Time `bson:"lastDeliveredAt,omitempty" json:"lastDeliveredAt,omitempty"`
+	ConsecutiveFailures int       `bson:"consecutiveFailures" json:"consecutiveFailures"`
+
+	CreatedBy string    `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ExportDelivery tracks pushing the artifact of a job to its destination
+type ExportDelivery struct {
+	Destination string `bson:"destination" json:"destination"`
+	Status      string `bson:"status" json:"status"`
+	Attempts    int    `bson:"attempts" json:"attempts"`
+	// pending deliveries are attempted at this time, it also acts as lease while an attempt runs
+	NextAttemptAt time.Time `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+	// where the file was stored, e.g. s3://bucket/key
+	Location    string    `bson:"location,omitempty" json:"location,omitempty"`
+	LastError   string    `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	DeliveredAt time.Time `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+}
@@ -20,6 +20,12 @@ type Study struct {
 	Configs                   StudyConfigs               `bson:"configs" json:"configs"`
 	NotificationSubscriptions []NotificationSubscription `bson:"notificationSubscriptions" json:"notificationSubscriptions"`
 
+	// Totals of data removed by the data retention policy, so aggregates are kept after pruning
+	DataRetentionStats *DataRetentionStats `bson:"dataRetentionStats,omitempty" json:"dataRetentionStats,omitempty"`
+
+	// Unix timestamp of the move to the trash, not set for active studies
+	DeletedAt int64 `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+
 	// depracted fields potentially to be removed in the future
 	Stats          StudyStats   `bson:"studyStats" json:"stats"`
 	NextTimerEvent int64        `bson:"nextTimerEvent" json:"nextTimerEvent"`
@@ -36,8 +42,92 @@ type StudyProps struct {
 }
 
 type StudyConfigs struct {
-	ParticipantFileUploadRule *Expression `bson:"participantFileUploadRule" json:"participantFileUploadRule"`
-	IdMappingMethod           string      `bson:"idMappingMethod" json:"idMappingMethod"`
+	ParticipantFileUploadRule *Expression               `bson:"participantFileUploadRule" json:"participantFileUploadRule"`
+	IdMappingMethod           string                    `bson:"idMappingMethod" json:"idMappingMethod"`
+	ResponseCorrections       *ResponseCorrectionConfig `bson:"responseCorrections,omitempty" json:"responseCorrections,omitempty"`
+	EnrollmentWindow          *EnrollmentWindow         `bson:"enrollmentWindow,omitempty" json:"enrollmentWindow,omitempty"`
+	// Maximum number of active participants, new joiners are put on a waiting list while the study is full
+	EnrollmentCap *EnrollmentCap `bson:"enrollmentCap,omitempty" json:"enrollmentCap,omitempty"`
+	// Declared value types of participant flags (flag key -> type), used to store typed copies of the flags for querying
+	ParticipantFlagTypes map[string]string    `bson:"participantFlagTypes,omitempty" json:"participantFlagTypes,omitempty"`
+	DataRetention        *DataRetentionPolicy `bson:"dataRetention,omitempty" json:"dataRetention,omitempty"`
+	// Checks of submitted responses against the survey definition, not checked if not set
+	ResponseValidation *ResponseValidationConfig `bson:"responseValidation,omitempty" json:"responseValidation,omitempty"`
+	// Reminders about assigned surveys sent by the messaging job, no reminders if not set
+	SurveyReminders *SurveyReminderConfig `bson:"surveyReminders,omitempty" json:"surveyReminders,omitempty"`
+	// Scoring of submissions for spam and bots, suspicious responses are quarantined. Not scored if not set.
+	ResponseQuarantine *ResponseQuarantineConfig `bson:"responseQuarantine,omitempty" json:"responseQuarantine,omitempty"`
+	// Surveys and study rules can only be published via approved drafts, direct uploads are rejected
+	RequireReview bool `bson:"requireReview,omitempty" json:"requireReview,omitempty"`
+}
+
+// EnrollmentWindow restricts when new participants can enter the study. Unset fields do not restrict enrollment.
+type EnrollmentWindow struct {
+	StartDate int64 `bson:"startDate,omitempty" json:"startDate,omitempty"`
+	EndDate   int64 `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	// Timezone for open hours and the daily limit, e.g. "Europe/Berlin". Defaults to UTC.
+	Timezone  string            `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	OpenHours []WeeklyOpenHours `bson:"openHours,omitempty" json:"openHours,omitempty"`
+	// Maximum number of enrollments per calendar day, 0 means no limit
+	MaxEnrollmentsPerDay int64 `bson:"maxEnrollmentsPerDay,omitempty" json:"maxEnrollmentsPerDay,omitempty"`
+	// Shown to participants while enrollment is closed
+	ClosedMessage []LocalisedObject `bson:"closedMessage,omitempty" json:"closedMessage,omitempty"`
+}
+
+const (
+	WAITING_LIST_PROMOTION_ORDER_FIFO   = "fifo"
+	WAITING_LIST_PROMOTION_ORDER_RANDOM = "random"
+)
+
+// EnrollmentCap limits the number of active participants. Participants on the waiting list are promoted by the study
+// timer when slots free up.
+type EnrollmentCap struct {
+	MaxActiveParticipants int64 `bson:"maxActiveParticipants" json:"maxActiveParticipants"`
+	// "fifo" (default) or "random"
+	PromotionOrder string `bson:"promotionOrder,omitempty" json:"promotionOrder,omitempty"`
+	// Message type of the study email template sent to promoted participants, no email if empty
+	PromotionMessageType string `bson:"promotionMessageType,omitempty" json:"promotionMessageType,omitempty"`
+}
+
+// WeeklyOpenHours is a time range on a weekday (0 = Sunday) with times as "HH:MM"
+type WeeklyOpenHours struct {
+	Weekday int    `bson:"weekday" json:"weekday"`
+	Start   string `bson:"start" json:"start"`
+	End     string `bson:"end" json:"end"`
+}
+
+type ResponseCorrectionConfig struct {
+	Allowed bool `bson:"allowed" json:"allowed"`
+	// Period (in seconds) after submission, during which a correction can be submitted. 0 means no limit.
+	Window int64 `bson:"window" json:"window"`
+}
+
+const (
+	// responses are stored without validation
+	RESPONSE_VALIDATION_MODE_ACCEPT = "accept"
+	// responses are stored with the validation issues found
+	RESPONSE_VALIDATION_MODE_WARN = "warn"
+	// responses with validation issues are refused
+	RESPONSE_VALIDATION_MODE_REJECT = "reject"
+)
+
+type ResponseValidationConfig struct {
+	Mode string `bson:"mode" json:"mode"`
+}
+
+// ResponseQuarantineConfig sets how submissions are scored, each signal adds its weight to the score
+type ResponseQuarantineConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Responses with at least this score are quarantined, defaults to 1
+	Threshold float64 `bson:"threshold,omitempty" json:"threshold,omitempty"`
+	// Expected completion time in seconds per survey key, faster submissions than MinCompletionRatio of it are suspicious
+	ExpectedCompletionSeconds map[string]int64 `bson:"expectedCompletionSeconds,omitempty" json:"expectedCompletionSeconds,omitempty"`
+	// Defaults to 0.25
+	MinCompletionRatio float64 `bson:"minCompletionRatio,omitempty" json:"minCompletionRatio,omitempty"`
+	// Items hidden from participants, any answer to them is suspicious
+	HoneypotItemKeys []string `bson:"honeypotItemKeys,omitempty" json:"honeypotItemKeys,omitempty"`
+	// Responses with at least this many items are compared to the responses of other participants, 0 disables the check
+	DuplicateMinItems int `bson:"duplicateMinItems,omitempty" json:"duplicateMinItems,omitempty"`
 }
 
 type StudyStats struct {
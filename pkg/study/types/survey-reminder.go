@@ -0,0 +1,67 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// reminds after the survey became available
+	SURVEY_REMINDER_TRIGGER_DUE = "due"
+	// reminds before the survey expires, only for surveys with validUntil
+	SURVEY_REMINDER_TRIGGER_EXPIRING = "expiring"
+)
+
+const (
+	SURVEY_REMINDER_CHANNEL_EMAIL = "email"
+	// sent to the confirmed phone number of the participant, if there is one
+	SURVEY_REMINDER_CHANNEL_SMS = "sms"
+)
+
+// SurveyReminderConfig defines when participants are reminded about their assigned surveys
+type SurveyReminderConfig struct {
+	Rules []SurveyReminderRule `bson:"rules" json:"rules"`
+	// Timezone for the quiet hours and the daily limit, e.g. "Europe/Berlin". Defaults to UTC.
+	Timezone string `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	// No reminders are sent in this time range, reminders due meanwhile are sent afterwards
+	QuietHours *QuietHours `bson:"quietHours,omitempty" json:"quietHours,omitempty"`
+	// Minimum time (in seconds) between two reminders to a participant, 0 means no limit
+	MinInterval int64 `bson:"minInterval,omitempty" json:"minInterval,omitempty"`
+	// Maximum number of reminders to a participant per calendar day, 0 means no limit
+	MaxPerDay int `bson:"maxPerDay,omitempty" json:"maxPerDay,omitempty"`
+}
+
+// QuietHours is a daily time range with times as "HH:MM", it spans midnight if end is before start
+type QuietHours struct {
+	Start string `bson:"start" json:"start"`
+	End   string `bson:"end" json:"end"`
+}
+
+// SurveyReminderRule sends one reminder per assigned survey, once the trigger time is reached
+type SurveyReminderRule struct {
+	// Identifies the rule in the sent reminders, must be unique in the study
+	Key string `bson:"key" json:"key"`
+	// Surveys the rule applies to, all assigned surveys if empty
+	SurveyKeys []string `bson:"surveyKeys,omitempty" json:"surveyKeys,omitempty"`
+	Trigger    string   `bson:"trigger" json:"trigger"`
+	// Seconds after the survey became available (due) or before it expires (expiring)
+	Offset int64 `bson:"offset" json:"offset"`
+	// Study email template (or SMS template) of the reminder
+	MessageType string `bson:"messageType" json:"messageType"`
+	// Defaults to email
+	Channel string `bson:"channel,omitempty" json:"channel,omitempty"`
+}
+
+// SentSurveyReminder records a reminder, so that it is sent only once and counted for the throttles
+type SentSurveyReminder struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	StudyKey      string             `bson:"studyKey" json:"studyKey"`
+	ParticipantID string             `bson:"participantID" json:"participantID"`
+	RuleKey       string             `bson:"ruleKey" json:"ruleKey"`
+	SurveyKey     string             `bson:"surveyKey" json:"surveyKey"`
+	// validFrom of the assigned survey, a survey assigned again is reminded about again
+	SurveyValidFrom int64     `bson:"surveyValidFrom" json:"surveyValidFrom"`
+	Channel         string    `bson:"channel" json:"channel"`
+	SentAt          time.Time `bson:"sentAt" json:"sentAt"`
+}
@@ -21,6 +21,7 @@ type Survey struct {
 
 	Published        int64             `bson:"published,omitempty" json:"published,omitempty"`
 	Unpublished      int64             `bson:"unpublished,omitempty" json:"unpublished,omitempty"`
+	DeletedAt        int64             `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 	SurveyDefinition SurveyItem        `bson:"surveyDefinition,omitempty" json:"surveyDefinition,omitempty"`
 	VersionID        string            `bson:"versionID,omitempty" json:"versionId,omitempty"`
 	Metadata         map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
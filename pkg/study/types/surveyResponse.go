@@ -12,6 +12,45 @@ type SurveyResponse struct {
 	ArrivedAt     int64                `bson:"arrivedAt" json:"arrivedAt"`
 	Responses     []SurveyItemResponse `bson:"responses" json:"responses"`
 	Context       map[string]string    `bson:"context" json:"context"`
+
+	// Correction versioning: a correction supersedes an earlier response of the same participant
+	Supersedes   string `bson:"supersedes,omitempty" json:"supersedes,omitempty"`
+	SupersededBy string `bson:"supersededBy,omitempty" json:"supersededBy,omitempty"`
+	Revision     int    `bson:"revision,omitempty" json:"revision,omitempty"`
+
+	// Submitted by a synthetic-monitoring or draft preview participant
+	Synthetic bool `bson:"synthetic,omitempty" json:"synthetic,omitempty"`
+
+	// Problems found when checking the response against the survey definition at submission
+	ValidationIssues []ResponseValidationIssue `bson:"validationIssues,omitempty" json:"validationIssues,omitempty"`
+
+	// Set for suspicious submissions, quarantined responses are excluded from exports until they are accepted
+	Quarantine *ResponseQuarantine `bson:"quarantine,omitempty" json:"quarantine,omitempty"`
+	// Hash of the item responses, only stored if duplicate content is checked
+	ContentHash string `bson:"contentHash,omitempty" json:"-"`
+
+	// Encrypted responses and context of confidential responses stored with field-level encryption
+	EncryptedData []byte `bson:"encryptedData,omitempty" json:"-"`
+	DataKeyID     string `bson:"dataKeyID,omitempty" json:"-"`
+	// Format of the encrypted data, responses encrypted before the data was bound to the response have no version
+	EncryptionVersion int `bson:"encryptionVersion,omitempty" json:"-"`
+}
+
+const (
+	QUARANTINE_SIGNAL_TOO_FAST          = "tooFast"
+	QUARANTINE_SIGNAL_HONEYPOT          = "honeypot"
+	QUARANTINE_SIGNAL_DUPLICATE_CONTENT = "duplicateContent"
+)
+
+type ResponseQuarantine struct {
+	Score   float64  `bson:"score" json:"score"`
+	Signals []string `bson:"signals" json:"signals"`
+}
+
+type ResponseValidationIssue struct {
+	ItemKey string `bson:"itemKey,omitempty" json:"itemKey,omitempty"`
+	Code    string `bson:"code" json:"code"`
+	Message string `bson:"message" json:"message"`
 }
 
 type SurveyItemResponse struct {
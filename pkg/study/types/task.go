@@ -10,19 +10,24 @@ const (
 	TASK_STATUS_IN_PROGRESS = "in_progress"
 	TASK_STATUS_COMPLETED   = "completed"
 
-	TASK_FILE_TYPE_JSON = "application/json"
-	TASK_FILE_TYPE_CSV  = "text/csv"
+	TASK_FILE_TYPE_JSON    = "application/json"
+	TASK_FILE_TYPE_CSV     = "text/csv"
+	TASK_FILE_TYPE_NDJSON  = "application/x-ndjson"
+	TASK_FILE_TYPE_PARQUET = "application/vnd.apache.parquet"
+	TASK_FILE_TYPE_XLSX    = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 )
 
 type Task struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
-	CreatedBy      string             `bson:"createdBy" json:"createdBy"`
-	UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
-	Status         string             `bson:"status" json:"status"`
-	TargetCount    int                `bson:"targetCount" json:"targetCount"`
-	ProcessedCount int                `bson:"processedCount" json:"processedCount"`
-	ResultFile     string             `bson:"resultFile" json:"resultFile"`
-	FileType       string             `bson:"fileType" json:"fileType"`
-	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	CreatedBy string             `bson:"createdBy" json:"createdBy"`
+	// set for tasks whose result may only be accessed within the study
+	StudyKey       string    `bson:"studyKey,omitempty" json:"studyKey,omitempty"`
+	UpdatedAt      time.Time `bson:"updatedAt" json:"updatedAt"`
+	Status         string    `bson:"status" json:"status"`
+	TargetCount    int       `bson:"targetCount" json:"targetCount"`
+	ProcessedCount int       `bson:"processedCount" json:"processedCount"`
+	ResultFile     string    `bson:"resultFile" json:"resultFile"`
+	FileType       string    `bson:"fileType" json:"fileType"`
+	Error          string    `bson:"error,omitempty" json:"error,omitempty"`
 }
@@ -0,0 +1,37 @@
+package studyutils
+
+import (
+	"errors"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func ValidateDataRetentionPolicy(p *studyTypes.DataRetentionPolicy) error {
+	if p == nil {
+		return nil
+	}
+	if p.ResponsesMonths < 0 || p.ConfidentialResponsesMonths < 0 || p.ReportsMonths < 0 || p.FilesMonths < 0 {
+		return errors.New("retention periods must not be negative")
+	}
+	return nil
+}
+
+// ComputeDataRetentionCutoffs returns the timestamps before which data has to be deleted at the given time
+func ComputeDataRetentionCutoffs(p *studyTypes.DataRetentionPolicy, now time.Time) studyTypes.DataRetentionCutoffs {
+	cutoffs := studyTypes.DataRetentionCutoffs{}
+	if p == nil {
+		return cutoffs
+	}
+	cutoff := func(months int) int64 {
+		if months <= 0 {
+			return 0
+		}
+		return now.AddDate(0, -months, 0).Unix()
+	}
+	cutoffs.Responses = cutoff(p.ResponsesMonths)
+	cutoffs.ConfidentialResponses = cutoff(p.ConfidentialResponsesMonths)
+	cutoffs.Reports = cutoff(p.ReportsMonths)
+	cutoffs.Files = cutoff(p.FilesMonths)
+	return cutoffs
+}
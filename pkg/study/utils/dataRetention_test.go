@@ -0,0 +1,43 @@
+package studyutils
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestValidateDataRetentionPolicy(t *testing.T) {
+	if err := ValidateDataRetentionPolicy(nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateDataRetentionPolicy(&studyTypes.DataRetentionPolicy{ResponsesMonths: 12}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateDataRetentionPolicy(&studyTypes.DataRetentionPolicy{FilesMonths: -1}); err == nil {
+		t.Error("expected error for negative period")
+	}
+}
+
+func TestComputeDataRetentionCutoffs(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	cutoffs := ComputeDataRetentionCutoffs(&studyTypes.DataRetentionPolicy{
+		ConfidentialResponsesMonths: 6,
+		ReportsMonths:               24,
+	}, now)
+
+	if cutoffs.Responses != 0 || cutoffs.Files != 0 {
+		t.Errorf("unset periods should not delete: %+v", cutoffs)
+	}
+	if cutoffs.ConfidentialResponses != time.Date(2023, 12, 15, 12, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("unexpected confidential responses cutoff: %v", time.Unix(cutoffs.ConfidentialResponses, 0).UTC())
+	}
+	if cutoffs.Reports != time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("unexpected reports cutoff: %v", time.Unix(cutoffs.Reports, 0).UTC())
+	}
+
+	if c := ComputeDataRetentionCutoffs(nil, now); c != (studyTypes.DataRetentionCutoffs{}) {
+		t.Errorf("unexpected cutoffs without policy: %+v", c)
+	}
+}
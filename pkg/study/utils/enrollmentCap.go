@@ -0,0 +1,32 @@
+package studyutils
+
+import (
+	"errors"
+	"fmt"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// ValidateEnrollmentCap checks the limit and the promotion order of the cap
+func ValidateEnrollmentCap(c *studyTypes.EnrollmentCap) error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxActiveParticipants <= 0 {
+		return errors.New("max active participants must be positive")
+	}
+	switch c.PromotionOrder {
+	case "", studyTypes.WAITING_LIST_PROMOTION_ORDER_FIFO, studyTypes.WAITING_LIST_PROMOTION_ORDER_RANDOM:
+	default:
+		return fmt.Errorf("unsupported promotion order: %s", c.PromotionOrder)
+	}
+	return nil
+}
+
+// FreeEnrollmentSlots is the number of participants that can become active, 0 if the study is full
+func FreeEnrollmentSlots(c *studyTypes.EnrollmentCap, activeCount int64) int64 {
+	if c == nil || activeCount >= c.MaxActiveParticipants {
+		return 0
+	}
+	return c.MaxActiveParticipants - activeCount
+}
@@ -0,0 +1,46 @@
+package studyutils
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestValidateEnrollmentCap(t *testing.T) {
+	if err := ValidateEnrollmentCap(nil); err != nil {
+		t.Errorf("unexpected error for nil cap: %v", err)
+	}
+	valid := []*studyTypes.EnrollmentCap{
+		{MaxActiveParticipants: 10},
+		{MaxActiveParticipants: 10, PromotionOrder: studyTypes.WAITING_LIST_PROMOTION_ORDER_RANDOM},
+	}
+	for i, c := range valid {
+		if err := ValidateEnrollmentCap(c); err != nil {
+			t.Errorf("unexpected error for cap %d: %v", i, err)
+		}
+	}
+
+	invalid := []*studyTypes.EnrollmentCap{
+		{},
+		{MaxActiveParticipants: -1},
+		{MaxActiveParticipants: 10, PromotionOrder: "lifo"},
+	}
+	for i, c := range invalid {
+		if err := ValidateEnrollmentCap(c); err == nil {
+			t.Errorf("expected error for cap %d", i)
+		}
+	}
+}
+
+func TestFreeEnrollmentSlots(t *testing.T) {
+	c := &studyTypes.EnrollmentCap{MaxActiveParticipants: 10}
+	if n := FreeEnrollmentSlots(c, 7); n != 3 {
+		t.Errorf("expected 3 free slots, got %d", n)
+	}
+	if n := FreeEnrollmentSlots(c, 12); n != 0 {
+		t.Errorf("expected no free slots above the cap, got %d", n)
+	}
+	if n := FreeEnrollmentSlots(nil, 0); n != 0 {
+		t.Errorf("expected no free slots without cap, got %d", n)
+	}
+}
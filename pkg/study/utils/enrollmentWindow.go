@@ -0,0 +1,174 @@
+package studyutils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// Reasons why enrollment is closed
+const (
+	ENROLLMENT_CLOSED_NOT_STARTED        = "notStarted"
+	ENROLLMENT_CLOSED_ENDED              = "ended"
+	ENROLLMENT_CLOSED_OUTSIDE_OPEN_HOURS = "outsideOpenHours"
+	ENROLLMENT_CLOSED_DAILY_LIMIT        = "dailyLimitReached"
+)
+
+func enrollmentLocation(w *studyTypes.EnrollmentWindow) (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// parseTimeOfDay parses "HH:MM" into minutes since midnight, "24:00" is allowed as end of day
+func parseTimeOfDay(v string) (int, error) {
+	if v == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %s", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateEnrollmentWindow checks dates, timezone and open hours of the window
+func ValidateEnrollmentWindow(w *studyTypes.EnrollmentWindow) error {
+	if w == nil {
+		return nil
+	}
+	if w.StartDate > 0 && w.EndDate > 0 && w.EndDate <= w.StartDate {
+		return errors.New("end date must be after start date")
+	}
+	if w.MaxEnrollmentsPerDay < 0 {
+		return errors.New("max enrollments per day must not be negative")
+	}
+	if _, err := enrollmentLocation(w); err != nil {
+		return err
+	}
+	for _, oh := range w.OpenHours {
+		if oh.Weekday < 0 || oh.Weekday > 6 {
+			return fmt.Errorf("invalid weekday: %d", oh.Weekday)
+		}
+		start, err := parseTimeOfDay(oh.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseTimeOfDay(oh.End)
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return fmt.Errorf("open hours end must be after start: %s-%s", oh.Start, oh.End)
+		}
+	}
+	return nil
+}
+
+// EnrollmentDay returns the calendar day of the time in the window's timezone, used to count daily enrollments
+func EnrollmentDay(w *studyTypes.EnrollmentWindow, now time.Time) string {
+	loc, err := enrollmentLocation(w)
+	if err != nil {
+		loc = time.UTC
+	}
+	return now.In(loc).Format(time.DateOnly)
+}
+
+// nextOpenHoursStart returns the earliest time at or after from that is within open hours
+func nextOpenHoursStart(w *studyTypes.EnrollmentWindow, loc *time.Location, from time.Time) (time.Time, bool) {
+	if len(w.OpenHours) == 0 {
+		return from, true
+	}
+	local := from.In(loc)
+	var best time.Time
+	found := false
+	for d := 0; d <= 7; d++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+d, 0, 0, 0, 0, loc)
+		for _, oh := range w.OpenHours {
+			if int(day.Weekday()) != oh.Weekday {
+				continue
+			}
+			start, err1 := parseTimeOfDay(oh.Start)
+			end, err2 := parseTimeOfDay(oh.End)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			slotStart := day.Add(time.Duration(start) * time.Minute)
+			slotEnd := day.Add(time.Duration(end) * time.Minute)
+			if !slotEnd.After(local) {
+				continue
+			}
+			candidate := slotStart
+			if candidate.Before(local) {
+				candidate = local
+			}
+			if !found || candidate.Before(best) {
+				best = candidate
+				found = true
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CheckEnrollmentWindow returns an empty reason if enrollment is open at the given time.
+// Otherwise the reason and, if known, the time when enrollment opens again are returned.
+// The daily limit is not checked here, as it depends on the number of enrollments.
+func CheckEnrollmentWindow(w *studyTypes.EnrollmentWindow, now time.Time) (reason string, nextOpensAt int64) {
+	if w == nil {
+		return "", 0
+	}
+	loc, err := enrollmentLocation(w)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	if w.EndDate > 0 && now.Unix() >= w.EndDate {
+		return ENROLLMENT_CLOSED_ENDED, 0
+	}
+
+	if w.StartDate > 0 && now.Unix() < w.StartDate {
+		return ENROLLMENT_CLOSED_NOT_STARTED, nextOpeningAfter(w, loc, time.Unix(w.StartDate, 0))
+	}
+
+	next, ok := nextOpenHoursStart(w, loc, now)
+	if !ok {
+		return ENROLLMENT_CLOSED_OUTSIDE_OPEN_HOURS, 0
+	}
+	if next.After(now) {
+		return ENROLLMENT_CLOSED_OUTSIDE_OPEN_HOURS, withinEndDate(w, next)
+	}
+	return "", 0
+}
+
+// NextOpeningAfterDailyLimit returns when enrollment opens again after the daily limit was reached
+func NextOpeningAfterDailyLimit(w *studyTypes.EnrollmentWindow, now time.Time) int64 {
+	loc, err := enrollmentLocation(w)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	nextDay := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	return nextOpeningAfter(w, loc, nextDay)
+}
+
+func nextOpeningAfter(w *studyTypes.EnrollmentWindow, loc *time.Location, from time.Time) int64 {
+	next, ok := nextOpenHoursStart(w, loc, from)
+	if !ok {
+		return 0
+	}
+	return withinEndDate(w, next)
+}
+
+func withinEndDate(w *studyTypes.EnrollmentWindow, t time.Time) int64 {
+	if w.EndDate > 0 && t.Unix() >= w.EndDate {
+		return 0
+	}
+	return t.Unix()
+}
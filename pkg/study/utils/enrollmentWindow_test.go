@@ -0,0 +1,112 @@
+package studyutils
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestValidateEnrollmentWindow(t *testing.T) {
+	valid := &studyTypes.EnrollmentWindow{
+		StartDate: 100,
+		EndDate:   200,
+		Timezone:  "UTC",
+		OpenHours: []studyTypes.WeeklyOpenHours{{Weekday: 1, Start: "08:00", End: "24:00"}},
+	}
+	if err := ValidateEnrollmentWindow(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateEnrollmentWindow(nil); err != nil {
+		t.Errorf("unexpected error for nil window: %v", err)
+	}
+
+	invalid := []*studyTypes.EnrollmentWindow{
+		{StartDate: 200, EndDate: 100},
+		{MaxEnrollmentsPerDay: -1},
+		{Timezone: "Not/AZone"},
+		{OpenHours: []studyTypes.WeeklyOpenHours{{Weekday: 7, Start: "08:00", End: "09:00"}}},
+		{OpenHours: []studyTypes.WeeklyOpenHours{{Weekday: 1, Start: "10:00", End: "09:00"}}},
+		{OpenHours: []studyTypes.WeeklyOpenHours{{Weekday: 1, Start: "8am", End: "09:00"}}},
+	}
+	for i, w := range invalid {
+		if err := ValidateEnrollmentWindow(w); err == nil {
+			t.Errorf("expected error for window %d", i)
+		}
+	}
+}
+
+func TestCheckEnrollmentWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	weekdays := []studyTypes.WeeklyOpenHours{
+		{Weekday: 1, Start: "09:00", End: "17:00"},
+		{Weekday: 3, Start: "09:00", End: "12:00"},
+		{Weekday: 5, Start: "09:00", End: "17:00"},
+	}
+
+	tests := []struct {
+		name        string
+		window      *studyTypes.EnrollmentWindow
+		now         time.Time
+		reason      string
+		nextOpensAt int64
+	}{
+		{"no window", nil, now, "", 0},
+		{"open", &studyTypes.EnrollmentWindow{StartDate: now.Add(-time.Hour).Unix(), EndDate: now.Add(time.Hour).Unix()}, now, "", 0},
+		{"not started", &studyTypes.EnrollmentWindow{StartDate: now.Add(time.Hour).Unix()}, now, ENROLLMENT_CLOSED_NOT_STARTED, now.Add(time.Hour).Unix()},
+		{"ended", &studyTypes.EnrollmentWindow{EndDate: now.Add(-time.Hour).Unix()}, now, ENROLLMENT_CLOSED_ENDED, 0},
+		{"within open hours", &studyTypes.EnrollmentWindow{OpenHours: weekdays}, now, "", 0},
+		{"after open hours", &studyTypes.EnrollmentWindow{OpenHours: weekdays}, now.Add(3 * time.Hour), ENROLLMENT_CLOSED_OUTSIDE_OPEN_HOURS, time.Date(2024, 5, 17, 9, 0, 0, 0, time.UTC).Unix()},
+		{"before open hours", &studyTypes.EnrollmentWindow{OpenHours: weekdays}, now.Add(-2 * time.Hour), ENROLLMENT_CLOSED_OUTSIDE_OPEN_HOURS, time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC).Unix()},
+		{"next opening after end", &studyTypes.EnrollmentWindow{OpenHours: weekdays, EndDate: time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC).Unix()}, now.Add(3 * time.Hour), ENROLLMENT_CLOSED_OUTSIDE_OPEN_HOURS, 0},
+		{"not started with open hours", &studyTypes.EnrollmentWindow{OpenHours: weekdays, StartDate: time.Date(2024, 5, 18, 0, 0, 0, 0, time.UTC).Unix()}, now, ENROLLMENT_CLOSED_NOT_STARTED, time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC).Unix()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, next := CheckEnrollmentWindow(tt.window, tt.now)
+			if reason != tt.reason {
+				t.Errorf("unexpected reason: %s, expected %s", reason, tt.reason)
+			}
+			if next != tt.nextOpensAt {
+				t.Errorf("unexpected next opening: %s, expected %s", time.Unix(next, 0).UTC(), time.Unix(tt.nextOpensAt, 0).UTC())
+			}
+		})
+	}
+}
+
+func TestEnrollmentWindowTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	w := &studyTypes.EnrollmentWindow{
+		Timezone:  "America/New_York",
+		OpenHours: []studyTypes.WeeklyOpenHours{{Weekday: 3, Start: "09:00", End: "17:00"}},
+	}
+	// 10:00 UTC is 06:00 in New York
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	reason, next := CheckEnrollmentWindow(w, now)
+	if reason != ENROLLMENT_CLOSED_OUTSIDE_OPEN_HOURS || next != time.Date(2024, 5, 15, 9, 0, 0, 0, loc).Unix() {
+		t.Errorf("unexpected result: %s %s", reason, time.Unix(next, 0).In(loc))
+	}
+
+	// 02:00 UTC on Thursday is still Wednesday in New York
+	if day := EnrollmentDay(w, time.Date(2024, 5, 16, 2, 0, 0, 0, time.UTC)); day != "2024-05-15" {
+		t.Errorf("unexpected enrollment day: %s", day)
+	}
+}
+
+func TestNextOpeningAfterDailyLimit(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	w := &studyTypes.EnrollmentWindow{MaxEnrollmentsPerDay: 10}
+	if next := NextOpeningAfterDailyLimit(w, now); next != time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("unexpected next opening: %s", time.Unix(next, 0).UTC())
+	}
+
+	w.OpenHours = []studyTypes.WeeklyOpenHours{{Weekday: 1, Start: "09:00", End: "17:00"}}
+	if next := NextOpeningAfterDailyLimit(w, now); next != time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("unexpected next opening: %s", time.Unix(next, 0).UTC())
+	}
+}
@@ -0,0 +1,135 @@
+package studyutils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	FLAG_QUERY_OP_EQ         = "eq"
+	FLAG_QUERY_OP_NE         = "ne"
+	FLAG_QUERY_OP_GT         = "gt"
+	FLAG_QUERY_OP_GTE        = "gte"
+	FLAG_QUERY_OP_LT         = "lt"
+	FLAG_QUERY_OP_LTE        = "lte"
+	FLAG_QUERY_OP_IN         = "in"
+	FLAG_QUERY_OP_EXISTS     = "exists"
+	FLAG_QUERY_OP_NOT_EXISTS = "notExists"
+)
+
+// FlagPredicate is a condition on a participant flag
+type FlagPredicate struct {
+	Flag  string      `json:"flag"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// ValidateFlagTypes checks the declared participant flag types
+func ValidateFlagTypes(flagTypes map[string]string) error {
+	for key, flagType := range flagTypes {
+		if err := validateFlagKey(key); err != nil {
+			return err
+		}
+		if !studyTypes.IsValidFlagType(flagType) {
+			return fmt.Errorf("invalid type for flag %s: %s", key, flagType)
+		}
+	}
+	return nil
+}
+
+func validateFlagKey(key string) error {
+	if key == "" || strings.ContainsAny(key, ".$") {
+		return fmt.Errorf("invalid flag key: %s", key)
+	}
+	return nil
+}
+
+// BuildFlagQueryFilter converts the predicates into a participant filter, all predicates must match.
+// Flags with a declared type are compared on their typed values, so ranges work for numbers and dates.
+// Other flags are compared as strings and only support equality checks.
+func BuildFlagQueryFilter(predicates []FlagPredicate, flagTypes map[string]string) (bson.M, error) {
+	if len(predicates) == 0 {
+		return nil, errors.New("at least one predicate is required")
+	}
+
+	conditions := bson.A{}
+	for _, p := range predicates {
+		if err := validateFlagKey(p.Flag); err != nil {
+			return nil, err
+		}
+
+		flagType, typed := flagTypes[p.Flag]
+		field := "flags." + p.Flag
+		if typed {
+			field = "typedFlags." + p.Flag
+		} else {
+			flagType = studyTypes.FLAG_TYPE_STRING
+		}
+
+		var condition interface{}
+		switch p.Op {
+		case FLAG_QUERY_OP_EXISTS:
+			condition = bson.M{"$exists": true}
+		case FLAG_QUERY_OP_NOT_EXISTS:
+			condition = bson.M{"$exists": false}
+		case FLAG_QUERY_OP_EQ, FLAG_QUERY_OP_NE:
+			v, err := predicateValue(flagType, p.Value)
+			if err != nil {
+				return nil, err
+			}
+			condition = bson.M{"$" + p.Op: v}
+		case FLAG_QUERY_OP_GT, FLAG_QUERY_OP_GTE, FLAG_QUERY_OP_LT, FLAG_QUERY_OP_LTE:
+			if flagType != studyTypes.FLAG_TYPE_NUMBER && flagType != studyTypes.FLAG_TYPE_DATE {
+				return nil, fmt.Errorf("range operator %s requires a number or date flag: %s", p.Op, p.Flag)
+			}
+			v, err := predicateValue(flagType, p.Value)
+			if err != nil {
+				return nil, err
+			}
+			condition = bson.M{"$" + p.Op: v}
+		case FLAG_QUERY_OP_IN:
+			list, ok := p.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("operator in requires a list of values: %s", p.Flag)
+			}
+			values := bson.A{}
+			for _, item := range list {
+				v, err := predicateValue(flagType, item)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, v)
+			}
+			condition = bson.M{"$in": values}
+		default:
+			return nil, fmt.Errorf("unknown operator: %s", p.Op)
+		}
+		conditions = append(conditions, bson.M{field: condition})
+	}
+
+	if len(conditions) == 1 {
+		return conditions[0].(bson.M), nil
+	}
+	return bson.M{"$and": conditions}, nil
+}
+
+// predicateValue converts the value from the request (JSON types) to the flag type
+func predicateValue(flagType string, value interface{}) (interface{}, error) {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		str = strconv.FormatBool(v)
+	default:
+		return nil, errors.New("predicate value must be a string, number or boolean")
+	}
+	return studyTypes.ParseTypedFlagValue(flagType, str)
+}
@@ -0,0 +1,116 @@
+package studyutils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var testFlagTypes = map[string]string{
+	"age":      studyTypes.FLAG_TYPE_NUMBER,
+	"consent":  studyTypes.FLAG_TYPE_BOOL,
+	"enrolled": studyTypes.FLAG_TYPE_DATE,
+	"group":    studyTypes.FLAG_TYPE_STRING,
+}
+
+func TestValidateFlagTypes(t *testing.T) {
+	if err := ValidateFlagTypes(testFlagTypes); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, invalid := range []map[string]string{
+		{"age": "integer"},
+		{"a.b": studyTypes.FLAG_TYPE_NUMBER},
+		{"$age": studyTypes.FLAG_TYPE_NUMBER},
+		{"": studyTypes.FLAG_TYPE_STRING},
+	} {
+		if err := ValidateFlagTypes(invalid); err == nil {
+			t.Errorf("expected error for %v", invalid)
+		}
+	}
+}
+
+func TestComputeTypedFlags(t *testing.T) {
+	typed := studyTypes.ComputeTypedFlags(map[string]string{
+		"age":      "42.000000",
+		"consent":  "true",
+		"enrolled": "2024-03-01",
+		"group":    "A",
+		"other":    "x",
+	}, testFlagTypes)
+
+	expected := map[string]interface{}{
+		"age":      42.0,
+		"consent":  true,
+		"enrolled": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"group":    "A",
+	}
+	if !reflect.DeepEqual(typed, expected) {
+		t.Errorf("unexpected typed flags: %v", typed)
+	}
+
+	typed = studyTypes.ComputeTypedFlags(map[string]string{"age": "unknown"}, testFlagTypes)
+	if typed != nil {
+		t.Errorf("invalid values should be left out: %v", typed)
+	}
+
+	typed = studyTypes.ComputeTypedFlags(map[string]string{"enrolled": "1709251200"}, testFlagTypes)
+	if !typed["enrolled"].(time.Time).Equal(time.Unix(1709251200, 0)) {
+		t.Errorf("unexpected date from timestamp: %v", typed)
+	}
+}
+
+func TestBuildFlagQueryFilter(t *testing.T) {
+	t.Run("single typed range predicate", func(t *testing.T) {
+		filter, err := BuildFlagQueryFilter([]FlagPredicate{
+			{Flag: "age", Op: FLAG_QUERY_OP_GTE, Value: 18.0},
+		}, testFlagTypes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := bson.M{"typedFlags.age": bson.M{"$gte": 18.0}}
+		if !reflect.DeepEqual(filter, expected) {
+			t.Errorf("unexpected filter: %v", filter)
+		}
+	})
+
+	t.Run("combined predicates", func(t *testing.T) {
+		filter, err := BuildFlagQueryFilter([]FlagPredicate{
+			{Flag: "enrolled", Op: FLAG_QUERY_OP_LT, Value: "2024-01-01"},
+			{Flag: "untyped", Op: FLAG_QUERY_OP_EQ, Value: "yes"},
+			{Flag: "group", Op: FLAG_QUERY_OP_IN, Value: []interface{}{"A", "B"}},
+			{Flag: "consent", Op: FLAG_QUERY_OP_EXISTS},
+		}, testFlagTypes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := bson.M{"$and": bson.A{
+			bson.M{"typedFlags.enrolled": bson.M{"$lt": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+			bson.M{"flags.untyped": bson.M{"$eq": "yes"}},
+			bson.M{"typedFlags.group": bson.M{"$in": bson.A{"A", "B"}}},
+			bson.M{"typedFlags.consent": bson.M{"$exists": true}},
+		}}
+		if !reflect.DeepEqual(filter, expected) {
+			t.Errorf("unexpected filter: %v", filter)
+		}
+	})
+
+	t.Run("invalid predicates", func(t *testing.T) {
+		for _, p := range []FlagPredicate{
+			{Flag: "untyped", Op: FLAG_QUERY_OP_GT, Value: "1"},
+			{Flag: "age", Op: FLAG_QUERY_OP_EQ, Value: "old"},
+			{Flag: "age", Op: "between", Value: 1.0},
+			{Flag: "a.b", Op: FLAG_QUERY_OP_EXISTS},
+			{Flag: "group", Op: FLAG_QUERY_OP_IN, Value: "A"},
+		} {
+			if _, err := BuildFlagQueryFilter([]FlagPredicate{p}, testFlagTypes); err == nil {
+				t.Errorf("expected error for %+v", p)
+			}
+		}
+		if _, err := BuildFlagQueryFilter(nil, testFlagTypes); err == nil {
+			t.Error("expected error for empty predicates")
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package studyutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	DEFAULT_QUARANTINE_THRESHOLD            = 1.0
+	DEFAULT_QUARANTINE_MIN_COMPLETION_RATIO = 0.25
+
+	// a honeypot answer alone is enough for the default threshold, the other signals need a second one
+	QUARANTINE_WEIGHT_HONEYPOT  = 1.0
+	QUARANTINE_WEIGHT_TOO_FAST  = 0.6
+	QUARANTINE_WEIGHT_DUPLICATE = 0.6
+)
+
+// ScoreSubmission scores the response with the signals of the config, duplicate is the result of the duplicate
+// content check. The result is nil if the response is not suspicious enough for the quarantine.
+func ScoreSubmission(config studyTypes.ResponseQuarantineConfig, response studyTypes.SurveyResponse, duplicate bool) *studyTypes.ResponseQuarantine {
+	result := studyTypes.ResponseQuarantine{Signals: []string{}}
+
+	if expected := config.ExpectedCompletionSeconds[response.Key]; expected > 0 && response.OpenedAt > 0 && response.SubmittedAt > 0 {
+		ratio := config.MinCompletionRatio
+		if ratio <= 0 {
+			ratio = DEFAULT_QUARANTINE_MIN_COMPLETION_RATIO
+		}
+		if float64(response.SubmittedAt-response.OpenedAt) < float64(expected)*ratio {
+			result.Score += QUARANTINE_WEIGHT_TOO_FAST
+			result.Signals = append(result.Signals, studyTypes.QUARANTINE_SIGNAL_TOO_FAST)
+		}
+	}
+
+	for _, item := range response.Responses {
+		if slices.Contains(config.HoneypotItemKeys, item.Key) && isAnswered(item) {
+			result.Score += QUARANTINE_WEIGHT_HONEYPOT
+			result.Signals = append(result.Signals, studyTypes.QUARANTINE_SIGNAL_HONEYPOT)
+			break
+		}
+	}
+
+	if duplicate {
+		result.Score += QUARANTINE_WEIGHT_DUPLICATE
+		result.Signals = append(result.Signals, studyTypes.QUARANTINE_SIGNAL_DUPLICATE_CONTENT)
+	}
+
+	threshold := config.Threshold
+	if threshold <= 0 {
+		threshold = DEFAULT_QUARANTINE_THRESHOLD
+	}
+	if result.Score < threshold {
+		return nil
+	}
+	return &result
+}
+
+// ResponseContentHash hashes the answers of the response without their timestamps and positions, so that equal
+// answers have equal hashes. Empty if the response has fewer than minItems items.
+func ResponseContentHash(response studyTypes.SurveyResponse, minItems int) string {
+	if minItems <= 0 || len(response.Responses) < minItems {
+		return ""
+	}
+
+	content := make([]studyTypes.SurveyItemResponse, len(response.Responses))
+	for i, item := range response.Responses {
+		content[i] = withoutMeta(item)
+	}
+	b, err := json.Marshal(struct {
+		Key       string                          `json:"key"`
+		Responses []studyTypes.SurveyItemResponse `json:"responses"`
+	}{response.Key, content})
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:])
+}
+
+func withoutMeta(item studyTypes.SurveyItemResponse) studyTypes.SurveyItemResponse {
+	item.Meta = studyTypes.ResponseMeta{}
+	if len(item.Items) > 0 {
+		items := make([]studyTypes.SurveyItemResponse, len(item.Items))
+		for i, child := range item.Items {
+			items[i] = withoutMeta(child)
+		}
+		item.Items = items
+	}
+	return item
+}
+
+func isAnswered(item studyTypes.SurveyItemResponse) bool {
+	if item.Response != nil && (item.Response.Value != "" || len(item.Response.Items) > 0) {
+		return true
+	}
+	for _, child := range item.Items {
+		if isAnswered(child) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package studyutils
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func screeningTestResponse(openedAt int64, submittedAt int64, items ...studyTypes.SurveyItemResponse) studyTypes.SurveyResponse {
+	return studyTypes.SurveyResponse{
+		Key:         "S",
+		OpenedAt:    openedAt,
+		SubmittedAt: submittedAt,
+		Responses:   items,
+	}
+}
+
+func answer(key string, value string) studyTypes.SurveyItemResponse {
+	return studyTypes.SurveyItemResponse{
+		Key:      key,
+		Response: &studyTypes.ResponseItem{Key: "rg", Items: []*studyTypes.ResponseItem{{Key: value}}},
+	}
+}
+
+func TestScoreSubmission(t *testing.T) {
+	config := studyTypes.ResponseQuarantineConfig{
+		Enabled:                   true,
+		ExpectedCompletionSeconds: map[string]int64{"S": 200},
+		HoneypotItemKeys:          []string{"S.hp"},
+	}
+
+	t.Run("normal submission", func(t *testing.T) {
+		if result := ScoreSubmission(config, screeningTestResponse(1000, 1200, answer("S.Q1", "1")), false); result != nil {
+			t.Errorf("unexpected quarantine: %v", result)
+		}
+	})
+
+	t.Run("too fast alone is below threshold", func(t *testing.T) {
+		if result := ScoreSubmission(config, screeningTestResponse(1000, 1010, answer("S.Q1", "1")), false); result != nil {
+			t.Errorf("unexpected quarantine: %v", result)
+		}
+	})
+
+	t.Run("too fast and duplicate", func(t *testing.T) {
+		result := ScoreSubmission(config, screeningTestResponse(1000, 1010, answer("S.Q1", "1")), true)
+		if result == nil || len(result.Signals) != 2 {
+			t.Fatalf("expected quarantine with two signals: %v", result)
+		}
+		if result.Signals[0] != studyTypes.QUARANTINE_SIGNAL_TOO_FAST || result.Signals[1] != studyTypes.QUARANTINE_SIGNAL_DUPLICATE_CONTENT {
+			t.Errorf("unexpected signals: %v", result.Signals)
+		}
+	})
+
+	t.Run("honeypot answered", func(t *testing.T) {
+		result := ScoreSubmission(config, screeningTestResponse(1000, 1200, answer("S.Q1", "1"), answer("S.hp", "x")), false)
+		if result == nil || result.Signals[0] != studyTypes.QUARANTINE_SIGNAL_HONEYPOT {
+			t.Errorf("expected honeypot quarantine: %v", result)
+		}
+	})
+
+	t.Run("empty honeypot", func(t *testing.T) {
+		hp := studyTypes.SurveyItemResponse{Key: "S.hp"}
+		if result := ScoreSubmission(config, screeningTestResponse(1000, 1200, hp), false); result != nil {
+			t.Errorf("unexpected quarantine: %v", result)
+		}
+	})
+
+	t.Run("custom threshold", func(t *testing.T) {
+		strict := config
+		strict.Threshold = 0.5
+		if result := ScoreSubmission(strict, screeningTestResponse(1000, 1010), false); result == nil {
+			t.Error("expected quarantine")
+		}
+	})
+}
+
+func TestResponseContentHash(t *testing.T) {
+	a := screeningTestResponse(1000, 1200, answer("S.Q1", "1"), answer("S.Q2", "2"))
+	b := screeningTestResponse(5000, 5100, answer("S.Q1", "1"), answer("S.Q2", "2"))
+	b.Responses[0].Meta = studyTypes.ResponseMeta{Responded: []int64{5050}}
+	c := screeningTestResponse(1000, 1200, answer("S.Q1", "1"), answer("S.Q2", "3"))
+
+	if ResponseContentHash(a, 2) == "" || ResponseContentHash(a, 2) != ResponseContentHash(b, 2) {
+		t.Error("expected equal hashes for equal answers")
+	}
+	if ResponseContentHash(a, 2) == ResponseContentHash(c, 2) {
+		t.Error("expected different hashes for different answers")
+	}
+	if ResponseContentHash(a, 3) != "" || ResponseContentHash(a, 0) != "" {
+		t.Error("expected no hash below min items")
+	}
+}
@@ -0,0 +1,187 @@
+package studyutils
+
+import (
+	"fmt"
+	"strconv"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	RESPONSE_VALIDATION_UNKNOWN_ITEM     = "unknownItem"
+	RESPONSE_VALIDATION_MISSING_RESPONSE = "missingResponse"
+	RESPONSE_VALIDATION_UNKNOWN_OPTION   = "unknownOption"
+	RESPONSE_VALIDATION_INVALID_NUMBER   = "invalidNumber"
+	RESPONSE_VALIDATION_OUT_OF_RANGE     = "outOfRange"
+)
+
+// option keys are only checked below these components, other components may use keys not in the definition
+var optionGroupRoles = map[string]bool{
+	"responseGroup":       true,
+	"singleChoiceGroup":   true,
+	"multipleChoiceGroup": true,
+	"dropDownGroup":       true,
+}
+
+var numericInputRoles = map[string]bool{
+	"numberInput":   true,
+	"sliderNumeric": true,
+}
+
+type validatedItem struct {
+	item *studyTypes.SurveyItem
+	// the item or one of its parent groups has a condition, so it may not have been shown
+	conditional bool
+}
+
+// ValidateSurveyResponse checks the response against the survey definition it was submitted for: responses to items
+// not in the survey, missing responses to required items, option keys not in the definition and numbers outside
+// the range of the input. Required items are only checked if neither they nor their groups have a condition.
+func ValidateSurveyResponse(survey studyTypes.Survey, response studyTypes.SurveyResponse) []studyTypes.ResponseValidationIssue {
+	issues := []studyTypes.ResponseValidationIssue{}
+
+	items := map[string]validatedItem{}
+	indexSurveyItems(&survey.SurveyDefinition, false, items)
+
+	responded := map[string]bool{}
+	for _, itemResponse := range response.Responses {
+		entry, ok := items[itemResponse.Key]
+		if !ok {
+			issues = append(issues, studyTypes.ResponseValidationIssue{
+				ItemKey: itemResponse.Key,
+				Code:    RESPONSE_VALIDATION_UNKNOWN_ITEM,
+				Message: "item is not part of the survey",
+			})
+			continue
+		}
+		if itemResponse.Response != nil && len(itemResponse.Response.Items) > 0 {
+			responded[itemResponse.Key] = true
+		}
+		if entry.item.Components == nil || itemResponse.Response == nil {
+			continue
+		}
+		issues = append(issues, validateResponseItem(itemResponse.Key, *entry.item.Components, itemResponse.Response, "")...)
+	}
+
+	for _, key := range surveyItemKeysInOrder(&survey.SurveyDefinition) {
+		entry, ok := items[key]
+		if !ok || entry.conditional || responded[key] || !isRequiredItem(entry.item) {
+			continue
+		}
+		issues = append(issues, studyTypes.ResponseValidationIssue{
+			ItemKey: key,
+			Code:    RESPONSE_VALIDATION_MISSING_RESPONSE,
+			Message: "required item has no response",
+		})
+	}
+	return issues
+}
+
+func indexSurveyItems(item *studyTypes.SurveyItem, conditional bool, items map[string]validatedItem) {
+	conditional = conditional || item.Condition != nil
+	if len(item.Items) > 0 {
+		for i := range item.Items {
+			indexSurveyItems(&item.Items[i], conditional, items)
+		}
+		return
+	}
+	if item.Type == studyTypes.SURVEY_ITEM_TYPE_PAGE_BREAK || item.Type == studyTypes.SURVEY_ITEM_TYPE_END {
+		return
+	}
+	items[item.Key] = validatedItem{item: item, conditional: conditional}
+}
+
+func surveyItemKeysInOrder(item *studyTypes.SurveyItem) []string {
+	if len(item.Items) == 0 {
+		return []string{item.Key}
+	}
+	keys := []string{}
+	for i := range item.Items {
+		keys = append(keys, surveyItemKeysInOrder(&item.Items[i])...)
+	}
+	return keys
+}
+
+// isRequiredItem is true if the item has a hard validation requiring a response to the item itself
+func isRequiredItem(item *studyTypes.SurveyItem) bool {
+	for _, validation := range item.Validations {
+		if validation.Type != "hard" || validation.Rule.Name != "hasResponse" {
+			continue
+		}
+		if len(validation.Rule.Data) > 0 && validation.Rule.Data[0].Str == item.Key {
+			return true
+		}
+	}
+	return false
+}
+
+// validateResponseItem looks up the response in the children of the component and checks its value and children
+func validateResponseItem(itemKey string, parent studyTypes.ItemComponent, response *studyTypes.ResponseItem, path string) []studyTypes.ResponseValidationIssue {
+	issues := []studyTypes.ResponseValidationIssue{}
+	if path != "" {
+		path += "."
+	}
+	path += response.Key
+
+	var component *studyTypes.ItemComponent
+	for i := range parent.Items {
+		if parent.Items[i].Key == response.Key {
+			component = &parent.Items[i]
+			break
+		}
+	}
+	if component == nil {
+		if optionGroupRoles[parent.Role] {
+			issues = append(issues, studyTypes.ResponseValidationIssue{
+				ItemKey: itemKey,
+				Code:    RESPONSE_VALIDATION_UNKNOWN_OPTION,
+				Message: fmt.Sprintf("response '%s' is not an option of the item", path),
+			})
+		}
+		return issues
+	}
+
+	if numericInputRoles[component.Role] && response.Value != "" {
+		if issue := checkNumberRange(itemKey, path, *component, response.Value); issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+
+	for _, child := range response.Items {
+		if child == nil {
+			continue
+		}
+		issues = append(issues, validateResponseItem(itemKey, *component, child, path)...)
+	}
+	return issues
+}
+
+// checkNumberRange compares the value with min and max of the input, limits set by expressions are not checked
+func checkNumberRange(itemKey string, path string, component studyTypes.ItemComponent, value string) *studyTypes.ResponseValidationIssue {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return &studyTypes.ResponseValidationIssue{
+			ItemKey: itemKey,
+			Code:    RESPONSE_VALIDATION_INVALID_NUMBER,
+			Message: fmt.Sprintf("response '%s' is not a number", path),
+		}
+	}
+	if component.Properties == nil {
+		return nil
+	}
+	if lower := component.Properties.Min; lower != nil && lower.DType == "num" && number < lower.Num {
+		return &studyTypes.ResponseValidationIssue{
+			ItemKey: itemKey,
+			Code:    RESPONSE_VALIDATION_OUT_OF_RANGE,
+			Message: fmt.Sprintf("response '%s' is below the minimum of %v", path, lower.Num),
+		}
+	}
+	if upper := component.Properties.Max; upper != nil && upper.DType == "num" && number > upper.Num {
+		return &studyTypes.ResponseValidationIssue{
+			ItemKey: itemKey,
+			Code:    RESPONSE_VALIDATION_OUT_OF_RANGE,
+			Message: fmt.Sprintf("response '%s' is above the maximum of %v", path, upper.Num),
+		}
+	}
+	return nil
+}
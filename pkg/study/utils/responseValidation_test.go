@@ -0,0 +1,143 @@
+package studyutils
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func validationTestSurvey() studyTypes.Survey {
+	return studyTypes.Survey{
+		SurveyDefinition: studyTypes.SurveyItem{
+			Key: "S",
+			Items: []studyTypes.SurveyItem{
+				{
+					Key: "S.Q1",
+					Components: &studyTypes.ItemComponent{Role: "root", Items: []studyTypes.ItemComponent{
+						{Role: "responseGroup", Key: "rg", Items: []studyTypes.ItemComponent{
+							{Role: "singleChoiceGroup", Key: "scg", Items: []studyTypes.ItemComponent{
+								{Role: "option", Key: "1"},
+								{Role: "option", Key: "2"},
+							}},
+						}},
+					}},
+					Validations: []studyTypes.Validation{
+						{Key: "r1", Type: "hard", Rule: studyTypes.Expression{Name: "hasResponse", Data: []studyTypes.ExpressionArg{strArg("S.Q1"), strArg("rg")}}},
+					},
+				},
+				{
+					Key: "S.Q2",
+					Components: &studyTypes.ItemComponent{Role: "root", Items: []studyTypes.ItemComponent{
+						{Role: "responseGroup", Key: "rg", Items: []studyTypes.ItemComponent{
+							{Role: "numberInput", Key: "num", Properties: &studyTypes.ComponentProperties{
+								Min: &studyTypes.ExpressionArg{DType: "num", Num: 0},
+								Max: &studyTypes.ExpressionArg{DType: "num", Num: 120},
+							}},
+						}},
+					}},
+				},
+				{
+					Key:       "S.G",
+					Condition: &studyTypes.Expression{Name: "responseHasKeysAny", Data: []studyTypes.ExpressionArg{strArg("S.Q1"), strArg("rg.scg"), strArg("2")}},
+					Items: []studyTypes.SurveyItem{
+						{
+							Key: "S.G.Q3",
+							Validations: []studyTypes.Validation{
+								{Key: "r1", Type: "hard", Rule: studyTypes.Expression{Name: "hasResponse", Data: []studyTypes.ExpressionArg{strArg("S.G.Q3"), strArg("rg")}}},
+							},
+						},
+					},
+				},
+				{Key: "S.end", Type: studyTypes.SURVEY_ITEM_TYPE_END},
+			},
+		},
+	}
+}
+
+func singleResponse(key string, slot string, option string, value string) studyTypes.SurveyItemResponse {
+	return studyTypes.SurveyItemResponse{
+		Key: key,
+		Response: &studyTypes.ResponseItem{Key: "rg", Items: []*studyTypes.ResponseItem{
+			{Key: slot, Value: value, Items: func() []*studyTypes.ResponseItem {
+				if option == "" {
+					return nil
+				}
+				return []*studyTypes.ResponseItem{{Key: option}}
+			}()},
+		}},
+	}
+}
+
+func TestValidateSurveyResponse(t *testing.T) {
+	survey := validationTestSurvey()
+
+	t.Run("valid response", func(t *testing.T) {
+		issues := ValidateSurveyResponse(survey, studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{
+			singleResponse("S.Q1", "scg", "1", ""),
+			singleResponse("S.Q2", "num", "", "42"),
+		}})
+		if len(issues) != 0 {
+			t.Errorf("unexpected issues: %+v", issues)
+		}
+	})
+
+	t.Run("missing required response", func(t *testing.T) {
+		issues := ValidateSurveyResponse(survey, studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{
+			{Key: "S.Q1", Response: &studyTypes.ResponseItem{Key: "rg"}},
+		}})
+		if len(issues) != 1 || issues[0].Code != RESPONSE_VALIDATION_MISSING_RESPONSE || issues[0].ItemKey != "S.Q1" {
+			t.Errorf("unexpected issues: %+v", issues)
+		}
+	})
+
+	t.Run("unknown item and option", func(t *testing.T) {
+		issues := ValidateSurveyResponse(survey, studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{
+			singleResponse("S.Q1", "scg", "3", ""),
+			singleResponse("S.Q9", "scg", "1", ""),
+		}})
+		codes := map[string]string{}
+		for _, issue := range issues {
+			codes[issue.ItemKey] = issue.Code
+		}
+		if len(issues) != 2 || codes["S.Q1"] != RESPONSE_VALIDATION_UNKNOWN_OPTION || codes["S.Q9"] != RESPONSE_VALIDATION_UNKNOWN_ITEM {
+			t.Errorf("unexpected issues: %+v", issues)
+		}
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		tests := []struct {
+			value string
+			code  string
+		}{
+			{value: "0", code: ""},
+			{value: "120", code: ""},
+			{value: "-1", code: RESPONSE_VALIDATION_OUT_OF_RANGE},
+			{value: "121.5", code: RESPONSE_VALIDATION_OUT_OF_RANGE},
+			{value: "abc", code: RESPONSE_VALIDATION_INVALID_NUMBER},
+		}
+		for _, tt := range tests {
+			issues := ValidateSurveyResponse(survey, studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{
+				singleResponse("S.Q1", "scg", "1", ""),
+				singleResponse("S.Q2", "num", "", tt.value),
+			}})
+			if tt.code == "" {
+				if len(issues) != 0 {
+					t.Errorf("value %s: unexpected issues: %+v", tt.value, issues)
+				}
+				continue
+			}
+			if len(issues) != 1 || issues[0].Code != tt.code {
+				t.Errorf("value %s: unexpected issues: %+v", tt.value, issues)
+			}
+		}
+	})
+
+	t.Run("unknown slot is reported", func(t *testing.T) {
+		issues := ValidateSurveyResponse(survey, studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{
+			singleResponse("S.Q1", "mcg", "1", ""),
+		}})
+		if len(issues) != 1 || issues[0].Code != RESPONSE_VALIDATION_UNKNOWN_OPTION {
+			t.Errorf("unexpected issues: %+v", issues)
+		}
+	})
+}
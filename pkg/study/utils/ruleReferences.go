@@ -0,0 +1,69 @@
+package studyutils
+
+import (
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	RULE_REFERENCE_MESSAGE_TYPE     = "messageType"
+	RULE_REFERENCE_SURVEY_KEY       = "surveyKey"
+	RULE_REFERENCE_REPORT_KEY       = "reportKey"
+	RULE_REFERENCE_EXTERNAL_SERVICE = "externalService"
+)
+
+// RuleReference is an item referenced by an action of a study rule
+type RuleReference struct {
+	RuleIndex int    `json:"ruleIndex"`
+	Action    string `json:"action"`
+	Kind      string `json:"kind"`
+	Key       string `json:"key,omitempty"`
+	// Dynamic is true if the key is computed at runtime and cannot be resolved from the rules
+	Dynamic bool `json:"dynamic,omitempty"`
+}
+
+// actions referencing another item with their first argument
+var referencingActions = map[string]string{
+	"ADD_MESSAGE":             RULE_REFERENCE_MESSAGE_TYPE,
+	"REMOVE_MESSAGES_BY_TYPE": RULE_REFERENCE_MESSAGE_TYPE,
+	"NOTIFY_RESEARCHER":       RULE_REFERENCE_MESSAGE_TYPE,
+	"ADD_NEW_SURVEY":          RULE_REFERENCE_SURVEY_KEY,
+	"REMOVE_SURVEY_BY_KEY":    RULE_REFERENCE_SURVEY_KEY,
+	"REMOVE_SURVEYS_BY_KEY":   RULE_REFERENCE_SURVEY_KEY,
+	"INIT_REPORT":             RULE_REFERENCE_REPORT_KEY,
+	"UPDATE_REPORT_DATA":      RULE_REFERENCE_REPORT_KEY,
+	"REMOVE_REPORT_DATA":      RULE_REFERENCE_REPORT_KEY,
+	"CANCEL_REPORT":           RULE_REFERENCE_REPORT_KEY,
+	"EXTERNAL_EVENT_HANDLER":  RULE_REFERENCE_EXTERNAL_SERVICE,
+}
+
+// FindRuleReferences lists the message types, survey keys, report keys and external services used by the actions of the rules
+func FindRuleReferences(rules []studyTypes.Expression) []RuleReference {
+	refs := []RuleReference{}
+	for i, rule := range rules {
+		refs = appendExpressionReferences(refs, i, rule)
+	}
+	return refs
+}
+
+func appendExpressionReferences(refs []RuleReference, ruleIndex int, exp studyTypes.Expression) []RuleReference {
+	if kind, ok := referencingActions[exp.Name]; ok && len(exp.Data) > 0 {
+		ref := RuleReference{
+			RuleIndex: ruleIndex,
+			Action:    exp.Name,
+			Kind:      kind,
+		}
+		if exp.Data[0].IsExpression() {
+			ref.Dynamic = true
+		} else {
+			ref.Key = exp.Data[0].Str
+		}
+		refs = append(refs, ref)
+	}
+
+	for _, arg := range exp.Data {
+		if arg.IsExpression() && arg.Exp != nil {
+			refs = appendExpressionReferences(refs, ruleIndex, *arg.Exp)
+		}
+	}
+	return refs
+}
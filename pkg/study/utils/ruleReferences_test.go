@@ -0,0 +1,64 @@
+package studyutils
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func strArg(s string) studyTypes.ExpressionArg {
+	return studyTypes.ExpressionArg{DType: "str", Str: s}
+}
+
+func expArg(exp studyTypes.Expression) studyTypes.ExpressionArg {
+	return studyTypes.ExpressionArg{DType: "exp", Exp: &exp}
+}
+
+func TestFindRuleReferences(t *testing.T) {
+	rules := []studyTypes.Expression{
+		{
+			Name: "IFTHEN",
+			Data: []studyTypes.ExpressionArg{
+				expArg(studyTypes.Expression{Name: "checkEventType", Data: []studyTypes.ExpressionArg{strArg("ENTER")}}),
+				expArg(studyTypes.Expression{Name: "ADD_NEW_SURVEY", Data: []studyTypes.ExpressionArg{strArg("intake")}}),
+				expArg(studyTypes.Expression{Name: "ADD_MESSAGE", Data: []studyTypes.ExpressionArg{
+					strArg("reminder"),
+					expArg(studyTypes.Expression{Name: "timestampWithOffset"}),
+				}}),
+			},
+		},
+		{
+			Name: "IFTHEN",
+			Data: []studyTypes.ExpressionArg{
+				expArg(studyTypes.Expression{Name: "checkEventType", Data: []studyTypes.ExpressionArg{strArg("SUBMIT")}}),
+				expArg(studyTypes.Expression{Name: "NOTIFY_RESEARCHER", Data: []studyTypes.ExpressionArg{
+					expArg(studyTypes.Expression{Name: "getStudyEntryTime"}),
+				}}),
+				expArg(studyTypes.Expression{Name: "EXTERNAL_EVENT_HANDLER", Data: []studyTypes.ExpressionArg{strArg("service")}}),
+			},
+		},
+	}
+
+	refs := FindRuleReferences(rules)
+	expected := []RuleReference{
+		{RuleIndex: 0, Action: "ADD_NEW_SURVEY", Kind: RULE_REFERENCE_SURVEY_KEY, Key: "intake"},
+		{RuleIndex: 0, Action: "ADD_MESSAGE", Kind: RULE_REFERENCE_MESSAGE_TYPE, Key: "reminder"},
+		{RuleIndex: 1, Action: "NOTIFY_RESEARCHER", Kind: RULE_REFERENCE_MESSAGE_TYPE, Dynamic: true},
+		{RuleIndex: 1, Action: "EXTERNAL_EVENT_HANDLER", Kind: RULE_REFERENCE_EXTERNAL_SERVICE, Key: "service"},
+	}
+
+	if len(refs) != len(expected) {
+		t.Fatalf("unexpected number of references: %d - %+v", len(refs), refs)
+	}
+	for i, ref := range refs {
+		if ref != expected[i] {
+			t.Errorf("unexpected reference at %d: %+v, expected %+v", i, ref, expected[i])
+		}
+	}
+}
+
+func TestFindRuleReferencesEmpty(t *testing.T) {
+	if refs := FindRuleReferences(nil); len(refs) != 0 {
+		t.Errorf("unexpected references: %+v", refs)
+	}
+}
@@ -0,0 +1,244 @@
+package studyutils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	SURVEY_LINT_SEVERITY_ERROR   = "error"
+	SURVEY_LINT_SEVERITY_WARNING = "warning"
+)
+
+const (
+	SURVEY_LINT_MISSING_KEY             = "missingKey"
+	SURVEY_LINT_DUPLICATE_KEY           = "duplicateKey"
+	SURVEY_LINT_INVALID_KEY_PREFIX      = "invalidKeyPrefix"
+	SURVEY_LINT_DUPLICATE_COMPONENT_KEY = "duplicateComponentKey"
+	SURVEY_LINT_UNKNOWN_REFERENCE       = "unknownReference"
+	SURVEY_LINT_FORWARD_REFERENCE       = "forwardReference"
+	SURVEY_LINT_UNREACHABLE_ITEM        = "unreachableItem"
+	SURVEY_LINT_MISSING_TRANSLATION     = "missingTranslation"
+	SURVEY_LINT_UNKNOWN_QUESTION_TYPE   = "unknownQuestionType"
+)
+
+// SurveyLintWarning is a problem found in a survey definition
+type SurveyLintWarning struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	ItemKey  string `json:"itemKey,omitempty"`
+	Message  string `json:"message"`
+}
+
+type surveyLinter struct {
+	rootKey   string
+	warnings  []SurveyLintWarning
+	itemOrder map[string]int
+	languages []string
+}
+
+// LintSurveyDefinition checks the survey definition for duplicate keys, conditions referencing
+// unknown or later items, unreachable items and missing translations
+func LintSurveyDefinition(survey studyTypes.Survey) []SurveyLintWarning {
+	root := survey.SurveyDefinition
+	l := &surveyLinter{
+		rootKey:   root.Key,
+		warnings:  []SurveyLintWarning{},
+		itemOrder: map[string]int{},
+	}
+
+	if root.Key == "" {
+		l.add(SURVEY_LINT_SEVERITY_ERROR, SURVEY_LINT_MISSING_KEY, "", "survey definition has no key")
+		return l.warnings
+	}
+
+	l.indexItems(root)
+	l.languages = collectSurveyLanguages(survey)
+
+	l.checkItem(root, "")
+	l.checkTranslations("", "props.name", survey.Props.Name)
+	l.checkTranslations("", "props.description", survey.Props.Description)
+	l.checkTranslations("", "props.typicalDuration", survey.Props.TypicalDuration)
+	return l.warnings
+}
+
+func (l *surveyLinter) add(severity string, code string, itemKey string, message string) {
+	l.warnings = append(l.warnings, SurveyLintWarning{
+		Severity: severity,
+		Code:     code,
+		ItemKey:  itemKey,
+		Message:  message,
+	})
+}
+
+// indexItems records the position of each item in display order and reports duplicate keys
+func (l *surveyLinter) indexItems(item studyTypes.SurveyItem) {
+	if item.Key != "" {
+		if _, ok := l.itemOrder[item.Key]; ok {
+			l.add(SURVEY_LINT_SEVERITY_ERROR, SURVEY_LINT_DUPLICATE_KEY, item.Key, fmt.Sprintf("item key '%s' is used more than once", item.Key))
+		} else {
+			l.itemOrder[item.Key] = len(l.itemOrder)
+		}
+	}
+	for _, child := range item.Items {
+		l.indexItems(child)
+	}
+}
+
+func (l *surveyLinter) checkItem(item studyTypes.SurveyItem, parentKey string) {
+	if item.Key == "" {
+		l.add(SURVEY_LINT_SEVERITY_ERROR, SURVEY_LINT_MISSING_KEY, parentKey, fmt.Sprintf("item in '%s' has no key", parentKey))
+	} else if parentKey != "" && !strings.HasPrefix(item.Key, parentKey+".") {
+		l.add(SURVEY_LINT_SEVERITY_WARNING, SURVEY_LINT_INVALID_KEY_PREFIX, item.Key, fmt.Sprintf("item key '%s' does not start with its parent key '%s'", item.Key, parentKey))
+	}
+
+	if item.Condition != nil {
+		l.checkConditionReferences(item)
+	}
+	for _, follow := range item.Follows {
+		if _, ok := l.itemOrder[follow]; !ok {
+			l.add(SURVEY_LINT_SEVERITY_WARNING, SURVEY_LINT_UNKNOWN_REFERENCE, item.Key, fmt.Sprintf("item follows unknown item '%s'", follow))
+		}
+	}
+
+	if item.Components != nil {
+		l.checkComponentKeys(item.Key, *item.Components)
+		l.checkComponentTranslations(item.Key, *item.Components, "")
+	}
+
+	endReached := false
+	for _, child := range item.Items {
+		if endReached && child.Type != studyTypes.SURVEY_ITEM_TYPE_PAGE_BREAK {
+			l.add(SURVEY_LINT_SEVERITY_WARNING, SURVEY_LINT_UNREACHABLE_ITEM, child.Key, "item is placed after an unconditional survey end")
+		}
+		if child.Type == studyTypes.SURVEY_ITEM_TYPE_END && child.Condition == nil {
+			endReached = true
+		}
+		l.checkItem(child, item.Key)
+	}
+}
+
+// checkConditionReferences reports conditions that depend on items that do not exist or are shown later
+func (l *surveyLinter) checkConditionReferences(item studyTypes.SurveyItem) {
+	ownIndex, hasIndex := l.itemOrder[item.Key]
+	for _, ref := range l.findItemReferences(*item.Condition) {
+		refIndex, ok := l.itemOrder[ref]
+		if !ok {
+			l.add(SURVEY_LINT_SEVERITY_WARNING, SURVEY_LINT_UNREACHABLE_ITEM, item.Key, fmt.Sprintf("condition references unknown item '%s', the item may never be shown", ref))
+			continue
+		}
+		if hasIndex && refIndex >= ownIndex {
+			l.add(SURVEY_LINT_SEVERITY_WARNING, SURVEY_LINT_FORWARD_REFERENCE, item.Key, fmt.Sprintf("condition references item '%s' which is not shown before this item", ref))
+		}
+	}
+}
+
+// findItemReferences lists the string arguments of the expression that look like item keys of the survey
+func (l *surveyLinter) findItemReferences(exp studyTypes.Expression) []string {
+	refs := []string{}
+	for _, arg := range exp.Data {
+		if arg.IsExpression() && arg.Exp != nil {
+			refs = append(refs, l.findItemReferences(*arg.Exp)...)
+			continue
+		}
+		if arg.IsString() && strings.HasPrefix(arg.Str, l.rootKey+".") {
+			refs = append(refs, arg.Str)
+		}
+	}
+	return refs
+}
+
+func (l *surveyLinter) checkComponentKeys(itemKey string, comp studyTypes.ItemComponent) {
+	seen := map[string]bool{}
+	for _, child := range comp.Items {
+		if child.Key == "" {
+			continue
+		}
+		if seen[child.Key] {
+			l.add(SURVEY_LINT_SEVERITY_ERROR, SURVEY_LINT_DUPLICATE_COMPONENT_KEY, itemKey, fmt.Sprintf("component key '%s' is used more than once in '%s'", child.Key, comp.Key))
+		}
+		seen[child.Key] = true
+		l.checkComponentKeys(itemKey, child)
+	}
+}
+
+func (l *surveyLinter) checkComponentTranslations(itemKey string, comp studyTypes.ItemComponent, parentPath string) {
+	path := comp.Key
+	if path == "" {
+		path = comp.Role
+	}
+	if parentPath != "" {
+		path = parentPath + "." + path
+	}
+	l.checkTranslations(itemKey, path, comp.Content)
+	l.checkTranslations(itemKey, path+" (description)", comp.Description)
+	for _, child := range comp.Items {
+		l.checkComponentTranslations(itemKey, child, path)
+	}
+}
+
+// checkTranslations reports languages used elsewhere in the survey that are missing from the given content
+func (l *surveyLinter) checkTranslations(itemKey string, location string, content []studyTypes.LocalisedObject) {
+	if len(content) == 0 {
+		return
+	}
+	missing := []string{}
+	for _, lang := range l.languages {
+		found := false
+		for _, c := range content {
+			if c.Code == lang {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, lang)
+		}
+	}
+	if len(missing) > 0 {
+		l.add(SURVEY_LINT_SEVERITY_WARNING, SURVEY_LINT_MISSING_TRANSLATION, itemKey, fmt.Sprintf("'%s' is missing translations for: %s", location, strings.Join(missing, ", ")))
+	}
+}
+
+func collectSurveyLanguages(survey studyTypes.Survey) []string {
+	langs := map[string]bool{}
+	addLangs := func(content []studyTypes.LocalisedObject) {
+		for _, c := range content {
+			if c.Code != "" {
+				langs[c.Code] = true
+			}
+		}
+	}
+	var addComponentLangs func(comp studyTypes.ItemComponent)
+	addComponentLangs = func(comp studyTypes.ItemComponent) {
+		addLangs(comp.Content)
+		addLangs(comp.Description)
+		for _, child := range comp.Items {
+			addComponentLangs(child)
+		}
+	}
+	var addItemLangs func(item studyTypes.SurveyItem)
+	addItemLangs = func(item studyTypes.SurveyItem) {
+		if item.Components != nil {
+			addComponentLangs(*item.Components)
+		}
+		for _, child := range item.Items {
+			addItemLangs(child)
+		}
+	}
+
+	addLangs(survey.Props.Name)
+	addLangs(survey.Props.Description)
+	addLangs(survey.Props.TypicalDuration)
+	addItemLangs(survey.SurveyDefinition)
+
+	result := make([]string, 0, len(langs))
+	for lang := range langs {
+		result = append(result, lang)
+	}
+	sort.Strings(result)
+	return result
+}
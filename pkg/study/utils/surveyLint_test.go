@@ -0,0 +1,148 @@
+package studyutils
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func textContent(langs ...string) []studyTypes.LocalisedObject {
+	content := []studyTypes.LocalisedObject{}
+	for _, lang := range langs {
+		content = append(content, studyTypes.LocalisedObject{Code: lang, Parts: []studyTypes.ExpressionArg{strArg("text")}})
+	}
+	return content
+}
+
+func countLintCodes(warnings []SurveyLintWarning) map[string]int {
+	counts := map[string]int{}
+	for _, w := range warnings {
+		counts[w.Code]++
+	}
+	return counts
+}
+
+func TestLintSurveyDefinition(t *testing.T) {
+	t.Run("valid survey", func(t *testing.T) {
+		survey := studyTypes.Survey{
+			Props: studyTypes.SurveyProps{Name: textContent("en", "de")},
+			SurveyDefinition: studyTypes.SurveyItem{
+				Key: "S",
+				Items: []studyTypes.SurveyItem{
+					{Key: "S.Q1", Components: &studyTypes.ItemComponent{Role: "root", Items: []studyTypes.ItemComponent{
+						{Role: "title", Content: textContent("en", "de")},
+						{Role: "responseGroup", Key: "rg", Items: []studyTypes.ItemComponent{
+							{Role: "singleChoiceGroup", Key: "scg", Items: []studyTypes.ItemComponent{
+								{Role: "option", Key: "1", Content: textContent("en", "de")},
+								{Role: "option", Key: "2", Content: textContent("en", "de")},
+							}},
+						}},
+					}}},
+					{Key: "S.Q2", Condition: &studyTypes.Expression{Name: "responseHasKeysAny", Data: []studyTypes.ExpressionArg{
+						strArg("S.Q1"), strArg("rg.scg"), strArg("1"),
+					}}},
+					{Key: "S.end", Type: studyTypes.SURVEY_ITEM_TYPE_END},
+				},
+			},
+		}
+		if warnings := LintSurveyDefinition(survey); len(warnings) != 0 {
+			t.Errorf("unexpected warnings: %+v", warnings)
+		}
+	})
+
+	t.Run("missing root key", func(t *testing.T) {
+		warnings := LintSurveyDefinition(studyTypes.Survey{})
+		if len(warnings) != 1 || warnings[0].Code != SURVEY_LINT_MISSING_KEY {
+			t.Errorf("unexpected warnings: %+v", warnings)
+		}
+	})
+
+	t.Run("duplicate and invalid keys", func(t *testing.T) {
+		survey := studyTypes.Survey{
+			SurveyDefinition: studyTypes.SurveyItem{
+				Key: "S",
+				Items: []studyTypes.SurveyItem{
+					{Key: "S.Q1", Components: &studyTypes.ItemComponent{Role: "root", Items: []studyTypes.ItemComponent{
+						{Role: "responseGroup", Key: "rg", Items: []studyTypes.ItemComponent{
+							{Role: "option", Key: "1"},
+							{Role: "option", Key: "1"},
+						}},
+					}}},
+					{Key: "S.Q1"},
+					{Key: "Other.Q3"},
+					{},
+				},
+			},
+		}
+		counts := countLintCodes(LintSurveyDefinition(survey))
+		if counts[SURVEY_LINT_DUPLICATE_KEY] != 1 {
+			t.Errorf("expected duplicate key warning: %v", counts)
+		}
+		if counts[SURVEY_LINT_DUPLICATE_COMPONENT_KEY] != 1 {
+			t.Errorf("expected duplicate component key warning: %v", counts)
+		}
+		if counts[SURVEY_LINT_INVALID_KEY_PREFIX] != 1 {
+			t.Errorf("expected invalid key prefix warning: %v", counts)
+		}
+		if counts[SURVEY_LINT_MISSING_KEY] != 1 {
+			t.Errorf("expected missing key warning: %v", counts)
+		}
+	})
+
+	t.Run("conditions and reachability", func(t *testing.T) {
+		survey := studyTypes.Survey{
+			SurveyDefinition: studyTypes.SurveyItem{
+				Key: "S",
+				Items: []studyTypes.SurveyItem{
+					{Key: "S.Q1", Condition: &studyTypes.Expression{Name: "not", Data: []studyTypes.ExpressionArg{
+						expArg(studyTypes.Expression{Name: "responseHasKeysAny", Data: []studyTypes.ExpressionArg{strArg("S.Q2"), strArg("rg")}}),
+					}}},
+					{Key: "S.Q2", Condition: &studyTypes.Expression{Name: "responseHasKeysAny", Data: []studyTypes.ExpressionArg{strArg("S.Q9"), strArg("rg")}}},
+					{Key: "S.Q3", Follows: []string{"S.Q8"}},
+					{Key: "S.end", Type: studyTypes.SURVEY_ITEM_TYPE_END},
+					{Key: "S.Q4"},
+				},
+			},
+		}
+		warnings := LintSurveyDefinition(survey)
+		expected := []SurveyLintWarning{
+			{Severity: SURVEY_LINT_SEVERITY_WARNING, Code: SURVEY_LINT_FORWARD_REFERENCE, ItemKey: "S.Q1"},
+			{Severity: SURVEY_LINT_SEVERITY_WARNING, Code: SURVEY_LINT_UNREACHABLE_ITEM, ItemKey: "S.Q2"},
+			{Severity: SURVEY_LINT_SEVERITY_WARNING, Code: SURVEY_LINT_UNKNOWN_REFERENCE, ItemKey: "S.Q3"},
+			{Severity: SURVEY_LINT_SEVERITY_WARNING, Code: SURVEY_LINT_UNREACHABLE_ITEM, ItemKey: "S.Q4"},
+		}
+		if len(warnings) != len(expected) {
+			t.Fatalf("unexpected warnings: %+v", warnings)
+		}
+		for i, w := range warnings {
+			if w.Severity != expected[i].Severity || w.Code != expected[i].Code || w.ItemKey != expected[i].ItemKey {
+				t.Errorf("unexpected warning at %d: %+v, expected %+v", i, w, expected[i])
+			}
+		}
+	})
+
+	t.Run("missing translations", func(t *testing.T) {
+		survey := studyTypes.Survey{
+			Props: studyTypes.SurveyProps{Name: textContent("en", "de", "fr")},
+			SurveyDefinition: studyTypes.SurveyItem{
+				Key: "S",
+				Items: []studyTypes.SurveyItem{
+					{Key: "S.Q1", Components: &studyTypes.ItemComponent{Role: "root", Items: []studyTypes.ItemComponent{
+						{Role: "title", Content: textContent("en")},
+						{Role: "text", Key: "info", Content: textContent("en", "de", "fr"), Description: textContent("de", "fr")},
+					}}},
+				},
+			},
+		}
+		warnings := LintSurveyDefinition(survey)
+		if len(warnings) != 2 {
+			t.Fatalf("unexpected warnings: %+v", warnings)
+		}
+		if warnings[0].Message != "'root.title' is missing translations for: de, fr" {
+			t.Errorf("unexpected message: %s", warnings[0].Message)
+		}
+		if warnings[1].Message != "'root.info (description)' is missing translations for: en" {
+			t.Errorf("unexpected message: %s", warnings[1].Message)
+		}
+	})
+}
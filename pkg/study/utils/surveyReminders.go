@@ -0,0 +1,213 @@
+package studyutils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// DueSurveyReminder is a reminder of a rule for an assigned survey that should be sent
+type DueSurveyReminder struct {
+	Rule   studyTypes.SurveyReminderRule
+	Survey studyTypes.AssignedSurvey
+	// when the reminder became due
+	DueAt int64
+}
+
+func reminderLocation(c *studyTypes.SurveyReminderConfig) (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// ValidateSurveyReminderConfig checks rules, timezone, quiet hours and throttles of the config
+func ValidateSurveyReminderConfig(c *studyTypes.SurveyReminderConfig) error {
+	if c == nil {
+		return nil
+	}
+	if _, err := reminderLocation(c); err != nil {
+		return err
+	}
+	if c.QuietHours != nil {
+		start, err := parseTimeOfDay(c.QuietHours.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseTimeOfDay(c.QuietHours.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return errors.New("quiet hours start and end must differ")
+		}
+	}
+	if c.MinInterval < 0 {
+		return errors.New("min interval must not be negative")
+	}
+	if c.MaxPerDay < 0 {
+		return errors.New("max reminders per day must not be negative")
+	}
+
+	keys := map[string]bool{}
+	for _, rule := range c.Rules {
+		if rule.Key == "" {
+			return errors.New("rule key is required")
+		}
+		if keys[rule.Key] {
+			return fmt.Errorf("duplicate rule key: %s", rule.Key)
+		}
+		keys[rule.Key] = true
+
+		if rule.Trigger != studyTypes.SURVEY_REMINDER_TRIGGER_DUE && rule.Trigger != studyTypes.SURVEY_REMINDER_TRIGGER_EXPIRING {
+			return fmt.Errorf("invalid trigger of rule %s: %s", rule.Key, rule.Trigger)
+		}
+		if rule.Offset < 0 {
+			return fmt.Errorf("offset of rule %s must not be negative", rule.Key)
+		}
+		if rule.MessageType == "" {
+			return fmt.Errorf("message type of rule %s is required", rule.Key)
+		}
+		switch rule.Channel {
+		case "", studyTypes.SURVEY_REMINDER_CHANNEL_EMAIL, studyTypes.SURVEY_REMINDER_CHANNEL_SMS:
+		default:
+			return fmt.Errorf("invalid channel of rule %s: %s", rule.Key, rule.Channel)
+		}
+	}
+	return nil
+}
+
+// IsInQuietHours checks if no reminders should be sent at the given time
+func IsInQuietHours(c *studyTypes.SurveyReminderConfig, now time.Time) bool {
+	if c == nil || c.QuietHours == nil {
+		return false
+	}
+	loc, err := reminderLocation(c)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err1 := parseTimeOfDay(c.QuietHours.Start)
+	end, err2 := parseTimeOfDay(c.QuietHours.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// spans midnight
+	return minute >= start || minute < end
+}
+
+// DueSurveyReminders returns the reminders of the rules that are due for the open surveys and were not sent yet,
+// oldest first
+func DueSurveyReminders(c *studyTypes.SurveyReminderConfig, surveys []studyTypes.AssignedSurvey, sent []studyTypes.SentSurveyReminder, now time.Time) []DueSurveyReminder {
+	if c == nil {
+		return nil
+	}
+	isSent := map[string]bool{}
+	for _, s := range sent {
+		isSent[sentReminderKey(s.RuleKey, s.SurveyKey, s.SurveyValidFrom)] = true
+	}
+
+	ts := now.Unix()
+	due := []DueSurveyReminder{}
+	for _, survey := range surveys {
+		if survey.ValidFrom > ts || (survey.ValidUntil > 0 && survey.ValidUntil <= ts) {
+			continue
+		}
+		for _, rule := range c.Rules {
+			if !ruleAppliesToSurvey(rule, survey.SurveyKey) {
+				continue
+			}
+			var dueAt int64
+			switch rule.Trigger {
+			case studyTypes.SURVEY_REMINDER_TRIGGER_DUE:
+				dueAt = survey.ValidFrom + rule.Offset
+			case studyTypes.SURVEY_REMINDER_TRIGGER_EXPIRING:
+				if survey.ValidUntil <= 0 {
+					continue
+				}
+				dueAt = survey.ValidUntil - rule.Offset
+			default:
+				continue
+			}
+			if dueAt > ts || isSent[sentReminderKey(rule.Key, survey.SurveyKey, survey.ValidFrom)] {
+				continue
+			}
+			due = append(due, DueSurveyReminder{Rule: rule, Survey: survey, DueAt: dueAt})
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].DueAt < due[j].DueAt
+	})
+	return due
+}
+
+func ruleAppliesToSurvey(rule studyTypes.SurveyReminderRule, surveyKey string) bool {
+	if len(rule.SurveyKeys) == 0 {
+		return true
+	}
+	for _, k := range rule.SurveyKeys {
+		if k == surveyKey {
+			return true
+		}
+	}
+	return false
+}
+
+func sentReminderKey(ruleKey string, surveyKey string, validFrom int64) string {
+	return fmt.Sprintf("%s|%s|%d", ruleKey, surveyKey, validFrom)
+}
+
+// SurveyRemindersAllowed returns how many reminders can be sent to the participant now according to the throttles,
+// given the reminders sent before. -1 means no limit.
+func SurveyRemindersAllowed(c *studyTypes.SurveyReminderConfig, sent []studyTypes.SentSurveyReminder, now time.Time) int {
+	if c == nil {
+		return -1
+	}
+	loc, err := reminderLocation(c)
+	if err != nil {
+		loc = time.UTC
+	}
+	today := now.In(loc).Format(time.DateOnly)
+
+	sentToday := 0
+	for _, s := range sent {
+		if c.MinInterval > 0 && now.Sub(s.SentAt) < time.Duration(c.MinInterval)*time.Second {
+			return 0
+		}
+		if s.SentAt.In(loc).Format(time.DateOnly) == today {
+			sentToday++
+		}
+	}
+
+	allowed := -1
+	if c.MaxPerDay > 0 {
+		allowed = c.MaxPerDay - sentToday
+		if allowed < 0 {
+			allowed = 0
+		}
+	}
+	// the next reminder is only allowed after the interval
+	if c.MinInterval > 0 && (allowed < 0 || allowed > 1) {
+		allowed = 1
+	}
+	return allowed
+}
+
+// IsSurveyReminderOptOut checks if the user does not want reminders of the study
+func IsSurveyReminderOptOut(studyKey string, optOutStudies []string) bool {
+	for _, k := range optOutStudies {
+		if k == studyKey {
+			return true
+		}
+	}
+	return false
+}
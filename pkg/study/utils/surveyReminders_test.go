@@ -0,0 +1,162 @@
+package studyutils
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestValidateSurveyReminderConfig(t *testing.T) {
+	valid := &studyTypes.SurveyReminderConfig{
+		Timezone:    "Europe/Berlin",
+		QuietHours:  &studyTypes.QuietHours{Start: "22:00", End: "07:00"},
+		MinInterval: 3600,
+		MaxPerDay:   2,
+		Rules: []studyTypes.SurveyReminderRule{
+			{Key: "due", Trigger: studyTypes.SURVEY_REMINDER_TRIGGER_DUE, Offset: 86400, MessageType: "survey-reminder"},
+			{Key: "expiring", Trigger: studyTypes.SURVEY_REMINDER_TRIGGER_EXPIRING, MessageType: "survey-expiring", Channel: studyTypes.SURVEY_REMINDER_CHANNEL_SMS},
+		},
+	}
+	if err := ValidateSurveyReminderConfig(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateSurveyReminderConfig(nil); err != nil {
+		t.Errorf("unexpected error for nil config: %v", err)
+	}
+
+	rule := studyTypes.SurveyReminderRule{Key: "r", Trigger: studyTypes.SURVEY_REMINDER_TRIGGER_DUE, MessageType: "m"}
+	withRule := func(f func(r *studyTypes.SurveyReminderRule)) *studyTypes.SurveyReminderConfig {
+		r := rule
+		f(&r)
+		return &studyTypes.SurveyReminderConfig{Rules: []studyTypes.SurveyReminderRule{r}}
+	}
+	invalid := []*studyTypes.SurveyReminderConfig{
+		{Timezone: "Not/AZone"},
+		{QuietHours: &studyTypes.QuietHours{Start: "22:00", End: "22:00"}},
+		{QuietHours: &studyTypes.QuietHours{Start: "10pm", End: "07:00"}},
+		{MinInterval: -1},
+		{MaxPerDay: -1},
+		{Rules: []studyTypes.SurveyReminderRule{rule, rule}},
+		withRule(func(r *studyTypes.SurveyReminderRule) { r.Key = "" }),
+		withRule(func(r *studyTypes.SurveyReminderRule) { r.Trigger = "weekly" }),
+		withRule(func(r *studyTypes.SurveyReminderRule) { r.Offset = -1 }),
+		withRule(func(r *studyTypes.SurveyReminderRule) { r.MessageType = "" }),
+		withRule(func(r *studyTypes.SurveyReminderRule) { r.Channel = "push" }),
+	}
+	for i, c := range invalid {
+		if err := ValidateSurveyReminderConfig(c); err == nil {
+			t.Errorf("expected error for config %d", i)
+		}
+	}
+}
+
+func TestIsInQuietHours(t *testing.T) {
+	overnight := &studyTypes.SurveyReminderConfig{QuietHours: &studyTypes.QuietHours{Start: "22:00", End: "07:00"}}
+	lunch := &studyTypes.SurveyReminderConfig{QuietHours: &studyTypes.QuietHours{Start: "12:00", End: "13:00"}, Timezone: "Europe/Berlin"}
+
+	day := func(h, m int) time.Time { return time.Date(2024, 5, 15, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name   string
+		config *studyTypes.SurveyReminderConfig
+		now    time.Time
+		quiet  bool
+	}{
+		{"no config", nil, day(23, 0), false},
+		{"no quiet hours", &studyTypes.SurveyReminderConfig{}, day(23, 0), false},
+		{"before midnight", overnight, day(23, 0), true},
+		{"after midnight", overnight, day(6, 59), true},
+		{"end", overnight, day(7, 0), false},
+		{"day time", overnight, day(15, 0), false},
+		{"timezone", lunch, day(10, 30), true},
+		{"outside in timezone", lunch, day(12, 30), false},
+	}
+	for _, tt := range tests {
+		if got := IsInQuietHours(tt.config, tt.now); got != tt.quiet {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.quiet, got)
+		}
+	}
+}
+
+func TestDueSurveyReminders(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	ts := now.Unix()
+	config := &studyTypes.SurveyReminderConfig{
+		Rules: []studyTypes.SurveyReminderRule{
+			{Key: "due", Trigger: studyTypes.SURVEY_REMINDER_TRIGGER_DUE, Offset: 3600, MessageType: "reminder"},
+			{Key: "expiring", SurveyKeys: []string{"weekly"}, Trigger: studyTypes.SURVEY_REMINDER_TRIGGER_EXPIRING, Offset: 7200, MessageType: "expiring"},
+		},
+	}
+	surveys := []studyTypes.AssignedSurvey{
+		// open for two hours, due reminder is due
+		{SurveyKey: "intake", ValidFrom: ts - 7200},
+		// just opened, no reminder yet
+		{SurveyKey: "daily", ValidFrom: ts - 60},
+		// expires in one hour, both rules are due
+		{SurveyKey: "weekly", ValidFrom: ts - 4000, ValidUntil: ts + 3600},
+		// expired
+		{SurveyKey: "old", ValidFrom: ts - 10000, ValidUntil: ts - 1},
+		// not open yet
+		{SurveyKey: "future", ValidFrom: ts + 10000},
+	}
+
+	due := DueSurveyReminders(config, surveys, nil, now)
+	got := []string{}
+	for _, d := range due {
+		got = append(got, d.Rule.Key+"/"+d.Survey.SurveyKey)
+	}
+	expected := []string{"due/intake", "expiring/weekly", "due/weekly"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, got)
+			break
+		}
+	}
+
+	sent := []studyTypes.SentSurveyReminder{
+		{RuleKey: "due", SurveyKey: "intake", SurveyValidFrom: ts - 7200},
+		// sent for an earlier assignment of the survey
+		{RuleKey: "due", SurveyKey: "weekly", SurveyValidFrom: ts - 700000},
+	}
+	due = DueSurveyReminders(config, surveys, sent, now)
+	if len(due) != 2 || due[0].Survey.SurveyKey != "weekly" || due[1].Survey.SurveyKey != "weekly" {
+		t.Errorf("unexpected reminders after sent ones: %v", due)
+	}
+
+	if due := DueSurveyReminders(nil, surveys, nil, now); len(due) != 0 {
+		t.Errorf("expected no reminders without config, got %v", due)
+	}
+}
+
+func TestSurveyRemindersAllowed(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	sent := []studyTypes.SentSurveyReminder{
+		{SentAt: now.Add(-2 * time.Hour)},
+		{SentAt: now.Add(-26 * time.Hour)},
+	}
+
+	tests := []struct {
+		name    string
+		config  *studyTypes.SurveyReminderConfig
+		sent    []studyTypes.SentSurveyReminder
+		allowed int
+	}{
+		{"no config", nil, sent, -1},
+		{"no throttles", &studyTypes.SurveyReminderConfig{}, sent, -1},
+		{"within min interval", &studyTypes.SurveyReminderConfig{MinInterval: 3 * 3600}, sent, 0},
+		{"after min interval", &studyTypes.SurveyReminderConfig{MinInterval: 3600}, sent, 1},
+		{"daily limit", &studyTypes.SurveyReminderConfig{MaxPerDay: 3}, sent, 2},
+		{"daily limit reached", &studyTypes.SurveyReminderConfig{MaxPerDay: 1}, sent, 0},
+		{"daily limit and interval", &studyTypes.SurveyReminderConfig{MaxPerDay: 3, MinInterval: 60}, sent, 1},
+		// 02:00 in Tokyo is the next day there
+		{"daily limit in timezone", &studyTypes.SurveyReminderConfig{MaxPerDay: 1, Timezone: "Asia/Tokyo"}, []studyTypes.SentSurveyReminder{{SentAt: time.Date(2024, 5, 14, 14, 0, 0, 0, time.UTC)}}, 1},
+	}
+	for _, tt := range tests {
+		if got := SurveyRemindersAllowed(tt.config, tt.sent, now); got != tt.allowed {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.allowed, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package study
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// isEnrollmentCapReached checks if the study has as many active participants as its enrollment cap allows. The count
+// is not reserved, concurrent enrollments can exceed the cap slightly.
+func isEnrollmentCapReached(instanceID string, study studyTypes.Study) (bool, error) {
+	if study.Configs.EnrollmentCap == nil {
+		return false, nil
+	}
+	activeCount, err := countActiveParticipants(instanceID, study.Key)
+	if err != nil {
+		slog.Error("Error counting active participants", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+		return false, err
+	}
+	return studyUtils.FreeEnrollmentSlots(study.Configs.EnrollmentCap, activeCount) == 0, nil
+}
+
+// synthetic-monitoring participants do not take a slot
+func countActiveParticipants(instanceID string, studyKey string) (int64, error) {
+	return studyDBService.GetParticipantCount(instanceID, studyKey, studyDB.ExcludeSynthetic(bson.M{
+		"studyStatus": studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
+	}))
+}
+
+// PromoteFromWaitingList activates participants of the waiting list while the study has free slots. The entry rules
+// run for each promoted participant, and the promotion message of the cap is scheduled for the messaging job.
+// Returns the number of promoted participants.
+func PromoteFromWaitingList(ctx context.Context, instanceID string, study studyTypes.Study) (int, error) {
+	enrollmentCap := study.Configs.EnrollmentCap
+	if enrollmentCap == nil {
+		return 0, nil
+	}
+
+	activeCount, err := countActiveParticipants(instanceID, study.Key)
+	if err != nil {
+		return 0, err
+	}
+	slots := studyUtils.FreeEnrollmentSlots(enrollmentCap, activeCount)
+	if slots == 0 {
+		return 0, nil
+	}
+
+	participants, err := studyDBService.GetWaitingListParticipants(instanceID, study.Key, slots, enrollmentCap.PromotionOrder == studyTypes.WAITING_LIST_PROMOTION_ORDER_RANDOM)
+	if err != nil {
+		return 0, err
+	}
+
+	promoted := 0
+	for _, p := range participants {
+		if err := promoteParticipant(ctx, instanceID, study, p); err != nil {
+			slog.Error("Error promoting participant from the waiting list", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+			continue
+		}
+		promoted += 1
+	}
+	return promoted, nil
+}
+
+func promoteParticipant(ctx context.Context, instanceID string, study studyTypes.Study, pState studyTypes.Participant) error {
+	confidentialID, err := ComputeConfidentialIDForParticipant(study, pState.ParticipantID)
+	if err != nil {
+		return err
+	}
+
+	pState.StudyStatus = studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE
+	// the participant enters the study now, with the same day resolution as a direct entry
+	pState.EnteredAt = time.Now().Truncate(24 * time.Hour).Add(12 * time.Hour).Unix()
+
+	currentEvent := studyengine.StudyEvent{
+		RequestID:                             requestid.FromContext(ctx),
+		Type:                                  studyengine.STUDY_EVENT_TYPE_ENTER,
+		InstanceID:                            instanceID,
+		StudyKey:                              study.Key,
+		ParticipantIDForConfidentialResponses: confidentialID,
+	}
+	actionResult, err := getAndPerformStudyRules(instanceID, study.Key, pState, currentEvent)
+	if err != nil {
+		return err
+	}
+
+	if messageType := study.Configs.EnrollmentCap.PromotionMessageType; messageType != "" {
+		actionResult.PState.Messages = append(actionResult.PState.Messages, studyTypes.ParticipantMessage{
+			ID:           primitive.NewObjectID().Hex(),
+			Type:         messageType,
+			ScheduledFor: time.Now().Unix(),
+		})
+	}
+
+	if _, err := saveParticipantState(instanceID, study.Key, studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST, actionResult.PState); err != nil {
+		return err
+	}
+	saveReports(instanceID, study.Key, actionResult.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_ENTER)
+	return nil
+}
@@ -0,0 +1,206 @@
+package taskrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency  = 10
+	defaultQueueSize    = 1000
+	defaultMaxAttempts  = 3
+	defaultRetryBackoff = time.Second
+)
+
+var (
+	ErrQueueFull = errors.New("task queue is full")
+	ErrClosed    = errors.New("task runner is shut down")
+)
+
+// Config of the runner in service config files
+type Config struct {
+	// number of tasks running at the same time, 10 if not set
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+	// tasks waiting for a free slot, further tasks are rejected, 1000 if not set
+	QueueSize int `json:"queue_size" yaml:"queue_size"`
+	// attempts of a failing task including the first one, 3 if not set
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+	// delay before the first retry, doubled for each further attempt, 1s if not set
+	RetryBackoff time.Duration `json:"retry_backoff" yaml:"retry_backoff"`
+}
+
+// Task is retried if it returns an error, unless the error is marked as permanent. The context is cancelled if the
+// runner is not drained before the shutdown deadline.
+type Task func(ctx context.Context) error
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that is not retried, e.g. if the task is not idempotent
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+type queuedTask struct {
+	name string
+	run  Task
+}
+
+// Runner executes fire-and-forget work of request handlers with bounded concurrency, panic recovery and retries
+type Runner struct {
+	config Config
+	queue  chan queuedTask
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// New starts the workers of the runner, stop them with Shutdown
+func New(config Config) *Runner {
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Runner{
+		config: config,
+		queue:  make(chan queuedTask, config.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := 0; i < config.Concurrency; i++ {
+		r.workers.Add(1)
+		go r.work()
+	}
+	return r
+}
+
+// Submit queues the task without blocking the caller
+func (r *Runner) Submit(name string, task Task) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return ErrClosed
+	}
+	select {
+	case r.queue <- queuedTask{name: name, run: task}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Go submits the task and logs if it is rejected
+func (r *Runner) Go(name string, task Task) {
+	if err := r.Submit(name, task); err != nil {
+		slog.Error("background task rejected", slog.String("task", name), slog.String("error", err.Error()))
+	}
+}
+
+// Pending returns the number of queued tasks not started yet
+func (r *Runner) Pending() int {
+	return len(r.queue)
+}
+
+// Shutdown rejects new tasks and waits until the queued and running tasks are finished. If the context is done first,
+// the context of the running tasks is cancelled, queued tasks are dropped and the context error is returned.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work() {
+	defer r.workers.Done()
+	for task := range r.queue {
+		if r.ctx.Err() != nil {
+			slog.Error("background task dropped on shutdown", slog.String("task", task.name))
+			continue
+		}
+		r.runWithRetries(task)
+	}
+}
+
+func (r *Runner) runWithRetries(task queuedTask) {
+	backoff := r.config.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := r.runOnce(task)
+		if err == nil {
+			return
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= r.config.MaxAttempts {
+			slog.Error("background task failed", slog.String("task", task.name), slog.Int("attempts", attempt), slog.String("error", err.Error()))
+			return
+		}
+		slog.Warn("background task failed, retrying", slog.String("task", task.name), slog.Int("attempt", attempt), slog.Duration("backoff", backoff), slog.String("error", err.Error()))
+
+		select {
+		case <-time.After(backoff):
+		case <-r.ctx.Done():
+			slog.Error("background task cancelled on shutdown", slog.String("task", task.name), slog.Int("attempts", attempt))
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// runOnce recovers panics of the task, they are not retried
+func (r *Runner) runOnce(task queuedTask) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Error("background task panicked", slog.String("task", task.name), slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+			err = Permanent(fmt.Errorf("panic: %v", p))
+		}
+	}()
+	return task.run(r.ctx)
+}
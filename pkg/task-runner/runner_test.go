@@ -0,0 +1,123 @@
+package taskrunner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	t.Run("limits concurrency", func(t *testing.T) {
+		r := New(Config{Concurrency: 2})
+		var running, maxRunning int32
+		for i := 0; i < 10; i++ {
+			err := r.Submit("task", func(ctx context.Context) error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if maxRunning > 2 {
+			t.Errorf("too many tasks at the same time: %d", maxRunning)
+		}
+	})
+
+	t.Run("retries failed tasks", func(t *testing.T) {
+		r := New(Config{MaxAttempts: 3, RetryBackoff: time.Millisecond})
+		var attempts int32
+		_ = r.Submit("task", func(ctx context.Context) error {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				return errors.New("temporary")
+			}
+			return nil
+		})
+		_ = r.Shutdown(context.Background())
+		if attempts != 3 {
+			t.Errorf("unexpected attempts: %d", attempts)
+		}
+	})
+
+	t.Run("does not retry permanent errors and panics", func(t *testing.T) {
+		r := New(Config{MaxAttempts: 3, RetryBackoff: time.Millisecond})
+		var permanentAttempts, panicAttempts int32
+		_ = r.Submit("permanent", func(ctx context.Context) error {
+			atomic.AddInt32(&permanentAttempts, 1)
+			return Permanent(errors.New("invalid"))
+		})
+		_ = r.Submit("panic", func(ctx context.Context) error {
+			atomic.AddInt32(&panicAttempts, 1)
+			panic("boom")
+		})
+		_ = r.Shutdown(context.Background())
+		if permanentAttempts != 1 || panicAttempts != 1 {
+			t.Errorf("unexpected attempts: %d %d", permanentAttempts, panicAttempts)
+		}
+	})
+
+	t.Run("rejects tasks if queue is full or closed", func(t *testing.T) {
+		r := New(Config{Concurrency: 1, QueueSize: 1})
+		release := make(chan struct{})
+		started := make(chan struct{})
+		_ = r.Submit("blocking", func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+		<-started
+		if err := r.Submit("queued", func(ctx context.Context) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Submit("rejected", func(ctx context.Context) error { return nil }); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("expected full queue, got %v", err)
+		}
+		close(release)
+		_ = r.Shutdown(context.Background())
+		if err := r.Submit("late", func(ctx context.Context) error { return nil }); !errors.Is(err, ErrClosed) {
+			t.Errorf("expected closed runner, got %v", err)
+		}
+	})
+
+	t.Run("shutdown cancels tasks after deadline", func(t *testing.T) {
+		r := New(Config{Concurrency: 1})
+		var mu sync.Mutex
+		cancelled := false
+		started := make(chan struct{})
+		_ = r.Submit("long", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			mu.Lock()
+			cancelled = true
+			mu.Unlock()
+			return nil
+		})
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := r.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline error, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		if !cancelled {
+			t.Error("task context not cancelled")
+		}
+	})
+}
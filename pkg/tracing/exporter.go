@@ -0,0 +1,264 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultExportInterval = 5 * time.Second
+	defaultExportTimeout  = 10 * time.Second
+	maxQueueSize          = 2048
+	maxExportBatchSize    = 512
+
+	instrumentationScope = "github.com/case-framework/case-backend/pkg/tracing"
+)
+
+// exporter queues ended spans and posts them in batches, spans are dropped if the queue is full
+type exporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	interval    time.Duration
+	client      *http.Client
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newExporter(config Config, serviceName string) *exporter {
+	interval := config.ExportInterval
+	if interval <= 0 {
+		interval = defaultExportInterval
+	}
+	timeout := config.ExportTimeout
+	if timeout <= 0 {
+		timeout = defaultExportTimeout
+	}
+	return &exporter{
+		url:         strings.TrimSuffix(config.OTLPEndpoint, "/") + "/v1/traces",
+		headers:     config.OTLPHeaders,
+		serviceName: serviceName,
+		interval:    interval,
+		// export requests must not be traced themselves
+		client: &http.Client{Timeout: timeout},
+		flush:  make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (e *exporter) add(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.queue) >= maxQueueSize {
+		e.dropped += 1
+		return
+	}
+	e.queue = append(e.queue, span)
+	if len(e.queue) >= maxExportBatchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.exportAll(context.Background())
+		case <-e.flush:
+			e.exportAll(context.Background())
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// shutdown stops the export loop and exports the remaining spans
+func (e *exporter) shutdown(ctx context.Context) error {
+	close(e.stop)
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.exportAll(ctx)
+}
+
+func (e *exporter) exportAll(ctx context.Context) error {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), maxExportBatchSize)
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		dropped := e.dropped
+		e.dropped = 0
+		e.mu.Unlock()
+
+		if dropped > 0 {
+			slog.Warn("tracing queue full, spans dropped", slog.Int("count", dropped))
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.export(ctx, batch); err != nil {
+			slog.Error("failed to export spans", slog.Int("count", len(batch)), slog.String("error", err.Error()))
+			return err
+		}
+	}
+}
+
+func (e *exporter) export(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(encodeSpans(e.serviceName, spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP JSON encoding, IDs are hex strings and 64 bit integers are decimal strings
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func encodeSpans(serviceName string, spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           s.spanContext.TraceID.String(),
+			SpanID:            s.spanContext.SpanID.String(),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: formatUnixNano(s.start),
+			EndTimeUnixNano:   formatUnixNano(s.end),
+			Attributes:        encodeAttributes(s.attributes),
+			Status:            otlpStatus{Code: s.statusCode, Message: s.statusMessage},
+		}
+		s.mu.Unlock()
+		if s.parentSpanID.IsValid() {
+			span.ParentSpanID = s.parentSpanID.String()
+		}
+		encoded = append(encoded, span)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes([]Attribute{String("service.name", serviceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: instrumentationScope}, Spans: encoded}},
+	}}}
+}
+
+func encodeAttributes(attributes []Attribute) []otlpKeyValue {
+	encoded := make([]otlpKeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		var value otlpValue
+		switch v := a.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int:
+			s := fmt.Sprint(v)
+			value.IntValue = &s
+		case int64:
+			s := fmt.Sprint(v)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		encoded = append(encoded, otlpKeyValue{Key: a.Key, Value: value})
+	}
+	return encoded
+}
@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const TRACEPARENT_HEADER = "traceparent"
+
+// Inject writes the traceparent header of the current span, nothing is written without a span
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	header.Set(TRACEPARENT_HEADER, "00-"+sc.TraceID.String()+"-"+sc.SpanID.String()+"-"+flags)
+}
+
+// Extract parses the traceparent header, invalid headers are ignored
+func Extract(header http.Header) (SpanContext, bool) {
+	value := strings.TrimSpace(header.Get(TRACEPARENT_HEADER))
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	// future versions may append fields, version 00 has exactly four
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil || !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags&1 == 1
+	sc.Remote = true
+	return sc, true
+}
+
+// Middleware creates a server span per request, continuing the trace of the caller if it sent a traceparent header.
+// Spans are named by the route pattern, so that IDs in the path do not create new span names.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Enabled() {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		if parent, ok := Extract(c.Request.Header); ok {
+			ctx = ContextWithRemoteSpanContext(ctx, parent)
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := Start(ctx, c.Request.Method+" "+route, SPAN_KIND_SERVER)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(
+			String("http.request.method", c.Request.Method),
+			String("http.route", route),
+			String("url.path", c.Request.URL.Path),
+			Int("http.response.status_code", status),
+		)
+		if instanceID := c.Param("instanceID"); instanceID != "" {
+			span.SetAttributes(String("case.instance_id", instanceID))
+		}
+		if status >= 500 {
+			span.SetStatus(STATUS_CODE_ERROR, "")
+		}
+	}
+}
+
+// Transport creates client spans for outgoing requests and propagates the trace context to the called service
+type Transport struct {
+	// defaults to http.DefaultTransport
+	Base http.RoundTripper
+}
+
+// NewTransport wraps the base transport, nil uses http.DefaultTransport
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return &Transport{Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !Enabled() {
+		return base.RoundTrip(req)
+	}
+
+	ctx, span := Start(req.Context(), req.Method+" "+req.URL.Host, SPAN_KIND_CLIENT)
+	defer span.End()
+	span.SetAttributes(
+		String("http.request.method", req.Method),
+		String("server.address", req.URL.Hostname()),
+		String("url.path", req.URL.Path),
+	)
+
+	// round trippers must not modify the request of the caller
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return resp, err
+	}
+	span.SetAttributes(Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(STATUS_CODE_ERROR, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,275 @@
+// Package tracing records spans of HTTP requests, DB commands and outgoing calls and exports them to an
+// OpenTelemetry collector with OTLP over HTTP (JSON encoding). Trace context is propagated with the W3C traceparent
+// header. Tracing is disabled until Init is called with an enabled config, spans are nil then and all span methods
+// are no-ops.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	SPAN_KIND_INTERNAL = 1
+	SPAN_KIND_SERVER   = 2
+	SPAN_KIND_CLIENT   = 3
+
+	STATUS_CODE_UNSET = 0
+	STATUS_CODE_OK    = 1
+	STATUS_CODE_ERROR = 2
+)
+
+// Config of tracing in service config files
+type Config struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// reported as service.name resource attribute, defaults to the name passed to Init
+	ServiceName string `json:"service_name" yaml:"service_name"`
+	// OTLP/HTTP endpoint of the collector, e.g. http://otel-collector:4318, spans are posted to <endpoint>/v1/traces
+	OTLPEndpoint string `json:"otlp_endpoint" yaml:"otlp_endpoint"`
+	// additional headers of export requests, e.g. for authentication at a hosted collector
+	OTLPHeaders map[string]string `json:"otlp_headers" yaml:"otlp_headers"`
+	// ratio of traces recorded if the caller did not decide, between 0 and 1, 0 records all traces
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio"`
+	// spans are exported in batches in this interval, defaults to 5s
+	ExportInterval time.Duration `json:"export_interval" yaml:"export_interval"`
+	// timeout of export requests, defaults to 10s
+	ExportTimeout time.Duration `json:"export_timeout" yaml:"export_timeout"`
+}
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+func (id TraceID) IsValid() bool  { return id != TraceID{} }
+func (id SpanID) IsValid() bool   { return id != SpanID{} }
+
+// SpanContext identifies a span across process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	// received from the caller, not started in this process
+	Remote bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Attribute values are string, bool, int, int64 or float64
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span is a timed operation of a trace. Unsampled spans only carry their context for propagation.
+type Span struct {
+	tracer       *Tracer
+	name         string
+	kind         int
+	spanContext  SpanContext
+	parentSpanID SpanID
+	start        time.Time
+
+	mu            sync.Mutex
+	end           time.Time
+	ended         bool
+	attributes    []Attribute
+	statusCode    int
+	statusMessage string
+}
+
+// Tracer creates spans and passes the sampled ones to the exporter
+type Tracer struct {
+	serviceName string
+	sampleRatio float64
+	exporter    *exporter
+}
+
+var globalTracer atomic.Pointer[Tracer]
+
+// Init enables tracing for the process. The returned function flushes the pending spans and must be called before
+// the process exits.
+func Init(config Config, defaultServiceName string) (func(ctx context.Context) error, error) {
+	noop := func(ctx context.Context) error { return nil }
+	if !config.Enabled {
+		return noop, nil
+	}
+	if config.OTLPEndpoint == "" {
+		return noop, errors.New("tracing enabled without otlp endpoint")
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return noop, fmt.Errorf("sample ratio %v out of range [0, 1]", config.SampleRatio)
+	}
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	t := &Tracer{
+		serviceName: serviceName,
+		sampleRatio: config.SampleRatio,
+		exporter:    newExporter(config, serviceName),
+	}
+	go t.exporter.run()
+	globalTracer.Store(t)
+
+	return func(ctx context.Context) error {
+		globalTracer.CompareAndSwap(t, nil)
+		return t.exporter.shutdown(ctx)
+	}, nil
+}
+
+// Enabled is false if Init was not called with an enabled config
+func Enabled() bool {
+	return globalTracer.Load() != nil
+}
+
+type spanContextKey struct{}
+
+// Start creates a span as child of the span in the context, or as a new trace if there is none. Returns a nil span
+// if tracing is disabled.
+func Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	t := globalTracer.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	return t.start(ctx, name, kind, SpanContextFromContext(ctx))
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind int, parent SpanContext) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+	span.spanContext.SpanID = newSpanID()
+	if parent.IsValid() {
+		span.spanContext.TraceID = parent.TraceID
+		span.spanContext.Sampled = parent.Sampled
+		span.parentSpanID = parent.SpanID
+	} else {
+		span.spanContext.TraceID = newTraceID()
+		span.spanContext.Sampled = t.shouldSample(span.spanContext.TraceID)
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// shouldSample decides on the trace ID, so that all services with the same ratio record the same traces
+func (t *Tracer) shouldSample(traceID TraceID) bool {
+	if t.sampleRatio == 0 || t.sampleRatio >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(traceID[8:])>>1) < t.sampleRatio*float64(uint64(1)<<63)
+}
+
+// SpanFromContext returns nil if the context has no span of this process
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the context of the current span or of the remote parent in the context
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	switch v := ctx.Value(spanContextKey{}).(type) {
+	case *Span:
+		return v.spanContext
+	case SpanContext:
+		return v
+	}
+	return SpanContext{}
+}
+
+// ContextWithRemoteSpanContext sets the parent of spans started with the context
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.spanContext
+}
+
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil || !s.spanContext.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// SetError marks the span as failed, nil errors are ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetStatus(STATUS_CODE_ERROR, err.Error())
+}
+
+func (s *Span) SetStatus(code int, message string) {
+	if s == nil || !s.spanContext.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = code
+	s.statusMessage = message
+}
+
+// End records the end time and exports sampled spans, further calls are ignored
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.spanContext.Sampled {
+		s.tracer.exporter.add(s)
+	}
+}
+
+func String(key string, value string) Attribute { return Attribute{Key: key, Value: value} }
+func Int(key string, value int) Attribute       { return Attribute{Key: key, Value: value} }
+func Int64(key string, value int64) Attribute   { return Attribute{Key: key, Value: value} }
+func Bool(key string, value bool) Attribute     { return Attribute{Key: key, Value: value} }
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func formatUnixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
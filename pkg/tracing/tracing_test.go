@@ -0,0 +1,217 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collector records the requests of the exporter
+type collector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+	headers  []http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header.Clone())
+}
+
+func (c *collector) spans() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spans := []otlpSpan{}
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func initTestTracing(t *testing.T, sampleRatio float64) (*collector, func()) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	shutdown, err := Init(Config{
+		Enabled:        true,
+		OTLPEndpoint:   server.URL,
+		OTLPHeaders:    map[string]string{"Authorization": "Bearer token"},
+		SampleRatio:    sampleRatio,
+		ExportInterval: time.Hour,
+	}, "test-service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, func() {
+		if err := shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+		server.Close()
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "op", SPAN_KIND_INTERNAL)
+	if span != nil {
+		t.Fatal("expected nil span without Init")
+	}
+	// methods of nil spans are no-ops
+	span.SetAttributes(String("k", "v"))
+	span.SetError(errors.New("failed"))
+	span.End()
+	if SpanFromContext(ctx) != nil {
+		t.Error("unexpected span in context")
+	}
+
+	if _, err := Init(Config{Enabled: true}, "test"); err == nil {
+		t.Error("expected error without endpoint")
+	}
+}
+
+func TestExport(t *testing.T) {
+	c, shutdown := initTestTracing(t, 0)
+
+	ctx, parent := Start(context.Background(), "parent", SPAN_KIND_SERVER)
+	_, child := Start(ctx, "child", SPAN_KIND_CLIENT)
+	child.SetAttributes(String("db.system", "mongodb"), Int("count", 3), Bool("ok", true))
+	child.SetError(errors.New("failed"))
+	child.End()
+	parent.End()
+	parent.End()
+
+	shutdown()
+
+	spans := c.spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.TraceID != parentSpan.TraceID || childSpan.ParentSpanID != parentSpan.SpanID {
+		t.Errorf("child not linked to parent: %+v %+v", childSpan, parentSpan)
+	}
+	if parentSpan.ParentSpanID != "" {
+		t.Error("root span with parent")
+	}
+	if childSpan.Status.Code != STATUS_CODE_ERROR || childSpan.Status.Message != "failed" {
+		t.Errorf("unexpected status %+v", childSpan.Status)
+	}
+	if len(childSpan.Attributes) != 3 || *childSpan.Attributes[1].Value.IntValue != "3" {
+		t.Errorf("unexpected attributes %+v", childSpan.Attributes)
+	}
+	if c.headers[0].Get("Authorization") != "Bearer token" {
+		t.Error("export headers not set")
+	}
+	serviceName := c.requests[0].ResourceSpans[0].Resource.Attributes[0]
+	if serviceName.Key != "service.name" || *serviceName.Value.StringValue != "test-service" {
+		t.Errorf("unexpected resource %+v", serviceName)
+	}
+	if Enabled() {
+		t.Error("tracing still enabled after shutdown")
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	header := http.Header{}
+	header.Set(TRACEPARENT_HEADER, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	sc, ok := Extract(header)
+	if !ok || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+
+	out := http.Header{}
+	Inject(ContextWithRemoteSpanContext(context.Background(), sc), out)
+	if out.Get(TRACEPARENT_HEADER) != header.Get(TRACEPARENT_HEADER) {
+		t.Errorf("unexpected header %s", out.Get(TRACEPARENT_HEADER))
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		header.Set(TRACEPARENT_HEADER, invalid)
+		if _, ok := Extract(header); ok {
+			t.Errorf("accepted invalid header %q", invalid)
+		}
+	}
+}
+
+func TestSampling(t *testing.T) {
+	tracer := &Tracer{sampleRatio: 0.5}
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if tracer.shouldSample(newTraceID()) {
+			sampled += 1
+		}
+	}
+	if sampled < 400 || sampled > 600 {
+		t.Errorf("unexpected number of sampled traces %d", sampled)
+	}
+}
+
+func TestMiddlewareAndTransport(t *testing.T) {
+	var received string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(TRACEPARENT_HEADER)
+	}))
+	defer downstream.Close()
+
+	c, shutdown := initTestTracing(t, 0)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/v1/:instanceID/items", func(ctx *gin.Context) {
+		client := &http.Client{Transport: NewTransport(nil)}
+		req, _ := http.NewRequestWithContext(ctx.Request.Context(), http.MethodGet, downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			ctx.Status(http.StatusBadGateway)
+			return
+		}
+		resp.Body.Close()
+		ctx.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/i1/items", nil)
+	req.Header.Set(TRACEPARENT_HEADER, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	shutdown()
+
+	spans := c.spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	clientSpan, serverSpan := spans[0], spans[1]
+	if serverSpan.Name != "GET /v1/:instanceID/items" || serverSpan.Kind != SPAN_KIND_SERVER {
+		t.Errorf("unexpected server span %+v", serverSpan)
+	}
+	if serverSpan.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || serverSpan.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("trace of the caller not continued: %+v", serverSpan)
+	}
+	if clientSpan.ParentSpanID != serverSpan.SpanID || clientSpan.Kind != SPAN_KIND_CLIENT {
+		t.Errorf("unexpected client span %+v", clientSpan)
+	}
+	if received != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+clientSpan.SpanID+"-01" {
+		t.Errorf("unexpected propagated header %s", received)
+	}
+}
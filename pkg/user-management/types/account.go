@@ -9,6 +9,9 @@ type Account struct {
 	VerificationCode   VerificationCode `bson:"verificationCode" json:"verificationCode"`
 	PreferredLanguage  string           `bson:"preferredLanguage" json:"preferredLanguage"`
 
+	// Set for accounts created without password, until the password is chosen via the setup link
+	SetupPendingSince int64 `bson:"setupPendingSince,omitempty" json:"setupPendingSince,omitempty"`
+
 	// Rate limiting
 	FailedLoginAttempts   []int64 `bson:"failedLoginAttempts" json:"failedLoginAttempts"`
 	PasswordResetTriggers []int64 `bson:"passwordResetTriggers" json:"passwordResetTriggers"`
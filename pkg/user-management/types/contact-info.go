@@ -9,4 +9,8 @@ type ContactInfo struct {
 	ConfirmationLinkSentAt int64              `bson:"confirmationLinkSentAt" json:"confirmationLinkSentAt"`
 	Email                  string             `bson:"email" json:"email"`
 	Phone                  string             `bson:"phone" json:"phone"`
+
+	// Set when the email provider reported a hard bounce or complaint for the address
+	UndeliverableSince  int64  `bson:"undeliverableSince,omitempty" json:"undeliverableSince,omitempty"`
+	UndeliverableReason string `bson:"undeliverableReason,omitempty" json:"undeliverableReason,omitempty"`
 }
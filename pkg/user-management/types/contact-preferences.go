@@ -5,4 +5,10 @@ type ContactPreferences struct {
 	SendNewsletterTo              []string `bson:"sendNewsletterTo" json:"sendNewsletterTo"`
 	SubscribedToWeekly            bool     `bson:"subscribedToWeekly" json:"subscribedToWeekly"`
 	ReceiveWeeklyMessageDayOfWeek int32    `bson:"receiveWeeklyMessageDayOfWeek" json:"receiveWeeklyMessageDayOfWeek"`
+	// "daily", "weekly" or "none", empty to use the default of the instance. Weekly digests are sent on ReceiveWeeklyMessageDayOfWeek.
+	DigestFrequency string `bson:"digestFrequency,omitempty" json:"digestFrequency,omitempty"`
+	// Studies whose tasks are not included in the digest
+	DigestOptOutStudies []string `bson:"digestOptOutStudies,omitempty" json:"digestOptOutStudies,omitempty"`
+	// Studies that do not send survey reminders to the user
+	SurveyReminderOptOutStudies []string `bson:"surveyReminderOptOutStudies,omitempty" json:"surveyReminderOptOutStudies,omitempty"`
 }
@@ -14,6 +14,7 @@ const (
 	TOKEN_PURPOSE_UNSUBSCRIBE_NEWSLETTER     = "unsubscribe-newsletter"
 	TOKEN_PURPOSE_RESTORE_ACCOUNT_ID         = "restore_account_id"
 	TOKEN_PURPOSE_INACTIVE_USER_NOTIFICATION = "inactive-user-notification"
+	TOKEN_PURPOSE_ACCOUNT_SETUP              = "account-setup"
 )
 
 type TempToken struct {
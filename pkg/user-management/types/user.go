@@ -9,6 +9,8 @@ import (
 
 const ACCOUNT_TYPE_EMAIL = "email"
 
+const ANONYMIZED_ACCOUNT_ID_PREFIX = "anonymized-"
+
 type User struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 
@@ -194,6 +196,31 @@ func (u *User) RemoveProfile(id string) error {
 	return errors.New("profile with given ID not found")
 }
 
+// Anonymize removes directly identifying fields from the user. Profiles are kept, so pseudonymous study data remains linked.
+func (u *User) Anonymize() {
+	u.Account.AccountID = ANONYMIZED_ACCOUNT_ID_PREFIX + u.ID.Hex()
+	u.Account.Password = ""
+	u.Account.VerificationCode = VerificationCode{}
+	u.Account.FailedLoginAttempts = []int64{}
+	u.Account.PasswordResetTriggers = []int64{}
+	u.ContactInfos = []ContactInfo{}
+	u.ContactPreferences = ContactPreferences{
+		SendNewsletterTo:              []string{},
+		ReceiveWeeklyMessageDayOfWeek: -1,
+	}
+	u.Timestamps.AnonymizedAt = time.Now().Unix()
+}
+
+// IsSetupPending returns true if the account was created without password and the setup is not completed yet
+func (u User) IsSetupPending() bool {
+	return u.Account.SetupPendingSince > 0
+}
+
+// IsAnonymized returns true if the user's identifying fields have been removed
+func (u User) IsAnonymized() bool {
+	return u.Timestamps.AnonymizedAt > 0
+}
+
 type Timestamps struct {
 	LastTokenRefresh        int64 `bson:"lastTokenRefresh" json:"lastTokenRefresh"`
 	LastLogin               int64 `bson:"lastLogin" json:"lastLogin"`
@@ -202,4 +229,6 @@ type Timestamps struct {
 	LastPasswordChange      int64 `bson:"lastPasswordChange" json:"lastPasswordChange"`
 	ReminderToConfirmSentAt int64 `bson:"reminderToConfirmSentAt" json:"reminderToConfirmSentAt"`
 	MarkedForDeletion       int64 `bson:"markedForDeletion" json:"markedForDeletion"`
+	AnonymizedAt            int64 `bson:"anonymizedAt,omitempty" json:"anonymizedAt,omitempty"`
+	LastDigestSentAt        int64 `bson:"lastDigestSentAt,omitempty" json:"lastDigestSentAt,omitempty"`
 }
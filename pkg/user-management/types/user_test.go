@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserAnonymize(t *testing.T) {
+	user := User{
+		ID: primitive.NewObjectID(),
+		Account: Account{
+			AccountID:             "user@example.com",
+			AccountConfirmedAt:    1,
+			Password:              "hash",
+			PreferredLanguage:     "en",
+			VerificationCode:      VerificationCode{Code: "123456"},
+			FailedLoginAttempts:   []int64{1, 2},
+			PasswordResetTriggers: []int64{3},
+		},
+		Profiles: []Profile{{ID: primitive.NewObjectID(), Alias: "main", MainProfile: true}},
+		ContactPreferences: ContactPreferences{
+			SubscribedToNewsletter:        true,
+			SendNewsletterTo:              []string{"user@example.com"},
+			ReceiveWeeklyMessageDayOfWeek: 2,
+		},
+	}
+	user.AddNewEmail("user@example.com", true)
+
+	user.Anonymize()
+
+	if user.Account.AccountID != ANONYMIZED_ACCOUNT_ID_PREFIX+user.ID.Hex() {
+		t.Errorf("unexpected account ID: %s", user.Account.AccountID)
+	}
+	if user.Account.Password != "" || user.Account.VerificationCode.Code != "" {
+		t.Error("credentials not removed")
+	}
+	if len(user.Account.FailedLoginAttempts) != 0 || len(user.Account.PasswordResetTriggers) != 0 {
+		t.Error("rate limiting timestamps not removed")
+	}
+	if len(user.ContactInfos) != 0 {
+		t.Errorf("contact infos not removed: %v", user.ContactInfos)
+	}
+	if len(user.ContactPreferences.SendNewsletterTo) != 0 || user.ContactPreferences.SubscribedToNewsletter || user.ContactPreferences.ReceiveWeeklyMessageDayOfWeek != -1 {
+		t.Errorf("contact preferences not reset: %v", user.ContactPreferences)
+	}
+	if !user.IsAnonymized() {
+		t.Error("user should be marked as anonymized")
+	}
+	// profiles are kept, the study data stays linked to them
+	if len(user.Profiles) != 1 || user.Account.PreferredLanguage != "en" || user.Account.AccountConfirmedAt != 1 {
+		t.Errorf("fields not needed for identification should be kept: %v", user)
+	}
+}
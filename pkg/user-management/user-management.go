@@ -196,19 +196,95 @@ func DeleteUser(
 		return err
 	}
 
-	// delete all renew tokens
-	_, err = pUserDBService.DeleteRenewTokensForUser(instanceID, userID)
+	// delete account with its renew tokens and OTPs
+	err = pUserDBService.DeleteUserAccount(instanceID, userID)
 	if err != nil {
 		return err
 	}
 
-	// delete account
-	err = pUserDBService.DeleteUser(instanceID, userID)
+	// notify user
+	err = sendEmail(user.Account.AccountID)
+	return err
+}
+
+// DeleteUsers deletes several accounts with bulk writes, e.g. for cleanup jobs. The profiles of each user are
+// reported to the study service first, users for which this fails are not deleted. sendEmail is called for each user
+// after the accounts are removed, its errors are logged. Returns the number of deleted accounts.
+func DeleteUsers(
+	instanceID string,
+	users []userTypes.User,
+	notifyStudyService func(instanceID string, profiles []string) error,
+	sendEmail func(user userTypes.User) error,
+	batchSize int,
+) (int64, error) {
+	toDelete := make([]userTypes.User, 0, len(users))
+	userIDs := make([]string, 0, len(users))
+	for _, user := range users {
+		profileIDs := make([]string, len(user.Profiles))
+		for i, profile := range user.Profiles {
+			profileIDs[i] = profile.ID.Hex()
+		}
+
+		if err := notifyStudyService(instanceID, profileIDs); err != nil {
+			slog.Error("failed to notify study service, user not deleted", slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+		toDelete = append(toDelete, user)
+		userIDs = append(userIDs, user.ID.Hex())
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	// delete all temp tokens
+	err := globalInfosDBServices.DeleteAllTempTokensForUsers(instanceID, userIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	// delete accounts with their renew tokens and OTPs
+	count, err := pUserDBService.DeleteUserAccounts(instanceID, userIDs, batchSize)
+	if err != nil {
+		return count, err
+	}
+
+	// notify users
+	for _, user := range toDelete {
+		if err := sendEmail(user); err != nil {
+			slog.Error("failed to notify deleted user", slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()), slog.String("error", err.Error()))
+		}
+	}
+	return count, nil
+}
+
+// AnonymizeUser strips contact infos and credentials from the user and removes all tokens of the account
+func AnonymizeUser(instanceID, userID string) error {
+	user, err := pUserDBService.GetUser(instanceID, userID)
 	if err != nil {
 		return err
 	}
+	if user.IsAnonymized() {
+		return errors.New("user already anonymized")
+	}
 
-	// notify user
-	err = sendEmail(user.Account.AccountID)
-	return err
+	user.Anonymize()
+	_, err = pUserDBService.ReplaceUser(instanceID, user)
+	if err != nil {
+		return err
+	}
+
+	// delete all temp tokens
+	err = globalInfosDBServices.DeleteAllTempTokenForUser(instanceID, userID, "")
+	if err != nil {
+		return err
+	}
+
+	// delete all renew tokens
+	_, err = pUserDBService.DeleteRenewTokensForUser(instanceID, userID)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("user anonymized", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.Int64("anonymizedAt", user.Timestamps.AnonymizedAt))
+	return nil
 }
@@ -0,0 +1,160 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five field cron expression
+type CronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	// as in standard cron, if both day fields are restricted, either of them has to match
+	domRestricted bool
+	dowRestricted bool
+}
+
+type cronField struct {
+	min int
+	max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week, 0 = Sunday
+}
+
+// ParseCron parses expressions like "0 9 * * 1-5" or "*/30 8-18 1,15 * *"
+func ParseCron(expr string) (*CronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields", len(cronFields))
+	}
+
+	values := make([]map[int]bool, len(parts))
+	for i, part := range parts {
+		v, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+		values[i] = v
+	}
+
+	// allow 7 as Sunday
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return &CronSchedule{
+		minutes:       values[0],
+		hours:         values[1],
+		daysOfMonth:   values[2],
+		months:        values[3],
+		daysOfWeek:    values[4],
+		domRestricted: !isCronWildcard(parts[2]),
+		dowRestricted: !isCronWildcard(parts[4]),
+	}, nil
+}
+
+// isCronWildcard reports whether the field matches every value, a step of one does not restrict it
+func isCronWildcard(field string) bool {
+	return field == "*" || field == "*/1"
+}
+
+func parseCronField(field string, bounds cronField) (map[int]bool, error) {
+	values := map[int]bool{}
+	max := bounds.max
+	if bounds.max == 6 {
+		max = 7
+	}
+
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if rangePart, stepPart, found := strings.Cut(item, "/"); found {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s < 1 {
+				return nil, fmt.Errorf("invalid step: %s", stepPart)
+			}
+			step = s
+			item = rangePart
+		}
+
+		start, end := bounds.min, max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			from, to, _ := strings.Cut(item, "-")
+			var err error
+			if start, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value: %s", from)
+			}
+			if end, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid value: %s", to)
+			}
+		default:
+			v, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value: %s", item)
+			}
+			start, end = v, v
+			if step > 1 {
+				end = max
+			}
+		}
+
+		if start < bounds.min || end > max || start > end {
+			return nil, fmt.Errorf("value out of range: %s", item)
+		}
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.daysOfMonth[t.Day()]
+	dow := s.daysOfWeek[int(t.Weekday())]
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Next returns the first matching time strictly after t, in the location of t
+func (s *CronSchedule) Next(t time.Time) (time.Time, error) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// search limit of five years covers all valid expressions, e.g. February 29th
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New("no matching time found for cron expression")
+}
@@ -0,0 +1,76 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{"* * * * *", "0 9 * * 1-5", "*/15 8-18 1,15 * *", "30 6 * 1-3 7"}
+	for _, expr := range valid {
+		if _, err := ParseCron(expr); err != nil {
+			t.Errorf("unexpected error for %s: %v", expr, err)
+		}
+	}
+
+	invalid := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "a * * * *", "5-1 * * * *"}
+	for _, expr := range invalid {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("expected error for %s", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 15, 10, 31, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 5, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2024, 5, 15, 10, 40, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		// either day field matches if both are restricted
+		{"0 8 20 * 4", time.Date(2024, 5, 16, 8, 0, 0, 0, time.UTC)},
+		// a step of one does not restrict the day field, so only the other one has to match
+		{"0 8 */1 * 1", time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)},
+		{"0 8 20 * */1", time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC)},
+		// a step of two restricts it
+		{"0 8 */2 * 1", time.Date(2024, 5, 17, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.expr, err)
+		}
+		next, err := s.Next(from)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", tt.expr, err)
+			continue
+		}
+		if !next.Equal(tt.expected) {
+			t.Errorf("unexpected next time for %s: %s, expected %s", tt.expr, next, tt.expected)
+		}
+	}
+}
+
+func TestCronNextInLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	s, _ := ParseCron("0 9 * * *")
+	next, err := s.Next(time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC).In(loc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !next.Equal(time.Date(2024, 5, 16, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next time: %s", next.UTC())
+	}
+}
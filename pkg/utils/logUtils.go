@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
 	"gopkg.in/natefinch/lumberjack.v2"
 	"gopkg.in/yaml.v2"
 )
@@ -38,6 +39,9 @@ type LoggerConfig struct {
 	IncludeBuildInfo string `json:"include_build_info" yaml:"include_build_info"` // never, always, once
 }
 
+// level of the default logger, changed with SetLogLevel when the config is reloaded
+var logLevel = new(slog.LevelVar)
+
 type CustomHandler struct {
 	slog.Handler
 	buildInfoAttrs []slog.Attr
@@ -49,7 +53,7 @@ func (h *CustomHandler) Handle(ctx context.Context, r slog.Record) error {
 }
 
 func InitLogger(
-	logLevel string,
+	level string,
 	includeSrc bool,
 	logToFile bool,
 	logFilename string,
@@ -67,8 +71,9 @@ func InitLogger(
 		buildInfoAttrs = loadBuildInfoAsSlogAttrs(buildInfoFilename, buildInfoPrefix)
 	}
 
+	SetLogLevel(level)
 	opts := &slog.HandlerOptions{
-		Level:     logLevelFromString(logLevel),
+		Level:     logLevel,
 		AddSource: includeSrc,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			if a.Key == slog.SourceKey {
@@ -93,10 +98,10 @@ func InitLogger(
 		}
 
 		w := io.MultiWriter(os.Stdout, logTarget)
-		handler := slog.NewJSONHandler(w, opts)
+		handler := requestid.Handler{Handler: slog.NewJSONHandler(w, opts)}
 		logger = slog.New(handler)
 	} else {
-		handler := slog.NewJSONHandler(os.Stdout, opts)
+		handler := requestid.Handler{Handler: slog.NewJSONHandler(os.Stdout, opts)}
 		logger = slog.New(handler)
 	}
 
@@ -118,6 +123,11 @@ func InitLogger(
 	}
 }
 
+// SetLogLevel changes the level of the logger created by InitLogger at runtime, unknown levels are treated as info
+func SetLogLevel(level string) {
+	logLevel.Set(logLevelFromString(level))
+}
+
 func logLevelFromString(level string) slog.Level {
 	switch level {
 	case "debug":
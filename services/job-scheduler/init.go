@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
+	"github.com/case-framework/case-backend/pkg/db"
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_GLOBAL_INFOS_DB_USERNAME = "GLOBAL_INFOS_DB_USERNAME"
+	ENV_GLOBAL_INFOS_DB_PASSWORD = "GLOBAL_INFOS_DB_PASSWORD"
+	ENV_ALERT_WEBHOOK_SECRET     = "ALERT_WEBHOOK_SECRET"
+	ENV_ALERT_EMAIL_API_KEY      = "ALERT_EMAIL_API_KEY"
+	ENV_ALERT_EMAIL_API_SECRET   = "ALERT_EMAIL_API_SECRET"
+	ENV_METRICS_BEARER_TOKEN     = "METRICS_BEARER_TOKEN"
+
+	// identifies the replica in the job locks, the hostname is used if not set
+	ENV_SCHEDULER_REPLICA_ID = "SCHEDULER_REPLICA_ID"
+)
+
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
+	// DB configs, job locks and the run history are stored in the global infos DB
+	DBConfigs struct {
+		GlobalInfosDB db.DBConfigYaml `json:"global_infos_db" yaml:"global_infos_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	// Registered jobs with their schedules, locks, run history and alert settings
+	Scheduler jobscheduler.Config `json:"scheduler" yaml:"scheduler"`
+
+	// Job run metrics, the port is required since the scheduler has no API
+	Metrics metrics.Config `json:"metrics" yaml:"metrics"`
+}
+
+var conf config
+
+var (
+	globalInfosDBService *globalinfosDB.GlobalInfosDBService
+	replicaID            string
+
+	secretsResolver *secrets.Resolver
+)
+
+func init() {
+	// Read config from file
+	yamlFile, err := os.ReadFile(os.Getenv(ENV_CONFIG_FILE_PATH))
+	if err != nil {
+		panic(err)
+	}
+
+	err = yaml.UnmarshalStrict(yamlFile, &conf)
+	if err != nil {
+		panic(err)
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
+	replicaID = os.Getenv(ENV_SCHEDULER_REPLICA_ID)
+	if replicaID == "" {
+		replicaID, err = os.Hostname()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// init db
+	initDBs()
+}
+
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.DB("db_configs.global_infos_db", conf.DBConfigs.GlobalInfosDB)
+	problems.Duration("scheduler.lock_ttl", conf.Scheduler.LockTTL, time.Second, 0)
+	problems.NonNegative("scheduler.output_length", conf.Scheduler.OutputLength)
+	problems.Duration("scheduler.history_retention", conf.Scheduler.HistoryRetention, time.Hour, 0)
+	problems.Duration("scheduler.shutdown_wait", conf.Scheduler.ShutdownWait, time.Second, 0)
+	// the scheduler checks jobs, schedules and alerts when it is created
+	_, err := jobscheduler.New(conf.Scheduler, nil, replicaID, nil)
+	problems.Check("scheduler", err)
+	if conf.Metrics.Enabled {
+		problems.Required("metrics.port", conf.Metrics.Port)
+	}
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config, the resolver is kept to detect rotations
+func resolveSecrets() {
+	var err error
+	secretsResolver, err = secrets.Resolve(context.Background(), conf.Secrets, &conf)
+	if err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
+func secretsOverride() {
+	// Override secrets from environment variables
+
+	if dbUsername := os.Getenv(ENV_GLOBAL_INFOS_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.GlobalInfosDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_GLOBAL_INFOS_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.GlobalInfosDB.Password = dbPassword
+	}
+
+	if secret := os.Getenv(ENV_ALERT_WEBHOOK_SECRET); secret != "" {
+		conf.Scheduler.Alerts.Webhook.Secret = secret
+	}
+
+	if apiKey := os.Getenv(ENV_ALERT_EMAIL_API_KEY); apiKey != "" {
+		conf.Scheduler.Alerts.Email.Provider.APIKey = apiKey
+	}
+
+	if apiSecret := os.Getenv(ENV_ALERT_EMAIL_API_SECRET); apiSecret != "" {
+		conf.Scheduler.Alerts.Email.Provider.APISecret = apiSecret
+	}
+
+	if token := os.Getenv(ENV_METRICS_BEARER_TOKEN); token != "" {
+		conf.Metrics.BearerToken = token
+	}
+}
+
+func initDBs() {
+	var err error
+	globalInfosDBService, err = globalinfosDB.NewGlobalInfosDBService(db.DBConfigFromYamlObj(conf.DBConfigs.GlobalInfosDB, nil))
+	if err != nil {
+		slog.Error("Error connecting to Global Infos DB", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	jobscheduler "github.com/case-framework/case-backend/pkg/job-scheduler"
+	"github.com/case-framework/case-backend/pkg/metrics"
+)
+
+func main() {
+	ctx, stop := apihelpers.ShutdownSignalContext()
+	defer stop()
+
+	scheduler, err := jobscheduler.New(conf.Scheduler, globalInfosDBService, replicaID, jobscheduler.ExecCommand)
+	if err != nil {
+		slog.Error("Invalid scheduler config", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	if conf.Metrics.Enabled {
+		if conf.Metrics.Port == "" {
+			slog.Error("metrics port is required for the job scheduler")
+			panic("metrics port missing")
+		}
+		metrics.Serve(conf.Metrics)
+	}
+
+	// rotated secrets shut the process down gracefully if restart_on_rotation is set
+	go secretsResolver.Watch(ctx, stop)
+
+	slog.Info("Starting job scheduler", slog.String("replicaID", replicaID), slog.Int("jobs", len(conf.Scheduler.Jobs)))
+	scheduler.Run(ctx)
+
+	if err := globalInfosDBService.Close(context.Background()); err != nil {
+		slog.Error("failed to close DB client", slog.String("error", err.Error()))
+	}
+	slog.Info("Job scheduler stopped")
+}
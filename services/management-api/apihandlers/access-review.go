@@ -0,0 +1,258 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+)
+
+// Where access to a study comes from
+const (
+	GRANT_PROVENANCE_INSTANCE_ADMIN          = "instance-admin"
+	GRANT_PROVENANCE_STUDY_PERMISSION        = "study-permission"
+	GRANT_PROVENANCE_ALL_STUDIES_PERMISSION  = "all-studies-permission"
+	GRANT_PROVENANCE_CONFIDENTIAL_DATA_GRANT = "confidential-access-grant"
+)
+
+type AccessGrant struct {
+	Provenance   string              `json:"provenance"`
+	PermissionID string              `json:"permissionId,omitempty"`
+	Action       string              `json:"action"`
+	Limiter      []map[string]string `json:"limiter,omitempty"`
+	// only for time limited grants
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	GrantedBy string     `json:"grantedBy,omitempty"`
+	Purpose   string     `json:"purpose,omitempty"`
+}
+
+// StudyAccessEntry is a management user or service account with access to a study
+type StudyAccessEntry struct {
+	SubjectID   string `json:"subjectId"`
+	SubjectType string `json:"subjectType"`
+	// email or username of management users, label of service accounts
+	Name    string `json:"name,omitempty"`
+	IsAdmin bool   `json:"isAdmin,omitempty"`
+	// permissions of deleted users or service accounts that were not cleaned up
+	SubjectMissing bool          `json:"subjectMissing,omitempty"`
+	Grants         []AccessGrant `json:"grants"`
+}
+
+type SubjectStudyAccess struct {
+	StudyKey string        `json:"studyKey"`
+	Grants   []AccessGrant `json:"grants"`
+}
+
+func permissionGrant(permission *muDB.Permission) AccessGrant {
+	provenance := GRANT_PROVENANCE_STUDY_PERMISSION
+	if permission.ResourceKey == pc.RESOURCE_KEY_STUDY_ALL {
+		provenance = GRANT_PROVENANCE_ALL_STUDIES_PERMISSION
+	}
+	return AccessGrant{
+		Provenance:   provenance,
+		PermissionID: permission.ID.Hex(),
+		Action:       permission.Action,
+		Limiter:      permission.Limiter,
+	}
+}
+
+func confidentialGrant(grant studyTypes.ConfidentialAccessGrant) AccessGrant {
+	expiresAt := grant.ExpiresAt
+	return AccessGrant{
+		Provenance: GRANT_PROVENANCE_CONFIDENTIAL_DATA_GRANT,
+		Action:     pc.ACTION_GET_CONFIDENTIAL_RESPONSES,
+		ExpiresAt:  &expiresAt,
+		GrantedBy:  grant.GrantedBy,
+		Purpose:    grant.Purpose,
+	}
+}
+
+func adminGrant() AccessGrant {
+	return AccessGrant{Provenance: GRANT_PROVENANCE_INSTANCE_ADMIN, Action: pc.ACTION_ALL}
+}
+
+// getStudyAccessReview lists everyone with access to the study and where the access comes from
+func (h *HttpEndpoints) getStudyAccessReview(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting study access review", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	users, err := h.muDBConn.GetAllUsers(token.InstanceID, false)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get management users", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get access review"})
+		return
+	}
+	serviceUsers, err := h.muDBConn.GetServiceUsers(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get service accounts", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get access review"})
+		return
+	}
+	permissions, err := h.muDBConn.GetPermissionsByResourceKeys(token.InstanceID, pc.RESOURCE_TYPE_STUDY, []string{studyKey, pc.RESOURCE_KEY_STUDY_ALL})
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study permissions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get access review"})
+		return
+	}
+	grants, err := h.studyDBConn.GetConfidentialAccessGrants(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get confidential access grants", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get access review"})
+		return
+	}
+
+	entries := map[string]*StudyAccessEntry{}
+	getEntry := func(subjectID string, subjectType string) *StudyAccessEntry {
+		key := subjectType + ":" + subjectID
+		if entry, ok := entries[key]; ok {
+			return entry
+		}
+		entry := &StudyAccessEntry{SubjectID: subjectID, SubjectType: subjectType, SubjectMissing: true, Grants: []AccessGrant{}}
+		entries[key] = entry
+		return entry
+	}
+
+	for _, user := range users {
+		if !user.IsAdmin {
+			continue
+		}
+		entry := getEntry(user.ID.Hex(), pc.SUBJECT_TYPE_MANAGEMENT_USER)
+		entry.Grants = append(entry.Grants, adminGrant())
+	}
+	for _, permission := range permissions {
+		entry := getEntry(permission.SubjectID, permission.SubjectType)
+		entry.Grants = append(entry.Grants, permissionGrant(permission))
+	}
+	now := time.Now()
+	for _, grant := range grants {
+		if grant.RevokedAt != nil || !grant.ExpiresAt.After(now) {
+			continue
+		}
+		entry := getEntry(grant.UserID, pc.SUBJECT_TYPE_MANAGEMENT_USER)
+		entry.Grants = append(entry.Grants, confidentialGrant(grant))
+	}
+
+	for _, user := range users {
+		if entry, ok := entries[pc.SUBJECT_TYPE_MANAGEMENT_USER+":"+user.ID.Hex()]; ok {
+			entry.Name = user.Email
+			if entry.Name == "" {
+				entry.Name = user.Username
+			}
+			entry.IsAdmin = user.IsAdmin
+			entry.SubjectMissing = false
+		}
+	}
+	for _, serviceUser := range serviceUsers {
+		if entry, ok := entries[pc.SUBJECT_TYPE_SERVICE_ACCOUNT+":"+serviceUser.ID.Hex()]; ok {
+			entry.Name = serviceUser.Label
+			entry.SubjectMissing = false
+		}
+	}
+
+	result := make([]*StudyAccessEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SubjectType != result[j].SubjectType {
+			return result[i].SubjectType < result[j].SubjectType
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{"studyKey": studyKey, "access": result})
+}
+
+func (h *HttpEndpoints) getManagementUserStudyAccess(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	userID := c.Param("userID")
+
+	slog.InfoContext(c, "getting study access of user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
+
+	user, err := h.muDBConn.GetUserByID(token.InstanceID, userID)
+	if err != nil {
+		slog.ErrorContext(c, "error retrieving user", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	h.respondWithSubjectStudyAccess(c, token, userID, pc.SUBJECT_TYPE_MANAGEMENT_USER, user.IsAdmin)
+}
+
+func (h *HttpEndpoints) getServiceAccountStudyAccess(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	serviceAccountID := c.Param("serviceAccountID")
+
+	slog.InfoContext(c, "getting study access of service account", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID))
+
+	if _, err := h.muDBConn.GetServiceUserByID(token.InstanceID, serviceAccountID); err != nil {
+		slog.ErrorContext(c, "error retrieving service account", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "service account not found"})
+		return
+	}
+	h.respondWithSubjectStudyAccess(c, token, serviceAccountID, pc.SUBJECT_TYPE_SERVICE_ACCOUNT, false)
+}
+
+// respondWithSubjectStudyAccess lists all studies the subject can access, permissions for all studies are listed
+// for every study
+func (h *HttpEndpoints) respondWithSubjectStudyAccess(c *gin.Context, token *jwthandling.ManagementUserClaims, subjectID string, subjectType string, isAdmin bool) {
+	studies, err := h.studyDBConn.GetStudies(token.InstanceID, "", true)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get studies", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study access"})
+		return
+	}
+	permissions, err := h.muDBConn.GetPermissionBySubject(token.InstanceID, subjectID, subjectType)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get permissions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study access"})
+		return
+	}
+
+	allStudiesGrants := []AccessGrant{}
+	studyGrants := map[string][]AccessGrant{}
+	for _, permission := range permissions {
+		if permission.ResourceType != pc.RESOURCE_TYPE_STUDY {
+			continue
+		}
+		if permission.ResourceKey == pc.RESOURCE_KEY_STUDY_ALL {
+			allStudiesGrants = append(allStudiesGrants, permissionGrant(permission))
+			continue
+		}
+		studyGrants[permission.ResourceKey] = append(studyGrants[permission.ResourceKey], permissionGrant(permission))
+	}
+
+	result := []SubjectStudyAccess{}
+	for _, study := range studies {
+		grants := []AccessGrant{}
+		if isAdmin {
+			grants = append(grants, adminGrant())
+		}
+		grants = append(grants, allStudiesGrants...)
+		grants = append(grants, studyGrants[study.Key]...)
+
+		if subjectType == pc.SUBJECT_TYPE_MANAGEMENT_USER {
+			confidentialGrants, err := h.studyDBConn.GetActiveConfidentialAccessGrants(token.InstanceID, study.Key, subjectID)
+			if err != nil {
+				slog.ErrorContext(c, "failed to get confidential access grants", slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			}
+			for _, grant := range confidentialGrants {
+				grants = append(grants, confidentialGrant(grant))
+			}
+		}
+
+		if len(grants) == 0 {
+			continue
+		}
+		result = append(result, SubjectStudyAccess{StudyKey: study.Key, Grants: grants})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subjectId": subjectID, "subjectType": subjectType, "isAdmin": isAdmin, "studies": result})
+}
@@ -0,0 +1,256 @@
+package apihandlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// newTestManagementUserDB connects to the MongoDB of TEST_MONGODB_URI with the database prefix of the study DB
+func newTestManagementUserDB(t *testing.T, studyDBService *studyDB.StudyDBService, instanceID string) *muDB.ManagementUserDBService {
+	dbService, err := muDB.NewManagementUserDBService(db.DBConfig{
+		URI:          os.Getenv("TEST_MONGODB_URI"),
+		DBNamePrefix: studyDBService.DBNamePrefix,
+		Timeout:      10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = dbService.DBClient.Database(dbService.DBNamePrefix + instanceID + "_users").Drop(context.Background())
+		_ = dbService.DBClient.Disconnect(context.Background())
+	})
+	return dbService
+}
+
+func grantProvenances(grants []AccessGrant) []string {
+	provenances := make([]string, len(grants))
+	for i, grant := range grants {
+		provenances[i] = grant.Provenance
+	}
+	return provenances
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestStudyAccessReview(t *testing.T) {
+	instanceID := "test"
+	studyDBService := newTestStudyDB(t, instanceID)
+	muDBService := newTestManagementUserDB(t, studyDBService, instanceID)
+	h := &HttpEndpoints{studyDBConn: studyDBService, muDBConn: muDBService}
+
+	for _, key := range []string{"study1", "study2"} {
+		if err := studyDBService.CreateStudy(instanceID, studyTypes.Study{Key: key, Status: studyTypes.STUDY_STATUS_ACTIVE}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newUser := func(email string, isAdmin bool) string {
+		user, err := muDBService.CreateUser(instanceID, &muDB.ManagementUser{Sub: email, Email: email, IsAdmin: isAdmin})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user.ID.Hex()
+	}
+	addPermission := func(subjectID string, subjectType string, studyKey string, action string) {
+		if _, err := muDBService.CreatePermission(instanceID, subjectID, subjectType, pc.RESOURCE_TYPE_STUDY, studyKey, action, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addConfidentialGrant := func(userID string, expiresAt time.Time) studyTypes.ConfidentialAccessGrant {
+		grant, err := studyDBService.AddConfidentialAccessGrant(instanceID, studyTypes.ConfidentialAccessGrant{
+			StudyKey:  "study1",
+			UserID:    userID,
+			Purpose:   "follow-up",
+			ExpiresAt: expiresAt,
+			GrantedBy: "admin",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return grant
+	}
+
+	adminID := newUser("admin@example.com", true)
+	studyUserID := newUser("researcher@example.com", false)
+	allStudiesUserID := newUser("analyst@example.com", false)
+	noAccessUserID := newUser("other@example.com", false)
+	serviceAccount, err := muDBService.CreateServiceUser(instanceID, "exporter", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedUserID := "000000000000000000000001"
+
+	addPermission(studyUserID, pc.SUBJECT_TYPE_MANAGEMENT_USER, "study1", pc.ACTION_READ_STUDY_CONFIG)
+	addPermission(allStudiesUserID, pc.SUBJECT_TYPE_MANAGEMENT_USER, pc.RESOURCE_KEY_STUDY_ALL, pc.ACTION_READ_STUDY_CONFIG)
+	addPermission(noAccessUserID, pc.SUBJECT_TYPE_MANAGEMENT_USER, "study2", pc.ACTION_READ_STUDY_CONFIG)
+	addPermission(serviceAccount.ID.Hex(), pc.SUBJECT_TYPE_SERVICE_ACCOUNT, "study1", pc.ACTION_GET_RESPONSES)
+	addPermission(deletedUserID, pc.SUBJECT_TYPE_MANAGEMENT_USER, "study1", pc.ACTION_READ_STUDY_CONFIG)
+
+	addConfidentialGrant(studyUserID, time.Now().Add(time.Hour))
+	addConfidentialGrant(allStudiesUserID, time.Now().Add(-time.Hour))
+	revoked := addConfidentialGrant(allStudiesUserID, time.Now().Add(time.Hour))
+	if err := studyDBService.RevokeConfidentialAccessGrant(instanceID, "study1", revoked.ID.Hex(), "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	token := &jwthandling.ManagementUserClaims{InstanceID: instanceID}
+	token.Subject = adminID
+
+	t.Run("who can access the study", func(t *testing.T) {
+		w := serveWithToken(h.getStudyAccessReview, token, http.MethodGet, "/studies/:studyKey/access-review", "/studies/study1/access-review")
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Access []StudyAccessEntry `json:"access"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		entries := map[string]StudyAccessEntry{}
+		for _, entry := range resp.Access {
+			entries[entry.SubjectID] = entry
+		}
+
+		tests := []struct {
+			name        string
+			subjectID   string
+			subjectType string
+			provenances []string
+			missing     bool
+		}{
+			{name: "instance admin", subjectID: adminID, subjectType: pc.SUBJECT_TYPE_MANAGEMENT_USER, provenances: []string{GRANT_PROVENANCE_INSTANCE_ADMIN}},
+			{name: "study permission and confidential grant", subjectID: studyUserID, subjectType: pc.SUBJECT_TYPE_MANAGEMENT_USER, provenances: []string{GRANT_PROVENANCE_STUDY_PERMISSION, GRANT_PROVENANCE_CONFIDENTIAL_DATA_GRANT}},
+			{name: "all studies without expired or revoked grants", subjectID: allStudiesUserID, subjectType: pc.SUBJECT_TYPE_MANAGEMENT_USER, provenances: []string{GRANT_PROVENANCE_ALL_STUDIES_PERMISSION}},
+			{name: "service account", subjectID: serviceAccount.ID.Hex(), subjectType: pc.SUBJECT_TYPE_SERVICE_ACCOUNT, provenances: []string{GRANT_PROVENANCE_STUDY_PERMISSION}},
+			{name: "deleted user", subjectID: deletedUserID, subjectType: pc.SUBJECT_TYPE_MANAGEMENT_USER, provenances: []string{GRANT_PROVENANCE_STUDY_PERMISSION}, missing: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				entry, ok := entries[tt.subjectID]
+				if !ok {
+					t.Fatalf("subject %s not listed", tt.subjectID)
+				}
+				if entry.SubjectType != tt.subjectType || entry.SubjectMissing != tt.missing {
+					t.Errorf("unexpected entry: %+v", entry)
+				}
+				if got := grantProvenances(entry.Grants); !reflect.DeepEqual(got, tt.provenances) {
+					t.Errorf("expected grants %v, got %v", tt.provenances, got)
+				}
+			})
+		}
+
+		if _, ok := entries[noAccessUserID]; ok {
+			t.Error("user with permission for another study listed")
+		}
+		if entries[serviceAccount.ID.Hex()].Name != "exporter" || entries[studyUserID].Name != "researcher@example.com" {
+			t.Error("subject names not resolved")
+		}
+	})
+
+	t.Run("studies a subject can access", func(t *testing.T) {
+		userRoute := "/management-users/:userID/study-access"
+		serviceAccountRoute := "/service-accounts/:serviceAccountID/study-access"
+
+		tests := []struct {
+			name     string
+			handler  func(c *gin.Context)
+			route    string
+			target   string
+			wantCode int
+			// provenances of the grants by study key
+			want map[string][]string
+		}{
+			{
+				name:     "admin",
+				handler:  h.getManagementUserStudyAccess,
+				route:    userRoute,
+				target:   "/management-users/" + adminID + "/study-access",
+				wantCode: http.StatusOK,
+				want: map[string][]string{
+					"study1": {GRANT_PROVENANCE_INSTANCE_ADMIN},
+					"study2": {GRANT_PROVENANCE_INSTANCE_ADMIN},
+				},
+			},
+			{
+				name:     "study permission and confidential grant",
+				handler:  h.getManagementUserStudyAccess,
+				route:    userRoute,
+				target:   "/management-users/" + studyUserID + "/study-access",
+				wantCode: http.StatusOK,
+				want:     map[string][]string{"study1": {GRANT_PROVENANCE_STUDY_PERMISSION, GRANT_PROVENANCE_CONFIDENTIAL_DATA_GRANT}},
+			},
+			{
+				name:     "all studies permission",
+				handler:  h.getManagementUserStudyAccess,
+				route:    userRoute,
+				target:   "/management-users/" + allStudiesUserID + "/study-access",
+				wantCode: http.StatusOK,
+				want: map[string][]string{
+					"study1": {GRANT_PROVENANCE_ALL_STUDIES_PERMISSION},
+					"study2": {GRANT_PROVENANCE_ALL_STUDIES_PERMISSION},
+				},
+			},
+			{
+				name:     "service account",
+				handler:  h.getServiceAccountStudyAccess,
+				route:    serviceAccountRoute,
+				target:   "/service-accounts/" + serviceAccount.ID.Hex() + "/study-access",
+				wantCode: http.StatusOK,
+				want:     map[string][]string{"study1": {GRANT_PROVENANCE_STUDY_PERMISSION}},
+			},
+			{
+				name:     "unknown user",
+				handler:  h.getManagementUserStudyAccess,
+				route:    userRoute,
+				target:   "/management-users/" + deletedUserID + "/study-access",
+				wantCode: http.StatusNotFound,
+			},
+			{
+				name:     "unknown service account",
+				handler:  h.getServiceAccountStudyAccess,
+				route:    serviceAccountRoute,
+				target:   "/service-accounts/" + deletedUserID + "/study-access",
+				wantCode: http.StatusNotFound,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := serveWithToken(tt.handler, token, http.MethodGet, tt.route, tt.target)
+				if w.Code != tt.wantCode {
+					t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+				}
+				if tt.wantCode != http.StatusOK {
+					return
+				}
+				var resp struct {
+					Studies []SubjectStudyAccess `json:"studies"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				got := map[string][]string{}
+				for _, study := range resp.Studies {
+					got[study.StudyKey] = grantProvenances(study.Grants)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			})
+		}
+	})
+}
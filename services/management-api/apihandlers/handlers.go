@@ -1,6 +1,7 @@
 package apihandlers
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -12,6 +13,8 @@ import (
 	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
 	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	"github.com/case-framework/case-backend/pkg/instances"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,11 +22,11 @@ func HealthCheckHandle(c *gin.Context) {
 	serviceInfos := make(map[string]interface{})
 	infos, err := os.ReadFile("serviceInfos.json")
 	if err != nil {
-		slog.Debug("Error reading serviceInfos.json", slog.String("error", err.Error()))
+		slog.DebugContext(c, "Error reading serviceInfos.json", slog.String("error", err.Error()))
 	} else {
 		err = json.Unmarshal(infos, &serviceInfos)
 		if err != nil {
-			slog.Debug("Error unmarshalling serviceInfos.json", slog.String("error", err.Error()))
+			slog.DebugContext(c, "Error unmarshalling serviceInfos.json", slog.String("error", err.Error()))
 		}
 	}
 
@@ -41,10 +44,39 @@ type HttpEndpoints struct {
 	globalInfosDBConn   *globalinfosDB.GlobalInfosDBService
 	tokenSignKey        string
 	tokenExpiresIn      time.Duration
-	allowedInstanceIDs  []string
+	instances           *instances.Registry
 	globalStudySecret   string
 	filestorePath       string
 	dailyFileExportPath string
+
+	// ad-hoc campaigns with a larger audience need approval by another user, 0 to disable
+	adHocCampaignApprovalThreshold int64
+
+	// validity of the invitation links sent to imported participants
+	participantInvitationTTL time.Duration
+
+	// signs download links of export job artifacts
+	exportDownloadSignKey string
+	exportDownloadURLTTL  time.Duration
+	// names and types of the destinations scheduled exports can be delivered to
+	exportDeliveryDestinations map[string]string
+
+	// admins of this instance can provision and archive instances, empty to disable
+	superAdminInstanceID string
+	// global email templates of this instance are copied to new instances
+	templateSourceInstanceID string
+
+	// work deferred by handlers, e.g. study actions and exports, drained on shutdown
+	backgroundTasks *taskrunner.Runner
+}
+
+// runInBackground queues the function without retries, the deferred work of this service is not idempotent and
+// records its errors in the task status
+func (h *HttpEndpoints) runInBackground(name string, fn func()) {
+	h.backgroundTasks.Go(name, func(ctx context.Context) error {
+		fn()
+		return nil
+	})
 }
 
 func NewHTTPHandler(
@@ -55,10 +87,11 @@ func NewHTTPHandler(
 	studyDBConn *studyDB.StudyDBService,
 	participantUserDB *userDB.ParticipantUserDBService,
 	globalInfosDBConn *globalinfosDB.GlobalInfosDBService,
-	allowedInstanceIDs []string,
+	instanceRegistry *instances.Registry,
 	globalStudySecret string,
 	filestorePath string,
 	dailyFileExportPath string,
+	backgroundTasks *taskrunner.Runner,
 ) *HttpEndpoints {
 	return &HttpEndpoints{
 		tokenSignKey:        tokenSignKey,
@@ -67,10 +100,16 @@ func NewHTTPHandler(
 		studyDBConn:         studyDBConn,
 		participantUserDB:   participantUserDB,
 		globalInfosDBConn:   globalInfosDBConn,
-		allowedInstanceIDs:  allowedInstanceIDs,
+		instances:           instanceRegistry,
 		globalStudySecret:   globalStudySecret,
 		tokenExpiresIn:      tokenExpiresIn,
 		filestorePath:       filestorePath,
 		dailyFileExportPath: dailyFileExportPath,
+		backgroundTasks:     backgroundTasks,
 	}
 }
+
+// SetAdHocCampaignApprovalThreshold sets the audience size above which ad-hoc campaigns need approval
+func (h *HttpEndpoints) SetAdHocCampaignApprovalThreshold(threshold int64) {
+	h.adHocCampaignApprovalThreshold = threshold
+}
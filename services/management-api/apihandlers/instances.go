@@ -0,0 +1,273 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	"github.com/case-framework/case-backend/pkg/db"
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	"github.com/case-framework/case-backend/pkg/instances"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SetInstanceManagementConfig enables the instance endpoints for admins of the super admin instance. Global email
+// templates and layouts of the template source instance are copied to new instances, if set.
+func (h *HttpEndpoints) SetInstanceManagementConfig(superAdminInstanceID string, templateSourceInstanceID string) {
+	h.superAdminInstanceID = superAdminInstanceID
+	h.templateSourceInstanceID = templateSourceInstanceID
+}
+
+// AddInstanceManagementAPI adds the endpoints to provision, archive and reactivate instances. Services reload the
+// allowed instances periodically, so changes reach other services after their reload interval.
+func (h *HttpEndpoints) AddInstanceManagementAPI(rg *gin.RouterGroup) {
+	instancesGroup := rg.Group("/instances")
+	instancesGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn))
+	instancesGroup.Use(mw.IsSuperAdmin(h.superAdminInstanceID))
+	{
+		instancesGroup.GET("/", h.getInstances)
+		instancesGroup.POST("/", mw.RequirePayload(), h.createInstance)
+		instancesGroup.POST("/:instanceID/archive", h.archiveInstance)
+		instancesGroup.POST("/:instanceID/activate", h.activateInstance)
+	}
+}
+
+type instanceInfos struct {
+	globalinfosDB.Instance
+	// allowed by the config of this service, also without a provisioned record
+	Allowed bool `json:"allowed"`
+}
+
+func (h *HttpEndpoints) getInstances(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting instances", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	provisioned, err := h.globalInfosDBConn.GetInstances()
+	if err != nil {
+		slog.ErrorContext(c, "failed to get instances", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get instances"})
+		return
+	}
+
+	known := map[string]bool{}
+	result := []instanceInfos{}
+	for _, instance := range provisioned {
+		known[instance.InstanceID] = true
+		result = append(result, instanceInfos{Instance: instance, Allowed: h.instances.IsAllowed(instance.InstanceID)})
+	}
+	for _, instanceID := range h.instances.InstanceIDs() {
+		if known[instanceID] {
+			continue
+		}
+		result = append(result, instanceInfos{
+			Instance: globalinfosDB.Instance{InstanceID: instanceID, Status: globalinfosDB.INSTANCE_STATUS_ACTIVE},
+			Allowed:  true,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"instances": result})
+}
+
+type createInstanceReq struct {
+	InstanceID  string `json:"instanceID"`
+	Description string `json:"description"`
+	// overrides the template source instance of the config, "-" to not copy templates
+	CopyTemplatesFrom string `json:"copyTemplatesFrom"`
+}
+
+type provisioningReport struct {
+	Migrations      []db.MigrationReport `json:"migrations"`
+	Indexes         []db.IndexReport     `json:"indexes"`
+	CopiedLayouts   int                  `json:"copiedLayouts"`
+	CopiedTemplates int                  `json:"copiedTemplates"`
+}
+
+func (h *HttpEndpoints) createInstance(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var req createInstanceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := instances.ValidateInstanceID(req.InstanceID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "creating instance", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("newInstanceID", req.InstanceID))
+
+	if _, err := h.globalInfosDBConn.GetInstance(req.InstanceID); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instance already exists"})
+		return
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		slog.ErrorContext(c, "failed to check instance", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create instance"})
+		return
+	}
+
+	templateSource := h.templateSourceInstanceID
+	if req.CopyTemplatesFrom != "" {
+		templateSource = req.CopyTemplatesFrom
+	}
+	if templateSource == "-" || templateSource == req.InstanceID {
+		templateSource = ""
+	}
+
+	// all steps are idempotent, a failed provisioning can be repeated with the same request
+	report, err := h.provisionInstance(req.InstanceID, templateSource)
+	if err != nil {
+		slog.ErrorContext(c, "failed to provision instance", slog.String("newInstanceID", req.InstanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to provision instance", "report": report})
+		return
+	}
+
+	instance, err := h.globalInfosDBConn.CreateInstance(globalinfosDB.Instance{
+		InstanceID:  req.InstanceID,
+		Description: req.Description,
+		CreatedBy:   token.Subject,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instance already exists"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c, "failed to save instance", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create instance"})
+		return
+	}
+	h.reloadInstances()
+
+	c.JSON(http.StatusOK, gin.H{"instance": instance, "report": report})
+}
+
+// provisionInstance runs the migrations and creates the indexes of the per instance DBs, then copies the global email
+// layouts and templates missing in the new instance
+func (h *HttpEndpoints) provisionInstance(instanceID string, templateSource string) (provisioningReport, error) {
+	report := provisioningReport{}
+	ctx := context.Background()
+
+	report.Migrations = []db.MigrationReport{
+		h.muDBConn.ApplyMigrations(ctx, instanceID, db.MigrationOptions{}),
+		h.participantUserDB.ApplyMigrations(ctx, instanceID, db.MigrationOptions{}),
+		h.messagingDBConn.ApplyMigrations(ctx, instanceID, db.MigrationOptions{}),
+		h.studyDBConn.ApplyMigrations(ctx, instanceID, db.MigrationOptions{}),
+	}
+	for _, r := range report.Migrations {
+		if r.HasErrors() {
+			return report, errors.New("migration failed in " + r.DBName)
+		}
+	}
+
+	report.Indexes = []db.IndexReport{
+		h.muDBConn.EnsureIndexes(instanceID, db.EnsureIndexesOptions{}),
+		h.participantUserDB.EnsureIndexes(instanceID, db.EnsureIndexesOptions{}),
+		h.messagingDBConn.EnsureIndexes(instanceID, db.EnsureIndexesOptions{}),
+	}
+	studyIndexes, err := h.studyDBConn.EnsureIndexes(instanceID, db.EnsureIndexesOptions{})
+	report.Indexes = append(report.Indexes, studyIndexes)
+	if err != nil {
+		return report, err
+	}
+	for _, r := range report.Indexes {
+		if r.HasErrors() {
+			return report, errors.New("failed to create indexes in " + r.DBName)
+		}
+	}
+
+	if templateSource == "" {
+		return report, nil
+	}
+
+	layouts, err := h.messagingDBConn.GetEmailLayouts(templateSource)
+	if err != nil {
+		return report, err
+	}
+	for _, layout := range layouts {
+		if _, err := h.messagingDBConn.GetEmailLayout(instanceID, layout.Key); err == nil {
+			continue
+		}
+		if _, err := h.messagingDBConn.SaveEmailLayout(instanceID, layout); err != nil {
+			return report, err
+		}
+		report.CopiedLayouts += 1
+	}
+
+	templates, err := h.messagingDBConn.GetGlobalEmailTemplates(templateSource)
+	if err != nil {
+		return report, err
+	}
+	for _, template := range templates {
+		if _, err := h.messagingDBConn.GetGlobalEmailTemplateByMessageType(instanceID, template.MessageType); err == nil {
+			continue
+		}
+		template.ID = primitive.NilObjectID
+		template.Version = 0
+		if _, err := h.messagingDBConn.SaveEmailTemplate(instanceID, template); err != nil {
+			return report, err
+		}
+		report.CopiedTemplates += 1
+	}
+	return report, nil
+}
+
+func (h *HttpEndpoints) archiveInstance(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	instanceID := c.Param("instanceID")
+
+	if instanceID == h.superAdminInstanceID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the super admin instance cannot be archived"})
+		return
+	}
+
+	slog.InfoContext(c, "archiving instance", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("archivedInstanceID", instanceID))
+
+	h.setInstanceStatus(c, instanceID, globalinfosDB.INSTANCE_STATUS_ARCHIVED, token.Subject)
+}
+
+func (h *HttpEndpoints) activateInstance(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	instanceID := c.Param("instanceID")
+
+	slog.InfoContext(c, "activating instance", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("activatedInstanceID", instanceID))
+
+	h.setInstanceStatus(c, instanceID, globalinfosDB.INSTANCE_STATUS_ACTIVE, token.Subject)
+}
+
+// setInstanceStatus updates provisioned instances and instances allowed by the config, unknown IDs are rejected
+func (h *HttpEndpoints) setInstanceStatus(c *gin.Context, instanceID string, status string, userID string) {
+	_, err := h.globalInfosDBConn.GetInstance(instanceID)
+	if errors.Is(err, mongo.ErrNoDocuments) && !h.instances.IsAllowed(instanceID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "instance not found"})
+		return
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		slog.ErrorContext(c, "failed to get instance", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update instance"})
+		return
+	}
+
+	instance, err := h.globalInfosDBConn.SetInstanceStatus(instanceID, status, userID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to update instance", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update instance"})
+		return
+	}
+	h.reloadInstances()
+
+	c.JSON(http.StatusOK, gin.H{"instance": instance})
+}
+
+// reloadInstances applies changes to this service immediately
+func (h *HttpEndpoints) reloadInstances() {
+	if err := h.instances.Reload(); err != nil {
+		slog.Error("failed to reload instances", slog.String("error", err.Error()))
+	}
+}
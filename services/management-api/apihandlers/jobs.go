@@ -0,0 +1,97 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AddJobHistoryAPI adds the endpoints to browse the run history of the job scheduler. Jobs run for all instances, so
+// only admins of the super admin instance have access.
+func (h *HttpEndpoints) AddJobHistoryAPI(rg *gin.RouterGroup) {
+	jobsGroup := rg.Group("/jobs")
+	jobsGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn))
+	jobsGroup.Use(mw.IsSuperAdmin(h.superAdminInstanceID))
+	{
+		jobsGroup.GET("/locks", h.getJobLocks)
+		jobsGroup.GET("/runs", h.getJobRuns) // ?job=messaging&status=failed&page=1&limit=20
+		jobsGroup.GET("/runs/:runID", h.getJobRun)
+	}
+}
+
+func (h *HttpEndpoints) getJobLocks(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting job locks", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	locks, err := h.globalInfosDBConn.GetJobLocks()
+	if err != nil {
+		slog.ErrorContext(c, "failed to get job locks", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job locks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"locks": locks})
+}
+
+func (h *HttpEndpoints) getJobRuns(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page parameter"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+	job := c.Query("job")
+	status := c.Query("status")
+
+	slog.InfoContext(c, "getting job runs", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("job", job), slog.String("status", status))
+
+	runs, totalCount, err := h.globalInfosDBConn.GetJobRuns(job, status, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get job runs", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job runs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"runs":       runs,
+		"totalCount": totalCount,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+func (h *HttpEndpoints) getJobRun(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	runID, err := primitive.ObjectIDFromHex(c.Param("runID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+
+	slog.InfoContext(c, "getting job run", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("runID", runID.Hex()))
+
+	run, err := h.globalInfosDBConn.GetJobRun(runID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job run not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to get job run", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job run"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"run": run})
+}
@@ -5,9 +5,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/apihelpers"
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 	mUserDB "github.com/case-framework/case-backend/pkg/db/management-user"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
 	"github.com/gin-gonic/gin"
 
 	pc "github.com/case-framework/case-backend/pkg/permission-checker"
@@ -16,21 +19,25 @@ import (
 func (h *HttpEndpoints) AddManagementAuthAPI(rg *gin.RouterGroup) {
 	auth := rg.Group("/auth")
 
-	auth.POST("/signin-with-idp", mw.RequirePayload(), h.signInWithIdP)
+	auth.POST("/signin-with-idp", metrics.AuthOutcome("management_signin"), mw.RequirePayload(), h.signInWithIdP)
 
+	// access tokens are scoped, so they must not be exchanged for an unscoped session token
 	auth.POST("/extend-session",
 		mw.RequirePayload(),
-		mw.ManagementAuthMiddleware(h.tokenSignKey, h.allowedInstanceIDs, h.muDBConn),
+		mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn),
+		mw.RejectAccessTokens(),
 		h.extendSession,
 	)
 
 	auth.GET("/renew-token/:sessionID",
-		mw.ManagementAuthMiddleware(h.tokenSignKey, h.allowedInstanceIDs, h.muDBConn),
+		mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn),
+		mw.RejectAccessTokens(),
 		h.getRenewToken,
 	)
 
 	auth.GET("/permissions",
-		mw.ManagementAuthMiddleware(h.tokenSignKey, h.allowedInstanceIDs, h.muDBConn),
+		mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn),
+		mw.RejectAccessTokens(),
 		h.getMyPermissions)
 }
 
@@ -49,20 +56,21 @@ type SignInRequest struct {
 func (h *HttpEndpoints) signInWithIdP(c *gin.Context) {
 	var req SignInRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	if !h.isInstanceAllowed(req.InstanceID) {
-		slog.Warn("instance not allowed", slog.String("instanceID", req.InstanceID))
-		c.JSON(http.StatusForbidden, gin.H{"error": "instance not allowed"})
+		slog.WarnContext(c, "instance not allowed", slog.String("instanceID", req.InstanceID))
+		emitManagementLoginFailure(c, req.InstanceID, "instance-not-allowed")
+		apihelpers.RespondError(c, http.StatusForbidden, apihelpers.ERROR_CODE_INVALID_INSTANCE, "instance not allowed")
 		return
 	}
 
 	if req.Sub == "" {
-		slog.Warn("no sub")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing sub"})
+		slog.WarnContext(c, "no sub")
+		apihelpers.RespondValidationError(c, "missing sub", apihelpers.FieldError{Field: "sub", Message: "required"})
 		return
 	}
 
@@ -77,7 +85,15 @@ func (h *HttpEndpoints) signInWithIdP(c *gin.Context) {
 	// Find user in database
 	existingUser, err := h.muDBConn.GetUserBySub(req.InstanceID, req.Sub)
 	if err != nil || existingUser == nil {
-		slog.Info("sign up with a new management user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email))
+		// deleted users can sign in again after they are restored from the trash
+		if deleted, err := h.muDBConn.IsDeletedUser(req.InstanceID, req.Sub); err == nil && deleted {
+			slog.WarnContext(c, "sign in of a deleted management user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID))
+			emitManagementLoginFailure(c, req.InstanceID, "deleted-user")
+			apihelpers.RespondError(c, http.StatusForbidden, apihelpers.ERROR_CODE_FORBIDDEN, "user is deleted")
+			return
+		}
+
+		slog.InfoContext(c, "sign up with a new management user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email))
 		// Create new user
 		existingUser, err = h.muDBConn.CreateUser(req.InstanceID, &mUserDB.ManagementUser{
 			Sub:         req.Sub,
@@ -88,19 +104,35 @@ func (h *HttpEndpoints) signInWithIdP(c *gin.Context) {
 			LastLoginAt: time.Now(),
 		})
 		if err != nil {
-			slog.Error("could not create new user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email), slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create new user"})
+			slog.ErrorContext(c, "could not create new user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email), slog.String("error", err.Error()))
+			apihelpers.RespondInternalError(c, "could not create new user")
 			return
 		}
 	} else {
-		slog.Info("sign in with an existing management user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email))
+		slog.InfoContext(c, "sign in with an existing management user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email))
 		// Update existing user
 		err = h.muDBConn.UpdateUser(req.InstanceID, existingUser.ID.Hex(), req.Email, req.Name, isAdmin, time.Now(), req.ImageURL)
 		if err != nil {
-			slog.Error("could not update existing user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email), slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update existing user"})
+			slog.ErrorContext(c, "could not update existing user", slog.String("sub", req.Sub), slog.String("instanceID", req.InstanceID), slog.String("name", req.Name), slog.String("email", req.Email), slog.String("error", err.Error()))
+			apihelpers.RespondInternalError(c, "could not update existing user")
 			return
 		}
+		if existingUser.IsAdmin != isAdmin {
+			change := "admin-revoked"
+			if isAdmin {
+				change = "admin-granted"
+			}
+			securityevents.EmitForRequest(c, securityevents.Event{
+				Type:       securityevents.EVENT_PRIVILEGE_CHANGED,
+				Outcome:    securityevents.OUTCOME_SUCCESS,
+				InstanceID: req.InstanceID,
+				ActorType:  securityevents.ACTOR_MANAGEMENT_USER,
+				ActorID:    existingUser.ID.Hex(),
+				TargetType: securityevents.ACTOR_MANAGEMENT_USER,
+				TargetID:   existingUser.ID.Hex(),
+				Details:    map[string]string{"change": change, "source": "idp-roles"},
+			})
+		}
 	}
 
 	sessionId := ""
@@ -109,8 +141,8 @@ func (h *HttpEndpoints) signInWithIdP(c *gin.Context) {
 	if req.RenewToken != "" {
 		session, err := h.muDBConn.CreateSession(req.InstanceID, existingUser.ID.Hex(), req.RenewToken)
 		if err != nil {
-			slog.Error("could not create session", slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create session"})
+			slog.ErrorContext(c, "could not create session", slog.String("error", err.Error()))
+			apihelpers.RespondInternalError(c, "could not create session")
 			return
 		}
 		sessionId = session.ID.Hex()
@@ -126,11 +158,18 @@ func (h *HttpEndpoints) signInWithIdP(c *gin.Context) {
 		h.tokenSignKey,
 	)
 	if err != nil {
-		slog.Error("could not generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		slog.ErrorContext(c, "could not generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "could not generate token")
 		return
 	}
 
+	securityevents.EmitForRequest(c, securityevents.Event{
+		Type:       securityevents.EVENT_LOGIN,
+		Outcome:    securityevents.OUTCOME_SUCCESS,
+		InstanceID: req.InstanceID,
+		ActorType:  securityevents.ACTOR_MANAGEMENT_USER,
+		ActorID:    existingUser.ID.Hex(),
+	})
 	c.JSON(http.StatusOK, gin.H{
 		"accessToken": token,
 		"sessionID":   sessionId,
@@ -139,6 +178,17 @@ func (h *HttpEndpoints) signInWithIdP(c *gin.Context) {
 	})
 }
 
+// emitManagementLoginFailure records a rejected sign in, the IdP subject is not logged as the user is not known
+func emitManagementLoginFailure(c *gin.Context, instanceID string, reason string) {
+	securityevents.EmitForRequest(c, securityevents.Event{
+		Type:       securityevents.EVENT_LOGIN,
+		Outcome:    securityevents.OUTCOME_FAILURE,
+		InstanceID: instanceID,
+		ActorType:  securityevents.ACTOR_MANAGEMENT_USER,
+		Reason:     reason,
+	})
+}
+
 // ExtendSessionRequest is the request body for the extend-session endpoint
 type ExtendSessionRequest struct {
 	RenewToken string `json:"renewToken"`
@@ -150,14 +200,14 @@ func (h *HttpEndpoints) extendSession(c *gin.Context) {
 
 	var req ExtendSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	if !h.isInstanceAllowed(token.InstanceID) {
-		slog.Warn("instance not allowed", slog.String("instanceID", token.InstanceID))
-		c.JSON(http.StatusForbidden, gin.H{"error": "instance not allowed"})
+		slog.WarnContext(c, "instance not allowed", slog.String("instanceID", token.InstanceID))
+		apihelpers.RespondError(c, http.StatusForbidden, apihelpers.ERROR_CODE_INVALID_INSTANCE, "instance not allowed")
 		return
 	}
 
@@ -167,8 +217,8 @@ func (h *HttpEndpoints) extendSession(c *gin.Context) {
 	if req.RenewToken != "" {
 		session, err := h.muDBConn.CreateSession(token.InstanceID, token.Subject, req.RenewToken)
 		if err != nil {
-			slog.Error("could not create session", slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create session"})
+			slog.ErrorContext(c, "could not create session", slog.String("error", err.Error()))
+			apihelpers.RespondInternalError(c, "could not create session")
 			return
 		}
 		sessionId = session.ID.Hex()
@@ -184,12 +234,12 @@ func (h *HttpEndpoints) extendSession(c *gin.Context) {
 		h.tokenSignKey,
 	)
 	if err != nil {
-		slog.Error("could not generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		slog.ErrorContext(c, "could not generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "could not generate token")
 		return
 	}
 
-	slog.Info("extended session", slog.String("userID", token.Subject), slog.String("instanceID", token.InstanceID))
+	slog.InfoContext(c, "extended session", slog.String("userID", token.Subject), slog.String("instanceID", token.InstanceID))
 
 	c.JSON(http.StatusOK, gin.H{
 		"accessToken": newAccessToken,
@@ -203,25 +253,25 @@ func (h *HttpEndpoints) extendSession(c *gin.Context) {
 func (h *HttpEndpoints) getRenewToken(c *gin.Context) {
 	sessionID := c.Param("sessionID")
 	if sessionID == "" {
-		slog.Warn("no sessionID")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no sessionID"})
+		slog.WarnContext(c, "no sessionID")
+		apihelpers.RespondValidationError(c, "no sessionID", apihelpers.FieldError{Field: "sessionID", Message: "required"})
 		return
 	}
 
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	existingSession, err := h.muDBConn.GetSession(token.InstanceID, sessionID)
 	if err != nil {
-		slog.Debug("could not get session", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not get session"})
+		slog.DebugContext(c, "could not get session", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "could not get session")
 		return
 	}
 	if existingSession.UserID != token.Subject {
-		slog.Warn("user not allowed to get renew token", slog.String("userID", token.Subject), slog.String("sessionUserID", existingSession.UserID))
-		c.JSON(http.StatusForbidden, gin.H{"error": "user not allowed to get renew token"})
+		slog.WarnContext(c, "user not allowed to get renew token", slog.String("userID", token.Subject), slog.String("sessionUserID", existingSession.UserID))
+		apihelpers.RespondError(c, http.StatusForbidden, apihelpers.ERROR_CODE_FORBIDDEN, "user not allowed to get renew token")
 		return
 	}
 
-	slog.Info("got renew token", slog.String("userID", token.Subject), slog.String("instanceID", token.InstanceID))
+	slog.InfoContext(c, "got renew token", slog.String("userID", token.Subject), slog.String("instanceID", token.InstanceID))
 
 	c.JSON(http.StatusOK, gin.H{"renewToken": existingSession.RenewToken})
 }
@@ -230,12 +280,12 @@ func (h *HttpEndpoints) getMyPermissions(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	userID := token.Subject
 
-	slog.Info("getting user permissions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting user permissions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	permissions, err := h.muDBConn.GetPermissionBySubject(token.InstanceID, userID, pc.SUBJECT_TYPE_MANAGEMENT_USER)
 	if err != nil {
-		slog.Error("error retrieving user permissions", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting user permissions"})
+		slog.ErrorContext(c, "error retrieving user permissions", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "error getting user permissions")
 		return
 	}
 
@@ -0,0 +1,186 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/case-framework/case-backend/pkg/messaging/campaigns"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AdHocCampaignReq struct {
+	Label      string                          `json:"label"`
+	Template   messagingTypes.EmailTemplate    `json:"template"`
+	Audience   messagingTypes.CampaignAudience `json:"audience"`
+	QuietHours *messagingTypes.QuietHours      `json:"quietHours,omitempty"`
+}
+
+// countCampaignAudience returns the number of users the campaign would currently be sent to
+func (h *HttpEndpoints) countCampaignAudience(instanceID string, audience messagingTypes.CampaignAudience, messageType string) (int64, error) {
+	var study *studyTypes.Study
+	if audience.StudyKey != "" {
+		s, err := h.studyDBConn.GetStudy(instanceID, audience.StudyKey)
+		if err != nil {
+			return 0, err
+		}
+		study = &s
+	}
+
+	now := time.Now()
+	var count int64
+	err := h.participantUserDB.FindAndExecuteOnUsers(
+		context.Background(),
+		instanceID,
+		campaigns.UserFilter(audience),
+		nil,
+		true,
+		func(user umTypes.User, args ...interface{}) error {
+			if !campaigns.MatchesUser(user, audience, messageType, now) {
+				return nil
+			}
+			if study != nil && !campaigns.HasMatchingParticipant(h.studyDBConn, instanceID, *study, user, audience.Flags) {
+				return nil
+			}
+			count += 1
+			return nil
+		},
+	)
+	return count, err
+}
+
+func (h *HttpEndpoints) previewAdHocCampaignAudience(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var req AdHocCampaignReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+	if err := campaigns.ValidateCampaign(messagingTypes.Campaign{Template: req.Template, Audience: req.Audience, QuietHours: req.QuietHours}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "previewing ad-hoc campaign audience", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	count, err := h.countCampaignAudience(token.InstanceID, req.Audience, req.Template.MessageType)
+	if err != nil {
+		slog.ErrorContext(c, "error counting campaign audience", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error counting campaign audience"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"audienceSize":     count,
+		"requiresApproval": h.adHocCampaignApprovalThreshold > 0 && count > h.adHocCampaignApprovalThreshold,
+	})
+}
+
+func (h *HttpEndpoints) createAdHocCampaign(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var req AdHocCampaignReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+
+	now := time.Now()
+	campaign := messagingTypes.Campaign{
+		Label:      req.Label,
+		Template:   req.Template,
+		Audience:   req.Audience,
+		QuietHours: req.QuietHours,
+		Recurrence: messagingTypes.CampaignRecurrence{StartAt: now.Unix()},
+		AdHoc:      true,
+		CreatedBy:  token.Subject,
+	}
+	if err := campaigns.ValidateCampaign(campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.checkEmailTemplateValidity(token.InstanceID, campaign.Template); err != nil {
+		slog.ErrorContext(c, "error parsing template", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error while checking template validity"})
+		return
+	}
+
+	count, err := h.countCampaignAudience(token.InstanceID, campaign.Audience, campaign.Template.MessageType)
+	if err != nil {
+		slog.ErrorContext(c, "error counting campaign audience", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error counting campaign audience"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no participants match the filter"})
+		return
+	}
+	campaign.AudienceSize = count
+
+	if h.adHocCampaignApprovalThreshold > 0 && count > h.adHocCampaignApprovalThreshold {
+		campaign.Status = messagingTypes.CAMPAIGN_STATUS_PENDING_APPROVAL
+		campaign.NextRunAt = 0
+	} else {
+		campaign.Status = messagingTypes.CAMPAIGN_STATUS_ACTIVE
+		campaign.NextRunAt = now.Unix()
+	}
+
+	slog.InfoContext(c, "creating ad-hoc campaign", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.Int64("audienceSize", count), slog.String("status", campaign.Status))
+
+	savedCampaign, err := h.messagingDBConn.SaveCampaign(token.InstanceID, campaign)
+	if err != nil {
+		slog.ErrorContext(c, "error saving campaign", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving campaign"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaign": savedCampaign})
+}
+
+func (h *HttpEndpoints) approveCampaign(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	campaign, err := h.messagingDBConn.GetCampaignByID(token.InstanceID, id)
+	if err != nil {
+		slog.ErrorContext(c, "error getting campaign", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting campaign"})
+		return
+	}
+	if campaign.Status != messagingTypes.CAMPAIGN_STATUS_PENDING_APPROVAL {
+		c.JSON(http.StatusConflict, gin.H{"error": "campaign is not waiting for approval"})
+		return
+	}
+	if campaign.CreatedBy == token.Subject {
+		slog.WarnContext(c, "user tried to approve own campaign", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("campaignID", id))
+		c.JSON(http.StatusForbidden, gin.H{"error": "campaign must be approved by another user"})
+		return
+	}
+
+	slog.InfoContext(c, "approving campaign", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("campaignID", id))
+
+	approved, err := h.messagingDBConn.ApproveCampaign(token.InstanceID, campaign.ID, token.Subject, time.Now().Unix())
+	if err != nil {
+		slog.ErrorContext(c, "error approving campaign", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusConflict, gin.H{"error": "campaign is not waiting for approval"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error approving campaign"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaign": approved})
+}
@@ -0,0 +1,227 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/case-framework/case-backend/pkg/messaging/campaigns"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) addMessagingCampaignsAPI(rg *gin.RouterGroup) {
+	rg.GET("/", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_CAMPAIGNS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.getCampaigns,
+	))
+
+	rg.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_CAMPAIGNS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.saveCampaign,
+	))
+
+	rg.POST("/ad-hoc/preview", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_CAMPAIGNS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.previewAdHocCampaignAudience,
+	))
+
+	rg.POST("/ad-hoc", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_CAMPAIGNS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.createAdHocCampaign,
+	))
+
+	rg.POST("/:id/approve", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_CAMPAIGNS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.approveCampaign,
+	))
+
+	rg.GET("/:id", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_CAMPAIGNS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.getCampaign,
+	))
+
+	rg.DELETE("/:id", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_CAMPAIGNS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.deleteCampaign,
+	))
+}
+
+func (h *HttpEndpoints) getCampaigns(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	slog.InfoContext(c, "getting campaigns", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	campaignList, err := h.messagingDBConn.GetCampaigns(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error getting campaigns", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting campaigns"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaignList})
+}
+
+func (h *HttpEndpoints) saveCampaign(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var campaign messagingTypes.Campaign
+	if err := c.ShouldBindJSON(&campaign); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+
+	slog.InfoContext(c, "saving campaign", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("campaignID", campaign.ID.Hex()))
+
+	if err := campaigns.ValidateCampaign(campaign); err != nil {
+		slog.ErrorContext(c, "invalid campaign", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.checkEmailTemplateValidity(token.InstanceID, campaign.Template); err != nil {
+		slog.ErrorContext(c, "error parsing template", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error while checking template validity"})
+		return
+	}
+
+	now := time.Now()
+	if 0 < campaign.Recurrence.Until && campaign.Recurrence.Until < now.Unix() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid termination date of campaign, is in past"})
+		return
+	}
+
+	// run state is managed by the campaign handler and kept from the stored campaign
+	campaign.LastRunAt = 0
+	if !campaign.ID.IsZero() {
+		existing, err := h.messagingDBConn.GetCampaignByID(token.InstanceID, campaign.ID.Hex())
+		if err != nil {
+			slog.ErrorContext(c, "error getting campaign", slog.String("error", err.Error()))
+			c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+			return
+		}
+		if existing.Status == messagingTypes.CAMPAIGN_STATUS_PENDING_APPROVAL {
+			c.JSON(http.StatusConflict, gin.H{"error": "campaign is waiting for approval"})
+			return
+		}
+		campaign.CreatedAt = existing.CreatedAt
+		campaign.LastRunAt = existing.LastRunAt
+		campaign.AdHoc = existing.AdHoc
+		campaign.AudienceSize = existing.AudienceSize
+		campaign.CreatedBy = existing.CreatedBy
+		campaign.ApprovedBy = existing.ApprovedBy
+		campaign.ApprovedAt = existing.ApprovedAt
+	} else {
+		campaign.AdHoc = false
+		campaign.AudienceSize = 0
+		campaign.CreatedBy = token.Subject
+		campaign.ApprovedBy = ""
+		campaign.ApprovedAt = 0
+	}
+
+	if campaign.Status == "" {
+		campaign.Status = messagingTypes.CAMPAIGN_STATUS_ACTIVE
+	}
+	switch campaign.Status {
+	case messagingTypes.CAMPAIGN_STATUS_ACTIVE:
+		nextRunAt, err := campaigns.NextRun(campaign, now)
+		if err != nil {
+			slog.ErrorContext(c, "error computing next run", slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		campaign.NextRunAt = nextRunAt
+		if nextRunAt == 0 {
+			campaign.Status = messagingTypes.CAMPAIGN_STATUS_FINISHED
+		}
+	case messagingTypes.CAMPAIGN_STATUS_PAUSED, messagingTypes.CAMPAIGN_STATUS_FINISHED:
+		campaign.NextRunAt = 0
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign status"})
+		return
+	}
+
+	savedCampaign, err := h.messagingDBConn.SaveCampaign(token.InstanceID, campaign)
+	if err != nil {
+		slog.ErrorContext(c, "error saving campaign", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving campaign"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaign": savedCampaign})
+}
+
+func (h *HttpEndpoints) getCampaign(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	slog.InfoContext(c, "getting campaign", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+
+	campaign, err := h.messagingDBConn.GetCampaignByID(token.InstanceID, id)
+	if err != nil {
+		slog.ErrorContext(c, "error getting campaign", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting campaign"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaign": campaign})
+}
+
+func (h *HttpEndpoints) deleteCampaign(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	slog.InfoContext(c, "deleting campaign", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+
+	err := h.messagingDBConn.DeleteCampaign(token.InstanceID, id)
+	if err != nil {
+		slog.ErrorContext(c, "error deleting campaign", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting campaign"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "campaign deleted"})
+}
@@ -0,0 +1,191 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyutils "github.com/case-framework/case-backend/pkg/study/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	DEPENDENCY_SOURCE_SCHEDULED_EMAIL = "scheduledEmail"
+	DEPENDENCY_SOURCE_STUDY_RULE      = "studyRule"
+	DEPENDENCY_SOURCE_CAMPAIGN        = "campaign"
+)
+
+// templateReference describes where an email template is used
+type templateReference struct {
+	Source    string `json:"source"`
+	StudyKey  string `json:"studyKey,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Label     string `json:"label,omitempty"`
+	RuleIndex *int   `json:"ruleIndex,omitempty"`
+	Action    string `json:"action,omitempty"`
+}
+
+type emailTemplateDependencies struct {
+	MessageType  string              `json:"messageType"`
+	StudyKey     string              `json:"studyKey,omitempty"`
+	ReferencedBy []templateReference `json:"referencedBy"`
+}
+
+type studyRuleReference struct {
+	studyutils.RuleReference
+	// Missing is true if the referenced item does not exist. References that cannot be checked are never reported as missing.
+	Missing bool `json:"missing,omitempty"`
+}
+
+type studyRuleDependencies struct {
+	StudyKey   string               `json:"studyKey"`
+	References []studyRuleReference `json:"references"`
+}
+
+func templateDependencyKey(studyKey string, messageType string) string {
+	return studyKey + "/" + messageType
+}
+
+// getMessagingDependencies reports for each email template where it is referenced, and for each study's rules which items they reference
+func (h *HttpEndpoints) getMessagingDependencies(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting messaging dependencies", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	templates := map[string]*emailTemplateDependencies{}
+	templateOrder := []string{}
+	addTemplate := func(studyKey string, messageType string) *emailTemplateDependencies {
+		key := templateDependencyKey(studyKey, messageType)
+		if t, ok := templates[key]; ok {
+			return t
+		}
+		t := &emailTemplateDependencies{
+			MessageType:  messageType,
+			StudyKey:     studyKey,
+			ReferencedBy: []templateReference{},
+		}
+		templates[key] = t
+		templateOrder = append(templateOrder, key)
+		return t
+	}
+
+	globalTemplates, err := h.messagingDBConn.GetGlobalEmailTemplates(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error getting global email templates", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting global email templates"})
+		return
+	}
+	for _, t := range globalTemplates {
+		addTemplate("", t.MessageType)
+	}
+
+	studyTemplates, err := h.messagingDBConn.GetEmailTemplatesForAllStudies(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error getting study email templates", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting study email templates"})
+		return
+	}
+	existingTemplates := map[string]bool{}
+	for _, t := range studyTemplates {
+		addTemplate(t.StudyKey, t.MessageType)
+		existingTemplates[templateDependencyKey(t.StudyKey, t.MessageType)] = true
+	}
+
+	schedules, err := h.messagingDBConn.GetAllScheduledEmails(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error getting scheduled emails", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting scheduled emails"})
+		return
+	}
+	for _, s := range schedules {
+		t := addTemplate(s.Template.StudyKey, s.Template.MessageType)
+		t.ReferencedBy = append(t.ReferencedBy, templateReference{
+			Source:   DEPENDENCY_SOURCE_SCHEDULED_EMAIL,
+			StudyKey: s.StudyKey,
+			ID:       s.ID.Hex(),
+			Label:    s.Label,
+		})
+	}
+
+	campaigns, err := h.messagingDBConn.GetCampaigns(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error getting campaigns", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting campaigns"})
+		return
+	}
+	for _, campaign := range campaigns {
+		t := addTemplate(campaign.Audience.StudyKey, campaign.Template.MessageType)
+		t.ReferencedBy = append(t.ReferencedBy, templateReference{
+			Source:   DEPENDENCY_SOURCE_CAMPAIGN,
+			StudyKey: campaign.Audience.StudyKey,
+			ID:       campaign.ID.Hex(),
+			Label:    campaign.Label,
+		})
+	}
+
+	studies, err := h.studyDBConn.GetStudies(token.InstanceID, "", true)
+	if err != nil {
+		slog.ErrorContext(c, "error getting studies", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting studies"})
+		return
+	}
+
+	rules := []studyRuleDependencies{}
+	for _, study := range studies {
+		studyRules, err := h.studyDBConn.GetCurrentStudyRules(token.InstanceID, study.Key)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				slog.ErrorContext(c, "error getting study rules", slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			}
+			continue
+		}
+
+		surveyKeys, err := h.studyDBConn.GetSurveyKeysForStudy(token.InstanceID, study.Key, true)
+		if err != nil {
+			slog.ErrorContext(c, "error getting survey keys", slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			continue
+		}
+		existingSurveys := map[string]bool{}
+		for _, k := range surveyKeys {
+			existingSurveys[k] = true
+		}
+
+		deps := studyRuleDependencies{
+			StudyKey:   study.Key,
+			References: []studyRuleReference{},
+		}
+		for _, ref := range studyutils.FindRuleReferences(studyRules.Rules) {
+			r := studyRuleReference{RuleReference: ref}
+			if !ref.Dynamic {
+				switch ref.Kind {
+				case studyutils.RULE_REFERENCE_MESSAGE_TYPE:
+					r.Missing = !existingTemplates[templateDependencyKey(study.Key, ref.Key)]
+
+					ruleIndex := ref.RuleIndex
+					t := addTemplate(study.Key, ref.Key)
+					t.ReferencedBy = append(t.ReferencedBy, templateReference{
+						Source:    DEPENDENCY_SOURCE_STUDY_RULE,
+						StudyKey:  study.Key,
+						RuleIndex: &ruleIndex,
+						Action:    ref.Action,
+					})
+				case studyutils.RULE_REFERENCE_SURVEY_KEY:
+					r.Missing = !existingSurveys[ref.Key]
+				}
+			}
+			deps.References = append(deps.References, r)
+		}
+		rules = append(rules, deps)
+	}
+
+	emailTemplates := make([]*emailTemplateDependencies, len(templateOrder))
+	for i, key := range templateOrder {
+		emailTemplates[i] = templates[key]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"emailTemplates": emailTemplates,
+		"studyRules":     rules,
+	})
+}
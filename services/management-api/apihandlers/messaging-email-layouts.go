@@ -0,0 +1,136 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	emailtemplates "github.com/case-framework/case-backend/pkg/messaging/email-templates"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) addMessagingEmailLayoutsAPI(rg *gin.RouterGroup) {
+	permission := RequiredPermission{
+		ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+		ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_GLOBAL_EMAIL_TEMPLATES},
+		Action:       pc.ACTION_ALL,
+	}
+
+	rg.GET("/", h.useAuthorisedHandler(permission, nil, h.getEmailLayouts))
+	rg.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(permission, nil, h.saveEmailLayout))
+	rg.GET("/:key", h.useAuthorisedHandler(permission, nil, h.getEmailLayout))
+	rg.DELETE("/:key", h.useAuthorisedHandler(permission, nil, h.deleteEmailLayout))
+}
+
+// checkEmailTemplateValidity checks that all translations can be rendered, using the layout of the template if it has one
+func (h *HttpEndpoints) checkEmailTemplateValidity(instanceID string, template messagingTypes.EmailTemplate) error {
+	if template.Layout == "" {
+		return emailtemplates.CheckAllTranslationsParsable(template)
+	}
+
+	layout, err := h.messagingDBConn.GetEmailLayout(instanceID, template.Layout)
+	if err != nil {
+		return err
+	}
+	return emailtemplates.CheckAllTranslationsWithLayout(template, *layout)
+}
+
+func (h *HttpEndpoints) getEmailLayouts(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting email layouts", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	layouts, err := h.messagingDBConn.GetEmailLayouts(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error getting email layouts", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email layouts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"layouts": layouts})
+}
+
+func (h *HttpEndpoints) getEmailLayout(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	key := c.Param("key")
+
+	layout, err := h.messagingDBConn.GetEmailLayout(token.InstanceID, key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "email layout not found"})
+			return
+		}
+		slog.ErrorContext(c, "error getting email layout", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email layout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"layout": layout})
+}
+
+func (h *HttpEndpoints) saveEmailLayout(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var layout messagingTypes.EmailLayout
+	if err := c.ShouldBindJSON(&layout); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+	if layout.Key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		return
+	}
+
+	layoutDef, err := emailtemplates.DecodeLayout(layout)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "layout must be base64 encoded"})
+		return
+	}
+	if _, err := emailtemplates.RenderWithLayout(layoutDef, "", map[string]string{}); err != nil {
+		slog.ErrorContext(c, "error parsing layout", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "saving email layout", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("key", layout.Key))
+
+	saved, err := h.messagingDBConn.SaveEmailLayout(token.InstanceID, layout)
+	if err != nil {
+		slog.ErrorContext(c, "error saving email layout", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving email layout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"layout": saved})
+}
+
+func (h *HttpEndpoints) deleteEmailLayout(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	key := c.Param("key")
+
+	usedBy, err := h.messagingDBConn.CountEmailTemplatesUsingLayout(token.InstanceID, key)
+	if err != nil {
+		slog.ErrorContext(c, "error checking email layout usage", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error checking email layout usage"})
+		return
+	}
+	if usedBy > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "email layout is used by templates", "templateCount": usedBy})
+		return
+	}
+
+	slog.InfoContext(c, "deleting email layout", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("key", key))
+
+	if err := h.messagingDBConn.DeleteEmailLayout(token.InstanceID, key); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "email layout not found"})
+			return
+		}
+		slog.ErrorContext(c, "error deleting email layout", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting email layout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "email layout deleted"})
+}
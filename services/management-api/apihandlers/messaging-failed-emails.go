@@ -0,0 +1,141 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) addMessagingFailedEmailsAPI(rg *gin.RouterGroup) {
+	rg.GET("/", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_FAILED_EMAILS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.getFailedEmails,
+	))
+
+	rg.GET("/:id", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_FAILED_EMAILS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.getFailedEmail,
+	))
+
+	rg.POST("/:id/requeue", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_FAILED_EMAILS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.requeueFailedEmail,
+	))
+
+	rg.DELETE("/:id", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_FAILED_EMAILS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.deleteFailedEmail,
+	))
+}
+
+func (h *HttpEndpoints) getFailedEmails(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page parameter"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	slog.InfoContext(c, "getting failed emails", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	emails, totalCount, err := h.messagingDBConn.GetFailedEmails(token.InstanceID, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "error getting failed emails", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting failed emails"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"emails":     emails,
+		"totalCount": totalCount,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+func (h *HttpEndpoints) getFailedEmail(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	slog.InfoContext(c, "getting failed email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+
+	email, err := h.messagingDBConn.GetFailedEmail(token.InstanceID, id)
+	if err != nil {
+		slog.ErrorContext(c, "error getting failed email", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "failed email not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting failed email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"email": email})
+}
+
+func (h *HttpEndpoints) requeueFailedEmail(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	slog.InfoContext(c, "requeueing failed email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+
+	email, err := h.messagingDBConn.RequeueFailedEmail(token.InstanceID, id)
+	if err != nil {
+		slog.ErrorContext(c, "error requeueing failed email", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "failed email not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error requeueing failed email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"email": email})
+}
+
+func (h *HttpEndpoints) deleteFailedEmail(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	slog.InfoContext(c, "deleting failed email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+
+	if err := h.messagingDBConn.DeleteFailedEmail(token.InstanceID, id); err != nil {
+		slog.ErrorContext(c, "error deleting failed email", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "failed email not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting failed email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "failed email deleted"})
+}
@@ -0,0 +1,67 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HttpEndpoints) addMessagingSandboxAPI(rg *gin.RouterGroup) {
+	permission := RequiredPermission{
+		ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+		ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_SANDBOX},
+		Action:       pc.ACTION_ALL,
+	}
+
+	rg.GET("/messages", h.useAuthorisedHandler(permission, nil, h.getCapturedMessages))
+	rg.DELETE("/messages", h.useAuthorisedHandler(permission, nil, h.deleteCapturedMessages))
+}
+
+func (h *HttpEndpoints) getCapturedMessages(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page parameter"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+	channel := c.Query("channel")
+
+	slog.InfoContext(c, "getting captured sandbox messages", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	messages, totalCount, err := h.messagingDBConn.GetCapturedMessages(token.InstanceID, channel, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "error getting captured messages", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting captured messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"messages":   messages,
+		"totalCount": totalCount,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+func (h *HttpEndpoints) deleteCapturedMessages(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "deleting captured sandbox messages", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	count, err := h.messagingDBConn.DeleteCapturedMessages(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error deleting captured messages", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting captured messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "captured messages deleted", "count": count})
+}
@@ -3,10 +3,12 @@ package apihandlers
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
 	emailtemplates "github.com/case-framework/case-backend/pkg/messaging/email-templates"
 	"github.com/case-framework/case-backend/pkg/messaging/templates"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
@@ -18,7 +20,7 @@ import (
 func (h *HttpEndpoints) AddMessagingServiceAPI(rg *gin.RouterGroup) {
 	messagingGroup := rg.Group("/messaging")
 
-	messagingGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.allowedInstanceIDs, h.muDBConn))
+	messagingGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn))
 
 	emailTemplatesGroup := messagingGroup.Group("/email-templates")
 
@@ -28,13 +30,64 @@ func (h *HttpEndpoints) AddMessagingServiceAPI(rg *gin.RouterGroup) {
 	// Add study email templates
 	h.addMessagingStudyEmailTemplatesAPI(emailTemplatesGroup)
 
+	// Version history, validation and previews of email templates
+	h.addMessagingGlobalEmailTemplateVersionsAPI(emailTemplatesGroup)
+	h.addMessagingStudyEmailTemplateVersionsAPI(emailTemplatesGroup)
+	h.addMessagingEmailTemplateToolsAPI(emailTemplatesGroup)
+
+	// Shared layouts for email templates
+	emailLayoutsGroup := messagingGroup.Group("/email-layouts")
+	h.addMessagingEmailLayoutsAPI(emailLayoutsGroup)
+
 	// Scheduled emails
 	scheduledEmailsGroup := messagingGroup.Group("/scheduled-emails")
 	h.addMessagingScheduledEmailsAPI(scheduledEmailsGroup)
 
+	// Campaigns
+	campaignsGroup := messagingGroup.Group("/campaigns")
+	h.addMessagingCampaignsAPI(campaignsGroup)
+
+	// Emails that could not be delivered
+	failedEmailsGroup := messagingGroup.Group("/failed-emails")
+	h.addMessagingFailedEmailsAPI(failedEmailsGroup)
+
+	// Webhook endpoints and their delivery logs
+	webhooksGroup := messagingGroup.Group("/webhooks")
+	h.addMessagingWebhooksAPI(webhooksGroup)
+
+	// Messages captured by the sandbox mode
+	sandboxGroup := messagingGroup.Group("/sandbox")
+	h.addMessagingSandboxAPI(sandboxGroup)
+
 	// SMS templates
 	smsTemplatesGroup := messagingGroup.Group("/sms-templates")
 	h.addMessagingSMSTemplatesAPI(smsTemplatesGroup)
+
+	// References between templates, schedules and study rules
+	messagingGroup.GET("/dependencies", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{
+				pc.RESOURCE_KEY_MESSAGING_GLOBAL_EMAIL_TEMPLATES,
+				pc.RESOURCE_KEY_MESSAGING_STUDY_EMAIL_TEMPLATES,
+				pc.RESOURCE_KEY_MESSAGING_SCHEDULED_EMAILS,
+			},
+			Action: pc.ACTION_ALL,
+		},
+		nil,
+		h.getMessagingDependencies,
+	))
+
+	// Aggregated open and click metrics
+	messagingGroup.GET("/tracking-stats", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_SCHEDULED_EMAILS},
+			Action:       pc.ACTION_ALL,
+		},
+		nil,
+		h.getEmailTrackingStats,
+	))
 }
 
 func (h *HttpEndpoints) addMessagingGlobalEmailTemplatesAPI(rg *gin.RouterGroup) {
@@ -198,11 +251,11 @@ func (h *HttpEndpoints) addMessagingScheduledEmailsAPI(rg *gin.RouterGroup) {
 
 func (h *HttpEndpoints) getGlobalMessageTemplates(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
-	slog.Info("getting global message templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting global message templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	messages, err := h.messagingDBConn.GetGlobalEmailTemplates(token.InstanceID)
 	if err != nil {
-		slog.Error("error getting global message templates", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting global message templates", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting global message templates"})
 		return
 	}
@@ -215,34 +268,39 @@ func (h *HttpEndpoints) saveGlobalMessageTemplate(c *gin.Context) {
 	// parse body
 	var template messagingTypes.EmailTemplate
 	if err := c.ShouldBindJSON(&template); err != nil {
-		slog.Error("error parsing request body", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
 		return
 	}
 
-	err := emailtemplates.CheckAllTranslationsParsable(template)
+	err := h.checkEmailTemplateValidity(token.InstanceID, template)
 	if err != nil {
-		slog.Error("error parsing template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing template", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error while checking template validity"})
 		return
 	}
 
-	slog.Info("saving global message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "saving global message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
-	savedTemplate, err := h.messagingDBConn.SaveEmailTemplate(token.InstanceID, template)
+	savedTemplate, err := h.messagingDBConn.SaveEmailTemplateWithHistory(token.InstanceID, template, token.Subject)
 	if err != nil {
-		slog.Error("error saving global message template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error saving global message template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving global message template"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"template": savedTemplate})
+
+	unknownPlaceholders, err := emailtemplates.FindUnknownPlaceholders(savedTemplate, knownTemplateVariables())
+	if err != nil {
+		slog.WarnContext(c, "error checking template placeholders", slog.String("error", err.Error()))
+	}
+	c.JSON(http.StatusOK, gin.H{"template": savedTemplate, "unknownPlaceholders": unknownPlaceholders})
 }
 
 func (h *HttpEndpoints) getGlobalMessageTemplate(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	messageType := c.Param("messageType")
 
-	slog.Info("getting global message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageType", messageType))
+	slog.InfoContext(c, "getting global message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageType", messageType))
 
 	message, err := h.messagingDBConn.GetGlobalEmailTemplateByMessageType(token.InstanceID, messageType)
 	if err != nil {
@@ -255,7 +313,7 @@ func (h *HttpEndpoints) getGlobalMessageTemplate(c *gin.Context) {
 			return
 		}
 
-		slog.Error("error getting global message template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting global message template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting global message template"})
 		return
 	}
@@ -267,11 +325,11 @@ func (h *HttpEndpoints) deleteGlobalMessageTemplate(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	messageType := c.Param("messageType")
 
-	slog.Info("deleting global message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageType", messageType))
+	slog.InfoContext(c, "deleting global message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageType", messageType))
 
 	err := h.messagingDBConn.DeleteEmailTemplate(token.InstanceID, messageType, "")
 	if err != nil {
-		slog.Error("error deleting global message template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting global message template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting global message template"})
 		return
 	}
@@ -283,7 +341,7 @@ func (h *HttpEndpoints) getSMSTemplate(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	messageType := c.Param("messageType")
 
-	slog.Info("getting SMS template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageType", messageType))
+	slog.InfoContext(c, "getting SMS template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageType", messageType))
 
 	message, err := h.messagingDBConn.GetSMSTemplateByType(token.InstanceID, messageType)
 	if err != nil {
@@ -296,7 +354,7 @@ func (h *HttpEndpoints) getSMSTemplate(c *gin.Context) {
 			return
 		}
 
-		slog.Error("error getting SMS template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting SMS template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting SMS template"})
 		return
 	}
@@ -310,23 +368,23 @@ func (h *HttpEndpoints) saveSMSTemplate(c *gin.Context) {
 	// parse body
 	var template messagingTypes.SMSTemplate
 	if err := c.ShouldBindJSON(&template); err != nil {
-		slog.Error("error parsing request body", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
 		return
 	}
 
 	err := templates.CheckAllTranslationsParsable(template.Translations, template.MessageType)
 	if err != nil {
-		slog.Error("error parsing template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing template", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error while checking template validity"})
 		return
 	}
 
-	slog.Info("saving SMS template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "saving SMS template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	savedTemplate, err := h.messagingDBConn.SaveSMSTemplate(token.InstanceID, template)
 	if err != nil {
-		slog.Error("error saving SMS template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error saving SMS template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving SMS template"})
 		return
 	}
@@ -335,11 +393,11 @@ func (h *HttpEndpoints) saveSMSTemplate(c *gin.Context) {
 
 func (h *HttpEndpoints) getStudyMessageTemplatesForAllStudies(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
-	slog.Info("getting study message templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting study message templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	messages, err := h.messagingDBConn.GetEmailTemplatesForAllStudies(token.InstanceID)
 	if err != nil {
-		slog.Error("error getting study message templates", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting study message templates", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting study message templates"})
 		return
 	}
@@ -350,11 +408,11 @@ func (h *HttpEndpoints) getStudyMessageTemplates(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting study message templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting study message templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	messages, err := h.messagingDBConn.GetStudyEmailTemplates(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("error getting study message templates", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting study message templates", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting study message templates"})
 		return
 	}
@@ -368,28 +426,33 @@ func (h *HttpEndpoints) saveStudyMessageTemplate(c *gin.Context) {
 	// parse body
 	var template messagingTypes.EmailTemplate
 	if err := c.ShouldBindJSON(&template); err != nil {
-		slog.Error("error parsing request body", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
 		return
 	}
 	template.StudyKey = studyKey
 
-	err := emailtemplates.CheckAllTranslationsParsable(template)
+	err := h.checkEmailTemplateValidity(token.InstanceID, template)
 	if err != nil {
-		slog.Error("error parsing template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing template", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error while checking template validity"})
 		return
 	}
 
-	slog.Info("saving study message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "saving study message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
-	savedTemplate, err := h.messagingDBConn.SaveEmailTemplate(token.InstanceID, template)
+	savedTemplate, err := h.messagingDBConn.SaveEmailTemplateWithHistory(token.InstanceID, template, token.Subject)
 	if err != nil {
-		slog.Error("error saving study message template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error saving study message template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving study message template"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"template": savedTemplate})
+
+	unknownPlaceholders, err := emailtemplates.FindUnknownPlaceholders(savedTemplate, knownTemplateVariables())
+	if err != nil {
+		slog.WarnContext(c, "error checking template placeholders", slog.String("error", err.Error()))
+	}
+	c.JSON(http.StatusOK, gin.H{"template": savedTemplate, "unknownPlaceholders": unknownPlaceholders})
 }
 
 func (h *HttpEndpoints) getStudyMessageTemplate(c *gin.Context) {
@@ -397,11 +460,11 @@ func (h *HttpEndpoints) getStudyMessageTemplate(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	messageType := c.Param("messageType")
 
-	slog.Info("getting study message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("messageType", messageType))
+	slog.InfoContext(c, "getting study message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("messageType", messageType))
 
 	message, err := h.messagingDBConn.GetStudyEmailTemplateByMessageType(token.InstanceID, studyKey, messageType)
 	if err != nil {
-		slog.Error("error getting study message template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting study message template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting study message template"})
 		return
 	}
@@ -413,11 +476,11 @@ func (h *HttpEndpoints) deleteStudyMessageTemplate(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	messageType := c.Param("messageType")
 
-	slog.Info("deleting study message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("messageType", messageType))
+	slog.InfoContext(c, "deleting study message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("messageType", messageType))
 
 	err := h.messagingDBConn.DeleteEmailTemplate(token.InstanceID, messageType, studyKey)
 	if err != nil {
-		slog.Error("error deleting study message template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting study message template", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting study message template"})
 		return
 	}
@@ -428,11 +491,11 @@ func (h *HttpEndpoints) deleteStudyMessageTemplate(c *gin.Context) {
 func (h *HttpEndpoints) getScheduledEmails(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
-	slog.Info("getting scheduled emails", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting scheduled emails", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	schedules, err := h.messagingDBConn.GetAllScheduledEmails(token.InstanceID)
 	if err != nil {
-		slog.Error("error getting scheduled emails", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting scheduled emails", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting scheduled emails"})
 		return
 	}
@@ -445,30 +508,37 @@ func (h *HttpEndpoints) SaveScheduledEmail(c *gin.Context) {
 	// parse body
 	var schedule messagingTypes.ScheduledEmail
 	if err := c.ShouldBindJSON(&schedule); err != nil {
-		slog.Error("error parsing request body", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
 		return
 	}
 
-	slog.Info("saving scheduled email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "saving scheduled email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	// check if template is valid
-	err := emailtemplates.CheckAllTranslationsParsable(schedule.Template)
+	err := h.checkEmailTemplateValidity(token.InstanceID, schedule.Template)
 	if err != nil {
-		slog.Error("error parsing template", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error parsing template", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error while checking template validity"})
 		return
 	}
 
+	schedule.Attachments, err = emailsending.PrepareAttachments(schedule.Attachments)
+	if err != nil {
+		slog.ErrorContext(c, "invalid attachments", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// ensure that times are in the future
 	if 0 < schedule.Until {
 		if schedule.Until < time.Now().Unix() {
-			slog.Error("error saving scheduled email", slog.String("error", "invalid termination date of auto message schedule, is in past"))
+			slog.ErrorContext(c, "error saving scheduled email", slog.String("error", "invalid termination date of auto message schedule, is in past"))
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid termination date of auto message schedule, is in past"})
 			return
 		}
 		if schedule.Until < schedule.NextTime {
-			slog.Error("error saving scheduled email", slog.String("error", "invalid termination date of auto message schedule, earlier than start date"))
+			slog.ErrorContext(c, "error saving scheduled email", slog.String("error", "invalid termination date of auto message schedule, earlier than start date"))
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid termination date of auto message schedule, earlier than start date"})
 			return
 		}
@@ -476,7 +546,7 @@ func (h *HttpEndpoints) SaveScheduledEmail(c *gin.Context) {
 
 	savedSchedule, err := h.messagingDBConn.SaveScheduledEmail(token.InstanceID, schedule)
 	if err != nil {
-		slog.Error("error saving scheduled email", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error saving scheduled email", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving scheduled email"})
 		return
 	}
@@ -487,11 +557,11 @@ func (h *HttpEndpoints) getScheduledEmail(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	id := c.Param("id")
 
-	slog.Info("getting scheduled email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+	slog.InfoContext(c, "getting scheduled email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
 
 	schedule, err := h.messagingDBConn.GetScheduledEmailByID(token.InstanceID, id)
 	if err != nil {
-		slog.Error("error getting scheduled email", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting scheduled email", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting scheduled email"})
 		return
 	}
@@ -502,14 +572,39 @@ func (h *HttpEndpoints) deleteScheduledEmail(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	id := c.Param("id")
 
-	slog.Info("deleting scheduled email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+	slog.InfoContext(c, "deleting scheduled email", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
 
 	err := h.messagingDBConn.DeleteScheduledEmail(token.InstanceID, id)
 	if err != nil {
-		slog.Error("error deleting scheduled email", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting scheduled email", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting scheduled email"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "schedule deleted"})
 }
+
+func (h *HttpEndpoints) getEmailTrackingStats(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	campaign := c.Query("campaign")
+
+	since := time.Now().AddDate(0, 0, -90)
+	if sinceParam := c.Query("since"); sinceParam != "" {
+		ts, err := strconv.ParseInt(sinceParam, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since parameter"})
+			return
+		}
+		since = time.Unix(ts, 0)
+	}
+
+	slog.InfoContext(c, "getting email tracking stats", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("campaign", campaign))
+
+	stats, err := h.messagingDBConn.GetEmailTrackingStats(token.InstanceID, campaign, since)
+	if err != nil {
+		slog.ErrorContext(c, "error getting email tracking stats", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email tracking stats"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
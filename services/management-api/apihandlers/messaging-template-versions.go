@@ -0,0 +1,295 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	emailtemplates "github.com/case-framework/case-backend/pkg/messaging/email-templates"
+	"github.com/case-framework/case-backend/pkg/messaging/templates"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) addMessagingEmailTemplateToolsAPI(rg *gin.RouterGroup) {
+	anyTemplatePermission := RequiredPermission{
+		ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+		ResourceKeys: []string{
+			pc.RESOURCE_KEY_MESSAGING_GLOBAL_EMAIL_TEMPLATES,
+			pc.RESOURCE_KEY_MESSAGING_STUDY_EMAIL_TEMPLATES,
+		},
+		Action: pc.ACTION_ALL,
+	}
+
+	rg.GET("/known-variables", h.useAuthorisedHandler(anyTemplatePermission, nil, h.getKnownTemplateVariables))
+	rg.POST("/validate", mw.RequirePayload(), h.useAuthorisedHandler(anyTemplatePermission, nil, h.validateEmailTemplate))
+	rg.POST("/preview", mw.RequirePayload(), h.useAuthorisedHandler(anyTemplatePermission, nil, h.previewEmailTemplate))
+}
+
+func (h *HttpEndpoints) addMessagingGlobalEmailTemplateVersionsAPI(rg *gin.RouterGroup) {
+	permission := RequiredPermission{
+		ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+		ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_GLOBAL_EMAIL_TEMPLATES},
+		Action:       pc.ACTION_ALL,
+	}
+
+	rg.GET("/global-templates/:messageType/versions", h.useAuthorisedHandler(permission, nil, h.getEmailTemplateVersions))
+	rg.GET("/global-templates/:messageType/versions/:version", h.useAuthorisedHandler(permission, nil, h.getEmailTemplateVersion))
+	rg.POST("/global-templates/:messageType/versions/:version/restore", h.useAuthorisedHandler(permission, nil, h.restoreEmailTemplateVersion))
+	rg.GET("/global-templates/:messageType/diff", h.useAuthorisedHandler(permission, nil, h.diffEmailTemplateVersions)) // ?from=1&to=2
+}
+
+func (h *HttpEndpoints) addMessagingStudyEmailTemplateVersionsAPI(rg *gin.RouterGroup) {
+	permission := RequiredPermission{
+		ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+		ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_STUDY_EMAIL_TEMPLATES},
+		Action:       pc.ACTION_ALL,
+	}
+
+	rg.GET("/study-templates/:studyKey/:messageType/versions", h.useAuthorisedHandler(permission, getStudyKeyLimiterFromContext, h.getEmailTemplateVersions))
+	rg.GET("/study-templates/:studyKey/:messageType/versions/:version", h.useAuthorisedHandler(permission, getStudyKeyLimiterFromContext, h.getEmailTemplateVersion))
+	rg.POST("/study-templates/:studyKey/:messageType/versions/:version/restore", h.useAuthorisedHandler(permission, getStudyKeyLimiterFromContext, h.restoreEmailTemplateVersion))
+	rg.GET("/study-templates/:studyKey/:messageType/diff", h.useAuthorisedHandler(permission, getStudyKeyLimiterFromContext, h.diffEmailTemplateVersions)) // ?from=1&to=2
+}
+
+// knownTemplateVariables returns the variables the backend fills in, including the configured global constants
+func knownTemplateVariables() []string {
+	known := append([]string{}, templates.KnownTemplateVariables...)
+	for k := range emailsending.GlobalTemplateInfos() {
+		known = append(known, k)
+	}
+	return known
+}
+
+func (h *HttpEndpoints) getKnownTemplateVariables(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"variables": knownTemplateVariables(),
+		"prefixes":  templates.KnownTemplateVariablePrefixes,
+	})
+}
+
+func (h *HttpEndpoints) validateEmailTemplate(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var template messagingTypes.EmailTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+
+	if err := h.checkEmailTemplateValidity(token.InstanceID, template); err != nil {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "error": err.Error()})
+		return
+	}
+
+	unknown, err := emailtemplates.FindUnknownPlaceholders(template, knownTemplateVariables())
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": len(unknown) == 0, "unknownPlaceholders": unknown})
+}
+
+type PreviewEmailTemplateReq struct {
+	Template   messagingTypes.EmailTemplate `json:"template"`
+	Lang       string                       `json:"lang"`
+	SampleData map[string]string            `json:"sampleData"`
+}
+
+func (h *HttpEndpoints) previewEmailTemplate(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var req PreviewEmailTemplateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+
+	lang := req.Lang
+	if lang == "" {
+		lang = req.Template.DefaultLanguage
+	}
+
+	payload := map[string]string{"language": lang}
+	for k, v := range req.SampleData {
+		payload[k] = v
+	}
+
+	slog.DebugContext(c, "rendering email template preview", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageType", req.Template.MessageType), slog.String("lang", lang))
+
+	subject, content, err := emailsending.GenerateEmailContent(token.InstanceID, req.Template, lang, payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subject": subject, "content": content})
+}
+
+func (h *HttpEndpoints) getEmailTemplateVersions(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	messageType := c.Param("messageType")
+
+	slog.InfoContext(c, "getting email template versions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("messageType", messageType))
+
+	versions, err := h.messagingDBConn.GetEmailTemplateVersions(token.InstanceID, messageType, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "error getting email template versions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email template versions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+func (h *HttpEndpoints) getEmailTemplateVersion(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	messageType := c.Param("messageType")
+
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	templateVersion, err := h.messagingDBConn.GetEmailTemplateVersion(token.InstanceID, messageType, studyKey, version)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+			return
+		}
+		slog.ErrorContext(c, "error getting email template version", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email template version"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"version": templateVersion})
+}
+
+func (h *HttpEndpoints) restoreEmailTemplateVersion(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	messageType := c.Param("messageType")
+
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	templateVersion, err := h.messagingDBConn.GetEmailTemplateVersion(token.InstanceID, messageType, studyKey, version)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+			return
+		}
+		slog.ErrorContext(c, "error getting email template version", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email template version"})
+		return
+	}
+
+	restored := templateVersion.Template
+	current, err := h.getCurrentEmailTemplate(token.InstanceID, messageType, studyKey)
+	if err != nil && err != mongo.ErrNoDocuments {
+		slog.ErrorContext(c, "error getting email template", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email template"})
+		return
+	}
+	if current != nil {
+		restored.ID = current.ID
+	} else {
+		// template was deleted since, insert it again
+		restored.ID = primitive.NilObjectID
+	}
+
+	slog.InfoContext(c, "restoring email template version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("messageType", messageType), slog.Int64("version", version))
+
+	saved, err := h.messagingDBConn.SaveEmailTemplateWithHistory(token.InstanceID, restored, token.Subject)
+	if err != nil {
+		slog.ErrorContext(c, "error restoring email template version", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error restoring email template version"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"template": saved})
+}
+
+func (h *HttpEndpoints) diffEmailTemplateVersions(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	messageType := c.Param("messageType")
+
+	from, err := strconv.ParseInt(c.Query("from"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from version"})
+		return
+	}
+
+	fromVersion, err := h.messagingDBConn.GetEmailTemplateVersion(token.InstanceID, messageType, studyKey, from)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "from version not found"})
+			return
+		}
+		slog.ErrorContext(c, "error getting email template version", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email template version"})
+		return
+	}
+
+	// compare with the current template if no target version is given
+	var toTemplate *messagingTypes.EmailTemplate
+	if c.Query("to") != "" {
+		to, err := strconv.ParseInt(c.Query("to"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to version"})
+			return
+		}
+		toVersion, err := h.messagingDBConn.GetEmailTemplateVersion(token.InstanceID, messageType, studyKey, to)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "to version not found"})
+				return
+			}
+			slog.ErrorContext(c, "error getting email template version", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email template version"})
+			return
+		}
+		toTemplate = &toVersion.Template
+	} else {
+		toTemplate, err = h.getCurrentEmailTemplate(token.InstanceID, messageType, studyKey)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+				return
+			}
+			slog.ErrorContext(c, "error getting email template", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting email template"})
+			return
+		}
+	}
+
+	diff, err := emailtemplates.DiffEmailTemplates(fromVersion.Template, *toTemplate)
+	if err != nil {
+		slog.ErrorContext(c, "error comparing email templates", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from": fromVersion.Version,
+		"to":   toTemplate.Version,
+		"diff": diff,
+	})
+}
+
+func (h *HttpEndpoints) getCurrentEmailTemplate(instanceID string, messageType string, studyKey string) (*messagingTypes.EmailTemplate, error) {
+	if studyKey == "" {
+		return h.messagingDBConn.GetGlobalEmailTemplateByMessageType(instanceID, messageType)
+	}
+	return h.messagingDBConn.GetStudyEmailTemplateByMessageType(instanceID, studyKey, messageType)
+}
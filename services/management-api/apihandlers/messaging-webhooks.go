@@ -0,0 +1,267 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) addMessagingWebhooksAPI(rg *gin.RouterGroup) {
+	permission := RequiredPermission{
+		ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+		ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_WEBHOOKS},
+		Action:       pc.ACTION_ALL,
+	}
+
+	rg.GET("/events", h.useAuthorisedHandler(permission, nil, h.getWebhookEvents))
+	rg.GET("/", h.useAuthorisedHandler(permission, nil, h.getWebhookEndpoints))
+	rg.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(permission, nil, h.createWebhookEndpoint))
+	rg.GET("/:id", h.useAuthorisedHandler(permission, nil, h.getWebhookEndpoint))
+	rg.PUT("/:id", mw.RequirePayload(), h.useAuthorisedHandler(permission, nil, h.updateWebhookEndpoint))
+	rg.DELETE("/:id", h.useAuthorisedHandler(permission, nil, h.deleteWebhookEndpoint))
+	rg.POST("/:id/rotate-secret", h.useAuthorisedHandler(permission, nil, h.rotateWebhookSecret))
+	rg.POST("/:id/test", h.useAuthorisedHandler(permission, nil, h.testWebhookEndpoint))
+	rg.GET("/:id/deliveries", h.useAuthorisedHandler(permission, nil, h.getWebhookDeliveries))
+	rg.POST("/:id/deliveries/:deliveryID/redeliver", h.useAuthorisedHandler(permission, nil, h.redeliverWebhook))
+}
+
+// the secret is only shown once, when it is generated
+func maskWebhookSecret(endpoint *messagingTypes.WebhookEndpoint) {
+	endpoint.Secret = ""
+}
+
+func (h *HttpEndpoints) getWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"events": webhooks.SupportedEvents})
+}
+
+func (h *HttpEndpoints) getWebhookEndpoints(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting webhook endpoints", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	endpoints, err := h.messagingDBConn.GetWebhookEndpoints(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "error getting webhook endpoints", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting webhook endpoints"})
+		return
+	}
+	for i := range endpoints {
+		maskWebhookSecret(&endpoints[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"endpoints": endpoints})
+}
+
+func (h *HttpEndpoints) getWebhookEndpoint(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	endpoint, ok := h.findWebhookEndpoint(c, token.InstanceID, c.Param("id"))
+	if !ok {
+		return
+	}
+	maskWebhookSecret(endpoint)
+	c.JSON(http.StatusOK, gin.H{"endpoint": endpoint})
+}
+
+func (h *HttpEndpoints) createWebhookEndpoint(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var endpoint messagingTypes.WebhookEndpoint
+	if err := c.ShouldBindJSON(&endpoint); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+	if err := webhooks.ValidateEndpoint(endpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "creating webhook endpoint", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("url", endpoint.URL))
+
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		slog.ErrorContext(c, "error generating webhook secret", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error creating webhook endpoint"})
+		return
+	}
+	endpoint.ID = primitive.NilObjectID
+	endpoint.Secret = secret
+
+	saved, err := h.messagingDBConn.SaveWebhookEndpoint(token.InstanceID, endpoint)
+	if err != nil {
+		slog.ErrorContext(c, "error saving webhook endpoint", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error creating webhook endpoint"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"endpoint": saved})
+}
+
+func (h *HttpEndpoints) updateWebhookEndpoint(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	existing, ok := h.findWebhookEndpoint(c, token.InstanceID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var endpoint messagingTypes.WebhookEndpoint
+	if err := c.ShouldBindJSON(&endpoint); err != nil {
+		slog.ErrorContext(c, "error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+	if err := webhooks.ValidateEndpoint(endpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "updating webhook endpoint", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", existing.ID.Hex()))
+
+	endpoint.ID = existing.ID
+	saved, err := h.messagingDBConn.SaveWebhookEndpoint(token.InstanceID, endpoint)
+	if err != nil {
+		slog.ErrorContext(c, "error saving webhook endpoint", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving webhook endpoint"})
+		return
+	}
+	maskWebhookSecret(&saved)
+	c.JSON(http.StatusOK, gin.H{"endpoint": saved})
+}
+
+func (h *HttpEndpoints) deleteWebhookEndpoint(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	slog.InfoContext(c, "deleting webhook endpoint", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+
+	err := h.messagingDBConn.DeleteWebhookEndpoint(token.InstanceID, id)
+	if err != nil {
+		slog.ErrorContext(c, "error deleting webhook endpoint", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook endpoint not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting webhook endpoint"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "webhook endpoint deleted"})
+}
+
+func (h *HttpEndpoints) rotateWebhookSecret(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	endpoint, ok := h.findWebhookEndpoint(c, token.InstanceID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	slog.InfoContext(c, "rotating webhook secret", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", endpoint.ID.Hex()))
+
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		slog.ErrorContext(c, "error generating webhook secret", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error rotating webhook secret"})
+		return
+	}
+	if err := h.messagingDBConn.UpdateWebhookEndpointSecret(token.InstanceID, endpoint.ID, secret); err != nil {
+		slog.ErrorContext(c, "error saving webhook secret", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error rotating webhook secret"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"secret": secret})
+}
+
+func (h *HttpEndpoints) testWebhookEndpoint(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	endpoint, ok := h.findWebhookEndpoint(c, token.InstanceID, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	slog.InfoContext(c, "sending webhook ping", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", endpoint.ID.Hex()))
+
+	delivery, err := webhooks.SendPing(token.InstanceID, *endpoint)
+	if err != nil {
+		slog.ErrorContext(c, "error sending webhook ping", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error sending webhook ping"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
+
+func (h *HttpEndpoints) getWebhookDeliveries(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page parameter"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+	status := c.Query("status")
+
+	slog.InfoContext(c, "getting webhook deliveries", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id))
+
+	deliveries, totalCount, err := h.messagingDBConn.GetWebhookDeliveries(token.InstanceID, id, status, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "error getting webhook deliveries", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting webhook deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"totalCount": totalCount,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+func (h *HttpEndpoints) redeliverWebhook(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	id := c.Param("id")
+	deliveryID := c.Param("deliveryID")
+
+	slog.InfoContext(c, "requeueing webhook delivery", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("id", id), slog.String("deliveryID", deliveryID))
+
+	err := h.messagingDBConn.ResetWebhookDelivery(token.InstanceID, id, deliveryID)
+	if err != nil {
+		slog.ErrorContext(c, "error requeueing webhook delivery", slog.String("error", err.Error()))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook delivery not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error requeueing webhook delivery"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "webhook delivery requeued"})
+}
+
+func (h *HttpEndpoints) findWebhookEndpoint(c *gin.Context, instanceID string, id string) (*messagingTypes.WebhookEndpoint, bool) {
+	endpoint, err := h.messagingDBConn.GetWebhookEndpoint(instanceID, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook endpoint not found"})
+			return nil, false
+		}
+		slog.ErrorContext(c, "error getting webhook endpoint", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting webhook endpoint"})
+		return nil, false
+	}
+	return endpoint, true
+}
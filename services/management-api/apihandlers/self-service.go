@@ -0,0 +1,329 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	managementalerts "github.com/case-framework/case-backend/pkg/messaging/management-alerts"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
+	"github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxAccessTokensPerUser    = 20
+	maxAccessTokenScopes      = 50
+	defaultAccessTokenTTL     = 90 * 24 * time.Hour
+	maxAccessTokenTTL         = 365 * 24 * time.Hour
+	accessTokenHintLength     = 4
+	maxAccessTokenLabelLength = 100
+)
+
+var accessTokenResourceTypes = []string{
+	pc.RESOURCE_TYPE_STUDY,
+	pc.RESOURCE_TYPE_MESSAGING,
+	pc.RESOURCE_TYPE_USERS,
+}
+
+// AddSelfServiceAPI adds the endpoints with which management users manage their own access tokens, sessions and
+// alert subscriptions. They are only available with a session, not with an access token or for service accounts.
+func (h *HttpEndpoints) AddSelfServiceAPI(rg *gin.RouterGroup) {
+	meGroup := rg.Group("/me")
+	meGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn))
+	meGroup.Use(mw.RejectAccessTokens())
+	meGroup.Use(rejectServiceAccounts)
+	{
+		meGroup.GET("/access-tokens", h.getMyAccessTokens)
+		meGroup.POST("/access-tokens", mw.RequirePayload(), h.createMyAccessToken)
+		meGroup.DELETE("/access-tokens/:tokenID", h.deleteMyAccessToken)
+
+		meGroup.GET("/sessions", h.getMySessions)
+		meGroup.DELETE("/sessions", h.deleteMySessions)
+		meGroup.DELETE("/sessions/:sessionID", h.deleteMySession)
+
+		meGroup.GET("/notification-settings", h.getMyNotificationSettings)
+		meGroup.PUT("/notification-settings", mw.RequirePayload(), h.updateMyNotificationSettings)
+	}
+}
+
+func rejectServiceAccounts(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	if token.IsServiceUser {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not available for service accounts"})
+	}
+}
+
+type CreateAccessTokenRequest struct {
+	Label  string                  `json:"label"`
+	Scopes []muDB.AccessTokenScope `json:"scopes"`
+	// unix timestamp, 90 days from now if not set, at most one year
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+func (h *HttpEndpoints) getMyAccessTokens(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting access tokens", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	accessTokens, err := h.muDBConn.GetAccessTokensByUserID(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get access tokens", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get access tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accessTokens": accessTokens})
+}
+
+func (h *HttpEndpoints) createMyAccessToken(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Label == "" || len(req.Label) > maxAccessTokenLabelLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "label is required and must be at most 100 characters"})
+		return
+	}
+	if err := validateAccessTokenScopes(req.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultAccessTokenTTL)
+	if req.ExpiresAt > 0 {
+		expiresAt = time.Unix(req.ExpiresAt, 0)
+		if !expiresAt.After(now) || expiresAt.After(now.Add(maxAccessTokenTTL)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future and at most one year from now"})
+			return
+		}
+	}
+
+	count, err := h.muDBConn.CountAccessTokensByUserID(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.ErrorContext(c, "failed to count access tokens", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create access token"})
+		return
+	}
+	if count >= maxAccessTokensPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many access tokens, delete unused ones first"})
+		return
+	}
+
+	tokenString, err := utils.GenerateUniqueTokenString()
+	if err != nil {
+		slog.ErrorContext(c, "failed to generate unique token string", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create access token"})
+		return
+	}
+	tokenString = muDB.ACCESS_TOKEN_PREFIX + tokenString
+
+	slog.InfoContext(c, "creating access token", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.Int("scopes", len(req.Scopes)))
+
+	accessToken, err := h.muDBConn.CreateAccessToken(token.InstanceID, muDB.AccessToken{
+		UserID:    token.Subject,
+		Label:     req.Label,
+		Hint:      tokenString[len(tokenString)-accessTokenHintLength:],
+		Scopes:    req.Scopes,
+		ExpiresAt: expiresAt,
+	}, tokenString)
+	if err != nil {
+		slog.ErrorContext(c, "failed to create access token", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create access token"})
+		return
+	}
+
+	emitPrivilegeChange(c, token, securityevents.ACTOR_MANAGEMENT_USER, token.Subject, map[string]string{"change": "access-token-created", "accessTokenID": accessToken.ID.Hex()})
+	// the token is only shown once, it is sent in the X-API-Key header with X-Instance-ID
+	c.JSON(http.StatusOK, gin.H{"accessToken": accessToken, "token": tokenString})
+}
+
+func validateAccessTokenScopes(scopes []muDB.AccessTokenScope) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	if len(scopes) > maxAccessTokenScopes {
+		return errors.New("too many scopes")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(accessTokenResourceTypes, scope.ResourceType) {
+			return errors.New("invalid resource type: " + scope.ResourceType)
+		}
+		if scope.ResourceKey == "" || scope.Action == "" {
+			return errors.New("resourceKey and action are required, use * for all")
+		}
+	}
+	return nil
+}
+
+func (h *HttpEndpoints) deleteMyAccessToken(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	tokenID := c.Param("tokenID")
+
+	slog.InfoContext(c, "deleting access token", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("accessTokenID", tokenID))
+
+	if err := h.muDBConn.DeleteAccessToken(token.InstanceID, token.Subject, tokenID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "access token not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to delete access token", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete access token"})
+		return
+	}
+
+	emitPrivilegeChange(c, token, securityevents.ACTOR_MANAGEMENT_USER, token.Subject, map[string]string{"change": "access-token-deleted", "accessTokenID": tokenID})
+	c.JSON(http.StatusOK, gin.H{"message": "access token deleted"})
+}
+
+// MySession is a session without its renew token
+type MySession struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (h *HttpEndpoints) getMySessions(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting sessions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	sessions, err := h.muDBConn.GetSessionsByUserID(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get sessions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sessions"})
+		return
+	}
+
+	mySessions := make([]MySession, len(sessions))
+	for i, session := range sessions {
+		mySessions[i] = MySession{ID: session.ID.Hex(), CreatedAt: session.CreatedAt}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": mySessions})
+}
+
+// deleteMySession revokes the session, it cannot be renewed anymore. Access tokens issued for it stay valid until
+// they expire.
+func (h *HttpEndpoints) deleteMySession(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	sessionID := c.Param("sessionID")
+
+	slog.InfoContext(c, "deleting session", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("sessionID", sessionID))
+
+	session, err := h.muDBConn.GetSession(token.InstanceID, sessionID)
+	if err != nil || session.UserID != token.Subject {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err := h.muDBConn.DeleteSession(token.InstanceID, sessionID); err != nil {
+		slog.ErrorContext(c, "failed to delete session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete session"})
+		return
+	}
+
+	emitSessionRevoked(c, token, 1)
+	c.JSON(http.StatusOK, gin.H{"message": "session deleted"})
+}
+
+func (h *HttpEndpoints) deleteMySessions(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "deleting all sessions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	if err := h.muDBConn.DeleteSessionsByUserID(token.InstanceID, token.Subject); err != nil {
+		slog.ErrorContext(c, "failed to delete sessions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete sessions"})
+		return
+	}
+
+	emitSessionRevoked(c, token, 0)
+	c.JSON(http.StatusOK, gin.H{"message": "sessions deleted"})
+}
+
+// emitSessionRevoked records revoked sessions in the security event log, count 0 for all sessions of the user
+func emitSessionRevoked(c *gin.Context, token *jwthandling.ManagementUserClaims, count int) {
+	scope := "single"
+	if count == 0 {
+		scope = "all"
+	}
+	securityevents.EmitForRequest(c, securityevents.Event{
+		Type:       securityevents.EVENT_TOKEN_REVOKED,
+		Outcome:    securityevents.OUTCOME_SUCCESS,
+		InstanceID: token.InstanceID,
+		ActorType:  securityevents.ACTOR_MANAGEMENT_USER,
+		ActorID:    token.Subject,
+		Details:    map[string]string{"sessions": scope},
+	})
+}
+
+type NotificationSettings struct {
+	// alert types the user receives by email
+	Alerts []string `json:"alerts"`
+}
+
+func (h *HttpEndpoints) getMyNotificationSettings(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	user, err := h.muDBConn.GetUserByID(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	alerts := user.AlertSubscriptions
+	if alerts == nil {
+		alerts = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"settings":        NotificationSettings{Alerts: alerts},
+		"supportedAlerts": managementalerts.SupportedAlerts,
+		"email":           user.Email,
+	})
+}
+
+func (h *HttpEndpoints) updateMyNotificationSettings(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	var req NotificationSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alerts := []string{}
+	for _, alert := range req.Alerts {
+		if !managementalerts.IsSupportedAlert(alert) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported alert: " + alert})
+			return
+		}
+		if !slices.Contains(alerts, alert) {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	slog.InfoContext(c, "updating notification settings", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	if err := h.muDBConn.UpdateUserAlertSubscriptions(token.InstanceID, token.Subject, alerts); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update notification settings", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update notification settings"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": NotificationSettings{Alerts: alerts}})
+}
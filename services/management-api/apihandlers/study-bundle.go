@@ -0,0 +1,309 @@
+package apihandlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/case-framework/case-backend/pkg/study/bundle"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MAX_STUDY_BUNDLE_SIZE = 64 << 20
+)
+
+var errStudyAlreadyExists = errors.New("study already exists")
+
+func (h *HttpEndpoints) addStudyBundleEndpoints(studiesGroup *gin.RouterGroup, studyGroup *gin.RouterGroup) {
+	studiesGroup.POST("/import", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys: []string{pc.RESOURCE_KEY_STUDY_ALL},
+			Action:       pc.ACTION_CREATE_STUDY,
+		},
+		nil,
+		h.importStudyBundle,
+	))
+
+	studyGroup.GET("/bundle", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_READ_STUDY_CONFIG,
+		},
+		nil,
+		h.exportStudyBundle,
+	))
+
+	studyGroup.POST("/clone", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys: []string{pc.RESOURCE_KEY_STUDY_ALL},
+			Action:       pc.ACTION_CREATE_STUDY,
+		},
+		nil,
+		h.cloneStudy,
+	))
+}
+
+func (h *HttpEndpoints) exportStudyBundle(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+
+	slog.InfoContext(c, "exporting study bundle", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("format", format))
+
+	b, err := h.buildStudyBundle(token.InstanceID, studyKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to build study bundle", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build study bundle"})
+		return
+	}
+
+	fileName := fmt.Sprintf("%s_bundle_%s", studyKey, b.ExportedAt.Format("2006-01-02"))
+	if format == "zip" {
+		buf := &bytes.Buffer{}
+		if err := bundle.WriteZip(buf, b); err != nil {
+			slog.ErrorContext(c, "failed to write study bundle", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write study bundle"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", fileName))
+		c.Data(http.StatusOK, "application/zip", buf.Bytes())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", fileName))
+	c.JSON(http.StatusOK, b)
+}
+
+// importStudyBundle creates a new study from a JSON or ZIP bundle. The study key of the bundle can be replaced with the studyKey query parameter.
+func (h *HttpEndpoints) importStudyBundle(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, MAX_STUDY_BUNDLE_SIZE+1))
+	if err != nil {
+		slog.ErrorContext(c, "failed to read request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if len(body) > MAX_STUDY_BUNDLE_SIZE {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "bundle is too large"})
+		return
+	}
+
+	var b bundle.StudyBundle
+	if strings.HasPrefix(c.ContentType(), "application/zip") {
+		b, err = bundle.ReadZip(bytes.NewReader(body), int64(len(body)))
+	} else {
+		err = json.Unmarshal(body, &b)
+	}
+	if err != nil {
+		slog.ErrorContext(c, "failed to parse study bundle", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid study bundle"})
+		return
+	}
+
+	studyKey := c.DefaultQuery("studyKey", b.Study.Key)
+
+	slog.InfoContext(c, "importing study bundle", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("sourceInstanceID", b.SourceInstanceID))
+
+	h.createStudyFromBundle(c, token, b, studyKey)
+}
+
+type CloneStudyReq struct {
+	StudyKey string `json:"studyKey"`
+}
+
+func (h *HttpEndpoints) cloneStudy(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	sourceStudyKey := c.Param("studyKey")
+
+	var req CloneStudyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "cloning study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", sourceStudyKey), slog.String("newStudyKey", req.StudyKey))
+
+	b, err := h.buildStudyBundle(token.InstanceID, sourceStudyKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to build study bundle", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build study bundle"})
+		return
+	}
+
+	h.createStudyFromBundle(c, token, b, req.StudyKey)
+}
+
+func (h *HttpEndpoints) createStudyFromBundle(c *gin.Context, token *jwthandling.ManagementUserClaims, b bundle.StudyBundle, studyKey string) {
+	if !utils.IsURLSafe(studyKey) || studyKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "study key is not URL safe"})
+		return
+	}
+	b = b.RemapStudyKey(studyKey)
+	if err := b.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	study, warnings, err := h.saveStudyBundle(token.InstanceID, b, token.Subject)
+	if err != nil {
+		if err == errStudyAlreadyExists {
+			c.JSON(http.StatusConflict, gin.H{"error": "study key already exists"})
+			return
+		}
+		slog.ErrorContext(c, "failed to import study bundle", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import study bundle", "warnings": warnings})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"study": study, "warnings": warnings})
+}
+
+// buildStudyBundle collects the study, the current versions of its published surveys, its current rules and its email templates
+func (h *HttpEndpoints) buildStudyBundle(instanceID string, studyKey string) (bundle.StudyBundle, error) {
+	study, err := h.studyDBConn.GetStudy(instanceID, studyKey)
+	if err != nil {
+		return bundle.StudyBundle{}, err
+	}
+
+	surveyKeys, err := h.studyDBConn.GetSurveyKeysForStudy(instanceID, studyKey, false)
+	if err != nil {
+		return bundle.StudyBundle{}, err
+	}
+	surveys := []studyTypes.Survey{}
+	for _, surveyKey := range surveyKeys {
+		survey, err := h.studyDBConn.GetCurrentSurveyVersion(instanceID, studyKey, surveyKey)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				continue
+			}
+			return bundle.StudyBundle{}, err
+		}
+		surveys = append(surveys, *survey)
+	}
+
+	var rules *studyTypes.StudyRules
+	currentRules, err := h.studyDBConn.GetCurrentStudyRules(instanceID, studyKey)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return bundle.StudyBundle{}, err
+		}
+	} else {
+		rules = &currentRules
+	}
+
+	emailTemplates, err := h.messagingDBConn.GetStudyEmailTemplates(instanceID, studyKey)
+	if err != nil {
+		return bundle.StudyBundle{}, err
+	}
+
+	return bundle.NewStudyBundle(instanceID, study, surveys, rules, emailTemplates), nil
+}
+
+// saveStudyBundle creates the study of the bundle in the instance. The study gets a new secret key and is created inactive,
+// so it can be reviewed before participants enter. Warnings list referenced items that are missing in this instance.
+func (h *HttpEndpoints) saveStudyBundle(instanceID string, b bundle.StudyBundle, userID string) (studyTypes.Study, []string, error) {
+	warnings := []string{}
+
+	if _, err := h.studyDBConn.GetStudy(instanceID, b.Study.Key); err == nil {
+		return studyTypes.Study{}, warnings, errStudyAlreadyExists
+	} else if err != mongo.ErrNoDocuments {
+		return studyTypes.Study{}, warnings, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return studyTypes.Study{}, warnings, err
+	}
+
+	study := b.Study
+	study.SecretKey = hex.EncodeToString(secret)
+	study.Status = studyTypes.STUDY_STATUS_INACTIVE
+	if study.Configs.IdMappingMethod == "" {
+		study.Configs.IdMappingMethod = studyTypes.DEFAULT_ID_MAPPING_METHOD
+	}
+	if err := h.studyDBConn.CreateStudy(instanceID, study); err != nil {
+		return study, warnings, err
+	}
+
+	now := time.Now()
+	for _, survey := range b.Surveys {
+		if survey.VersionID == "" {
+			survey.VersionID = utils.GenerateSurveyVersionID(nil)
+		}
+		survey.Published = now.Unix()
+		survey.Unpublished = 0
+		if err := h.studyDBConn.SaveSurveyVersion(instanceID, study.Key, &survey); err != nil {
+			return study, warnings, fmt.Errorf("failed to save survey %s: %w", survey.SurveyKey, err)
+		}
+	}
+
+	if b.StudyRules != nil {
+		rules := *b.StudyRules
+		rules.UploadedAt = now.Unix()
+		rules.UploadedBy = userID
+		if err := rules.MarshalRules(); err != nil {
+			return study, warnings, err
+		}
+		if err := h.studyDBConn.SaveStudyRules(instanceID, study.Key, rules); err != nil {
+			return study, warnings, fmt.Errorf("failed to save study rules: %w", err)
+		}
+	}
+
+	for _, template := range b.EmailTemplates {
+		if template.Layout != "" {
+			if _, err := h.messagingDBConn.GetEmailLayout(instanceID, template.Layout); err != nil {
+				warnings = append(warnings, fmt.Sprintf("email template %s uses layout %s, which does not exist in this instance", template.MessageType, template.Layout))
+			}
+		}
+		if err := h.saveImportedEmailTemplate(instanceID, template, userID); err != nil {
+			return study, warnings, fmt.Errorf("failed to save email template %s: %w", template.MessageType, err)
+		}
+	}
+
+	return study, warnings, nil
+}
+
+func (h *HttpEndpoints) saveImportedEmailTemplate(instanceID string, template messagingTypes.EmailTemplate, userID string) error {
+	existing, err := h.messagingDBConn.GetStudyEmailTemplateByMessageType(instanceID, template.StudyKey, template.MessageType)
+	if err == nil && existing != nil {
+		template.ID = existing.ID
+	}
+	_, err = h.messagingDBConn.SaveEmailTemplateWithHistory(instanceID, template, userID)
+	return err
+}
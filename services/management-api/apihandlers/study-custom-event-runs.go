@@ -0,0 +1,136 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	managementalerts "github.com/case-framework/case-backend/pkg/messaging/management-alerts"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type RunCustomEventRequest struct {
+	EventKey string                 `json:"eventKey"`
+	Payload  map[string]interface{} `json:"payload"`
+	// one participant, shortcut for a selection with only this participant ID
+	ParticipantID string `json:"participantId"`
+	// participants of a search, sort, cursor and limit are ignored. All participants if neither is set.
+	Selection *ParticipantSearchRequest `json:"selection"`
+}
+
+func (h *HttpEndpoints) runCustomEventOnParticipants(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req RunCustomEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.EventKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "eventKey is required"})
+		return
+	}
+	if req.ParticipantID != "" && req.Selection != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "use either participantId or selection"})
+		return
+	}
+
+	var filter bson.M
+	switch {
+	case req.ParticipantID != "":
+		filter = bson.M{"participantID": req.ParticipantID}
+	case req.Selection != nil:
+		query, ok := h.prepParticipantSearchQuery(c, token.InstanceID, studyKey, *req.Selection)
+		if !ok {
+			return
+		}
+		var err error
+		filter, err = studyDB.ParticipantSearchFilter(query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	slog.InfoContext(c, "running custom event on participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("eventKey", req.EventKey), slog.Bool("allParticipants", filter == nil))
+
+	relativeFolderName := filepath.Join(token.InstanceID, "actionRuns")
+	if err := os.MkdirAll(filepath.Join(h.filestorePath, relativeFolderName), os.ModePerm); err != nil {
+		slog.ErrorContext(c, "failed to create actionRuns folder", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create actionRuns folder"})
+		return
+	}
+
+	task, err := h.studyDBConn.CreateTask(
+		token.InstanceID,
+		token.Subject,
+		10000000000000, // updated when the participants are counted
+		studyTypes.TASK_FILE_TYPE_JSON,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to create task", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
+		return
+	}
+	taskID := task.ID.Hex()
+
+	ctx := requestid.Detach(c.Request.Context())
+	h.runInBackground("custom-event-run", func() {
+		results, err := studyService.OnRunCustomEvent(ctx, studyService.RunCustomEventReq{
+			InstanceID: token.InstanceID,
+			StudyKey:   studyKey,
+			EventKey:   req.EventKey,
+			Payload:    req.Payload,
+			Filter:     filter,
+			OnProgressFn: func(totalCount int64, processedCount int64) {
+				if processedCount == 0 {
+					if err := h.studyDBConn.UpdateTaskTotalCount(token.InstanceID, taskID, int(totalCount)); err != nil {
+						slog.Error("failed to update task total count", slog.String("error", err.Error()))
+					}
+					return
+				}
+				if err := h.studyDBConn.UpdateTaskProgress(token.InstanceID, taskID, int(processedCount)); err != nil {
+					// not a big issue, so let's try next time
+					slog.Error("failed to update task progress", slog.String("error", err.Error()))
+				}
+			},
+		})
+		if err != nil {
+			slog.Error("running custom event resulted in error", slog.String("eventKey", req.EventKey), slog.String("error", err.Error()))
+			h.taskFailed(token.InstanceID, taskID, err.Error())
+			managementalerts.Send(token.InstanceID, token.Subject, managementalerts.Alert{
+				Type:     managementalerts.ALERT_JOB_FAILED,
+				StudyKey: studyKey,
+				Title:    "Custom event run failed",
+				Details:  [][2]string{{"Task", taskID}, {"Event", req.EventKey}, {"Error", err.Error()}},
+			})
+			return
+		}
+
+		if results.FailedCount > 0 {
+			managementalerts.Send(token.InstanceID, token.Subject, managementalerts.Alert{
+				Type:     managementalerts.ALERT_RULE_ERRORS,
+				StudyKey: studyKey,
+				Title:    "Custom event run finished with rule errors",
+				Details: [][2]string{
+					{"Task", taskID},
+					{"Event", req.EventKey},
+					{"Failed participants", strconv.FormatInt(results.FailedCount, 10)},
+				},
+			})
+		}
+		h.completeTaskWithResults(taskID, results, int(results.ParticipantCount), token.InstanceID, relativeFolderName)
+	})
+
+	c.JSON(http.StatusOK, gin.H{"task": task})
+}
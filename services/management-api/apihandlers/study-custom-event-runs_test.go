@@ -0,0 +1,172 @@
+package apihandlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
+)
+
+// serveJSONWithToken calls the handler as the management user of the token with the body as JSON payload
+func serveJSONWithToken(handler gin.HandlerFunc, token *jwthandling.ManagementUserClaims, method string, route string, target string, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		c.Set("validatedToken", token)
+		handler(c)
+	})
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRunCustomEventOnParticipantsValidation(t *testing.T) {
+	h := &HttpEndpoints{}
+	token := &jwthandling.ManagementUserClaims{InstanceID: "test"}
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "invalid body", body: `{"eventKey": 1}`, wantErr: "invalid request"},
+		{name: "missing event key", body: `{"participantId": "p1"}`, wantErr: "eventKey is required"},
+		{name: "participant and selection", body: `{"eventKey": "backfill", "participantId": "p1", "selection": {}}`, wantErr: "use either participantId or selection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveJSONWithToken(h.runCustomEventOnParticipants, token, http.MethodPost, "/studies/:studyKey/run-custom-event", "/studies/study1/run-custom-event", tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", w.Code)
+			}
+			var resp struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, resp.Error)
+			}
+		})
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestRunCustomEventOnParticipants(t *testing.T) {
+	instanceID := "test"
+	studyKey := "study1"
+	dbService := newTestStudyDB(t, instanceID)
+	studyService.Init(dbService, "global-secret", nil)
+
+	if err := dbService.CreateStudy(instanceID, studyTypes.Study{Key: studyKey, SecretKey: "study-secret", Status: studyTypes.STUDY_STATUS_ACTIVE}); err != nil {
+		t.Fatal(err)
+	}
+	// sets the group flag to the group of the payload on the "backfill" event
+	rule := studyTypes.Expression{Name: "IFTHEN", Data: []studyTypes.ExpressionArg{
+		{DType: "exp", Exp: &studyTypes.Expression{Name: "checkEventKey", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "backfill"}}}},
+		{DType: "exp", Exp: &studyTypes.Expression{Name: "UPDATE_FLAG", Data: []studyTypes.ExpressionArg{
+			{DType: "str", Str: "group"},
+			{DType: "exp", Exp: &studyTypes.Expression{Name: "getEventPayloadValueAsStr", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "group"}}}},
+		}}},
+	}}
+	if err := dbService.SaveStudyRules(instanceID, studyKey, studyTypes.StudyRules{StudyKey: studyKey, UploadedAt: time.Now().Unix(), Rules: []studyTypes.Expression{rule}}); err != nil {
+		t.Fatal(err)
+	}
+	for participantID, status := range map[string]string{
+		"p1": studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
+		"p2": studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE,
+		"p3": studyTypes.PARTICIPANT_STUDY_STATUS_TEMPORARY,
+	} {
+		if _, err := dbService.SaveParticipantState(instanceID, studyKey, studyTypes.Participant{ParticipantID: participantID, StudyStatus: status, Flags: map[string]string{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token := &jwthandling.ManagementUserClaims{InstanceID: instanceID}
+	token.Subject = "researcher"
+
+	// runs the event and waits for the task to finish, returns the result file of the task
+	runEvent := func(t *testing.T, body string) studyService.RunCustomEventResult {
+		t.Helper()
+		h := &HttpEndpoints{studyDBConn: dbService, filestorePath: t.TempDir(), backgroundTasks: taskrunner.New(taskrunner.Config{})}
+		w := serveJSONWithToken(h.runCustomEventOnParticipants, token, http.MethodPost, "/studies/:studyKey/run-custom-event", "/studies/"+studyKey+"/run-custom-event", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Task studyTypes.Task `json:"task"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.backgroundTasks.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		task, err := dbService.GetTaskByID(instanceID, resp.Task.ID.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Status != studyTypes.TASK_STATUS_COMPLETED {
+			t.Fatalf("task not completed: %+v", task)
+		}
+		content, err := os.ReadFile(filepath.Join(h.filestorePath, task.ResultFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result studyService.RunCustomEventResult
+		if err := json.Unmarshal(content, &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	groupFlags := func() map[string]string {
+		flags := map[string]string{}
+		for _, participantID := range []string{"p1", "p2", "p3"} {
+			p, err := dbService.GetParticipantByID(instanceID, studyKey, participantID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			flags[participantID] = p.Flags["group"]
+		}
+		return flags
+	}
+
+	result := runEvent(t, `{"eventKey": "backfill", "payload": {"group": "a"}, "participantId": "p1"}`)
+	if result.ParticipantCount != 1 || result.ChangedCount != 1 || result.FailedCount != 0 {
+		t.Errorf("unexpected result for one participant: %+v", result)
+	}
+	if flags := groupFlags(); flags["p1"] != "a" || flags["p2"] != "" || flags["p3"] != "" {
+		t.Errorf("expected only p1 to be updated, got %v", flags)
+	}
+
+	result = runEvent(t, `{"eventKey": "backfill", "payload": {"group": "a"}}`)
+	if result.ParticipantCount != 2 || result.ChangedCount != 1 {
+		t.Errorf("unexpected result for all participants: %+v", result)
+	}
+	if flags := groupFlags(); flags["p1"] != "a" || flags["p2"] != "a" || flags["p3"] != "" {
+		t.Errorf("expected all participants except temporary ones to be updated, got %v", flags)
+	}
+
+	result = runEvent(t, `{"eventKey": "other", "payload": {"group": "b"}}`)
+	if result.ParticipantCount != 2 || result.ChangedCount != 0 {
+		t.Errorf("unexpected result for an event without rules: %+v", result)
+	}
+}
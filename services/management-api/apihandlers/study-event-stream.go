@@ -0,0 +1,142 @@
+package apihandlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	"github.com/gin-gonic/gin"
+)
+
+// comment lines are sent in this interval, so that proxies do not close idle streams
+const studyEventStreamKeepAliveInterval = 30 * time.Second
+
+// access tokens do not expire, so streams are closed after this duration and the client has to reconnect,
+// which checks its permissions again
+const studyEventStreamMaxDuration = time.Hour
+
+const (
+	STUDY_STREAM_EVENT_TOKEN_EXPIRED = "tokenExpired"
+	STUDY_STREAM_EVENT_RECONNECT     = "reconnect"
+	STUDY_STREAM_EVENT_ERROR         = "error"
+)
+
+func (h *HttpEndpoints) addStudyEventStreamEndpoints(rg *gin.RouterGroup) {
+	rg.GET("/event-stream", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+		},
+		nil,
+		h.streamStudyEvents,
+	))
+}
+
+// streamStudyEvents sends new responses, new participants and rule errors of the study as server-sent events
+// (?types=newResponse,newParticipant,ruleError). The stream ends when the access token expires, after
+// studyEventStreamMaxDuration or when the server shuts down.
+func (h *HttpEndpoints) streamStudyEvents(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	eventTypes, err := studyService.ParseStudyStreamEventTypes(c.DefaultQuery("types", ""))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "opening study event stream", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.Any("eventTypes", eventTypes))
+
+	// the stream is cancelled when the server shuts down
+	streamCtx, stopStream := apihelpers.StreamContext(c.Request)
+	defer stopStream()
+	deadline, deadlineEvent := studyEventStreamDeadline(token, time.Now())
+	ctx, cancel := context.WithDeadline(streamCtx, deadline)
+	defer cancel()
+
+	err = serveEventStream(ctx, c, studyEventStreamKeepAliveInterval, func(ctx context.Context, send func(event string, data interface{}) error) error {
+		return studyService.WatchStudyEvents(ctx, token.InstanceID, studyKey, eventTypes, func(event studyService.StudyStreamEvent) error {
+			return send(event.Type, event.Data)
+		})
+	})
+
+	switch {
+	case err == context.DeadlineExceeded:
+		writeEvent(c, deadlineEvent, gin.H{})
+	case err != nil && err != context.Canceled:
+		slog.ErrorContext(c, "error watching study events", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		writeEvent(c, STUDY_STREAM_EVENT_ERROR, gin.H{"error": "error watching study events"})
+	}
+}
+
+// studyEventStreamDeadline returns when the stream of the token ends and the event sent to the client then
+func studyEventStreamDeadline(token *jwthandling.ManagementUserClaims, now time.Time) (time.Time, string) {
+	maxDeadline := now.Add(studyEventStreamMaxDuration)
+	if token.ExpiresAt != nil && token.ExpiresAt.Time.Before(maxDeadline) {
+		return token.ExpiresAt.Time, STUDY_STREAM_EVENT_TOKEN_EXPIRED
+	}
+	return maxDeadline, STUDY_STREAM_EVENT_RECONNECT
+}
+
+// serveEventStream writes the events sent by watch and keep-alive comments until watch returns.
+// The keep-alive goroutine is stopped before it returns, so the caller can write to the response again.
+func serveEventStream(
+	ctx context.Context,
+	c *gin.Context,
+	keepAliveInterval time.Duration,
+	watch func(ctx context.Context, send func(event string, data interface{}) error) error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				fmt.Fprint(c.Writer, ": keep-alive\n\n")
+				c.Writer.Flush()
+				mu.Unlock()
+			}
+		}
+	}()
+
+	err := watch(ctx, func(event string, data interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		writeEvent(c, event, data)
+		return ctx.Err()
+	})
+	cancel()
+	wg.Wait()
+	return err
+}
+
+func writeEvent(c *gin.Context, event string, data interface{}) {
+	c.SSEvent(event, data)
+	c.Writer.Flush()
+}
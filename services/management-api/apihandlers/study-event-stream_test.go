@@ -0,0 +1,106 @@
+package apihandlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+)
+
+func TestStudyEventStreamDeadline(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		expiresAt    *jwt.NumericDate
+		wantDeadline time.Time
+		wantEvent    string
+	}{
+		{name: "token expires before the max duration", expiresAt: jwt.NewNumericDate(now.Add(10 * time.Minute)), wantDeadline: now.Add(10 * time.Minute), wantEvent: STUDY_STREAM_EVENT_TOKEN_EXPIRED},
+		{name: "token expires after the max duration", expiresAt: jwt.NewNumericDate(now.Add(5 * time.Hour)), wantDeadline: now.Add(studyEventStreamMaxDuration), wantEvent: STUDY_STREAM_EVENT_RECONNECT},
+		{name: "access token without expiry", wantDeadline: now.Add(studyEventStreamMaxDuration), wantEvent: STUDY_STREAM_EVENT_RECONNECT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &jwthandling.ManagementUserClaims{}
+			token.ExpiresAt = tt.expiresAt
+			deadline, event := studyEventStreamDeadline(token, now)
+			// numeric dates are rounded to seconds
+			if deadline.Sub(tt.wantDeadline).Abs() > time.Second || event != tt.wantEvent {
+				t.Errorf("unexpected deadline %v with event %s", deadline, event)
+			}
+		})
+	}
+}
+
+func TestServeEventStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("stops keep-alive before returning", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		err := serveEventStream(context.Background(), c, 5*time.Millisecond, func(ctx context.Context, send func(event string, data interface{}) error) error {
+			if err := send("newResponse", gin.H{"id": "r1"}); err != nil {
+				return err
+			}
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the handler writes the final event after serveEventStream returned
+		writeEvent(c, STUDY_STREAM_EVENT_RECONNECT, gin.H{})
+		body := w.Body.String()
+		time.Sleep(30 * time.Millisecond)
+		if w.Body.String() != body {
+			t.Error("keep-alive written after the stream ended")
+		}
+		if !strings.Contains(body, "event:newResponse") || !strings.HasSuffix(body, "event:"+STUDY_STREAM_EVENT_RECONNECT+"\ndata:{}\n\n") {
+			t.Errorf("unexpected body: %q", body)
+		}
+		if !strings.Contains(body, ": keep-alive\n\n") {
+			t.Error("no keep-alive sent")
+		}
+		if w.Header().Get("Content-Type") != "text/event-stream" {
+			t.Errorf("unexpected content type: %s", w.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("ends at the deadline", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := serveEventStream(ctx, c, time.Minute, func(ctx context.Context, send func(event string, data interface{}) error) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	})
+
+	t.Run("send fails after the stream is cancelled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		err := serveEventStream(ctx, c, time.Minute, func(ctx context.Context, send func(event string, data interface{}) error) error {
+			cancel()
+			return send("newResponse", gin.H{})
+		})
+		if err != context.Canceled {
+			t.Errorf("expected canceled, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,386 @@
+package apihandlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	exportjobs "github.com/case-framework/case-backend/pkg/study/exporter/export-jobs"
+	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const defaultExportDownloadURLTTL = 15 * time.Minute
+
+// SetExportJobDownloadConfig sets the key used to sign download links of export job artifacts and how long links stay valid
+func (h *HttpEndpoints) SetExportJobDownloadConfig(signKey string, urlTTL time.Duration) {
+	h.exportDownloadSignKey = signKey
+	h.exportDownloadURLTTL = urlTTL
+}
+
+func (h *HttpEndpoints) addStudyExportJobEndpoints(rg *gin.RouterGroup) {
+	exportJobsGroup := rg.Group("/data-exporter/export-jobs")
+	{
+		// queue a new response export
+		exportJobsGroup.POST("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			getSurveyKeyLimiterFromQuery,
+			h.createExportJob,
+		))
+
+		exportJobsGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.getExportJobs,
+		))
+
+		// checkpoints of delta export configurations
+		exportJobsGroup.GET("/checkpoints", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.getExportCheckpoints,
+		))
+
+		// reset a delta export configuration, the next job with this key exports all responses again
+		exportJobsGroup.DELETE("/checkpoints/:deltaKey", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.deleteExportCheckpoint,
+		))
+
+		exportJobsGroup.GET("/:jobID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.getExportJob,
+		))
+
+		// get a signed, expiring link to download the artifact without authorization header
+		exportJobsGroup.GET("/:jobID/download-url", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.getExportJobDownloadURL,
+		))
+	}
+}
+
+// AddExportDownloadAPI adds the endpoint serving export job artifacts for signed download links
+func (h *HttpEndpoints) AddExportDownloadAPI(rg *gin.RouterGroup) {
+	rg.GET("/export-downloads/:instanceID/:jobID", h.downloadExportJobArtifact)
+}
+
+func (h *HttpEndpoints) createExportJob(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	query, err := apihelpers.ParseResponseExportQueryFromCtx(c)
+	if err != nil || query == nil {
+		slog.ErrorContext(c, "failed to parse query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if query.SurveyKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "surveyKey is required"})
+		return
+	}
+
+	if !surveyresponses.IsSupportedExportFormat(query.Format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format"})
+		return
+	}
+
+	// delta exports: only responses that arrived after since, or after the last successful job with the same deltaKey
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since"})
+		return
+	}
+	deltaKey := c.DefaultQuery("deltaKey", "")
+
+	slog.InfoContext(c, "creating export job", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", query.SurveyKey), slog.String("deltaKey", deltaKey))
+
+	countFilter := studyDB.ExcludeSynthetic(query.PaginationInfos.Filter)
+	if !query.IncludeQuarantined {
+		countFilter = studyDB.ExcludeQuarantined(countFilter)
+	}
+	count, err := h.studyDBConn.GetResponsesCount(token.InstanceID, studyKey, countFilter)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get responses count", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get responses count"})
+		return
+	}
+
+	filter, err := json.Marshal(query.PaginationInfos.Filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter"})
+		return
+	}
+	sort := []byte{}
+	if len(query.PaginationInfos.Sort) > 0 {
+		sort, err = json.Marshal(query.PaginationInfos.Sort)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort"})
+			return
+		}
+	}
+
+	req := studyTypes.ExportJobRequest{
+		SurveyKey:            query.SurveyKey,
+		Format:               query.Format,
+		Filter:               string(filter),
+		Sort:                 string(sort),
+		Since:                since,
+		DeltaKey:             deltaKey,
+		UseShortKeys:         query.UseShortKeys,
+		QuestionOptionSep:    query.QuestionOptionSep,
+		ValueLabels:          query.ValueLabels,
+		LabelLang:            query.LabelLang,
+		ParticipantIDs:       query.Pseudonymization.ParticipantIDs,
+		PseudonymizationSalt: query.Pseudonymization.Salt,
+		RedactColumns:        query.Pseudonymization.RedactColumns,
+		TimestampPrecision:   query.Pseudonymization.TimestampPrecision,
+		ParticipantInfo:      query.ParticipantInfo,
+		ParticipantFlags:     query.ParticipantFlags,
+		ValidationIssues:     query.ValidationIssues,
+		IncludeQuarantined:   query.IncludeQuarantined,
+	}
+	if query.ExtraCtxCols != nil {
+		req.ExtraContextColumns = *query.ExtraCtxCols
+	}
+
+	job, err := h.studyDBConn.CreateExportJob(token.InstanceID, studyTypes.ExportJob{
+		StudyKey:    studyKey,
+		CreatedBy:   token.Subject,
+		Request:     req,
+		TargetCount: int(count),
+		FileType:    exportjobs.FileType(query.Format),
+	})
+	if err != nil {
+		slog.ErrorContext(c, "failed to create export job", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export job"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func (h *HttpEndpoints) getExportJobs(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	slog.InfoContext(c, "getting export jobs", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	// admins see the jobs of all users
+	createdBy := token.Subject
+	if token.IsAdmin {
+		createdBy = ""
+	}
+
+	jobs, paginationInfo, err := h.studyDBConn.GetExportJobs(token.InstanceID, studyKey, createdBy, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get export jobs", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":       jobs,
+		"pagination": paginationInfo,
+	})
+}
+
+func (h *HttpEndpoints) getExportCheckpoints(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting export checkpoints", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	checkpoints, err := h.studyDBConn.GetExportCheckpoints(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get export checkpoints", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export checkpoints"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"checkpoints": checkpoints})
+}
+
+func (h *HttpEndpoints) deleteExportCheckpoint(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	deltaKey := c.Param("deltaKey")
+
+	slog.InfoContext(c, "resetting export checkpoint", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("deltaKey", deltaKey))
+
+	err := h.studyDBConn.DeleteExportCheckpoint(token.InstanceID, studyKey, deltaKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "export checkpoint not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to delete export checkpoint", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete export checkpoint"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "export checkpoint deleted"})
+}
+
+// getExportJobOfUser returns the job if it belongs to the study and was created by the user (or the user is admin), otherwise writes the error response
+func (h *HttpEndpoints) getExportJobOfUser(c *gin.Context, token *jwthandling.ManagementUserClaims) (*studyTypes.ExportJob, bool) {
+	studyKey := c.Param("studyKey")
+	jobID := c.Param("jobID")
+
+	job, err := h.studyDBConn.GetExportJobByID(token.InstanceID, jobID)
+	if err != nil || job.StudyKey != studyKey {
+		if err == nil || err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "export job not found"})
+			return nil, false
+		}
+		slog.ErrorContext(c, "failed to get export job", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export job"})
+		return nil, false
+	}
+
+	if job.CreatedBy != token.Subject && !token.IsAdmin {
+		slog.WarnContext(c, "user is not allowed to access export job", slog.String("userID", token.Subject), slog.String("jobID", jobID))
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+	return &job, true
+}
+
+func (h *HttpEndpoints) getExportJob(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting export job", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("jobID", c.Param("jobID")))
+
+	job, ok := h.getExportJobOfUser(c, token)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func (h *HttpEndpoints) getExportJobDownloadURL(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "creating export job download link", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("jobID", c.Param("jobID")))
+
+	if h.exportDownloadSignKey == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export downloads not configured"})
+		return
+	}
+
+	job, ok := h.getExportJobOfUser(c, token)
+	if !ok {
+		return
+	}
+	if job.Status != studyTypes.EXPORT_JOB_STATUS_COMPLETED || job.ResultFile == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "export job is not completed"})
+		return
+	}
+
+	urlTTL := h.exportDownloadURLTTL
+	if urlTTL <= 0 {
+		urlTTL = defaultExportDownloadURLTTL
+	}
+	expiresAt := time.Now().Add(urlTTL)
+	if expiresAt.After(job.ExpiresAt) {
+		expiresAt = job.ExpiresAt
+	}
+
+	jobID := job.ID.Hex()
+	downloadPath := "/v1/export-downloads/" + token.InstanceID + "/" + jobID
+	query := exportjobs.DownloadQuery(h.exportDownloadSignKey, token.InstanceID, jobID, expiresAt.Unix())
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":       downloadPath + "?" + query.Encode(),
+		"expiresAt": expiresAt.Unix(),
+	})
+}
+
+func (h *HttpEndpoints) downloadExportJobArtifact(c *gin.Context) {
+	instanceID := c.Param("instanceID")
+	jobID := c.Param("jobID")
+
+	err := exportjobs.VerifyDownload(h.exportDownloadSignKey, instanceID, jobID, c.Query("expires"), c.Query("signature"), time.Now())
+	if err != nil {
+		slog.WarnContext(c, "rejected export download", slog.String("instanceID", instanceID), slog.String("jobID", jobID), slog.String("error", err.Error()))
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.studyDBConn.GetExportJobByID(instanceID, jobID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get export job", slog.String("instanceID", instanceID), slog.String("jobID", jobID), slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "export job not found"})
+		return
+	}
+	if job.Status != studyTypes.EXPORT_JOB_STATUS_COMPLETED || job.ResultFile == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "export job not found"})
+		return
+	}
+
+	resultFilePath := filepath.Join(h.filestorePath, job.ResultFile)
+	if _, err := os.Stat(resultFilePath); os.IsNotExist(err) {
+		slog.ErrorContext(c, "file does not exist", slog.String("path", resultFilePath))
+		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
+		return
+	}
+
+	slog.InfoContext(c, "downloading export job artifact", slog.String("instanceID", instanceID), slog.String("studyKey", job.StudyKey), slog.String("jobID", jobID), slog.String("createdBy", job.CreatedBy))
+
+	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(job.ResultFile))
+	c.Header("Content-Type", job.FileType)
+	c.File(resultFilePath)
+}
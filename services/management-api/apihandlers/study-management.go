@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,26 +20,37 @@ import (
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 	managementuser "github.com/case-framework/case-backend/pkg/db/management-user"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	managementalerts "github.com/case-framework/case-backend/pkg/messaging/management-alerts"
 	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
 	studyutils "github.com/case-framework/case-backend/pkg/study/utils"
 	"github.com/case-framework/case-backend/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	studyService "github.com/case-framework/case-backend/pkg/study"
+	"github.com/case-framework/case-backend/pkg/study/exporter/encryption"
+	exportjobs "github.com/case-framework/case-backend/pkg/study/exporter/export-jobs"
+	"github.com/case-framework/case-backend/pkg/study/exporter/manifest"
 	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
 	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
 const (
 	MIN_STUDY_SECRET_KEY_LENGTH = 5
+
+	MAX_CONFIDENTIAL_ACCESS_GRANT_DURATION = 90 * 24 * time.Hour
 )
 
 func (h *HttpEndpoints) AddStudyManagementAPI(rg *gin.RouterGroup) {
 	studiesGroup := rg.Group("/studies")
 
-	studiesGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.allowedInstanceIDs, h.muDBConn))
+	studiesGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn))
 	{
 		studiesGroup.GET("/", h.getAllStudies)
 		studiesGroup.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
@@ -56,9 +71,17 @@ func (h *HttpEndpoints) AddStudyManagementAPI(rg *gin.RouterGroup) {
 		h.addStudyConfigEndpoints(studyGroup)
 		h.addStudyRuleEndpoints(studyGroup)
 		h.addSurveyEndpoints(studyGroup)
+		h.addDraftEndpoints(studyGroup)
+		h.addReportTemplateEndpoints(studyGroup)
 		h.addStudyActionEndpoints(studyGroup)
 		h.addStudyDataExporterEndpoints(studyGroup)
+		h.addStudyExportJobEndpoints(studyGroup)
+		h.addStudyScheduledExportEndpoints(studyGroup)
 		h.addStudyDataExplorerEndpoints(studyGroup)
+		h.addStudyParticipantEndpoints(studyGroup)
+		h.addStudyStatisticsEndpoints(studyGroup)
+		h.addStudyEventStreamEndpoints(studyGroup)
+		h.addStudyBundleEndpoints(studiesGroup, studyGroup)
 	}
 }
 
@@ -144,6 +167,172 @@ func (h *HttpEndpoints) addGeneralStudyEndpoints(rg *gin.RouterGroup) {
 		h.updateStudyFileUploadRule,
 	))
 
+	rg.PUT("/response-correction-config", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyResponseCorrectionConfig,
+	))
+
+	rg.PUT("/response-validation-config", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyResponseValidationConfig,
+	))
+
+	rg.PUT("/response-quarantine-config", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyResponseQuarantineConfig,
+	))
+
+	rg.PUT("/enrollment-window", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyEnrollmentWindow,
+	))
+
+	rg.PUT("/survey-reminders", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudySurveyReminders,
+	))
+
+	rg.PUT("/require-review", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyRequireReview,
+	))
+
+	rg.DELETE("/survey-reminders", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.removeStudySurveyReminders,
+	))
+
+	rg.PUT("/participant-flag-types", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyParticipantFlagTypes,
+	))
+
+	rg.PUT("/data-retention", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyDataRetentionPolicy,
+	))
+
+	rg.DELETE("/data-retention", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.removeStudyDataRetentionPolicy,
+	))
+
+	rg.GET("/data-retention/preview", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_READ_STUDY_CONFIG,
+		},
+		nil,
+		h.previewStudyDataRetention,
+	))
+
+	rg.DELETE("/enrollment-window", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.removeStudyEnrollmentWindow,
+	))
+
+	rg.PUT("/enrollment-cap", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyEnrollmentCap,
+	))
+
+	rg.DELETE("/enrollment-cap", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.removeStudyEnrollmentCap,
+	))
+
+	// participants waiting for a free slot, in the order they joined
+	rg.GET("/waiting-list", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+		},
+		nil,
+		h.getStudyWaitingList,
+	))
+
 	rg.DELETE("/", h.useAuthorisedHandler(
 		RequiredPermission{
 			ResourceType:        pc.RESOURCE_TYPE_STUDY,
@@ -180,6 +369,17 @@ func (h *HttpEndpoints) addSurveyEndpoints(rg *gin.RouterGroup) {
 			nil,
 			h.createSurvey,
 		))
+
+		surveysGroup.POST("/validate", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.validateSurvey,
+		))
 	}
 
 	surveyGroup := surveysGroup.Group("/:surveyKey")
@@ -239,6 +439,17 @@ func (h *HttpEndpoints) addSurveyEndpoints(rg *gin.RouterGroup) {
 			h.getSurveyVersion,
 		))
 
+		surveyGroup.GET("/versions/:versionID/diff", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getSurveyVersionDiff,
+		))
+
 		surveyGroup.DELETE("/versions/:versionID", h.useAuthorisedHandler(
 			RequiredPermission{
 				ResourceType:        pc.RESOURCE_TYPE_STUDY,
@@ -250,6 +461,99 @@ func (h *HttpEndpoints) addSurveyEndpoints(rg *gin.RouterGroup) {
 			h.deleteSurveyVersion,
 		))
 
+		surveyGroup.GET("/drift-analysis", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.getSurveyDriftAnalysis,
+		))
+
+	}
+}
+
+func (h *HttpEndpoints) addDraftEndpoints(rg *gin.RouterGroup) {
+	draftsGroup := rg.Group("/drafts")
+	{
+		draftsGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getDrafts,
+		))
+
+		draftsGroup.POST("/surveys", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_UPDATE_SURVEY,
+			},
+			nil,
+			h.saveSurveyDraft,
+		))
+
+		draftsGroup.POST("/rules", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_UPDATE_STUDY_RULES,
+			},
+			nil,
+			h.saveStudyRulesDraft,
+		))
+
+		draftsGroup.GET("/:draftID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getDraft,
+		))
+
+		draftsGroup.POST("/:draftID/approve", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_REVIEW_DRAFTS,
+			},
+			nil,
+			h.approveDraft,
+		))
+
+		draftsGroup.POST("/:draftID/publish", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_PUBLISH_DRAFTS,
+			},
+			nil,
+			h.publishDraft,
+		))
+
+		draftsGroup.DELETE("/:draftID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_PUBLISH_DRAFTS,
+			},
+			nil,
+			h.deleteDraft,
+		))
 	}
 }
 
@@ -291,59 +595,65 @@ func (h *HttpEndpoints) addStudyConfigEndpoints(rg *gin.RouterGroup) {
 		))
 	}
 
-	notificationSubGroup := rg.Group("/notification-subscriptions")
+	confidentialAccessGroup := rg.Group("/confidential-access-grants")
 	{
-		notificationSubGroup.GET("/", h.useAuthorisedHandler(
+		confidentialAccessGroup.GET("/", h.useAuthorisedHandler(
 			RequiredPermission{
 				ResourceType:        pc.RESOURCE_TYPE_STUDY,
 				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
 				ExtractResourceKeys: getStudyKeyFromParams,
-				Action:              pc.ACTION_READ_STUDY_CONFIG,
+				Action:              pc.ACTION_MANAGE_STUDY_PERMISSIONS,
 			},
 			nil,
-			h.getNotificationSubscriptions,
+			h.getConfidentialAccessGrants,
 		))
 
-		notificationSubGroup.PUT("/", mw.RequirePayload(), h.useAuthorisedHandler(
+		confidentialAccessGroup.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
 			RequiredPermission{
 				ResourceType:        pc.RESOURCE_TYPE_STUDY,
 				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
 				ExtractResourceKeys: getStudyKeyFromParams,
-				Action:              pc.ACTION_UPDATE_NOTIFICATION_SUBSCRIPTIONS,
+				Action:              pc.ACTION_MANAGE_STUDY_PERMISSIONS,
 			},
 			nil,
-			h.updateNotificationSubscriptions,
+			h.addConfidentialAccessGrant,
 		))
-	}
-}
 
-func (h *HttpEndpoints) addStudyRuleEndpoints(rg *gin.RouterGroup) {
-	rulesGroup := rg.Group("/rules")
+		confidentialAccessGroup.DELETE("/:grantID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_MANAGE_STUDY_PERMISSIONS,
+			},
+			nil,
+			h.revokeConfidentialAccessGrant,
+		))
+	}
 
-	rulesGroup.GET("/", h.useAuthorisedHandler(
+	rg.GET("/audit-log", h.useAuthorisedHandler(
 		RequiredPermission{
 			ResourceType:        pc.RESOURCE_TYPE_STUDY,
 			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
 			ExtractResourceKeys: getStudyKeyFromParams,
-			Action:              pc.ACTION_READ_STUDY_CONFIG,
+			Action:              pc.ACTION_MANAGE_STUDY_PERMISSIONS,
 		},
 		nil,
-		h.getCurrentStudyRules,
+		h.getStudyAuditLog,
 	))
 
-	rulesGroup.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
+	rg.GET("/access-review", h.useAuthorisedHandler(
 		RequiredPermission{
 			ResourceType:        pc.RESOURCE_TYPE_STUDY,
 			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
 			ExtractResourceKeys: getStudyKeyFromParams,
-			Action:              pc.ACTION_UPDATE_STUDY_RULES,
+			Action:              pc.ACTION_MANAGE_STUDY_PERMISSIONS,
 		},
 		nil,
-		h.publishNewStudyRulesVersion,
+		h.getStudyAccessReview,
 	))
 
-	// get rule history
-	rulesGroup.GET("/versions", h.useAuthorisedHandler(
+	rg.GET("/data-quality/findings", h.useAuthorisedHandler(
 		RequiredPermission{
 			ResourceType:        pc.RESOURCE_TYPE_STUDY,
 			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
@@ -351,10 +661,73 @@ func (h *HttpEndpoints) addStudyRuleEndpoints(rg *gin.RouterGroup) {
 			Action:              pc.ACTION_READ_STUDY_CONFIG,
 		},
 		nil,
-		h.getStudyRuleVersions,
+		h.getStudyDataQualityFindings,
 	))
 
-	// get specific rule version
+	notificationSubGroup := rg.Group("/notification-subscriptions")
+	{
+		notificationSubGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getNotificationSubscriptions,
+		))
+
+		notificationSubGroup.PUT("/", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_UPDATE_NOTIFICATION_SUBSCRIPTIONS,
+			},
+			nil,
+			h.updateNotificationSubscriptions,
+		))
+	}
+}
+
+func (h *HttpEndpoints) addStudyRuleEndpoints(rg *gin.RouterGroup) {
+	rulesGroup := rg.Group("/rules")
+
+	rulesGroup.GET("/", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_READ_STUDY_CONFIG,
+		},
+		nil,
+		h.getCurrentStudyRules,
+	))
+
+	rulesGroup.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_RULES,
+		},
+		nil,
+		h.publishNewStudyRulesVersion,
+	))
+
+	// get rule history
+	rulesGroup.GET("/versions", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_READ_STUDY_CONFIG,
+		},
+		nil,
+		h.getStudyRuleVersions,
+	))
+
+	// get specific rule version
 	rulesGroup.GET("/versions/:id", h.useAuthorisedHandler(
 		RequiredPermission{
 			ResourceType:        pc.RESOURCE_TYPE_STUDY,
@@ -366,6 +739,18 @@ func (h *HttpEndpoints) addStudyRuleEndpoints(rg *gin.RouterGroup) {
 		h.getStudyRuleVersion,
 	))
 
+	// evaluate rules or a single expression against a participant state without saving anything
+	rulesGroup.POST("/dry-run", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_READ_STUDY_CONFIG,
+		},
+		nil,
+		h.dryRunStudyRules,
+	))
+
 	// delete rule version
 	rulesGroup.DELETE("/versions/:id", h.useAuthorisedHandler(
 		RequiredPermission{
@@ -477,6 +862,43 @@ func (h *HttpEndpoints) addStudyActionEndpoints(rg *gin.RouterGroup) {
 			h.getStudyActionTaskResult,
 		))
 	}
+
+	// run the study rules of a custom event for one, selected or all participants, e.g. for backfills
+	customEventsGroup := actionsGroup.Group("/custom-events")
+	{
+		customEventsGroup.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.runCustomEventOnParticipants,
+		))
+
+		customEventsGroup.GET("/task/:taskID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.getStudyActionTaskStatus,
+		))
+
+		customEventsGroup.GET("/task/:taskID/result", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.getStudyActionTaskResult,
+		))
+	}
 }
 
 func (h *HttpEndpoints) addStudyDataExporterEndpoints(rg *gin.RouterGroup) {
@@ -495,6 +917,18 @@ func (h *HttpEndpoints) addStudyDataExporterEndpoints(rg *gin.RouterGroup) {
 			nil,
 			h.getSurveyInfo,
 		))
+
+		// get the codebook of a survey version
+		surveyInfoGroup.GET("/codebook", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getSurveyCodebook,
+		))
 	}
 
 	responsesGroup := exporterGroup.Group("/responses")
@@ -547,6 +981,18 @@ func (h *HttpEndpoints) addStudyDataExporterEndpoints(rg *gin.RouterGroup) {
 			h.getExportTaskResult,
 		))
 
+		// get export manifest
+		responsesGroup.GET("/task/:taskID/manifest", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.getExportTaskManifest,
+		))
+
 		responsesGroup.GET("/daily-exports", h.useAuthorisedHandler(
 			RequiredPermission{
 				ResourceType:        pc.RESOURCE_TYPE_STUDY,
@@ -619,6 +1065,18 @@ func (h *HttpEndpoints) addStudyDataExporterEndpoints(rg *gin.RouterGroup) {
 			nil,
 			h.getExportTaskResult,
 		))
+
+		// get export manifest
+		participantsGroup.GET("/task/:taskID/manifest", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.getExportTaskManifest,
+		))
 	}
 
 	reportsGroup := exporterGroup.Group("/reports")
@@ -670,6 +1128,18 @@ func (h *HttpEndpoints) addStudyDataExporterEndpoints(rg *gin.RouterGroup) {
 			nil,
 			h.getExportTaskResult,
 		))
+
+		// get export manifest
+		reportsGroup.GET("/task/:taskID/manifest", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_REPORTS,
+			},
+			nil,
+			h.getExportTaskManifest,
+		))
 	}
 
 	confidentialResponsesGroup := exporterGroup.Group("/confidential-responses")
@@ -690,6 +1160,58 @@ func (h *HttpEndpoints) addStudyDataExporterEndpoints(rg *gin.RouterGroup) {
 			),
 		)
 
+		// browse confidential responses of a participant page by page
+		confidentialResponsesGroup.POST("/query",
+			mw.RequirePayload(),
+			h.useAuthorisedHandler(
+				RequiredPermission{
+					ResourceType:        pc.RESOURCE_TYPE_STUDY,
+					ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+					ExtractResourceKeys: getStudyKeyFromParams,
+					Action:              pc.ACTION_GET_CONFIDENTIAL_RESPONSES,
+				},
+				nil,
+				h.queryConfidentialResponses,
+			),
+		)
+
+		// generate an encrypted export file of confidential responses, only the recipients can decrypt it
+		confidentialResponsesGroup.POST("/export",
+			mw.RequirePayload(),
+			h.useAuthorisedHandler(
+				RequiredPermission{
+					ResourceType:        pc.RESOURCE_TYPE_STUDY,
+					ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+					ExtractResourceKeys: getStudyKeyFromParams,
+					Action:              pc.ACTION_GET_CONFIDENTIAL_RESPONSES,
+				},
+				nil,
+				h.generateEncryptedConfidentialResponsesExport,
+			),
+		)
+
+		confidentialResponsesGroup.GET("/task/:taskID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_CONFIDENTIAL_RESPONSES,
+			},
+			nil,
+			h.getExportTaskStatus,
+		))
+
+		confidentialResponsesGroup.GET("/task/:taskID/result", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_CONFIDENTIAL_RESPONSES,
+			},
+			nil,
+			h.getConfidentialResponsesExportResult,
+		))
+
 	}
 }
 
@@ -745,6 +1267,43 @@ func (h *HttpEndpoints) addStudyDataExplorerEndpoints(rg *gin.RouterGroup) {
 		))
 	}
 
+	// responses held back by the spam and bot screening, excluded from exports until accepted
+	quarantineGroup := dataExplGroup.Group("/quarantined-responses")
+	{
+		quarantineGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			getSurveyKeyLimiterFromQuery,
+			h.getQuarantinedResponses,
+		))
+
+		quarantineGroup.POST("/:responseID/accept", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_DELETE_RESPONSES,
+			},
+			nil,
+			h.acceptQuarantinedResponse,
+		))
+
+		quarantineGroup.DELETE("/:responseID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_DELETE_RESPONSES,
+			},
+			nil,
+			h.purgeQuarantinedResponse,
+		))
+	}
+
 	participantsGroup := dataExplGroup.Group("/participants")
 	{
 		// get participants with pagination
@@ -759,6 +1318,18 @@ func (h *HttpEndpoints) addStudyDataExplorerEndpoints(rg *gin.RouterGroup) {
 			h.getStudyParticipants,
 		))
 
+		// query participants by flag predicates
+		participantsGroup.POST("/flag-query", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.queryStudyParticipantsByFlags,
+		))
+
 		// get single participant
 		participantsGroup.GET("/:participantID", h.useAuthorisedHandler(
 			RequiredPermission{
@@ -770,6 +1341,18 @@ func (h *HttpEndpoints) addStudyDataExplorerEndpoints(rg *gin.RouterGroup) {
 			nil,
 			h.getStudyParticipant,
 		))
+
+		// erase all data of the participant
+		participantsGroup.DELETE("/:participantID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_DELETE_PARTICIPANT_DATA,
+			},
+			nil,
+			h.deleteStudyParticipantData,
+		))
 	}
 
 	reportsGroup := dataExplGroup.Group("/reports")
@@ -799,6 +1382,42 @@ func (h *HttpEndpoints) addStudyDataExplorerEndpoints(rg *gin.RouterGroup) {
 		))
 	}
 
+	// external service calls queued by study rules
+	dataExplGroup.GET("/external-service-tasks", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+		},
+		nil,
+		h.getStudyExternalServiceTasks,
+	))
+
+	// arms assigned by randomization actions
+	dataExplGroup.GET("/randomization-allocations", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+		},
+		nil,
+		h.getStudyRandomizationAllocations,
+	))
+
+	// events registered by study rules to be fired later
+	dataExplGroup.GET("/scheduled-events", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+		},
+		nil,
+		h.getStudyScheduledEvents,
+	))
+
 	filesGroup := dataExplGroup.Group("/files")
 	{
 		// get files with pagination
@@ -842,15 +1461,25 @@ func (h *HttpEndpoints) addStudyDataExplorerEndpoints(rg *gin.RouterGroup) {
 func (h *HttpEndpoints) getAllStudies(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
-	slog.Info("getting all studies", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting all studies", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	studies, err := h.studyDBConn.GetStudies(token.InstanceID, "", false)
 	if err != nil {
-		slog.Error("failed to get all studies", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get all studies", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get studies"})
 		return
 	}
 
+	// access tokens only see the studies they are scoped to
+	if accessToken, ok := c.Get(mw.ContextKeyAccessToken); ok {
+		scopes := accessToken.(*managementuser.AccessToken).Scopes
+		studies = slices.DeleteFunc(studies, func(study studyTypes.Study) bool {
+			return !slices.ContainsFunc(scopes, func(scope managementuser.AccessTokenScope) bool {
+				return scope.ResourceType == pc.RESOURCE_TYPE_STUDY && (scope.ResourceKey == "*" || scope.ResourceKey == study.Key)
+			})
+		})
+	}
+
 	for i := range studies {
 		studies[i].SecretKey = ""
 		studies[i].Rules = nil
@@ -871,22 +1500,22 @@ func (h *HttpEndpoints) createStudy(c *gin.Context) {
 
 	var req NewStudyReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("creating new study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", req.StudyKey))
+	slog.InfoContext(c, "creating new study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", req.StudyKey))
 
 	// check if study key is URL safe
 	if !utils.IsURLSafe(req.StudyKey) {
-		slog.Error("study key is not URL safe", slog.String("studyKey", req.StudyKey))
+		slog.ErrorContext(c, "study key is not URL safe", slog.String("studyKey", req.StudyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "study key is not URL safe"})
 		return
 	}
 
 	if len(req.SecretKey) < MIN_STUDY_SECRET_KEY_LENGTH {
-		slog.Error("secret key is too short", slog.String("studyKey", req.StudyKey), slog.Int("length", len(req.SecretKey)))
+		slog.ErrorContext(c, "secret key is too short", slog.String("studyKey", req.StudyKey), slog.Int("length", len(req.SecretKey)))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "secret key is too short"})
 		return
 	}
@@ -911,8 +1540,13 @@ func (h *HttpEndpoints) createStudy(c *gin.Context) {
 	}
 
 	err := h.studyDBConn.CreateStudy(token.InstanceID, study)
+	if mongo.IsDuplicateKeyError(err) {
+		slog.ErrorContext(c, "study key already in use", slog.String("studyKey", req.StudyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "study key already in use, also by studies in the trash"})
+		return
+	}
 	if err != nil {
-		slog.Error("failed to create study", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create study"})
 		return
 	}
@@ -925,11 +1559,11 @@ func (h *HttpEndpoints) getStudyProps(c *gin.Context) {
 
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting study props", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting study props", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to get study", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
 		return
 	}
@@ -948,16 +1582,16 @@ func (h *HttpEndpoints) updateStudyIsDefault(c *gin.Context) {
 
 	var req StudyIsDefaultUpdateReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("updating study is default", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.Bool("isDefault", req.IsDefault))
+	slog.InfoContext(c, "updating study is default", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.Bool("isDefault", req.IsDefault))
 
 	err := h.studyDBConn.UpdateStudyIsDefault(token.InstanceID, studyKey, req.IsDefault)
 	if err != nil {
-		slog.Error("failed to update study is default", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update study is default", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study is default"})
 		return
 	}
@@ -976,16 +1610,16 @@ func (h *HttpEndpoints) updateStudyStatus(c *gin.Context) {
 
 	var req StudyStatusUpdateReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("updating study status", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("status", req.Status))
+	slog.InfoContext(c, "updating study status", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("status", req.Status))
 
 	err := h.studyDBConn.UpdateStudyStatus(token.InstanceID, studyKey, req.Status)
 	if err != nil {
-		slog.Error("failed to update study status", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update study status", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study status"})
 		return
 	}
@@ -1005,16 +1639,16 @@ func (h *HttpEndpoints) updateStudyDisplayProps(c *gin.Context) {
 
 	var req StudyDisplayPropsUpdateReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("updating study display props", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "updating study display props", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	err := h.studyDBConn.UpdateStudyDisplayProps(token.InstanceID, studyKey, req.Name, req.Description, req.Tags)
 	if err != nil {
-		slog.Error("failed to update study display props", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update study display props", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study display props"})
 		return
 	}
@@ -1033,7 +1667,7 @@ func (h *HttpEndpoints) updateStudyFileUploadRule(c *gin.Context) {
 
 	var req FileUploadRuleUpdateReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
@@ -1059,27 +1693,444 @@ func (h *HttpEndpoints) updateStudyFileUploadRule(c *gin.Context) {
 		}
 	}
 
-	slog.Info("updating study file upload rule", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "updating study file upload rule", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	err := h.studyDBConn.UpdateStudyFileUploadRule(token.InstanceID, studyKey, newRule)
 	if err != nil {
-		slog.Error("failed to update study file upload rule", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update study file upload rule", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study file upload rule"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "study file upload rule updated"})
 }
 
+func (h *HttpEndpoints) updateStudyResponseCorrectionConfig(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req studyTypes.ResponseCorrectionConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if req.Window < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must not be negative"})
+		return
+	}
+
+	slog.InfoContext(c, "updating study response correction config", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyResponseCorrectionConfig(token.InstanceID, studyKey, &req)
+	if err != nil {
+		slog.ErrorContext(c, "failed to update study response correction config", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study response correction config"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study response correction config updated"})
+}
+
+func (h *HttpEndpoints) updateStudyResponseValidationConfig(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req studyTypes.ResponseValidationConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	switch req.Mode {
+	case studyTypes.RESPONSE_VALIDATION_MODE_ACCEPT, studyTypes.RESPONSE_VALIDATION_MODE_WARN, studyTypes.RESPONSE_VALIDATION_MODE_REJECT:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be accept, warn or reject"})
+		return
+	}
+
+	slog.InfoContext(c, "updating study response validation config", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("mode", req.Mode))
+
+	err := h.studyDBConn.UpdateStudyResponseValidationConfig(token.InstanceID, studyKey, &req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study response validation config", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study response validation config"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study response validation config updated"})
+}
+
+func (h *HttpEndpoints) updateStudyEnrollmentWindow(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req studyTypes.EnrollmentWindow
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := studyutils.ValidateEnrollmentWindow(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "updating study enrollment window", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyEnrollmentWindow(token.InstanceID, studyKey, &req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study enrollment window", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study enrollment window"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study enrollment window updated"})
+}
+
+func (h *HttpEndpoints) updateStudySurveyReminders(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req studyTypes.SurveyReminderConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := studyutils.ValidateSurveyReminderConfig(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "updating study survey reminders", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudySurveyReminders(token.InstanceID, studyKey, &req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study survey reminders", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study survey reminders"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study survey reminders updated"})
+}
+
+type StudyRequireReviewUpdateReq struct {
+	RequireReview bool `json:"requireReview"`
+}
+
+func (h *HttpEndpoints) updateStudyRequireReview(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req StudyRequireReviewUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "updating study review requirement", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.Bool("requireReview", req.RequireReview))
+
+	err := h.studyDBConn.UpdateStudyRequireReview(token.InstanceID, studyKey, req.RequireReview)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study review requirement", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study review requirement"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study review requirement updated"})
+}
+
+func (h *HttpEndpoints) removeStudySurveyReminders(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "removing study survey reminders", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudySurveyReminders(token.InstanceID, studyKey, nil)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to remove study survey reminders", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove study survey reminders"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study survey reminders removed"})
+}
+
+func (h *HttpEndpoints) updateStudyParticipantFlagTypes(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req struct {
+		FlagTypes map[string]string `json:"flagTypes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := studyutils.ValidateFlagTypes(req.FlagTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "updating study participant flag types", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyParticipantFlagTypes(token.InstanceID, studyKey, req.FlagTypes)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study participant flag types", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study participant flag types"})
+		return
+	}
+
+	// existing participants get their typed flags with the new types
+	count, err := h.studyDBConn.RebuildParticipantTypedFlags(context.Background(), token.InstanceID, studyKey, req.FlagTypes)
+	if err != nil {
+		slog.ErrorContext(c, "failed to rebuild participant typed flags", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "flag types updated, but failed to update existing participants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "study participant flag types updated",
+		"updatedParticipants": count,
+	})
+}
+
+func (h *HttpEndpoints) updateStudyDataRetentionPolicy(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req studyTypes.DataRetentionPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := studyutils.ValidateDataRetentionPolicy(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "updating study data retention policy", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyDataRetentionPolicy(token.InstanceID, studyKey, &req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study data retention policy", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study data retention policy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study data retention policy updated"})
+}
+
+func (h *HttpEndpoints) removeStudyDataRetentionPolicy(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "removing study data retention policy", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyDataRetentionPolicy(token.InstanceID, studyKey, nil)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to remove study data retention policy", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove study data retention policy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study data retention policy removed"})
+}
+
+func (h *HttpEndpoints) previewStudyDataRetention(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return
+	}
+
+	if study.Configs.DataRetention == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "study has no data retention policy"})
+		return
+	}
+
+	report, err := studyService.ApplyDataRetention(token.InstanceID, study, h.filestorePath, true)
+	if err != nil {
+		slog.ErrorContext(c, "failed to preview data retention", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to preview data retention"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+func (h *HttpEndpoints) removeStudyEnrollmentWindow(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "removing study enrollment window", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyEnrollmentWindow(token.InstanceID, studyKey, nil)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to remove study enrollment window", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove study enrollment window"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study enrollment window removed"})
+}
+
+func (h *HttpEndpoints) updateStudyEnrollmentCap(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req studyTypes.EnrollmentCap
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := studyutils.ValidateEnrollmentCap(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "updating study enrollment cap", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.Int64("maxActiveParticipants", req.MaxActiveParticipants))
+
+	err := h.studyDBConn.UpdateStudyEnrollmentCap(token.InstanceID, studyKey, &req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study enrollment cap", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study enrollment cap"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study enrollment cap updated"})
+}
+
+// removeStudyEnrollmentCap removes the cap, participants still on the waiting list are promoted by the next study timer run
+func (h *HttpEndpoints) removeStudyEnrollmentCap(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "removing study enrollment cap", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyEnrollmentCap(token.InstanceID, studyKey, nil)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to remove study enrollment cap", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove study enrollment cap"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study enrollment cap removed"})
+}
+
+func (h *HttpEndpoints) getStudyWaitingList(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	slog.InfoContext(c, "getting study waiting list", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	participants, paginationInfo, err := h.studyDBConn.GetParticipants(
+		token.InstanceID,
+		studyKey,
+		bson.M{"studyStatus": studyTypes.PARTICIPANT_STUDY_STATUS_WAITING_LIST},
+		bson.M{"enteredAt": 1},
+		page,
+		limit,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study waiting list", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study waiting list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"participants": participants,
+		"pagination":   paginationInfo,
+	})
+}
+
 func (h *HttpEndpoints) deleteStudy(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
 	studyKey := c.Param("studyKey")
 
-	slog.Info("deleting study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "deleting study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	err := h.studyDBConn.DeleteStudy(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to delete study", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete study"})
 		return
 	}
@@ -1096,11 +2147,11 @@ func (h *HttpEndpoints) getSurveyInfoList(c *gin.Context) {
 
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting survey info list", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting survey info list", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	surveyKeys, err := h.studyDBConn.GetSurveyKeysForStudy(token.InstanceID, studyKey, true)
 	if err != nil {
-		slog.Error("failed to get survey info list", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey info list", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey info list"})
 		return
 	}
@@ -1120,33 +2171,38 @@ func (h *HttpEndpoints) createSurvey(c *gin.Context) {
 
 	var survey studyTypes.Survey
 	if err := c.ShouldBindJSON(&survey); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
+
+	if !h.directPublishAllowed(c, token.InstanceID, studyKey) {
+		return
+	}
+
 	survey.SurveyKey = survey.SurveyDefinition.Key
 
-	slog.Info("creating survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", survey.SurveyDefinition.Key))
+	slog.InfoContext(c, "creating survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", survey.SurveyDefinition.Key))
 
 	surveyKeys, err := h.studyDBConn.GetSurveyKeysForStudy(token.InstanceID, studyKey, true)
 	if err != nil {
-		slog.Error("failed to get survey info list", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey info list", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey info list"})
 		return
 	}
 
 	for _, key := range surveyKeys {
 		if key == survey.SurveyKey {
-			slog.Error("survey key already exists", slog.String("key", survey.SurveyKey))
+			slog.ErrorContext(c, "survey key already exists", slog.String("key", survey.SurveyKey))
 			c.JSON(http.StatusBadRequest, gin.H{"error": "survey key already exists"})
 			return
 		}
 	}
 
 	if survey.VersionID == "" {
-		surveyHistory, err := h.studyDBConn.GetSurveyVersions(token.InstanceID, studyKey, survey.SurveyKey)
+		surveyHistory, err := h.studyDBConn.GetSurveyVersionIDs(token.InstanceID, studyKey, survey.SurveyKey)
 		if err != nil {
-			slog.Error("failed to get survey versions", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 			return
 		}
@@ -1157,12 +2213,29 @@ func (h *HttpEndpoints) createSurvey(c *gin.Context) {
 
 	err = h.studyDBConn.SaveSurveyVersion(token.InstanceID, studyKey, &survey)
 	if err != nil {
-		slog.Error("failed to create survey", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create survey", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create survey"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"survey": survey})
+	c.JSON(http.StatusCreated, gin.H{"survey": survey, "warnings": studyService.ValidateSurveyDefinition(survey)})
+}
+
+func (h *HttpEndpoints) validateSurvey(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var survey studyTypes.Survey
+	if err := c.ShouldBindJSON(&survey); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "validating survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", survey.SurveyDefinition.Key))
+
+	c.JSON(http.StatusOK, gin.H{"warnings": studyService.ValidateSurveyDefinition(survey)})
 }
 
 func (h *HttpEndpoints) getLatestSurvey(c *gin.Context) {
@@ -1171,11 +2244,11 @@ func (h *HttpEndpoints) getLatestSurvey(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	surveyKey := c.Param("surveyKey")
 
-	slog.Info("getting latest survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	slog.InfoContext(c, "getting latest survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
 	survey, err := h.studyDBConn.GetCurrentSurveyVersion(token.InstanceID, studyKey, surveyKey)
 	if err != nil {
-		slog.Error("failed to get latest survey", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get latest survey", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get latest survey"})
 		return
 	}
@@ -1191,22 +2264,27 @@ func (h *HttpEndpoints) updateSurvey(c *gin.Context) {
 
 	var survey studyTypes.Survey
 	if err := c.ShouldBindJSON(&survey); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
+
+	if !h.directPublishAllowed(c, token.InstanceID, studyKey) {
+		return
+	}
+
 	survey.SurveyKey = survey.SurveyDefinition.Key
 
 	if survey.SurveyKey != surveyKey {
-		slog.Error("survey key in request does not match", slog.String("key", survey.SurveyKey))
+		slog.ErrorContext(c, "survey key in request does not match", slog.String("key", survey.SurveyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "survey key in request does not match"})
 		return
 	}
 
 	if survey.VersionID == "" {
-		surveyHistory, err := h.studyDBConn.GetSurveyVersions(token.InstanceID, studyKey, survey.SurveyKey)
+		surveyHistory, err := h.studyDBConn.GetSurveyVersionIDs(token.InstanceID, studyKey, survey.SurveyKey)
 		if err != nil {
-			slog.Error("failed to get survey versions", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 			return
 		}
@@ -1215,16 +2293,30 @@ func (h *HttpEndpoints) updateSurvey(c *gin.Context) {
 
 	survey.Published = time.Now().Unix()
 
-	slog.Info("updating survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	slog.InfoContext(c, "updating survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
-	err := h.studyDBConn.SaveSurveyVersion(token.InstanceID, studyKey, &survey)
+	var diff *surveydefinition.SurveyVersionDiff
+	previous, err := h.studyDBConn.GetCurrentSurveyVersion(token.InstanceID, studyKey, surveyKey)
 	if err != nil {
-		slog.Error("failed to update survey", slog.String("error", err.Error()))
+		if err != mongo.ErrNoDocuments {
+			slog.WarnContext(c, "failed to get previous survey version for diff", slog.String("error", err.Error()))
+		}
+	} else {
+		d := surveydefinition.DiffSurveyVersions(
+			surveydefinition.SurveyDefToVersionPreview(previous, nil),
+			surveydefinition.SurveyDefToVersionPreview(&survey, nil),
+		)
+		diff = &d
+	}
+
+	err = h.studyDBConn.SaveSurveyVersion(token.InstanceID, studyKey, &survey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to update survey", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update survey"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"survey": survey})
+	c.JSON(http.StatusOK, gin.H{"survey": survey, "warnings": studyService.ValidateSurveyDefinition(survey), "diff": diff})
 }
 
 func (h *HttpEndpoints) unpublishSurvey(c *gin.Context) {
@@ -1233,11 +2325,11 @@ func (h *HttpEndpoints) unpublishSurvey(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	surveyKey := c.Param("surveyKey")
 
-	slog.Info("unpublishing survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	slog.InfoContext(c, "unpublishing survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
 	err := h.studyDBConn.UnpublishSurvey(token.InstanceID, studyKey, surveyKey)
 	if err != nil {
-		slog.Error("failed to unpublish survey", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to unpublish survey", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unpublish survey"})
 		return
 	}
@@ -1251,11 +2343,11 @@ func (h *HttpEndpoints) getSurveyVersions(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	surveyKey := c.Param("surveyKey")
 
-	slog.Info("getting survey versions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	slog.InfoContext(c, "getting survey versions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
 	versions, err := h.studyDBConn.GetSurveyVersions(token.InstanceID, studyKey, surveyKey)
 	if err != nil {
-		slog.Error("failed to get survey versions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 		return
 	}
@@ -1270,12 +2362,12 @@ func (h *HttpEndpoints) getSurveyVersion(c *gin.Context) {
 	surveyKey := c.Param("surveyKey")
 	versionID := c.Param("versionID")
 
-	slog.Info("getting survey version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID))
+	slog.InfoContext(c, "getting survey version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID))
 
 	version, err := h.studyDBConn.GetSurveyVersion(token.InstanceID, studyKey, surveyKey, versionID)
 
 	if err != nil {
-		slog.Error("failed to get survey version", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey version", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey version"})
 		return
 	}
@@ -1283,23 +2375,356 @@ func (h *HttpEndpoints) getSurveyVersion(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"survey": version})
 }
 
-func (h *HttpEndpoints) deleteSurveyVersion(c *gin.Context) {
+func (h *HttpEndpoints) getSurveyVersionDiff(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	surveyKey := c.Param("surveyKey")
+	versionID := c.Param("versionID")
+	compareTo := c.DefaultQuery("compareTo", "")
+	lang := c.DefaultQuery("lang", "")
+
+	slog.InfoContext(c, "getting survey version diff", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID), slog.String("compareTo", compareTo))
+
+	if compareTo == "" {
+		// by default, compare to the version published before this one
+		versions, err := h.studyDBConn.GetSurveyVersions(token.InstanceID, studyKey, surveyKey)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
+			return
+		}
+		for i, v := range versions {
+			if v.VersionID == versionID && i+1 < len(versions) {
+				compareTo = versions[i+1].VersionID
+				break
+			}
+		}
+		if compareTo == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no previous version found"})
+			return
+		}
+	}
+
+	version, err := h.studyDBConn.GetSurveyVersion(token.InstanceID, studyKey, surveyKey, versionID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get survey version", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "survey version not found"})
+		return
+	}
+	previous, err := h.studyDBConn.GetSurveyVersion(token.InstanceID, studyKey, surveyKey, compareTo)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get survey version", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "survey version to compare to not found"})
+		return
+	}
+
+	var opts *surveydefinition.ExtractOptions
+	if lang != "" {
+		opts = &surveydefinition.ExtractOptions{UseLabelLang: lang}
+	}
+
+	diff := surveydefinition.DiffSurveyVersions(
+		surveydefinition.SurveyDefToVersionPreview(previous, opts),
+		surveydefinition.SurveyDefToVersionPreview(version, opts),
+	)
+	c.JSON(http.StatusOK, gin.H{"diff": diff})
+}
+
+func (h *HttpEndpoints) deleteSurveyVersion(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	surveyKey := c.Param("surveyKey")
+	versionID := c.Param("versionID")
+
+	slog.InfoContext(c, "deleting survey version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID))
+
+	err := h.studyDBConn.DeleteSurveyVersion(token.InstanceID, studyKey, surveyKey, versionID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to delete survey version", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete survey version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "survey version deleted"})
+}
+
+func (h *HttpEndpoints) getDrafts(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	includePublished := c.DefaultQuery("includePublished", "false") == "true"
+
+	slog.InfoContext(c, "getting drafts", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	drafts, err := h.studyDBConn.GetDrafts(token.InstanceID, studyKey, includePublished)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get drafts", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get drafts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drafts": drafts})
+}
+
+func (h *HttpEndpoints) getDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	draftID := c.Param("draftID")
+
+	slog.InfoContext(c, "getting draft", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("draftID", draftID))
+
+	draft, err := h.studyDBConn.GetDraftByID(token.InstanceID, studyKey, draftID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get draft", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "draft not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
+func (h *HttpEndpoints) saveSurveyDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var survey studyTypes.Survey
+	if err := c.ShouldBindJSON(&survey); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if survey.SurveyDefinition.Key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "survey key is required"})
+		return
+	}
+
+	slog.InfoContext(c, "saving survey draft", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", survey.SurveyDefinition.Key))
+
+	draft, err := studyService.SaveSurveyDraft(token.InstanceID, studyKey, survey, token.Subject)
+	if err != nil {
+		respondDraftError(c, err, "failed to save survey draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft, "warnings": studyService.ValidateSurveyDefinition(*draft.Survey)})
+}
+
+func (h *HttpEndpoints) saveStudyRulesDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var rules studyTypes.StudyRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "saving study rules draft", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	draft, err := studyService.SaveStudyRulesDraft(token.InstanceID, studyKey, rules, token.Subject)
+	if err != nil {
+		respondDraftError(c, err, "failed to save study rules draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
+type ApproveDraftReq struct {
+	Comment string `json:"comment"`
+}
+
+func (h *HttpEndpoints) approveDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	draftID := c.Param("draftID")
+
+	var req ApproveDraftReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "approving draft", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("draftID", draftID))
+
+	draft, err := studyService.ApproveDraft(token.InstanceID, studyKey, draftID, token.Subject, req.Comment)
+	if err != nil {
+		respondDraftError(c, err, "failed to approve draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
+type PublishDraftReq struct {
+	// optional, publish immediately if empty or in the past
+	ScheduledFor *time.Time `json:"scheduledFor"`
+}
+
+func (h *HttpEndpoints) publishDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	draftID := c.Param("draftID")
+
+	var req PublishDraftReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "publishing draft", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("draftID", draftID))
+
+	draft, err := studyService.PublishDraft(token.InstanceID, studyKey, draftID, token.Subject, req.ScheduledFor)
+	if err != nil {
+		respondDraftError(c, err, "failed to publish draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
+func (h *HttpEndpoints) deleteDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	draftID := c.Param("draftID")
+
+	slog.InfoContext(c, "deleting draft", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("draftID", draftID))
+
+	err := h.studyDBConn.DeleteDraft(token.InstanceID, studyKey, draftID)
+	if err != nil {
+		respondDraftError(c, err, "failed to delete draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "draft deleted"})
+}
+
+func respondDraftError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, primitive.ErrInvalidHex):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid draft id"})
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": "draft not found or changed in the meantime"})
+	case errors.Is(err, studyService.ErrDraftSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, studyService.ErrDraftNotApprovable),
+		errors.Is(err, studyService.ErrDraftNotApproved),
+		errors.Is(err, studyService.ErrDraftAlreadyPublished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		slog.ErrorContext(c, message, slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// directPublishAllowed answers with 409 if the study publishes surveys and study rules only via approved drafts
+func (h *HttpEndpoints) directPublishAllowed(c *gin.Context, instanceID string, studyKey string) bool {
+	study, err := h.studyDBConn.GetStudy(instanceID, studyKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return false
+		}
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return false
+	}
+	if study.Configs.RequireReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "study requires review, publish via an approved draft"})
+		return false
+	}
+	return true
+}
+
+func (h *HttpEndpoints) getSurveyDriftAnalysis(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
 	studyKey := c.Param("studyKey")
 	surveyKey := c.Param("surveyKey")
-	versionID := c.Param("versionID")
 
-	slog.Info("deleting survey version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID))
+	until := time.Now().Unix()
+	from := time.Now().AddDate(0, 0, -30).Unix()
+	if v := c.DefaultQuery("until", ""); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid until"})
+			return
+		}
+		until = parsed
+	}
+	if v := c.DefaultQuery("from", ""); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		from = parsed
+	}
+	if from > until {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before until"})
+		return
+	}
+
+	slog.InfoContext(c, "analysing survey drift", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
-	err := h.studyDBConn.DeleteSurveyVersion(token.InstanceID, studyKey, surveyKey, versionID)
+	surveyVersions, err := surveydefinition.PrepareSurveyInfosFromDB(
+		h.studyDBConn,
+		token.InstanceID,
+		studyKey,
+		surveyKey,
+		&surveydefinition.ExtractOptions{
+			UseLabelLang: "",
+			IncludeItems: nil,
+			ExcludeItems: nil,
+		},
+	)
 	if err != nil {
-		slog.Error("failed to delete survey version", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete survey version"})
+		slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "survey version deleted"})
+	analyzer := surveyresponses.NewDriftAnalyzer(surveyVersions)
+	err = h.studyDBConn.FindAndExecuteOnResponses(
+		context.Background(),
+		token.InstanceID,
+		studyKey,
+		bson.M{
+			"key": surveyKey,
+			"$and": bson.A{
+				bson.M{"arrivedAt": bson.M{"$gte": from}},
+				bson.M{"arrivedAt": bson.M{"$lte": until}},
+			},
+		},
+		bson.M{"arrivedAt": 1},
+		false,
+		func(dbService *studyDB.StudyDBService, r studyTypes.SurveyResponse, instanceID, studyKey string, args ...interface{}) error {
+			analyzer.AddResponse(&r)
+			return nil
+		},
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to analyse responses", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to analyse responses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":   from,
+		"until":  until,
+		"report": analyzer.Report(),
+	})
 }
 
 type StudyUserPermissionInfo struct {
@@ -1314,7 +2739,7 @@ func (h *HttpEndpoints) getStudyPermissions(c *gin.Context) {
 
 	permissions, err := h.muDBConn.GetPermissionByResource(token.InstanceID, pc.RESOURCE_TYPE_STUDY, studyKey)
 	if err != nil {
-		slog.Error("failed to get study permissions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study permissions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study permissions"})
 		return
 	}
@@ -1353,7 +2778,7 @@ func (h *HttpEndpoints) getStudyPermissions(c *gin.Context) {
 			var err error
 			user, err = h.muDBConn.GetUserByID(token.InstanceID, permission.SubjectID)
 			if err != nil {
-				slog.Error("failed to get user info", slog.String("error", err.Error()))
+				slog.ErrorContext(c, "failed to get user info", slog.String("error", err.Error()))
 				continue
 			}
 			studyUserPermissionInfos[userID] = &StudyUserPermissionInfo{
@@ -1382,12 +2807,12 @@ func (h *HttpEndpoints) addStudyPermission(c *gin.Context) {
 
 	var permission managementuser.Permission
 	if err := c.ShouldBindJSON(&permission); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("adding study permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("subjectID", permission.SubjectID), slog.String("action", permission.Action))
+	slog.InfoContext(c, "adding study permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("subjectID", permission.SubjectID), slog.String("action", permission.Action))
 
 	permission.SubjectType = pc.SUBJECT_TYPE_MANAGEMENT_USER
 	permission.ResourceType = pc.RESOURCE_TYPE_STUDY
@@ -1404,7 +2829,7 @@ func (h *HttpEndpoints) addStudyPermission(c *gin.Context) {
 	)
 
 	if err != nil {
-		slog.Error("failed to add study permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to add study permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add study permission"})
 		return
 	}
@@ -1419,24 +2844,24 @@ func (h *HttpEndpoints) deleteStudyPermission(c *gin.Context) {
 
 	permissionID := c.Param("permissionID")
 
-	slog.Info("deleting study permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("permissionID", permissionID))
+	slog.InfoContext(c, "deleting study permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("permissionID", permissionID))
 
 	permission, err := h.muDBConn.GetPermissionByID(token.InstanceID, permissionID)
 	if err != nil {
-		slog.Error("failed to get study permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study permission"})
 		return
 	}
 
 	if permission.ResourceType != pc.RESOURCE_TYPE_STUDY || permission.ResourceKey != studyKey {
-		slog.Warn("permission does not belong to the study", slog.String("permissionID", permissionID))
+		slog.WarnContext(c, "permission does not belong to the study", slog.String("permissionID", permissionID))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "permission does not belong to the study"})
 		return
 	}
 
 	err = h.muDBConn.DeletePermission(token.InstanceID, permissionID)
 	if err != nil {
-		slog.Error("failed to delete study permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete study permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete study permission"})
 		return
 	}
@@ -1444,16 +2869,233 @@ func (h *HttpEndpoints) deleteStudyPermission(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "study permission deleted"})
 }
 
+type ConfidentialAccessGrantReq struct {
+	UserID    string    `json:"userID"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (h *HttpEndpoints) getConfidentialAccessGrants(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting confidential access grants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	grants, err := h.studyDBConn.GetConfidentialAccessGrants(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get confidential access grants", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get confidential access grants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grants": grants})
+}
+
+func (h *HttpEndpoints) addConfidentialAccessGrant(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req ConfidentialAccessGrantReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	req.Purpose = strings.TrimSpace(req.Purpose)
+	if req.UserID == "" || req.Purpose == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userID and purpose are required"})
+		return
+	}
+	if req.UserID == token.Subject {
+		slog.WarnContext(c, "attempted to grant confidential access to self", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot grant confidential access to yourself"})
+		return
+	}
+	now := time.Now()
+	if !req.ExpiresAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+		return
+	}
+	if req.ExpiresAt.After(now.Add(MAX_CONFIDENTIAL_ACCESS_GRANT_DURATION)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt is too far in the future"})
+		return
+	}
+
+	slog.InfoContext(c, "adding confidential access grant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("granteeID", req.UserID))
+
+	if _, err := h.muDBConn.GetUserByID(token.InstanceID, req.UserID); err != nil {
+		if err == mongo.ErrNoDocuments || errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown management user"})
+			return
+		}
+		slog.ErrorContext(c, "failed to get grantee", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get grantee"})
+		return
+	}
+
+	grant := studyTypes.ConfidentialAccessGrant{
+		ID:        primitive.NewObjectID(),
+		StudyKey:  studyKey,
+		UserID:    req.UserID,
+		Purpose:   req.Purpose,
+		ExpiresAt: req.ExpiresAt,
+		GrantedBy: token.Subject,
+	}
+
+	// the grant is only added if it is audited
+	err := h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+		StudyKey: studyKey,
+		UserID:   token.Subject,
+		Action:   studyTypes.AUDIT_ACTION_CONFIDENTIAL_ACCESS_GRANTED,
+		Purpose:  grant.Purpose,
+		Details: map[string]string{
+			"grantID":   grant.ID.Hex(),
+			"granteeID": grant.UserID,
+			"expiresAt": grant.ExpiresAt.Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add confidential access grant"})
+		return
+	}
+
+	grant, err = h.studyDBConn.AddConfidentialAccessGrant(token.InstanceID, grant)
+	if err != nil {
+		slog.ErrorContext(c, "failed to add confidential access grant", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add confidential access grant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"grant": grant})
+}
+
+func (h *HttpEndpoints) revokeConfidentialAccessGrant(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	grantID := c.Param("grantID")
+
+	slog.InfoContext(c, "revoking confidential access grant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("grantID", grantID))
+
+	grants, err := h.studyDBConn.GetConfidentialAccessGrants(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get confidential access grants", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke confidential access grant"})
+		return
+	}
+	if !slices.ContainsFunc(grants, func(grant studyTypes.ConfidentialAccessGrant) bool {
+		return grant.ID.Hex() == grantID && grant.RevokedAt == nil
+	}) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "active grant not found"})
+		return
+	}
+
+	// the grant is only revoked if it is audited
+	err = h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+		StudyKey: studyKey,
+		UserID:   token.Subject,
+		Action:   studyTypes.AUDIT_ACTION_CONFIDENTIAL_ACCESS_REVOKED,
+		Details:  map[string]string{"grantID": grantID},
+	})
+	if err != nil {
+		slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke confidential access grant"})
+		return
+	}
+
+	err = h.studyDBConn.RevokeConfidentialAccessGrant(token.InstanceID, studyKey, grantID, token.Subject)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "active grant not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to revoke confidential access grant", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke confidential access grant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "confidential access grant revoked"})
+}
+
+func (h *HttpEndpoints) getStudyAuditLog(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting study audit log", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil || query == nil {
+		slog.ErrorContext(c, "failed to parse paginated query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	entries, paginationInfo, err := h.studyDBConn.GetAuditLogEntries(
+		token.InstanceID,
+		studyKey,
+		c.DefaultQuery("userID", ""),
+		c.DefaultQuery("action", ""),
+		query.Page,
+		query.Limit,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get audit log", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":    entries,
+		"pagination": paginationInfo,
+	})
+}
+
+func (h *HttpEndpoints) getStudyDataQualityFindings(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting study data quality findings", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil || query == nil {
+		slog.ErrorContext(c, "failed to parse paginated query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	findings, paginationInfo, err := h.studyDBConn.GetDataQualityFindings(
+		token.InstanceID,
+		studyKey,
+		c.DefaultQuery("type", ""),
+		query.Page,
+		query.Limit,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get data quality findings", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get data quality findings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"findings":   findings,
+		"pagination": paginationInfo,
+	})
+}
+
 func (h *HttpEndpoints) getNotificationSubscriptions(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting notification subscriptions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting notification subscriptions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	subscriptions, err := h.studyDBConn.GetNotificationSubscriptions(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to get notification subscriptions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get notification subscriptions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get notification subscriptions"})
 		return
 	}
@@ -1472,16 +3114,16 @@ func (h *HttpEndpoints) updateNotificationSubscriptions(c *gin.Context) {
 
 	var req NotificationSubscriptionsUpdateReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("updating notification subscriptions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "updating notification subscriptions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	err := h.studyDBConn.UpdateStudyNotificationSubscriptions(token.InstanceID, studyKey, req.Subscriptions)
 	if err != nil {
-		slog.Error("failed to update notification subscriptions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update notification subscriptions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update notification subscriptions"})
 		return
 	}
@@ -1494,11 +3136,11 @@ func (h *HttpEndpoints) getCurrentStudyRules(c *gin.Context) {
 
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting current study rules", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting current study rules", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	rules, err := h.studyDBConn.GetCurrentStudyRules(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to get current study rules", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get current study rules", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get current study rules"})
 		return
 	}
@@ -1513,27 +3155,31 @@ func (h *HttpEndpoints) publishNewStudyRulesVersion(c *gin.Context) {
 
 	var rules studyTypes.StudyRules
 	if err := c.ShouldBindJSON(&rules); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
+	if !h.directPublishAllowed(c, token.InstanceID, studyKey) {
+		return
+	}
+
 	rules.StudyKey = studyKey
 	rules.UploadedAt = time.Now().Unix()
 	rules.UploadedBy = token.Subject
 
 	err := rules.MarshalRules()
 	if err != nil {
-		slog.Error("failed to marshal study rules", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to marshal study rules", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid study rules"})
 		return
 	}
 
-	slog.Info("publishing new study rules version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "publishing new study rules version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	err = h.studyDBConn.SaveStudyRules(token.InstanceID, studyKey, rules)
 	if err != nil {
-		slog.Error("failed to publish new study rules version", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to publish new study rules version", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to publish new study rules version"})
 		return
 	}
@@ -1546,11 +3192,11 @@ func (h *HttpEndpoints) getStudyRuleVersions(c *gin.Context) {
 
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting study rule versions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting study rule versions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	versions, err := h.studyDBConn.GetStudyRulesHistory(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to get study rule versions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study rule versions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study rule versions"})
 		return
 	}
@@ -1565,11 +3211,11 @@ func (h *HttpEndpoints) getStudyRuleVersion(c *gin.Context) {
 
 	versionID := c.Param("id")
 
-	slog.Info("getting study rule version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("versionID", versionID))
+	slog.InfoContext(c, "getting study rule version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("versionID", versionID))
 
 	version, err := h.studyDBConn.GetStudyRulesByID(token.InstanceID, studyKey, versionID)
 	if err != nil {
-		slog.Error("failed to get study rule version", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study rule version", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study rule version"})
 		return
 	}
@@ -1577,6 +3223,99 @@ func (h *HttpEndpoints) getStudyRuleVersion(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"studyRules": version})
 }
 
+type dryRunStudyRulesReq struct {
+	// if neither rules nor expression are provided, the current study rules are used
+	Rules      []studyTypes.Expression `json:"rules"`
+	Expression *studyTypes.Expression  `json:"expression"`
+
+	// state to evaluate against: either an existing participant, or a supplied state
+	ParticipantID    string                  `json:"participantID"`
+	ParticipantState *studyTypes.Participant `json:"participantState"`
+
+	Event struct {
+		Type     string                    `json:"type"`
+		EventKey string                    `json:"eventKey"`
+		Payload  map[string]interface{}    `json:"payload"`
+		Response studyTypes.SurveyResponse `json:"response"`
+	} `json:"event"`
+}
+
+func (h *HttpEndpoints) dryRunStudyRules(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req dryRunStudyRulesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Event.Type == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event type is required"})
+		return
+	}
+
+	slog.InfoContext(c, "dry run of study rules", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	var pState studyTypes.Participant
+	if req.ParticipantState != nil {
+		pState = *req.ParticipantState
+	} else if req.ParticipantID != "" {
+		p, err := h.studyDBConn.GetParticipantByID(token.InstanceID, studyKey, req.ParticipantID)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get participant", slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "participant not found"})
+			return
+		}
+		pState = p
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "participantID or participantState is required"})
+		return
+	}
+
+	event := studyengine.StudyEvent{
+		InstanceID: token.InstanceID,
+		StudyKey:   studyKey,
+		Type:       req.Event.Type,
+		EventKey:   req.Event.EventKey,
+		Payload:    req.Event.Payload,
+		Response:   req.Event.Response,
+	}
+
+	if req.Expression != nil {
+		val, trace, err := studyengine.DryRunExpression(*req.Expression, pState, event)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"value": val,
+			"error": errMsg,
+			"trace": trace,
+		})
+		return
+	}
+
+	rules := req.Rules
+	if len(rules) == 0 {
+		rulesObj, err := h.studyDBConn.GetCurrentStudyRules(token.InstanceID, studyKey)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get current study rules", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get current study rules"})
+			return
+		}
+		rules = rulesObj.Rules
+	}
+
+	result, trace := studyengine.DryRunRules(rules, pState, event)
+	c.JSON(http.StatusOK, gin.H{
+		"participantState": result.PState,
+		"reportsToCreate":  result.ReportsToCreate,
+		"trace":            trace,
+	})
+}
+
 func (h *HttpEndpoints) deleteStudyRuleVersion(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
@@ -1584,11 +3323,11 @@ func (h *HttpEndpoints) deleteStudyRuleVersion(c *gin.Context) {
 
 	versionID := c.Param("id")
 
-	slog.Info("deleting study rule version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("versionID", versionID))
+	slog.InfoContext(c, "deleting study rule version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("versionID", versionID))
 
 	err := h.studyDBConn.DeleteStudyRulesByID(token.InstanceID, studyKey, versionID)
 	if err != nil {
-		slog.Error("failed to delete study rule version", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete study rule version", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete study rule version"})
 		return
 	}
@@ -1605,14 +3344,14 @@ func (h *HttpEndpoints) runActionOnParticipant(c *gin.Context) {
 		Rules []studyTypes.Expression `json:"rules"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("running study action on participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+	slog.InfoContext(c, "running study action on participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
 
-	result, err := studyService.OnRunStudyAction(studyService.RunStudyActionReq{
+	result, err := studyService.OnRunStudyAction(c.Request.Context(), studyService.RunStudyActionReq{
 		InstanceID:           token.InstanceID,
 		StudyKey:             studyKey,
 		OnlyForParticipantID: participantID,
@@ -1621,7 +3360,7 @@ func (h *HttpEndpoints) runActionOnParticipant(c *gin.Context) {
 	})
 
 	if err != nil {
-		slog.Error("failed to run study action", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to run study action", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -1664,7 +3403,17 @@ func (h *HttpEndpoints) onActionTaskCompleted(
 		h.taskFailed(instanceID, taskID, err.Error())
 		return
 	}
+	h.completeTaskWithResults(taskID, results, int(results.ParticipantCount), instanceID, relativeFolderName)
+}
 
+// completeTaskWithResults writes the results as JSON file of the task and marks it completed
+func (h *HttpEndpoints) completeTaskWithResults(
+	taskID string,
+	results any,
+	participantCount int,
+	instanceID string,
+	relativeFolderName string,
+) {
 	// create file write
 	relativeFilepath := filepath.Join(relativeFolderName, "results_"+taskID+".json")
 	exportFilePath := filepath.Join(h.filestorePath, relativeFilepath)
@@ -1688,7 +3437,7 @@ func (h *HttpEndpoints) onActionTaskCompleted(
 		instanceID,
 		taskID,
 		studyTypes.TASK_STATUS_COMPLETED,
-		int(results.ParticipantCount),
+		participantCount,
 		"",
 		relativeFilepath,
 	)
@@ -1706,17 +3455,17 @@ func (h *HttpEndpoints) runActionOnParticipants(c *gin.Context) {
 		Rules []studyTypes.Expression `json:"rules"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("running study action on participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "running study action on participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	relativeFolderName := filepath.Join(token.InstanceID, "actionRuns")
 	exportFolder := filepath.Join(h.filestorePath, relativeFolderName)
 	if err := os.MkdirAll(exportFolder, os.ModePerm); err != nil {
-		slog.Error("failed to create actionRuns folder", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create actionRuns folder", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create actionRuns folder"})
 		return
 	}
@@ -1728,15 +3477,16 @@ func (h *HttpEndpoints) runActionOnParticipants(c *gin.Context) {
 		studyTypes.TASK_FILE_TYPE_JSON,
 	)
 	if err != nil {
-		slog.Error("failed to create task", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create task", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
 		return
 	}
 
-	go func() {
+	ctx := requestid.Detach(c.Request.Context())
+	h.runInBackground("study-action", func() {
 		first := true
 
-		results, err := studyService.OnRunStudyAction(studyService.RunStudyActionReq{
+		results, err := studyService.OnRunStudyAction(ctx, studyService.RunStudyActionReq{
 			InstanceID: token.InstanceID,
 			StudyKey:   studyKey,
 			Rules:      req.Rules,
@@ -1768,12 +3518,18 @@ func (h *HttpEndpoints) runActionOnParticipants(c *gin.Context) {
 		})
 		if err != nil {
 			slog.Error("running study actions resulted in error", slog.String("error", err.Error()))
+			managementalerts.Send(token.InstanceID, token.Subject, managementalerts.Alert{
+				Type:     managementalerts.ALERT_RULE_ERRORS,
+				StudyKey: studyKey,
+				Title:    "Study action failed",
+				Details:  [][2]string{{"Task", task.ID.Hex()}, {"Error", err.Error()}},
+			})
 			return
 		}
 
 		h.onActionTaskCompleted(task.ID.Hex(), results, err, token.InstanceID, relativeFolderName)
 
-	}()
+	})
 
 	c.JSON(http.StatusOK, gin.H{"task": task})
 }
@@ -1783,17 +3539,17 @@ func (h *HttpEndpoints) getStudyActionTaskStatus(c *gin.Context) {
 
 	taskID := c.Param("taskID")
 
-	slog.Info("getting study action task status", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
+	slog.InfoContext(c, "getting study action task status", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
 
 	task, err := h.studyDBConn.GetTaskByID(token.InstanceID, taskID)
 	if err != nil {
-		slog.Error("failed to get export task status", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get export task status", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export task status"})
 		return
 	}
 
 	if task.CreatedBy != token.Subject && !token.IsAdmin {
-		slog.Warn("user is not allowed to get task status", slog.String("userID", token.Subject), slog.String("taskID", taskID))
+		slog.WarnContext(c, "user is not allowed to get task status", slog.String("userID", token.Subject), slog.String("taskID", taskID))
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
@@ -1806,23 +3562,23 @@ func (h *HttpEndpoints) getStudyActionTaskResult(c *gin.Context) {
 
 	taskID := c.Param("taskID")
 
-	slog.Info("getting export task result", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
+	slog.InfoContext(c, "getting export task result", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
 
 	task, err := h.studyDBConn.GetTaskByID(token.InstanceID, taskID)
 	if err != nil {
-		slog.Error("failed to get export task result", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get export task result", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export task result"})
 		return
 	}
 
 	if task.CreatedBy != token.Subject && !token.IsAdmin {
-		slog.Warn("user is not allowed to get task result", slog.String("userID", token.Subject), slog.String("taskID", taskID))
+		slog.WarnContext(c, "user is not allowed to get task result", slog.String("userID", token.Subject), slog.String("taskID", taskID))
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
 
 	if task.Status != studyTypes.TASK_STATUS_COMPLETED {
-		slog.Error("task is not completed", slog.String("taskID", taskID), slog.String("status", task.Status))
+		slog.ErrorContext(c, "task is not completed", slog.String("taskID", taskID), slog.String("status", task.Status))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "task is not completed"})
 		return
 	}
@@ -1831,7 +3587,7 @@ func (h *HttpEndpoints) getStudyActionTaskResult(c *gin.Context) {
 
 	// file exists?
 	if _, err := os.Stat(resultFilePath); os.IsNotExist(err) {
-		slog.Error("file does not exist", slog.String("path", resultFilePath))
+		slog.ErrorContext(c, "file does not exist", slog.String("path", resultFilePath))
 		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
 		return
 	}
@@ -1839,7 +3595,7 @@ func (h *HttpEndpoints) getStudyActionTaskResult(c *gin.Context) {
 	// read JSON file and send back
 	file, err := os.Open(resultFilePath)
 	if err != nil {
-		slog.Error("failed to open file", slog.String("path", resultFilePath), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to open file", slog.String("path", resultFilePath), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open file"})
 		return
 	}
@@ -1848,7 +3604,7 @@ func (h *HttpEndpoints) getStudyActionTaskResult(c *gin.Context) {
 	var result map[string]interface{}
 	err = json.NewDecoder(file).Decode(&result)
 	if err != nil {
-		slog.Error("failed to decode JSON file", slog.String("path", resultFilePath), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to decode JSON file", slog.String("path", resultFilePath), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode JSON file"})
 		return
 	}
@@ -1868,14 +3624,14 @@ func (h *HttpEndpoints) runActionOnPreviousResponsesForParticipant(c *gin.Contex
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("running study action on previous responses for participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+	slog.InfoContext(c, "running study action on previous responses for participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
 
-	result, err := studyService.OnRunStudyActionForPreviousResponses(studyService.RunStudyActionReq{
+	result, err := studyService.OnRunStudyActionForPreviousResponses(c.Request.Context(), studyService.RunStudyActionReq{
 		InstanceID:           token.InstanceID,
 		StudyKey:             studyKey,
 		OnlyForParticipantID: participantID,
@@ -1884,7 +3640,7 @@ func (h *HttpEndpoints) runActionOnPreviousResponsesForParticipant(c *gin.Contex
 	}, req.SurveyKeys, req.From, req.To)
 
 	if err != nil {
-		slog.Error("failed to run study action", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to run study action", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -1907,17 +3663,17 @@ func (h *HttpEndpoints) runActionOnPreviousResponsesForParticipants(c *gin.Conte
 		To         int64                   `json:"to"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("running study action on participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "running study action on participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	relativeFolderName := filepath.Join(token.InstanceID, "actionRuns")
 	exportFolder := filepath.Join(h.filestorePath, relativeFolderName)
 	if err := os.MkdirAll(exportFolder, os.ModePerm); err != nil {
-		slog.Error("failed to create actionRuns folder", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create actionRuns folder", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create actionRuns folder"})
 		return
 	}
@@ -1929,15 +3685,17 @@ func (h *HttpEndpoints) runActionOnPreviousResponsesForParticipants(c *gin.Conte
 		studyTypes.TASK_FILE_TYPE_JSON,
 	)
 	if err != nil {
-		slog.Error("failed to create task", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create task", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
 		return
 	}
 
-	go func() {
+	ctx := requestid.Detach(c.Request.Context())
+	h.runInBackground("study-action-on-previous-responses", func() {
 		first := true
 
 		results, err := studyService.OnRunStudyActionForPreviousResponses(
+			ctx,
 			studyService.RunStudyActionReq{
 				InstanceID: token.InstanceID,
 				StudyKey:   studyKey,
@@ -1974,12 +3732,18 @@ func (h *HttpEndpoints) runActionOnPreviousResponsesForParticipants(c *gin.Conte
 		)
 		if err != nil {
 			slog.Error("running study actions resulted in error", slog.String("error", err.Error()))
+			managementalerts.Send(token.InstanceID, token.Subject, managementalerts.Alert{
+				Type:     managementalerts.ALERT_RULE_ERRORS,
+				StudyKey: studyKey,
+				Title:    "Study action failed",
+				Details:  [][2]string{{"Task", task.ID.Hex()}, {"Error", err.Error()}},
+			})
 			return
 		}
 
 		h.onActionTaskCompleted(task.ID.Hex(), results, err, token.InstanceID, relativeFolderName)
 
-	}()
+	})
 
 	c.JSON(http.StatusOK, gin.H{"task": task})
 }
@@ -1991,14 +3755,14 @@ func (h *HttpEndpoints) getSurveyInfo(c *gin.Context) {
 
 	surveyKey := c.DefaultQuery("surveyKey", "")
 	if surveyKey == "" {
-		slog.Error("surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "surveyKey is required"})
 		return
 	}
 
 	format := c.DefaultQuery("format", "json")
 	if format != "json" && format != "csv" {
-		slog.Error("invalid format", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("format", format))
+		slog.ErrorContext(c, "invalid format", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("format", format))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format query parameter"})
 		return
 	}
@@ -2006,7 +3770,7 @@ func (h *HttpEndpoints) getSurveyInfo(c *gin.Context) {
 	language := c.DefaultQuery("language", "en")
 	shortKeys := c.DefaultQuery("shortKeys", "false") == "true"
 
-	slog.Info("getting survey info", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	slog.InfoContext(c, "getting survey info", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
 	sInfos, err := surveydefinition.PrepareSurveyInfosFromDB(
 		h.studyDBConn,
@@ -2018,7 +3782,7 @@ func (h *HttpEndpoints) getSurveyInfo(c *gin.Context) {
 		},
 	)
 	if err != nil {
-		slog.Error("failed to get survey info", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey info", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey info"})
 		return
 	}
@@ -2029,19 +3793,82 @@ func (h *HttpEndpoints) getSurveyInfo(c *gin.Context) {
 		shortKeys,
 	)
 
-	if format == "json" {
-		c.Header("Content-Disposition", `attachment; filename=`+fmt.Sprintf("survey-infos_%s_%s.json", studyKey, surveyKey))
-		c.JSON(http.StatusOK, gin.H{"versions": siExp.GetSurveyInfos(), "key": surveyKey})
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename=`+fmt.Sprintf("survey-infos_%s_%s.json", studyKey, surveyKey))
+		c.JSON(http.StatusOK, gin.H{"versions": siExp.GetSurveyInfos(), "key": surveyKey})
+		return
+	}
+
+	// CSV:
+	c.Header("Content-Disposition", `attachment; filename=`+fmt.Sprintf("survey-infos_%s_%s.csv", studyKey, surveyKey))
+	c.Header("Content-Type", "text/csv")
+	err = siExp.GetSurveyInfoCSV(c.Writer)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get survey info csv", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey info csv"})
+		return
+	}
+}
+
+func (h *HttpEndpoints) getSurveyCodebook(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	surveyKey := c.DefaultQuery("surveyKey", "")
+	if surveyKey == "" {
+		slog.ErrorContext(c, "surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "surveyKey is required"})
+		return
+	}
+
+	format := c.DefaultQuery("format", surveydefinition.CODEBOOK_FORMAT_CSV)
+	if !surveydefinition.IsSupportedCodebookFormat(format) {
+		slog.ErrorContext(c, "invalid format", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("format", format))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format query parameter"})
+		return
+	}
+	// latest version if empty
+	versionID := c.DefaultQuery("versionID", "")
+	language := c.DefaultQuery("language", "en")
+	shortKeys := c.DefaultQuery("shortKeys", "false") == "true"
+
+	slog.InfoContext(c, "getting survey codebook", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID))
+
+	sInfos, err := surveydefinition.PrepareSurveyInfosFromDB(
+		h.studyDBConn,
+		token.InstanceID,
+		studyKey,
+		surveyKey,
+		&surveydefinition.ExtractOptions{
+			UseLabelLang: language,
+		},
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get survey info", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey info"})
+		return
+	}
+
+	siExp := surveydefinition.NewSurveyInfoExporter(sInfos, surveyKey, shortKeys)
+	version, ok := surveydefinition.SelectSurveyVersion(siExp.GetSurveyInfos(), versionID)
+	if !ok {
+		slog.WarnContext(c, "survey version not found", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID))
+		c.JSON(http.StatusNotFound, gin.H{"error": "survey version not found"})
 		return
 	}
 
-	// CSV:
-	c.Header("Content-Disposition", `attachment; filename=`+fmt.Sprintf("survey-infos_%s_%s.csv", studyKey, surveyKey))
-	c.Header("Content-Type", "text/csv")
-	err = siExp.GetSurveyInfoCSV(c.Writer)
-	if err != nil {
-		slog.Error("failed to get survey info csv", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey info csv"})
+	codebook := surveydefinition.Codebook{
+		StudyKey:  studyKey,
+		SurveyKey: surveyKey,
+		Version:   version,
+	}
+
+	c.Header("Content-Disposition", `attachment; filename=`+fmt.Sprintf("codebook_%s_%s_%s%s", studyKey, surveyKey, version.VersionID, surveydefinition.CodebookFileExtension(format)))
+	c.Header("Content-Type", surveydefinition.CodebookContentType(format))
+	if err := codebook.Write(c.Writer, format); err != nil {
+		slog.ErrorContext(c, "failed to write codebook", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write codebook"})
 		return
 	}
 }
@@ -2053,18 +3880,18 @@ func (h *HttpEndpoints) getResponsesCount(c *gin.Context) {
 
 	filter, err := apihelpers.ParseFilterQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse filter", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse filter", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	filter["key"] = c.DefaultQuery("surveyKey", "")
 
-	slog.Info("getting responses count", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting responses count", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	count, err := h.studyDBConn.GetResponsesCount(token.InstanceID, studyKey, filter)
 	if err != nil {
-		slog.Error("failed to get responses count", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get responses count", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get responses count"})
 		return
 	}
@@ -2078,22 +3905,33 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 
 	query, err := apihelpers.ParseResponseExportQueryFromCtx(c)
 	if err != nil || query == nil {
-		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse query", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	if query.SurveyKey == "" {
-		slog.Error("surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "surveyKey is required"})
 		return
 	}
 
-	slog.Info("generating responses export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", query.SurveyKey))
+	if !surveyresponses.IsSupportedExportFormat(query.Format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format"})
+		return
+	}
+
+	// responses of synthetic-monitoring accounts are not exported
+	query.PaginationInfos.Filter = studyDB.ExcludeSynthetic(query.PaginationInfos.Filter)
+	if !query.IncludeQuarantined {
+		query.PaginationInfos.Filter = studyDB.ExcludeQuarantined(query.PaginationInfos.Filter)
+	}
+
+	slog.InfoContext(c, "generating responses export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", query.SurveyKey))
 
 	count, err := h.studyDBConn.GetResponsesCount(token.InstanceID, studyKey, query.PaginationInfos.Filter)
 	if err != nil {
-		slog.Error("failed to get responses count", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get responses count", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get responses count"})
 		return
 	}
@@ -2111,13 +3949,13 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 		studyKey,
 		query.SurveyKey,
 		&surveydefinition.ExtractOptions{
-			UseLabelLang: "",
+			UseLabelLang: query.LabelLang,
 			IncludeItems: nil,
 			ExcludeItems: nil,
 		},
 	)
 	if err != nil {
-		slog.Error("failed to get survey versions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 		return
 	}
@@ -2131,25 +3969,20 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 		query.ExtraCtxCols,
 	)
 	if err != nil {
-		slog.Error("failed to create response parser", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create response parser", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response parser"})
 		return
 	}
 
-	fileType := studyTypes.TASK_FILE_TYPE_CSV
-	if query.Format == "json" {
-		fileType = studyTypes.TASK_FILE_TYPE_JSON
-	}
-
 	exportTask, err := h.studyDBConn.CreateTask(
 		token.InstanceID,
 		token.Subject,
 		int(count),
-		fileType,
+		exportjobs.FileType(query.Format),
 	)
 
 	if err != nil {
-		slog.Error("failed to create export task", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create export task", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export task"})
 		return
 	}
@@ -2157,19 +3990,14 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 	relativeFolderName := filepath.Join(token.InstanceID, "exports")
 	exportFolder := filepath.Join(h.filestorePath, relativeFolderName)
 	if err := os.MkdirAll(exportFolder, os.ModePerm); err != nil {
-		slog.Error("failed to create export folder", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create export folder", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export folder"})
 		return
 	}
 
-	go func() {
+	h.runInBackground("responses-export", func() {
 		// create file write
-		ext := ".csv"
-		if query.Format == "json" {
-			ext = ".json"
-		}
-
-		relativeFilepath := filepath.Join(relativeFolderName, "responses_"+exportTask.ID.Hex()+ext)
+		relativeFilepath := filepath.Join(relativeFolderName, "responses_"+exportTask.ID.Hex()+surveyresponses.ExportFileExtension(query.Format))
 		exportFilePath := filepath.Join(h.filestorePath, relativeFilepath)
 		file, err := os.Create(exportFilePath)
 		if err != nil {
@@ -2181,10 +4009,20 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 
 		defer file.Close()
 
-		exporter, err := surveyresponses.NewResponseExporter(
+		exporter, err := surveyresponses.NewResponseExporterWithOptions(
 			respParser,
 			file,
-			query.Format,
+			surveyresponses.ExportOptions{
+				Format:           query.Format,
+				ValueLabels:      query.ValueLabels,
+				Pseudonymization: query.Pseudonymization,
+				ParticipantInfo: surveyresponses.ParticipantInfoOptions{
+					Fields: query.ParticipantInfo,
+					Flags:  query.ParticipantFlags,
+					Lookup: surveyresponses.ParticipantsFromDB(h.studyDBConn, token.InstanceID, studyKey),
+				},
+				ValidationIssues: query.ValidationIssues,
+			},
 		)
 		if err != nil {
 			slog.Error("failed to create response exporter", slog.String("error", err.Error()))
@@ -2193,43 +4031,22 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 			return
 		}
 
-		ctx := context.Background()
-		counter := 0
-
-		err = h.studyDBConn.FindAndExecuteOnResponses(
-			ctx,
-			token.InstanceID,
-			studyKey,
-			query.PaginationInfos.Filter,
-			query.PaginationInfos.Sort,
-			true,
-			func(dbService *studyDB.StudyDBService, r studyTypes.SurveyResponse, instanceID, studyKey string, args ...interface{}) error {
-				task := args[0].(*studyTypes.Task)
-				exporter := args[1].(*surveyresponses.ResponseExporter)
-
-				err := exporter.WriteResponse(&r)
-				if err != nil {
-					return err
-				}
-				counter += 1
-
-				err = dbService.UpdateTaskProgress(
-					instanceID,
-					task.ID.Hex(),
-					counter,
-				)
-				if err != nil {
-					slog.Error("failed to update task progress", slog.String("error", err.Error()))
-					// not a big issue, so let's try next time
-					return nil
-				}
+		exportManifest := manifest.NewExportManifest(token.InstanceID, studyKey, query.SurveyKey, "responses", query.Format, query.PaginationInfos.Filter)
+		exportManifest.Columns = exporter.Schema()
 
-				return nil
+		counter, err := exporter.Stream(
+			context.Background(),
+			surveyresponses.ResponsesFromDB(h.studyDBConn, token.InstanceID, studyKey, query.PaginationInfos.Filter, query.PaginationInfos.Sort),
+			surveyresponses.StreamOptions{
+				OnResponseWritten: exportManifest.ObserveResponse,
+				OnProgress: func(written int) {
+					if err := h.studyDBConn.UpdateTaskProgress(token.InstanceID, exportTask.ID.Hex(), written); err != nil {
+						// not a big issue, so let's try next time
+						slog.Error("failed to update task progress", slog.String("error", err.Error()))
+					}
+				},
 			},
-			&exportTask,
-			exporter,
 		)
-
 		if err != nil {
 			slog.Error("failed to export responses", slog.String("error", err.Error()))
 			h.onExportTaskFailed(token.InstanceID, exportTask.ID.Hex(), err.Error())
@@ -2243,6 +4060,8 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 			return
 		}
 
+		writeExportManifest(exportManifest, exportFilePath)
+
 		err = h.studyDBConn.UpdateTaskCompleted(
 			token.InstanceID,
 			exportTask.ID.Hex(),
@@ -2256,7 +4075,7 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 			return
 		}
 
-	}()
+	})
 
 	c.JSON(http.StatusOK, gin.H{"task": exportTask})
 }
@@ -2268,16 +4087,16 @@ func (h *HttpEndpoints) getParticipantsCount(c *gin.Context) {
 
 	filter, err := apihelpers.ParseFilterQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse filter", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse filter", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("getting participants count", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting participants count", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	count, err := h.studyDBConn.GetParticipantCount(token.InstanceID, studyKey, filter)
 	if err != nil {
-		slog.Error("failed to get participants count", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get participants count", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participants count"})
 		return
 	}
@@ -2292,23 +4111,26 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 
 	filter, err := apihelpers.ParseFilterQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse filter", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse filter", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	sort, err := apihelpers.ParseSortQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse sort", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse sort", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("generating participants export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	// participants of synthetic-monitoring accounts are not exported
+	filter = studyDB.ExcludeSynthetic(filter)
+
+	slog.InfoContext(c, "generating participants export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	count, err := h.studyDBConn.GetParticipantCount(token.InstanceID, studyKey, filter)
 	if err != nil {
-		slog.Error("failed to get participants count", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get participants count", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participants count"})
 		return
 	}
@@ -2328,7 +4150,7 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 	)
 
 	if err != nil {
-		slog.Error("failed to create export task", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create export task", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export task"})
 		return
 	}
@@ -2336,12 +4158,12 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 	relativeFolderName := filepath.Join(token.InstanceID, "exports")
 	exportFolder := filepath.Join(h.filestorePath, relativeFolderName)
 	if err := os.MkdirAll(exportFolder, os.ModePerm); err != nil {
-		slog.Error("failed to create export folder", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create export folder", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export folder"})
 		return
 	}
 
-	go func() {
+	h.runInBackground("participants-export", func() {
 
 		// create file write
 		relativeFilepath := filepath.Join(relativeFolderName, "participants_"+exportTask.ID.Hex()+".json")
@@ -2365,6 +4187,7 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 
 		ctx := context.Background()
 		counter := 0
+		exportManifest := manifest.NewExportManifest(token.InstanceID, studyKey, "", "participants", "json", filter)
 
 		err = h.studyDBConn.FindAndExecuteOnParticipantsStates(
 			ctx,
@@ -2396,6 +4219,7 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 					return err
 				}
 
+				exportManifest.ObserveRow(p.EnteredAt)
 				counter += 1
 
 				err = dbService.UpdateTaskProgress(
@@ -2426,6 +4250,8 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 			return
 		}
 
+		writeExportManifest(exportManifest, exportFilePath)
+
 		err = h.studyDBConn.UpdateTaskCompleted(
 			token.InstanceID,
 			exportTask.ID.Hex(),
@@ -2438,7 +4264,7 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 			slog.Error("failed to update task status", slog.String("error", err.Error()))
 			return
 		}
-	}()
+	})
 
 	c.JSON(http.StatusOK, gin.H{"task": exportTask})
 }
@@ -2450,7 +4276,7 @@ func (h *HttpEndpoints) getReportsCount(c *gin.Context) {
 
 	filter, err := apihelpers.ParseFilterQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse filter", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse filter", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
@@ -2460,11 +4286,11 @@ func (h *HttpEndpoints) getReportsCount(c *gin.Context) {
 		filter["key"] = reportKey
 	}
 
-	slog.Info("getting reports count", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting reports count", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	count, err := h.studyDBConn.GetReportCountForQuery(token.InstanceID, studyKey, filter)
 	if err != nil {
-		slog.Error("failed to get reports count", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get reports count", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reports count"})
 		return
 	}
@@ -2479,7 +4305,7 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 
 	filter, err := apihelpers.ParseFilterQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse filter", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse filter", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
@@ -2489,11 +4315,11 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 		filter["key"] = reportKey
 	}
 
-	slog.Info("generating reports export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "generating reports export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	count, err := h.studyDBConn.GetReportCountForQuery(token.InstanceID, studyKey, filter)
 	if err != nil {
-		slog.Error("failed to get reports count", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get reports count", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reports count"})
 		return
 	}
@@ -2513,7 +4339,7 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 	)
 
 	if err != nil {
-		slog.Error("failed to create export task", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create export task", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export task"})
 		return
 	}
@@ -2521,12 +4347,12 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 	relativeFolderName := filepath.Join(token.InstanceID, "exports")
 	exportFolder := filepath.Join(h.filestorePath, relativeFolderName)
 	if err := os.MkdirAll(exportFolder, os.ModePerm); err != nil {
-		slog.Error("failed to create export folder", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create export folder", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export folder"})
 		return
 	}
 
-	go func() {
+	h.runInBackground("reports-export", func() {
 		// create file write
 		relativeFilepath := filepath.Join(relativeFolderName, "reports_"+exportTask.ID.Hex()+".json")
 		exportFilePath := filepath.Join(h.filestorePath, relativeFilepath)
@@ -2549,6 +4375,7 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 
 		ctx := context.Background()
 		counter := 0
+		exportManifest := manifest.NewExportManifest(token.InstanceID, studyKey, "", "reports", "json", filter)
 
 		err = h.studyDBConn.FindAndExecuteOnReports(
 			ctx,
@@ -2579,6 +4406,7 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 					return err
 				}
 
+				exportManifest.ObserveRow(r.Timestamp)
 				counter += 1
 
 				err = h.studyDBConn.UpdateTaskProgress(
@@ -2609,6 +4437,8 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 			return
 		}
 
+		writeExportManifest(exportManifest, exportFilePath)
+
 		err = h.studyDBConn.UpdateTaskCompleted(
 			token.InstanceID,
 			exportTask.ID.Hex(),
@@ -2621,7 +4451,7 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 			slog.Error("failed to update task status", slog.String("error", err.Error()))
 			return
 		}
-	}()
+	})
 
 	c.JSON(http.StatusOK, gin.H{"task": exportTask})
 }
@@ -2629,6 +4459,8 @@ func (h *HttpEndpoints) generateReportsExport(c *gin.Context) {
 type ConfidentialResponsesExportQuery struct {
 	ParticipantIDs []string `json:"participantIDs"`
 	KeyFilter      string   `json:"keyFilter"`
+	// Optional, the grant to use if the user has several active ones
+	GrantID string `json:"grantID"`
 }
 
 func parseSlots(respItem *studyTypes.ResponseItem, slotKey string) map[string]string {
@@ -2647,86 +4479,446 @@ func parseSlots(respItem *studyTypes.ResponseItem, slotKey string) map[string]st
 		return parsedResp
 	}
 
-	for _, subItem := range respItem.Items {
-		r := parseSlots(subItem, currentSlotKey)
-		for k, v := range r {
-			parsedResp[k] = v
-		}
+	for _, subItem := range respItem.Items {
+		r := parseSlots(subItem, currentSlotKey)
+		for k, v := range r {
+			parsedResp[k] = v
+		}
+	}
+	return parsedResp
+}
+
+func confidentialResponseExport(resp studyTypes.SurveyResponse, realPID string) map[string]string {
+	parsedResp := map[string]string{
+		"participantID": realPID,
+	}
+
+	for _, r := range resp.Responses {
+		slotKey := r.Key + "-"
+
+		slots := parseSlots(r.Response, slotKey)
+		for k, v := range slots {
+			parsedResp[k] = v
+		}
+	}
+
+	return parsedResp
+}
+
+func (h *HttpEndpoints) getConfidentialResponses(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var query ConfidentialResponsesExportQuery
+	if err := c.ShouldBindJSON(&query); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if len(query.ParticipantIDs) == 0 {
+		slog.ErrorContext(c, "participantIDs is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "participantIDs is required"})
+		return
+	}
+
+	slog.InfoContext(c, "getting confidential responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	grant := h.requireConfidentialAccessGrant(c, token, studyKey, query.GrantID)
+	if grant == nil {
+		return
+	}
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return
+	}
+
+	studySecretKey := study.SecretKey
+	idMappingMethod := study.Configs.IdMappingMethod
+	globalSecret := h.globalStudySecret
+
+	results := []map[string]string{}
+
+	for _, pID := range query.ParticipantIDs {
+		// confidentialID := studyTypes.GetConfidentialParticipantID(token.InstanceID, studyKey, pID)
+		confidentialID, err := studyutils.ProfileIDtoParticipantID(pID, globalSecret, studySecretKey, idMappingMethod)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get confidential participantID", slog.String("error", err.Error()))
+			continue
+		}
+		slog.InfoContext(c, "getting confidential responses for participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", pID))
+
+		// every read is logged before the data is accessed, no data is returned if this fails
+		err = h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+			StudyKey:      studyKey,
+			UserID:        token.Subject,
+			Action:        studyTypes.AUDIT_ACTION_CONFIDENTIAL_RESPONSES_READ,
+			ParticipantID: pID,
+			Purpose:       grant.Purpose,
+			Details: map[string]string{
+				"grantID":   grant.ID.Hex(),
+				"keyFilter": query.KeyFilter,
+			},
+		})
+		if err != nil {
+			slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log access"})
+			return
+		}
+
+		responses, err := h.studyDBConn.FindConfidentialResponses(token.InstanceID, studyKey, confidentialID, query.KeyFilter)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get confidential responses", slog.String("error", err.Error()))
+			continue
+		}
+
+		for _, r := range responses {
+			results = append(results, confidentialResponseExport(r, pID))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"responses": results})
+}
+
+type EncryptedConfidentialResponsesExportReq struct {
+	ParticipantIDs []string `json:"participantIDs"`
+	KeyFilter      string   `json:"keyFilter"`
+	// Optional, the grant to use if the user has several active ones
+	GrantID    string                `json:"grantID"`
+	Recipients encryption.Recipients `json:"recipients"`
+}
+
+// generateEncryptedConfidentialResponsesExport writes the confidential responses of the participants into a file
+// encrypted for the given age or GPG recipients. The plain responses are never written to disk.
+func (h *HttpEndpoints) generateEncryptedConfidentialResponsesExport(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req EncryptedConfidentialResponsesExportReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if len(req.ParticipantIDs) == 0 {
+		slog.ErrorContext(c, "participantIDs is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "participantIDs is required"})
+		return
+	}
+
+	recipientFingerprints, err := req.Recipients.Fingerprints()
+	if err != nil {
+		slog.ErrorContext(c, "invalid recipients", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "generating encrypted confidential responses export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("recipientType", req.Recipients.Type))
+
+	grant := h.requireConfidentialAccessGrant(c, token, studyKey, req.GrantID)
+	if grant == nil {
+		return
+	}
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return
+	}
+
+	exportTask, err := h.studyDBConn.CreateStudyTask(
+		token.InstanceID,
+		studyKey,
+		token.Subject,
+		len(req.ParticipantIDs),
+		req.Recipients.ContentType(),
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to create export task", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export task"})
+		return
+	}
+
+	confidentialIDs := map[string]string{}
+	for _, pID := range req.ParticipantIDs {
+		confidentialID, err := studyutils.ProfileIDtoParticipantID(pID, h.globalStudySecret, study.SecretKey, study.Configs.IdMappingMethod)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get confidential participantID", slog.String("error", err.Error()))
+			continue
+		}
+		confidentialIDs[pID] = confidentialID
+
+		// every export is logged before the data is accessed, no export is generated if this fails
+		err = h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+			StudyKey:      studyKey,
+			UserID:        token.Subject,
+			Action:        studyTypes.AUDIT_ACTION_CONFIDENTIAL_RESPONSES_EXPORTED,
+			ParticipantID: pID,
+			Purpose:       grant.Purpose,
+			Details: map[string]string{
+				"grantID":       grant.ID.Hex(),
+				"keyFilter":     req.KeyFilter,
+				"taskID":        exportTask.ID.Hex(),
+				"recipientType": req.Recipients.Type,
+				"recipients":    strings.Join(recipientFingerprints, ","),
+			},
+		})
+		if err != nil {
+			slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+			h.onExportTaskFailed(token.InstanceID, exportTask.ID.Hex(), "failed to log access")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log access"})
+			return
+		}
+	}
+
+	relativeFolderName := filepath.Join(token.InstanceID, "exports")
+	exportFolder := filepath.Join(h.filestorePath, relativeFolderName)
+	if err := os.MkdirAll(exportFolder, os.ModePerm); err != nil {
+		slog.ErrorContext(c, "failed to create export folder", slog.String("error", err.Error()))
+		h.onExportTaskFailed(token.InstanceID, exportTask.ID.Hex(), "failed to create export folder")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export folder"})
+		return
+	}
+
+	h.runInBackground("confidential-responses-export", func() {
+		plainFileName := "confidential-responses_" + exportTask.ID.Hex() + ".json"
+		relativeFilepath := filepath.Join(relativeFolderName, plainFileName+req.Recipients.FileExtension())
+		exportFilePath := filepath.Join(h.filestorePath, relativeFilepath)
+
+		counter, err := h.writeEncryptedConfidentialResponses(token.InstanceID, studyKey, exportTask.ID.Hex(), req, confidentialIDs, plainFileName, exportFilePath)
+		if err != nil {
+			slog.Error("failed to export confidential responses", slog.String("error", err.Error()))
+			if removeErr := os.Remove(exportFilePath); removeErr != nil && !os.IsNotExist(removeErr) {
+				slog.Error("failed to remove incomplete export file", slog.String("path", exportFilePath), slog.String("error", removeErr.Error()))
+			}
+			h.onExportTaskFailed(token.InstanceID, exportTask.ID.Hex(), "failed to export confidential responses")
+			return
+		}
+
+		err = h.studyDBConn.UpdateTaskCompleted(
+			token.InstanceID,
+			exportTask.ID.Hex(),
+			studyTypes.TASK_STATUS_COMPLETED,
+			counter,
+			"",
+			relativeFilepath,
+		)
+		if err != nil {
+			slog.Error("failed to update task status", slog.String("error", err.Error()))
+			return
+		}
+	})
+
+	c.JSON(http.StatusOK, gin.H{"task": exportTask})
+}
+
+// writeEncryptedConfidentialResponses streams the responses as JSON through the encryption into the export file,
+// returns the number of participants processed
+func (h *HttpEndpoints) writeEncryptedConfidentialResponses(
+	instanceID string,
+	studyKey string,
+	taskID string,
+	req EncryptedConfidentialResponsesExportReq,
+	confidentialIDs map[string]string,
+	plainFileName string,
+	exportFilePath string,
+) (int, error) {
+	file, err := os.Create(exportFilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	w, err := encryption.NewEncryptWriter(file, req.Recipients, plainFileName)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := io.WriteString(w, "{\"responses\": ["); err != nil {
+		return 0, err
+	}
+
+	counter := 0
+	written := 0
+	for _, pID := range req.ParticipantIDs {
+		confidentialID, ok := confidentialIDs[pID]
+		if !ok {
+			continue
+		}
+
+		responses, err := h.studyDBConn.FindConfidentialResponses(instanceID, studyKey, confidentialID, req.KeyFilter)
+		if err != nil {
+			return counter, err
+		}
+		for _, r := range responses {
+			rJSON, err := json.Marshal(confidentialResponseExport(r, pID))
+			if err != nil {
+				return counter, err
+			}
+			if written > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return counter, err
+				}
+			}
+			if _, err := w.Write(rJSON); err != nil {
+				return counter, err
+			}
+			written += 1
+		}
+
+		counter += 1
+		if err := h.studyDBConn.UpdateTaskProgress(instanceID, taskID, counter); err != nil {
+			slog.Error("failed to update task progress", slog.String("error", err.Error()))
+		}
+	}
+
+	if _, err := io.WriteString(w, "]}"); err != nil {
+		return counter, err
 	}
-	return parsedResp
+	if err := w.Close(); err != nil {
+		return counter, err
+	}
+	return counter, file.Sync()
 }
 
-func confidentialResponseExport(resp studyTypes.SurveyResponse, realPID string) map[string]string {
-	parsedResp := map[string]string{
-		"participantID": realPID,
+// requireConfidentialAccessGrant returns the active grant of the user, or writes the error response and returns nil
+func (h *HttpEndpoints) requireConfidentialAccessGrant(c *gin.Context, token *jwthandling.ManagementUserClaims, studyKey string, grantID string) *studyTypes.ConfidentialAccessGrant {
+	grants, err := h.studyDBConn.GetActiveConfidentialAccessGrants(token.InstanceID, studyKey, token.Subject)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get confidential access grants", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get confidential access grants"})
+		return nil
 	}
-
-	for _, r := range resp.Responses {
-		slotKey := r.Key + "-"
-
-		slots := parseSlots(r.Response, slotKey)
-		for k, v := range slots {
-			parsedResp[k] = v
-		}
+	grant := selectConfidentialAccessGrant(grants, grantID)
+	if grant == nil {
+		slog.WarnContext(c, "confidential responses requested without access grant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusForbidden, gin.H{"error": "no active access grant for confidential responses"})
+		return nil
 	}
+	return grant
+}
 
-	return parsedResp
+type ConfidentialResponsesPageQuery struct {
+	ParticipantID string `json:"participantID"`
+	KeyFilter     string `json:"keyFilter"`
+	KeyPrefix     string `json:"keyPrefix"`
+	// "arrivedAt" (default) or "submittedAt"
+	SortBy string `json:"sortBy"`
+	// "asc" (default) or "desc"
+	SortOrder string   `json:"sortOrder"`
+	Fields    []string `json:"fields"`
+	Cursor    string   `json:"cursor"`
+	Limit     int64    `json:"limit"`
+	GrantID   string   `json:"grantID"`
 }
 
-func (h *HttpEndpoints) getConfidentialResponses(c *gin.Context) {
+func (h *HttpEndpoints) queryConfidentialResponses(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
 	studyKey := c.Param("studyKey")
 
-	var query ConfidentialResponsesExportQuery
+	var query ConfidentialResponsesPageQuery
 	if err := c.ShouldBindJSON(&query); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	if len(query.ParticipantIDs) == 0 {
-		slog.Error("participantIDs is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "participantIDs is required"})
+	if query.ParticipantID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "participantID is required"})
+		return
+	}
+	if query.SortOrder != "" && query.SortOrder != "asc" && query.SortOrder != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sortOrder must be asc or desc"})
 		return
 	}
 
-	slog.Info("getting confidential responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "querying confidential responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", query.ParticipantID))
+
+	grant := h.requireConfidentialAccessGrant(c, token, studyKey, query.GrantID)
+	if grant == nil {
+		return
+	}
 
 	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to get study", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
 		return
 	}
 
-	studySecretKey := study.SecretKey
-	idMappingMethod := study.Configs.IdMappingMethod
-	globalSecret := h.globalStudySecret
-
-	results := []map[string]string{}
+	confidentialID, err := studyutils.ProfileIDtoParticipantID(query.ParticipantID, h.globalStudySecret, study.SecretKey, study.Configs.IdMappingMethod)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get confidential participantID", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get confidential participantID"})
+		return
+	}
+
+	err = h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+		StudyKey:      studyKey,
+		UserID:        token.Subject,
+		Action:        studyTypes.AUDIT_ACTION_CONFIDENTIAL_RESPONSES_READ,
+		ParticipantID: query.ParticipantID,
+		Purpose:       grant.Purpose,
+		Details: map[string]string{
+			"grantID":   grant.ID.Hex(),
+			"keyFilter": query.KeyFilter,
+			"keyPrefix": query.KeyPrefix,
+			"cursor":    query.Cursor,
+		},
+	})
+	if err != nil {
+		slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log access"})
+		return
+	}
 
-	for _, pID := range query.ParticipantIDs {
-		// confidentialID := studyTypes.GetConfidentialParticipantID(token.InstanceID, studyKey, pID)
-		confidentialID, err := studyutils.ProfileIDtoParticipantID(pID, globalSecret, studySecretKey, idMappingMethod)
-		if err != nil {
-			slog.Error("failed to get confidential participantID", slog.String("error", err.Error()))
-			continue
+	responses, nextCursor, err := h.studyDBConn.FindConfidentialResponsesPage(token.InstanceID, studyKey, studyDB.ConfidentialResponsesQuery{
+		ParticipantID: confidentialID,
+		Key:           query.KeyFilter,
+		KeyPrefix:     query.KeyPrefix,
+		SortBy:        query.SortBy,
+		SortDesc:      query.SortOrder == "desc",
+		Fields:        query.Fields,
+		Cursor:        query.Cursor,
+		Limit:         query.Limit,
+	})
+	if err != nil {
+		if errors.Is(err, studyDB.ErrInvalidConfidentialResponsesQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-		slog.Info("getting confidential responses for participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", pID))
+		slog.ErrorContext(c, "failed to query confidential responses", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query confidential responses"})
+		return
+	}
 
-		responses, err := h.studyDBConn.FindConfidentialResponses(token.InstanceID, studyKey, confidentialID, query.KeyFilter)
-		if err != nil {
-			slog.Error("failed to get confidential responses", slog.String("error", err.Error()))
-			continue
-		}
+	// the stored ID is the confidential one
+	for i := range responses {
+		responses[i].ParticipantID = query.ParticipantID
+	}
 
-		for _, r := range responses {
-			results = append(results, confidentialResponseExport(r, pID))
+	c.JSON(http.StatusOK, gin.H{
+		"responses":  responses,
+		"nextCursor": nextCursor,
+	})
+}
+
+// selectConfidentialAccessGrant returns the grant with grantID, or the first grant if grantID is empty
+func selectConfidentialAccessGrant(grants []studyTypes.ConfidentialAccessGrant, grantID string) *studyTypes.ConfidentialAccessGrant {
+	for i := range grants {
+		if grantID == "" || grants[i].ID.Hex() == grantID {
+			return &grants[i]
 		}
 	}
-
-	c.JSON(http.StatusOK, gin.H{"responses": results})
+	return nil
 }
 
 func (h *HttpEndpoints) getExportTaskStatus(c *gin.Context) {
@@ -2734,17 +4926,17 @@ func (h *HttpEndpoints) getExportTaskStatus(c *gin.Context) {
 
 	taskID := c.Param("taskID")
 
-	slog.Info("getting export task status", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
+	slog.InfoContext(c, "getting export task status", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
 
 	task, err := h.studyDBConn.GetTaskByID(token.InstanceID, taskID)
 	if err != nil {
-		slog.Error("failed to get export task status", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get export task status", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export task status"})
 		return
 	}
 
 	if task.CreatedBy != token.Subject && !token.IsAdmin {
-		slog.Warn("user is not allowed to get task status", slog.String("userID", token.Subject), slog.String("taskID", taskID))
+		slog.WarnContext(c, "user is not allowed to get task status", slog.String("userID", token.Subject), slog.String("taskID", taskID))
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
@@ -2757,23 +4949,23 @@ func (h *HttpEndpoints) getExportTaskResult(c *gin.Context) {
 
 	taskID := c.Param("taskID")
 
-	slog.Info("getting export task result", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
+	slog.InfoContext(c, "getting export task result", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
 
 	task, err := h.studyDBConn.GetTaskByID(token.InstanceID, taskID)
 	if err != nil {
-		slog.Error("failed to get export task result", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get export task result", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export task result"})
 		return
 	}
 
 	if task.CreatedBy != token.Subject && !token.IsAdmin {
-		slog.Warn("user is not allowed to get task result", slog.String("userID", token.Subject), slog.String("taskID", taskID))
+		slog.WarnContext(c, "user is not allowed to get task result", slog.String("userID", token.Subject), slog.String("taskID", taskID))
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
 
 	if task.Status != studyTypes.TASK_STATUS_COMPLETED {
-		slog.Error("task is not completed", slog.String("taskID", taskID), slog.String("status", task.Status))
+		slog.ErrorContext(c, "task is not completed", slog.String("taskID", taskID), slog.String("status", task.Status))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "task is not completed"})
 		return
 	}
@@ -2782,7 +4974,7 @@ func (h *HttpEndpoints) getExportTaskResult(c *gin.Context) {
 
 	// file exists?
 	if _, err := os.Stat(resultFilePath); os.IsNotExist(err) {
-		slog.Error("file does not exist", slog.String("path", resultFilePath))
+		slog.ErrorContext(c, "file does not exist", slog.String("path", resultFilePath))
 		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
 		return
 	}
@@ -2794,12 +4986,116 @@ func (h *HttpEndpoints) getExportTaskResult(c *gin.Context) {
 	c.File(resultFilePath)
 }
 
+// getConfidentialResponsesExportResult serves the export only while its creator still has an active access grant,
+// every download is audited
+func (h *HttpEndpoints) getConfidentialResponsesExportResult(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	taskID := c.Param("taskID")
+
+	slog.InfoContext(c, "getting confidential responses export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("taskID", taskID))
+
+	task, err := h.studyDBConn.GetTaskByID(token.InstanceID, taskID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get export task result", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export task result"})
+		return
+	}
+
+	// the export can only be downloaded by its creator, in the study it was created for
+	if task.CreatedBy != token.Subject || task.StudyKey != studyKey {
+		slog.WarnContext(c, "user is not allowed to get confidential responses export", slog.String("userID", token.Subject), slog.String("taskID", taskID))
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	if task.Status != studyTypes.TASK_STATUS_COMPLETED {
+		slog.ErrorContext(c, "task is not completed", slog.String("taskID", taskID), slog.String("status", task.Status))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task is not completed"})
+		return
+	}
+
+	grant := h.requireConfidentialAccessGrant(c, token, studyKey, c.Query("grantID"))
+	if grant == nil {
+		return
+	}
+
+	resultFilePath := filepath.Join(h.filestorePath, task.ResultFile)
+	if _, err := os.Stat(resultFilePath); os.IsNotExist(err) {
+		slog.ErrorContext(c, "file does not exist", slog.String("path", resultFilePath))
+		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
+		return
+	}
+
+	// the file is only served if the download is audited
+	err = h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+		StudyKey: studyKey,
+		UserID:   token.Subject,
+		Action:   studyTypes.AUDIT_ACTION_CONFIDENTIAL_RESPONSES_DOWNLOADED,
+		Purpose:  grant.Purpose,
+		Details: map[string]string{
+			"grantID": grant.ID.Hex(),
+			"taskID":  taskID,
+		},
+	})
+	if err != nil {
+		slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log access"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(task.ResultFile))
+	c.Header("Content-Type", task.FileType)
+	c.File(resultFilePath)
+}
+
+func (h *HttpEndpoints) getExportTaskManifest(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	taskID := c.Param("taskID")
+
+	slog.InfoContext(c, "getting export task manifest", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
+
+	task, err := h.studyDBConn.GetTaskByID(token.InstanceID, taskID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get export task", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get export task"})
+		return
+	}
+
+	if task.CreatedBy != token.Subject && !token.IsAdmin {
+		slog.WarnContext(c, "user is not allowed to get task manifest", slog.String("userID", token.Subject), slog.String("taskID", taskID))
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	if task.Status != studyTypes.TASK_STATUS_COMPLETED || task.ResultFile == "" {
+		slog.ErrorContext(c, "task is not completed", slog.String("taskID", taskID), slog.String("status", task.Status))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task is not completed"})
+		return
+	}
+
+	manifestFilePath := manifest.ManifestPathForFile(filepath.Join(h.filestorePath, task.ResultFile))
+
+	// file exists?
+	if _, err := os.Stat(manifestFilePath); os.IsNotExist(err) {
+		slog.ErrorContext(c, "file does not exist", slog.String("path", manifestFilePath))
+		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filepath.Base(manifestFilePath))
+	c.Header("Content-Type", studyTypes.TASK_FILE_TYPE_JSON)
+	c.File(manifestFilePath)
+}
+
 func (h *HttpEndpoints) getDailyExports(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting daily exports", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting daily exports", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	if h.dailyFileExportPath == "" {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "daily file export path not set"})
@@ -2833,7 +5129,7 @@ func (h *HttpEndpoints) getDailyExports(c *gin.Context) {
 		return nil
 	})
 	if err != nil {
-		slog.Error("unexpected error when reading daily file exports", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "unexpected error when reading daily file exports", slog.String("error", err.Error()))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"dailyExports": dailyExports})
@@ -2852,18 +5148,18 @@ func (h *HttpEndpoints) getDailyExport(c *gin.Context) {
 
 	decoded, err := base64.URLEncoding.DecodeString(exportID)
 	if err != nil {
-		slog.Error("error decoding exportID", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error decoding exportID", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error decoding exportID"})
 	}
 	filename := string(decoded)
 
-	slog.Info("downloading daily export file", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("fileName", filename))
+	slog.InfoContext(c, "downloading daily export file", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("fileName", filename))
 
 	resultFilePath := filepath.Join(h.dailyFileExportPath, token.InstanceID, studyKey, filename)
 
 	// file exists?
 	if _, err := os.Stat(resultFilePath); os.IsNotExist(err) {
-		slog.Error("file does not exist", slog.String("path", resultFilePath))
+		slog.ErrorContext(c, "file does not exist", slog.String("path", resultFilePath))
 		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
 		return
 	}
@@ -2888,19 +5184,19 @@ func (h *HttpEndpoints) getStudyResponses(c *gin.Context) {
 
 	query, err := apihelpers.ParseResponseExportQueryFromCtx(c)
 	if err != nil || query == nil {
-		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse query", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	surveyKey := query.SurveyKey
 	if surveyKey == "" {
-		slog.Error("surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "surveyKey is required"})
 		return
 	}
 
-	slog.Info("getting study responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	slog.InfoContext(c, "getting study responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
 	rawResponses, paginationInfo, err := h.studyDBConn.GetResponses(
 		token.InstanceID,
@@ -2911,7 +5207,7 @@ func (h *HttpEndpoints) getStudyResponses(c *gin.Context) {
 		query.PaginationInfos.Limit,
 	)
 	if err != nil {
-		slog.Error("failed to get study responses", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study responses", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study responses"})
 		return
 	}
@@ -2928,7 +5224,7 @@ func (h *HttpEndpoints) getStudyResponses(c *gin.Context) {
 		},
 	)
 	if err != nil {
-		slog.Error("failed to get survey versions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 		return
 	}
@@ -2942,7 +5238,7 @@ func (h *HttpEndpoints) getStudyResponses(c *gin.Context) {
 		query.ExtraCtxCols,
 	)
 	if err != nil {
-		slog.Error("failed to create response parser", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create response parser", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response parser"})
 		return
 	}
@@ -2952,12 +5248,12 @@ func (h *HttpEndpoints) getStudyResponses(c *gin.Context) {
 	for i, rawResp := range rawResponses {
 		resp, err := respParser.ParseResponse(&rawResp)
 		if err != nil {
-			slog.Error("failed to parse response", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to parse response", slog.String("error", err.Error()))
 			continue
 		}
 		output, err := respParser.ResponseToFlatObj(resp)
 		if err != nil {
-			slog.Error("failed to convert response to flat object", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to convert response to flat object", slog.String("error", err.Error()))
 			continue
 		}
 		responses[i] = output
@@ -2975,18 +5271,18 @@ func (h *HttpEndpoints) getStudyResponseById(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	responseID := c.Param("responseID")
 
-	slog.Info("getting study response by ID", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("responseID", responseID))
+	slog.InfoContext(c, "getting study response by ID", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("responseID", responseID))
 
 	query, err := apihelpers.ParseResponseExportQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse response export query")
+		slog.ErrorContext(c, "failed to parse response export query")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	rawResponse, err := h.studyDBConn.GetResponseByID(token.InstanceID, studyKey, responseID)
 	if err != nil {
-		slog.Error("failed to get study response by ID", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study response by ID", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study response by ID"})
 		return
 	}
@@ -3003,7 +5299,7 @@ func (h *HttpEndpoints) getStudyResponseById(c *gin.Context) {
 		},
 	)
 	if err != nil {
-		slog.Error("failed to get survey versions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 		return
 	}
@@ -3017,21 +5313,21 @@ func (h *HttpEndpoints) getStudyResponseById(c *gin.Context) {
 		nil, // TODO: add extra context columns optionally
 	)
 	if err != nil {
-		slog.Error("failed to create response parser", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create response parser", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response parser"})
 		return
 	}
 
 	resp, err := respParser.ParseResponse(&rawResponse)
 	if err != nil {
-		slog.Error("failed to parse response", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse response", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse response"})
 		return
 	}
 
 	output, err := respParser.ResponseToFlatObj(resp)
 	if err != nil {
-		slog.Error("failed to convert response to flat object", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to convert response to flat object", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to convert response to flat object"})
 		return
 	}
@@ -3046,21 +5342,21 @@ func (h *HttpEndpoints) deleteStudyResponses(c *gin.Context) {
 
 	query, err := apihelpers.ParseResponseExportQueryFromCtx(c)
 	if err != nil {
-		slog.Error("failed to parse response export query")
+		slog.ErrorContext(c, "failed to parse response export query")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	controlField := c.DefaultQuery("controlField", "")
 	if controlField != studyKey {
-		slog.Error("controlField does not match studyKey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "controlField does not match studyKey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to delete study responses"})
 		return
 	}
 
 	surveyKey := query.SurveyKey
 	if surveyKey == "" {
-		slog.Error("surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "surveyKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "surveyKey is required"})
 		return
 	}
@@ -3068,11 +5364,11 @@ func (h *HttpEndpoints) deleteStudyResponses(c *gin.Context) {
 	filter := query.PaginationInfos.Filter
 	filter["key"] = surveyKey // ensure surveyKey is included in the filter
 
-	slog.Info("deleting study responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	slog.InfoContext(c, "deleting study responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 
 	err = h.studyDBConn.DeleteResponses(token.InstanceID, studyKey, filter)
 	if err != nil {
-		slog.Error("failed to delete study responses", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete study responses", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete study responses"})
 		return
 	}
@@ -3087,16 +5383,16 @@ func (h *HttpEndpoints) deleteStudyResponse(c *gin.Context) {
 	responseID := c.Param("responseID")
 
 	if responseID == "" {
-		slog.Error("responseID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "responseID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "responseID is required"})
 		return
 	}
 
-	slog.Info("deleting study response", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("responseID", responseID))
+	slog.InfoContext(c, "deleting study response", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("responseID", responseID))
 
 	err := h.studyDBConn.DeleteResponseByID(token.InstanceID, studyKey, responseID)
 	if err != nil {
-		slog.Error("failed to delete study response", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete study response", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete study response"})
 		return
 	}
@@ -3108,11 +5404,11 @@ func (h *HttpEndpoints) getStudyParticipants(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting study participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting study participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
 	if err != nil || query == nil {
-		slog.Error("failed to parse paginated query", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse paginated query", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
@@ -3126,7 +5422,62 @@ func (h *HttpEndpoints) getStudyParticipants(c *gin.Context) {
 		query.Limit,
 	)
 	if err != nil {
-		slog.Error("failed to get study participants", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study participants", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study participants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"participants": participants,
+		"pagination":   paginationInfo,
+	})
+}
+
+func (h *HttpEndpoints) queryStudyParticipantsByFlags(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req struct {
+		Predicates []studyutils.FlagPredicate `json:"predicates"`
+		Page       int64                      `json:"page"`
+		Limit      int64                      `json:"limit"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "querying study participants by flags", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	flagTypes, err := h.studyDBConn.GetParticipantFlagTypes(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get participant flag types", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participant flag types"})
+		return
+	}
+
+	filter, err := studyutils.BuildFlagQueryFilter(req.Predicates, flagTypes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+
+	participants, paginationInfo, err := h.studyDBConn.GetParticipants(
+		token.InstanceID,
+		studyKey,
+		filter,
+		bson.M{"enteredAt": 1},
+		page,
+		req.Limit,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study participants", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study participants"})
 		return
 	}
@@ -3143,11 +5494,11 @@ func (h *HttpEndpoints) getStudyParticipant(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	participantID := c.Param("participantID")
 
-	slog.Info("getting study participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+	slog.InfoContext(c, "getting study participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
 
 	participant, err := h.studyDBConn.GetParticipantByID(token.InstanceID, studyKey, participantID)
 	if err != nil {
-		slog.Error("failed to get study participant", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study participant", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study participant"})
 		return
 	}
@@ -3155,15 +5506,135 @@ func (h *HttpEndpoints) getStudyParticipant(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"participant": participant})
 }
 
+func (h *HttpEndpoints) deleteStudyParticipantData(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	participantID := c.Param("participantID")
+
+	slog.InfoContext(c, "deleting all data of study participant", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+
+	result, err := studyService.DeleteAllParticipantData(token.InstanceID, studyKey, participantID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to delete participant data", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete participant data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "participant data deleted", "deleted": result})
+}
+
+func (h *HttpEndpoints) getStudyExternalServiceTasks(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting study external service tasks", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil || query == nil {
+		slog.ErrorContext(c, "failed to parse paginated query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	tasks, paginationInfo, err := h.studyDBConn.GetExternalServiceTasks(
+		token.InstanceID,
+		studyKey,
+		c.DefaultQuery("participantID", ""),
+		c.DefaultQuery("status", ""),
+		query.Page,
+		query.Limit,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get external service tasks", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get external service tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":      tasks,
+		"pagination": paginationInfo,
+	})
+}
+
+func (h *HttpEndpoints) getStudyRandomizationAllocations(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting study randomization allocations", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil || query == nil {
+		slog.ErrorContext(c, "failed to parse paginated query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	allocations, paginationInfo, err := h.studyDBConn.GetRandomizationAllocations(
+		token.InstanceID,
+		studyKey,
+		c.DefaultQuery("schemeKey", ""),
+		query.Page,
+		query.Limit,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get randomization allocations", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get randomization allocations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"allocations": allocations,
+		"pagination":  paginationInfo,
+	})
+}
+
+func (h *HttpEndpoints) getStudyScheduledEvents(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting study scheduled events", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil || query == nil {
+		slog.ErrorContext(c, "failed to parse paginated query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	events, paginationInfo, err := h.studyDBConn.GetScheduledEvents(
+		token.InstanceID,
+		studyKey,
+		c.DefaultQuery("participantID", ""),
+		c.DefaultQuery("status", ""),
+		query.Page,
+		query.Limit,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get scheduled events", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scheduled events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":     events,
+		"pagination": paginationInfo,
+	})
+}
+
 func (h *HttpEndpoints) getStudyReports(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting study reports", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting study reports", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
 	if err != nil || query == nil {
-		slog.Error("failed to parse paginated query", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse paginated query", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
@@ -3181,7 +5652,7 @@ func (h *HttpEndpoints) getStudyReports(c *gin.Context) {
 		query.Limit,
 	)
 	if err != nil {
-		slog.Error("failed to get study reports", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study reports", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study reports"})
 		return
 	}
@@ -3198,11 +5669,11 @@ func (h *HttpEndpoints) getStudyReport(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	reportID := c.Param("reportID")
 
-	slog.Info("getting study report", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("reportID", reportID))
+	slog.InfoContext(c, "getting study report", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("reportID", reportID))
 
 	report, err := h.studyDBConn.GetReportByID(token.InstanceID, studyKey, reportID)
 	if err != nil {
-		slog.Error("failed to get study report", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study report", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study report"})
 		return
 	}
@@ -3217,12 +5688,12 @@ func (h *HttpEndpoints) getStudyFiles(c *gin.Context) {
 
 	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
 	if err != nil || query == nil {
-		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse query", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("getting study files", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting study files", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	files, paginationInfo, err := h.studyDBConn.GetParticipantFileInfos(
 		token.InstanceID,
@@ -3232,7 +5703,7 @@ func (h *HttpEndpoints) getStudyFiles(c *gin.Context) {
 		query.Limit,
 	)
 	if err != nil {
-		slog.Error("failed to get study files", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study files", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study files"})
 		return
 	}
@@ -3250,11 +5721,11 @@ func (h *HttpEndpoints) getStudyFile(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	fileID := c.Param("fileID")
 
-	slog.Info("getting study file", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
+	slog.InfoContext(c, "getting study file", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
 
 	fileInfo, err := h.studyDBConn.GetParticipantFileInfoByID(token.InstanceID, studyKey, fileID)
 	if err != nil {
-		slog.Error("failed to get study file info", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study file info", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study file info"})
 		return
 	}
@@ -3263,7 +5734,7 @@ func (h *HttpEndpoints) getStudyFile(c *gin.Context) {
 
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		slog.Error("file does not exist", slog.String("path", filePath))
+		slog.ErrorContext(c, "file does not exist", slog.String("path", filePath))
 		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
 		return
 	}
@@ -3280,11 +5751,11 @@ func (h *HttpEndpoints) deleteStudyFile(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 	fileID := c.Param("fileID")
 
-	slog.Info("deleting study file", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
+	slog.InfoContext(c, "deleting study file", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
 
 	fileInfo, err := h.studyDBConn.GetParticipantFileInfoByID(token.InstanceID, studyKey, fileID)
 	if err != nil {
-		slog.Error("failed to get study file info", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study file info", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study file info"})
 		return
 	}
@@ -3292,19 +5763,19 @@ func (h *HttpEndpoints) deleteStudyFile(c *gin.Context) {
 	// remove file from file system
 	err = os.Remove(filepath.Join(h.filestorePath, fileInfo.Path))
 	if err != nil {
-		slog.Error("failed to delete study file", slog.String("error", err.Error()), slog.String("path", fileInfo.Path))
+		slog.ErrorContext(c, "failed to delete study file", slog.String("error", err.Error()), slog.String("path", fileInfo.Path))
 	}
 	if fileInfo.PreviewPath != "" {
 		err := os.Remove(filepath.Join(h.filestorePath, fileInfo.PreviewPath))
 		if err != nil {
-			slog.Error("failed to delete study file preview", slog.String("error", err.Error()), slog.String("path", fileInfo.PreviewPath))
+			slog.ErrorContext(c, "failed to delete study file preview", slog.String("error", err.Error()), slog.String("path", fileInfo.PreviewPath))
 		}
 	}
 
 	// delete file info from database
 	err = h.studyDBConn.DeleteParticipantFileInfoByID(token.InstanceID, studyKey, fileID)
 	if err != nil {
-		slog.Error("failed to delete study file", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete study file", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete study file"})
 		return
 	}
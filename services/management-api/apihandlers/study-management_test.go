@@ -0,0 +1,115 @@
+package apihandlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// newTestStudyDB connects to the MongoDB of TEST_MONGODB_URI with a fresh database prefix, the test is skipped if not set
+func newTestStudyDB(t *testing.T, instanceID string) *studyDB.StudyDBService {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	dbService, err := studyDB.NewStudyDBService(db.DBConfig{
+		URI:          uri,
+		DBNamePrefix: fmt.Sprintf("apihandlers_test_%d_", time.Now().UnixNano()),
+		Timeout:      10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = dbService.DBClient.Database(dbService.DBNamePrefix + instanceID + "_studyDB").Drop(context.Background())
+		_ = dbService.DBClient.Disconnect(context.Background())
+	})
+	return dbService
+}
+
+// serveWithToken calls the handler as the management user of the token
+func serveWithToken(handler gin.HandlerFunc, token *jwthandling.ManagementUserClaims, method string, route string, target string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		c.Set("validatedToken", token)
+		handler(c)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestConfidentialResponsesExportResultAfterRevocation(t *testing.T) {
+	instanceID := "test"
+	studyKey := "study1"
+	dbService := newTestStudyDB(t, instanceID)
+	h := &HttpEndpoints{studyDBConn: dbService, filestorePath: t.TempDir()}
+
+	token := &jwthandling.ManagementUserClaims{InstanceID: instanceID}
+	token.Subject = "researcher"
+
+	grant, err := dbService.AddConfidentialAccessGrant(instanceID, studyTypes.ConfidentialAccessGrant{
+		StudyKey:  studyKey,
+		UserID:    token.Subject,
+		Purpose:   "follow-up",
+		ExpiresAt: time.Now().Add(time.Hour),
+		GrantedBy: "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := dbService.CreateStudyTask(instanceID, studyKey, token.Subject, 1, studyTypes.TASK_FILE_TYPE_JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultFile := "confidential-responses_" + task.ID.Hex() + ".json.age"
+	if err := os.WriteFile(filepath.Join(h.filestorePath, resultFile), []byte("encrypted"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbService.UpdateTaskCompleted(instanceID, task.ID.Hex(), studyTypes.TASK_STATUS_COMPLETED, 1, "", resultFile); err != nil {
+		t.Fatal(err)
+	}
+
+	route := "/studies/:studyKey/confidential-responses/task/:taskID/result"
+	target := "/studies/" + studyKey + "/confidential-responses/task/" + task.ID.Hex() + "/result"
+
+	w := serveWithToken(h.getConfidentialResponsesExportResult, token, http.MethodGet, route, target)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected download with active grant, got %d: %s", w.Code, w.Body.String())
+	}
+	entries, _, err := dbService.GetAuditLogEntries(instanceID, studyKey, token.Subject, studyTypes.AUDIT_ACTION_CONFIDENTIAL_RESPONSES_DOWNLOADED, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Details["taskID"] != task.ID.Hex() {
+		t.Errorf("download not audited: %+v", entries)
+	}
+
+	otherStudyTarget := "/studies/other/confidential-responses/task/" + task.ID.Hex() + "/result"
+	if w := serveWithToken(h.getConfidentialResponsesExportResult, token, http.MethodGet, route, otherStudyTarget); w.Code != http.StatusForbidden {
+		t.Errorf("expected export of another study to be forbidden, got %d", w.Code)
+	}
+
+	if err := dbService.RevokeConfidentialAccessGrant(instanceID, studyKey, grant.ID.Hex(), "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if w := serveWithToken(h.getConfidentialResponsesExportResult, token, http.MethodGet, route, target); w.Code != http.StatusForbidden {
+		t.Errorf("expected download after revocation to be forbidden, got %d", w.Code)
+	}
+}
@@ -0,0 +1,379 @@
+package apihandlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxParticipantImportRows        = 1000
+	defaultParticipantInvitationTTL = 7 * 24 * time.Hour
+
+	participantImportColumnEmail      = "email"
+	participantImportColumnLanguage   = "language"
+	participantImportFlagColumnPrefix = "flag."
+)
+
+const (
+	PARTICIPANT_IMPORT_ROW_VALID    = "valid"
+	PARTICIPANT_IMPORT_ROW_INVALID  = "invalid"
+	PARTICIPANT_IMPORT_ROW_EXISTS   = "exists"
+	PARTICIPANT_IMPORT_ROW_IMPORTED = "imported"
+	PARTICIPANT_IMPORT_ROW_FAILED   = "failed"
+)
+
+var errParticipantImportAccountExists = errors.New("account already exists")
+
+// SetParticipantInvitationTTL sets the validity of the invitation links sent to imported participants, 7 days if 0
+func (h *HttpEndpoints) SetParticipantInvitationTTL(ttl time.Duration) {
+	h.participantInvitationTTL = ttl
+}
+
+type ParticipantImportRequest struct {
+	// CSV with a header row, an "email" column, an optional "language" column and "flag.<key>" columns
+	CSV string `json:"csv"`
+	// flags set for all imported participants, flag columns of the CSV take precedence
+	Flags map[string]string `json:"flags"`
+	// language of rows without language column or value
+	DefaultLanguage string `json:"defaultLanguage"`
+	// only validate the rows, no accounts are created and no emails sent
+	DryRun bool `json:"dryRun"`
+}
+
+type ParticipantImportRow struct {
+	// line number in the CSV file, the header is row 1
+	Row      int               `json:"row"`
+	Email    string            `json:"email"`
+	Language string            `json:"language,omitempty"`
+	Flags    map[string]string `json:"flags,omitempty"`
+	Status   string            `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	UserID   string            `json:"userId,omitempty"`
+}
+
+type participantImportColumns struct {
+	email    int
+	language int
+	flags    map[int]string
+}
+
+func (h *HttpEndpoints) importStudyParticipants(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req ParticipantImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.DefaultLanguage == "" {
+		req.DefaultLanguage = "en"
+	}
+	if !umUtils.CheckLanguageCode(req.DefaultLanguage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid default language"})
+		return
+	}
+	for key := range req.Flags {
+		if !isValidImportFlagKey(key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid flag key: " + key})
+			return
+		}
+	}
+
+	rows, err := parseParticipantImportCSV(req.CSV, req.Flags, req.DefaultLanguage)
+	if err != nil {
+		slog.ErrorContext(c, "invalid participant import file", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "importing study participants", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("userID", token.Subject), slog.Int("rows", len(rows)), slog.Bool("dryRun", req.DryRun))
+
+	// problems preventing the import as a whole, returned with the rows in a dry run
+	problems := []string{}
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+		return
+	}
+	if study.Status != studyTypes.STUDY_STATUS_ACTIVE {
+		problems = append(problems, "study is not active")
+	}
+	if _, err := h.messagingDBConn.GetGlobalEmailTemplateByMessageType(token.InstanceID, emailTypes.EMAIL_TYPE_INVITATION); err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			slog.ErrorContext(c, "failed to get invitation template", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get invitation template"})
+			return
+		}
+		problems = append(problems, "no global email template for message type "+emailTypes.EMAIL_TYPE_INVITATION)
+	}
+
+	for i := range rows {
+		if rows[i].Status != PARTICIPANT_IMPORT_ROW_VALID {
+			continue
+		}
+		if _, err := h.participantUserDB.GetUserByAccountID(token.InstanceID, rows[i].Email); err == nil {
+			rows[i].Status = PARTICIPANT_IMPORT_ROW_EXISTS
+			rows[i].Error = errParticipantImportAccountExists.Error()
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			slog.ErrorContext(c, "failed to check existing account", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check existing accounts"})
+			return
+		}
+	}
+
+	if req.DryRun {
+		c.JSON(http.StatusOK, gin.H{"rows": rows, "summary": summarizeParticipantImport(rows), "problems": problems})
+		return
+	}
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(problems, ", ")})
+		return
+	}
+
+	ttl := h.participantInvitationTTL
+	if ttl <= 0 {
+		ttl = defaultParticipantInvitationTTL
+	}
+	ctx := requestid.Detach(c.Request.Context())
+	for i := range rows {
+		if rows[i].Status != PARTICIPANT_IMPORT_ROW_VALID {
+			continue
+		}
+		userID, err := h.inviteImportedParticipant(ctx, token.InstanceID, studyKey, rows[i], ttl)
+		if errors.Is(err, errParticipantImportAccountExists) {
+			// created since the check above, e.g. by a concurrent signup
+			rows[i].Status = PARTICIPANT_IMPORT_ROW_EXISTS
+			rows[i].Error = err.Error()
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(c, "failed to import participant", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.Int("row", rows[i].Row), slog.String("error", err.Error()))
+			rows[i].Status = PARTICIPANT_IMPORT_ROW_FAILED
+			rows[i].Error = err.Error()
+			continue
+		}
+		rows[i].Status = PARTICIPANT_IMPORT_ROW_IMPORTED
+		rows[i].UserID = userID
+	}
+
+	summary := summarizeParticipantImport(rows)
+	slog.InfoContext(c, "study participants imported", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("userID", token.Subject), slog.Int("imported", summary[PARTICIPANT_IMPORT_ROW_IMPORTED]), slog.Int("failed", summary[PARTICIPANT_IMPORT_ROW_FAILED]))
+	c.JSON(http.StatusOK, gin.H{"rows": rows, "summary": summary})
+}
+
+// inviteImportedParticipant creates an account without password, enters its main profile into the study and sends
+// the invitation link, with which the participant sets the password. The account is removed if it cannot be entered
+// into the study.
+func (h *HttpEndpoints) inviteImportedParticipant(ctx context.Context, instanceID string, studyKey string, row ParticipantImportRow, ttl time.Duration) (string, error) {
+	newUser := umUtils.InitNewEmailUser(row.Email, "", row.Language)
+	newUser.Account.SetupPendingSince = time.Now().Unix()
+	newUser.Timestamps.LastLogin = 0
+
+	userID, err := h.participantUserDB.AddUser(instanceID, newUser)
+	if errors.Is(err, userDB.ErrUserExists) || mongo.IsDuplicateKeyError(err) {
+		return "", errParticipantImportAccountExists
+	}
+	if err != nil {
+		return "", errors.New("failed to create account")
+	}
+
+	mainProfileID, _ := umUtils.GetMainAndOtherProfiles(newUser)
+	if err := studyService.OnImportParticipant(ctx, instanceID, studyKey, mainProfileID, row.Flags); err != nil {
+		if err := h.participantUserDB.DeleteUser(instanceID, userID); err != nil {
+			slog.Error("failed to remove account of failed import", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("error", err.Error()))
+		}
+		return "", errors.New("failed to enter study")
+	}
+
+	h.runInBackground("webhook", func() {
+		webhooks.Publish(instanceID, webhooks.EVENT_PARTICIPANT_SIGNUP, map[string]any{
+			"userId":      userID,
+			"accountType": newUser.Account.Type,
+			"invited":     true,
+		})
+	})
+	domainevents.Publish(instanceID, domainevents.EVENT_USER_CREATED, map[string]any{
+		"userId":      userID,
+		"accountType": newUser.Account.Type,
+		"invited":     true,
+	})
+
+	tempToken, err := h.globalInfosDBConn.AddTempToken(userTypes.TempToken{
+		UserID:     userID,
+		InstanceID: instanceID,
+		Purpose:    userTypes.TOKEN_PURPOSE_INVITATION,
+		Info: map[string]string{
+			"type":  userTypes.ACCOUNT_TYPE_EMAIL,
+			"email": row.Email,
+		},
+		Expiration: umUtils.GetExpirationTime(ttl),
+	})
+	if err != nil {
+		return userID, errors.New("account created, but failed to create invitation token")
+	}
+
+	if err := emailsending.QueueEmailByTemplate(
+		instanceID,
+		[]string{row.Email},
+		emailTypes.EMAIL_TYPE_INVITATION,
+		"",
+		row.Language,
+		map[string]string{"token": tempToken},
+		false,
+	); err != nil {
+		return userID, errors.New("account created, but failed to queue invitation email")
+	}
+	return userID, nil
+}
+
+// parseParticipantImportCSV reads the rows of the import file and validates them, rows are only checked against
+// each other, not against existing accounts
+func parseParticipantImportCSV(content string, flags map[string]string, defaultLanguage string) ([]ParticipantImportRow, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("file is empty")
+		}
+		return nil, err
+	}
+	columns, err := parseParticipantImportHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []ParticipantImportRow{}
+	seen := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if isEmptyCSVRecord(record) {
+			continue
+		}
+		if len(rows) >= maxParticipantImportRows {
+			return nil, errors.New("file has too many rows")
+		}
+
+		row := ParticipantImportRow{
+			Row:      line,
+			Language: defaultLanguage,
+			Flags:    map[string]string{},
+			Status:   PARTICIPANT_IMPORT_ROW_VALID,
+		}
+		for k, v := range flags {
+			row.Flags[k] = v
+		}
+		if columns.email < len(record) {
+			row.Email = umUtils.SanitizeEmail(record[columns.email])
+		}
+		if columns.language >= 0 && columns.language < len(record) && strings.TrimSpace(record[columns.language]) != "" {
+			row.Language = strings.TrimSpace(record[columns.language])
+		}
+		for index, key := range columns.flags {
+			if index < len(record) && strings.TrimSpace(record[index]) != "" {
+				row.Flags[key] = strings.TrimSpace(record[index])
+			}
+		}
+
+		switch {
+		case !umUtils.CheckEmailFormat(row.Email):
+			row.Status = PARTICIPANT_IMPORT_ROW_INVALID
+			row.Error = "invalid email"
+		case !umUtils.CheckLanguageCode(row.Language):
+			row.Status = PARTICIPANT_IMPORT_ROW_INVALID
+			row.Error = "invalid language"
+		case seen[row.Email] > 0:
+			row.Status = PARTICIPANT_IMPORT_ROW_INVALID
+			row.Error = "duplicate of row " + strconv.Itoa(seen[row.Email])
+		default:
+			seen[row.Email] = row.Row
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file has no rows")
+	}
+	return rows, nil
+}
+
+func parseParticipantImportHeader(header []string) (participantImportColumns, error) {
+	columns := participantImportColumns{email: -1, language: -1, flags: map[int]string{}}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		switch {
+		case strings.EqualFold(name, participantImportColumnEmail):
+			columns.email = i
+		case strings.EqualFold(name, participantImportColumnLanguage):
+			columns.language = i
+		case strings.HasPrefix(name, participantImportFlagColumnPrefix):
+			key := strings.TrimPrefix(name, participantImportFlagColumnPrefix)
+			if !isValidImportFlagKey(key) {
+				return columns, errors.New("invalid flag column: " + name)
+			}
+			columns.flags[i] = key
+		default:
+			return columns, errors.New("unknown column: " + name)
+		}
+	}
+	if columns.email < 0 {
+		return columns, errors.New("missing email column")
+	}
+	return columns, nil
+}
+
+func isValidImportFlagKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, ".$")
+}
+
+func isEmptyCSVRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func summarizeParticipantImport(rows []ParticipantImportRow) map[string]int {
+	summary := map[string]int{"total": len(rows)}
+	for _, row := range rows {
+		summary[row.Status]++
+	}
+	return summary
+}
@@ -0,0 +1,157 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+)
+
+func TestParseParticipantImportCSV(t *testing.T) {
+	manyRows := func(n int) string {
+		lines := []string{"email"}
+		for i := 0; i < n; i++ {
+			lines = append(lines, fmt.Sprintf("user%d@example.com", i))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		wantErr    string
+		wantStatus []string
+		check      func(t *testing.T, rows []ParticipantImportRow)
+	}{
+		{
+			name:       "header with BOM",
+			content:    "\ufeffemail,language\nuser@example.com,de\n",
+			wantStatus: []string{PARTICIPANT_IMPORT_ROW_VALID},
+			check: func(t *testing.T, rows []ParticipantImportRow) {
+				if rows[0].Email != "user@example.com" || rows[0].Language != "de" || rows[0].Row != 2 {
+					t.Errorf("unexpected row: %+v", rows[0])
+				}
+			},
+		},
+		{
+			name:       "duplicates",
+			content:    "email\nuser@example.com\n\nUser@Example.com \nother@example.com\n",
+			wantStatus: []string{PARTICIPANT_IMPORT_ROW_VALID, PARTICIPANT_IMPORT_ROW_INVALID, PARTICIPANT_IMPORT_ROW_VALID},
+			check: func(t *testing.T, rows []ParticipantImportRow) {
+				if rows[1].Row != 4 || rows[1].Error != "duplicate of row 2" {
+					t.Errorf("unexpected duplicate row: %+v", rows[1])
+				}
+			},
+		},
+		{
+			name:       "invalid values",
+			content:    "email,language\nnot-an-email,en\nuser@example.com,xx-invalid\n",
+			wantStatus: []string{PARTICIPANT_IMPORT_ROW_INVALID, PARTICIPANT_IMPORT_ROW_INVALID},
+		},
+		{
+			name:       "flags and default language",
+			content:    "email,flag.group\nuser@example.com,b\nother@example.com,\n",
+			wantStatus: []string{PARTICIPANT_IMPORT_ROW_VALID, PARTICIPANT_IMPORT_ROW_VALID},
+			check: func(t *testing.T, rows []ParticipantImportRow) {
+				if rows[0].Flags["group"] != "b" || rows[0].Flags["source"] != "import" || rows[0].Language != "en" {
+					t.Errorf("unexpected first row: %+v", rows[0])
+				}
+				if rows[1].Flags["group"] != "a" {
+					t.Errorf("default flag not set: %+v", rows[1])
+				}
+			},
+		},
+		{name: "row limit", content: manyRows(maxParticipantImportRows), wantStatus: make([]string, maxParticipantImportRows)},
+		{name: "too many rows", content: manyRows(maxParticipantImportRows + 1), wantErr: "file has too many rows"},
+		{name: "unknown column", content: "email,phone\nuser@example.com,123\n", wantErr: "unknown column: phone"},
+		{name: "invalid flag column", content: "email,flag.a.b\nuser@example.com,1\n", wantErr: "invalid flag column: flag.a.b"},
+		{name: "missing email column", content: "language\nen\n", wantErr: "missing email column"},
+		{name: "empty file", content: "", wantErr: "file is empty"},
+		{name: "header only", content: "email\n\n", wantErr: "file has no rows"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseParticipantImportCSV(tt.content, map[string]string{"source": "import", "group": "a"}, "en")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(tt.wantStatus) {
+				t.Fatalf("expected %d rows, got %d", len(tt.wantStatus), len(rows))
+			}
+			for i, status := range tt.wantStatus {
+				if status != "" && rows[i].Status != status {
+					t.Errorf("row %d: expected %s, got %s (%s)", i, status, rows[i].Status, rows[i].Error)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, rows)
+			}
+		})
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestInviteImportedParticipant(t *testing.T) {
+	instanceID := "test"
+	studyDBService := newTestStudyDB(t, instanceID)
+	studyService.Init(studyDBService, "secret", nil)
+
+	participantUserDB, err := userDB.NewParticipantUserDBService(db.DBConfig{
+		URI:              os.Getenv("TEST_MONGODB_URI"),
+		DBNamePrefix:     studyDBService.DBNamePrefix,
+		Timeout:          10,
+		InstanceIDs:      []string{instanceID},
+		RunIndexCreation: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = participantUserDB.DBClient.Database(studyDBService.DBNamePrefix + instanceID + "_users").Drop(context.Background())
+		_ = participantUserDB.DBClient.Disconnect(context.Background())
+	})
+	h := &HttpEndpoints{studyDBConn: studyDBService, participantUserDB: participantUserDB}
+
+	t.Run("account removed if the study cannot be entered", func(t *testing.T) {
+		row := ParticipantImportRow{Email: "rollback@example.com", Language: "en", Status: PARTICIPANT_IMPORT_ROW_VALID}
+		// the study does not exist, so entering it fails
+		_, err := h.inviteImportedParticipant(context.Background(), instanceID, "missing-study", row, time.Hour)
+		if err == nil || err.Error() != "failed to enter study" {
+			t.Fatalf("expected failed study entry, got %v", err)
+		}
+		if _, err := participantUserDB.GetUserByAccountID(instanceID, row.Email); !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Errorf("account of failed import not removed: %v", err)
+		}
+	})
+
+	t.Run("existing account", func(t *testing.T) {
+		row := ParticipantImportRow{Email: "existing@example.com", Language: "en", Status: PARTICIPANT_IMPORT_ROW_VALID}
+		if _, err := participantUserDB.AddUser(instanceID, umUtils.InitNewEmailUser(row.Email, "", "en")); err != nil {
+			t.Fatal(err)
+		}
+		_, err := h.inviteImportedParticipant(context.Background(), instanceID, "missing-study", row, time.Hour)
+		if !errors.Is(err, errParticipantImportAccountExists) {
+			t.Errorf("expected existing account, got %v", err)
+		}
+		if _, err := participantUserDB.GetUserByAccountID(instanceID, row.Email); err != nil {
+			t.Errorf("existing account removed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,513 @@
+package apihandlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyutils "github.com/case-framework/case-backend/pkg/study/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) addStudyParticipantEndpoints(rg *gin.RouterGroup) {
+	participantsGroup := rg.Group("/participants")
+	{
+		// search participants with compound filters and cursor pagination
+		participantsGroup.POST("/search", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.searchStudyParticipants,
+		))
+
+		// create invited accounts from a CSV of contacts and enter them into the study, dryRun to preview the rows
+		participantsGroup.POST("/import", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_IMPORT_PARTICIPANTS,
+			},
+			nil,
+			h.importStudyParticipants,
+		))
+
+		// full state of a single participant, to inspect it before a correction
+		participantsGroup.GET("/:participantID/state", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.getStudyParticipantState,
+		))
+
+		// correct a broken participant state, recorded in the audit log, dryRun to preview the new state
+		participantsGroup.PATCH("/:participantID/state", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_EDIT_PARTICIPANT_STATES,
+			},
+			nil,
+			h.patchStudyParticipantState,
+		))
+
+		// apply an action to the participants of a search in the background
+		bulkJobsGroup := participantsGroup.Group("/bulk-jobs")
+		bulkJobsGroup.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.createParticipantBulkJob,
+		))
+
+		bulkJobsGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.getParticipantBulkJobs,
+		))
+
+		bulkJobsGroup.GET("/:jobID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.getParticipantBulkJob,
+		))
+
+		bulkJobsGroup.GET("/:jobID/results", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.getParticipantBulkJobResults,
+		))
+
+		bulkJobsGroup.POST("/:jobID/cancel", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_RUN_STUDY_ACTION,
+			},
+			nil,
+			h.cancelParticipantBulkJob,
+		))
+	}
+}
+
+// ParticipantSearchRequest is the body of the participant search, set filters are combined
+type ParticipantSearchRequest struct {
+	ParticipantIDs []string `json:"participantIds"`
+	Statuses       []string `json:"statuses"`
+	// all predicates must match, see the flag query
+	Flags []studyutils.FlagPredicate `json:"flags"`
+	// unix timestamps, inclusive, unbounded if 0
+	EnteredFrom int64 `json:"enteredFrom"`
+	EnteredTo   int64 `json:"enteredTo"`
+	// last submission of any survey, or of surveyKey if set
+	LastSubmissionFrom int64  `json:"lastSubmissionFrom"`
+	LastSubmissionTo   int64  `json:"lastSubmissionTo"`
+	SurveyKey          string `json:"surveyKey"`
+	// "enteredAt" (default) or "lastSubmittedAt"
+	SortBy string `json:"sortBy"`
+	// "asc" (default) or "desc"
+	SortOrder string `json:"sortOrder"`
+	Cursor    string `json:"cursor"`
+	Limit     int64  `json:"limit"`
+	// only count the matching participants
+	CountOnly bool `json:"countOnly"`
+}
+
+// prepParticipantSearchQuery converts the request, resolving the flag predicates with the flag types of the study,
+// otherwise writes the error response
+func (h *HttpEndpoints) prepParticipantSearchQuery(c *gin.Context, instanceID string, studyKey string, req ParticipantSearchRequest) (studyDB.ParticipantSearchQuery, bool) {
+	if req.SortOrder != "" && req.SortOrder != "asc" && req.SortOrder != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sortOrder must be asc or desc"})
+		return studyDB.ParticipantSearchQuery{}, false
+	}
+
+	var flagFilter bson.M
+	if len(req.Flags) > 0 {
+		flagTypes, err := h.studyDBConn.GetParticipantFlagTypes(instanceID, studyKey)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get participant flag types", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participant flag types"})
+			return studyDB.ParticipantSearchQuery{}, false
+		}
+		flagFilter, err = studyutils.BuildFlagQueryFilter(req.Flags, flagTypes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return studyDB.ParticipantSearchQuery{}, false
+		}
+	}
+
+	return studyDB.ParticipantSearchQuery{
+		ParticipantIDs:     req.ParticipantIDs,
+		Statuses:           req.Statuses,
+		FlagFilter:         flagFilter,
+		EnteredFrom:        req.EnteredFrom,
+		EnteredTo:          req.EnteredTo,
+		LastSubmissionFrom: req.LastSubmissionFrom,
+		LastSubmissionTo:   req.LastSubmissionTo,
+		SurveyKey:          req.SurveyKey,
+		SortBy:             req.SortBy,
+		SortDesc:           req.SortOrder == "desc",
+		Cursor:             req.Cursor,
+		Limit:              req.Limit,
+	}, true
+}
+
+func (h *HttpEndpoints) searchStudyParticipants(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req ParticipantSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "searching study participants", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.Bool("countOnly", req.CountOnly))
+
+	query, ok := h.prepParticipantSearchQuery(c, token.InstanceID, studyKey, req)
+	if !ok {
+		return
+	}
+
+	if req.CountOnly {
+		count, err := h.studyDBConn.CountParticipantsForSearch(token.InstanceID, studyKey, query)
+		if err != nil {
+			if errors.Is(err, studyDB.ErrInvalidParticipantSearchQuery) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			slog.ErrorContext(c, "failed to count study participants", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count study participants"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"count": count})
+		return
+	}
+
+	participants, nextCursor, err := h.studyDBConn.SearchParticipants(token.InstanceID, studyKey, query)
+	if err != nil {
+		if errors.Is(err, studyDB.ErrInvalidParticipantSearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		slog.ErrorContext(c, "failed to search study participants", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search study participants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"participants": participants,
+		"nextCursor":   nextCursor,
+	})
+}
+
+func (h *HttpEndpoints) createParticipantBulkJob(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req struct {
+		// participants the action is applied to, sort, cursor and limit are ignored
+		Selection ParticipantSearchRequest         `json:"selection"`
+		Action    studyTypes.ParticipantBulkAction `json:"action"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "creating participant bulk job", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("action", req.Action.Type))
+
+	if err := studyService.ValidateParticipantBulkAction(req.Action); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, ok := h.prepParticipantSearchQuery(c, token.InstanceID, studyKey, req.Selection)
+	if !ok {
+		return
+	}
+	filter, err := studyDB.ParticipantSearchFilter(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	selection, err := json.Marshal(req.Selection)
+	if err != nil {
+		slog.ErrorContext(c, "failed to encode selection", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode selection"})
+		return
+	}
+
+	job, err := studyService.CreateParticipantBulkJob(token.InstanceID, studyKey, token.Subject, req.Action, string(selection))
+	if err != nil {
+		slog.ErrorContext(c, "failed to create participant bulk job", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create participant bulk job"})
+		return
+	}
+
+	ctx := requestid.Detach(c.Request.Context())
+	h.runInBackground("participant-bulk-job", func() {
+		studyService.RunParticipantBulkJob(ctx, token.InstanceID, job, filter)
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+func (h *HttpEndpoints) getParticipantBulkJobs(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	slog.InfoContext(c, "getting participant bulk jobs", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	jobs, paginationInfo, err := h.studyDBConn.GetParticipantBulkJobs(token.InstanceID, studyKey, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get participant bulk jobs", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participant bulk jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":       jobs,
+		"pagination": paginationInfo,
+	})
+}
+
+// getParticipantBulkJobOfStudy returns the job if it belongs to the study, otherwise writes the error response
+func (h *HttpEndpoints) getParticipantBulkJobOfStudy(c *gin.Context, token *jwthandling.ManagementUserClaims) (*studyTypes.ParticipantBulkJob, bool) {
+	job, err := h.studyDBConn.GetParticipantBulkJob(token.InstanceID, c.Param("studyKey"), c.Param("jobID"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments || errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "participant bulk job not found"})
+			return nil, false
+		}
+		slog.ErrorContext(c, "failed to get participant bulk job", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participant bulk job"})
+		return nil, false
+	}
+	return &job, true
+}
+
+func (h *HttpEndpoints) getParticipantBulkJob(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting participant bulk job", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("jobID", c.Param("jobID")))
+
+	job, ok := h.getParticipantBulkJobOfStudy(c, token)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func (h *HttpEndpoints) getParticipantBulkJobResults(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	result := c.Query("result")
+	switch result {
+	case "", studyTypes.PARTICIPANT_BULK_RESULT_UPDATED, studyTypes.PARTICIPANT_BULK_RESULT_UNCHANGED, studyTypes.PARTICIPANT_BULK_RESULT_FAILED:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid result"})
+		return
+	}
+
+	slog.InfoContext(c, "getting participant bulk job results", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("jobID", c.Param("jobID")))
+
+	job, ok := h.getParticipantBulkJobOfStudy(c, token)
+	if !ok {
+		return
+	}
+
+	results, nextCursor, err := h.studyDBConn.GetParticipantBulkJobResults(token.InstanceID, job.ID, result, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		slog.ErrorContext(c, "failed to get participant bulk job results", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participant bulk job results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":    results,
+		"nextCursor": nextCursor,
+	})
+}
+
+func (h *HttpEndpoints) cancelParticipantBulkJob(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "cancelling participant bulk job", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("jobID", c.Param("jobID")))
+
+	job, ok := h.getParticipantBulkJobOfStudy(c, token)
+	if !ok {
+		return
+	}
+
+	updated, err := h.studyDBConn.CancelParticipantBulkJob(token.InstanceID, job.StudyKey, job.ID, time.Now())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "participant bulk job is already finished"})
+			return
+		}
+		slog.ErrorContext(c, "failed to cancel participant bulk job", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel participant bulk job"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": updated})
+}
+
+func (h *HttpEndpoints) getStudyParticipantState(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	participantID := c.Param("participantID")
+
+	slog.InfoContext(c, "getting participant state", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+
+	participant, err := h.studyDBConn.GetParticipantByID(token.InstanceID, studyKey, participantID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to get participant", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"participant": participant})
+}
+
+type PatchParticipantStateRequest struct {
+	studyService.ParticipantStatePatch
+	// why the state is corrected, recorded in the audit log
+	Reason string `json:"reason"`
+	DryRun bool   `json:"dryRun"`
+}
+
+func (h *HttpEndpoints) patchStudyParticipantState(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	participantID := c.Param("participantID")
+
+	var req PatchParticipantStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	if err := studyService.ValidateParticipantStatePatch(req.ParticipantStatePatch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.DryRun && req.RerunEntryRules {
+		c.JSON(http.StatusBadRequest, gin.H{"error": studyService.ErrEntryRulesInDryRun.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "patching participant state", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.Bool("dryRun", req.DryRun))
+
+	if !req.DryRun {
+		// the correction is logged before the state is changed, nothing is changed if this fails
+		patch, err := json.Marshal(req.ParticipantStatePatch)
+		if err != nil {
+			slog.ErrorContext(c, "failed to encode patch", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log participant state edit"})
+			return
+		}
+		err = h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+			StudyKey:      studyKey,
+			UserID:        token.Subject,
+			Action:        studyTypes.AUDIT_ACTION_PARTICIPANT_STATE_EDITED,
+			ParticipantID: participantID,
+			Purpose:       req.Reason,
+			Details: map[string]string{
+				"patch": string(patch),
+			},
+		})
+		if err != nil {
+			slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log participant state edit"})
+			return
+		}
+	}
+
+	before, after, err := studyService.PatchParticipantState(c.Request.Context(), token.InstanceID, studyKey, participantID, req.ParticipantStatePatch, req.DryRun)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to patch participant state", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to patch participant state: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"before": before, "after": after, "dryRun": req.DryRun})
+}
@@ -0,0 +1,126 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	reportrenderer "github.com/case-framework/case-backend/pkg/study/report-renderer"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) addReportTemplateEndpoints(rg *gin.RouterGroup) {
+	templatesGroup := rg.Group("/report-templates")
+	{
+		templatesGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getReportTemplates,
+		))
+
+		templatesGroup.PUT("/:reportKey", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+			},
+			nil,
+			h.saveReportTemplate,
+		))
+
+		templatesGroup.DELETE("/:reportKey", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+			},
+			nil,
+			h.deleteReportTemplate,
+		))
+	}
+}
+
+func (h *HttpEndpoints) getReportTemplates(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting report templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	templates, err := h.studyDBConn.GetReportTemplates(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get report templates", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get report templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reportTemplates": templates})
+}
+
+// saveReportTemplate creates or replaces the template of the report key. Reports with a template are available to participants.
+func (h *HttpEndpoints) saveReportTemplate(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	reportKey := c.Param("reportKey")
+
+	var template studyTypes.ReportTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	template.StudyKey = studyKey
+	template.ReportKey = reportKey
+	template.UpdatedAt = time.Now()
+	template.UpdatedBy = token.Subject
+
+	slog.InfoContext(c, "saving report template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("reportKey", reportKey))
+
+	if err := reportrenderer.ValidateTemplate(template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := h.studyDBConn.SaveReportTemplate(token.InstanceID, template)
+	if err != nil {
+		slog.ErrorContext(c, "failed to save report template", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save report template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+func (h *HttpEndpoints) deleteReportTemplate(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	reportKey := c.Param("reportKey")
+
+	slog.InfoContext(c, "deleting report template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("reportKey", reportKey))
+
+	if err := h.studyDBConn.DeleteReportTemplate(token.InstanceID, studyKey, reportKey); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "report template not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to delete report template", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete report template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "report template deleted"})
+}
@@ -0,0 +1,139 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *HttpEndpoints) updateStudyResponseQuarantineConfig(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req studyTypes.ResponseQuarantineConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if req.Threshold < 0 || req.MinCompletionRatio < 0 || req.MinCompletionRatio > 1 || req.DuplicateMinItems < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold and duplicateMinItems must not be negative, minCompletionRatio must be between 0 and 1"})
+		return
+	}
+	for surveyKey, seconds := range req.ExpectedCompletionSeconds {
+		if surveyKey == "" || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expectedCompletionSeconds"})
+			return
+		}
+	}
+
+	slog.InfoContext(c, "updating study response quarantine config", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.Bool("enabled", req.Enabled))
+
+	err := h.studyDBConn.UpdateStudyResponseQuarantineConfig(token.InstanceID, studyKey, &req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to update study response quarantine config", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study response quarantine config"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "study response quarantine config updated"})
+}
+
+func (h *HttpEndpoints) getQuarantinedResponses(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	surveyKey := c.DefaultQuery("surveyKey", "")
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	slog.InfoContext(c, "getting quarantined responses", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+
+	responses, paginationInfo, err := h.studyDBConn.GetQuarantinedResponses(token.InstanceID, studyKey, surveyKey, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get quarantined responses", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get quarantined responses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"responses":  responses,
+		"pagination": paginationInfo,
+	})
+}
+
+func (h *HttpEndpoints) acceptQuarantinedResponse(c *gin.Context) {
+	h.reviewQuarantinedResponse(c, studyTypes.AUDIT_ACTION_RESPONSE_QUARANTINE_ACCEPTED)
+}
+
+func (h *HttpEndpoints) purgeQuarantinedResponse(c *gin.Context) {
+	h.reviewQuarantinedResponse(c, studyTypes.AUDIT_ACTION_RESPONSE_QUARANTINE_PURGED)
+}
+
+// reviewQuarantinedResponse accepts or purges a quarantined response and records the decision in the audit log
+func (h *HttpEndpoints) reviewQuarantinedResponse(c *gin.Context, action string) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	responseID := c.Param("responseID")
+
+	slog.InfoContext(c, "reviewing quarantined response", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("responseID", responseID), slog.String("action", action))
+
+	response, err := h.studyDBConn.GetResponseByID(token.InstanceID, studyKey, responseID)
+	if err != nil || response.Quarantine == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "quarantined response not found"})
+		return
+	}
+
+	if action == studyTypes.AUDIT_ACTION_RESPONSE_QUARANTINE_ACCEPTED {
+		err = h.studyDBConn.AcceptQuarantinedResponse(token.InstanceID, studyKey, responseID)
+	} else {
+		err = h.studyDBConn.PurgeQuarantinedResponse(token.InstanceID, studyKey, responseID)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "quarantined response not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to review quarantined response", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to review quarantined response"})
+		return
+	}
+
+	err = h.studyDBConn.AddAuditLogEntry(token.InstanceID, studyTypes.AuditLogEntry{
+		StudyKey:      studyKey,
+		UserID:        token.Subject,
+		Action:        action,
+		ParticipantID: response.ParticipantID,
+		Details: map[string]string{
+			"responseID": responseID,
+			"surveyKey":  response.Key,
+		},
+	})
+	if err != nil {
+		slog.ErrorContext(c, "failed to add audit log entry", slog.String("error", err.Error()))
+	}
+
+	if action == studyTypes.AUDIT_ACTION_RESPONSE_QUARANTINE_ACCEPTED {
+		c.JSON(http.StatusOK, gin.H{"message": "response accepted"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "response purged"})
+}
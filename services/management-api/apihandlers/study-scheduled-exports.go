@@ -0,0 +1,269 @@
+package apihandlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	exportjobs "github.com/case-framework/case-backend/pkg/study/exporter/export-jobs"
+	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SetExportDeliveryDestinations sets the names and types of the destinations scheduled exports can be delivered to
+func (h *HttpEndpoints) SetExportDeliveryDestinations(destinations map[string]string) {
+	h.exportDeliveryDestinations = destinations
+}
+
+func (h *HttpEndpoints) addStudyScheduledExportEndpoints(rg *gin.RouterGroup) {
+	// scheduled exports push data to external systems, so only admins manage them
+	scheduledExportsGroup := rg.Group("/data-exporter/scheduled-exports")
+	scheduledExportsGroup.Use(mw.IsAdminUser())
+	{
+		scheduledExportsGroup.GET("/", h.getScheduledExports)
+		scheduledExportsGroup.GET("/destinations", h.getExportDeliveryDestinations)
+		scheduledExportsGroup.POST("/", mw.RequirePayload(), h.createScheduledExport)
+		scheduledExportsGroup.GET("/:scheduleID", h.getScheduledExport)
+		scheduledExportsGroup.PUT("/:scheduleID", mw.RequirePayload(), h.updateScheduledExport)
+		scheduledExportsGroup.DELETE("/:scheduleID", h.deleteScheduledExport)
+	}
+}
+
+type ScheduledExportPayload struct {
+	Label    string `json:"label"`
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone"`
+	// name of a destination of the service config
+	Destination string                      `json:"destination"`
+	Active      bool                        `json:"active"`
+	Incremental bool                        `json:"incremental"`
+	Request     studyTypes.ExportJobRequest `json:"request"`
+	// salt for hashed participant IDs, generated if empty so that IDs stay the same across runs
+	PseudonymizationSalt string `json:"pseudonymizationSalt"`
+}
+
+func (h *HttpEndpoints) getScheduledExports(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "getting scheduled exports", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	scheduledExports, err := h.studyDBConn.GetScheduledExports(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get scheduled exports", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scheduled exports"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"scheduledExports": scheduledExports})
+}
+
+func (h *HttpEndpoints) getExportDeliveryDestinations(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting export delivery destinations", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	destinations := []gin.H{}
+	for name, destinationType := range h.exportDeliveryDestinations {
+		destinations = append(destinations, gin.H{"name": name, "type": destinationType})
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		return destinations[i]["name"].(string) < destinations[j]["name"].(string)
+	})
+	c.JSON(http.StatusOK, gin.H{"destinations": destinations})
+}
+
+func (h *HttpEndpoints) createScheduledExport(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req ScheduledExportPayload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "creating scheduled export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("destination", req.Destination))
+
+	scheduledExport := studyTypes.ScheduledExport{
+		StudyKey:  studyKey,
+		CreatedBy: token.Subject,
+	}
+	if err := h.applyScheduledExportPayload(&scheduledExport, req, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scheduledExport, err := h.studyDBConn.CreateScheduledExport(token.InstanceID, scheduledExport)
+	if err != nil {
+		slog.ErrorContext(c, "failed to create scheduled export", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create scheduled export"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"scheduledExport": scheduledExport})
+}
+
+func (h *HttpEndpoints) getScheduledExport(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	scheduleID := c.Param("scheduleID")
+
+	slog.InfoContext(c, "getting scheduled export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scheduleID", scheduleID))
+
+	scheduledExport, err := h.studyDBConn.GetScheduledExportByID(token.InstanceID, studyKey, scheduleID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err == primitive.ErrInvalidHex {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scheduled export not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to get scheduled export", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scheduled export"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"scheduledExport": scheduledExport})
+}
+
+func (h *HttpEndpoints) updateScheduledExport(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	scheduleID := c.Param("scheduleID")
+
+	var req ScheduledExportPayload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.InfoContext(c, "updating scheduled export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scheduleID", scheduleID))
+
+	scheduledExport, err := h.studyDBConn.GetScheduledExportByID(token.InstanceID, studyKey, scheduleID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err == primitive.ErrInvalidHex {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scheduled export not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to get scheduled export", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scheduled export"})
+		return
+	}
+
+	// the salt is not returned by the API, keep it unless a new one is set
+	if req.PseudonymizationSalt == "" {
+		req.PseudonymizationSalt = scheduledExport.Request.PseudonymizationSalt
+	}
+	if err := h.applyScheduledExportPayload(&scheduledExport, req, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.studyDBConn.UpdateScheduledExport(token.InstanceID, scheduledExport); err != nil {
+		slog.ErrorContext(c, "failed to update scheduled export", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update scheduled export"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"scheduledExport": scheduledExport})
+}
+
+func (h *HttpEndpoints) deleteScheduledExport(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	scheduleID := c.Param("scheduleID")
+
+	slog.InfoContext(c, "deleting scheduled export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scheduleID", scheduleID))
+
+	err := h.studyDBConn.DeleteScheduledExport(token.InstanceID, studyKey, scheduleID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err == primitive.ErrInvalidHex {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scheduled export not found"})
+			return
+		}
+		slog.ErrorContext(c, "failed to delete scheduled export", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete scheduled export"})
+		return
+	}
+
+	// checkpoint of incremental runs
+	err = h.studyDBConn.DeleteExportCheckpoint(token.InstanceID, studyKey, exportjobs.DeltaKeyForScheduledExport(scheduleID))
+	if err != nil && err != mongo.ErrNoDocuments {
+		slog.ErrorContext(c, "failed to delete export checkpoint of scheduled export", slog.String("scheduleID", scheduleID), slog.String("error", err.Error()))
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "scheduled export deleted"})
+}
+
+// applyScheduledExportPayload validates the payload and copies it into the scheduled export, the next run is computed from now
+func (h *HttpEndpoints) applyScheduledExportPayload(scheduledExport *studyTypes.ScheduledExport, req ScheduledExportPayload, now time.Time) error {
+	if req.Request.SurveyKey == "" {
+		return errors.New("surveyKey is required")
+	}
+	if !surveyresponses.IsSupportedExportFormat(req.Request.Format) {
+		return errors.New("unsupported format")
+	}
+	if req.Request.Filter != "" {
+		if err := json.Unmarshal([]byte(req.Request.Filter), &bson.M{}); err != nil {
+			return errors.New("invalid filter")
+		}
+	}
+	if req.Request.Sort != "" {
+		if err := json.Unmarshal([]byte(req.Request.Sort), &bson.M{}); err != nil {
+			return errors.New("invalid sort")
+		}
+	}
+	if req.Request.Since < 0 {
+		return errors.New("invalid since")
+	}
+	for _, field := range req.Request.ParticipantInfo {
+		if !surveyresponses.IsSupportedParticipantInfoField(field) {
+			return errors.New("unsupported participantInfo field")
+		}
+	}
+	if _, ok := h.exportDeliveryDestinations[req.Destination]; !ok {
+		return errors.New("unknown destination")
+	}
+
+	nextRunAt, err := exportjobs.NextScheduledRun(req.Cron, req.Timezone, now)
+	if err != nil {
+		return errors.New("invalid schedule: " + err.Error())
+	}
+
+	pseudonymization := surveyresponses.PseudonymizationOptions{
+		ParticipantIDs:     req.Request.ParticipantIDs,
+		Salt:               req.PseudonymizationSalt,
+		RedactColumns:      req.Request.RedactColumns,
+		TimestampPrecision: req.Request.TimestampPrecision,
+	}
+	if err := pseudonymization.Validate(); err != nil {
+		return err
+	}
+	if pseudonymization.ParticipantIDs == surveyresponses.PARTICIPANT_IDS_HASH && pseudonymization.Salt == "" {
+		salt := make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		pseudonymization.Salt = hex.EncodeToString(salt)
+	}
+
+	scheduledExport.Label = req.Label
+	scheduledExport.Cron = req.Cron
+	scheduledExport.Timezone = req.Timezone
+	scheduledExport.Destination = req.Destination
+	scheduledExport.Active = req.Active
+	scheduledExport.Incremental = req.Incremental
+	scheduledExport.Request = req.Request
+	// set by the worker for incremental runs
+	scheduledExport.Request.DeltaKey = ""
+	scheduledExport.Request.PseudonymizationSalt = pseudonymization.Salt
+	scheduledExport.NextRunAt = nextRunAt
+	return nil
+}
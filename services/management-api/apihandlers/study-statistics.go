@@ -0,0 +1,203 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStatisticsPeriod      = 30 * 24 * time.Hour
+	defaultActiveWithinDays      = 30
+	statisticsUntilRoundingSteps = 5 * time.Minute
+)
+
+func (h *HttpEndpoints) addStudyStatisticsEndpoints(rg *gin.RouterGroup) {
+	statsGroup := rg.Group("/statistics")
+	{
+		statsGroup.GET("/responses-per-day", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_RESPONSES,
+			},
+			nil,
+			h.getResponseCountsPerDay,
+		))
+
+		statsGroup.GET("/participants", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.getParticipantCounts,
+		))
+
+		statsGroup.GET("/funnel", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.getCompletionFunnel,
+		))
+
+		statsGroup.GET("/enrollment", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.getEnrollmentPerDay,
+		))
+	}
+}
+
+type statisticsPeriod struct {
+	From     int64
+	Until    int64
+	Timezone string
+}
+
+// parseStatisticsPeriod reads from, until (unix seconds) and tz from the query. The default until is rounded up,
+// so repeated dashboard requests without explicit range hit the statistics cache.
+func parseStatisticsPeriod(c *gin.Context) (statisticsPeriod, bool) {
+	until := time.Now().Truncate(statisticsUntilRoundingSteps).Add(statisticsUntilRoundingSteps).Unix()
+	if v := c.DefaultQuery("until", ""); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid until"})
+			return statisticsPeriod{}, false
+		}
+		until = parsed
+	}
+	from := until - int64(defaultStatisticsPeriod.Seconds())
+	if v := c.DefaultQuery("from", ""); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return statisticsPeriod{}, false
+		}
+		from = parsed
+	}
+	if from > until {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before until"})
+		return statisticsPeriod{}, false
+	}
+
+	timezone := c.DefaultQuery("tz", "UTC")
+	if _, err := time.LoadLocation(timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timezone"})
+		return statisticsPeriod{}, false
+	}
+	return statisticsPeriod{From: from, Until: until, Timezone: timezone}, true
+}
+
+func (h *HttpEndpoints) getResponseCountsPerDay(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	period, ok := parseStatisticsPeriod(c)
+	if !ok {
+		return
+	}
+
+	slog.InfoContext(c, "getting response counts per day", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	result, err := h.studyDBConn.GetResponseCountsPerDay(token.InstanceID, studyKey, period.From, period.Until, period.Timezone)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get response counts", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get response counts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *HttpEndpoints) getParticipantCounts(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	activeWithinDays, err := strconv.Atoi(c.DefaultQuery("activeWithinDays", strconv.Itoa(defaultActiveWithinDays)))
+	if err != nil || activeWithinDays < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid activeWithinDays"})
+		return
+	}
+	// start of the current day, so that the cache key stays the same during the day
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	activeSince := today.AddDate(0, 0, -activeWithinDays).Unix()
+
+	slog.InfoContext(c, "getting participant counts", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	result, err := h.studyDBConn.GetParticipantCounts(token.InstanceID, studyKey, activeSince)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get participant counts", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get participant counts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *HttpEndpoints) getCompletionFunnel(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	surveys := c.DefaultQuery("surveys", "")
+	if surveys == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "surveys query parameter is required"})
+		return
+	}
+	surveyKeys := strings.Split(surveys, ",")
+	if err := studyDB.ValidateFunnelSurveyKeys(surveyKeys); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.InfoContext(c, "getting completion funnel", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveys", surveys))
+
+	result, err := h.studyDBConn.GetCompletionFunnel(token.InstanceID, studyKey, surveyKeys)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get completion funnel", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get completion funnel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *HttpEndpoints) getEnrollmentPerDay(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	period, ok := parseStatisticsPeriod(c)
+	if !ok {
+		return
+	}
+
+	slog.InfoContext(c, "getting enrollment per day", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	result, err := h.studyDBConn.GetEnrollmentPerDay(token.InstanceID, studyKey, period.From, period.Until, period.Timezone)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get enrollment statistics", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get enrollment statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,171 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AddTrashAPI adds the endpoints to list and restore deleted studies, survey versions, message templates and
+// management users. Items in the trash are purged after the retention window by the trash-purge job.
+func (h *HttpEndpoints) AddTrashAPI(rg *gin.RouterGroup) {
+	trashGroup := rg.Group("/trash")
+	trashGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn))
+	trashGroup.Use(mw.IsAdminUser())
+	{
+		trashGroup.GET("/", h.getTrash)
+		trashGroup.POST("/studies/:studyKey/restore", h.restoreStudy)
+		trashGroup.POST("/studies/:studyKey/surveys/:surveyKey/versions/:versionID/restore", h.restoreSurveyVersion)
+		trashGroup.POST("/message-templates/:templateID/restore", h.restoreMessageTemplate)
+		trashGroup.POST("/management-users/:userID/restore", h.restoreManagementUser)
+	}
+}
+
+type deletedSurveyVersions struct {
+	StudyKey string               `json:"studyKey"`
+	Versions []*studyTypes.Survey `json:"versions"`
+}
+
+func (h *HttpEndpoints) getTrash(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	slog.InfoContext(c, "getting trash", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	studies, err := h.studyDBConn.GetDeletedStudies(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get deleted studies", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get deleted studies"})
+		return
+	}
+
+	// survey versions of deleted studies are restored with the study
+	activeStudies, err := h.studyDBConn.GetStudies(token.InstanceID, "", true)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get studies", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get studies"})
+		return
+	}
+	surveyVersions := []deletedSurveyVersions{}
+	for _, study := range activeStudies {
+		versions, err := h.studyDBConn.GetDeletedSurveyVersions(token.InstanceID, study.Key)
+		if err != nil {
+			slog.ErrorContext(c, "failed to get deleted survey versions", slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get deleted survey versions"})
+			return
+		}
+		if len(versions) > 0 {
+			surveyVersions = append(surveyVersions, deletedSurveyVersions{StudyKey: study.Key, Versions: versions})
+		}
+	}
+
+	templates, err := h.messagingDBConn.GetDeletedEmailTemplates(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get deleted message templates", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get deleted message templates"})
+		return
+	}
+
+	users, err := h.muDBConn.GetDeletedUsers(token.InstanceID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get deleted management users", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get deleted management users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"studies":          studies,
+		"surveyVersions":   surveyVersions,
+		"messageTemplates": templates,
+		"managementUsers":  users,
+	})
+}
+
+func (h *HttpEndpoints) restoreStudy(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.InfoContext(c, "restoring study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.RestoreStudy(token.InstanceID, studyKey)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "study not found in trash"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c, "failed to restore study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore study"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "study restored"})
+}
+
+func (h *HttpEndpoints) restoreSurveyVersion(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	surveyKey := c.Param("surveyKey")
+	versionID := c.Param("versionID")
+
+	slog.InfoContext(c, "restoring survey version", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("versionID", versionID))
+
+	err := h.studyDBConn.RestoreSurveyVersion(token.InstanceID, studyKey, surveyKey, versionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "survey version not found in trash"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c, "failed to restore survey version", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore survey version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "survey version restored"})
+}
+
+func (h *HttpEndpoints) restoreMessageTemplate(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	templateID := c.Param("templateID")
+
+	slog.InfoContext(c, "restoring message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("templateID", templateID))
+
+	err := h.messagingDBConn.RestoreEmailTemplate(token.InstanceID, templateID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message template not found in trash"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c, "failed to restore message template", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore message template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "message template restored"})
+}
+
+// restoreManagementUser restores the account only, permissions are removed when the user is deleted and need to be
+// granted again
+func (h *HttpEndpoints) restoreManagementUser(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	userID := c.Param("userID")
+
+	slog.InfoContext(c, "restoring management user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
+
+	err := h.muDBConn.RestoreUser(token.InstanceID, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found in trash"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c, "failed to restore management user", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user restored"})
+}
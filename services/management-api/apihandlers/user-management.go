@@ -9,6 +9,7 @@ import (
 	mUserDB "github.com/case-framework/case-backend/pkg/db/management-user"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
 	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
 	"github.com/case-framework/case-backend/pkg/user-management/utils"
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
 
@@ -18,11 +19,11 @@ import (
 
 func (h *HttpEndpoints) AddUserManagementAPI(rg *gin.RouterGroup) {
 	umGroup := rg.Group("/user-management")
-	umGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.allowedInstanceIDs, h.muDBConn))
+	umGroup.Use(mw.ManagementAuthMiddleware(h.tokenSignKey, h.isInstanceAllowed, h.muDBConn))
 
 	// all management users can see other users (though not all details if not admin)
 	{
-		umGroup.GET("/management-users", h.getAllManagementUsers)
+		umGroup.GET("/management-users", mw.RejectAccessTokens(), h.getAllManagementUsers)
 	}
 
 	managementUsersGroup := umGroup.Group("/management-users")
@@ -31,6 +32,7 @@ func (h *HttpEndpoints) AddUserManagementAPI(rg *gin.RouterGroup) {
 		managementUsersGroup.GET("/:userID", h.getManagementUser)
 		managementUsersGroup.DELETE("/:userID", h.deleteManagementUser)
 		managementUsersGroup.GET("/:userID/permissions", h.getManagementUserPermissions)
+		managementUsersGroup.GET("/:userID/study-access", h.getManagementUserStudyAccess)
 		managementUsersGroup.POST("/:userID/permissions", mw.RequirePayload(), h.createManagementUserPermission)
 		managementUsersGroup.DELETE("/:userID/permissions/:permissionID", h.deleteManagementUserPermission)
 		managementUsersGroup.PUT("/:userID/permissions/:permissionID/limiter", mw.RequirePayload(), h.updateManagementUserPermissionLimiter)
@@ -62,6 +64,7 @@ func (h *HttpEndpoints) AddUserManagementAPI(rg *gin.RouterGroup) {
 		serviceAccountsGroup.DELETE("/:serviceAccountID/api-keys/:apiKeyID", h.deleteServiceAccountAPIKey)
 		serviceAccountsGroup.DELETE("/:serviceAccountID", h.deleteServiceAccount)
 		serviceAccountsGroup.GET("/:serviceAccountID/permissions", h.getServiceAccountPermissions)
+		serviceAccountsGroup.GET("/:serviceAccountID/study-access", h.getServiceAccountStudyAccess)
 		serviceAccountsGroup.POST("/:serviceAccountID/permissions", mw.RequirePayload(), h.createServiceAccountPermission)
 		serviceAccountsGroup.DELETE("/:serviceAccountID/permissions/:permissionID", h.deleteServiceAccountPermission)
 		serviceAccountsGroup.PUT("/:serviceAccountID/permissions/:permissionID/limiter", mw.RequirePayload(), h.updateServiceAccountPermissionLimiter)
@@ -72,11 +75,11 @@ func (h *HttpEndpoints) AddUserManagementAPI(rg *gin.RouterGroup) {
 func (h *HttpEndpoints) getAllManagementUsers(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
-	slog.Info("getting all users", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting all users", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	users, err := h.muDBConn.GetAllUsers(token.InstanceID, token.IsAdmin)
 	if err != nil {
-		slog.Error("error retrieving users", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error retrieving users", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting users"})
 		return
 	}
@@ -88,11 +91,11 @@ func (h *HttpEndpoints) getManagementUser(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	userID := c.Param("userID")
 
-	slog.Info("getting user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
+	slog.InfoContext(c, "getting user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
 
 	user, err := h.muDBConn.GetUserByID(token.InstanceID, userID)
 	if err != nil {
-		slog.Error("error retrieving user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error retrieving user", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting user"})
 		return
 	}
@@ -105,29 +108,35 @@ func (h *HttpEndpoints) deleteManagementUser(c *gin.Context) {
 	userID := c.Param("userID")
 
 	if token.Subject == userID {
-		slog.Error("user cannot delete itself", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
+		slog.ErrorContext(c, "user cannot delete itself", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user cannot delete itself"})
 		return
 	}
 
-	slog.Info("deleting user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
+	slog.InfoContext(c, "deleting user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
 
 	// delete sessions
 	err := h.muDBConn.DeleteSessionsByUserID(token.InstanceID, userID)
 	if err != nil {
-		slog.Error("error deleting sessions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting sessions", slog.String("error", err.Error()))
+	}
+
+	// delete access tokens
+	err = h.muDBConn.DeleteAccessTokensByUserID(token.InstanceID, userID)
+	if err != nil {
+		slog.ErrorContext(c, "error deleting access tokens", slog.String("error", err.Error()))
 	}
 
 	// delete permissions
 	err = h.muDBConn.DeletePermissionsBySubject(token.InstanceID, userID, pc.SUBJECT_TYPE_MANAGEMENT_USER)
 	if err != nil {
-		slog.Error("error deleting permissions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting permissions", slog.String("error", err.Error()))
 	}
 
 	// delete user
 	err = h.muDBConn.DeleteUser(token.InstanceID, userID)
 	if err != nil {
-		slog.Error("error deleting user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting user", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting user"})
 		return
 	}
@@ -139,11 +148,11 @@ func (h *HttpEndpoints) getManagementUserPermissions(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	userID := c.Param("userID")
 
-	slog.Info("getting user permissions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
+	slog.InfoContext(c, "getting user permissions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
 
 	permissions, err := h.muDBConn.GetPermissionBySubject(token.InstanceID, userID, pc.SUBJECT_TYPE_MANAGEMENT_USER)
 	if err != nil {
-		slog.Error("error retrieving user permissions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error retrieving user permissions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting user permissions"})
 		return
 	}
@@ -157,16 +166,16 @@ func (h *HttpEndpoints) createManagementUserPermission(c *gin.Context) {
 
 	var newPerm mUserDB.Permission
 	if err := c.ShouldBindJSON(&newPerm); err != nil {
-		slog.Error("error binding permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error binding permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing payload"})
 		return
 	}
 
-	slog.Info("creating user permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
+	slog.InfoContext(c, "creating user permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedUserID", userID))
 
 	_, err := h.muDBConn.GetUserByID(token.InstanceID, userID)
 	if err != nil {
-		slog.Error("user not found", slog.String("userID", userID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+		slog.ErrorContext(c, "user not found", slog.String("userID", userID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user not found"})
 		return
 	}
@@ -184,11 +193,18 @@ func (h *HttpEndpoints) createManagementUserPermission(c *gin.Context) {
 		newPerm.Limiter,
 	)
 	if err != nil {
-		slog.Error("error creating user permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error creating user permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error creating user permission"})
 		return
 	}
 
+	emitPrivilegeChange(c, token, securityevents.ACTOR_MANAGEMENT_USER, userID, map[string]string{
+		"change":       "permission-created",
+		"permissionID": permission.ID.Hex(),
+		"resourceType": permission.ResourceType,
+		"resourceKey":  permission.ResourceKey,
+		"action":       permission.Action,
+	})
 	c.JSON(http.StatusOK, gin.H{"permission": permission})
 }
 
@@ -197,15 +213,16 @@ func (h *HttpEndpoints) deleteManagementUserPermission(c *gin.Context) {
 	userID := c.Param("userID")
 	permissionID := c.Param("permissionID")
 
-	slog.Info("deleting user permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("permissionForUser", userID), slog.String("permissionID", permissionID))
+	slog.InfoContext(c, "deleting user permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("permissionForUser", userID), slog.String("permissionID", permissionID))
 
 	err := h.muDBConn.DeletePermission(token.InstanceID, permissionID)
 	if err != nil {
-		slog.Error("error deleting user permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting user permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting user permission"})
 		return
 	}
 
+	emitPrivilegeChange(c, token, securityevents.ACTOR_MANAGEMENT_USER, userID, map[string]string{"change": "permission-deleted", "permissionID": permissionID})
 	c.JSON(http.StatusOK, gin.H{"message": "permission deleted"})
 }
 
@@ -216,20 +233,21 @@ func (h *HttpEndpoints) updateManagementUserPermissionLimiter(c *gin.Context) {
 
 	var newLimiter mUserDB.Permission
 	if err := c.ShouldBindJSON(&newLimiter); err != nil {
-		slog.Error("error binding permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error binding permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing payload"})
 		return
 	}
 
-	slog.Info("updating user permission limiter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("permissionForUser", userID), slog.String("permissionID", permissionID))
+	slog.InfoContext(c, "updating user permission limiter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("permissionForUser", userID), slog.String("permissionID", permissionID))
 
 	err := h.muDBConn.UpdatePermissionLimiter(token.InstanceID, permissionID, newLimiter.Limiter)
 	if err != nil {
-		slog.Error("error updating user permission limiter", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error updating user permission limiter", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error updating user permission limiter"})
 		return
 	}
 
+	emitPrivilegeChange(c, token, securityevents.ACTOR_MANAGEMENT_USER, userID, map[string]string{"change": "permission-limiter-updated", "permissionID": permissionID})
 	c.JSON(http.StatusOK, gin.H{"message": "permission limiter updated"})
 }
 
@@ -240,39 +258,39 @@ func (h *HttpEndpoints) requestParticipantUserDeletion(c *gin.Context) {
 		Email string `json:"email"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	if !umUtils.CheckEmailFormat(req.Email) {
-		slog.Error("invalid email format", slog.String("email", req.Email))
+		slog.ErrorContext(c, "invalid email format", slog.String("email", req.Email))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email format"})
 		return
 	}
 
-	slog.Info("requesting participant user deletion", slog.String("instanceID", token.InstanceID), slog.String("by", token.Subject), slog.String("email", req.Email))
+	slog.InfoContext(c, "requesting participant user deletion", slog.String("instanceID", token.InstanceID), slog.String("by", token.Subject), slog.String("email", req.Email))
 
 	user, err := h.participantUserDB.GetUserByAccountID(token.InstanceID, req.Email)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceID", token.InstanceID), slog.String("email", req.Email), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceID", token.InstanceID), slog.String("email", req.Email), slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user could not be deleted"})
 		return
 	}
 
 	for _, profile := range user.Profiles {
-		studyService.OnProfileDeleted(token.InstanceID, profile.ID.Hex(), nil)
+		studyService.OnProfileDeleted(c.Request.Context(), token.InstanceID, profile.ID.Hex(), nil)
 	}
 
 	// delete all temp tokens
 	err = h.globalInfosDBConn.DeleteAllTempTokenForUser(token.InstanceID, user.ID.Hex(), "")
 	if err != nil {
-		slog.Error("failed to delete temp tokens", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete temp tokens", slog.String("error", err.Error()))
 	}
 
-	err = h.participantUserDB.DeleteUser(token.InstanceID, user.ID.Hex())
+	err = h.participantUserDB.DeleteUserAccount(token.InstanceID, user.ID.Hex())
 	if err != nil {
-		slog.Error("cannot delete user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot delete user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete user"})
 		return
 	}
@@ -284,11 +302,11 @@ func (h *HttpEndpoints) requestParticipantUserDeletion(c *gin.Context) {
 func (h *HttpEndpoints) getAllServiceAccounts(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
-	slog.Info("getting all service accounts", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting all service accounts", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	serviceUser, err := h.muDBConn.GetServiceUsers(token.InstanceID)
 	if err != nil {
-		slog.Error("error retrieving service accounts", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error retrieving service accounts", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting service accounts"})
 		return
 	}
@@ -306,16 +324,16 @@ func (h *HttpEndpoints) createServiceAccount(c *gin.Context) {
 
 	var req ServiceUserProps
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("creating a new service account", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("label", req.Label))
+	slog.InfoContext(c, "creating a new service account", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("label", req.Label))
 
 	nu, err := h.muDBConn.CreateServiceUser(token.InstanceID, req.Label, req.Description)
 	if err != nil {
-		slog.Error("error creating service account", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error creating service account", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error creating service account"})
 		return
 	}
@@ -327,11 +345,11 @@ func (h *HttpEndpoints) getServiceAccount(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	serviceAccountID := c.Param("serviceAccountID")
 
-	slog.Info("getting service account", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedServiceAccountID", serviceAccountID))
+	slog.InfoContext(c, "getting service account", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("requestedServiceAccountID", serviceAccountID))
 
 	serviceUser, err := h.muDBConn.GetServiceUserByID(token.InstanceID, serviceAccountID)
 	if err != nil {
-		slog.Error("error retrieving service account", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error retrieving service account", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting service account"})
 		return
 	}
@@ -344,15 +362,15 @@ func (h *HttpEndpoints) updateServiceAccount(c *gin.Context) {
 	serviceAccountID := c.Param("serviceAccountID")
 	var req ServiceUserProps
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("updating service account", slog.String("serviceAccountID", serviceAccountID))
+	slog.InfoContext(c, "updating service account", slog.String("serviceAccountID", serviceAccountID))
 
 	if err := h.muDBConn.UpdateServiceUser(token.InstanceID, serviceAccountID, req.Label, req.Description); err != nil {
-		slog.Error("failed to update service account", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update service account", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -363,11 +381,11 @@ func (h *HttpEndpoints) getServiceAccountAPIKeys(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	serviceAccountID := c.Param("serviceAccountID")
 
-	slog.Info("getting API keys for service account", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "getting API keys for service account", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	apiKeys, err := h.muDBConn.GetServiceUserAPIKeys(token.InstanceID, serviceAccountID)
 	if err != nil {
-		slog.Error("failed to get api keys for service account", slog.String("error", err.Error()), slog.String("instanceID", token.InstanceID), slog.String("serviceAccountID", serviceAccountID))
+		slog.ErrorContext(c, "failed to get api keys for service account", slog.String("error", err.Error()), slog.String("instanceID", token.InstanceID), slog.String("serviceAccountID", serviceAccountID))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get API keys"})
 		return
 	}
@@ -387,16 +405,16 @@ func (h *HttpEndpoints) createServiceAccountAPIKey(c *gin.Context) {
 
 	var req ServiceAccountAPIKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	slog.Info("creating service account API key", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "creating service account API key", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	_, err := h.muDBConn.GetServiceUserByID(token.InstanceID, serviceAccountID)
 	if err != nil {
-		slog.Error("service account not found", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+		slog.ErrorContext(c, "service account not found", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "service account not found"})
 		return
 	}
@@ -409,16 +427,17 @@ func (h *HttpEndpoints) createServiceAccountAPIKey(c *gin.Context) {
 
 	newApiKey, err := utils.GenerateUniqueTokenString()
 	if err != nil {
-		slog.Error("failed to generate unique token string", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to generate unique token string", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	if err := h.muDBConn.CreateServiceUserAPIKey(token.InstanceID, serviceAccountID, newApiKey, expiresAt); err != nil {
-		slog.Error("failed to create service account API key", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create service account API key", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	emitPrivilegeChange(c, token, securityevents.ACTOR_SERVICE_ACCOUNT, serviceAccountID, map[string]string{"change": "api-key-created"})
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
@@ -427,14 +446,15 @@ func (h *HttpEndpoints) deleteServiceAccountAPIKey(c *gin.Context) {
 	serviceAccountID := c.Param("serviceAccountID")
 	apiKeyID := c.Param("apiKeyID")
 
-	slog.Info("deleting service account API key", slog.String("serviceAccountID", serviceAccountID), slog.String("apiKeyID", apiKeyID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "deleting service account API key", slog.String("serviceAccountID", serviceAccountID), slog.String("apiKeyID", apiKeyID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	if err := h.muDBConn.DeleteServiceUserAPIKey(token.InstanceID, apiKeyID); err != nil {
-		slog.Error("failed to delete service account API key", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete service account API key", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	emitPrivilegeChange(c, token, securityevents.ACTOR_SERVICE_ACCOUNT, serviceAccountID, map[string]string{"change": "api-key-deleted", "apiKeyID": apiKeyID})
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
@@ -442,10 +462,10 @@ func (h *HttpEndpoints) deleteServiceAccount(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	serviceAccountID := c.Param("serviceAccountID")
 
-	slog.Info("deleting service account", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "deleting service account", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 
 	if err := h.muDBConn.DeleteServiceUser(token.InstanceID, serviceAccountID); err != nil {
-		slog.Error("failed to delete service account", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete service account", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -456,11 +476,11 @@ func (h *HttpEndpoints) getServiceAccountPermissions(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	serviceAccountID := c.Param("serviceAccountID")
 
-	slog.Info("getting user permissions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID))
+	slog.InfoContext(c, "getting user permissions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID))
 
 	permissions, err := h.muDBConn.GetPermissionBySubject(token.InstanceID, serviceAccountID, pc.SUBJECT_TYPE_SERVICE_ACCOUNT)
 	if err != nil {
-		slog.Error("error retrieving sercice account permissions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error retrieving sercice account permissions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting service account permissions"})
 		return
 	}
@@ -474,16 +494,16 @@ func (h *HttpEndpoints) createServiceAccountPermission(c *gin.Context) {
 
 	var newPerm mUserDB.Permission
 	if err := c.ShouldBindJSON(&newPerm); err != nil {
-		slog.Error("error binding permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error binding permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing payload"})
 		return
 	}
 
-	slog.Info("creating service account permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID))
+	slog.InfoContext(c, "creating service account permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID))
 
 	_, err := h.muDBConn.GetServiceUserByID(token.InstanceID, serviceAccountID)
 	if err != nil {
-		slog.Error("service account not found", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+		slog.ErrorContext(c, "service account not found", slog.String("serviceAccountID", serviceAccountID), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "service account not found"})
 		return
 	}
@@ -501,11 +521,18 @@ func (h *HttpEndpoints) createServiceAccountPermission(c *gin.Context) {
 		newPerm.Limiter,
 	)
 	if err != nil {
-		slog.Error("error creating service account permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error creating service account permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error creating service account permission"})
 		return
 	}
 
+	emitPrivilegeChange(c, token, securityevents.ACTOR_SERVICE_ACCOUNT, serviceAccountID, map[string]string{
+		"change":       "permission-created",
+		"permissionID": permission.ID.Hex(),
+		"resourceType": permission.ResourceType,
+		"resourceKey":  permission.ResourceKey,
+		"action":       permission.Action,
+	})
 	c.JSON(http.StatusOK, gin.H{"permission": permission})
 }
 
@@ -515,15 +542,16 @@ func (h *HttpEndpoints) deleteServiceAccountPermission(c *gin.Context) {
 
 	permissionID := c.Param("permissionID")
 
-	slog.Info("deleting service account permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID), slog.String("permissionID", permissionID))
+	slog.InfoContext(c, "deleting service account permission", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID), slog.String("permissionID", permissionID))
 
 	err := h.muDBConn.DeletePermission(token.InstanceID, permissionID)
 	if err != nil {
-		slog.Error("error deleting service account permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error deleting service account permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting service account permission"})
 		return
 	}
 
+	emitPrivilegeChange(c, token, securityevents.ACTOR_SERVICE_ACCOUNT, serviceAccountID, map[string]string{"change": "permission-deleted", "permissionID": permissionID})
 	c.JSON(http.StatusOK, gin.H{"message": "permission deleted"})
 }
 
@@ -535,19 +563,20 @@ func (h *HttpEndpoints) updateServiceAccountPermissionLimiter(c *gin.Context) {
 
 	var newLimiter mUserDB.Permission
 	if err := c.ShouldBindJSON(&newLimiter); err != nil {
-		slog.Error("error binding permission", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error binding permission", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing payload"})
 		return
 	}
 
-	slog.Info("updating service account permission limiter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID), slog.String("permissionID", permissionID))
+	slog.InfoContext(c, "updating service account permission limiter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("serviceAccountID", serviceAccountID), slog.String("permissionID", permissionID))
 
 	err := h.muDBConn.UpdatePermissionLimiter(token.InstanceID, permissionID, newLimiter.Limiter)
 	if err != nil {
-		slog.Error("error updating service account permission limiter", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error updating service account permission limiter", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error updating service account permission limiter"})
 		return
 	}
 
+	emitPrivilegeChange(c, token, securityevents.ACTOR_SERVICE_ACCOUNT, serviceAccountID, map[string]string{"change": "permission-limiter-updated", "permissionID": permissionID})
 	c.JSON(http.StatusOK, gin.H{"message": "permission limiter updated"})
 }
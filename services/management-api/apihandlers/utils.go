@@ -5,19 +5,33 @@ import (
 	"net/http"
 	"strings"
 
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
 	pc "github.com/case-framework/case-backend/pkg/permission-checker"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
+	"github.com/case-framework/case-backend/pkg/study/exporter/manifest"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"github.com/gin-gonic/gin"
 )
 
 func (h *HttpEndpoints) isInstanceAllowed(instanceID string) bool {
-	for _, id := range h.allowedInstanceIDs {
-		if id == instanceID {
-			return true
-		}
-	}
-	return false
+	return h.instances.IsAllowed(instanceID)
+}
+
+// emitPrivilegeChange records a change of the permissions or credentials of a management user or service account in
+// the security event log
+func emitPrivilegeChange(c *gin.Context, token *jwthandling.ManagementUserClaims, targetType string, targetID string, details map[string]string) {
+	securityevents.EmitForRequest(c, securityevents.Event{
+		Type:       securityevents.EVENT_PRIVILEGE_CHANGED,
+		Outcome:    securityevents.OUTCOME_SUCCESS,
+		InstanceID: token.InstanceID,
+		ActorType:  securityevents.ACTOR_MANAGEMENT_USER,
+		ActorID:    token.Subject,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+	})
 }
 
 type RequiredPermission struct {
@@ -62,6 +76,9 @@ func (h *HttpEndpoints) useAuthorisedHandler(
 			requiredPermission.Action,
 			limiterReq,
 		)
+		if accessToken, ok := c.Get(mw.ContextKeyAccessToken); ok && hasPermission {
+			hasPermission = pc.IsInScope(accessToken.(*muDB.AccessToken).Scopes, requiredPermission.ResourceType, rks, requiredPermission.Action)
+		}
 		if !hasPermission {
 			slog.Warn("unauthorised access attempted",
 				slog.String("instanceID", token.InstanceID),
@@ -95,3 +112,14 @@ func (h *HttpEndpoints) onExportTaskFailed(
 		slog.Error("failed to update task status", slog.String("error", err.Error()), slog.String("taskID", taskID))
 	}
 }
+
+// writeExportManifest adds the checksum of the export file and stores the manifest next to it
+func writeExportManifest(exportManifest *manifest.ExportManifest, exportFilePath string) {
+	if err := exportManifest.AddFile(exportFilePath); err != nil {
+		slog.Error("failed to compute export file checksum", slog.String("path", exportFilePath), slog.String("error", err.Error()))
+		return
+	}
+	if err := exportManifest.WriteToFile(manifest.ManifestPathForFile(exportFilePath)); err != nil {
+		slog.Error("failed to write export manifest", slog.String("path", exportFilePath), slog.String("error", err.Error()))
+	}
+}
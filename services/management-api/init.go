@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
@@ -8,9 +9,25 @@ import (
 	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	"github.com/case-framework/case-backend/pkg/cache"
+	configreload "github.com/case-framework/case-backend/pkg/config-reload"
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
 	"github.com/case-framework/case-backend/pkg/db"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	managementalerts "github.com/case-framework/case-backend/pkg/messaging/management-alerts"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
 	"github.com/case-framework/case-backend/pkg/study"
+	"github.com/case-framework/case-backend/pkg/study/exporter/delivery"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
+	"github.com/case-framework/case-backend/pkg/tracing"
 	"github.com/case-framework/case-backend/pkg/utils"
 	"gopkg.in/yaml.v2"
 
@@ -49,12 +66,19 @@ const (
 	ENV_GLOBAL_INFOS_DB_PASSWORD     = "GLOBAL_INFOS_DB_PASSWORD"
 	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
 	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
+	ENV_CACHE_REDIS_PASSWORD         = "CACHE_REDIS_PASSWORD"
 	ENV_STUDY_DB_USERNAME            = "STUDY_DB_USERNAME"
 	ENV_STUDY_DB_PASSWORD            = "STUDY_DB_PASSWORD"
 
 	ENV_STUDY_GLOBAL_SECRET = "STUDY_GLOBAL_SECRET"
 
 	ENV_FILESTORE_PATH = "FILESTORE_PATH"
+
+	ENV_EXPORT_DOWNLOAD_SIGN_KEY = "EXPORT_DOWNLOAD_SIGN_KEY"
+
+	ENV_CONFIDENTIAL_RESPONSE_VAULT_TOKEN = "CONFIDENTIAL_RESPONSE_VAULT_TOKEN"
+
+	ENV_METRICS_BEARER_TOKEN = "METRICS_BEARER_TOKEN"
 )
 
 var (
@@ -63,16 +87,34 @@ var (
 	messagingDBService       *messagingDB.MessagingDBService
 	participantUserDBService *userDB.ParticipantUserDBService
 	globalInfosDBService     *globalinfosDB.GlobalInfosDBService
+
+	secretsResolver *secrets.Resolver
+
+	// problems of settings read from environment variables, reported with the other ones by validateConfig
+	envProblems configvalidation.Problems
 )
 
 type Config struct {
 	// Logging configs
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
 
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
+	// The config file is read again on SIGHUP or when it changes, applying email template constants and the log level
+	ConfigReload configreload.Config `json:"config_reload" yaml:"config_reload"`
+
 	// Gin configs
 	GinDebugMode bool     `json:"gin_debug_mode"`
 	AllowOrigins []string `json:"allow_origins"`
 	Port         string   `json:"port"`
+	// time to finish running requests and background tasks on shutdown, 30s if not set
+	ShutdownTimeout time.Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// Deprecation and sunset dates of the API versions, announced in the response headers
+	APIVersions map[string]apihelpers.APIVersionLifecycle `json:"api_versions" yaml:"api_versions"`
+	// Limits of request bodies, 16 MiB and a JSON depth of 256 if not set. Study bundle imports allow 64 MiB.
+	BodyLimits middlewares.BodyLimitsConfig `json:"body_limits" yaml:"body_limits"`
 
 	// JWT configs
 	ManagementUserJWTSignKey   string        `json:"management_user_jwt_sign_key"`
@@ -80,6 +122,16 @@ type Config struct {
 
 	AllowedInstanceIDs []string `json:"allowed_instance_ids" yaml:"allowed_instance_ids"`
 
+	// Instances can also be provisioned at runtime, the allowed instances are the configured and the active provisioned
+	// ones, reloaded in the given interval
+	InstanceManagement struct {
+		// admins of this instance can manage all instances, empty to disable the instance endpoints
+		SuperAdminInstanceID string `json:"super_admin_instance_id" yaml:"super_admin_instance_id"`
+		// global email templates and layouts of this instance are copied to new instances
+		TemplateSourceInstanceID string        `json:"template_source_instance_id" yaml:"template_source_instance_id"`
+		ReloadInterval           time.Duration `json:"reload_interval" yaml:"reload_interval"`
+	} `json:"instance_management" yaml:"instance_management"`
+
 	// Mutual TLS configs
 	UseMTLS          bool                        `json:"use_mtls"`
 	CertificatePaths apihelpers.CertificatePaths `json:"certificate_paths"`
@@ -93,14 +145,63 @@ type Config struct {
 		StudyDB           db.DBConfigYaml `json:"study_db" yaml:"study_db"`
 	} `json:"db_configs" yaml:"db_configs"`
 
+	// Optional cache of studies, surveys and email templates, disabled if no type is set
+	Cache cache.Config `json:"cache" yaml:"cache"`
+
+	// Prometheus metrics endpoint, disabled by default
+	Metrics metrics.Config `json:"metrics" yaml:"metrics"`
+
+	// OpenTelemetry tracing, exported with OTLP over HTTP, disabled by default
+	Tracing tracing.Config `json:"tracing" yaml:"tracing"`
+
+	// Security event log for SIEM ingestion, disabled if no sinks are set
+	SecurityEvents securityevents.Config `json:"security_events" yaml:"security_events"`
+
+	// Domain events published to message brokers for external data pipelines, disabled if no brokers are set
+	DomainEvents domainevents.Config `json:"domain_events" yaml:"domain_events"`
+
+	// Runner of the work deferred by request handlers, e.g. study actions and exports
+	BackgroundTasks taskrunner.Config `json:"background_tasks" yaml:"background_tasks"`
+
 	// Study module config
 	StudyConfigs struct {
 		GlobalSecret     string                        `json:"global_secret" yaml:"global_secret"`
 		ExternalServices []studyengine.ExternalService `json:"external_services" yaml:"external_services"`
+
+		// Field-level encryption of confidential responses, disabled if no provider is set
+		ConfidentialResponseEncryption fieldencryption.Config `json:"confidential_response_encryption" yaml:"confidential_response_encryption"`
+
+		// Validity of the invitation links sent to participants imported from a CSV file, 7 days if not set
+		ParticipantInvitationTTL time.Duration `json:"participant_invitation_ttl" yaml:"participant_invitation_ttl"`
 	} `json:"study_configs" yaml:"study_configs"`
 
+	// Messaging configs used for template validation, previews and checking attachments
+	MessagingConfigs struct {
+		GlobalEmailTemplateConstants map[string]string                     `json:"global_email_template_constants" yaml:"global_email_template_constants"`
+		EmailAttachments             messagingTypes.EmailAttachmentsConfig `json:"email_attachments" yaml:"email_attachments"`
+		Webhooks                     messagingTypes.WebhookConfig          `json:"webhooks" yaml:"webhooks"`
+		// Ad-hoc campaigns reaching more users need approval by a second user, 0 to disable
+		AdHocCampaignApprovalThreshold int64 `json:"ad_hoc_campaign_approval_threshold" yaml:"ad_hoc_campaign_approval_threshold"`
+	} `json:"messaging_configs" yaml:"messaging_configs"`
+
 	FilestorePath       string `json:"filestore_path" yaml:"filestore_path"`
 	DailyFileExportPath string `json:"daily_file_export_path" yaml:"daily_file_export_path"`
+
+	// Export jobs are produced by a worker in the background, the artifacts are served through signed download links
+	ExportJobs struct {
+		Concurrency  int           `json:"concurrency" yaml:"concurrency"`
+		PollInterval time.Duration `json:"poll_interval" yaml:"poll_interval"`
+		// completed artifacts are removed after this duration
+		ArtifactTTL    time.Duration `json:"artifact_ttl" yaml:"artifact_ttl"`
+		DownloadURLTTL time.Duration `json:"download_url_ttl" yaml:"download_url_ttl"`
+		// key to sign download links, set with EXPORT_DOWNLOAD_SIGN_KEY
+		DownloadSignKey string `json:"download_sign_key" yaml:"download_sign_key"`
+		// destinations of scheduled exports, admins select them by name
+		Destinations []delivery.DestinationConfig `json:"destinations" yaml:"destinations"`
+		// failed deliveries are retried with exponential backoff
+		MaxDeliveryAttempts    int           `json:"max_delivery_attempts" yaml:"max_delivery_attempts"`
+		DeliveryInitialBackoff time.Duration `json:"delivery_initial_backoff" yaml:"delivery_initial_backoff"`
+	} `json:"export_jobs" yaml:"export_jobs"`
 }
 
 func init() {
@@ -112,9 +213,17 @@ func init() {
 	// Override secrets from environment variables
 	secretsOverride()
 
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
 	initDBs()
 
 	initStudyService()
+
+	initMessagingService()
 }
 
 func initDBs() {
@@ -148,6 +257,22 @@ func initDBs() {
 		slog.Error("Error connecting to Global Infos DB", slog.String("error", err.Error()))
 		return
 	}
+
+	initCache()
+}
+
+func initCache() {
+	c, ttl, err := cache.New(conf.Cache)
+	if err != nil {
+		slog.Error("Error initializing cache, continuing without cache", slog.String("error", err.Error()))
+		return
+	}
+	if c == nil {
+		return
+	}
+	slog.Info("Cache enabled", slog.String("type", conf.Cache.Type), slog.Duration("ttl", ttl))
+	studyDBService.SetCache(c, ttl)
+	messagingDBService.SetCache(c, ttl)
 }
 
 func initStudyService() {
@@ -156,21 +281,80 @@ func initStudyService() {
 		conf.StudyConfigs.GlobalSecret,
 		conf.StudyConfigs.ExternalServices,
 	)
-}
+	study.SetParticipantFilestorePath(conf.FilestorePath)
 
-func getAndCheckFilestorePath() string {
-	// To store dynamically generated files
-	fsPath := os.Getenv(ENV_FILESTORE_PATH)
-	if fsPath == "" {
-		slog.Error("Filestore path not set")
-		panic("Filestore path not set")
+	keyProvider, err := fieldencryption.NewKeyProvider(conf.StudyConfigs.ConfidentialResponseEncryption)
+	if err != nil {
+		slog.Error("Error initializing confidential response encryption", slog.String("error", err.Error()))
+		panic(err)
 	}
+	studyDBService.EnableConfidentialResponseEncryption(keyProvider)
+}
 
-	if _, err := os.Stat(fsPath); os.IsNotExist(err) {
-		slog.Error("Filestore path does not exist", slog.String("path", fsPath))
-		panic("Filestore path does not exist")
+func initMessagingService() {
+	emailsending.InitMessageSendingVariables(
+		nil,
+		conf.MessagingConfigs.GlobalEmailTemplateConstants,
+		messagingDBService,
+	)
+	emailsending.InitEmailAttachments(conf.MessagingConfigs.EmailAttachments)
+	webhooks.Init(messagingDBService, conf.MessagingConfigs.Webhooks)
+	managementalerts.Init(muDBService, messagingDBService)
+}
+
+// validateConfig checks the whole config and panics with all problems found. Settings read from environment variables
+// are reported with the variable name.
+func validateConfig() {
+	problems := envProblems
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+
+	problems.Required(ENV_MANAGEMENT_API_LISTEN_PORT, conf.Port)
+	problems.Duration("shutdown_timeout", conf.ShutdownTimeout, time.Second, 0)
+	problems.APIVersions("api_versions", conf.APIVersions, apiVersions)
+	problems.BodyLimits("body_limits", conf.BodyLimits)
+	problems.MTLS(ENV_REQUIRE_MUTUAL_TLS, conf.UseMTLS, conf.CertificatePaths)
+	problems.Required(ENV_MANAGEMENT_USER_JWT_SIGN_KEY, conf.ManagementUserJWTSignKey)
+	problems.Required(ENV_MANAGEMENT_USER_JWT_EXPIRES_IN, os.Getenv(ENV_MANAGEMENT_USER_JWT_EXPIRES_IN))
+	problems.Duration(ENV_MANAGEMENT_USER_JWT_EXPIRES_IN, conf.ManagementUserJWTExpiresIn, time.Minute, 0)
+
+	problems.Duration("instance_management.reload_interval", conf.InstanceManagement.ReloadInterval, time.Second, 0)
+	problems.DB("db_configs.participant_user_db", conf.DBConfigs.ParticipantUserDB)
+	problems.DB("db_configs.management_user_db", conf.DBConfigs.ManagementUserDB)
+	problems.DB("db_configs.global_infos_db", conf.DBConfigs.GlobalInfosDB)
+	problems.DB("db_configs.messaging_db", conf.DBConfigs.MessagingDB)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+
+	problems.Cache("cache", conf.Cache)
+	problems.Tracing("tracing", conf.Tracing)
+	problems.SecurityEvents("security_events", conf.SecurityEvents)
+	problems.DomainEvents("domain_events", conf.DomainEvents)
+	problems.TaskRunner("background_tasks", conf.BackgroundTasks)
+
+	problems.Required(ENV_STUDY_GLOBAL_SECRET, conf.StudyConfigs.GlobalSecret)
+	problems.ExternalServices("study_configs.external_services", conf.StudyConfigs.ExternalServices)
+	problems.Encryption("study_configs.confidential_response_encryption", conf.StudyConfigs.ConfidentialResponseEncryption)
+	problems.Duration("study_configs.participant_invitation_ttl", conf.StudyConfigs.ParticipantInvitationTTL, time.Hour, 0)
+
+	problems.Webhooks("messaging_configs.webhooks", conf.MessagingConfigs.Webhooks)
+	problems.Dir("messaging_configs.email_attachments.filestore_path", conf.MessagingConfigs.EmailAttachments.FilestorePath)
+
+	problems.Required(ENV_FILESTORE_PATH, conf.FilestorePath)
+	problems.Dir(ENV_FILESTORE_PATH, conf.FilestorePath)
+	problems.Dir("daily_file_export_path", conf.DailyFileExportPath)
+
+	problems.NonNegative("export_jobs.concurrency", conf.ExportJobs.Concurrency)
+	problems.Duration("export_jobs.poll_interval", conf.ExportJobs.PollInterval, time.Second, 0)
+	problems.Duration("export_jobs.artifact_ttl", conf.ExportJobs.ArtifactTTL, time.Minute, 0)
+	problems.Duration("export_jobs.download_url_ttl", conf.ExportJobs.DownloadURLTTL, time.Second, 0)
+	problems.NonNegative("export_jobs.max_delivery_attempts", conf.ExportJobs.MaxDeliveryAttempts)
+	problems.Duration("export_jobs.delivery_initial_backoff", conf.ExportJobs.DeliveryInitialBackoff, time.Second, 0)
+	problems.Destinations("export_jobs.destinations", conf.ExportJobs.Destinations)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
 	}
-	return fsPath
 }
 
 func initConfig() Config {
@@ -206,15 +390,15 @@ func initConfig() Config {
 	conf.Port = os.Getenv(ENV_MANAGEMENT_API_LISTEN_PORT)
 	conf.AllowOrigins = strings.Split(os.Getenv(ENV_CORS_ALLOW_ORIGINS), ",")
 
-	conf.FilestorePath = getAndCheckFilestorePath()
+	// To store dynamically generated files, checked in validateConfig
+	conf.FilestorePath = os.Getenv(ENV_FILESTORE_PATH)
 
 	// JWT configs
 	conf.ManagementUserJWTSignKey = os.Getenv(ENV_MANAGEMENT_USER_JWT_SIGN_KEY)
 	expInVal := os.Getenv(ENV_MANAGEMENT_USER_JWT_EXPIRES_IN)
-	conf.ManagementUserJWTExpiresIn, err = utils.ParseDurationString(expInVal)
-	if err != nil {
-		slog.Error("error during initConfig", slog.String("error", err.Error()), ENV_MANAGEMENT_USER_JWT_EXPIRES_IN, expInVal)
-		panic(err)
+	if expInVal != "" {
+		conf.ManagementUserJWTExpiresIn, err = utils.ParseDurationString(expInVal)
+		envProblems.Check(ENV_MANAGEMENT_USER_JWT_EXPIRES_IN, err)
 	}
 
 	// Mutual TLS configs
@@ -227,10 +411,6 @@ func initConfig() Config {
 
 	// Study global secret
 	conf.StudyConfigs.GlobalSecret = os.Getenv(ENV_STUDY_GLOBAL_SECRET)
-	if conf.StudyConfigs.GlobalSecret == "" {
-		slog.Error("Study global secret not set - configure STUDY_GLOBAL_SECRET env variable.")
-		panic("Study global secret not set")
-	}
 
 	// Allowed instance IDs
 	envInstanceIDs := readInstanceIDs()
@@ -240,6 +420,17 @@ func initConfig() Config {
 	return conf
 }
 
+// readConfigFile reads the config file without the settings from the environment, used when the config is reloaded
+func readConfigFile(path string) (Config, error) {
+	c := Config{}
+	yamlFile, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	err = yaml.UnmarshalStrict(yamlFile, &c)
+	return c, err
+}
+
 func readInstanceIDs() []string {
 	instanceIDs := strings.Split(os.Getenv(ENV_INSTANCE_IDS), ",")
 	// filter out empty strings
@@ -252,8 +443,22 @@ func readInstanceIDs() []string {
 	return filteredInstanceIDs
 }
 
+// resolveSecrets replaces secret references in the config, the resolver is kept to detect rotations
+func resolveSecrets() {
+	var err error
+	secretsResolver, err = secrets.Resolve(context.Background(), conf.Secrets, &conf)
+	if err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
+}
+
 func secretsOverride() {
 	// Override secrets from environment variables
+	if password := os.Getenv(ENV_CACHE_REDIS_PASSWORD); password != "" {
+		conf.Cache.Redis.Password = password
+	}
+
 	if dbUsername := os.Getenv(ENV_MANAGEMENT_USER_DB_USERNAME); dbUsername != "" {
 		conf.DBConfigs.ManagementUserDB.Username = dbUsername
 	}
@@ -294,4 +499,16 @@ func secretsOverride() {
 		conf.DBConfigs.StudyDB.Password = dbPassword
 	}
 
+	if vaultToken := os.Getenv(ENV_CONFIDENTIAL_RESPONSE_VAULT_TOKEN); vaultToken != "" {
+		conf.StudyConfigs.ConfidentialResponseEncryption.VaultTransit.Token = vaultToken
+	}
+
+	if signKey := os.Getenv(ENV_EXPORT_DOWNLOAD_SIGN_KEY); signKey != "" {
+		conf.ExportJobs.DownloadSignKey = signKey
+	}
+
+	if token := os.Getenv(ENV_METRICS_BEARER_TOKEN); token != "" {
+		conf.Metrics.BearerToken = token
+	}
+
 }
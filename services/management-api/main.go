@@ -1,11 +1,27 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/apihelpers/health"
+	"github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	configreload "github.com/case-framework/case-backend/pkg/config-reload"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	"github.com/case-framework/case-backend/pkg/instances"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
+	"github.com/case-framework/case-backend/pkg/study/exporter/delivery"
+	exportjobs "github.com/case-framework/case-backend/pkg/study/exporter/export-jobs"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
+	"github.com/case-framework/case-backend/pkg/tracing"
+	"github.com/case-framework/case-backend/pkg/utils"
 	"github.com/case-framework/case-backend/services/management-api/apihandlers"
 
 	"github.com/gin-contrib/cors"
@@ -14,25 +30,91 @@ import (
 
 var conf Config
 
+const (
+	defaultMaxBodySize  = 16 << 20
+	defaultMaxJSONDepth = 256
+)
+
+// API versions served under their URL prefix, the handlers adapt their payloads to apihelpers.RequestAPIVersion
+var apiVersions = []string{"v1", "v2"}
+
 func main() {
+	ctx, stop := apihelpers.ShutdownSignalContext()
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(conf.Tracing, "management-api")
+	if err != nil {
+		slog.Error("failed to init tracing", slog.String("error", err.Error()))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	securityLog, err := securityevents.Init(conf.SecurityEvents, "management-api")
+	if err != nil {
+		slog.Error("failed to init security event log", slog.String("error", err.Error()))
+	} else if securityLog != nil {
+		defer securityLog.Close()
+	}
+
+	domainEvents, err := domainevents.Init(conf.DomainEvents, "management-api")
+	if err != nil {
+		slog.Error("failed to init domain event publisher", slog.String("error", err.Error()))
+	} else if domainEvents != nil {
+		defer domainEvents.Close()
+	}
 
 	// Start webserver
 	router := gin.Default()
+	// handlers log with the gin context, which has to look up the request ID in the request context
+	router.ContextWithFallback = true
+	router.Use(middlewares.RequestID())
+	bodyLimits := conf.BodyLimits.WithDefaults(defaultMaxBodySize, defaultMaxJSONDepth)
+	// configured limits of the same route take precedence
+	bodyLimits.Routes = append(bodyLimits.Routes, middlewares.RouteBodyLimit{Route: "/studies/import", Method: http.MethodPost, MaxBodySize: apihandlers.MAX_STUDY_BUNDLE_SIZE})
+	router.Use(middlewares.BodyLimits(bodyLimits))
 	router.Use(cors.New(cors.Config{
 		// AllowAllOrigins: true,
 		AllowOrigins:     conf.AllowOrigins,
 		AllowMethods:     []string{"POST", "GET", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length"},
-		ExposeHeaders:    []string{"Authorization", "Content-Type", "Content-Length"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length", requestid.HEADER},
+		ExposeHeaders:    []string{"Authorization", "Content-Type", "Content-Length", apihelpers.HEADER_DEPRECATION, apihelpers.HEADER_SUNSET, apihelpers.HEADER_LINK, requestid.HEADER},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
+	metrics.Setup(router, conf.Metrics)
+	router.Use(tracing.Middleware())
 
 	// Add handlers
 	router.GET("/", apihandlers.HealthCheckHandle)
-	v1Root := router.Group("/v1")
+	health.NewChecker(0).
+		Add("managementUserDB", muDBService.Ping).
+		Add("messagingDB", messagingDBService.Ping).
+		Add("studyDB", studyDBService.Ping).
+		Add("participantUserDB", participantUserDBService.Ping).
+		Add("globalInfosDB", globalInfosDBService.Ping).
+		Add("filestore", health.DirProbe(conf.FilestorePath)).
+		AddRoutes(router)
+
+	var instanceSource instances.InstanceSource
+	if globalInfosDBService != nil {
+		instanceSource = globalInfosDBService
+	}
+	instanceRegistry := instances.NewRegistry(conf.AllowedInstanceIDs, instanceSource)
+	if err := instanceRegistry.Reload(); err != nil {
+		slog.Error("failed to load instances", slog.String("error", err.Error()))
+	}
+	go instanceRegistry.Run(ctx, conf.InstanceManagement.ReloadInterval)
+
+	if conf.Metrics.Enabled {
+		metrics.RegisterDBMetrics(metrics.DefaultRegistry)
+		registerEmailQueueMetrics(instanceRegistry.InstanceIDs)
+	}
 
-	v1APIHandlers := apihandlers.NewHTTPHandler(
+	backgroundTasks := taskrunner.New(conf.BackgroundTasks)
+	apiHandlers := apihandlers.NewHTTPHandler(
 		conf.ManagementUserJWTSignKey,
 		conf.ManagementUserJWTExpiresIn,
 		muDBService,
@@ -40,46 +122,160 @@ func main() {
 		studyDBService,
 		participantUserDBService,
 		globalInfosDBService,
-		conf.AllowedInstanceIDs,
+		instanceRegistry,
 		conf.StudyConfigs.GlobalSecret,
 		conf.FilestorePath,
 		conf.DailyFileExportPath,
+		backgroundTasks,
 	)
-	v1APIHandlers.AddManagementAuthAPI(v1Root)
-	v1APIHandlers.AddUserManagementAPI(v1Root)
-	v1APIHandlers.AddMessagingServiceAPI(v1Root)
-	v1APIHandlers.AddStudyManagementAPI(v1Root)
+	// rotated secrets shut the process down gracefully if restart_on_rotation is set
+	go secretsResolver.Watch(ctx, stop)
+	startConfigReload(ctx)
+
+	apiHandlers.SetAdHocCampaignApprovalThreshold(conf.MessagingConfigs.AdHocCampaignApprovalThreshold)
+	apiHandlers.SetExportJobDownloadConfig(conf.ExportJobs.DownloadSignKey, conf.ExportJobs.DownloadURLTTL)
+	apiHandlers.SetInstanceManagementConfig(conf.InstanceManagement.SuperAdminInstanceID, conf.InstanceManagement.TemplateSourceInstanceID)
+	apiHandlers.SetParticipantInvitationTTL(conf.StudyConfigs.ParticipantInvitationTTL)
+
+	exportDestinations, err := delivery.NewDeliverers(conf.ExportJobs.Destinations)
+	if err != nil {
+		panic(err)
+	}
+	destinationTypes := map[string]string{}
+	for _, d := range conf.ExportJobs.Destinations {
+		destinationTypes[d.Name] = d.Type
+	}
+	apiHandlers.SetExportDeliveryDestinations(destinationTypes)
+
+	apihelpers.MountAPIVersions(router, apiVersions, conf.APIVersions, func(version string, root *gin.RouterGroup) {
+		apiHandlers.AddManagementAuthAPI(root)
+		apiHandlers.AddSelfServiceAPI(root)
+		apiHandlers.AddUserManagementAPI(root)
+		apiHandlers.AddMessagingServiceAPI(root)
+		apiHandlers.AddStudyManagementAPI(root)
+		apiHandlers.AddExportDownloadAPI(root)
+		apiHandlers.AddTrashAPI(root)
+		apiHandlers.AddInstanceManagementAPI(root)
+		apiHandlers.AddJobHistoryAPI(root)
+	})
+
+	exportWorker := exportjobs.NewWorker(studyDBService, instanceRegistry.InstanceIDs, exportjobs.WorkerConfig{
+		FilestorePath: conf.FilestorePath,
+		Concurrency:   conf.ExportJobs.Concurrency,
+		PollInterval:  conf.ExportJobs.PollInterval,
+		ArtifactTTL:   conf.ExportJobs.ArtifactTTL,
+
+		Destinations:           exportDestinations,
+		MaxDeliveryAttempts:    conf.ExportJobs.MaxDeliveryAttempts,
+		DeliveryInitialBackoff: conf.ExportJobs.DeliveryInitialBackoff,
+	})
+	exportWorkerDone := make(chan struct{})
+	go func() {
+		exportWorker.Run(ctx)
+		close(exportWorkerDone)
+	}()
 
 	if conf.GinDebugMode {
 		apihelpers.WriteRoutesToFile(router, "management-api-routes.txt")
 	}
 
-	// Start the server
-	slog.Info("Starting Management API on port " + conf.Port)
-	if !conf.UseMTLS {
-		err := router.Run(":" + conf.Port)
-		if err != nil {
-			slog.Error("Exited Management API", slog.String("error", err.Error()))
-			return
-		}
-	} else {
+	server := &http.Server{
+		Addr:    ":" + conf.Port,
+		Handler: router,
+	}
+	certFile, keyFile := "", ""
+	if conf.UseMTLS {
 		// Create tls config for mutual TLS
 		tlsConfig, err := apihelpers.LoadTLSConfig(conf.CertificatePaths)
 		if err != nil {
 			slog.Error("Error loading TLS config.", slog.String("error", err.Error()))
 			return
 		}
+		server.TLSConfig = tlsConfig
+		certFile, keyFile = conf.CertificatePaths.ServerCertPath, conf.CertificatePaths.ServerKeyPath
+	}
+
+	// Start the server, it runs until SIGINT or SIGTERM
+	slog.Info("Starting Management API on port " + conf.Port)
+	shutdownTimeout := apihelpers.ShutdownTimeout(conf.ShutdownTimeout)
+	if err := apihelpers.ServeUntilDone(ctx, server, certFile, keyFile, shutdownTimeout); err != nil {
+		slog.Error("Exited Management API", slog.String("error", err.Error()))
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := backgroundTasks.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("background tasks not finished before shutdown", slog.String("error", err.Error()))
+	}
+	select {
+	case <-exportWorkerDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("export jobs not finished before shutdown")
+	}
+	closeDBServices(shutdownCtx)
+	slog.Info("Management API stopped")
+}
 
-		server := &http.Server{
-			Addr:      ":" + conf.Port,
-			Handler:   router,
-			TLSConfig: tlsConfig,
+// closeDBServices disconnects the DB clients after requests and background tasks are finished
+func closeDBServices(ctx context.Context) {
+	closers := map[string]func(context.Context) error{
+		"managementUserDB":  muDBService.Close,
+		"messagingDB":       messagingDBService.Close,
+		"studyDB":           studyDBService.Close,
+		"participantUserDB": participantUserDBService.Close,
+		"globalInfosDB":     globalInfosDBService.Close,
+	}
+	for name, closeDB := range closers {
+		if err := closeDB(ctx); err != nil {
+			slog.Error("failed to close DB client", slog.String("db", name), slog.String("error", err.Error()))
 		}
+	}
+}
 
-		err = server.ListenAndServeTLS(conf.CertificatePaths.ServerCertPath, conf.CertificatePaths.ServerKeyPath)
-		if err != nil {
-			slog.Error("Exited Management API", slog.String("error", err.Error()))
-			return
+// registerEmailQueueMetrics exports the number of emails waiting to be sent by the messaging job, counted on each
+// scrape
+func registerEmailQueueMetrics(instanceIDs func() []string) {
+	if messagingDBService == nil {
+		return
+	}
+	metrics.DefaultRegistry.NewGaugeFunc("email_queue_depth", "Outgoing emails waiting to be sent by instance and priority.", []string{"instance_id", "priority"}, func() []metrics.Sample {
+		samples := []metrics.Sample{}
+		for _, instanceID := range instanceIDs() {
+			for _, highPrio := range []bool{true, false} {
+				count, err := messagingDBService.CountOutgoingEmails(instanceID, highPrio)
+				if err != nil {
+					slog.Error("failed to count outgoing emails", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+					continue
+				}
+				priority := "normal"
+				if highPrio {
+					priority = "high"
+				}
+				samples = append(samples, metrics.Sample{LabelValues: []string{instanceID, priority}, Value: float64(count)})
+			}
 		}
+		return samples
+	})
+}
+
+// startConfigReload applies the reloadable config sections until the context is done
+func startConfigReload(ctx context.Context) {
+	reloader, err := configreload.New(os.Getenv(ENV_CONFIG_FILE_PATH), conf, readConfigFile,
+		configreload.Section[Config]{
+			Path:  "logging.log_level",
+			Apply: func(c *Config) { utils.SetLogLevel(c.Logging.LogLevel) },
+		},
+		configreload.Section[Config]{
+			Path: "messaging_configs.global_email_template_constants",
+			Apply: func(c *Config) {
+				emailsending.SetGlobalTemplateInfos(c.MessagingConfigs.GlobalEmailTemplateConstants)
+			},
+		},
+	)
+	if err != nil {
+		slog.Error("failed to init config reload", slog.String("error", err.Error()))
+		return
 	}
+	go reloader.Run(ctx, conf.ConfigReload)
 }
@@ -1,20 +1,25 @@
 package apihandlers
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/apihelpers"
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
 	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
 	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
 	"github.com/case-framework/case-backend/pkg/user-management/pwhash"
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
@@ -34,13 +39,16 @@ const (
 func (h *HttpEndpoints) AddParticipantAuthAPI(rg *gin.RouterGroup) {
 	authGroup := rg.Group("/auth")
 	{
-		authGroup.POST("/login", mw.RequirePayload(), h.loginWithEmail)
-		authGroup.POST("/signup", mw.RequirePayload(), h.signupWithEmail)
+		authGroup.POST("/login", metrics.AuthOutcome("participant_login"), mw.RequirePayload(), h.loginWithEmail)
+		authGroup.POST("/signup", metrics.AuthOutcome("participant_signup"), mw.RequirePayload(), h.idempotent(), h.signupWithEmail)
+		authGroup.POST("/signup/deferred", metrics.AuthOutcome("participant_signup_deferred"), mw.RequirePayload(), h.idempotent(), h.signupWithoutPassword)
+		authGroup.POST("/signup/resend-setup", mw.RequirePayload(), h.resendAccountSetup)
+		authGroup.POST("/signup/complete", mw.RequirePayload(), h.completeAccountSetup)
 
-		authGroup.POST("/login-with-temptoken", mw.RequirePayload(), h.loginWithTempToken)
+		authGroup.POST("/login-with-temptoken", metrics.AuthOutcome("participant_login_with_temptoken"), mw.RequirePayload(), h.loginWithTempToken)
 		authGroup.POST("/temptoken-info", mw.RequirePayload(), h.getTempTokenInfo)
 
-		authGroup.POST("/token/renew", mw.RequirePayload(), mw.GetAndValidateParticipantUserJWTWithIgnoringExpiration(h.tokenSignKey), h.refreshToken)
+		authGroup.POST("/token/renew", metrics.AuthOutcome("participant_token_renew"), mw.RequirePayload(), mw.GetAndValidateParticipantUserJWTWithIgnoringExpiration(h.tokenSignKey), h.refreshToken)
 		authGroup.GET("/token/validate", mw.RequirePayload(), mw.GetAndValidateParticipantUserJWT(h.tokenSignKey), h.validateToken)
 		authGroup.GET("/token/revoke", mw.GetAndValidateParticipantUserJWT(h.tokenSignKey), h.revokeRefreshTokens)
 		authGroup.POST("/resend-email-verification", mw.RequirePayload(), mw.GetAndValidateParticipantUserJWT(h.tokenSignKey), h.resendEmailVerification)
@@ -65,20 +73,20 @@ type LoginWithEmailReq struct {
 func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 	var req LoginWithEmailReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
-	if req.Email == "" || req.Password == "" || req.InstanceID == "" {
-		slog.Error("missing required fields")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+	if missing := apihelpers.MissingFields(map[string]string{"email": req.Email, "password": req.Password, "instanceId": req.InstanceID}); len(missing) > 0 {
+		slog.ErrorContext(c, "missing required fields")
+		apihelpers.RespondValidationError(c, "missing required fields", missing...)
 		return
 	}
 
 	if !h.isInstanceAllowed(req.InstanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", req.InstanceID))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_INSTANCE, "invalid instance id")
 		return
 	}
 
@@ -86,20 +94,22 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 
 	user, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.Email)
 	if err != nil {
-		slog.Warn("login attempt with wrong email address", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		slog.WarnContext(c, "login attempt with wrong email address", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_FAILURE, req.InstanceID, "", "unknown-account")
 		randomWait(5, 10)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_CREDENTIALS, "invalid email or password")
 		return
 	}
 
-	if umUtils.HasMoreAttemptsRecently(user.Account.FailedLoginAttempts, allowedPasswordAttempts, loginFailedAttemptWindow) {
-		slog.Warn("login attempt with too many failed attempts", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+	if !h.isSyntheticMonitoringRequest(c, req.Email) && umUtils.HasMoreAttemptsRecently(user.Account.FailedLoginAttempts, allowedPasswordAttempts, loginFailedAttemptWindow) {
+		slog.WarnContext(c, "login attempt with too many failed attempts", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+		emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_FAILURE, req.InstanceID, user.ID.Hex(), "too-many-attempts")
 
 		if err := h.userDBConn.SaveFailedLoginAttempt(req.InstanceID, user.ID.Hex()); err != nil {
-			slog.Error("failed to save failed login attempt", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to save failed login attempt", slog.String("error", err.Error()))
 		}
 		randomWait(5, 10)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_CREDENTIALS, "invalid email or password")
 		return
 	}
 
@@ -108,12 +118,13 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 		if err == nil {
 			err = errors.New("passwords do not match")
 		}
-		slog.Warn("login attempt with wrong password", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		slog.WarnContext(c, "login attempt with wrong password", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_FAILURE, req.InstanceID, user.ID.Hex(), "wrong-password")
 		if err := h.userDBConn.SaveFailedLoginAttempt(req.InstanceID, user.ID.Hex()); err != nil {
-			slog.Error("failed to save failed login attempt", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to save failed login attempt", slog.String("error", err.Error()))
 		}
 		randomWait(5, 10)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_CREDENTIALS, "invalid email or password")
 		return
 	}
 
@@ -125,7 +136,7 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 		user.ID.Hex(),
 		req.InstanceID,
 		mainProfileID,
-		map[string]string{},
+		h.tokenPayloadForUser(user),
 		user.Account.AccountConfirmedAt > 0,
 		nil,
 		otherProfileIDs,
@@ -133,23 +144,23 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 		nil,
 	)
 	if err != nil {
-		slog.Error("failed to generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// generate refresh token
 	renewToken, err := umUtils.GenerateUniqueTokenString()
 	if err != nil {
-		slog.Error("failed to generate renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	err = h.userDBConn.CreateRenewToken(req.InstanceID, user.ID.Hex(), renewToken, 0)
 	if err != nil {
-		slog.Error("failed to save renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to save renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
@@ -162,8 +173,8 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 
 	user, err = h.userDBConn.ReplaceUser(req.InstanceID, user)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
@@ -173,10 +184,11 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 		user.ID.Hex(),
 		userTypes.TOKEN_PURPOSE_PASSWORD_RESET,
 	); err != nil {
-		slog.Error("failed to delete temp tokens", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete temp tokens", slog.String("error", err.Error()))
 	}
 
-	slog.Info("login successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", req.InstanceID))
+	slog.InfoContext(c, "login successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", req.InstanceID))
+	emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_SUCCESS, req.InstanceID, user.ID.Hex(), "")
 
 	user.Account.Password = ""
 	user.Account.VerificationCode = userTypes.VerificationCode{}
@@ -188,7 +200,8 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 			"expiresIn":       h.ttls.AccessToken.Seconds(),
 			"selectedProfile": mainProfileID,
 		},
-		"user": user,
+		"user":        user,
+		"nextActions": h.computeNextActions(req.InstanceID, user),
 	})
 }
 
@@ -198,114 +211,147 @@ type SignupWithEmailReq struct {
 	InstanceID        string `json:"instanceId"`
 	InfoCheck         string `json:"infoCheck"`
 	PreferredLanguage string `json:"preferredLanguage"`
+
+	// optional: temporary participant to be merged into the new account's main profile
+	TempParticipantToken string `json:"tempParticipantToken"`
 }
 
 func (h *HttpEndpoints) signupWithEmail(c *gin.Context) {
 	var req SignupWithEmailReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
-	if req.Email == "" || req.Password == "" || req.InstanceID == "" {
-		slog.Error("missing required fields")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+	if missing := apihelpers.MissingFields(map[string]string{"email": req.Email, "password": req.Password, "instanceId": req.InstanceID}); len(missing) > 0 {
+		slog.ErrorContext(c, "missing required fields")
+		apihelpers.RespondValidationError(c, "missing required fields", missing...)
 		return
 	}
 
 	if req.InfoCheck != "" {
-		slog.Warn("honeypot field filled out", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("infoCheck", req.InfoCheck))
+		slog.WarnContext(c, "honeypot field filled out", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("infoCheck", req.InfoCheck))
 		randomWait(5, 10)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid request"})
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_REQUEST_INVALID, "invalid request")
 		return
 	}
 
 	if !h.isInstanceAllowed(req.InstanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", req.InstanceID))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_INSTANCE, "invalid instance id")
 		return
 	}
 
 	req.Email = umUtils.SanitizeEmail(req.Email)
 
 	if !umUtils.CheckEmailFormat(req.Email) {
-		slog.Error("invalid email format", slog.String("email", req.Email))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email format"})
+		slog.ErrorContext(c, "invalid email format", slog.String("email", req.Email))
+		apihelpers.RespondValidationError(c, "invalid email format", apihelpers.FieldError{Field: "email", Message: "invalid email format"})
 		return
 	}
 
 	if !umUtils.CheckPasswordFormat(req.Password) {
-		slog.Error("invalid password format")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
+		slog.ErrorContext(c, "invalid password format")
+		apihelpers.RespondValidationError(c, "invalid password format", apihelpers.FieldError{Field: "password", Message: "invalid password format"})
 		return
 	}
 
 	if umUtils.IsPasswordOnBlocklist(req.Password) {
-		slog.Error("password on blocklist")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
+		slog.ErrorContext(c, "password on blocklist")
+		apihelpers.RespondValidationError(c, "password on blocklist", apihelpers.FieldError{Field: "password", Message: "password on blocklist"})
 		return
 	}
 
 	if !umUtils.CheckLanguageCode(req.PreferredLanguage) {
-		slog.Error("invalid preferred language code", slog.String("preferredLanguage", req.PreferredLanguage))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid preferred language code"})
+		slog.ErrorContext(c, "invalid preferred language code", slog.String("preferredLanguage", req.PreferredLanguage))
+		apihelpers.RespondValidationError(c, "invalid preferred language code", apihelpers.FieldError{Field: "preferredLanguage", Message: "invalid preferred language code"})
 		return
 	}
 
-	// rate limit
-	newUserCount, err := h.userDBConn.CountRecentlyCreatedUsers(req.InstanceID, signupRateLimitWindow)
-	if err != nil {
-		slog.Error("failed to count new users", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
-	}
-	if newUserCount >= int64(h.maxNewUsersPer5Minute) {
-		slog.Warn("rate limit for new users reached", slog.String("instanceID", req.InstanceID))
-		randomWait(5, 10)
-		c.JSON(http.StatusTooManyRequests, gin.H{"error": "try again later"})
-		return
+	// rate limit (synthetic monitoring probes are exempt)
+	if !h.isSyntheticMonitoringRequest(c, req.Email) {
+		newUserCount, err := h.userDBConn.CountRecentlyCreatedUsers(req.InstanceID, signupRateLimitWindow)
+		if err != nil {
+			slog.ErrorContext(c, "failed to count new users", slog.String("error", err.Error()))
+			apihelpers.RespondInternalError(c, "internal server error")
+			return
+		}
+		if newUserCount >= h.maxNewUsersPer5Minute.Load() {
+			slog.WarnContext(c, "rate limit for new users reached", slog.String("instanceID", req.InstanceID))
+			randomWait(5, 10)
+			apihelpers.RespondError(c, http.StatusTooManyRequests, apihelpers.ERROR_CODE_RATE_LIMITED, "try again later")
+			return
+		}
 	}
 
 	// hash password
 	password, err := pwhash.HashPassword(req.Password)
 	if err != nil {
-		slog.Error("failed to hash password", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to hash password", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+
+	// generate refresh token, saved together with the user
+	renewToken, err := umUtils.GenerateUniqueTokenString()
+	if err != nil {
+		slog.ErrorContext(c, "failed to generate renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// create user
 	newUser := umUtils.InitNewEmailUser(req.Email, password, req.PreferredLanguage)
-	id, err := h.userDBConn.AddUser(req.InstanceID, newUser)
+	id, err := h.userDBConn.CreateUserWithRenewToken(req.InstanceID, newUser, renewToken)
 	if err != nil {
-		slog.Error("failed to create new user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create new user", slog.String("error", err.Error()))
 		randomWait(5, 10)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 
 	}
 	newUser.ID, _ = primitive.ObjectIDFromHex(id)
 
-	// contact verification in go routine
-	go h.prepAndSendEmailVerification(
-		newUser.ID.Hex(),
-		req.InstanceID,
-		req.Email,
-		req.PreferredLanguage,
-		h.ttls.EmailContactVerificationToken,
-		emailTypes.EMAIL_TYPE_REGISTRATION,
-	)
+	h.runInBackground("webhook", func(ctx context.Context) error {
+		webhooks.Publish(req.InstanceID, webhooks.EVENT_PARTICIPANT_SIGNUP, map[string]any{
+			"userId":      id,
+			"accountType": newUser.Account.Type,
+		})
+		return nil
+	})
+	domainevents.Publish(req.InstanceID, domainevents.EVENT_USER_CREATED, map[string]any{
+		"userId":      id,
+		"accountType": newUser.Account.Type,
+	})
+
+	// contact verification in the background
+	h.runInBackground("email-verification", func(ctx context.Context) error {
+		return h.prepAndSendEmailVerification(
+			newUser.ID.Hex(),
+			req.InstanceID,
+			req.Email,
+			req.PreferredLanguage,
+			h.ttls.EmailContactVerificationToken,
+			emailTypes.EMAIL_TYPE_REGISTRATION,
+		)
+	})
 
 	// generate jwt
 	mainProfileID, otherProfileIDs := umUtils.GetMainAndOtherProfiles(newUser)
 
+	if req.TempParticipantToken != "" {
+		if err := h.mergeTempParticipantIntoProfile(c.Request.Context(), req.InstanceID, mainProfileID, req.TempParticipantToken); err != nil {
+			slog.ErrorContext(c, "failed to merge temporary participant into new account", slog.String("instanceID", req.InstanceID), slog.String("userID", newUser.ID.Hex()), slog.String("error", err.Error()))
+		}
+	}
+
 	token, err := jwthandling.GenerateNewParticipantUserToken(
 		h.ttls.AccessToken,
 		newUser.ID.Hex(),
 		req.InstanceID,
 		mainProfileID,
-		map[string]string{},
+		h.tokenPayloadForUser(newUser),
 		newUser.Account.AccountConfirmedAt > 0,
 		nil,
 		otherProfileIDs,
@@ -313,29 +359,13 @@ func (h *HttpEndpoints) signupWithEmail(c *gin.Context) {
 		nil,
 	)
 	if err != nil {
-		slog.Error("failed to generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
-	}
-
-	// generate refresh token
-	renewToken, err := umUtils.GenerateUniqueTokenString()
-	if err != nil {
-		slog.Error("failed to generate renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
-	}
-
-	// generate refresh token
-	err = h.userDBConn.CreateRenewToken(req.InstanceID, newUser.ID.Hex(), renewToken, 0)
-	if err != nil {
-		slog.Error("failed to save renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// return tokens and user
-	slog.Info("signup successful", slog.String("subject", newUser.ID.Hex()), slog.String("instanceID", req.InstanceID))
+	slog.InfoContext(c, "signup successful", slog.String("subject", newUser.ID.Hex()), slog.String("instanceID", req.InstanceID))
 
 	newUser.Account.Password = ""
 	newUser.Account.VerificationCode = userTypes.VerificationCode{}
@@ -347,7 +377,8 @@ func (h *HttpEndpoints) signupWithEmail(c *gin.Context) {
 			"expiresIn":       h.ttls.AccessToken.Seconds(),
 			"selectedProfile": mainProfileID,
 		},
-		"user": newUser,
+		"user":        newUser,
+		"nextActions": h.computeNextActions(req.InstanceID, newUser),
 	})
 }
 
@@ -357,8 +388,8 @@ func (h *HttpEndpoints) getTempTokenInfo(c *gin.Context) {
 		TempToken  string `json:"tempToken"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
@@ -366,24 +397,25 @@ func (h *HttpEndpoints) getTempTokenInfo(c *gin.Context) {
 		req.TempToken, []string{
 			userTypes.TOKEN_PURPOSE_SURVEY_LOGIN,
 			userTypes.TOKEN_PURPOSE_INVITATION,
+			userTypes.TOKEN_PURPOSE_ACCOUNT_SETUP,
 		},
 	)
 	if err != nil {
-		slog.Error("invalid token", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		slog.ErrorContext(c, "invalid token", slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
 	if req.InstanceID != tokenInfos.InstanceID {
-		slog.Error("instanceID does not match", slog.String("instanceID", req.InstanceID), slog.String("tokenInfos.InstanceID", tokenInfos.InstanceID))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		slog.ErrorContext(c, "instanceID does not match", slog.String("instanceID", req.InstanceID), slog.String("tokenInfos.InstanceID", tokenInfos.InstanceID))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
 	if err != nil {
-		slog.Error("failed to get user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve infos"})
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "failed to retrieve infos")
 		return
 	}
 
@@ -400,15 +432,18 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		Password    string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	// it is ok if only one of the two is set
 	if req.AccessToken == "" && req.Password == "" {
-		slog.Error("missing required fields")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+		slog.ErrorContext(c, "missing required fields")
+		apihelpers.RespondValidationError(c, "missing required fields",
+			apihelpers.FieldError{Field: "accessToken", Message: "accessToken or password required"},
+			apihelpers.FieldError{Field: "password", Message: "accessToken or password required"},
+		)
 		return
 	}
 
@@ -421,8 +456,8 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		},
 	)
 	if err != nil {
-		slog.Error("invalid token", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		slog.ErrorContext(c, "invalid token", slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
@@ -430,22 +465,24 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		// validate access token
 		tokenClaims, valid, err := jwthandling.ValidateParticipantUserToken(req.AccessToken, h.tokenSignKey)
 		if err != nil || !valid {
-			slog.Warn("access token not valid")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access token"})
+			slog.WarnContext(c, "access token not valid")
+			emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_FAILURE, tokenInfos.InstanceID, tokenInfos.UserID, "invalid-access-token")
+			apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid access token")
 			return
 		}
 
 		if tokenClaims.Subject != tokenInfos.UserID {
-			slog.Warn("access token does not match user")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access token"})
+			slog.WarnContext(c, "access token does not match user")
+			emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_FAILURE, tokenInfos.InstanceID, tokenInfos.UserID, "access-token-of-other-user")
+			apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid access token")
 			return
 		}
 	}
 
 	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
 	if err != nil {
-		slog.Warn("user not found", slog.String("subject", tokenInfos.UserID), slog.String("instanceID", tokenInfos.InstanceID), slog.String("error", err.Error()))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		slog.WarnContext(c, "user not found", slog.String("subject", tokenInfos.UserID), slog.String("instanceID", tokenInfos.InstanceID), slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
@@ -453,8 +490,9 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		// validate password
 		match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
 		if err != nil || !match {
-			slog.Warn("password not valid")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid password"})
+			slog.WarnContext(c, "password not valid")
+			emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_FAILURE, tokenInfos.InstanceID, user.ID.Hex(), "wrong-password")
+			apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_CREDENTIALS, "invalid password")
 			return
 		}
 	}
@@ -471,7 +509,7 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		user.ID.Hex(),
 		tokenInfos.InstanceID,
 		mainProfileID,
-		map[string]string{},
+		h.tokenPayloadForUser(user),
 		user.Account.AccountConfirmedAt > 0,
 		nil,
 		otherProfileIDs,
@@ -479,24 +517,24 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		lastOTP,
 	)
 	if err != nil {
-		slog.Error("failed to generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// generate refresh token
 	renewToken, err := umUtils.GenerateUniqueTokenString()
 	if err != nil {
-		slog.Error("failed to generate renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// generate refresh token
 	err = h.userDBConn.CreateRenewToken(tokenInfos.InstanceID, user.ID.Hex(), renewToken, 0)
 	if err != nil {
-		slog.Error("failed to save renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to save renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
@@ -509,13 +547,14 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 
 	user, err = h.userDBConn.ReplaceUser(tokenInfos.InstanceID, user)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// return tokens and user
-	slog.Info("login with temptoken successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID)) //
+	slog.InfoContext(c, "login with temptoken successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID)) //
+	emitSecurityEvent(c, securityevents.EVENT_LOGIN, securityevents.OUTCOME_SUCCESS, tokenInfos.InstanceID, user.ID.Hex(), "")
 
 	user.Account.Password = ""
 	user.Account.VerificationCode = userTypes.VerificationCode{}
@@ -541,62 +580,37 @@ func (h *HttpEndpoints) refreshToken(c *gin.Context) {
 
 	var req RefreshTokenReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	// check if user still exists
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Warn("user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		slog.WarnContext(c, "user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
 	// generate new refresh token
 	newRenewToken, err := umUtils.GenerateUniqueTokenString()
 	if err != nil {
-		slog.Error("failed to generate renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
-	// Check if previous token is still valid
-	rt, err := h.userDBConn.FindAndUpdateRenewToken(
+	// check if previous token is still valid, save its successor and update the refresh timestamps of the user
+	newRenewToken, err = h.userDBConn.RotateRenewToken(
 		token.InstanceID,
 		token.Subject,
 		req.RefreshToken,
 		newRenewToken,
 	)
 	if err != nil {
-		slog.Error("failed to find and update renew token", slog.String("error", err.Error()), slog.String("instanceID", token.InstanceID), slog.String("renewToken", req.RefreshToken))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
-	}
-
-	if rt.NextToken == newRenewToken {
-		// this is the first time the refresh token is used
-		err := h.userDBConn.CreateRenewToken(token.InstanceID, token.Subject, newRenewToken, 0)
-		if err != nil {
-			slog.Error("failed to save renew token", slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-			return
-		}
-	} else {
-		newRenewToken = rt.NextToken
-	}
-
-	// update timestamps (last token refresh, reset markeed for deletion, etc.)
-	err = h.userDBConn.UpdateUser(token.InstanceID, token.Subject, bson.M{
-		"$set": bson.M{
-			"timestamps.lastTokenRefresh":  time.Now().Unix(),
-			"timestamps.markedForDeletion": 0,
-		},
-	})
-	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to rotate renew token", slog.String("error", err.Error()), slog.String("instanceID", token.InstanceID), slog.String("renewToken", req.RefreshToken))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
@@ -608,7 +622,7 @@ func (h *HttpEndpoints) refreshToken(c *gin.Context) {
 		user.ID.Hex(),
 		token.InstanceID,
 		mainProfileID,
-		map[string]string{},
+		h.tokenPayloadForUser(user),
 		user.Account.AccountConfirmedAt > 0,
 		nil,
 		otherProfileIDs,
@@ -616,15 +630,15 @@ func (h *HttpEndpoints) refreshToken(c *gin.Context) {
 		token.LastOTPProvided,
 	)
 	if err != nil {
-		slog.Error("failed to generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	user.Account.Password = ""
 	user.Account.VerificationCode = userTypes.VerificationCode{}
 
-	slog.Info("token refreshed", slog.String("subject", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
+	slog.InfoContext(c, "token refreshed", slog.String("subject", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
 
 	c.JSON(http.StatusOK, gin.H{
 		"token": gin.H{
@@ -634,7 +648,8 @@ func (h *HttpEndpoints) refreshToken(c *gin.Context) {
 			"selectedProfile": mainProfileID,
 			"lastOTP":         token.LastOTPProvided,
 		},
-		"user": user,
+		"user":        user,
+		"nextActions": h.computeNextActions(token.InstanceID, user),
 	})
 }
 
@@ -645,8 +660,8 @@ func (h *HttpEndpoints) validateToken(c *gin.Context) {
 	// check if user still exists
 	_, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Warn("user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		slog.WarnContext(c, "user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
@@ -660,29 +675,29 @@ func (h *HttpEndpoints) resendEmailVerification(c *gin.Context) {
 		Email string `json:"email"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Warn("user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		slog.WarnContext(c, "user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
 	ci, found := user.FindContactInfoByTypeAndAddr("email", req.Email)
 	if !found {
-		slog.Warn("email not found", slog.String("email", req.Email))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "email not found"})
+		slog.WarnContext(c, "email not found", slog.String("email", req.Email))
+		apihelpers.RespondValidationError(c, "email not found", apihelpers.FieldError{Field: "email", Message: "email not found"})
 		return
 	}
 
 	if ci.ConfirmationLinkSentAt > time.Now().Unix()-emailVerificationMessageCooldown {
-		slog.Warn("email verification message cooldown", slog.String("email", req.Email))
+		slog.WarnContext(c, "email verification message cooldown", slog.String("email", req.Email))
 		randomWait(5, 10)
-		c.JSON(http.StatusTooManyRequests, gin.H{"error": "try again later"})
+		apihelpers.RespondError(c, http.StatusTooManyRequests, apihelpers.ERROR_CODE_RATE_LIMITED, "try again later")
 		return
 	}
 
@@ -690,20 +705,22 @@ func (h *HttpEndpoints) resendEmailVerification(c *gin.Context) {
 	user.SetContactInfoVerificationSent("email", req.Email)
 	_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// send email
-	go h.prepAndSendEmailVerification(
-		user.ID.Hex(),
-		token.InstanceID,
-		req.Email,
-		user.Account.PreferredLanguage,
-		h.ttls.EmailContactVerificationToken,
-		emailTypes.EMAIL_TYPE_VERIFY_EMAIL,
-	)
+	h.runInBackground("email-verification", func(ctx context.Context) error {
+		return h.prepAndSendEmailVerification(
+			user.ID.Hex(),
+			token.InstanceID,
+			req.Email,
+			user.Account.PreferredLanguage,
+			h.ttls.EmailContactVerificationToken,
+			emailTypes.EMAIL_TYPE_VERIFY_EMAIL,
+		)
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "email sending initiated"})
 }
@@ -713,11 +730,12 @@ func (h *HttpEndpoints) revokeRefreshTokens(c *gin.Context) {
 
 	count, err := h.userDBConn.DeleteRenewTokensForUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("failed to delete renew tokens", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to delete renew tokens", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
-	slog.Debug("deleted renew tokens", slog.Int64("count", count))
+	slog.DebugContext(c, "deleted renew tokens", slog.Int64("count", count))
+	emitSecurityEvent(c, securityevents.EVENT_TOKEN_REVOKED, securityevents.OUTCOME_SUCCESS, token.InstanceID, token.Subject, "")
 	c.JSON(http.StatusOK, gin.H{"message": "tokens revoked"})
 }
 
@@ -726,8 +744,8 @@ func (h *HttpEndpoints) verifyEmail(c *gin.Context) {
 		Token string `json:"token"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_REQUEST_INVALID, "cannot bind request")
 		return
 	}
 
@@ -738,35 +756,35 @@ func (h *HttpEndpoints) verifyEmail(c *gin.Context) {
 		},
 	)
 	if err != nil {
-		slog.Error("invalid token", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		slog.ErrorContext(c, "invalid token", slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
 	if err != nil {
-		slog.Error("failed to get user", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
+		apihelpers.RespondInternalError(c, "failed to get user")
 		return
 	}
 
 	if user.Account.AccountID != tokenInfos.Info["email"] {
-		slog.Error("user does not match token", slog.String("error", "user does not match token"), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user does not match token"})
+		slog.ErrorContext(c, "user does not match token", slog.String("error", "user does not match token"), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "user does not match token")
 		return
 	}
 
 	cType, ok1 := tokenInfos.Info["type"]
 	email, ok2 := tokenInfos.Info["email"]
 	if !ok1 || !ok2 {
-		slog.Error("missing type or email in token infos", slog.String("error", "missing type or email in token infos"), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing type or email in token infos"})
+		slog.ErrorContext(c, "missing type or email in token infos", slog.String("error", "missing type or email in token infos"), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "missing type or email in token infos")
 		return
 	}
 
 	if err := user.ConfirmContactInfo(cType, email); err != nil {
-		slog.Error("failed to confirm contact info", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm contact info"})
+		slog.ErrorContext(c, "failed to confirm contact info", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
+		apihelpers.RespondInternalError(c, "failed to confirm contact info")
 		return
 	}
 
@@ -776,8 +794,8 @@ func (h *HttpEndpoints) verifyEmail(c *gin.Context) {
 
 	_, err = h.userDBConn.ReplaceUser(tokenInfos.InstanceID, user)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
+		apihelpers.RespondInternalError(c, "failed to update user")
 		return
 	}
 
@@ -786,11 +804,11 @@ func (h *HttpEndpoints) verifyEmail(c *gin.Context) {
 	if newExpiration.Before(tokenInfos.Expiration) {
 		err := h.globalInfosDBConn.UpdateTempTokenExpirationTime(tokenInfos.Token, newExpiration)
 		if err != nil {
-			slog.Error("failed to update token expiration time", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
+			slog.ErrorContext(c, "failed to update token expiration time", slog.String("error", err.Error()), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
 		}
 	}
 
-	slog.Info("email verified", slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
+	slog.InfoContext(c, "email verified", slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
 
 	user.Account.Password = ""
 	c.JSON(http.StatusOK, gin.H{"user": user})
@@ -830,9 +848,9 @@ func (h *HttpEndpoints) requestOTP(c *gin.Context) {
 			},
 		)
 		if err != nil {
-			slog.Error("failed to send OTP by email", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to send OTP by email", slog.String("error", err.Error()))
 			randomWait(2, 5)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			apihelpers.RespondInternalError(c, "internal server error")
 			return
 		}
 	case "sms":
@@ -841,16 +859,24 @@ func (h *HttpEndpoints) requestOTP(c *gin.Context) {
 			token.Subject,
 		)
 		if err != nil {
-			slog.Error("failed to send OTP by SMS", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to send OTP by SMS", slog.String("error", err.Error()))
 			randomWait(2, 5)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			apihelpers.RespondInternalError(c, "internal server error")
 			return
 		}
 	default:
-		slog.Error("invalid OTP type", slog.String("type", otpType))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid OTP type"})
+		slog.ErrorContext(c, "invalid OTP type", slog.String("type", otpType))
+		apihelpers.RespondValidationError(c, "invalid OTP type", apihelpers.FieldError{Field: "type", Message: "invalid OTP type"})
 		return
 	}
+	securityevents.EmitForRequest(c, securityevents.Event{
+		Type:       securityevents.EVENT_OTP_ISSUED,
+		Outcome:    securityevents.OUTCOME_SUCCESS,
+		InstanceID: token.InstanceID,
+		ActorType:  securityevents.ACTOR_PARTICIPANT,
+		ActorID:    token.Subject,
+		Details:    map[string]string{"channel": otpType},
+	})
 	c.JSON(http.StatusOK, gin.H{"message": "OTP sent"})
 }
 
@@ -863,22 +889,23 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 
 	var req VerifyOTPReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	count, err := h.userDBConn.CountFailedOtpAttempts(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("failed to count failed otp attempts", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to count failed otp attempts", slog.String("error", err.Error()))
 	}
 	if count >= maxFailedOtpAttempts {
-		slog.Warn("too many failed otp attempts", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+		slog.WarnContext(c, "too many failed otp attempts", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+		emitSecurityEvent(c, securityevents.EVENT_OTP_VERIFICATION, securityevents.OUTCOME_FAILURE, token.InstanceID, token.Subject, "too-many-attempts")
 		if err = h.userDBConn.DeleteOTPs(token.InstanceID, token.Subject); err != nil {
-			slog.Error("failed to delete otps", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to delete otps", slog.String("error", err.Error()))
 		}
 		randomWait(5, 10)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "too many failed otp attempts"})
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_TOO_MANY_ATTEMPTS, "too many failed otp attempts")
 		return
 	}
 
@@ -889,31 +916,41 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 		req.Code,
 	)
 	if err != nil {
-		slog.Warn("failed to verify OTP", slog.String("error", err.Error()))
+		slog.WarnContext(c, "failed to verify OTP", slog.String("error", err.Error()))
+		emitSecurityEvent(c, securityevents.EVENT_OTP_VERIFICATION, securityevents.OUTCOME_FAILURE, token.InstanceID, token.Subject, "invalid-code")
 		if err := h.userDBConn.AddFailedOtpAttempt(token.InstanceID, token.Subject); err != nil {
-			slog.Error("failed to add failed otp attempt", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to add failed otp attempt", slog.String("error", err.Error()))
 		}
 		randomWait(5, 10)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_OTP, "invalid code")
 		return
 	}
 
 	// check if user still exists
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Warn("user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
+		slog.WarnContext(c, "user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
 		randomWait(5, 10)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
+	securityevents.EmitForRequest(c, securityevents.Event{
+		Type:       securityevents.EVENT_OTP_VERIFICATION,
+		Outcome:    securityevents.OUTCOME_SUCCESS,
+		InstanceID: token.InstanceID,
+		ActorType:  securityevents.ACTOR_PARTICIPANT,
+		ActorID:    token.Subject,
+		Details:    map[string]string{"channel": string(otp.Type)},
+	})
+
 	// mark account verified if email otp is valid
 	if otp.Type == userTypes.EmailOTP && user.Account.AccountConfirmedAt == 0 {
 		user.Account.AccountConfirmedAt = time.Now().Unix()
 		_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 		if err != nil {
-			slog.Error("failed to update user", slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
+			apihelpers.RespondInternalError(c, "internal server error")
 			return
 		}
 	}
@@ -924,11 +961,11 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 			// phone number is not confirmed yet
 			err = user.ConfirmPhoneNumber()
 			if err != nil {
-				slog.Error("failed to confirm phone number", slog.String("error", err.Error()))
+				slog.ErrorContext(c, "failed to confirm phone number", slog.String("error", err.Error()))
 			}
 			_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 			if err != nil {
-				slog.Error("failed to update user after confirming phone number", slog.String("error", err.Error()))
+				slog.ErrorContext(c, "failed to update user after confirming phone number", slog.String("error", err.Error()))
 			}
 		}
 	}
@@ -946,7 +983,7 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 		token.Subject,
 		token.InstanceID,
 		mainProfileID,
-		map[string]string{},
+		h.tokenPayloadForUser(user),
 		user.Account.AccountConfirmedAt > 0,
 		nil,
 		otherProfileIDs,
@@ -954,24 +991,24 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 		token.LastOTPProvided,
 	)
 	if err != nil {
-		slog.Error("failed to generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// generate refresh token
 	renewToken, err := umUtils.GenerateUniqueTokenString()
 	if err != nil {
-		slog.Error("failed to generate renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to generate renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	// generate refresh token
 	err = h.userDBConn.CreateRenewToken(token.InstanceID, user.ID.Hex(), renewToken, 0)
 	if err != nil {
-		slog.Error("failed to save renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to save renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
@@ -983,6 +1020,7 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 			"selectedProfile": mainProfileID,
 			"lastOTP":         token.LastOTPProvided,
 		},
-		"user": user,
+		"user":        user,
+		"nextActions": h.computeNextActions(token.InstanceID, user),
 	})
 }
@@ -0,0 +1,378 @@
+package apihandlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	"github.com/case-framework/case-backend/pkg/user-management/pwhash"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DeferredSignupReq struct {
+	Email             string `json:"email"`
+	InstanceID        string `json:"instanceId"`
+	InfoCheck         string `json:"infoCheck"`
+	PreferredLanguage string `json:"preferredLanguage"`
+
+	// optional: temporary participant to be merged into the main profile of the account pending setup, also when the
+	// signup is repeated before the setup is completed. Ignored for accounts that completed the setup.
+	TempParticipantToken string `json:"tempParticipantToken"`
+}
+
+// signupWithoutPassword creates an account from the email address only and sends a link to complete the setup.
+// The response does not reveal if the email address was already registered.
+func (h *HttpEndpoints) signupWithoutPassword(c *gin.Context) {
+	var req DeferredSignupReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
+		return
+	}
+
+	if missing := apihelpers.MissingFields(map[string]string{"email": req.Email, "instanceId": req.InstanceID}); len(missing) > 0 {
+		slog.ErrorContext(c, "missing required fields")
+		apihelpers.RespondValidationError(c, "missing required fields", missing...)
+		return
+	}
+
+	if req.InfoCheck != "" {
+		slog.WarnContext(c, "honeypot field filled out", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("infoCheck", req.InfoCheck))
+		randomWait(5, 10)
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_REQUEST_INVALID, "invalid request")
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", req.InstanceID))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_INSTANCE, "invalid instance id")
+		return
+	}
+
+	req.Email = umUtils.SanitizeEmail(req.Email)
+	if !umUtils.CheckEmailFormat(req.Email) {
+		slog.ErrorContext(c, "invalid email format", slog.String("email", req.Email))
+		apihelpers.RespondValidationError(c, "invalid email format", apihelpers.FieldError{Field: "email", Message: "invalid email format"})
+		return
+	}
+
+	if !umUtils.CheckLanguageCode(req.PreferredLanguage) {
+		slog.ErrorContext(c, "invalid preferred language code", slog.String("preferredLanguage", req.PreferredLanguage))
+		apihelpers.RespondValidationError(c, "invalid preferred language code", apihelpers.FieldError{Field: "preferredLanguage", Message: "invalid preferred language code"})
+		return
+	}
+
+	existingUser, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.Email)
+	if err == nil {
+		if existingUser.IsSetupPending() {
+			// the signup is repeated before the setup was completed, e.g. from another survey
+			h.mergeDeferredSignupTempParticipant(c, req.InstanceID, existingUser, req.TempParticipantToken)
+			h.resendAccountSetupLink(req.InstanceID, existingUser)
+		} else {
+			slog.InfoContext(c, "deferred signup for existing account ignored", slog.String("instanceID", req.InstanceID), slog.String("userID", existingUser.ID.Hex()))
+		}
+		respondSetupLinkSent(c)
+		return
+	}
+
+	newUserCount, err := h.userDBConn.CountRecentlyCreatedUsers(req.InstanceID, signupRateLimitWindow)
+	if err != nil {
+		slog.ErrorContext(c, "failed to count new users", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+	if newUserCount >= h.maxNewUsersPer5Minute.Load() {
+		slog.WarnContext(c, "rate limit for new users reached", slog.String("instanceID", req.InstanceID))
+		randomWait(5, 10)
+		apihelpers.RespondError(c, http.StatusTooManyRequests, apihelpers.ERROR_CODE_RATE_LIMITED, "try again later")
+		return
+	}
+
+	newUser := umUtils.InitNewEmailUser(req.Email, "", req.PreferredLanguage)
+	newUser.Account.SetupPendingSince = time.Now().Unix()
+	newUser.Timestamps.LastLogin = 0
+	newUser.SetContactInfoVerificationSent(userTypes.ACCOUNT_TYPE_EMAIL, req.Email)
+
+	id, err := h.userDBConn.AddUser(req.InstanceID, newUser)
+	if err != nil {
+		slog.ErrorContext(c, "failed to create new user", slog.String("error", err.Error()))
+		randomWait(5, 10)
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+	newUser.ID, _ = primitive.ObjectIDFromHex(id)
+
+	h.runInBackground("webhook", func(ctx context.Context) error {
+		webhooks.Publish(req.InstanceID, webhooks.EVENT_PARTICIPANT_SIGNUP, map[string]any{
+			"userId":      id,
+			"accountType": newUser.Account.Type,
+			"deferred":    true,
+		})
+		return nil
+	})
+	domainevents.Publish(req.InstanceID, domainevents.EVENT_USER_CREATED, map[string]any{
+		"userId":      id,
+		"accountType": newUser.Account.Type,
+		"deferred":    true,
+	})
+
+	h.mergeDeferredSignupTempParticipant(c, req.InstanceID, newUser, req.TempParticipantToken)
+
+	h.runInBackground("token-email", func(ctx context.Context) error {
+		return h.prepTokenAndSendEmail(
+			newUser.ID.Hex(),
+			req.InstanceID,
+			req.Email,
+			req.PreferredLanguage,
+			userTypes.TOKEN_PURPOSE_ACCOUNT_SETUP,
+			h.ttls.AccountSetupToken,
+			emailTypes.EMAIL_TYPE_ACCOUNT_SETUP,
+			nil,
+		)
+	})
+
+	slog.InfoContext(c, "deferred signup successful", slog.String("subject", newUser.ID.Hex()), slog.String("instanceID", req.InstanceID))
+	respondSetupLinkSent(c)
+}
+
+// respondSetupLinkSent responds the same way and after a similar delay whether an account existed or was created, so
+// that the response does not reveal registered email addresses
+func respondSetupLinkSent(c *gin.Context) {
+	randomWait(1, 3)
+	c.JSON(http.StatusOK, gin.H{"message": "setup link sent"})
+}
+
+// mergeDeferredSignupTempParticipant merges the temporary participant of the signup, if any, into the main profile of
+// the account pending setup. Errors are only logged, the signup itself succeeded.
+func (h *HttpEndpoints) mergeDeferredSignupTempParticipant(c *gin.Context, instanceID string, user userTypes.User, tempParticipantToken string) {
+	if tempParticipantToken == "" {
+		return
+	}
+	mainProfileID, _ := umUtils.GetMainAndOtherProfiles(user)
+	if err := h.mergeTempParticipantIntoProfile(c.Request.Context(), instanceID, mainProfileID, tempParticipantToken); err != nil {
+		slog.ErrorContext(c, "failed to merge temporary participant into account pending setup", slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()), slog.String("error", err.Error()))
+	}
+}
+
+func (h *HttpEndpoints) resendAccountSetup(c *gin.Context) {
+	var req struct {
+		Email      string `json:"email"`
+		InstanceID string `json:"instanceId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", req.InstanceID))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_INSTANCE, "invalid instance id")
+		return
+	}
+
+	req.Email = umUtils.SanitizeEmail(req.Email)
+	user, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.Email)
+	if err != nil || !user.IsSetupPending() {
+		// same response as for existing accounts, so account existence is not revealed
+		respondSetupLinkSent(c)
+		return
+	}
+
+	h.resendAccountSetupLink(req.InstanceID, user)
+	respondSetupLinkSent(c)
+}
+
+// resendAccountSetupLink sends a new setup link, unless one was sent within the verification message cooldown
+func (h *HttpEndpoints) resendAccountSetupLink(instanceID string, user userTypes.User) {
+	ci, found := user.FindContactInfoByTypeAndAddr(userTypes.ACCOUNT_TYPE_EMAIL, user.Account.AccountID)
+	if found && ci.ConfirmationLinkSentAt > time.Now().Unix()-emailVerificationMessageCooldown {
+		slog.Warn("account setup message cooldown", slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()))
+		return
+	}
+
+	user.SetContactInfoVerificationSent(userTypes.ACCOUNT_TYPE_EMAIL, user.Account.AccountID)
+	if _, err := h.userDBConn.ReplaceUser(instanceID, user); err != nil {
+		slog.Error("failed to update user", slog.String("error", err.Error()))
+		return
+	}
+
+	// links sent before are invalidated
+	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(instanceID, user.ID.Hex(), userTypes.TOKEN_PURPOSE_ACCOUNT_SETUP); err != nil {
+		slog.Error("failed to delete previous setup tokens", slog.String("error", err.Error()))
+	}
+
+	h.runInBackground("token-email", func(ctx context.Context) error {
+		return h.prepTokenAndSendEmail(
+			user.ID.Hex(),
+			instanceID,
+			user.Account.AccountID,
+			user.Account.PreferredLanguage,
+			userTypes.TOKEN_PURPOSE_ACCOUNT_SETUP,
+			h.ttls.AccountSetupToken,
+			emailTypes.EMAIL_TYPE_ACCOUNT_SETUP,
+			nil,
+		)
+	})
+	slog.Info("account setup link resent", slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()))
+}
+
+// completeAccountSetup sets the password of an account created without password and logs the user in
+func (h *HttpEndpoints) completeAccountSetup(c *gin.Context) {
+	var req struct {
+		Token             string `json:"token"`
+		Password          string `json:"password"`
+		PreferredLanguage string `json:"preferredLanguage"`
+		ProfileAlias      string `json:"profileAlias"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
+		return
+	}
+
+	if missing := apihelpers.MissingFields(map[string]string{"token": req.Token, "password": req.Password}); len(missing) > 0 {
+		slog.ErrorContext(c, "missing required fields")
+		apihelpers.RespondValidationError(c, "missing required fields", missing...)
+		return
+	}
+
+	if !umUtils.CheckPasswordFormat(req.Password) {
+		slog.ErrorContext(c, "invalid password format")
+		apihelpers.RespondValidationError(c, "invalid password format", apihelpers.FieldError{Field: "password", Message: "invalid password format"})
+		return
+	}
+
+	if umUtils.IsPasswordOnBlocklist(req.Password) {
+		slog.ErrorContext(c, "password on blocklist")
+		apihelpers.RespondValidationError(c, "password on blocklist", apihelpers.FieldError{Field: "password", Message: "password on blocklist"})
+		return
+	}
+
+	if req.PreferredLanguage != "" && !umUtils.CheckLanguageCode(req.PreferredLanguage) {
+		slog.ErrorContext(c, "invalid preferred language code", slog.String("preferredLanguage", req.PreferredLanguage))
+		apihelpers.RespondValidationError(c, "invalid preferred language code", apihelpers.FieldError{Field: "preferredLanguage", Message: "invalid preferred language code"})
+		return
+	}
+
+	tokenInfos, err := h.validateTempToken(req.Token, []string{userTypes.TOKEN_PURPOSE_ACCOUNT_SETUP})
+	if err != nil {
+		slog.ErrorContext(c, "invalid token", slog.String("error", err.Error()))
+		randomWait(5, 10)
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
+		return
+	}
+
+	if !user.IsSetupPending() || user.Account.AccountID != tokenInfos.Info["email"] {
+		slog.ErrorContext(c, "account setup not pending or token does not match user", slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", user.ID.Hex()))
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
+		return
+	}
+
+	password, err := pwhash.HashPassword(req.Password)
+	if err != nil {
+		slog.ErrorContext(c, "failed to hash password", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+
+	// the setup link was received by email, so the address is confirmed
+	if err := user.ConfirmContactInfo(userTypes.ACCOUNT_TYPE_EMAIL, user.Account.AccountID); err != nil {
+		slog.ErrorContext(c, "failed to confirm contact info", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+
+	now := time.Now().Unix()
+	user.Account.Password = password
+	user.Account.SetupPendingSince = 0
+	user.Account.AccountConfirmedAt = now
+	if req.PreferredLanguage != "" {
+		user.Account.PreferredLanguage = req.PreferredLanguage
+	}
+	if req.ProfileAlias != "" {
+		for i, p := range user.Profiles {
+			if p.MainProfile {
+				user.Profiles[i].Alias = req.ProfileAlias
+			}
+		}
+	}
+	user.Timestamps.LastPasswordChange = now
+	user.Timestamps.LastLogin = now
+
+	user, err = h.userDBConn.ReplaceUser(tokenInfos.InstanceID, user)
+	if err != nil {
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+
+	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(tokenInfos.InstanceID, user.ID.Hex(), userTypes.TOKEN_PURPOSE_ACCOUNT_SETUP); err != nil {
+		slog.ErrorContext(c, "failed to delete setup tokens", slog.String("error", err.Error()))
+	}
+
+	mainProfileID, otherProfileIDs := umUtils.GetMainAndOtherProfiles(user)
+	token, err := jwthandling.GenerateNewParticipantUserToken(
+		h.ttls.AccessToken,
+		user.ID.Hex(),
+		tokenInfos.InstanceID,
+		mainProfileID,
+		h.tokenPayloadForUser(user),
+		true,
+		nil,
+		otherProfileIDs,
+		h.tokenSignKey,
+		nil,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to generate token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+
+	renewToken, err := umUtils.GenerateUniqueTokenString()
+	if err != nil {
+		slog.ErrorContext(c, "failed to generate renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+
+	err = h.userDBConn.CreateRenewToken(tokenInfos.InstanceID, user.ID.Hex(), renewToken, 0)
+	if err != nil {
+		slog.ErrorContext(c, "failed to save renew token", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
+		return
+	}
+
+	slog.InfoContext(c, "account setup completed", slog.String("subject", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID))
+
+	user.Account.Password = ""
+	user.Account.VerificationCode = userTypes.VerificationCode{}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": gin.H{
+			"accessToken":     token,
+			"refreshToken":    renewToken,
+			"expiresIn":       h.ttls.AccessToken.Seconds(),
+			"selectedProfile": mainProfileID,
+		},
+		"user":        user,
+		"nextActions": h.computeNextActions(tokenInfos.InstanceID, user),
+	})
+}
@@ -0,0 +1,108 @@
+package apihandlers
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/gin-gonic/gin"
+)
+
+// 1x1 transparent gif
+var trackingPixel, _ = base64.StdEncoding.DecodeString("R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7")
+
+func (h *HttpEndpoints) AddEmailEventsAPI(rg *gin.RouterGroup) {
+	emailGroup := rg.Group("/email")
+	{
+		emailGroup.POST("/events/:instanceID/:provider", h.onEmailDeliveryEvents)
+
+		emailGroup.GET("/track/open/:instanceID", h.onEmailOpened)
+		emailGroup.GET("/track/click/:instanceID", h.onEmailLinkClicked)
+	}
+}
+
+// onEmailDeliveryEvents receives bounce and complaint events from email providers
+func (h *HttpEndpoints) onEmailDeliveryEvents(c *gin.Context) {
+	instanceID := c.Param("instanceID")
+	provider := c.Param("provider")
+
+	if !h.isInstanceAllowed(instanceID) {
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	if !emailsending.VerifyWebhookSecret(instanceID, provider, c.Query("secret")) {
+		slog.WarnContext(c, "email event webhook with invalid secret", slog.String("instanceID", instanceID), slog.String("provider", provider))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	events, err := emailsending.ParseDeliveryEvents(provider, c.Request)
+	if err != nil {
+		slog.ErrorContext(c, "failed to parse email events", slog.String("instanceID", instanceID), slog.String("provider", provider), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	for _, event := range events {
+		reason, err := emailsending.OnDeliveryEvent(instanceID, provider, event)
+		if err != nil {
+			slog.ErrorContext(c, "failed to handle email event", slog.String("instanceID", instanceID), slog.String("provider", provider), slog.String("error", err.Error()))
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+
+		count, err := h.userDBConn.MarkContactInfoUndeliverable(instanceID, event.Address, reason)
+		if err != nil {
+			slog.ErrorContext(c, "failed to mark contact info as undeliverable", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+			continue
+		}
+		slog.InfoContext(c, "email address suppressed", slog.String("instanceID", instanceID), slog.String("provider", provider), slog.String("reason", reason), slog.Int64("updatedUsers", count))
+	}
+
+	// acknowledge all events, so that the provider does not retry
+	c.JSON(http.StatusOK, gin.H{"message": "events received"})
+}
+
+func (h *HttpEndpoints) onEmailOpened(c *gin.Context) {
+	instanceID := c.Param("instanceID")
+	campaign := c.Query("c")
+
+	if h.isInstanceAllowed(instanceID) &&
+		emailsending.IsTrackingEnabled(instanceID) &&
+		emailsending.VerifyTrackingSignature(instanceID, campaign, "", c.Query("sig")) {
+		if err := emailsending.OnEmailTrackingEvent(instanceID, campaign, messagingTypes.EMAIL_TRACKING_COUNTER_OPENS); err != nil {
+			slog.ErrorContext(c, "failed to count email open", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		}
+	}
+
+	// always return the image, so that email clients don't show broken images
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/gif", trackingPixel)
+}
+
+func (h *HttpEndpoints) onEmailLinkClicked(c *gin.Context) {
+	instanceID := c.Param("instanceID")
+	campaign := c.Query("c")
+	target := c.Query("url")
+
+	// only redirect to signed targets to avoid acting as open redirect
+	if !h.isInstanceAllowed(instanceID) || !emailsending.VerifyTrackingSignature(instanceID, campaign, target, c.Query("sig")) {
+		slog.WarnContext(c, "email click tracking with invalid signature", slog.String("instanceID", instanceID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid link"})
+		return
+	}
+
+	if emailsending.IsTrackingEnabled(instanceID) {
+		if err := emailsending.OnEmailTrackingEvent(instanceID, campaign, messagingTypes.EMAIL_TRACKING_COUNTER_CLICKS); err != nil {
+			slog.ErrorContext(c, "failed to count email click", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		}
+	}
+
+	c.Redirect(http.StatusFound, target)
+}
@@ -2,12 +2,16 @@ package apihandlers
 
 import (
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
 	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
 	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	"github.com/case-framework/case-backend/pkg/instances"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,9 +19,21 @@ func HealthCheckHandle(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// Route groups with ETags, their Cache-Control header can be configured
+const (
+	CACHE_GROUP_STUDIES = "studies"
+	CACHE_GROUP_SURVEYS = "surveys"
+)
+
+var CacheGroups = []string{CACHE_GROUP_STUDIES, CACHE_GROUP_SURVEYS}
+
+// AuthenticatedCacheGroups only contain routes with per-user responses, shared caches must not store them
+var AuthenticatedCacheGroups = []string{CACHE_GROUP_SURVEYS}
+
 type TTLs struct {
 	AccessToken                   time.Duration
 	EmailContactVerificationToken time.Duration
+	AccountSetupToken             time.Duration
 }
 
 type HttpEndpoints struct {
@@ -26,11 +42,23 @@ type HttpEndpoints struct {
 	globalInfosDBConn     *globalinfosDB.GlobalInfosDBService
 	messagingDBConn       *messagingDB.MessagingDBService
 	tokenSignKey          string
-	allowedInstanceIDs    []string
+	instances             *instances.Registry
 	globalStudySecret     string
 	filestorePath         string
-	maxNewUsersPer5Minute int
+	maxNewUsersPer5Minute atomic.Int64 // can be changed on config reload
 	ttls                  TTLs
+	nextActionHints       NextActionHintsConfig
+	syntheticMonitoring   SyntheticMonitoringConfig
+	idempotency           mw.IdempotencyConfig
+	cacheControl          map[string]string
+
+	// work deferred by handlers, e.g. sending emails, drained on shutdown
+	backgroundTasks *taskrunner.Runner
+}
+
+// runInBackground queues the task, it is retried if it returns an error
+func (h *HttpEndpoints) runInBackground(name string, task taskrunner.Task) {
+	h.backgroundTasks.Go(name, task)
 }
 
 func NewHTTPHandler(
@@ -39,22 +67,54 @@ func NewHTTPHandler(
 	userDBConn *userDB.ParticipantUserDBService,
 	globalInfosDBConn *globalinfosDB.GlobalInfosDBService,
 	messagingDBConn *messagingDB.MessagingDBService,
-	allowedInstanceIDs []string,
+	instanceRegistry *instances.Registry,
 	globalStudySecret string,
 	filestorePath string,
 	maxNewUsersPer5Minute int,
 	ttls TTLs,
+	nextActionHints NextActionHintsConfig,
+	syntheticMonitoring SyntheticMonitoringConfig,
+	idempotency mw.IdempotencyConfig,
+	cacheControl map[string]string,
+	backgroundTasks *taskrunner.Runner,
 ) *HttpEndpoints {
-	return &HttpEndpoints{
-		tokenSignKey:          tokenSignKey,
-		studyDBConn:           studyDBConn,
-		userDBConn:            userDBConn,
-		globalInfosDBConn:     globalInfosDBConn,
-		messagingDBConn:       messagingDBConn,
-		allowedInstanceIDs:    allowedInstanceIDs,
-		globalStudySecret:     globalStudySecret,
-		filestorePath:         filestorePath,
-		maxNewUsersPer5Minute: maxNewUsersPer5Minute,
-		ttls:                  ttls,
+	h := &HttpEndpoints{
+		tokenSignKey:        tokenSignKey,
+		studyDBConn:         studyDBConn,
+		userDBConn:          userDBConn,
+		globalInfosDBConn:   globalInfosDBConn,
+		messagingDBConn:     messagingDBConn,
+		instances:           instanceRegistry,
+		globalStudySecret:   globalStudySecret,
+		filestorePath:       filestorePath,
+		ttls:                ttls,
+		nextActionHints:     nextActionHints,
+		syntheticMonitoring: syntheticMonitoring,
+		idempotency:         idempotency,
+		cacheControl:        cacheControl,
+		backgroundTasks:     backgroundTasks,
 	}
+	h.SetMaxNewUsersPer5Minutes(maxNewUsersPer5Minute)
+	return h
+}
+
+// idempotent replays the stored response for retries of the request with the same Idempotency-Key header
+func (h *HttpEndpoints) idempotent() gin.HandlerFunc {
+	if h.globalInfosDBConn == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	return mw.Idempotency(h.globalInfosDBConn, h.idempotency)
+}
+
+// cached adds ETags to the responses, so that clients can revalidate unchanged content instead of downloading it.
+// Responses of authenticated requests are kept private.
+func (h *HttpEndpoints) cached(group string) gin.HandlerFunc {
+	return mw.ETag(h.cacheControl[group])
+}
+
+// SetMaxNewUsersPer5Minutes changes the signup limit per instance, e.g. when the config is reloaded
+func (h *HttpEndpoints) SetMaxNewUsersPer5Minutes(limit int) {
+	h.maxNewUsersPer5Minute.Store(int64(limit))
 }
@@ -0,0 +1,70 @@
+package apihandlers
+
+import (
+	"log/slog"
+
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+)
+
+const (
+	NEXT_ACTION_VERIFY_EMAIL = "verify-email"
+	NEXT_ACTION_ENROLL_2FA   = "enroll-2fa"
+)
+
+type NextActionHintsConfig struct {
+	Enabled          bool   `json:"enabled" yaml:"enabled"`
+	Require2FA       bool   `json:"require_2fa" yaml:"require_2fa"`
+	ReconsentFlagKey string `json:"reconsent_flag_key" yaml:"reconsent_flag_key"`
+	IncludeStudies   bool   `json:"include_studies" yaml:"include_studies"`
+}
+
+type NextAction struct {
+	Type      string `json:"type"`
+	StudyKey  string `json:"studyKey,omitempty"`
+	ProfileID string `json:"profileID,omitempty"`
+	SurveyKey string `json:"surveyKey,omitempty"`
+}
+
+// computeNextActions derives hints for the frontend on where to route the user after login or token refresh
+func (h *HttpEndpoints) computeNextActions(instanceID string, user userTypes.User) []NextAction {
+	actions := []NextAction{}
+	if !h.nextActionHints.Enabled {
+		return actions
+	}
+
+	if user.Account.AccountConfirmedAt <= 0 {
+		actions = append(actions, NextAction{Type: NEXT_ACTION_VERIFY_EMAIL})
+	}
+
+	if h.nextActionHints.Require2FA {
+		phone, err := user.GetPhoneNumber()
+		if err != nil || phone.ConfirmedAt <= 0 {
+			actions = append(actions, NextAction{Type: NEXT_ACTION_ENROLL_2FA})
+		}
+	}
+
+	if !h.nextActionHints.IncludeStudies {
+		return actions
+	}
+
+	profileIDs := make([]string, len(user.Profiles))
+	for i, p := range user.Profiles {
+		profileIDs[i] = p.ID.Hex()
+	}
+
+	studyActions, err := studyService.GetStudyNextActions(instanceID, profileIDs, h.nextActionHints.ReconsentFlagKey)
+	if err != nil {
+		slog.Error("failed to compute study next actions", slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()), slog.String("error", err.Error()))
+		return actions
+	}
+	for _, a := range studyActions {
+		actions = append(actions, NextAction{
+			Type:      a.Type,
+			StudyKey:  a.StudyKey,
+			ProfileID: a.ProfileID,
+			SurveyKey: a.SurveyKey,
+		})
+	}
+	return actions
+}
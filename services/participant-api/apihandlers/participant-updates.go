@@ -0,0 +1,137 @@
+package apihandlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	participantUpdatesAuthTimeout  = 10 * time.Second
+	participantUpdatesPollInterval = 30 * time.Second
+)
+
+const (
+	PARTICIPANT_UPDATE_TYPE_SNAPSHOT      = "snapshot"
+	PARTICIPANT_UPDATE_TYPE_TOKEN_EXPIRED = "tokenExpired"
+	PARTICIPANT_UPDATE_TYPE_ERROR         = "error"
+)
+
+// participantUpdatesAuthMsg is the first message the client has to send after connecting
+type participantUpdatesAuthMsg struct {
+	Token      string   `json:"token"`
+	ProfileIDs []string `json:"profileIDs"`
+}
+
+type participantUpdateMsg struct {
+	Type            string                                 `json:"type"`
+	AssignedSurveys *studyService.AssignedSurveysWithInfos `json:"assignedSurveys,omitempty"`
+	Messages        []studyService.ParticipantMessages     `json:"messages,omitempty"`
+	Error           string                                 `json:"error,omitempty"`
+}
+
+// participantUpdatesWS pushes the assigned surveys and messages of the profiles whenever they change.
+// Browsers cannot set headers for WebSocket connections, so the token is sent with the first message instead of the Authorization header.
+func (h *HttpEndpoints) participantUpdatesWS(c *gin.Context) {
+	studyKey := c.Param("studyKey")
+
+	server := websocket.Server{
+		// no cookie based auth is used, so connections from other origins cannot act on behalf of the user
+		Handshake: func(config *websocket.Config, r *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			h.handleParticipantUpdates(ws, studyKey)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func (h *HttpEndpoints) handleParticipantUpdates(ws *websocket.Conn, studyKey string) {
+	var auth participantUpdatesAuthMsg
+	_ = ws.SetReadDeadline(time.Now().Add(participantUpdatesAuthTimeout))
+	if err := websocket.JSON.Receive(ws, &auth); err != nil {
+		slog.Debug("no auth message received on participant updates connection", slog.String("error", err.Error()))
+		return
+	}
+	_ = ws.SetReadDeadline(time.Time{})
+
+	token, ok, err := jwthandling.ValidateParticipantUserToken(auth.Token, h.tokenSignKey)
+	if err != nil || !ok {
+		slog.Warn("token validation failed for participant updates")
+		_ = websocket.JSON.Send(ws, participantUpdateMsg{Type: PARTICIPANT_UPDATE_TYPE_ERROR, Error: "error during token validation"})
+		return
+	}
+
+	if !h.isInstanceAllowed(token.InstanceID) || len(auth.ProfileIDs) < 1 ||
+		!h.checkAllProfilesBelongsToUser(token.InstanceID, token.Subject, auth.ProfileIDs) {
+		slog.Warn("invalid profiles for participant updates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+		_ = websocket.JSON.Send(ws, participantUpdateMsg{Type: PARTICIPANT_UPDATE_TYPE_ERROR, Error: "at least one profile did not belong to the user"})
+		return
+	}
+
+	// connection lives until the token expires, the client disconnects or the server shuts down
+	streamCtx, stopStream := apihelpers.StreamContext(ws.Request())
+	defer stopStream()
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if token.ExpiresAt != nil {
+		ctx, cancel = context.WithDeadline(streamCtx, token.ExpiresAt.Time)
+	} else {
+		ctx, cancel = context.WithCancel(streamCtx)
+	}
+	defer cancel()
+
+	go func() {
+		var msg string
+		for {
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	sendSnapshot := func() {
+		if err := websocket.JSON.Send(ws, h.participantUpdateSnapshot(token.InstanceID, studyKey, auth.ProfileIDs)); err != nil {
+			cancel()
+		}
+	}
+
+	slog.Debug("participant updates connection opened", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	sendSnapshot()
+	err = studyService.WatchParticipantUpdates(ctx, token.InstanceID, studyKey, auth.ProfileIDs, participantUpdatesPollInterval, sendSnapshot)
+	if err == context.DeadlineExceeded {
+		_ = websocket.JSON.Send(ws, participantUpdateMsg{Type: PARTICIPANT_UPDATE_TYPE_TOKEN_EXPIRED})
+	} else if err != nil && err != context.Canceled {
+		slog.Error("error watching participant updates", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		_ = websocket.JSON.Send(ws, participantUpdateMsg{Type: PARTICIPANT_UPDATE_TYPE_ERROR, Error: "error watching participant updates"})
+	}
+}
+
+func (h *HttpEndpoints) participantUpdateSnapshot(instanceID string, studyKey string, profileIDs []string) participantUpdateMsg {
+	surveys, err := studyService.GetAssignedSurveys(instanceID, studyKey, profileIDs)
+	if err != nil {
+		slog.Error("error getting assigned surveys", slog.String("error", err.Error()))
+		return participantUpdateMsg{Type: PARTICIPANT_UPDATE_TYPE_ERROR, Error: "error getting assigned surveys"}
+	}
+
+	messages, err := studyService.GetParticipantMessages(instanceID, studyKey, profileIDs)
+	if err != nil {
+		slog.Error("error getting participant messages", slog.String("error", err.Error()))
+		return participantUpdateMsg{Type: PARTICIPANT_UPDATE_TYPE_ERROR, Error: "error getting participant messages"}
+	}
+
+	return participantUpdateMsg{
+		Type:            PARTICIPANT_UPDATE_TYPE_SNAPSHOT,
+		AssignedSurveys: &surveys,
+		Messages:        messages,
+	}
+}
@@ -0,0 +1,252 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/db"
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	"github.com/case-framework/case-backend/pkg/instances"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+)
+
+// startParticipantUpdatesServer serves the participant updates endpoint with ServeUntilDone, stop shuts the server
+// down and returns an error if the shutdown waited for the drain timeout
+func startParticipantUpdatesServer(t *testing.T, h *HttpEndpoints) (url string, stop func() error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/participant-updates/:studyKey", h.participantUpdatesWS)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- apihelpers.ServeUntilDone(ctx, &http.Server{Addr: addr, Handler: router}, "", "", 5*time.Second)
+	}()
+	t.Cleanup(cancel)
+
+	stop = func() error {
+		start := time.Now()
+		cancel()
+		select {
+		case err := <-served:
+			if err != nil {
+				return err
+			}
+			if time.Since(start) > 2*time.Second {
+				return errors.New("shutdown waited for the open connection")
+			}
+			return nil
+		case <-time.After(10 * time.Second):
+			return errors.New("server did not stop")
+		}
+	}
+	return "ws://" + addr + "/participant-updates/", stop
+}
+
+// dialParticipantUpdates connects and sends the auth message, retrying until the server listens
+func dialParticipantUpdates(t *testing.T, url string, auth participantUpdatesAuthMsg) *websocket.Conn {
+	t.Helper()
+
+	var ws *websocket.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		ws, err = websocket.Dial(url, "", "http://localhost/")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	if err := websocket.JSON.Send(ws, auth); err != nil {
+		t.Fatal(err)
+	}
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return ws
+}
+
+func receiveParticipantUpdate(t *testing.T, ws *websocket.Conn) participantUpdateMsg {
+	t.Helper()
+
+	var msg participantUpdateMsg
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	return msg
+}
+
+func expectParticipantUpdatesClosed(t *testing.T, ws *websocket.Conn) {
+	t.Helper()
+
+	var msg participantUpdateMsg
+	if err := websocket.JSON.Receive(ws, &msg); err == nil {
+		t.Errorf("expected closed connection, got %+v", msg)
+	}
+}
+
+func TestParticipantUpdatesAuth(t *testing.T) {
+	signKey := "test-sign-key"
+	h := &HttpEndpoints{tokenSignKey: signKey, instances: instances.NewRegistry([]string{"instance1"}, nil)}
+	url, stop := startParticipantUpdatesServer(t, h)
+
+	validToken := func(instanceID string) string {
+		token, err := jwthandling.GenerateNewParticipantUserToken(time.Minute, "user1", instanceID, "profile1", nil, true, nil, nil, signKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	otherKeyToken, err := jwthandling.GenerateNewParticipantUserToken(time.Minute, "user1", "instance1", "profile1", nil, true, nil, nil, "other-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		auth    participantUpdatesAuthMsg
+		wantErr string
+	}{
+		{name: "invalid token", auth: participantUpdatesAuthMsg{Token: "invalid", ProfileIDs: []string{"profile1"}}, wantErr: "error during token validation"},
+		{name: "token of another key", auth: participantUpdatesAuthMsg{Token: otherKeyToken, ProfileIDs: []string{"profile1"}}, wantErr: "error during token validation"},
+		{name: "unknown instance", auth: participantUpdatesAuthMsg{Token: validToken("instance2"), ProfileIDs: []string{"profile1"}}, wantErr: "at least one profile did not belong to the user"},
+		{name: "no profiles", auth: participantUpdatesAuthMsg{Token: validToken("instance1")}, wantErr: "at least one profile did not belong to the user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := dialParticipantUpdates(t, url+"study1", tt.auth)
+			msg := receiveParticipantUpdate(t, ws)
+			if msg.Type != PARTICIPANT_UPDATE_TYPE_ERROR || msg.Error != tt.wantErr {
+				t.Errorf("expected error %q, got %+v", tt.wantErr, msg)
+			}
+			expectParticipantUpdatesClosed(t, ws)
+		})
+	}
+
+	if err := stop(); err != nil {
+		t.Error(err)
+	}
+}
+
+// needs a MongoDB instance, e.g. TEST_MONGODB_URI=mongodb://localhost:27017
+func TestParticipantUpdatesConnection(t *testing.T) {
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGODB_URI not set")
+	}
+
+	instanceID := "test"
+	studyKey := "study1"
+	signKey := "test-sign-key"
+	dbConfig := db.DBConfig{
+		URI:          uri,
+		DBNamePrefix: fmt.Sprintf("participant_updates_test_%d_", time.Now().UnixNano()),
+		Timeout:      10,
+		InstanceIDs:  []string{instanceID},
+	}
+	userDBService, err := userDB.NewParticipantUserDBService(dbConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	studyDBService, err := studyDB.NewStudyDBService(dbConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = userDBService.DBClient.Database(dbConfig.DBNamePrefix + instanceID + "_users").Drop(context.Background())
+		_ = studyDBService.DBClient.Database(dbConfig.DBNamePrefix + instanceID + "_studyDB").Drop(context.Background())
+		_ = userDBService.DBClient.Disconnect(context.Background())
+		_ = studyDBService.DBClient.Disconnect(context.Background())
+	})
+	studyService.Init(studyDBService, "global-secret", nil)
+
+	study := studyTypes.Study{Key: studyKey, SecretKey: "study-secret", Status: studyTypes.STUDY_STATUS_ACTIVE}
+	if err := studyDBService.CreateStudy(instanceID, study); err != nil {
+		t.Fatal(err)
+	}
+	user := umUtils.InitNewEmailUser("participant@example.com", "", "en")
+	userID, err := userDBService.AddUser(instanceID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileID := user.Profiles[0].ID.Hex()
+	participantID, _, err := studyService.ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := studyDBService.SaveParticipantState(instanceID, studyKey, studyTypes.Participant{ParticipantID: participantID, StudyStatus: studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &HttpEndpoints{tokenSignKey: signKey, userDBConn: userDBService, instances: instances.NewRegistry([]string{instanceID}, nil)}
+	connect := func(t *testing.T, url string, expiresIn time.Duration) *websocket.Conn {
+		token, err := jwthandling.GenerateNewParticipantUserToken(expiresIn, userID, instanceID, profileID, nil, true, nil, nil, signKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ws := dialParticipantUpdates(t, url+studyKey, participantUpdatesAuthMsg{Token: token, ProfileIDs: []string{profileID}})
+		if msg := receiveParticipantUpdate(t, ws); msg.Type != PARTICIPANT_UPDATE_TYPE_SNAPSHOT || msg.AssignedSurveys == nil || len(msg.Messages) != 1 {
+			t.Fatalf("expected snapshot, got %+v", msg)
+		}
+		return ws
+	}
+
+	t.Run("server shutdown", func(t *testing.T) {
+		url, stop := startParticipantUpdatesServer(t, h)
+		ws := connect(t, url, time.Hour)
+
+		if err := stop(); err != nil {
+			t.Error(err)
+		}
+		expectParticipantUpdatesClosed(t, ws)
+	})
+
+	t.Run("token expiry", func(t *testing.T) {
+		url, stop := startParticipantUpdatesServer(t, h)
+		ws := connect(t, url, 2*time.Second)
+
+		if msg := receiveParticipantUpdate(t, ws); msg.Type != PARTICIPANT_UPDATE_TYPE_TOKEN_EXPIRED {
+			t.Errorf("expected token expiry, got %+v", msg)
+		}
+		expectParticipantUpdatesClosed(t, ws)
+		if err := stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("client disconnect", func(t *testing.T) {
+		url, stop := startParticipantUpdatesServer(t, h)
+		ws := connect(t, url, time.Hour)
+
+		ws.Close()
+		// the connection is released without the server shutdown having to cancel it
+		time.Sleep(100 * time.Millisecond)
+		if err := stop(); err != nil {
+			t.Error(err)
+		}
+	})
+}
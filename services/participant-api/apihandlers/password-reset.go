@@ -1,11 +1,14 @@
 package apihandlers
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/case-framework/case-backend/pkg/apihelpers"
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
 	"github.com/case-framework/case-backend/pkg/user-management/pwhash"
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
@@ -37,13 +40,13 @@ func (h *HttpEndpoints) initiatePasswordReset(c *gin.Context) {
 		InstanceID string `json:"instanceID"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("bad request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "bad request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 	if !h.isInstanceAllowed(req.InstanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", req.InstanceID))
+		apihelpers.RespondError(c, http.StatusUnauthorized, apihelpers.ERROR_CODE_INVALID_INSTANCE, "invalid instance id")
 		return
 	}
 
@@ -51,40 +54,45 @@ func (h *HttpEndpoints) initiatePasswordReset(c *gin.Context) {
 
 	user, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.Email)
 	if err != nil {
-		slog.Warn("password reset for non-existing user", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		slog.WarnContext(c, "password reset for non-existing user", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		emitSecurityEvent(c, securityevents.EVENT_PASSWORD_RESET_REQUESTED, securityevents.OUTCOME_FAILURE, req.InstanceID, "", "unknown-account")
 		randomWait(5, 10)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	if umUtils.HasMoreAttemptsRecently(user.Account.PasswordResetTriggers, PASSWWORD_RESET_MAX_ATTEMPTS, passwordResetAttemptWindow) {
-		slog.Warn("password reset rate limited", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+		slog.WarnContext(c, "password reset rate limited", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+		emitSecurityEvent(c, securityevents.EVENT_PASSWORD_RESET_REQUESTED, securityevents.OUTCOME_FAILURE, req.InstanceID, user.ID.Hex(), "too-many-attempts")
 		randomWait(5, 10)
-		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limited"})
+		apihelpers.RespondError(c, http.StatusTooManyRequests, apihelpers.ERROR_CODE_RATE_LIMITED, "rate limited")
 		return
 	}
 
-	go h.prepTokenAndSendEmail(
-		user.ID.Hex(),
-		req.InstanceID,
-		user.Account.AccountID,
-		user.Account.PreferredLanguage,
-		userTypes.TOKEN_PURPOSE_PASSWORD_RESET,
-		PASSWORD_RESET_TOKEN_TTL,
-		emailTypes.EMAIL_TYPE_PASSWORD_RESET,
-		map[string]string{
-			"validUntil": "24",
-		},
-	)
+	h.runInBackground("token-email", func(ctx context.Context) error {
+		return h.prepTokenAndSendEmail(
+			user.ID.Hex(),
+			req.InstanceID,
+			user.Account.AccountID,
+			user.Account.PreferredLanguage,
+			userTypes.TOKEN_PURPOSE_PASSWORD_RESET,
+			PASSWORD_RESET_TOKEN_TTL,
+			emailTypes.EMAIL_TYPE_PASSWORD_RESET,
+			map[string]string{
+				"validUntil": "24",
+			},
+		)
+	})
 
 	if err := h.userDBConn.SavePasswordResetTrigger(
 		req.InstanceID,
 		user.ID.Hex(),
 	); err != nil {
-		slog.Error("failed to save password reset trigger", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to save password reset trigger", slog.String("error", err.Error()))
 	}
 
-	slog.Info("password reset initiated", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+	slog.InfoContext(c, "password reset initiated", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+	emitSecurityEvent(c, securityevents.EVENT_PASSWORD_RESET_REQUESTED, securityevents.OUTCOME_SUCCESS, req.InstanceID, user.ID.Hex(), "")
 	randomWait(1, 4) // to discourage click-flooding
 	c.JSON(http.StatusOK, gin.H{"message": "password reset initiated"})
 }
@@ -94,13 +102,13 @@ func (h *HttpEndpoints) getPasswordResetInfos(c *gin.Context) {
 		Token string `json:"token"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("bad request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "bad request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	if req.Token == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		apihelpers.RespondValidationError(c, "token is required", apihelpers.FieldError{Field: "token", Message: "required"})
 		return
 	}
 
@@ -110,16 +118,16 @@ func (h *HttpEndpoints) getPasswordResetInfos(c *gin.Context) {
 			userTypes.TOKEN_PURPOSE_INVITATION,
 		})
 	if err != nil {
-		slog.Error("invalid token", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "invalid token", slog.String("error", err.Error()))
 		randomWait(5, 10)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
 	if err != nil {
-		slog.Error("failed to get user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
@@ -134,26 +142,26 @@ func (h *HttpEndpoints) resetPassword(c *gin.Context) {
 		NewPassword string `json:"newPassword"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("missing or invalid request", slog.String("error", err.Error()))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		slog.ErrorContext(c, "missing or invalid request", slog.String("error", err.Error()))
+		apihelpers.RespondBindError(c, err)
 		return
 	}
 
 	if req.Token == "" {
 		randomWait(5, 10)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		apihelpers.RespondValidationError(c, "token is required", apihelpers.FieldError{Field: "token", Message: "required"})
 		return
 	}
 
 	if !umUtils.CheckPasswordFormat(req.NewPassword) {
-		slog.Error("invalid password format")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
+		slog.ErrorContext(c, "invalid password format")
+		apihelpers.RespondValidationError(c, "invalid password format", apihelpers.FieldError{Field: "password", Message: "invalid password format"})
 		return
 	}
 
 	if umUtils.IsPasswordOnBlocklist(req.NewPassword) {
-		slog.Error("password on blocklist")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
+		slog.ErrorContext(c, "password on blocklist")
+		apihelpers.RespondValidationError(c, "password on blocklist", apihelpers.FieldError{Field: "password", Message: "password on blocklist"})
 		return
 	}
 
@@ -163,31 +171,36 @@ func (h *HttpEndpoints) resetPassword(c *gin.Context) {
 			userTypes.TOKEN_PURPOSE_INVITATION,
 		})
 	if err != nil {
-		slog.Error("invalid token", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "invalid token", slog.String("error", err.Error()))
+		emitSecurityEvent(c, securityevents.EVENT_PASSWORD_RESET, securityevents.OUTCOME_FAILURE, "", "", "invalid-token")
 		randomWait(5, 10)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		apihelpers.RespondError(c, http.StatusBadRequest, apihelpers.ERROR_CODE_INVALID_TOKEN, "invalid token")
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
 	if err != nil {
-		slog.Error("failed to get user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
 	password, err := pwhash.HashPassword(req.NewPassword)
 	if err != nil {
-		slog.Error("failed to hash password", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to hash password", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
-	update := bson.M{"$set": bson.M{"account.password": password, "timestamps.lastPasswordChange": time.Now().Unix()}}
+	update := bson.M{
+		"$set": bson.M{"account.password": password, "timestamps.lastPasswordChange": time.Now().Unix()},
+		// a password reset also completes a pending signup without password
+		"$unset": bson.M{"account.setupPendingSince": ""},
+	}
 	err = h.userDBConn.UpdateUser(tokenInfos.InstanceID, user.ID.Hex(), update)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
+		apihelpers.RespondInternalError(c, "internal server error")
 		return
 	}
 
@@ -198,24 +211,27 @@ func (h *HttpEndpoints) resetPassword(c *gin.Context) {
 		contactUpdate := bson.M{"$set": bson.M{"contactPreferences": newContactPrefs, "timestamps.updatedAt": time.Now().Unix()}}
 		err := h.userDBConn.UpdateUser(tokenInfos.InstanceID, user.ID.Hex(), contactUpdate)
 		if err != nil {
-			slog.Error("failed to update contact preferences", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to update contact preferences", slog.String("error", err.Error()))
 		}
 	}
 
-	go h.sendSimpleEmail(
-		tokenInfos.InstanceID,
-		[]string{user.Account.AccountID},
-		emailTypes.EMAIL_TYPE_PASSWORD_CHANGED,
-		"",
-		user.Account.PreferredLanguage,
-		nil,
-		true,
-	)
+	h.runInBackground("email", func(ctx context.Context) error {
+		return h.sendSimpleEmail(
+			tokenInfos.InstanceID,
+			[]string{user.Account.AccountID},
+			emailTypes.EMAIL_TYPE_PASSWORD_CHANGED,
+			"",
+			user.Account.PreferredLanguage,
+			nil,
+			true,
+		)
+	})
 
-	slog.Info("password reset successful", slog.String("userID", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID))
+	slog.InfoContext(c, "password reset successful", slog.String("userID", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID))
+	emitSecurityEvent(c, securityevents.EVENT_PASSWORD_RESET, securityevents.OUTCOME_SUCCESS, tokenInfos.InstanceID, user.ID.Hex(), "")
 
 	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(tokenInfos.InstanceID, user.ID.Hex(), userTypes.TOKEN_PURPOSE_PASSWORD_RESET); err != nil {
-		slog.Error("failed to delete temp token", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete temp token", slog.String("error", err.Error()))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "password reset successful"})
@@ -0,0 +1,50 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
+	smssending "github.com/case-framework/case-backend/pkg/messaging/sms-sending"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HttpEndpoints) AddSMSCallbacksAPI(rg *gin.RouterGroup) {
+	smsGroup := rg.Group("/sms")
+	{
+		// providers send delivery reports either as GET or POST requests
+		smsGroup.GET("/status/:instanceID", h.onSMSDeliveryStatus)
+		smsGroup.POST("/status/:instanceID", h.onSMSDeliveryStatus)
+	}
+}
+
+func (h *HttpEndpoints) onSMSDeliveryStatus(c *gin.Context) {
+	instanceID := c.Param("instanceID")
+
+	if !h.isInstanceAllowed(instanceID) {
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	smsConfig := sms.GetSMSConfig(instanceID)
+	if !smssending.VerifyCallbackSecret(smsConfig, c.Query("secret")) {
+		slog.WarnContext(c, "sms status callback with invalid secret", slog.String("instanceID", instanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	update, err := smssending.ParseDeliveryStatusCallback(smsConfig.Provider, c.Request)
+	if err != nil {
+		slog.ErrorContext(c, "failed to parse sms status callback", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := sms.OnDeliveryStatusUpdate(instanceID, update); err != nil {
+		slog.ErrorContext(c, "failed to update sms status", slog.String("instanceID", instanceID), slog.String("messageID", update.ProviderMessageID), slog.String("error", err.Error()))
+		// acknowledge anyway, so that the provider does not retry for unknown messages
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "status received"})
+}
@@ -0,0 +1,187 @@
+package apihandlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	reportrenderer "github.com/case-framework/case-backend/pkg/study/report-renderer"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Participants can only access reports that have a report template in the study
+func (h *HttpEndpoints) getReportsForProfile(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	pid := c.DefaultQuery("pid", "")
+	reportKey := c.DefaultQuery("reportKey", "")
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	participantID, ok := h.getParticipantIDForProfile(c, token, studyKey, pid)
+	if !ok {
+		return
+	}
+
+	templates, err := h.studyDBConn.GetReportTemplates(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get report templates", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reports"})
+		return
+	}
+	visibleKeys := []string{}
+	for _, t := range templates {
+		if reportKey == "" || t.ReportKey == reportKey {
+			visibleKeys = append(visibleKeys, t.ReportKey)
+		}
+	}
+
+	filter := bson.M{
+		"participantID": participantID,
+		"key":           bson.M{"$in": visibleKeys},
+	}
+	reports, paginationInfo, err := h.studyDBConn.GetReports(token.InstanceID, studyKey, filter, page, limit)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get reports", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get reports"})
+		return
+	}
+	if reports == nil {
+		reports = []studyTypes.Report{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports":    reports,
+		"pagination": paginationInfo,
+	})
+}
+
+func (h *HttpEndpoints) getReportForProfile(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	pid := c.DefaultQuery("pid", "")
+
+	participantID, ok := h.getParticipantIDForProfile(c, token, studyKey, pid)
+	if !ok {
+		return
+	}
+
+	report, _, ok := h.getVisibleReport(c, token.InstanceID, studyKey, c.Param("reportID"), participantID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *HttpEndpoints) renderReportForProfile(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	pid := c.DefaultQuery("pid", "")
+	lang := c.DefaultQuery("lang", "")
+	format := c.DefaultQuery("format", reportrenderer.FORMAT_HTML)
+	if format != reportrenderer.FORMAT_HTML && format != reportrenderer.FORMAT_PDF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html or pdf"})
+		return
+	}
+
+	participantID, ok := h.getParticipantIDForProfile(c, token, studyKey, pid)
+	if !ok {
+		return
+	}
+
+	report, template, ok := h.getVisibleReport(c, token.InstanceID, studyKey, c.Param("reportID"), participantID)
+	if !ok {
+		return
+	}
+
+	rendered, err := reportrenderer.RenderHTML(template, report, lang)
+	if err != nil {
+		slog.ErrorContext(c, "failed to render report", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("reportKey", report.Key), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render report"})
+		return
+	}
+
+	fileName := fmt.Sprintf("%s_%s", report.Key, report.ID.Hex())
+	if format == reportrenderer.FORMAT_PDF {
+		pdf, err := reportrenderer.RenderPDF(rendered)
+		if err != nil {
+			slog.ErrorContext(c, "failed to render report as PDF", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("reportKey", report.Key), slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render report"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", fileName))
+		c.Data(http.StatusOK, "application/pdf", pdf)
+		return
+	}
+
+	// report templates must not load external resources or run scripts
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src data:")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+}
+
+// getParticipantIDForProfile checks that the profile belongs to the user and returns its study specific participant ID.
+// If not ok, the error response has been written.
+func (h *HttpEndpoints) getParticipantIDForProfile(c *gin.Context, token *jwthandling.ParticipantUserClaims, studyKey string, pid string) (string, bool) {
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return "", false
+	}
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return "", false
+	}
+
+	participantID, _, err := studyService.ComputeParticipantIDs(study, pid)
+	if err != nil {
+		slog.ErrorContext(c, "Error computing participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing participant IDs"})
+		return "", false
+	}
+	return participantID, true
+}
+
+// getVisibleReport returns the report with its template if it belongs to the participant and has a template.
+// If not ok, the error response has been written.
+func (h *HttpEndpoints) getVisibleReport(c *gin.Context, instanceID string, studyKey string, reportID string, participantID string) (studyTypes.Report, studyTypes.ReportTemplate, bool) {
+	report, err := h.studyDBConn.GetReportByID(instanceID, studyKey, reportID)
+	if err != nil || report.ParticipantID != participantID {
+		slog.WarnContext(c, "report not found", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("reportID", reportID))
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return report, studyTypes.ReportTemplate{}, false
+	}
+
+	template, err := h.studyDBConn.GetReportTemplate(instanceID, studyKey, report.Key)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			slog.ErrorContext(c, "failed to get report template", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get report"})
+			return report, template, false
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return report, template, false
+	}
+	return report, template, true
+}
@@ -1,15 +1,18 @@
 package apihandlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 
 	studyService "github.com/case-framework/case-backend/pkg/study"
 	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
@@ -21,9 +24,11 @@ func (h *HttpEndpoints) AddStudyServiceAPI(rg *gin.RouterGroup) {
 	studyServiceGroup := rg.Group("/study-service")
 
 	studiesGroup := studyServiceGroup.Group("/studies")
+	studiesGroup.Use(h.cached(CACHE_GROUP_STUDIES))
 	{
 		studiesGroup.GET("/", h.getStudiesByStatus) // ?status=active&instanceID=test
 		studiesGroup.GET("/:studyKey", h.getStudy)
+		studiesGroup.GET("/:studyKey/enrollment-status", h.getStudyEnrollmentStatus) // ?instanceID=test
 		studiesGroup.GET("/participating", mw.GetAndValidateParticipantUserJWT(h.tokenSignKey), h.getParticipatingStudies)
 	}
 
@@ -34,7 +39,7 @@ func (h *HttpEndpoints) AddStudyServiceAPI(rg *gin.RouterGroup) {
 	{
 		eventsGroup.POST("/enter", h.enterStudy)
 		eventsGroup.POST("/custom", h.customStudyEvent)
-		eventsGroup.POST("/submit", h.submitSurveyEvent)
+		eventsGroup.POST("/submit", h.idempotent(), h.submitSurveyEvent)
 		eventsGroup.POST("/leave", h.leaveStudyEvent)
 		eventsGroup.POST("/merge-temporary-participant", h.mergeTempParticipant)
 	}
@@ -42,27 +47,34 @@ func (h *HttpEndpoints) AddStudyServiceAPI(rg *gin.RouterGroup) {
 	participantInfoGroup := studyServiceGroup.Group("/participant-data/:studyKey")
 	participantInfoGroup.Use(mw.GetAndValidateParticipantUserJWT(h.tokenSignKey))
 	{
-		participantInfoGroup.GET("/surveys", h.getAssignedSurveys)             // ?pids=p1,p2,p3
-		participantInfoGroup.GET("/survey/:surveyKey", h.getSurveyWithContext) // ?pid=profileID
+		participantInfoGroup.GET("/surveys", h.cached(CACHE_GROUP_SURVEYS), h.getAssignedSurveys)             // ?pids=p1,p2,p3
+		participantInfoGroup.GET("/survey/:surveyKey", h.cached(CACHE_GROUP_SURVEYS), h.getSurveyWithContext) // ?pid=profileID
 
 		// TODO: delete files
 		// TODO: file upload
 
 		// reports:
-		// TODO: get reports reports/studyKey - query for profileIDs, report key, page, limit, filter
+		participantInfoGroup.GET("/reports", h.getReportsForProfile)                    // ?pid=profileID&reportKey=&page=&limit=
+		participantInfoGroup.GET("/reports/:reportID", h.getReportForProfile)           // ?pid=profileID
+		participantInfoGroup.GET("/reports/:reportID/render", h.renderReportForProfile) // ?pid=profileID&lang=en&format=html|pdf
 
 		participantInfoGroup.GET("/responses", h.getStudyResponsesForProfile)
+		participantInfoGroup.GET("/responses/:responseID", h.getStudyResponseSummaryForProfile) // ?pid=profileID
+		participantInfoGroup.POST("/responses/:responseID/correction", mw.RequirePayload(), h.idempotent(), h.submitResponseCorrection)
 		participantInfoGroup.GET("/submission-history", h.getSubmissionHistory)
 
 	}
 
+	// real time updates of assigned surveys and messages, authenticated with the first message
+	studyServiceGroup.GET("/participant-updates/:studyKey", h.participantUpdatesWS)
+
 	// temporary participants
 	tempParticipantGroup := studyServiceGroup.Group("/temp-participant")
 	{
-		tempParticipantGroup.POST("/register", mw.RequirePayload(), h.registerTempParticipant)
-		tempParticipantGroup.GET("/surveys", h.getTempParticipantSurveys)          // ?pid=profileID&instanceID=instanceID&studyKey=studyKey
-		tempParticipantGroup.GET("/survey", h.getTempParticipantSurveyWithContext) // ?pid=profileID&instanceID=instanceID&studyKey=studyKey&surveyKey=surveyKey
-		tempParticipantGroup.POST("/submit-response", mw.RequirePayload(), h.submitTempParticipantResponse)
+		tempParticipantGroup.POST("/register", mw.RequirePayload(), h.idempotent(), h.registerTempParticipant)
+		tempParticipantGroup.GET("/surveys", h.cached(CACHE_GROUP_SURVEYS), h.getTempParticipantSurveys)          // ?instanceID=instanceID&studyKey=studyKey, token in the Authorization header
+		tempParticipantGroup.GET("/survey", h.cached(CACHE_GROUP_SURVEYS), h.getTempParticipantSurveyWithContext) // ?instanceID=instanceID&studyKey=studyKey&surveyKey=surveyKey, token in the Authorization header
+		tempParticipantGroup.POST("/submit-response", mw.RequirePayload(), h.idempotent(), h.submitTempParticipantResponse)
 	}
 }
 
@@ -71,14 +83,14 @@ func (h *HttpEndpoints) getStudiesByStatus(c *gin.Context) {
 	status := c.DefaultQuery("status", "")
 
 	if !h.isInstanceAllowed(instanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", instanceID))
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
 		return
 	}
 
 	studies, err := h.studyDBConn.GetStudies(instanceID, status, false)
 	if err != nil {
-		slog.Error("error getting studies", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting studies", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting studies"})
 		return
 	}
@@ -109,20 +121,20 @@ func (h *HttpEndpoints) getStudy(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 
 	if !h.isInstanceAllowed(instanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", instanceID))
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
 		return
 	}
 
 	if studyKey == "" {
-		slog.Error("studyKey is required", slog.String("instanceID", instanceID))
+		slog.ErrorContext(c, "studyKey is required", slog.String("instanceID", instanceID))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "studyKey is required"})
 		return
 	}
 
 	study, err := h.studyDBConn.GetStudy(instanceID, studyKey)
 	if err != nil {
-		slog.Error("error getting study", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting study"})
 		return
 	}
@@ -136,19 +148,53 @@ func (h *HttpEndpoints) getStudy(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"study": studyInfo})
 }
 
+func (h *HttpEndpoints) getStudyEnrollmentStatus(c *gin.Context) {
+	instanceID := c.DefaultQuery("instanceID", "")
+	studyKey := c.Param("studyKey")
+
+	if !h.isInstanceAllowed(instanceID) {
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
+		return
+	}
+
+	status, err := studyService.GetEnrollmentStatus(instanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "error getting enrollment status", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting enrollment status"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// respondIfEnrollmentClosed writes a forbidden response with the reason and the next opening time if err is an EnrollmentClosedError
+func respondIfEnrollmentClosed(c *gin.Context, err error) bool {
+	var closedErr *studyService.EnrollmentClosedError
+	if !errors.As(err, &closedErr) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":       "enrollment closed",
+		"reason":      closedErr.Reason,
+		"nextOpensAt": closedErr.NextOpensAt,
+		"message":     closedErr.Message,
+	})
+	return true
+}
+
 func (h *HttpEndpoints) getParticipatingStudies(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
 
 	studies, err := h.studyDBConn.GetStudies(token.InstanceID, "", false)
 	if err != nil {
-		slog.Error("error getting studies", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting studies", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting studies"})
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("error getting user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting user", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting user"})
 		return
 	}
@@ -167,7 +213,7 @@ func (h *HttpEndpoints) getParticipatingStudies(c *gin.Context) {
 		for _, profile := range user.Profiles {
 			participantID, _, err := studyService.ComputeParticipantIDs(study, profile.ID.Hex())
 			if err != nil {
-				slog.Error("Error computing participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				slog.ErrorContext(c, "Error computing participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
 				continue
 			}
 
@@ -177,7 +223,7 @@ func (h *HttpEndpoints) getParticipatingStudies(c *gin.Context) {
 			}
 
 			if pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
-				slog.Error("Participant is not active", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("participantID", participantID))
+				slog.ErrorContext(c, "Participant is not active", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("participantID", participantID))
 				continue
 			}
 
@@ -201,33 +247,37 @@ func (h *HttpEndpoints) enterStudy(c *gin.Context) {
 		ProfileID string `json:"profileID"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	if req.ProfileID == "" {
-		slog.Error("profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "profileID is required"})
 		return
 	}
 
 	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
-		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
 		return
 	}
 
-	slog.Debug("entering study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.DebugContext(c, "entering study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
-	result, err := studyService.OnEnterStudy(token.InstanceID, studyKey, req.ProfileID)
+	result, waitingList, err := studyService.OnEnterStudy(c.Request.Context(), token.InstanceID, studyKey, req.ProfileID)
 	if err != nil {
-		slog.Error("error entering study", slog.String("error", err.Error()))
+		if respondIfEnrollmentClosed(c, err) {
+			return
+		}
+		slog.ErrorContext(c, "error entering study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error entering study"})
 		return
 	}
+	h.markSyntheticParticipant(token, studyKey, req.ProfileID)
 
-	c.JSON(http.StatusOK, gin.H{"assignedSurveys": result})
+	c.JSON(http.StatusOK, gin.H{"assignedSurveys": result, "waitingList": waitingList})
 }
 
 func (h *HttpEndpoints) customStudyEvent(c *gin.Context) {
@@ -241,34 +291,34 @@ func (h *HttpEndpoints) customStudyEvent(c *gin.Context) {
 		Payload   map[string]interface{} `json:"payload"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
-		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
 		return
 	}
 
 	if req.EventKey == "" {
-		slog.Error("eventKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "eventKey is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "eventKey is required"})
 		return
 	}
 
 	if req.ProfileID == "" {
-		slog.Error("profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "profileID is required"})
 		return
 	}
 
-	slog.Debug("custom study event", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("eventKey", req.EventKey))
+	slog.DebugContext(c, "custom study event", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("eventKey", req.EventKey))
 
-	result, err := studyService.OnCustomStudyEvent(token.InstanceID, studyKey, req.ProfileID, req.EventKey, req.Payload)
+	result, err := studyService.OnCustomStudyEvent(c.Request.Context(), token.InstanceID, studyKey, req.ProfileID, req.EventKey, req.Payload)
 	if err != nil {
-		slog.Error("error firing custom study event", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error firing custom study event", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error firing custom study event"})
 		return
 	}
@@ -286,22 +336,29 @@ func (h *HttpEndpoints) submitSurveyEvent(c *gin.Context) {
 		Response  studyTypes.SurveyResponse `json:"response"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
-		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "profile not found"})
 		return
 	}
 
-	slog.Debug("submitting survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("profileID", req.ProfileID))
+	slog.DebugContext(c, "submitting survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("profileID", req.ProfileID))
+
+	h.markSyntheticParticipant(token, studyKey, req.ProfileID)
 
-	result, err := studyService.OnSubmitResponse(token.InstanceID, studyKey, req.ProfileID, req.Response)
+	result, err := studyService.OnSubmitResponse(c.Request.Context(), token.InstanceID, studyKey, req.ProfileID, req.Response)
 	if err != nil {
-		slog.Error("error submitting survey", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error submitting survey", slog.String("error", err.Error()))
+		var validationErr *studyService.ResponseValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "response is not valid", "validationIssues": validationErr.Issues})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error submitting survey"})
 		return
 	}
@@ -318,22 +375,22 @@ func (h *HttpEndpoints) leaveStudyEvent(c *gin.Context) {
 		ProfileID string `json:"profileID"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
-		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "profile not found"})
 		return
 	}
 
-	slog.Debug("leaving study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.DebugContext(c, "leaving study", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
-	result, err := studyService.OnLeaveStudy(token.InstanceID, studyKey, req.ProfileID)
+	result, err := studyService.OnLeaveStudy(c.Request.Context(), token.InstanceID, studyKey, req.ProfileID)
 	if err != nil {
-		slog.Error("error leaving study", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error leaving study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error leaving study"})
 		return
 	}
@@ -347,24 +404,31 @@ func (h *HttpEndpoints) mergeTempParticipant(c *gin.Context) {
 	studyKey := c.Param("studyKey")
 
 	var req struct {
-		ProfileID              string `json:"profileID"`
-		TemporaryParticipantID string `json:"temporaryParticipantID"`
+		ProfileID                 string `json:"profileID"`
+		TemporaryParticipantToken string `json:"temporaryParticipantToken"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	_, _, tempPID, err := h.resolveTempParticipant(req.TemporaryParticipantToken, token.InstanceID, studyKey)
+	if err != nil || tempPID == "" {
+		slog.WarnContext(c, "invalid temporary participant token", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid temporary participant token"})
+		return
+	}
+
 	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
-		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
 		return
 	}
 
-	result, err := studyService.OnMergeTempParticipant(token.InstanceID, studyKey, req.ProfileID, req.TemporaryParticipantID)
+	result, err := studyService.OnMergeTempParticipant(c.Request.Context(), token.InstanceID, studyKey, req.ProfileID, tempPID)
 	if err != nil {
-		slog.Error("error merging temporary participant", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error merging temporary participant", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error merging temporary participant"})
 		return
 	}
@@ -380,20 +444,20 @@ func (h *HttpEndpoints) getAssignedSurveys(c *gin.Context) {
 	pids := c.DefaultQuery("pids", "")
 	profileIDs := strings.Split(pids, ",")
 	if len(profileIDs) < 1 {
-		slog.Error("missing required fields", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "missing required fields", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
 
 	if !h.checkAllProfilesBelongsToUser(token.InstanceID, token.Subject, profileIDs) {
-		slog.Warn("at least one profile did not belong to the user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.WarnContext(c, "at least one profile did not belong to the user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "at least one profile did not belong to the user"})
 		return
 	}
 
 	assignedSurveysWithInfos, err := studyService.GetAssignedSurveys(token.InstanceID, studyKey, profileIDs)
 	if err != nil {
-		slog.Error("error getting assigned surveys", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting assigned surveys", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting assigned surveys"})
 		return
 	}
@@ -409,22 +473,22 @@ func (h *HttpEndpoints) getSurveyWithContext(c *gin.Context) {
 	pid := c.DefaultQuery("pid", "")
 
 	if pid == "" {
-		slog.Error("profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "profileID is required"})
 		return
 	}
 
 	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
-		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
 		return
 	}
 
-	slog.Info("getting survey with context", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("profileID", pid))
+	slog.InfoContext(c, "getting survey with context", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("profileID", pid))
 
 	result, err := studyService.GetAssignedSurveyWithContext(token.InstanceID, studyKey, surveyKey, pid)
 	if err != nil {
-		slog.Error("error getting survey with context", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting survey with context", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting survey with context"})
 		return
 	}
@@ -437,54 +501,77 @@ func (h *HttpEndpoints) registerTempParticipant(c *gin.Context) {
 		StudyKey   string `json:"studyKey"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	if req.InstanceID == "" || req.StudyKey == "" {
-		slog.Error("missing required fields", slog.String("instanceID", req.InstanceID), slog.String("studyKey", req.StudyKey))
+		slog.ErrorContext(c, "missing required fields", slog.String("instanceID", req.InstanceID), slog.String("studyKey", req.StudyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
 
 	if !h.isInstanceAllowed(req.InstanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", req.InstanceID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
 		return
 	}
 
-	slog.Info("registering temporary participant", slog.String("instanceID", req.InstanceID), slog.String("studyKey", req.StudyKey))
+	slog.InfoContext(c, "registering temporary participant", slog.String("instanceID", req.InstanceID), slog.String("studyKey", req.StudyKey))
 
-	pState, err := studyService.OnRegisterTempParticipant(req.InstanceID, req.StudyKey)
+	pState, err := studyService.OnRegisterTempParticipant(c.Request.Context(), req.InstanceID, req.StudyKey)
 	if err != nil {
-		slog.Error("error registering temporary participant", slog.String("error", err.Error()))
+		if respondIfEnrollmentClosed(c, err) {
+			return
+		}
+		slog.ErrorContext(c, "error registering temporary participant", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error registering temporary participant"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"participant": pState})
+	token, err := jwthandling.GenerateNewTempParticipantToken(
+		time.Duration(studyService.TEMPORARY_PARTICIPANT_TAKEOVER_PERIOD)*time.Second,
+		pState.ParticipantID,
+		req.InstanceID,
+		req.StudyKey,
+		h.tokenSignKey,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to generate temporary participant token", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error registering temporary participant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"participant": pState, "token": token})
 }
 
 func (h *HttpEndpoints) getTempParticipantSurveys(c *gin.Context) {
-	instanceID := c.DefaultQuery("instanceID", "")
-	studyKey := c.DefaultQuery("studyKey", "")
-	pid := c.DefaultQuery("pid", "")
+	instanceID, studyKey, pid, err := h.resolveTempParticipant(
+		getTempParticipantTokenFromHeader(c),
+		c.DefaultQuery("instanceID", ""),
+		c.DefaultQuery("studyKey", ""),
+	)
+	if err != nil {
+		slog.WarnContext(c, "invalid temporary participant token", slog.String("error", err.Error()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
 
 	if !h.isInstanceAllowed(instanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", instanceID))
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
 		return
 	}
 
 	if instanceID == "" || studyKey == "" || pid == "" {
-		slog.Error("missing required fields", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("pid", pid))
+		slog.ErrorContext(c, "missing required fields", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("pid", pid))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
 
 	assignedSurveysWithInfos, err := studyService.GetAssignedSurveysForTempParticipant(instanceID, studyKey, pid)
 	if err != nil {
-		slog.Error("error getting assigned surveys for temporary participant", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting assigned surveys for temporary participant", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting assigned surveys for temporary participant"})
 		return
 	}
@@ -493,26 +580,33 @@ func (h *HttpEndpoints) getTempParticipantSurveys(c *gin.Context) {
 }
 
 func (h *HttpEndpoints) getTempParticipantSurveyWithContext(c *gin.Context) {
-	instanceID := c.DefaultQuery("instanceID", "")
-	studyKey := c.DefaultQuery("studyKey", "")
 	surveyKey := c.DefaultQuery("surveyKey", "")
-	pid := c.DefaultQuery("pid", "")
+	instanceID, studyKey, pid, err := h.resolveTempParticipant(
+		getTempParticipantTokenFromHeader(c),
+		c.DefaultQuery("instanceID", ""),
+		c.DefaultQuery("studyKey", ""),
+	)
+	if err != nil {
+		slog.WarnContext(c, "invalid temporary participant token", slog.String("error", err.Error()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
 
 	if !h.isInstanceAllowed(instanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", instanceID))
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
 		return
 	}
 
 	if instanceID == "" || studyKey == "" || surveyKey == "" {
-		slog.Error("missing required fields", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+		slog.ErrorContext(c, "missing required fields", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
 
 	result, err := studyService.GetSurveyWithContextForTempParticipant(instanceID, studyKey, surveyKey, pid)
 	if err != nil {
-		slog.Error("error getting survey with context", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error getting survey with context", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting survey with context"})
 		return
 	}
@@ -523,31 +617,43 @@ func (h *HttpEndpoints) submitTempParticipantResponse(c *gin.Context) {
 	var req struct {
 		InstanceID string                    `json:"instanceId"`
 		StudyKey   string                    `json:"studyKey"`
-		Pid        string                    `json:"pid"`
 		Response   studyTypes.SurveyResponse `json:"response"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if req.InstanceID == "" || req.StudyKey == "" || req.Pid == "" {
-		slog.Error("missing required fields", slog.String("instanceID", req.InstanceID), slog.String("studyKey", req.StudyKey), slog.String("pid", req.Pid))
+
+	instanceID, studyKey, pid, err := h.resolveTempParticipant(getTempParticipantTokenFromHeader(c), req.InstanceID, req.StudyKey)
+	if err != nil {
+		slog.WarnContext(c, "invalid temporary participant token", slog.String("error", err.Error()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	if instanceID == "" || studyKey == "" || pid == "" {
+		slog.ErrorContext(c, "missing required fields", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("pid", pid))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
 
-	if !h.isInstanceAllowed(req.InstanceID) {
-		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+	if !h.isInstanceAllowed(instanceID) {
+		slog.ErrorContext(c, "instance not allowed", slog.String("instanceID", instanceID))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
 		return
 	}
 
-	slog.Info("submitting response for temporary participant", slog.String("instanceID", req.InstanceID), slog.String("studyKey", req.StudyKey), slog.String("pid", req.Pid))
+	slog.InfoContext(c, "submitting response for temporary participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("pid", pid))
 
-	result, err := studyService.OnSubmitResponseForTempParticipant(req.InstanceID, req.StudyKey, req.Pid, req.Response)
+	result, err := studyService.OnSubmitResponseForTempParticipant(c.Request.Context(), instanceID, studyKey, pid, req.Response)
 	if err != nil {
-		slog.Error("error submitting response for temporary participant", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "error submitting response for temporary participant", slog.String("error", err.Error()))
+		var validationErr *studyService.ResponseValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "response is not valid", "validationIssues": validationErr.Issues})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error submitting response for temporary participant"})
 		return
 	}
@@ -555,6 +661,10 @@ func (h *HttpEndpoints) submitTempParticipantResponse(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"assignedSurveys": result})
 }
 
+func getTempParticipantTokenFromHeader(c *gin.Context) string {
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
+
 func (h *HttpEndpoints) getStudyResponsesForProfile(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
 
@@ -565,30 +675,33 @@ func (h *HttpEndpoints) getStudyResponsesForProfile(c *gin.Context) {
 
 	query, err := apihelpers.ParseResponseExportQueryFromCtx(c)
 	if err != nil || query == nil {
-		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse query", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to get study", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
 		return
 	}
 
 	participantID, _, err := studyService.ComputeParticipantIDs(study, pid)
 	if err != nil {
-		slog.Error("Error computing participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "Error computing participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing participant IDs"})
 		return
 	}
 
 	filter := query.PaginationInfos.Filter
 	filter["participantID"] = participantID
+	if c.DefaultQuery("includeSuperseded", "false") != "true" {
+		filter["supersededBy"] = bson.M{"$exists": false}
+	}
 
 	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
-		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
 		return
 	}
@@ -602,12 +715,12 @@ func (h *HttpEndpoints) getStudyResponsesForProfile(c *gin.Context) {
 		query.PaginationInfos.Limit,
 	)
 	if err != nil {
-		slog.Error("failed to get study responses", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get study responses", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study responses"})
 		return
 	}
 
-	slog.Debug("survey key", slog.Any("query", query))
+	slog.DebugContext(c, "survey key", slog.Any("query", query))
 
 	surveyVersions, err := surveydefinition.PrepareSurveyInfosFromDB(
 		h.studyDBConn,
@@ -621,7 +734,7 @@ func (h *HttpEndpoints) getStudyResponsesForProfile(c *gin.Context) {
 		},
 	)
 	if err != nil {
-		slog.Error("failed to get survey versions", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
 		return
 	}
@@ -635,7 +748,7 @@ func (h *HttpEndpoints) getStudyResponsesForProfile(c *gin.Context) {
 		query.ExtraCtxCols,
 	)
 	if err != nil {
-		slog.Error("failed to create response parser", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to create response parser", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response parser"})
 		return
 	}
@@ -645,12 +758,12 @@ func (h *HttpEndpoints) getStudyResponsesForProfile(c *gin.Context) {
 	for i, rawResp := range rawResponses {
 		resp, err := respParser.ParseResponse(&rawResp)
 		if err != nil {
-			slog.Error("failed to parse response", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to parse response", slog.String("error", err.Error()))
 			continue
 		}
 		output, err := respParser.ResponseToFlatObj(resp)
 		if err != nil {
-			slog.Error("failed to convert response to flat object", slog.String("error", err.Error()))
+			slog.ErrorContext(c, "failed to convert response to flat object", slog.String("error", err.Error()))
 			continue
 		}
 		responses[i] = output
@@ -662,6 +775,131 @@ func (h *HttpEndpoints) getStudyResponsesForProfile(c *gin.Context) {
 	})
 }
 
+func (h *HttpEndpoints) getStudyResponseSummaryForProfile(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	responseID := c.Param("responseID")
+	pid := c.DefaultQuery("pid", "")
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return
+	}
+
+	participantID, _, err := studyService.ComputeParticipantIDs(study, pid)
+	if err != nil {
+		slog.ErrorContext(c, "Error computing participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing participant IDs"})
+		return
+	}
+
+	rawResp, err := h.studyDBConn.GetResponseByID(token.InstanceID, studyKey, responseID)
+	if err != nil || rawResp.ParticipantID != participantID {
+		slog.WarnContext(c, "response not found", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("responseID", responseID))
+		c.JSON(http.StatusNotFound, gin.H{"error": "response not found"})
+		return
+	}
+
+	surveyVersions, err := surveydefinition.PrepareSurveyInfosFromDB(
+		h.studyDBConn,
+		token.InstanceID,
+		studyKey,
+		rawResp.Key,
+		&surveydefinition.ExtractOptions{
+			UseLabelLang: c.DefaultQuery("lang", ""),
+			IncludeItems: nil,
+			ExcludeItems: nil,
+		},
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to get survey versions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get survey versions"})
+		return
+	}
+
+	respParser, err := surveyresponses.NewResponseParser(
+		rawResp.Key,
+		surveyVersions,
+		false,
+		&surveyresponses.IncludeMeta{},
+		"-",
+		nil,
+	)
+	if err != nil {
+		slog.ErrorContext(c, "failed to create response parser", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create response parser"})
+		return
+	}
+
+	resp, err := respParser.ParseResponse(&rawResp)
+	if err != nil {
+		slog.ErrorContext(c, "failed to parse response", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse response"})
+		return
+	}
+	summary, err := respParser.ResponseToFlatObj(resp)
+	if err != nil {
+		slog.ErrorContext(c, "failed to convert response to flat object", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse response"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary":      summary,
+		"revision":     rawResp.Revision,
+		"supersedes":   rawResp.Supersedes,
+		"supersededBy": rawResp.SupersededBy,
+	})
+}
+
+func (h *HttpEndpoints) submitResponseCorrection(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	responseID := c.Param("responseID")
+
+	var req struct {
+		ProfileID string                    `json:"profileID"`
+		Response  studyTypes.SurveyResponse `json:"response"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
+		slog.WarnContext(c, "profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	slog.InfoContext(c, "submitting response correction", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("responseID", responseID))
+
+	newResponseID, err := studyService.OnSubmitResponseCorrection(token.InstanceID, studyKey, req.ProfileID, responseID, req.Response)
+	if err != nil {
+		slog.ErrorContext(c, "error submitting response correction", slog.String("error", err.Error()))
+		var validationErr *studyService.ResponseValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "response is not valid", "validationIssues": validationErr.Issues})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error submitting response correction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"responseID": newResponseID})
+}
+
 func (h *HttpEndpoints) getSubmissionHistory(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
 
@@ -670,29 +908,29 @@ func (h *HttpEndpoints) getSubmissionHistory(c *gin.Context) {
 	pids := c.DefaultQuery("pids", "")
 	profileIDs := strings.Split(pids, ",")
 	if len(profileIDs) < 1 {
-		slog.Error("missing required fields", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.ErrorContext(c, "missing required fields", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
 	limit := c.DefaultQuery("limit", "100")
 	limitInt, err := strconv.Atoi(limit)
 	if err != nil {
-		slog.Error("failed to parse limit", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to parse limit", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse limit"})
 		return
 	}
 
 	if !h.checkAllProfilesBelongsToUser(token.InstanceID, token.Subject, profileIDs) {
-		slog.Warn("at least one profile did not belong to the user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		slog.WarnContext(c, "at least one profile did not belong to the user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "at least one profile did not belong to the user"})
 		return
 	}
 
-	slog.Info("getting submission history", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.InfoContext(c, "getting submission history", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	submissionHistory, err := studyService.GetSubmissionHistory(token.InstanceID, studyKey, profileIDs, int64(limitInt))
 	if err != nil {
-		slog.Error("failed to get submission history", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get submission history", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get submission history"})
 		return
 	}
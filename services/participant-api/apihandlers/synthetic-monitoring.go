@@ -0,0 +1,68 @@
+package apihandlers
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"slices"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	SYNTHETIC_MONITORING_TOKEN_HEADER = "X-Synthetic-Monitoring-Token"
+	TOKEN_PAYLOAD_KEY_SYNTHETIC       = "synthetic"
+)
+
+type SyntheticMonitoringConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Email addresses of the accounts used by monitoring probes
+	Accounts []string `json:"accounts" yaml:"accounts"`
+	// Probes need to send this token in the X-Synthetic-Monitoring-Token header to bypass rate limits
+	ProbeToken string `json:"probe_token" yaml:"probe_token"`
+}
+
+func (h *HttpEndpoints) isSyntheticMonitoringAccount(email string) bool {
+	if !h.syntheticMonitoring.Enabled {
+		return false
+	}
+	email = umUtils.SanitizeEmail(email)
+	return slices.ContainsFunc(h.syntheticMonitoring.Accounts, func(account string) bool {
+		return umUtils.SanitizeEmail(account) == email
+	})
+}
+
+// isSyntheticMonitoringRequest checks if the request comes from a monitoring probe using one of the designated accounts
+func (h *HttpEndpoints) isSyntheticMonitoringRequest(c *gin.Context, email string) bool {
+	if !h.isSyntheticMonitoringAccount(email) || h.syntheticMonitoring.ProbeToken == "" {
+		return false
+	}
+	probeToken := c.GetHeader(SYNTHETIC_MONITORING_TOKEN_HEADER)
+	return subtle.ConstantTimeCompare([]byte(probeToken), []byte(h.syntheticMonitoring.ProbeToken)) == 1
+}
+
+// tokenPayloadForUser returns the payload for participant user tokens, marking tokens of synthetic-monitoring accounts
+func (h *HttpEndpoints) tokenPayloadForUser(user userTypes.User) map[string]string {
+	payload := map[string]string{}
+	if h.isSyntheticMonitoringAccount(user.Account.AccountID) {
+		payload[TOKEN_PAYLOAD_KEY_SYNTHETIC] = "true"
+	}
+	return payload
+}
+
+func isSyntheticToken(token *jwthandling.ParticipantUserClaims) bool {
+	return token.Payload[TOKEN_PAYLOAD_KEY_SYNTHETIC] == "true"
+}
+
+// markSyntheticParticipant flags the study participant of synthetic-monitoring accounts
+func (h *HttpEndpoints) markSyntheticParticipant(token *jwthandling.ParticipantUserClaims, studyKey string, profileID string) {
+	if !isSyntheticToken(token) {
+		return
+	}
+	if err := studyService.MarkParticipantAsSynthetic(token.InstanceID, studyKey, profileID); err != nil {
+		slog.Debug("could not mark participant as synthetic", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+}
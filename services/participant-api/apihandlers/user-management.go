@@ -1,6 +1,7 @@
 package apihandlers
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -8,8 +9,10 @@ import (
 
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/case-framework/case-backend/pkg/messaging/digests"
 	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -72,7 +75,7 @@ func (h *HttpEndpoints) addNewProfileHandl(c *gin.Context) {
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
 	}
@@ -85,12 +88,12 @@ func (h *HttpEndpoints) addNewProfileHandl(c *gin.Context) {
 
 	_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 	if err != nil {
-		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
 		return
 	}
 
-	slog.Info("profile added", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("profileId", profile.ID.Hex()))
+	slog.InfoContext(c, "profile added", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("profileId", profile.ID.Hex()))
 
 	c.JSON(http.StatusOK, gin.H{"profile": profile})
 }
@@ -106,26 +109,26 @@ func (h *HttpEndpoints) updateProfileHandl(c *gin.Context) {
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
 	}
 
 	err = user.UpdateProfile(profile)
 	if err != nil {
-		slog.Error("cannot update profile", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot update profile", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update profile"})
 		return
 	}
 
 	_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 	if err != nil {
-		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
 		return
 	}
 
-	slog.Info("profile updated", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("profileId", profile.ID.Hex()))
+	slog.InfoContext(c, "profile updated", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("profileId", profile.ID.Hex()))
 
 	c.JSON(http.StatusOK, gin.H{"profile": profile})
 }
@@ -144,28 +147,28 @@ func (h *HttpEndpoints) removeProfileHandl(c *gin.Context) {
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
 	}
 
 	err = user.RemoveProfile(req.ProfileID)
 	if err != nil {
-		slog.Error("cannot remove profile", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot remove profile", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot remove profile"})
 		return
 	}
 
 	_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 	if err != nil {
-		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
 		return
 	}
 
-	slog.Info("profile removed", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("profileId", req.ProfileID))
+	slog.InfoContext(c, "profile removed", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("profileId", req.ProfileID))
 
-	studyService.OnProfileDeleted(token.InstanceID, req.ProfileID, req.ExitSurveyResponse)
+	studyService.OnProfileDeleted(c.Request.Context(), token.InstanceID, req.ProfileID, req.ExitSurveyResponse)
 
 	c.JSON(http.StatusOK, gin.H{"message": "profile removed"})
 }
@@ -184,59 +187,63 @@ func (h *HttpEndpoints) changePasswordHandl(c *gin.Context) {
 
 	// check password format
 	if !umUtils.CheckPasswordFormat(req.NewPassword) {
-		slog.Error("invalid password format", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "invalid password format"))
+		slog.ErrorContext(c, "invalid password format", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "invalid password format"))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
 		return
 	}
 
 	if umUtils.IsPasswordOnBlocklist(req.NewPassword) {
-		slog.Error("password on blocklist", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "password on blocklist"))
+		slog.ErrorContext(c, "password on blocklist", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "password on blocklist"))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
 	}
 
 	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.OldPassword)
 	if err != nil || !match {
-		slog.Error("old password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.ErrorContext(c, "old password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		emitSecurityEvent(c, securityevents.EVENT_PASSWORD_CHANGED, securityevents.OUTCOME_FAILURE, token.InstanceID, token.Subject, "wrong-password")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
 		return
 	}
 
 	hashedPassword, err := pwhash.HashPassword(req.NewPassword)
 	if err != nil {
-		slog.Error("cannot hash password", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot hash password", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot hash password"})
 		return
 	}
 
 	update := bson.M{"$set": bson.M{"account.password": hashedPassword, "timestamps.lastPasswordChange": time.Now().Unix()}}
 	if err := h.userDBConn.UpdateUser(token.InstanceID, user.ID.Hex(), update); err != nil {
-		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
 		return
 	}
 
-	go h.sendSimpleEmail(
-		token.InstanceID,
-		[]string{user.Account.AccountID},
-		emailTypes.EMAIL_TYPE_PASSWORD_CHANGED,
-		"",
-		user.Account.PreferredLanguage,
-		nil,
-		true,
-	)
+	h.runInBackground("email", func(ctx context.Context) error {
+		return h.sendSimpleEmail(
+			token.InstanceID,
+			[]string{user.Account.AccountID},
+			emailTypes.EMAIL_TYPE_PASSWORD_CHANGED,
+			"",
+			user.Account.PreferredLanguage,
+			nil,
+			true,
+		)
+	})
 
-	slog.Info("password change successful", slog.String("userID", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
+	slog.InfoContext(c, "password change successful", slog.String("userID", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
+	emitSecurityEvent(c, securityevents.EVENT_PASSWORD_CHANGED, securityevents.OUTCOME_SUCCESS, token.InstanceID, token.Subject, "")
 
 	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(token.InstanceID, user.ID.Hex(), userTypes.TOKEN_PURPOSE_PASSWORD_RESET); err != nil {
-		slog.Error("failed to delete temp tokens", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete temp tokens", slog.String("error", err.Error()))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
@@ -257,27 +264,27 @@ func (h *HttpEndpoints) changeAccountEmailHandl(c *gin.Context) {
 	req.Email = umUtils.SanitizeEmail(req.Email)
 
 	if !umUtils.CheckEmailFormat(req.Email) {
-		slog.Error("invalid email format", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "invalid email format"))
+		slog.ErrorContext(c, "invalid email format", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "invalid email format"))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email format"})
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
 	}
 
 	if user.Account.AccountID == req.Email {
-		slog.Error("cannot change account email to self", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.ErrorContext(c, "cannot change account email to self", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot change account email to self"})
 		return
 	}
 
 	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
 	if err != nil || !match {
-		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.ErrorContext(c, "password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
 		return
 	}
@@ -285,38 +292,40 @@ func (h *HttpEndpoints) changeAccountEmailHandl(c *gin.Context) {
 	// is email already in use?
 	_, err = h.userDBConn.GetUserByAccountID(token.InstanceID, req.Email)
 	if err == nil {
-		slog.Error("email already in use", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("email", req.Email))
+		slog.ErrorContext(c, "email already in use", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("email", req.Email))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "something went wrong"})
 		return
 	}
 
 	if user.Account.Type != userTypes.ACCOUNT_TYPE_EMAIL {
-		slog.Error("account type not email", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "account type not email"))
+		slog.ErrorContext(c, "account type not email", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "account type not email"))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "account type not email"})
 		return
 	}
 
 	oldCI, oldFound := user.FindContactInfoByTypeAndAddr("email", user.Account.AccountID)
 	if !oldFound {
-		slog.Error("old contact info not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "old contact info not found"))
+		slog.ErrorContext(c, "old contact info not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "old contact info not found"))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "old contact info not found"})
 		return
 	}
 
 	if user.Account.AccountConfirmedAt > 0 {
 		// old account is confirmed already
-		go h.prepTokenAndSendEmail(
-			user.ID.Hex(),
-			token.InstanceID,
-			oldCI.Email,
-			user.Account.PreferredLanguage,
-			userTypes.TOKEN_PURPOSE_RESTORE_ACCOUNT_ID,
-			h.ttls.EmailContactVerificationToken,
-			emailTypes.EMAIL_TYPE_ACCOUNT_ID_CHANGED,
-			map[string]string{
-				"newEmail": req.Email,
-			},
-		)
+		h.runInBackground("token-email", func(ctx context.Context) error {
+			return h.prepTokenAndSendEmail(
+				user.ID.Hex(),
+				token.InstanceID,
+				oldCI.Email,
+				user.Account.PreferredLanguage,
+				userTypes.TOKEN_PURPOSE_RESTORE_ACCOUNT_ID,
+				h.ttls.EmailContactVerificationToken,
+				emailTypes.EMAIL_TYPE_ACCOUNT_ID_CHANGED,
+				map[string]string{
+					"newEmail": req.Email,
+				},
+			)
+		})
 	}
 
 	// update user
@@ -339,7 +348,7 @@ func (h *HttpEndpoints) changeAccountEmailHandl(c *gin.Context) {
 
 	newCI, newFound := user.FindContactInfoByTypeAndAddr("email", req.Email)
 	if !newFound {
-		slog.Error("new contact info not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "new contact info not found"))
+		slog.ErrorContext(c, "new contact info not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "new contact info not found"))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "new contact info not found"})
 		return
 	}
@@ -348,31 +357,33 @@ func (h *HttpEndpoints) changeAccountEmailHandl(c *gin.Context) {
 
 	// start confirmation workflow of necessary:
 	if user.Account.AccountConfirmedAt <= 0 {
-		go h.prepTokenAndSendEmail(
-			user.ID.Hex(),
-			token.InstanceID,
-			user.Account.AccountID,
-			user.Account.PreferredLanguage,
-			userTypes.TOKEN_PURPOSE_CONTACT_VERIFICATION,
-			h.ttls.EmailContactVerificationToken,
-			emailTypes.EMAIL_TYPE_VERIFY_EMAIL,
-			nil,
-		)
+		h.runInBackground("token-email", func(ctx context.Context) error {
+			return h.prepTokenAndSendEmail(
+				user.ID.Hex(),
+				token.InstanceID,
+				user.Account.AccountID,
+				user.Account.PreferredLanguage,
+				userTypes.TOKEN_PURPOSE_CONTACT_VERIFICATION,
+				h.ttls.EmailContactVerificationToken,
+				emailTypes.EMAIL_TYPE_VERIFY_EMAIL,
+				nil,
+			)
+		})
 	}
 
 	err = user.RemoveContactInfo(oldCI.ID.Hex())
 	if err != nil {
-		slog.Error("cannot remove old contact info", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot remove old contact info", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 	}
 
 	_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 	if err != nil {
-		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
 		return
 	}
 
-	slog.Info("changing account email", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("email", req.Email))
+	slog.InfoContext(c, "changing account email", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("email", req.Email))
 
 	c.JSON(http.StatusOK, gin.H{"message": "account email changed"})
 }
@@ -391,7 +402,7 @@ func (h *HttpEndpoints) updatePhoneNumberHandler(c *gin.Context) {
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		randomWait(5, 10)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
@@ -399,7 +410,7 @@ func (h *HttpEndpoints) updatePhoneNumberHandler(c *gin.Context) {
 
 	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
 	if err != nil || !match {
-		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.ErrorContext(c, "password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
 		return
@@ -408,10 +419,10 @@ func (h *HttpEndpoints) updatePhoneNumberHandler(c *gin.Context) {
 	// if have too many phone numbers within the last 24 hours, return error
 	count, err := h.messagingDBConn.CountSentSMSForUser(token.InstanceID, token.Subject, sms.SMS_MESSAGE_TYPE_VERIFY_PHONE_NUMBER, time.Now().Add(-time.Hour*24))
 	if err != nil {
-		slog.Error("failed to count sent SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to count sent SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 	}
 	if count > MAX_PHONE_NUMBER_VERIFICATION_REQUEST_PER_24H || err != nil {
-		slog.Warn("too many phone numbers sent within the last 24 hours", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.WarnContext(c, "too many phone numbers sent within the last 24 hours", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many phone numbers sent within the last 24 hours"})
 		return
@@ -421,7 +432,7 @@ func (h *HttpEndpoints) updatePhoneNumberHandler(c *gin.Context) {
 	phoneNumber := umUtils.SanitizePhoneNumber(req.NewPhoneNumber)
 	currentPhoneNumber, err := user.GetPhoneNumber()
 	if err == nil && currentPhoneNumber.Phone == phoneNumber {
-		slog.Error("phone number is already set", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.ErrorContext(c, "phone number is already set", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "phone number is already set"})
 		return
@@ -432,27 +443,29 @@ func (h *HttpEndpoints) updatePhoneNumberHandler(c *gin.Context) {
 	// send email to user about phone number change
 	if user.Account.AccountConfirmedAt > 0 {
 		// old account is confirmed already
-		go h.prepTokenAndSendEmail(
-			user.ID.Hex(),
-			token.InstanceID,
-			user.Account.AccountID,
-			user.Account.PreferredLanguage,
-			userTypes.TOKEN_PURPOSE_RESTORE_ACCOUNT_ID,
-			h.ttls.EmailContactVerificationToken,
-			emailTypes.EMAIL_TYPE_PHONE_NUMBER_CHANGED,
-			map[string]string{
-				"newPhoneNumber": req.NewPhoneNumber,
-			},
-		)
+		h.runInBackground("token-email", func(ctx context.Context) error {
+			return h.prepTokenAndSendEmail(
+				user.ID.Hex(),
+				token.InstanceID,
+				user.Account.AccountID,
+				user.Account.PreferredLanguage,
+				userTypes.TOKEN_PURPOSE_RESTORE_ACCOUNT_ID,
+				h.ttls.EmailContactVerificationToken,
+				emailTypes.EMAIL_TYPE_PHONE_NUMBER_CHANGED,
+				map[string]string{
+					"newPhoneNumber": req.NewPhoneNumber,
+				},
+			)
+		})
 	}
 
 	_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 	if err != nil {
-		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
 		return
 	}
-	slog.Info("phone number changed", slog.String("instanceId", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "phone number changed", slog.String("instanceId", token.InstanceID), slog.String("userID", token.Subject))
 
 	c.JSON(http.StatusOK, gin.H{"message": "phone number changed"})
 }
@@ -462,14 +475,14 @@ func (h *HttpEndpoints) requestPhoneNumberVerificationHandl(c *gin.Context) {
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		randomWait(5, 10)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
 	}
 
 	if user.Account.AccountConfirmedAt < 1 {
-		slog.Error("account not confirmed", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.ErrorContext(c, "account not confirmed", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "account not confirmed"})
 		return
@@ -478,10 +491,10 @@ func (h *HttpEndpoints) requestPhoneNumberVerificationHandl(c *gin.Context) {
 	// check daily limit
 	count24h, err := h.messagingDBConn.CountSentSMSForUser(token.InstanceID, token.Subject, sms.SMS_MESSAGE_TYPE_VERIFY_PHONE_NUMBER, time.Now().Add(-time.Hour*24))
 	if err != nil {
-		slog.Error("failed to count sent SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to count sent SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 	}
 	if count24h > MAX_PHONE_NUMBER_VERIFICATION_REQUEST_PER_24H || err != nil {
-		slog.Warn("too many phone numbers sent within the last 24 hours", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.WarnContext(c, "too many phone numbers sent within the last 24 hours", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many phone numbers sent within the last 24 hours"})
 		return
@@ -490,10 +503,10 @@ func (h *HttpEndpoints) requestPhoneNumberVerificationHandl(c *gin.Context) {
 	// check short term limit
 	countShortTerm, err := h.messagingDBConn.CountSentSMSForUser(token.InstanceID, token.Subject, sms.SMS_MESSAGE_TYPE_VERIFY_PHONE_NUMBER, time.Now().Add(-time.Second*15))
 	if err != nil {
-		slog.Error("failed to count sent SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to count sent SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 	}
 	if countShortTerm > 0 || err != nil {
-		slog.Warn("already sent an SMS within the last 15 seconds", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.WarnContext(c, "already sent an SMS within the last 15 seconds", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusOK, gin.H{"message": "already sent an SMS within the last 15 seconds"})
 		return
@@ -502,14 +515,14 @@ func (h *HttpEndpoints) requestPhoneNumberVerificationHandl(c *gin.Context) {
 	// check if phone number is already verified
 	phoneContact, err := user.GetPhoneNumber()
 	if err != nil {
-		slog.Error("failed to get phone number, maybe it is not set", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get phone number, maybe it is not set", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		randomWait(5, 10)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get phone number"})
 		return
 	}
 
 	if phoneContact.ConfirmedAt > 0 {
-		slog.Error("phone number already verified", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		slog.ErrorContext(c, "phone number already verified", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "phone number already verified"})
 		return
@@ -518,7 +531,7 @@ func (h *HttpEndpoints) requestPhoneNumberVerificationHandl(c *gin.Context) {
 	// generate OTP
 	code, err := umUtils.GenerateOTPCode(6)
 	if err != nil {
-		slog.Error("failed to generate OTP", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to generate OTP", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate OTP"})
 		return
 	}
@@ -526,7 +539,7 @@ func (h *HttpEndpoints) requestPhoneNumberVerificationHandl(c *gin.Context) {
 	// save OTP
 	err = h.userDBConn.CreateOTP(token.InstanceID, token.Subject, code, userTypes.SMSOTP, MAX_PHONE_NUMBER_VERIFICATION_REQUEST_PER_24H)
 	if err != nil {
-		slog.Error("failed to save OTP", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to save OTP", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save OTP"})
 		return
 	}
@@ -539,11 +552,11 @@ func (h *HttpEndpoints) requestPhoneNumberVerificationHandl(c *gin.Context) {
 		"verificationCode": formattedCode,
 	})
 	if err != nil {
-		slog.Error("failed to send SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to send SMS", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send SMS"})
 		return
 	}
-	slog.Info("sent SMS for phone number verification", slog.String("instanceId", token.InstanceID), slog.String("userID", token.Subject))
+	slog.InfoContext(c, "sent SMS for phone number verification", slog.String("instanceId", token.InstanceID), slog.String("userID", token.Subject))
 	c.JSON(http.StatusOK, gin.H{"message": "SMS sent"})
 }
 
@@ -552,13 +565,13 @@ func (h *HttpEndpoints) unsubscribeNewsletter(c *gin.Context) {
 		Token string `json:"token"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
 	if req.Token == "" {
-		slog.Error("token is required")
+		slog.ErrorContext(c, "token is required")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
 		return
 	}
@@ -569,7 +582,7 @@ func (h *HttpEndpoints) unsubscribeNewsletter(c *gin.Context) {
 		},
 	)
 	if err != nil {
-		slog.Error("invalid token", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "invalid token", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
 		return
 	}
@@ -577,7 +590,7 @@ func (h *HttpEndpoints) unsubscribeNewsletter(c *gin.Context) {
 	// find user
 	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
 	if err != nil {
-		slog.Error("failed to get user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
 		return
 	}
@@ -586,12 +599,12 @@ func (h *HttpEndpoints) unsubscribeNewsletter(c *gin.Context) {
 	user.ContactPreferences.SubscribedToNewsletter = false
 	_, err = h.userDBConn.ReplaceUser(tokenInfos.InstanceID, user)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
 		return
 	}
 
-	slog.Info("unsubscribed user from newsletter", slog.String("userID", tokenInfos.UserID), slog.String("instanceID", tokenInfos.InstanceID))
+	slog.InfoContext(c, "unsubscribed user from newsletter", slog.String("userID", tokenInfos.UserID), slog.String("instanceID", tokenInfos.InstanceID))
 
 	c.JSON(http.StatusOK, gin.H{"message": "newsletter unsubscribed"})
 }
@@ -601,31 +614,49 @@ func (h *HttpEndpoints) updateContactPreferences(c *gin.Context) {
 
 	var req struct {
 		SubscribedToNewsletter bool `json:"subscribedToNewsletter"`
+		// optional, unchanged if not set
+		DigestFrequency             *string   `json:"digestFrequency"`
+		DigestOptOutStudies         *[]string `json:"digestOptOutStudies"`
+		SurveyReminderOptOutStudies *[]string `json:"surveyReminderOptOutStudies"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if req.DigestFrequency != nil && !digests.IsValidFrequency(*req.DigestFrequency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid digest frequency"})
+		return
+	}
+
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("failed to get user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to get user", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
 		return
 	}
 
 	user.ContactPreferences.SubscribedToNewsletter = req.SubscribedToNewsletter
+	if req.DigestFrequency != nil {
+		user.ContactPreferences.DigestFrequency = *req.DigestFrequency
+	}
+	if req.DigestOptOutStudies != nil {
+		user.ContactPreferences.DigestOptOutStudies = *req.DigestOptOutStudies
+	}
+	if req.SurveyReminderOptOutStudies != nil {
+		user.ContactPreferences.SurveyReminderOptOutStudies = *req.SurveyReminderOptOutStudies
+	}
 
 	_, err = h.userDBConn.ReplaceUser(token.InstanceID, user)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to update user", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
 		return
 	}
 
-	slog.Info("updated contact preferences", slog.String("userID", token.Subject), slog.String("instanceID", token.InstanceID))
+	slog.InfoContext(c, "updated contact preferences", slog.String("userID", token.Subject), slog.String("instanceID", token.InstanceID))
 
 	c.JSON(http.StatusOK, gin.H{"message": "contact preferences updated"})
 }
@@ -637,14 +668,14 @@ func (h *HttpEndpoints) deleteUser(c *gin.Context) {
 		ExitSurveyResponse *studyTypes.SurveyResponse `json:"exitSurveyResponse"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
 	if err != nil {
-		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
 		return
 	}
@@ -656,13 +687,13 @@ func (h *HttpEndpoints) deleteUser(c *gin.Context) {
 		} else {
 			exitResp = nil
 		}
-		studyService.OnProfileDeleted(token.InstanceID, profile.ID.Hex(), exitResp)
+		studyService.OnProfileDeleted(c.Request.Context(), token.InstanceID, profile.ID.Hex(), exitResp)
 	}
 
 	// delete all temp tokens
 	err = h.globalInfosDBConn.DeleteAllTempTokenForUser(token.InstanceID, user.ID.Hex(), "")
 	if err != nil {
-		slog.Error("failed to delete temp tokens", slog.String("error", err.Error()))
+		slog.ErrorContext(c, "failed to delete temp tokens", slog.String("error", err.Error()))
 	}
 
 	h.sendSimpleEmail(
@@ -675,14 +706,14 @@ func (h *HttpEndpoints) deleteUser(c *gin.Context) {
 		true,
 	)
 
-	err = h.userDBConn.DeleteUser(token.InstanceID, user.ID.Hex())
+	err = h.userDBConn.DeleteUserAccount(token.InstanceID, user.ID.Hex())
 	if err != nil {
-		slog.Error("cannot delete user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		slog.ErrorContext(c, "cannot delete user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete user"})
 		return
 	}
 
-	slog.Info("user deleted successfully", slog.String("userID", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
+	slog.InfoContext(c, "user deleted successfully", slog.String("userID", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
 }
@@ -1,26 +1,28 @@
 package apihandlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"time"
 
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
+	studyService "github.com/case-framework/case-backend/pkg/study"
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
 )
 
 func (h *HttpEndpoints) isInstanceAllowed(instanceID string) bool {
-	for _, id := range h.allowedInstanceIDs {
-		if id == instanceID {
-			return true
-		}
-	}
-	return false
+	return h.instances.IsAllowed(instanceID)
 }
 
+// prepTokenAndSendEmail returns an error if the token cannot be created, so that the task is retried. Failed emails are
+// queued for the messaging job and not returned, a retry would send them twice.
 func (h *HttpEndpoints) prepTokenAndSendEmail(
 	userID string,
 	instanceID string,
@@ -30,7 +32,7 @@ func (h *HttpEndpoints) prepTokenAndSendEmail(
 	expiresIn time.Duration,
 	emailTemplate string,
 	payload map[string]string,
-) {
+) error {
 	tempTokenInfos := userTypes.TempToken{
 		UserID:     userID,
 		InstanceID: instanceID,
@@ -44,7 +46,7 @@ func (h *HttpEndpoints) prepTokenAndSendEmail(
 	tempToken, err := h.globalInfosDBConn.AddTempToken(tempTokenInfos)
 	if err != nil {
 		slog.Error("failed to create token", slog.String("error", err.Error()))
-		return
+		return err
 	}
 
 	if payload == nil {
@@ -66,10 +68,10 @@ func (h *HttpEndpoints) prepTokenAndSendEmail(
 	)
 	if err != nil {
 		slog.Error("failed to send email", slog.String("error", err.Error()))
-		return
+		return nil
 	}
 	slog.Debug("email sent", slog.String("email", email))
-
+	return nil
 }
 
 func (h *HttpEndpoints) prepAndSendEmailVerification(
@@ -79,8 +81,8 @@ func (h *HttpEndpoints) prepAndSendEmailVerification(
 	lang string,
 	expiresIn time.Duration,
 	emailTemplate string,
-) {
-	h.prepTokenAndSendEmail(
+) error {
+	return h.prepTokenAndSendEmail(
 		userID,
 		instanceID,
 		email,
@@ -94,7 +96,7 @@ func (h *HttpEndpoints) prepAndSendEmailVerification(
 
 func (h *HttpEndpoints) sendSimpleEmail(
 	instanceID string, to []string, messageType string, studyKey string, lang string, payload map[string]string, useLowPrio bool,
-) {
+) error {
 	err := emailsending.SendInstantEmailByTemplate(
 		instanceID,
 		to,
@@ -107,8 +109,24 @@ func (h *HttpEndpoints) sendSimpleEmail(
 	)
 	if err != nil {
 		slog.Error("failed to send email", slog.String("error", err.Error()))
-		return
 	}
+	return nil
+}
+
+// emitSecurityEvent records an event of a participant account in the security event log, the user ID is empty if
+// the account is unknown
+func emitSecurityEvent(c *gin.Context, eventType string, outcome string, instanceID string, userID string, reason string) {
+	event := securityevents.Event{
+		Type:       eventType,
+		Outcome:    outcome,
+		InstanceID: instanceID,
+		Reason:     reason,
+	}
+	if userID != "" {
+		event.ActorType = securityevents.ACTOR_PARTICIPANT
+		event.ActorID = userID
+	}
+	securityevents.EmitForRequest(c, event)
 }
 
 func randomWait(minTimeSec int, maxTimeSec int) {
@@ -169,3 +187,33 @@ func (h *HttpEndpoints) checkAllProfilesBelongsToUser(instanceID, userID string,
 	}
 	return true
 }
+
+// resolveTempParticipant returns instanceID, studyKey and participantID of a temporary participant from the signed
+// temporary participant token. instanceID and studyKey of the request, if set, must match the token.
+func (h *HttpEndpoints) resolveTempParticipant(tempParticipantToken string, instanceID string, studyKey string) (string, string, string, error) {
+	if tempParticipantToken == "" {
+		return "", "", "", errors.New("missing temporary participant token")
+	}
+
+	claims, ok, err := jwthandling.ValidateTempParticipantToken(tempParticipantToken, h.tokenSignKey)
+	if err != nil || !ok {
+		return "", "", "", errors.New("invalid temporary participant token")
+	}
+	if (instanceID != "" && claims.InstanceID != instanceID) || (studyKey != "" && claims.StudyKey != studyKey) {
+		return "", "", "", errors.New("temporary participant token does not match request")
+	}
+	return claims.InstanceID, claims.StudyKey, claims.Subject, nil
+}
+
+func (h *HttpEndpoints) mergeTempParticipantIntoProfile(ctx context.Context, instanceID string, profileID string, tempParticipantToken string) error {
+	claims, ok, err := jwthandling.ValidateTempParticipantToken(tempParticipantToken, h.tokenSignKey)
+	if err != nil || !ok {
+		return errors.New("invalid temporary participant token")
+	}
+	if claims.InstanceID != instanceID {
+		return errors.New("temporary participant token belongs to a different instance")
+	}
+
+	_, err = studyService.OnMergeTempParticipant(ctx, instanceID, claims.StudyKey, profileID, claims.Subject)
+	return err
+}
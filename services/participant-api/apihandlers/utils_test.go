@@ -0,0 +1,64 @@
+package apihandlers
+
+import (
+	"testing"
+	"time"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+)
+
+func TestResolveTempParticipant(t *testing.T) {
+	signKey := "test-sign-key"
+	h := &HttpEndpoints{tokenSignKey: signKey}
+
+	t.Run("valid token", func(t *testing.T) {
+		token, err := jwthandling.GenerateNewTempParticipantToken(time.Minute, "temp-pid", "instance1", "study1", signKey)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		instanceID, studyKey, pid, err := h.resolveTempParticipant(token, "instance1", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if instanceID != "instance1" || studyKey != "study1" || pid != "temp-pid" {
+			t.Errorf("unexpected values: %s %s %s", instanceID, studyKey, pid)
+		}
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		if _, _, _, err := h.resolveTempParticipant("", "instance1", "study1"); err == nil {
+			t.Error("expected error without token")
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		token, _ := jwthandling.GenerateNewTempParticipantToken(time.Minute, "temp-pid", "instance1", "study1", "other-key")
+		if _, _, _, err := h.resolveTempParticipant(token, "instance1", "study1"); err == nil {
+			t.Error("expected error for token with wrong signature")
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		token, _ := jwthandling.GenerateNewTempParticipantToken(-time.Minute, "temp-pid", "instance1", "study1", signKey)
+		if _, _, _, err := h.resolveTempParticipant(token, "instance1", "study1"); err == nil {
+			t.Error("expected error for expired token")
+		}
+	})
+
+	t.Run("participant access token", func(t *testing.T) {
+		token, err := jwthandling.GenerateNewParticipantUserToken(time.Minute, "user1", "instance1", "temp-pid", nil, true, nil, nil, signKey, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, _, _, err := h.resolveTempParticipant(token, "instance1", "study1"); err == nil {
+			t.Error("expected error for token without temp-participant audience")
+		}
+	})
+
+	t.Run("token of other study", func(t *testing.T) {
+		token, _ := jwthandling.GenerateNewTempParticipantToken(time.Minute, "temp-pid", "instance1", "study1", signKey)
+		if _, _, _, err := h.resolveTempParticipant(token, "instance1", "study2"); err == nil {
+			t.Error("expected error for token of another study")
+		}
+	})
+}
@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
 	"github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	"github.com/case-framework/case-backend/pkg/cache"
+	configreload "github.com/case-framework/case-backend/pkg/config-reload"
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
 	"github.com/case-framework/case-backend/pkg/db"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	fieldencryption "github.com/case-framework/case-backend/pkg/field-encryption"
 	httpclient "github.com/case-framework/case-backend/pkg/http-client"
+	internalapi "github.com/case-framework/case-backend/pkg/internal-api"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/sandbox"
 	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/messaging/webhooks"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	"github.com/case-framework/case-backend/pkg/secrets"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
 	"github.com/case-framework/case-backend/pkg/study"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
+	"github.com/case-framework/case-backend/pkg/tracing"
 	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
 	"github.com/case-framework/case-backend/pkg/user-management/pwhash"
 	"github.com/case-framework/case-backend/pkg/utils"
@@ -21,6 +37,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/case-framework/case-backend/services/participant-api/apihandlers"
 
 	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
 	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
@@ -42,18 +59,35 @@ const (
 	ENV_GLOBAL_INFOS_DB_PASSWORD     = "GLOBAL_INFOS_DB_PASSWORD"
 	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
 	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
+	ENV_CACHE_REDIS_PASSWORD         = "CACHE_REDIS_PASSWORD"
 	ENV_SMS_GATEWAY_API_KEY          = "SMS_GATEWAY_API_KEY"
+	ENV_SYNTHETIC_MONITORING_TOKEN   = "SYNTHETIC_MONITORING_PROBE_TOKEN"
+	ENV_EMAIL_TRACKING_SIGNING_KEY   = "EMAIL_TRACKING_SIGNING_KEY"
+	ENV_METRICS_BEARER_TOKEN         = "METRICS_BEARER_TOKEN"
+	ENV_INTERNAL_API_JWT_SIGN_KEY    = "INTERNAL_API_SERVICE_JWT_SIGN_KEY"
+
+	ENV_CONFIDENTIAL_RESPONSE_VAULT_TOKEN = "CONFIDENTIAL_RESPONSE_VAULT_TOKEN"
 )
 
 type ParticipantApiConfig struct {
 	// Logging configs
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
 
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
+	// The config file is read again on SIGHUP or when it changes, applying allowed origins, the signup limit, email
+	// template constants and the log level
+	ConfigReload configreload.Config `json:"config_reload" yaml:"config_reload"`
+
 	// Gin configs
 	GinConfig struct {
 		DebugMode    bool     `json:"debug_mode" yaml:"debug_mode"`
 		AllowOrigins []string `json:"allow_origins" yaml:"allow_origins"`
 		Port         string   `json:"port" yaml:"port"`
+		// time to finish running requests and background tasks on shutdown, 30s if not set
+		ShutdownTimeout time.Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
 
 		// Mutual TLS configs
 		MTLS struct {
@@ -61,6 +95,14 @@ type ParticipantApiConfig struct {
 			CertificatePaths apihelpers.CertificatePaths `json:"certificate_paths" yaml:"certificate_paths"`
 		} `json:"mtls" yaml:"mtls"`
 		OtpConfigs []middlewares.OTPConfig `json:"otp_configs" yaml:"otp_configs"`
+		// Deprecation and sunset dates of the API versions, announced in the response headers
+		APIVersions map[string]apihelpers.APIVersionLifecycle `json:"api_versions" yaml:"api_versions"`
+		// Limits of request bodies, 1 MiB and a JSON depth of 64 if not set
+		BodyLimits middlewares.BodyLimitsConfig `json:"body_limits" yaml:"body_limits"`
+		// Replaying responses of retried signups and submissions sent with an Idempotency-Key header
+		Idempotency middlewares.IdempotencyConfig `json:"idempotency" yaml:"idempotency"`
+		// Cache-Control header of the route groups with ETags ("studies", "surveys"), "private, no-cache" if not set
+		CacheControl map[string]string `json:"cache_control" yaml:"cache_control"`
 	} `json:"gin_config" yaml:"gin_config"`
 
 	// user management configs
@@ -76,11 +118,17 @@ type ParticipantApiConfig struct {
 		} `json:"participant_user_jwt_config" yaml:"participant_user_jwt_config"`
 		MaxNewUsersPer5Minutes           int            `json:"max_new_users_per_5_minutes" yaml:"max_new_users_per_5_minutes"`
 		EmailContactVerificationTokenTTL time.Duration  `json:"email_contact_verification_token_ttl" yaml:"email_contact_verification_token_ttl"`
+		AccountSetupTokenTTL             time.Duration  `json:"account_setup_token_ttl" yaml:"account_setup_token_ttl"` // for signups without password, defaults to the contact verification token TTL
 		WeekdayAssignationWeights        map[string]int `json:"weekday_assignation_weights" yaml:"weekday_assignation_weights"`
 		BlockedPasswordsFilePath         string         `json:"blocked_passwords_file_path" yaml:"blocked_passwords_file_path"`
+
+		NextActionHints     apihandlers.NextActionHintsConfig     `json:"next_action_hints" yaml:"next_action_hints"`
+		SyntheticMonitoring apihandlers.SyntheticMonitoringConfig `json:"synthetic_monitoring" yaml:"synthetic_monitoring"`
 	} `json:"user_management_config" yaml:"user_management_config"`
 
 	AllowedInstanceIDs []string `json:"allowed_instance_ids" yaml:"allowed_instance_ids"`
+	// provisioned instances are allowed too, they are reloaded in this interval
+	InstanceReloadInterval time.Duration `json:"instance_reload_interval" yaml:"instance_reload_interval"`
 
 	// DB configs
 	DBConfigs struct {
@@ -90,11 +138,35 @@ type ParticipantApiConfig struct {
 		MessagingDB       db.DBConfigYaml `json:"messaging_db" yaml:"messaging_db"`
 	} `json:"db_configs" yaml:"db_configs"`
 
+	// Optional cache of studies, surveys and email templates, disabled if no type is set
+	Cache cache.Config `json:"cache" yaml:"cache"`
+
+	// gRPC API for trusted backend services on its own port, disabled by default
+	InternalAPI internalapi.Config `json:"internal_api" yaml:"internal_api"`
+
+	// Prometheus metrics endpoint, disabled by default
+	Metrics metrics.Config `json:"metrics" yaml:"metrics"`
+
+	// OpenTelemetry tracing, exported with OTLP over HTTP, disabled by default
+	Tracing tracing.Config `json:"tracing" yaml:"tracing"`
+
+	// Security event log for SIEM ingestion, disabled if no sinks are set
+	SecurityEvents securityevents.Config `json:"security_events" yaml:"security_events"`
+
+	// Domain events published to message brokers for external data pipelines, disabled if no brokers are set
+	DomainEvents domainevents.Config `json:"domain_events" yaml:"domain_events"`
+
+	// Runner of the work deferred by request handlers, e.g. sending emails
+	BackgroundTasks taskrunner.Config `json:"background_tasks" yaml:"background_tasks"`
+
 	// Study module config
 	StudyConfigs struct {
 		GlobalSecret string `json:"global_secret" yaml:"global_secret"`
 
 		ExternalServices []studyengine.ExternalService `json:"external_services" yaml:"external_services"`
+
+		// Field-level encryption of confidential responses, disabled if no provider is set
+		ConfidentialResponseEncryption fieldencryption.Config `json:"confidential_response_encryption" yaml:"confidential_response_encryption"`
 	} `json:"study_configs" yaml:"study_configs"`
 
 	FilestorePath string `json:"filestore_path" yaml:"filestore_path"`
@@ -107,16 +179,14 @@ var (
 	globalInfosDBService     *globalinfosDB.GlobalInfosDBService
 	messagingDBService       *messagingDB.MessagingDBService
 	studyDBService           *studyDB.StudyDBService
+
+	secretsResolver *secrets.Resolver
 )
 
 func init() {
 	// Read config from file
-	yamlFile, err := os.ReadFile(os.Getenv(ENV_CONFIG_FILE_PATH))
-	if err != nil {
-		panic(err)
-	}
-
-	err = yaml.UnmarshalStrict(yamlFile, &conf)
+	var err error
+	conf, err = readConfig(os.Getenv(ENV_CONFIG_FILE_PATH))
 	if err != nil {
 		panic(err)
 	}
@@ -137,6 +207,12 @@ func init() {
 	// Override secrets from environment variables
 	secretsOverride()
 
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
 	// Init DBs
 	initDBs()
 
@@ -153,6 +229,10 @@ func init() {
 
 	umUtils.InitWeekdayAssignationStrategy(conf.UserManagementConfig.WeekdayAssignationWeights)
 
+	if conf.UserManagementConfig.AccountSetupTokenTTL == 0 {
+		conf.UserManagementConfig.AccountSetupTokenTTL = conf.UserManagementConfig.EmailContactVerificationTokenTTL
+	}
+
 	if conf.UserManagementConfig.BlockedPasswordsFilePath != "" {
 		if err := umUtils.LoadBlockedPasswords(conf.UserManagementConfig.BlockedPasswordsFilePath); err != nil {
 			panic(err)
@@ -166,12 +246,35 @@ func init() {
 
 	// init message sending config
 	initMessageSendingConfig()
+}
 
-	checkParticipantFilestorePath()
+// readConfig reads the config file without secrets from the environment, also used when the config is reloaded
+func readConfig(path string) (ParticipantApiConfig, error) {
+	c := ParticipantApiConfig{}
+	yamlFile, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	err = yaml.UnmarshalStrict(yamlFile, &c)
+	return c, err
+}
+
+// resolveSecrets replaces secret references in the config, the resolver is kept to detect rotations
+func resolveSecrets() {
+	var err error
+	secretsResolver, err = secrets.Resolve(context.Background(), conf.Secrets, &conf)
+	if err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
 }
 
 func secretsOverride() {
 	// Override secrets from environment variables
+	if password := os.Getenv(ENV_CACHE_REDIS_PASSWORD); password != "" {
+		conf.Cache.Redis.Password = password
+	}
+
 	if apiKey := os.Getenv(ENV_SMTP_BRIDGE_API_KEY); apiKey != "" {
 		conf.MessagingConfigs.SmtpBridgeConfig.APIKey = apiKey
 	}
@@ -184,6 +287,10 @@ func secretsOverride() {
 		conf.DBConfigs.StudyDB.Password = dbPassword
 	}
 
+	if vaultToken := os.Getenv(ENV_CONFIDENTIAL_RESPONSE_VAULT_TOKEN); vaultToken != "" {
+		conf.StudyConfigs.ConfidentialResponseEncryption.VaultTransit.Token = vaultToken
+	}
+
 	if dbUsername := os.Getenv(ENV_PARTICIPANT_USER_DB_USERNAME); dbUsername != "" {
 		conf.DBConfigs.ParticipantUserDB.Username = dbUsername
 	}
@@ -214,19 +321,102 @@ func secretsOverride() {
 		}
 		conf.MessagingConfigs.SMSConfig.APIKey = smsGatewayAPIKey
 	}
+
+	if probeToken := os.Getenv(ENV_SYNTHETIC_MONITORING_TOKEN); probeToken != "" {
+		conf.UserManagementConfig.SyntheticMonitoring.ProbeToken = probeToken
+	}
+
+	if signingKey := os.Getenv(ENV_EMAIL_TRACKING_SIGNING_KEY); signingKey != "" {
+		conf.MessagingConfigs.EmailTracking.SigningKey = signingKey
+	}
+
+	if token := os.Getenv(ENV_METRICS_BEARER_TOKEN); token != "" {
+		conf.Metrics.BearerToken = token
+	}
+
+	if signKey := os.Getenv(ENV_INTERNAL_API_JWT_SIGN_KEY); signKey != "" {
+		conf.InternalAPI.ServiceJWTSignKey = signKey
+	}
 }
 
-func checkParticipantFilestorePath() {
-	// To store dynamically generated files
-	fsPath := conf.FilestorePath
-	if fsPath == "" {
-		slog.Error("Filestore path not set - configure PARTICIPANT_FILESTORE_PATH env variable.")
-		panic("Filestore path not set")
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+
+	problems.Required("gin_config.port", conf.GinConfig.Port)
+	problems.Duration("gin_config.shutdown_timeout", conf.GinConfig.ShutdownTimeout, time.Second, 0)
+	problems.MTLS("gin_config.mtls.certificate_paths", conf.GinConfig.MTLS.Use, conf.GinConfig.MTLS.CertificatePaths)
+	problems.APIVersions("gin_config.api_versions", conf.GinConfig.APIVersions, apiVersions)
+	problems.BodyLimits("gin_config.body_limits", conf.GinConfig.BodyLimits)
+	problems.Duration("gin_config.idempotency.window", conf.GinConfig.Idempotency.Window, time.Minute, 0)
+	for group, cacheControl := range conf.GinConfig.CacheControl {
+		problems.OneOf("gin_config.cache_control."+group, group, apihandlers.CacheGroups...)
+		if slices.Contains(apihandlers.AuthenticatedCacheGroups, group) && middlewares.AllowsSharedCaching(cacheControl) {
+			problems.Add("gin_config.cache_control."+group, "public and s-maxage are not allowed for authenticated routes")
+		}
+	}
+	for i, otpConfig := range conf.GinConfig.OtpConfigs {
+		problems.Required(fmt.Sprintf("gin_config.otp_configs[%d].route", i), otpConfig.Route)
+		problems.Duration(fmt.Sprintf("gin_config.otp_configs[%d].max_age", i), otpConfig.MaxAge, time.Second, 0)
+	}
+
+	umConfig := conf.UserManagementConfig
+	problems.Required("user_management_config.participant_user_jwt_config.sign_key", umConfig.ParticipantUserJWTConfig.SignKey)
+	problems.RequiredDuration("user_management_config.participant_user_jwt_config.expires_in", umConfig.ParticipantUserJWTConfig.ExpiresIn, time.Minute, 0)
+	problems.NonNegative("user_management_config.max_new_users_per_5_minutes", umConfig.MaxNewUsersPer5Minutes)
+	problems.RequiredDuration("user_management_config.email_contact_verification_token_ttl", umConfig.EmailContactVerificationTokenTTL, time.Minute, 0)
+	problems.Duration("user_management_config.account_setup_token_ttl", umConfig.AccountSetupTokenTTL, time.Minute, 0)
+	problems.File("user_management_config.blocked_passwords_file_path", umConfig.BlockedPasswordsFilePath)
+	if umConfig.SyntheticMonitoring.Enabled {
+		problems.Required("user_management_config.synthetic_monitoring.probe_token", umConfig.SyntheticMonitoring.ProbeToken)
 	}
 
-	if _, err := os.Stat(fsPath); os.IsNotExist(err) {
-		slog.Error("Filestore path does not exist", slog.String("path", fsPath))
-		panic("Filestore path does not exist")
+	if conf.InternalAPI.Enabled {
+		problems.Required("internal_api.port", conf.InternalAPI.Port)
+		problems.MTLS("internal_api.mtls.certificate_paths", conf.InternalAPI.MTLS.Use, conf.InternalAPI.MTLS.CertificatePaths)
+		if !conf.InternalAPI.MTLS.Use && conf.InternalAPI.ServiceJWTSignKey == "" {
+			problems.Add("internal_api", "mtls or service_jwt_sign_key is required")
+		}
+		if !conf.InternalAPI.MTLS.Use && conf.InternalAPI.ServiceJWTSignKey != "" {
+			// service JWTs are bearer tokens, they are only accepted over TLS
+			if conf.InternalAPI.TLS.ServerCertPath == "" || conf.InternalAPI.TLS.ServerKeyPath == "" {
+				problems.Add("internal_api.tls", "service_jwt_sign_key without TLS: server_cert_path and server_key_path are required if mtls is not used")
+			}
+			problems.File("internal_api.tls.server_cert_path", conf.InternalAPI.TLS.ServerCertPath)
+			problems.File("internal_api.tls.server_key_path", conf.InternalAPI.TLS.ServerKeyPath)
+		}
+		if len(conf.InternalAPI.Clients) == 0 {
+			problems.Add("internal_api.clients", "at least one client is required")
+		}
+	}
+
+	problems.Duration("instance_reload_interval", conf.InstanceReloadInterval, time.Second, 0)
+	problems.DB("db_configs.study_db", conf.DBConfigs.StudyDB)
+	problems.DB("db_configs.participant_user_db", conf.DBConfigs.ParticipantUserDB)
+	problems.DB("db_configs.global_infos_db", conf.DBConfigs.GlobalInfosDB)
+	problems.DB("db_configs.messaging_db", conf.DBConfigs.MessagingDB)
+
+	problems.Cache("cache", conf.Cache)
+	problems.Tracing("tracing", conf.Tracing)
+	problems.SecurityEvents("security_events", conf.SecurityEvents)
+	problems.DomainEvents("domain_events", conf.DomainEvents)
+	problems.TaskRunner("background_tasks", conf.BackgroundTasks)
+
+	problems.Required("study_configs.global_secret", conf.StudyConfigs.GlobalSecret)
+	problems.ExternalServices("study_configs.external_services", conf.StudyConfigs.ExternalServices)
+	problems.Encryption("study_configs.confidential_response_encryption", conf.StudyConfigs.ConfidentialResponseEncryption)
+
+	// To store dynamically generated files
+	problems.Required("filestore_path", conf.FilestorePath)
+	problems.Dir("filestore_path", conf.FilestorePath)
+
+	problems.Messaging("messaging_configs", conf.MessagingConfigs)
+
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
 	}
 }
 
@@ -240,6 +430,14 @@ func initStudyService() {
 		conf.StudyConfigs.GlobalSecret,
 		conf.StudyConfigs.ExternalServices,
 	)
+	study.SetParticipantFilestorePath(conf.FilestorePath)
+
+	keyProvider, err := fieldencryption.NewKeyProvider(conf.StudyConfigs.ConfidentialResponseEncryption)
+	if err != nil {
+		slog.Error("Error initializing confidential response encryption", slog.String("error", err.Error()))
+		panic(err)
+	}
+	studyDBService.EnableConfidentialResponseEncryption(keyProvider)
 }
 
 func initMessageSendingConfig() {
@@ -249,8 +447,24 @@ func initMessageSendingConfig() {
 		messagingDBService,
 	)
 
+	if err := emailsending.InitEmailProviders(
+		conf.MessagingConfigs.EmailProviders,
+		conf.MessagingConfigs.InstanceEmailProviders,
+	); err != nil {
+		slog.Error("Error initializing email providers", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	emailsending.InitEmailTracking(conf.MessagingConfigs.EmailTracking)
+	emailsending.InitEmailRetry(conf.MessagingConfigs.EmailRetry)
+	emailsending.InitEmailAttachments(conf.MessagingConfigs.EmailAttachments)
+	emailsending.InitLanguageFallbacks(conf.MessagingConfigs.LanguageFallbacks, conf.MessagingConfigs.InstanceLanguageFallbacks)
+	webhooks.Init(messagingDBService, conf.MessagingConfigs.Webhooks)
+	sandbox.Init(messagingDBService, conf.MessagingConfigs.Sandbox)
+
 	sms.Init(
 		conf.MessagingConfigs.SMSConfig,
+		conf.MessagingConfigs.InstanceSMSConfigs,
 		messagingDBService,
 	)
 }
@@ -288,4 +502,20 @@ func initDBs() {
 		slog.Error("Error connecting to Messaging DB", slog.String("error", err.Error()))
 		return
 	}
+
+	initCache()
+}
+
+func initCache() {
+	c, ttl, err := cache.New(conf.Cache)
+	if err != nil {
+		slog.Error("Error initializing cache, continuing without cache", slog.String("error", err.Error()))
+		return
+	}
+	if c == nil {
+		return
+	}
+	slog.Info("Cache enabled", slog.String("type", conf.Cache.Type), slog.Duration("ttl", ttl))
+	studyDBService.SetCache(c, ttl)
+	messagingDBService.SetCache(c, ttl)
 }
@@ -1,12 +1,27 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/apihelpers/health"
 	"github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	configreload "github.com/case-framework/case-backend/pkg/config-reload"
+	domainevents "github.com/case-framework/case-backend/pkg/domain-events"
+	"github.com/case-framework/case-backend/pkg/instances"
+	internalapi "github.com/case-framework/case-backend/pkg/internal-api"
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	requestid "github.com/case-framework/case-backend/pkg/request-id"
+	securityevents "github.com/case-framework/case-backend/pkg/security-events"
+	taskrunner "github.com/case-framework/case-backend/pkg/task-runner"
+	"github.com/case-framework/case-backend/pkg/tracing"
+	"github.com/case-framework/case-backend/pkg/utils"
 	"github.com/case-framework/case-backend/services/participant-api/apihandlers"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -14,76 +29,224 @@ import (
 
 var conf ParticipantApiConfig
 
+const (
+	defaultMaxBodySize  = 1 << 20
+	defaultMaxJSONDepth = 64
+)
+
+// API versions served under their URL prefix, the handlers adapt their payloads to apihelpers.RequestAPIVersion
+var apiVersions = []string{"v1", "v2"}
+
 func main() {
+	ctx, stop := apihelpers.ShutdownSignalContext()
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(conf.Tracing, "participant-api")
+	if err != nil {
+		slog.Error("failed to init tracing", slog.String("error", err.Error()))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	securityLog, err := securityevents.Init(conf.SecurityEvents, "participant-api")
+	if err != nil {
+		slog.Error("failed to init security event log", slog.String("error", err.Error()))
+	} else if securityLog != nil {
+		defer securityLog.Close()
+	}
+
+	domainEvents, err := domainevents.Init(conf.DomainEvents, "participant-api")
+	if err != nil {
+		slog.Error("failed to init domain event publisher", slog.String("error", err.Error()))
+	} else if domainEvents != nil {
+		defer domainEvents.Close()
+	}
 
 	// Start webserver
 	router := gin.Default()
+	// handlers log with the gin context, which has to look up the request ID in the request context
+	router.ContextWithFallback = true
+	router.Use(middlewares.RequestID())
+	router.Use(middlewares.BodyLimits(conf.GinConfig.BodyLimits.WithDefaults(defaultMaxBodySize, defaultMaxJSONDepth)))
+	allowedOrigins := apihelpers.NewAllowedOrigins(conf.GinConfig.AllowOrigins)
 	router.Use(cors.New(cors.Config{
-		// AllowAllOrigins: true,
-		AllowOrigins:     conf.GinConfig.AllowOrigins,
+		// replaced on config reload
+		AllowOriginFunc:  allowedOrigins.Allow,
 		AllowMethods:     []string{"POST", "GET", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length"},
-		ExposeHeaders:    []string{"Authorization", "Content-Type", "Content-Length"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length", requestid.HEADER, middlewares.HEADER_IDEMPOTENCY_KEY, middlewares.HEADER_IF_NONE_MATCH},
+		ExposeHeaders:    []string{"Authorization", "Content-Type", "Content-Length", apihelpers.HEADER_DEPRECATION, apihelpers.HEADER_SUNSET, apihelpers.HEADER_LINK, requestid.HEADER, middlewares.HEADER_IDEMPOTENT_REPLAYED, middlewares.HEADER_ETAG},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
+	metrics.Setup(router, conf.Metrics)
+	router.Use(tracing.Middleware())
+	if conf.Metrics.Enabled {
+		metrics.RegisterDBMetrics(metrics.DefaultRegistry)
+	}
 
 	// Add handlers
 	router.GET("/", apihandlers.HealthCheckHandle)
-	v1Root := router.Group("/v1")
-	v1Root.Use(middlewares.CheckOTP(conf.GinConfig.OtpConfigs, conf.UserManagementConfig.ParticipantUserJWTConfig.SignKey))
+	healthChecker := health.NewChecker(0).
+		Add("studyDB", studyDBService.Ping).
+		Add("participantUserDB", participantUserDBService.Ping).
+		Add("globalInfosDB", globalInfosDBService.Ping).
+		Add("messagingDB", messagingDBService.Ping).
+		Add("filestore", health.DirProbe(conf.FilestorePath))
+	if len(conf.MessagingConfigs.EmailProviders) == 0 {
+		healthChecker.Add("smtpBridge", health.URLProbe(conf.MessagingConfigs.SmtpBridgeConfig.URL))
+	}
+	for i, provider := range conf.MessagingConfigs.EmailProviders {
+		// API providers without URL override use their public endpoints, only configured URLs are probed
+		healthChecker.Add(fmt.Sprintf("emailProvider%d-%s", i, provider.Provider), health.URLProbe(provider.URL))
+	}
+	healthChecker.AddRoutes(router)
+
+	var instanceSource instances.InstanceSource
+	if globalInfosDBService != nil {
+		instanceSource = globalInfosDBService
+	}
+	instanceRegistry := instances.NewRegistry(conf.AllowedInstanceIDs, instanceSource)
+	if err := instanceRegistry.Reload(); err != nil {
+		slog.Error("failed to load instances", slog.String("error", err.Error()))
+	}
+	go instanceRegistry.Run(ctx, conf.InstanceReloadInterval)
 
-	v1APIHandlers := apihandlers.NewHTTPHandler(
+	backgroundTasks := taskrunner.New(conf.BackgroundTasks)
+	apiHandlers := apihandlers.NewHTTPHandler(
 		conf.UserManagementConfig.ParticipantUserJWTConfig.SignKey,
 		studyDBService,
 		participantUserDBService,
 		globalInfosDBService,
 		messagingDBService,
-		conf.AllowedInstanceIDs,
+		instanceRegistry,
 		conf.StudyConfigs.GlobalSecret,
 		conf.FilestorePath,
 		conf.UserManagementConfig.MaxNewUsersPer5Minutes,
 		apihandlers.TTLs{
 			AccessToken:                   conf.UserManagementConfig.ParticipantUserJWTConfig.ExpiresIn,
 			EmailContactVerificationToken: conf.UserManagementConfig.EmailContactVerificationTokenTTL,
+			AccountSetupToken:             conf.UserManagementConfig.AccountSetupTokenTTL,
 		},
+		conf.UserManagementConfig.NextActionHints,
+		conf.UserManagementConfig.SyntheticMonitoring,
+		conf.GinConfig.Idempotency,
+		conf.GinConfig.CacheControl,
+		backgroundTasks,
 	)
-	v1APIHandlers.AddParticipantAuthAPI(v1Root)
-	v1APIHandlers.AddPasswordResetAPI(v1Root)
-	v1APIHandlers.AddUserManagementAPI(v1Root)
-	v1APIHandlers.AddStudyServiceAPI(v1Root)
+	apihelpers.MountAPIVersions(router, apiVersions, conf.GinConfig.APIVersions, func(version string, root *gin.RouterGroup) {
+		root.Use(middlewares.CheckOTP(middlewares.OTPConfigsForAPIVersion(conf.GinConfig.OtpConfigs, version), conf.UserManagementConfig.ParticipantUserJWTConfig.SignKey))
+		apiHandlers.AddParticipantAuthAPI(root)
+		apiHandlers.AddPasswordResetAPI(root)
+		apiHandlers.AddUserManagementAPI(root)
+		apiHandlers.AddStudyServiceAPI(root)
+		apiHandlers.AddSMSCallbacksAPI(root)
+		apiHandlers.AddEmailEventsAPI(root)
+	})
+
+	var internalAPIStopped <-chan struct{}
+	if conf.InternalAPI.Enabled {
+		internalAPIStopped, err = internalapi.Serve(ctx, conf.InternalAPI, internalapi.NewServer(participantUserDBService, messagingDBService))
+		if err != nil {
+			slog.Error("Error starting internal API", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	// rotated secrets shut the process down gracefully if restart_on_rotation is set
+	go secretsResolver.Watch(ctx, stop)
+	startConfigReload(ctx, allowedOrigins, apiHandlers)
 
 	if conf.GinConfig.DebugMode {
 		apihelpers.WriteRoutesToFile(router, "participant-api-routes.txt")
 	}
 
-	// Start the server
-	slog.Info("Starting Participant API on port " + conf.GinConfig.Port)
-	if !conf.GinConfig.MTLS.Use {
-		err := router.Run(":" + conf.GinConfig.Port)
-		if err != nil {
-			slog.Error("Exited Participant API", slog.String("error", err.Error()))
-			return
-		}
-	} else {
+	server := &http.Server{
+		Addr:    ":" + conf.GinConfig.Port,
+		Handler: router,
+	}
+	certFile, keyFile := "", ""
+	if conf.GinConfig.MTLS.Use {
 		// Create tls config for mutual TLS
 		tlsConfig, err := apihelpers.LoadTLSConfig(conf.GinConfig.MTLS.CertificatePaths)
 		if err != nil {
 			slog.Error("Error loading TLS config.", slog.String("error", err.Error()))
 			return
 		}
+		server.TLSConfig = tlsConfig
+		certFile, keyFile = conf.GinConfig.MTLS.CertificatePaths.ServerCertPath, conf.GinConfig.MTLS.CertificatePaths.ServerKeyPath
+	}
 
-		server := &http.Server{
-			Addr:      ":" + conf.GinConfig.Port,
-			Handler:   router,
-			TLSConfig: tlsConfig,
-		}
+	// Start the server, it runs until SIGINT or SIGTERM
+	slog.Info("Starting Participant API on port " + conf.GinConfig.Port)
+	shutdownTimeout := apihelpers.ShutdownTimeout(conf.GinConfig.ShutdownTimeout)
+	if err := apihelpers.ServeUntilDone(ctx, server, certFile, keyFile, shutdownTimeout); err != nil {
+		slog.Error("Exited Participant API", slog.String("error", err.Error()))
+	}
+	stop()
 
-		err = server.ListenAndServeTLS(conf.GinConfig.MTLS.CertificatePaths.ServerCertPath, conf.GinConfig.MTLS.CertificatePaths.ServerKeyPath)
-		if err != nil {
-			slog.Error("Exited Participant API", slog.String("error", err.Error()))
-			return
+	// pending emails, e.g. verification links of new accounts, are sent before the DB clients are closed
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := backgroundTasks.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("background tasks not finished before shutdown", slog.String("error", err.Error()))
+	}
+	if internalAPIStopped != nil {
+		// pending internal API calls still use the DBs
+		select {
+		case <-internalAPIStopped:
+		case <-shutdownCtx.Done():
+			slog.Warn("internal API calls not finished before shutdown")
 		}
 	}
+	closeDBServices(shutdownCtx)
+	slog.Info("Participant API stopped")
+}
 
+// startConfigReload applies the reloadable config sections until the context is done
+func startConfigReload(ctx context.Context, allowedOrigins *apihelpers.AllowedOrigins, handlers *apihandlers.HttpEndpoints) {
+	reloader, err := configreload.New(os.Getenv(ENV_CONFIG_FILE_PATH), conf, readConfig,
+		configreload.Section[ParticipantApiConfig]{
+			Path:  "logging.log_level",
+			Apply: func(c *ParticipantApiConfig) { utils.SetLogLevel(c.Logging.LogLevel) },
+		},
+		configreload.Section[ParticipantApiConfig]{
+			Path:  "gin_config.allow_origins",
+			Apply: func(c *ParticipantApiConfig) { allowedOrigins.Set(c.GinConfig.AllowOrigins) },
+		},
+		configreload.Section[ParticipantApiConfig]{
+			Path: "user_management_config.max_new_users_per_5_minutes",
+			Apply: func(c *ParticipantApiConfig) {
+				handlers.SetMaxNewUsersPer5Minutes(c.UserManagementConfig.MaxNewUsersPer5Minutes)
+			},
+		},
+		configreload.Section[ParticipantApiConfig]{
+			Path: "messaging_configs.global_email_template_constants",
+			Apply: func(c *ParticipantApiConfig) {
+				emailsending.SetGlobalTemplateInfos(c.MessagingConfigs.GlobalEmailTemplateConstants)
+			},
+		},
+	)
+	if err != nil {
+		slog.Error("failed to init config reload", slog.String("error", err.Error()))
+		return
+	}
+	go reloader.Run(ctx, conf.ConfigReload)
+}
+
+// closeDBServices disconnects the DB clients after requests and background tasks are finished
+func closeDBServices(ctx context.Context) {
+	closers := map[string]func(context.Context) error{
+		"studyDB":           studyDBService.Close,
+		"participantUserDB": participantUserDBService.Close,
+		"globalInfosDB":     globalInfosDBService.Close,
+		"messagingDB":       messagingDBService.Close,
+	}
+	for name, closeDB := range closers {
+		if err := closeDB(ctx); err != nil {
+			slog.Error("failed to close DB client", slog.String("db", name), slog.String("error", err.Error()))
+		}
+	}
 }
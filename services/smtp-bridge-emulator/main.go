@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/apihelpers/health"
 	"github.com/case-framework/case-backend/services/smtp-bridge-emulator/apihandlers"
 
 	"github.com/gin-contrib/cors"
@@ -28,6 +29,7 @@ func main() {
 
 	// Add handlers
 	router.GET("/", apihandlers.HealthCheckHandle)
+	health.NewChecker(0).Add("emailsDir", health.DirProbe(conf.EmailsDir)).AddRoutes(router)
 	root := router.Group("/")
 
 	apiModule := apihandlers.NewHTTPHandler(conf.ApiKeys, conf.EmailsDir)
@@ -8,6 +8,7 @@ import (
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	smtp_client "github.com/case-framework/case-backend/pkg/smtp-client"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,6 +32,7 @@ type SendEmailReq struct {
 	Content         string                          `json:"content"`
 	HighPrio        bool                            `json:"highPrio"`
 	HeaderOverrides *messagingTypes.HeaderOverrides `json:"headerOverrides"`
+	Attachments     []smtp_client.Attachment        `json:"attachments,omitempty"`
 }
 
 func (h *HttpEndpoints) sendEmail(c *gin.Context) {
@@ -56,6 +58,7 @@ func (h *HttpEndpoints) sendEmail(c *gin.Context) {
 				req.Subject,
 				req.Content,
 				req.HeaderOverrides,
+				req.Attachments...,
 			)
 		} else {
 			err = h.lowPrioSmtpClients.SendMail(
@@ -63,6 +66,7 @@ func (h *HttpEndpoints) sendEmail(c *gin.Context) {
 				req.Subject,
 				req.Content,
 				req.HeaderOverrides,
+				req.Attachments...,
 			)
 		}
 		if err != nil {
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
 
+	configvalidation "github.com/case-framework/case-backend/pkg/config-validation"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	"github.com/case-framework/case-backend/pkg/secrets"
 	smtp_client "github.com/case-framework/case-backend/pkg/smtp-client"
+	"github.com/case-framework/case-backend/pkg/tracing"
 	"github.com/case-framework/case-backend/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"gopkg.in/yaml.v2"
@@ -11,12 +18,17 @@ import (
 
 // Environment variables
 const (
-	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+	ENV_CONFIG_FILE_PATH     = "CONFIG_FILE_PATH"
+	ENV_METRICS_BEARER_TOKEN = "METRICS_BEARER_TOKEN"
 )
 
 type config struct {
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
 
+	// Config values of the form "secret:<provider>:<name>" are fetched from Vault, AWS or GCP secret managers or
+	// mounted files at startup
+	Secrets secrets.Config `json:"secrets" yaml:"secrets"`
+
 	// Gin configs
 	GinConfig struct {
 		DebugMode    bool     `json:"debug_mode" yaml:"debug_mode"`
@@ -30,6 +42,12 @@ type config struct {
 		HighPrio smtp_client.SmtpServerList `json:"high_prio" yaml:"high_prio"`
 		LowPrio  smtp_client.SmtpServerList `json:"low_prio" yaml:"low_prio"`
 	} `json:"smtp_server_config" yaml:"smtp_server_config"`
+
+	// Prometheus metrics endpoint, disabled by default
+	Metrics metrics.Config `json:"metrics" yaml:"metrics"`
+
+	// OpenTelemetry tracing, exported with OTLP over HTTP, disabled by default
+	Tracing tracing.Config `json:"tracing" yaml:"tracing"`
 }
 
 func init() {
@@ -44,6 +62,10 @@ func init() {
 		panic(err)
 	}
 
+	if token := os.Getenv(ENV_METRICS_BEARER_TOKEN); token != "" {
+		conf.Metrics.BearerToken = token
+	}
+
 	// Init logger:
 	utils.InitLogger(
 		conf.Logging.LogLevel,
@@ -57,12 +79,48 @@ func init() {
 		conf.Logging.IncludeBuildInfo,
 	)
 
+	// Resolve secret references, values set from environment variables may be references too
+	resolveSecrets()
+
+	// Report all config problems before connecting to the DBs
+	validateConfig()
+
 	if !conf.GinConfig.DebugMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
+}
 
-	if len(conf.ApiKeys) == 0 {
-		panic("No API keys provided for SMTP Bridge API.")
+// validateConfig checks the whole config and panics with all problems found
+func validateConfig() {
+	problems := configvalidation.Problems{}
+	problems.Logging("logging", conf.Logging)
+	problems.Secrets("secrets", conf.Secrets)
+	problems.Required("gin_config.port", conf.GinConfig.Port)
+	problems.RequiredList("api_keys", conf.ApiKeys)
+	for name, servers := range map[string]smtp_client.SmtpServerList{
+		"smtp_server_config.high_prio": conf.SMTPServerConfig.HighPrio,
+		"smtp_server_config.low_prio":  conf.SMTPServerConfig.LowPrio,
+	} {
+		if len(servers.Servers) == 0 {
+			problems.Add(name+".servers", "at least one server is required")
+		}
+		for i, server := range servers.Servers {
+			problems.Required(fmt.Sprintf("%s.servers[%d].host", name, i), server.Host)
+			problems.Required(fmt.Sprintf("%s.servers[%d].port", name, i), server.Port)
+		}
 	}
+	problems.Tracing("tracing", conf.Tracing)
 
+	if err := problems.Err(); err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
+}
+
+// resolveSecrets replaces secret references in the config
+func resolveSecrets() {
+	if _, err := secrets.Resolve(context.Background(), conf.Secrets, &conf); err != nil {
+		slog.Error("failed to resolve secrets", slog.String("error", err.Error()))
+		panic(err)
+	}
 }
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/apihelpers/health"
+	"github.com/case-framework/case-backend/pkg/metrics"
+	"github.com/case-framework/case-backend/pkg/tracing"
 	"github.com/case-framework/case-backend/services/smtp-bridge/apihandlers"
 
 	"github.com/gin-gonic/gin"
@@ -14,8 +18,20 @@ import (
 var conf config
 
 func main() {
+	shutdownTracing, err := tracing.Init(conf.Tracing, "smtp-bridge")
+	if err != nil {
+		slog.Error("failed to init tracing", slog.String("error", err.Error()))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
 	// Start webserver
 	router := gin.Default()
+	metrics.Setup(router, conf.Metrics)
+	router.Use(tracing.Middleware())
 
 	smtpClients, err := sc.NewSmtpClients(conf.SMTPServerConfig.LowPrio)
 	if err != nil {
@@ -30,6 +46,14 @@ func main() {
 
 	// Add handlers
 	router.GET("/", apihandlers.HealthCheckHandle)
+	healthChecker := health.NewChecker(0)
+	for _, server := range conf.SMTPServerConfig.HighPrio.Servers {
+		healthChecker.Add("highPrioSmtp-"+server.Address(), health.TCPProbe(server.Address()))
+	}
+	for _, server := range conf.SMTPServerConfig.LowPrio.Servers {
+		healthChecker.Add("lowPrioSmtp-"+server.Address(), health.TCPProbe(server.Address()))
+	}
+	healthChecker.AddRoutes(router)
 	root := router.Group("/")
 	apiModule := apihandlers.NewHTTPHandler(
 		conf.ApiKeys,
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME            = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD            = "STUDY_DB_PASSWORD"
+	ENV_PARTICIPANT_USER_DB_USERNAME = "PARTICIPANT_USER_DB_USERNAME"
+	ENV_PARTICIPANT_USER_DB_PASSWORD = "PARTICIPANT_USER_DB_PASSWORD"
+	ENV_MANAGEMENT_USER_DB_USERNAME  = "MANAGEMENT_USER_DB_USERNAME"
+	ENV_MANAGEMENT_USER_DB_PASSWORD  = "MANAGEMENT_USER_DB_PASSWORD"
+	ENV_GLOBAL_INFOS_DB_USERNAME     = "GLOBAL_INFOS_DB_USERNAME"
+	ENV_GLOBAL_INFOS_DB_PASSWORD     = "GLOBAL_INFOS_DB_PASSWORD"
+	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
+	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
+)
+
+// config uses the same format as the config files of the services and jobs, DBs without connection string are skipped
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// DB configs
+	DBConfigs struct {
+		ParticipantUserDB db.DBConfigYaml `json:"participant_user_db" yaml:"participant_user_db"`
+		ManagementUserDB  db.DBConfigYaml `json:"management_user_db" yaml:"management_user_db"`
+		GlobalInfosDB     db.DBConfigYaml `json:"global_infos_db" yaml:"global_infos_db"`
+		MessagingDB       db.DBConfigYaml `json:"messaging_db" yaml:"messaging_db"`
+		StudyDB           db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
+}
+
+// indexOptions are set through command line flags
+type indexOptions struct {
+	configFile string
+	// overrides the instance IDs of the config file
+	instanceIDs []string
+
+	dryRun            bool
+	dropObsolete      bool
+	recreateDiffering bool
+}
+
+var conf config
+
+func parseFlags(args []string) (indexOptions, error) {
+	opts := indexOptions{}
+	var instanceIDs string
+
+	fs := flag.NewFlagSet("db-indexes", flag.ContinueOnError)
+	fs.StringVar(&opts.configFile, "config", os.Getenv(ENV_CONFIG_FILE_PATH), "path of the config file, defaults to $"+ENV_CONFIG_FILE_PATH)
+	fs.StringVar(&instanceIDs, "instances", "", "comma separated instance IDs, defaults to instance_ids of the config file")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "only report missing, obsolete and differing indexes")
+	fs.BoolVar(&opts.dropObsolete, "drop-obsolete", false, "drop indexes that are not in the registry")
+	fs.BoolVar(&opts.recreateDiffering, "recreate-differing", false, "drop and create again indexes whose keys or options differ from the registry")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	for _, id := range strings.Split(instanceIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			opts.instanceIDs = append(opts.instanceIDs, id)
+		}
+	}
+
+	if opts.configFile == "" {
+		return opts, fmt.Errorf("config file not set, use -config or $%s", ENV_CONFIG_FILE_PATH)
+	}
+	if opts.dryRun && opts.dropObsolete {
+		return opts, fmt.Errorf("-dry-run and -drop-obsolete cannot be combined")
+	}
+	if opts.dryRun && opts.recreateDiffering {
+		return opts, fmt.Errorf("-dry-run and -recreate-differing cannot be combined")
+	}
+	return opts, nil
+}
+
+func initConfig(configFile string) error {
+	yamlFile, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	// not strict, so that config files of the services and jobs can be used as they are
+	if err := yaml.Unmarshal(yamlFile, &conf); err != nil {
+		return err
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+	return nil
+}
+
+func secretsOverride() {
+	overrideDBCredentials(&conf.DBConfigs.StudyDB, ENV_STUDY_DB_USERNAME, ENV_STUDY_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.ParticipantUserDB, ENV_PARTICIPANT_USER_DB_USERNAME, ENV_PARTICIPANT_USER_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.ManagementUserDB, ENV_MANAGEMENT_USER_DB_USERNAME, ENV_MANAGEMENT_USER_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.GlobalInfosDB, ENV_GLOBAL_INFOS_DB_USERNAME, ENV_GLOBAL_INFOS_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.MessagingDB, ENV_MESSAGING_DB_USERNAME, ENV_MESSAGING_DB_PASSWORD)
+}
+
+func overrideDBCredentials(dbConf *db.DBConfigYaml, usernameEnv string, passwordEnv string) {
+	if dbUsername := os.Getenv(usernameEnv); dbUsername != "" {
+		dbConf.Username = dbUsername
+	}
+	if dbPassword := os.Getenv(passwordEnv); dbPassword != "" {
+		dbConf.Password = dbPassword
+	}
+}
+
+func dbConfig(yamlObj db.DBConfigYaml, instanceIDs []string) db.DBConfig {
+	dbConf := db.DBConfigFromYamlObj(yamlObj, instanceIDs)
+	// indexes are handled by the tool with its own options
+	dbConf.RunIndexCreation = false
+	return dbConf
+}
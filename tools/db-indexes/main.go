@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// db-indexes compares the indexes of all configured DBs with the index registry, creates missing indexes and
+// prints the report as JSON. Services create missing indexes at startup if run_index_creation is set, this tool
+// can run instead, e.g. as a deployment step.
+// Usage: db-indexes -config config.yaml [-instances id1,id2] [-dry-run] [-drop-obsolete] [-recreate-differing]
+func main() {
+	os.Exit(run())
+}
+
+// run returns the exit code: 0 if all indexes are in place, 1 if a DB or collection failed, 2 for invalid options
+func run() int {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := initConfig(opts.configFile); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to read config:", err)
+		return 1
+	}
+
+	instanceIDs := conf.InstanceIDs
+	if len(opts.instanceIDs) > 0 {
+		instanceIDs = opts.instanceIDs
+	}
+	if len(instanceIDs) == 0 {
+		slog.Error("no instance IDs configured")
+		return 2
+	}
+
+	ensureOpts := db.EnsureIndexesOptions{
+		DryRun:            opts.dryRun,
+		DropObsolete:      opts.dropObsolete,
+		RecreateDiffering: opts.recreateDiffering,
+	}
+
+	reports := []db.IndexReport{}
+	failed := false
+	for _, target := range dbTargets(instanceIDs) {
+		dbReports, err := target.ensure(ensureOpts)
+		if err != nil {
+			slog.Error("failed to ensure indexes", slog.String("db", target.label), slog.String("error", err.Error()))
+			failed = true
+		}
+		for _, report := range dbReports {
+			report.Log()
+			failed = failed || report.HasErrors()
+		}
+		reports = append(reports, dbReports...)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(reports); err != nil {
+		slog.Error("failed to write report", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+type dbTarget struct {
+	label  string
+	config db.DBConfigYaml
+	ensure func(opts db.EnsureIndexesOptions) ([]db.IndexReport, error)
+}
+
+// dbTargets connects to each configured DB when its indexes are ensured, DBs without config are skipped
+func dbTargets(instanceIDs []string) []dbTarget {
+	targets := []dbTarget{
+		{
+			label:  "participant user DB",
+			config: conf.DBConfigs.ParticipantUserDB,
+			ensure: func(opts db.EnsureIndexesOptions) ([]db.IndexReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.ParticipantUserDB, instanceIDs)
+				dbService, err := userDB.NewParticipantUserDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) (db.IndexReport, error) {
+					return dbService.EnsureIndexes(instanceID, opts), nil
+				})
+			},
+		},
+		{
+			label:  "management user DB",
+			config: conf.DBConfigs.ManagementUserDB,
+			ensure: func(opts db.EnsureIndexesOptions) ([]db.IndexReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.ManagementUserDB, instanceIDs)
+				dbService, err := muDB.NewManagementUserDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) (db.IndexReport, error) {
+					return dbService.EnsureIndexes(instanceID, opts), nil
+				})
+			},
+		},
+		{
+			label:  "global infos DB",
+			config: conf.DBConfigs.GlobalInfosDB,
+			ensure: func(opts db.EnsureIndexesOptions) ([]db.IndexReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.GlobalInfosDB, instanceIDs)
+				dbService, err := globalinfosDB.NewGlobalInfosDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				// the global infos DB is shared by all instances
+				return []db.IndexReport{dbService.EnsureIndexes(opts)}, nil
+			},
+		},
+		{
+			label:  "messaging DB",
+			config: conf.DBConfigs.MessagingDB,
+			ensure: func(opts db.EnsureIndexesOptions) ([]db.IndexReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.MessagingDB, instanceIDs)
+				dbService, err := messagingDB.NewMessagingDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) (db.IndexReport, error) {
+					return dbService.EnsureIndexes(instanceID, opts), nil
+				})
+			},
+		},
+		{
+			label:  "study DB",
+			config: conf.DBConfigs.StudyDB,
+			ensure: func(opts db.EnsureIndexesOptions) ([]db.IndexReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.StudyDB, instanceIDs)
+				dbService, err := studyDB.NewStudyDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) (db.IndexReport, error) {
+					return dbService.EnsureIndexes(instanceID, opts)
+				})
+			},
+		},
+	}
+
+	configured := []dbTarget{}
+	for _, target := range targets {
+		if target.config.ConnectionStr == "" {
+			slog.Info("DB not configured, skipping", slog.String("db", target.label))
+			continue
+		}
+		configured = append(configured, target)
+	}
+	return configured
+}
+
+func perInstance(instanceIDs []string, ensure func(instanceID string) (db.IndexReport, error)) ([]db.IndexReport, error) {
+	reports := []db.IndexReport{}
+	for _, instanceID := range instanceIDs {
+		report, err := ensure(instanceID)
+		if err != nil {
+			return reports, fmt.Errorf("instance %s: %w", instanceID, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func disconnect(client *mongo.Client) {
+	if err := client.Disconnect(context.Background()); err != nil {
+		slog.Error("Error closing DB connection", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME            = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD            = "STUDY_DB_PASSWORD"
+	ENV_PARTICIPANT_USER_DB_USERNAME = "PARTICIPANT_USER_DB_USERNAME"
+	ENV_PARTICIPANT_USER_DB_PASSWORD = "PARTICIPANT_USER_DB_PASSWORD"
+	ENV_MANAGEMENT_USER_DB_USERNAME  = "MANAGEMENT_USER_DB_USERNAME"
+	ENV_MANAGEMENT_USER_DB_PASSWORD  = "MANAGEMENT_USER_DB_PASSWORD"
+	ENV_GLOBAL_INFOS_DB_USERNAME     = "GLOBAL_INFOS_DB_USERNAME"
+	ENV_GLOBAL_INFOS_DB_PASSWORD     = "GLOBAL_INFOS_DB_PASSWORD"
+	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
+	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
+)
+
+// config uses the same format as the config files of the services and jobs, DBs without connection string are skipped
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// DB configs
+	DBConfigs struct {
+		ParticipantUserDB db.DBConfigYaml `json:"participant_user_db" yaml:"participant_user_db"`
+		ManagementUserDB  db.DBConfigYaml `json:"management_user_db" yaml:"management_user_db"`
+		GlobalInfosDB     db.DBConfigYaml `json:"global_infos_db" yaml:"global_infos_db"`
+		MessagingDB       db.DBConfigYaml `json:"messaging_db" yaml:"messaging_db"`
+		StudyDB           db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
+}
+
+// migrationOptions are set through command line flags
+type migrationOptions struct {
+	configFile string
+	// overrides the instance IDs of the config file
+	instanceIDs []string
+	// config keys of the DBs to migrate, e.g. study_db, empty for all configured DBs
+	dbs []string
+
+	dryRun        bool
+	targetVersion int
+}
+
+var conf config
+
+// dbKeys are the keys of the DB sections in the config file
+var dbKeys = []string{"participant_user_db", "management_user_db", "global_infos_db", "messaging_db", "study_db"}
+
+func parseFlags(args []string) (migrationOptions, error) {
+	opts := migrationOptions{}
+	var instanceIDs, dbs string
+
+	fs := flag.NewFlagSet("db-migrations", flag.ContinueOnError)
+	fs.StringVar(&opts.configFile, "config", os.Getenv(ENV_CONFIG_FILE_PATH), "path of the config file, defaults to $"+ENV_CONFIG_FILE_PATH)
+	fs.StringVar(&instanceIDs, "instances", "", "comma separated instance IDs, defaults to instance_ids of the config file")
+	fs.StringVar(&dbs, "dbs", "", "comma separated DBs to migrate: "+strings.Join(dbKeys, ", ")+", defaults to all configured DBs")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "only report pending migrations")
+	fs.IntVar(&opts.targetVersion, "target", 0, "apply migrations up to and including this version, requires a single DB in -dbs")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	opts.instanceIDs = splitList(instanceIDs)
+	opts.dbs = splitList(dbs)
+
+	if opts.configFile == "" {
+		return opts, fmt.Errorf("config file not set, use -config or $%s", ENV_CONFIG_FILE_PATH)
+	}
+	for _, key := range opts.dbs {
+		if !slices.Contains(dbKeys, key) {
+			return opts, fmt.Errorf("unknown DB %s in -dbs", key)
+		}
+	}
+	if opts.targetVersion < 0 {
+		return opts, fmt.Errorf("-target must not be negative")
+	}
+	// versions are counted per DB
+	if opts.targetVersion > 0 && len(opts.dbs) != 1 {
+		return opts, fmt.Errorf("-target requires a single DB in -dbs")
+	}
+	return opts, nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func initConfig(configFile string) error {
+	yamlFile, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	// not strict, so that config files of the services and jobs can be used as they are
+	if err := yaml.Unmarshal(yamlFile, &conf); err != nil {
+		return err
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+	return nil
+}
+
+func secretsOverride() {
+	overrideDBCredentials(&conf.DBConfigs.StudyDB, ENV_STUDY_DB_USERNAME, ENV_STUDY_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.ParticipantUserDB, ENV_PARTICIPANT_USER_DB_USERNAME, ENV_PARTICIPANT_USER_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.ManagementUserDB, ENV_MANAGEMENT_USER_DB_USERNAME, ENV_MANAGEMENT_USER_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.GlobalInfosDB, ENV_GLOBAL_INFOS_DB_USERNAME, ENV_GLOBAL_INFOS_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.MessagingDB, ENV_MESSAGING_DB_USERNAME, ENV_MESSAGING_DB_PASSWORD)
+}
+
+func overrideDBCredentials(dbConf *db.DBConfigYaml, usernameEnv string, passwordEnv string) {
+	if dbUsername := os.Getenv(usernameEnv); dbUsername != "" {
+		dbConf.Username = dbUsername
+	}
+	if dbPassword := os.Getenv(passwordEnv); dbPassword != "" {
+		dbConf.Password = dbPassword
+	}
+}
+
+func dbConfig(yamlObj db.DBConfigYaml, instanceIDs []string) db.DBConfig {
+	dbConf := db.DBConfigFromYamlObj(yamlObj, instanceIDs)
+	// the tool only applies migrations, with its own options
+	dbConf.RunIndexCreation = false
+	dbConf.RunMigrations = false
+	return dbConf
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	muDB "github.com/case-framework/case-backend/pkg/db/management-user"
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// db-migrations applies the pending migrations of the configured DBs in order and prints the report as JSON.
+// Services apply migrations at startup if run_migrations is set, this tool can run instead, e.g. as a deployment
+// step. A migration that was interrupted stays marked as running and has to be checked and its entry removed from
+// the migrations collection before migrations of the DB can continue.
+// Usage: db-migrations -config config.yaml [-instances id1,id2] [-dbs study_db] [-target 3] [-dry-run]
+func main() {
+	os.Exit(run())
+}
+
+// run returns the exit code: 0 if all migrations are applied, 1 if a DB or migration failed, 2 for invalid options
+func run() int {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := initConfig(opts.configFile); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to read config:", err)
+		return 1
+	}
+
+	instanceIDs := conf.InstanceIDs
+	if len(opts.instanceIDs) > 0 {
+		instanceIDs = opts.instanceIDs
+	}
+	if len(instanceIDs) == 0 {
+		slog.Error("no instance IDs configured")
+		return 2
+	}
+
+	// stop cleanly on Ctrl+C, the running migration is cancelled and retried on the next run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	migrationOpts := db.MigrationOptions{
+		DryRun:        opts.dryRun,
+		TargetVersion: opts.targetVersion,
+	}
+
+	reports := []db.MigrationReport{}
+	failed := false
+	for _, target := range dbTargets(instanceIDs, opts.dbs) {
+		if ctx.Err() != nil {
+			break
+		}
+		dbReports, err := target.apply(ctx, migrationOpts)
+		if err != nil {
+			slog.Error("failed to apply migrations", slog.String("db", target.label), slog.String("error", err.Error()))
+			failed = true
+		}
+		for _, report := range dbReports {
+			report.Log()
+			failed = failed || report.HasErrors()
+		}
+		reports = append(reports, dbReports...)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(reports); err != nil {
+		slog.Error("failed to write report", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if failed || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+type dbTarget struct {
+	key    string
+	label  string
+	config db.DBConfigYaml
+	apply  func(ctx context.Context, opts db.MigrationOptions) ([]db.MigrationReport, error)
+}
+
+// dbTargets connects to each selected DB when its migrations are applied, DBs without config are skipped
+func dbTargets(instanceIDs []string, keys []string) []dbTarget {
+	targets := []dbTarget{
+		{
+			key:    "participant_user_db",
+			label:  "participant user DB",
+			config: conf.DBConfigs.ParticipantUserDB,
+			apply: func(ctx context.Context, opts db.MigrationOptions) ([]db.MigrationReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.ParticipantUserDB, instanceIDs)
+				dbService, err := userDB.NewParticipantUserDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) db.MigrationReport {
+					return dbService.ApplyMigrations(ctx, instanceID, opts)
+				}), nil
+			},
+		},
+		{
+			key:    "management_user_db",
+			label:  "management user DB",
+			config: conf.DBConfigs.ManagementUserDB,
+			apply: func(ctx context.Context, opts db.MigrationOptions) ([]db.MigrationReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.ManagementUserDB, instanceIDs)
+				dbService, err := muDB.NewManagementUserDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) db.MigrationReport {
+					return dbService.ApplyMigrations(ctx, instanceID, opts)
+				}), nil
+			},
+		},
+		{
+			key:    "global_infos_db",
+			label:  "global infos DB",
+			config: conf.DBConfigs.GlobalInfosDB,
+			apply: func(ctx context.Context, opts db.MigrationOptions) ([]db.MigrationReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.GlobalInfosDB, instanceIDs)
+				dbService, err := globalinfosDB.NewGlobalInfosDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				// the global infos DB is shared by all instances
+				return []db.MigrationReport{dbService.ApplyMigrations(ctx, opts)}, nil
+			},
+		},
+		{
+			key:    "messaging_db",
+			label:  "messaging DB",
+			config: conf.DBConfigs.MessagingDB,
+			apply: func(ctx context.Context, opts db.MigrationOptions) ([]db.MigrationReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.MessagingDB, instanceIDs)
+				dbService, err := messagingDB.NewMessagingDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) db.MigrationReport {
+					return dbService.ApplyMigrations(ctx, instanceID, opts)
+				}), nil
+			},
+		},
+		{
+			key:    "study_db",
+			label:  "study DB",
+			config: conf.DBConfigs.StudyDB,
+			apply: func(ctx context.Context, opts db.MigrationOptions) ([]db.MigrationReport, error) {
+				dbConf := dbConfig(conf.DBConfigs.StudyDB, instanceIDs)
+				dbService, err := studyDB.NewStudyDBService(dbConf)
+				if err != nil {
+					return nil, err
+				}
+				defer disconnect(dbService.DBClient)
+				return perInstance(instanceIDs, func(instanceID string) db.MigrationReport {
+					return dbService.ApplyMigrations(ctx, instanceID, opts)
+				}), nil
+			},
+		},
+	}
+
+	configured := []dbTarget{}
+	for _, target := range targets {
+		if len(keys) > 0 && !slices.Contains(keys, target.key) {
+			continue
+		}
+		if target.config.ConnectionStr == "" {
+			slog.Info("DB not configured, skipping", slog.String("db", target.label))
+			continue
+		}
+		configured = append(configured, target)
+	}
+	return configured
+}
+
+func perInstance(instanceIDs []string, apply func(instanceID string) db.MigrationReport) []db.MigrationReport {
+	reports := []db.MigrationReport{}
+	for _, instanceID := range instanceIDs {
+		reports = append(reports, apply(instanceID))
+	}
+	return reports
+}
+
+func disconnect(client *mongo.Client) {
+	if err := client.Disconnect(context.Background()); err != nil {
+		slog.Error("Error closing DB connection", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/backup"
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME            = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD            = "STUDY_DB_PASSWORD"
+	ENV_PARTICIPANT_USER_DB_USERNAME = "PARTICIPANT_USER_DB_USERNAME"
+	ENV_PARTICIPANT_USER_DB_PASSWORD = "PARTICIPANT_USER_DB_PASSWORD"
+	ENV_BACKUP_S3_ACCESS_KEY_ID      = "BACKUP_S3_ACCESS_KEY_ID"
+	ENV_BACKUP_S3_SECRET_ACCESS_KEY  = "BACKUP_S3_SECRET_ACCESS_KEY"
+	ENV_BACKUP_VAULT_TOKEN           = "BACKUP_VAULT_TOKEN"
+)
+
+// config uses the same format as the config file of the db-backup job
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// DB configs
+	DBConfigs struct {
+		StudyDB           db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+		ParticipantUserDB db.DBConfigYaml `json:"participant_user_db" yaml:"participant_user_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+
+	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
+
+	Backup backup.Config `json:"backup" yaml:"backup"`
+}
+
+// restoreOptions are set through command line flags
+type restoreOptions struct {
+	configFile string
+	// name of the archive in the configured storage
+	archive string
+	// path of a downloaded archive, used instead of the storage
+	file string
+	// restores into another instance than the one the archive was created for
+	targetInstanceID string
+	// restores only these entries, e.g. "studyDB/study1_surveyResponses"
+	entries []string
+
+	drop   bool
+	dryRun bool
+}
+
+var conf config
+
+func parseFlags(args []string) (restoreOptions, error) {
+	opts := restoreOptions{}
+	var entries string
+
+	fs := flag.NewFlagSet("db-restore", flag.ContinueOnError)
+	fs.StringVar(&opts.configFile, "config", os.Getenv(ENV_CONFIG_FILE_PATH), "path of the config file, defaults to $"+ENV_CONFIG_FILE_PATH)
+	fs.StringVar(&opts.archive, "archive", "", "name of the archive in the backup storage, e.g. instance1/backup_20240510T020000Z.cbk")
+	fs.StringVar(&opts.file, "file", "", "path of a local archive file, instead of -archive")
+	fs.StringVar(&opts.targetInstanceID, "target-instance", "", "instance to restore into, defaults to the instance of the archive")
+	fs.StringVar(&entries, "entries", "", "comma separated entries to restore, defaults to all")
+	fs.BoolVar(&opts.drop, "drop", false, "drop the collections before restoring, otherwise documents are replaced by _id")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "only decrypt the archive and count the documents per entry")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	for _, entry := range strings.Split(entries, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			opts.entries = append(opts.entries, entry)
+		}
+	}
+
+	if opts.configFile == "" {
+		return opts, fmt.Errorf("config file not set, use -config or $%s", ENV_CONFIG_FILE_PATH)
+	}
+	if (opts.archive == "") == (opts.file == "") {
+		return opts, fmt.Errorf("either -archive or -file is required")
+	}
+	if opts.dryRun && opts.drop {
+		return opts, fmt.Errorf("-dry-run and -drop cannot be combined")
+	}
+	return opts, nil
+}
+
+func initConfig(configFile string) error {
+	yamlFile, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	// not strict, so that the config file of the backup job can be used as it is
+	if err := yaml.Unmarshal(yamlFile, &conf); err != nil {
+		return err
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+	return nil
+}
+
+func secretsOverride() {
+	overrideDBCredentials(&conf.DBConfigs.StudyDB, ENV_STUDY_DB_USERNAME, ENV_STUDY_DB_PASSWORD)
+	overrideDBCredentials(&conf.DBConfigs.ParticipantUserDB, ENV_PARTICIPANT_USER_DB_USERNAME, ENV_PARTICIPANT_USER_DB_PASSWORD)
+
+	if conf.Backup.Storage.S3 != nil {
+		if accessKeyID := os.Getenv(ENV_BACKUP_S3_ACCESS_KEY_ID); accessKeyID != "" {
+			conf.Backup.Storage.S3.AccessKeyID = accessKeyID
+		}
+		if secretAccessKey := os.Getenv(ENV_BACKUP_S3_SECRET_ACCESS_KEY); secretAccessKey != "" {
+			conf.Backup.Storage.S3.SecretAccessKey = secretAccessKey
+		}
+	}
+
+	if vaultToken := os.Getenv(ENV_BACKUP_VAULT_TOKEN); vaultToken != "" {
+		conf.Backup.Encryption.VaultTransit.Token = vaultToken
+		for instanceID, encryption := range conf.Backup.InstanceEncryption {
+			encryption.VaultTransit.Token = vaultToken
+			conf.Backup.InstanceEncryption[instanceID] = encryption
+		}
+	}
+}
+
+func overrideDBCredentials(dbConf *db.DBConfigYaml, usernameEnv string, passwordEnv string) {
+	if dbUsername := os.Getenv(usernameEnv); dbUsername != "" {
+		dbConf.Username = dbUsername
+	}
+	if dbPassword := os.Getenv(passwordEnv); dbPassword != "" {
+		dbConf.Password = dbPassword
+	}
+}
+
+func dbConfig(yamlObj db.DBConfigYaml, instanceID string) db.DBConfig {
+	dbConf := db.DBConfigFromYamlObj(yamlObj, []string{instanceID})
+	// a restore only writes documents, indexes and migrations are left to the services
+	dbConf.RunIndexCreation = false
+	dbConf.RunMigrations = false
+	return dbConf
+}
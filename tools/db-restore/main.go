@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/backup"
+	"github.com/case-framework/case-backend/pkg/db"
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+)
+
+// db-restore decrypts an archive of the db-backup job and writes its documents back, replacing documents with the
+// same _id. The report with the number of documents per entry is printed as JSON.
+// Usage: db-restore -config config.yaml (-archive instance1/backup_20240510T020000Z.cbk | -file backup.cbk)
+// [-target-instance id] [-entries studyDB/study1_surveyResponses] [-drop] [-dry-run]
+func main() {
+	os.Exit(run())
+}
+
+type entryReport struct {
+	Entry     string `json:"entry"`
+	Documents int64  `json:"documents"`
+	Restored  int64  `json:"restored"`
+	Error     string `json:"error,omitempty"`
+}
+
+// restoreTarget writes the documents of an entry into a collection of the target instance
+type restoreTarget interface {
+	NewRestoreWriter(instanceID string, collectionName string) *db.BulkWriter
+	DropCollection(instanceID string, collectionName string) error
+}
+
+// run returns the exit code: 0 if all selected entries are restored, 1 if the archive or an entry failed, 2 for
+// invalid options
+func run() int {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := initConfig(opts.configFile); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to read config:", err)
+		return 1
+	}
+
+	archiveFile, err := openArchive(opts)
+	if err != nil {
+		slog.Error("failed to open archive", slog.String("error", err.Error()))
+		return 1
+	}
+	defer archiveFile.Close()
+
+	header, err := backup.ReadHeader(archiveFile)
+	if err != nil {
+		slog.Error("failed to read archive header", slog.String("error", err.Error()))
+		return 1
+	}
+	keys, err := conf.Backup.KeyProvider(header.InstanceID)
+	if err != nil {
+		slog.Error("no key for the archive", slog.String("instanceID", header.InstanceID), slog.String("error", err.Error()))
+		return 1
+	}
+	archive, err := backup.NewArchiveReader(archiveFile, header, keys)
+	if err != nil {
+		slog.Error("failed to decrypt archive", slog.String("error", err.Error()))
+		return 1
+	}
+
+	targetInstanceID := header.InstanceID
+	if opts.targetInstanceID != "" {
+		targetInstanceID = opts.targetInstanceID
+	}
+	slog.Info("restoring archive",
+		slog.String("instanceID", header.InstanceID),
+		slog.String("targetInstanceID", targetInstanceID),
+		slog.Time("createdAt", header.CreatedAt),
+		slog.Bool("dryRun", opts.dryRun),
+	)
+
+	targets := map[string]restoreTarget{}
+	reports := []entryReport{}
+	failed := false
+	for {
+		entry, err := archive.NextEntry()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			slog.Error("failed to read archive", slog.String("error", err.Error()))
+			failed = true
+			break
+		}
+		if len(opts.entries) > 0 && !slices.Contains(opts.entries, entry) {
+			continue
+		}
+
+		report := restoreEntry(archive, entry, targets, targetInstanceID, opts)
+		if report.Error != "" {
+			slog.Error("failed to restore entry", slog.String("entry", entry), slog.String("error", report.Error))
+			failed = true
+		} else {
+			slog.Info("restored entry", slog.String("entry", entry), slog.Int64("documents", report.Documents), slog.Int64("restored", report.Restored))
+		}
+		reports = append(reports, report)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(reports); err != nil {
+		slog.Error("failed to write report", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func openArchive(opts restoreOptions) (io.ReadCloser, error) {
+	if opts.file != "" {
+		return os.Open(opts.file)
+	}
+	storage, err := backup.NewStorage(conf.Backup.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Open(context.Background(), opts.archive)
+}
+
+// restoreEntry reads all documents of the entry, writing them unless it is a dry run
+func restoreEntry(archive *backup.ArchiveReader, entry string, targets map[string]restoreTarget, instanceID string, opts restoreOptions) entryReport {
+	report := entryReport{Entry: entry}
+
+	var writer *db.BulkWriter
+	if !opts.dryRun {
+		target, collectionName, err := getTarget(entry, targets, instanceID)
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		if opts.drop {
+			if err := target.DropCollection(instanceID, collectionName); err != nil {
+				report.Error = err.Error()
+				return report
+			}
+		}
+		writer = target.NewRestoreWriter(instanceID, collectionName)
+	}
+
+	var errs []error
+	for {
+		doc, err := archive.NextDocument()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		report.Documents++
+		if writer == nil {
+			continue
+		}
+
+		model, err := db.RestoreModel(doc)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := writer.Add(model); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if writer != nil {
+		if err := writer.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+		result := writer.Result()
+		report.Restored = result.UpsertedCount + result.MatchedCount
+	}
+	if err := errors.Join(errs...); err != nil {
+		report.Error = err.Error()
+	}
+	return report
+}
+
+// getTarget connects to the DB of the entry on first use and returns the collection name within it
+func getTarget(entry string, targets map[string]restoreTarget, instanceID string) (restoreTarget, string, error) {
+	switch {
+	case strings.HasPrefix(entry, backup.ENTRY_PREFIX_PARTICIPANT_USER_DB):
+		target, ok := targets[backup.ENTRY_PREFIX_PARTICIPANT_USER_DB]
+		if !ok {
+			dbService, err := userDB.NewParticipantUserDBService(dbConfig(conf.DBConfigs.ParticipantUserDB, instanceID))
+			if err != nil {
+				return nil, "", err
+			}
+			target = dbService
+			targets[backup.ENTRY_PREFIX_PARTICIPANT_USER_DB] = target
+		}
+		return target, strings.TrimPrefix(entry, backup.ENTRY_PREFIX_PARTICIPANT_USER_DB), nil
+	case strings.HasPrefix(entry, backup.ENTRY_PREFIX_STUDY_DB):
+		target, ok := targets[backup.ENTRY_PREFIX_STUDY_DB]
+		if !ok {
+			dbService, err := studyDB.NewStudyDBService(dbConfig(conf.DBConfigs.StudyDB, instanceID))
+			if err != nil {
+				return nil, "", err
+			}
+			target = dbService
+			targets[backup.ENTRY_PREFIX_STUDY_DB] = target
+		}
+		return target, strings.TrimPrefix(entry, backup.ENTRY_PREFIX_STUDY_DB), nil
+	}
+	return nil, "", fmt.Errorf("unknown entry: %s", entry)
+}
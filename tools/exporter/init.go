@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/case-framework/case-backend/pkg/db"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"gopkg.in/yaml.v2"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+)
+
+// Environment variables
+const (
+	ENV_CONFIG_FILE_PATH = "CONFIG_FILE_PATH"
+
+	// Variables to override "secrets" in the config file
+	ENV_STUDY_DB_USERNAME = "STUDY_DB_USERNAME"
+	ENV_STUDY_DB_PASSWORD = "STUDY_DB_PASSWORD"
+)
+
+// config uses the same format as the config files of the jobs, other sections of job configs are ignored
+type config struct {
+	// Logging configs
+	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
+
+	// DB configs
+	DBConfigs struct {
+		StudyDB db.DBConfigYaml `json:"study_db" yaml:"study_db"`
+	} `json:"db_configs" yaml:"db_configs"`
+}
+
+// exportOptions are set through command line flags
+type exportOptions struct {
+	configFile string
+
+	instanceID string
+	studyKey   string
+	surveyKeys []string
+	outputDir  string
+
+	format            string
+	shortKeys         bool
+	questionOptionSep string
+	extraCtxCols      []string
+	valueLabels       string
+	labelLang         string
+
+	// arrival time range, empty for no limit
+	from   string
+	until  string
+	filter string
+
+	participantIDs     string
+	salt               string
+	redactColumns      []string
+	timestampPrecision string
+
+	participantInfo  []string
+	participantFlags []string
+	validationIssues bool
+
+	includeQuarantined bool
+
+	writeManifest bool
+}
+
+var (
+	conf           config
+	studyDBService *studyDB.StudyDBService
+)
+
+func parseFlags(args []string) (exportOptions, error) {
+	opts := exportOptions{}
+	var surveyKeys, extraCtxCols, redactColumns, participantInfo, participantFlags string
+
+	fs := flag.NewFlagSet("exporter", flag.ContinueOnError)
+	fs.StringVar(&opts.configFile, "config", os.Getenv(ENV_CONFIG_FILE_PATH), "path of the config file, defaults to $"+ENV_CONFIG_FILE_PATH)
+	fs.StringVar(&opts.instanceID, "instance", "", "instance ID (required)")
+	fs.StringVar(&opts.studyKey, "study", "", "study key (required)")
+	fs.StringVar(&surveyKeys, "surveys", "", "comma separated survey keys, one file is written per survey (required)")
+	fs.StringVar(&opts.outputDir, "out", ".", "directory the export files are written to")
+	fs.StringVar(&opts.format, "format", "wide", "export format: wide, long, json, ndjson, parquet or xlsx")
+	fs.BoolVar(&opts.shortKeys, "short-keys", false, "use short item keys for column names")
+	fs.StringVar(&opts.questionOptionSep, "separator", "-", "separator between question and option keys in column names")
+	fs.StringVar(&extraCtxCols, "extra-context-columns", "", "comma separated context keys exported as extra columns")
+	fs.StringVar(&opts.valueLabels, "value-labels", "", "export option labels: labels or both")
+	fs.StringVar(&opts.labelLang, "label-lang", "", "language of the labels")
+	fs.StringVar(&opts.from, "from", "", "only responses that arrived at or after this time (RFC 3339 or YYYY-MM-DD)")
+	fs.StringVar(&opts.until, "until", "", "only responses that arrived before this time (RFC 3339 or YYYY-MM-DD)")
+	fs.StringVar(&opts.filter, "filter", "", "additional response filter as JSON, e.g. {\"participantID\": \"...\"}")
+	fs.StringVar(&opts.participantIDs, "participant-ids", "", "pseudonymize participant IDs: hash or remap")
+	fs.StringVar(&opts.salt, "salt", "", "salt for hashed participant IDs, random for each file if empty")
+	fs.StringVar(&redactColumns, "redact-columns", "", "comma separated column names or patterns dropped from the export")
+	fs.StringVar(&opts.timestampPrecision, "timestamp-precision", "", "truncate timestamps to minute, hour, day or month")
+	fs.StringVar(&participantInfo, "participant-info", "", "comma separated participant fields added to each response: enteredAt, studyStatus")
+	fs.StringVar(&participantFlags, "participant-flags", "", "comma separated participant flag keys added to each response")
+	fs.BoolVar(&opts.validationIssues, "validation-issues", false, "add a column with the validation issues found at submission")
+	fs.BoolVar(&opts.includeQuarantined, "include-quarantined", false, "also export responses quarantined as suspected spam or bots")
+	fs.BoolVar(&opts.writeManifest, "manifest", true, "write a manifest with checksums next to each export file")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	opts.surveyKeys = splitList(surveyKeys)
+	opts.extraCtxCols = splitList(extraCtxCols)
+	opts.redactColumns = splitList(redactColumns)
+	opts.participantInfo = splitList(participantInfo)
+	opts.participantFlags = splitList(participantFlags)
+
+	if opts.configFile == "" {
+		return opts, fmt.Errorf("config file not set, use -config or $%s", ENV_CONFIG_FILE_PATH)
+	}
+	if opts.instanceID == "" || opts.studyKey == "" || len(opts.surveyKeys) == 0 {
+		return opts, fmt.Errorf("-instance, -study and -surveys are required")
+	}
+	return opts, nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func initConfig(configFile string) error {
+	yamlFile, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	// not strict, so that config files of the jobs can be used as they are
+	if err := yaml.Unmarshal(yamlFile, &conf); err != nil {
+		return err
+	}
+
+	// Init logger:
+	utils.InitLogger(
+		conf.Logging.LogLevel,
+		conf.Logging.IncludeSrc,
+		conf.Logging.LogToFile,
+		conf.Logging.Filename,
+		conf.Logging.MaxSize,
+		conf.Logging.MaxAge,
+		conf.Logging.MaxBackups,
+		conf.Logging.CompressOldLogs,
+		conf.Logging.IncludeBuildInfo,
+	)
+
+	// Override secrets from environment variables
+	secretsOverride()
+	return nil
+}
+
+func secretsOverride() {
+	// Override secrets from environment variables
+
+	if dbUsername := os.Getenv(ENV_STUDY_DB_USERNAME); dbUsername != "" {
+		conf.DBConfigs.StudyDB.Username = dbUsername
+	}
+
+	if dbPassword := os.Getenv(ENV_STUDY_DB_PASSWORD); dbPassword != "" {
+		conf.DBConfigs.StudyDB.Password = dbPassword
+	}
+}
+
+func initDBs(instanceID string) error {
+	dbConfig := db.DBConfigFromYamlObj(conf.DBConfigs.StudyDB, []string{instanceID})
+	// the exporter only reads, e.g. with a read-only DB user
+	dbConfig.RunIndexCreation = false
+
+	var err error
+	studyDBService, err = studyDB.NewStudyDBService(dbConfig)
+	return err
+}
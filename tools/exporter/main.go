@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	"github.com/case-framework/case-backend/pkg/study/exporter/manifest"
+	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// exporter writes response exports straight from the study DB, without the management API.
+// Usage: exporter -config config.yaml -instance <id> -study <key> -surveys <key1,key2> [-format parquet] [-out dir]
+func main() {
+	os.Exit(run())
+}
+
+// run returns the exit code: 0 if all exports succeeded, 1 if one failed, 2 for invalid options
+func run() int {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err := initConfig(opts.configFile); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to read config:", err)
+		return 1
+	}
+
+	baseFilter, err := responseFilter(opts)
+	if err != nil {
+		slog.Error("invalid export options", slog.String("error", err.Error()))
+		return 2
+	}
+	if !surveyresponses.IsSupportedExportFormat(opts.format) {
+		slog.Error("unsupported format", slog.String("format", opts.format))
+		return 2
+	}
+	if err := pseudonymizationOptions(opts).Validate(); err != nil {
+		slog.Error("invalid pseudonymization options", slog.String("error", err.Error()))
+		return 2
+	}
+	for _, field := range opts.participantInfo {
+		if !surveyresponses.IsSupportedParticipantInfoField(field) {
+			slog.Error("unsupported participant info field", slog.String("field", field))
+			return 2
+		}
+	}
+
+	if err := os.MkdirAll(opts.outputDir, os.ModePerm); err != nil {
+		slog.Error("failed to create output directory", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if err := initDBs(opts.instanceID); err != nil {
+		slog.Error("Error connecting to Study DB", slog.String("error", err.Error()))
+		return 1
+	}
+	defer func() {
+		if err := studyDBService.DBClient.Disconnect(context.Background()); err != nil {
+			slog.Error("Error closing DB connection", slog.String("error", err.Error()))
+		}
+	}()
+
+	// stop cleanly on Ctrl+C, the unfinished file is removed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	failed := 0
+	for _, surveyKey := range opts.surveyKeys {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := exportSurvey(ctx, opts, surveyKey, baseFilter); err != nil {
+			slog.Error("export failed", slog.String("surveyKey", surveyKey), slog.String("error", err.Error()))
+			failed += 1
+		}
+	}
+
+	slog.Info("exports finished", slog.Int("surveys", len(opts.surveyKeys)), slog.Int("failed", failed), slog.String("duration", time.Since(start).String()))
+	if failed > 0 || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+func exportSurvey(ctx context.Context, opts exportOptions, surveyKey string, baseFilter bson.M) error {
+	filter := bson.M{}
+	for k, v := range baseFilter {
+		filter[k] = v
+	}
+	filter["key"] = surveyKey
+	// responses of synthetic-monitoring accounts are not exported
+	filter = studyDB.ExcludeSynthetic(filter)
+	if !opts.includeQuarantined {
+		filter = studyDB.ExcludeQuarantined(filter)
+	}
+
+	count, err := studyDBService.GetResponsesCount(opts.instanceID, opts.studyKey, filter)
+	if err != nil {
+		return err
+	}
+	slog.Info("exporting responses", slog.String("surveyKey", surveyKey), slog.Int64("responses", count))
+
+	surveyVersions, err := surveydefinition.PrepareSurveyInfosFromDB(
+		studyDBService,
+		opts.instanceID,
+		opts.studyKey,
+		surveyKey,
+		&surveydefinition.ExtractOptions{
+			UseLabelLang: opts.labelLang,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get survey versions: %w", err)
+	}
+
+	parser, err := surveyresponses.NewResponseParser(
+		surveyKey,
+		surveyVersions,
+		opts.shortKeys,
+		nil,
+		opts.questionOptionSep,
+		&opts.extraCtxCols,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create response parser: %w", err)
+	}
+
+	exportFilePath := filepath.Join(opts.outputDir, exportFileName(opts.studyKey, surveyKey, opts.format, time.Now()))
+	file, err := os.Create(exportFilePath)
+	if err != nil {
+		return err
+	}
+	err = writeExport(ctx, opts, surveyKey, filter, count, parser, file, exportFilePath)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := os.Remove(exportFilePath); removeErr != nil {
+			slog.Error("failed to remove incomplete export file", slog.String("path", exportFilePath), slog.String("error", removeErr.Error()))
+		}
+		return err
+	}
+	return nil
+}
+
+func writeExport(
+	ctx context.Context,
+	opts exportOptions,
+	surveyKey string,
+	filter bson.M,
+	count int64,
+	parser *surveyresponses.ResponseParser,
+	file *os.File,
+	exportFilePath string,
+) error {
+	exporter, err := surveyresponses.NewResponseExporterWithOptions(
+		parser,
+		file,
+		surveyresponses.ExportOptions{
+			Format:           opts.format,
+			ValueLabels:      opts.valueLabels,
+			Pseudonymization: pseudonymizationOptions(opts),
+			ParticipantInfo: surveyresponses.ParticipantInfoOptions{
+				Fields: opts.participantInfo,
+				Flags:  opts.participantFlags,
+				Lookup: surveyresponses.ParticipantsFromDB(studyDBService, opts.instanceID, opts.studyKey),
+			},
+			ValidationIssues: opts.validationIssues,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	exportManifest := manifest.NewExportManifest(opts.instanceID, opts.studyKey, surveyKey, "responses", opts.format, filter)
+	exportManifest.Columns = exporter.Schema()
+
+	written, err := exporter.Stream(
+		ctx,
+		surveyresponses.ResponsesFromDB(studyDBService, opts.instanceID, opts.studyKey, filter, bson.M{"arrivedAt": 1}),
+		surveyresponses.StreamOptions{
+			OnResponseWritten: exportManifest.ObserveResponse,
+			OnProgress: func(written int) {
+				slog.Info("export progress", slog.String("surveyKey", surveyKey), slog.Int("written", written), slog.Int64("total", count))
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if err := exporter.Finish(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	slog.Info("generated response export", slog.String("path", exportFilePath), slog.Int("responses", written))
+
+	if !opts.writeManifest {
+		return nil
+	}
+	if err := exportManifest.AddFile(exportFilePath); err != nil {
+		return fmt.Errorf("failed to compute export file checksum: %w", err)
+	}
+	return exportManifest.WriteToFile(manifest.ManifestPathForFile(exportFilePath))
+}
+
+func pseudonymizationOptions(opts exportOptions) surveyresponses.PseudonymizationOptions {
+	return surveyresponses.PseudonymizationOptions{
+		ParticipantIDs:     opts.participantIDs,
+		Salt:               opts.salt,
+		RedactColumns:      opts.redactColumns,
+		TimestampPrecision: opts.timestampPrecision,
+	}
+}
+
+// responseFilter combines the filter flag with the arrival time range
+func responseFilter(opts exportOptions) (bson.M, error) {
+	filter := bson.M{}
+	if opts.filter != "" {
+		if err := json.Unmarshal([]byte(opts.filter), &filter); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+
+	arrivedAt := bson.M{}
+	if opts.from != "" {
+		from, err := parseTime(opts.from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+		arrivedAt["$gte"] = from.Unix()
+	}
+	if opts.until != "" {
+		until, err := parseTime(opts.until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+		arrivedAt["$lt"] = until.Unix()
+	}
+	if len(arrivedAt) == 0 {
+		return filter, nil
+	}
+	if _, ok := filter["arrivedAt"]; ok {
+		return bson.M{"$and": bson.A{filter, bson.M{"arrivedAt": arrivedAt}}}, nil
+	}
+	filter["arrivedAt"] = arrivedAt
+	return filter, nil
+}
+
+// parseTime accepts RFC 3339 timestamps and dates (start of the day in UTC)
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func exportFileName(studyKey string, surveyKey string, format string, now time.Time) string {
+	return fmt.Sprintf("%s_%s_%s_%s%s", studyKey, surveyKey, format, now.UTC().Format("20060102-150405"), surveyresponses.ExportFileExtension(format))
+}